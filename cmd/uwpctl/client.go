@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errNotLoggedIn is returned for requests the panel rejects for want of a
+// valid login
+var errNotLoggedIn = errors.New("not logged in to the panel (run uwpctl login, or set UWP_TOKEN)")
+
+// apiError is an error response from the panel
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("panel returned %d: %s", e.Status, e.Message)
+}
+
+// client makes authenticated requests to the panel API
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// newClient creates a client for the panel at baseURL
+func newClient(baseURL, token string, insecure bool, timeout time.Duration) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Transport: transport, Timeout: timeout},
+	}
+}
+
+// do sends a request to path, below /api, with body encoded as JSON, and
+// decodes the JSON response into out
+func (c *client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "uwpctl/"+version)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &e)
+		msg := e.Error
+		if msg == "" {
+			msg = e.Message
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			if c.token == "" {
+				return errNotLoggedIn
+			}
+			return fmt.Errorf("%w (the session may have expired, run uwpctl login)", &apiError{Status: resp.StatusCode, Message: firstNonEmpty(msg, "unauthorized")})
+		}
+		if msg == "" {
+			msg = strings.TrimSpace(string(data))
+			if len(msg) > 200 {
+				msg = msg[:200] + "..."
+			}
+		}
+		if resp.StatusCode == http.StatusNotFound && strings.HasPrefix(path, "/plugin/") && msg == "404 page not found" {
+			msg = "not found, is the " + strings.SplitN(strings.TrimPrefix(path, "/plugin/"), "/", 2)[0] + " plugin installed and enabled?"
+		}
+		return &apiError{Status: resp.StatusCode, Message: msg}
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response from the panel: %v", err)
+	}
+	return nil
+}
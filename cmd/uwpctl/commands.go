@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runLogin logs in with a username and password and remembers the session
+// token. The password is read from UWP_PASSWORD or the first line of stdin,
+// so it never appears in the process list.
+func runLogin(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("login", "")
+	username := fs.String("username", firstNonEmpty(os.Getenv("UWP_USERNAME"), a.settings.Username), "panel `username`")
+	otp := fs.String("otp", "", "two-factor `code`, if the TOTP 2FA plugin requires one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *username == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	password := os.Getenv("UWP_PASSWORD")
+	if password == "" {
+		fmt.Fprintf(a.stderr, "Password for %s: ", *username)
+		line, err := bufio.NewReader(a.stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		fmt.Fprintln(a.stderr)
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return errors.New("no password given")
+	}
+
+	a.token = ""
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	body := map[string]string{"username": *username, "password": password}
+	if err := c.do(ctx, "POST", "/auth/login", body, &login); err != nil {
+		if errors.Is(err, errNotLoggedIn) {
+			return errors.New("invalid username or password")
+		}
+		return err
+	}
+	if login.Token == "" {
+		return errors.New("the panel did not return a session token")
+	}
+	c.token = login.Token
+
+	if *otp != "" {
+		if err := c.do(ctx, "POST", "/plugin/totp-2fa/verify", map[string]string{"code": *otp}, nil); err != nil {
+			return fmt.Errorf("two-factor verification failed: %v", err)
+		}
+	}
+
+	a.settings.URL = a.url
+	a.settings.Token = login.Token
+	a.settings.Username = *username
+	a.settings.Insecure = a.insecure
+	if err := saveSettings(a.configPath, a.settings); err != nil {
+		return fmt.Errorf("failed to save %s: %v", a.configPath, err)
+	}
+	fmt.Fprintf(a.stderr, "Logged in to %s as %s\n", a.url, *username)
+	return nil
+}
+
+// runLogout forgets the remembered session token
+func runLogout(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("logout", "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if a.settings.Token == "" {
+		fmt.Fprintln(a.stderr, "Not logged in")
+		return nil
+	}
+	a.settings.Token = ""
+	if err := saveSettings(a.configPath, a.settings); err != nil {
+		return fmt.Errorf("failed to save %s: %v", a.configPath, err)
+	}
+	fmt.Fprintln(a.stderr, "Logged out")
+	return nil
+}
+
+// graphql runs a query on the GraphQL Gateway plugin
+func (a *app) graphql(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data   interface{} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	resp.Data = out
+	body := map[string]interface{}{"query": query, "variables": variables}
+	if err := c.do(ctx, "POST", "/plugin/graphql-gateway/graphql", body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return errors.New(resp.Errors[0].Message)
+	}
+	return nil
+}
+
+// statsSample is a sample of network totals from the GraphQL Gateway
+type statsSample struct {
+	Time       string `json:"time,omitempty"`
+	Users      int    `json:"users"`
+	Opers      int    `json:"opers"`
+	UserRecord int    `json:"userRecord,omitempty"`
+	Channels   int    `json:"channels"`
+	Servers    int    `json:"servers"`
+	ServerBans int    `json:"serverBans"`
+}
+
+// runStats shows the network totals, or with export the stats history
+func runStats(ctx context.Context, a *app, args []string) error {
+	if len(args) > 0 && args[0] == "export" {
+		return runStatsExport(ctx, a, args[1:])
+	}
+	fs := a.flags("stats", "| uwpctl stats export [flags]")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	var data struct {
+		Stats *statsSample `json:"stats"`
+	}
+	if err := a.graphql(ctx, "{ stats { users opers userRecord channels servers serverBans } }", nil, &data); err != nil {
+		return err
+	}
+	if data.Stats == nil {
+		return errors.New("no stats returned")
+	}
+	if a.json {
+		return a.printJSON(data.Stats)
+	}
+	tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Users\t%d\n", data.Stats.Users)
+	fmt.Fprintf(tw, "Opers\t%d\n", data.Stats.Opers)
+	fmt.Fprintf(tw, "User record\t%d\n", data.Stats.UserRecord)
+	fmt.Fprintf(tw, "Channels\t%d\n", data.Stats.Channels)
+	fmt.Fprintf(tw, "Servers\t%d\n", data.Stats.Servers)
+	fmt.Fprintf(tw, "Server bans\t%d\n", data.Stats.ServerBans)
+	return tw.Flush()
+}
+
+// runStatsExport writes the stats history sampled by the GraphQL Gateway
+// as CSV or JSON
+func runStatsExport(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("stats export", "")
+	minutes := fs.Int("minutes", 1440, "only samples from the last this many `minutes` (0 for all)")
+	limit := fs.Int("limit", 0, "only the latest this many `samples` (0 for all)")
+	format := fs.String("format", "csv", "output `format`, csv or json")
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if a.json {
+		*format = "json"
+	}
+	if fs.NArg() > 0 || *minutes < 0 || *limit < 0 || (*format != "csv" && *format != "json") {
+		fs.Usage()
+		return errUsage
+	}
+
+	vars := map[string]interface{}{}
+	if *minutes > 0 {
+		vars["minutes"] = *minutes
+	}
+	if *limit > 0 {
+		vars["limit"] = *limit
+	}
+	var data struct {
+		StatsHistory []statsSample `json:"statsHistory"`
+	}
+	query := `query ($minutes: Int, $limit: Int) {
+  statsHistory(minutes: $minutes, limit: $limit) { time users opers channels servers serverBans }
+}`
+	if err := a.graphql(ctx, query, vars, &data); err != nil {
+		return err
+	}
+
+	w := a.stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		return writeJSON(w, data.StatsHistory)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "users", "opers", "channels", "servers", "server_bans"})
+	for _, s := range data.StatsHistory {
+		cw.Write([]string{s.Time, strconv.Itoa(s.Users), strconv.Itoa(s.Opers), strconv.Itoa(s.Channels), strconv.Itoa(s.Servers), strconv.Itoa(s.ServerBans)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// geoInfo is the location the Whois Tool found for an address
+type geoInfo struct {
+	CountryCode string `json:"country_code,omitempty"`
+	Country     string `json:"country,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+	ASN         string `json:"asn,omitempty"`
+	ASName      string `json:"asname,omitempty"`
+	ISP         string `json:"isp,omitempty"`
+	Source      string `json:"source,omitempty"`
+}
+
+// geoResult is the result of looking up one address
+type geoResult struct {
+	IP    string   `json:"ip"`
+	GeoIP *geoInfo `json:"geoip"`
+	Error string   `json:"error,omitempty"`
+}
+
+// runGeoIP looks up addresses with the Whois Tool plugin. Addresses are
+// given as arguments, or one per line on stdin with -.
+func runGeoIP(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("geoip", "<ip>... | -")
+	refresh := fs.Bool("refresh", false, "skip the Whois Tool's cache")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ips := fs.Args()
+	if len(ips) == 1 && ips[0] == "-" {
+		ips = nil
+		scanner := bufio.NewScanner(a.stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				ips = append(ips, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	if len(ips) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	for _, ip := range ips {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("%q is not an IP address", ip)
+		}
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	results := make([]geoResult, 0, len(ips))
+	failed := 0
+	for _, ip := range ips {
+		path := "/plugin/whois-tool/whois/" + url.PathEscape(ip) + "?type=ip"
+		if *refresh {
+			path += "&refresh=1"
+		}
+		var whois struct {
+			GeoIP *geoInfo `json:"geoip"`
+		}
+		r := geoResult{IP: ip}
+		if err := c.do(ctx, "GET", path, nil, &whois); err != nil {
+			if errors.Is(err, errNotLoggedIn) || ctx.Err() != nil {
+				return err
+			}
+			r.Error = err.Error()
+			failed++
+		}
+		r.GeoIP = whois.GeoIP
+		results = append(results, r)
+	}
+
+	if a.json {
+		if err := a.printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "IP\tCOUNTRY\tREGION\tCITY\tASN\tNETWORK")
+		for _, r := range results {
+			switch {
+			case r.Error != "":
+				fmt.Fprintf(tw, "%s\terror: %s\t\t\t\t\n", r.IP, r.Error)
+			case r.GeoIP == nil:
+				fmt.Fprintf(tw, "%s\t-\t\t\t\t\n", r.IP)
+			default:
+				g := r.GeoIP
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.IP, dash(g.CountryCode), dash(g.Region), dash(g.City), dash(g.ASN), dash(firstNonEmpty(g.ASName, g.ISP)))
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d lookups failed", failed, len(results))
+	}
+	return nil
+}
+
+// runBan adds a server ban through the RPC Console plugin, so it is
+// recorded in the console's history under the logged in user
+func runBan(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 || args[0] != "add" {
+		fmt.Fprintln(a.stderr, "Usage: uwpctl ban add [flags] <mask>")
+		return errUsage
+	}
+	fs := a.flags("ban add", "<mask>")
+	banType := fs.String("type", "gline", "ban `type`: kline, gline, zline, gzline, shun, ...")
+	duration := fs.String("duration", "1d", "`duration` such as 2h, 7d or 0 for permanent")
+	reason := fs.String("reason", "", "ban `reason`")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	mask := fs.Arg(0)
+	// A bare address bans every user at it
+	if net.ParseIP(mask) != nil {
+		mask = "*@" + mask
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	params := map[string]interface{}{
+		"type":            *banType,
+		"name":            mask,
+		"duration_string": *duration,
+	}
+	if *reason != "" {
+		params["reason"] = *reason
+	}
+	var call struct {
+		Success bool        `json:"success"`
+		Error   string      `json:"error"`
+		Result  interface{} `json:"result"`
+	}
+	body := map[string]interface{}{"method": "server_ban.add", "params": params}
+	if err := c.do(ctx, "POST", "/plugin/rpc-console/call", body, &call); err != nil {
+		return err
+	}
+	if !call.Success {
+		return fmt.Errorf("failed to add the ban: %s", call.Error)
+	}
+
+	if a.json {
+		return a.printJSON(call.Result)
+	}
+	fmt.Fprintf(a.stdout, "Added %s on %s for %s\n", *banType, mask, *duration)
+	return nil
+}
+
+// announcement is the part of an announcement uwpctl reports
+type announcement struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Kind       string    `json:"kind"`
+	Target     string    `json:"target"`
+	Status     string    `json:"status"`
+	SendAt     time.Time `json:"send_at"`
+	Recipients int       `json:"recipients"`
+	Failed     int       `json:"failed"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// runAnnounce drafts, previews and sends or schedules an announcement with
+// the Announcements plugin. The message is the arguments, or stdin with -.
+func runAnnounce(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("announce", "<message>... | -")
+	title := fs.String("title", "", "`title` shown in the panel (default: the start of the message)")
+	kind := fs.String("kind", "notice", "`kind`, notice or wallops")
+	target := fs.String("target", "all", "`recipients`: all, opers or servers")
+	servers := fs.String("servers", "", "comma separated `servers` for -target servers")
+	at := fs.String("at", "", "schedule for this RFC 3339 `time` instead of sending now")
+	dryRun := fs.Bool("dry-run", false, "preview only, leaving the announcement as a draft")
+	wait := fs.Bool("wait", false, "wait until the announcement has been delivered")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	message := strings.Join(fs.Args(), " ")
+	if fs.NArg() == 1 && fs.Arg(0) == "-" {
+		data, err := io.ReadAll(io.LimitReader(a.stdin, 1<<20))
+		if err != nil {
+			return err
+		}
+		message = string(data)
+	}
+	message = strings.TrimSpace(message)
+	if message == "" {
+		fs.Usage()
+		return errUsage
+	}
+	if *at != "" {
+		if _, err := time.Parse(time.RFC3339, *at); err != nil {
+			return errors.New("-at must be an RFC 3339 time such as 2026-10-15T18:00:00Z")
+		}
+	}
+	if *title == "" {
+		*title = message
+		if i := strings.IndexByte(*title, '\n'); i >= 0 {
+			*title = (*title)[:i]
+		}
+		if r := []rune(*title); len(r) > 60 {
+			*title = string(r[:57]) + "..."
+		}
+	}
+	serverList := make([]string, 0)
+	for _, s := range strings.Split(*servers, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			serverList = append(serverList, s)
+		}
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	const base = "/plugin/announcements/announcements"
+	var draft announcement
+	req := map[string]interface{}{"title": *title, "message": message, "kind": *kind, "target": *target, "servers": serverList}
+	if err := c.do(ctx, "POST", base, req, &draft); err != nil {
+		return err
+	}
+	var preview struct {
+		Lines      []string       `json:"lines"`
+		Recipients int            `json:"recipients"`
+		ByServer   map[string]int `json:"by_server"`
+	}
+	if err := c.do(ctx, "POST", base+"/"+draft.ID+"/preview", nil, &preview); err != nil {
+		return err
+	}
+	if *dryRun {
+		if a.json {
+			return a.printJSON(map[string]interface{}{"id": draft.ID, "lines": preview.Lines, "recipients": preview.Recipients, "by_server": preview.ByServer})
+		}
+		for _, line := range preview.Lines {
+			fmt.Fprintln(a.stdout, line)
+		}
+		fmt.Fprintf(a.stdout, "\nWould reach %d users. Draft %s was left in the panel.\n", preview.Recipients, draft.ID)
+		return nil
+	}
+
+	var result announcement
+	if *at != "" {
+		err = c.do(ctx, "POST", base+"/"+draft.ID+"/schedule", map[string]string{"send_at": *at}, &result)
+	} else {
+		err = c.do(ctx, "POST", base+"/"+draft.ID+"/send", nil, &result)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *wait && *at == "" {
+		for result.Status == "sending" {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			if err := c.do(ctx, "GET", base+"/"+draft.ID, nil, &result); err != nil {
+				return err
+			}
+		}
+	}
+
+	if a.json {
+		if err := a.printJSON(result); err != nil {
+			return err
+		}
+	} else {
+		switch result.Status {
+		case "scheduled":
+			fmt.Fprintf(a.stdout, "Scheduled %s for %s, to about %d users\n", result.ID, result.SendAt.Local().Format(time.RFC1123), preview.Recipients)
+		case "sending":
+			fmt.Fprintf(a.stdout, "Sending %s to about %d users\n", result.ID, preview.Recipients)
+		default:
+			fmt.Fprintf(a.stdout, "Announcement %s %s: %d recipients, %d failed\n", result.ID, result.Status, result.Recipients, result.Failed)
+		}
+	}
+	if result.Status == "failed" {
+		return fmt.Errorf("delivery failed: %s", result.Error)
+	}
+	return nil
+}
+
+// dash returns s, or - if it is empty
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
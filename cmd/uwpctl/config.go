@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// settings is what uwpctl remembers between runs
+type settings struct {
+	URL      string `json:"url"`
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+}
+
+// defaultConfigPath returns where the settings are kept unless -config or
+// UWP_CONFIG says otherwise
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".uwpctl.json"
+	}
+	return filepath.Join(dir, "uwpctl", "config.json")
+}
+
+// loadSettings reads the settings file. A missing file gives empty
+// settings.
+func loadSettings(path string) (settings, error) {
+	var s settings
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	return s, json.Unmarshal(data, &s)
+}
+
+// saveSettings writes the settings file. It holds a login token, so only
+// its owner may read it.
+func saveSettings(path string, s settings) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,194 @@
+// uwpctl - Command line client for the UnrealIRCd Web Panel plugin APIs
+// Logs in to the panel and wraps the endpoints of the plugins in this
+// repository, for cron jobs and shell scripts
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+)
+
+// version is reported in the User-Agent and by uwpctl version
+const version = "1.0.0"
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage is returned by commands given invalid arguments, after they
+// have printed their usage
+var errUsage = errors.New("usage")
+
+// app is the state shared by the commands
+type app struct {
+	configPath string
+	settings   settings
+	url        string
+	token      string
+	insecure   bool
+	json       bool
+	timeout    time.Duration
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+// command is a subcommand of uwpctl
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = []command{
+	{"login", "Log in to the panel and remember the session", runLogin},
+	{"logout", "Forget the remembered session", runLogout},
+	{"stats", "Show network totals, or export stats history", runStats},
+	{"geoip", "Look up the location of IP addresses", runGeoIP},
+	{"ban", "Add a server ban", runBan},
+	{"announce", "Send a notice or wallops announcement", runAnnounce},
+	{"version", "Show the version of uwpctl", runVersion},
+}
+
+func main() {
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(a.main(os.Args[1:]))
+}
+
+// main parses the global flags and runs a command, returning the exit code
+func (a *app) main(args []string) int {
+	fs := flag.NewFlagSet("uwpctl", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.StringVar(&a.configPath, "config", envOr("UWP_CONFIG", defaultConfigPath()), "settings file `path`")
+	url := fs.String("url", os.Getenv("UWP_URL"), "panel `URL`, e.g. https://panel.example.org")
+	token := fs.String("token", os.Getenv("UWP_TOKEN"), "panel session `token`, instead of the remembered login")
+	insecure := fs.Bool("insecure", envBool("UWP_INSECURE"), "skip TLS certificate verification")
+	fs.BoolVar(&a.json, "json", false, "print results as JSON")
+	fs.DurationVar(&a.timeout, "timeout", 30*time.Second, "request `timeout`")
+	fs.Usage = func() { a.usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		a.usage(fs)
+		return exitUsage
+	}
+
+	var err error
+	if a.settings, err = loadSettings(a.configPath); err != nil {
+		fmt.Fprintf(a.stderr, "uwpctl: failed to read %s: %v\n", a.configPath, err)
+		return exitError
+	}
+	// Flags and the environment win over the settings file
+	a.url = firstNonEmpty(*url, a.settings.URL)
+	a.token = firstNonEmpty(*token, a.settings.Token)
+	a.insecure = *insecure || a.settings.Insecure
+
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		err := cmd.run(ctx, a, fs.Args()[1:])
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, errUsage):
+			return exitUsage
+		}
+		fmt.Fprintf(a.stderr, "uwpctl %s: %v\n", name, err)
+		return exitError
+	}
+	fmt.Fprintf(a.stderr, "uwpctl: unknown command %q\n", name)
+	a.usage(fs)
+	return exitUsage
+}
+
+// usage prints the global help
+func (a *app) usage(fs *flag.FlagSet) {
+	fmt.Fprintf(a.stderr, "Usage: uwpctl [flags] <command> [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(a.stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(a.stderr, "\nRun uwpctl <command> -h for the arguments of a command.\n\nFlags:\n")
+	fs.PrintDefaults()
+}
+
+// client returns a client for the configured panel
+func (a *app) client() (*client, error) {
+	if a.url == "" {
+		return nil, errors.New("no panel URL (use -url, set UWP_URL or run uwpctl login)")
+	}
+	return newClient(a.url, a.token, a.insecure, a.timeout), nil
+}
+
+// flags returns a flag set for a command, printing errors and help to
+// stderr
+func (a *app) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet("uwpctl "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: uwpctl %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// printJSON writes v to stdout as indented JSON
+func (a *app) printJSON(v interface{}) error {
+	return writeJSON(a.stdout, v)
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// runVersion prints the version
+func runVersion(ctx context.Context, a *app, args []string) error {
+	fmt.Fprintf(a.stdout, "uwpctl %s\n", version)
+	return nil
+}
+
+// envOr returns the environment variable key, or def if it is empty
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envBool reports whether the environment variable key is set to true
+func envBool(key string) bool {
+	v, _ := strconv.ParseBool(os.Getenv(key))
+	return v
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,666 @@
+// Abandoned Channels Plugin for UnrealIRCd Web Panel
+// Flags channels without ops for a long time, with tiny member counts or
+// with expired founders, and offers a cleanup report with bulk actions
+
+package abandonedchannels
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/services"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Reasons a channel can be flagged for
+const (
+	FlagNoOps          = "no_ops"
+	FlagTiny           = "tiny"
+	FlagFounderExpired = "founder_expired"
+)
+
+// AbandonedChannelsPlugin implements the Plugin interface
+type AbandonedChannelsPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	services  services.Backend
+	data      storeData
+	dirty     bool
+	lastScan  time.Time
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL           string `json:"rpc_url"`
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	DataDir          string `json:"data_dir"`
+	ScanInterval     int    `json:"scan_interval"`
+	NoOpDays         int    `json:"no_op_days"`
+	TinyMembers      int    `json:"tiny_members"`
+	ServicesBackend  string `json:"services_backend"`
+	ServicesEndpoint string `json:"services_endpoint"`
+	ServicesUser     string `json:"services_user"`
+	ServicesPassword string `json:"services_password"`
+	ServicesSourceIP string `json:"services_source_ip"`
+	FounderChecks    int    `json:"founder_checks"`
+	AllowActions     bool   `json:"allow_actions"`
+}
+
+// servicesConfig returns the settings of the services client
+func (cfg Config) servicesConfig() services.Config {
+	return services.Config{
+		Backend:  cfg.ServicesBackend,
+		Endpoint: cfg.ServicesEndpoint,
+		Username: cfg.ServicesUser,
+		Password: cfg.ServicesPassword,
+		SourceIP: cfg.ServicesSourceIP,
+	}
+}
+
+// Channel is what the plugin knows about a channel
+type Channel struct {
+	Name             string    `json:"name"`
+	Members          int       `json:"members"`
+	Registered       bool      `json:"registered"`
+	FirstSeen        time.Time `json:"first_seen"`
+	LastSeen         time.Time `json:"last_seen"`
+	LastOpSeen       time.Time `json:"last_op_seen"`
+	Founder          string    `json:"founder,omitempty"`
+	FounderExpired   bool      `json:"founder_expired"`
+	FounderCheckedAt time.Time `json:"founder_checked_at"`
+	Dismissed        bool      `json:"dismissed"`
+}
+
+// Report is a flagged channel in the cleanup report
+type Report struct {
+	Channel
+	Flags     []string `json:"flags"`
+	NoOpsDays int      `json:"no_ops_days"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Channels map[string]*Channel `json:"channels"`
+}
+
+// rpcChannel is the UnrealIRCd channel object at detail level 3
+type rpcChannel struct {
+	Name     string `json:"name"`
+	NumUsers int    `json:"num_users"`
+	Modes    string `json:"modes"`
+	Members  []struct {
+		Name  string `json:"name"`
+		Level string `json:"level"`
+	} `json:"members"`
+}
+
+var (
+	founderLine   = regexp.MustCompile(`(?i)^\s*founder\s*:\s*(\S+)`)
+	notRegistered = regexp.MustCompile(`(?i)(isn't|is not) registered`)
+)
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &AbandonedChannelsPlugin{
+		config: Config{
+			RPCURL:           "https://127.0.0.1:8600/api",
+			DataDir:          "data/plugins/abandoned-channels",
+			ScanInterval:     900,
+			NoOpDays:         30,
+			TinyMembers:      1,
+			ServicesBackend:  "none",
+			ServicesEndpoint: "http://127.0.0.1:8080/xmlrpc",
+			ServicesSourceIP: "127.0.0.1",
+			FounderChecks:    25,
+			AllowActions:     false,
+		},
+		data: storeData{Channels: make(map[string]*Channel)},
+	}
+}
+
+// Info returns plugin metadata
+func (p *AbandonedChannelsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Abandoned Channels",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Find channels without ops, members or founders",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *AbandonedChannelsPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.data); err != nil {
+		log.Printf("[abandoned-channels] failed to load data: %v", err)
+	}
+	if p.data.Channels == nil {
+		p.data.Channels = make(map[string]*Channel)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "abandoned-channels-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		counts := map[string]int{FlagNoOps: 0, FlagTiny: 0, FlagFounderExpired: 0}
+		for _, r := range p.report() {
+			for _, f := range r.Flags {
+				counts[f]++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Abandoned Channels",
+			Icon:  "Archive",
+			Content: map[string]interface{}{
+				"no_ops":          counts[FlagNoOps],
+				"tiny":            counts[FlagTiny],
+				"founder_expired": counts[FlagFounderExpired],
+			},
+			Order: 45,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.scanLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *AbandonedChannelsPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *AbandonedChannelsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/abandoned-channels")
+	{
+		plugin.GET("/report", p.handleReport)
+		plugin.POST("/scan", p.handleScan)
+		plugin.POST("/actions", p.handleAction)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *AbandonedChannelsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "channels.json")
+}
+
+// save persists the channel data if it changed
+func (p *AbandonedChannelsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.data); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *AbandonedChannelsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// getServices returns the services backend, or nil if services are not configured
+func (p *AbandonedChannelsPlugin) getServices() (services.Backend, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.ServicesBackend == "none" || p.config.ServicesBackend == "" {
+		return nil, nil
+	}
+	if p.services == nil {
+		b, err := services.New(p.config.servicesConfig())
+		if err != nil {
+			return nil, err
+		}
+		p.services = b
+	}
+	return p.services, nil
+}
+
+// scanLoop scans channels until shutdown
+func (p *AbandonedChannelsPlugin) scanLoop() {
+	defer p.wg.Done()
+
+	p.scan()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.ScanInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < time.Minute {
+			interval = time.Minute
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.scan()
+		}
+	}
+}
+
+// scan updates channel state from the IRCd and checks a batch of founders
+func (p *AbandonedChannelsPlugin) scan() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var result struct {
+		List []rpcChannel `json:"list"`
+	}
+	err := p.client().Call(ctx, "channel.list", map[string]interface{}{"object_detail_level": 3}, &result)
+	if err != nil {
+		p.mu.Lock()
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		return err
+	}
+
+	now := time.Now().UTC()
+	p.mu.Lock()
+	present := make(map[string]bool, len(result.List))
+	for _, rc := range result.List {
+		key := strings.ToLower(rc.Name)
+		present[key] = true
+
+		ch, ok := p.data.Channels[key]
+		if !ok {
+			// Give new channels the full grace period before flagging
+			ch = &Channel{Name: rc.Name, FirstSeen: now, LastOpSeen: now}
+			p.data.Channels[key] = ch
+		}
+		ch.Members = rc.NumUsers
+		ch.LastSeen = now
+		if f := strings.Fields(rc.Modes); len(f) > 0 {
+			ch.Registered = strings.ContainsRune(f[0], 'r')
+		} else {
+			ch.Registered = false
+		}
+		for _, m := range rc.Members {
+			if strings.ContainsAny(m.Level, "qaoh") {
+				ch.LastOpSeen = now
+				break
+			}
+		}
+	}
+	// Channels that no longer exist aren't abandoned, they're gone
+	for key := range p.data.Channels {
+		if !present[key] {
+			delete(p.data.Channels, key)
+		}
+	}
+	p.dirty = true
+	p.lastScan = now
+	p.lastError = ""
+	p.mu.Unlock()
+
+	if err := p.checkFounders(ctx); err != nil {
+		log.Printf("[abandoned-channels] founder check failed: %v", err)
+	}
+
+	if err := p.save(); err != nil {
+		log.Printf("[abandoned-channels] failed to save data: %v", err)
+	}
+	return nil
+}
+
+// checkFounders looks up the founders of registered channels that have not
+// been checked for a day, oldest check first
+func (p *AbandonedChannelsPlugin) checkFounders(ctx context.Context) error {
+	b, err := p.getServices()
+	if err != nil || b == nil {
+		return err
+	}
+
+	p.mu.RLock()
+	limit := p.config.FounderChecks
+	due := make([]*Channel, 0)
+	cutoff := time.Now().Add(-24 * time.Hour)
+	for _, ch := range p.data.Channels {
+		if ch.Registered && ch.FounderCheckedAt.Before(cutoff) {
+			due = append(due, ch)
+		}
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].FounderCheckedAt.Before(due[j].FounderCheckedAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, ch := range due {
+		founder, expired, err := p.lookupFounder(ctx, b, ch.Name)
+		if err != nil {
+			return err
+		}
+		p.mu.Lock()
+		ch.Founder = founder
+		ch.FounderExpired = expired
+		ch.FounderCheckedAt = time.Now().UTC()
+		p.dirty = true
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// lookupFounder asks ChanServ for a channel's founder and NickServ whether
+// that account still exists
+func (p *AbandonedChannelsPlugin) lookupFounder(ctx context.Context, b services.Backend, channel string) (string, bool, error) {
+	if !ircname.ValidChannel(channel) {
+		return "", false, fmt.Errorf("%q is not a valid channel name", channel)
+	}
+	lines, err := b.Command(ctx, "ChanServ", "INFO "+channel)
+	if err != nil {
+		return "", false, err
+	}
+	founder := ""
+	for _, line := range lines {
+		if m := founderLine.FindStringSubmatch(line); m != nil {
+			founder = m[1]
+			break
+		}
+	}
+	if founder == "" {
+		return "", false, nil
+	}
+	if !ircname.ValidNick(founder) {
+		return founder, false, fmt.Errorf("%q is not a valid account name", founder)
+	}
+
+	lines, err = b.Command(ctx, "NickServ", "INFO "+founder)
+	if err != nil {
+		return founder, false, err
+	}
+	for _, line := range lines {
+		if notRegistered.MatchString(line) {
+			return founder, true, nil
+		}
+	}
+	return founder, false, nil
+}
+
+// report returns the flagged channels, most stale first. Caller must hold p.mu.
+func (p *AbandonedChannelsPlugin) report() []Report {
+	now := time.Now()
+	reports := make([]Report, 0)
+	for _, ch := range p.data.Channels {
+		if ch.Dismissed {
+			continue
+		}
+		r := Report{
+			Channel:   *ch,
+			Flags:     make([]string, 0, 3),
+			NoOpsDays: int(now.Sub(ch.LastOpSeen).Hours() / 24),
+		}
+		if p.config.NoOpDays > 0 && r.NoOpsDays >= p.config.NoOpDays {
+			r.Flags = append(r.Flags, FlagNoOps)
+		}
+		if ch.Members <= p.config.TinyMembers {
+			r.Flags = append(r.Flags, FlagTiny)
+		}
+		if ch.FounderExpired {
+			r.Flags = append(r.Flags, FlagFounderExpired)
+		}
+		if len(r.Flags) > 0 {
+			reports = append(reports, r)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if len(reports[i].Flags) != len(reports[j].Flags) {
+			return len(reports[i].Flags) > len(reports[j].Flags)
+		}
+		return reports[i].LastOpSeen.Before(reports[j].LastOpSeen)
+	})
+	return reports
+}
+
+// handleReport returns the cleanup report, optionally filtered by flag
+func (p *AbandonedChannelsPlugin) handleReport(c *gin.Context) {
+	flag := c.Query("flag")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	reports := p.report()
+	if flag != "" {
+		filtered := make([]Report, 0)
+		for _, r := range reports {
+			for _, f := range r.Flags {
+				if f == flag {
+					filtered = append(filtered, r)
+					break
+				}
+			}
+		}
+		reports = filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels":   reports,
+		"count":      len(reports),
+		"tracked":    len(p.data.Channels),
+		"last_scan":  p.lastScan,
+		"last_error": p.lastError,
+	})
+}
+
+// handleScan runs a scan immediately
+func (p *AbandonedChannelsPlugin) handleScan(c *gin.Context) {
+	if err := p.scan(); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Scan complete"})
+}
+
+// handleAction applies a cleanup action to a list of channels
+func (p *AbandonedChannelsPlugin) handleAction(c *gin.Context) {
+	var req struct {
+		Action   string   `json:"action" binding:"required"`
+		Channels []string `json:"channels" binding:"required"`
+		Modes    string   `json:"modes"`
+		DryRun   bool     `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action and channels are required"})
+		return
+	}
+	for _, channel := range req.Channels {
+		if !ircname.ValidChannel(channel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%q is not a valid channel name", channel)})
+			return
+		}
+	}
+
+	p.mu.RLock()
+	allowed := p.config.AllowActions
+	p.mu.RUnlock()
+	if !allowed && req.Action != "dismiss" && !req.DryRun {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bulk actions are disabled in the plugin settings"})
+		return
+	}
+
+	var apply func(ctx context.Context, channel string) error
+	switch req.Action {
+	case "dismiss":
+		apply = p.dismiss
+	case "set_mode":
+		if req.Modes == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "modes is required for set_mode"})
+			return
+		}
+		apply = func(ctx context.Context, channel string) error {
+			return p.client().Call(ctx, "channel.set_mode", map[string]interface{}{
+				"channel": channel,
+				"modes":   req.Modes,
+			}, nil)
+		}
+	case "drop":
+		b, err := p.getServices()
+		if err != nil || b == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "drop requires a services backend"})
+			return
+		}
+		apply = func(ctx context.Context, channel string) error {
+			cmd := "DROP " + channel
+			if b.Name() == "atheme" {
+				cmd = "FDROP " + channel
+			}
+			_, err := b.Command(ctx, "ChanServ", cmd)
+			return err
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be dismiss, set_mode or drop"})
+		return
+	}
+
+	if req.DryRun {
+		c.JSON(http.StatusOK, gin.H{"action": req.Action, "channels": req.Channels, "dry_run": true})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+
+	actor := access.Actor(c)
+	results := make(map[string]string, len(req.Channels))
+	for _, channel := range req.Channels {
+		if err := apply(ctx, channel); err != nil {
+			results[channel] = err.Error()
+			continue
+		}
+		results[channel] = "ok"
+		log.Printf("[abandoned-channels] %s: %s %s", actor, req.Action, channel)
+	}
+	p.save()
+
+	c.JSON(http.StatusOK, gin.H{"action": req.Action, "results": results})
+}
+
+// dismiss hides a channel from the report until it is recreated
+func (p *AbandonedChannelsPlugin) dismiss(ctx context.Context, channel string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ch, ok := p.data.Channels[strings.ToLower(channel)]
+	if !ok {
+		return fmt.Errorf("channel %s is not tracked", channel)
+	}
+	ch.Dismissed = true
+	p.dirty = true
+	return nil
+}
+
+// handleGetConfig returns the current configuration
+func (p *AbandonedChannelsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.ServicesPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *AbandonedChannelsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	switch newConfig.ServicesBackend {
+	case "none", "anope", "atheme":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "services_backend must be none, anope or atheme"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.ServicesPassword == "" {
+		newConfig.ServicesPassword = p.config.ServicesPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.services = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *AbandonedChannelsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *AbandonedChannelsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	p.services = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,229 @@
+/**
+ * Account Retention Frontend Script
+ *
+ * Shows weekly cohort retention as a table and a curve, and churn per week.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'account-retention';
+  const PLUGIN_NAME = 'Account Retention';
+  const PAGE_PATH = '/plugins/account-retention';
+  const API_BASE = '/api/plugin/account-retention';
+
+  let rangeWeeks = 12;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  // Cell shaded by retention, stronger for higher shares
+  function retentionCell(rate) {
+    if (rate == null) return '<td class="art-pending"></td>';
+    const alpha = Math.min(rate / 100, 1) * 0.8 + 0.05;
+    return `<td style="background: rgba(137, 180, 250, ${alpha.toFixed(2)})">${rate}%</td>`;
+  }
+
+  function cohortTable(data) {
+    const weeks = Array.from({ length: data.cohort_weeks }, (_, i) => i + 1);
+    const rows = data.cohorts.slice().reverse().map(c => `
+      <tr>
+        <td>${escapeHtml(c.week)}</td>
+        <td>${c.size.toLocaleString()}</td>
+        ${c.size ? c.retention.map(retentionCell).join('') : weeks.map(() => '<td class="art-pending"></td>').join('')}
+      </tr>
+    `).join('');
+    return `
+      <table class="art-table">
+        <thead>
+          <tr><th>First seen (week of)</th><th>Accounts</th>${weeks.map(w => `<th>+${w}w</th>`).join('')}</tr>
+        </thead>
+        <tbody>${rows}</tbody>
+      </table>
+    `;
+  }
+
+  // Line of the average share returning in each week after the first
+  function curveChart(curve, width, height) {
+    const step = width / Math.max(curve.length - 1, 1);
+    const points = curve
+      .map((v, i) => v == null ? null : `${(i * step).toFixed(1)},${(height - (v / 100) * (height - 10)).toFixed(1)}`)
+      .filter(Boolean);
+    const dots = curve.map((v, i) => v == null ? '' : `
+      <circle cx="${(i * step).toFixed(1)}" cy="${(height - (v / 100) * (height - 10)).toFixed(1)}" r="3" fill="var(--accent, #89b4fa)">
+        <title>Week +${i + 1}: ${v}%</title>
+      </circle>
+    `).join('');
+    return `
+      <svg viewBox="-5 0 ${width + 10} ${height}" class="art-chart" preserveAspectRatio="none">
+        <polyline points="${points.join(' ')}" fill="none" stroke="var(--accent, #89b4fa)" stroke-width="2"/>
+        ${dots}
+      </svg>
+    `;
+  }
+
+  function churnTable(weeks) {
+    const rows = weeks.slice().reverse().map(w => `
+      <tr>
+        <td>${escapeHtml(w.week)}</td>
+        <td>${w.active.toLocaleString()}</td>
+        <td>${w.new.toLocaleString()}</td>
+        <td>${w.retained.toLocaleString()}</td>
+        <td>${w.churned.toLocaleString()}</td>
+        <td>${w.churn_rate == null ? '-' : `${w.churn_rate}%`}</td>
+      </tr>
+    `).join('');
+    return `
+      <table class="art-table">
+        <thead>
+          <tr><th>Week of</th><th>Active</th><th>New</th><th>Back from week before</th><th>Churned</th><th>Churn rate</th></tr>
+        </thead>
+        <tbody>${rows}</tbody>
+      </table>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('account-retention-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'account-retention-styles';
+    style.textContent = `
+      .art-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .art-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .art-table th, .art-table td { text-align: left; padding: 0.35rem 0.5rem; border-bottom: 1px solid var(--border-primary, #313244); }
+      .art-table td { color: var(--text-primary, #cdd6f4); }
+      .art-pending { background: var(--bg-secondary, #181825); }
+      .art-chart { width: 100%; height: 160px; background: var(--bg-secondary, #181825); border-radius: 8px; }
+      .art-range button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        margin-right: 0.25rem;
+        cursor: pointer;
+      }
+      .art-range button.active { background: var(--accent, #89b4fa); color: #fff; }
+      .art-note { font-size: 0.85rem; }
+      .art-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#art-body');
+    try {
+      const status = await api('/status');
+      const cohorts = await api(`/cohorts?weeks=${Math.min(rangeWeeks, status.retention_weeks)}`);
+      const churn = await api(`/churn?weeks=${Math.min(rangeWeeks, status.retention_weeks - 2)}`);
+
+      body.innerHTML = `
+        ${status.last_error ? `<div class="art-error">Last check: ${escapeHtml(status.last_error)}</div>` : ''}
+        <div class="art-note">
+          ${status.accounts.toLocaleString()} accounts recorded since the week of ${escapeHtml(status.oldest_week)}.
+          Accounts already around when the plugin was installed all count as first seen in that week.
+        </div>
+        <h3>Average retention</h3>
+        ${curveChart(cohorts.average, 600, 160)}
+        <h3>Cohorts</h3>
+        ${cohortTable(cohorts)}
+        <h3>Churn</h3>
+        ${churnTable(churn.weeks)}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="art-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="art-app" data-plugin="${PLUGIN_ID}">
+        <div class="art-range">
+          ${[[8, '8 weeks'], [12, '12 weeks'], [26, '26 weeks']].map(([w, label]) =>
+            `<button data-weeks="${w}" class="${w === rangeWeeks ? 'active' : ''}">${label}</button>`).join('')}
+        </div>
+        <div id="art-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelectorAll('.art-range button').forEach(btn => {
+      btn.addEventListener('click', () => {
+        rangeWeeks = parseInt(btn.dataset.weeks, 10);
+        container.querySelectorAll('.art-range button').forEach(b => b.classList.toggle('active', b === btn));
+        load(container);
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('account-retention-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
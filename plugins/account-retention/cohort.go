@@ -0,0 +1,196 @@
+package accountretention
+
+import (
+	"sort"
+	"time"
+)
+
+// cohortWeeks is how many weeks after its first a cohort is followed
+const cohortWeeks = 8
+
+// weekKey is the layout of week keys, the date of the week's Monday
+const weekKey = "2006-01-02"
+
+// History records the weeks an account was seen in
+type History struct {
+	// First is the week the account was first seen
+	First string `json:"first"`
+	// Weeks lists the weeks seen within the retention period, oldest first
+	Weeks []string `json:"weeks"`
+}
+
+// seen reports whether the account was seen in a week
+func (h *History) seen(week string) bool {
+	i := sort.SearchStrings(h.Weeks, week)
+	return i < len(h.Weeks) && h.Weeks[i] == week
+}
+
+// add records the account as seen in a week and reports whether that is new
+func (h *History) add(week string) bool {
+	i := sort.SearchStrings(h.Weeks, week)
+	if i < len(h.Weeks) && h.Weeks[i] == week {
+		return false
+	}
+	h.Weeks = append(h.Weeks, "")
+	copy(h.Weeks[i+1:], h.Weeks[i:])
+	h.Weeks[i] = week
+	if h.First == "" || week < h.First {
+		h.First = week
+	}
+	return true
+}
+
+// prune drops weeks before cutoff and reports whether any are left
+func (h *History) prune(cutoff string) bool {
+	i := sort.SearchStrings(h.Weeks, cutoff)
+	h.Weeks = h.Weeks[i:]
+	return len(h.Weeks) > 0
+}
+
+// weekStart returns the Monday 00:00 UTC of the week holding t
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// weekOf returns the key of the week holding t
+func weekOf(t time.Time) string {
+	return weekStart(t).Format(weekKey)
+}
+
+// addWeeks returns the key of the week n weeks after the given one
+func addWeeks(week string, n int) string {
+	t, err := time.Parse(weekKey, week)
+	if err != nil {
+		return week
+	}
+	return t.AddDate(0, 0, 7*n).Format(weekKey)
+}
+
+// Cohort is the retention of the accounts first seen in one week
+type Cohort struct {
+	Week string `json:"week"`
+	Size int    `json:"size"`
+	// Returned and Retention hold weeks 1 to cohortWeeks after the first;
+	// weeks that haven't ended yet are null
+	Returned  []*int     `json:"returned"`
+	Retention []*float64 `json:"retention"`
+}
+
+// Churn is how many of the accounts active in the week before a week were
+// not seen in it
+type Churn struct {
+	Week      string   `json:"week"`
+	Active    int      `json:"active"`
+	Previous  int      `json:"previous"`
+	Retained  int      `json:"retained"`
+	Churned   int      `json:"churned"`
+	New       int      `json:"new"`
+	ChurnRate *float64 `json:"churn_rate"`
+}
+
+// cohorts works out the retention of the cohorts of the given weeks.
+// current is the week in progress, which isn't counted as a return week
+// until it has ended.
+func cohorts(accounts map[string]*History, weeks []string, current string) []Cohort {
+	index := make(map[string]int, len(weeks))
+	list := make([]Cohort, len(weeks))
+	counts := make([][cohortWeeks]int, len(weeks))
+	for i, week := range weeks {
+		index[week] = i
+		list[i] = Cohort{Week: week}
+	}
+
+	for _, h := range accounts {
+		i, ok := index[h.First]
+		if !ok {
+			continue
+		}
+		list[i].Size++
+		for k := 1; k <= cohortWeeks; k++ {
+			if h.seen(addWeeks(h.First, k)) {
+				counts[i][k-1]++
+			}
+		}
+	}
+
+	for i := range list {
+		c := &list[i]
+		c.Returned = make([]*int, cohortWeeks)
+		c.Retention = make([]*float64, cohortWeeks)
+		for k := 1; k <= cohortWeeks; k++ {
+			if addWeeks(c.Week, k) >= current {
+				break
+			}
+			n := counts[i][k-1]
+			c.Returned[k-1] = &n
+			c.Retention[k-1] = percent(n, c.Size)
+		}
+	}
+	return list
+}
+
+// averageCurve weights the retention of each week after the first by
+// cohort size, over the cohorts for which that week has ended
+func averageCurve(list []Cohort) []*float64 {
+	curve := make([]*float64, cohortWeeks)
+	for k := 0; k < cohortWeeks; k++ {
+		returned, size := 0, 0
+		for _, c := range list {
+			if c.Returned[k] != nil {
+				returned += *c.Returned[k]
+				size += c.Size
+			}
+		}
+		curve[k] = percent(returned, size)
+	}
+	return curve
+}
+
+// churn works out the churn of each of the given weeks against the week
+// before it
+func churn(accounts map[string]*History, weeks []string) []Churn {
+	list := make([]Churn, len(weeks))
+	for i, week := range weeks {
+		prev := addWeeks(week, -1)
+		c := Churn{Week: week}
+		for _, h := range accounts {
+			now, before := h.seen(week), h.seen(prev)
+			if now {
+				c.Active++
+				if h.First == week {
+					c.New++
+				}
+			}
+			if before {
+				c.Previous++
+				if now {
+					c.Retained++
+				}
+			}
+		}
+		c.Churned = c.Previous - c.Retained
+		c.ChurnRate = percent(c.Churned, c.Previous)
+		list[i] = c
+	}
+	return list
+}
+
+// weeksBack returns the keys of the n weeks ending with last, oldest first
+func weeksBack(last string, n int) []string {
+	weeks := make([]string, n)
+	for i := 0; i < n; i++ {
+		weeks[i] = addWeeks(last, i-n+1)
+	}
+	return weeks
+}
+
+// percent returns n as a share of of, or nil when of is zero
+func percent(n, of int) *float64 {
+	if of == 0 {
+		return nil
+	}
+	v := float64(int64(float64(n)*1000/float64(of)+0.5)) / 10
+	return &v
+}
@@ -0,0 +1,491 @@
+// Account Retention Plugin for UnrealIRCd Web Panel
+// Groups accounts by the week they were first seen and follows how many
+// come back in the weeks after, with week-on-week churn
+
+package accountretention
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// AccountRetentionPlugin implements the Plugin interface
+type AccountRetentionPlugin struct {
+	config      Config
+	rpc         *jsonrpc.Client
+	accounts    map[string]*History
+	dirty       bool
+	lastPoll    time.Time
+	lastError   string
+	whowasError string
+	mu          sync.RWMutex
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	PollInterval   int    `json:"poll_interval"`
+	UseWhowas      bool   `json:"use_whowas"`
+	RetentionWeeks int    `json:"retention_weeks"`
+}
+
+// rpcUser is the subset of the UnrealIRCd user and WHOWAS objects we need
+type rpcUser struct {
+	LogonTime  string `json:"logon_time"`
+	LogoffTime string `json:"logoff_time"`
+	User       struct {
+		Account string `json:"account"`
+	} `json:"user"`
+}
+
+// logEntry is the subset of an UnrealIRCd JSON log line we need
+type logEntry struct {
+	Timestamp string `json:"timestamp"`
+	Client    *struct {
+		User struct {
+			Account string `json:"account"`
+		} `json:"user"`
+	} `json:"client"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &AccountRetentionPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/account-retention",
+			PollInterval:   120,
+			UseWhowas:      true,
+			RetentionWeeks: 26,
+		},
+		accounts: make(map[string]*History),
+	}
+}
+
+// Info returns plugin metadata
+func (p *AccountRetentionPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Account Retention",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Weekly cohort retention and churn of accounts",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *AccountRetentionPlugin) Init() error {
+	p.mu.Lock()
+	stored := make(map[string]*History)
+	if err := storage.LoadJSON(p.storePath(), &stored); err != nil {
+		log.Printf("[account-retention] failed to load history: %v", err)
+	}
+	for account, h := range stored {
+		if h != nil {
+			p.accounts[account] = h
+		}
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "account-retention-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		current := weekOf(time.Now())
+		last := churn(p.accounts, []string{current, addWeeks(current, -1)})
+		content := map[string]interface{}{
+			"active_this_week": last[0].Active,
+			"new_this_week":    last[0].New,
+		}
+		if rate := last[1].ChurnRate; rate != nil {
+			content["churn_last_week"] = *rate
+		}
+		curve := averageCurve(cohorts(p.accounts, weeksBack(addWeeks(current, -1), 12), current))
+		if curve[0] != nil {
+			content["week1_retention"] = *curve[0]
+		}
+		if p.lastError != "" {
+			content["status"] = "IRCd unreachable"
+		}
+		return plugins.DashboardCard{
+			Title:   "Account Retention",
+			Icon:    "UserCheck",
+			Content: content,
+			Order:   89,
+			Size:    "md",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *AccountRetentionPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *AccountRetentionPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/account-retention")
+	{
+		plugin.GET("/cohorts", p.handleCohorts)
+		plugin.GET("/churn", p.handleChurn)
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/import", p.handleImport)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the history file
+func (p *AccountRetentionPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "accounts.json")
+}
+
+// save persists the history if it changed
+func (p *AccountRetentionPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.accounts); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *AccountRetentionPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop records the accounts seen until shutdown
+func (p *AccountRetentionPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.poll()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.poll()
+			if err := p.save(); err != nil {
+				log.Printf("[account-retention] failed to save history: %v", err)
+			}
+		}
+	}
+}
+
+// poll records the accounts of connected users as seen this week, and
+// those in WHOWAS as seen in the weeks they logged on and off
+func (p *AccountRetentionPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client := p.client()
+	p.mu.RLock()
+	useWhowas := p.config.UseWhowas
+	p.mu.RUnlock()
+
+	var online struct {
+		List []rpcUser `json:"list"`
+	}
+	err := client.Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &online)
+
+	// whowas.get needs UnrealIRCd 6.1 or later
+	var whowas struct {
+		List []rpcUser `json:"list"`
+	}
+	var whowasErr error
+	if err == nil && useWhowas {
+		whowasErr = client.Call(ctx, "whowas.get", map[string]interface{}{"object_detail_level": 2}, &whowas)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		log.Printf("[account-retention] failed to list users: %v", err)
+		return
+	}
+	p.lastError = ""
+	p.whowasError = ""
+	if whowasErr != nil {
+		p.whowasError = whowasErr.Error()
+	}
+	now := time.Now()
+	p.lastPoll = now
+
+	week := weekOf(now)
+	for _, u := range online.List {
+		p.record(u.User.Account, week)
+	}
+	for _, u := range whowas.List {
+		for _, ts := range []string{u.LogonTime, u.LogoffTime} {
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				p.record(u.User.Account, weekOf(t))
+			}
+		}
+	}
+	p.prune(now)
+}
+
+// record marks an account as seen in a week. Caller must hold p.mu.
+func (p *AccountRetentionPlugin) record(account, week string) {
+	if account == "" || account == "0" || account == "*" {
+		return
+	}
+	if week < p.cutoff(time.Now()) {
+		return
+	}
+	key := strings.ToLower(account)
+	h, ok := p.accounts[key]
+	if !ok {
+		h = &History{}
+		p.accounts[key] = h
+	}
+	if h.add(week) {
+		p.dirty = true
+	}
+}
+
+// cutoff returns the oldest week kept. Caller must hold p.mu.
+func (p *AccountRetentionPlugin) cutoff(now time.Time) string {
+	return addWeeks(weekOf(now), -(p.config.RetentionWeeks - 1))
+}
+
+// prune drops weeks older than the retention period, and accounts with
+// none left. Caller must hold p.mu.
+func (p *AccountRetentionPlugin) prune(now time.Time) {
+	cutoff := p.cutoff(now)
+	for key, h := range p.accounts {
+		if len(h.Weeks) > 0 && h.Weeks[0] >= cutoff {
+			continue
+		}
+		if !h.prune(cutoff) {
+			delete(p.accounts, key)
+		}
+		p.dirty = true
+	}
+}
+
+// weeksParam reads ?weeks=, which must leave room for the cohort window
+// within the retention period. Caller must hold p.mu.
+func (p *AccountRetentionPlugin) weeksParam(c *gin.Context, max int) (int, bool) {
+	n := 12
+	if n > max {
+		n = max
+	}
+	if s := c.Query("weeks"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > max {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weeks must be between 1 and " + strconv.Itoa(max)})
+			return 0, false
+		}
+		n = v
+	}
+	return n, true
+}
+
+// handleCohorts returns the retention of the cohorts of the last ?weeks=
+// weeks, 12 by default, oldest first, and the average curve
+func (p *AccountRetentionPlugin) handleCohorts(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	n, ok := p.weeksParam(c, p.config.RetentionWeeks)
+	if !ok {
+		return
+	}
+	current := weekOf(time.Now())
+	list := cohorts(p.accounts, weeksBack(current, n), current)
+
+	c.JSON(http.StatusOK, gin.H{
+		"cohorts":      list,
+		"average":      averageCurve(list),
+		"cohort_weeks": cohortWeeks,
+	})
+}
+
+// handleChurn returns the churn of the last ?weeks= weeks that have ended,
+// 12 by default, oldest first
+func (p *AccountRetentionPlugin) handleChurn(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	n, ok := p.weeksParam(c, p.config.RetentionWeeks-2)
+	if !ok {
+		return
+	}
+	last := addWeeks(weekOf(time.Now()), -1)
+	c.JSON(http.StatusOK, gin.H{
+		"weeks": churn(p.accounts, weeksBack(last, n)),
+	})
+}
+
+// handleStatus returns the size of the history and the polling state
+func (p *AccountRetentionPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"accounts":        len(p.accounts),
+		"current_week":    weekOf(time.Now()),
+		"oldest_week":     p.cutoff(time.Now()),
+		"retention_weeks": p.config.RetentionWeeks,
+		"last_error":      p.lastError,
+		"whowas_error":    p.whowasError,
+	}
+	if !p.lastPoll.IsZero() {
+		status["last_poll"] = p.lastPoll
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleImport records accounts from an UnrealIRCd JSON log, to fill in
+// history from before the plugin was installed. The log is sent as the
+// request body, one JSON object per line.
+func (p *AccountRetentionPlugin) handleImport(c *gin.Context) {
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	imported, skipped := 0, 0
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for scanner.Scan() {
+		var entry logEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Client == nil {
+			skipped++
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		if err != nil || entry.Client.User.Account == "" {
+			skipped++
+			continue
+		}
+		p.record(entry.Client.User.Account, weekOf(ts))
+		imported++
+	}
+
+	if err := scanner.Err(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "imported": imported})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Import complete",
+		"imported": imported,
+		"skipped":  skipped,
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *AccountRetentionPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *AccountRetentionPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.PollInterval < 30 || newConfig.PollInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poll_interval must be between 30 and 3600 seconds"})
+		return
+	}
+	if newConfig.RetentionWeeks < cohortWeeks+2 || newConfig.RetentionWeeks > 104 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_weeks must be between " + strconv.Itoa(cohortWeeks+2) + " and 104"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *AccountRetentionPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *AccountRetentionPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
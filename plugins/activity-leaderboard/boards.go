@@ -0,0 +1,289 @@
+package activityleaderboard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Board names
+const (
+	BoardChannels  = "channels"
+	BoardSessions  = "sessions"
+	BoardHours     = "hours"
+	BoardCountries = "countries"
+)
+
+// boardNames are the boards in the order they are shown
+var boardNames = []string{BoardChannels, BoardSessions, BoardHours, BoardCountries}
+
+// Ways of naming the holder of a session
+const (
+	IdentityAccount = "account"
+	IdentityNick    = "nick"
+	IdentityNone    = "none"
+)
+
+// dayKey is the layout of the keys of daily totals
+const dayKey = "2006-01-02"
+
+// Day holds the connections counted on one UTC day
+type Day struct {
+	Connects  [24]int        `json:"connects"`
+	Countries map[string]int `json:"countries"`
+}
+
+// Session is a connection, open or finished, considered for the
+// longest sessions board
+type Session struct {
+	Nick    string     `json:"nick"`
+	Account string     `json:"account,omitempty"`
+	Start   time.Time  `json:"start"`
+	End     *time.Time `json:"end,omitempty"`
+}
+
+// duration returns how long a session lasted, or has lasted so far
+func (s *Session) duration(now time.Time) time.Duration {
+	if s.End != nil {
+		return s.End.Sub(s.Start)
+	}
+	return now.Sub(s.Start)
+}
+
+// Boards are the computed leaderboards. A disabled board is nil.
+type Boards struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	WindowDays  int               `json:"window_days"`
+	Channels    []ChannelEntry    `json:"channels,omitempty"`
+	Sessions    []SessionEntry    `json:"sessions,omitempty"`
+	Hours       []HourEntry       `json:"hours,omitempty"`
+	Countries   []CountryEntry    `json:"countries,omitempty"`
+	Errors      map[string]string `json:"errors,omitempty"`
+	Enabled     map[string]bool   `json:"enabled"`
+}
+
+// ChannelEntry is a place on the largest channels board
+type ChannelEntry struct {
+	Rank  int    `json:"rank"`
+	Name  string `json:"name"`
+	Users int    `json:"users"`
+}
+
+// SessionEntry is a place on the longest sessions board
+type SessionEntry struct {
+	Rank     int       `json:"rank"`
+	Name     string    `json:"name,omitempty"`
+	Start    time.Time `json:"start"`
+	Seconds  int64     `json:"seconds"`
+	Duration string    `json:"duration"`
+	Ongoing  bool      `json:"ongoing"`
+}
+
+// HourEntry is a place on the busiest joining hours board
+type HourEntry struct {
+	Rank       int     `json:"rank"`
+	Hour       int     `json:"hour"`
+	Label      string  `json:"label"`
+	Connects   int     `json:"connects"`
+	PerDay     float64 `json:"per_day"`
+	Percentage float64 `json:"percentage"`
+}
+
+// CountryEntry is a place on the top countries board
+type CountryEntry struct {
+	Rank       int     `json:"rank"`
+	Country    string  `json:"country"`
+	Connects   int     `json:"connects"`
+	Percentage float64 `json:"percentage"`
+}
+
+// rpcChannel is the part of the UnrealIRCd channel object we need
+type rpcChannel struct {
+	Name     string `json:"name"`
+	NumUsers int    `json:"num_users"`
+	Modes    string `json:"modes"`
+}
+
+// channelBoard ranks channels by users. Secret and private channels are
+// left out unless allowed, as are channels matching an exclude mask.
+func channelBoard(channels []rpcChannel, cfg Config) []ChannelEntry {
+	exclude := lists.Split(cfg.ExcludeChannels)
+	out := make([]ChannelEntry, 0, len(channels))
+	for _, ch := range channels {
+		// Modes look like "ntk secret"; only the letters matter here
+		letters := strings.SplitN(ch.Modes, " ", 2)[0]
+		if !cfg.IncludeSecret && strings.ContainsAny(letters, "sp") {
+			continue
+		}
+		if matchAny(exclude, ch.Name) {
+			continue
+		}
+		out = append(out, ChannelEntry{Name: ch.Name, Users: ch.NumUsers})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Users != out[j].Users {
+			return out[i].Users > out[j].Users
+		}
+		return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name)
+	})
+	if len(out) > cfg.BoardSize {
+		out = out[:cfg.BoardSize]
+	}
+	for i := range out {
+		out[i].Rank = i + 1
+	}
+	return out
+}
+
+// sessionBoard ranks sessions by length, open and finished within the
+// window. Each person appears once, with their longest session; with
+// identity "none" nobody is named, but people are still told apart.
+func sessionBoard(sessions []*Session, cfg Config, now time.Time) []SessionEntry {
+	best := make(map[string]*Session)
+	for _, s := range sessions {
+		key := strings.ToLower(s.Nick)
+		if cfg.SessionIdentity != IdentityNick && s.Account != "" {
+			key = "account:" + strings.ToLower(s.Account)
+		}
+		if cur, ok := best[key]; !ok || s.duration(now) > cur.duration(now) {
+			best[key] = s
+		}
+	}
+	out := make([]SessionEntry, 0, len(best))
+	for _, s := range best {
+		d := s.duration(now)
+		e := SessionEntry{
+			Start:    s.Start,
+			Seconds:  int64(d.Seconds()),
+			Duration: formatDuration(d),
+			Ongoing:  s.End == nil,
+		}
+		switch cfg.SessionIdentity {
+		case IdentityAccount:
+			e.Name = s.Account
+		case IdentityNick:
+			e.Name = s.Nick
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Seconds != out[j].Seconds {
+			return out[i].Seconds > out[j].Seconds
+		}
+		return out[i].Start.Before(out[j].Start)
+	})
+	if len(out) > cfg.BoardSize {
+		out = out[:cfg.BoardSize]
+	}
+	for i := range out {
+		out[i].Rank = i + 1
+	}
+	return out
+}
+
+// hourBoard ranks the hours of the day (UTC) by connections over the
+// window
+func hourBoard(days map[string]*Day, cfg Config) []HourEntry {
+	var totals [24]int
+	sum := 0
+	for _, d := range days {
+		for h, n := range d.Connects {
+			totals[h] += n
+			sum += n
+		}
+	}
+	if sum == 0 {
+		return make([]HourEntry, 0)
+	}
+	out := make([]HourEntry, 0, 24)
+	for h, n := range totals {
+		if n == 0 {
+			continue
+		}
+		out = append(out, HourEntry{
+			Hour:       h,
+			Label:      time.Date(2000, 1, 1, h, 0, 0, 0, time.UTC).Format("15:04") + " UTC",
+			Connects:   n,
+			PerDay:     round1(float64(n) / float64(len(days))),
+			Percentage: round1(float64(n) * 100 / float64(sum)),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Connects > out[j].Connects })
+	if len(out) > cfg.BoardSize {
+		out = out[:cfg.BoardSize]
+	}
+	for i := range out {
+		out[i].Rank = i + 1
+	}
+	return out
+}
+
+// countryBoard ranks countries by connections over the window. Countries
+// with fewer than min_country_connects are left out, so a handful of
+// users can't be picked out by where they connect from.
+func countryBoard(days map[string]*Day, cfg Config) []CountryEntry {
+	totals := make(map[string]int)
+	sum := 0
+	for _, d := range days {
+		for cc, n := range d.Countries {
+			totals[cc] += n
+			sum += n
+		}
+	}
+	out := make([]CountryEntry, 0, len(totals))
+	for cc, n := range totals {
+		if n < cfg.MinCountryConnects {
+			continue
+		}
+		out = append(out, CountryEntry{Country: cc, Connects: n, Percentage: round1(float64(n) * 100 / float64(sum))})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Connects != out[j].Connects {
+			return out[i].Connects > out[j].Connects
+		}
+		return out[i].Country < out[j].Country
+	})
+	if len(out) > cfg.BoardSize {
+		out = out[:cfg.BoardSize]
+	}
+	for i := range out {
+		out[i].Rank = i + 1
+	}
+	return out
+}
+
+// formatDuration writes a session length as days, hours and minutes
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	d -= time.Duration(days) * 24 * time.Hour
+	hours := int(d / time.Hour)
+	minutes := int((d - time.Duration(hours)*time.Hour) / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// round1 rounds to one decimal place
+func round1(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
+
+// matchAny reports whether s matches any of the masks
+func matchAny(masks []string, s string) bool {
+	for _, m := range masks {
+		if ircname.MatchMask(m, s) {
+			return true
+		}
+	}
+	return false
+}
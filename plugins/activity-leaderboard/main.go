@@ -0,0 +1,667 @@
+// Activity Leaderboard Plugin for UnrealIRCd Web Panel
+// Ranks the largest channels, longest sessions, busiest joining hours and
+// top countries, refreshed on a schedule, with each board optional
+
+package activityleaderboard
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits
+const (
+	maxSessions = 500
+	maxMasks    = 200
+)
+
+// ActivityLeaderboardPlugin implements the Plugin interface
+type ActivityLeaderboardPlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	days       map[string]*Day
+	sessions   []*Session
+	active     map[string]*activeClient
+	lastSample time.Time
+	boards     *Boards
+	sampleErr  string
+	dirty      bool
+	refresh    chan struct{}
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL             string `json:"rpc_url"`
+	RPCUser            string `json:"rpc_user"`
+	RPCPassword        string `json:"rpc_password"`
+	RPCInsecure        bool   `json:"rpc_insecure"`
+	DataDir            string `json:"data_dir"`
+	EnableChannels     bool   `json:"enable_channels"`
+	EnableSessions     bool   `json:"enable_sessions"`
+	EnableHours        bool   `json:"enable_hours"`
+	EnableCountries    bool   `json:"enable_countries"`
+	SessionIdentity    string `json:"session_identity"`
+	IncludeSecret      bool   `json:"include_secret"`
+	ExcludeChannels    string `json:"exclude_channels"`
+	ExcludeNicks       string `json:"exclude_nicks"`
+	MinCountryConnects int    `json:"min_country_connects"`
+	BoardSize          int    `json:"board_size"`
+	WindowDays         int    `json:"window_days"`
+	SampleInterval     int    `json:"sample_interval"`
+	RefreshInterval    int    `json:"refresh_interval"`
+}
+
+// activeClient is a connected client being followed
+type activeClient struct {
+	session *Session
+	seen    time.Time
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Days       map[string]*Day `json:"days"`
+	Sessions   []*Session      `json:"sessions"`
+	LastSample time.Time       `json:"last_sample"`
+}
+
+// rpcUser is the part of the UnrealIRCd user object we need
+type rpcUser struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	ConnectedSince string `json:"connected_since"`
+	GeoIP          struct {
+		CountryCode string `json:"country_code"`
+	} `json:"geoip"`
+	User struct {
+		Account string `json:"account"`
+		Modes   string `json:"modes"`
+	} `json:"user"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ActivityLeaderboardPlugin{
+		config: Config{
+			RPCURL:             "https://127.0.0.1:8600/api",
+			DataDir:            "data/plugins/activity-leaderboard",
+			EnableChannels:     true,
+			EnableSessions:     true,
+			EnableHours:        true,
+			EnableCountries:    true,
+			SessionIdentity:    IdentityAccount,
+			MinCountryConnects: 5,
+			BoardSize:          10,
+			WindowDays:         7,
+			SampleInterval:     60,
+			RefreshInterval:    15,
+		},
+		days:     make(map[string]*Day),
+		sessions: make([]*Session, 0),
+		active:   make(map[string]*activeClient),
+		refresh:  make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ActivityLeaderboardPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Activity Leaderboard",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Leaderboards of channels, sessions, joining hours and countries",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ActivityLeaderboardPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[activity-leaderboard] failed to load data: %v", err)
+	}
+	if data.Days != nil {
+		p.days = data.Days
+	}
+	if data.Sessions != nil {
+		p.sessions = data.Sessions
+	}
+	p.lastSample = data.LastSample
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card with the top three of each board
+	hm.Register(hooks.HookOverviewCard, "activity-leaderboard-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{}
+		if b := p.boards; b != nil {
+			content["generated_at"] = b.GeneratedAt
+			if b.Channels != nil {
+				content["largest_channels"] = b.Channels[:minInt(3, len(b.Channels))]
+			}
+			if b.Sessions != nil {
+				content["longest_sessions"] = b.Sessions[:minInt(3, len(b.Sessions))]
+			}
+			if b.Hours != nil {
+				content["busiest_hours"] = b.Hours[:minInt(3, len(b.Hours))]
+			}
+			if b.Countries != nil {
+				content["top_countries"] = b.Countries[:minInt(3, len(b.Countries))]
+			}
+		} else {
+			content["status"] = "Leaderboards not built yet"
+		}
+		return plugins.DashboardCard{
+			Title:   "Activity Leaderboard",
+			Icon:    "Trophy",
+			Content: content,
+			Order:   86,
+			Size:    "md",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.sampleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ActivityLeaderboardPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ActivityLeaderboardPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/activity-leaderboard")
+	{
+		plugin.GET("/boards", p.handleBoards)
+		plugin.GET("/boards/:name", p.handleBoard)
+		plugin.POST("/refresh", p.handleRefresh)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *ActivityLeaderboardPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "leaderboard.json")
+}
+
+// save persists the state if it changed
+func (p *ActivityLeaderboardPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	data := storeData{Days: p.days, Sessions: p.sessions, LastSample: p.lastSample}
+	if err := storage.SaveJSON(p.storePath(), data); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *ActivityLeaderboardPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// minInt returns the smaller of two ints
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// sampleLoop samples the users until shutdown, rebuilding the boards
+// every refresh_interval minutes or when asked to
+func (p *ActivityLeaderboardPlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.refresh:
+			p.build()
+			continue
+		case <-timer.C:
+		}
+		p.sample()
+		if err := p.save(); err != nil {
+			log.Printf("[activity-leaderboard] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		due := p.boards == nil || time.Since(p.boards.GeneratedAt) >= time.Duration(p.config.RefreshInterval)*time.Minute
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+		if due {
+			p.build()
+		}
+		timer.Reset(interval)
+	}
+}
+
+// requestBuild asks the loop to rebuild the boards soon
+func (p *ActivityLeaderboardPlugin) requestBuild() {
+	select {
+	case p.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// excluded reports whether a client is kept off the boards: services,
+// and nicks matching exclude_nicks
+func excluded(u rpcUser, masks []string) bool {
+	return strings.Contains(u.User.Modes, "S") || matchAny(masks, u.Name)
+}
+
+// sample follows connected clients. A client first seen that connected
+// after the previous sample is a new connection, counted for the hours
+// and countries boards; clients already connected when sampling starts
+// are only followed. A client that is gone ends its session. Only the
+// data of enabled boards is gathered.
+func (p *ActivityLeaderboardPlugin) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+	if !cfg.EnableSessions && !cfg.EnableHours && !cfg.EnableCountries {
+		return
+	}
+
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		if p.sampleErr == "" {
+			log.Printf("[activity-leaderboard] failed to list users: %v", err)
+		}
+		p.sampleErr = err.Error()
+		return
+	}
+	p.sampleErr = ""
+	now := time.Now().UTC()
+	masks := lists.Split(cfg.ExcludeNicks)
+
+	for _, u := range result.List {
+		if excluded(u, masks) {
+			continue
+		}
+		if a, ok := p.active[u.ID]; ok {
+			a.seen = now
+			a.session.Nick = u.Name
+			if u.User.Account != "" {
+				a.session.Account = u.User.Account
+			}
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, u.ConnectedSince)
+		if err != nil || start.After(now) {
+			start = now
+		}
+		start = start.UTC()
+		p.active[u.ID] = &activeClient{
+			session: &Session{Nick: u.Name, Account: u.User.Account, Start: start},
+			seen:    now,
+		}
+		if p.lastSample.IsZero() || !start.After(p.lastSample) {
+			continue
+		}
+		day := p.day(start)
+		if cfg.EnableHours {
+			day.Connects[start.Hour()]++
+		}
+		if cfg.EnableCountries && u.GeoIP.CountryCode != "" {
+			day.Countries[strings.ToUpper(u.GeoIP.CountryCode)]++
+		}
+	}
+
+	for id, a := range p.active {
+		if a.seen.Equal(now) {
+			continue
+		}
+		if cfg.EnableSessions {
+			end := a.seen
+			a.session.End = &end
+			p.sessions = append(p.sessions, a.session)
+		}
+		delete(p.active, id)
+	}
+
+	p.lastSample = now
+	p.prune(now)
+	p.dirty = true
+}
+
+// day returns the totals of the UTC day of t, creating them. Caller must
+// hold p.mu.
+func (p *ActivityLeaderboardPlugin) day(t time.Time) *Day {
+	key := t.UTC().Format(dayKey)
+	d, ok := p.days[key]
+	if !ok {
+		d = &Day{Countries: make(map[string]int)}
+		p.days[key] = d
+	}
+	if d.Countries == nil {
+		d.Countries = make(map[string]int)
+	}
+	return d
+}
+
+// prune drops days and finished sessions older than the window, and all
+// but the longest sessions beyond the limit. Caller must hold p.mu.
+func (p *ActivityLeaderboardPlugin) prune(now time.Time) {
+	cutoff := now.AddDate(0, 0, -p.config.WindowDays)
+	for key := range p.days {
+		if t, err := time.Parse(dayKey, key); err != nil || t.Before(cutoff.Truncate(24*time.Hour)) {
+			delete(p.days, key)
+		}
+	}
+	kept := p.sessions[:0]
+	for _, s := range p.sessions {
+		if s.End != nil && s.End.After(cutoff) {
+			kept = append(kept, s)
+		}
+	}
+	p.sessions = kept
+	if len(p.sessions) > maxSessions {
+		sort.Slice(p.sessions, func(i, j int) bool { return p.sessions[i].duration(now) > p.sessions[j].duration(now) })
+		p.sessions = p.sessions[:maxSessions]
+	}
+}
+
+// build computes the enabled boards. The largest channels board is read
+// from UnrealIRCd now; the others come from the samples.
+func (p *ActivityLeaderboardPlugin) build() {
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	var channels []rpcChannel
+	var chanErr error
+	if cfg.EnableChannels {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		var result struct {
+			List []rpcChannel `json:"list"`
+		}
+		chanErr = p.client().Call(ctx, "channel.list", map[string]interface{}{"object_detail_level": 1}, &result)
+		cancel()
+		channels = result.List
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now().UTC()
+	b := &Boards{
+		GeneratedAt: now,
+		WindowDays:  cfg.WindowDays,
+		Errors:      make(map[string]string),
+		Enabled: map[string]bool{
+			BoardChannels:  cfg.EnableChannels,
+			BoardSessions:  cfg.EnableSessions,
+			BoardHours:     cfg.EnableHours,
+			BoardCountries: cfg.EnableCountries,
+		},
+	}
+	if cfg.EnableChannels {
+		if chanErr != nil {
+			log.Printf("[activity-leaderboard] failed to list channels: %v", chanErr)
+			b.Errors[BoardChannels] = chanErr.Error()
+			// Keep the previous board rather than showing none
+			if p.boards != nil && p.boards.Channels != nil {
+				b.Channels = p.boards.Channels
+			}
+		} else {
+			b.Channels = channelBoard(channels, cfg)
+		}
+	}
+	if cfg.EnableSessions {
+		all := append([]*Session(nil), p.sessions...)
+		for _, a := range p.active {
+			all = append(all, a.session)
+		}
+		b.Sessions = sessionBoard(all, cfg, now)
+	}
+	if cfg.EnableHours {
+		b.Hours = hourBoard(p.days, cfg)
+	}
+	if cfg.EnableCountries {
+		b.Countries = countryBoard(p.days, cfg)
+	}
+	if p.sampleErr != "" && (cfg.EnableSessions || cfg.EnableHours || cfg.EnableCountries) {
+		b.Errors["sampling"] = p.sampleErr
+	}
+	p.boards = b
+}
+
+// handleBoards returns all enabled boards
+func (p *ActivityLeaderboardPlugin) handleBoards(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.boards == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Leaderboards have not been built yet"})
+		return
+	}
+	c.JSON(http.StatusOK, p.boards)
+}
+
+// handleBoard returns one board
+func (p *ActivityLeaderboardPlugin) handleBoard(c *gin.Context) {
+	name := c.Param("name")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	b := p.boards
+	if b == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Leaderboards have not been built yet"})
+		return
+	}
+	var entries interface{}
+	switch name {
+	case BoardChannels:
+		entries = b.Channels
+	case BoardSessions:
+		entries = b.Sessions
+	case BoardHours:
+		entries = b.Hours
+	case BoardCountries:
+		entries = b.Countries
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown board"})
+		return
+	}
+	if !b.Enabled[name] {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Board is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"board":        name,
+		"entries":      entries,
+		"generated_at": b.GeneratedAt,
+		"window_days":  b.WindowDays,
+		"error":        b.Errors[name],
+	})
+}
+
+// handleRefresh rebuilds the boards now
+func (p *ActivityLeaderboardPlugin) handleRefresh(c *gin.Context) {
+	p.build()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.boards)
+}
+
+// handleStatus returns the state of the sampling
+func (p *ActivityLeaderboardPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"following": len(p.active),
+		"sessions":  len(p.sessions),
+		"days":      len(p.days),
+		"error":     p.sampleErr,
+	}
+	if !p.lastSample.IsZero() {
+		status["last_sample"] = p.lastSample
+	}
+	if p.boards != nil {
+		status["generated_at"] = p.boards.GeneratedAt
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *ActivityLeaderboardPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration. Turning a board off
+// also deletes what was gathered for it.
+func (p *ActivityLeaderboardPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	switch newConfig.SessionIdentity {
+	case IdentityAccount, IdentityNick, IdentityNone:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session_identity must be account, nick or none"})
+		return
+	}
+	if newConfig.MinCountryConnects < 1 || newConfig.MinCountryConnects > 10000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_country_connects must be between 1 and 10000"})
+		return
+	}
+	if newConfig.BoardSize < 1 || newConfig.BoardSize > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "board_size must be between 1 and 100"})
+		return
+	}
+	if newConfig.WindowDays < 1 || newConfig.WindowDays > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window_days must be between 1 and 90"})
+		return
+	}
+	if newConfig.SampleInterval < 10 || newConfig.SampleInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sample_interval must be between 10 and 3600 seconds"})
+		return
+	}
+	if newConfig.RefreshInterval < 1 || newConfig.RefreshInterval > 1440 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_interval must be between 1 and 1440 minutes"})
+		return
+	}
+	for _, list := range []string{newConfig.ExcludeChannels, newConfig.ExcludeNicks} {
+		if len(lists.Split(list)) > maxMasks {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d exclude masks are allowed", maxMasks)})
+			return
+		}
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	if !newConfig.EnableSessions && len(p.sessions) > 0 {
+		p.sessions = make([]*Session, 0)
+		p.dirty = true
+	}
+	for _, d := range p.days {
+		if !newConfig.EnableHours {
+			d.Connects = [24]int{}
+		}
+		if !newConfig.EnableCountries {
+			d.Countries = make(map[string]int)
+		}
+		p.dirty = true
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[activity-leaderboard] failed to save data: %v", err)
+	}
+	p.requestBuild()
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ActivityLeaderboardPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ActivityLeaderboardPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
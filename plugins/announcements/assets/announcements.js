@@ -0,0 +1,396 @@
+/**
+ * Announcements Frontend Script
+ *
+ * Lets staff write a notice or wallops, preview who will receive it, send
+ * it now or schedule it, and browse the archive of past announcements.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'announcements';
+  const PLUGIN_NAME = 'Announcements';
+  const PAGE_PATH = '/plugins/announcements';
+  const API_BASE = '/api/plugin/announcements';
+
+  let editing = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('announcements-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'announcements-styles';
+    style.textContent = `
+      .ann-app { display: flex; flex-direction: column; gap: 1rem; }
+      .ann-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .ann-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .ann-btn.danger { background: var(--error, #f38ba8); color: #fff; }
+      .ann-btn:disabled { opacity: 0.5; cursor: default; }
+      .ann-form { display: grid; grid-template-columns: repeat(auto-fit, minmax(180px, 1fr)); gap: 0.5rem; align-items: end; }
+      .ann-form label { display: flex; flex-direction: column; gap: 0.25rem; font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .ann-form input, .ann-form select, .ann-form textarea, .ann-filter input, .ann-filter select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .ann-form textarea { min-height: 6rem; }
+      .ann-form .wide { grid-column: 1 / -1; }
+      .ann-actions { display: flex; gap: 0.5rem; flex-wrap: wrap; align-items: center; }
+      .ann-preview {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.75rem;
+        font-size: 0.85rem;
+        color: var(--text-secondary, #a6adc8);
+      }
+      .ann-preview pre { white-space: pre-wrap; word-break: break-word; font-size: 0.8rem; margin: 0.5rem 0; }
+      .ann-filter { display: flex; gap: 0.5rem; }
+      .ann-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .ann-table th, .ann-table td {
+        text-align: left;
+        padding: 0.5rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+        vertical-align: top;
+      }
+      .ann-table th { color: var(--text-primary, #cdd6f4); }
+      .ann-status { font-size: 0.75rem; padding: 0.1rem 0.4rem; border-radius: 4px; background: var(--bg-tertiary, #45475a); }
+      .ann-status.sent { color: var(--success, #a6e3a1); }
+      .ann-status.failed, .ann-status.missed { color: var(--error, #f38ba8); }
+      .ann-status.scheduled { color: var(--accent, #89b4fa); }
+      .ann-error { color: var(--error, #f38ba8); }
+      .ann-muted { color: var(--text-muted, #6c7086); }
+      .ann-empty { color: var(--text-muted, #6c7086); padding: 1rem 0; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function describeTarget(a) {
+    if (a.target === 'servers') return 'Servers: ' + (a.servers || []).join(', ');
+    return a.target === 'opers' ? 'Opers only' : 'All users';
+  }
+
+  function renderRow(a) {
+    const id = escapeHtml(a.id);
+    const editable = a.status === 'draft' || a.status === 'scheduled';
+    let when = '-';
+    if (a.status === 'scheduled') when = 'Sends ' + formatTime(a.send_at);
+    if (a.status === 'sent' || a.status === 'failed') {
+      when = `${formatTime(a.sent_at)}<br><small class="ann-muted">${a.recipients} users${a.failed ? `, ${a.failed} failed` : ''}</small>`;
+    }
+    return `
+      <tr>
+        <td><strong>${escapeHtml(a.title || '(untitled)')}</strong><br><small class="ann-muted">${escapeHtml(a.message.slice(0, 120))}</small></td>
+        <td>${escapeHtml(a.kind)}<br><small class="ann-muted">${escapeHtml(describeTarget(a))}</small></td>
+        <td><span class="ann-status ${escapeHtml(a.status)}">${escapeHtml(a.status)}</span>${a.error ? `<br><small class="ann-error">${escapeHtml(a.error)}</small>` : ''}</td>
+        <td>${when}</td>
+        <td><small>${escapeHtml(a.created_by)}${a.sent_by ? '<br>sent by ' + escapeHtml(a.sent_by) : ''}</small></td>
+        <td>
+          ${editable ? `<button class="ann-btn" data-action="edit" data-id="${id}">Edit</button>` : `<button class="ann-btn" data-action="copy" data-id="${id}">Reuse</button>`}
+          ${a.status === 'scheduled' ? `<button class="ann-btn" data-action="cancel" data-id="${id}">Cancel</button>` : ''}
+          ${a.status === 'draft' || a.status === 'cancelled' ? `<button class="ann-btn danger" data-action="delete" data-id="${id}">Delete</button>` : ''}
+        </td>
+      </tr>
+    `;
+  }
+
+  async function loadList(container) {
+    const list = container.querySelector('#ann-list');
+    const status = container.querySelector('#ann-status').value;
+    const q = container.querySelector('#ann-search').value.trim();
+
+    try {
+      const data = await api('GET', `/announcements?status=${encodeURIComponent(status)}&q=${encodeURIComponent(q)}`);
+      if (!data.announcements.length) {
+        list.innerHTML = '<div class="ann-empty">No announcements.</div>';
+        return;
+      }
+      list.innerHTML = `
+        <table class="ann-table">
+          <thead><tr><th>Announcement</th><th>Kind</th><th>Status</th><th>When</th><th>By</th><th></th></tr></thead>
+          <tbody>${data.announcements.map(renderRow).join('')}</tbody>
+        </table>
+      `;
+    } catch (e) {
+      list.innerHTML = `<div class="ann-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function formBody(form) {
+    const f = form.elements;
+    return {
+      title: f.title.value,
+      message: f.message.value,
+      kind: f.kind.value,
+      target: f.target.value,
+      servers: f.servers.value.split(',').map(s => s.trim()).filter(Boolean)
+    };
+  }
+
+  function fillForm(container, a, keepId) {
+    const form = container.querySelector('#ann-form');
+    const f = form.elements;
+    f.title.value = a.title || '';
+    f.message.value = a.message || '';
+    f.kind.value = a.kind || 'notice';
+    f.target.value = a.target || 'all';
+    f.servers.value = (a.servers || []).join(', ');
+    f.servers.disabled = f.target.value !== 'servers';
+    editing = keepId ? a.id : null;
+    setPreviewed(container, false);
+    container.querySelector('#ann-preview').innerHTML = '';
+  }
+
+  function setPreviewed(container, ok) {
+    container.querySelector('#ann-send').disabled = !ok;
+    container.querySelector('#ann-schedule').disabled = !ok;
+  }
+
+  // save stores the form as a new draft or over the one being edited
+  async function save(container) {
+    const body = formBody(container.querySelector('#ann-form'));
+    const a = editing
+      ? await api('PUT', `/announcements/${encodeURIComponent(editing)}`, body)
+      : await api('POST', '/announcements', body);
+    editing = a.id;
+    return a;
+  }
+
+  async function preview(container) {
+    const out = container.querySelector('#ann-preview');
+    out.innerHTML = '<div class="ann-empty">Loading...</div>';
+    try {
+      const a = await save(container);
+      const pv = await api('POST', `/announcements/${encodeURIComponent(a.id)}/preview`);
+      const servers = Object.entries(pv.by_server).map(([s, n]) => `${escapeHtml(s)}: ${n}`).join(', ');
+      out.innerHTML = `
+        <strong>${pv.recipients} recipients</strong>${servers ? ` <span class="ann-muted">(${servers})</span>` : ''}
+        <pre>${pv.lines.map(escapeHtml).join('\n')}</pre>
+        ${pv.sample.length ? `<small class="ann-muted">${pv.sample.map(escapeHtml).join(', ')}${pv.recipients > pv.sample.length ? ', ...' : ''}</small>` : ''}
+      `;
+      setPreviewed(container, true);
+    } catch (e) {
+      out.innerHTML = `<div class="ann-error">${escapeHtml(e.message)}</div>`;
+    }
+    loadList(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="ann-app" data-plugin="${PLUGIN_ID}">
+        <form class="ann-form" id="ann-form">
+          <label>Title<input name="title" placeholder="Maintenance tonight"></label>
+          <label>Kind
+            <select name="kind">
+              <option value="notice">Notice</option>
+              <option value="wallops">Wallops</option>
+            </select>
+          </label>
+          <label>Send to
+            <select name="target">
+              <option value="all">All users</option>
+              <option value="opers">Opers only</option>
+              <option value="servers">Specific servers</option>
+            </select>
+          </label>
+          <label>Servers<input name="servers" disabled placeholder="irc1.example.net, irc2.example.net"></label>
+          <label class="wide">Message<textarea name="message" required placeholder="Each paragraph is sent as its own line"></textarea></label>
+          <div class="wide ann-actions">
+            <button class="ann-btn" type="button" id="ann-new">New</button>
+            <button class="ann-btn" type="button" id="ann-draft">Save draft</button>
+            <button class="ann-btn" type="button" id="ann-preview-btn">Preview</button>
+            <button class="ann-btn primary" type="button" id="ann-send" disabled>Send now</button>
+            <input type="datetime-local" id="ann-at">
+            <button class="ann-btn" type="button" id="ann-schedule" disabled>Schedule</button>
+          </div>
+        </form>
+        <div class="ann-preview" id="ann-preview"></div>
+        <div class="ann-filter">
+          <select id="ann-status">
+            <option value="">All</option>
+            <option value="draft">Drafts</option>
+            <option value="scheduled">Scheduled</option>
+            <option value="sent">Sent</option>
+            <option value="failed">Failed</option>
+            <option value="missed">Missed</option>
+            <option value="cancelled">Cancelled</option>
+          </select>
+          <input id="ann-search" placeholder="Search">
+        </div>
+        <div id="ann-list"><div class="ann-empty">Loading...</div></div>
+      </div>
+    `;
+
+    const form = container.querySelector('#ann-form');
+
+    // Any change needs a new preview before sending
+    form.addEventListener('input', () => setPreviewed(container, false));
+    form.elements.target.addEventListener('change', () => {
+      form.elements.servers.disabled = form.elements.target.value !== 'servers';
+    });
+
+    container.querySelector('#ann-new').addEventListener('click', () => fillForm(container, {}, false));
+    container.querySelector('#ann-preview-btn').addEventListener('click', () => preview(container));
+
+    container.querySelector('#ann-draft').addEventListener('click', async () => {
+      try {
+        await save(container);
+        setPreviewed(container, false);
+      } catch (e) {
+        alert(e.message);
+      }
+      loadList(container);
+    });
+
+    container.querySelector('#ann-send').addEventListener('click', async () => {
+      if (!confirm('Send this announcement now?')) return;
+      try {
+        await api('POST', `/announcements/${encodeURIComponent(editing)}/send`);
+        fillForm(container, {}, false);
+      } catch (e) {
+        alert(e.message);
+      }
+      setTimeout(() => loadList(container), 1000);
+    });
+
+    container.querySelector('#ann-schedule').addEventListener('click', async () => {
+      const at = container.querySelector('#ann-at').value;
+      if (!at) {
+        alert('Pick a time to send it');
+        return;
+      }
+      try {
+        await api('POST', `/announcements/${encodeURIComponent(editing)}/schedule`, { send_at: new Date(at).toISOString() });
+        fillForm(container, {}, false);
+      } catch (e) {
+        alert(e.message);
+      }
+      loadList(container);
+    });
+
+    container.querySelector('#ann-status').addEventListener('change', () => loadList(container));
+    container.querySelector('#ann-search').addEventListener('input', () => loadList(container));
+
+    container.querySelector('#ann-list').addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+      const path = `/announcements/${encodeURIComponent(btn.dataset.id)}`;
+
+      try {
+        switch (btn.dataset.action) {
+          case 'edit':
+            fillForm(container, await api('GET', path), true);
+            return;
+          case 'copy':
+            fillForm(container, await api('GET', path), false);
+            return;
+          case 'cancel':
+            await api('POST', path + '/cancel');
+            break;
+          case 'delete':
+            if (!confirm('Delete this announcement?')) return;
+            await api('DELETE', path);
+            if (editing === btn.dataset.id) fillForm(container, {}, false);
+            break;
+        }
+      } catch (err) {
+        alert(err.message);
+      }
+      loadList(container);
+    });
+
+    loadList(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('announcements-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
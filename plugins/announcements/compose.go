@@ -0,0 +1,117 @@
+package announcements
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Announcement kinds
+const (
+	KindNotice  = "notice"
+	KindWallops = "wallops"
+)
+
+// Announcement targets
+const (
+	TargetAll     = "all"
+	TargetOpers   = "opers"
+	TargetServers = "servers"
+)
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	Name string `json:"name"`
+	User struct {
+		Servername string `json:"servername"`
+		Modes      string `json:"modes"`
+		Operlogin  string `json:"operlogin"`
+	} `json:"user"`
+}
+
+// validate checks the kind, target and servers of an announcement
+func (a *Announcement) validate() error {
+	if strings.TrimSpace(a.Message) == "" {
+		return fmt.Errorf("message is required")
+	}
+	if a.Kind != KindNotice && a.Kind != KindWallops {
+		return fmt.Errorf("kind must be %s or %s", KindNotice, KindWallops)
+	}
+	switch a.Target {
+	case TargetAll, TargetOpers:
+		a.Servers = nil
+	case TargetServers:
+		if len(a.Servers) == 0 {
+			return fmt.Errorf("servers is required for target %s", TargetServers)
+		}
+	default:
+		return fmt.Errorf("target must be %s, %s or %s", TargetAll, TargetOpers, TargetServers)
+	}
+	return nil
+}
+
+// wants reports whether a user should receive the announcement. Services
+// (user mode +S) never do, and wallops only go to users with +w.
+func (a *Announcement) wants(u rpcUser) bool {
+	if strings.ContainsRune(u.User.Modes, 'S') {
+		return false
+	}
+	if a.Kind == KindWallops && !strings.ContainsRune(u.User.Modes, 'w') {
+		return false
+	}
+	switch a.Target {
+	case TargetOpers:
+		return u.User.Operlogin != ""
+	case TargetServers:
+		return lists.ContainsFold(a.Servers, u.User.Servername)
+	}
+	return true
+}
+
+// lines splits a message into the lines sent to users: one or more per
+// paragraph, each no longer than max bytes including the prefix
+func lines(msg, prefix string, max int) []string {
+	if prefix != "" {
+		prefix += " "
+	}
+	room := max - len(prefix)
+	if room < 50 {
+		room = 50
+	}
+
+	out := make([]string, 0)
+	for _, para := range strings.Split(strings.ReplaceAll(msg, "\r\n", "\n"), "\n") {
+		words := strings.Fields(para)
+		cur := ""
+		for _, w := range words {
+			// Words longer than a whole line are cut
+			for len(w) > room {
+				if cur != "" {
+					out = append(out, prefix+cur)
+					cur = ""
+				}
+				cut := room
+				for cut > 0 && !utf8.RuneStart(w[cut]) {
+					cut--
+				}
+				out = append(out, prefix+w[:cut])
+				w = w[cut:]
+			}
+			switch {
+			case cur == "":
+				cur = w
+			case len(cur)+1+len(w) <= room:
+				cur += " " + w
+			default:
+				out = append(out, prefix+cur)
+				cur = w
+			}
+		}
+		if cur != "" {
+			out = append(out, prefix+cur)
+		}
+	}
+	return out
+}
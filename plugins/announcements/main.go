@@ -0,0 +1,896 @@
+// Announcements Plugin for UnrealIRCd Web Panel
+// Composes network-wide notices and wallops, previews who will get them,
+// delivers them over JSON-RPC now or at a set time and keeps an archive
+
+package announcements
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/apidocs"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Announcement states
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusSending   = "sending"
+	StatusSent      = "sent"
+	StatusFailed    = "failed"
+	StatusMissed    = "missed"
+	StatusCancelled = "cancelled"
+)
+
+const (
+	// maxLate is how late a scheduled announcement may go out, e.g. after
+	// the panel was down. Later ones are marked missed rather than sent.
+	maxLate = 15 * time.Minute
+	// previewSample is the number of recipient nicks shown in a preview
+	previewSample = 50
+)
+
+// AnnouncementsPlugin implements the Plugin interface
+type AnnouncementsPlugin struct {
+	config        Config
+	rpc           *jsonrpc.Client
+	announcements []*Announcement
+	dirty         bool
+	mu            sync.RWMutex
+	stop          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL            string `json:"rpc_url"`
+	RPCUser           string `json:"rpc_user"`
+	RPCPassword       string `json:"rpc_password"`
+	RPCInsecure       bool   `json:"rpc_insecure"`
+	DataDir           string `json:"data_dir"`
+	NoticePrefix      string `json:"notice_prefix"`
+	WallopsPrefix     string `json:"wallops_prefix"`
+	MaxLineLength     int    `json:"max_line_length"`
+	KeepAnnouncements int    `json:"keep_announcements"`
+}
+
+// Announcement is a notice or wallops, from draft to archive
+type Announcement struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	Message     string         `json:"message"`
+	Kind        string         `json:"kind"`
+	Target      string         `json:"target"`
+	Servers     []string       `json:"servers,omitempty"`
+	Status      string         `json:"status"`
+	SendAt      time.Time      `json:"send_at"`
+	CreatedBy   string         `json:"created_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	PreviewedAt time.Time      `json:"previewed_at"`
+	PreviewedBy string         `json:"previewed_by,omitempty"`
+	SentBy      string         `json:"sent_by,omitempty"`
+	SentAt      time.Time      `json:"sent_at"`
+	Lines       []string       `json:"lines,omitempty"`
+	Recipients  int            `json:"recipients"`
+	Failed      int            `json:"failed"`
+	ByServer    map[string]int `json:"by_server,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// AnnouncementRequest is the body of create and update requests
+type AnnouncementRequest struct {
+	Title   string   `json:"title"`
+	Message string   `json:"message"`
+	Kind    string   `json:"kind"`
+	Target  string   `json:"target"`
+	Servers []string `json:"servers"`
+}
+
+// ScheduleRequest is the body of schedule requests
+type ScheduleRequest struct {
+	SendAt string `json:"send_at"`
+}
+
+// Preview shows who an announcement would reach and what they would see
+type Preview struct {
+	Lines      []string       `json:"lines"`
+	Recipients int            `json:"recipients"`
+	ByServer   map[string]int `json:"by_server"`
+	Sample     []string       `json:"sample"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Announcements []*Announcement `json:"announcements"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &AnnouncementsPlugin{
+		config: Config{
+			RPCURL:            "https://127.0.0.1:8600/api",
+			DataDir:           "data/plugins/announcements",
+			NoticePrefix:      "[Network Notice]",
+			WallopsPrefix:     "[Wallops]",
+			MaxLineLength:     400,
+			KeepAnnouncements: 1000,
+		},
+		announcements: make([]*Announcement, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *AnnouncementsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Announcements",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Compose, schedule and archive network-wide notices and wallops",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *AnnouncementsPlugin) Init() error {
+	apidocs.Describe("announcements", "Drafted, previewed and scheduled network notices and wallops",
+		apidocs.Operation{
+			Method: "GET", Path: "/announcements", Summary: "List announcements, newest first",
+			Query: []apidocs.Param{
+				{Name: "status", Description: "draft, scheduled, sending, sent, failed, missed or cancelled"},
+				{Name: "q", Description: "Text in the title or message"},
+			},
+			Response: struct {
+				Announcements []Announcement `json:"announcements"`
+			}{},
+		},
+		apidocs.Operation{Method: "POST", Path: "/announcements", Summary: "Save a new draft", Request: AnnouncementRequest{}, Response: Announcement{}, Status: http.StatusCreated},
+		apidocs.Operation{Method: "GET", Path: "/announcements/:id", Summary: "One announcement", Response: Announcement{}},
+		apidocs.Operation{Method: "PUT", Path: "/announcements/:id", Summary: "Change a draft or scheduled announcement", Request: AnnouncementRequest{}, Response: Announcement{}},
+		apidocs.Operation{Method: "DELETE", Path: "/announcements/:id", Summary: "Delete an announcement"},
+		apidocs.Operation{Method: "POST", Path: "/announcements/:id/preview", Summary: "Who the announcement would reach and what they would see", Response: Preview{}},
+		apidocs.Operation{
+			Method: "POST", Path: "/announcements/:id/send", Summary: "Send a previewed announcement now",
+			Description: "Sending carries on in the background; poll the announcement for its outcome.",
+			Response:    Announcement{}, Status: http.StatusAccepted,
+		},
+		apidocs.Operation{Method: "POST", Path: "/announcements/:id/schedule", Summary: "Send a previewed announcement later", Request: ScheduleRequest{}, Response: Announcement{}},
+		apidocs.Operation{Method: "POST", Path: "/announcements/:id/cancel", Summary: "Cancel a scheduled announcement"},
+		apidocs.Operation{Method: "GET", Path: "/config", Summary: "Get current configuration", Response: Config{}},
+		apidocs.Operation{Method: "PUT", Path: "/config", Summary: "Update configuration", Request: Config{}},
+	)
+
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[announcements] failed to load data: %v", err)
+	}
+	if data.Announcements != nil {
+		p.announcements = data.Announcements
+	}
+	// An announcement cut off by a restart isn't sent again, as some users
+	// already have it
+	for _, a := range p.announcements {
+		if a.Status == StatusSending {
+			a.Status = StatusFailed
+			a.Error = "the panel stopped while this was being sent"
+			p.dirty = true
+		}
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "announcements-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{"scheduled": 0, "drafts": 0}
+		var next, last *Announcement
+		for _, a := range p.announcements {
+			switch a.Status {
+			case StatusScheduled:
+				content["scheduled"] = content["scheduled"].(int) + 1
+				if next == nil || a.SendAt.Before(next.SendAt) {
+					next = a
+				}
+			case StatusDraft:
+				content["drafts"] = content["drafts"].(int) + 1
+			case StatusSent:
+				if last == nil || a.SentAt.After(last.SentAt) {
+					last = a
+				}
+			}
+		}
+		if next != nil {
+			content["next"] = next.label()
+			content["next_at"] = next.SendAt
+		}
+		if last != nil {
+			content["last"] = last.label()
+			content["last_at"] = last.SentAt
+		}
+		return plugins.DashboardCard{
+			Title:   "Announcements",
+			Icon:    "Megaphone",
+			Content: content,
+			Order:   80,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.scheduleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *AnnouncementsPlugin) Shutdown() error {
+	apidocs.Forget("announcements")
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *AnnouncementsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/announcements")
+	{
+		plugin.GET("/announcements", p.handleList)
+		plugin.POST("/announcements", p.handleCreate)
+		plugin.GET("/announcements/:id", p.handleGet)
+		plugin.PUT("/announcements/:id", p.handleUpdate)
+		plugin.DELETE("/announcements/:id", p.handleDelete)
+		plugin.POST("/announcements/:id/preview", p.handlePreview)
+		plugin.POST("/announcements/:id/send", p.handleSend)
+		plugin.POST("/announcements/:id/schedule", p.handleSchedule)
+		plugin.POST("/announcements/:id/cancel", p.handleCancel)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *AnnouncementsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "announcements.json")
+}
+
+// save persists the state if it changed
+func (p *AnnouncementsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Announcements: p.announcements}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *AnnouncementsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// announcement finds an announcement by ID. Caller must hold p.mu.
+func (p *AnnouncementsPlugin) announcement(id string) *Announcement {
+	for _, a := range p.announcements {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// prefix returns the prefix for an announcement's kind. Caller must hold
+// p.mu.
+func (p *AnnouncementsPlugin) prefix(kind string) string {
+	if kind == KindWallops {
+		return p.config.WallopsPrefix
+	}
+	return p.config.NoticePrefix
+}
+
+// prune drops the oldest archived announcements beyond
+// keep_announcements. Drafts and scheduled ones are kept. Caller must hold
+// p.mu.
+func (p *AnnouncementsPlugin) prune() {
+	keep := p.config.KeepAnnouncements
+	if keep < 1 {
+		keep = 1000
+	}
+	archived := 0
+	for _, a := range p.announcements {
+		if a.archived() {
+			archived++
+		}
+	}
+	if archived <= keep {
+		return
+	}
+	kept := make([]*Announcement, 0, len(p.announcements))
+	for _, a := range p.announcements {
+		if a.archived() && archived > keep {
+			archived--
+			continue
+		}
+		kept = append(kept, a)
+	}
+	p.announcements = kept
+	p.dirty = true
+}
+
+// archived reports whether an announcement is finished with
+func (a *Announcement) archived() bool {
+	switch a.Status {
+	case StatusSent, StatusFailed, StatusMissed, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// label returns the title, or the start of the message if there is none
+func (a *Announcement) label() string {
+	if a.Title != "" {
+		return a.Title
+	}
+	if r := []rune(a.Message); len(r) > 60 {
+		return string(r[:57]) + "..."
+	}
+	return a.Message
+}
+
+// previewed reports whether the announcement has been previewed since it
+// was last changed
+func (a *Announcement) previewed() bool {
+	return !a.PreviewedAt.IsZero() && !a.PreviewedAt.Before(a.UpdatedAt)
+}
+
+// recipients fetches the users an announcement goes to
+func (p *AnnouncementsPlugin) recipients(ctx context.Context, a *Announcement) ([]rpcUser, error) {
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	if err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result); err != nil {
+		return nil, err
+	}
+	users := make([]rpcUser, 0, len(result.List))
+	for _, u := range result.List {
+		if a.wants(u) {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// scheduleLoop sends scheduled announcements when they are due
+func (p *AnnouncementsPlugin) scheduleLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		p.sendDue(time.Now().UTC())
+		if err := p.save(); err != nil {
+			log.Printf("[announcements] failed to save data: %v", err)
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue sends the scheduled announcements whose time has come, and marks
+// those too late to send as missed
+func (p *AnnouncementsPlugin) sendDue(now time.Time) {
+	p.mu.Lock()
+	due := make([]*Announcement, 0)
+	for _, a := range p.announcements {
+		if a.Status != StatusScheduled || now.Before(a.SendAt) {
+			continue
+		}
+		if now.Sub(a.SendAt) > maxLate {
+			a.Status = StatusMissed
+			a.Error = fmt.Sprintf("not sent: the panel was not running at %s", a.SendAt.Format(time.RFC3339))
+			log.Printf("[announcements] %q missed its time", a.label())
+		} else {
+			a.Status = StatusSending
+			due = append(due, a)
+		}
+		p.dirty = true
+	}
+	p.prune()
+	p.mu.Unlock()
+
+	for _, a := range due {
+		p.deliver(a)
+	}
+}
+
+// deliver sends an announcement to its recipients and records the result.
+// The caller sets the status to sending.
+func (p *AnnouncementsPlugin) deliver(a *Announcement) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	p.mu.RLock()
+	msgLines := lines(a.Message, p.prefix(a.Kind), p.config.MaxLineLength)
+	target := *a
+	p.mu.RUnlock()
+
+	rpc := p.client()
+	sent, failed := 0, 0
+	byServer := make(map[string]int)
+	var lastErr error
+
+	users, err := p.recipients(ctx, &target)
+	if err != nil {
+		lastErr = err
+	}
+	for _, u := range users {
+		ok := true
+		for _, line := range msgLines {
+			var out json.RawMessage
+			if err := rpc.Call(ctx, "message.send_notice", map[string]interface{}{"nick": u.Name, "message": line}, &out); err != nil {
+				lastErr = err
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			failed++
+			continue
+		}
+		sent++
+		byServer[u.User.Servername]++
+	}
+
+	p.mu.Lock()
+	a.SentAt = time.Now().UTC()
+	a.Lines = msgLines
+	a.Recipients = sent
+	a.Failed = failed
+	a.ByServer = byServer
+	a.Status = StatusSent
+	a.Error = ""
+	if lastErr != nil {
+		a.Error = lastErr.Error()
+		if sent == 0 {
+			a.Status = StatusFailed
+		}
+	}
+	p.prune()
+	p.dirty = true
+	p.mu.Unlock()
+
+	if lastErr != nil {
+		log.Printf("[announcements] %q sent to %d users, %d failed: %v", target.label(), sent, failed, lastErr)
+	} else {
+		log.Printf("[announcements] %q sent to %d users", target.label(), sent)
+	}
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// apply validates a request and copies it onto the announcement
+func (req *AnnouncementRequest) apply(a *Announcement) error {
+	updated := *a
+	updated.Title = strings.TrimSpace(req.Title)
+	updated.Message = strings.TrimSpace(req.Message)
+	updated.Kind = strings.ToLower(strings.TrimSpace(req.Kind))
+	if updated.Kind == "" {
+		updated.Kind = KindNotice
+	}
+	updated.Target = strings.ToLower(strings.TrimSpace(req.Target))
+	if updated.Target == "" {
+		updated.Target = TargetAll
+	}
+	updated.Servers = make([]string, 0, len(req.Servers))
+	for _, s := range req.Servers {
+		if s = strings.TrimSpace(s); s != "" {
+			updated.Servers = append(updated.Servers, s)
+		}
+	}
+	if err := updated.validate(); err != nil {
+		return err
+	}
+	*a = updated
+	return nil
+}
+
+// handleList returns the announcements, newest first. ?status= and ?q=
+// filter by state and by text in the title or message.
+func (p *AnnouncementsPlugin) handleList(c *gin.Context) {
+	status := c.Query("status")
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+
+	p.mu.RLock()
+	list := make([]Announcement, 0)
+	for _, a := range p.announcements {
+		if status != "" && a.Status != status {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(a.Title+" "+a.Message), q) {
+			continue
+		}
+		list = append(list, *a)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{"announcements": list})
+}
+
+// handleCreate saves a new draft
+func (p *AnnouncementsPlugin) handleCreate(c *gin.Context) {
+	var req AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	now := time.Now().UTC()
+	a := &Announcement{
+		ID:        newID(),
+		Status:    StatusDraft,
+		CreatedBy: access.Actor(c),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := req.apply(a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	p.announcements = append(p.announcements, a)
+	p.dirty = true
+	v := *a
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[announcements] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusCreated, v)
+}
+
+// handleGet returns a single announcement
+func (p *AnnouncementsPlugin) handleGet(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	a := p.announcement(c.Param("id"))
+	if a == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// handleUpdate changes a draft or scheduled announcement. A scheduled one
+// goes back to draft, to be previewed and scheduled again.
+func (p *AnnouncementsPlugin) handleUpdate(c *gin.Context) {
+	var req AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	a := p.announcement(c.Param("id"))
+	if a == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	if a.Status != StatusDraft && a.Status != StatusScheduled {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Announcement is " + a.Status})
+		return
+	}
+	if err := req.apply(a); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	a.Status = StatusDraft
+	a.SendAt = time.Time{}
+	a.UpdatedAt = time.Now().UTC()
+	p.dirty = true
+	v := *a
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[announcements] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// handleDelete removes a draft or cancelled announcement. Sent ones stay
+// in the archive.
+func (p *AnnouncementsPlugin) handleDelete(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	for i, a := range p.announcements {
+		if a.ID != id {
+			continue
+		}
+		if a.Status != StatusDraft && a.Status != StatusCancelled {
+			p.mu.Unlock()
+			c.JSON(http.StatusConflict, gin.H{"error": "Only drafts and cancelled announcements can be deleted"})
+			return
+		}
+		p.announcements = append(p.announcements[:i], p.announcements[i+1:]...)
+		p.dirty = true
+		p.mu.Unlock()
+
+		if err := p.save(); err != nil {
+			log.Printf("[announcements] failed to save data: %v", err)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Announcement deleted"})
+		return
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+}
+
+// handlePreview shows the lines users will see and who will get them, and
+// records that the announcement was previewed
+func (p *AnnouncementsPlugin) handlePreview(c *gin.Context) {
+	p.mu.RLock()
+	a := p.announcement(c.Param("id"))
+	if a == nil {
+		p.mu.RUnlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	target := *a
+	msgLines := lines(a.Message, p.prefix(a.Kind), p.config.MaxLineLength)
+	p.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	users, err := p.recipients(ctx, &target)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	pv := Preview{Lines: msgLines, Recipients: len(users), ByServer: make(map[string]int), Sample: make([]string, 0)}
+	for _, u := range users {
+		pv.ByServer[u.User.Servername]++
+		if len(pv.Sample) < previewSample {
+			pv.Sample = append(pv.Sample, u.Name)
+		}
+	}
+
+	p.mu.Lock()
+	// Only count the preview if nothing changed while users were fetched
+	if a.UpdatedAt.Equal(target.UpdatedAt) {
+		a.PreviewedAt = time.Now().UTC()
+		a.PreviewedBy = access.Actor(c)
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, pv)
+}
+
+// handleSend sends a previewed announcement now
+func (p *AnnouncementsPlugin) handleSend(c *gin.Context) {
+	p.mu.Lock()
+	a := p.announcement(c.Param("id"))
+	if a == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	if a.Status != StatusDraft && a.Status != StatusScheduled {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Announcement is " + a.Status})
+		return
+	}
+	if !a.previewed() {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Preview the announcement before sending it"})
+		return
+	}
+	a.Status = StatusSending
+	a.SentBy = access.Actor(c)
+	p.dirty = true
+	v := *a
+	p.mu.Unlock()
+
+	log.Printf("[announcements] %s is sending %q", v.SentBy, v.label())
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.deliver(a)
+		if err := p.save(); err != nil {
+			log.Printf("[announcements] failed to save data: %v", err)
+		}
+	}()
+	c.JSON(http.StatusAccepted, v)
+}
+
+// handleSchedule sets a previewed announcement to go out at a given time
+func (p *AnnouncementsPlugin) handleSchedule(c *gin.Context) {
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	at, err := time.Parse(time.RFC3339, req.SendAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "send_at must be an RFC 3339 timestamp"})
+		return
+	}
+	if !at.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "send_at must be in the future"})
+		return
+	}
+
+	p.mu.Lock()
+	a := p.announcement(c.Param("id"))
+	if a == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	if a.Status != StatusDraft && a.Status != StatusScheduled {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Announcement is " + a.Status})
+		return
+	}
+	if !a.previewed() {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Preview the announcement before scheduling it"})
+		return
+	}
+	a.Status = StatusScheduled
+	a.SendAt = at.UTC()
+	a.SentBy = access.Actor(c)
+	p.dirty = true
+	v := *a
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[announcements] failed to save data: %v", err)
+	}
+	log.Printf("[announcements] %s scheduled %q for %s", v.SentBy, v.label(), v.SendAt.Format(time.RFC3339))
+	c.JSON(http.StatusOK, v)
+}
+
+// handleCancel cancels a scheduled announcement
+func (p *AnnouncementsPlugin) handleCancel(c *gin.Context) {
+	p.mu.Lock()
+	a := p.announcement(c.Param("id"))
+	if a == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	if a.Status != StatusScheduled {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Announcement is not scheduled"})
+		return
+	}
+	a.Status = StatusCancelled
+	p.prune()
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[announcements] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Announcement cancelled"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *AnnouncementsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *AnnouncementsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.MaxLineLength < 100 || newConfig.MaxLineLength > 450 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_line_length must be between 100 and 450"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.prune()
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *AnnouncementsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *AnnouncementsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,276 @@
+/**
+ * API Keys Frontend Script
+ *
+ * Issues, rotates and revokes scoped API keys and shows when each key was
+ * last used.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'api-keys';
+  const PLUGIN_NAME = 'API Keys';
+  const PAGE_PATH = '/plugins/api-keys';
+  const API_BASE = '/api/plugin/api-keys';
+
+  const SCOPES = {
+    stats: 'Read-only stats',
+    bans: 'Ban management',
+    admin: 'Full admin'
+  };
+
+  let canManage = false;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('api-keys-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'api-keys-styles';
+    style.textContent = `
+      .apikeys-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .apikeys-form { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .apikeys-app input, .apikeys-app select {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .apikeys-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .apikeys-app button.apikeys-primary { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); }
+      .apikeys-app button.apikeys-danger { background: var(--error, #f38ba8); color: var(--bg-primary, #11111b); }
+      .apikeys-table { width: 100%; border-collapse: collapse; }
+      .apikeys-table th, .apikeys-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+      }
+      .apikeys-table tr.apikeys-inactive td { opacity: 0.5; }
+      .apikeys-secret {
+        padding: 0.8rem;
+        border-radius: 6px;
+        background: var(--bg-secondary, #1e1e2e);
+        border: 1px solid var(--accent, #89b4fa);
+      }
+      .apikeys-secret code { font-size: 0.95rem; overflow-wrap: anywhere; user-select: all; }
+      .apikeys-meta { font-size: 0.75rem; color: var(--text-muted, #6c7086); }
+      .apikeys-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function showSecret(container, key, name) {
+    container.querySelector('#apikeys-secret').innerHTML = `
+      <div class="apikeys-secret">
+        <p>New key for <strong>${escapeHtml(name)}</strong>. Copy it now, it will not be shown again.</p>
+        <code>${escapeHtml(key)}</code>
+      </div>
+    `;
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#apikeys-status');
+    try {
+      const s = await api('GET', '/status');
+      canManage = s.can_manage;
+      container.querySelector('#apikeys-create').style.display = canManage ? '' : 'none';
+      let state;
+      if (!s.listen_addr) {
+        state = 'The key API is off. Set a listen address in the plugin settings to accept keys.';
+      } else if (s.gateway_error) {
+        state = `<span class="apikeys-error">The key API could not listen on ${escapeHtml(s.listen_addr)}: ${escapeHtml(s.gateway_error)}</span>`;
+      } else {
+        state = `The key API listens on <code>${s.tls ? 'https' : 'http'}://${escapeHtml(s.listening)}/v1</code>.`;
+      }
+      body.innerHTML = `
+        <div>${state}</div>
+        ${s.plugin_routes.length ? `<div class="apikeys-meta">Plugin routes: ${s.plugin_routes.map(r => `<code>${escapeHtml(r)}</code>`).join(', ')}</div>` : ''}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="apikeys-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadKeys(container) {
+    const body = container.querySelector('#apikeys-keys');
+    try {
+      const data = await api('GET', '/keys');
+      if (data.keys.length === 0) {
+        body.innerHTML = '<p>No keys issued yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="apikeys-table">
+          <thead><tr><th>Name</th><th>Key</th><th>Scope</th><th>Created</th><th>Expires</th><th>Last used</th><th>Uses</th><th></th></tr></thead>
+          <tbody>
+            ${data.keys.map(k => `
+              <tr class="${k.status === 'active' ? '' : 'apikeys-inactive'}">
+                <td>${escapeHtml(k.name)}</td>
+                <td><code>${escapeHtml(k.prefix)}…</code>${k.previous_valid_until ? `<div class="apikeys-meta">old key valid until ${formatTime(k.previous_valid_until)}</div>` : ''}</td>
+                <td>${escapeHtml(SCOPES[k.scope] || k.scope)}</td>
+                <td>${formatTime(k.created_at)}<div class="apikeys-meta">by ${escapeHtml(k.created_by)}</div></td>
+                <td>${k.status === 'active' ? (formatTime(k.expires_at) || 'never') : escapeHtml(k.status)}</td>
+                <td>${formatTime(k.last_used) || 'never'}${k.last_ip ? `<div class="apikeys-meta">${escapeHtml(k.last_ip)}</div>` : ''}</td>
+                <td>${k.uses}</td>
+                <td>${canManage && k.status !== 'revoked' ? `
+                  <button data-rotate="${escapeHtml(k.id)}" data-name="${escapeHtml(k.name)}">Rotate</button>
+                  <button class="apikeys-danger" data-revoke="${escapeHtml(k.id)}" data-name="${escapeHtml(k.name)}">Revoke</button>
+                ` : ''}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+
+      body.querySelectorAll('[data-rotate]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          if (!confirm(`Rotate the key ${btn.dataset.name}? Scripts using it must be updated.`)) return;
+          try {
+            const res = await api('POST', `/keys/${btn.dataset.rotate}/rotate`);
+            showSecret(container, res.key, res.info.name);
+            loadKeys(container);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+      });
+      body.querySelectorAll('[data-revoke]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          if (!confirm(`Revoke the key ${btn.dataset.name}? It stops working immediately.`)) return;
+          try {
+            await api('DELETE', `/keys/${btn.dataset.revoke}`);
+            loadKeys(container);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="apikeys-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="apikeys-app" data-plugin="${PLUGIN_ID}">
+        <div id="apikeys-status">Loading...</div>
+        <div class="apikeys-form" id="apikeys-create" style="display: none">
+          <input type="text" id="apikeys-name" placeholder="Name, e.g. stats-bot">
+          <select id="apikeys-scope">
+            ${Object.entries(SCOPES).map(([id, label]) => `<option value="${id}">${label}</option>`).join('')}
+          </select>
+          <input type="number" id="apikeys-expire" min="0" placeholder="Expires in days (optional)">
+          <button class="apikeys-primary" id="apikeys-add">Create key</button>
+        </div>
+        <div id="apikeys-secret"></div>
+        <div id="apikeys-keys">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#apikeys-add').addEventListener('click', async () => {
+      const name = container.querySelector('#apikeys-name').value.trim();
+      if (!name) return;
+      try {
+        const res = await api('POST', '/keys', {
+          name,
+          scope: container.querySelector('#apikeys-scope').value,
+          expire_days: parseInt(container.querySelector('#apikeys-expire').value, 10) || 0
+        });
+        container.querySelector('#apikeys-name').value = '';
+        showSecret(container, res.key, res.info.name);
+        loadKeys(container);
+      } catch (e) {
+        alert(e.message);
+      }
+    });
+
+    loadStatus(container).then(() => loadKeys(container));
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('api-keys-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
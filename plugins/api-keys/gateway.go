@@ -0,0 +1,257 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// route is a handler added to the gateway by another plugin
+type route struct {
+	scope   string
+	handler gin.HandlerFunc
+}
+
+// extraRoutes holds the routes added with Handle, by method and path
+var (
+	routesMu    sync.RWMutex
+	extraRoutes = make(map[string]route)
+)
+
+// Handle adds a route to the key-authenticated gateway, served as
+// /v1/plugin/<path>. Requests need a key with at least the given scope.
+// Plugins call it from Init; routes can be added before or after the
+// gateway starts.
+func Handle(method, path, scope string, handler gin.HandlerFunc) {
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	extraRoutes[strings.ToUpper(method)+" /"+strings.Trim(path, "/")] = route{scope: scope, handler: handler}
+}
+
+// gateway is the HTTP listener that accepts API keys
+type gateway struct {
+	server *http.Server
+	addr   string
+}
+
+// startGateway listens on the configured address, if any
+func (p *APIKeysPlugin) startGateway() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.gatewayErr = ""
+	if p.config.ListenAddr == "" {
+		return
+	}
+
+	if err := grants.Allow(grants.Listen); err != nil {
+		p.gatewayErr = err.Error()
+		return
+	}
+	ln, err := net.Listen("tcp", p.config.ListenAddr)
+	if err != nil {
+		p.gatewayErr = err.Error()
+		log.Printf("[api-keys] failed to listen on %s: %v", p.config.ListenAddr, err)
+		return
+	}
+
+	srv := &http.Server{
+		Handler:           p.gatewayHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+	}
+	gw := &gateway{server: srv, addr: ln.Addr().String()}
+	p.gateway = gw
+
+	cert, key := p.config.TLSCert, p.config.TLSKey
+	go func() {
+		var err error
+		if cert != "" && key != "" {
+			err = srv.ServeTLS(ln, cert, key)
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[api-keys] gateway stopped: %v", err)
+			p.mu.Lock()
+			if p.gateway == gw {
+				p.gateway = nil
+				p.gatewayErr = err.Error()
+			}
+			p.mu.Unlock()
+		}
+	}()
+	log.Printf("[api-keys] gateway listening on %s", gw.addr)
+}
+
+// stopGateway shuts the listener down, letting running requests finish
+func (p *APIKeysPlugin) stopGateway() {
+	p.mu.Lock()
+	gw := p.gateway
+	p.gateway = nil
+	p.mu.Unlock()
+	if gw == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := gw.server.Shutdown(ctx); err != nil {
+		log.Printf("[api-keys] failed to stop gateway: %v", err)
+	}
+}
+
+// gatewayHandler builds the routes served to key holders
+func (p *APIKeysPlugin) gatewayHandler() http.Handler {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	v1 := r.Group("/v1")
+	{
+		v1.GET("/whoami", Middleware(ScopeStats), p.handleWhoami)
+		v1.GET("/stats", Middleware(ScopeStats), p.rpcHandler("stats.get"))
+		v1.GET("/servers", Middleware(ScopeStats), p.rpcHandler("server.list"))
+		v1.GET("/bans", Middleware(ScopeBans), p.rpcHandler("server_ban.list"))
+		v1.POST("/bans", Middleware(ScopeBans), p.handleAddBan)
+		v1.DELETE("/bans", Middleware(ScopeBans), p.handleDelBan)
+		v1.POST("/rpc", Middleware(ScopeAdmin), p.handleRPC)
+		v1.Any("/plugin/*path", p.handlePluginRoute)
+	}
+	return r
+}
+
+// handleWhoami describes the key making the request
+func (p *APIKeysPlugin) handleWhoami(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"id":    c.GetString(ContextKeyID),
+		"name":  c.GetString(ContextKeyName),
+		"scope": c.GetString(ContextKeyScope),
+	})
+}
+
+// rpcHandler returns a handler that passes the result of a parameterless
+// RPC method through
+func (p *APIKeysPlugin) rpcHandler(method string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+		defer cancel()
+
+		var result json.RawMessage
+		if err := p.client().Call(ctx, method, nil, &result); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// handleAddBan places a server ban
+func (p *APIKeysPlugin) handleAddBan(c *gin.Context) {
+	var req BanRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if req.Type == "" {
+		req.Type = "gline"
+	}
+	if req.Duration == "" {
+		req.Duration = "1d"
+	}
+	if req.Reason == "" {
+		req.Reason = "Banned by " + c.GetString(ContextKeyName)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	params := map[string]interface{}{
+		"name":            req.Name,
+		"type":            req.Type,
+		"reason":          req.Reason,
+		"duration_string": req.Duration,
+		"set_by":          "apikey:" + c.GetString(ContextKeyName),
+	}
+	var result json.RawMessage
+	if err := p.client().Call(ctx, "server_ban.add", params, &result); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("[api-keys] key %s added %s on %s", c.GetString(ContextKeyName), req.Type, req.Name)
+	c.JSON(http.StatusCreated, result)
+}
+
+// handleDelBan removes a server ban
+func (p *APIKeysPlugin) handleDelBan(c *gin.Context) {
+	var req BanRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" || req.Type == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and type are required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	var result json.RawMessage
+	if err := p.client().Call(ctx, "server_ban.del", map[string]string{"name": req.Name, "type": req.Type}, &result); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("[api-keys] key %s removed %s on %s", c.GetString(ContextKeyName), req.Type, req.Name)
+	c.JSON(http.StatusOK, result)
+}
+
+// handleRPC passes any JSON-RPC call through for admin keys
+func (p *APIKeysPlugin) handleRPC(c *gin.Context) {
+	var req struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Method == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "method is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	var params interface{}
+	if len(req.Params) > 0 {
+		params = req.Params
+	}
+	var result json.RawMessage
+	if err := p.client().Call(ctx, req.Method, params, &result); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("[api-keys] key %s called %s", c.GetString(ContextKeyName), req.Method)
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// handlePluginRoute dispatches to a route added with Handle
+func (p *APIKeysPlugin) handlePluginRoute(c *gin.Context) {
+	routesMu.RLock()
+	rt, ok := extraRoutes[c.Request.Method+" /"+strings.Trim(c.Param("path"), "/")]
+	routesMu.RUnlock()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+
+	Middleware(rt.scope)(c)
+	if c.IsAborted() {
+		return
+	}
+	rt.handler(c)
+}
@@ -0,0 +1,152 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Key scopes, from least to most access. A key is allowed everything its
+// own scope and the scopes below it allow.
+const (
+	ScopeStats = "stats"
+	ScopeBans  = "bans"
+	ScopeAdmin = "admin"
+)
+
+// keyPrefix starts every key so leaked keys are easy to search for
+const keyPrefix = "uwp_"
+
+// scopeRank orders the scopes
+var scopeRank = map[string]int{
+	ScopeStats: 1,
+	ScopeBans:  2,
+	ScopeAdmin: 3,
+}
+
+// active is the loaded plugin instance that Middleware checks keys against
+var (
+	activeMu sync.RWMutex
+	active   *APIKeysPlugin
+)
+
+// Context keys set by Middleware on authenticated requests
+const (
+	ContextKeyID    = "api_key_id"
+	ContextKeyName  = "api_key_name"
+	ContextKeyScope = "api_key_scope"
+)
+
+// validScope reports whether s is a known scope
+func validScope(s string) bool {
+	_, ok := scopeRank[s]
+	return ok
+}
+
+// allows reports whether a key with scope has the access need requires
+func allows(scope, need string) bool {
+	return scopeRank[scope] >= scopeRank[need]
+}
+
+// newKey returns a new random key
+func newKey() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return keyPrefix + hex.EncodeToString(b)
+}
+
+// hashKey hashes a key for storage. Keys are random, so a plain hash is
+// enough.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashEqual compares two hashes in constant time
+func hashEqual(a, b string) bool {
+	return a != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// requestKey returns the key sent with a request, either as a bearer token
+// or in the X-API-Key header
+func requestKey(c *gin.Context) string {
+	if key := strings.TrimSpace(c.GetHeader("X-API-Key")); key != "" {
+		return key
+	}
+	auth := c.GetHeader("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
+
+// Middleware returns gin middleware that lets a request through only when
+// it carries a valid API key with at least the given scope. Other plugins
+// can put it in front of their own handlers. The key's name is set as the
+// request's username, and its id and scope under the Context* keys.
+func Middleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		activeMu.RLock()
+		p := active
+		activeMu.RUnlock()
+		if p == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "API keys are not available"})
+			return
+		}
+
+		key := requestKey(c)
+		if key == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+			return
+		}
+		k, ok := p.authenticate(key, c.ClientIP())
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+		if !allows(k.Scope, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key scope " + k.Scope + " does not allow this"})
+			return
+		}
+
+		c.Set("username", "apikey:"+k.Name)
+		c.Set(ContextKeyID, k.ID)
+		c.Set(ContextKeyName, k.Name)
+		c.Set(ContextKeyScope, k.Scope)
+		c.Next()
+	}
+}
+
+// authenticate finds the key matching a presented key and records its use.
+// It returns a copy so callers need no lock.
+func (p *APIKeysPlugin) authenticate(key, ip string) (APIKey, bool) {
+	hash := hashKey(key)
+	now := time.Now().UTC()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, k := range p.keys {
+		if k.Revoked || (k.ExpiresAt != nil && now.After(*k.ExpiresAt)) {
+			continue
+		}
+		current := hashEqual(k.Hash, hash)
+		previous := k.PrevUntil != nil && now.Before(*k.PrevUntil) && hashEqual(k.PrevHash, hash)
+		if !current && !previous {
+			continue
+		}
+		k.LastUsed = &now
+		k.LastIP = ip
+		k.Uses++
+		p.dirty = true
+		return *k, true
+	}
+	return APIKey{}, false
+}
@@ -0,0 +1,551 @@
+// API Keys Plugin for UnrealIRCd Web Panel
+// Issues scoped API keys for external scripts and serves a key-authenticated
+// API that other plugins can add their own routes to
+
+package apikeys
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// APIKeysPlugin implements the Plugin interface
+type APIKeysPlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	keys       []*APIKey
+	gateway    *gateway
+	gatewayErr string
+	dirty      bool
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	DataDir       string `json:"data_dir"`
+	ListenAddr    string `json:"listen_addr"`
+	TLSCert       string `json:"tls_cert"`
+	TLSKey        string `json:"tls_key"`
+	RotationGrace int    `json:"rotation_grace"`
+}
+
+// APIKey is an issued key. Only the hash of the key itself is stored.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"hash"`
+	PrevHash  string     `json:"prev_hash,omitempty"`
+	PrevUntil *time.Time `json:"prev_until,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+	LastIP    string     `json:"last_ip,omitempty"`
+	Uses      int64      `json:"uses"`
+	Revoked   bool       `json:"revoked"`
+	RevokedBy string     `json:"revoked_by,omitempty"`
+}
+
+// KeyView is a key as shown in the panel, without hashes
+type KeyView struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope"`
+	Prefix    string     `json:"prefix"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	PrevUntil *time.Time `json:"previous_valid_until,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+	LastIP    string     `json:"last_ip,omitempty"`
+	Uses      int64      `json:"uses"`
+	Status    string     `json:"status"`
+	RevokedBy string     `json:"revoked_by,omitempty"`
+}
+
+// CreateRequest describes a new key
+type CreateRequest struct {
+	Name       string `json:"name"`
+	Scope      string `json:"scope"`
+	ExpireDays int    `json:"expire_days"`
+}
+
+// BanRequest is a server ban added or removed through the gateway
+type BanRequest struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Keys []*APIKey `json:"keys"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &APIKeysPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/api-keys",
+			RotationGrace: 60,
+		},
+		keys: make([]*APIKey, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *APIKeysPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "API Keys",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Scoped API keys for external scripts",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *APIKeysPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[api-keys] failed to load data: %v", err)
+	}
+	if data.Keys != nil {
+		p.keys = data.Keys
+	}
+	p.mu.Unlock()
+
+	activeMu.Lock()
+	active = p
+	activeMu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "api-keys-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		now := time.Now()
+		live, used := 0, 0
+		for _, k := range p.keys {
+			if k.Revoked || (k.ExpiresAt != nil && now.After(*k.ExpiresAt)) {
+				continue
+			}
+			live++
+			if k.LastUsed != nil && now.Sub(*k.LastUsed) < 24*time.Hour {
+				used++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "API Keys",
+			Icon:  "key",
+			Content: map[string]interface{}{
+				"active":       live,
+				"used_24h":     used,
+				"gateway_up":   p.gateway != nil,
+				"gateway_addr": p.config.ListenAddr,
+			},
+			Order: 56,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.startGateway()
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.saveLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *APIKeysPlugin) Shutdown() error {
+	activeMu.Lock()
+	if active == p {
+		active = nil
+	}
+	activeMu.Unlock()
+
+	p.stopGateway()
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *APIKeysPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/api-keys")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/keys", p.handleListKeys)
+		plugin.POST("/keys", p.handleCreateKey)
+		plugin.POST("/keys/:id/rotate", p.handleRotateKey)
+		plugin.DELETE("/keys/:id", p.handleRevokeKey)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *APIKeysPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "keys.json")
+}
+
+// save persists the state if it changed
+func (p *APIKeysPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Keys: p.keys}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// saveLoop periodically saves last-used times until shutdown
+func (p *APIKeysPlugin) saveLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if err := p.save(); err != nil {
+				log.Printf("[api-keys] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// client returns the RPC client, creating it on first use
+func (p *APIKeysPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// findKey returns the key with the given id. Caller must hold p.mu.
+func (p *APIKeysPlugin) findKey(id string) *APIKey {
+	for _, k := range p.keys {
+		if k.ID == id {
+			return k
+		}
+	}
+	return nil
+}
+
+// view returns how a key is shown in the panel
+func (k *APIKey) view(now time.Time) KeyView {
+	status := "active"
+	switch {
+	case k.Revoked:
+		status = "revoked"
+	case k.ExpiresAt != nil && now.After(*k.ExpiresAt):
+		status = "expired"
+	}
+	v := KeyView{
+		ID:        k.ID,
+		Name:      k.Name,
+		Scope:     k.Scope,
+		Prefix:    k.Prefix,
+		CreatedBy: k.CreatedBy,
+		CreatedAt: k.CreatedAt,
+		RotatedAt: k.RotatedAt,
+		ExpiresAt: k.ExpiresAt,
+		LastUsed:  k.LastUsed,
+		LastIP:    k.LastIP,
+		Uses:      k.Uses,
+		Status:    status,
+		RevokedBy: k.RevokedBy,
+	}
+	if k.PrevUntil != nil && now.Before(*k.PrevUntil) {
+		v.PrevUntil = k.PrevUntil
+	}
+	return v
+}
+
+// handleStatus reports whether the gateway is listening
+func (p *APIKeysPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	addr := ""
+	if p.gateway != nil {
+		addr = p.gateway.addr
+	}
+	routesMu.RLock()
+	routes := make([]string, 0, len(extraRoutes))
+	for r := range extraRoutes {
+		routes = append(routes, r)
+	}
+	routesMu.RUnlock()
+	sort.Strings(routes)
+
+	c.JSON(http.StatusOK, gin.H{
+		"listen_addr":   p.config.ListenAddr,
+		"listening":     addr,
+		"tls":           p.config.TLSCert != "" && p.config.TLSKey != "",
+		"gateway_error": p.gatewayErr,
+		"plugin_routes": routes,
+		"can_manage":    access.IsAdmin(c.GetString("username")),
+	})
+}
+
+// handleListKeys returns every key, newest first
+func (p *APIKeysPlugin) handleListKeys(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	list := make([]KeyView, 0, len(p.keys))
+	for i := len(p.keys) - 1; i >= 0; i-- {
+		list = append(list, p.keys[i].view(now))
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": list})
+}
+
+// handleCreateKey issues a new key. The key is returned only in this
+// response.
+func (p *APIKeysPlugin) handleCreateKey(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	if !validScope(req.Scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be stats, bans or admin"})
+		return
+	}
+
+	secret := newKey()
+	k := &APIKey{
+		ID:        newID(),
+		Name:      req.Name,
+		Scope:     req.Scope,
+		Prefix:    secret[:len(keyPrefix)+8],
+		Hash:      hashKey(secret),
+		CreatedBy: access.Actor(c),
+		CreatedAt: time.Now().UTC(),
+	}
+	if req.ExpireDays > 0 {
+		expires := k.CreatedAt.AddDate(0, 0, req.ExpireDays)
+		k.ExpiresAt = &expires
+	}
+
+	p.mu.Lock()
+	p.keys = append(p.keys, k)
+	p.dirty = true
+	view := k.view(time.Now())
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[api-keys] failed to save data: %v", err)
+	}
+	log.Printf("[api-keys] %s created %s key %s", k.CreatedBy, k.Scope, k.Name)
+	c.JSON(http.StatusCreated, gin.H{"key": secret, "info": view})
+}
+
+// handleRotateKey gives a key a new secret. The old one keeps working for
+// the configured grace period so scripts can be updated.
+func (p *APIKeysPlugin) handleRotateKey(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+
+	secret := newKey()
+	now := time.Now().UTC()
+
+	p.mu.Lock()
+	k := p.findKey(c.Param("id"))
+	if k == nil || k.Revoked {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	k.PrevHash, k.PrevUntil = "", nil
+	if grace := p.config.RotationGrace; grace > 0 {
+		until := now.Add(time.Duration(grace) * time.Minute)
+		k.PrevHash = k.Hash
+		k.PrevUntil = &until
+	}
+	k.Hash = hashKey(secret)
+	k.Prefix = secret[:len(keyPrefix)+8]
+	k.RotatedAt = &now
+	p.dirty = true
+	view := k.view(now)
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[api-keys] failed to save data: %v", err)
+	}
+	log.Printf("[api-keys] %s rotated key %s", access.Actor(c), view.Name)
+	c.JSON(http.StatusOK, gin.H{"key": secret, "info": view})
+}
+
+// handleRevokeKey disables a key at once, including any previous secret
+// still in its grace period
+func (p *APIKeysPlugin) handleRevokeKey(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+
+	p.mu.Lock()
+	k := p.findKey(c.Param("id"))
+	if k == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	k.Revoked = true
+	k.RevokedBy = access.Actor(c)
+	k.PrevHash, k.PrevUntil = "", nil
+	p.dirty = true
+	name := k.Name
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[api-keys] failed to save data: %v", err)
+	}
+	log.Printf("[api-keys] %s revoked key %s", access.Actor(c), name)
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Key %s revoked", name)})
+}
+
+// handleGetConfig returns the current configuration
+func (p *APIKeysPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration and restarts the gateway
+// when its listener settings changed. Only key admins may change it.
+func (p *APIKeysPlugin) handleUpdateConfig(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if (newConfig.TLSCert == "") != (newConfig.TLSKey == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set both tls_cert and tls_key, or neither"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	restart := newConfig.ListenAddr != p.config.ListenAddr ||
+		newConfig.TLSCert != p.config.TLSCert ||
+		newConfig.TLSKey != p.config.TLSKey
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	if restart {
+		p.stopGateway()
+		p.startGateway()
+	}
+
+	p.mu.RLock()
+	gatewayErr := p.gatewayErr
+	p.mu.RUnlock()
+	if gatewayErr != "" {
+		c.JSON(http.StatusOK, gin.H{"message": "Configuration updated, but the gateway could not start: " + gatewayErr})
+		return
+	}
+	log.Printf("[api-keys] %s updated the configuration", access.Actor(c))
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *APIKeysPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *APIKeysPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
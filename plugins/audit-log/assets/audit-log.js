@@ -0,0 +1,305 @@
+/**
+ * Audit Log Frontend Script
+ *
+ * Renders the audit log viewer on the plugin page. Changes are recorded
+ * on the server, so this script only reads the log.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'audit-log';
+  const PLUGIN_NAME = 'Audit Log';
+  const PAGE_PATH = '/plugins/audit-log';
+  const API_BASE = '/api/plugin/audit-log';
+
+  let filters = {};
+  let offset = 0;
+  const PAGE_SIZE = 50;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('audit-log-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'audit-log-styles';
+    style.textContent = `
+      .aud-app { display: flex; flex-direction: column; gap: 1rem; }
+      .aud-toolbar { display: flex; gap: 0.5rem; flex-wrap: wrap; align-items: center; }
+      .aud-toolbar input, .aud-toolbar select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .aud-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .aud-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .aud-banner { padding: 0.5rem 0.75rem; border-radius: 6px; font-size: 0.85rem; }
+      .aud-banner.ok { background: rgba(166, 227, 161, 0.15); color: var(--success, #a6e3a1); }
+      .aud-banner.bad { background: rgba(243, 139, 168, 0.15); color: var(--error, #f38ba8); }
+      .aud-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .aud-table th, .aud-table td {
+        text-align: left;
+        padding: 0.45rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+        vertical-align: top;
+      }
+      .aud-table th { color: var(--text-primary, #cdd6f4); }
+      .aud-table tr.aud-row { cursor: pointer; }
+      .aud-table tr.aud-row:hover td { background: var(--bg-secondary, #181825); }
+      .aud-method { font-family: monospace; font-weight: 600; }
+      .aud-status-err { color: var(--error, #f38ba8); }
+      .aud-diff { display: grid; grid-template-columns: 1fr 1fr; gap: 0.5rem; }
+      .aud-diff pre {
+        margin: 0;
+        padding: 0.5rem;
+        background: var(--bg-secondary, #181825);
+        border-radius: 6px;
+        font-size: 0.75rem;
+        max-height: 300px;
+        overflow: auto;
+        white-space: pre-wrap;
+        word-break: break-all;
+      }
+      .aud-hash { font-family: monospace; font-size: 0.7rem; color: var(--text-muted, #6c7086); }
+      .aud-pager { display: flex; gap: 0.5rem; align-items: center; color: var(--text-muted, #6c7086); font-size: 0.85rem; }
+      .aud-error { color: var(--error, #f38ba8); }
+      .aud-empty { color: var(--text-muted, #6c7086); padding: 1rem 0; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function pretty(value) {
+    if (value === undefined || value === null) return '(none)';
+    return JSON.stringify(value, null, 2);
+  }
+
+  function renderRow(e) {
+    return `
+      <tr class="aud-row" data-seq="${e.seq}">
+        <td>${e.seq}</td>
+        <td>${escapeHtml(new Date(e.time).toLocaleString())}</td>
+        <td>${escapeHtml(e.actor)}<br><small>${escapeHtml(e.ip)}</small></td>
+        <td><span class="aud-method">${escapeHtml(e.method)}</span> ${escapeHtml(e.path)}</td>
+        <td class="${e.status >= 400 ? 'aud-status-err' : ''}">${e.status || ''}</td>
+      </tr>
+      <tr class="aud-detail" data-detail="${e.seq}" hidden>
+        <td colspan="5">
+          <div class="aud-diff">
+            ${e.source === 'server' ? `
+            <div><strong>Previous request body</strong><pre>${escapeHtml(pretty(e.previous_body !== undefined ? e.previous_body : e.before))}</pre></div>
+            <div><strong>Request body</strong><pre>${escapeHtml(pretty(e.after))}</pre></div>
+            ` : `
+            <div><strong>Before</strong><pre>${escapeHtml(pretty(e.before))}</pre></div>
+            <div><strong>After</strong><pre>${escapeHtml(pretty(e.after))}</pre></div>
+            `}
+          </div>
+          <div class="aud-hash">hash ${escapeHtml(e.hash)}<br>prev ${escapeHtml(e.prev_hash)}</div>
+        </td>
+      </tr>
+    `;
+  }
+
+  function queryString(extra) {
+    const params = new URLSearchParams();
+    Object.entries(Object.assign({}, filters, extra)).forEach(([k, v]) => {
+      if (v !== '' && v != null) params.set(k, v);
+    });
+    return params.toString();
+  }
+
+  async function loadEntries(container) {
+    const list = container.querySelector('#aud-list');
+    list.innerHTML = '<div class="aud-empty">Loading...</div>';
+
+    try {
+      const data = await api('GET', '/entries?' + queryString({ limit: PAGE_SIZE, offset }));
+      container.querySelector('#aud-warning').hidden = data.recording;
+      if (!data.entries.length) {
+        list.innerHTML = '<div class="aud-empty">No entries.</div>';
+        return;
+      }
+      list.innerHTML = `
+        <table class="aud-table">
+          <thead><tr><th>#</th><th>Time</th><th>Who</th><th>Action</th><th>Status</th></tr></thead>
+          <tbody>${data.entries.map(renderRow).join('')}</tbody>
+        </table>
+        <div class="aud-pager">
+          <button class="aud-btn" id="aud-prev" ${offset === 0 ? 'disabled' : ''}>Newer</button>
+          <span>${offset + 1}-${offset + data.entries.length} of ${data.total}</span>
+          <button class="aud-btn" id="aud-next" ${offset + PAGE_SIZE >= data.total ? 'disabled' : ''}>Older</button>
+        </div>
+      `;
+      list.querySelector('#aud-prev').addEventListener('click', () => {
+        offset = Math.max(0, offset - PAGE_SIZE);
+        loadEntries(container);
+      });
+      list.querySelector('#aud-next').addEventListener('click', () => {
+        offset += PAGE_SIZE;
+        loadEntries(container);
+      });
+    } catch (e) {
+      list.innerHTML = `<div class="aud-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function verify(container) {
+    const banner = container.querySelector('#aud-banner');
+    try {
+      const data = await api('GET', '/verify');
+      const r = data.result;
+      banner.hidden = false;
+      banner.className = 'aud-banner ' + (r.ok ? 'ok' : 'bad');
+      banner.textContent = r.ok
+        ? `Chain intact: ${r.entries} entries, head ${r.last_hash.slice(0, 16)}...`
+        : `Chain broken${r.bad_seq ? ` at entry ${r.bad_seq}` : ''}: ${r.error}`;
+    } catch (e) {
+      banner.hidden = false;
+      banner.className = 'aud-banner bad';
+      banner.textContent = e.message;
+    }
+  }
+
+  async function download(format) {
+    const res = await fetch(API_BASE + '/export?' + queryString({ format }), { headers: getAuthHeaders() });
+    if (!res.ok) throw new Error(`Request failed with status ${res.status}`);
+    const url = URL.createObjectURL(await res.blob());
+    const a = document.createElement('a');
+    a.href = url;
+    a.download = `audit-log.${format}`;
+    a.click();
+    URL.revokeObjectURL(url);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="aud-app" data-plugin="${PLUGIN_ID}">
+        <form class="aud-toolbar" id="aud-filters">
+          <input name="q" placeholder="Search">
+          <input name="actor" placeholder="Staff member">
+          <select name="method">
+            <option value="">Any method</option>
+            ${['POST', 'PUT', 'PATCH', 'DELETE'].map(m => `<option>${m}</option>`).join('')}
+          </select>
+          <input name="path" placeholder="Path contains">
+          <input name="since" type="date" title="Since">
+          <input name="until" type="date" title="Until">
+          <button class="aud-btn primary" type="submit">Filter</button>
+          <button class="aud-btn" type="button" id="aud-verify">Verify chain</button>
+          <button class="aud-btn" type="button" data-export="csv">Export CSV</button>
+          <button class="aud-btn" type="button" data-export="jsonl">Export JSONL</button>
+        </form>
+        <div class="aud-error" id="aud-warning" hidden>The panel does not run the plugin request hook, so changes are not being recorded.</div>
+        <div class="aud-banner" id="aud-banner" hidden></div>
+        <div id="aud-list"></div>
+      </div>
+    `;
+
+    container.querySelector('#aud-filters').addEventListener('submit', (e) => {
+      e.preventDefault();
+      filters = Object.fromEntries(new FormData(e.target).entries());
+      offset = 0;
+      loadEntries(container);
+    });
+
+    container.querySelector('#aud-verify').addEventListener('click', () => verify(container));
+
+    container.querySelectorAll('[data-export]').forEach(btn => {
+      btn.addEventListener('click', () => download(btn.dataset.export).catch(err => alert(err.message)));
+    });
+
+    container.querySelector('#aud-list').addEventListener('click', (e) => {
+      const row = e.target.closest('.aud-row');
+      if (!row) return;
+      const detail = container.querySelector(`[data-detail="${row.dataset.seq}"]`);
+      if (detail) detail.hidden = !detail.hidden;
+    });
+
+    loadEntries(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('audit-log-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
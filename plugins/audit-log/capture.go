@@ -0,0 +1,102 @@
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+)
+
+// maxCapture is the most of a request body read for an entry. Larger
+// bodies are passed through untouched and stored as a marker.
+const maxCapture = 1 << 20
+
+// capture records every mutating panel API call around the rest of the
+// handler chain: the panel user, their address, the request body, the
+// response status and, for PUT and PATCH, the previous request body. It
+// runs on the panel's request hook, after authentication, so the username
+// is known and the panel's own routes are covered.
+func (p *AuditLogPlugin) capture(c *gin.Context, next func()) {
+	method := c.Request.Method
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		next()
+		return
+	}
+
+	path := c.Request.URL.Path
+	p.mu.RLock()
+	skip := p.excluded(path)
+	p.mu.RUnlock()
+	if skip || !strings.HasPrefix(path, "/api/") {
+		next()
+		return
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		body, _ = io.ReadAll(io.LimitReader(c.Request.Body, maxCapture+1))
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	}
+	var previous json.RawMessage
+	if method == http.MethodPut || method == http.MethodPatch {
+		previous = p.previousBody(path)
+	}
+
+	next()
+
+	after := json.RawMessage(body)
+	if len(body) > maxCapture {
+		after = json.RawMessage(`"[body too large]"`)
+	}
+	p.mu.RLock()
+	after = p.sanitize(after)
+	p.mu.RUnlock()
+
+	e := &Entry{
+		Time:         time.Now().UTC().Format(time.RFC3339Nano),
+		Actor:        access.Actor(c),
+		IP:           c.ClientIP(),
+		Method:       method,
+		Path:         path,
+		Status:       c.Writer.Status(),
+		Source:       "server",
+		PreviousBody: previous,
+		After:        after,
+		UserAgent:    c.GetHeader("User-Agent"),
+	}
+	if err := p.record(e); err != nil {
+		log.Printf("[audit-log] failed to append entry: %v", err)
+	}
+}
+
+// previousBody returns the body of the last successful PUT or PATCH to
+// path already in the log, or nil if there was none or the resource was
+// deleted since. It is not the resource's state: a PATCH body holds only
+// the fields it changed, and changes made outside the panel are missing.
+// The panel is never asked for the current value, so no request is made
+// with the caller's credentials.
+func (p *AuditLogPlugin) previousBody(path string) json.RawMessage {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for i := len(p.entries) - 1; i >= 0; i-- {
+		e := p.entries[i]
+		if e.Path != path || e.Status < 200 || e.Status > 299 {
+			continue
+		}
+		switch e.Method {
+		case http.MethodPut, http.MethodPatch:
+			return e.After
+		case http.MethodDelete:
+			return nil
+		}
+	}
+	return nil
+}
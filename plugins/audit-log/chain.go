@@ -0,0 +1,142 @@
+package auditlog
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// genesisHash is the previous hash of the first entry
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Entry is a single audit record. Each entry carries the hash of the one
+// before it, so editing, removing or reordering entries breaks the chain.
+// Before is the value a change replaced, which only the plugin knows for
+// its own settings. Entries from the request hook have PreviousBody, the
+// body of the last change to the same path, instead.
+type Entry struct {
+	Seq          int64           `json:"seq"`
+	Time         string          `json:"time"`
+	Actor        string          `json:"actor"`
+	IP           string          `json:"ip"`
+	Method       string          `json:"method"`
+	Path         string          `json:"path"`
+	Status       int             `json:"status"`
+	Source       string          `json:"source"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	PreviousBody json.RawMessage `json:"previous_body,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	UserAgent    string          `json:"user_agent,omitempty"`
+	PrevHash     string          `json:"prev_hash"`
+	Hash         string          `json:"hash"`
+}
+
+// computeHash returns the hash of the entry's content and previous hash
+func (e *Entry) computeHash() (string, error) {
+	c := *e
+	c.Hash = ""
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// appendEntry writes one entry to the end of the log file and syncs it
+func appendEntry(path string, e *Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// readLog reads every entry from the log file. A missing file is not an
+// error.
+func readLog(path string) ([]*Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make([]*Entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return entries, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, &e)
+	}
+	return entries, scanner.Err()
+}
+
+// VerifyResult is the outcome of checking the hash chain
+type VerifyResult struct {
+	OK       bool   `json:"ok"`
+	Entries  int    `json:"entries"`
+	LastSeq  int64  `json:"last_seq"`
+	LastHash string `json:"last_hash"`
+	BadSeq   int64  `json:"bad_seq,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// verifyChain checks that sequence numbers are consecutive and every hash
+// matches its content and predecessor
+func verifyChain(entries []*Entry) VerifyResult {
+	res := VerifyResult{OK: true, Entries: len(entries), LastHash: genesisHash}
+	prev := genesisHash
+	for i, e := range entries {
+		fail := func(msg string) VerifyResult {
+			res.OK = false
+			res.BadSeq = e.Seq
+			res.Error = msg
+			return res
+		}
+		if e.Seq != int64(i+1) {
+			return fail(fmt.Sprintf("expected sequence %d, found %d", i+1, e.Seq))
+		}
+		if e.PrevHash != prev {
+			return fail("previous hash does not match")
+		}
+		sum, err := e.computeHash()
+		if err != nil {
+			return fail(err.Error())
+		}
+		if sum != e.Hash {
+			return fail("entry hash does not match its content")
+		}
+		prev = e.Hash
+		res.LastSeq = e.Seq
+		res.LastHash = e.Hash
+	}
+	return res
+}
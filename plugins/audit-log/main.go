@@ -0,0 +1,623 @@
+// Audit Log Plugin for UnrealIRCd Web Panel
+// Records every change made through the panel - who, what, and the values
+// before and after - in a hash-chained, append-only log
+
+package auditlog
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/requesthook"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// ownPrefix is the API prefix of this plugin; calls to it are not audited
+const ownPrefix = "/api/plugin/audit-log"
+
+// maxAnchors is the number of anchor records that are kept
+const maxAnchors = 1000
+
+// AuditLogPlugin implements the Plugin interface
+type AuditLogPlugin struct {
+	config    Config
+	entries   []*Entry
+	integrity VerifyResult
+	anchors   []Anchor
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	DataDir       string `json:"data_dir"`
+	RedactKeys    string `json:"redact_keys"`
+	MaxBodyBytes  int    `json:"max_body_bytes"`
+	ExcludePaths  string `json:"exclude_paths"`
+	AnchorWebhook string `json:"anchor_webhook"`
+	AnchorHours   int    `json:"anchor_hours"`
+}
+
+// Anchor records the chain head sent to an external system
+type Anchor struct {
+	Time  time.Time `json:"time"`
+	Seq   int64     `json:"seq"`
+	Hash  string    `json:"hash"`
+	Error string    `json:"error,omitempty"`
+}
+
+// filter selects entries for listing and export
+type filter struct {
+	actor  string
+	method string
+	path   string
+	query  string
+	since  string
+	until  string
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &AuditLogPlugin{
+		config: Config{
+			DataDir:      "data/plugins/audit-log",
+			RedactKeys:   "password,passwd,secret,token,api_key,apikey",
+			MaxBodyBytes: 16384,
+			AnchorHours:  24,
+		},
+		entries: make([]*Entry, 0),
+		anchors: make([]Anchor, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *AuditLogPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Audit Log",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Tamper-evident log of every change made through the panel",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *AuditLogPlugin) Init() error {
+	p.mu.Lock()
+	entries, err := readLog(p.logPath())
+	if err != nil {
+		log.Printf("[audit-log] failed to read log: %v", err)
+	}
+	if entries != nil {
+		p.entries = entries
+	}
+	p.integrity = verifyChain(p.entries)
+	if !p.integrity.OK {
+		log.Printf("[audit-log] chain verification failed at entry %d: %s", p.integrity.BadSeq, p.integrity.Error)
+	}
+	if err := storage.LoadJSON(p.anchorPath(), &p.anchors); err != nil {
+		log.Printf("[audit-log] failed to load anchors: %v", err)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "audit-log-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		today := time.Now().UTC().Format("2006-01-02")
+		count := 0
+		actors := make(map[string]bool)
+		for i := len(p.entries) - 1; i >= 0 && strings.HasPrefix(p.entries[i].Time, today); i-- {
+			count++
+			actors[p.entries[i].Actor] = true
+		}
+		return plugins.DashboardCard{
+			Title: "Audit Log",
+			Icon:  "ClipboardCheck",
+			Content: map[string]interface{}{
+				"changes_today": count,
+				"staff_today":   len(actors),
+				"total":         len(p.entries),
+				"chain_ok":      p.integrity.OK,
+				"recording":     requesthook.Installed(),
+			},
+			Order: 46,
+			Size:  "sm",
+		}
+	}, 50)
+
+	// Record after the checks that turn requests away
+	requesthook.Register("audit-log", 50, p.capture)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.anchorLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *AuditLogPlugin) Shutdown() error {
+	requesthook.Unregister("audit-log")
+
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *AuditLogPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/audit-log")
+	{
+		plugin.GET("/entries", p.handleEntries)
+		plugin.GET("/entries/:seq", p.handleEntry)
+		plugin.GET("/verify", p.handleVerify)
+		plugin.GET("/export", p.handleExport)
+		plugin.GET("/anchors", p.handleAnchors)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// logPath returns the location of the audit log
+func (p *AuditLogPlugin) logPath() string {
+	return filepath.Join(p.config.DataDir, "audit.log")
+}
+
+// anchorPath returns the location of the anchor records
+func (p *AuditLogPlugin) anchorPath() string {
+	return filepath.Join(p.config.DataDir, "anchors.json")
+}
+
+// head returns the sequence number and hash of the last entry. Caller must
+// hold p.mu.
+func (p *AuditLogPlugin) head() (int64, string) {
+	if len(p.entries) == 0 {
+		return 0, genesisHash
+	}
+	last := p.entries[len(p.entries)-1]
+	return last.Seq, last.Hash
+}
+
+// record chains and appends an entry. The write happens under the lock so
+// entries reach the file in sequence order.
+func (p *AuditLogPlugin) record(e *Entry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seq, prev := p.head()
+	e.Seq = seq + 1
+	e.PrevHash = prev
+	sum, err := e.computeHash()
+	if err != nil {
+		return err
+	}
+	e.Hash = sum
+	if err := appendEntry(p.logPath(), e); err != nil {
+		return err
+	}
+	p.entries = append(p.entries, e)
+	return nil
+}
+
+// sanitize redacts secrets from a request or response body and caps its
+// size. Caller must hold p.mu.
+func (p *AuditLogPlugin) sanitize(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return json.RawMessage(`"[non-JSON body]"`)
+	}
+
+	keys := make([]string, 0)
+	for _, k := range strings.Split(p.config.RedactKeys, ",") {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	out, err := json.Marshal(redact(v, keys))
+	if err != nil {
+		return nil
+	}
+	if max := p.config.MaxBodyBytes; max > 0 && len(out) > max {
+		return json.RawMessage(fmt.Sprintf(`{"truncated":true,"bytes":%d}`, len(out)))
+	}
+	return out
+}
+
+// redact replaces the values of keys containing any of the given words
+func redact(v interface{}, keys []string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			lower := strings.ToLower(k)
+			secret := false
+			for _, word := range keys {
+				if strings.Contains(lower, word) {
+					secret = true
+					break
+				}
+			}
+			if secret {
+				if s, ok := val.(string); ok && s == "" {
+					continue
+				}
+				t[k] = "[redacted]"
+				continue
+			}
+			t[k] = redact(val, keys)
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = redact(t[i], keys)
+		}
+	}
+	return v
+}
+
+// excluded reports whether a path should not be audited. Caller must hold
+// p.mu.
+func (p *AuditLogPlugin) excluded(path string) bool {
+	if strings.HasPrefix(path, ownPrefix) {
+		return true
+	}
+	for _, prefix := range strings.Split(p.config.ExcludePaths, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" && strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFilter reads the common filter parameters
+func parseFilter(c *gin.Context) (filter, error) {
+	f := filter{
+		actor:  c.Query("actor"),
+		method: strings.ToUpper(c.Query("method")),
+		path:   c.Query("path"),
+		query:  strings.ToLower(c.Query("q")),
+	}
+	for _, param := range []struct {
+		name string
+		dst  *string
+	}{{"since", &f.since}, {"until", &f.until}} {
+		v := c.Query(param.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be a date or RFC 3339 timestamp", param.name)
+			}
+		}
+		*param.dst = t.UTC().Format(time.RFC3339Nano)
+	}
+	return f, nil
+}
+
+// match reports whether an entry passes the filter
+func (f filter) match(e *Entry) bool {
+	if f.actor != "" && !strings.EqualFold(e.Actor, f.actor) {
+		return false
+	}
+	if f.method != "" && e.Method != f.method {
+		return false
+	}
+	if f.path != "" && !strings.Contains(e.Path, f.path) {
+		return false
+	}
+	// Timestamps share one format, so they compare as strings
+	if f.since != "" && e.Time < f.since {
+		return false
+	}
+	if f.until != "" && e.Time >= f.until {
+		return false
+	}
+	if f.query != "" {
+		text := strings.ToLower(e.Actor + " " + e.Path + " " + string(e.Before) + " " + string(e.PreviousBody) + " " + string(e.After))
+		if !strings.Contains(text, f.query) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleEntries searches the log, newest first
+func (p *AuditLogPlugin) handleEntries(c *gin.Context) {
+	f, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must not be negative"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Entry, 0)
+	total := 0
+	for i := len(p.entries) - 1; i >= 0; i-- {
+		if !f.match(p.entries[i]) {
+			continue
+		}
+		if total >= offset && len(list) < limit {
+			list = append(list, p.entries[i])
+		}
+		total++
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "entries": list, "recording": requesthook.Ran(c)})
+}
+
+// handleEntry returns a single entry by sequence number
+func (p *AuditLogPlugin) handleEntry(c *gin.Context) {
+	seq, err := strconv.ParseInt(c.Param("seq"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sequence number"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if seq < 1 || seq > int64(len(p.entries)) || p.entries[seq-1].Seq != seq {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+		return
+	}
+	c.JSON(http.StatusOK, p.entries[seq-1])
+}
+
+// handleVerify re-reads the log from disk and checks the whole chain
+func (p *AuditLogPlugin) handleVerify(c *gin.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entries, err := readLog(p.logPath())
+	res := verifyChain(entries)
+	if err != nil {
+		res.OK = false
+		res.Error = err.Error()
+	} else if res.OK && len(entries) != len(p.entries) {
+		// Entries removed from the end leave a valid chain behind; the
+		// copy in memory and the anchors catch that
+		res.OK = false
+		res.Error = fmt.Sprintf("log holds %d entries, %d were recorded", len(entries), len(p.entries))
+	}
+	p.integrity = res
+
+	c.JSON(http.StatusOK, gin.H{"result": res, "anchors": len(p.anchors)})
+}
+
+// handleExport downloads matching entries as JSON lines or CSV
+func (p *AuditLogPlugin) handleExport(c *gin.Context) {
+	f, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := c.DefaultQuery("format", "jsonl")
+	if format != "jsonl" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be jsonl or csv"})
+		return
+	}
+
+	p.mu.RLock()
+	list := make([]*Entry, 0)
+	for _, e := range p.entries {
+		if f.match(e) {
+			list = append(list, e)
+		}
+	}
+	p.mu.RUnlock()
+
+	name := "audit-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+
+	if format == "jsonl" {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		enc := json.NewEncoder(c.Writer)
+		for _, e := range list {
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"seq", "time", "actor", "ip", "method", "path", "status", "before", "previous_body", "after", "hash"})
+	for _, e := range list {
+		_ = w.Write([]string{
+			strconv.FormatInt(e.Seq, 10), e.Time, e.Actor, e.IP, e.Method, e.Path,
+			strconv.Itoa(e.Status), string(e.Before), string(e.PreviousBody), string(e.After), e.Hash,
+		})
+	}
+	w.Flush()
+}
+
+// handleAnchors returns the anchors sent so far, newest first
+func (p *AuditLogPlugin) handleAnchors(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Anchor, 0, len(p.anchors))
+	for i := len(p.anchors) - 1; i >= 0; i-- {
+		list = append(list, p.anchors[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"anchors": list})
+}
+
+// anchorLoop periodically sends the chain head to the anchor webhook until
+// shutdown
+func (p *AuditLogPlugin) anchorLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.anchor()
+		}
+	}
+}
+
+// anchor sends the chain head off-host if it moved and an anchor is due.
+// Once a hash has left the panel host, rewriting the log up to that point
+// can no longer go unnoticed.
+func (p *AuditLogPlugin) anchor() {
+	p.mu.RLock()
+	webhook := p.config.AnchorWebhook
+	hours := p.config.AnchorHours
+	seq, hash := p.head()
+	var last Anchor
+	for i := len(p.anchors) - 1; i >= 0; i-- {
+		if p.anchors[i].Error == "" {
+			last = p.anchors[i]
+			break
+		}
+	}
+	p.mu.RUnlock()
+
+	if hours < 1 {
+		hours = 1
+	}
+	if webhook == "" || seq == 0 || seq == last.Seq || time.Since(last.Time) < time.Duration(hours)*time.Hour {
+		return
+	}
+
+	now := time.Now().UTC()
+	a := Anchor{Time: now, Seq: seq, Hash: hash}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	err := notify.Send(ctx, webhook, notify.Event{
+		Source:    "audit-log",
+		Type:      "audit_anchor",
+		Severity:  "info",
+		Title:     "Audit log checkpoint",
+		Message:   fmt.Sprintf("Audit log entry %d has hash %s", seq, hash),
+		Timestamp: now,
+		Data:      map[string]interface{}{"seq": seq, "hash": hash},
+	})
+	if err != nil {
+		a.Error = err.Error()
+		log.Printf("[audit-log] failed to send anchor: %v", err)
+	}
+
+	p.mu.Lock()
+	p.anchors = append(p.anchors, a)
+	if len(p.anchors) > maxAnchors {
+		p.anchors = append([]Anchor(nil), p.anchors[len(p.anchors)-maxAnchors:]...)
+	}
+	if err := storage.SaveJSON(p.anchorPath(), p.anchors); err != nil {
+		log.Printf("[audit-log] failed to save anchors: %v", err)
+	}
+	p.mu.Unlock()
+}
+
+// handleGetConfig returns the current configuration
+func (p *AuditLogPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration. Changing the data
+// directory would start a new chain, so it is kept.
+func (p *AuditLogPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	before, _ := json.Marshal(p.config)
+	newConfig.DataDir = p.config.DataDir
+	p.config = newConfig
+	after, _ := json.Marshal(p.config)
+	p.mu.Unlock()
+
+	// Changes to the audit settings themselves are audited here, since the
+	// capture middleware skips this plugin's own calls
+	err := p.record(&Entry{
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Actor:     access.Actor(c),
+		IP:        c.ClientIP(),
+		Method:    http.MethodPut,
+		Path:      ownPrefix + "/config",
+		Status:    http.StatusOK,
+		Source:    "audit-log",
+		Before:    before,
+		After:     after,
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+	if err != nil {
+		log.Printf("[audit-log] failed to append entry: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *AuditLogPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *AuditLogPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,837 @@
+// Auth Watch Plugin for UnrealIRCd Web Panel
+// Watches failed panel logins and OPER attempts, detects brute forcing,
+// recommends per-IP lockouts and alerts staff
+
+package authwatch
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Attempt kinds
+const (
+	KindPanel = "panel"
+	KindOper  = "oper"
+)
+
+// Recommendation states
+const (
+	RecOpen      = "open"
+	RecApplied   = "applied"
+	RecDismissed = "dismissed"
+)
+
+// AuthWatchPlugin implements the Plugin interface
+type AuthWatchPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	attempts     []*Attempt
+	recs         map[string]*Recommendation
+	targetAlerts map[string]time.Time
+	dirty        bool
+	streamOK     bool
+	tailError    string
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL           string `json:"rpc_url"`
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	StreamURL        string `json:"stream_url"`
+	LogSources       string `json:"log_sources"`
+	FailureEvents    string `json:"failure_events"`
+	SuccessEvents    string `json:"success_events"`
+	PanelLogFile     string `json:"panel_log_file"`
+	PanelLogPattern  string `json:"panel_log_pattern"`
+	DataDir          string `json:"data_dir"`
+	BurstThreshold   int    `json:"burst_threshold"`
+	BurstWindow      int    `json:"burst_window"`
+	LockoutDurations string `json:"lockout_durations"`
+	NotifyOperUp     bool   `json:"notify_oper_up"`
+	AllowApply       bool   `json:"allow_apply"`
+	BanType          string `json:"ban_type"`
+	BanReason        string `json:"ban_reason"`
+	MaxAttempts      int    `json:"max_attempts"`
+	AlertWebhook     string `json:"alert_webhook"`
+}
+
+// Attempt is a single login or OPER attempt
+type Attempt struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Success bool      `json:"success"`
+	IP      string    `json:"ip"`
+	Target  string    `json:"target"`
+	Nick    string    `json:"nick,omitempty"`
+	Server  string    `json:"server,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// Recommendation suggests locking out an IP that brute forced
+type Recommendation struct {
+	IP        string     `json:"ip"`
+	Bursts    int        `json:"bursts"`
+	Failures  int        `json:"failures"`
+	Kinds     []string   `json:"kinds"`
+	Targets   []string   `json:"targets"`
+	FirstSeen time.Time  `json:"first_seen"`
+	LastSeen  time.Time  `json:"last_seen"`
+	LastBurst time.Time  `json:"last_burst"`
+	Duration  string     `json:"duration"`
+	Action    string     `json:"action"`
+	Status    string     `json:"status"`
+	ActedBy   string     `json:"acted_by,omitempty"`
+	ActedAt   *time.Time `json:"acted_at,omitempty"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Attempts        []*Attempt                 `json:"attempts"`
+	Recommendations map[string]*Recommendation `json:"recommendations"`
+}
+
+// operEvent is the subset of an OPER log entry we need
+type operEvent struct {
+	LogSource string `json:"log_source"`
+	OperBlock string `json:"oper_block"`
+	Reason    string `json:"reason"`
+	Client    *struct {
+		Name     string `json:"name"`
+		IP       string `json:"ip"`
+		Hostname string `json:"hostname"`
+	} `json:"client"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &AuthWatchPlugin{
+		config: Config{
+			RPCURL:           "https://127.0.0.1:8600/api",
+			StreamURL:        "wss://127.0.0.1:8600/",
+			LogSources:       "oper",
+			FailureEvents:    "OPER_FAILED",
+			SuccessEvents:    "OPER_SUCCESS",
+			PanelLogPattern:  `(?i)login failed.*?user(?:name)?[=: ]+(?P<user>\S+).*?(?:ip|from)[=: ]+(?P<ip>[0-9a-fA-F:.]+)`,
+			DataDir:          "data/plugins/auth-watch",
+			BurstThreshold:   5,
+			BurstWindow:      10,
+			LockoutDurations: "1h,1d,7d",
+			NotifyOperUp:     true,
+			BanType:          "zline",
+			BanReason:        "Too many failed authentication attempts",
+			MaxAttempts:      5000,
+		},
+		attempts:     make([]*Attempt, 0),
+		recs:         make(map[string]*Recommendation),
+		targetAlerts: make(map[string]time.Time),
+	}
+}
+
+// Info returns plugin metadata
+func (p *AuthWatchPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Auth Watch",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Brute force detection for panel logins and OPER attempts",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *AuthWatchPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[auth-watch] failed to load data: %v", err)
+	}
+	if data.Attempts != nil {
+		p.attempts = data.Attempts
+	}
+	if data.Recommendations != nil {
+		p.recs = data.Recommendations
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "auth-watch-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		failures := make(map[string]int)
+		for i := len(p.attempts) - 1; i >= 0 && p.attempts[i].Time.After(since); i-- {
+			if !p.attempts[i].Success {
+				failures[p.attempts[i].Kind]++
+			}
+		}
+		open := 0
+		for _, r := range p.recs {
+			if r.Status == RecOpen {
+				open++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Auth Watch",
+			Icon:  "ShieldAlert",
+			Content: map[string]interface{}{
+				"panel_failures_24h": failures[KindPanel],
+				"oper_failures_24h":  failures[KindOper],
+				"open_lockouts":      open,
+				"streaming":          p.streamOK,
+			},
+			Order: 47,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.tailLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *AuthWatchPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *AuthWatchPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/auth-watch")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/attempts", p.handleAttempts)
+		plugin.GET("/offenders", p.handleOffenders)
+		plugin.GET("/recommendations", p.handleRecommendations)
+		plugin.POST("/recommendations/:ip/apply", p.handleApply)
+		plugin.POST("/recommendations/:ip/dismiss", p.handleDismiss)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *AuthWatchPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "auth.json")
+}
+
+// save persists the state if it changed
+func (p *AuthWatchPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Attempts: p.attempts, Recommendations: p.recs}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *AuthWatchPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// streamLoop follows OPER log events until shutdown
+func (p *AuthWatchPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *AuthWatchPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[auth-watch] log stream: %v", err)
+	}
+}
+
+// handleEvent records OPER successes and failures
+func (p *AuthWatchPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	p.mu.RLock()
+	failed := lists.ContainsFold(lists.Split(p.config.FailureEvents), ev.EventID)
+	succeeded := lists.ContainsFold(lists.Split(p.config.SuccessEvents), ev.EventID)
+	p.mu.RUnlock()
+	if !failed && !succeeded {
+		return
+	}
+
+	var oe operEvent
+	_ = json.Unmarshal(ev.Raw, &oe)
+	a := &Attempt{
+		Time:    time.Now().UTC(),
+		Kind:    KindOper,
+		Success: succeeded,
+		Target:  oe.OperBlock,
+		Server:  oe.LogSource,
+		Reason:  oe.Reason,
+	}
+	if oe.Client != nil {
+		a.Nick = oe.Client.Name
+		a.IP = oe.Client.IP
+	}
+	if t, err := time.Parse(time.RFC3339, ev.Timestamp); err == nil {
+		a.Time = t.UTC()
+	}
+	p.record(a)
+}
+
+// tailLoop reads failed panel logins from the panel log and saves the
+// state until shutdown
+func (p *AuthWatchPlugin) tailLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	var tail *fileTail
+	var pattern string
+	var re *regexp.Regexp
+	saveAt := time.Now().Add(time.Minute)
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.RLock()
+		path := p.config.PanelLogFile
+		cfgPattern := p.config.PanelLogPattern
+		p.mu.RUnlock()
+
+		if path == "" {
+			tail = nil
+		} else if tail == nil || tail.path != path {
+			tail = newFileTail(path)
+		}
+		if cfgPattern != pattern {
+			pattern = cfgPattern
+			var err error
+			if re, err = regexp.Compile(pattern); err != nil {
+				log.Printf("[auth-watch] invalid panel log pattern: %v", err)
+				re = nil
+			}
+		}
+
+		if tail != nil && re != nil {
+			lines, err := tail.ReadLines()
+			p.mu.Lock()
+			p.tailError = ""
+			if err != nil {
+				p.tailError = err.Error()
+			}
+			p.mu.Unlock()
+			for _, line := range lines {
+				if f, ok := matchPanelFailure(re, line); ok {
+					p.record(&Attempt{Time: time.Now().UTC(), Kind: KindPanel, IP: f.ip, Target: f.user})
+				}
+			}
+		}
+
+		if time.Now().After(saveAt) {
+			saveAt = time.Now().Add(time.Minute)
+			if err := p.save(); err != nil {
+				log.Printf("[auth-watch] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// window returns the burst detection window. Caller must hold p.mu.
+func (p *AuthWatchPlugin) window() time.Duration {
+	minutes := p.config.BurstWindow
+	if minutes < 1 {
+		minutes = 1
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// record stores an attempt, runs burst detection and sends any alerts
+func (p *AuthWatchPlugin) record(a *Attempt) {
+	alerts := make([]notify.Event, 0)
+
+	p.mu.Lock()
+	p.attempts = append(p.attempts, a)
+	if max := p.config.MaxAttempts; max > 0 && len(p.attempts) > max {
+		p.attempts = append([]*Attempt(nil), p.attempts[len(p.attempts)-max:]...)
+	}
+	p.dirty = true
+
+	window := p.window()
+	since := a.Time.Add(-window)
+	threshold := p.config.BurstThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	ipFailures := 0
+	targetFailures := 0
+	targetIPs := make(map[string]bool)
+	for i := len(p.attempts) - 1; i >= 0 && !p.attempts[i].Time.Before(since); i-- {
+		o := p.attempts[i]
+		if o.Success || o.Kind != a.Kind {
+			continue
+		}
+		if a.IP != "" && o.IP == a.IP {
+			ipFailures++
+		}
+		if a.Target != "" && strings.EqualFold(o.Target, a.Target) {
+			targetFailures++
+			targetIPs[o.IP] = true
+		}
+	}
+
+	label := "panel login"
+	if a.Kind == KindOper {
+		label = "OPER"
+	}
+
+	switch {
+	case a.Success && a.Kind == KindOper:
+		if ipFailures > 0 {
+			alerts = append(alerts, notify.Event{
+				Type:     "oper_after_failures",
+				Severity: "warning",
+				Title:    "OPER succeeded after failures",
+				Message:  fmt.Sprintf("%s (%s) opered up as %s after %d failed attempts from the same IP", a.Nick, a.IP, a.Target, ipFailures),
+				Data:     map[string]interface{}{"nick": a.Nick, "ip": a.IP, "oper_block": a.Target, "server": a.Server, "failures": ipFailures},
+			})
+		} else if p.config.NotifyOperUp {
+			alerts = append(alerts, notify.Event{
+				Type:     "oper_up",
+				Severity: "info",
+				Title:    "OPER",
+				Message:  fmt.Sprintf("%s (%s) opered up as %s", a.Nick, a.IP, a.Target),
+				Data:     map[string]interface{}{"nick": a.Nick, "ip": a.IP, "oper_block": a.Target, "server": a.Server},
+			})
+		}
+	case !a.Success:
+		if a.IP != "" {
+			r := p.recommendation(a)
+			if ipFailures >= threshold && a.Time.Sub(r.LastBurst) > window {
+				r.Bursts++
+				r.LastBurst = a.Time
+				r.Status = RecOpen
+				r.ActedBy = ""
+				r.ActedAt = nil
+				r.Duration = lockoutDuration(p.config.LockoutDurations, r.Bursts)
+				r.Action = fmt.Sprintf("%s *@%s for %s", strings.ToUpper(p.config.BanType), r.IP, r.Duration)
+				alerts = append(alerts, notify.Event{
+					Type:     "brute_force",
+					Severity: "critical",
+					Title:    "Brute force detected",
+					Message:  fmt.Sprintf("%d failed %s attempts from %s in %s; recommended: %s", ipFailures, label, a.IP, window, r.Action),
+					Data:     map[string]interface{}{"ip": a.IP, "kind": a.Kind, "failures": ipFailures, "targets": r.Targets, "recommendation": r.Action},
+				})
+				log.Printf("[auth-watch] brute force from %s (%d %s failures)", a.IP, ipFailures, label)
+			}
+		}
+		// Many addresses guessing the same account or oper block
+		key := a.Kind + ":" + strings.ToLower(a.Target)
+		if a.Target != "" && targetFailures >= threshold && len(targetIPs) > 1 && a.Time.Sub(p.targetAlerts[key]) > window {
+			p.targetAlerts[key] = a.Time
+			ips := make([]string, 0, len(targetIPs))
+			for ip := range targetIPs {
+				ips = append(ips, ip)
+			}
+			sort.Strings(ips)
+			alerts = append(alerts, notify.Event{
+				Type:     "target_brute_force",
+				Severity: "critical",
+				Title:    "Distributed brute force detected",
+				Message:  fmt.Sprintf("%d failed %s attempts for %s from %d addresses in %s", targetFailures, label, a.Target, len(ips), window),
+				Data:     map[string]interface{}{"target": a.Target, "kind": a.Kind, "failures": targetFailures, "ips": ips},
+			})
+		}
+	}
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	for _, al := range alerts {
+		log.Printf("[auth-watch] %s: %s", al.Title, al.Message)
+		if webhook != "" {
+			al.Source = "auth-watch"
+			al.Timestamp = a.Time
+			go p.alert(webhook, al)
+		}
+	}
+}
+
+// recommendation returns the lockout record of a failed attempt's IP,
+// updated with the attempt. Caller must hold p.mu.
+func (p *AuthWatchPlugin) recommendation(a *Attempt) *Recommendation {
+	r, ok := p.recs[a.IP]
+	if !ok {
+		r = &Recommendation{IP: a.IP, FirstSeen: a.Time, Kinds: make([]string, 0), Targets: make([]string, 0), Status: RecOpen}
+		p.recs[a.IP] = r
+	}
+	r.Failures++
+	r.LastSeen = a.Time
+	if !lists.ContainsFold(r.Kinds, a.Kind) {
+		r.Kinds = append(r.Kinds, a.Kind)
+	}
+	if a.Target != "" && !lists.ContainsFold(r.Targets, a.Target) && len(r.Targets) < 20 {
+		r.Targets = append(r.Targets, a.Target)
+	}
+	return r
+}
+
+// lockoutDuration picks the lockout length for the nth burst from an IP,
+// repeating the longest once the list runs out
+func lockoutDuration(setting string, bursts int) string {
+	durations := lists.Split(setting)
+	if len(durations) == 0 {
+		return "1h"
+	}
+	if bursts > len(durations) {
+		bursts = len(durations)
+	}
+	return durations[bursts-1]
+}
+
+// alert posts an alert to the webhook
+func (p *AuthWatchPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[auth-watch] failed to send alert: %v", err)
+	}
+}
+
+// handleStatus reports whether both sources are being read
+func (p *AuthWatchPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"log_stream":      p.streamOK,
+		"panel_log_file":  p.config.PanelLogFile,
+		"panel_log_error": p.tailError,
+		"attempts":        len(p.attempts),
+	})
+}
+
+// handleAttempts returns recent attempts, newest first
+func (p *AuthWatchPlugin) handleAttempts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	kind := c.Query("kind")
+	ip := c.Query("ip")
+	result := c.Query("result")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Attempt, 0)
+	for i := len(p.attempts) - 1; i >= 0 && len(list) < limit; i-- {
+		a := p.attempts[i]
+		if kind != "" && a.Kind != kind {
+			continue
+		}
+		if ip != "" && a.IP != ip {
+			continue
+		}
+		if (result == "success" && !a.Success) || (result == "failure" && a.Success) {
+			continue
+		}
+		list = append(list, a)
+	}
+	c.JSON(http.StatusOK, gin.H{"attempts": list})
+}
+
+// handleOffenders ranks IPs by failed attempts over a period
+func (p *AuthWatchPlugin) handleOffenders(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be a positive number"})
+		return
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	type offender struct {
+		IP       string         `json:"ip"`
+		Failures int            `json:"failures"`
+		ByKind   map[string]int `json:"by_kind"`
+		Targets  []string       `json:"targets"`
+		Last     time.Time      `json:"last"`
+		Status   string         `json:"recommendation,omitempty"`
+	}
+	byIP := make(map[string]*offender)
+	for i := len(p.attempts) - 1; i >= 0 && p.attempts[i].Time.After(since); i-- {
+		a := p.attempts[i]
+		if a.Success || a.IP == "" {
+			continue
+		}
+		o, ok := byIP[a.IP]
+		if !ok {
+			o = &offender{IP: a.IP, ByKind: make(map[string]int), Targets: make([]string, 0), Last: a.Time}
+			if r, ok := p.recs[a.IP]; ok && r.Bursts > 0 {
+				o.Status = r.Status
+			}
+			byIP[a.IP] = o
+		}
+		o.Failures++
+		o.ByKind[a.Kind]++
+		if a.Target != "" && !lists.ContainsFold(o.Targets, a.Target) {
+			o.Targets = append(o.Targets, a.Target)
+		}
+	}
+
+	list := make([]*offender, 0, len(byIP))
+	for _, o := range byIP {
+		list = append(list, o)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Failures > list[j].Failures })
+	c.JSON(http.StatusOK, gin.H{"hours": hours, "offenders": list})
+}
+
+// handleRecommendations returns lockout recommendations, most recent
+// burst first
+func (p *AuthWatchPlugin) handleRecommendations(c *gin.Context) {
+	status := c.DefaultQuery("status", RecOpen)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Recommendation, 0)
+	for _, r := range p.recs {
+		if r.Bursts == 0 || (status != "all" && r.Status != status) {
+			continue
+		}
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastBurst.After(list[j].LastBurst) })
+	c.JSON(http.StatusOK, gin.H{"recommendations": list})
+}
+
+// openRecommendation looks up an open recommendation for the handlers below
+func (p *AuthWatchPlugin) openRecommendation(c *gin.Context) (*Recommendation, bool) {
+	r, ok := p.recs[c.Param("ip")]
+	if !ok || r.Bursts == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recommendation not found"})
+		return nil, false
+	}
+	if r.Status != RecOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": "Recommendation is already " + r.Status})
+		return nil, false
+	}
+	return r, true
+}
+
+// handleApply places the recommended ban over JSON-RPC
+func (p *AuthWatchPlugin) handleApply(c *gin.Context) {
+	ip := c.Param("ip")
+	if net.ParseIP(ip) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+
+	p.mu.RLock()
+	allowed := p.config.AllowApply
+	banType := p.config.BanType
+	reason := p.config.BanReason
+	p.mu.RUnlock()
+
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Applying lockouts is disabled in the plugin settings"})
+		return
+	}
+
+	p.mu.RLock()
+	r, ok := p.openRecommendation(c)
+	duration := ""
+	if ok {
+		duration = r.Duration
+	}
+	p.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+	params := map[string]interface{}{
+		"name":            "*@" + ip,
+		"type":            banType,
+		"reason":          reason,
+		"duration_string": duration,
+	}
+	var out json.RawMessage
+	if err := p.client().Call(ctx, "server_ban.add", params, &out); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := access.Actor(c)
+	now := time.Now().UTC()
+	p.mu.Lock()
+	if r, ok := p.recs[ip]; ok {
+		r.Status = RecApplied
+		r.ActedBy = actor
+		r.ActedAt = &now
+	}
+	p.dirty = true
+	p.mu.Unlock()
+
+	log.Printf("[auth-watch] %s applied %s on %s for %s", actor, banType, ip, duration)
+	c.JSON(http.StatusOK, gin.H{"message": "Lockout applied"})
+}
+
+// handleDismiss marks a recommendation as not needing action
+func (p *AuthWatchPlugin) handleDismiss(c *gin.Context) {
+	p.mu.Lock()
+	r, ok := p.openRecommendation(c)
+	if ok {
+		now := time.Now().UTC()
+		r.Status = RecDismissed
+		r.ActedBy = access.Actor(c)
+		r.ActedAt = &now
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if ok {
+		c.JSON(http.StatusOK, gin.H{"message": "Recommendation dismissed"})
+	}
+}
+
+// handleGetConfig returns the current configuration
+func (p *AuthWatchPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *AuthWatchPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if _, err := regexp.Compile(newConfig.PanelLogPattern); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid panel log pattern: " + err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *AuthWatchPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *AuthWatchPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,93 @@
+package authwatch
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// fileTail reads lines appended to a log file since the last read
+type fileTail struct {
+	path    string
+	offset  int64
+	started bool
+	partial string
+}
+
+// newFileTail creates a tail for path. The first read skips what is
+// already in the file.
+func newFileTail(path string) *fileTail {
+	return &fileTail{path: path}
+}
+
+// ReadLines returns the complete lines added since the previous call. A file
+// that shrank is assumed to have been rotated and is read from the start.
+func (t *fileTail) ReadLines() ([]string, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !t.started {
+		t.started = true
+		t.offset = info.Size()
+		return nil, nil
+	}
+	if info.Size() < t.offset {
+		t.offset = 0
+		t.partial = ""
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0)
+	reader := bufio.NewReader(f)
+	for {
+		chunk, err := reader.ReadString('\n')
+		t.offset += int64(len(chunk))
+		if err == io.EOF {
+			// Keep an unfinished line for the next read
+			t.partial += chunk
+			break
+		}
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, strings.TrimRight(t.partial+chunk, "\r\n"))
+		t.partial = ""
+	}
+	return lines, nil
+}
+
+// panelFailure is a failed panel login parsed from a log line
+type panelFailure struct {
+	user string
+	ip   string
+}
+
+// matchPanelFailure applies the configured pattern to a log line. The
+// pattern may name "user" and "ip" groups.
+func matchPanelFailure(re *regexp.Regexp, line string) (panelFailure, bool) {
+	m := re.FindStringSubmatch(line)
+	if m == nil {
+		return panelFailure{}, false
+	}
+	var f panelFailure
+	for i, name := range re.SubexpNames() {
+		switch name {
+		case "user":
+			f.user = strings.Trim(m[i], `"'`)
+		case "ip":
+			f.ip = strings.Trim(m[i], `"'[]`)
+		}
+	}
+	return f, true
+}
@@ -0,0 +1,318 @@
+package backuprestore
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// formatVersion is the layout of the archives this plugin writes. Restores
+// refuse archives from a newer version.
+const formatVersion = 1
+
+// manifestName is the first entry of every archive
+const manifestName = "manifest.json"
+
+// Manifest describes the contents of a backup archive
+type Manifest struct {
+	FormatVersion int         `json:"format_version"`
+	CreatedAt     time.Time   `json:"created_at"`
+	CreatedBy     string      `json:"created_by"`
+	Trigger       string      `json:"trigger"`
+	Hostname      string      `json:"hostname"`
+	Paths         []string    `json:"paths"`
+	Files         []FileEntry `json:"files"`
+}
+
+// FileEntry is a file in a backup and its checksum
+type FileEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Mode    uint32    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+}
+
+// collect walks the include paths and returns the files to back up, with
+// their checksums. Paths matching an exclude pattern, and anything under
+// a skipped directory, are left out.
+func collect(include, exclude, skip []string, maxBytes int64) ([]FileEntry, error) {
+	files := make([]FileEntry, 0)
+	var total int64
+	for _, root := range include {
+		root = filepath.Clean(root)
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) && p == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				if p != root && (excluded(p, exclude) || underAny(p, skip)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || excluded(p, exclude) || underAny(p, skip) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+			if maxBytes > 0 && total > maxBytes {
+				return fmt.Errorf("backup would be larger than %d MB", maxBytes>>20)
+			}
+			sum, err := fileSHA256(p)
+			if err != nil {
+				return err
+			}
+			files = append(files, FileEntry{
+				Path:    filepath.ToSlash(p),
+				Size:    info.Size(),
+				Mode:    uint32(info.Mode().Perm()),
+				ModTime: info.ModTime().UTC(),
+				SHA256:  sum,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// excluded reports whether the base name of p matches one of the patterns
+func excluded(p string, patterns []string) bool {
+	base := filepath.Base(p)
+	for _, pat := range patterns {
+		if ok, _ := filepath.Match(pat, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// underAny reports whether p is one of dirs or inside one of them
+func underAny(p string, dirs []string) bool {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	for _, dir := range dirs {
+		d, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if abs == d || strings.HasPrefix(abs, d+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileSHA256 returns the hex SHA-256 of a file
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeArchive writes a gzipped tar of the manifest and its files to
+// dest and returns the SHA-256 and size of the archive. A file that
+// changed since it was collected fails the backup rather than storing
+// something that doesn't match its checksum.
+func writeArchive(dest string, m *Manifest) (string, int64, error) {
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	counter := &countWriter{w: io.MultiWriter(out, h)}
+	gz := gzip.NewWriter(counter)
+	tw := tar.NewWriter(gz)
+
+	err = func() error {
+		manifest, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{Name: manifestName, Mode: 0o600, Size: int64(len(manifest)), ModTime: m.CreatedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(manifest); err != nil {
+			return err
+		}
+		for _, fe := range m.Files {
+			if err := addFile(tw, fe); err != nil {
+				return err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	}()
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), counter.n, nil
+}
+
+// addFile copies one file into the archive and checks it against its
+// collected checksum
+func addFile(tw *tar.Writer, fe FileEntry) error {
+	f, err := os.Open(filepath.FromSlash(fe.Path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hdr := &tar.Header{Name: "files/" + fe.Path, Mode: int64(fe.Mode), Size: fe.Size, ModTime: fe.ModTime}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tw, h), io.LimitReader(f, fe.Size))
+	if err != nil {
+		return err
+	}
+	if n != fe.Size || hex.EncodeToString(h.Sum(nil)) != fe.SHA256 {
+		return fmt.Errorf("%s changed while it was being backed up; try again", fe.Path)
+	}
+	return nil
+}
+
+// countWriter counts the bytes written through it
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// readArchive checks an archive and calls fn with each file's entry and
+// contents. The manifest comes first; every file must match its checksum,
+// and every file in the manifest must be present. fn may be nil to only
+// verify. Nothing read before an error should be trusted.
+func readArchive(src string, fn func(fe FileEntry, r io.Reader) error) (*Manifest, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, fmt.Errorf("not a backup archive: no manifest")
+	}
+	var m Manifest
+	if err := json.NewDecoder(io.LimitReader(tr, 16<<20)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if m.FormatVersion < 1 || m.FormatVersion > formatVersion {
+		return nil, fmt.Errorf("archive format %d is not supported", m.FormatVersion)
+	}
+	want := make(map[string]FileEntry, len(m.Files))
+	for _, fe := range m.Files {
+		if !safePath(fe.Path) {
+			return nil, fmt.Errorf("unsafe path %q in manifest", fe.Path)
+		}
+		want[fe.Path] = fe
+	}
+
+	seen := make(map[string]bool, len(m.Files))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(hdr.Name, "files/")
+		fe, ok := want[name]
+		if !ok || seen[name] || hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unexpected entry %q in archive", hdr.Name)
+		}
+		seen[name] = true
+
+		h := sha256.New()
+		var r io.Reader = io.TeeReader(io.LimitReader(tr, fe.Size+1), h)
+		if fn != nil {
+			err = fn(fe, r)
+		}
+		if err == nil {
+			_, err = io.Copy(io.Discard, r)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != fe.SHA256 {
+			return nil, fmt.Errorf("checksum mismatch for %s", fe.Path)
+		}
+	}
+	for p := range want {
+		if !seen[p] {
+			return nil, fmt.Errorf("%s is missing from the archive", p)
+		}
+	}
+	return &m, nil
+}
+
+// safePath reports whether an archive path is relative and stays inside
+// the panel directory
+func safePath(p string) bool {
+	if p == "" || path.IsAbs(p) || strings.Contains(p, "\\") || filepath.IsAbs(filepath.FromSlash(p)) {
+		return false
+	}
+	clean := path.Clean(p)
+	return clean == p && clean != "." && clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// insideRoots reports whether an archive path is under one of the include
+// paths
+func insideRoots(p string, roots []string) bool {
+	for _, root := range roots {
+		root = path.Clean(filepath.ToSlash(root))
+		if p == root || strings.HasPrefix(p, root+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,330 @@
+/**
+ * Backup & Restore Frontend Script
+ *
+ * Lists backups on the plugin page, with buttons to take one now, verify,
+ * download, delete and restore them, and to upload an archive.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'backup-restore';
+  const PLUGIN_NAME = 'Backup & Restore';
+  const PAGE_PATH = '/plugins/backup-restore';
+  const API_BASE = '/api/plugin/backup-restore';
+
+  let pollTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  function formatSize(bytes) {
+    if (bytes < 1024) return `${bytes} B`;
+    if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+    return `${(bytes / 1024 / 1024).toFixed(1)} MB`;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('backup-restore-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'backup-restore-styles';
+    style.textContent = `
+      .bkr-app { display: flex; flex-direction: column; gap: 1rem; }
+      .bkr-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .bkr-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .bkr-btn.danger { color: var(--error, #f38ba8); }
+      .bkr-btn.small { padding: 0.2rem 0.5rem; font-size: 0.75rem; }
+      .bkr-row { display: flex; gap: 0.75rem; align-items: center; flex-wrap: wrap; font-size: 0.85rem; color: var(--text-secondary, #a6adc8); }
+      .bkr-status {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.6rem 0.75rem;
+        font-size: 0.85rem;
+        color: var(--text-secondary, #a6adc8);
+      }
+      .bkr-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .bkr-table th, .bkr-table td {
+        text-align: left;
+        padding: 0.4rem 0.5rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-primary, #cdd6f4);
+      }
+      .bkr-table th { color: var(--text-secondary, #a6adc8); font-weight: 500; }
+      .bkr-tag { font-size: 0.7rem; padding: 0.05rem 0.35rem; border-radius: 4px; background: var(--bg-tertiary, #45475a); margin-right: 0.25rem; }
+      .bkr-ok { color: var(--success, #a6e3a1); }
+      .bkr-error { color: var(--error, #f38ba8); }
+      .bkr-muted { color: var(--text-muted, #6c7086); }
+      .bkr-empty { color: var(--text-muted, #6c7086); padding: 0.5rem 0; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderStatus(s) {
+    const parts = [];
+    if (s.running) parts.push(`<strong>A ${escapeHtml(s.running)} is running...</strong>`);
+    parts.push(`Last backup: ${s.last_backup ? escapeHtml(formatTime(s.last_backup.created_at)) : 'never'}`);
+    if (s.next_backup) parts.push(`Next: ${escapeHtml(formatTime(s.next_backup))}`);
+    parts.push(`Target: ${escapeHtml(s.target)}`);
+    if (s.last_error) parts.push(`<span class="bkr-error">Last backup failed: ${escapeHtml(s.last_error)}</span>`);
+    if (s.last_restore) {
+      parts.push(`Last restore: ${escapeHtml(s.last_restore.backup)} by ${escapeHtml(s.last_restore.actor)} at ${escapeHtml(formatTime(s.last_restore.time))}`);
+    }
+    return parts.join(' &middot; ');
+  }
+
+  function renderBackup(b) {
+    const name = escapeHtml(b.name);
+    let verified = '<span class="bkr-muted">not verified</span>';
+    if (b.verify_error) {
+      verified = `<span class="bkr-error" title="${escapeHtml(b.verify_error)}">failed</span>`;
+    } else if (b.verified_at && !b.verified_at.startsWith('0001-')) {
+      verified = `<span class="bkr-ok">ok ${escapeHtml(formatTime(b.verified_at))}</span>`;
+    }
+    return `
+      <tr>
+        <td>${name}<br><span class="bkr-muted">${escapeHtml(b.trigger)} by ${escapeHtml(b.created_by)}</span></td>
+        <td>${escapeHtml(formatTime(b.created_at))}</td>
+        <td>${b.files} files, ${escapeHtml(formatSize(b.size))}</td>
+        <td>${b.local ? '<span class="bkr-tag">local</span>' : ''}${b.remote ? '<span class="bkr-tag">S3</span>' : ''}</td>
+        <td>${verified}</td>
+        <td>
+          <button class="bkr-btn small" data-action="verify" data-name="${name}">Verify</button>
+          <button class="bkr-btn small" data-action="download" data-name="${name}">Download</button>
+          <button class="bkr-btn small" data-action="restore" data-name="${name}">Restore</button>
+          <button class="bkr-btn small danger" data-action="delete" data-name="${name}">Delete</button>
+        </td>
+      </tr>
+    `;
+  }
+
+  async function load(container) {
+    const status = container.querySelector('#bkr-status');
+    const list = container.querySelector('#bkr-list');
+    try {
+      const [s, data] = await Promise.all([api('GET', '/status'), api('GET', '/backups')]);
+      status.innerHTML = renderStatus(s);
+      container.querySelector('#bkr-create').disabled = !!s.running;
+      list.innerHTML = data.backups.length
+        ? `<table class="bkr-table">
+            <thead><tr><th>Backup</th><th>Created</th><th>Contents</th><th>Stored</th><th>Verified</th><th></th></tr></thead>
+            <tbody>${data.backups.map(renderBackup).join('')}</tbody>
+          </table>`
+        : '<div class="bkr-empty">No backups yet.</div>';
+
+      // Keep polling while a backup runs in the background
+      clearTimeout(pollTimer);
+      if (s.running) pollTimer = setTimeout(() => load(container), 2000);
+    } catch (e) {
+      status.innerHTML = `<span class="bkr-error">${escapeHtml(e.message)}</span>`;
+    }
+  }
+
+  async function download(name) {
+    const headers = getAuthHeaders();
+    delete headers['Content-Type'];
+    const res = await fetch(`${API_BASE}/backups/${encodeURIComponent(name)}/download`, { headers });
+    if (!res.ok) {
+      const data = await res.json().catch(() => ({}));
+      throw new Error(data.error || `Download failed with status ${res.status}`);
+    }
+    const url = URL.createObjectURL(await res.blob());
+    const a = document.createElement('a');
+    a.href = url;
+    a.download = name;
+    a.click();
+    setTimeout(() => URL.revokeObjectURL(url), 1000);
+  }
+
+  async function upload(file) {
+    const headers = getAuthHeaders();
+    headers['Content-Type'] = 'application/gzip';
+    const res = await fetch(`${API_BASE}/backups/upload?name=${encodeURIComponent(file.name)}`, { method: 'POST', headers, body: file });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Upload failed with status ${res.status}`);
+    }
+  }
+
+  async function restore(name) {
+    const paths = prompt('Paths to restore, comma separated (empty restores everything)', '');
+    if (paths === null) return;
+    const body = { paths: paths.split(',').map(p => p.trim()).filter(Boolean), dry_run: true };
+
+    const plan = await api('POST', `/backups/${encodeURIComponent(name)}/restore`, body);
+    const changed = plan.files.filter(f => f.changed);
+    let summary = `${plan.files.length} files in ${name}, ${changed.length} differ from the current ones.`;
+    if (plan.skipped.length) summary += `\n${plan.skipped.length} files are outside the included paths and will be skipped.`;
+    summary += '\n\nA backup of the current files is taken first if pre_restore_backup is on. Restore now?';
+    if (!confirm(summary)) return;
+
+    body.dry_run = false;
+    const result = await api('POST', `/backups/${encodeURIComponent(name)}/restore`, body);
+    let done = `Restored ${result.files.length} files.`;
+    if (result.pre_restore_backup) done += ` The previous files were saved as ${result.pre_restore_backup}.`;
+    if (result.restart_required) done += ' Restart the panel to load the restored settings.';
+    alert(done);
+  }
+
+  async function backupAction(container, btn) {
+    const name = btn.dataset.name;
+    const path = `/backups/${encodeURIComponent(name || '')}`;
+    switch (btn.dataset.action) {
+      case 'verify':
+        btn.disabled = true;
+        try {
+          await api('POST', `${path}/verify`);
+        } catch (e) {
+          // The failure is recorded on the backup and shown in the list
+        }
+        break;
+      case 'download':
+        await download(name);
+        return;
+      case 'restore':
+        await restore(name);
+        break;
+      case 'delete':
+        if (!confirm(`Delete ${name}? This removes the archive from every target.`)) return;
+        await api('DELETE', path);
+        break;
+      default:
+        return;
+    }
+    load(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="bkr-app" data-plugin="${PLUGIN_ID}">
+        <div class="bkr-row">
+          <button class="bkr-btn primary" id="bkr-create">Back up now</button>
+          <label class="bkr-btn">Upload archive<input type="file" id="bkr-upload" accept=".tar.gz,.gz" hidden></label>
+        </div>
+        <div class="bkr-status" id="bkr-status">Loading...</div>
+        <div id="bkr-list"><div class="bkr-empty">Loading...</div></div>
+      </div>
+    `;
+
+    container.querySelector('#bkr-create').addEventListener('click', async () => {
+      try {
+        await api('POST', '/backups');
+      } catch (err) {
+        alert(err.message);
+      }
+      load(container);
+    });
+
+    container.querySelector('#bkr-upload').addEventListener('change', async (e) => {
+      const file = e.target.files[0];
+      if (!file) return;
+      try {
+        await upload(file);
+      } catch (err) {
+        alert(err.message);
+      }
+      e.target.value = '';
+      load(container);
+    });
+
+    container.querySelector('#bkr-list').addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+      try {
+        await backupAction(container, btn);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    clearTimeout(pollTimer);
+    const style = document.getElementById('backup-restore-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
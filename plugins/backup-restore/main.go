@@ -0,0 +1,1095 @@
+// Backup & Restore Plugin for UnrealIRCd Web Panel
+// Bundles panel settings, plugin configuration and plugin data into
+// checksummed, versioned archives on a schedule, kept locally or in S3
+
+package backuprestore
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Backup targets
+const (
+	TargetLocal = "local"
+	TargetS3    = "s3"
+	TargetBoth  = "both"
+)
+
+// Backup triggers
+const (
+	TriggerManual     = "manual"
+	TriggerScheduled  = "scheduled"
+	TriggerPreRestore = "pre-restore"
+	TriggerUpload     = "upload"
+)
+
+// retryAfter is how long a failed scheduled backup waits before trying again
+const retryAfter = 15 * time.Minute
+
+// namePattern matches the archive names this plugin accepts
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*\.tar\.gz$`)
+
+// errBusy is returned while another backup or restore is running
+var errBusy = errors.New("a backup or restore is already running")
+
+// BackupRestorePlugin implements the Plugin interface
+type BackupRestorePlugin struct {
+	config      Config
+	backups     []*Backup
+	running     string
+	lastAttempt time.Time
+	lastError   string
+	lastRestore *RestoreResult
+	mu          sync.RWMutex
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	DataDir          string `json:"data_dir"`
+	IncludePaths     string `json:"include_paths"`
+	ExcludePatterns  string `json:"exclude_patterns"`
+	Target           string `json:"target"`
+	LocalPath        string `json:"local_path"`
+	S3Endpoint       string `json:"s3_endpoint"`
+	S3Region         string `json:"s3_region"`
+	S3Bucket         string `json:"s3_bucket"`
+	S3Prefix         string `json:"s3_prefix"`
+	S3AccessKey      string `json:"s3_access_key"`
+	S3SecretKey      string `json:"s3_secret_key"`
+	S3PathStyle      bool   `json:"s3_path_style"`
+	IntervalHours    int    `json:"interval_hours"`
+	KeepBackups      int    `json:"keep_backups"`
+	MaxSizeMB        int    `json:"max_size_mb"`
+	PreRestoreBackup bool   `json:"pre_restore_backup"`
+}
+
+// Backup is an archive in the backup index
+type Backup struct {
+	Name        string    `json:"name"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   string    `json:"created_by"`
+	Trigger     string    `json:"trigger"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	Files       int       `json:"files"`
+	Local       bool      `json:"local"`
+	Remote      bool      `json:"remote"`
+	VerifiedAt  time.Time `json:"verified_at,omitempty"`
+	VerifyError string    `json:"verify_error,omitempty"`
+}
+
+// RestoreRequest is the body of a restore request
+type RestoreRequest struct {
+	Paths  []string `json:"paths"`
+	DryRun bool     `json:"dry_run"`
+}
+
+// RestoreFile is a file a restore would write or has written
+type RestoreFile struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Exists  bool   `json:"exists"`
+	Changed bool   `json:"changed"`
+}
+
+// RestoreResult reports what a restore did, or would do for a dry run
+type RestoreResult struct {
+	Backup          string        `json:"backup"`
+	DryRun          bool          `json:"dry_run"`
+	Actor           string        `json:"actor"`
+	Time            time.Time     `json:"time"`
+	Files           []RestoreFile `json:"files"`
+	Skipped         []string      `json:"skipped"`
+	PreRestore      string        `json:"pre_restore_backup,omitempty"`
+	RestartRequired bool          `json:"restart_required"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &BackupRestorePlugin{
+		config: Config{
+			DataDir:          "data/plugins/backup-restore",
+			IncludePaths:     "config,data",
+			ExcludePatterns:  "*.tmp,*.lock,*.sock,*.pid",
+			Target:           TargetLocal,
+			LocalPath:        "backups",
+			S3Region:         "us-east-1",
+			S3Prefix:         "uwp-backups/",
+			S3PathStyle:      true,
+			IntervalHours:    24,
+			KeepBackups:      14,
+			MaxSizeMB:        512,
+			PreRestoreBackup: true,
+		},
+		backups: make([]*Backup, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *BackupRestorePlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Backup & Restore",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Scheduled, checksummed backups of panel and plugin data with restore",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *BackupRestorePlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.backups); err != nil {
+		log.Printf("[backup-restore] failed to load backup index: %v", err)
+	}
+	if p.backups == nil {
+		p.backups = make([]*Backup, 0)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "backup-restore-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"backups": len(p.backups),
+			"running": p.running,
+		}
+		if last := p.latest(); last != nil {
+			content["last_backup"] = last.CreatedAt
+			content["last_size"] = last.Size
+		}
+		if p.lastError != "" {
+			content["error"] = p.lastError
+		}
+		return plugins.DashboardCard{
+			Title:   "Backups",
+			Icon:    "Archive",
+			Content: content,
+			Order:   83,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.scheduleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *BackupRestorePlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *BackupRestorePlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/backup-restore")
+	{
+		plugin.GET("/backups", p.handleList)
+		plugin.POST("/backups", p.handleCreate)
+		plugin.POST("/backups/upload", p.handleUpload)
+		plugin.GET("/backups/:name", p.handleGet)
+		plugin.GET("/backups/:name/download", p.handleDownload)
+		plugin.POST("/backups/:name/verify", p.handleVerify)
+		plugin.POST("/backups/:name/restore", p.handleRestore)
+		plugin.DELETE("/backups/:name", p.handleDelete)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the backup index
+func (p *BackupRestorePlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "backups.json")
+}
+
+// save persists the backup index. Caller must hold p.mu.
+func (p *BackupRestorePlugin) save() {
+	if err := storage.SaveJSON(p.storePath(), p.backups); err != nil {
+		log.Printf("[backup-restore] failed to save backup index: %v", err)
+	}
+}
+
+// find returns a backup by name. Caller must hold p.mu.
+func (p *BackupRestorePlugin) find(name string) *Backup {
+	for _, b := range p.backups {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// latest returns the newest backup that wasn't taken before a restore.
+// Caller must hold p.mu.
+func (p *BackupRestorePlugin) latest() *Backup {
+	var last *Backup
+	for _, b := range p.backups {
+		if b.Trigger == TriggerPreRestore || b.Trigger == TriggerUpload {
+			continue
+		}
+		if last == nil || b.CreatedAt.After(last.CreatedAt) {
+			last = b
+		}
+	}
+	return last
+}
+
+// begin marks an operation as running, so backups and restores never
+// overlap
+func (p *BackupRestorePlugin) begin(what string) (Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running != "" {
+		return Config{}, errBusy
+	}
+	p.running = what
+	return p.config, nil
+}
+
+// end clears the running operation
+func (p *BackupRestorePlugin) end() {
+	p.mu.Lock()
+	p.running = ""
+	p.mu.Unlock()
+}
+
+// skipDirs are never backed up: the backups themselves and this plugin's
+// index, which a restore must not overwrite
+func skipDirs(cfg Config) []string {
+	return []string{cfg.LocalPath, cfg.DataDir}
+}
+
+// localFile returns where a backup is kept locally
+func localFile(cfg Config, name string) string {
+	return filepath.Join(cfg.LocalPath, name)
+}
+
+// wantsLocal reports whether backups are kept on disk
+func wantsLocal(cfg Config) bool {
+	return cfg.Target == TargetLocal || cfg.Target == TargetBoth
+}
+
+// wantsS3 reports whether backups are uploaded to S3
+func wantsS3(cfg Config) bool {
+	return cfg.Target == TargetS3 || cfg.Target == TargetBoth
+}
+
+// backup takes a backup with the given config. Retention never removes
+// the backup named by keep. The caller must have called begin.
+func (p *BackupRestorePlugin) backup(ctx context.Context, cfg Config, actor, trigger, keep string) (*Backup, error) {
+	now := time.Now().UTC()
+	include := lists.Split(cfg.IncludePaths)
+	files, err := collect(include, lists.Split(cfg.ExcludePatterns), skipDirs(cfg), int64(cfg.MaxSizeMB)<<20)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	m := &Manifest{
+		FormatVersion: formatVersion,
+		CreatedAt:     now,
+		CreatedBy:     actor,
+		Trigger:       trigger,
+		Hostname:      hostname,
+		Paths:         include,
+		Files:         files,
+	}
+
+	p.mu.RLock()
+	name := "backup-" + now.Format("20060102-150405") + ".tar.gz"
+	for i := 2; p.find(name) != nil; i++ {
+		name = fmt.Sprintf("backup-%s-%d.tar.gz", now.Format("20060102-150405"), i)
+	}
+	p.mu.RUnlock()
+
+	dir := cfg.LocalPath
+	if !wantsLocal(cfg) {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	file := filepath.Join(dir, name)
+	sum, size, err := writeArchive(file, m)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Backup{
+		Name:      name,
+		CreatedAt: now,
+		CreatedBy: actor,
+		Trigger:   trigger,
+		Size:      size,
+		SHA256:    sum,
+		Files:     len(files),
+		Local:     wantsLocal(cfg),
+	}
+	if b.Local {
+		if err := writeChecksum(file, name, sum); err != nil {
+			os.Remove(file)
+			return nil, err
+		}
+	}
+	if wantsS3(cfg) {
+		err := p.upload(ctx, cfg, file, name, sum)
+		if !b.Local {
+			os.Remove(file)
+		}
+		if err != nil {
+			if !b.Local {
+				return nil, err
+			}
+			// Keep the local copy, but say the upload failed
+			log.Printf("[backup-restore] failed to upload %s: %v", name, err)
+			b.VerifyError = "upload failed: " + err.Error()
+		} else {
+			b.Remote = true
+		}
+	}
+
+	p.mu.Lock()
+	p.backups = append(p.backups, b)
+	removed := p.prune(cfg, keep)
+	p.save()
+	p.mu.Unlock()
+
+	for _, old := range removed {
+		p.removeFiles(ctx, cfg, old)
+	}
+	log.Printf("[backup-restore] %s backup %s by %s: %d files, %d bytes", trigger, name, actor, len(files), size)
+	return b, nil
+}
+
+// writeChecksum writes a sha256sum compatible file next to an archive
+func writeChecksum(file, name, sum string) error {
+	return os.WriteFile(file+".sha256", []byte(sum+"  "+name+"\n"), 0o640)
+}
+
+// upload sends an archive and its checksum file to S3
+func (p *BackupRestorePlugin) upload(ctx context.Context, cfg Config, file, name, sum string) error {
+	s3, err := newS3Client(cfg)
+	if err != nil {
+		return err
+	}
+	if err := s3.Put(ctx, name, file); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp("", "backup-*.sha256")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(sum + "  " + name + "\n")
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return s3.Put(ctx, name+".sha256", tmp.Name())
+}
+
+// prune drops the oldest backups beyond keep_backups from the index, except
+// the one named keep, and returns them so their files can be removed.
+// Caller must hold p.mu.
+func (p *BackupRestorePlugin) prune(cfg Config, keep string) []*Backup {
+	if cfg.KeepBackups <= 0 || len(p.backups) <= cfg.KeepBackups {
+		return nil
+	}
+	sort.Slice(p.backups, func(i, j int) bool { return p.backups[i].CreatedAt.Before(p.backups[j].CreatedAt) })
+	n := len(p.backups) - cfg.KeepBackups
+	removed := make([]*Backup, 0, n)
+	kept := make([]*Backup, 0, cfg.KeepBackups+1)
+	for _, b := range p.backups {
+		if len(removed) < n && b.Name != keep {
+			removed = append(removed, b)
+		} else {
+			kept = append(kept, b)
+		}
+	}
+	p.backups = kept
+	return removed
+}
+
+// removeFiles deletes a backup's archive and checksum wherever they are
+func (p *BackupRestorePlugin) removeFiles(ctx context.Context, cfg Config, b *Backup) {
+	if b.Local {
+		file := localFile(cfg, b.Name)
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[backup-restore] failed to remove %s: %v", file, err)
+		}
+		os.Remove(file + ".sha256")
+	}
+	if b.Remote {
+		s3, err := newS3Client(cfg)
+		if err == nil {
+			err = s3.Delete(ctx, b.Name)
+		}
+		if err == nil {
+			err = s3.Delete(ctx, b.Name+".sha256")
+		}
+		if err != nil {
+			log.Printf("[backup-restore] failed to remove %s from S3: %v", b.Name, err)
+		}
+	}
+}
+
+// fetch returns a local path to a backup's archive, downloading it from
+// S3 if needed. The cleanup function removes any downloaded copy.
+func (p *BackupRestorePlugin) fetch(ctx context.Context, cfg Config, b *Backup) (string, func(), error) {
+	if b.Local {
+		file := localFile(cfg, b.Name)
+		if _, err := os.Stat(file); err == nil || !b.Remote {
+			return file, func() {}, err
+		}
+	}
+	s3, err := newS3Client(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	tmp, err := os.CreateTemp("", "restore-*.tar.gz")
+	if err != nil {
+		return "", nil, err
+	}
+	tmp.Close()
+	cleanup := func() { os.Remove(tmp.Name()) }
+	if err := s3.Get(ctx, b.Name, tmp.Name()); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tmp.Name(), cleanup, nil
+}
+
+// verify checks an archive against its recorded checksum and the
+// checksums of every file in it
+func verify(file string, b *Backup) (*Manifest, error) {
+	sum, err := fileSHA256(file)
+	if err != nil {
+		return nil, err
+	}
+	if sum != b.SHA256 {
+		return nil, fmt.Errorf("archive checksum mismatch: expected %s, got %s", b.SHA256, sum)
+	}
+	return readArchive(file, nil)
+}
+
+// restore writes files from a backup back into place. Every checksum is
+// checked before anything is written, and files are staged next to their
+// targets and only moved into place once all of them were extracted.
+func (p *BackupRestorePlugin) restore(ctx context.Context, cfg Config, b *Backup, req RestoreRequest, actor string) (*RestoreResult, error) {
+	file, cleanup, err := p.fetch(ctx, cfg, b)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	m, err := verify(file, b)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := lists.Split(cfg.IncludePaths)
+	skip := skipDirs(cfg)
+	result := &RestoreResult{
+		Backup:  b.Name,
+		DryRun:  req.DryRun,
+		Actor:   actor,
+		Time:    time.Now().UTC(),
+		Files:   make([]RestoreFile, 0),
+		Skipped: make([]string, 0),
+	}
+	selected := make(map[string]bool)
+	for _, fe := range m.Files {
+		if !selectedPath(fe.Path, req.Paths) {
+			continue
+		}
+		if !insideRoots(fe.Path, roots) || underAny(filepath.FromSlash(fe.Path), skip) {
+			result.Skipped = append(result.Skipped, fe.Path)
+			continue
+		}
+		rf := RestoreFile{Path: fe.Path, Size: fe.Size, Changed: true}
+		if sum, err := fileSHA256(filepath.FromSlash(fe.Path)); err == nil {
+			rf.Exists = true
+			rf.Changed = sum != fe.SHA256
+		}
+		result.Files = append(result.Files, rf)
+		selected[fe.Path] = true
+	}
+	if len(req.Paths) > 0 && len(selected) == 0 {
+		return nil, fmt.Errorf("none of the requested paths are in this backup")
+	}
+	if req.DryRun {
+		return result, nil
+	}
+
+	if cfg.PreRestoreBackup {
+		pre, err := p.backup(ctx, cfg, actor, TriggerPreRestore, b.Name)
+		if err != nil {
+			return nil, fmt.Errorf("pre-restore backup failed, nothing was restored: %w", err)
+		}
+		result.PreRestore = pre.Name
+	}
+
+	suffix := ".restore-" + newID()
+	staged := make([]string, 0, len(selected))
+	unstage := func() {
+		for _, target := range staged {
+			os.Remove(target + suffix)
+		}
+	}
+	_, err = readArchive(file, func(fe FileEntry, r io.Reader) error {
+		if !selected[fe.Path] {
+			return nil
+		}
+		target := filepath.FromSlash(fe.Path)
+		if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+			return err
+		}
+		mode := os.FileMode(fe.Mode) & 0o777
+		if mode == 0 {
+			mode = 0o640
+		}
+		out, err := os.OpenFile(target+suffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+		staged = append(staged, target)
+		if _, err := io.Copy(out, r); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+	if err != nil {
+		unstage()
+		return nil, err
+	}
+	for _, target := range staged {
+		if err := os.Rename(target+suffix, target); err != nil {
+			unstage()
+			return nil, fmt.Errorf("restore stopped part way, at %s: %w", target, err)
+		}
+	}
+	result.RestartRequired = true
+
+	log.Printf("[backup-restore] %s restored %d files from %s", actor, len(staged), b.Name)
+	return result, nil
+}
+
+// selectedPath reports whether an archive path was asked for. No paths
+// selects everything; a path selects itself and everything under it.
+func selectedPath(p string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, want := range paths {
+		want = strings.TrimSuffix(path.Clean(filepath.ToSlash(want)), "/")
+		if p == want || strings.HasPrefix(p, want+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleLoop takes a backup every interval_hours
+func (p *BackupRestorePlugin) scheduleLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.runScheduled(time.Now())
+		}
+	}
+}
+
+// runScheduled takes a scheduled backup if one is due
+func (p *BackupRestorePlugin) runScheduled(now time.Time) {
+	p.mu.Lock()
+	interval := time.Duration(p.config.IntervalHours) * time.Hour
+	due := interval > 0 && now.Sub(p.lastAttempt) >= retryAfter
+	if last := p.latest(); due && last != nil {
+		due = now.Sub(last.CreatedAt) >= interval
+	}
+	if due {
+		p.lastAttempt = now
+	}
+	p.mu.Unlock()
+	if !due {
+		return
+	}
+
+	cfg, err := p.begin("backup")
+	if err != nil {
+		return
+	}
+	defer p.end()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	_, err = p.backup(ctx, cfg, "system", TriggerScheduled, "")
+	p.setError(err)
+}
+
+// setError records the outcome of the last backup
+func (p *BackupRestorePlugin) setError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastError = ""
+	if err != nil {
+		p.lastError = err.Error()
+		log.Printf("[backup-restore] backup failed: %v", err)
+	}
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// backupFor looks up the backup named in the request, answering 404 if
+// there is none
+func (p *BackupRestorePlugin) backupFor(c *gin.Context) (Backup, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	b := p.find(c.Param("name"))
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return Backup{}, false
+	}
+	return *b, true
+}
+
+// handleList returns the backups, newest first
+func (p *BackupRestorePlugin) handleList(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := append([]*Backup(nil), p.backups...)
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{"backups": list, "count": len(list)})
+}
+
+// handleCreate starts a backup in the background
+func (p *BackupRestorePlugin) handleCreate(c *gin.Context) {
+	cfg, err := p.begin("backup")
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A backup or restore is already running"})
+		return
+	}
+	actor := access.Actor(c)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer p.end()
+		_, err := p.backup(context.Background(), cfg, actor, TriggerManual, "")
+		p.setError(err)
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Backup started"})
+}
+
+// handleUpload adds an archive sent as the request body, e.g. one
+// downloaded from another panel, after checking it
+func (p *BackupRestorePlugin) handleUpload(c *gin.Context) {
+	name := c.Query("name")
+	if name == "" {
+		name = "upload-" + time.Now().UTC().Format("20060102-150405") + ".tar.gz"
+	}
+	if !namePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must end in .tar.gz and contain only letters, digits, dots, dashes and underscores"})
+		return
+	}
+	cfg, err := p.begin("upload")
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A backup or restore is already running"})
+		return
+	}
+	defer p.end()
+
+	p.mu.RLock()
+	exists := p.find(name) != nil
+	p.mu.RUnlock()
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "A backup with this name already exists"})
+		return
+	}
+
+	if err := os.MkdirAll(cfg.LocalPath, 0o750); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	file := localFile(cfg, name)
+	tmp := file + ".upload"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	limit := int64(cfg.MaxSizeMB) << 20
+	n, err := io.Copy(out, io.LimitReader(c.Request.Body, limit+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > limit {
+		err = fmt.Errorf("archive is larger than %d MB", cfg.MaxSizeMB)
+	}
+	var m *Manifest
+	if err == nil {
+		m, err = readArchive(tmp, nil)
+	}
+	var sum string
+	if err == nil {
+		sum, err = fileSHA256(tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := writeChecksum(file, name, sum); err != nil {
+		log.Printf("[backup-restore] failed to write checksum for %s: %v", name, err)
+	}
+
+	b := &Backup{
+		Name:       name,
+		CreatedAt:  m.CreatedAt,
+		CreatedBy:  access.Actor(c),
+		Trigger:    TriggerUpload,
+		Size:       n,
+		SHA256:     sum,
+		Files:      len(m.Files),
+		Local:      true,
+		VerifiedAt: time.Now().UTC(),
+	}
+	p.mu.Lock()
+	p.backups = append(p.backups, b)
+	p.save()
+	p.mu.Unlock()
+
+	log.Printf("[backup-restore] %s uploaded %s (%d files)", b.CreatedBy, name, b.Files)
+	c.JSON(http.StatusCreated, b)
+}
+
+// handleGet returns a backup with the manifest of its archive
+func (p *BackupRestorePlugin) handleGet(c *gin.Context) {
+	b, ok := p.backupFor(c)
+	if !ok {
+		return
+	}
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	file, cleanup, err := p.fetch(c.Request.Context(), cfg, &b)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer cleanup()
+	m, err := readArchive(file, nil)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "backup": b})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"backup": b, "manifest": m})
+}
+
+// handleDownload sends a backup's archive
+func (p *BackupRestorePlugin) handleDownload(c *gin.Context) {
+	b, ok := p.backupFor(c)
+	if !ok {
+		return
+	}
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	file, cleanup, err := p.fetch(c.Request.Context(), cfg, &b)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer cleanup()
+
+	c.Header("X-Checksum-Sha256", b.SHA256)
+	c.FileAttachment(file, b.Name)
+}
+
+// handleVerify checks a backup's archive and every file in it against
+// their checksums
+func (p *BackupRestorePlugin) handleVerify(c *gin.Context) {
+	b, ok := p.backupFor(c)
+	if !ok {
+		return
+	}
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	file, cleanup, err := p.fetch(c.Request.Context(), cfg, &b)
+	if err == nil {
+		defer cleanup()
+		_, err = verify(file, &b)
+	}
+
+	p.mu.Lock()
+	if stored := p.find(b.Name); stored != nil {
+		stored.VerifiedAt = time.Now().UTC()
+		stored.VerifyError = ""
+		if err != nil {
+			stored.VerifyError = err.Error()
+		}
+		b = *stored
+		p.save()
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "backup": b})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup verified", "backup": b})
+}
+
+// handleRestore restores a backup, or reports what it would restore for a
+// dry run
+func (p *BackupRestorePlugin) handleRestore(c *gin.Context) {
+	var req RestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	b, ok := p.backupFor(c)
+	if !ok {
+		return
+	}
+	actor := access.Actor(c)
+
+	cfg, err := p.begin("restore")
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A backup or restore is already running"})
+		return
+	}
+	defer p.end()
+
+	result, err := p.restore(c.Request.Context(), cfg, &b, req, actor)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.DryRun {
+		p.mu.Lock()
+		p.lastRestore = result
+		p.mu.Unlock()
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handleDelete removes a backup from the index and deletes its files
+func (p *BackupRestorePlugin) handleDelete(c *gin.Context) {
+	p.mu.Lock()
+	if p.running != "" {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "A backup or restore is already running"})
+		return
+	}
+	var b *Backup
+	for i, item := range p.backups {
+		if item.Name == c.Param("name") {
+			b = item
+			p.backups = append(p.backups[:i], p.backups[i+1:]...)
+			break
+		}
+	}
+	if b == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return
+	}
+	cfg := p.config
+	p.save()
+	p.mu.Unlock()
+
+	p.removeFiles(c.Request.Context(), cfg, b)
+	log.Printf("[backup-restore] %s deleted %s", access.Actor(c), b.Name)
+	c.JSON(http.StatusOK, gin.H{"message": "Backup deleted"})
+}
+
+// handleStatus returns what is running, the outcome of the last backup and
+// restore, and when the next backup is due
+func (p *BackupRestorePlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"running":      p.running,
+		"last_error":   p.lastError,
+		"last_restore": p.lastRestore,
+		"target":       p.config.Target,
+	}
+	if last := p.latest(); last != nil {
+		status["last_backup"] = last
+		if p.config.IntervalHours > 0 {
+			status["next_backup"] = last.CreatedAt.Add(time.Duration(p.config.IntervalHours) * time.Hour)
+		}
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *BackupRestorePlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.S3SecretKey = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *BackupRestorePlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if newConfig.S3SecretKey == "" {
+		newConfig.S3SecretKey = p.config.S3SecretKey
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	if err := validateConfig(newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.config = newConfig
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// validateConfig checks the settings that would make every backup fail
+func validateConfig(cfg Config) error {
+	switch cfg.Target {
+	case TargetLocal, TargetBoth:
+		if cfg.LocalPath == "" {
+			return fmt.Errorf("local_path is required")
+		}
+	case TargetS3:
+	default:
+		return fmt.Errorf("target must be local, s3 or both")
+	}
+	if wantsS3(cfg) {
+		if _, err := newS3Client(cfg); err != nil {
+			return err
+		}
+	}
+	include := lists.Split(cfg.IncludePaths)
+	if len(include) == 0 {
+		return fmt.Errorf("include_paths needs at least one path")
+	}
+	for _, p := range include {
+		if !safePath(path.Clean(filepath.ToSlash(p))) {
+			return fmt.Errorf("include path %q must be relative to the panel directory", p)
+		}
+	}
+	for _, pat := range lists.Split(cfg.ExcludePatterns) {
+		if _, err := filepath.Match(pat, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q", pat)
+		}
+	}
+	if cfg.MaxSizeMB < 1 {
+		return fmt.Errorf("max_size_mb must be at least 1")
+	}
+	if cfg.IntervalHours < 0 || cfg.KeepBackups < 0 {
+		return fmt.Errorf("interval_hours and keep_backups cannot be negative")
+	}
+	return nil
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *BackupRestorePlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *BackupRestorePlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,224 @@
+/**
+ * Ban Sync Frontend Script
+ *
+ * Shows the global bans that only some servers have, lets them be
+ * re-propagated and lists earlier re-propagations.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'ban-sync';
+  const PLUGIN_NAME = 'Ban Sync';
+  const PAGE_PATH = '/plugins/ban-sync';
+  const API_BASE = '/api/plugin/ban-sync';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const res = await fetch(API_BASE + path, {
+      method,
+      headers: getAuthHeaders(),
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('ban-sync-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'ban-sync-styles';
+    style.textContent = `
+      .bsy-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .bsy-toolbar { display: flex; gap: 0.5rem; align-items: center; flex-wrap: wrap; }
+      .bsy-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .bsy-table th, .bsy-table td { text-align: left; padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .bsy-table td { color: var(--text-primary, #cdd6f4); word-break: break-all; }
+      .bsy-app button { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); border: none; border-radius: 6px; padding: 0.4rem 0.8rem; cursor: pointer; }
+      .bsy-app button:disabled { opacity: 0.5; cursor: default; }
+      .bsy-ok { color: var(--success, #a6e3a1); }
+      .bsy-missing, .bsy-error { color: var(--error, #f38ba8); }
+      .bsy-desc { font-size: 0.8rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderReport(data) {
+    const r = data.report;
+    if (!r) return `<div>${data.checking ? 'A check is running...' : 'No check has run yet.'}</div>`;
+    return `
+      <div>
+        Checked ${formatTime(r.time)}: ${r.bans} bans on ${r.compared} servers,
+        <strong class="${r.desynced.length ? 'bsy-missing' : 'bsy-ok'}">${r.desynced.length} missing from some servers</strong>
+      </div>
+      ${r.error ? `<div class="bsy-error">${escapeHtml(r.error)}</div>` : ''}
+      <table class="bsy-table">
+        <thead><tr><th><input type="checkbox" id="bsy-all"></th><th>Type</th><th>Ban</th><th>Reason</th><th>Set by</th><th>Present on</th><th>Missing on</th></tr></thead>
+        <tbody>
+          ${r.desynced.map(e => `
+            <tr>
+              <td><input type="checkbox" class="bsy-select" value="${escapeHtml(e.key)}"></td>
+              <td>${escapeHtml(e.type)}</td>
+              <td>${escapeHtml(e.name)}</td>
+              <td>${escapeHtml(e.reason)}</td>
+              <td>${escapeHtml(e.set_by)}</td>
+              <td>${e.present.map(escapeHtml).join('<br>')}</td>
+              <td class="bsy-missing">${e.missing.map(escapeHtml).join('<br>')}</td>
+            </tr>
+          `).join('') || '<tr><td colspan="7">Every server has the same bans</td></tr>'}
+        </tbody>
+      </table>
+      <h3>Servers</h3>
+      <table class="bsy-table">
+        <thead><tr><th>Server</th><th>Bans compared</th><th>Error</th></tr></thead>
+        <tbody>
+          ${r.servers.map(s => `<tr><td>${escapeHtml(s.name)}</td><td>${s.error ? '-' : s.bans}</td><td class="bsy-error">${escapeHtml(s.error || '')}</td></tr>`).join('')}
+        </tbody>
+      </table>
+    `;
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#bsy-body');
+    try {
+      const [data, actions] = await Promise.all([api('GET', '/report'), api('GET', '/actions')]);
+      body.innerHTML = `
+        ${renderReport(data)}
+        <h3>Re-propagations</h3>
+        <table class="bsy-table">
+          <thead><tr><th>Time</th><th>By</th><th>Type</th><th>Ban</th><th>Was missing on</th><th>Result</th></tr></thead>
+          <tbody>
+            ${actions.actions.slice(0, 100).map(a => `
+              <tr>
+                <td>${formatTime(a.time)}</td><td>${escapeHtml(a.actor)}</td><td>${escapeHtml(a.type)}</td><td>${escapeHtml(a.name)}</td>
+                <td>${a.missing.map(escapeHtml).join(', ')}</td>
+                <td class="${a.success ? 'bsy-ok' : 'bsy-error'}">${a.success ? 'Re-propagated' : escapeHtml(a.error)}</td>
+              </tr>
+            `).join('') || '<tr><td colspan="6">Nothing re-propagated yet</td></tr>'}
+          </tbody>
+        </table>
+      `;
+      const all = body.querySelector('#bsy-all');
+      if (all) {
+        all.addEventListener('change', () => {
+          body.querySelectorAll('.bsy-select').forEach(cb => { cb.checked = all.checked; });
+        });
+      }
+    } catch (e) {
+      body.innerHTML = `<div class="bsy-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function run(container, button, fn) {
+    button.disabled = true;
+    try {
+      await fn();
+    } catch (err) {
+      alert(err.message);
+    }
+    button.disabled = false;
+    load(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="bsy-app" data-plugin="${PLUGIN_ID}">
+        <div class="bsy-toolbar">
+          <button id="bsy-check">Check now</button>
+          <button id="bsy-reconcile">Re-propagate selected</button>
+          <span class="bsy-desc">Re-propagating removes the ban and sets it again from the panel's server, with the same reason, setter and time left.</span>
+        </div>
+        <div id="bsy-body">Loading...</div>
+      </div>
+    `;
+
+    const check = container.querySelector('#bsy-check');
+    check.addEventListener('click', () => run(container, check, () => api('POST', '/check')));
+
+    const reconcile = container.querySelector('#bsy-reconcile');
+    reconcile.addEventListener('click', () => {
+      const keys = Array.from(container.querySelectorAll('.bsy-select:checked')).map(cb => cb.value);
+      if (!keys.length) {
+        alert('Select the bans to re-propagate');
+        return;
+      }
+      if (!confirm(`Re-propagate ${keys.length} ban(s) to all servers?`)) return;
+      run(container, reconcile, async () => {
+        const res = await api('POST', '/reconcile', { keys });
+        if (res.failed) alert(`${res.failed} of ${res.actions.length} failed; see the list below`);
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('ban-sync-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,182 @@
+package bansync
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// The ban lists compared, by the RPC namespace that lists and sets them
+const (
+	KindServerBan = "server_ban"
+	KindException = "server_ban_exception"
+	KindNameBan   = "name_ban"
+)
+
+// kinds are the ban lists fetched from every server
+var kinds = []string{KindServerBan, KindException, KindNameBan}
+
+// banMethods are the RPC methods that list, remove and set each kind
+var banMethods = map[string]struct{ list, del, add string }{
+	KindServerBan: {"server_ban.list", "server_ban.del", "server_ban.add"},
+	KindException: {"server_ban_exception.list", "server_ban_exception.del", "server_ban_exception.add"},
+	KindNameBan:   {"name_ban.list", "name_ban.del", "name_ban.add"},
+}
+
+// rpcBan is a server ban, ban exception or name ban as the IRCd lists it
+type rpcBan struct {
+	Type           string `json:"type"`
+	Name           string `json:"name"`
+	Reason         string `json:"reason"`
+	SetBy          string `json:"set_by"`
+	SetAt          string `json:"set_at"`
+	ExpireAt       string `json:"expire_at"`
+	ExceptionTypes string `json:"exception_types"`
+	SetInConfig    bool   `json:"set_in_config"`
+}
+
+// key identifies a ban across servers
+func (b *rpcBan) key(kind string) string {
+	return kind + " " + b.Type + " " + strings.ToLower(b.Name)
+}
+
+// fromConfig reports whether the ban comes from a server's config file.
+// Those are set per server, so they are expected to differ.
+func (b *rpcBan) fromConfig() bool {
+	return b.SetInConfig || strings.HasPrefix(b.SetBy, "-config-")
+}
+
+// ServerState is how one server answered the ban list calls
+type ServerState struct {
+	Name  string `json:"name"`
+	Bans  int    `json:"bans"`
+	Error string `json:"error,omitempty"`
+}
+
+// Entry is a ban that is set on some servers only
+type Entry struct {
+	Key            string   `json:"key"`
+	Kind           string   `json:"kind"`
+	Type           string   `json:"type"`
+	Name           string   `json:"name"`
+	Reason         string   `json:"reason,omitempty"`
+	SetBy          string   `json:"set_by,omitempty"`
+	SetAt          string   `json:"set_at,omitempty"`
+	ExpireAt       string   `json:"expire_at,omitempty"`
+	ExceptionTypes string   `json:"exception_types,omitempty"`
+	Present        []string `json:"present"`
+	Missing        []string `json:"missing"`
+}
+
+// Report is the result of comparing the ban lists of all servers
+type Report struct {
+	Time     time.Time     `json:"time"`
+	Servers  []ServerState `json:"servers"`
+	Compared int           `json:"compared"`
+	Bans     int           `json:"bans"`
+	Desynced []*Entry      `json:"desynced"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// serverBans is one server's ban lists, keyed by ban
+type serverBans map[string]*Entry
+
+// add records a ban listed by a server. Bans from config files, of types
+// not compared, and bans set or expiring within grace of now are left
+// out, since they can't be expected on every server.
+func (sb serverBans) add(kind string, b *rpcBan, types []string, grace time.Duration, now time.Time) {
+	if b.fromConfig() || !lists.ContainsFold(types, b.Type) {
+		return
+	}
+	if t, err := time.Parse(time.RFC3339, b.SetAt); err == nil && t.After(now.Add(-grace)) {
+		return
+	}
+	if t, err := time.Parse(time.RFC3339, b.ExpireAt); err == nil && t.Before(now.Add(grace)) {
+		return
+	}
+	sb[b.key(kind)] = &Entry{
+		Key:            b.key(kind),
+		Kind:           kind,
+		Type:           b.Type,
+		Name:           b.Name,
+		Reason:         b.Reason,
+		SetBy:          b.SetBy,
+		SetAt:          b.SetAt,
+		ExpireAt:       b.ExpireAt,
+		ExceptionTypes: b.ExceptionTypes,
+	}
+}
+
+// compare finds the bans set on some of the servers only. Servers whose
+// lists couldn't be fetched are left out.
+func compare(report *Report, lists map[string]serverBans) {
+	names := make([]string, 0, len(lists))
+	for name := range lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	report.Compared = len(names)
+
+	all := make(map[string]*Entry)
+	for _, name := range names {
+		for key, e := range lists[name] {
+			if _, ok := all[key]; !ok {
+				all[key] = e
+			}
+		}
+	}
+	report.Bans = len(all)
+
+	report.Desynced = make([]*Entry, 0)
+	for key, e := range all {
+		entry := *e
+		entry.Present = make([]string, 0)
+		entry.Missing = make([]string, 0)
+		for _, name := range names {
+			if _, ok := lists[name][key]; ok {
+				entry.Present = append(entry.Present, name)
+			} else {
+				entry.Missing = append(entry.Missing, name)
+			}
+		}
+		if len(entry.Missing) > 0 {
+			report.Desynced = append(report.Desynced, &entry)
+		}
+	}
+	sort.Slice(report.Desynced, func(i, j int) bool {
+		return report.Desynced[i].Key < report.Desynced[j].Key
+	})
+}
+
+// durationString returns the time left on a ban as an UnrealIRCd duration,
+// "0" for a permanent ban, or "" if it has expired
+func durationString(expireAt string, now time.Time) string {
+	t, err := time.Parse(time.RFC3339, expireAt)
+	if err != nil {
+		return "0"
+	}
+	left := int64(t.Sub(now) / time.Second)
+	if left < 1 {
+		return ""
+	}
+	return formatSeconds(left)
+}
+
+// formatSeconds formats seconds as an UnrealIRCd duration such as 1d2h3m4s
+func formatSeconds(s int64) string {
+	var b strings.Builder
+	for _, u := range []struct {
+		n    int64
+		unit string
+	}{{86400, "d"}, {3600, "h"}, {60, "m"}, {1, "s"}} {
+		if s >= u.n {
+			b.WriteString(strconv.FormatInt(s/u.n, 10))
+			b.WriteString(u.unit)
+			s %= u.n
+		}
+	}
+	return b.String()
+}
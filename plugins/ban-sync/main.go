@@ -0,0 +1,526 @@
+// Ban Sync Plugin for UnrealIRCd Web Panel
+// Compares the global bans on every linked server, reports bans that
+// only some servers have and re-propagates them on request
+
+package bansync
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// rpcErrNotFound is the JSON-RPC error code for an unknown ban
+const rpcErrNotFound = -1000
+
+// BanSyncPlugin implements the Plugin interface
+type BanSyncPlugin struct {
+	config   Config
+	rpc      *jsonrpc.Client
+	report   *Report
+	actions  []*Action
+	checking bool
+	mu       sync.RWMutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	DataDir       string `json:"data_dir"`
+	BanTypes      string `json:"ban_types"`
+	SkipServices  bool   `json:"skip_services"`
+	CheckInterval int    `json:"check_interval"`
+	GraceSeconds  int    `json:"grace_seconds"`
+	MaxActions    int    `json:"max_actions"`
+}
+
+// Action is the re-propagation of one ban
+type Action struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Key     string    `json:"key"`
+	Kind    string    `json:"kind"`
+	Type    string    `json:"type"`
+	Name    string    `json:"name"`
+	Missing []string  `json:"missing"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined bool `json:"ulined"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &BanSyncPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/ban-sync",
+			BanTypes:      "gline,gzline,shun,qline,except",
+			SkipServices:  true,
+			CheckInterval: 15,
+			GraceSeconds:  120,
+			MaxActions:    500,
+		},
+		actions: make([]*Action, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *BanSyncPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Ban Sync",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Finds global bans missing from some servers and re-propagates them",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *BanSyncPlugin) Init() error {
+	p.mu.Lock()
+	var actions []*Action
+	if err := storage.LoadJSON(p.actionsPath(), &actions); err != nil {
+		log.Printf("[ban-sync] failed to load actions: %v", err)
+	}
+	if actions != nil {
+		p.actions = actions
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "ban-sync-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{"status": "Not checked yet"}
+		if r := p.report; r != nil {
+			content = map[string]interface{}{
+				"desynced": len(r.Desynced),
+				"servers":  r.Compared,
+				"bans":     r.Bans,
+				"checked":  r.Time,
+			}
+			if r.Error != "" {
+				content["status"] = r.Error
+			}
+		}
+		return plugins.DashboardCard{
+			Title:   "Ban Sync",
+			Icon:    "GitCompare",
+			Content: content,
+			Order:   95,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.checkLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *BanSyncPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *BanSyncPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/ban-sync")
+	{
+		plugin.GET("/report", p.handleReport)
+		plugin.POST("/check", p.handleCheck)
+		plugin.POST("/reconcile", p.handleReconcile)
+		plugin.GET("/actions", p.handleActions)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// actionsPath returns the file re-propagations are logged in
+func (p *BanSyncPlugin) actionsPath() string {
+	return filepath.Join(p.config.DataDir, "actions.json")
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *BanSyncPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// checkLoop compares the servers every check_interval minutes until
+// shutdown. An interval of 0 only checks on request.
+func (p *BanSyncPlugin) checkLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(time.Minute)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.CheckInterval) * time.Minute
+		p.mu.RUnlock()
+		if interval == 0 {
+			timer.Reset(time.Minute)
+			continue
+		}
+
+		p.runCheck()
+		timer.Reset(interval)
+	}
+}
+
+// runCheck compares the servers and keeps the report. It returns nil if
+// a check is already running.
+func (p *BanSyncPlugin) runCheck() *Report {
+	p.mu.Lock()
+	if p.checking {
+		p.mu.Unlock()
+		return nil
+	}
+	p.checking = true
+	cfg := p.config
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	r := p.check(ctx, cfg)
+
+	p.mu.Lock()
+	p.report = r
+	p.checking = false
+	p.mu.Unlock()
+	if len(r.Desynced) > 0 {
+		log.Printf("[ban-sync] %d bans are missing from some servers", len(r.Desynced))
+	}
+	return r
+}
+
+// check fetches the ban lists of every linked server and compares them.
+// A server that fails any of the list calls is left out of the
+// comparison, since its missing bans would all look desynced.
+func (p *BanSyncPlugin) check(ctx context.Context, cfg Config) *Report {
+	now := time.Now().UTC()
+	r := &Report{Time: now, Servers: make([]ServerState, 0), Desynced: make([]*Entry, 0)}
+	rpc := p.client()
+
+	var servers struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := rpc.Call(ctx, "server.list", nil, &servers); err != nil {
+		r.Error = "Failed to list servers: " + err.Error()
+		return r
+	}
+	sort.Slice(servers.List, func(i, j int) bool { return servers.List[i].Name < servers.List[j].Name })
+
+	types := lists.Split(cfg.BanTypes)
+	grace := time.Duration(cfg.GraceSeconds) * time.Second
+	lists := make(map[string]serverBans)
+	for _, s := range servers.List {
+		if s.Server.ULined && cfg.SkipServices {
+			continue
+		}
+		state := ServerState{Name: s.Name}
+		bans := make(serverBans)
+		for _, kind := range kinds {
+			var out struct {
+				List []rpcBan `json:"list"`
+			}
+			method := banMethods[kind].list
+			if err := rpc.Call(ctx, method, map[string]interface{}{"server": s.Name}, &out); err != nil {
+				state.Error = method + ": " + err.Error()
+				break
+			}
+			for i := range out.List {
+				bans.add(kind, &out.List[i], types, grace, now)
+			}
+		}
+		if state.Error == "" {
+			state.Bans = len(bans)
+			lists[s.Name] = bans
+		}
+		r.Servers = append(r.Servers, state)
+	}
+	compare(r, lists)
+	if r.Compared < 2 {
+		r.Error = "Fewer than two servers could be compared"
+	}
+	return r
+}
+
+// reconcile re-propagates a ban from the server the panel talks to. The
+// ban is removed and set again with its reason, setter and the time it
+// has left, which sends it to every server. If that server doesn't hold
+// the ban, removing it fails as not found and it is just set.
+func (p *BanSyncPlugin) reconcile(ctx context.Context, e *Entry) error {
+	duration := durationString(e.ExpireAt, time.Now())
+	if duration == "" {
+		return errors.New("the ban has expired")
+	}
+	rpc := p.client()
+
+	del := map[string]interface{}{"name": e.Name}
+	add := map[string]interface{}{
+		"name":            e.Name,
+		"reason":          e.Reason,
+		"set_by":          e.SetBy,
+		"duration_string": duration,
+	}
+	switch e.Kind {
+	case KindServerBan:
+		del["type"] = e.Type
+		add["type"] = e.Type
+	case KindException:
+		add["exception_types"] = e.ExceptionTypes
+	}
+
+	methods := banMethods[e.Kind]
+	err := rpc.Call(ctx, methods.del, del, nil)
+	var rerr *jsonrpc.Error
+	if err != nil && !(errors.As(err, &rerr) && rerr.Code == rpcErrNotFound) {
+		return err
+	}
+	return rpc.Call(ctx, methods.add, add, nil)
+}
+
+// handleReport returns the last comparison
+func (p *BanSyncPlugin) handleReport(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"report":   p.report,
+		"checking": p.checking,
+	})
+}
+
+// handleCheck compares the servers now
+func (p *BanSyncPlugin) handleCheck(c *gin.Context) {
+	r := p.runCheck()
+	if r == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A check is already running"})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// handleReconcile re-propagates the bans in keys, or every desynced ban
+// of the last report if keys is empty
+func (p *BanSyncPlugin) handleReconcile(c *gin.Context) {
+	var req struct {
+		Keys []string `json:"keys"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.RLock()
+	report := p.report
+	p.mu.RUnlock()
+	if report == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Run a check first"})
+		return
+	}
+
+	byKey := make(map[string]*Entry, len(report.Desynced))
+	for _, e := range report.Desynced {
+		byKey[e.Key] = e
+	}
+	entries := make([]*Entry, 0, len(req.Keys))
+	if len(req.Keys) == 0 {
+		entries = append(entries, report.Desynced...)
+	}
+	for _, key := range req.Keys {
+		e, ok := byKey[key]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not a desynced ban in the last report: " + key})
+			return
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No bans to reconcile"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+	actor := access.Actor(c)
+	actions := make([]*Action, 0, len(entries))
+	failed := 0
+	for _, e := range entries {
+		a := &Action{
+			ID:      newID(),
+			Time:    time.Now().UTC(),
+			Actor:   actor,
+			Key:     e.Key,
+			Kind:    e.Kind,
+			Type:    e.Type,
+			Name:    e.Name,
+			Missing: e.Missing,
+			Success: true,
+		}
+		if err := p.reconcile(ctx, e); err != nil {
+			a.Success = false
+			a.Error = err.Error()
+			failed++
+		}
+		actions = append(actions, a)
+		log.Printf("[ban-sync] %s re-propagated %s %s (missing on %s): %v", actor, e.Type, e.Name, strings.Join(e.Missing, ", "), a.Success)
+	}
+
+	p.mu.Lock()
+	p.actions = append(p.actions, actions...)
+	if len(p.actions) > p.config.MaxActions {
+		p.actions = p.actions[len(p.actions)-p.config.MaxActions:]
+	}
+	err := storage.SaveJSON(p.actionsPath(), p.actions)
+	p.mu.Unlock()
+	if err != nil {
+		log.Printf("[ban-sync] failed to save actions: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"actions": actions,
+		"failed":  failed,
+	})
+}
+
+// handleActions returns the logged re-propagations, newest first
+func (p *BanSyncPlugin) handleActions(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Action, 0, len(p.actions))
+	for i := len(p.actions) - 1; i >= 0; i-- {
+		list = append(list, p.actions[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"actions": list})
+}
+
+// handleGetConfig returns the current configuration
+func (p *BanSyncPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *BanSyncPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if len(lists.Split(newConfig.BanTypes)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ban_types must list at least one ban type"})
+		return
+	}
+	if newConfig.CheckInterval < 0 || newConfig.CheckInterval > 1440 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check_interval must be between 0 and 1440 minutes"})
+		return
+	}
+	if newConfig.GraceSeconds < 0 || newConfig.GraceSeconds > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "grace_seconds must be between 0 and 3600"})
+		return
+	}
+	if newConfig.MaxActions < 10 || newConfig.MaxActions > 10000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_actions must be between 10 and 10000"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *BanSyncPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *BanSyncPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,662 @@
+// Bot Heuristics Plugin for UnrealIRCd Web Panel
+// Scores connected users on drone-like behaviour and exposes a ranked
+// list of suspects with an explanation for every score component
+
+package botheuristics
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+)
+
+// BotHeuristicsPlugin implements the Plugin interface
+type BotHeuristicsPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	state     map[string]*userState
+	suspects  []Suspect
+	lastScan  time.Time
+	lastError string
+	// streamOK is set while join and part events arrive from the log
+	// stream. Without it they are inferred from the channel lists of
+	// successive scans.
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL            string `json:"rpc_url"`
+	RPCUser           string `json:"rpc_user"`
+	RPCPassword       string `json:"rpc_password"`
+	RPCInsecure       bool   `json:"rpc_insecure"`
+	StreamURL         string `json:"stream_url"`
+	ScanInterval      int    `json:"scan_interval"`
+	MinScore          int    `json:"min_score"`
+	DatacenterASNs    string `json:"datacenter_asns"`
+	RealnameCluster   int    `json:"realname_cluster"`
+	JoinFloodChannels int    `json:"join_flood_channels"`
+	JoinFloodWindow   int    `json:"join_flood_window"`
+	InstantPartWindow int    `json:"instant_part_window"`
+	WeightJoinFlood   int    `json:"weight_join_flood"`
+	WeightRealname    int    `json:"weight_realname"`
+	WeightDatacenter  int    `json:"weight_datacenter"`
+	WeightNoSASL      int    `json:"weight_no_sasl"`
+	WeightInstantPart int    `json:"weight_instant_part"`
+}
+
+// ScoreComponent is a single heuristic that contributed to a score
+type ScoreComponent struct {
+	Heuristic string `json:"heuristic"`
+	Points    int    `json:"points"`
+	Reason    string `json:"reason"`
+}
+
+// Suspect is a scored user
+type Suspect struct {
+	Nick           string           `json:"nick"`
+	ID             string           `json:"id"`
+	Host           string           `json:"host"`
+	IP             string           `json:"ip"`
+	Realname       string           `json:"realname"`
+	Account        string           `json:"account,omitempty"`
+	ASName         string           `json:"asname,omitempty"`
+	ConnectedSince time.Time        `json:"connected_since"`
+	Score          int              `json:"score"`
+	Components     []ScoreComponent `json:"components"`
+}
+
+// userState tracks what we have observed about a user between scans
+type userState struct {
+	connected    time.Time
+	channels     map[string]time.Time
+	earlyJoins   int
+	instantParts int
+	// updated is when the state last changed, so users seen only in
+	// events since the last scan started are not forgotten
+	updated time.Time
+}
+
+// joinPartEntry is the subset of a join or part log entry we need
+type joinPartEntry struct {
+	Client struct {
+		ID             string `json:"id"`
+		ConnectedSince string `json:"connected_since"`
+	} `json:"client"`
+	Channel json.RawMessage `json:"channel"`
+}
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	Name           string `json:"name"`
+	ID             string `json:"id"`
+	Hostname       string `json:"hostname"`
+	IP             string `json:"ip"`
+	ConnectedSince string `json:"connected_since"`
+	GeoIP          struct {
+		ASN    json.Number `json:"asn"`
+		ASName string      `json:"asname"`
+	} `json:"geoip"`
+	User struct {
+		Realname string `json:"realname"`
+		Account  string `json:"account"`
+		Channels []struct {
+			Name string `json:"name"`
+		} `json:"channels"`
+	} `json:"user"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &BotHeuristicsPlugin{
+		config: Config{
+			RPCURL:            "https://127.0.0.1:8600/api",
+			StreamURL:         "wss://127.0.0.1:8600/",
+			ScanInterval:      60,
+			MinScore:          40,
+			DatacenterASNs:    "amazon,google,microsoft,digitalocean,ovh,hetzner,linode,akamai,vultr,choopa,contabo,leaseweb",
+			RealnameCluster:   3,
+			JoinFloodChannels: 5,
+			JoinFloodWindow:   30,
+			InstantPartWindow: 10,
+			WeightJoinFlood:   25,
+			WeightRealname:    20,
+			WeightDatacenter:  25,
+			WeightNoSASL:      10,
+			WeightInstantPart: 20,
+		},
+		state: make(map[string]*userState),
+	}
+}
+
+// Info returns plugin metadata
+func (p *BotHeuristicsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Bot Heuristics",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Scores users on drone-like behaviour and lists suspects",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *BotHeuristicsPlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "bot-heuristics-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		flagged := 0
+		for _, s := range p.suspects {
+			if s.Score >= p.config.MinScore {
+				flagged++
+			}
+		}
+		content := map[string]interface{}{
+			"flagged":   flagged,
+			"threshold": p.config.MinScore,
+			"last_scan": p.lastScan,
+			"streaming": p.streamOK,
+		}
+		if flagged > 0 {
+			content["top_suspect"] = p.suspects[0].Nick
+			content["top_score"] = p.suspects[0].Score
+		}
+		return plugins.DashboardCard{
+			Title:   "Bot Suspects",
+			Icon:    "Bot",
+			Content: content,
+			Order:   60,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.scanLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *BotHeuristicsPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *BotHeuristicsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/bot-heuristics")
+	{
+		plugin.GET("/suspects", p.handleGetSuspects)
+		plugin.GET("/suspects/:nick", p.handleGetSuspect)
+		plugin.POST("/scan", p.handleScan)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// scanLoop periodically rescores the user list until shutdown
+func (p *BotHeuristicsPlugin) scanLoop() {
+	defer p.wg.Done()
+
+	p.scan()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.ScanInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 10*time.Second {
+			interval = 10 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.scan()
+		}
+	}
+}
+
+// streamLoop follows joins and parts in the IRCd log until shutdown
+func (p *BotHeuristicsPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, []string{"join", "part"})
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *BotHeuristicsPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[bot-heuristics] log stream: %v", err)
+	}
+}
+
+// handleEvent records a join or part at the time the IRCd logged it
+func (p *BotHeuristicsPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	join := strings.HasSuffix(ev.EventID, "CLIENT_JOIN")
+	if !join && !strings.HasSuffix(ev.EventID, "CLIENT_PART") {
+		return
+	}
+	var entry joinPartEntry
+	if err := json.Unmarshal(ev.Raw, &entry); err != nil || entry.Client.ID == "" {
+		return
+	}
+	channel := channelName(entry.Channel)
+	if channel == "" {
+		return
+	}
+	at, err := time.Parse(time.RFC3339Nano, ev.Timestamp)
+	if err != nil {
+		at = time.Now()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := p.userState(entry.Client.ID)
+	if connected, err := time.Parse(time.RFC3339, entry.Client.ConnectedSince); err == nil {
+		st.connected = connected
+	}
+	st.updated = at
+	if join {
+		p.joined(st, channel, at)
+	} else {
+		p.parted(st, channel, at)
+	}
+}
+
+// channelName returns the name of the channel in a log entry, which is
+// logged either as an object or as a plain name
+func channelName(raw json.RawMessage) string {
+	var ch struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(raw, &ch) == nil && ch.Name != "" {
+		return ch.Name
+	}
+	var name string
+	_ = json.Unmarshal(raw, &name)
+	return name
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *BotHeuristicsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// scan fetches the user list and recomputes every score
+func (p *BotHeuristicsPlugin) scan() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	started := time.Now()
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 4}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		return
+	}
+	p.lastError = ""
+	p.lastScan = time.Now()
+	p.suspects = p.score(result.List, started)
+}
+
+// score applies every heuristic to the user list fetched at now. Caller
+// must hold p.mu.
+func (p *BotHeuristicsPlugin) score(users []rpcUser, now time.Time) []Suspect {
+	realnames := make(map[string]int)
+	for _, u := range users {
+		if u.User.Realname != "" {
+			realnames[u.User.Realname]++
+		}
+	}
+
+	keywords := splitList(p.config.DatacenterASNs)
+	seen := make(map[string]bool, len(users))
+	suspects := make([]Suspect, 0)
+
+	for _, u := range users {
+		seen[u.ID] = true
+		connected, _ := time.Parse(time.RFC3339, u.ConnectedSince)
+		st := p.observe(u, connected, now)
+
+		s := Suspect{
+			Nick:           u.Name,
+			ID:             u.ID,
+			Host:           u.Hostname,
+			IP:             u.IP,
+			Realname:       u.User.Realname,
+			Account:        u.User.Account,
+			ASName:         u.GeoIP.ASName,
+			ConnectedSince: connected,
+			Components:     make([]ScoreComponent, 0),
+		}
+
+		if st.earlyJoins >= p.config.JoinFloodChannels {
+			s.add("join_flood", p.config.WeightJoinFlood,
+				fmt.Sprintf("joined %d channels within %ds of connecting", st.earlyJoins, p.config.JoinFloodWindow))
+		}
+		if n := realnames[u.User.Realname]; u.User.Realname != "" && n >= p.config.RealnameCluster {
+			s.add("identical_realname", p.config.WeightRealname,
+				fmt.Sprintf("realname %q is shared by %d connected users", u.User.Realname, n))
+		}
+		if match := matchDatacenter(u, keywords); match != "" {
+			s.add("datacenter_asn", p.config.WeightDatacenter,
+				fmt.Sprintf("connecting from hosting network AS%s (%s), matched %q", u.GeoIP.ASN, u.GeoIP.ASName, match))
+		}
+		if u.User.Account == "" {
+			s.add("no_sasl", p.config.WeightNoSASL, "not authenticated to an account")
+		}
+		if st.instantParts > 0 {
+			s.add("instant_part", p.config.WeightInstantPart,
+				fmt.Sprintf("left %d channel(s) within %ds of joining", st.instantParts, p.config.InstantPartWindow))
+		}
+
+		if s.Score > 100 {
+			s.Score = 100
+		}
+		if s.Score > 0 {
+			suspects = append(suspects, s)
+		}
+	}
+
+	// Forget users that have disconnected, but not those that connected
+	// after the list was fetched
+	for id, st := range p.state {
+		if !seen[id] && st.updated.Before(now) {
+			delete(p.state, id)
+		}
+	}
+
+	sort.Slice(suspects, func(i, j int) bool {
+		if suspects[i].Score != suspects[j].Score {
+			return suspects[i].Score > suspects[j].Score
+		}
+		return suspects[i].Nick < suspects[j].Nick
+	})
+	return suspects
+}
+
+// userState returns the tracked state of a user, creating it if needed.
+// Caller must hold p.mu.
+func (p *BotHeuristicsPlugin) userState(id string) *userState {
+	st, ok := p.state[id]
+	if !ok {
+		st = &userState{channels: make(map[string]time.Time)}
+		p.state[id] = st
+	}
+	return st
+}
+
+// joined records that a user joined a channel at the given time. Caller
+// must hold p.mu.
+func (p *BotHeuristicsPlugin) joined(st *userState, channel string, at time.Time) {
+	if _, known := st.channels[channel]; known {
+		return
+	}
+	st.channels[channel] = at
+	if !st.connected.IsZero() && at.Sub(st.connected) <= time.Duration(p.config.JoinFloodWindow)*time.Second {
+		st.earlyJoins++
+	}
+}
+
+// parted records that a user left a channel at the given time. Caller must
+// hold p.mu.
+func (p *BotHeuristicsPlugin) parted(st *userState, channel string, at time.Time) {
+	joined, known := st.channels[channel]
+	if !known {
+		return
+	}
+	if at.Sub(joined) <= time.Duration(p.config.InstantPartWindow)*time.Second {
+		st.instantParts++
+	}
+	delete(st.channels, channel)
+}
+
+// observe updates the tracked state of a user from a scan. While the log
+// stream is up joins and parts come from it; otherwise they are inferred
+// from the channels that appeared or vanished since the last scan, which
+// cannot see a join and part that both fall between two scans. Caller must
+// hold p.mu.
+func (p *BotHeuristicsPlugin) observe(u rpcUser, connected, now time.Time) *userState {
+	st := p.userState(u.ID)
+	if !connected.IsZero() {
+		st.connected = connected
+	}
+	if p.streamOK {
+		return st
+	}
+
+	current := make(map[string]bool, len(u.User.Channels))
+	for _, ch := range u.User.Channels {
+		current[ch.Name] = true
+		p.joined(st, ch.Name, now)
+	}
+	for name := range st.channels {
+		if !current[name] {
+			p.parted(st, name, now)
+		}
+	}
+	return st
+}
+
+// add records a score component
+func (s *Suspect) add(heuristic string, points int, reason string) {
+	if points <= 0 {
+		return
+	}
+	s.Score += points
+	s.Components = append(s.Components, ScoreComponent{
+		Heuristic: heuristic,
+		Points:    points,
+		Reason:    reason,
+	})
+}
+
+// matchDatacenter returns the keyword matching the user's ASN, if any
+func matchDatacenter(u rpcUser, keywords []string) string {
+	asname := strings.ToLower(u.GeoIP.ASName)
+	asn := u.GeoIP.ASN.String()
+	for _, kw := range keywords {
+		if asn != "" && (kw == asn || kw == "as"+asn) {
+			return kw
+		}
+		if asname != "" && strings.Contains(asname, kw) {
+			return kw
+		}
+	}
+	return ""
+}
+
+// splitList parses a comma separated, case-insensitive list
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// handleGetSuspects returns the ranked suspect list
+func (p *BotHeuristicsPlugin) handleGetSuspects(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	minScore := p.config.MinScore
+	if v, err := strconv.Atoi(c.Query("min_score")); err == nil {
+		minScore = v
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	list := make([]Suspect, 0)
+	for _, s := range p.suspects {
+		if s.Score < minScore {
+			break
+		}
+		if limit > 0 && len(list) >= limit {
+			break
+		}
+		list = append(list, s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suspects":   list,
+		"count":      len(list),
+		"min_score":  minScore,
+		"last_scan":  p.lastScan,
+		"last_error": p.lastError,
+		"streaming":  p.streamOK,
+	})
+}
+
+// handleGetSuspect returns the score breakdown for a single nick
+func (p *BotHeuristicsPlugin) handleGetSuspect(c *gin.Context) {
+	nick := c.Param("nick")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, s := range p.suspects {
+		if strings.EqualFold(s.Nick, nick) {
+			c.JSON(http.StatusOK, s)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "User has no heuristic score"})
+}
+
+// handleScan triggers an immediate rescan
+func (p *BotHeuristicsPlugin) handleScan(c *gin.Context) {
+	p.scan()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.lastError != "" {
+		c.JSON(http.StatusBadGateway, gin.H{"error": p.lastError})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Scan complete",
+		"scored":    len(p.suspects),
+		"last_scan": p.lastScan,
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *BotHeuristicsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *BotHeuristicsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *BotHeuristicsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *BotHeuristicsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
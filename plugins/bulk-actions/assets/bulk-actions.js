@@ -0,0 +1,436 @@
+/**
+ * Bulk Actions Frontend Script
+ *
+ * Uploads CSV or JSON lists of targets, shows the preview of affected
+ * users, runs batches with progress and downloads their reports.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'bulk-actions';
+  const PLUGIN_NAME = 'Bulk Actions';
+  const PAGE_PATH = '/plugins/bulk-actions';
+  const API_BASE = '/api/plugin/bulk-actions';
+
+  let pollTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body, contentType) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body !== undefined) {
+      options.body = contentType ? body : JSON.stringify(body);
+      if (contentType) options.headers['Content-Type'] = contentType;
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('bulk-actions-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'bulk-actions-styles';
+    style.textContent = `
+      .bka-app { display: flex; flex-direction: column; gap: 1rem; }
+      .bka-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .bka-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .bka-btn.danger { background: var(--error, #f38ba8); color: #fff; }
+      .bka-form { display: grid; grid-template-columns: repeat(auto-fit, minmax(180px, 1fr)); gap: 0.5rem; align-items: end; }
+      .bka-form label { display: flex; flex-direction: column; gap: 0.25rem; font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .bka-form input, .bka-form select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .bka-panel {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 1rem;
+      }
+      .bka-stats { display: flex; gap: 1.5rem; flex-wrap: wrap; margin-bottom: 0.75rem; }
+      .bka-stats div { font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .bka-stats strong { display: block; font-size: 1.2rem; color: var(--text-primary, #cdd6f4); }
+      .bka-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .bka-table th, .bka-table td {
+        text-align: left;
+        padding: 0.4rem 0.5rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+        vertical-align: top;
+      }
+      .bka-table th { color: var(--text-primary, #cdd6f4); }
+      .bka-scroll { max-height: 24rem; overflow-y: auto; }
+      .bka-status { font-weight: 600; }
+      .bka-status.ok, .bka-status.done { color: var(--success, #a6e3a1); }
+      .bka-status.failed, .bka-status.invalid { color: var(--error, #f38ba8); }
+      .bka-status.running, .bka-status.preview { color: var(--accent, #89b4fa); }
+      .bka-status.skipped, .bka-status.cancelled, .bka-status.interrupted { color: var(--warning, #f9e2af); }
+      .bka-actions { display: flex; gap: 0.5rem; flex-wrap: wrap; margin-top: 0.75rem; }
+      .bka-muted { color: var(--text-muted, #6c7086); }
+      .bka-error { color: var(--error, #f38ba8); }
+      .bka-empty { color: var(--text-muted, #6c7086); padding: 1rem 0; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderStats(b) {
+    const stats = [
+      ['Targets', b.total],
+      ['Invalid', b.invalid],
+      ['Affected users', b.affected_users],
+      ['Succeeded', b.succeeded],
+      ['Failed', b.failed],
+      ['Skipped', b.skipped]
+    ];
+    return `<div class="bka-stats">${stats.map(([label, n]) => `<div><strong>${n}</strong>${label}</div>`).join('')}</div>`;
+  }
+
+  function renderEntries(entries) {
+    return `
+      <div class="bka-scroll">
+        <table class="bka-table">
+          <thead><tr><th>Line</th><th>Target</th><th>Reason</th><th>Affected users</th><th>Status</th></tr></thead>
+          <tbody>${entries.map(e => `
+            <tr>
+              <td>${e.line}</td>
+              <td>${escapeHtml(e.target || e.input)}</td>
+              <td>${escapeHtml(e.reason)}${e.duration ? ` <span class="bka-muted">(${escapeHtml(e.duration)})</span>` : ''}</td>
+              <td>${e.matches}${e.users && e.users.length ? ` <span class="bka-muted">${e.users.map(escapeHtml).join(', ')}${e.matches > e.users.length ? ', ...' : ''}</span>` : ''}</td>
+              <td><span class="bka-status ${escapeHtml(e.status)}">${escapeHtml(e.status)}</span>
+                ${e.error ? `<br><small class="bka-error">${escapeHtml(e.error)}</small>` : ''}
+                ${e.result ? `<br><small class="bka-muted">${escapeHtml(e.result)}</small>` : ''}</td>
+            </tr>
+          `).join('')}</tbody>
+        </table>
+      </div>
+    `;
+  }
+
+  async function showBatch(container, id) {
+    clearTimeout(pollTimer);
+    const panel = container.querySelector('#bka-batch');
+
+    let b;
+    try {
+      b = await api('GET', `/batches/${encodeURIComponent(id)}`);
+    } catch (e) {
+      panel.innerHTML = `<div class="bka-error">${escapeHtml(e.message)}</div>`;
+      return;
+    }
+
+    let actions = '';
+    if (b.status === 'preview') {
+      actions += `<button class="bka-btn danger" data-action="execute" data-token="${escapeHtml(b.preview_token)}">Run ${escapeHtml(b.action)} on ${b.total - b.invalid} targets</button>`;
+      actions += ' <button class="bka-btn" data-action="preview">Refresh preview</button>';
+    } else if (b.status === 'running') {
+      actions += '<button class="bka-btn" data-action="cancel">Cancel</button>';
+    } else if (b.status === 'cancelled' || b.status === 'interrupted') {
+      actions += '<button class="bka-btn" data-action="preview">Preview remaining targets</button>';
+    }
+    actions += ' <button class="bka-btn" data-action="report" data-format="csv">Download CSV report</button>';
+    actions += ' <button class="bka-btn" data-action="report" data-format="json">Download JSON report</button>';
+
+    panel.dataset.id = b.id;
+    panel.innerHTML = `
+      <div class="bka-panel">
+        <h3>${escapeHtml(b.name)} <span class="bka-status ${escapeHtml(b.status)}">${escapeHtml(b.status)}</span></h3>
+        <p class="bka-muted">${escapeHtml(b.action)}${b.duration ? ' for ' + escapeHtml(b.duration) : ''}, uploaded by ${escapeHtml(b.created_by)} at ${escapeHtml(formatTime(b.created_at))}${b.status === 'preview' ? `, previewed at ${escapeHtml(formatTime(b.previewed_at))}` : ''}</p>
+        ${renderStats(b)}
+        ${renderEntries(b.entries || [])}
+        <div class="bka-actions">${actions}</div>
+      </div>
+    `;
+
+    if (b.status === 'running') {
+      pollTimer = setTimeout(() => {
+        showBatch(container, id);
+        loadBatches(container);
+      }, 2000);
+    }
+  }
+
+  async function downloadReport(id, format) {
+    const res = await fetch(`${API_BASE}/batches/${encodeURIComponent(id)}/report?format=${format}`, { headers: getAuthHeaders() });
+    if (!res.ok) {
+      alert(`Download failed with status ${res.status}`);
+      return;
+    }
+    const url = URL.createObjectURL(await res.blob());
+    const a = document.createElement('a');
+    a.href = url;
+    a.download = `bulk-${id}.${format}`;
+    a.click();
+    URL.revokeObjectURL(url);
+  }
+
+  async function loadBatches(container) {
+    const list = container.querySelector('#bka-list');
+
+    try {
+      const data = await api('GET', '/batches');
+      if (!data.batches.length) {
+        list.innerHTML = '<div class="bka-empty">No batches yet.</div>';
+        return;
+      }
+      list.innerHTML = `
+        <table class="bka-table">
+          <thead><tr><th>Batch</th><th>Action</th><th>Targets</th><th>Status</th><th>Uploaded</th></tr></thead>
+          <tbody>${data.batches.map(b => `
+            <tr>
+              <td><a href="#" data-id="${escapeHtml(b.id)}">${escapeHtml(b.name)}</a></td>
+              <td>${escapeHtml(b.action)}</td>
+              <td>${b.total - b.invalid} valid, ${b.invalid} invalid</td>
+              <td><span class="bka-status ${escapeHtml(b.status)}">${escapeHtml(b.status)}</span>${b.status !== 'preview' ? ` <small class="bka-muted">${b.succeeded} ok, ${b.failed} failed</small>` : ''}</td>
+              <td>${escapeHtml(formatTime(b.created_at))}<br><small class="bka-muted">${escapeHtml(b.created_by)}</small></td>
+            </tr>
+          `).join('')}</tbody>
+        </table>
+      `;
+    } catch (e) {
+      list.innerHTML = `<div class="bka-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadWatchlist(container) {
+    const panel = container.querySelector('#bka-watchlist');
+
+    try {
+      const data = await api('GET', '/watchlist');
+      if (!data.watchlist.length) {
+        panel.innerHTML = '<div class="bka-empty">The watchlist is empty.</div>';
+        return;
+      }
+      panel.innerHTML = `
+        <div class="bka-scroll">
+          <table class="bka-table">
+            <thead><tr><th>Mask</th><th>Reason</th><th>Added</th><th></th></tr></thead>
+            <tbody>${data.watchlist.map(w => `
+              <tr>
+                <td>${escapeHtml(w.mask)}</td>
+                <td>${escapeHtml(w.reason)}</td>
+                <td>${escapeHtml(formatTime(w.added_at))}<br><small class="bka-muted">${escapeHtml(w.added_by)}</small></td>
+                <td><button class="bka-btn" data-unwatch="${escapeHtml(w.id)}">Remove</button></td>
+              </tr>
+            `).join('')}</tbody>
+          </table>
+        </div>
+        <div class="bka-actions"><button class="bka-btn" data-action="online">Show watched users online</button></div>
+        <div id="bka-online"></div>
+      `;
+    } catch (e) {
+      panel.innerHTML = `<div class="bka-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadOnline(container) {
+    const out = container.querySelector('#bka-online');
+    out.innerHTML = '<div class="bka-empty">Loading...</div>';
+    try {
+      const data = await api('GET', '/watchlist/online');
+      out.innerHTML = data.users.length ? `
+        <table class="bka-table">
+          <thead><tr><th>Nick</th><th>Host</th><th>Server</th><th>Matched</th></tr></thead>
+          <tbody>${data.users.map(u => `
+            <tr>
+              <td>${escapeHtml(u.nick)}</td>
+              <td>${escapeHtml(u.host)}<br><small class="bka-muted">${escapeHtml(u.ip)}</small></td>
+              <td>${escapeHtml(u.server)}</td>
+              <td>${escapeHtml(u.mask)}</td>
+            </tr>
+          `).join('')}</tbody>
+        </table>
+      ` : '<div class="bka-empty">No watched users are online.</div>';
+    } catch (e) {
+      out.innerHTML = `<div class="bka-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="bka-app" data-plugin="${PLUGIN_ID}">
+        <form class="bka-form" id="bka-upload">
+          <label>File (CSV or JSON)<input name="file" type="file" accept=".csv,.txt,.json" required></label>
+          <label>Action
+            <select name="action">
+              <option value="gline">G-line</option>
+              <option value="gzline">Global Z-line</option>
+              <option value="zline">Z-line</option>
+              <option value="kill">Kill</option>
+              <option value="watchlist">Add to watchlist</option>
+            </select>
+          </label>
+          <label>Reason<input name="reason" placeholder="Default from settings"></label>
+          <label>Duration<input name="duration" placeholder="Default from settings"></label>
+          <label>Name<input name="name" placeholder="Optional"></label>
+          <button class="bka-btn primary" type="submit">Upload and preview</button>
+        </form>
+        <div id="bka-batch"></div>
+        <h3>Batches</h3>
+        <div id="bka-list"><div class="bka-empty">Loading...</div></div>
+        <h3>Watchlist</h3>
+        <div id="bka-watchlist"></div>
+      </div>
+    `;
+
+    const form = container.querySelector('#bka-upload');
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = form.elements;
+      const file = f.file.files[0];
+      if (!file) return;
+
+      const query = new URLSearchParams({ action: f.action.value, name: f.name.value || file.name });
+      if (f.reason.value) query.set('reason', f.reason.value);
+      if (f.duration.value) query.set('duration', f.duration.value);
+      if (/\.json$/i.test(file.name)) query.set('format', 'json');
+
+      try {
+        const b = await api('POST', '/batches?' + query.toString(), await file.text(), 'text/plain');
+        form.reset();
+        showBatch(container, b.id);
+        loadBatches(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    container.querySelector('#bka-batch').addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+      const id = container.querySelector('#bka-batch').dataset.id;
+      const path = `/batches/${encodeURIComponent(id)}`;
+
+      try {
+        switch (btn.dataset.action) {
+          case 'execute':
+            if (!confirm(`${btn.textContent}? This can't be undone from here.`)) return;
+            await api('POST', path + '/execute', { preview_token: btn.dataset.token });
+            break;
+          case 'preview':
+            await api('POST', path + '/preview');
+            break;
+          case 'cancel':
+            await api('POST', path + '/cancel');
+            break;
+          case 'report':
+            downloadReport(id, btn.dataset.format);
+            return;
+        }
+      } catch (err) {
+        alert(err.message);
+      }
+      showBatch(container, id);
+      loadBatches(container);
+      loadWatchlist(container);
+    });
+
+    container.querySelector('#bka-list').addEventListener('click', (e) => {
+      const link = e.target.closest('[data-id]');
+      if (!link) return;
+      e.preventDefault();
+      showBatch(container, link.dataset.id);
+    });
+
+    container.querySelector('#bka-watchlist').addEventListener('click', async (e) => {
+      if (e.target.closest('[data-action="online"]')) {
+        loadOnline(container);
+        return;
+      }
+      const btn = e.target.closest('[data-unwatch]');
+      if (!btn) return;
+      try {
+        await api('DELETE', `/watchlist/${encodeURIComponent(btn.dataset.unwatch)}`);
+      } catch (err) {
+        alert(err.message);
+      }
+      loadWatchlist(container);
+    });
+
+    loadBatches(container);
+    loadWatchlist(container);
+    return true;
+  }
+
+  function cleanup() {
+    clearTimeout(pollTimer);
+    const style = document.getElementById('bulk-actions-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
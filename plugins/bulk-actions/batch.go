@@ -0,0 +1,278 @@
+package bulkactions
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Bulk actions
+const (
+	ActionGline     = "gline"
+	ActionZline     = "zline"
+	ActionGZline    = "gzline"
+	ActionKill      = "kill"
+	ActionWatchlist = "watchlist"
+)
+
+// Entry states
+const (
+	EntryPending = "pending"
+	EntryOK      = "ok"
+	EntryFailed  = "failed"
+	EntryInvalid = "invalid"
+	EntrySkipped = "skipped"
+)
+
+// maxPreviewUsers is the number of affected nicks listed per entry
+const maxPreviewUsers = 20
+
+// knownAction reports whether action is a bulk action
+func knownAction(action string) bool {
+	switch action {
+	case ActionGline, ActionZline, ActionGZline, ActionKill, ActionWatchlist:
+		return true
+	}
+	return false
+}
+
+// isBan reports whether action places a server ban
+func isBan(action string) bool {
+	return action == ActionGline || action == ActionZline || action == ActionGZline
+}
+
+// row is one target read from an upload, with the reason and duration
+// given on its line, if any
+type row struct {
+	line     int
+	target   string
+	reason   string
+	duration string
+}
+
+// targetColumns are the header names accepted for the target column
+var targetColumns = []string{"target", "mask", "ip", "nick", "host"}
+
+// parseUpload reads the targets from a CSV or JSON upload. format is "csv",
+// "json" or empty to tell from the content.
+//
+// CSV has the target in the first column and an optional reason and
+// duration after it, or columns named by a header row. JSON is an array of
+// strings or of objects with target (or mask, ip or nick), reason and
+// duration.
+func parseUpload(data []byte, format string) ([]row, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if format == "" {
+		format = "csv"
+		if t := bytes.TrimSpace(data); len(t) > 0 && t[0] == '[' {
+			format = "json"
+		}
+	}
+	switch format {
+	case "csv":
+		return parseCSV(data)
+	case "json":
+		return parseJSON(data)
+	}
+	return nil, fmt.Errorf("format must be csv or json")
+}
+
+// parseCSV reads targets from CSV
+func parseCSV(data []byte) ([]row, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	cols := map[string]int{"target": 0, "reason": 1, "duration": 2}
+	rows := make([]row, 0)
+	first := true
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			first = false
+			if hdr, ok := csvHeader(rec); ok {
+				cols = hdr
+				continue
+			}
+		}
+		line, _ := r.FieldPos(0)
+		get := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		if get("target") == "" {
+			continue
+		}
+		rows = append(rows, row{line: line, target: get("target"), reason: get("reason"), duration: get("duration")})
+	}
+	return rows, nil
+}
+
+// csvHeader returns the column positions if rec is a header row
+func csvHeader(rec []string) (map[string]int, bool) {
+	cols := make(map[string]int)
+	for i, name := range rec {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case lists.ContainsFold(targetColumns, name):
+			if _, ok := cols["target"]; !ok {
+				cols["target"] = i
+			}
+		case name == "reason" || name == "duration":
+			cols[name] = i
+		}
+	}
+	_, ok := cols["target"]
+	return cols, ok
+}
+
+// parseJSON reads targets from JSON
+func parseJSON(data []byte) ([]row, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("JSON upload must be an array: %v", err)
+	}
+	rows := make([]row, 0, len(items))
+	for i, raw := range items {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			rows = append(rows, row{line: i + 1, target: strings.TrimSpace(s)})
+			continue
+		}
+		var obj struct {
+			Target   string `json:"target"`
+			Mask     string `json:"mask"`
+			IP       string `json:"ip"`
+			Nick     string `json:"nick"`
+			Reason   string `json:"reason"`
+			Duration string `json:"duration"`
+		}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("item %d is neither a string nor an object", i+1)
+		}
+		target := obj.Target
+		for _, alt := range []string{obj.Mask, obj.IP, obj.Nick} {
+			if target == "" {
+				target = alt
+			}
+		}
+		rows = append(rows, row{line: i + 1, target: strings.TrimSpace(target), reason: obj.Reason, duration: obj.Duration})
+	}
+	return rows, nil
+}
+
+// nickPattern matches a valid IRC nick
+var nickPattern = regexp.MustCompile("^[A-Za-z\\[\\]\\\\`_^{|}][A-Za-z0-9\\[\\]\\\\`_^{|}-]*$")
+
+// normalizeTarget checks a target for an action and returns it in the form
+// the action uses: a nick for kill, and otherwise a user@host mask.
+// minV4 and minV6 are the shortest CIDR prefixes allowed.
+func normalizeTarget(action, target string, minV4, minV6 int) (string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", fmt.Errorf("target is empty")
+	}
+	if strings.ContainsAny(target, " \t,") {
+		return "", fmt.Errorf("target can't contain spaces or commas")
+	}
+
+	switch action {
+	case ActionKill:
+		if nickPattern.MatchString(target) {
+			return target, nil
+		}
+	case ActionZline, ActionGZline:
+		// Z-lines match IP addresses only
+		host := strings.TrimPrefix(target, "*@")
+		if err := checkIP(host, minV4, minV6); err != nil {
+			return "", err
+		}
+		return "*@" + host, nil
+	}
+
+	user, host := "*", target
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		user, host = target[:i], target[i+1:]
+		if user == "" {
+			user = "*"
+		}
+	}
+	if host == "" {
+		return "", fmt.Errorf("host part is empty")
+	}
+	if strings.ContainsAny(host, "/:") || net.ParseIP(host) != nil {
+		if err := checkIP(host, minV4, minV6); err != nil {
+			return "", err
+		}
+	} else if strings.Trim(host, "*?.") == "" {
+		return "", fmt.Errorf("host %q matches everyone", host)
+	}
+	return user + "@" + host, nil
+}
+
+// checkIP checks that s is an IP address or a CIDR range no wider than
+// the minimum prefixes allow
+func checkIP(s string, minV4, minV6 int) error {
+	if net.ParseIP(s) != nil {
+		return nil
+	}
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return fmt.Errorf("%q is not an IP address or CIDR range", s)
+	}
+	ones, _ := ipnet.Mask.Size()
+	if ip.To4() != nil && ones < minV4 {
+		return fmt.Errorf("%s is wider than /%d", s, minV4)
+	}
+	if ip.To4() == nil && ones < minV6 {
+		return fmt.Errorf("%s is wider than /%d", s, minV6)
+	}
+	return nil
+}
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	User     struct {
+		Username   string `json:"username"`
+		Servername string `json:"servername"`
+	} `json:"user"`
+}
+
+// matchUser reports whether a user is affected by a normalized target: a
+// nick, or a user@host mask whose host part matches the user's hostname or
+// IP, or a CIDR range containing the IP
+func matchUser(target string, u rpcUser) bool {
+	i := strings.LastIndex(target, "@")
+	if i < 0 {
+		return strings.EqualFold(target, u.Name)
+	}
+	user, host := target[:i], target[i+1:]
+	if !ircname.MatchMask(user, u.User.Username) {
+		return false
+	}
+	if _, ipnet, err := net.ParseCIDR(host); err == nil {
+		ip := net.ParseIP(u.IP)
+		return ip != nil && ipnet.Contains(ip)
+	}
+	return ircname.MatchMask(host, u.Hostname) || (u.IP != "" && ircname.MatchMask(host, u.IP))
+}
@@ -0,0 +1,1010 @@
+// Bulk Actions Plugin for UnrealIRCd Web Panel
+// Imports lists of masks, IPs or nicks from CSV or JSON and G-lines,
+// Z-lines, kills or watchlists them in batches after a mandatory preview
+
+package bulkactions
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Batch states
+const (
+	StatusPreview     = "preview"
+	StatusRunning     = "running"
+	StatusDone        = "done"
+	StatusCancelled   = "cancelled"
+	StatusInterrupted = "interrupted"
+)
+
+// maxUpload is the largest upload accepted, in bytes
+const maxUpload = 4 << 20
+
+// durationPattern matches an UnrealIRCd duration such as "1d", "2h30m" or
+// "0" for permanent
+var durationPattern = regexp.MustCompile(`^(\d+|(\d+[smhdw])+)$`)
+
+// BulkActionsPlugin implements the Plugin interface
+type BulkActionsPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	batches   []*Batch
+	watchlist []*WatchEntry
+	cancels   map[string]context.CancelFunc
+	dirty     bool
+	mu        sync.RWMutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL          string `json:"rpc_url"`
+	RPCUser         string `json:"rpc_user"`
+	RPCPassword     string `json:"rpc_password"`
+	RPCInsecure     bool   `json:"rpc_insecure"`
+	DataDir         string `json:"data_dir"`
+	DefaultReason   string `json:"default_reason"`
+	DefaultDuration string `json:"default_duration"`
+	BatchSize       int    `json:"batch_size"`
+	BatchDelayMs    int    `json:"batch_delay_ms"`
+	MaxRows         int    `json:"max_rows"`
+	MinCIDRv4       int    `json:"min_cidr_v4"`
+	MinCIDRv6       int    `json:"min_cidr_v6"`
+	PreviewMinutes  int    `json:"preview_minutes"`
+	KeepBatches     int    `json:"keep_batches"`
+}
+
+// Batch is an uploaded list of targets and the action to take on them
+type Batch struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Action       string    `json:"action"`
+	Reason       string    `json:"reason"`
+	Duration     string    `json:"duration,omitempty"`
+	Status       string    `json:"status"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	PreviewToken string    `json:"preview_token,omitempty"`
+	PreviewedAt  time.Time `json:"previewed_at"`
+	ExecutedBy   string    `json:"executed_by,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Total        int       `json:"total"`
+	Invalid      int       `json:"invalid"`
+	Affected     int       `json:"affected_users"`
+	Succeeded    int       `json:"succeeded"`
+	Failed       int       `json:"failed"`
+	Skipped      int       `json:"skipped"`
+	Entries      []*Entry  `json:"entries,omitempty"`
+}
+
+// Entry is one target of a batch
+type Entry struct {
+	Line     int      `json:"line"`
+	Input    string   `json:"input"`
+	Target   string   `json:"target,omitempty"`
+	Reason   string   `json:"reason"`
+	Duration string   `json:"duration,omitempty"`
+	Matches  int      `json:"matches"`
+	Users    []string `json:"users,omitempty"`
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+	Result   string   `json:"result,omitempty"`
+}
+
+// WatchEntry is a mask on the plugin's watchlist
+type WatchEntry struct {
+	ID      string    `json:"id"`
+	Mask    string    `json:"mask"`
+	Reason  string    `json:"reason"`
+	AddedBy string    `json:"added_by"`
+	AddedAt time.Time `json:"added_at"`
+	Batch   string    `json:"batch,omitempty"`
+}
+
+// ExecuteRequest is the body of execute requests
+type ExecuteRequest struct {
+	PreviewToken string `json:"preview_token"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Batches   []*Batch      `json:"batches"`
+	Watchlist []*WatchEntry `json:"watchlist"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &BulkActionsPlugin{
+		config: Config{
+			RPCURL:          "https://127.0.0.1:8600/api",
+			DataDir:         "data/plugins/bulk-actions",
+			DefaultReason:   "Banned by network staff",
+			DefaultDuration: "1d",
+			BatchSize:       25,
+			BatchDelayMs:    1000,
+			MaxRows:         5000,
+			MinCIDRv4:       16,
+			MinCIDRv6:       32,
+			PreviewMinutes:  30,
+			KeepBatches:     50,
+		},
+		batches:   make([]*Batch, 0),
+		watchlist: make([]*WatchEntry, 0),
+		cancels:   make(map[string]context.CancelFunc),
+	}
+}
+
+// Info returns plugin metadata
+func (p *BulkActionsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Bulk Actions",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Ban, kill or watchlist lists of masks and IPs imported from CSV or JSON",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *BulkActionsPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[bulk-actions] failed to load data: %v", err)
+	}
+	if data.Batches != nil {
+		p.batches = data.Batches
+	}
+	if data.Watchlist != nil {
+		p.watchlist = data.Watchlist
+	}
+	// Batches that were running when the panel stopped keep their pending
+	// entries and can be previewed and run again
+	for _, b := range p.batches {
+		if b.Status == StatusRunning {
+			b.Status = StatusInterrupted
+			b.PreviewToken = ""
+			p.dirty = true
+		}
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "bulk-actions-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		running := 0
+		for _, b := range p.batches {
+			if b.Status == StatusRunning {
+				running++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Bulk Actions",
+			Icon:  "Layers",
+			Content: map[string]interface{}{
+				"batches":   len(p.batches),
+				"running":   running,
+				"watchlist": len(p.watchlist),
+			},
+			Order: 79,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *BulkActionsPlugin) Shutdown() error {
+	if p.cancel != nil {
+		p.cancel()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *BulkActionsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/bulk-actions")
+	{
+		plugin.GET("/batches", p.handleListBatches)
+		plugin.POST("/batches", p.handleUpload)
+		plugin.GET("/batches/:id", p.handleGetBatch)
+		plugin.DELETE("/batches/:id", p.handleDeleteBatch)
+		plugin.POST("/batches/:id/preview", p.handlePreview)
+		plugin.POST("/batches/:id/execute", p.handleExecute)
+		plugin.POST("/batches/:id/cancel", p.handleCancel)
+		plugin.GET("/batches/:id/report", p.handleReport)
+		plugin.GET("/watchlist", p.handleWatchlist)
+		plugin.GET("/watchlist/online", p.handleWatchlistOnline)
+		plugin.DELETE("/watchlist/:id", p.handleDeleteWatch)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *BulkActionsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "bulk-actions.json")
+}
+
+// save persists the state if it changed
+func (p *BulkActionsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Batches: p.batches, Watchlist: p.watchlist}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *BulkActionsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// batch finds a batch by ID. Caller must hold p.mu.
+func (p *BulkActionsPlugin) batch(id string) *Batch {
+	for _, b := range p.batches {
+		if b.ID == id {
+			return b
+		}
+	}
+	return nil
+}
+
+// summary returns a copy of the batch without its entries
+func (b *Batch) summary() Batch {
+	s := *b
+	s.Entries = nil
+	return s
+}
+
+// count recomputes the batch totals from its entries
+func (b *Batch) count() {
+	b.Total, b.Invalid, b.Affected, b.Succeeded, b.Failed, b.Skipped = len(b.Entries), 0, 0, 0, 0, 0
+	for _, e := range b.Entries {
+		switch e.Status {
+		case EntryInvalid:
+			b.Invalid++
+		case EntryOK:
+			b.Succeeded++
+		case EntryFailed:
+			b.Failed++
+		case EntrySkipped:
+			b.Skipped++
+		}
+		if e.Status != EntryInvalid {
+			b.Affected += e.Matches
+		}
+	}
+}
+
+// listUsers fetches the users currently online
+func (p *BulkActionsPlugin) listUsers(ctx context.Context) ([]rpcUser, error) {
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	if err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result); err != nil {
+		return nil, err
+	}
+	return result.List, nil
+}
+
+// preview fills in the users each pending entry affects and issues a new
+// preview token. Caller must hold p.mu.
+func (p *BulkActionsPlugin) preview(b *Batch, users []rpcUser, now time.Time) {
+	for _, e := range b.Entries {
+		if e.Status != EntryPending {
+			continue
+		}
+		e.Matches = 0
+		e.Users = nil
+		for _, u := range users {
+			if matchUser(e.Target, u) {
+				e.Matches++
+				if len(e.Users) < maxPreviewUsers {
+					e.Users = append(e.Users, u.Name)
+				}
+			}
+		}
+	}
+	b.count()
+	b.PreviewToken = newID()
+	b.PreviewedAt = now
+	b.Status = StatusPreview
+	p.dirty = true
+}
+
+// prune drops the oldest finished batches beyond keep_batches. Caller must
+// hold p.mu.
+func (p *BulkActionsPlugin) prune() {
+	keep := p.config.KeepBatches
+	if keep < 1 {
+		keep = 50
+	}
+	for len(p.batches) > keep {
+		i := 0
+		for i < len(p.batches) && p.batches[i].Status == StatusRunning {
+			i++
+		}
+		if i == len(p.batches) {
+			return
+		}
+		p.batches = append(p.batches[:i], p.batches[i+1:]...)
+		p.dirty = true
+	}
+}
+
+// run carries out the pending entries of a batch in chunks of batch_size,
+// pausing batch_delay_ms between chunks so the RPC server isn't flooded
+func (p *BulkActionsPlugin) run(ctx context.Context, b *Batch, actor string) {
+	defer p.wg.Done()
+
+	p.mu.RLock()
+	size, delay := p.config.BatchSize, time.Duration(p.config.BatchDelayMs)*time.Millisecond
+	action := b.Action
+	pending := make([]*Entry, 0)
+	for _, e := range b.Entries {
+		if e.Status == EntryPending {
+			pending = append(pending, e)
+		}
+	}
+	p.mu.RUnlock()
+	if size < 1 {
+		size = 25
+	}
+
+	rpc := p.client()
+
+	// Kills by mask go to the users matching when the batch runs, which
+	// may differ a little from the preview
+	var users []rpcUser
+	var listErr error
+	if action == ActionKill {
+		users, listErr = p.listUsers(ctx)
+	}
+
+	for i := 0; i < len(pending) && ctx.Err() == nil; i += size {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				continue
+			case <-time.After(delay):
+			}
+		}
+		end := i + size
+		if end > len(pending) {
+			end = len(pending)
+		}
+		for _, e := range pending[i:end] {
+			if ctx.Err() != nil {
+				break
+			}
+			status, result := p.apply(ctx, rpc, b, e, actor, users, listErr)
+			p.mu.Lock()
+			e.Status = status
+			if status == EntryFailed {
+				e.Error = result
+			} else {
+				e.Result = result
+			}
+			b.count()
+			p.dirty = true
+			p.mu.Unlock()
+		}
+	}
+
+	p.mu.Lock()
+	delete(p.cancels, b.ID)
+	switch {
+	case p.ctx.Err() != nil:
+		b.Status = StatusInterrupted
+	case ctx.Err() != nil:
+		b.Status = StatusCancelled
+	default:
+		b.Status = StatusDone
+	}
+	b.FinishedAt = time.Now().UTC()
+	p.dirty = true
+	done := b.summary()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[bulk-actions] failed to save data: %v", err)
+	}
+	log.Printf("[bulk-actions] batch %q %s: %d ok, %d failed, %d skipped", done.Name, done.Status, done.Succeeded, done.Failed, done.Skipped)
+}
+
+// apply carries out the batch action on one entry and returns the entry's
+// new status with a result or error message
+func (p *BulkActionsPlugin) apply(ctx context.Context, rpc *jsonrpc.Client, b *Batch, e *Entry, actor string, users []rpcUser, listErr error) (string, string) {
+	var out json.RawMessage
+	switch b.Action {
+	case ActionWatchlist:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, w := range p.watchlist {
+			if strings.EqualFold(w.Mask, e.Target) {
+				return EntrySkipped, "already on the watchlist"
+			}
+		}
+		p.watchlist = append(p.watchlist, &WatchEntry{
+			ID:      newID(),
+			Mask:    e.Target,
+			Reason:  e.Reason,
+			AddedBy: actor,
+			AddedAt: time.Now().UTC(),
+			Batch:   b.ID,
+		})
+		return EntryOK, "added to the watchlist"
+
+	case ActionKill:
+		if !strings.Contains(e.Target, "@") {
+			if err := rpc.Call(ctx, "user.kill", map[string]interface{}{"nick": e.Target, "reason": e.Reason}, &out); err != nil {
+				return EntryFailed, err.Error()
+			}
+			return EntryOK, "killed"
+		}
+		if listErr != nil {
+			return EntryFailed, "listing users: " + listErr.Error()
+		}
+		killed, failed := 0, 0
+		var lastErr error
+		for _, u := range users {
+			if !matchUser(e.Target, u) {
+				continue
+			}
+			if err := rpc.Call(ctx, "user.kill", map[string]interface{}{"nick": u.Name, "reason": e.Reason}, &out); err != nil {
+				failed++
+				lastErr = err
+				continue
+			}
+			killed++
+		}
+		switch {
+		case killed+failed == 0:
+			return EntrySkipped, "no matching users online"
+		case failed > 0 && killed == 0:
+			return EntryFailed, lastErr.Error()
+		case failed > 0:
+			return EntryOK, fmt.Sprintf("killed %d users, %d failed: %v", killed, failed, lastErr)
+		}
+		return EntryOK, fmt.Sprintf("killed %d users", killed)
+	}
+
+	params := map[string]interface{}{
+		"name":            e.Target,
+		"type":            b.Action,
+		"reason":          e.Reason,
+		"duration_string": e.Duration,
+		"set_by":          actor,
+	}
+	if err := rpc.Call(ctx, "server_ban.add", params, &out); err != nil {
+		return EntryFailed, err.Error()
+	}
+	return EntryOK, b.Action + " added"
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleListBatches returns all batches without their entries, newest
+// first
+func (p *BulkActionsPlugin) handleListBatches(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Batch, 0, len(p.batches))
+	for i := len(p.batches) - 1; i >= 0; i-- {
+		list = append(list, p.batches[i].summary())
+	}
+	c.JSON(http.StatusOK, gin.H{"batches": list})
+}
+
+// handleUpload creates a batch from an uploaded list and previews it. The
+// list is the request body; action, reason, duration, format and name are
+// query parameters.
+func (p *BulkActionsPlugin) handleUpload(c *gin.Context) {
+	action := strings.ToLower(c.Query("action"))
+	if !knownAction(action) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be gline, zline, gzline, kill or watchlist"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxUpload+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	if len(data) > maxUpload {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Upload is larger than %d MB", maxUpload>>20)})
+		return
+	}
+	rows, err := parseUpload(data, strings.ToLower(c.Query("format")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The upload contains no targets"})
+		return
+	}
+	if len(rows) > cfg.MaxRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The upload has %d targets, more than the limit of %d", len(rows), cfg.MaxRows)})
+		return
+	}
+
+	reason := strings.TrimSpace(c.Query("reason"))
+	if reason == "" && action != ActionWatchlist {
+		reason = cfg.DefaultReason
+	}
+	duration := strings.TrimSpace(c.DefaultQuery("duration", cfg.DefaultDuration))
+	if isBan(action) && !durationPattern.MatchString(duration) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be like 1d, 2h30m or 0 for permanent"})
+		return
+	}
+
+	now := time.Now().UTC()
+	b := &Batch{
+		ID:        newID(),
+		Name:      strings.TrimSpace(c.Query("name")),
+		Action:    action,
+		Reason:    reason,
+		CreatedBy: access.Actor(c),
+		CreatedAt: now,
+		Entries:   make([]*Entry, 0, len(rows)),
+	}
+	if b.Name == "" {
+		b.Name = fmt.Sprintf("%s of %d targets", action, len(rows))
+	}
+	if isBan(action) {
+		b.Duration = duration
+	}
+
+	seen := make(map[string]int)
+	for _, r := range rows {
+		e := &Entry{Line: r.line, Input: r.target, Reason: reason, Duration: b.Duration, Status: EntryPending}
+		if r.reason != "" {
+			e.Reason = r.reason
+		}
+		target, err := normalizeTarget(action, r.target, cfg.MinCIDRv4, cfg.MinCIDRv6)
+		switch {
+		case err != nil:
+			e.Status, e.Error = EntryInvalid, err.Error()
+		case isBan(action) && r.duration != "" && !durationPattern.MatchString(r.duration):
+			e.Status, e.Error = EntryInvalid, fmt.Sprintf("duration %q is not valid", r.duration)
+		case seen[strings.ToLower(target)] > 0:
+			e.Status, e.Error = EntryInvalid, fmt.Sprintf("duplicate of line %d", seen[strings.ToLower(target)])
+		default:
+			e.Target = target
+			seen[strings.ToLower(target)] = r.line
+			if isBan(action) && r.duration != "" {
+				e.Duration = r.duration
+			}
+		}
+		b.Entries = append(b.Entries, e)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	users, err := p.listUsers(ctx)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Can't preview affected users: " + err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	p.preview(b, users, now)
+	p.batches = append(p.batches, b)
+	p.prune()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[bulk-actions] failed to save data: %v", err)
+	}
+	log.Printf("[bulk-actions] %s uploaded batch %q: %d targets, %d invalid", b.CreatedBy, b.Name, b.Total, b.Invalid)
+	c.JSON(http.StatusCreated, b)
+}
+
+// handleGetBatch returns a batch with its entries
+func (p *BulkActionsPlugin) handleGetBatch(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	b := p.batch(c.Param("id"))
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// handleDeleteBatch removes a batch that is not running
+func (p *BulkActionsPlugin) handleDeleteBatch(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	for i, b := range p.batches {
+		if b.ID != id {
+			continue
+		}
+		if b.Status == StatusRunning {
+			p.mu.Unlock()
+			c.JSON(http.StatusConflict, gin.H{"error": "Batch is running"})
+			return
+		}
+		p.batches = append(p.batches[:i], p.batches[i+1:]...)
+		p.dirty = true
+		p.mu.Unlock()
+
+		if err := p.save(); err != nil {
+			log.Printf("[bulk-actions] failed to save data: %v", err)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Batch deleted"})
+		return
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+}
+
+// handlePreview refreshes the affected users of a batch that has not run,
+// or the pending entries of one that was cancelled or interrupted
+func (p *BulkActionsPlugin) handlePreview(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	users, err := p.listUsers(ctx)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Can't preview affected users: " + err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	b := p.batch(c.Param("id"))
+	if b == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+		return
+	}
+	if b.Status == StatusRunning || b.Status == StatusDone {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Batch is " + b.Status})
+		return
+	}
+	p.preview(b, users, time.Now().UTC())
+	v := *b
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, v)
+}
+
+// handleExecute starts a previewed batch. The preview token from the
+// latest preview must be given, so a batch can't run unseen.
+func (p *BulkActionsPlugin) handleExecute(c *gin.Context) {
+	var req ExecuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.PreviewToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "preview_token is required"})
+		return
+	}
+
+	p.mu.Lock()
+	b := p.batch(c.Param("id"))
+	if b == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+		return
+	}
+	if b.Status != StatusPreview {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Batch is " + b.Status + "; preview it again first"})
+		return
+	}
+	if req.PreviewToken != b.PreviewToken {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Batch has been previewed again since; check the new preview"})
+		return
+	}
+	if time.Since(b.PreviewedAt) > time.Duration(p.config.PreviewMinutes)*time.Minute {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Preview is out of date; preview the batch again"})
+		return
+	}
+	if b.Total == b.Invalid {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Batch has no valid targets"})
+		return
+	}
+
+	actor := access.Actor(c)
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.cancels[b.ID] = cancel
+	b.Status = StatusRunning
+	b.ExecutedBy = actor
+	b.StartedAt = time.Now().UTC()
+	b.PreviewToken = ""
+	p.dirty = true
+	v := b.summary()
+	p.wg.Add(1)
+	go p.run(ctx, b, actor)
+	p.mu.Unlock()
+
+	log.Printf("[bulk-actions] %s started batch %q (%s on %d targets)", actor, b.Name, b.Action, v.Total-v.Invalid)
+	c.JSON(http.StatusAccepted, v)
+}
+
+// handleCancel stops a running batch after the entry in progress
+func (p *BulkActionsPlugin) handleCancel(c *gin.Context) {
+	p.mu.Lock()
+	cancel, ok := p.cancels[c.Param("id")]
+	p.mu.Unlock()
+
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Batch is not running"})
+		return
+	}
+	cancel()
+	log.Printf("[bulk-actions] %s cancelled batch %s", access.Actor(c), c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"message": "Batch cancelled"})
+}
+
+// handleReport downloads the entries of a batch and their results as CSV,
+// or JSON with ?format=json
+func (p *BulkActionsPlugin) handleReport(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	p.mu.RLock()
+	b := p.batch(c.Param("id"))
+	if b == nil {
+		p.mu.RUnlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+		return
+	}
+	report := *b
+	report.Entries = make([]*Entry, len(b.Entries))
+	for i, e := range b.Entries {
+		copied := *e
+		report.Entries[i] = &copied
+	}
+	p.mu.RUnlock()
+
+	name := "bulk-" + report.ID + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+
+	if format == "json" {
+		report.PreviewToken = ""
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"line", "input", "target", "action", "reason", "duration", "matched_users", "status", "result"})
+	for _, e := range report.Entries {
+		result := e.Result
+		if e.Error != "" {
+			result = e.Error
+		}
+		_ = w.Write([]string{
+			strconv.Itoa(e.Line),
+			e.Input,
+			e.Target,
+			report.Action,
+			e.Reason,
+			e.Duration,
+			strconv.Itoa(e.Matches),
+			e.Status,
+			result,
+		})
+	}
+	w.Flush()
+}
+
+// handleWatchlist returns the watchlist, newest first
+func (p *BulkActionsPlugin) handleWatchlist(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]WatchEntry, 0, len(p.watchlist))
+	for i := len(p.watchlist) - 1; i >= 0; i-- {
+		list = append(list, *p.watchlist[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"watchlist": list})
+}
+
+// handleWatchlistOnline returns the online users matching a watchlist mask
+func (p *BulkActionsPlugin) handleWatchlistOnline(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	users, err := p.listUsers(ctx)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	type match struct {
+		Nick   string `json:"nick"`
+		Host   string `json:"host"`
+		IP     string `json:"ip"`
+		Server string `json:"server"`
+		Mask   string `json:"mask"`
+		Reason string `json:"reason"`
+	}
+
+	p.mu.RLock()
+	matches := make([]match, 0)
+	for _, u := range users {
+		for _, w := range p.watchlist {
+			if matchUser(w.Mask, u) {
+				matches = append(matches, match{Nick: u.Name, Host: u.Hostname, IP: u.IP, Server: u.User.Servername, Mask: w.Mask, Reason: w.Reason})
+				break
+			}
+		}
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool { return strings.ToLower(matches[i].Nick) < strings.ToLower(matches[j].Nick) })
+	c.JSON(http.StatusOK, gin.H{"users": matches})
+}
+
+// handleDeleteWatch removes a mask from the watchlist
+func (p *BulkActionsPlugin) handleDeleteWatch(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	found := false
+	for i, w := range p.watchlist {
+		if w.ID == id {
+			p.watchlist = append(p.watchlist[:i], p.watchlist[i+1:]...)
+			p.dirty = true
+			found = true
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist entry not found"})
+		return
+	}
+	if err := p.save(); err != nil {
+		log.Printf("[bulk-actions] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Removed from the watchlist"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *BulkActionsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *BulkActionsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	switch {
+	case !durationPattern.MatchString(newConfig.DefaultDuration):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "default_duration must be like 1d, 2h30m or 0 for permanent"})
+		return
+	case newConfig.BatchSize < 1 || newConfig.BatchSize > 500:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "batch_size must be between 1 and 500"})
+		return
+	case newConfig.BatchDelayMs < 0 || newConfig.BatchDelayMs > 60000:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "batch_delay_ms must be between 0 and 60000"})
+		return
+	case newConfig.MaxRows < 1 || newConfig.MaxRows > 100000:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_rows must be between 1 and 100000"})
+		return
+	case newConfig.MinCIDRv4 < 0 || newConfig.MinCIDRv4 > 32 || newConfig.MinCIDRv6 < 0 || newConfig.MinCIDRv6 > 128:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_cidr_v4 must be 0-32 and min_cidr_v6 0-128"})
+		return
+	case newConfig.PreviewMinutes < 1:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "preview_minutes must be at least 1"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	if strings.TrimSpace(newConfig.DefaultReason) == "" {
+		newConfig.DefaultReason = "Banned by network staff"
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.prune()
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *BulkActionsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *BulkActionsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,153 @@
+package captchagateway
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// softTypes are the ban exception types a verified visitor may be given:
+// connect and handshake floods, maxperip, antirandom, antimixedutf8, ban
+// version and DNS blacklists. Exceptions from real bans (k, G, z, Z, Q,
+// s, F) are never handed out by a CAPTCHA.
+const softTypes = "cdmr8vb"
+
+// Attempt results
+const (
+	ResultOK          = "ok"
+	ResultFailed      = "failed"
+	ResultRateLimited = "rate_limited"
+	ResultError       = "error"
+)
+
+// Entry is an address on the allowlist: a visitor who passed the CAPTCHA
+// and the ban exception they were given for it
+type Entry struct {
+	ID         string    `json:"id"`
+	Mask       string    `json:"mask"`
+	IP         string    `json:"ip"`
+	Provider   string    `json:"provider"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	VerifiedAt time.Time `json:"verified_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Attempt is one answer posted to the verification page
+type Attempt struct {
+	Time   time.Time `json:"time"`
+	IP     string    `json:"ip"`
+	Result string    `json:"result"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// checkTypes validates a set of exception types against softTypes
+func checkTypes(types string) error {
+	if types == "" {
+		return fmt.Errorf("exception_types is required")
+	}
+	for _, t := range types {
+		if !strings.ContainsRune(softTypes, t) {
+			return fmt.Errorf("exception type %q is not allowed; use only %s", t, softTypes)
+		}
+	}
+	return nil
+}
+
+// maskFor returns the ban exception mask for a visitor. IPv4 addresses
+// are exempted on their own; IPv6 visitors usually hold a whole prefix,
+// so the prefix is exempted instead.
+func maskFor(ip string, prefix int) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("invalid address %q", ip)
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return "*@" + v4.String(), nil
+	}
+	if prefix >= 128 {
+		return "*@" + addr.String(), nil
+	}
+	network := addr.Mask(net.CIDRMask(prefix, 128))
+	return fmt.Sprintf("*@%s/%d", network.String(), prefix), nil
+}
+
+// exempt adds the ban exception for a verified visitor. An exception
+// from an earlier verification is replaced, which extends it.
+func (p *CaptchaGatewayPlugin) exempt(ctx context.Context, mask, types string, hours int, replace bool) error {
+	rpc := p.client()
+	if replace {
+		// It may have expired on the server already
+		_ = rpc.Call(ctx, "server_ban_exception.del", map[string]interface{}{
+			"name":   mask,
+			"set_by": "captcha-gateway",
+		}, nil)
+	}
+	return rpc.Call(ctx, "server_ban_exception.add", map[string]interface{}{
+		"name":            mask,
+		"exception_types": types,
+		"reason":          "Verified by CAPTCHA",
+		"set_by":          "captcha-gateway",
+		"duration_string": fmt.Sprintf("%dh", hours),
+	}, nil)
+}
+
+// revoke removes a visitor's ban exception
+func (p *CaptchaGatewayPlugin) revoke(ctx context.Context, mask, actor string) error {
+	return p.client().Call(ctx, "server_ban_exception.del", map[string]interface{}{
+		"name":   mask,
+		"set_by": actor,
+	}, nil)
+}
+
+// entryByMask returns the entry for a mask. Caller must hold p.mu.
+func (p *CaptchaGatewayPlugin) entryByMask(mask string) *Entry {
+	for _, e := range p.entries {
+		if e.Mask == mask {
+			return e
+		}
+	}
+	return nil
+}
+
+// prune drops entries whose exception has expired. Caller must hold p.mu.
+func (p *CaptchaGatewayPlugin) prune(now time.Time) {
+	kept := p.entries[:0]
+	for _, e := range p.entries {
+		if e.ExpiresAt.After(now) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) != len(p.entries) {
+		p.dirty = true
+	}
+	p.entries = kept
+}
+
+// addAttempt records an attempt, dropping the oldest beyond the limit.
+// Caller must hold p.mu.
+func (p *CaptchaGatewayPlugin) addAttempt(a Attempt) {
+	p.attempts = append(p.attempts, a)
+	if len(p.attempts) > maxAttempts {
+		p.attempts = p.attempts[len(p.attempts)-maxAttempts:]
+	}
+	p.dirty = true
+}
+
+// limited reports whether ip has posted attemptsPerWindow answers in the
+// last attemptWindow, and counts this one if not. Caller must hold p.mu.
+func (p *CaptchaGatewayPlugin) limited(ip string, now time.Time) bool {
+	recent := p.rate[ip][:0]
+	for _, t := range p.rate[ip] {
+		if now.Sub(t) < attemptWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= attemptsPerWindow {
+		p.rate[ip] = recent
+		return true
+	}
+	p.rate[ip] = append(recent, now)
+	return false
+}
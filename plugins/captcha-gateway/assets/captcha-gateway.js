@@ -0,0 +1,240 @@
+/**
+ * Verification Gateway Frontend Script
+ *
+ * Shows the addresses that passed the CAPTCHA and the recent attempts,
+ * and lets staff revoke them one by one or all at once after an attack.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'captcha-gateway';
+  const PLUGIN_NAME = 'Verification Gateway';
+  const PAGE_PATH = '/plugins/captcha-gateway';
+  const API_BASE = '/api/plugin/captcha-gateway';
+
+  const RESULTS = {
+    ok: 'Verified',
+    failed: 'Failed',
+    rate_limited: 'Too many attempts',
+    error: 'Error',
+  };
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const res = await fetch(API_BASE + path, {
+      method,
+      headers: getAuthHeaders(),
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t && !t.startsWith('0001') ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('captcha-gateway-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'captcha-gateway-styles';
+    style.textContent = `
+      .cgw-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .cgw-summary { display: flex; flex-wrap: wrap; gap: 0.75rem; }
+      .cgw-stat { background: var(--bg-secondary, #181825); border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 0.6rem 1rem; min-width: 8rem; }
+      .cgw-stat b { display: block; font-size: 1.3rem; color: var(--text-primary, #cdd6f4); }
+      .cgw-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .cgw-table th, .cgw-table td { text-align: left; padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .cgw-table td { color: var(--text-primary, #cdd6f4); }
+      .cgw-table code { word-break: break-all; font-size: 0.8rem; }
+      .cgw-app button { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); border: none; border-radius: 6px; padding: 0.4rem 0.8rem; cursor: pointer; }
+      .cgw-app button:disabled { opacity: 0.5; cursor: default; }
+      .cgw-app button.cgw-danger { background: var(--error, #f38ba8); }
+      .cgw-ok { color: var(--success, #a6e3a1); }
+      .cgw-failed { color: var(--text-secondary, #a6adc8); }
+      .cgw-rate_limited { color: var(--warning, #f9e2af); }
+      .cgw-error { color: var(--error, #f38ba8); }
+      .cgw-desc { font-size: 0.8rem; }
+      .cgw-agent { font-size: 0.75rem; color: var(--text-secondary, #a6adc8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderSummary(status) {
+    const url = window.location.origin + status.page_path;
+    const counts = status.attempts_24h;
+    let notice = '';
+    if (!status.enabled) {
+      notice = '<div class="cgw-error">The verification page is disabled.</div>';
+    } else if (!status.ready) {
+      notice = '<div class="cgw-error">Set the site key and secret key in the plugin settings before sending people to the page.</div>';
+    }
+    return `
+      ${notice}
+      <div class="cgw-desc">Verification page: <a href="${escapeHtml(url)}" target="_blank" rel="noopener"><code>${escapeHtml(url)}</code></a></div>
+      <div class="cgw-summary">
+        <div class="cgw-stat">Allowed now<b>${status.active} / ${status.max_entries}</b></div>
+        <div class="cgw-stat">Verified (24h)<b>${counts.ok}</b></div>
+        <div class="cgw-stat">Failed (24h)<b>${counts.failed}</b></div>
+        <div class="cgw-stat">Rate limited (24h)<b>${counts.rate_limited}</b></div>
+        <div class="cgw-stat">Errors (24h)<b>${counts.error}</b></div>
+      </div>
+    `;
+  }
+
+  function renderEntries(entries) {
+    return `
+      <table class="cgw-table">
+        <thead><tr><th>Mask</th><th>Address</th><th>Verified</th><th>Expires</th><th></th></tr></thead>
+        <tbody>
+          ${entries.map(e => `
+            <tr>
+              <td><code>${escapeHtml(e.mask)}</code></td>
+              <td>${escapeHtml(e.ip)}${e.user_agent ? `<div class="cgw-agent">${escapeHtml(e.user_agent)}</div>` : ''}</td>
+              <td>${formatTime(e.verified_at)}</td>
+              <td>${formatTime(e.expires_at)}</td>
+              <td><button class="cgw-danger cgw-revoke" data-id="${escapeHtml(e.id)}">Revoke</button></td>
+            </tr>
+          `).join('') || '<tr><td colspan="5">No verified addresses</td></tr>'}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function renderAttempts(attempts) {
+    return `
+      <table class="cgw-table">
+        <thead><tr><th>Time</th><th>Address</th><th>Result</th></tr></thead>
+        <tbody>
+          ${attempts.map(a => `
+            <tr>
+              <td>${formatTime(a.time)}</td>
+              <td>${escapeHtml(a.ip)}</td>
+              <td class="cgw-${escapeHtml(a.result)}">${escapeHtml(RESULTS[a.result] || a.result)}${a.error ? `: ${escapeHtml(a.error)}` : ''}</td>
+            </tr>
+          `).join('') || '<tr><td colspan="3">No attempts yet</td></tr>'}
+        </tbody>
+      </table>
+    `;
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#cgw-body');
+    try {
+      const [status, list] = await Promise.all([api('GET', '/status'), api('GET', '/allowlist?limit=100')]);
+      body.innerHTML = `
+        ${renderSummary(status)}
+        <h3>Verified addresses</h3>
+        ${list.entries.length ? '<div><button class="cgw-danger" id="cgw-clear">Revoke all</button></div>' : ''}
+        ${renderEntries(list.entries)}
+        <h3>Recent attempts</h3>
+        ${renderAttempts(list.attempts)}
+      `;
+
+      body.querySelectorAll('.cgw-revoke').forEach(btn => {
+        btn.addEventListener('click', () => run(container, btn, () => api('DELETE', `/allowlist/${btn.dataset.id}`)));
+      });
+      const clear = body.querySelector('#cgw-clear');
+      if (clear) {
+        clear.addEventListener('click', () => {
+          if (!confirm('Revoke every verified address? Their exceptions are removed from the server straight away.')) return;
+          run(container, clear, async () => {
+            const res = await api('DELETE', '/allowlist');
+            if (res.failed) alert(`${res.removed} revoked, ${res.failed} failed: ${res.error}`);
+          });
+        });
+      }
+    } catch (e) {
+      body.innerHTML = `<div class="cgw-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function run(container, button, fn) {
+    button.disabled = true;
+    try {
+      await fn();
+    } catch (err) {
+      alert(err.message);
+    }
+    button.disabled = false;
+    load(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="cgw-app" data-plugin="${PLUGIN_ID}">
+        <div class="cgw-desc">People who pass the CAPTCHA on the verification page get a ban exception for their address, so tightened connect restrictions do not keep them out.</div>
+        <div id="cgw-body">Loading...</div>
+      </div>
+    `;
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('captcha-gateway-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
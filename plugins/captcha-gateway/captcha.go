@@ -0,0 +1,102 @@
+package captchagateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// CAPTCHA providers
+const (
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderTurnstile = "turnstile"
+)
+
+// provider describes how a CAPTCHA service is embedded in the page and
+// how its answers are checked
+type provider struct {
+	Name      string
+	ScriptURL string
+	Widget    string
+	Field     string
+	VerifyURL string
+	Origins   string
+}
+
+// providers are the supported CAPTCHA services
+var providers = map[string]provider{
+	ProviderHCaptcha: {
+		Name:      "hCaptcha",
+		ScriptURL: "https://js.hcaptcha.com/1/api.js",
+		Widget:    "h-captcha",
+		Field:     "h-captcha-response",
+		VerifyURL: "https://api.hcaptcha.com/siteverify",
+		Origins:   "https://hcaptcha.com https://*.hcaptcha.com",
+	},
+	ProviderTurnstile: {
+		Name:      "Turnstile",
+		ScriptURL: "https://challenges.cloudflare.com/turnstile/v0/api.js",
+		Widget:    "cf-turnstile",
+		Field:     "cf-turnstile-response",
+		VerifyURL: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+		Origins:   "https://challenges.cloudflare.com",
+	},
+}
+
+// httpClient checks CAPTCHA answers
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// verifyResult is the answer of a siteverify endpoint, which hCaptcha and
+// Turnstile share
+type verifyResult struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+	Hostname   string   `json:"hostname"`
+}
+
+// verify asks the provider whether response is a solved CAPTCHA for the
+// visitor at ip. An error means the check could not be made; a wrong
+// answer is a result without success.
+func verify(ctx context.Context, pr provider, secret, response, ip string) (verifyResult, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return verifyResult{}, err
+	}
+	form := url.Values{
+		"secret":   {secret},
+		"response": {response},
+		"remoteip": {ip},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pr.VerifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return verifyResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return verifyResult{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode != http.StatusOK {
+		return verifyResult{}, fmt.Errorf("%s answered %d", pr.Name, resp.StatusCode)
+	}
+
+	var result verifyResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return verifyResult{}, fmt.Errorf("invalid answer from %s: %v", pr.Name, err)
+	}
+	// A wrong secret is our fault, not the visitor's
+	for _, code := range result.ErrorCodes {
+		if code == "invalid-input-secret" || code == "missing-input-secret" || code == "sitekey-secret-mismatch" {
+			return result, fmt.Errorf("%s rejected the secret key (%s)", pr.Name, code)
+		}
+	}
+	return result, nil
+}
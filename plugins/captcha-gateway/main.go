@@ -0,0 +1,623 @@
+// Verification Gateway Plugin for UnrealIRCd Web Panel
+// Lets visitors pass an hCaptcha or Turnstile check on a public page and
+// exempts their address from connect restrictions while the network is under attack
+
+package captchagateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on attempts and history
+const (
+	maxAttempts       = 500
+	attemptsPerWindow = 5
+	attemptWindow     = 10 * time.Minute
+)
+
+// CaptchaGatewayPlugin implements the Plugin interface
+type CaptchaGatewayPlugin struct {
+	config   Config
+	rpc      *jsonrpc.Client
+	entries  []*Entry
+	attempts []Attempt
+	rate     map[string][]time.Time
+	dirty    bool
+	mu       sync.RWMutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	Enabled        bool   `json:"enabled"`
+	Provider       string `json:"provider"`
+	SiteKey        string `json:"site_key"`
+	SecretKey      string `json:"secret_key"`
+	ExceptionTypes string `json:"exception_types"`
+	DurationHours  int    `json:"duration_hours"`
+	IPv6Prefix     int    `json:"ipv6_prefix"`
+	MaxEntries     int    `json:"max_entries"`
+	NetworkName    string `json:"network_name"`
+	SuccessMessage string `json:"success_message"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Entries  []*Entry  `json:"entries"`
+	Attempts []Attempt `json:"attempts"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &CaptchaGatewayPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/captcha-gateway",
+			Enabled:        true,
+			Provider:       ProviderHCaptcha,
+			ExceptionTypes: "cd",
+			DurationHours:  24,
+			IPv6Prefix:     64,
+			MaxEntries:     10000,
+			SuccessMessage: "You can connect to IRC now.",
+		},
+		entries:  make([]*Entry, 0),
+		attempts: make([]Attempt, 0),
+		rate:     make(map[string][]time.Time),
+	}
+}
+
+// Info returns plugin metadata
+func (p *CaptchaGatewayPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Verification Gateway",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Exempts visitors who pass a CAPTCHA from connect restrictions during attacks",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *CaptchaGatewayPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[captcha-gateway] failed to load data: %v", err)
+	}
+	if data.Entries != nil {
+		p.entries = data.Entries
+	}
+	if data.Attempts != nil {
+		p.attempts = data.Attempts
+	}
+	p.prune(time.Now())
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "captcha-gateway-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		counts := p.countSince(time.Now().Add(-24 * time.Hour))
+		return plugins.DashboardCard{
+			Title: "Verification Gateway",
+			Icon:  "ShieldCheck",
+			Content: map[string]interface{}{
+				"active":       len(p.entries),
+				"verified_24h": counts[ResultOK],
+				"failed_24h":   counts[ResultFailed],
+			},
+			Order: 100,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pruneLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *CaptchaGatewayPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *CaptchaGatewayPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/captcha-gateway")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/allowlist", p.handleListAllowlist)
+		plugin.DELETE("/allowlist", p.handleClearAllowlist)
+		plugin.DELETE("/allowlist/:id", p.handleRevoke)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+
+		// The verification page is for visitors who cannot connect yet,
+		// so it needs no login; the CAPTCHA is the check
+		plugin.GET("/public/verify", p.handlePage)
+		plugin.POST("/public/verify", p.handleVerify)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *CaptchaGatewayPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "captcha-gateway.json")
+}
+
+// save persists the state if it changed
+func (p *CaptchaGatewayPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Entries: p.entries, Attempts: p.attempts}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *CaptchaGatewayPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pruneLoop drops expired entries and stale rate limits every minute.
+// The server expires the exceptions itself.
+func (p *CaptchaGatewayPlugin) pruneLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.mu.Lock()
+			p.prune(now)
+			for ip, times := range p.rate {
+				if len(times) == 0 || now.Sub(times[len(times)-1]) >= attemptWindow {
+					delete(p.rate, ip)
+				}
+			}
+			p.mu.Unlock()
+			if err := p.save(); err != nil {
+				log.Printf("[captcha-gateway] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// countSince counts attempts since a time by result. Caller must hold
+// p.mu.
+func (p *CaptchaGatewayPlugin) countSince(since time.Time) map[string]int {
+	counts := map[string]int{ResultOK: 0, ResultFailed: 0, ResultRateLimited: 0, ResultError: 0}
+	for _, a := range p.attempts {
+		if !a.Time.Before(since) {
+			counts[a.Result]++
+		}
+	}
+	return counts
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// formPage returns the page data showing the CAPTCHA
+func formPage(cfg Config) pageData {
+	return pageData{
+		Network:  cfg.NetworkName,
+		Provider: providers[cfg.Provider],
+		SiteKey:  cfg.SiteKey,
+	}
+}
+
+// ready reports whether the gateway can check answers
+func ready(cfg Config) bool {
+	_, ok := providers[cfg.Provider]
+	return ok && cfg.SiteKey != "" && cfg.SecretKey != ""
+}
+
+// writePage renders the verification page. Only the provider's scripts
+// and frames are allowed on it.
+func writePage(c *gin.Context, status int, data pageData) {
+	var buf bytes.Buffer
+	if err := renderPage(&buf, data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	origins := data.Provider.Origins
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline' "+origins+
+		"; script-src "+origins+"; frame-src "+origins+"; connect-src "+origins+"; form-action 'self'")
+	c.Header("Cache-Control", "no-store")
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// handlePage serves the verification page. It needs no login.
+func (p *CaptchaGatewayPlugin) handlePage(c *gin.Context) {
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	if !cfg.Enabled {
+		c.String(http.StatusNotFound, "Verification is disabled")
+		return
+	}
+	data := formPage(cfg)
+	if !ready(cfg) {
+		data.Unavailable = true
+		writePage(c, http.StatusServiceUnavailable, data)
+		return
+	}
+	writePage(c, http.StatusOK, data)
+}
+
+// handleVerify checks a posted CAPTCHA answer with the provider and, if
+// it was solved, exempts the visitor's address. It needs no login.
+func (p *CaptchaGatewayPlugin) handleVerify(c *gin.Context) {
+	now := time.Now().UTC()
+	ip := c.ClientIP()
+
+	p.mu.Lock()
+	cfg := p.config
+	if !cfg.Enabled {
+		p.mu.Unlock()
+		c.String(http.StatusNotFound, "Verification is disabled")
+		return
+	}
+	data := formPage(cfg)
+	if !ready(cfg) {
+		p.mu.Unlock()
+		data.Unavailable = true
+		writePage(c, http.StatusServiceUnavailable, data)
+		return
+	}
+	if p.limited(ip, now) {
+		p.addAttempt(Attempt{Time: now, IP: ip, Result: ResultRateLimited})
+		p.mu.Unlock()
+		data.Error = "Too many attempts. Please wait a few minutes and try again."
+		writePage(c, http.StatusTooManyRequests, data)
+		return
+	}
+	p.mu.Unlock()
+
+	// record logs the outcome of an attempt that got this far
+	record := func(result, reason string) {
+		p.mu.Lock()
+		p.addAttempt(Attempt{Time: now, IP: ip, Result: result, Error: reason})
+		p.mu.Unlock()
+		if err := p.save(); err != nil {
+			log.Printf("[captcha-gateway] failed to save data: %v", err)
+		}
+	}
+
+	response := c.PostForm(data.Provider.Field)
+	if response == "" {
+		record(ResultFailed, "no answer")
+		data.Error = "Please complete the check first."
+		writePage(c, http.StatusBadRequest, data)
+		return
+	}
+	mask, err := maskFor(ip, cfg.IPv6Prefix)
+	if err != nil {
+		record(ResultError, err.Error())
+		data.Error = "Your address could not be read."
+		writePage(c, http.StatusBadRequest, data)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	result, err := verify(ctx, data.Provider, cfg.SecretKey, response, ip)
+	if err != nil {
+		log.Printf("[captcha-gateway] failed to check an answer from %s: %v", ip, err)
+		record(ResultError, err.Error())
+		data.Error = "The check could not be completed. Please try again later."
+		writePage(c, http.StatusBadGateway, data)
+		return
+	}
+	if !result.Success {
+		record(ResultFailed, strings.Join(result.ErrorCodes, ", "))
+		data.Error = "The check was not passed. Please try again."
+		writePage(c, http.StatusForbidden, data)
+		return
+	}
+
+	p.mu.RLock()
+	known := p.entryByMask(mask) != nil
+	full := !known && len(p.entries) >= cfg.MaxEntries
+	p.mu.RUnlock()
+	if full {
+		log.Printf("[captcha-gateway] allowlist is full, %s was not exempted", mask)
+		record(ResultError, "allowlist is full")
+		data.Error = "Too many addresses are verified at the moment. Please try again later."
+		writePage(c, http.StatusServiceUnavailable, data)
+		return
+	}
+	if err := p.exempt(ctx, mask, cfg.ExceptionTypes, cfg.DurationHours, known); err != nil {
+		log.Printf("[captcha-gateway] failed to exempt %s: %v", mask, err)
+		record(ResultError, err.Error())
+		data.Error = "Your address could not be allowed. Please try again later."
+		writePage(c, http.StatusBadGateway, data)
+		return
+	}
+
+	expires := now.Add(time.Duration(cfg.DurationHours) * time.Hour)
+	p.mu.Lock()
+	e := p.entryByMask(mask)
+	if e == nil {
+		e = &Entry{ID: newID(), Mask: mask}
+		p.entries = append(p.entries, e)
+	}
+	e.IP = ip
+	e.Provider = cfg.Provider
+	e.UserAgent = c.Request.UserAgent()
+	e.VerifiedAt = now
+	e.ExpiresAt = expires
+	p.mu.Unlock()
+	record(ResultOK, "")
+
+	log.Printf("[captcha-gateway] exempted %s until %s", mask, expires.Format(time.RFC3339))
+	data.Success = true
+	data.Message = cfg.SuccessMessage
+	data.Mask = strings.TrimPrefix(mask, "*@")
+	data.Expires = expires
+	writePage(c, http.StatusOK, data)
+}
+
+// handleStatus reports the allowlist and recent attempts by result
+func (p *CaptchaGatewayPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":      p.config.Enabled,
+		"ready":        ready(p.config),
+		"provider":     p.config.Provider,
+		"active":       len(p.entries),
+		"max_entries":  p.config.MaxEntries,
+		"attempts_24h": p.countSince(time.Now().Add(-24 * time.Hour)),
+		"page_path":    "/api/plugin/captcha-gateway/public/verify",
+	})
+}
+
+// handleListAllowlist returns the verified addresses and the recent
+// attempts, newest first
+func (p *CaptchaGatewayPlugin) handleListAllowlist(c *gin.Context) {
+	limit := 100
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		limit = v
+	}
+
+	p.mu.RLock()
+	entries := make([]Entry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, *e)
+	}
+	attempts := make([]Attempt, 0, limit)
+	for i := len(p.attempts) - 1; i >= 0 && len(attempts) < limit; i-- {
+		attempts = append(attempts, p.attempts[i])
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].VerifiedAt.After(entries[j].VerifiedAt)
+	})
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "attempts": attempts})
+}
+
+// removeEntry revokes an entry's exception and drops it. An exception the
+// server no longer has is dropped as well.
+func (p *CaptchaGatewayPlugin) removeEntry(ctx context.Context, e Entry, actor string) error {
+	var rerr *jsonrpc.Error
+	if err := p.revoke(ctx, e.Mask, actor); err != nil && !errors.As(err, &rerr) {
+		return err
+	}
+	p.mu.Lock()
+	for i, cur := range p.entries {
+		if cur.ID == e.ID {
+			p.entries = append(p.entries[:i], p.entries[i+1:]...)
+			p.dirty = true
+			break
+		}
+	}
+	p.mu.Unlock()
+	return nil
+}
+
+// handleRevoke removes one verified address
+func (p *CaptchaGatewayPlugin) handleRevoke(c *gin.Context) {
+	id := c.Param("id")
+	p.mu.RLock()
+	var entry *Entry
+	for _, e := range p.entries {
+		if e.ID == id {
+			cp := *e
+			entry = &cp
+			break
+		}
+	}
+	p.mu.RUnlock()
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+	actor := access.Actor(c)
+	if err := p.removeEntry(ctx, *entry, actor); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to remove exception: " + err.Error()})
+		return
+	}
+	if err := p.save(); err != nil {
+		log.Printf("[captcha-gateway] failed to save data: %v", err)
+	}
+
+	log.Printf("[captcha-gateway] %s revoked %s", actor, entry.Mask)
+	c.JSON(http.StatusOK, gin.H{"message": "Entry removed"})
+}
+
+// handleClearAllowlist removes every verified address, for when an
+// attack is over and the restrictions are relaxed again
+func (p *CaptchaGatewayPlugin) handleClearAllowlist(c *gin.Context) {
+	p.mu.RLock()
+	entries := make([]Entry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, *e)
+	}
+	p.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+	actor := access.Actor(c)
+	removed, failed := 0, 0
+	var lastErr error
+	for _, e := range entries {
+		if err := p.removeEntry(ctx, e, actor); err != nil {
+			failed++
+			lastErr = err
+			continue
+		}
+		removed++
+	}
+	if err := p.save(); err != nil {
+		log.Printf("[captcha-gateway] failed to save data: %v", err)
+	}
+
+	log.Printf("[captcha-gateway] %s cleared the allowlist: %d removed, %d failed", actor, removed, failed)
+	resp := gin.H{"removed": removed, "failed": failed}
+	if lastErr != nil {
+		resp["error"] = lastErr.Error()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleGetConfig returns plugin configuration
+func (p *CaptchaGatewayPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.SecretKey = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *CaptchaGatewayPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if _, ok := providers[newConfig.Provider]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider must be hcaptcha or turnstile"})
+		return
+	}
+	if err := checkTypes(newConfig.ExceptionTypes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if newConfig.DurationHours < 1 || newConfig.DurationHours > 720 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_hours must be between 1 and 720"})
+		return
+	}
+	if newConfig.IPv6Prefix < 32 || newConfig.IPv6Prefix > 128 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ipv6_prefix must be between 32 and 128"})
+		return
+	}
+	if newConfig.MaxEntries < 1 || newConfig.MaxEntries > 100000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_entries must be between 1 and 100000"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.SecretKey == "" {
+		newConfig.SecretKey = p.config.SecretKey
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *CaptchaGatewayPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *CaptchaGatewayPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,82 @@
+package captchagateway
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"time"
+)
+
+// pageFuncs are the helpers available to the page template
+var pageFuncs = htmltemplate.FuncMap{
+	"time": func(t time.Time) string {
+		return t.UTC().Format("2 Jan 2006 15:04 UTC")
+	},
+}
+
+// pageData is what the verification page is rendered with. Without
+// Success or Unavailable the CAPTCHA is shown, below Error if set.
+type pageData struct {
+	Network     string
+	Provider    provider
+	SiteKey     string
+	Success     bool
+	Unavailable bool
+	Error       string
+	Message     string
+	Mask        string
+	Expires     time.Time
+}
+
+var verifyPage = htmltemplate.Must(htmltemplate.New("verify").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{if .Network}}{{.Network}} {{end}}Connection Verification</title>
+<style>
+body{margin:0;padding:24px 16px;background:#f4f5f7;font-family:-apple-system,"Segoe UI",Roboto,Arial,sans-serif;color:#1f2328;}
+main{max-width:520px;margin:0 auto;}
+h1{font-size:22px;margin:0 0 16px;}
+.box{background:#fff;border:1px solid #e3e5e8;border-radius:8px;padding:20px;}
+.muted{color:#6b7280;font-size:13px;}
+.error{margin:0 0 16px;padding:10px 14px;border-radius:6px;background:#fdecea;color:#b42318;}
+.ok{margin:0 0 16px;padding:10px 14px;border-radius:6px;background:#e7f6ec;color:#1a7f37;font-weight:600;}
+code{background:#eef0f2;padding:1px 5px;border-radius:4px;}
+button{margin-top:16px;padding:8px 20px;border:0;border-radius:6px;background:#2563eb;color:#fff;font-size:15px;cursor:pointer;}
+</style>
+{{- if not (or .Success .Unavailable)}}
+<script src="{{.Provider.ScriptURL}}" async defer></script>
+{{- end}}
+</head>
+<body>
+<main>
+<h1>{{if .Network}}{{.Network}}: {{end}}Connection Verification</h1>
+<div class="box">
+{{- if .Unavailable}}
+<p>Verification is not available right now. Please try again later.</p>
+{{- else if .Success}}
+<p class="ok">You are verified.</p>
+<p>{{.Message}}</p>
+<p class="muted">Your address <code>{{.Mask}}</code> can connect until {{time .Expires}}.</p>
+{{- else}}
+{{- if .Error}}
+<p class="error">{{.Error}}</p>
+{{- end}}
+<p>The network is limiting new connections at the moment. Complete the check below and your address will be allowed to connect.</p>
+<form method="post" action="verify">
+<div class="{{.Provider.Widget}}" data-sitekey="{{.SiteKey}}"></div>
+<button type="submit">Verify</button>
+</form>
+<p class="muted">Protected by {{.Provider.Name}}.</p>
+{{- end}}
+</div>
+</main>
+</body>
+</html>
+`))
+
+// renderPage writes the verification page
+func renderPage(w io.Writer, data pageData) error {
+	return verifyPage.Execute(w, data)
+}
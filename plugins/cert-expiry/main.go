@@ -0,0 +1,530 @@
+// Certificate Expiry Plugin for UnrealIRCd Web Panel
+// Connects to the configured TLS ports on a schedule, records the
+// certificate chains and warns before certificates expire
+
+package certexpiry
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Alert levels, in increasing order of urgency
+const (
+	levelNone     = ""
+	levelExpiring = "expiring"
+	levelExpired  = "expired"
+)
+
+// CertExpiryPlugin implements the Plugin interface
+type CertExpiryPlugin struct {
+	config   Config
+	results  map[string]*Result
+	dirty    bool
+	checkNow chan struct{}
+	mu       sync.RWMutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	Targets       string `json:"targets"`
+	DataDir       string `json:"data_dir"`
+	CheckInterval int    `json:"check_interval"`
+	WarnDays      int    `json:"warn_days"`
+	Timeout       int    `json:"timeout"`
+	AlertWebhook  string `json:"alert_webhook"`
+}
+
+// Certificate is one certificate of a presented chain
+type Certificate struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	DNSNames    []string  `json:"dns_names"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	Fingerprint string    `json:"fingerprint_sha256"`
+}
+
+// Renewal records when a new leaf certificate was first seen
+type Renewal struct {
+	Fingerprint string    `json:"fingerprint_sha256"`
+	NotAfter    time.Time `json:"not_after"`
+	FirstSeen   time.Time `json:"first_seen"`
+}
+
+// Result is the outcome of the latest check of a target
+type Result struct {
+	Target      string        `json:"target"`
+	CheckedAt   time.Time     `json:"checked_at"`
+	Error       string        `json:"error,omitempty"`
+	VerifyError string        `json:"verify_error,omitempty"`
+	Chain       []Certificate `json:"chain"`
+	ExpiresAt   *time.Time    `json:"expires_at"`
+	DaysLeft    *int          `json:"days_left"`
+	Alerted     string        `json:"alerted"`
+	History     []Renewal     `json:"history"`
+}
+
+// maxHistory is the number of renewals kept per target
+const maxHistory = 20
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &CertExpiryPlugin{
+		config: Config{
+			Targets:       "127.0.0.1:6697",
+			DataDir:       "data/plugins/cert-expiry",
+			CheckInterval: 6,
+			WarnDays:      14,
+			Timeout:       10,
+		},
+		results:  make(map[string]*Result),
+		checkNow: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *CertExpiryPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Certificate Expiry",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "TLS certificate chain and expiry monitoring for client and link ports",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *CertExpiryPlugin) Init() error {
+	p.mu.Lock()
+	var data map[string]*Result
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[cert-expiry] failed to load data: %v", err)
+	}
+	if data != nil {
+		p.results = data
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "cert-expiry-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"targets":  len(p.results),
+			"expiring": 0,
+			"expired":  0,
+			"failing":  0,
+		}
+		soonest := -1
+		for _, r := range p.results {
+			switch {
+			case r.Error != "":
+				content["failing"] = content["failing"].(int) + 1
+			case r.Alerted == levelExpired:
+				content["expired"] = content["expired"].(int) + 1
+			case r.Alerted == levelExpiring:
+				content["expiring"] = content["expiring"].(int) + 1
+			}
+			if r.DaysLeft != nil && (soonest < 0 || *r.DaysLeft < soonest) {
+				soonest = *r.DaysLeft
+			}
+		}
+		if soonest >= 0 {
+			content["soonest_days"] = soonest
+		}
+		return plugins.DashboardCard{
+			Title:   "TLS Certificates",
+			Icon:    "ShieldCheck",
+			Content: content,
+			Order:   34,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.checkLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *CertExpiryPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *CertExpiryPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/cert-expiry")
+	{
+		plugin.GET("/certificates", p.handleList)
+		plugin.GET("/certificates/:target", p.handleGet)
+		plugin.POST("/check", p.handleCheck)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *CertExpiryPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "certificates.json")
+}
+
+// save persists the results if they changed
+func (p *CertExpiryPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.results); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// checkLoop checks all targets until shutdown
+func (p *CertExpiryPlugin) checkLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.checkAll()
+		if err := p.save(); err != nil {
+			log.Printf("[cert-expiry] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.CheckInterval) * time.Hour
+		p.mu.RUnlock()
+		if interval < time.Hour {
+			interval = time.Hour
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.checkNow:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// parseTargets splits the targets setting into host:port pairs. A target
+// without a port uses 6697.
+func parseTargets(s string) []string {
+	targets := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	}) {
+		if _, _, err := net.SplitHostPort(t); err != nil {
+			t = net.JoinHostPort(strings.Trim(t, "[]"), "6697")
+		}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// checkAll checks every configured target and raises alerts
+func (p *CertExpiryPlugin) checkAll() {
+	p.mu.RLock()
+	targets := parseTargets(p.config.Targets)
+	timeout := time.Duration(p.config.Timeout) * time.Second
+	p.mu.RUnlock()
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	checked := make(map[string]*Result, len(targets))
+	for _, target := range targets {
+		checked[target] = checkTarget(target, timeout)
+	}
+
+	p.mu.Lock()
+	now := time.Now().UTC()
+	warn := p.config.WarnDays
+	alerts := make([]notify.Event, 0)
+	for target, r := range checked {
+		prev := p.results[target]
+		if prev != nil {
+			r.History = prev.History
+			r.Alerted = prev.Alerted
+		}
+
+		if len(r.Chain) > 0 {
+			leaf := r.Chain[0]
+			if len(r.History) == 0 || r.History[len(r.History)-1].Fingerprint != leaf.Fingerprint {
+				if len(r.History) > 0 {
+					// A new certificate starts a fresh alert cycle
+					r.Alerted = levelNone
+				}
+				r.History = append(r.History, Renewal{Fingerprint: leaf.Fingerprint, NotAfter: leaf.NotAfter, FirstSeen: now})
+				if len(r.History) > maxHistory {
+					r.History = r.History[len(r.History)-maxHistory:]
+				}
+			}
+
+			level := levelNone
+			switch {
+			case *r.DaysLeft < 0:
+				level = levelExpired
+			case *r.DaysLeft <= warn:
+				level = levelExpiring
+			}
+			if level != levelNone && level != r.Alerted {
+				severity := "warning"
+				title := "TLS certificate expiring"
+				msg := fmt.Sprintf("Certificate on %s expires in %d day(s) (%s)", target, *r.DaysLeft, leaf.NotAfter.Format("2006-01-02"))
+				if level == levelExpired {
+					severity = "critical"
+					title = "TLS certificate expired"
+					msg = fmt.Sprintf("Certificate on %s expired on %s", target, leaf.NotAfter.Format("2006-01-02"))
+				}
+				alerts = append(alerts, notify.Event{
+					Type:     "cert_" + level,
+					Severity: severity,
+					Title:    title,
+					Message:  msg,
+					Data: map[string]interface{}{
+						"target":      target,
+						"subject":     leaf.Subject,
+						"not_after":   leaf.NotAfter,
+						"days_left":   *r.DaysLeft,
+						"fingerprint": leaf.Fingerprint,
+					},
+				})
+			}
+			r.Alerted = level
+		}
+		p.results[target] = r
+	}
+
+	// Forget targets that were removed from the configuration
+	for target := range p.results {
+		if _, ok := checked[target]; !ok {
+			delete(p.results, target)
+		}
+	}
+	p.dirty = true
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	for _, a := range alerts {
+		log.Printf("[cert-expiry] %s: %s", a.Title, a.Message)
+		if webhook != "" {
+			a.Source = "cert-expiry"
+			a.Timestamp = now
+			go p.alert(webhook, a)
+		}
+	}
+}
+
+// checkTarget connects to a target and records the presented chain. The
+// handshake accepts any certificate so that expired or self-signed ones
+// can still be reported; verification against the system roots is done
+// separately.
+func checkTarget(target string, timeout time.Duration) *Result {
+	r := &Result{Target: target, CheckedAt: time.Now().UTC(), Chain: make([]Certificate, 0)}
+	if err := grants.Allow(grants.Outbound); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	host, _, _ := net.SplitHostPort(target)
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", target, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		r.Error = "no certificate presented"
+		return r
+	}
+	for _, cert := range certs {
+		sum := sha256.Sum256(cert.Raw)
+		r.Chain = append(r.Chain, Certificate{
+			Subject:     cert.Subject.String(),
+			Issuer:      cert.Issuer.String(),
+			DNSNames:    cert.DNSNames,
+			NotBefore:   cert.NotBefore,
+			NotAfter:    cert.NotAfter,
+			Fingerprint: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	leaf := certs[0]
+	expires := leaf.NotAfter
+	days := int(time.Until(expires).Hours() / 24)
+	if time.Now().After(expires) {
+		days = -1 - int(time.Since(expires).Hours()/24)
+	}
+	r.ExpiresAt = &expires
+	r.DaysLeft = &days
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	opts := x509.VerifyOptions{Intermediates: intermediates}
+	if net.ParseIP(host) == nil {
+		opts.DNSName = host
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		r.VerifyError = err.Error()
+	}
+	return r
+}
+
+// alert delivers an alert to the webhook
+func (p *CertExpiryPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[cert-expiry] failed to send alert: %v", err)
+	}
+}
+
+// handleList returns the latest result for every target, soonest expiry first
+func (p *CertExpiryPlugin) handleList(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Result, 0, len(p.results))
+	for _, r := range p.results {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i].DaysLeft, list[j].DaysLeft
+		switch {
+		case a == nil && b == nil:
+			return list[i].Target < list[j].Target
+		case a == nil:
+			return true
+		case b == nil:
+			return false
+		}
+		return *a < *b
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"certificates": list,
+		"warn_days":    p.config.WarnDays,
+	})
+}
+
+// handleGet returns the result for a single target
+func (p *CertExpiryPlugin) handleGet(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	r, ok := p.results[c.Param("target")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target not found"})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// handleCheck schedules an immediate check of all targets
+func (p *CertExpiryPlugin) handleCheck(c *gin.Context) {
+	select {
+	case p.checkNow <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check scheduled"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *CertExpiryPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *CertExpiryPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *CertExpiryPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *CertExpiryPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,210 @@
+/**
+ * Channel Analytics Frontend Script
+ *
+ * Lists the fastest growing channels and draws member count history.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'channel-analytics';
+  const PLUGIN_NAME = 'Channel Analytics';
+  const PAGE_PATH = '/plugins/channel-analytics';
+  const API_BASE = '/api/plugin/channel-analytics';
+
+  let rangeHours = 24;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  // Line chart of members per hour, with the hourly peak drawn faintly
+  function historyChart(points, width, height) {
+    if (points.length === 0) {
+      return '<div class="chanstats-empty">No samples in this range yet</div>';
+    }
+    const max = Math.max(...points.map(pt => pt.peak), 1);
+    const step = width / Math.max(points.length - 1, 1);
+    const line = key => points.map((pt, i) => {
+      const y = height - (pt[key] / max) * (height - 20) - 10;
+      return `${(i * step).toFixed(1)},${y.toFixed(1)}`;
+    }).join(' ');
+
+    return `
+      <svg viewBox="0 0 ${width} ${height}" class="chanstats-chart" preserveAspectRatio="none">
+        <polyline points="${line('peak')}" fill="none" stroke="var(--border-primary, #313244)" stroke-width="1"/>
+        <polyline points="${line('members')}" fill="none" stroke="var(--accent, #89b4fa)" stroke-width="2"/>
+      </svg>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('channel-analytics-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'channel-analytics-styles';
+    style.textContent = `
+      .chanstats-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .chanstats-table { width: 100%; border-collapse: collapse; }
+      .chanstats-table th, .chanstats-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+      }
+      .chanstats-table tr[data-channel] { cursor: pointer; }
+      .chanstats-table tr[data-channel]:hover { background: var(--bg-secondary, #181825); }
+      .chanstats-up { color: var(--success, #a6e3a1); }
+      .chanstats-chart { width: 100%; height: 180px; background: var(--bg-secondary, #181825); border-radius: 8px; }
+      .chanstats-range button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        margin-right: 0.25rem;
+        cursor: pointer;
+      }
+      .chanstats-range button.active { background: var(--accent, #89b4fa); color: #fff; }
+      .chanstats-empty { padding: 1rem; }
+      .chanstats-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function showHistory(container, name) {
+    const panel = container.querySelector('#chanstats-history');
+    panel.innerHTML = 'Loading...';
+    try {
+      const data = await api(`/channels/${encodeURIComponent(name)}/history?hours=${Math.max(rangeHours, 24 * 7)}`);
+      panel.innerHTML = `
+        <h3>${escapeHtml(data.name)}</h3>
+        ${historyChart(data.points, 600, 180)}
+      `;
+    } catch (e) {
+      panel.innerHTML = `<div class="chanstats-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#chanstats-body');
+    try {
+      const data = await api(`/top-growing?hours=${rangeHours}&limit=25`);
+      if (data.channels.length === 0) {
+        body.innerHTML = '<div class="chanstats-empty">No channels grew in this range</div>';
+        return;
+      }
+
+      body.innerHTML = `
+        <table class="chanstats-table">
+          <thead><tr><th>Channel</th><th>Members</th><th>Change</th><th>%</th></tr></thead>
+          <tbody>
+            ${data.channels.map(g => `
+              <tr data-channel="${escapeHtml(g.name)}">
+                <td>${escapeHtml(g.name)}</td>
+                <td>${g.members}</td>
+                <td class="chanstats-up">+${g.change}</td>
+                <td>${g.start > 0 ? g.percent.toFixed(0) + '%' : 'new'}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+
+      body.querySelectorAll('tr[data-channel]').forEach(row => {
+        row.addEventListener('click', () => showHistory(container, row.dataset.channel));
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="chanstats-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="chanstats-app" data-plugin="${PLUGIN_ID}">
+        <div class="chanstats-range">
+          ${[24, 24 * 7, 24 * 30].map(h => `<button data-hours="${h}" class="${h === rangeHours ? 'active' : ''}">${h / 24} days</button>`).join('')}
+        </div>
+        <div id="chanstats-body">Loading...</div>
+        <div id="chanstats-history"></div>
+      </div>
+    `;
+
+    container.querySelectorAll('.chanstats-range button').forEach(btn => {
+      btn.addEventListener('click', () => {
+        rangeHours = parseInt(btn.dataset.hours, 10);
+        container.querySelectorAll('.chanstats-range button').forEach(b => b.classList.toggle('active', b === btn));
+        load(container);
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('channel-analytics-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
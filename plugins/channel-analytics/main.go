@@ -0,0 +1,503 @@
+// Channel Analytics Plugin for UnrealIRCd Web Panel
+// Samples per-channel member counts over time and reports channel growth
+
+package channelanalytics
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// ChannelAnalyticsPlugin implements the Plugin interface
+type ChannelAnalyticsPlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	channels   map[string]*Series
+	dirty      bool
+	lastSample time.Time
+	lastError  string
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	SampleInterval int    `json:"sample_interval"`
+	RetentionDays  int    `json:"retention_days"`
+	MinMembers     int    `json:"min_members"`
+}
+
+// Point is the member count of a channel during one hour
+type Point struct {
+	Time    time.Time `json:"time"`
+	Members int       `json:"members"`
+	Peak    int       `json:"peak"`
+}
+
+// Series is the member count history of one channel
+type Series struct {
+	Name   string  `json:"name"`
+	Points []Point `json:"points"`
+}
+
+// Growth describes how a channel's member count changed over a window
+type Growth struct {
+	Name    string  `json:"name"`
+	Members int     `json:"members"`
+	Start   int     `json:"start"`
+	Change  int     `json:"change"`
+	Percent float64 `json:"percent"`
+}
+
+// rpcChannel is the subset of the UnrealIRCd channel object we need
+type rpcChannel struct {
+	Name     string `json:"name"`
+	NumUsers int    `json:"num_users"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ChannelAnalyticsPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/channel-analytics",
+			SampleInterval: 300,
+			RetentionDays:  30,
+			MinMembers:     3,
+		},
+		channels: make(map[string]*Series),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ChannelAnalyticsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Channel Analytics",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Per-channel member count history and growth",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ChannelAnalyticsPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.channels); err != nil {
+		log.Printf("[channel-analytics] failed to load history: %v", err)
+	}
+	if p.channels == nil {
+		p.channels = make(map[string]*Series)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "channel-analytics-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		top := p.topGrowing(24*time.Hour, 3)
+		names := make([]string, 0, len(top))
+		for _, g := range top {
+			names = append(names, g.Name+" (+"+strconv.Itoa(g.Change)+")")
+		}
+		return plugins.DashboardCard{
+			Title: "Growing Channels",
+			Icon:  "TrendingUp",
+			Content: map[string]interface{}{
+				"tracked": len(p.channels),
+				"top_24h": names,
+			},
+			Order: 40,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.sampleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ChannelAnalyticsPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ChannelAnalyticsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/channel-analytics")
+	{
+		plugin.GET("/channels", p.handleListChannels)
+		plugin.GET("/channels/:name/history", p.handleHistory)
+		plugin.GET("/top-growing", p.handleTopGrowing)
+		plugin.POST("/sample", p.handleSample)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the history file
+func (p *ChannelAnalyticsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "channels.json")
+}
+
+// save persists the history if it changed
+func (p *ChannelAnalyticsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.channels); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ChannelAnalyticsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// sampleLoop samples the channel list until shutdown
+func (p *ChannelAnalyticsPlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	p.sample()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < time.Minute {
+			interval = time.Minute
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.sample()
+			if err := p.save(); err != nil {
+				log.Printf("[channel-analytics] failed to save history: %v", err)
+			}
+		}
+	}
+}
+
+// sample records the current member count of every channel
+func (p *ChannelAnalyticsPlugin) sample() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcChannel `json:"list"`
+	}
+	err := p.client().Call(ctx, "channel.list", map[string]interface{}{"object_detail_level": 1}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		return err
+	}
+	p.lastError = ""
+	now := time.Now().UTC()
+	p.lastSample = now
+	hour := now.Truncate(time.Hour)
+
+	for _, ch := range result.List {
+		key := strings.ToLower(ch.Name)
+		s, ok := p.channels[key]
+		if !ok {
+			// Don't start tracking tiny channels, but keep recording
+			// channels we already follow so shrinkage shows up
+			if ch.NumUsers < p.config.MinMembers {
+				continue
+			}
+			s = &Series{Name: ch.Name}
+			p.channels[key] = s
+		}
+
+		if n := len(s.Points); n > 0 && s.Points[n-1].Time.Equal(hour) {
+			last := &s.Points[n-1]
+			last.Members = ch.NumUsers
+			if ch.NumUsers > last.Peak {
+				last.Peak = ch.NumUsers
+			}
+		} else {
+			s.Points = append(s.Points, Point{Time: hour, Members: ch.NumUsers, Peak: ch.NumUsers})
+		}
+	}
+
+	p.prune(now)
+	p.dirty = true
+	return nil
+}
+
+// prune drops points older than the retention period and channels left
+// without any points. Caller must hold p.mu.
+func (p *ChannelAnalyticsPlugin) prune(now time.Time) {
+	if p.config.RetentionDays < 1 {
+		return
+	}
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays)
+	for key, s := range p.channels {
+		i := sort.Search(len(s.Points), func(i int) bool {
+			return !s.Points[i].Time.Before(cutoff)
+		})
+		s.Points = s.Points[i:]
+		if len(s.Points) == 0 {
+			delete(p.channels, key)
+		}
+	}
+}
+
+// growth computes how a channel changed since the given time. Channels that
+// have not been seen since the last sample count as empty.
+// Caller must hold p.mu.
+func (p *ChannelAnalyticsPlugin) growth(s *Series, since time.Time) Growth {
+	g := Growth{Name: s.Name}
+	if len(s.Points) == 0 {
+		return g
+	}
+
+	i := sort.Search(len(s.Points), func(i int) bool {
+		return !s.Points[i].Time.Before(since)
+	})
+	if i == len(s.Points) {
+		i = len(s.Points) - 1
+	}
+	g.Start = s.Points[i].Members
+
+	last := s.Points[len(s.Points)-1]
+	if last.Time.Equal(p.lastSample.Truncate(time.Hour)) {
+		g.Members = last.Members
+	}
+	g.Change = g.Members - g.Start
+	if g.Start > 0 {
+		g.Percent = float64(g.Change) * 100 / float64(g.Start)
+	}
+	return g
+}
+
+// topGrowing returns the channels with the largest absolute growth.
+// Caller must hold p.mu.
+func (p *ChannelAnalyticsPlugin) topGrowing(window time.Duration, limit int) []Growth {
+	since := time.Now().UTC().Add(-window)
+	list := make([]Growth, 0)
+	for _, s := range p.channels {
+		if g := p.growth(s, since); g.Change > 0 {
+			list = append(list, g)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Change != list[j].Change {
+			return list[i].Change > list[j].Change
+		}
+		return list[i].Name < list[j].Name
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// parseHours reads the "hours" query parameter
+func parseHours(c *gin.Context, def int) (int, bool) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", strconv.Itoa(def)))
+	if err != nil || hours < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be a positive number"})
+		return 0, false
+	}
+	return hours, true
+}
+
+// handleListChannels returns tracked channels with their growth, largest first
+func (p *ChannelAnalyticsPlugin) handleListChannels(c *gin.Context) {
+	hours, ok := parseHours(c, 24)
+	if !ok {
+		return
+	}
+	since := time.Now().UTC().Add(-time.Duration(hours) * time.Hour)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Growth, 0, len(p.channels))
+	for _, s := range p.channels {
+		list = append(list, p.growth(s, since))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Members != list[j].Members {
+			return list[i].Members > list[j].Members
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels":    list,
+		"hours":       hours,
+		"last_sample": p.lastSample,
+		"last_error":  p.lastError,
+	})
+}
+
+// handleHistory returns the member count history of one channel
+func (p *ChannelAnalyticsPlugin) handleHistory(c *gin.Context) {
+	name := c.Param("name")
+	if !strings.HasPrefix(name, "#") {
+		name = "#" + name
+	}
+	hours, ok := parseHours(c, 24*7)
+	if !ok {
+		return
+	}
+	since := time.Now().UTC().Add(-time.Duration(hours) * time.Hour)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s, ok := p.channels[strings.ToLower(name)]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not tracked"})
+		return
+	}
+
+	i := sort.Search(len(s.Points), func(i int) bool {
+		return !s.Points[i].Time.Before(since)
+	})
+	points := make([]Point, len(s.Points)-i)
+	copy(points, s.Points[i:])
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":   s.Name,
+		"points": points,
+		"growth": p.growth(s, since),
+	})
+}
+
+// handleTopGrowing returns the fastest growing channels
+func (p *ChannelAnalyticsPlugin) handleTopGrowing(c *gin.Context) {
+	hours, ok := parseHours(c, 24)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels": p.topGrowing(time.Duration(hours)*time.Hour, limit),
+		"hours":    hours,
+	})
+}
+
+// handleSample takes a sample immediately
+func (p *ChannelAnalyticsPlugin) handleSample(c *gin.Context) {
+	if err := p.sample(); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if err := p.save(); err != nil {
+		log.Printf("[channel-analytics] failed to save history: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sample taken"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ChannelAnalyticsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ChannelAnalyticsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.RetentionDays < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be at least 1"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ChannelAnalyticsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ChannelAnalyticsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,220 @@
+/**
+ * Channel Explorer Frontend Script
+ *
+ * Search, filter and page through the cached channel list.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'channel-explorer';
+  const PLUGIN_NAME = 'Channel Explorer';
+  const PAGE_PATH = '/plugins/channel-explorer';
+  const API_BASE = '/api/plugin/channel-explorer';
+
+  const state = { q: '', topic: '', modes: '', min_members: '', sort: 'members', order: 'desc', page: 1 };
+  let debounceTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('channel-explorer-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'channel-explorer-styles';
+    style.textContent = `
+      .chanexp-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .chanexp-filters { display: flex; flex-wrap: wrap; gap: 0.5rem; }
+      .chanexp-filters input, .chanexp-filters select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.35rem 0.6rem;
+      }
+      .chanexp-table { width: 100%; border-collapse: collapse; }
+      .chanexp-table th, .chanexp-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        vertical-align: top;
+      }
+      .chanexp-table th[data-sort] { cursor: pointer; }
+      .chanexp-topic { max-width: 40rem; overflow-wrap: anywhere; }
+      .chanexp-pager { display: flex; align-items: center; gap: 0.5rem; }
+      .chanexp-pager button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .chanexp-pager button:disabled { opacity: 0.5; cursor: default; }
+      .chanexp-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#chanexp-body');
+    const params = new URLSearchParams();
+    Object.entries(state).forEach(([k, v]) => {
+      if (v !== '') params.set(k, v);
+    });
+
+    try {
+      const data = await api(`/channels?${params}`);
+      const arrow = key => state.sort === key ? (state.order === 'desc' ? ' ▼' : ' ▲') : '';
+
+      body.innerHTML = `
+        <table class="chanexp-table">
+          <thead>
+            <tr>
+              <th data-sort="name">Channel${arrow('name')}</th>
+              <th data-sort="members">Users${arrow('members')}</th>
+              <th>Modes</th>
+              <th data-sort="created">Created${arrow('created')}</th>
+              <th>Topic</th>
+            </tr>
+          </thead>
+          <tbody>
+            ${data.channels.map(ch => `
+              <tr>
+                <td>${escapeHtml(ch.name)}</td>
+                <td>${ch.num_users}</td>
+                <td>${ch.modes ? '+' + escapeHtml(ch.modes) : ''}</td>
+                <td>${escapeHtml(new Date(ch.creation_time).toLocaleDateString())}</td>
+                <td class="chanexp-topic">${escapeHtml(ch.topic)}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+        <div class="chanexp-pager">
+          <button data-page="${data.page - 1}" ${data.page <= 1 ? 'disabled' : ''}>Previous</button>
+          <span>Page ${data.page} of ${Math.max(data.pages, 1)} (${data.total} channels)</span>
+          <button data-page="${data.page + 1}" ${data.page >= data.pages ? 'disabled' : ''}>Next</button>
+          <span>Cached ${escapeHtml(new Date(data.cached_at).toLocaleTimeString())}</span>
+        </div>
+        ${data.last_error ? `<div class="chanexp-error">Last refresh failed: ${escapeHtml(data.last_error)}</div>` : ''}
+      `;
+
+      body.querySelectorAll('th[data-sort]').forEach(th => {
+        th.addEventListener('click', () => {
+          if (state.sort === th.dataset.sort) {
+            state.order = state.order === 'desc' ? 'asc' : 'desc';
+          } else {
+            state.sort = th.dataset.sort;
+            state.order = th.dataset.sort === 'name' ? 'asc' : 'desc';
+          }
+          state.page = 1;
+          load(container);
+        });
+      });
+      body.querySelectorAll('.chanexp-pager button').forEach(btn => {
+        btn.addEventListener('click', () => {
+          state.page = parseInt(btn.dataset.page, 10);
+          load(container);
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="chanexp-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="chanexp-app" data-plugin="${PLUGIN_ID}">
+        <div class="chanexp-filters">
+          <input name="q" placeholder="Channel name" value="${escapeHtml(state.q)}">
+          <input name="topic" placeholder="Topic contains" value="${escapeHtml(state.topic)}">
+          <input name="modes" placeholder="Modes, e.g. +r-i" value="${escapeHtml(state.modes)}" size="12">
+          <input name="min_members" type="number" min="0" placeholder="Min users" value="${escapeHtml(state.min_members)}">
+        </div>
+        <div id="chanexp-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelectorAll('.chanexp-filters input').forEach(input => {
+      input.addEventListener('input', () => {
+        state[input.name] = input.value.trim();
+        state.page = 1;
+        clearTimeout(debounceTimer);
+        debounceTimer = setTimeout(() => load(container), 300);
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    clearTimeout(debounceTimer);
+    const style = document.getElementById('channel-explorer-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,438 @@
+// Channel Explorer Plugin for UnrealIRCd Web Panel
+// Caches the channel list and serves search, filtering, sorting and
+// pagination without calling the RPC API on every page load
+
+package channelexplorer
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+)
+
+// maxPerPage limits the page size clients may request
+const maxPerPage = 200
+
+// ChannelExplorerPlugin implements the Plugin interface
+type ChannelExplorerPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	channels  []Channel
+	cachedAt  time.Time
+	lastError string
+	refresh   chan struct{}
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL          string `json:"rpc_url"`
+	RPCUser         string `json:"rpc_user"`
+	RPCPassword     string `json:"rpc_password"`
+	RPCInsecure     bool   `json:"rpc_insecure"`
+	RefreshInterval int    `json:"refresh_interval"`
+	HideSecret      bool   `json:"hide_secret"`
+}
+
+// Channel is a cached channel entry
+type Channel struct {
+	Name        string    `json:"name"`
+	Members     int       `json:"num_users"`
+	Created     time.Time `json:"creation_time"`
+	Topic       string    `json:"topic"`
+	TopicSetBy  string    `json:"topic_set_by,omitempty"`
+	TopicSetAt  time.Time `json:"topic_set_at"`
+	Modes       string    `json:"modes"`
+	lowerName   string
+	lowerTopic  string
+	modeLetters string
+}
+
+// rpcChannel is the UnrealIRCd channel object at detail level 1
+type rpcChannel struct {
+	Name         string `json:"name"`
+	CreationTime string `json:"creation_time"`
+	NumUsers     int    `json:"num_users"`
+	Topic        string `json:"topic"`
+	TopicSetBy   string `json:"topic_set_by"`
+	TopicSetAt   string `json:"topic_set_at"`
+	Modes        string `json:"modes"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ChannelExplorerPlugin{
+		config: Config{
+			RPCURL:          "https://127.0.0.1:8600/api",
+			RefreshInterval: 60,
+			HideSecret:      false,
+		},
+		channels: make([]Channel, 0),
+		refresh:  make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ChannelExplorerPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Channel Explorer",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Fast cached search and browsing of the channel list",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ChannelExplorerPlugin) Init() error {
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.refreshLoop()
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ChannelExplorerPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ChannelExplorerPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/channel-explorer")
+	{
+		plugin.GET("/channels", p.handleSearch)
+		plugin.POST("/refresh", p.handleRefresh)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ChannelExplorerPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// refreshLoop reloads the channel list until shutdown
+func (p *ChannelExplorerPlugin) refreshLoop() {
+	defer p.wg.Done()
+
+	p.load()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.RefreshInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 10*time.Second {
+			interval = 10 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.refresh:
+			p.load()
+		case <-time.After(interval):
+			p.load()
+		}
+	}
+}
+
+// load fetches the channel list and replaces the cache
+func (p *ChannelExplorerPlugin) load() {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcChannel `json:"list"`
+	}
+	err := p.client().Call(ctx, "channel.list", map[string]interface{}{"object_detail_level": 1}, &result)
+	if err != nil {
+		log.Printf("[channel-explorer] failed to load channel list: %v", err)
+		p.mu.Lock()
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		return
+	}
+
+	channels := make([]Channel, 0, len(result.List))
+	for _, ch := range result.List {
+		entry := Channel{
+			Name:       ch.Name,
+			Members:    ch.NumUsers,
+			Topic:      ch.Topic,
+			TopicSetBy: ch.TopicSetBy,
+			Modes:      ch.Modes,
+			lowerName:  strings.ToLower(ch.Name),
+			lowerTopic: strings.ToLower(ch.Topic),
+		}
+		entry.Created, _ = time.Parse(time.RFC3339, ch.CreationTime)
+		entry.TopicSetAt, _ = time.Parse(time.RFC3339, ch.TopicSetAt)
+		// Modes look like "ntk secret"; only the letters are filtered on
+		if fields := strings.Fields(ch.Modes); len(fields) > 0 {
+			entry.modeLetters = strings.TrimPrefix(fields[0], "+")
+		}
+		channels = append(channels, entry)
+	}
+
+	p.mu.Lock()
+	p.channels = channels
+	p.cachedAt = time.Now()
+	p.lastError = ""
+	p.mu.Unlock()
+}
+
+// filter holds the parsed search parameters
+type filter struct {
+	name          string
+	topic         string
+	minMembers    int
+	maxMembers    int
+	withModes     string
+	withoutModes  string
+	createdAfter  time.Time
+	createdBefore time.Time
+	hideSecret    bool
+}
+
+// parseModes splits "+s-i" style input into required and excluded letters
+func parseModes(s string) (with, without string) {
+	adding := true
+	for _, r := range s {
+		switch r {
+		case '+':
+			adding = true
+		case '-':
+			adding = false
+		default:
+			if adding {
+				with += string(r)
+			} else {
+				without += string(r)
+			}
+		}
+	}
+	return with, without
+}
+
+// parseTime accepts RFC 3339 timestamps, dates or unix seconds
+func parseTime(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), true
+	}
+	return time.Time{}, false
+}
+
+// match reports whether a channel passes the filter
+func (f *filter) match(ch *Channel) bool {
+	if f.name != "" && !strings.Contains(ch.lowerName, f.name) {
+		return false
+	}
+	if f.topic != "" && !strings.Contains(ch.lowerTopic, f.topic) {
+		return false
+	}
+	if ch.Members < f.minMembers || (f.maxMembers > 0 && ch.Members > f.maxMembers) {
+		return false
+	}
+	if f.hideSecret && strings.ContainsAny(ch.modeLetters, "sp") {
+		return false
+	}
+	for _, r := range f.withModes {
+		if !strings.ContainsRune(ch.modeLetters, r) {
+			return false
+		}
+	}
+	if f.withoutModes != "" && strings.ContainsAny(ch.modeLetters, f.withoutModes) {
+		return false
+	}
+	if !f.createdAfter.IsZero() && ch.Created.Before(f.createdAfter) {
+		return false
+	}
+	if !f.createdBefore.IsZero() && ch.Created.After(f.createdBefore) {
+		return false
+	}
+	return true
+}
+
+// sorters maps the "sort" parameter to an ascending comparison
+var sorters = map[string]func(a, b *Channel) bool{
+	"name":    func(a, b *Channel) bool { return a.lowerName < b.lowerName },
+	"members": func(a, b *Channel) bool { return a.Members < b.Members },
+	"created": func(a, b *Channel) bool { return a.Created.Before(b.Created) },
+	"topic":   func(a, b *Channel) bool { return a.TopicSetAt.Before(b.TopicSetAt) },
+}
+
+// handleSearch filters, sorts and paginates the cached channel list
+func (p *ChannelExplorerPlugin) handleSearch(c *gin.Context) {
+	f := filter{
+		name:  strings.ToLower(c.Query("q")),
+		topic: strings.ToLower(c.Query("topic")),
+	}
+	f.minMembers, _ = strconv.Atoi(c.Query("min_members"))
+	f.maxMembers, _ = strconv.Atoi(c.Query("max_members"))
+	f.withModes, f.withoutModes = parseModes(c.Query("modes"))
+
+	var ok bool
+	if f.createdAfter, ok = parseTime(c.Query("created_after")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid created_after"})
+		return
+	}
+	if f.createdBefore, ok = parseTime(c.Query("created_before")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid created_before"})
+		return
+	}
+
+	sortKey := c.DefaultQuery("sort", "members")
+	less, ok := sorters[sortKey]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be name, members, created or topic"})
+		return
+	}
+	desc := c.DefaultQuery("order", "desc") == "desc"
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "50"))
+	if perPage < 1 || perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	p.mu.RLock()
+	f.hideSecret = p.config.HideSecret
+	matches := make([]*Channel, 0)
+	for i := range p.channels {
+		if f.match(&p.channels[i]) {
+			matches = append(matches, &p.channels[i])
+		}
+	}
+	cachedAt := p.cachedAt
+	lastError := p.lastError
+	p.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if desc {
+			return less(matches[j], matches[i])
+		}
+		return less(matches[i], matches[j])
+	})
+
+	total := len(matches)
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+
+	results := make([]Channel, 0, end-start)
+	for _, ch := range matches[start:end] {
+		results = append(results, *ch)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels":   results,
+		"total":      total,
+		"page":       page,
+		"per_page":   perPage,
+		"pages":      (total + perPage - 1) / perPage,
+		"cached_at":  cachedAt,
+		"last_error": lastError,
+	})
+}
+
+// handleRefresh schedules an immediate reload of the cache
+func (p *ChannelExplorerPlugin) handleRefresh(c *gin.Context) {
+	select {
+	case p.refresh <- struct{}{}:
+	default:
+		// A refresh is already pending
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Refresh scheduled"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ChannelExplorerPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ChannelExplorerPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ChannelExplorerPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ChannelExplorerPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,618 @@
+// Channel Mode Audit Plugin for UnrealIRCd Web Panel
+// Records who set which channel modes and when, searchable per channel
+// and per oper
+
+package channelmodeaudit
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Record sources
+const (
+	SourceLog  = "log"
+	SourcePoll = "poll"
+)
+
+// ChannelModeAuditPlugin implements the Plugin interface
+type ChannelModeAuditPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	filter       *regexp.Regexp
+	records      []Record
+	modes        map[string]string
+	lastLog      map[string]time.Time
+	dirty        bool
+	streamOK     bool
+	streamError  string
+	lastPoll     time.Time
+	pollError    string
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL       string `json:"rpc_url"`
+	RPCUser      string `json:"rpc_user"`
+	RPCPassword  string `json:"rpc_password"`
+	RPCInsecure  bool   `json:"rpc_insecure"`
+	StreamURL    string `json:"stream_url"`
+	LogSources   string `json:"log_sources"`
+	EventFilter  string `json:"event_filter"`
+	DataDir      string `json:"data_dir"`
+	PollInterval int    `json:"poll_interval"`
+	MaxRecords   int    `json:"max_records"`
+}
+
+// Record is a single channel mode change
+type Record struct {
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel"`
+	Modes   string    `json:"modes"`
+	Setter  string    `json:"setter"`
+	Account string    `json:"account,omitempty"`
+	Oper    string    `json:"oper,omitempty"`
+	Source  string    `json:"source"`
+	EventID string    `json:"event_id,omitempty"`
+}
+
+// modeEvent holds the objects we need from a mode log entry
+type modeEvent struct {
+	Modes  string `json:"modes"`
+	Client *struct {
+		Name string `json:"name"`
+		User struct {
+			Account   string `json:"account"`
+			Operlogin string `json:"operlogin"`
+		} `json:"user"`
+	} `json:"client"`
+	Channel *struct {
+		Name string `json:"name"`
+	} `json:"channel"`
+}
+
+// rpcChannel is the subset of the UnrealIRCd channel object we need
+type rpcChannel struct {
+	Name  string `json:"name"`
+	Modes string `json:"modes"`
+}
+
+var (
+	channelName = regexp.MustCompile(`#\S+`)
+	modeString  = regexp.MustCompile(`(?:^|\s)([+-][A-Za-z+-]*[A-Za-z](?:\s+[^\s#][^\s]*)*)`)
+)
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ChannelModeAuditPlugin{
+		config: Config{
+			RPCURL:       "https://127.0.0.1:8600/api",
+			StreamURL:    "wss://127.0.0.1:8600/",
+			LogSources:   "all,!debug",
+			EventFilter:  "MODE",
+			DataDir:      "data/plugins/channel-mode-audit",
+			PollInterval: 60,
+			MaxRecords:   20000,
+		},
+		records: make([]Record, 0),
+		modes:   make(map[string]string),
+		lastLog: make(map[string]time.Time),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ChannelModeAuditPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Channel Mode Audit",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Audit trail of channel mode changes",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ChannelModeAuditPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.records); err != nil {
+		log.Printf("[channel-mode-audit] failed to load records: %v", err)
+	}
+	if p.records == nil {
+		p.records = make([]Record, 0)
+	}
+	p.compileFilter()
+	p.mu.Unlock()
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ChannelModeAuditPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ChannelModeAuditPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/channel-mode-audit")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/changes", p.handleSearch)
+		plugin.GET("/channels/:name", p.handleChannel)
+		plugin.GET("/setters/:setter", p.handleSetter)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the records file
+func (p *ChannelModeAuditPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "changes.json")
+}
+
+// save persists the records if they changed
+func (p *ChannelModeAuditPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.records); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// compileFilter compiles the event filter, matching nothing if it is
+// invalid. Caller must hold p.mu.
+func (p *ChannelModeAuditPlugin) compileFilter() {
+	re, err := regexp.Compile(p.config.EventFilter)
+	if err != nil {
+		log.Printf("[channel-mode-audit] invalid event_filter: %v", err)
+		re = nil
+	}
+	p.filter = re
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ChannelModeAuditPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// streamLoop follows the IRCd log until shutdown, restarting the stream
+// whenever the configuration changes
+func (p *ChannelModeAuditPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *ChannelModeAuditPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		p.streamError = err.Error()
+		log.Printf("[channel-mode-audit] log stream: %v", err)
+	} else {
+		p.streamError = ""
+	}
+}
+
+// handleEvent records a mode change from the log stream
+func (p *ChannelModeAuditPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	p.mu.RLock()
+	match := p.filter != nil && p.filter.MatchString(ev.EventID)
+	p.mu.RUnlock()
+	if !match {
+		return
+	}
+
+	var data modeEvent
+	if err := json.Unmarshal(ev.Raw, &data); err != nil {
+		return
+	}
+
+	rec := Record{
+		Time:    time.Now().UTC(),
+		Modes:   data.Modes,
+		Source:  SourceLog,
+		EventID: ev.EventID,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, ev.Timestamp); err == nil {
+		rec.Time = t
+	}
+	if data.Channel != nil {
+		rec.Channel = data.Channel.Name
+	} else {
+		rec.Channel = channelName.FindString(ev.Msg)
+	}
+	if rec.Modes == "" {
+		if m := modeString.FindStringSubmatch(ev.Msg); m != nil {
+			rec.Modes = m[1]
+		}
+	}
+	if data.Client != nil {
+		rec.Setter = data.Client.Name
+		rec.Account = data.Client.User.Account
+		rec.Oper = data.Client.User.Operlogin
+	}
+	if rec.Channel == "" || rec.Modes == "" {
+		return
+	}
+
+	p.mu.Lock()
+	p.lastLog[strings.ToLower(rec.Channel)] = rec.Time
+	p.add(rec)
+	p.mu.Unlock()
+}
+
+// add appends a record, trimming the oldest. Caller must hold p.mu.
+func (p *ChannelModeAuditPlugin) add(rec Record) {
+	p.records = append(p.records, rec)
+	if max := p.config.MaxRecords; max > 0 && len(p.records) > max {
+		p.records = append([]Record(nil), p.records[len(p.records)-max:]...)
+	}
+	p.dirty = true
+}
+
+// pollLoop compares channel modes between samples until shutdown, catching
+// changes that never appear in the log
+func (p *ChannelModeAuditPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.poll()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		if interval <= 0 {
+			// Polling disabled; still flush records written by the stream
+			interval = time.Minute
+		} else if interval < 15*time.Second {
+			interval = 15 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.poll()
+			if err := p.save(); err != nil {
+				log.Printf("[channel-mode-audit] failed to save records: %v", err)
+			}
+		}
+	}
+}
+
+// poll records mode differences since the previous sample
+func (p *ChannelModeAuditPlugin) poll() {
+	p.mu.RLock()
+	enabled := p.config.PollInterval > 0
+	p.mu.RUnlock()
+	if !enabled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcChannel `json:"list"`
+	}
+	err := p.client().Call(ctx, "channel.list", map[string]interface{}{"object_detail_level": 1}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.pollError = err.Error()
+		return
+	}
+	p.pollError = ""
+
+	now := time.Now().UTC()
+	seeded := !p.lastPoll.IsZero()
+	modes := make(map[string]string, len(result.List))
+	for _, ch := range result.List {
+		key := strings.ToLower(ch.Name)
+		modes[key] = ch.Modes
+
+		old, known := p.modes[key]
+		if !seeded || !known || old == ch.Modes {
+			continue
+		}
+		// The log already told us who did it
+		if t, ok := p.lastLog[key]; ok && !t.Before(p.lastPoll) {
+			continue
+		}
+		p.add(Record{
+			Time:    now,
+			Channel: ch.Name,
+			Modes:   modeDiff(old, ch.Modes),
+			Source:  SourcePoll,
+		})
+	}
+	p.modes = modes
+	p.lastPoll = now
+	for key, t := range p.lastLog {
+		if t.Before(now.Add(-time.Hour)) {
+			delete(p.lastLog, key)
+		}
+	}
+}
+
+// modeDiff describes the change between two mode strings such as "ntk key".
+// Parameter changes without a letter change return the new mode string.
+func modeDiff(old, cur string) string {
+	letters := func(s string) string {
+		if f := strings.Fields(s); len(f) > 0 {
+			return strings.TrimPrefix(f[0], "+")
+		}
+		return ""
+	}
+	o, n := letters(old), letters(cur)
+
+	var added, removed strings.Builder
+	for _, r := range n {
+		if !strings.ContainsRune(o, r) {
+			added.WriteRune(r)
+		}
+	}
+	for _, r := range o {
+		if !strings.ContainsRune(n, r) {
+			removed.WriteRune(r)
+		}
+	}
+
+	diff := ""
+	if added.Len() > 0 {
+		diff += "+" + added.String()
+	}
+	if removed.Len() > 0 {
+		diff += "-" + removed.String()
+	}
+	if diff == "" {
+		diff = "+" + cur
+	}
+	return diff
+}
+
+// query holds search parameters
+type query struct {
+	channel string
+	setter  string
+	mode    string
+	source  string
+	since   time.Time
+	limit   int
+}
+
+// match reports whether a record matches the query
+func (q *query) match(r *Record) bool {
+	if q.channel != "" && !strings.EqualFold(r.Channel, q.channel) {
+		return false
+	}
+	if q.setter != "" && !strings.EqualFold(r.Setter, q.setter) &&
+		!strings.EqualFold(r.Account, q.setter) && !strings.EqualFold(r.Oper, q.setter) {
+		return false
+	}
+	if q.mode != "" && !strings.Contains(r.Modes, q.mode) {
+		return false
+	}
+	if q.source != "" && r.Source != q.source {
+		return false
+	}
+	return q.since.IsZero() || !r.Time.Before(q.since)
+}
+
+// search returns matching records, newest first
+func (p *ChannelModeAuditPlugin) search(q query) []Record {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	results := make([]Record, 0)
+	for i := len(p.records) - 1; i >= 0; i-- {
+		if q.match(&p.records[i]) {
+			results = append(results, p.records[i])
+			if q.limit > 0 && len(results) >= q.limit {
+				break
+			}
+		}
+	}
+	return results
+}
+
+// parseQuery reads the common search parameters
+func parseQuery(c *gin.Context) (query, bool) {
+	q := query{
+		channel: c.Query("channel"),
+		setter:  c.Query("setter"),
+		mode:    c.Query("mode"),
+		source:  c.Query("source"),
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return q, false
+	}
+	q.limit = limit
+	if s := c.Query("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return q, false
+		}
+		q.since = t
+	}
+	return q, true
+}
+
+// handleStatus reports the state of the log stream and poller
+func (p *ChannelModeAuditPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"stream_connected": p.streamOK,
+		"stream_error":     p.streamError,
+		"last_poll":        p.lastPoll,
+		"poll_error":       p.pollError,
+		"records":          len(p.records),
+	})
+}
+
+// handleSearch searches all recorded mode changes
+func (p *ChannelModeAuditPlugin) handleSearch(c *gin.Context) {
+	q, ok := parseQuery(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"changes": p.search(q)})
+}
+
+// handleChannel returns mode changes for one channel
+func (p *ChannelModeAuditPlugin) handleChannel(c *gin.Context) {
+	q, ok := parseQuery(c)
+	if !ok {
+		return
+	}
+	q.channel = c.Param("name")
+	if !strings.HasPrefix(q.channel, "#") {
+		q.channel = "#" + q.channel
+	}
+	c.JSON(http.StatusOK, gin.H{"channel": q.channel, "changes": p.search(q)})
+}
+
+// handleSetter returns mode changes made by one nick, account or oper
+func (p *ChannelModeAuditPlugin) handleSetter(c *gin.Context) {
+	q, ok := parseQuery(c)
+	if !ok {
+		return
+	}
+	q.setter = c.Param("setter")
+	c.JSON(http.StatusOK, gin.H{"setter": q.setter, "changes": p.search(q)})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ChannelModeAuditPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ChannelModeAuditPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if _, err := regexp.Compile(newConfig.EventFilter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event_filter: " + err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.compileFilter()
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ChannelModeAuditPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ChannelModeAuditPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	if err := json.Unmarshal(data, &p.config); err != nil {
+		return err
+	}
+	p.compileFilter()
+	return nil
+}
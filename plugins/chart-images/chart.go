@@ -0,0 +1,290 @@
+package chartimages
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Sample is one reading of the network's numbers
+type Sample struct {
+	Time     time.Time `json:"t"`
+	Users    int       `json:"users"`
+	Opers    int       `json:"opers"`
+	Channels int       `json:"channels"`
+	Servers  int       `json:"servers"`
+}
+
+// metricLabels are the metrics that can be charted and their titles
+var metricLabels = map[string]string{
+	"users":    "Users",
+	"channels": "Channels",
+	"opers":    "Opers",
+	"servers":  "Servers",
+}
+
+// value returns the reading of a metric in a sample
+func (s *Sample) value(metric string) float64 {
+	switch metric {
+	case "users":
+		return float64(s.Users)
+	case "channels":
+		return float64(s.Channels)
+	case "opers":
+		return float64(s.Opers)
+	default:
+		return float64(s.Servers)
+	}
+}
+
+// Chart layout. Text is 5x7 glyphs with a pixel of spacing; the title is
+// drawn twice as large.
+const (
+	glyphWidth  = 6
+	glyphHeight = 7
+	titleScale  = 2
+	marginTop   = 36
+	marginRight = 16
+	marginBot   = 24
+	tickSpacing = 90
+)
+
+// palette holds the colours of a theme
+type palette struct {
+	Background color.RGBA
+	Grid       color.RGBA
+	Text       color.RGBA
+}
+
+// themes are the available chart themes
+var themes = map[string]palette{
+	"light": {
+		Background: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		Grid:       color.RGBA{0xe5, 0xe7, 0xeb, 0xff},
+		Text:       color.RGBA{0x37, 0x41, 0x51, 0xff},
+	},
+	"dark": {
+		Background: color.RGBA{0x1e, 0x1e, 0x2e, 0xff},
+		Grid:       color.RGBA{0x31, 0x32, 0x44, 0xff},
+		Text:       color.RGBA{0xcd, 0xd6, 0xf4, 0xff},
+	},
+}
+
+// point is a position in pixels
+type point struct {
+	X, Y float64
+}
+
+// tick is a labelled position on an axis, in pixels
+type tick struct {
+	Pos   float64
+	Label string
+}
+
+// chart is a laid out line chart, ready to be drawn as PNG or SVG
+type chart struct {
+	Width, Height int
+	Title         string
+	Colors        palette
+	Line          color.RGBA
+	// Plot area
+	Left, Top, Right, Bottom float64
+	YTicks                   []tick
+	XTicks                   []tick
+	// Runs of points; the line is broken where sampling stopped
+	Segments [][]point
+}
+
+// chartOptions are the choices a chart is drawn with
+type chartOptions struct {
+	Metric  string
+	Network string
+	Hours   int
+	Width   int
+	Height  int
+	Theme   string
+	Line    color.RGBA
+	// Samples further apart than this break the line
+	Gap time.Duration
+}
+
+// layoutChart places the samples of the last opt.Hours hours on a chart.
+// Samples must be sorted by time.
+func layoutChart(samples []Sample, opt chartOptions, now time.Time) *chart {
+	from := now.Add(-time.Duration(opt.Hours) * time.Hour)
+	start := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(from) })
+	samples = samples[start:]
+
+	max := 0.0
+	for i := range samples {
+		max = math.Max(max, samples[i].value(opt.Metric))
+	}
+	step := niceStep(max, 4)
+	top := math.Ceil(max/step) * step
+	if top == 0 {
+		top = step
+	}
+
+	ch := &chart{
+		Width:  opt.Width,
+		Height: opt.Height,
+		Title:  chartTitle(opt),
+		Colors: themes[opt.Theme],
+		Line:   opt.Line,
+	}
+
+	labelLen := 0
+	for v := 0.0; v <= top; v += step {
+		labelLen = maxInt(labelLen, len(formatCount(v)))
+	}
+	ch.Left = float64(12 + labelLen*glyphWidth + 6)
+	ch.Top = marginTop
+	ch.Right = float64(opt.Width - marginRight)
+	ch.Bottom = float64(opt.Height - marginBot)
+
+	plotW := ch.Right - ch.Left
+	plotH := ch.Bottom - ch.Top
+	span := now.Sub(from)
+	xOf := func(t time.Time) float64 {
+		return ch.Left + plotW*float64(t.Sub(from))/float64(span)
+	}
+	yOf := func(v float64) float64 {
+		return ch.Bottom - plotH*v/top
+	}
+
+	for v := 0.0; v <= top; v += step {
+		ch.YTicks = append(ch.YTicks, tick{Pos: yOf(v), Label: formatCount(v)})
+	}
+	// Leave out marks whose label would run off the image
+	for _, t := range timeTicks(from, now, int(plotW)/tickSpacing, xOf) {
+		if int(t.Pos)+textWidth(t.Label, 1)/2 < opt.Width {
+			ch.XTicks = append(ch.XTicks, t)
+		}
+	}
+
+	// Average runs of samples so there is at most one point per pixel
+	per := time.Duration(float64(span) / math.Max(plotW, 1))
+	var seg []point
+	var last time.Time
+	for i := 0; i < len(samples); {
+		bucket := samples[i].Time.Truncate(per)
+		sum, n := 0.0, 0
+		first := samples[i].Time
+		for i < len(samples) && samples[i].Time.Truncate(per) == bucket {
+			sum += samples[i].value(opt.Metric)
+			n++
+			i++
+		}
+		if !last.IsZero() && first.Sub(last) > opt.Gap && len(seg) > 0 {
+			ch.Segments = append(ch.Segments, seg)
+			seg = nil
+		}
+		seg = append(seg, point{X: xOf(first), Y: yOf(sum / float64(n))})
+		last = samples[i-1].Time
+	}
+	if len(seg) > 0 {
+		ch.Segments = append(ch.Segments, seg)
+	}
+	return ch
+}
+
+// chartTitle names the metric and the period shown
+func chartTitle(opt chartOptions) string {
+	title := metricLabels[opt.Metric]
+	if opt.Network != "" {
+		title = opt.Network + " " + title
+	}
+	switch {
+	case opt.Hours == 24:
+		return title + ", last 24 hours (UTC)"
+	case opt.Hours%24 == 0:
+		return fmt.Sprintf("%s, last %d days (UTC)", title, opt.Hours/24)
+	case opt.Hours == 1:
+		return title + ", last hour (UTC)"
+	default:
+		return fmt.Sprintf("%s, last %d hours (UTC)", title, opt.Hours)
+	}
+}
+
+// tickSteps are the intervals the time axis may be marked at
+var tickSteps = []time.Duration{
+	15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour, 48 * time.Hour, 7 * 24 * time.Hour, 14 * 24 * time.Hour, 28 * 24 * time.Hour,
+}
+
+// timeTicks marks the time axis at round UTC times, no more than max of
+// them
+func timeTicks(from, to time.Time, max int, xOf func(time.Time) float64) []tick {
+	if max < 2 {
+		max = 2
+	}
+	span := to.Sub(from)
+	step := tickSteps[len(tickSteps)-1]
+	for _, s := range tickSteps {
+		if int(span/s) <= max {
+			step = s
+			break
+		}
+	}
+	layout := "15:04"
+	if step >= 24*time.Hour {
+		layout = "Jan 2"
+	}
+	ticks := make([]tick, 0, max+1)
+	for t := from.Truncate(step).Add(step); !t.After(to); t = t.Add(step) {
+		ticks = append(ticks, tick{Pos: xOf(t), Label: t.UTC().Format(layout)})
+	}
+	return ticks
+}
+
+// niceStep picks a round interval of 1, 2 or 5 times a power of ten that
+// divides max into about n parts. It is never below 1, as counts are whole.
+func niceStep(max float64, n int) float64 {
+	raw := max / float64(n)
+	if raw <= 1 {
+		return 1
+	}
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if m*mag >= raw {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// formatCount writes an axis value compactly, as 950, 1.2k or 3M
+func formatCount(v float64) string {
+	switch {
+	case v >= 1e6:
+		return strconv.FormatFloat(v/1e6, 'f', -1, 64) + "M"
+	case v >= 1e4:
+		return strconv.FormatFloat(v/1e3, 'f', -1, 64) + "k"
+	default:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+}
+
+// parseColor reads a colour written as #rrggbb
+func parseColor(s string) (color.RGBA, bool) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, false
+	}
+	n, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 0xff}, true
+}
+
+// maxInt returns the larger of a and b
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
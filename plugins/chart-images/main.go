@@ -0,0 +1,453 @@
+// Chart Images Plugin for UnrealIRCd Web Panel
+// Renders network statistics as PNG and SVG charts on the server, for
+// emails, chat digests and pages where JavaScript charts can't run
+
+package chartimages
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// saveEvery is how often new samples are written to disk
+const saveEvery = 5 * time.Minute
+
+// Limits on the size of a chart
+const (
+	minWidth  = 200
+	maxWidth  = 2000
+	minHeight = 120
+	maxHeight = 1000
+)
+
+// ChartImagesPlugin implements the Plugin interface
+type ChartImagesPlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	samples    []Sample
+	lastSample time.Time
+	sampleErr  string
+	dirty      bool
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	Network        string `json:"network"`
+	ImageToken     string `json:"image_token"`
+	SampleInterval int    `json:"sample_interval"`
+	RetentionDays  int    `json:"retention_days"`
+	DefaultHours   int    `json:"default_hours"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+	Theme          string `json:"theme"`
+	LineColor      string `json:"line_color"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Samples []Sample `json:"samples"`
+}
+
+// statsResult is the part of stats.get we sample
+type statsResult struct {
+	Server struct {
+		Total int `json:"total"`
+	} `json:"server"`
+	User struct {
+		Total int `json:"total"`
+		Oper  int `json:"oper"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ChartImagesPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/chart-images",
+			SampleInterval: 300,
+			RetentionDays:  30,
+			DefaultHours:   24,
+			Width:          800,
+			Height:         300,
+			Theme:          "light",
+			LineColor:      "#3b82f6",
+		},
+		samples: make([]Sample, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ChartImagesPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Chart Images",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Renders network statistics as PNG and SVG chart images",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ChartImagesPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[chart-images] failed to load data: %v", err)
+	}
+	if data.Samples != nil {
+		p.samples = data.Samples
+		sort.Slice(p.samples, func(i, j int) bool { return p.samples[i].Time.Before(p.samples[j].Time) })
+	}
+	p.mu.Unlock()
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.sampleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ChartImagesPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ChartImagesPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/chart-images")
+	{
+		plugin.GET("/charts/:file", p.handleChart)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file samples are kept in
+func (p *ChartImagesPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "samples.json")
+}
+
+// save writes samples to disk if they changed
+func (p *ChartImagesPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Samples: p.samples}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *ChartImagesPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// sampleLoop samples the network every sample_interval until shutdown
+func (p *ChartImagesPlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	lastSave := time.Now()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.sample()
+		if time.Since(lastSave) >= saveEvery {
+			if err := p.save(); err != nil {
+				log.Printf("[chart-images] failed to save data: %v", err)
+			}
+			lastSave = time.Now()
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// sample records the network's numbers and drops samples older than the
+// retention
+func (p *ChartImagesPlugin) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var stats statsResult
+	err := p.client().Call(ctx, "stats.get", nil, &stats)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.sampleErr = err.Error()
+		log.Printf("[chart-images] failed to sample: %v", err)
+		return
+	}
+	p.sampleErr = ""
+
+	now := time.Now().UTC()
+	p.samples = append(p.samples, Sample{
+		Time:     now,
+		Users:    stats.User.Total,
+		Opers:    stats.User.Oper,
+		Channels: stats.Channel.Total,
+		Servers:  stats.Server.Total,
+	})
+	p.lastSample = now
+
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays)
+	drop := sort.Search(len(p.samples), func(i int) bool { return !p.samples[i].Time.Before(cutoff) })
+	if drop > 0 {
+		p.samples = append([]Sample(nil), p.samples[drop:]...)
+	}
+	p.dirty = true
+}
+
+// authorized checks the token query parameter when an image token is set,
+// and answers 401 when it does not match
+func (p *ChartImagesPlugin) authorized(c *gin.Context) bool {
+	p.mu.RLock()
+	token := p.config.ImageToken
+	p.mu.RUnlock()
+
+	if token == "" {
+		return true
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid image token"})
+		return false
+	}
+	return true
+}
+
+// intQuery reads a whole number query parameter, falling back to def when
+// it is absent
+func intQuery(c *gin.Context, name string, def, min, max int) (int, error) {
+	s := c.Query(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", name, min, max)
+	}
+	return n, nil
+}
+
+// handleChart renders a metric as users.png, channels.svg and so on. The
+// hours, width, height and theme query parameters override the defaults.
+func (p *ChartImagesPlugin) handleChart(c *gin.Context) {
+	if !p.authorized(c) {
+		return
+	}
+	file := c.Param("file")
+	ext := path.Ext(file)
+	metric := strings.TrimSuffix(file, ext)
+	if _, ok := metricLabels[metric]; !ok || (ext != ".png" && ext != ".svg") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chart not found"})
+		return
+	}
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	opt := chartOptions{Metric: metric, Network: cfg.Network, Theme: c.DefaultQuery("theme", cfg.Theme)}
+	if _, ok := themes[opt.Theme]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "theme must be light or dark"})
+		return
+	}
+	var err error
+	if opt.Hours, err = intQuery(c, "hours", cfg.DefaultHours, 1, cfg.RetentionDays*24); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if opt.Width, err = intQuery(c, "width", cfg.Width, minWidth, maxWidth); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if opt.Height, err = intQuery(c, "height", cfg.Height, minHeight, maxHeight); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opt.Line, _ = parseColor(cfg.LineColor)
+	opt.Gap = 3 * time.Duration(cfg.SampleInterval) * time.Second
+
+	p.mu.RLock()
+	ch := layoutChart(p.samples, opt, time.Now().UTC())
+	p.mu.RUnlock()
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", cfg.SampleInterval))
+	if ext == ".svg" {
+		c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", renderSVG(ch))
+		return
+	}
+	data, err := renderPNG(ch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render chart"})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// handleStatus returns sampling state and the charts on offer
+func (p *ChartImagesPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	metrics := make([]string, 0, len(metricLabels))
+	for m := range metricLabels {
+		metrics = append(metrics, m)
+	}
+	sort.Strings(metrics)
+	status := gin.H{
+		"samples":   len(p.samples),
+		"error":     p.sampleErr,
+		"metrics":   metrics,
+		"max_hours": p.config.RetentionDays * 24,
+		"token_set": p.config.ImageToken != "",
+	}
+	if len(p.samples) > 0 {
+		status["oldest"] = p.samples[0].Time
+	}
+	if !p.lastSample.IsZero() {
+		status["last_sample"] = p.lastSample
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *ChartImagesPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.ImageToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ChartImagesPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.SampleInterval < 10 || newConfig.SampleInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sample_interval must be between 10 and 3600 seconds"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 365"})
+		return
+	}
+	if newConfig.DefaultHours < 1 || newConfig.DefaultHours > newConfig.RetentionDays*24 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "default_hours must be between 1 and the retention in hours"})
+		return
+	}
+	if newConfig.Width < minWidth || newConfig.Width > maxWidth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("width must be between %d and %d", minWidth, maxWidth)})
+		return
+	}
+	if newConfig.Height < minHeight || newConfig.Height > maxHeight {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("height must be between %d and %d", minHeight, maxHeight)})
+		return
+	}
+	if _, ok := themes[newConfig.Theme]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "theme must be light or dark"})
+		return
+	}
+	if _, ok := parseColor(newConfig.LineColor); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "line_color must be a colour such as #3b82f6"})
+		return
+	}
+	newConfig.Network = strings.TrimSpace(newConfig.Network)
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.ImageToken == "" {
+		newConfig.ImageToken = p.config.ImageToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ChartImagesPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ChartImagesPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
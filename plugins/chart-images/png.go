@@ -0,0 +1,125 @@
+package chartimages
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+// areaAlpha is the opacity of the fill under the line
+const areaAlpha = 0.15
+
+// renderPNG draws a chart as a PNG image
+func renderPNG(ch *chart) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, ch.Width, ch.Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{ch.Colors.Background}, image.Point{}, draw.Src)
+
+	left, right := int(ch.Left), int(ch.Right)
+	for _, t := range ch.YTicks {
+		y := int(math.Round(t.Pos))
+		for x := left; x <= right; x++ {
+			img.SetRGBA(x, y, ch.Colors.Grid)
+		}
+		drawText(img, t.Label, left-6-textWidth(t.Label, 1), y-glyphHeight/2, 1, ch.Colors.Text)
+	}
+	bottom := int(math.Round(ch.Bottom))
+	for _, t := range ch.XTicks {
+		x := int(math.Round(t.Pos))
+		for y := bottom; y <= bottom+4; y++ {
+			img.SetRGBA(x, y, ch.Colors.Grid)
+		}
+		drawText(img, t.Label, x-textWidth(t.Label, 1)/2, bottom+8, 1, ch.Colors.Text)
+	}
+	drawText(img, ch.Title, left, 10, titleScale, ch.Colors.Text)
+
+	if len(ch.Segments) == 0 {
+		msg := "No data yet"
+		drawText(img, msg, (left+right-textWidth(msg, 1))/2, int(ch.Top+ch.Bottom)/2, 1, ch.Colors.Text)
+	}
+	for _, seg := range ch.Segments {
+		fillArea(img, seg, ch.Bottom, ch.Line)
+	}
+	for _, seg := range ch.Segments {
+		for i := range seg {
+			a := seg[i]
+			b := a
+			if i+1 < len(seg) {
+				b = seg[i+1]
+			}
+			drawLine(img, a, b, ch.Line)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawText writes s with its top left corner at x, y
+func drawText(img *image.RGBA, s string, x, y, scale int, c color.RGBA) {
+	for _, r := range s {
+		if g, ok := glyph(r); ok {
+			for row, line := range g {
+				for col := 0; col < len(line); col++ {
+					if line[col] != '#' {
+						continue
+					}
+					for dy := 0; dy < scale; dy++ {
+						for dx := 0; dx < scale; dx++ {
+							img.SetRGBA(x+col*scale+dx, y+row*scale+dy, c)
+						}
+					}
+				}
+			}
+		}
+		x += glyphWidth * scale
+	}
+}
+
+// drawLine draws a line two pixels thick from a to b
+func drawLine(img *image.RGBA, a, b point, c color.RGBA) {
+	steps := int(math.Ceil(math.Max(math.Abs(b.X-a.X), math.Abs(b.Y-a.Y)) * 2))
+	for i := 0; i <= steps; i++ {
+		f := 0.0
+		if steps > 0 {
+			f = float64(i) / float64(steps)
+		}
+		x := int(math.Round(a.X + (b.X-a.X)*f))
+		y := int(math.Round(a.Y + (b.Y-a.Y)*f))
+		img.SetRGBA(x, y, c)
+		img.SetRGBA(x+1, y, c)
+		img.SetRGBA(x, y+1, c)
+		img.SetRGBA(x+1, y+1, c)
+	}
+}
+
+// fillArea shades the area between a run of points and the bottom of the
+// plot
+func fillArea(img *image.RGBA, seg []point, bottom float64, c color.RGBA) {
+	for i := 0; i+1 < len(seg); i++ {
+		a, b := seg[i], seg[i+1]
+		for x := int(math.Ceil(a.X)); float64(x) < b.X; x++ {
+			y := a.Y + (b.Y-a.Y)*(float64(x)-a.X)/(b.X-a.X)
+			for py := int(math.Round(y)); py < int(bottom); py++ {
+				blend(img, x, py, c, areaAlpha)
+			}
+		}
+	}
+}
+
+// blend mixes c into the pixel at x, y with the given opacity
+func blend(img *image.RGBA, x, y int, c color.RGBA, alpha float64) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+	bg := img.RGBAAt(x, y)
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a)*(1-alpha) + float64(b)*alpha))
+	}
+	img.SetRGBA(x, y, color.RGBA{mix(bg.R, c.R), mix(bg.G, c.G), mix(bg.B, c.B), 0xff})
+}
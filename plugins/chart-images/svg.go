@@ -0,0 +1,59 @@
+package chartimages
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"strings"
+)
+
+// renderSVG draws a chart as an SVG image
+func renderSVG(ch *chart) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11" role="img" aria-label="%s">`,
+		ch.Width, ch.Height, ch.Width, ch.Height, html.EscapeString(ch.Title))
+	fmt.Fprintf(&b, `<title>%s</title>`, html.EscapeString(ch.Title))
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, hex(ch.Colors.Background))
+
+	text := hex(ch.Colors.Text)
+	grid := hex(ch.Colors.Grid)
+	for _, t := range ch.YTicks {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`, ch.Left, t.Pos, ch.Right, t.Pos, grid)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="end" dominant-baseline="middle">%s</text>`,
+			ch.Left-6, t.Pos, text, html.EscapeString(t.Label))
+	}
+	for _, t := range ch.XTicks {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`, t.Pos, ch.Bottom, t.Pos, ch.Bottom+4, grid)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="middle">%s</text>`,
+			t.Pos, ch.Bottom+17, text, html.EscapeString(t.Label))
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="24" fill="%s" font-size="15" font-weight="bold">%s</text>`,
+		ch.Left, text, html.EscapeString(ch.Title))
+
+	if len(ch.Segments) == 0 {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="middle">No data yet</text>`,
+			(ch.Left+ch.Right)/2, (ch.Top+ch.Bottom)/2, text)
+	}
+	line := hex(ch.Line)
+	for _, seg := range ch.Segments {
+		var path strings.Builder
+		for i, pt := range seg {
+			if i == 0 {
+				fmt.Fprintf(&path, "M%.1f %.1f", pt.X, pt.Y)
+			} else {
+				fmt.Fprintf(&path, " L%.1f %.1f", pt.X, pt.Y)
+			}
+		}
+		fmt.Fprintf(&b, `<path d="%s L%.1f %.1f L%.1f %.1f Z" fill="%s" fill-opacity="%.2f"/>`,
+			path.String(), seg[len(seg)-1].X, ch.Bottom, seg[0].X, ch.Bottom, line, areaAlpha)
+		fmt.Fprintf(&b, `<path d="%s" fill="none" stroke="%s" stroke-width="2" stroke-linejoin="round" stroke-linecap="round"/>`,
+			path.String(), line)
+	}
+	b.WriteString(`</svg>`)
+	return []byte(b.String())
+}
+
+// hex writes a colour as #rrggbb
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,384 @@
+/**
+ * Client Census Frontend Script
+ *
+ * Shows which IRC clients and versions are in use, how that changed across
+ * census runs, and manages the opt-out list.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'client-census';
+  const PLUGIN_NAME = 'Client Census';
+  const PAGE_PATH = '/plugins/client-census';
+  const API_BASE = '/api/plugin/client-census';
+  const COLORS = ['#89b4fa', '#a6e3a1', '#f9e2af', '#fab387', '#cba6f7', '#94e2d5', '#f38ba8', '#74c7ec'];
+  const OTHER_COLOR = '#6c7086';
+
+  let groupBy = 'client';
+  let pollTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('client-census-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'client-census-styles';
+    style.textContent = `
+      .ccn-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .ccn-card { background: var(--bg-secondary, #181825); border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 1rem; }
+      .ccn-card h3 { margin: 0 0 0.75rem; color: var(--text-primary, #cdd6f4); font-size: 1rem; }
+      .ccn-status { display: flex; flex-wrap: wrap; align-items: center; gap: 1rem; }
+      .ccn-status .ccn-grow { flex: 1; }
+      .ccn-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.35rem 0.8rem;
+        cursor: pointer;
+      }
+      .ccn-btn.primary, .ccn-btn.active { background: var(--accent, #89b4fa); color: #fff; }
+      .ccn-btn:disabled { opacity: 0.5; cursor: default; }
+      .ccn-bar-row { display: grid; grid-template-columns: 12rem 1fr 6rem; gap: 0.5rem; align-items: center; margin-bottom: 0.3rem; font-size: 0.85rem; }
+      .ccn-bar-row span:first-child { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; color: var(--text-primary, #cdd6f4); }
+      .ccn-bar { height: 0.8rem; border-radius: 3px; }
+      .ccn-chart { width: 100%; height: 180px; border-radius: 6px; background: var(--bg-primary, #11111b); }
+      .ccn-legend { display: flex; flex-wrap: wrap; gap: 0.75rem; font-size: 0.85rem; margin-top: 0.5rem; }
+      .ccn-legend i { display: inline-block; width: 0.8rem; height: 0.8rem; border-radius: 2px; margin-right: 0.3rem; vertical-align: middle; }
+      .ccn-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .ccn-table th, .ccn-table td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid var(--border-primary, #313244); }
+      .ccn-form { display: flex; gap: 0.5rem; margin-top: 0.75rem; }
+      .ccn-form input, .ccn-form select {
+        background: var(--bg-primary, #11111b);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.35rem 0.5rem;
+      }
+      .ccn-muted { color: var(--text-muted, #6c7086); }
+      .ccn-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function statusHtml(status) {
+    const run = status.running;
+    const last = status.last;
+    let text;
+    if (run) {
+      text = `Census running since ${escapeHtml(formatTime(run.started_at))}: asked ${run.asked} of ${run.queued}, ${run.replied} replied`;
+    } else if (last) {
+      text = `Last census ${escapeHtml(formatTime(last.at))} (${escapeHtml(last.trigger)}): ${last.asked} asked, ${last.replied} replied, ${last.hinted} from hints`;
+      if (last.error) text += ` <span class="ccn-error">${escapeHtml(last.error)}</span>`;
+    } else {
+      text = 'No census has run yet';
+    }
+    return `
+      <div class="ccn-grow">
+        <div>${text}</div>
+        <div class="ccn-muted">${status.samples} users sampled, ${status.opt_outs} opt-outs${status.next_at && !run ? `, next census ${escapeHtml(formatTime(status.next_at))}` : ''}</div>
+      </div>
+      ${run
+        ? '<button class="ccn-btn" data-action="cancel">Cancel</button>'
+        : '<button class="ccn-btn primary" data-action="run">Run census now</button>'}
+    `;
+  }
+
+  function distributionHtml(dist) {
+    if (dist.total === 0) {
+      return '<div class="ccn-muted">No clients identified yet</div>';
+    }
+    const max = dist.entries[0].count;
+    return dist.entries.slice(0, 25).map((e, i) => `
+      <div class="ccn-bar-row">
+        <span title="${escapeHtml(e.name)}">${escapeHtml(e.name)}</span>
+        <div class="ccn-bar" style="width:${(100 * e.count / max).toFixed(1)}%;background:${COLORS[i % COLORS.length]}"></div>
+        <span>${e.count} (${e.percent}%)</span>
+      </div>
+    `).join('') + `
+      <div class="ccn-muted">${dist.total} identified: ${dist.sources.ctcp} by CTCP reply, ${dist.sources.hint} by hint; ${dist.sources.no_reply} didn't reply</div>
+    `;
+  }
+
+  // Stacked share of the most common clients per census, oldest first
+  function trendHtml(snapshots) {
+    const list = snapshots.filter(s => s.sampled > 0).reverse();
+    if (list.length < 2) {
+      return '<div class="ccn-muted">Trends appear after two censuses</div>';
+    }
+    const totals = {};
+    list.forEach(s => Object.entries(s.clients || {}).forEach(([name, n]) => { totals[name] = (totals[name] || 0) + n / s.sampled; }));
+    const top = Object.keys(totals).sort((a, b) => totals[b] - totals[a]).slice(0, COLORS.length - 1);
+    const width = 600;
+    const height = 180;
+    const step = width / list.length;
+    const bars = list.map((s, i) => {
+      let y = height;
+      let rest = s.sampled;
+      const parts = top.map((name, j) => {
+        const n = (s.clients || {})[name] || 0;
+        rest -= n;
+        return [name, n, COLORS[j]];
+      });
+      parts.push(['Other', rest, OTHER_COLOR]);
+      return parts.map(([name, n, color]) => {
+        if (!n) return '';
+        const h = (n / s.sampled) * height;
+        y -= h;
+        return `<rect x="${(i * step + 1).toFixed(1)}" y="${y.toFixed(1)}" width="${Math.max(step - 2, 1).toFixed(1)}" height="${h.toFixed(1)}" fill="${color}"><title>${escapeHtml(formatTime(s.at))}: ${escapeHtml(name)} ${(100 * n / s.sampled).toFixed(1)}%</title></rect>`;
+      }).join('');
+    }).join('');
+
+    return `
+      <svg viewBox="0 0 ${width} ${height}" class="ccn-chart" preserveAspectRatio="none">${bars}</svg>
+      <div class="ccn-legend">
+        ${top.map((name, j) => `<span><i style="background:${COLORS[j]}"></i>${escapeHtml(name)}</span>`).join('')}
+        <span><i style="background:${OTHER_COLOR}"></i>Other</span>
+      </div>
+    `;
+  }
+
+  function snapshotsHtml(snapshots) {
+    if (snapshots.length === 0) return '<div class="ccn-muted">No censuses yet</div>';
+    return `
+      <table class="ccn-table">
+        <thead><tr><th>Time</th><th>Trigger</th><th>Online</th><th>Asked</th><th>Replied</th><th>Hinted</th><th>Opted out</th><th>No CTCP</th><th>Skipped</th><th>Duration</th></tr></thead>
+        <tbody>
+          ${snapshots.slice(0, 20).map(s => `
+            <tr>
+              <td>${escapeHtml(formatTime(s.at))}${s.error ? `<div class="ccn-error">${escapeHtml(s.error)}</div>` : ''}</td>
+              <td>${escapeHtml(s.trigger)}</td>
+              <td>${s.online}</td>
+              <td>${s.asked}</td>
+              <td>${s.replied}</td>
+              <td>${s.hinted}</td>
+              <td>${s.opted_out}</td>
+              <td>${s.no_ctcp}</td>
+              <td>${s.skipped}</td>
+              <td>${s.duration_s}s</td>
+            </tr>
+          `).join('')}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function optOutsHtml(optOuts) {
+    const rows = optOuts.length === 0
+      ? '<div class="ccn-muted">Nobody has opted out</div>'
+      : `
+        <table class="ccn-table">
+          <thead><tr><th>Kind</th><th>Name</th><th>Via</th><th>Added</th><th></th></tr></thead>
+          <tbody>
+            ${optOuts.map(o => `
+              <tr>
+                <td>${escapeHtml(o.kind)}</td>
+                <td>${escapeHtml(o.name)}</td>
+                <td>${escapeHtml(o.via === 'panel' ? `panel (${o.by})` : `/msg from ${o.by}`)}</td>
+                <td>${escapeHtml(formatTime(o.at))}</td>
+                <td><button class="ccn-btn" data-action="remove-optout" data-id="${escapeHtml(o.id)}">Remove</button></td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    return rows + `
+      <form class="ccn-form" id="ccn-optout-form">
+        <select name="kind"><option value="nick">Nick</option><option value="account">Account</option></select>
+        <input name="name" placeholder="Nick, account or mask" required>
+        <button class="ccn-btn primary" type="submit">Add opt-out</button>
+      </form>
+    `;
+  }
+
+  async function loadStatus(container) {
+    const el = container.querySelector('#ccn-status');
+    if (!el) return;
+    try {
+      const status = await api('GET', '/status');
+      el.innerHTML = statusHtml(status);
+      clearTimeout(pollTimer);
+      if (status.running) {
+        pollTimer = setTimeout(() => {
+          if (!container.querySelector('#ccn-status')) return;
+          loadStatus(container).then(() => {
+            if (!container.querySelector('[data-action="cancel"]')) load(container);
+          });
+        }, 3000);
+      }
+    } catch (e) {
+      el.innerHTML = `<div class="ccn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadDistribution(container) {
+    const el = container.querySelector('#ccn-dist');
+    try {
+      el.innerHTML = distributionHtml(await api('GET', `/distribution?by=${groupBy}`));
+    } catch (e) {
+      el.innerHTML = `<div class="ccn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function load(container) {
+    loadStatus(container);
+    loadDistribution(container);
+    try {
+      const [snaps, optOuts] = await Promise.all([api('GET', '/snapshots?limit=90'), api('GET', '/optouts')]);
+      container.querySelector('#ccn-trend').innerHTML = trendHtml(snaps.snapshots);
+      container.querySelector('#ccn-snapshots').innerHTML = snapshotsHtml(snaps.snapshots);
+      container.querySelector('#ccn-optouts').innerHTML = optOutsHtml(optOuts.opt_outs);
+    } catch (e) {
+      container.querySelector('#ccn-snapshots').innerHTML = `<div class="ccn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="ccn-app" data-plugin="${PLUGIN_ID}">
+        <div class="ccn-card ccn-status" id="ccn-status">Loading...</div>
+        <div class="ccn-card">
+          <h3>Distribution
+            <button class="ccn-btn ${groupBy === 'client' ? 'active' : ''}" data-by="client">Clients</button>
+            <button class="ccn-btn ${groupBy === 'version' ? 'active' : ''}" data-by="version">Versions</button>
+          </h3>
+          <div id="ccn-dist">Loading...</div>
+        </div>
+        <div class="ccn-card"><h3>Client share over time</h3><div id="ccn-trend">Loading...</div></div>
+        <div class="ccn-card"><h3>Censuses</h3><div id="ccn-snapshots">Loading...</div></div>
+        <div class="ccn-card">
+          <h3>Opt-outs</h3>
+          <p class="ccn-muted">Users can also opt themselves out by messaging the census client OPTOUT while it is connected.</p>
+          <div id="ccn-optouts">Loading...</div>
+        </div>
+      </div>
+    `;
+
+    const app = container.querySelector('.ccn-app');
+    app.addEventListener('click', async (e) => {
+      const byBtn = e.target.closest('[data-by]');
+      if (byBtn) {
+        groupBy = byBtn.dataset.by;
+        app.querySelectorAll('[data-by]').forEach(b => b.classList.toggle('active', b === byBtn));
+        loadDistribution(container);
+        return;
+      }
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+      btn.disabled = true;
+      try {
+        if (btn.dataset.action === 'run') {
+          await api('POST', '/run');
+        } else if (btn.dataset.action === 'cancel') {
+          await api('POST', '/cancel');
+        } else if (btn.dataset.action === 'remove-optout') {
+          if (!confirm('Remove this opt-out? The user may be asked again in the next census.')) {
+            btn.disabled = false;
+            return;
+          }
+          await api('DELETE', `/optouts/${encodeURIComponent(btn.dataset.id)}`);
+        }
+        load(container);
+      } catch (err) {
+        btn.disabled = false;
+        alert(err.message);
+      }
+    });
+
+    app.addEventListener('submit', async (e) => {
+      if (e.target.id !== 'ccn-optout-form') return;
+      e.preventDefault();
+      const form = e.target;
+      try {
+        await api('POST', '/optouts', { kind: form.kind.value, name: form.name.value.trim() });
+        load(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    clearTimeout(pollTimer);
+    const style = document.getElementById('client-census-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,191 @@
+package clientcensus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+)
+
+// maxRawLength is the longest reply kept as sent
+const maxRawLength = 200
+
+// unknownClient names replies that couldn't be parsed
+const unknownClient = "Unknown"
+
+// versionToken matches a version number such as 2.16.1, v1.4.3 or 0.14.0-rc1
+var versionToken = regexp.MustCompile(`^[vV]?(\d+(?:\.\d+)+[\w.+-]*)$`)
+
+// knownClients canonicalises the names of common clients, keyed by their
+// lower-cased name without spaces or by its first word
+var knownClients = map[string]string{
+	"adiirc":       "AdiIRC",
+	"hexchat":      "HexChat",
+	"irccloud":     "IRCCloud",
+	"irssi":        "irssi",
+	"kiwiirc":      "KiwiIRC",
+	"konversation": "Konversation",
+	"kvirc":        "KVIrc",
+	"limechat":     "LimeChat",
+	"mirc":         "mIRC",
+	"quassel":      "Quassel",
+	"textual":      "Textual",
+	"thelounge":    "The Lounge",
+	"weechat":      "WeeChat",
+	"xchat":        "XChat",
+	"znc":          "ZNC",
+}
+
+// parseVersion splits a CTCP VERSION reply into a client name and version.
+// The name is the words before the first version number, so
+// "HexChat 2.16.1 [x64] / Windows 10" is HexChat 2.16.1. Formatting
+// codes are removed first.
+func parseVersion(raw string) (string, string) {
+	raw = stripFormatting(raw)
+	words := strings.Fields(raw)
+	name := make([]string, 0, 3)
+	version := ""
+	for _, w := range words {
+		if m := versionToken.FindStringSubmatch(strings.Trim(w, "()[],;:")); m != nil {
+			version = m[1]
+			break
+		}
+		if len(name) == 3 || strings.ContainsAny(w, "/([-") {
+			break
+		}
+		name = append(name, strings.Trim(w, ",:"))
+	}
+	client := strings.Join(name, " ")
+	if client == "" && len(words) > 0 {
+		client = words[0]
+	}
+	if client == "" {
+		return unknownClient, version
+	}
+
+	if canonical, ok := knownClients[strings.ToLower(strings.Join(name, ""))]; ok {
+		return canonical, version
+	}
+	if len(name) > 0 {
+		if canonical, ok := knownClients[strings.ToLower(name[0])]; ok {
+			return canonical, version
+		}
+	}
+	return client, version
+}
+
+// stripFormatting removes mIRC formatting codes and other control
+// characters
+func stripFormatting(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0x03:
+			// Colour: up to two digits, optionally a comma and two more
+			j := i + 1
+			for n := 0; n < 2 && j < len(s) && s[j] >= '0' && s[j] <= '9'; n++ {
+				j++
+			}
+			if j+1 < len(s) && s[j] == ',' && s[j+1] >= '0' && s[j+1] <= '9' {
+				j += 2
+				if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+					j++
+				}
+			}
+			i = j - 1
+		default:
+			if c >= 0x20 && c != 0x7f {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return strings.TrimFunc(b.String(), unicode.IsSpace)
+}
+
+// hintRule names the client of users whose connection matches a pattern,
+// for clients that identify themselves without a CTCP reply
+type hintRule struct {
+	field  string
+	mask   string
+	client string
+}
+
+// hintFields are the user fields a hint rule can match
+var hintFields = map[string]bool{"realname": true, "username": true, "host": true, "group": true}
+
+// parseHints parses hint rules written as field:mask=Client, separated by
+// semicolons, e.g. "realname:*kiwiirc*=KiwiIRC; group:websocket-users=Web"
+func parseHints(s string) ([]hintRule, error) {
+	rules := make([]hintRule, 0)
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		field, rest, ok := strings.Cut(item, ":")
+		if !ok || !hintFields[strings.ToLower(strings.TrimSpace(field))] {
+			return nil, fmt.Errorf("hint %q must start with realname:, username:, host: or group:", item)
+		}
+		mask, client, ok := strings.Cut(rest, "=")
+		mask, client = strings.TrimSpace(mask), strings.TrimSpace(client)
+		if !ok || mask == "" || client == "" {
+			return nil, fmt.Errorf("hint %q must be field:mask=Client", item)
+		}
+		rules = append(rules, hintRule{field: strings.ToLower(strings.TrimSpace(field)), mask: mask, client: client})
+	}
+	return rules, nil
+}
+
+// hintFor returns the client named by the first matching hint rule, or ""
+func hintFor(rules []hintRule, u *rpcUser) string {
+	for _, r := range rules {
+		switch r.field {
+		case "realname":
+			if ircname.MatchMask(r.mask, u.User.Realname) {
+				return r.client
+			}
+		case "username":
+			if ircname.MatchMask(r.mask, u.User.Username) {
+				return r.client
+			}
+		case "host":
+			if ircname.MatchMask(r.mask, u.Hostname) || ircname.MatchMask(r.mask, u.IP) {
+				return r.client
+			}
+		case "group":
+			for _, g := range u.User.SecurityGroups {
+				if ircname.MatchMask(r.mask, g) {
+					return r.client
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// sampleKey identifies a user in the census without storing who they are:
+// a salted hash of their account, or their nick when not logged in
+func sampleKey(salt, nick, account string) string {
+	id := "nick:" + strings.ToLower(nick)
+	if account != "" {
+		id = "account:" + strings.ToLower(account)
+	}
+	sum := sha256.Sum256([]byte(salt + "\x00" + id))
+	return hex.EncodeToString(sum[:12])
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
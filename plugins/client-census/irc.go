@@ -0,0 +1,215 @@
+package clientcensus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// message is a parsed IRC line
+type message struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+// param returns the i'th parameter, or "" if there are fewer
+func (m message) param(i int) string {
+	if i < len(m.params) {
+		return m.params[i]
+	}
+	return ""
+}
+
+// last returns the last parameter, usually the human readable text
+func (m message) last() string {
+	if len(m.params) == 0 {
+		return ""
+	}
+	return m.params[len(m.params)-1]
+}
+
+// nick returns the nick part of the prefix
+func (m message) nick() string {
+	if i := strings.IndexByte(m.prefix, '!'); i >= 0 {
+		return m.prefix[:i]
+	}
+	return m.prefix
+}
+
+// parseLine splits an IRC line into prefix, command and parameters,
+// dropping any message tags
+func parseLine(line string) message {
+	var m message
+	if strings.HasPrefix(line, "@") {
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			line = strings.TrimLeft(line[i+1:], " ")
+		} else {
+			return m
+		}
+	}
+	if strings.HasPrefix(line, ":") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return m
+		}
+		m.prefix, line = line[1:i], strings.TrimLeft(line[i+1:], " ")
+	}
+	for line != "" {
+		if strings.HasPrefix(line, ":") {
+			m.params = append(m.params, line[1:])
+			break
+		}
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			m.params = append(m.params, line)
+			break
+		}
+		m.params = append(m.params, line[:i])
+		line = strings.TrimLeft(line[i+1:], " ")
+	}
+	if len(m.params) > 0 {
+		m.command, m.params = strings.ToUpper(m.params[0]), m.params[1:]
+	}
+	return m
+}
+
+// ircConn is the census client's connection. Lines read are delivered on
+// Lines, which is closed when the connection ends; PINGs are answered as
+// they arrive.
+type ircConn struct {
+	Nick  string
+	Lines chan message
+
+	conn    net.Conn
+	writeMu sync.Mutex
+	err     error
+}
+
+// dialIRC connects to the server and registers, returning once the
+// server has welcomed the client
+func dialIRC(ctx context.Context, cfg Config) (*ircConn, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(cfg.IRCHost, strconv.Itoa(cfg.IRCPort))
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	var conn net.Conn
+	var err error
+	if cfg.IRCTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{
+			ServerName:         cfg.IRCHost,
+			InsecureSkipVerify: cfg.IRCInsecure,
+		}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ircConn{Nick: cfg.Nick, Lines: make(chan message, 64), conn: conn}
+	go c.readLoop()
+
+	if cfg.ServerPassword != "" {
+		c.Send("PASS :%s", cfg.ServerPassword)
+	}
+	c.Send("NICK %s", c.Nick)
+	c.Send("USER census 0 * :Client version census - /msg %s OPTOUT to opt out", cfg.Nick)
+
+	deadline := time.NewTimer(30 * time.Second)
+	defer deadline.Stop()
+	for tries := 0; ; {
+		select {
+		case <-ctx.Done():
+			c.Close()
+			return nil, ctx.Err()
+		case <-deadline.C:
+			c.Close()
+			return nil, errors.New("timed out waiting for registration")
+		case m, ok := <-c.Lines:
+			if !ok {
+				return nil, fmt.Errorf("connection closed during registration: %v", c.Err())
+			}
+			switch m.command {
+			case "001":
+				c.Nick = m.param(0)
+				return c, nil
+			case "433":
+				if tries++; tries > 3 {
+					c.Close()
+					return nil, fmt.Errorf("nick %s is in use", cfg.Nick)
+				}
+				c.Nick = fmt.Sprintf("%s%d", cfg.Nick, tries)
+				c.Send("NICK %s", c.Nick)
+			case "ERROR", "464", "465":
+				c.Close()
+				return nil, fmt.Errorf("server refused the connection: %s", m.last())
+			}
+		}
+	}
+}
+
+// readLoop delivers lines until the connection ends
+func (c *ircConn) readLoop() {
+	defer close(c.Lines)
+	r := bufio.NewReaderSize(c.conn, 8192)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			c.err = err
+			return
+		}
+		m := parseLine(strings.TrimRight(line, "\r\n"))
+		if m.command == "PING" {
+			c.Send("PONG :%s", m.last())
+			continue
+		}
+		c.Lines <- m
+	}
+}
+
+// Send writes a line to the server
+func (c *ircConn) Send(format string, args ...interface{}) error {
+	line := fmt.Sprintf(format, args...)
+	if strings.ContainsAny(line, "\r\n") {
+		return errors.New("line contains a line break")
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write([]byte(line + "\r\n"))
+	return err
+}
+
+// Err returns why the connection ended. Only valid once Lines is closed.
+func (c *ircConn) Err() error {
+	return c.err
+}
+
+// Quit says goodbye and closes the connection
+func (c *ircConn) Quit(reason string) {
+	c.Send("QUIT :%s", reason)
+	time.AfterFunc(2*time.Second, func() { c.conn.Close() })
+	for range c.Lines {
+	}
+}
+
+// Close closes the connection without waiting
+func (c *ircConn) Close() {
+	c.conn.Close()
+	go func() {
+		for range c.Lines {
+		}
+	}()
+}
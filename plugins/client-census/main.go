@@ -0,0 +1,974 @@
+// Client Census Plugin for UnrealIRCd Web Panel
+// Surveys which IRC clients and versions are used on the network with CTCP
+// VERSION and gateway hints, respecting opt-outs and rate limits
+
+package clientcensus
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mrand "math/rand"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Sample sources
+const (
+	SourceCTCP    = "ctcp"
+	SourceHint    = "hint"
+	SourceNoReply = "no_reply"
+)
+
+// Opt-out kinds
+const (
+	OptOutNick    = "nick"
+	OptOutAccount = "account"
+)
+
+// retryAfter is how long a failed scheduled census waits before retrying
+const retryAfter = 15 * time.Minute
+
+// errBusy is returned when a census is already running
+var errBusy = errors.New("a census is already running")
+
+// ClientCensusPlugin implements the Plugin interface
+type ClientCensusPlugin struct {
+	config      Config
+	salt        string
+	samples     map[string]*Sample
+	snapshots   []*Snapshot
+	optOuts     []*OptOut
+	running     *Progress
+	cancelRun   context.CancelFunc
+	lastAttempt time.Time
+	rpc         *jsonrpc.Client
+	stop        chan struct{}
+	mu          sync.RWMutex
+	wg          sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	IRCHost        string `json:"irc_host"`
+	IRCPort        int    `json:"irc_port"`
+	IRCTLS         bool   `json:"irc_tls"`
+	IRCInsecure    bool   `json:"irc_insecure"`
+	ServerPassword string `json:"server_password"`
+	Nick           string `json:"nick"`
+	IntervalHours  int    `json:"interval_hours"`
+	PerMinute      int    `json:"per_minute"`
+	MaxPerRun      int    `json:"max_per_run"`
+	ReplyWait      int    `json:"reply_wait"`
+	ResurveyDays   int    `json:"resurvey_days"`
+	KeepDays       int    `json:"keep_days"`
+	KeepSnapshots  int    `json:"keep_snapshots"`
+	HintRules      string `json:"hint_rules"`
+	ExcludeMasks   string `json:"exclude_masks"`
+	SkipOpers      bool   `json:"skip_opers"`
+}
+
+// Sample is the latest census answer of one user. Users are identified
+// by a salted hash only; see sampleKey.
+type Sample struct {
+	Client  string    `json:"client,omitempty"`
+	Version string    `json:"version,omitempty"`
+	Raw     string    `json:"raw,omitempty"`
+	Source  string    `json:"source"`
+	At      time.Time `json:"at"`
+}
+
+// Snapshot is the outcome of one census run and the distribution of
+// clients across all current samples afterwards
+type Snapshot struct {
+	At       time.Time      `json:"at"`
+	Trigger  string         `json:"trigger"`
+	Online   int            `json:"online"`
+	Asked    int            `json:"asked"`
+	Replied  int            `json:"replied"`
+	Hinted   int            `json:"hinted"`
+	OptedOut int            `json:"opted_out"`
+	NoCTCP   int            `json:"no_ctcp"`
+	Skipped  int            `json:"skipped"`
+	Sampled  int            `json:"sampled"`
+	Clients  map[string]int `json:"clients"`
+	Versions map[string]int `json:"versions"`
+	Duration float64        `json:"duration_s"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// OptOut excludes a nick or account, or a mask of them, from the census
+type OptOut struct {
+	ID   string    `json:"id"`
+	Kind string    `json:"kind"`
+	Name string    `json:"name"`
+	Via  string    `json:"via"`
+	By   string    `json:"by"`
+	At   time.Time `json:"at"`
+}
+
+// Progress is the state of a running census
+type Progress struct {
+	Trigger   string    `json:"trigger"`
+	By        string    `json:"by"`
+	StartedAt time.Time `json:"started_at"`
+	Queued    int       `json:"queued"`
+	Asked     int       `json:"asked"`
+	Replied   int       `json:"replied"`
+}
+
+// DistEntry is one client or version in a distribution
+type DistEntry struct {
+	Name    string  `json:"name"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// storeData is the on-disk form of the census
+type storeData struct {
+	Salt      string             `json:"salt"`
+	Samples   map[string]*Sample `json:"samples"`
+	Snapshots []*Snapshot        `json:"snapshots"`
+	OptOuts   []*OptOut          `json:"opt_outs"`
+}
+
+// rpcUser is the subset of the UnrealIRCd user object used here
+type rpcUser struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	User     struct {
+		Username       string   `json:"username"`
+		Realname       string   `json:"realname"`
+		Account        string   `json:"account"`
+		Modes          string   `json:"modes"`
+		SecurityGroups []string `json:"security-groups"`
+	} `json:"user"`
+}
+
+// candidate is a user to send a CTCP VERSION to
+type candidate struct {
+	nick string
+	key  string
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ClientCensusPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/client-census",
+			IRCHost:       "127.0.0.1",
+			IRCPort:       6697,
+			IRCTLS:        true,
+			Nick:          "census",
+			IntervalHours: 24,
+			PerMinute:     30,
+			MaxPerRun:     500,
+			ReplyWait:     30,
+			ResurveyDays:  30,
+			KeepDays:      90,
+			KeepSnapshots: 365,
+			SkipOpers:     true,
+		},
+		samples:   make(map[string]*Sample),
+		snapshots: make([]*Snapshot, 0),
+		optOuts:   make([]*OptOut, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ClientCensusPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Client Census",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Distribution of IRC clients and versions on the network over time",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ClientCensusPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[client-census] failed to load census: %v", err)
+	}
+	p.salt = data.Salt
+	if p.salt == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		p.salt = hex.EncodeToString(b)
+	}
+	if data.Samples != nil {
+		p.samples = data.Samples
+	}
+	if data.Snapshots != nil {
+		p.snapshots = data.Snapshots
+	}
+	if data.OptOuts != nil {
+		p.optOuts = data.OptOuts
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "client-census-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		clients, _, sampled := distribution(p.samples)
+		top := sortDist(clients, sampled)
+		if len(top) > 5 {
+			top = top[:5]
+		}
+		content := map[string]interface{}{
+			"sampled":     sampled,
+			"top_clients": top,
+		}
+		if n := len(p.snapshots); n > 0 {
+			content["last_census"] = p.snapshots[n-1].At
+		}
+		if p.running != nil {
+			content["status"] = fmt.Sprintf("Census running: %d of %d asked", p.running.Asked, p.running.Queued)
+		}
+		return plugins.DashboardCard{
+			Title:   "Client Census",
+			Icon:    "PieChart",
+			Content: content,
+			Order:   85,
+			Size:    "md",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.scheduleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ClientCensusPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelRun != nil {
+			p.cancelRun()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ClientCensusPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/client-census")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/distribution", p.handleDistribution)
+		plugin.GET("/snapshots", p.handleSnapshots)
+		plugin.POST("/run", p.handleRun)
+		plugin.POST("/cancel", p.handleCancel)
+		plugin.GET("/optouts", p.handleListOptOuts)
+		plugin.POST("/optouts", p.handleAddOptOut)
+		plugin.DELETE("/optouts/:id", p.handleDeleteOptOut)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the saved census
+func (p *ClientCensusPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "census.json")
+}
+
+// save persists the census. Caller must hold p.mu.
+func (p *ClientCensusPlugin) save() {
+	data := storeData{Salt: p.salt, Samples: p.samples, Snapshots: p.snapshots, OptOuts: p.optOuts}
+	if err := storage.SaveJSON(p.storePath(), data); err != nil {
+		log.Printf("[client-census] failed to save census: %v", err)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ClientCensusPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// optedOut reports whether a user is on the opt-out list. Caller must
+// hold p.mu.
+func (p *ClientCensusPlugin) optedOut(nick, account string) bool {
+	for _, o := range p.optOuts {
+		switch o.Kind {
+		case OptOutAccount:
+			if account != "" && ircname.MatchMask(o.Name, account) {
+				return true
+			}
+		case OptOutNick:
+			if ircname.MatchMask(o.Name, nick) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addOptOut adds an opt-out and forgets the user's sample. Caller must
+// hold p.mu.
+func (p *ClientCensusPlugin) addOptOut(kind, name, via, by string) *OptOut {
+	for _, o := range p.optOuts {
+		if o.Kind == kind && strings.EqualFold(o.Name, name) {
+			return o
+		}
+	}
+	o := &OptOut{ID: newID(), Kind: kind, Name: name, Via: via, By: by, At: time.Now()}
+	p.optOuts = append(p.optOuts, o)
+	if !strings.ContainsAny(name, "*?") {
+		if kind == OptOutAccount {
+			delete(p.samples, sampleKey(p.salt, "", name))
+		} else {
+			delete(p.samples, sampleKey(p.salt, name, ""))
+		}
+	}
+	p.save()
+	return o
+}
+
+// removeOptOut removes the opt-outs of a nick or account, returning how
+// many were removed. Caller must hold p.mu.
+func (p *ClientCensusPlugin) removeOptOut(kind, name string) int {
+	kept := p.optOuts[:0]
+	n := 0
+	for _, o := range p.optOuts {
+		if o.Kind == kind && strings.EqualFold(o.Name, name) {
+			n++
+			continue
+		}
+		kept = append(kept, o)
+	}
+	p.optOuts = kept
+	if n > 0 {
+		p.save()
+	}
+	return n
+}
+
+// excluded reports whether a user matches one of the exclude masks
+func excluded(masks []string, u *rpcUser) bool {
+	full := u.Name + "!" + u.User.Username + "@" + u.Hostname
+	fullIP := u.Name + "!" + u.User.Username + "@" + u.IP
+	for _, mask := range masks {
+		if ircname.MatchMask(mask, full) || ircname.MatchMask(mask, fullIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// begin marks a census as running and returns the config and context to
+// run it with
+func (p *ClientCensusPlugin) begin(trigger, actor string) (Config, context.Context, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running != nil {
+		return Config{}, nil, errBusy
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.running = &Progress{Trigger: trigger, By: actor, StartedAt: time.Now()}
+	p.cancelRun = cancel
+	return p.config, ctx, nil
+}
+
+// end clears the running census
+func (p *ClientCensusPlugin) end() {
+	p.mu.Lock()
+	if p.cancelRun != nil {
+		p.cancelRun()
+	}
+	p.running = nil
+	p.cancelRun = nil
+	p.mu.Unlock()
+}
+
+// run takes a census and records its snapshot. The caller must have
+// called begin.
+func (p *ClientCensusPlugin) run(ctx context.Context, cfg Config, trigger string) *Snapshot {
+	defer p.end()
+	start := time.Now()
+	snap := &Snapshot{At: start, Trigger: trigger}
+	if err := p.survey(ctx, cfg, snap); err != nil {
+		snap.Error = err.Error()
+		log.Printf("[client-census] census failed: %v", err)
+	}
+	snap.Duration = math.Round(time.Since(start).Seconds()*10) / 10
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cutoff := time.Now().AddDate(0, 0, -cfg.KeepDays)
+	for key, s := range p.samples {
+		if cfg.KeepDays > 0 && s.At.Before(cutoff) {
+			delete(p.samples, key)
+		}
+	}
+	snap.Clients, snap.Versions, snap.Sampled = distribution(p.samples)
+	p.snapshots = append(p.snapshots, snap)
+	if cfg.KeepSnapshots > 0 && len(p.snapshots) > cfg.KeepSnapshots {
+		p.snapshots = p.snapshots[len(p.snapshots)-cfg.KeepSnapshots:]
+	}
+	p.save()
+	log.Printf("[client-census] %s census: %d asked, %d replied, %d hinted", trigger, snap.Asked, snap.Replied, snap.Hinted)
+	return snap
+}
+
+// survey sorts the online users into those to skip, those a hint rule
+// identifies and those to ask, then asks them
+func (p *ClientCensusPlugin) survey(ctx context.Context, cfg Config, snap *Snapshot) error {
+	rules, err := parseHints(cfg.HintRules)
+	if err != nil {
+		return err
+	}
+	masks := lists.Split(cfg.ExcludeMasks)
+
+	var out struct {
+		List []rpcUser `json:"list"`
+	}
+	if err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &out); err != nil {
+		return err
+	}
+	snap.Online = len(out.List)
+
+	now := time.Now()
+	resurvey := time.Duration(cfg.ResurveyDays) * 24 * time.Hour
+	accounts := make(map[string]string, len(out.List))
+	candidates := make([]candidate, 0)
+
+	p.mu.Lock()
+	for i := range out.List {
+		u := &out.List[i]
+		accounts[strings.ToLower(u.Name)] = u.User.Account
+		modes := u.User.Modes
+		switch {
+		case strings.EqualFold(u.Name, cfg.Nick), strings.ContainsAny(modes, "SB"):
+			snap.Skipped++
+			continue
+		case cfg.SkipOpers && strings.Contains(modes, "o"):
+			snap.Skipped++
+			continue
+		case excluded(masks, u):
+			snap.Skipped++
+			continue
+		case p.optedOut(u.Name, u.User.Account):
+			snap.OptedOut++
+			continue
+		}
+
+		key := sampleKey(p.salt, u.Name, u.User.Account)
+		if client := hintFor(rules, u); client != "" {
+			p.samples[key] = &Sample{Client: client, Source: SourceHint, At: now}
+			snap.Hinted++
+			continue
+		}
+		// User mode +T blocks CTCPs
+		if strings.Contains(modes, "T") {
+			snap.NoCTCP++
+			continue
+		}
+		if s := p.samples[key]; s != nil && s.Source != SourceHint && now.Sub(s.At) < resurvey {
+			continue
+		}
+		candidates = append(candidates, candidate{nick: u.Name, key: key})
+	}
+	mrand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	if cfg.MaxPerRun > 0 && len(candidates) > cfg.MaxPerRun {
+		candidates = candidates[:cfg.MaxPerRun]
+	}
+	if p.running != nil {
+		p.running.Queued = len(candidates)
+	}
+	p.mu.Unlock()
+
+	if len(candidates) == 0 {
+		return nil
+	}
+	return p.ask(ctx, cfg, candidates, accounts, snap)
+}
+
+// ask connects as the census client and sends CTCP VERSION to each
+// candidate at the configured rate, recording replies as they arrive.
+// Once every request is sent it waits reply_wait seconds for the rest.
+// Users can opt out or back in by messaging the client OPTOUT or OPTIN.
+func (p *ClientCensusPlugin) ask(ctx context.Context, cfg Config, candidates []candidate, accounts map[string]string, snap *Snapshot) error {
+	conn, err := dialIRC(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", cfg.IRCHost, err)
+	}
+	defer conn.Quit("Census complete")
+
+	perMinute := cfg.PerMinute
+	if perMinute <= 0 {
+		perMinute = 30
+	}
+	ticker := time.NewTicker(time.Minute / time.Duration(perMinute))
+	defer ticker.Stop()
+
+	pending := make(map[string]candidate)
+	next := 0
+	var done time.Time
+	defer func() {
+		// Whoever didn't answer isn't asked again until resurvey_days
+		p.mu.Lock()
+		now := time.Now()
+		for _, c := range pending {
+			p.samples[c.key] = &Sample{Source: SourceNoReply, At: now}
+		}
+		p.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case now := <-ticker.C:
+			if next < len(candidates) {
+				c := candidates[next]
+				next++
+				// They may have opted out since the run started
+				p.mu.Lock()
+				skip := p.optedOut(c.nick, accounts[strings.ToLower(c.nick)])
+				if !skip {
+					snap.Asked++
+					if p.running != nil {
+						p.running.Asked = snap.Asked
+					}
+				}
+				p.mu.Unlock()
+				if !skip {
+					if err := conn.Send("PRIVMSG %s :\x01VERSION\x01", c.nick); err != nil {
+						return err
+					}
+					pending[strings.ToLower(c.nick)] = c
+				}
+				if next == len(candidates) {
+					done = now.Add(time.Duration(cfg.ReplyWait) * time.Second)
+				}
+			} else if !done.IsZero() && now.After(done) {
+				return nil
+			}
+
+		case m, ok := <-conn.Lines:
+			if !ok {
+				return fmt.Errorf("connection lost: %v", conn.Err())
+			}
+			if m.command == "ERROR" {
+				return fmt.Errorf("disconnected: %s", m.last())
+			}
+			if !strings.EqualFold(m.param(0), conn.Nick) {
+				continue
+			}
+			text := m.last()
+			switch {
+			case m.command == "NOTICE" && strings.HasPrefix(text, "\x01VERSION"):
+				c, ok := pending[strings.ToLower(m.nick())]
+				if !ok {
+					continue
+				}
+				delete(pending, strings.ToLower(m.nick()))
+				raw := strings.TrimSpace(strings.Trim(strings.TrimPrefix(text, "\x01VERSION"), "\x01"))
+				client, version := parseVersion(raw)
+				snap.Replied++
+				p.mu.Lock()
+				p.samples[c.key] = &Sample{Client: client, Version: version, Raw: truncate(stripFormatting(raw), maxRawLength), Source: SourceCTCP, At: time.Now()}
+				if p.running != nil {
+					p.running.Replied = snap.Replied
+				}
+				p.mu.Unlock()
+
+			case m.command == "PRIVMSG":
+				p.handleMessage(conn, m.nick(), accounts[strings.ToLower(m.nick())], text)
+				delete(pending, strings.ToLower(m.nick()))
+			}
+		}
+	}
+}
+
+// handleMessage answers a private message to the census client
+func (p *ClientCensusPlugin) handleMessage(conn *ircConn, nick, account, text string) {
+	if strings.HasPrefix(text, "\x01") {
+		if strings.HasPrefix(text, "\x01VERSION") {
+			conn.Send("NOTICE %s :\x01VERSION UnrealIRCd Web Panel client census\x01", nick)
+		}
+		return
+	}
+
+	kind, name := OptOutNick, nick
+	if account != "" {
+		kind, name = OptOutAccount, account
+	}
+	switch strings.ToUpper(strings.TrimSpace(stripFormatting(text))) {
+	case "OPTOUT":
+		p.mu.Lock()
+		p.addOptOut(kind, name, "message", nick)
+		p.mu.Unlock()
+		log.Printf("[client-census] %s opted out", nick)
+		conn.Send("NOTICE %s :You won't be included in the client census any more. Send OPTIN to undo this.", nick)
+	case "OPTIN":
+		p.mu.Lock()
+		p.removeOptOut(kind, name)
+		p.mu.Unlock()
+		conn.Send("NOTICE %s :You may be included in the client census again.", nick)
+	default:
+		conn.Send("NOTICE %s :I ask clients which IRC client they use, to see which clients the network should support. Send OPTOUT to be left out.", nick)
+	}
+}
+
+// distribution counts the clients and versions of the samples that name
+// a client
+func distribution(samples map[string]*Sample) (map[string]int, map[string]int, int) {
+	clients := make(map[string]int)
+	versions := make(map[string]int)
+	total := 0
+	for _, s := range samples {
+		if s.Client == "" {
+			continue
+		}
+		total++
+		clients[s.Client]++
+		name := s.Client
+		if s.Version != "" {
+			name += " " + s.Version
+		}
+		versions[name]++
+	}
+	return clients, versions, total
+}
+
+// sortDist returns a distribution as entries, most common first
+func sortDist(counts map[string]int, total int) []DistEntry {
+	out := make([]DistEntry, 0, len(counts))
+	for name, n := range counts {
+		e := DistEntry{Name: name, Count: n}
+		if total > 0 {
+			e.Percent = math.Round(float64(n)*1000/float64(total)) / 10
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// scheduleLoop runs a census every interval_hours
+func (p *ClientCensusPlugin) scheduleLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.runScheduled(time.Now())
+		}
+	}
+}
+
+// runScheduled runs a census if one is due
+func (p *ClientCensusPlugin) runScheduled(now time.Time) {
+	p.mu.Lock()
+	interval := time.Duration(p.config.IntervalHours) * time.Hour
+	due := interval > 0 && p.running == nil && now.Sub(p.lastAttempt) >= retryAfter
+	if n := len(p.snapshots); due && n > 0 {
+		due = now.Sub(p.snapshots[n-1].At) >= interval
+	}
+	if due {
+		p.lastAttempt = now
+	}
+	p.mu.Unlock()
+	if !due {
+		return
+	}
+
+	cfg, ctx, err := p.begin("scheduled", "")
+	if err != nil {
+		return
+	}
+	p.run(ctx, cfg, "scheduled")
+}
+
+// handleStatus reports whether a census is running and when the last and
+// next ones are
+func (p *ClientCensusPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"running":  p.running,
+		"samples":  len(p.samples),
+		"opt_outs": len(p.optOuts),
+	}
+	if n := len(p.snapshots); n > 0 {
+		last := p.snapshots[n-1]
+		status["last"] = last
+		if p.config.IntervalHours > 0 {
+			status["next_at"] = last.At.Add(time.Duration(p.config.IntervalHours) * time.Hour)
+		}
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleDistribution returns the current distribution of clients, or of
+// versions with ?by=version. ?client= limits versions to one client and
+// ?source=ctcp or hint to one kind of sample.
+func (p *ClientCensusPlugin) handleDistribution(c *gin.Context) {
+	by := c.DefaultQuery("by", "client")
+	source := c.Query("source")
+	client := c.Query("client")
+
+	p.mu.RLock()
+	samples := make(map[string]*Sample)
+	sources := map[string]int{SourceCTCP: 0, SourceHint: 0, SourceNoReply: 0}
+	for key, s := range p.samples {
+		sources[s.Source]++
+		if (source == "" || s.Source == source) && (client == "" || s.Client == client) {
+			samples[key] = s
+		}
+	}
+	p.mu.RUnlock()
+
+	clients, versions, total := distribution(samples)
+	counts := clients
+	if by == "version" {
+		counts = versions
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"by":      by,
+		"entries": sortDist(counts, total),
+		"total":   total,
+		"sources": sources,
+	})
+}
+
+// handleSnapshots returns past census runs, newest first
+func (p *ClientCensusPlugin) handleSnapshots(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "90"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 90
+	}
+
+	p.mu.RLock()
+	list := make([]*Snapshot, 0, limit)
+	for i := len(p.snapshots) - 1; i >= 0 && len(list) < limit; i-- {
+		list = append(list, p.snapshots[i])
+	}
+	total := len(p.snapshots)
+	p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"snapshots": list, "total": total})
+}
+
+// handleRun starts a census in the background
+func (p *ClientCensusPlugin) handleRun(c *gin.Context) {
+	actor := access.Actor(c)
+	cfg, ctx, err := p.begin("manual", actor)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A census is already running"})
+		return
+	}
+	log.Printf("[client-census] %s started a census", actor)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.run(ctx, cfg, "manual")
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Census started"})
+}
+
+// handleCancel stops the running census. Replies so far are kept.
+func (p *ClientCensusPlugin) handleCancel(c *gin.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancelRun == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No census is running"})
+		return
+	}
+	p.cancelRun()
+	log.Printf("[client-census] %s cancelled the census", access.Actor(c))
+	c.JSON(http.StatusOK, gin.H{"message": "Census cancelled"})
+}
+
+// handleListOptOuts returns the opt-out list
+func (p *ClientCensusPlugin) handleListOptOuts(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"opt_outs": p.optOuts})
+}
+
+// handleAddOptOut opts a nick or account out on a user's behalf
+func (p *ClientCensusPlugin) handleAddOptOut(c *gin.Context) {
+	var req struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Kind != OptOutNick && req.Kind != OptOutAccount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be nick or account"})
+		return
+	}
+	if req.Name == "" || len(req.Name) > 64 || strings.ContainsAny(req.Name, " !@,\r\n\x00") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be a nick, account or mask of them"})
+		return
+	}
+
+	p.mu.Lock()
+	o := p.addOptOut(req.Kind, req.Name, "panel", access.Actor(c))
+	p.mu.Unlock()
+	c.JSON(http.StatusOK, gin.H{"message": "Opt-out added", "opt_out": o})
+}
+
+// handleDeleteOptOut removes an opt-out
+func (p *ClientCensusPlugin) handleDeleteOptOut(c *gin.Context) {
+	id := c.Param("id")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, o := range p.optOuts {
+		if o.ID == id {
+			p.optOuts = append(p.optOuts[:i], p.optOuts[i+1:]...)
+			p.save()
+			log.Printf("[client-census] %s removed the opt-out of %s %s", access.Actor(c), o.Kind, o.Name)
+			c.JSON(http.StatusOK, gin.H{"message": "Opt-out removed"})
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Opt-out not found"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ClientCensusPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.ServerPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ClientCensusPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if _, err := parseHints(newConfig.HintRules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if newConfig.Nick == "" || strings.ContainsAny(newConfig.Nick, " !@,:\r\n\x00") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nick is not valid"})
+		return
+	}
+	if newConfig.IRCPort < 1 || newConfig.IRCPort > 65535 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "irc_port must be between 1 and 65535"})
+		return
+	}
+	if newConfig.PerMinute < 1 || newConfig.PerMinute > 120 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "per_minute must be between 1 and 120"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.ServerPassword == "" {
+		newConfig.ServerPassword = p.config.ServerPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ClientCensusPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ClientCensusPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,339 @@
+/**
+ * Cloak Debugger Frontend Script
+ *
+ * Computes the cloaked host of an IP address, hostname or online nick and
+ * shows which bans match it, and why the others don't.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'cloak-debugger';
+  const PLUGIN_NAME = 'Cloak Debugger';
+  const PAGE_PATH = '/plugins/cloak-debugger';
+  const API_BASE = '/api/plugin/cloak-debugger';
+
+  let showAll = false;
+  let lastResult = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('cloak-debugger-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'cloak-debugger-styles';
+    style.textContent = `
+      .ckd-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .ckd-card { background: var(--bg-secondary, #181825); border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 1rem; }
+      .ckd-card h3 { margin: 0 0 0.75rem; color: var(--text-primary, #cdd6f4); font-size: 1rem; }
+      .ckd-form { display: grid; grid-template-columns: repeat(auto-fill, minmax(12rem, 1fr)); gap: 0.5rem; }
+      .ckd-form label { display: flex; flex-direction: column; gap: 0.2rem; font-size: 0.8rem; }
+      .ckd-form .ckd-wide { grid-column: 1 / -1; }
+      .ckd-form input, .ckd-form textarea {
+        background: var(--bg-primary, #11111b);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.5rem;
+        font-family: inherit;
+      }
+      .ckd-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.4rem 0.9rem;
+        cursor: pointer;
+      }
+      .ckd-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .ckd-cloak { font-family: monospace; font-size: 1.2rem; color: var(--text-primary, #cdd6f4); word-break: break-all; }
+      .ckd-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .ckd-table th, .ckd-table td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .ckd-table code, .ckd-mono { font-family: monospace; word-break: break-all; }
+      .ckd-yes { color: var(--success, #a6e3a1); font-weight: 600; }
+      .ckd-no { color: var(--text-muted, #6c7086); }
+      .ckd-unknown { color: var(--warning, #f9e2af); }
+      .ckd-muted { color: var(--text-muted, #6c7086); }
+      .ckd-error { color: var(--error, #f38ba8); }
+      .ckd-warning { color: var(--warning, #f9e2af); }
+      .ckd-notes { margin: 0.5rem 0 0; padding-left: 1.2rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function verdictCell(v) {
+    if (!v.checked) return '<span class="ckd-unknown">Not checked</span>';
+    return v.matches ? '<span class="ckd-yes">Matches</span>' : '<span class="ckd-no">No match</span>';
+  }
+
+  function verdictTable(list, withReason) {
+    const rows = showAll ? list : list.filter(v => v.matches || !v.checked);
+    if (rows.length === 0) {
+      return `<div class="ckd-muted">${list.length ? `None of ${list.length} match` : 'None'}</div>`;
+    }
+    return `
+      <table class="ckd-table">
+        <thead><tr><th>Type</th><th>Mask</th><th>Result</th><th>Why</th>${withReason ? '<th>Reason</th>' : ''}</tr></thead>
+        <tbody>
+          ${rows.map(v => `
+            <tr>
+              <td>${escapeHtml(v.type)}</td>
+              <td><code>${escapeHtml(v.mask)}</code>${v.set_by ? `<div class="ckd-muted">by ${escapeHtml(v.set_by)}</div>` : ''}</td>
+              <td>${verdictCell(v)}</td>
+              <td>${escapeHtml(v.why)}</td>
+              ${withReason ? `<td>${escapeHtml(v.reason)}</td>` : ''}
+            </tr>
+          `).join('')}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function cloakHtml(d) {
+    if (d.cloak_error) {
+      return `<div class="ckd-error">${escapeHtml(d.cloak_error)}</div>`;
+    }
+    const c = d.cloak;
+    let check = '';
+    if (d.cloak_ok === true) {
+      check = '<div class="ckd-yes">Matches the cloak the IRCd gave this user</div>';
+    } else if (d.cloak_ok === false) {
+      check = `<div class="ckd-error">The IRCd gave this user <span class="ckd-mono">${escapeHtml(d.ircd_cloak)}</span>; run the key check</div>`;
+    }
+    return `
+      <div class="ckd-cloak">${escapeHtml(c.host)}</div>
+      <div class="ckd-muted">Cloak of ${escapeHtml(c.of)} (${escapeHtml(c.kind)})</div>
+      ${check}
+      <table class="ckd-table">
+        <thead><tr><th>Part</th><th>Shared by</th></tr></thead>
+        <tbody>
+          ${c.parts.map(part => `<tr><td><code>${escapeHtml(part.value)}</code></td><td>${escapeHtml(part.unique_for)}</td></tr>`).join('')}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function identityHtml(d) {
+    const id = d.identity;
+    const rows = [
+      ['Nick', id.nick],
+      ['Ident', id.ident],
+      ['Account', id.account],
+      ['Real host', id.hostname],
+      ['IP address', id.ip],
+      ['Vhost', id.vhost && id.vhost !== id.cloaked_host ? id.vhost : ''],
+      ['Security groups', (id.security_groups || []).join(', ')]
+    ].filter(r => r[1]);
+    return `
+      <table class="ckd-table">
+        ${rows.map(([k, v]) => `<tr><th>${k}</th><td class="ckd-mono">${escapeHtml(v)}</td></tr>`).join('')}
+      </table>
+      ${d.online ? '' : '<div class="ckd-muted">Not an online user; blank fields are assumed to match</div>'}
+      ${d.notes.length ? `<ul class="ckd-notes">${d.notes.map(n => `<li>${escapeHtml(n)}</li>`).join('')}</ul>` : ''}
+    `;
+  }
+
+  function suggestionsHtml(list) {
+    if (list.length === 0) return '<div class="ckd-muted">None</div>';
+    return `
+      <table class="ckd-table">
+        <thead><tr><th>Ban</th><th>Mask</th><th>Covers</th></tr></thead>
+        <tbody>
+          ${list.map(s => `<tr><td>${escapeHtml(s.kind)}</td><td><code>${escapeHtml(s.mask)}</code></td><td>${escapeHtml(s.covers)}</td></tr>`).join('')}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function renderResult(container) {
+    const el = container.querySelector('#ckd-result');
+    const d = lastResult;
+    if (!d) return;
+    el.innerHTML = `
+      <div class="ckd-card"><h3>Cloaked host</h3>${cloakHtml(d)}</div>
+      <div class="ckd-card"><h3>Checked as</h3>${identityHtml(d)}</div>
+      ${d.tests.length ? `<div class="ckd-card"><h3>Test masks</h3>${verdictTable(d.tests.map(v => Object.assign({}, v, { type: v.type === 'gline' ? 'G-line' : 'channel ban' })), false)}</div>` : ''}
+      <div class="ckd-card">
+        <h3>Server bans
+          <label class="ckd-muted" style="font-weight:normal;margin-left:1rem"><input type="checkbox" id="ckd-show-all" ${showAll ? 'checked' : ''}> Show bans that don't match</label>
+        </h3>
+        ${d.ban_errors.length ? `<div class="ckd-error">${d.ban_errors.map(escapeHtml).join('<br>')}</div>` : ''}
+        ${verdictTable(d.server_bans, true)}
+        <h3 style="margin-top:1rem">Ban exceptions</h3>
+        ${verdictTable(d.exceptions, true)}
+      </div>
+      ${d.channel ? `<div class="ckd-card"><h3>${escapeHtml(d.channel)} bans and exceptions</h3>${verdictTable(d.channel_bans, false)}</div>` : ''}
+      <div class="ckd-card"><h3>Masks that would match</h3>${suggestionsHtml(d.suggestions)}</div>
+    `;
+  }
+
+  async function loadCloaking(container) {
+    const el = container.querySelector('#ckd-cloaking');
+    try {
+      const data = await api('GET', '/cloaking');
+      const cl = data.cloaking;
+      el.innerHTML = `
+        ${cl ? `Module <strong>${escapeHtml(cl.module)}</strong>, method <strong>${escapeHtml(cl.method)}</strong>, prefix <strong>${escapeHtml(cl.prefix)}</strong>, keys ${cl.keys_set ? 'loaded' : '<span class="ckd-error">missing</span>'} from ${escapeHtml(cl.source)}` : ''}
+        ${data.error ? `<div class="ckd-error">${escapeHtml(data.error)}</div>` : ''}
+        ${cl && cl.warnings.length ? `<ul class="ckd-notes ckd-warning">${cl.warnings.map(w => `<li>${escapeHtml(w)}</li>`).join('')}</ul>` : ''}
+        <div id="ckd-keycheck" style="margin-top:0.5rem"><button class="ckd-btn" data-action="keycheck">Check against online users</button></div>
+      `;
+    } catch (e) {
+      el.innerHTML = `<div class="ckd-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function keyCheck(container) {
+    const el = container.querySelector('#ckd-keycheck');
+    el.innerHTML = 'Checking...';
+    try {
+      const k = await api('GET', '/keycheck');
+      el.innerHTML = `
+        <div class="${k.checked && k.matched === k.checked ? 'ckd-yes' : 'ckd-warning'}">${k.matched} of ${k.checked} cloaks match. ${escapeHtml(k.hint)}</div>
+        ${k.mismatches.length ? `
+          <table class="ckd-table">
+            <thead><tr><th>Nick</th><th>Cloak of</th><th>Computed</th><th>IRCd</th></tr></thead>
+            <tbody>
+              ${k.mismatches.map(m => `<tr><td>${escapeHtml(m.nick)}</td><td class="ckd-mono">${escapeHtml(m.of)}</td><td class="ckd-mono">${escapeHtml(m.expected)}</td><td class="ckd-mono">${escapeHtml(m.actual)}</td></tr>`).join('')}
+            </tbody>
+          </table>
+        ` : ''}
+      `;
+    } catch (e) {
+      el.innerHTML = `<div class="ckd-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function runDebug(container, form) {
+    const el = container.querySelector('#ckd-result');
+    el.innerHTML = '<div class="ckd-card">Working...</div>';
+    const body = {
+      target: form.target.value.trim(),
+      nick: form.nick.value.trim(),
+      ident: form.ident.value.trim(),
+      account: form.account.value.trim(),
+      channel: form.channel.value.trim(),
+      masks: form.masks.value.split('\n').map(m => m.trim()).filter(Boolean)
+    };
+    try {
+      lastResult = await api('POST', '/debug', body);
+      renderResult(container);
+    } catch (e) {
+      lastResult = null;
+      el.innerHTML = `<div class="ckd-card ckd-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="ckd-app" data-plugin="${PLUGIN_ID}">
+        <div class="ckd-card">
+          <h3>Why didn't my ban catch them?</h3>
+          <form class="ckd-form" id="ckd-form">
+            <label>IP address, hostname or online nick<input name="target" required placeholder="192.0.2.10"></label>
+            <label>Nick <span class="ckd-muted">(optional)</span><input name="nick"></label>
+            <label>Ident <span class="ckd-muted">(optional)</span><input name="ident" placeholder="~user"></label>
+            <label>Account <span class="ckd-muted">(optional)</span><input name="account"></label>
+            <label>Channel <span class="ckd-muted">(optional)</span><input name="channel" placeholder="#channel"></label>
+            <label class="ckd-wide">Masks to test, one per line <span class="ckd-muted">(optional)</span><textarea name="masks" rows="3" placeholder="*!*@*.example.net"></textarea></label>
+            <div class="ckd-wide"><button class="ckd-btn primary" type="submit">Debug</button></div>
+          </form>
+        </div>
+        <div id="ckd-result"></div>
+        <div class="ckd-card"><h3>Cloaking settings</h3><div id="ckd-cloaking">Loading...</div></div>
+      </div>
+    `;
+
+    const app = container.querySelector('.ckd-app');
+    app.addEventListener('submit', (e) => {
+      if (e.target.id !== 'ckd-form') return;
+      e.preventDefault();
+      runDebug(container, e.target);
+    });
+    app.addEventListener('change', (e) => {
+      if (e.target.id !== 'ckd-show-all') return;
+      showAll = e.target.checked;
+      renderResult(container);
+    });
+    app.addEventListener('click', (e) => {
+      if (e.target.closest('[data-action="keycheck"]')) keyCheck(container);
+    });
+
+    loadCloaking(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('cloak-debugger-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,272 @@
+package cloakdebugger
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Cloaking modules
+const (
+	ModuleSHA256 = "cloak_sha256"
+	ModuleMD5    = "cloak_md5"
+	ModuleNone   = "cloak_none"
+)
+
+// Cloak methods, as in set::cloak-method
+const (
+	MethodHost = "host"
+	MethodIP   = "ip"
+)
+
+// hostLen is HOSTLEN in UnrealIRCd, the longest host a cloak may be
+const hostLen = 63
+
+// Cloaking is how the IRCd cloaks hosts: the module, set::cloak-method,
+// set::cloak-prefix and set::cloak-keys
+type Cloaking struct {
+	Module   string    `json:"module"`
+	Method   string    `json:"method"`
+	Prefix   string    `json:"prefix"`
+	Keys     [3]string `json:"-"`
+	KeysSet  bool      `json:"keys_set"`
+	Source   string    `json:"source"`
+	Warnings []string  `json:"warnings"`
+}
+
+// Cloak is a cloaked host and how it was made
+type Cloak struct {
+	Host  string      `json:"host"`
+	Of    string      `json:"of"`
+	Kind  string      `json:"kind"`
+	Parts []CloakPart `json:"parts"`
+}
+
+// CloakPart is one part of a cloak and the range of addresses or hosts
+// that share it, which is what a wildcard ban on it covers
+type CloakPart struct {
+	Value     string `json:"value"`
+	UniqueFor string `json:"unique_for"`
+}
+
+// loadCloaking reads the cloaking settings from unrealircd.conf and the
+// files it includes
+func loadCloaking(path string) (*Cloaking, error) {
+	entries, warnings, err := loadConf(path)
+	if err != nil {
+		return nil, err
+	}
+	cl := &Cloaking{Method: MethodHost, Source: path, Warnings: warnings}
+
+	blacklisted := make(map[string]bool)
+	modules := make([]string, 0)
+	keys := make([]string, 0)
+	for _, e := range entries {
+		switch e.Name {
+		case "loadmodule":
+			if strings.HasPrefix(e.Value, "cloak_") {
+				modules = append(modules, e.Value)
+			}
+		case "blacklist-module":
+			blacklisted[e.Value] = true
+		case "set":
+			for _, s := range e.Children {
+				switch s.Name {
+				case "cloak-keys":
+					for _, k := range s.Children {
+						keys = append(keys, k.Name)
+					}
+				case "cloak-method":
+					cl.Method = s.Value
+				case "cloak-prefix":
+					cl.Prefix = s.Value
+				}
+			}
+		}
+	}
+
+	for _, m := range modules {
+		if !blacklisted[m] {
+			cl.Module = m
+		}
+	}
+	if cl.Module == "" {
+		cl.Module = ModuleSHA256
+		cl.Warnings = append(cl.Warnings, "no cloaking module is loaded in the configuration read, assuming cloak_sha256")
+	}
+	if cl.Prefix == "" {
+		cl.Prefix = "Clk"
+		cl.Warnings = append(cl.Warnings, "set::cloak-prefix not found, assuming Clk")
+	}
+	if cl.Module != ModuleNone {
+		if len(keys) != 3 {
+			return cl, fmt.Errorf("set::cloak-keys must have 3 keys, found %d", len(keys))
+		}
+		copy(cl.Keys[:], keys)
+		cl.KeysSet = true
+	}
+	return cl, nil
+}
+
+// manualCloaking builds the cloaking settings from the plugin config
+func manualCloaking(cfg Config) (*Cloaking, error) {
+	cl := &Cloaking{Module: cfg.CloakModule, Method: cfg.CloakMethod, Prefix: cfg.CloakPrefix, Source: "settings", Warnings: make([]string, 0)}
+	if cl.Module == ModuleNone {
+		return cl, nil
+	}
+	keys := strings.Fields(strings.ReplaceAll(cfg.CloakKeys, ",", " "))
+	if len(keys) != 3 {
+		return cl, fmt.Errorf("cloak_keys must have 3 keys, found %d", len(keys))
+	}
+	copy(cl.Keys[:], keys)
+	cl.KeysSet = true
+	return cl, nil
+}
+
+// hash is the digest the cloaking module uses
+func (cl *Cloaking) hash(data []byte) []byte {
+	if cl.Module == ModuleMD5 {
+		sum := md5.Sum(data)
+		return sum[:]
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// downsample folds a digest into 32 bits by XORing each quarter of it
+// into one byte
+func downsample(digest []byte) uint32 {
+	n := len(digest) / 4
+	var out uint32
+	for q := 0; q < 4; q++ {
+		var b byte
+		for _, c := range digest[q*n : (q+1)*n] {
+			b ^= c
+		}
+		out = out<<8 | uint32(b)
+	}
+	return out
+}
+
+// mix is the building block of every cloak: the digest of text is hashed
+// again with key appended and downsampled
+func (cl *Cloaking) mix(text, key string) uint32 {
+	first := cl.hash([]byte(text))
+	return downsample(cl.hash(append(first, key...)))
+}
+
+// cloak returns the cloaked host the IRCd gives a user with this host and
+// IP address. host may be empty or the IP address when it didn't resolve.
+func (cl *Cloaking) cloak(host, ip string) (*Cloak, error) {
+	if cl.Module == ModuleNone {
+		return nil, fmt.Errorf("cloak_none is loaded, so hosts are not cloaked")
+	}
+	if !cl.KeysSet {
+		return nil, fmt.Errorf("the cloak keys are not known")
+	}
+	if cl.Method == MethodIP || host == "" || net.ParseIP(host) != nil {
+		host = ip
+	}
+	addr := net.ParseIP(host)
+	switch {
+	case addr == nil:
+		return cl.cloakHost(host), nil
+	case addr.To4() != nil:
+		return cl.cloakIPv4(addr.To4()), nil
+	default:
+		return cl.cloakIPv6(addr), nil
+	}
+}
+
+// cloakIPv4 cloaks a.b.c.d as ALPHA.BETA.GAMMA.IP, where ALPHA is unique
+// for a.b.c.d, BETA for a.b.c.* and GAMMA for a.b.*
+func (cl *Cloaking) cloakIPv4(ip net.IP) *Cloak {
+	k := cl.Keys
+	a, b, c := ip[0], ip[1], ip[2]
+	alpha := cl.mix(k[1]+":"+ip.String()+":"+k[2], k[0])
+	beta := cl.mix(fmt.Sprintf("%s:%d.%d.%d:%s", k[2], a, b, c, k[0]), k[1])
+	gamma := cl.mix(fmt.Sprintf("%s:%d.%d:%s", k[0], a, b, k[1]), k[2])
+	return &Cloak{
+		Host: fmt.Sprintf("%X.%X.%X.IP", alpha, beta, gamma),
+		Of:   ip.String(),
+		Kind: "ipv4",
+		Parts: []CloakPart{
+			{Value: fmt.Sprintf("%X", alpha), UniqueFor: ip.String()},
+			{Value: fmt.Sprintf("%X", beta), UniqueFor: fmt.Sprintf("%d.%d.%d.0/24", a, b, c)},
+			{Value: fmt.Sprintf("%X", gamma), UniqueFor: fmt.Sprintf("%d.%d.0.0/16", a, b)},
+		},
+	}
+}
+
+// cloakIPv6 cloaks a:b:c:d:e:f:g:h as ALPHA:BETA:GAMMA:IP, where ALPHA is
+// unique for the address, BETA for a:b:c:d:e:f:g:* and GAMMA for a:b:c:d:*
+func (cl *Cloaking) cloakIPv6(ip net.IP) *Cloak {
+	k := cl.Keys
+	var h [8]string
+	for i := range h {
+		h[i] = fmt.Sprintf("%x", uint16(ip[2*i])<<8|uint16(ip[2*i+1]))
+	}
+	alpha := cl.mix(k[1]+":"+ip.String()+":"+k[2], k[0])
+	beta := cl.mix(k[2]+":"+strings.Join(h[:7], ":")+":"+k[0], k[1])
+	gamma := cl.mix(k[0]+":"+strings.Join(h[:4], ":")+":"+k[1], k[2])
+	return &Cloak{
+		Host: fmt.Sprintf("%X:%X:%X:IP", alpha, beta, gamma),
+		Of:   ip.String(),
+		Kind: "ipv6",
+		Parts: []CloakPart{
+			{Value: fmt.Sprintf("%X", alpha), UniqueFor: ip.String()},
+			{Value: fmt.Sprintf("%X", beta), UniqueFor: prefixOf(ip, 112)},
+			{Value: fmt.Sprintf("%X", gamma), UniqueFor: prefixOf(ip, 64)},
+		},
+	}
+}
+
+// cloakHost cloaks a hostname as PREFIX-ALPHA followed by the host from
+// its first dot followed by a letter, so the ISP's domain stays visible
+func (cl *Cloaking) cloakHost(host string) *Cloak {
+	k := cl.Keys
+	alpha := cl.mix(k[0]+":"+host+":"+k[1], k[2])
+	c := &Cloak{Of: host, Kind: "host"}
+
+	rest := ""
+	for i := 0; i+1 < len(host); i++ {
+		if host[i] == '.' && isAlpha(host[i+1]) {
+			rest = host[i+1:]
+			break
+		}
+	}
+	if rest == "" {
+		c.Host = fmt.Sprintf("%s-%X", cl.Prefix, alpha)
+		c.Parts = []CloakPart{{Value: c.Host, UniqueFor: host}}
+		return c
+	}
+	head := fmt.Sprintf("%s-%X.", cl.Prefix, alpha)
+	if n := len(head) + len(rest); n > hostLen {
+		rest = rest[n-hostLen:]
+	}
+	c.Host = head + rest
+	c.Parts = []CloakPart{
+		{Value: strings.TrimSuffix(head, "."), UniqueFor: host},
+		{Value: rest, UniqueFor: "*." + rest},
+	}
+	return c
+}
+
+// isAlpha reports whether c is an ASCII letter, as isalpha() in the C
+// locale
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// prefixOf returns the network of ip with the given prefix length in CIDR
+// notation
+func prefixOf(ip net.IP, bits int) string {
+	size := 8 * len(ip)
+	if v4 := ip.To4(); v4 != nil {
+		ip, size = v4, 32
+	}
+	network := ip.Mask(net.CIDRMask(bits, size))
+	return fmt.Sprintf("%s/%d", network, bits)
+}
@@ -0,0 +1,647 @@
+// Cloak Debugger Plugin for UnrealIRCd Web Panel
+// Computes the cloaked host of an IP address or hostname from the IRCd's
+// cloak keys and shows which server and channel bans would match it
+
+package cloakdebugger
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+)
+
+// Target kinds
+const (
+	KindIP   = "ip"
+	KindHost = "host"
+	KindNick = "nick"
+)
+
+// Limits on requests
+const (
+	maxTestMasks  = 20
+	maxMismatches = 10
+)
+
+// hostnamePattern matches a DNS name with at least one dot
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)+\.?$`)
+
+// CloakDebuggerPlugin implements the Plugin interface
+type CloakDebuggerPlugin struct {
+	config Config
+	rpc    *jsonrpc.Client
+	mu     sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL      string `json:"rpc_url"`
+	RPCUser     string `json:"rpc_user"`
+	RPCPassword string `json:"rpc_password"`
+	RPCInsecure bool   `json:"rpc_insecure"`
+	ConfigFile  string `json:"config_file"`
+	CloakModule string `json:"cloak_module"`
+	CloakMethod string `json:"cloak_method"`
+	CloakPrefix string `json:"cloak_prefix"`
+	CloakKeys   string `json:"cloak_keys"`
+	ResolveDNS  bool   `json:"resolve_dns"`
+	Timeout     int    `json:"timeout"`
+}
+
+// Debug is everything worked out about one target
+type Debug struct {
+	Target      string       `json:"target"`
+	Kind        string       `json:"kind"`
+	Online      bool         `json:"online"`
+	Identity    *Identity    `json:"identity"`
+	Cloaking    *Cloaking    `json:"cloaking,omitempty"`
+	Cloak       *Cloak       `json:"cloak,omitempty"`
+	CloakError  string       `json:"cloak_error,omitempty"`
+	IRCdCloak   string       `json:"ircd_cloak,omitempty"`
+	CloakOK     *bool        `json:"cloak_ok,omitempty"`
+	Notes       []string     `json:"notes"`
+	Tests       []*Verdict   `json:"tests"`
+	ServerBans  []*Verdict   `json:"server_bans"`
+	Exceptions  []*Verdict   `json:"exceptions"`
+	ChannelBans []*Verdict   `json:"channel_bans"`
+	Channel     string       `json:"channel,omitempty"`
+	BanErrors   []string     `json:"ban_errors"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// KeyCheck compares computed cloaks with those the IRCd gave online users
+type KeyCheck struct {
+	Checked    int        `json:"checked"`
+	Matched    int        `json:"matched"`
+	Mismatches []Mismatch `json:"mismatches"`
+	Hint       string     `json:"hint,omitempty"`
+}
+
+// Mismatch is an online user whose cloak differs from the computed one
+type Mismatch struct {
+	Nick     string `json:"nick"`
+	Of       string `json:"of"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// debugRequest is the body of a debug request
+type debugRequest struct {
+	Target  string   `json:"target"`
+	Nick    string   `json:"nick"`
+	Ident   string   `json:"ident"`
+	Account string   `json:"account"`
+	Channel string   `json:"channel"`
+	Masks   []string `json:"masks"`
+}
+
+// rpcUser is the subset of the UnrealIRCd user object used here
+type rpcUser struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	User     struct {
+		Username       string   `json:"username"`
+		Realname       string   `json:"realname"`
+		Account        string   `json:"account"`
+		Vhost          string   `json:"vhost"`
+		CloakedHost    string   `json:"cloakedhost"`
+		SecurityGroups []string `json:"security-groups"`
+	} `json:"user"`
+}
+
+// rpcBan is a ban as returned by server_ban.list and
+// server_ban_exception.list
+type rpcBan struct {
+	Type           string `json:"type"`
+	Name           string `json:"name"`
+	SetBy          string `json:"set_by"`
+	Reason         string `json:"reason"`
+	ExceptionTypes string `json:"exception_types"`
+}
+
+// rpcListEntry is a channel ban, exception or invite exception
+type rpcListEntry struct {
+	Name  string `json:"name"`
+	SetBy string `json:"set_by"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &CloakDebuggerPlugin{
+		config: Config{
+			RPCURL:      "https://127.0.0.1:8600/api",
+			ConfigFile:  "/home/ircd/unrealircd/conf/unrealircd.conf",
+			CloakModule: ModuleSHA256,
+			CloakMethod: MethodHost,
+			CloakPrefix: "Clk",
+			ResolveDNS:  true,
+			Timeout:     10,
+		},
+	}
+}
+
+// Info returns plugin metadata
+func (p *CloakDebuggerPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Cloak Debugger",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Compute cloaked hosts and see which bans match a host",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *CloakDebuggerPlugin) Init() error {
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *CloakDebuggerPlugin) Shutdown() error {
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *CloakDebuggerPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/cloak-debugger")
+	{
+		plugin.GET("/cloaking", p.handleCloaking)
+		plugin.POST("/debug", p.handleDebug)
+		plugin.GET("/keycheck", p.handleKeyCheck)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *CloakDebuggerPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// settings returns a copy of the config and a context limited to the
+// configured timeout
+func (p *CloakDebuggerPlugin) settings(parent context.Context) (Config, context.Context, context.CancelFunc) {
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	return cfg, ctx, cancel
+}
+
+// cloaking reads the cloaking settings from unrealircd.conf, or from the
+// plugin settings when no config file is set. The file is read on every
+// request so changes to it are picked up without a reload.
+func cloaking(cfg Config) (*Cloaking, error) {
+	if cfg.ConfigFile != "" {
+		return loadCloaking(cfg.ConfigFile)
+	}
+	return manualCloaking(cfg)
+}
+
+// parseTarget works out whether a target is an IP address, a hostname or
+// a nick
+func parseTarget(target string) (string, string, error) {
+	target = strings.TrimSpace(target)
+	switch {
+	case target == "":
+		return "", "", fmt.Errorf("target is required")
+	case net.ParseIP(target) != nil:
+		return net.ParseIP(target).String(), KindIP, nil
+	case hostnamePattern.MatchString(target):
+		return strings.TrimSuffix(target, "."), KindHost, nil
+	case len(target) <= 64 && !strings.ContainsAny(target, " ,.*?!@:\r\n\x00"):
+		return target, KindNick, nil
+	}
+	return "", "", fmt.Errorf("%s is not an IP address, hostname or nick", target)
+}
+
+// resolve fills in the host or IP address the IRCd would see for an IP
+// address or hostname. Like the IRCd, a reverse DNS name is only used
+// when it resolves back to the address.
+func resolve(ctx context.Context, d *Debug) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		d.Notes = append(d.Notes, fmt.Sprintf("DNS lookups are unavailable: %v", err))
+		return
+	}
+	id := d.Identity
+	if d.Kind == KindHost {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, id.Hostname)
+		if err != nil || len(addrs) == 0 {
+			d.Notes = append(d.Notes, fmt.Sprintf("%s doesn't resolve, so IP address bans can't be checked", id.Hostname))
+			return
+		}
+		id.IP = addrs[0].IP.String()
+		if len(addrs) > 1 {
+			d.Notes = append(d.Notes, fmt.Sprintf("%s resolves to %d addresses; using %s, but a user connects from whichever one they are on", id.Hostname, len(addrs), id.IP))
+		}
+		return
+	}
+
+	id.Hostname = id.IP
+	names, err := net.DefaultResolver.LookupAddr(ctx, id.IP)
+	if err != nil || len(names) == 0 {
+		d.Notes = append(d.Notes, fmt.Sprintf("%s has no reverse DNS, so the IRCd uses the IP address as the host", id.IP))
+		return
+	}
+	want := net.ParseIP(id.IP)
+	for _, name := range names {
+		name = strings.TrimSuffix(name, ".")
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if a.IP.Equal(want) {
+				id.Hostname = name
+				return
+			}
+		}
+	}
+	d.Notes = append(d.Notes, fmt.Sprintf("The reverse DNS name %s doesn't resolve back to %s, so the IRCd uses the IP address as the host", strings.TrimSuffix(names[0], "."), id.IP))
+}
+
+// debug works out the cloak of a target and the bans that match it
+func (p *CloakDebuggerPlugin) debug(ctx context.Context, cfg Config, req debugRequest, target, kind string) (*Debug, error) {
+	d := &Debug{
+		Target:      target,
+		Kind:        kind,
+		Identity:    &Identity{Nick: req.Nick, Ident: req.Ident, Account: req.Account},
+		Notes:       make([]string, 0),
+		Tests:       make([]*Verdict, 0),
+		ServerBans:  make([]*Verdict, 0),
+		Exceptions:  make([]*Verdict, 0),
+		ChannelBans: make([]*Verdict, 0),
+		BanErrors:   make([]string, 0),
+	}
+	id := d.Identity
+
+	switch kind {
+	case KindNick:
+		var out struct {
+			Client rpcUser `json:"client"`
+		}
+		params := map[string]interface{}{"nick": target, "object_detail_level": 2}
+		if err := p.client().Call(ctx, "user.get", params, &out); err != nil {
+			return nil, err
+		}
+		u := out.Client
+		d.Online = true
+		*id = Identity{
+			Nick:           u.Name,
+			Ident:          u.User.Username,
+			Realname:       u.User.Realname,
+			Account:        u.User.Account,
+			Hostname:       u.Hostname,
+			IP:             u.IP,
+			Vhost:          u.User.Vhost,
+			SecurityGroups: u.User.SecurityGroups,
+		}
+		if id.SecurityGroups == nil {
+			id.SecurityGroups = make([]string, 0)
+		}
+		d.IRCdCloak = u.User.CloakedHost
+	case KindIP:
+		id.IP = target
+		if cfg.ResolveDNS {
+			resolve(ctx, d)
+		} else {
+			id.Hostname = target
+		}
+	case KindHost:
+		id.Hostname = target
+		if cfg.ResolveDNS {
+			resolve(ctx, d)
+		}
+	}
+
+	cl, err := cloaking(cfg)
+	if cl != nil {
+		d.Cloaking = cl
+	}
+	if err == nil {
+		d.Cloak, err = cl.cloak(id.Hostname, id.IP)
+	}
+	if err != nil {
+		d.CloakError = err.Error()
+	} else {
+		id.CloakedHost = d.Cloak.Host
+	}
+	if d.IRCdCloak != "" {
+		ok := d.Cloak != nil && d.Cloak.Host == d.IRCdCloak
+		d.CloakOK = &ok
+		// Bans are checked against the cloak the user really has
+		id.CloakedHost = d.IRCdCloak
+	}
+
+	// Test masks are checked as a G-line and as a channel ban, except for
+	// nick!user@host masks, which only channel bans can be
+	for _, mask := range req.Masks {
+		if !strings.Contains(mask, "!") {
+			server := &Verdict{Kind: "test", Type: "gline", Mask: mask}
+			checkServerBan(server, id)
+			d.Tests = append(d.Tests, server)
+		}
+		channel := &Verdict{Kind: "test", Type: "channel", Mask: mask}
+		checkChannelBan(channel, id)
+		d.Tests = append(d.Tests, channel)
+	}
+
+	p.checkBans(ctx, d, req.Channel)
+	d.Suggestions = suggestions(id, d.Cloak)
+	return d, nil
+}
+
+// checkBans checks the server bans and exceptions, and the bans and
+// exceptions of a channel, against the target. A list that can't be
+// fetched is reported in BanErrors.
+func (p *CloakDebuggerPlugin) checkBans(ctx context.Context, d *Debug, channel string) {
+	var bans struct {
+		List []rpcBan `json:"list"`
+	}
+	if err := p.client().Call(ctx, "server_ban.list", nil, &bans); err != nil {
+		d.BanErrors = append(d.BanErrors, fmt.Sprintf("server bans: %v", err))
+	}
+	for _, b := range bans.List {
+		v := &Verdict{Kind: "server", Type: b.Type, Mask: b.Name, SetBy: b.SetBy, Reason: b.Reason}
+		checkServerBan(v, d.Identity)
+		d.ServerBans = append(d.ServerBans, v)
+	}
+
+	var excepts struct {
+		List []rpcBan `json:"list"`
+	}
+	if err := p.client().Call(ctx, "server_ban_exception.list", nil, &excepts); err != nil {
+		d.BanErrors = append(d.BanErrors, fmt.Sprintf("ban exceptions: %v", err))
+	}
+	for _, b := range excepts.List {
+		v := &Verdict{Kind: "exception", Type: b.ExceptionTypes, Mask: b.Name, SetBy: b.SetBy, Reason: b.Reason}
+		checkServerBan(v, d.Identity)
+		d.Exceptions = append(d.Exceptions, v)
+	}
+
+	if channel != "" {
+		d.Channel = channel
+		var out struct {
+			Channel struct {
+				Bans             []rpcListEntry `json:"bans"`
+				BanExemptions    []rpcListEntry `json:"ban_exemptions"`
+				InviteExceptions []rpcListEntry `json:"invite_exceptions"`
+			} `json:"channel"`
+		}
+		params := map[string]interface{}{"channel": channel, "object_detail_level": 3}
+		if err := p.client().Call(ctx, "channel.get", params, &out); err != nil {
+			d.BanErrors = append(d.BanErrors, fmt.Sprintf("%s: %v", channel, err))
+		}
+		lists := []struct {
+			kind    string
+			entries []rpcListEntry
+		}{
+			{"ban", out.Channel.Bans},
+			{"exempt", out.Channel.BanExemptions},
+			{"invex", out.Channel.InviteExceptions},
+		}
+		for _, l := range lists {
+			for _, e := range l.entries {
+				v := &Verdict{Kind: "channel", Type: l.kind, Mask: e.Name, SetBy: e.SetBy}
+				checkChannelBan(v, d.Identity)
+				d.ChannelBans = append(d.ChannelBans, v)
+			}
+		}
+	}
+
+	for _, list := range [][]*Verdict{d.ServerBans, d.Exceptions, d.ChannelBans} {
+		sortVerdicts(list)
+	}
+}
+
+// sortVerdicts puts matching bans first, then those that couldn't be
+// checked
+func sortVerdicts(list []*Verdict) {
+	rank := func(v *Verdict) int {
+		switch {
+		case v.Matches:
+			return 0
+		case !v.Checked:
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(list, func(i, j int) bool { return rank(list[i]) < rank(list[j]) })
+}
+
+// handleCloaking returns the cloaking settings in use, without the keys
+func (p *CloakDebuggerPlugin) handleCloaking(c *gin.Context) {
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	cl, err := cloaking(cfg)
+	resp := gin.H{"cloaking": cl}
+	if err != nil {
+		resp["error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleDebug computes the cloak of an IP address, hostname or online
+// nick and checks the bans against it
+func (p *CloakDebuggerPlugin) handleDebug(c *gin.Context) {
+	var req debugRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	target, kind, err := parseTarget(req.Target)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Channel = strings.TrimSpace(req.Channel)
+	if req.Channel != "" && (!strings.HasPrefix(req.Channel, "#") || strings.ContainsAny(req.Channel, " ,\r\n\x00")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "channel must start with #"})
+		return
+	}
+	masks := make([]string, 0, len(req.Masks))
+	for _, m := range req.Masks {
+		if m = strings.TrimSpace(m); m != "" {
+			masks = append(masks, m)
+		}
+	}
+	if len(masks) > maxTestMasks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d masks can be tested at once", maxTestMasks)})
+		return
+	}
+	req.Masks = masks
+
+	cfg, ctx, cancel := p.settings(c.Request.Context())
+	defer cancel()
+
+	d, err := p.debug(ctx, cfg, req, target, kind)
+	if err != nil {
+		var rerr *jsonrpc.Error
+		if errors.As(err, &rerr) && rerr.Code == -1000 {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s is not online", target)})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("[cloak-debugger] %s debugged %s %s", access.Actor(c), kind, target)
+	c.JSON(http.StatusOK, d)
+}
+
+// handleKeyCheck computes the cloak of every online user and compares it
+// with the cloak the IRCd gave them, which shows whether the keys and
+// settings in use are the IRCd's
+func (p *CloakDebuggerPlugin) handleKeyCheck(c *gin.Context) {
+	cfg, ctx, cancel := p.settings(c.Request.Context())
+	defer cancel()
+
+	cl, err := cloaking(cfg)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var out struct {
+		List []rpcUser `json:"list"`
+	}
+	if err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &out); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	check := KeyCheck{Mismatches: make([]Mismatch, 0)}
+	prefixes := make(map[string]int)
+	for _, u := range out.List {
+		if u.User.CloakedHost == "" || u.IP == "" {
+			continue
+		}
+		check.Checked++
+		cloak, err := cl.cloak(u.Hostname, u.IP)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if cloak.Host == u.User.CloakedHost {
+			check.Matched++
+			continue
+		}
+		if prefix, _, ok := strings.Cut(u.User.CloakedHost, "-"); ok && cloak.Kind == "host" {
+			prefixes[prefix]++
+		}
+		if len(check.Mismatches) < maxMismatches {
+			check.Mismatches = append(check.Mismatches, Mismatch{Nick: u.Name, Of: cloak.Of, Expected: cloak.Host, Actual: u.User.CloakedHost})
+		}
+	}
+
+	switch {
+	case check.Checked == 0:
+		check.Hint = "No online users have a cloaked host to compare with"
+	case check.Matched == check.Checked:
+		check.Hint = "Every cloak matches; the keys and settings are the IRCd's"
+	case check.Matched == 0:
+		check.Hint = "No cloak matches; the cloak keys, module or method differ from the IRCd's, or it hasn't been rehashed since they changed"
+		for prefix := range prefixes {
+			if prefix != cl.Prefix {
+				check.Hint = fmt.Sprintf("Hostname cloaks start with %s- rather than %s-; check set::cloak-prefix", prefix, cl.Prefix)
+			}
+		}
+	default:
+		check.Hint = "Some cloaks differ; users with a host or IP address changed by WEBIRC or services may be cloaked from a different host"
+	}
+	c.JSON(http.StatusOK, check)
+}
+
+// handleGetConfig returns the current configuration
+func (p *CloakDebuggerPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.CloakKeys = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *CloakDebuggerPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	switch newConfig.CloakModule {
+	case ModuleSHA256, ModuleMD5, ModuleNone:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cloak_module must be cloak_sha256, cloak_md5 or cloak_none"})
+		return
+	}
+	if newConfig.CloakMethod != MethodHost && newConfig.CloakMethod != MethodIP {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cloak_method must be host or ip"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.CloakKeys == "" {
+		newConfig.CloakKeys = p.config.CloakKeys
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *CloakDebuggerPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *CloakDebuggerPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
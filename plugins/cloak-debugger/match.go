@@ -0,0 +1,362 @@
+package cloakdebugger
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+)
+
+// extbanPattern matches an extended ban such as ~account:name, as opposed
+// to a mask whose ident starts with ~
+var extbanPattern = regexp.MustCompile(`^~[A-Za-z-]+:`)
+
+// Identity is the connection a ban is checked against. Empty fields are
+// unknown.
+type Identity struct {
+	Nick           string   `json:"nick"`
+	Ident          string   `json:"ident"`
+	Realname       string   `json:"realname"`
+	Account        string   `json:"account"`
+	Hostname       string   `json:"hostname"`
+	IP             string   `json:"ip"`
+	CloakedHost    string   `json:"cloaked_host"`
+	Vhost          string   `json:"vhost"`
+	SecurityGroups []string `json:"security_groups,omitempty"`
+}
+
+// Host forms a ban may be matched against
+const (
+	FormHost   = "real host"
+	FormIP     = "IP address"
+	FormCloak  = "cloaked host"
+	FormVhost  = "vhost"
+	FormIdent  = "ident"
+	FormNick   = "nick"
+	FormExtban = "extended ban"
+)
+
+// Verdict is whether one ban mask covers the identity, and why
+type Verdict struct {
+	Kind      string   `json:"kind"`
+	Type      string   `json:"type"`
+	Mask      string   `json:"mask"`
+	SetBy     string   `json:"set_by,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+	Matches   bool     `json:"matches"`
+	Checked   bool     `json:"checked"`
+	MatchedOn []string `json:"matched_on"`
+	Why       string   `json:"why"`
+}
+
+// hostForm is a host and which form of the identity it is
+type hostForm struct {
+	label string
+	host  string
+}
+
+// hostForms returns the hosts a ban can match for the identity, without
+// duplicates
+func hostForms(id *Identity, withCloaks bool) []hostForm {
+	// The IP address comes first so an unresolved host is labelled as one
+	forms := []hostForm{{FormIP, id.IP}, {FormHost, id.Hostname}}
+	if withCloaks {
+		forms = append(forms, hostForm{FormCloak, id.CloakedHost}, hostForm{FormVhost, id.Vhost})
+	}
+	out := make([]hostForm, 0, len(forms))
+	seen := make(map[string]bool)
+	for _, f := range forms {
+		if f.host == "" || seen[strings.ToLower(f.host)] {
+			continue
+		}
+		seen[strings.ToLower(f.host)] = true
+		out = append(out, f)
+	}
+	return out
+}
+
+// matchHost reports whether a ban's host part matches a host, handling
+// CIDR masks for IP addresses
+func matchHost(mask, host string) bool {
+	if _, ipnet, err := net.ParseCIDR(mask); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && ipnet.Contains(ip)
+	}
+	return ircname.MatchMask(mask, host)
+}
+
+// matchPart matches part of a mask against a value. An unknown value is
+// assumed to match, and noted.
+func matchPart(mask, value, label string, assumed *[]string) bool {
+	if value == "" {
+		if strings.Trim(mask, "*") != "" {
+			*assumed = append(*assumed, label)
+		}
+		return true
+	}
+	return ircname.MatchMask(mask, value)
+}
+
+// isZline reports whether a server ban type is only checked against IP
+// addresses
+func isZline(banType string) bool {
+	switch strings.ToLower(banType) {
+	case "zline", "gzline", "z-line", "gz-line", "global z-line":
+		return true
+	}
+	return false
+}
+
+// checkServerBan works out whether a server ban (G/K/Z-line, shun or
+// exception) covers the identity. Server bans match the ident with the
+// real host or IP address; Z-lines match only the IP address, since they
+// are checked before DNS. Neither looks at cloaked hosts or vhosts.
+func checkServerBan(v *Verdict, id *Identity) {
+	v.MatchedOn = make([]string, 0)
+	v.Checked = true
+	if extbanPattern.MatchString(v.Mask) {
+		checkExtban(v, id, v.Mask, true)
+		return
+	}
+
+	user, host := "*", v.Mask
+	if i := strings.LastIndex(v.Mask, "@"); i >= 0 {
+		user, host = v.Mask[:i], v.Mask[i+1:]
+	}
+	forms := hostForms(id, false)
+	if isZline(v.Type) {
+		forms = hostForms(&Identity{IP: id.IP}, false)
+	}
+
+	assumed := make([]string, 0)
+	userOK := matchPart(user, id.Ident, FormIdent, &assumed)
+	for _, f := range forms {
+		if matchHost(host, f.host) {
+			v.MatchedOn = append(v.MatchedOn, f.label)
+		}
+	}
+	v.Matches = userOK && len(v.MatchedOn) > 0
+
+	switch {
+	case v.Matches:
+		v.Why = fmt.Sprintf("Matches the %s", strings.Join(v.MatchedOn, " and "))
+		if len(assumed) > 0 {
+			v.Why += fmt.Sprintf(", if the %s matches %s", strings.Join(assumed, " and "), user)
+		}
+	case len(v.MatchedOn) > 0:
+		v.Why = fmt.Sprintf("The host matches, but the user part %s doesn't match the ident %s", user, id.Ident)
+		if strings.HasPrefix(id.Ident, "~") && !strings.HasPrefix(user, "~") && !strings.HasPrefix(user, "*") {
+			v.Why += " (no identd reply, so the ident starts with ~)"
+		}
+	case isZline(v.Type) && id.Hostname != "" && matchHost(host, id.Hostname):
+		v.Why = "Z-lines are checked before DNS lookups, so they only match IP addresses, not the hostname"
+	case id.CloakedHost != "" && matchHost(host, id.CloakedHost):
+		v.Why = "Server bans don't match cloaked hosts; ban the real host or IP address instead"
+	case id.Vhost != "" && matchHost(host, id.Vhost):
+		v.Why = "Server bans don't match vhosts; ban the real host or IP address instead"
+	default:
+		v.Why = fmt.Sprintf("%s doesn't match the %s", host, describeForms(forms))
+	}
+}
+
+// normalizeChannelMask expands a channel ban mask to nick!user@host the
+// way the IRCd does, so "bob" is bob!*@* and "*.example.net" is
+// *!*@*.example.net
+func normalizeChannelMask(mask string) string {
+	hasBang := strings.Contains(mask, "!")
+	hasAt := strings.Contains(mask, "@")
+	switch {
+	case hasBang && hasAt:
+		return mask
+	case hasBang:
+		return mask + "@*"
+	case hasAt:
+		return "*!" + mask
+	case strings.ContainsAny(mask, ".:"):
+		return "*!*@" + mask
+	default:
+		return mask + "!*@*"
+	}
+}
+
+// checkChannelBan works out whether a channel ban or exception covers the
+// identity. Channel bans match the nick and ident with the real host, IP
+// address, cloaked host and vhost.
+func checkChannelBan(v *Verdict, id *Identity) {
+	v.MatchedOn = make([]string, 0)
+	v.Checked = true
+	if extbanPattern.MatchString(v.Mask) {
+		checkExtban(v, id, v.Mask, false)
+		return
+	}
+
+	full := normalizeChannelMask(v.Mask)
+	bang := strings.Index(full, "!")
+	at := strings.LastIndex(full, "@")
+	if at < bang {
+		v.Why = "Not a valid ban mask"
+		return
+	}
+	nick, user, host := full[:bang], full[bang+1:at], full[at+1:]
+
+	assumed := make([]string, 0)
+	nickOK := matchPart(nick, id.Nick, FormNick, &assumed)
+	userOK := matchPart(user, id.Ident, FormIdent, &assumed)
+	forms := hostForms(id, true)
+	for _, f := range forms {
+		if matchHost(host, f.host) {
+			v.MatchedOn = append(v.MatchedOn, f.label)
+		}
+	}
+	v.Matches = nickOK && userOK && len(v.MatchedOn) > 0
+
+	switch {
+	case v.Matches:
+		v.Why = fmt.Sprintf("%s matches the %s", full, strings.Join(v.MatchedOn, " and "))
+		if len(assumed) > 0 {
+			v.Why += fmt.Sprintf(", if the %s matches", strings.Join(assumed, " and "))
+		}
+	case len(v.MatchedOn) > 0 && !nickOK:
+		v.Why = fmt.Sprintf("The host matches, but %s doesn't match the nick %s", nick, id.Nick)
+	case len(v.MatchedOn) > 0:
+		v.Why = fmt.Sprintf("The host matches, but %s doesn't match the ident %s", user, id.Ident)
+	default:
+		v.Why = fmt.Sprintf("%s doesn't match the %s", host, describeForms(forms))
+	}
+}
+
+// checkExtban evaluates the extended bans that can be worked out from the
+// identity. Extbans that only act on some actions, such as ~quiet, are
+// evaluated on their inner mask.
+func checkExtban(v *Verdict, id *Identity, mask string, server bool) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(mask, "~"), ":")
+	switch strings.ToLower(name) {
+	case "account", "a":
+		switch {
+		case arg == "0":
+			v.Matches = id.Account == ""
+			v.Why = "Matches users who aren't logged in"
+		case id.Account == "":
+			v.Matches = false
+			v.Why = "Matches accounts, and the account isn't known or the user isn't logged in"
+		default:
+			v.Matches = ircname.MatchMask(arg, id.Account)
+			v.Why = fmt.Sprintf("%s against the account %s", matchWord(v.Matches), id.Account)
+		}
+	case "realname", "r":
+		if id.Realname == "" {
+			v.Checked = false
+			v.Why = "Matches the realname, which is only known for online users"
+			return
+		}
+		v.Matches = ircname.MatchMask(arg, id.Realname)
+		v.Why = fmt.Sprintf("%s against the realname %s", matchWord(v.Matches), id.Realname)
+	case "security-group", "G":
+		if id.SecurityGroups == nil {
+			v.Checked = false
+			v.Why = "Matches security groups, which are only known for online users"
+			return
+		}
+		for _, g := range id.SecurityGroups {
+			if strings.EqualFold(g, arg) {
+				v.Matches = true
+			}
+		}
+		v.Why = fmt.Sprintf("%s against the security groups %s", matchWord(v.Matches), strings.Join(id.SecurityGroups, ", "))
+	case "quiet", "q", "nickchange", "n", "join", "j", "time", "t":
+		inner := arg
+		if strings.EqualFold(name, "time") || name == "t" {
+			_, inner, _ = strings.Cut(arg, ":")
+		}
+		if inner == "" {
+			v.Checked = false
+			v.Why = "Extended ban without a mask"
+			return
+		}
+		sub := &Verdict{Type: v.Type, Mask: inner}
+		if server {
+			checkServerBan(sub, id)
+		} else {
+			checkChannelBan(sub, id)
+		}
+		v.Matches, v.Checked, v.MatchedOn = sub.Matches, sub.Checked, sub.MatchedOn
+		v.Why = fmt.Sprintf("~%s applies to %s: %s", name, inner, sub.Why)
+		return
+	default:
+		v.Checked = false
+		v.Why = fmt.Sprintf("~%s extended bans aren't checked here", name)
+		return
+	}
+	if v.Matches {
+		v.MatchedOn = append(v.MatchedOn, FormExtban)
+	}
+}
+
+// matchWord describes a match result
+func matchWord(ok bool) string {
+	if ok {
+		return "Matches"
+	}
+	return "Doesn't match"
+}
+
+// describeForms lists the hosts a ban was compared with
+func describeForms(forms []hostForm) string {
+	if len(forms) == 0 {
+		return "host (none known)"
+	}
+	parts := make([]string, 0, len(forms))
+	for _, f := range forms {
+		parts = append(parts, fmt.Sprintf("%s %s", f.label, f.host))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Suggestion is a ban mask that would cover the identity
+type Suggestion struct {
+	Kind   string `json:"kind"`
+	Mask   string `json:"mask"`
+	Covers string `json:"covers"`
+}
+
+// suggestions returns server and channel ban masks that would cover the
+// identity, from the narrowest to the widest
+func suggestions(id *Identity, c *Cloak) []Suggestion {
+	out := make([]Suggestion, 0)
+	if ip := net.ParseIP(id.IP); ip != nil {
+		out = append(out, Suggestion{Kind: "server", Mask: "*@" + id.IP, Covers: "This IP address; a GZ-line also stops the connection before DNS and ident lookups"})
+		if ip.To4() != nil {
+			out = append(out, Suggestion{Kind: "server", Mask: "*@" + prefixOf(ip, 24), Covers: "The IP address's /24"})
+		} else {
+			out = append(out, Suggestion{Kind: "server", Mask: "*@" + prefixOf(ip, 64), Covers: "The IP address's /64, usually one customer"})
+		}
+	}
+	if id.Hostname != "" && net.ParseIP(id.Hostname) == nil {
+		out = append(out, Suggestion{Kind: "server", Mask: "*@" + id.Hostname, Covers: "This hostname (a G-line; Z-lines can't match hostnames)"})
+	}
+	if id.CloakedHost != "" {
+		out = append(out, Suggestion{Kind: "channel", Mask: "*!*@" + id.CloakedHost, Covers: "This cloaked host"})
+	}
+	if c != nil {
+		switch c.Kind {
+		case "ipv4":
+			out = append(out,
+				Suggestion{Kind: "channel", Mask: "*!*@*." + c.Parts[1].Value + "." + c.Parts[2].Value + ".IP", Covers: c.Parts[1].UniqueFor},
+				Suggestion{Kind: "channel", Mask: "*!*@*." + c.Parts[2].Value + ".IP", Covers: c.Parts[2].UniqueFor})
+		case "ipv6":
+			out = append(out,
+				Suggestion{Kind: "channel", Mask: "*!*@*:" + c.Parts[1].Value + ":" + c.Parts[2].Value + ":IP", Covers: c.Parts[1].UniqueFor},
+				Suggestion{Kind: "channel", Mask: "*!*@*:" + c.Parts[2].Value + ":IP", Covers: c.Parts[2].UniqueFor})
+		case "host":
+			if len(c.Parts) > 1 {
+				out = append(out, Suggestion{Kind: "channel", Mask: "*!*@*." + c.Parts[1].Value, Covers: "Every cloaked host under " + c.Parts[1].Value})
+			}
+		}
+	}
+	if id.Account != "" {
+		out = append(out, Suggestion{Kind: "channel", Mask: "~account:" + id.Account, Covers: "The account, whatever host it connects from"})
+	}
+	return out
+}
@@ -0,0 +1,293 @@
+/**
+ * Config Drift Frontend Script
+ *
+ * Shows the items that differ between servers, each server's snapshots
+ * and their changes, and takes configuration file uploads.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'config-drift';
+  const PLUGIN_NAME = 'Config Drift';
+  const PAGE_PATH = '/plugins/config-drift';
+  const API_BASE = '/api/plugin/config-drift';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const res = await fetch(API_BASE + path, {
+      method,
+      headers: getAuthHeaders(),
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function formatValue(v) {
+    return v == null ? '<span class="cfd-none">(not set)</span>' : escapeHtml(v === '' ? '(present)' : v);
+  }
+
+  function injectStyles() {
+    if (document.getElementById('config-drift-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'config-drift-styles';
+    style.textContent = `
+      .cfd-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .cfd-toolbar { display: flex; gap: 0.5rem; align-items: center; flex-wrap: wrap; }
+      .cfd-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .cfd-table th, .cfd-table td { text-align: left; padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .cfd-table td { color: var(--text-primary, #cdd6f4); word-break: break-all; }
+      .cfd-app button, .cfd-app select { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); border: none; border-radius: 6px; padding: 0.4rem 0.8rem; cursor: pointer; }
+      .cfd-app button:disabled { opacity: 0.5; cursor: default; }
+      .cfd-key { font-family: monospace; }
+      .cfd-ok { color: var(--success, #a6e3a1); }
+      .cfd-old, .cfd-error { color: var(--error, #f38ba8); }
+      .cfd-new { color: var(--success, #a6e3a1); }
+      .cfd-none { opacity: 0.6; font-style: italic; }
+      .cfd-desc { font-size: 0.8rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderDrift(data) {
+    if (data.servers.length < 2) return '<div>At least two servers need a snapshot to compare them.</div>';
+    return `
+      <div>
+        <strong class="${data.drift.length ? 'cfd-old' : 'cfd-ok'}">${data.drift.length} items differ</strong>
+        between ${data.servers.length} servers
+      </div>
+      <table class="cfd-table">
+        <thead><tr><th>Item</th>${data.servers.map(s => `<th>${escapeHtml(s)}</th>`).join('')}</tr></thead>
+        <tbody>
+          ${data.drift.map(d => `
+            <tr>
+              <td class="cfd-key">${escapeHtml(d.key)}</td>
+              ${data.servers.map(s => `<td>${s in d.values ? formatValue(d.values[s]) : '<span class="cfd-none">no files</span>'}</td>`).join('')}
+            </tr>
+          `).join('') || `<tr><td colspan="${data.servers.length + 1}">Every server has the same configuration</td></tr>`}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function renderServers(data) {
+    return `
+      <table class="cfd-table">
+        <thead><tr><th>Server</th><th>Latest snapshot</th><th>Items</th><th>Snapshots</th><th>Files</th><th></th></tr></thead>
+        <tbody>
+          ${data.servers.map(s => `
+            <tr>
+              <td>${escapeHtml(s.name)}${s.linked ? '' : ' <span class="cfd-none">(not linked)</span>'}
+                ${s.error ? `<div class="cfd-error">${escapeHtml(s.error)}</div>` : ''}</td>
+              <td>${s.latest ? formatTime(s.latest.time) : '-'}</td>
+              <td>${s.latest ? s.latest.count : '-'}</td>
+              <td>${s.snapshots}</td>
+              <td>${s.files.map(f => `
+                <div>${escapeHtml(f.name)} (${f.entries} entries, ${escapeHtml(f.uploaded_by)})
+                  <button class="cfd-remove" data-server="${escapeHtml(s.name)}" data-name="${escapeHtml(f.name)}">Remove</button></div>
+              `).join('') || '<span class="cfd-none">none</span>'}</td>
+              <td>
+                <button class="cfd-upload" data-server="${escapeHtml(s.name)}">Upload file</button>
+                <button class="cfd-history" data-server="${escapeHtml(s.name)}">History</button>
+              </td>
+            </tr>
+          `).join('') || '<tr><td colspan="6">No snapshots yet</td></tr>'}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function renderChanges(changes) {
+    return `
+      <table class="cfd-table">
+        <thead><tr><th>Item</th><th>Before</th><th>After</th></tr></thead>
+        <tbody>
+          ${changes.map(ch => `
+            <tr>
+              <td class="cfd-key">${escapeHtml(ch.key)}</td>
+              <td class="cfd-old">${formatValue(ch.old)}</td>
+              <td class="cfd-new">${formatValue(ch.new)}</td>
+            </tr>
+          `).join('') || '<tr><td colspan="3">No differences</td></tr>'}
+        </tbody>
+      </table>
+    `;
+  }
+
+  async function showHistory(container, server) {
+    const panel = container.querySelector('#cfd-history');
+    panel.innerHTML = 'Loading...';
+    try {
+      const [list, diff] = await Promise.all([
+        api('GET', `/servers/${encodeURIComponent(server)}/snapshots`),
+        api('GET', `/diff?server=${encodeURIComponent(server)}`).catch(() => null),
+      ]);
+      panel.innerHTML = `
+        <h3>History of ${escapeHtml(server)}</h3>
+        <table class="cfd-table">
+          <thead><tr><th>Time</th><th>Reason</th><th>By</th><th>Items</th><th>Changes</th><th>Files</th></tr></thead>
+          <tbody>
+            ${list.snapshots.map(s => `
+              <tr>
+                <td>${formatTime(s.time)}</td><td>${escapeHtml(s.reason)}</td><td>${escapeHtml(s.actor || '')}</td>
+                <td>${s.count}</td><td>${s.changes}</td><td>${s.files.map(escapeHtml).join(', ')}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+        ${diff ? `<h3>Latest changes</h3>${renderChanges(diff.changes)}` : ''}
+      `;
+    } catch (e) {
+      panel.innerHTML = `<div class="cfd-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function upload(container, server) {
+    const input = document.createElement('input');
+    input.type = 'file';
+    input.accept = '.conf,text/plain';
+    input.addEventListener('change', async () => {
+      const file = input.files[0];
+      if (!file) return;
+      try {
+        const res = await api('POST', `/servers/${encodeURIComponent(server)}/files`, { name: file.name, content: await file.text() });
+        alert(`${file.name}: ${res.entries} entries, ${res.changes.length} changes`);
+      } catch (err) {
+        alert(err.message);
+      }
+      load(container);
+    });
+    input.click();
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#cfd-body');
+    try {
+      const [servers, drift] = await Promise.all([api('GET', '/servers'), api('GET', '/drift')]);
+      body.innerHTML = `
+        ${servers.error ? `<div class="cfd-error">${escapeHtml(servers.error)}</div>` : ''}
+        <div class="cfd-desc">${servers.last_run ? `Last snapshot run ${formatTime(servers.last_run)}` : 'No snapshot has run yet.'}</div>
+        ${renderDrift(drift)}
+        <h3>Servers</h3>
+        ${renderServers(servers)}
+        <div id="cfd-history"></div>
+      `;
+      body.querySelectorAll('.cfd-upload').forEach(btn => {
+        btn.addEventListener('click', () => upload(container, btn.dataset.server));
+      });
+      body.querySelectorAll('.cfd-history').forEach(btn => {
+        btn.addEventListener('click', () => showHistory(container, btn.dataset.server));
+      });
+      body.querySelectorAll('.cfd-remove').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          if (!confirm(`Remove ${btn.dataset.name} from ${btn.dataset.server}?`)) return;
+          try {
+            await api('DELETE', `/servers/${encodeURIComponent(btn.dataset.server)}/files/${encodeURIComponent(btn.dataset.name)}`);
+          } catch (err) {
+            alert(err.message);
+          }
+          load(container);
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="cfd-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="cfd-app" data-plugin="${PLUGIN_ID}">
+        <div class="cfd-toolbar">
+          <button id="cfd-snapshot">Snapshot now</button>
+          <span class="cfd-desc">Features and modules come from RPC. Upload a server's config files to compare its blocks too; secrets are stored but only shown as hashes.</span>
+        </div>
+        <div id="cfd-body">Loading...</div>
+      </div>
+    `;
+
+    const snapshot = container.querySelector('#cfd-snapshot');
+    snapshot.addEventListener('click', async () => {
+      snapshot.disabled = true;
+      try {
+        await api('POST', '/snapshot');
+      } catch (err) {
+        alert(err.message);
+      }
+      snapshot.disabled = false;
+      load(container);
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('config-drift-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,270 @@
+package configdrift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+)
+
+// confNode is an entry of an UnrealIRCd configuration file: a name, an
+// optional value and, for blocks, the entries inside
+type confNode struct {
+	Name     string
+	Value    string
+	Block    bool
+	Children []*confNode
+}
+
+// confToken is a lexical token of a configuration file
+type confToken struct {
+	text   string
+	quoted bool
+	line   int
+}
+
+// tokenize splits a configuration file into words, quoted strings and the
+// { } ; punctuation, dropping # // and /* */ comments
+func tokenize(src string) ([]confToken, error) {
+	tokens := make([]confToken, 0)
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			start := line
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: comment is not closed", start)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '{' || c == '}' || c == ';':
+			tokens = append(tokens, confToken{text: string(c), line: line})
+			i++
+		case c == '"':
+			start := line
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(src) {
+					return nil, fmt.Errorf("line %d: string is not closed", start)
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					b.WriteByte(src[i+1])
+					i += 2
+					continue
+				}
+				if src[i] == '"' {
+					i++
+					break
+				}
+				if src[i] == '\n' {
+					line++
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			tokens = append(tokens, confToken{text: b.String(), quoted: true, line: start})
+		default:
+			start := i
+			for i < len(src) && !strings.ContainsRune(" \t\r\n{};\"", rune(src[i])) {
+				i++
+			}
+			tokens = append(tokens, confToken{text: src[start:i], line: line})
+		}
+	}
+	return tokens, nil
+}
+
+// parseConfig parses a configuration file into its top level entries
+func parseConfig(src string) ([]*confNode, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	pos := 0
+	nodes, err := parseEntries(tokens, &pos, false)
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// parseEntries parses entries up to the } closing a block, or the end of
+// the file at the top level
+func parseEntries(tokens []confToken, pos *int, inBlock bool) ([]*confNode, error) {
+	nodes := make([]*confNode, 0)
+	for *pos < len(tokens) {
+		t := tokens[*pos]
+		if !t.quoted && t.text == "}" {
+			if !inBlock {
+				return nil, fmt.Errorf("line %d: unexpected }", t.line)
+			}
+			*pos++
+			return nodes, nil
+		}
+		if !t.quoted && t.text == ";" {
+			*pos++
+			continue
+		}
+		if !t.quoted && t.text == "{" {
+			return nil, fmt.Errorf("line %d: block without a name", t.line)
+		}
+
+		n := &confNode{Name: t.text}
+		*pos++
+		if *pos < len(tokens) && (tokens[*pos].quoted || !strings.Contains("{};", tokens[*pos].text)) {
+			n.Value = tokens[*pos].text
+			*pos++
+		}
+		if *pos >= len(tokens) {
+			return nil, fmt.Errorf("line %d: %s is missing a ;", t.line, n.Name)
+		}
+		next := tokens[*pos]
+		switch {
+		case !next.quoted && next.text == "{":
+			*pos++
+			children, err := parseEntries(tokens, pos, true)
+			if err != nil {
+				return nil, err
+			}
+			n.Block = true
+			n.Children = children
+		case !next.quoted && next.text == ";":
+			*pos++
+		case !next.quoted && next.text == "}":
+		default:
+			return nil, fmt.Errorf("line %d: %s is missing a ;", t.line, n.Name)
+		}
+		nodes = append(nodes, n)
+	}
+	if inBlock {
+		return nil, fmt.Errorf("block is not closed at the end of the file")
+	}
+	return nodes, nil
+}
+
+// flattenConfig turns entries into "block::entry" = value items. Blocks
+// are keyed by name and value, such as oper "admin"::class. Where a name
+// appears more than once in a block, simple entries are keyed by their
+// value too, so reordering loadmodule lines is not a change, and blocks
+// that still collide are numbered. Secrets are replaced by a hash.
+func flattenConfig(nodes []*confNode, items map[string]string) {
+	flatten(nodes, "", false, items)
+}
+
+// flatten adds the items of nodes below prefix
+func flatten(nodes []*confNode, prefix string, secret bool, items map[string]string) {
+	names := make(map[string]int)
+	for _, n := range nodes {
+		names[n.Name]++
+	}
+	seen := make(map[string]int)
+	for i, n := range nodes {
+		name, value := n.Name, n.Value
+		if secret {
+			// Entries of a secret block, such as the cloak keys, are
+			// the secrets themselves
+			name, value = "entry#"+strconv.Itoa(i+1), redact(n.Name+" "+n.Value)
+		} else if isSecret(name) && value != "" {
+			value = redact(value)
+		}
+
+		key := prefix + name
+		if value != "" && (n.Block || names[n.Name] > 1) {
+			key += " " + strconv.Quote(value)
+		}
+		seen[key]++
+		if seen[key] > 1 {
+			key += "#" + strconv.Itoa(seen[key])
+		}
+
+		switch {
+		case n.Block && len(n.Children) > 0:
+			flatten(n.Children, key+"::", secret || secretBlocks[strings.ToLower(n.Name)], items)
+		case n.Block:
+			items[key] = "{}"
+		case names[n.Name] > 1:
+			items[key] = ""
+		default:
+			items[key] = value
+		}
+	}
+}
+
+// secretBlocks are blocks whose entries are secrets
+var secretBlocks = map[string]bool{
+	"cloak-keys": true,
+}
+
+// isSecret reports whether an entry's value is a secret
+func isSecret(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "password") || strings.Contains(name, "secret") || name == "cookie"
+}
+
+// redact replaces a secret with a short hash, so that changes to it still
+// show without revealing it
+func redact(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "<redacted " + hex.EncodeToString(sum[:6]) + ">"
+}
+
+// Change is an item that differs between two sets of items. Old or New
+// is nil when the item is missing on that side.
+type Change struct {
+	Key string  `json:"key"`
+	Old *string `json:"old"`
+	New *string `json:"new"`
+}
+
+// diffItems returns the items that differ between a and b, sorted by key.
+// Items matching any of ignore are left out.
+func diffItems(a, b map[string]string, ignore []string) []Change {
+	changes := make([]Change, 0)
+	for key, av := range a {
+		if matchAny(ignore, key) {
+			continue
+		}
+		av := av
+		if bv, ok := b[key]; !ok {
+			changes = append(changes, Change{Key: key, Old: &av})
+		} else if bv != av {
+			bv := bv
+			changes = append(changes, Change{Key: key, Old: &av, New: &bv})
+		}
+	}
+	for key, bv := range b {
+		if _, ok := a[key]; ok || matchAny(ignore, key) {
+			continue
+		}
+		bv := bv
+		changes = append(changes, Change{Key: key, New: &bv})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// matchAny reports whether key matches any of the wildcard masks
+func matchAny(masks []string, key string) bool {
+	for _, m := range masks {
+		if ircname.MatchMask(m, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,780 @@
+// Config Drift Plugin for UnrealIRCd Web Panel
+// Snapshots each server's features, modules and uploaded configuration
+// files, compares servers and snapshots and alerts on unexpected drift
+
+package configdrift
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on uploaded files
+const (
+	maxFileSize       = 1 << 20
+	maxFilesPerServer = 50
+)
+
+// maxAlertKeys limits the items listed in an alert
+const maxAlertKeys = 20
+
+// ConfigDriftPlugin implements the Plugin interface
+type ConfigDriftPlugin struct {
+	config  Config
+	rpc     *jsonrpc.Client
+	servers map[string]*ServerState
+	alerted map[string]bool
+	lastRun time.Time
+	lastErr string
+	running bool
+	mu      sync.RWMutex
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL              string `json:"rpc_url"`
+	RPCUser             string `json:"rpc_user"`
+	RPCPassword         string `json:"rpc_password"`
+	RPCInsecure         bool   `json:"rpc_insecure"`
+	DataDir             string `json:"data_dir"`
+	SkipServices        bool   `json:"skip_services"`
+	SnapshotInterval    int    `json:"snapshot_interval"`
+	MaxSnapshots        int    `json:"max_snapshots"`
+	ExpectedDifferences string `json:"expected_differences"`
+	IgnoreChanges       string `json:"ignore_changes"`
+	AlertWebhook        string `json:"alert_webhook"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Servers map[string]*ServerState `json:"servers"`
+	Alerted []string                `json:"alerted"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined   bool                   `json:"ulined"`
+		Features map[string]interface{} `json:"features"`
+	} `json:"server"`
+}
+
+// rpcModule is an entry of server.module_list
+type rpcModule struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ConfigDriftPlugin{
+		config: Config{
+			RPCURL:              "https://127.0.0.1:8600/api",
+			DataDir:             "data/plugins/config-drift",
+			SkipServices:        true,
+			SnapshotInterval:    60,
+			MaxSnapshots:        100,
+			ExpectedDifferences: "me::*,listen*,link *",
+		},
+		servers: make(map[string]*ServerState),
+		alerted: make(map[string]bool),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ConfigDriftPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Config Drift",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Snapshots server configuration and reports differences and drift",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ConfigDriftPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[config-drift] failed to load data: %v", err)
+	}
+	if data.Servers != nil {
+		p.servers = data.Servers
+	}
+	for _, key := range data.Alerted {
+		p.alerted[key] = true
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "config-drift-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"servers": len(p.servers),
+			"drift":   len(findDrift(p.linked(), lists.Split(p.config.ExpectedDifferences))),
+		}
+		var last time.Time
+		for _, s := range p.servers {
+			if snap := s.latest(); snap != nil && snap.Time.After(last) {
+				last = snap.Time
+			}
+		}
+		if !last.IsZero() {
+			content["last_change"] = last
+		}
+		if p.lastErr != "" {
+			content["status"] = p.lastErr
+		}
+		return plugins.DashboardCard{
+			Title:   "Config Drift",
+			Icon:    "FileDiff",
+			Content: content,
+			Order:   96,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.snapshotLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ConfigDriftPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ConfigDriftPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/config-drift")
+	{
+		plugin.GET("/servers", p.handleServers)
+		plugin.POST("/snapshot", p.handleSnapshot)
+		plugin.GET("/servers/:server/snapshots", p.handleSnapshots)
+		plugin.GET("/servers/:server/snapshots/:id", p.handleGetSnapshot)
+		plugin.POST("/servers/:server/files", p.handleUpload)
+		plugin.DELETE("/servers/:server/files/:name", p.handleDeleteFile)
+		plugin.GET("/diff", p.handleDiff)
+		plugin.GET("/drift", p.handleDrift)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file snapshots are kept in
+func (p *ConfigDriftPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "snapshots.json")
+}
+
+// save writes the servers' state to disk
+func (p *ConfigDriftPlugin) save() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	alerted := make([]string, 0, len(p.alerted))
+	for key := range p.alerted {
+		alerted = append(alerted, key)
+	}
+	sort.Strings(alerted)
+	return storage.SaveJSON(p.storePath(), storeData{Servers: p.servers, Alerted: alerted})
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *ConfigDriftPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// linked returns the servers seen in the last server list, sorted by
+// name. Caller must hold p.mu.
+func (p *ConfigDriftPlugin) linked() []*ServerState {
+	list := make([]*ServerState, 0, len(p.servers))
+	for _, s := range p.servers {
+		if s.Linked {
+			list = append(list, s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// snapshotLoop snapshots the servers every snapshot_interval minutes
+// until shutdown. An interval of 0 only snapshots on request.
+func (p *ConfigDriftPlugin) snapshotLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(time.Minute)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.SnapshotInterval) * time.Minute
+		p.mu.RUnlock()
+		if interval == 0 {
+			timer.Reset(time.Minute)
+			continue
+		}
+
+		if err := p.snapshot("scheduled", ""); err != nil {
+			log.Printf("[config-drift] %v", err)
+		}
+		timer.Reset(interval)
+	}
+}
+
+// snapshot fetches every server's features and modules, records a
+// snapshot of each server whose items changed and checks for drift
+func (p *ConfigDriftPlugin) snapshot(reason, actor string) error {
+	p.mu.Lock()
+	if p.running {
+		p.mu.Unlock()
+		return fmt.Errorf("a snapshot is already running")
+	}
+	p.running = true
+	cfg := p.config
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.running = false
+		p.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	rpc := p.client()
+
+	var list struct {
+		List []rpcServer `json:"list"`
+	}
+	err := rpc.Call(ctx, "server.list", nil, &list)
+	now := time.Now().UTC()
+	if err != nil {
+		p.mu.Lock()
+		p.lastRun = now
+		p.lastErr = "Failed to list servers: " + err.Error()
+		p.mu.Unlock()
+		return fmt.Errorf("failed to list servers: %w", err)
+	}
+
+	type fetched struct {
+		items map[string]string
+		err   error
+	}
+	results := make(map[string]fetched)
+	for _, s := range list.List {
+		if s.Server.ULined && cfg.SkipServices {
+			continue
+		}
+		var mods struct {
+			List []rpcModule `json:"list"`
+		}
+		err := rpc.Call(ctx, "server.module_list", map[string]interface{}{"server": s.Name}, &mods)
+		results[s.Name] = fetched{items: rpcItems(s.Server.Features, mods.List), err: err}
+	}
+
+	alerts := make([]notify.Event, 0)
+	ignore := lists.Split(cfg.IgnoreChanges)
+
+	p.mu.Lock()
+	p.lastRun = now
+	p.lastErr = ""
+	for _, s := range p.servers {
+		s.Linked = false
+	}
+	for name, res := range results {
+		s := p.server(name)
+		s.Linked = true
+		s.LastChecked = now
+		if res.err != nil {
+			s.Error = res.err.Error()
+			continue
+		}
+		s.Error = ""
+		s.RPC = res.items
+		changes := s.record(newID(), reason, actor, now, p.config.MaxSnapshots)
+		if changes == nil || reason != "scheduled" {
+			continue
+		}
+		if al := changeAlert(name, filterChanges(changes, ignore)); al != nil {
+			alerts = append(alerts, *al)
+		}
+	}
+	if al := p.checkDrift(); al != nil {
+		alerts = append(alerts, *al)
+	}
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[config-drift] failed to save data: %v", err)
+	}
+	p.send(webhook, alerts)
+	return nil
+}
+
+// server returns the state of the named server, creating it if needed.
+// Caller must hold p.mu.
+func (p *ConfigDriftPlugin) server(name string) *ServerState {
+	s, ok := p.servers[name]
+	if !ok {
+		s = &ServerState{
+			Name:      name,
+			RPC:       make(map[string]string),
+			Files:     make(map[string]*UploadedFile),
+			Snapshots: make([]*Snapshot, 0),
+		}
+		p.servers[name] = s
+	}
+	return s
+}
+
+// checkDrift compares the linked servers and returns an alert for items
+// that started to differ since the last check. Items that stop differing
+// can alert again later. Caller must hold p.mu.
+func (p *ConfigDriftPlugin) checkDrift() *notify.Event {
+	drift := findDrift(p.linked(), lists.Split(p.config.ExpectedDifferences))
+	current := make(map[string]bool, len(drift))
+	fresh := make([]string, 0)
+	for _, d := range drift {
+		current[d.Key] = true
+		if !p.alerted[d.Key] {
+			fresh = append(fresh, d.Key)
+		}
+	}
+	p.alerted = current
+	if len(fresh) == 0 {
+		return nil
+	}
+	return &notify.Event{
+		Type:     "config_drift",
+		Severity: "warning",
+		Title:    fmt.Sprintf("%d configuration items differ between servers", len(fresh)),
+		Message:  strings.Join(truncate(fresh), ", "),
+		Data:     map[string]interface{}{"keys": truncate(fresh), "count": len(fresh), "total": len(drift)},
+	}
+}
+
+// changeAlert returns an alert for a server's configuration changes, or
+// nil if there are none
+func changeAlert(server string, changes []Change) *notify.Event {
+	if len(changes) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		keys = append(keys, ch.Key)
+	}
+	return &notify.Event{
+		Type:     "config_changed",
+		Severity: "warning",
+		Title:    fmt.Sprintf("Configuration of %s changed (%d items)", server, len(changes)),
+		Message:  strings.Join(truncate(keys), ", "),
+		Data:     map[string]interface{}{"server": server, "keys": truncate(keys), "count": len(changes)},
+	}
+}
+
+// filterChanges drops the changes to items matching ignore
+func filterChanges(changes []Change, ignore []string) []Change {
+	out := make([]Change, 0, len(changes))
+	for _, ch := range changes {
+		if !matchAny(ignore, ch.Key) {
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// truncate limits a list of keys to maxAlertKeys
+func truncate(keys []string) []string {
+	if len(keys) > maxAlertKeys {
+		return keys[:maxAlertKeys]
+	}
+	return keys
+}
+
+// send logs alerts and posts them to the webhook
+func (p *ConfigDriftPlugin) send(webhook string, alerts []notify.Event) {
+	for _, al := range alerts {
+		log.Printf("[config-drift] %s: %s", al.Title, al.Message)
+		if webhook != "" {
+			al.Source = "config-drift"
+			al.Timestamp = time.Now().UTC()
+			go p.alert(webhook, al)
+		}
+	}
+}
+
+// alert posts an alert to the webhook
+func (p *ConfigDriftPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[config-drift] failed to send alert: %v", err)
+	}
+}
+
+// handleServers returns each server with its latest snapshot and files
+func (p *ConfigDriftPlugin) handleServers(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]gin.H, 0, len(p.servers))
+	names := make([]string, 0, len(p.servers))
+	for name := range p.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := p.servers[name]
+		files := make([]UploadedFile, 0, len(s.Files))
+		for _, f := range s.Files {
+			meta := *f
+			meta.Content = ""
+			files = append(files, meta)
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+		entry := gin.H{
+			"name":         s.Name,
+			"linked":       s.Linked,
+			"files":        files,
+			"snapshots":    len(s.Snapshots),
+			"last_checked": s.LastChecked,
+			"error":        s.Error,
+		}
+		if snap := s.latest(); snap != nil {
+			meta := *snap
+			meta.Items = nil
+			entry["latest"] = meta
+		}
+		list = append(list, entry)
+	}
+	status := gin.H{"servers": list, "running": p.running, "error": p.lastErr}
+	if !p.lastRun.IsZero() {
+		status["last_run"] = p.lastRun
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleSnapshot snapshots every server now
+func (p *ConfigDriftPlugin) handleSnapshot(c *gin.Context) {
+	if err := p.snapshot("manual", access.Actor(c)); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Snapshot failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Snapshot taken"})
+}
+
+// handleSnapshots lists a server's snapshots, newest first, without their
+// items
+func (p *ConfigDriftPlugin) handleSnapshots(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s, ok := p.servers[c.Param("server")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+		return
+	}
+	list := make([]Snapshot, 0, len(s.Snapshots))
+	for i := len(s.Snapshots) - 1; i >= 0; i-- {
+		meta := *s.Snapshots[i]
+		meta.Items = nil
+		list = append(list, meta)
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshots": list})
+}
+
+// handleGetSnapshot returns one snapshot with its items
+func (p *ConfigDriftPlugin) handleGetSnapshot(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s, ok := p.servers[c.Param("server")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+		return
+	}
+	snap := s.snapshot(c.Param("id"))
+	if snap == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+		return
+	}
+	c.JSON(http.StatusOK, snap)
+}
+
+// handleUpload stores a configuration file for a server and records a
+// snapshot with it
+func (p *ConfigDriftPlugin) handleUpload(c *gin.Context) {
+	var req struct {
+		Name    string `json:"name"`
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.Name = filepath.Base(strings.TrimSpace(req.Name))
+	if req.Name == "" || req.Name == "." || req.Name == "/" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if len(req.Content) > maxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is larger than 1 MiB"})
+		return
+	}
+	nodes, err := parseConfig(req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse " + req.Name + ": " + err.Error()})
+		return
+	}
+	entries := make(map[string]string)
+	flattenConfig(nodes, entries)
+
+	p.mu.Lock()
+	s, ok := p.servers[c.Param("server")]
+	if !ok {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+		return
+	}
+	if _, exists := s.Files[req.Name]; !exists && len(s.Files) >= maxFilesPerServer {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A server can have at most %d files", maxFilesPerServer)})
+		return
+	}
+	actor := access.Actor(c)
+	s.Files[req.Name] = &UploadedFile{
+		Name:       req.Name,
+		Content:    req.Content,
+		Size:       len(req.Content),
+		Entries:    len(entries),
+		UploadedAt: time.Now().UTC(),
+		UploadedBy: actor,
+	}
+	changes := s.record(newID(), "upload", actor, time.Now().UTC(), p.config.MaxSnapshots)
+	alerts := make([]notify.Event, 0)
+	if al := p.checkDrift(); al != nil {
+		alerts = append(alerts, *al)
+	}
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[config-drift] failed to save data: %v", err)
+	}
+	p.send(webhook, alerts)
+	log.Printf("[config-drift] %s uploaded %s for %s", actor, req.Name, c.Param("server"))
+
+	if changes == nil {
+		changes = make([]Change, 0)
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": len(entries), "changes": changes})
+}
+
+// handleDeleteFile removes an uploaded file and records a snapshot
+// without it
+func (p *ConfigDriftPlugin) handleDeleteFile(c *gin.Context) {
+	p.mu.Lock()
+	s, ok := p.servers[c.Param("server")]
+	if !ok {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+		return
+	}
+	if _, ok := s.Files[c.Param("name")]; !ok {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	delete(s.Files, c.Param("name"))
+	s.record(newID(), "upload", access.Actor(c), time.Now().UTC(), p.config.MaxSnapshots)
+	p.checkDrift()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[config-drift] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "File removed"})
+}
+
+// handleDiff compares two snapshots. With ?a= and ?b= it compares the
+// latest snapshots of two servers; with ?server= it compares the
+// server's snapshots ?from= and ?to=, by default the last two.
+func (p *ConfigDriftPlugin) handleDiff(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var from, to *Snapshot
+	if name := c.Query("server"); name != "" {
+		s, ok := p.servers[name]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+			return
+		}
+		n := len(s.Snapshots)
+		if id := c.Query("to"); id != "" {
+			to = s.snapshot(id)
+		} else if n > 0 {
+			to = s.Snapshots[n-1]
+		}
+		if id := c.Query("from"); id != "" {
+			from = s.snapshot(id)
+		} else if n > 1 {
+			from = s.Snapshots[n-2]
+		}
+	} else {
+		a, aok := p.servers[c.Query("a")]
+		b, bok := p.servers[c.Query("b")]
+		if !aok || !bok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+			return
+		}
+		from, to = a.latest(), b.latest()
+	}
+	if from == nil || to == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    gin.H{"id": from.ID, "time": from.Time},
+		"to":      gin.H{"id": to.ID, "time": to.Time},
+		"changes": diffItems(from.Items, to.Items, nil),
+	})
+}
+
+// handleDrift returns the items that differ between the linked servers,
+// leaving out the expected differences
+func (p *ConfigDriftPlugin) handleDrift(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	servers := p.linked()
+	names := make([]string, 0, len(servers))
+	for _, s := range servers {
+		if s.latest() != nil {
+			names = append(names, s.Name)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"servers":  names,
+		"expected": lists.Split(p.config.ExpectedDifferences),
+		"drift":    findDrift(servers, lists.Split(p.config.ExpectedDifferences)),
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ConfigDriftPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ConfigDriftPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.SnapshotInterval < 0 || newConfig.SnapshotInterval > 1440 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "snapshot_interval must be between 0 and 1440 minutes"})
+		return
+	}
+	if newConfig.MaxSnapshots < 2 || newConfig.MaxSnapshots > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_snapshots must be between 2 and 1000"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ConfigDriftPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ConfigDriftPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
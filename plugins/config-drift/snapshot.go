@@ -0,0 +1,221 @@
+package configdrift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Prefixes of the items that come from the IRCd over RPC. All other items
+// come from uploaded configuration files.
+const (
+	featurePrefix = "feature."
+	modulePrefix  = "module."
+)
+
+// Snapshot is a server's configuration items at one point in time
+type Snapshot struct {
+	ID      string            `json:"id"`
+	Time    time.Time         `json:"time"`
+	Reason  string            `json:"reason"`
+	Actor   string            `json:"actor,omitempty"`
+	Hash    string            `json:"hash"`
+	Files   []string          `json:"files"`
+	Count   int               `json:"count"`
+	Changes int               `json:"changes"`
+	Items   map[string]string `json:"items,omitempty"`
+}
+
+// UploadedFile is a configuration file uploaded for a server
+type UploadedFile struct {
+	Name       string    `json:"name"`
+	Content    string    `json:"content,omitempty"`
+	Size       int       `json:"size"`
+	Entries    int       `json:"entries"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	UploadedBy string    `json:"uploaded_by"`
+}
+
+// ServerState is what is known about one server's configuration
+type ServerState struct {
+	Name        string                   `json:"name"`
+	Linked      bool                     `json:"linked"`
+	RPC         map[string]string        `json:"rpc"`
+	Files       map[string]*UploadedFile `json:"files"`
+	Snapshots   []*Snapshot              `json:"snapshots"`
+	LastChecked time.Time                `json:"last_checked"`
+	Error       string                   `json:"error,omitempty"`
+}
+
+// latest returns the server's newest snapshot, or nil
+func (s *ServerState) latest() *Snapshot {
+	if len(s.Snapshots) == 0 {
+		return nil
+	}
+	return s.Snapshots[len(s.Snapshots)-1]
+}
+
+// snapshot returns the snapshot with the given ID, or nil
+func (s *ServerState) snapshot(id string) *Snapshot {
+	for _, snap := range s.Snapshots {
+		if snap.ID == id {
+			return snap
+		}
+	}
+	return nil
+}
+
+// items merges the RPC items with the items of the uploaded files. The
+// files are flattened together, since includes make them one
+// configuration.
+func (s *ServerState) items() (map[string]string, []string) {
+	items := make(map[string]string, len(s.RPC))
+	for key, value := range s.RPC {
+		items[key] = value
+	}
+	names := make([]string, 0, len(s.Files))
+	for name := range s.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	nodes := make([]*confNode, 0)
+	for _, name := range names {
+		// Files were checked when uploaded
+		parsed, _ := parseConfig(s.Files[name].Content)
+		nodes = append(nodes, parsed...)
+	}
+	flattenConfig(nodes, items)
+	return items, names
+}
+
+// record adds a snapshot of the server's current items if they changed
+// since the last one, keeping at most max. It returns the changes, or
+// nil if nothing changed.
+func (s *ServerState) record(id, reason, actor string, now time.Time, max int) []Change {
+	items, files := s.items()
+	hash := hashItems(items)
+	prev := s.latest()
+	if prev != nil && prev.Hash == hash {
+		return nil
+	}
+	var changes []Change
+	if prev != nil {
+		changes = diffItems(prev.Items, items, nil)
+	}
+	s.Snapshots = append(s.Snapshots, &Snapshot{
+		ID:      id,
+		Time:    now,
+		Reason:  reason,
+		Actor:   actor,
+		Hash:    hash,
+		Files:   files,
+		Count:   len(items),
+		Changes: len(changes),
+		Items:   items,
+	})
+	if len(s.Snapshots) > max {
+		s.Snapshots = s.Snapshots[len(s.Snapshots)-max:]
+	}
+	if changes == nil {
+		changes = make([]Change, 0)
+	}
+	return changes
+}
+
+// hashItems returns a hash of the items, independent of map order
+func hashItems(items map[string]string) string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(items[key]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// rpcItems flattens a server's features and loaded modules into items
+func rpcItems(features map[string]interface{}, modules []rpcModule) map[string]string {
+	items := make(map[string]string, len(features)+len(modules))
+	for key, value := range features {
+		if s, ok := value.(string); ok {
+			items[featurePrefix+key] = s
+		} else if b, err := json.Marshal(value); err == nil {
+			items[featurePrefix+key] = string(b)
+		}
+	}
+	for _, m := range modules {
+		items[modulePrefix+m.Name] = m.Version
+	}
+	return items
+}
+
+// fromRPC reports whether an item comes from the IRCd rather than from an
+// uploaded file
+func fromRPC(key string) bool {
+	return strings.HasPrefix(key, featurePrefix) || strings.HasPrefix(key, modulePrefix)
+}
+
+// Drift is an item that is not the same on every server. Values holds
+// each server's value, nil where the server doesn't have the item.
+type Drift struct {
+	Key    string             `json:"key"`
+	Values map[string]*string `json:"values"`
+}
+
+// findDrift compares the latest snapshots of the servers. Items matching
+// expected are left out. Items from files are only compared between
+// servers with uploaded files.
+func findDrift(servers []*ServerState, expected []string) []Drift {
+	snaps := make(map[string]*Snapshot)
+	for _, s := range servers {
+		if snap := s.latest(); snap != nil {
+			snaps[s.Name] = snap
+		}
+	}
+
+	keys := make(map[string]bool)
+	for _, snap := range snaps {
+		for key := range snap.Items {
+			if !matchAny(expected, key) {
+				keys[key] = true
+			}
+		}
+	}
+
+	drift := make([]Drift, 0)
+	for key := range keys {
+		d := Drift{Key: key, Values: make(map[string]*string)}
+		var first *string
+		differs, compared := false, 0
+		for name, snap := range snaps {
+			if !fromRPC(key) && len(snap.Files) == 0 {
+				continue
+			}
+			compared++
+			var v *string
+			if value, ok := snap.Items[key]; ok {
+				v = &value
+			}
+			d.Values[name] = v
+			if compared == 1 {
+				first = v
+			} else if (v == nil) != (first == nil) || (v != nil && *v != *first) {
+				differs = true
+			}
+		}
+		if differs {
+			drift = append(drift, d)
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Key < drift[j].Key })
+	return drift
+}
@@ -0,0 +1,316 @@
+/**
+ * Connection Test Frontend Script
+ *
+ * Runs client connection tests from the plugin page and shows each step's
+ * timing and outcome, with the protocol transcript and past results.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'connection-test';
+  const PLUGIN_NAME = 'Connection Test';
+  const PAGE_PATH = '/plugins/connection-test';
+  const API_BASE = '/api/plugin/connection-test';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  function formatMs(ms) {
+    return ms >= 1000 ? `${(ms / 1000).toFixed(2)} s` : `${ms.toFixed(1)} ms`;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('connection-test-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'connection-test-styles';
+    style.textContent = `
+      .cnt-app { display: flex; flex-direction: column; gap: 1rem; }
+      .cnt-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .cnt-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .cnt-btn.small { padding: 0.2rem 0.5rem; font-size: 0.75rem; }
+      .cnt-btn:disabled { opacity: 0.6; cursor: wait; }
+      .cnt-app input, .cnt-app select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .cnt-app input.port { width: 6rem; }
+      .cnt-row { display: flex; gap: 0.75rem; align-items: center; flex-wrap: wrap; font-size: 0.85rem; color: var(--text-secondary, #a6adc8); }
+      .cnt-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .cnt-table th, .cnt-table td {
+        text-align: left;
+        padding: 0.35rem 0.5rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-primary, #cdd6f4);
+        vertical-align: top;
+      }
+      .cnt-table th { color: var(--text-secondary, #a6adc8); font-weight: 500; }
+      .cnt-table tr.clickable { cursor: pointer; }
+      .cnt-bar { height: 0.5rem; background: var(--accent, #89b4fa); border-radius: 2px; min-width: 2px; }
+      .cnt-ok { color: var(--success, #a6e3a1); }
+      .cnt-fail { color: var(--error, #f38ba8); }
+      .cnt-warn { color: var(--warning, #f9e2af); font-size: 0.8rem; }
+      .cnt-muted { color: var(--text-muted, #6c7086); }
+      .cnt-empty { color: var(--text-muted, #6c7086); padding: 0.5rem 0; }
+      .cnt-transcript {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.5rem;
+        max-height: 24rem;
+        overflow: auto;
+        font-family: monospace;
+        font-size: 0.75rem;
+        white-space: pre-wrap;
+        color: var(--text-primary, #cdd6f4);
+      }
+      .cnt-transcript .out { color: var(--accent, #89b4fa); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderResult(r) {
+    const total = Math.max(r.duration_ms, 1);
+    const steps = r.steps.map(s => `
+      <tr>
+        <td class="${s.ok ? 'cnt-ok' : 'cnt-fail'}">${s.ok ? '✓' : '✗'} ${escapeHtml(s.name)}</td>
+        <td>${escapeHtml(formatMs(s.duration_ms))}</td>
+        <td style="width: 25%"><div class="cnt-bar" style="margin-left: ${(s.started_ms / total) * 100}%; width: ${(s.duration_ms / total) * 100}%"></div></td>
+        <td>${escapeHtml(s.detail)}${s.warning ? `<div class="cnt-warn">⚠ ${escapeHtml(s.warning)}</div>` : ''}</td>
+      </tr>
+    `).join('');
+
+    const transcript = r.transcript.map(l =>
+      `<div class="${l.dir === '>' ? 'out' : ''}">${escapeHtml(l.at_ms.toFixed(1).padStart(9))} ${escapeHtml(l.dir)} ${escapeHtml(l.text)}</div>`
+    ).join('');
+
+    return `
+      <div class="cnt-row">
+        <strong class="${r.ok ? 'cnt-ok' : 'cnt-fail'}">${r.ok ? 'Connected' : 'Failed'}</strong>
+        <span>${escapeHtml(r.target)} (${escapeHtml(r.transport)}${r.sasl ? `, SASL ${escapeHtml(r.sasl)}` : ''})</span>
+        <span>${escapeHtml(formatMs(r.duration_ms))}</span>
+        ${r.server ? `<span>${escapeHtml(r.server)} ${escapeHtml(r.version || '')}</span>` : ''}
+        ${r.network ? `<span>${escapeHtml(r.network)}</span>` : ''}
+        <span class="cnt-muted">by ${escapeHtml(r.run_by)} ${escapeHtml(formatTime(r.started_at))}</span>
+      </div>
+      ${r.error ? `<div class="cnt-fail">${escapeHtml(r.error)}</div>` : ''}
+      <table class="cnt-table">
+        <thead><tr><th>Step</th><th>Time</th><th></th><th>Result</th></tr></thead>
+        <tbody>${steps}</tbody>
+      </table>
+      <details>
+        <summary>Protocol transcript (${r.transcript.length} lines)</summary>
+        <div class="cnt-transcript">${transcript}</div>
+      </details>
+    `;
+  }
+
+  async function loadHistory(container) {
+    const list = container.querySelector('#cnt-history');
+    try {
+      const data = await api('GET', '/tests');
+      list.innerHTML = data.tests.length
+        ? `<table class="cnt-table">
+            <thead><tr><th>When</th><th>Target</th><th>Result</th><th>Time</th><th>By</th><th></th></tr></thead>
+            <tbody>${data.tests.map(r => `
+              <tr class="clickable" data-id="${escapeHtml(r.id)}">
+                <td>${escapeHtml(formatTime(r.started_at))}</td>
+                <td>${escapeHtml(r.target)} (${escapeHtml(r.transport)})</td>
+                <td class="${r.ok ? 'cnt-ok' : 'cnt-fail'}">${r.ok ? 'OK' : escapeHtml(r.error)}</td>
+                <td>${escapeHtml(formatMs(r.duration_ms))}</td>
+                <td>${escapeHtml(r.run_by)}</td>
+                <td><button class="cnt-btn small" data-action="delete" data-id="${escapeHtml(r.id)}">Delete</button></td>
+              </tr>
+            `).join('')}</tbody>
+          </table>`
+        : '<div class="cnt-empty">No tests yet.</div>';
+    } catch (e) {
+      list.innerHTML = `<div class="cnt-fail">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadServers(container) {
+    try {
+      const data = await api('GET', '/servers');
+      container.querySelector('#cnt-servers').innerHTML = data.servers.map(s => `<option value="${escapeHtml(s)}">`).join('');
+    } catch (e) {
+      // Suggestions are optional; RPC may not be configured
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="cnt-app" data-plugin="${PLUGIN_ID}">
+        <form id="cnt-form">
+          <div class="cnt-row">
+            <input type="text" name="host" placeholder="irc.example.net" list="cnt-servers" required>
+            <datalist id="cnt-servers"></datalist>
+            <select name="transport">
+              <option value="tls">TLS</option>
+              <option value="plain">Plaintext</option>
+              <option value="wss">WebSocket (wss)</option>
+              <option value="ws">WebSocket (ws)</option>
+            </select>
+            <input type="number" name="port" class="port" placeholder="Port" min="1" max="65535">
+            <input type="text" name="path" placeholder="WebSocket path">
+            <input type="text" name="nick" placeholder="Nick (random)">
+          </div>
+          <div class="cnt-row" style="margin-top: 0.5rem">
+            <select name="sasl">
+              <option value="">No SASL</option>
+              <option value="plain">SASL PLAIN</option>
+              <option value="external">SASL EXTERNAL</option>
+            </select>
+            <input type="text" name="sasl_user" placeholder="SASL account">
+            <input type="password" name="sasl_pass" placeholder="SASL password">
+            <input type="password" name="password" placeholder="Server password">
+            <button class="cnt-btn primary" type="submit">Run test</button>
+          </div>
+        </form>
+        <div id="cnt-result"></div>
+        <div>
+          <h3>Past tests</h3>
+          <div id="cnt-history"><div class="cnt-empty">Loading...</div></div>
+        </div>
+      </div>
+    `;
+
+    const form = container.querySelector('#cnt-form');
+    const out = container.querySelector('#cnt-result');
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = form.elements;
+      const btn = form.querySelector('button[type="submit"]');
+      btn.disabled = true;
+      out.innerHTML = '<div class="cnt-empty">Connecting...</div>';
+      try {
+        const result = await api('POST', '/tests', {
+          host: f.host.value,
+          transport: f.transport.value,
+          port: parseInt(f.port.value, 10) || 0,
+          path: f.path.value,
+          nick: f.nick.value,
+          sasl: f.sasl.value,
+          sasl_user: f.sasl_user.value,
+          sasl_pass: f.sasl_pass.value,
+          password: f.password.value
+        });
+        out.innerHTML = renderResult(result);
+        loadHistory(container);
+      } catch (err) {
+        out.innerHTML = `<div class="cnt-fail">${escapeHtml(err.message)}</div>`;
+      } finally {
+        btn.disabled = false;
+      }
+    });
+
+    container.querySelector('#cnt-history').addEventListener('click', async (e) => {
+      const del = e.target.closest('[data-action="delete"]');
+      const row = e.target.closest('tr[data-id]');
+      try {
+        if (del) {
+          await api('DELETE', `/tests/${encodeURIComponent(del.dataset.id)}`);
+          loadHistory(container);
+        } else if (row) {
+          out.innerHTML = renderResult(await api('GET', `/tests/${encodeURIComponent(row.dataset.id)}`));
+          out.scrollIntoView({ behavior: 'smooth' });
+        }
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    loadHistory(container);
+    loadServers(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('connection-test-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
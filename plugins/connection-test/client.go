@@ -0,0 +1,824 @@
+package connectiontest
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// Transports a test can connect with
+const (
+	TransportPlain = "plain"
+	TransportTLS   = "tls"
+	TransportWS    = "ws"
+	TransportWSS   = "wss"
+)
+
+// maxTranscript caps the protocol lines kept for a test
+const maxTranscript = 500
+
+// errTimeout is returned when the server doesn't answer in time
+var errTimeout = errors.New("timed out waiting for the server")
+
+// closedError is returned when the server sends ERROR and closes the link
+type closedError struct {
+	reason string
+}
+
+func (e *closedError) Error() string {
+	return "server closed the link: " + e.reason
+}
+
+// quitWait is how long to wait for the server to close the link after QUIT
+const quitWait = 5 * time.Second
+
+// TestRequest describes a connection test
+type TestRequest struct {
+	Host      string   `json:"host"`
+	Port      int      `json:"port"`
+	Transport string   `json:"transport"`
+	Path      string   `json:"path"`
+	Nick      string   `json:"nick"`
+	Username  string   `json:"username"`
+	Realname  string   `json:"realname"`
+	Password  string   `json:"password,omitempty"`
+	SASL      string   `json:"sasl"`
+	SASLUser  string   `json:"sasl_user"`
+	SASLPass  string   `json:"sasl_pass,omitempty"`
+	Caps      []string `json:"caps"`
+	Timeout   int      `json:"timeout"`
+}
+
+// Step is one stage of a test and how long it took. Times are in
+// milliseconds from the start of the test.
+type Step struct {
+	Name     string  `json:"name"`
+	Started  float64 `json:"started_ms"`
+	Duration float64 `json:"duration_ms"`
+	OK       bool    `json:"ok"`
+	Detail   string  `json:"detail"`
+	Warning  string  `json:"warning,omitempty"`
+}
+
+// Line is a protocol line sent (">") or received ("<")
+type Line struct {
+	At   float64 `json:"at_ms"`
+	Dir  string  `json:"dir"`
+	Text string  `json:"text"`
+}
+
+// Result is the outcome of a connection test
+type Result struct {
+	ID         string    `json:"id"`
+	Target     string    `json:"target"`
+	Transport  string    `json:"transport"`
+	SASL       string    `json:"sasl"`
+	RunBy      string    `json:"run_by"`
+	StartedAt  time.Time `json:"started_at"`
+	Duration   float64   `json:"duration_ms"`
+	OK         bool      `json:"ok"`
+	Error      string    `json:"error,omitempty"`
+	Nick       string    `json:"nick"`
+	Server     string    `json:"server,omitempty"`
+	Version    string    `json:"version,omitempty"`
+	Network    string    `json:"network,omitempty"`
+	Caps       []string  `json:"caps"`
+	Steps      []Step    `json:"steps"`
+	Transcript []Line    `json:"transcript"`
+}
+
+// message is a parsed IRC line
+type message struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+// param returns the i'th parameter, or "" if there are fewer
+func (m message) param(i int) string {
+	if i < len(m.params) {
+		return m.params[i]
+	}
+	return ""
+}
+
+// last returns the last parameter, usually the human readable text
+func (m message) last() string {
+	if len(m.params) == 0 {
+		return ""
+	}
+	return m.params[len(m.params)-1]
+}
+
+// parseLine splits an IRC line into prefix, command and parameters,
+// dropping any message tags
+func parseLine(line string) message {
+	var m message
+	if strings.HasPrefix(line, "@") {
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			line = strings.TrimLeft(line[i+1:], " ")
+		} else {
+			return m
+		}
+	}
+	if strings.HasPrefix(line, ":") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return m
+		}
+		m.prefix, line = line[1:i], strings.TrimLeft(line[i+1:], " ")
+	}
+	for line != "" {
+		if strings.HasPrefix(line, ":") {
+			m.params = append(m.params, line[1:])
+			break
+		}
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			m.params = append(m.params, line)
+			break
+		}
+		m.params = append(m.params, line[:i])
+		line = strings.TrimLeft(line[i+1:], " ")
+	}
+	if len(m.params) > 0 {
+		m.command, m.params = strings.ToUpper(m.params[0]), m.params[1:]
+	}
+	return m
+}
+
+// lineConn sends and receives IRC lines over a transport
+type lineConn interface {
+	ReadLine(deadline time.Time) (string, error)
+	WriteLine(line string) error
+	Close() error
+}
+
+// streamConn carries lines over TCP or TLS
+type streamConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *streamConn) ReadLine(deadline time.Time) (string, error) {
+	c.conn.SetReadDeadline(deadline)
+	line, err := c.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("server sent a line longer than %d bytes", c.r.Size())
+	}
+	if err != nil && len(line) == 0 {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+func (c *streamConn) WriteLine(line string) error {
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write([]byte(line + "\r\n"))
+	return err
+}
+
+func (c *streamConn) Close() error {
+	return c.conn.Close()
+}
+
+// wsConn carries lines over a websocket, one line per message
+type wsConn struct {
+	conn    *websocket.Conn
+	binary  bool
+	pending []string
+}
+
+func (c *wsConn) ReadLine(deadline time.Time) (string, error) {
+	for len(c.pending) == 0 {
+		c.conn.SetReadDeadline(deadline)
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimRight(line, "\r"); line != "" {
+				c.pending = append(c.pending, line)
+			}
+		}
+	}
+	line := c.pending[0]
+	c.pending = c.pending[1:]
+	return line, nil
+}
+
+func (c *wsConn) WriteLine(line string) error {
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	kind := websocket.TextMessage
+	if c.binary {
+		kind = websocket.BinaryMessage
+	}
+	return c.conn.WriteMessage(kind, []byte(line))
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
+
+// tester runs one connection test
+type tester struct {
+	req        TestRequest
+	clientCert *tls.Certificate
+	res        *Result
+	start      time.Time
+	deadline   time.Time
+	conn       lineConn
+	registered bool
+	nickTries  int
+	pushback   []message
+	warnings   []string
+}
+
+// sinceStart returns the milliseconds since the test started
+func (t *tester) sinceStart() float64 {
+	return millis(time.Since(t.start))
+}
+
+// millis converts a duration to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// step runs fn as a named step, recording its timing and outcome
+func (t *tester) step(name string, fn func(s *Step) error) error {
+	s := Step{Name: name, Started: t.sinceStart()}
+	begin := time.Now()
+	t.warnings = nil
+	err := fn(&s)
+	s.Duration = millis(time.Since(begin))
+	s.OK = err == nil
+	if err != nil {
+		if s.Detail != "" {
+			s.Detail += ": "
+		}
+		s.Detail += err.Error()
+		if t.res.Error == "" {
+			t.res.Error = name + ": " + err.Error()
+		}
+	}
+	if len(t.warnings) > 0 {
+		s.Warning = strings.Join(t.warnings, "; ")
+	}
+	t.res.Steps = append(t.res.Steps, s)
+	return err
+}
+
+// warn adds a warning to the running step
+func (t *tester) warn(format string, args ...interface{}) {
+	t.warnings = append(t.warnings, fmt.Sprintf(format, args...))
+}
+
+// record adds a line to the transcript, hiding credentials
+func (t *tester) record(dir, text string) {
+	if len(t.res.Transcript) >= maxTranscript {
+		return
+	}
+	if dir == ">" {
+		upper := strings.ToUpper(text)
+		switch {
+		case strings.HasPrefix(upper, "PASS "):
+			text = "PASS ********"
+		case strings.HasPrefix(upper, "AUTHENTICATE ") && !strings.HasPrefix(upper, "AUTHENTICATE PLAIN") &&
+			!strings.HasPrefix(upper, "AUTHENTICATE EXTERNAL") && upper != "AUTHENTICATE +" && upper != "AUTHENTICATE *":
+			text = "AUTHENTICATE ********"
+		}
+	}
+	t.res.Transcript = append(t.res.Transcript, Line{At: t.sinceStart(), Dir: dir, Text: text})
+}
+
+// send writes a line to the server
+func (t *tester) send(format string, args ...interface{}) error {
+	line := fmt.Sprintf(format, args...)
+	t.record(">", line)
+	return t.conn.WriteLine(line)
+}
+
+// read returns the next message from the server. PINGs are answered, a
+// nick in use during registration is retried with a suffix, and ERROR
+// ends the test.
+func (t *tester) read() (message, error) {
+	if len(t.pushback) > 0 {
+		m := t.pushback[0]
+		t.pushback = t.pushback[1:]
+		return m, nil
+	}
+	for {
+		line, err := t.conn.ReadLine(t.deadline)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return message{}, errTimeout
+			}
+			return message{}, err
+		}
+		t.record("<", line)
+		m := parseLine(line)
+		switch m.command {
+		case "PING":
+			if err := t.send("PONG :%s", m.last()); err != nil {
+				return message{}, err
+			}
+			continue
+		case "ERROR":
+			return message{}, &closedError{reason: m.last()}
+		case "433":
+			if !t.registered && t.nickTries < 3 {
+				t.nickTries++
+				t.res.Nick += "_"
+				t.warn("nick %s is in use, trying %s", m.param(1), t.res.Nick)
+				if err := t.send("NICK %s", t.res.Nick); err != nil {
+					return message{}, err
+				}
+				continue
+			}
+		}
+		return m, nil
+	}
+}
+
+// unread puts a message back to be read by the next step
+func (t *tester) unread(m message) {
+	t.pushback = append(t.pushback, m)
+}
+
+// run performs the test, stopping at the first step the connection can't
+// get past
+func (t *tester) run(ctx context.Context) {
+	t.start = time.Now()
+	t.deadline, _ = ctx.Deadline()
+	defer func() {
+		t.res.Duration = t.sinceStart()
+		t.res.OK = t.res.Error == ""
+		if t.conn != nil {
+			t.conn.Close()
+		}
+	}()
+
+	var addrs []net.IP
+	if err := t.step("dns", func(s *Step) error {
+		var err error
+		addrs, err = resolve(ctx, t.req.Host)
+		if err == nil {
+			list := make([]string, len(addrs))
+			for i, a := range addrs {
+				list[i] = a.String()
+			}
+			s.Detail = strings.Join(list, ", ")
+		}
+		return err
+	}); err != nil {
+		return
+	}
+
+	var conn net.Conn
+	if err := t.step("connect", func(s *Step) error {
+		var err error
+		conn, err = t.dial(ctx, addrs)
+		if err == nil {
+			s.Detail = "connected to " + conn.RemoteAddr().String()
+		}
+		return err
+	}); err != nil {
+		return
+	}
+	t.conn = &streamConn{conn: conn, r: bufio.NewReaderSize(conn, 16<<10)}
+
+	if t.req.Transport == TransportTLS || t.req.Transport == TransportWSS {
+		if err := t.step("tls", func(s *Step) error {
+			tlsConn, err := t.handshake(ctx, conn, s)
+			if err == nil {
+				conn = tlsConn
+				t.conn = &streamConn{conn: conn, r: bufio.NewReaderSize(conn, 16<<10)}
+			}
+			return err
+		}); err != nil {
+			return
+		}
+	}
+
+	if t.req.Transport == TransportWS || t.req.Transport == TransportWSS {
+		if err := t.step("websocket", func(s *Step) error {
+			ws, err := t.upgrade(ctx, conn, s)
+			if err == nil {
+				t.conn = ws
+			}
+			return err
+		}); err != nil {
+			return
+		}
+	}
+
+	offered := make(map[string]string)
+	if err := t.step("cap", func(s *Step) error {
+		return t.negotiate(offered, s)
+	}); err != nil {
+		return
+	}
+
+	if t.req.SASL != "" {
+		// A failed login doesn't stop registration, as with a real client
+		_ = t.step("sasl", func(s *Step) error {
+			return t.authenticate(offered, s)
+		})
+	}
+	if len(offered) > 0 {
+		if err := t.send("CAP END"); err != nil {
+			return
+		}
+	}
+
+	if err := t.step("register", func(s *Step) error {
+		return t.register(s)
+	}); err != nil {
+		return
+	}
+
+	if err := t.step("motd", func(s *Step) error {
+		return t.motd(s)
+	}); err != nil {
+		return
+	}
+
+	_ = t.step("quit", func(s *Step) error {
+		return t.quit(s)
+	})
+}
+
+// resolve looks up the addresses of a host, or returns it if it is one
+func resolve(ctx context.Context, host string) ([]net.IP, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	found, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]net.IP, len(found))
+	for i, a := range found {
+		addrs[i] = a.IP
+	}
+	return addrs, nil
+}
+
+// dial connects to the first address that answers, noting the ones that
+// didn't
+func (t *tester) dial(ctx context.Context, addrs []net.IP) (net.Conn, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	var lastErr error
+	for _, ip := range addrs {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(t.req.Port)))
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out connecting")
+		}
+		if len(addrs) > 1 {
+			t.warn("%s: %v", ip, err)
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// handshake performs the TLS handshake. Certificate problems are reported
+// as warnings rather than failing the test, since the rest of the test is
+// still useful, but a client checking certificates would refuse them.
+func (t *tester) handshake(ctx context.Context, conn net.Conn, s *Step) (net.Conn, error) {
+	cfg := &tls.Config{ServerName: t.req.Host, InsecureSkipVerify: true}
+	if t.clientCert != nil {
+		cfg.Certificates = []tls.Certificate{*t.clientCert}
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+
+	state := tlsConn.ConnectionState()
+	cert := state.PeerCertificates[0]
+	s.Detail = fmt.Sprintf("%s, %s, certificate for %s issued by %s, expires %s",
+		tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite),
+		certName(cert.Subject.CommonName, cert.DNSNames), certName(cert.Issuer.CommonName, nil), cert.NotAfter.UTC().Format("2006-01-02"))
+
+	opts := x509.VerifyOptions{DNSName: t.req.Host, Intermediates: x509.NewCertPool()}
+	for _, c := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	if _, err := cert.Verify(opts); err != nil {
+		t.warn("certificate is not trusted: %v", err)
+	} else if left := time.Until(cert.NotAfter); left < 14*24*time.Hour {
+		t.warn("certificate expires in %d days", int(left.Hours()/24))
+	}
+	return tlsConn, nil
+}
+
+// certName names a certificate by its common name, or its first DNS name
+func certName(cn string, dnsNames []string) string {
+	switch {
+	case cn != "":
+		return cn
+	case len(dnsNames) > 0:
+		return dnsNames[0]
+	}
+	return "(no name)"
+}
+
+// upgrade performs the websocket handshake over an open connection,
+// asking for the IRCv3 websocket subprotocols
+func (t *tester) upgrade(ctx context.Context, conn net.Conn, s *Step) (lineConn, error) {
+	scheme := "ws"
+	if t.req.Transport == TransportWSS {
+		scheme = "wss"
+	}
+	path := t.req.Path
+	if path == "" {
+		path = "/"
+	}
+	u := url.URL{Scheme: scheme, Host: net.JoinHostPort(t.req.Host, strconv.Itoa(t.req.Port)), Path: path}
+
+	// The connection is already open (and TLS set up), so hand it over
+	existing := func(context.Context, string, string) (net.Conn, error) { return conn, nil }
+	dialer := websocket.Dialer{
+		NetDialContext:    existing,
+		NetDialTLSContext: existing,
+		Subprotocols:      []string{"text.ircv3.net", "binary.ircv3.net"},
+	}
+	ws, resp, err := dialer.DialContext(ctx, u.String(), http.Header{})
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%v (HTTP %s)", err, resp.Status)
+		}
+		return nil, err
+	}
+	proto := ws.Subprotocol()
+	s.Detail = u.String()
+	if proto != "" {
+		s.Detail += ", subprotocol " + proto
+	} else {
+		t.warn("server chose no subprotocol; assuming text frames")
+	}
+	return &wsConn{conn: ws, binary: proto == "binary.ircv3.net"}, nil
+}
+
+// negotiate starts registration with CAP LS 302 and requests the wanted
+// capabilities the server offers
+func (t *tester) negotiate(offered map[string]string, s *Step) error {
+	if err := t.send("CAP LS 302"); err != nil {
+		return err
+	}
+	if t.req.Password != "" {
+		if err := t.send("PASS %s", t.req.Password); err != nil {
+			return err
+		}
+	}
+	if err := t.send("NICK %s", t.res.Nick); err != nil {
+		return err
+	}
+	if err := t.send("USER %s 0 * :%s", t.req.Username, t.req.Realname); err != nil {
+		return err
+	}
+
+	for {
+		m, err := t.read()
+		if err != nil {
+			return err
+		}
+		if m.command == "CAP" && strings.EqualFold(m.param(1), "LS") {
+			for _, c := range strings.Fields(m.last()) {
+				name, value, _ := strings.Cut(c, "=")
+				offered[name] = value
+				t.res.Caps = append(t.res.Caps, c)
+			}
+			if m.param(2) == "*" && len(m.params) > 3 {
+				continue
+			}
+			break
+		}
+		if isNumeric(m.command) {
+			// No CAP support: the server went straight on with registration
+			t.unread(m)
+			s.Detail = "server does not support CAP"
+			return nil
+		}
+	}
+
+	want := append([]string(nil), t.req.Caps...)
+	if t.req.SASL != "" {
+		want = append(want, "sasl")
+	}
+	req := make([]string, 0, len(want))
+	missing := make([]string, 0)
+	for _, c := range want {
+		if _, ok := offered[c]; ok {
+			req = append(req, c)
+		} else {
+			missing = append(missing, c)
+		}
+	}
+	s.Detail = fmt.Sprintf("%d capabilities offered", len(offered))
+	if len(missing) > 0 {
+		t.warn("not offered: %s", strings.Join(missing, ", "))
+	}
+	if len(req) == 0 {
+		return nil
+	}
+
+	if err := t.send("CAP REQ :%s", strings.Join(req, " ")); err != nil {
+		return err
+	}
+	for {
+		m, err := t.read()
+		if err != nil {
+			return err
+		}
+		if m.command != "CAP" {
+			continue
+		}
+		switch strings.ToUpper(m.param(1)) {
+		case "ACK":
+			s.Detail += ", acknowledged: " + strings.TrimSpace(m.last())
+			return nil
+		case "NAK":
+			return fmt.Errorf("server refused: %s", strings.TrimSpace(m.last()))
+		}
+	}
+}
+
+// authenticate logs in with SASL PLAIN or EXTERNAL
+func (t *tester) authenticate(offered map[string]string, s *Step) error {
+	mechs, ok := offered["sasl"]
+	if !ok {
+		return fmt.Errorf("server does not offer SASL")
+	}
+	mech := strings.ToUpper(t.req.SASL)
+	if mechs != "" && !containsFold(strings.Split(mechs, ","), mech) {
+		return fmt.Errorf("server only offers %s", mechs)
+	}
+
+	if err := t.send("AUTHENTICATE %s", mech); err != nil {
+		return err
+	}
+	for {
+		m, err := t.read()
+		if err != nil {
+			return err
+		}
+		switch m.command {
+		case "AUTHENTICATE":
+			if m.param(0) != "+" {
+				continue
+			}
+			if mech == "EXTERNAL" {
+				if err := t.send("AUTHENTICATE +"); err != nil {
+					return err
+				}
+				continue
+			}
+			payload := base64.StdEncoding.EncodeToString([]byte(t.req.SASLUser + "\x00" + t.req.SASLUser + "\x00" + t.req.SASLPass))
+			for len(payload) >= 400 {
+				if err := t.send("AUTHENTICATE %s", payload[:400]); err != nil {
+					return err
+				}
+				payload = payload[400:]
+			}
+			if payload == "" {
+				payload = "+"
+			}
+			if err := t.send("AUTHENTICATE %s", payload); err != nil {
+				return err
+			}
+		case "900":
+			s.Detail = "logged in as " + m.param(2)
+		case "903":
+			if s.Detail == "" {
+				s.Detail = "authenticated"
+			}
+			return nil
+		case "902", "904", "905", "906", "907":
+			return fmt.Errorf("%s %s", m.command, m.last())
+		case "908":
+			s.Detail = "server offers " + m.param(1)
+		}
+	}
+}
+
+// register waits for the welcome, noting the server, version and network
+func (t *tester) register(s *Step) error {
+	for {
+		m, err := t.read()
+		if err != nil {
+			return err
+		}
+		switch m.command {
+		case "001":
+			t.registered = true
+			t.res.Nick = m.param(0)
+			t.res.Server = m.prefix
+			s.Detail = fmt.Sprintf("registered as %s on %s", t.res.Nick, t.res.Server)
+			return nil
+		case "432", "433", "436", "451", "464", "465", "466":
+			return fmt.Errorf("%s %s", m.command, m.last())
+		}
+	}
+}
+
+// motd waits for the end of the MOTD, collecting what ISUPPORT tells us
+func (t *tester) motd(s *Step) error {
+	lines := 0
+	for {
+		m, err := t.read()
+		if err != nil {
+			return err
+		}
+		switch m.command {
+		case "004":
+			t.res.Version = m.param(2)
+		case "005":
+			if len(m.params) < 3 {
+				continue
+			}
+			for _, token := range m.params[1 : len(m.params)-1] {
+				if v, ok := strings.CutPrefix(token, "NETWORK="); ok {
+					t.res.Network = v
+				}
+			}
+		case "372":
+			lines++
+		case "376":
+			s.Detail = fmt.Sprintf("%d MOTD lines", lines)
+			return nil
+		case "422":
+			s.Detail = "no MOTD"
+			return nil
+		}
+	}
+}
+
+// quit disconnects and waits for the server to close the link
+func (t *tester) quit(s *Step) error {
+	if err := t.send("QUIT :Connection test finished"); err != nil {
+		return err
+	}
+	if wait := time.Now().Add(quitWait); wait.Before(t.deadline) {
+		t.deadline = wait
+	}
+	for {
+		_, err := t.read()
+		if err == nil {
+			continue
+		}
+		var closed *closedError
+		switch {
+		case errors.As(err, &closed):
+			s.Detail = closed.reason
+		case errors.Is(err, errTimeout):
+			t.warn("server did not close the link")
+		default:
+			s.Detail = "connection closed"
+		}
+		return nil
+	}
+}
+
+// isNumeric reports whether an IRC command is a three digit numeric
+func isNumeric(command string) bool {
+	if len(command) != 3 {
+		return false
+	}
+	_, err := strconv.Atoi(command)
+	return err == nil
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,467 @@
+// Connection Test Plugin for UnrealIRCd Web Panel
+// Connects to a server as a real IRC client over plaintext, TLS or
+// websocket and reports the timing and outcome of every step
+
+package connectiontest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// maxTimeout caps how long a single test may run, in seconds
+const maxTimeout = 60
+
+// ConnectionTestPlugin implements the Plugin interface
+type ConnectionTestPlugin struct {
+	config  Config
+	results []*Result
+	running int
+	rpc     *jsonrpc.Client
+	mu      sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	AllowedHosts   string `json:"allowed_hosts"`
+	DefaultCaps    string `json:"default_caps"`
+	NickPrefix     string `json:"nick_prefix"`
+	Timeout        int    `json:"timeout"`
+	MaxConcurrent  int    `json:"max_concurrent"`
+	KeepResults    int    `json:"keep_results"`
+	ClientCertFile string `json:"client_cert_file"`
+	ClientKeyFile  string `json:"client_key_file"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ConnectionTestPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/connection-test",
+			AllowedHosts:  "*",
+			DefaultCaps:   "message-tags,server-time,account-tag,multi-prefix",
+			NickPrefix:    "uwptest",
+			Timeout:       20,
+			MaxConcurrent: 3,
+			KeepResults:   100,
+		},
+		results: make([]*Result, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ConnectionTestPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Connection Test",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Test client connections step by step over plaintext, TLS and websocket",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ConnectionTestPlugin) Init() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := storage.LoadJSON(p.storePath(), &p.results); err != nil {
+		log.Printf("[connection-test] failed to load results: %v", err)
+	}
+	if p.results == nil {
+		p.results = make([]*Result, 0)
+	}
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ConnectionTestPlugin) Shutdown() error {
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ConnectionTestPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/connection-test")
+	{
+		plugin.GET("/tests", p.handleList)
+		plugin.POST("/tests", p.handleRun)
+		plugin.GET("/tests/:id", p.handleGet)
+		plugin.DELETE("/tests/:id", p.handleDelete)
+		plugin.GET("/servers", p.handleServers)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the saved results
+func (p *ConnectionTestPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "results.json")
+}
+
+// save persists the results. Caller must hold p.mu.
+func (p *ConnectionTestPlugin) save() {
+	if err := storage.SaveJSON(p.storePath(), p.results); err != nil {
+		log.Printf("[connection-test] failed to save results: %v", err)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ConnectionTestPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// defaultPort returns the usual port for a transport
+func defaultPort(transport string) int {
+	switch transport {
+	case TransportTLS:
+		return 6697
+	case TransportWSS:
+		return 443
+	case TransportWS:
+		return 80
+	}
+	return 6667
+}
+
+// prepare checks a test request and fills in defaults
+func prepare(req *TestRequest, cfg Config) error {
+	req.Host = strings.TrimSpace(req.Host)
+	if req.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	allowed := false
+	for _, mask := range lists.Split(cfg.AllowedHosts) {
+		if ircname.MatchMask(mask, req.Host) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("host %s is not in allowed_hosts", req.Host)
+	}
+
+	if req.Transport == "" {
+		req.Transport = TransportTLS
+	}
+	switch req.Transport {
+	case TransportPlain, TransportTLS, TransportWS, TransportWSS:
+	default:
+		return fmt.Errorf("transport must be plain, tls, ws or wss")
+	}
+	if req.Port == 0 {
+		req.Port = defaultPort(req.Transport)
+	}
+	if req.Port < 1 || req.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if req.Path != "" && !strings.HasPrefix(req.Path, "/") {
+		return fmt.Errorf("path must start with /")
+	}
+
+	if req.Nick == "" {
+		n, _ := rand.Int(rand.Reader, big.NewInt(10000))
+		req.Nick = fmt.Sprintf("%s%04d", cfg.NickPrefix, n.Int64())
+	}
+	if req.Username == "" {
+		req.Username = "uwptest"
+	}
+	if req.Realname == "" {
+		req.Realname = "UnrealIRCd Web Panel connection test"
+	}
+	for _, field := range []string{req.Nick, req.Username, req.Realname, req.Password, req.SASLUser} {
+		if strings.ContainsAny(field, "\r\n\x00") {
+			return fmt.Errorf("fields cannot contain line breaks")
+		}
+	}
+	if strings.ContainsAny(req.Nick+req.Username, " :") {
+		return fmt.Errorf("nick and username cannot contain spaces or colons")
+	}
+
+	req.SASL = strings.ToLower(req.SASL)
+	switch req.SASL {
+	case "":
+	case "plain":
+		if req.SASLUser == "" || req.SASLPass == "" {
+			return fmt.Errorf("sasl_user and sasl_pass are required for SASL PLAIN")
+		}
+	case "external":
+		if cfg.ClientCertFile == "" || cfg.ClientKeyFile == "" {
+			return fmt.Errorf("SASL EXTERNAL needs client_cert_file and client_key_file in the plugin settings")
+		}
+		if req.Transport != TransportTLS && req.Transport != TransportWSS {
+			return fmt.Errorf("SASL EXTERNAL needs a TLS transport")
+		}
+	default:
+		return fmt.Errorf("sasl must be plain or external")
+	}
+
+	if req.Caps == nil {
+		req.Caps = lists.Split(cfg.DefaultCaps)
+	}
+	if req.Timeout <= 0 {
+		req.Timeout = cfg.Timeout
+	}
+	if req.Timeout > maxTimeout {
+		req.Timeout = maxTimeout
+	}
+	return nil
+}
+
+// runTest runs a connection test and returns its result
+func runTest(ctx context.Context, req TestRequest, cert *tls.Certificate) *Result {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Second)
+	defer cancel()
+
+	t := &tester{
+		req:        req,
+		clientCert: cert,
+		res: &Result{
+			Target:     fmt.Sprintf("%s:%d", req.Host, req.Port),
+			Transport:  req.Transport,
+			SASL:       req.SASL,
+			StartedAt:  time.Now().UTC(),
+			Nick:       req.Nick,
+			Caps:       make([]string, 0),
+			Steps:      make([]Step, 0),
+			Transcript: make([]Line, 0),
+		},
+	}
+	t.run(ctx)
+	return t.res
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleList returns past results without their transcripts, newest first
+func (p *ConnectionTestPlugin) handleList(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Result, 0, len(p.results))
+	for _, r := range p.results {
+		summary := *r
+		summary.Transcript = nil
+		list = append(list, summary)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.After(list[j].StartedAt) })
+	c.JSON(http.StatusOK, gin.H{"tests": list, "count": len(list)})
+}
+
+// handleRun runs a test and returns its result
+func (p *ConnectionTestPlugin) handleRun(c *gin.Context) {
+	var req TestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	cfg := p.config
+	if err := prepare(&req, cfg); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if p.running >= cfg.MaxConcurrent {
+		p.mu.Unlock()
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many tests are running, try again shortly"})
+		return
+	}
+	p.running++
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.running--
+		p.mu.Unlock()
+	}()
+
+	var cert *tls.Certificate
+	if req.SASL == "external" {
+		pair, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load client certificate: " + err.Error()})
+			return
+		}
+		cert = &pair
+	}
+
+	result := runTest(c.Request.Context(), req, cert)
+	result.ID = newID()
+	result.RunBy = access.Actor(c)
+
+	p.mu.Lock()
+	p.results = append(p.results, result)
+	if keep := p.config.KeepResults; keep > 0 && len(p.results) > keep {
+		p.results = append([]*Result(nil), p.results[len(p.results)-keep:]...)
+	}
+	p.save()
+	p.mu.Unlock()
+
+	outcome := "ok"
+	if !result.OK {
+		outcome = result.Error
+	}
+	log.Printf("[connection-test] %s tested %s (%s): %s", result.RunBy, result.Target, result.Transport, outcome)
+	c.JSON(http.StatusOK, result)
+}
+
+// handleGet returns a result with its transcript
+func (p *ConnectionTestPlugin) handleGet(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, r := range p.results {
+		if r.ID == c.Param("id") {
+			c.JSON(http.StatusOK, r)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Test not found"})
+}
+
+// handleDelete removes a result
+func (p *ConnectionTestPlugin) handleDelete(c *gin.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, r := range p.results {
+		if r.ID == c.Param("id") {
+			p.results = append(p.results[:i], p.results[i+1:]...)
+			p.save()
+			c.JSON(http.StatusOK, gin.H{"message": "Test deleted"})
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Test not found"})
+}
+
+// handleServers returns the names of the linked servers, to suggest as
+// targets
+func (p *ConnectionTestPlugin) handleServers(c *gin.Context) {
+	var out struct {
+		List []struct {
+			Name string `json:"name"`
+		} `json:"list"`
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+	if err := p.client().Call(ctx, "server.list", nil, &out); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	names := make([]string, 0, len(out.List))
+	for _, s := range out.List {
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	c.JSON(http.StatusOK, gin.H{"servers": names})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ConnectionTestPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ConnectionTestPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.Timeout < 1 || newConfig.Timeout > maxTimeout {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("timeout must be between 1 and %d seconds", maxTimeout)})
+		return
+	}
+	if newConfig.MaxConcurrent < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_concurrent must be at least 1"})
+		return
+	}
+	if newConfig.NickPrefix == "" || strings.ContainsAny(newConfig.NickPrefix, " :,\r\n") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nick_prefix must be a valid nick"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ConnectionTestPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ConnectionTestPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
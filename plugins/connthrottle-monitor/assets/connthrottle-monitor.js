@@ -0,0 +1,358 @@
+/**
+ * Connect Throttle Monitor Frontend Script
+ *
+ * Charts connections rejected by connect throttling per minute, lists
+ * activations and lets operators raise the throttle for a limited time.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'connthrottle-monitor';
+  const PLUGIN_NAME = 'Connect Throttle';
+  const PAGE_PATH = '/plugins/connthrottle-monitor';
+  const API_BASE = '/api/plugin/connthrottle-monitor';
+
+  let hours = 24;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  // Rejected (red) over accepted (green) per minute, with activations marked
+  function minuteChart(minutes, activations, width, height) {
+    if (minutes.length === 0) {
+      return '<p>No connthrottle reports in this period.</p>';
+    }
+    const accepted = m => m.known + m.sasl + m.webirc + m.new;
+    const max = Math.max(...minutes.map(m => m.rejected + accepted(m)), 1);
+    const start = new Date(minutes[0].time).getTime();
+    const span = Math.max(new Date(minutes[minutes.length - 1].time).getTime() - start, 60000);
+    const x = t => ((new Date(t).getTime() - start) / span) * (width - 4);
+    const bars = minutes.map(m => {
+      const hr = (m.rejected / max) * (height - 10);
+      const ha = (accepted(m) / max) * (height - 10);
+      const title = `${formatTime(m.time)}: ${m.rejected} rejected, ${accepted(m)} accepted`;
+      return `
+        <rect x="${x(m.time).toFixed(1)}" y="${(height - ha).toFixed(1)}" width="3" height="${ha.toFixed(1)}" fill="var(--success, #a6e3a1)"><title>${escapeHtml(title)}</title></rect>
+        <rect x="${x(m.time).toFixed(1)}" y="${(height - ha - hr).toFixed(1)}" width="3" height="${hr.toFixed(1)}" fill="var(--error, #f38ba8)"><title>${escapeHtml(title)}</title></rect>
+      `;
+    });
+    const marks = activations.map(a => `<line x1="${x(a.time).toFixed(1)}" x2="${x(a.time).toFixed(1)}" y1="0" y2="${height}" stroke="var(--warning, #f9e2af)" stroke-dasharray="4 3"><title>${escapeHtml(`${formatTime(a.time)} ${a.server}: ${a.message}`)}</title></line>`);
+    return `<svg viewBox="0 0 ${width} ${height}" class="ctm-chart" preserveAspectRatio="none">${bars.join('')}${marks.join('')}</svg>`;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('connthrottle-monitor-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'connthrottle-monitor-styles';
+    style.textContent = `
+      .ctm-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .ctm-toolbar { display: flex; gap: 0.5rem; }
+      .ctm-tiles { display: grid; grid-template-columns: repeat(auto-fill, minmax(200px, 1fr)); gap: 0.75rem; }
+      .ctm-tile {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        padding: 0.75rem;
+      }
+      .ctm-tile h4 { margin: 0 0 0.25rem; color: var(--text-primary, #cdd6f4); }
+      .ctm-tile strong { font-size: 1.4rem; color: var(--error, #f38ba8); }
+      .ctm-meta { font-size: 0.75rem; color: var(--text-muted, #6c7086); }
+      .ctm-chart { width: 100%; height: 180px; background: var(--bg-secondary, #181825); border-radius: 8px; }
+      .ctm-emergency {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        padding: 0.75rem;
+      }
+      .ctm-emergency.active { border-color: var(--warning, #f9e2af); }
+      .ctm-form { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: flex-end; }
+      .ctm-form label { display: flex; flex-direction: column; font-size: 0.8rem; gap: 0.2rem; }
+      .ctm-form input {
+        background: var(--bg-primary, #11111b);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.3rem 0.5rem;
+      }
+      .ctm-table { width: 100%; border-collapse: collapse; }
+      .ctm-table th, .ctm-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        overflow-wrap: anywhere;
+      }
+      .ctm-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .ctm-app button.active { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); }
+      .ctm-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function resultsText(results) {
+    return (results || []).map(r => `${r.server || 'local server'}${r.error ? ` (${r.error})` : ''}`).join(', ');
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#ctm-status');
+    const emergency = container.querySelector('#ctm-emergency');
+    try {
+      const s = await api('GET', '/status');
+      const servers = Object.keys(s.latest).sort();
+      body.innerHTML = `
+        ${s.log_stream ? '' : '<div class="ctm-error">The log stream is not connected.</div>'}
+        <div class="ctm-tiles">
+          ${servers.length === 0 ? '<p>No connthrottle reports received yet.</p>' : servers.map(name => {
+            const m = s.latest[name];
+            return `
+              <div class="ctm-tile">
+                <h4>${escapeHtml(name || 'unknown server')}</h4>
+                <strong>${m.rejected}</strong> rejected
+                <div class="ctm-meta">${m.known} known, ${m.sasl} SASL, ${m.webirc} WEBIRC, ${m.new} new accepted at ${formatTime(m.time)}</div>
+              </div>
+            `;
+          }).join('')}
+        </div>
+      `;
+
+      const e = s.emergency;
+      emergency.classList.toggle('active', !!e);
+      if (e) {
+        emergency.innerHTML = `
+          <h4>Emergency override active</h4>
+          <p>Local ${escapeHtml(e.local_throttle)}, global ${escapeHtml(e.global_throttle)} until ${formatTime(e.expires_at)}.</p>
+          <p class="ctm-meta">Started by ${escapeHtml(e.actor)} at ${formatTime(e.started_at)}: ${escapeHtml(e.reason)}. Rehashed ${escapeHtml(resultsText(e.applied))}.</p>
+          <button id="ctm-end">End now</button>
+        `;
+        emergency.querySelector('#ctm-end').addEventListener('click', async () => {
+          if (!confirm('Restore the normal connect throttle now?')) return;
+          try {
+            await api('POST', '/emergency/end');
+            loadAll(container);
+          } catch (err) {
+            alert(err.message);
+          }
+        });
+        return;
+      }
+
+      const d = s.emergency_defaults;
+      emergency.innerHTML = s.override_file ? `
+        <h4>Raise the throttle</h4>
+        <form class="ctm-form">
+          <label>Local throttle <input name="local_throttle" value="${escapeHtml(d.local_throttle)}" size="8"></label>
+          <label>Global throttle <input name="global_throttle" value="${escapeHtml(d.global_throttle)}" size="8"></label>
+          <label>Minutes <input name="minutes" type="number" min="1" max="1440" value="${d.minutes}"></label>
+          <label>Reason <input name="reason" size="30" required></label>
+          <button type="submit">Raise</button>
+        </form>
+      ` : '<p>Set an override file in the plugin settings to enable emergency raises.</p>';
+      const form = emergency.querySelector('form');
+      if (form) {
+        form.addEventListener('submit', async (ev) => {
+          ev.preventDefault();
+          try {
+            await api('POST', '/emergency', {
+              local_throttle: form.elements.local_throttle.value.trim(),
+              global_throttle: form.elements.global_throttle.value.trim(),
+              minutes: parseInt(form.elements.minutes.value, 10) || 0,
+              reason: form.elements.reason.value.trim(),
+            });
+            loadAll(container);
+          } catch (err) {
+            alert(err.message);
+          }
+        });
+      }
+    } catch (e) {
+      body.innerHTML = `<div class="ctm-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadHistory(container) {
+    const body = container.querySelector('#ctm-history');
+    try {
+      const data = await api('GET', `/history?hours=${hours}`);
+      const rejected = data.minutes.reduce((sum, m) => sum + m.rejected, 0);
+      body.innerHTML = `
+        <p>${rejected} connections rejected, ${data.activations.length} activations.</p>
+        ${minuteChart(data.minutes, data.activations, 800, 180)}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="ctm-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadActivations(container) {
+    const body = container.querySelector('#ctm-activations');
+    try {
+      const data = await api('GET', '/activations');
+      if (data.activations.length === 0) {
+        body.innerHTML = '<p>Connect throttling has not been activated.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="ctm-table">
+          <thead><tr><th>Time</th><th>Server</th><th>Message</th></tr></thead>
+          <tbody>
+            ${data.activations.slice(0, 50).map(a => `
+              <tr><td>${formatTime(a.time)}</td><td>${escapeHtml(a.server)}</td><td>${escapeHtml(a.message)}</td></tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="ctm-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadEmergencyLog(container) {
+    const body = container.querySelector('#ctm-log');
+    try {
+      const data = await api('GET', '/emergency');
+      if (data.emergencies.length === 0) {
+        body.innerHTML = '<p>No emergency overrides yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="ctm-table">
+          <thead><tr><th>Started</th><th>By</th><th>Throttle</th><th>Reason</th><th>Ended</th></tr></thead>
+          <tbody>
+            ${data.emergencies.map(e => `
+              <tr>
+                <td>${formatTime(e.started_at)}</td>
+                <td>${escapeHtml(e.actor)}</td>
+                <td>local ${escapeHtml(e.local_throttle)}, global ${escapeHtml(e.global_throttle)}</td>
+                <td>${escapeHtml(e.reason)}</td>
+                <td>${e.ended_at ? `${formatTime(e.ended_at)} (${escapeHtml(e.ended_by)})` : 'active'}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="ctm-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function loadAll(container) {
+    loadStatus(container);
+    loadHistory(container);
+    loadActivations(container);
+    loadEmergencyLog(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="ctm-app" data-plugin="${PLUGIN_ID}">
+        <div id="ctm-status">Loading...</div>
+        <div id="ctm-emergency" class="ctm-emergency"></div>
+        <div class="ctm-toolbar">
+          <button data-hours="1">1h</button>
+          <button data-hours="24" class="active">24h</button>
+          <button data-hours="168">7d</button>
+        </div>
+        <div id="ctm-history">Loading...</div>
+        <h3>Activations</h3>
+        <div id="ctm-activations">Loading...</div>
+        <h3>Emergency overrides</h3>
+        <div id="ctm-log">Loading...</div>
+      </div>
+    `;
+
+    container.querySelectorAll('[data-hours]').forEach(btn => {
+      btn.addEventListener('click', () => {
+        hours = parseInt(btn.dataset.hours, 10);
+        container.querySelectorAll('[data-hours]').forEach(b => b.classList.toggle('active', b === btn));
+        loadHistory(container);
+      });
+    });
+
+    loadAll(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('connthrottle-monitor-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,797 @@
+// Connect Throttle Monitor Plugin for UnrealIRCd Web Panel
+// Tracks connections rejected by connect throttling per minute and can
+// temporarily raise the throttle through a config push
+
+package connthrottlemonitor
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Patterns for the numbers in a connthrottle stats report:
+// "Connections rejected: 12. Accepted: 3 known user(s), 1 SASL, 0 WEBIRC and 2 new user(s)."
+var (
+	rejectedRe = regexp.MustCompile(`(?i)rejected:\s*(\d+)`)
+	knownRe    = regexp.MustCompile(`(?i)(\d+)\s+known`)
+	saslRe     = regexp.MustCompile(`(?i)(\d+)\s+SASL`)
+	webircRe   = regexp.MustCompile(`(?i)(\d+)\s+WEBIRC`)
+	newRe      = regexp.MustCompile(`(?i)(\d+)\s+new`)
+)
+
+// ConnthrottleMonitorPlugin implements the Plugin interface
+type ConnthrottleMonitorPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	minutes      []*Minute
+	activations  []*Activation
+	emergencies  []*Emergency
+	lastAlert    map[string]time.Time
+	dirty        bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	pushMu       sync.Mutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL           string `json:"rpc_url"`
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	StreamURL        string `json:"stream_url"`
+	LogSources       string `json:"log_sources"`
+	ReportEvents     string `json:"report_events"`
+	ActivationEvents string `json:"activation_events"`
+	DataDir          string `json:"data_dir"`
+	HistoryDays      int    `json:"history_days"`
+	OverrideFile     string `json:"override_file"`
+	RehashServers    string `json:"rehash_servers"`
+	EmergencyLocal   string `json:"emergency_local_throttle"`
+	EmergencyGlobal  string `json:"emergency_global_throttle"`
+	EmergencyMinutes int    `json:"emergency_minutes"`
+	AlertThreshold   int    `json:"alert_threshold"`
+	AlertWebhook     string `json:"alert_webhook"`
+}
+
+// Minute is one server's connthrottle stats for one minute
+type Minute struct {
+	Time     time.Time `json:"time"`
+	Server   string    `json:"server"`
+	Rejected int       `json:"rejected"`
+	Known    int       `json:"known"`
+	SASL     int       `json:"sasl"`
+	WEBIRC   int       `json:"webirc"`
+	New      int       `json:"new"`
+}
+
+// Activation is a moment connect throttling switched on
+type Activation struct {
+	Time    time.Time `json:"time"`
+	Server  string    `json:"server"`
+	Message string    `json:"message"`
+}
+
+// RehashResult is the outcome of rehashing one server after a push
+type RehashResult struct {
+	Server string `json:"server"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Emergency is a temporary raise of the new-users throttle
+type Emergency struct {
+	ID        string         `json:"id"`
+	Actor     string         `json:"actor"`
+	Reason    string         `json:"reason"`
+	Local     string         `json:"local_throttle"`
+	Global    string         `json:"global_throttle"`
+	StartedAt time.Time      `json:"started_at"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	EndedAt   *time.Time     `json:"ended_at,omitempty"`
+	EndedBy   string         `json:"ended_by,omitempty"`
+	Applied   []RehashResult `json:"applied"`
+	Restored  []RehashResult `json:"restored,omitempty"`
+}
+
+// EmergencyRequest is the body of an emergency request. Empty fields use
+// the configured defaults.
+type EmergencyRequest struct {
+	Local   string `json:"local_throttle"`
+	Global  string `json:"global_throttle"`
+	Minutes int    `json:"minutes"`
+	Reason  string `json:"reason"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Minutes     []*Minute     `json:"minutes"`
+	Activations []*Activation `json:"activations"`
+	Emergencies []*Emergency  `json:"emergencies"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ConnthrottleMonitorPlugin{
+		config: Config{
+			RPCURL:           "https://127.0.0.1:8600/api",
+			StreamURL:        "wss://127.0.0.1:8600/",
+			LogSources:       "connthrottle",
+			ReportEvents:     "CONNTHROTTLE_REPORT",
+			ActivationEvents: "CONNTHROTTLE_ACTIVATED",
+			DataDir:          "data/plugins/connthrottle-monitor",
+			HistoryDays:      7,
+			EmergencyLocal:   "60:60",
+			EmergencyGlobal:  "90:60",
+			EmergencyMinutes: 30,
+			AlertThreshold:   50,
+		},
+		minutes:     make([]*Minute, 0),
+		activations: make([]*Activation, 0),
+		emergencies: make([]*Emergency, 0),
+		lastAlert:   make(map[string]time.Time),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ConnthrottleMonitorPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Connect Throttle Monitor",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Connect throttling rejections per minute with an emergency raise",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ConnthrottleMonitorPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[connthrottle-monitor] failed to load data: %v", err)
+	}
+	if data.Minutes != nil {
+		p.minutes = data.Minutes
+	}
+	if data.Activations != nil {
+		p.activations = data.Activations
+	}
+	if data.Emergencies != nil {
+		p.emergencies = data.Emergencies
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "connthrottle-monitor-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-time.Hour)
+		rejected := 0
+		for i := len(p.minutes) - 1; i >= 0 && p.minutes[i].Time.After(since); i-- {
+			rejected += p.minutes[i].Rejected
+		}
+		content := map[string]interface{}{
+			"rejected_1h": rejected,
+			"emergency":   false,
+			"streaming":   p.streamOK,
+		}
+		if e := p.activeEmergency(); e != nil {
+			content["emergency"] = true
+			content["emergency_expires"] = e.ExpiresAt
+		}
+		return plugins.DashboardCard{
+			Title:   "Connect Throttle",
+			Icon:    "Gauge",
+			Content: content,
+			Order:   54,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.tickLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin. An active emergency is left in place and
+// ended by the next run once it expires.
+func (p *ConnthrottleMonitorPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ConnthrottleMonitorPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/connthrottle-monitor")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/history", p.handleHistory)
+		plugin.GET("/activations", p.handleActivations)
+		plugin.GET("/emergency", p.handleListEmergencies)
+		plugin.POST("/emergency", p.handleStartEmergency)
+		plugin.POST("/emergency/end", p.handleEndEmergency)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *ConnthrottleMonitorPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "connthrottle.json")
+}
+
+// save persists the state if it changed
+func (p *ConnthrottleMonitorPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Minutes: p.minutes, Activations: p.activations, Emergencies: p.emergencies}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ConnthrottleMonitorPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// streamLoop follows connthrottle log events until shutdown
+func (p *ConnthrottleMonitorPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *ConnthrottleMonitorPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[connthrottle-monitor] log stream: %v", err)
+	}
+}
+
+// firstNumber returns the first group of re in s, or 0
+func firstNumber(re *regexp.Regexp, s string) int {
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// handleEvent records stats reports and activations
+func (p *ConnthrottleMonitorPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	p.mu.RLock()
+	report := lists.ContainsFold(lists.Split(p.config.ReportEvents), ev.EventID)
+	activation := lists.ContainsFold(lists.Split(p.config.ActivationEvents), ev.EventID)
+	p.mu.RUnlock()
+	if !report && !activation {
+		return
+	}
+
+	var entry struct {
+		LogSource string `json:"log_source"`
+	}
+	_ = json.Unmarshal(ev.Raw, &entry)
+	now := time.Now().UTC()
+	if t, err := time.Parse(time.RFC3339, ev.Timestamp); err == nil {
+		now = t.UTC()
+	}
+
+	alerts := make([]notify.Event, 0)
+
+	p.mu.Lock()
+	if activation {
+		p.activations = append(p.activations, &Activation{Time: now, Server: entry.LogSource, Message: ev.Msg})
+		alerts = append(alerts, notify.Event{
+			Type:     "connthrottle_activated",
+			Severity: "warning",
+			Title:    "Connect throttling activated",
+			Message:  fmt.Sprintf("Connect throttling was activated on %s", entry.LogSource),
+			Data:     map[string]interface{}{"server": entry.LogSource},
+		})
+	}
+	if report && rejectedRe.MatchString(ev.Msg) {
+		m := &Minute{
+			Time:     now.Truncate(time.Minute),
+			Server:   entry.LogSource,
+			Rejected: firstNumber(rejectedRe, ev.Msg),
+			Known:    firstNumber(knownRe, ev.Msg),
+			SASL:     firstNumber(saslRe, ev.Msg),
+			WEBIRC:   firstNumber(webircRe, ev.Msg),
+			New:      firstNumber(newRe, ev.Msg),
+		}
+		p.minutes = append(p.minutes, m)
+		if t := p.config.AlertThreshold; t > 0 && m.Rejected >= t && now.Sub(p.lastAlert[m.Server]) > 10*time.Minute {
+			p.lastAlert[m.Server] = now
+			alerts = append(alerts, notify.Event{
+				Type:     "connthrottle_rejections",
+				Severity: "critical",
+				Title:    "Connections rejected by throttling",
+				Message:  fmt.Sprintf("%d connections were rejected on %s in the past minute", m.Rejected, m.Server),
+				Data:     map[string]interface{}{"server": m.Server, "rejected": m.Rejected},
+			})
+		}
+	}
+	p.dirty = true
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	for _, a := range alerts {
+		log.Printf("[connthrottle-monitor] %s: %s", a.Title, a.Message)
+		if webhook != "" {
+			a.Source = "connthrottle-monitor"
+			a.Timestamp = now
+			go p.alert(webhook, a)
+		}
+	}
+}
+
+// alert posts an alert to the webhook
+func (p *ConnthrottleMonitorPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[connthrottle-monitor] failed to send alert: %v", err)
+	}
+}
+
+// tickLoop ends expired emergencies, drops old history and saves until
+// shutdown
+func (p *ConnthrottleMonitorPlugin) tickLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.RLock()
+		e := p.activeEmergency()
+		expired := e != nil && time.Now().After(e.ExpiresAt)
+		p.mu.RUnlock()
+		if expired {
+			if err := p.endEmergency("expired"); err != nil {
+				log.Printf("[connthrottle-monitor] failed to end emergency: %v", err)
+			}
+		}
+
+		p.prune()
+		if err := p.save(); err != nil {
+			log.Printf("[connthrottle-monitor] failed to save data: %v", err)
+		}
+	}
+}
+
+// prune drops stats and activations older than the history period
+func (p *ConnthrottleMonitorPlugin) prune() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	days := p.config.HistoryDays
+	if days < 1 {
+		days = 1
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	if drop := sort.Search(len(p.minutes), func(i int) bool { return !p.minutes[i].Time.Before(cutoff) }); drop > 0 {
+		p.minutes = append([]*Minute(nil), p.minutes[drop:]...)
+		p.dirty = true
+	}
+	if drop := sort.Search(len(p.activations), func(i int) bool { return !p.activations[i].Time.Before(cutoff) }); drop > 0 {
+		p.activations = append([]*Activation(nil), p.activations[drop:]...)
+		p.dirty = true
+	}
+}
+
+// activeEmergency returns the emergency in force, if any. Caller must hold
+// p.mu.
+func (p *ConnthrottleMonitorPlugin) activeEmergency() *Emergency {
+	if n := len(p.emergencies); n > 0 && p.emergencies[n-1].EndedAt == nil {
+		return p.emergencies[n-1]
+	}
+	return nil
+}
+
+// push writes content to the override file and rehashes the configured
+// servers. An empty server list rehashes the server the panel talks to.
+func (p *ConnthrottleMonitorPlugin) push(content string) ([]RehashResult, error) {
+	p.mu.RLock()
+	path := p.config.OverrideFile
+	servers := lists.Split(p.config.RehashServers)
+	p.mu.RUnlock()
+
+	if path == "" {
+		return nil, fmt.Errorf("no override_file is configured")
+	}
+	if err := writeFile(path, content); err != nil {
+		return nil, err
+	}
+
+	if len(servers) == 0 {
+		servers = []string{""}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	rpc := p.client()
+	results := make([]RehashResult, 0, len(servers))
+	for _, server := range servers {
+		params := map[string]interface{}{}
+		if server != "" {
+			params["server"] = server
+		}
+		res := RehashResult{Server: server}
+		var out json.RawMessage
+		if err := rpc.Call(ctx, "server.rehash", params, &out); err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// endEmergency restores the idle override file and closes the active
+// emergency
+func (p *ConnthrottleMonitorPlugin) endEmergency(by string) error {
+	p.pushMu.Lock()
+	defer p.pushMu.Unlock()
+
+	p.mu.RLock()
+	e := p.activeEmergency()
+	p.mu.RUnlock()
+	if e == nil {
+		return nil
+	}
+
+	results, err := p.push(idleOverride)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	p.mu.Lock()
+	e.EndedAt = &now
+	e.EndedBy = by
+	e.Restored = results
+	p.dirty = true
+	p.mu.Unlock()
+
+	log.Printf("[connthrottle-monitor] emergency override ended (%s)", by)
+	return nil
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleStatus reports the stream, the latest stats per server and any
+// active emergency
+func (p *ConnthrottleMonitorPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	latest := make(map[string]*Minute)
+	for i := len(p.minutes) - 1; i >= 0; i-- {
+		m := p.minutes[i]
+		if _, ok := latest[m.Server]; !ok {
+			latest[m.Server] = m
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"log_stream":         p.streamOK,
+		"latest":             latest,
+		"emergency":          p.activeEmergency(),
+		"override_file":      p.config.OverrideFile,
+		"emergency_defaults": gin.H{"local_throttle": p.config.EmergencyLocal, "global_throttle": p.config.EmergencyGlobal, "minutes": p.config.EmergencyMinutes},
+	})
+}
+
+// handleHistory returns per-minute stats summed over servers, or for one
+// server
+func (p *ConnthrottleMonitorPlugin) handleHistory(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be a positive number"})
+		return
+	}
+	server := c.Query("server")
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	byMinute := make(map[time.Time]*Minute)
+	list := make([]*Minute, 0)
+	start := sort.Search(len(p.minutes), func(i int) bool { return p.minutes[i].Time.After(since) })
+	for _, m := range p.minutes[start:] {
+		if server != "" && !strings.EqualFold(m.Server, server) {
+			continue
+		}
+		sum, ok := byMinute[m.Time]
+		if !ok {
+			sum = &Minute{Time: m.Time, Server: server}
+			byMinute[m.Time] = sum
+			list = append(list, sum)
+		}
+		sum.Rejected += m.Rejected
+		sum.Known += m.Known
+		sum.SASL += m.SASL
+		sum.WEBIRC += m.WEBIRC
+		sum.New += m.New
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Time.Before(list[j].Time) })
+
+	activations := make([]*Activation, 0)
+	for _, a := range p.activations {
+		if a.Time.After(since) && (server == "" || strings.EqualFold(a.Server, server)) {
+			activations = append(activations, a)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"hours": hours, "minutes": list, "activations": activations})
+}
+
+// handleActivations returns throttling activations, newest first
+func (p *ConnthrottleMonitorPlugin) handleActivations(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Activation, 0, len(p.activations))
+	for i := len(p.activations) - 1; i >= 0; i-- {
+		list = append(list, p.activations[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"activations": list})
+}
+
+// handleListEmergencies returns past and active emergencies, newest first
+func (p *ConnthrottleMonitorPlugin) handleListEmergencies(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Emergency, 0, len(p.emergencies))
+	for i := len(p.emergencies) - 1; i >= 0; i-- {
+		list = append(list, p.emergencies[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"emergencies": list})
+}
+
+// handleStartEmergency raises the new-users throttle for a limited time
+func (p *ConnthrottleMonitorPlugin) handleStartEmergency(c *gin.Context) {
+	var req EmergencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.RLock()
+	if req.Local == "" {
+		req.Local = p.config.EmergencyLocal
+	}
+	if req.Global == "" {
+		req.Global = p.config.EmergencyGlobal
+	}
+	if req.Minutes == 0 {
+		req.Minutes = p.config.EmergencyMinutes
+	}
+	p.mu.RUnlock()
+
+	if !throttleRe.MatchString(req.Local) || !throttleRe.MatchString(req.Global) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Throttles must look like connections:seconds, for example 60:60"})
+		return
+	}
+	if req.Minutes < 1 || req.Minutes > 24*60 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minutes must be between 1 and 1440"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	p.pushMu.Lock()
+	defer p.pushMu.Unlock()
+
+	p.mu.RLock()
+	active := p.activeEmergency() != nil
+	p.mu.RUnlock()
+	if active {
+		c.JSON(http.StatusConflict, gin.H{"error": "An emergency override is already active"})
+		return
+	}
+
+	results, err := p.push(renderOverride(req.Local, req.Global, reason))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	e := &Emergency{
+		ID:        newID(),
+		Actor:     access.Actor(c),
+		Reason:    reason,
+		Local:     req.Local,
+		Global:    req.Global,
+		StartedAt: now,
+		ExpiresAt: now.Add(time.Duration(req.Minutes) * time.Minute),
+		Applied:   results,
+	}
+	p.mu.Lock()
+	p.emergencies = append(p.emergencies, e)
+	p.dirty = true
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	msg := fmt.Sprintf("%s raised the connect throttle to local %s, global %s for %d minutes: %s", e.Actor, e.Local, e.Global, req.Minutes, reason)
+	log.Printf("[connthrottle-monitor] %s", msg)
+	if webhook != "" {
+		go p.alert(webhook, notify.Event{
+			Source:    "connthrottle-monitor",
+			Type:      "connthrottle_emergency",
+			Severity:  "warning",
+			Title:     "Connect throttle raised",
+			Message:   msg,
+			Timestamp: now,
+			Data:      map[string]interface{}{"local_throttle": e.Local, "global_throttle": e.Global, "expires_at": e.ExpiresAt, "actor": e.Actor},
+		})
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+// handleEndEmergency restores the normal throttle before the emergency
+// expires
+func (p *ConnthrottleMonitorPlugin) handleEndEmergency(c *gin.Context) {
+	p.mu.RLock()
+	active := p.activeEmergency() != nil
+	p.mu.RUnlock()
+	if !active {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No emergency override is active"})
+		return
+	}
+
+	if err := p.endEmergency(access.Actor(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Emergency override ended"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ConnthrottleMonitorPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ConnthrottleMonitorPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if !throttleRe.MatchString(newConfig.EmergencyLocal) || !throttleRe.MatchString(newConfig.EmergencyGlobal) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Emergency throttles must look like connections:seconds"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ConnthrottleMonitorPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ConnthrottleMonitorPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
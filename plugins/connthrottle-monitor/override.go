@@ -0,0 +1,48 @@
+package connthrottlemonitor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// throttleRe matches a connthrottle rate such as 20:60 (connections:seconds)
+var throttleRe = regexp.MustCompile(`^[1-9][0-9]*:[1-9][0-9]*$`)
+
+// idleOverride is written when no emergency is active, so the include in
+// unrealircd.conf always finds the file
+const idleOverride = "/* Managed by the connthrottle-monitor panel plugin. No emergency override is active. */\n"
+
+// renderOverride returns the config that raises the new-users throttle
+func renderOverride(local, global, reason string) string {
+	return fmt.Sprintf(`/* Managed by the connthrottle-monitor panel plugin. Emergency override: %s */
+set {
+	connthrottle {
+		new-users {
+			local-throttle %s;
+			global-throttle %s;
+		};
+	};
+};
+`, sanitizeComment(reason), local, global)
+}
+
+// sanitizeComment keeps free text on one line and from closing the config
+// comment
+func sanitizeComment(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ", "*/", "* /").Replace(s)
+}
+
+// writeFile replaces a file atomically, keeping its permissions
+func writeFile(path, content string) error {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
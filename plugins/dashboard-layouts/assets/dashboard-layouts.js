@@ -0,0 +1,365 @@
+/**
+ * Dashboard Layouts Frontend Script
+ *
+ * Applies the signed-in user's saved card order, hidden cards, sizes and
+ * refresh intervals to the dashboard, and lets them change it.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'dashboard-layouts';
+  const PLUGIN_NAME = 'Dashboard Layouts';
+  const API_BASE = '/api/plugin/dashboard-layouts';
+  const CARD_SELECTOR = '[data-card-id]';
+
+  let layout = null;
+  let custom = false;
+  let loading = null;
+  let refreshTimers = [];
+  let observer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      const err = new Error(data.error || `Request failed with status ${res.status}`);
+      err.status = res.status;
+      err.data = data;
+      throw err;
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('dashboard-layouts-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'dashboard-layouts-styles';
+    style.textContent = `
+      [data-dbl-hidden="true"] { display: none !important; }
+      [data-dbl-size="sm"] { grid-column: span 1; }
+      [data-dbl-size="md"] { grid-column: span 2; }
+      [data-dbl-size="lg"] { grid-column: 1 / -1; }
+      .dbl-toggle {
+        position: fixed; right: 1.25rem; bottom: 1.25rem; z-index: 900;
+        background: var(--bg-tertiary, #45475a); color: var(--text-primary, #cdd6f4);
+        border: none; border-radius: 999px; padding: 0.5rem 1rem; cursor: pointer;
+        box-shadow: 0 2px 8px rgba(0, 0, 0, 0.3);
+      }
+      .dbl-panel {
+        position: fixed; right: 1.25rem; bottom: 4rem; z-index: 901; width: 26rem; max-height: 70vh; overflow-y: auto;
+        background: var(--bg-secondary, #181825); color: var(--text-secondary, #a6adc8);
+        border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 1rem;
+        box-shadow: 0 4px 16px rgba(0, 0, 0, 0.4);
+      }
+      .dbl-panel h3 { margin: 0 0 0.75rem; color: var(--text-primary, #cdd6f4); font-size: 1rem; }
+      .dbl-row { display: flex; align-items: center; gap: 0.4rem; padding: 0.3rem 0; border-bottom: 1px solid var(--border-primary, #313244); font-size: 0.85rem; }
+      .dbl-row .dbl-name { flex: 1; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+      .dbl-row select, .dbl-row input, .dbl-field input {
+        background: var(--bg-primary, #11111b); color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244); border-radius: 4px; padding: 0.15rem 0.3rem;
+      }
+      .dbl-row input[type="number"] { width: 4rem; }
+      .dbl-field { display: flex; align-items: center; gap: 0.5rem; margin: 0.5rem 0; font-size: 0.85rem; }
+      .dbl-field input[type="number"] { width: 5rem; }
+      .dbl-btn {
+        background: var(--bg-tertiary, #45475a); color: var(--text-primary, #cdd6f4);
+        border: none; border-radius: 6px; padding: 0.3rem 0.7rem; cursor: pointer;
+      }
+      .dbl-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .dbl-btn.small { padding: 0.1rem 0.4rem; }
+      .dbl-actions { display: flex; gap: 0.5rem; justify-content: flex-end; margin-top: 0.75rem; }
+      .dbl-muted { color: var(--text-muted, #6c7086); font-size: 0.8rem; }
+      .dbl-error { color: var(--error, #f38ba8); font-size: 0.85rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function cards() {
+    return Array.from(document.querySelectorAll(CARD_SELECTOR));
+  }
+
+  function cardTitle(el) {
+    const heading = el.querySelector('h1, h2, h3, h4, [data-card-title]');
+    return (heading && heading.textContent.trim()) || el.dataset.cardId;
+  }
+
+  // Cards in the saved order come first; cards the user hasn't placed yet,
+  // such as those of a newly installed plugin, keep their own order after
+  function orderedIds() {
+    const present = cards().map(el => el.dataset.cardId);
+    const placed = layout.order.filter(id => present.includes(id));
+    return placed.concat(present.filter(id => !placed.includes(id)));
+  }
+
+  // The layout is applied with CSS rather than by moving elements, so the
+  // panel can keep re-rendering its cards
+  function applyLayout() {
+    if (!layout) return;
+    const ids = orderedIds();
+    cards().forEach(el => {
+      const id = el.dataset.cardId;
+      el.style.order = String(ids.indexOf(id));
+      el.dataset.dblHidden = layout.hidden.includes(id) ? 'true' : 'false';
+      if (layout.sizes[id]) {
+        el.dataset.dblSize = layout.sizes[id];
+      } else {
+        delete el.dataset.dblSize;
+      }
+    });
+  }
+
+  function publish() {
+    window.UWPDashboardLayout = { get: () => layout, custom: () => custom };
+    window.dispatchEvent(new CustomEvent('uwp-dashboard-layout', { detail: { layout, custom } }));
+  }
+
+  function scheduleRefresh() {
+    refreshTimers.forEach(clearInterval);
+    refreshTimers = [];
+    if (!layout) return;
+
+    const intervals = {};
+    cards().forEach(el => {
+      const id = el.dataset.cardId;
+      if (layout.hidden.includes(id)) return;
+      const seconds = layout.refresh.cards[id] !== undefined ? layout.refresh.cards[id] : layout.refresh.interval;
+      if (seconds > 0) {
+        (intervals[seconds] = intervals[seconds] || []).push(id);
+      }
+    });
+    Object.entries(intervals).forEach(([seconds, ids]) => {
+      refreshTimers.push(setInterval(() => {
+        if (layout.refresh.pause_when_hidden && document.hidden) return;
+        window.dispatchEvent(new CustomEvent('uwp-dashboard-refresh', { detail: { cards: ids } }));
+      }, seconds * 1000));
+    });
+  }
+
+  function adopt(data) {
+    layout = data.layout;
+    custom = data.custom;
+    applyLayout();
+    scheduleRefresh();
+    publish();
+  }
+
+  async function load() {
+    if (!loading) {
+      loading = api('GET', '/layout')
+        .then(adopt)
+        .catch(e => console.warn(`[${PLUGIN_NAME}] Could not load layout: ${e.message}`))
+        .finally(() => { loading = null; });
+    }
+    return loading;
+  }
+
+  async function save(next, panel) {
+    try {
+      adopt(await api('PUT', '/layout', next));
+      closePanel();
+    } catch (e) {
+      if (e.status === 409 && e.data.layout) {
+        adopt(e.data);
+        renderPanel(panel, 'The layout was changed in another browser and has been reloaded; make your changes again.');
+        return;
+      }
+      renderPanel(panel, e.message);
+    }
+  }
+
+  function closePanel() {
+    const panel = document.getElementById('dbl-panel');
+    if (panel) panel.remove();
+  }
+
+  function renderPanel(panel, error) {
+    const ids = orderedIds();
+    panel.innerHTML = `
+      <h3>Dashboard layout</h3>
+      ${error ? `<div class="dbl-error">${escapeHtml(error)}</div>` : ''}
+      <div class="dbl-muted">${custom ? 'Saved to your account' : 'Using the default layout'}</div>
+      <div id="dbl-cards">
+        ${ids.map((id, i) => {
+          const el = document.querySelector(`[data-card-id="${CSS.escape(id)}"]`);
+          const refresh = layout.refresh.cards[id];
+          return `
+            <div class="dbl-row" data-id="${escapeHtml(id)}">
+              <input type="checkbox" data-field="visible" ${layout.hidden.includes(id) ? '' : 'checked'} title="Show">
+              <span class="dbl-name" title="${escapeHtml(id)}">${escapeHtml(el ? cardTitle(el) : id)}</span>
+              <select data-field="size" title="Size">
+                ${['', 'sm', 'md', 'lg'].map(s => `<option value="${s}" ${(layout.sizes[id] || '') === s ? 'selected' : ''}>${s || 'auto'}</option>`).join('')}
+              </select>
+              <input type="number" data-field="refresh" min="0" placeholder="${layout.refresh.interval}" value="${refresh !== undefined ? refresh : ''}" title="Refresh every (seconds)">
+              <button class="dbl-btn small" data-move="-1" ${i === 0 ? 'disabled' : ''}>↑</button>
+              <button class="dbl-btn small" data-move="1" ${i === ids.length - 1 ? 'disabled' : ''}>↓</button>
+            </div>
+          `;
+        }).join('')}
+      </div>
+      <label class="dbl-field">Refresh every <input type="number" id="dbl-interval" min="0" value="${layout.refresh.interval}"> seconds <span class="dbl-muted">(0 is off)</span></label>
+      <label class="dbl-field"><input type="checkbox" id="dbl-pause" ${layout.refresh.pause_when_hidden ? 'checked' : ''}> Pause while the tab is in the background</label>
+      <div class="dbl-actions">
+        <button class="dbl-btn" data-action="reset">Reset to default</button>
+        <button class="dbl-btn" data-action="close">Cancel</button>
+        <button class="dbl-btn primary" data-action="save">Save</button>
+      </div>
+    `;
+  }
+
+  function readPanel(panel) {
+    const next = {
+      order: [],
+      hidden: [],
+      sizes: {},
+      refresh: {
+        interval: parseInt(panel.querySelector('#dbl-interval').value, 10) || 0,
+        cards: {},
+        pause_when_hidden: panel.querySelector('#dbl-pause').checked
+      },
+      revision: layout.revision
+    };
+    panel.querySelectorAll('.dbl-row').forEach(row => {
+      const id = row.dataset.id;
+      next.order.push(id);
+      if (!row.querySelector('[data-field="visible"]').checked) next.hidden.push(id);
+      const size = row.querySelector('[data-field="size"]').value;
+      if (size) next.sizes[id] = size;
+      const refresh = row.querySelector('[data-field="refresh"]').value;
+      if (refresh !== '') next.refresh.cards[id] = parseInt(refresh, 10) || 0;
+    });
+    // Keep hidden cards that aren't on this page, so hiding a card from a
+    // disabled plugin survives enabling it again
+    layout.hidden.forEach(id => {
+      if (!next.order.includes(id)) next.hidden.push(id);
+    });
+    return next;
+  }
+
+  function openPanel() {
+    if (document.getElementById('dbl-panel')) {
+      closePanel();
+      return;
+    }
+    const panel = document.createElement('div');
+    panel.id = 'dbl-panel';
+    panel.className = 'dbl-panel';
+    panel.dataset.plugin = PLUGIN_ID;
+    document.body.appendChild(panel);
+    renderPanel(panel);
+
+    panel.addEventListener('click', async (e) => {
+      const move = e.target.closest('[data-move]');
+      if (move) {
+        const row = move.closest('.dbl-row');
+        const sibling = move.dataset.move === '-1' ? row.previousElementSibling : row.nextElementSibling;
+        if (sibling) {
+          if (move.dataset.move === '-1') sibling.before(row);
+          else sibling.after(row);
+        }
+        panel.querySelectorAll('.dbl-row').forEach((r, i, all) => {
+          r.querySelector('[data-move="-1"]').disabled = i === 0;
+          r.querySelector('[data-move="1"]').disabled = i === all.length - 1;
+        });
+        return;
+      }
+      const action = e.target.closest('[data-action]');
+      if (!action) return;
+      if (action.dataset.action === 'close') closePanel();
+      if (action.dataset.action === 'save') save(readPanel(panel), panel);
+      if (action.dataset.action === 'reset') {
+        try {
+          adopt(await api('DELETE', '/layout'));
+          renderPanel(panel);
+        } catch (err) {
+          renderPanel(panel, err.message);
+        }
+      }
+    });
+  }
+
+  // Cards come and go as the panel renders pages, so the layout and the
+  // Customize button follow them
+  function sync() {
+    const present = cards().length > 0;
+    let toggle = document.getElementById('dbl-toggle');
+    if (!present) {
+      if (toggle) toggle.remove();
+      closePanel();
+      return;
+    }
+    if (!toggle) {
+      toggle = document.createElement('button');
+      toggle.id = 'dbl-toggle';
+      toggle.className = 'dbl-toggle';
+      toggle.textContent = 'Customize';
+      toggle.addEventListener('click', openPanel);
+      document.body.appendChild(toggle);
+    }
+    if (layout) {
+      applyLayout();
+    } else {
+      load();
+    }
+  }
+
+  function cleanup() {
+    if (observer) observer.disconnect();
+    refreshTimers.forEach(clearInterval);
+    refreshTimers = [];
+    closePanel();
+    const toggle = document.getElementById('dbl-toggle');
+    if (toggle) toggle.remove();
+    const style = document.getElementById('dashboard-layouts-styles');
+    if (style) style.remove();
+    delete window.UWPDashboardLayout;
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+    injectStyles();
+    let pending = false;
+    observer = new MutationObserver(() => {
+      if (pending) return;
+      pending = true;
+      requestAnimationFrame(() => {
+        pending = false;
+        sync();
+      });
+    });
+    observer.observe(document.body, { childList: true, subtree: true });
+    sync();
+  }
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+})();
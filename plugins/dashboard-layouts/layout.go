@@ -0,0 +1,134 @@
+package dashboardlayouts
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Card sizes, as used by dashboard cards
+var cardSizes = map[string]bool{"sm": true, "md": true, "lg": true}
+
+// Limits on a layout
+const (
+	maxCards       = 200
+	maxRefresh     = 3600
+	maxPreferences = 50
+)
+
+// cardIDPattern matches a dashboard card ID such as help-tickets-card
+var cardIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,64}$`)
+
+// Layout is one panel user's dashboard arrangement
+type Layout struct {
+	Order     []string          `json:"order"`
+	Hidden    []string          `json:"hidden"`
+	Sizes     map[string]string `json:"sizes"`
+	Refresh   Refresh           `json:"refresh"`
+	Revision  int               `json:"revision"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Refresh holds how often the dashboard reloads its cards
+type Refresh struct {
+	Interval        int            `json:"interval"`
+	Cards           map[string]int `json:"cards"`
+	PauseWhenHidden bool           `json:"pause_when_hidden"`
+}
+
+// defaultLayout builds the layout of users who haven't saved one from the
+// plugin settings
+func defaultLayout(cfg Config) *Layout {
+	return &Layout{
+		Order:  lists.Split(cfg.DefaultOrder),
+		Hidden: lists.Split(cfg.DefaultHidden),
+		Sizes:  make(map[string]string),
+		Refresh: Refresh{
+			Interval:        cfg.DefaultRefresh,
+			Cards:           make(map[string]int),
+			PauseWhenHidden: true,
+		},
+	}
+}
+
+// normalize checks a layout sent by a browser and tidies it up: card IDs
+// are deduplicated, and missing lists and maps are made empty so the
+// frontend never sees null
+func (l *Layout) normalize(minRefresh int) error {
+	var err error
+	if l.Order, err = cleanIDs("order", l.Order); err != nil {
+		return err
+	}
+	if l.Hidden, err = cleanIDs("hidden", l.Hidden); err != nil {
+		return err
+	}
+
+	if l.Sizes == nil {
+		l.Sizes = make(map[string]string)
+	}
+	if len(l.Sizes) > maxCards {
+		return fmt.Errorf("sizes can have at most %d cards", maxCards)
+	}
+	for id, size := range l.Sizes {
+		if !cardIDPattern.MatchString(id) {
+			return fmt.Errorf("%q is not a valid card ID", id)
+		}
+		if !cardSizes[size] {
+			return fmt.Errorf("size of %s must be sm, md or lg", id)
+		}
+	}
+
+	if err := checkInterval("refresh interval", l.Refresh.Interval, minRefresh); err != nil {
+		return err
+	}
+	if l.Refresh.Cards == nil {
+		l.Refresh.Cards = make(map[string]int)
+	}
+	if len(l.Refresh.Cards) > maxPreferences {
+		return fmt.Errorf("at most %d cards can have their own refresh interval", maxPreferences)
+	}
+	for id, interval := range l.Refresh.Cards {
+		if !cardIDPattern.MatchString(id) {
+			return fmt.Errorf("%q is not a valid card ID", id)
+		}
+		if err := checkInterval("refresh interval of "+id, interval, minRefresh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanIDs validates a list of card IDs and drops duplicates
+func cleanIDs(field string, ids []string) ([]string, error) {
+	if len(ids) > maxCards {
+		return nil, fmt.Errorf("%s can have at most %d cards", field, maxCards)
+	}
+	seen := make(map[string]bool)
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if !cardIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("%q in %s is not a valid card ID", id, field)
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// checkInterval validates a refresh interval in seconds; 0 turns
+// refreshing off
+func checkInterval(what string, seconds, min int) error {
+	if seconds == 0 {
+		return nil
+	}
+	if seconds < min || seconds > maxRefresh {
+		return fmt.Errorf("%s must be 0 or between %d and %d seconds", what, min, maxRefresh)
+	}
+	return nil
+}
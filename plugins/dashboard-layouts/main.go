@@ -0,0 +1,289 @@
+// Dashboard Layouts Plugin for UnrealIRCd Web Panel
+// Keeps each panel user's dashboard card order, hidden cards and refresh
+// preferences on the server so they follow the user between browsers
+
+package dashboardlayouts
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// DashboardLayoutsPlugin implements the Plugin interface
+type DashboardLayoutsPlugin struct {
+	config  Config
+	layouts map[string]*Layout
+	mu      sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	DataDir        string `json:"data_dir"`
+	DefaultOrder   string `json:"default_order"`
+	DefaultHidden  string `json:"default_hidden"`
+	DefaultRefresh int    `json:"default_refresh"`
+	MinRefresh     int    `json:"min_refresh"`
+}
+
+// storeData is the on-disk form of the saved layouts
+type storeData struct {
+	Layouts map[string]*Layout `json:"layouts"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &DashboardLayoutsPlugin{
+		config: Config{
+			DataDir:        "data/plugins/dashboard-layouts",
+			DefaultRefresh: 30,
+			MinRefresh:     10,
+		},
+		layouts: make(map[string]*Layout),
+	}
+}
+
+// Info returns plugin metadata
+func (p *DashboardLayoutsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Dashboard Layouts",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Saves each panel user's dashboard layout on the server",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *DashboardLayoutsPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[dashboard-layouts] failed to load layouts: %v", err)
+	}
+	if data.Layouts != nil {
+		p.layouts = data.Layouts
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Supply the layout to the page, for the user when the panel says who
+	// is viewing it and otherwise the default with where to fetch the
+	// user's own
+	hm.Register(hooks.HookFooter, "dashboard-layouts-layout", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		layout, custom := p.layoutFor(hookUsername(args))
+		return map[string]interface{}{
+			"plugin":   "dashboard-layouts",
+			"endpoint": "/api/plugin/dashboard-layouts/layout",
+			"layout":   layout,
+			"custom":   custom,
+		}
+	}, 10)
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *DashboardLayoutsPlugin) Shutdown() error {
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *DashboardLayoutsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/dashboard-layouts")
+	{
+		plugin.GET("/layout", p.handleGetLayout)
+		plugin.PUT("/layout", p.handlePutLayout)
+		plugin.DELETE("/layout", p.handleResetLayout)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the path of the layouts file
+func (p *DashboardLayoutsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "layouts.json")
+}
+
+// save writes the layouts to disk. Caller must hold p.mu.
+func (p *DashboardLayoutsPlugin) save() {
+	if err := storage.SaveJSON(p.storePath(), storeData{Layouts: p.layouts}); err != nil {
+		log.Printf("[dashboard-layouts] failed to save layouts: %v", err)
+	}
+}
+
+// layoutFor returns the saved layout of a panel user, or the default and
+// false if they have none. Caller must hold p.mu.
+func (p *DashboardLayoutsPlugin) layoutFor(username string) (*Layout, bool) {
+	if l, ok := p.layouts[strings.ToLower(username)]; ok && username != "" {
+		return l, true
+	}
+	return defaultLayout(p.config), false
+}
+
+// hookUsername extracts the panel user viewing the page from hook
+// arguments, if the panel passes it
+func hookUsername(args interface{}) string {
+	m, ok := args.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if name, ok := m["username"].(string); ok {
+		return name
+	}
+	if user, ok := m["user"].(map[string]interface{}); ok {
+		name, _ := user["username"].(string)
+		return name
+	}
+	return ""
+}
+
+// handleGetLayout returns the signed-in user's layout
+func (p *DashboardLayoutsPlugin) handleGetLayout(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not signed in"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	layout, custom := p.layoutFor(username)
+	c.JSON(http.StatusOK, gin.H{"layout": layout, "custom": custom})
+}
+
+// handlePutLayout saves the signed-in user's layout. The revision must be
+// the one the browser last loaded, so a browser with an old copy can't
+// silently undo changes made in another.
+func (p *DashboardLayoutsPlugin) handlePutLayout(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not signed in"})
+		return
+	}
+	var layout Layout
+	if err := c.ShouldBindJSON(&layout); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid layout"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := layout.normalize(p.config.MinRefresh); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key := strings.ToLower(username)
+	revision := 0
+	if current, ok := p.layouts[key]; ok {
+		revision = current.Revision
+	}
+	if layout.Revision != revision {
+		latest, custom := p.layoutFor(username)
+		c.JSON(http.StatusConflict, gin.H{"error": "The layout was changed in another browser", "layout": latest, "custom": custom})
+		return
+	}
+	layout.Revision = revision + 1
+	layout.UpdatedAt = time.Now()
+	p.layouts[key] = &layout
+	p.save()
+
+	c.JSON(http.StatusOK, gin.H{"layout": &layout, "custom": true})
+}
+
+// handleResetLayout deletes the signed-in user's layout, returning them
+// to the default
+func (p *DashboardLayoutsPlugin) handleResetLayout(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not signed in"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.layouts, strings.ToLower(username))
+	p.save()
+	c.JSON(http.StatusOK, gin.H{"layout": defaultLayout(p.config), "custom": false})
+}
+
+// handleGetConfig returns the current configuration
+func (p *DashboardLayoutsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *DashboardLayoutsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.MinRefresh < 1 || newConfig.MinRefresh > maxRefresh {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_refresh must be between 1 and 3600"})
+		return
+	}
+	if err := checkInterval("default_refresh", newConfig.DefaultRefresh, newConfig.MinRefresh); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, list := range []string{newConfig.DefaultOrder, newConfig.DefaultHidden} {
+		if _, err := cleanIDs("default layout", lists.Split(list)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	p.mu.Lock()
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *DashboardLayoutsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *DashboardLayoutsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
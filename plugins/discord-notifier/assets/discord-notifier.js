@@ -0,0 +1,305 @@
+/**
+ * Discord Notifier Frontend Script
+ *
+ * Manage Discord webhooks, choose which events go where and review
+ * recent deliveries.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'discord-notifier';
+  const PLUGIN_NAME = 'Discord Notifier';
+  const PAGE_PATH = '/plugins/discord-notifier';
+  const API_BASE = '/api/plugin/discord-notifier';
+
+  const SEVERITIES = ['info', 'warning', 'critical'];
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function splitEvents(value) {
+    return value.split(',').map(s => s.trim()).filter(Boolean);
+  }
+
+  function severityOptions(selected) {
+    return SEVERITIES.map(s => `<option value="${s}" ${s === selected ? 'selected' : ''}>${s}</option>`).join('');
+  }
+
+  function injectStyles() {
+    if (document.getElementById('discord-notifier-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'discord-notifier-styles';
+    style.textContent = `
+      .dn-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .dn-form { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .dn-app input, .dn-app select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.35rem 0.6rem;
+      }
+      .dn-app input[name="url"] { min-width: 22rem; }
+      .dn-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .dn-table { width: 100%; border-collapse: collapse; }
+      .dn-table th, .dn-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        overflow-wrap: anywhere;
+      }
+      .dn-status { font-size: 0.9rem; }
+      .dn-hint { font-size: 0.85rem; color: var(--text-muted, #6c7086); }
+      .dn-disabled { color: var(--text-muted, #6c7086); }
+      .dn-ok { color: var(--success, #a6e3a1); }
+      .dn-warning { color: var(--warning, #f9e2af); }
+      .dn-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#dn-status');
+    try {
+      const s = await api('GET', '/status');
+      const dropped = Object.values(s.queues || {}).reduce((n, q) => n + q.dropped, 0);
+      body.innerHTML = `
+        ${s.notify_bans && !s.log_stream ? '<span class="dn-warning">Not connected to the IRCd log stream; new bans are not being seen.</span>' : ''}
+        ${s.notify_records && s.poll_error ? `<span class="dn-warning">User counts unavailable: ${escapeHtml(s.poll_error)}</span>` : ''}
+        ${s.events_enabled ? '' : '<span class="dn-warning">No events token set; other plugins cannot send events.</span>'}
+        ${s.record ? `User record: <strong>${s.record}</strong>.` : ''}
+        ${dropped ? `<span class="dn-error">${dropped} events dropped because a queue was full.</span>` : ''}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="dn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadWebhooks(container) {
+    const body = container.querySelector('#dn-webhooks');
+    try {
+      const data = await api('GET', '/webhooks');
+      if (data.webhooks.length === 0) {
+        body.innerHTML = '<p>No webhooks yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="dn-table">
+          <thead><tr><th>Name</th><th>Events</th><th>Minimum</th><th>Webhook</th><th>Enabled</th><th></th></tr></thead>
+          <tbody>
+            ${data.webhooks.map(w => `
+              <tr class="${w.enabled ? '' : 'dn-disabled'}">
+                <td>${escapeHtml(w.name)}</td>
+                <td><input data-events="${escapeHtml(w.id)}" value="${escapeHtml(w.events.join(', '))}"></td>
+                <td><select data-severity="${escapeHtml(w.id)}">${severityOptions(w.min_severity)}</select></td>
+                <td><code>${escapeHtml(w.url)}</code></td>
+                <td><input type="checkbox" data-enabled="${escapeHtml(w.id)}" ${w.enabled ? 'checked' : ''}></td>
+                <td>
+                  <button data-test="${escapeHtml(w.id)}">Test</button>
+                  <button data-delete="${escapeHtml(w.id)}">Delete</button>
+                </td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+      const byId = Object.fromEntries(data.webhooks.map(w => [w.id, w]));
+      const update = async (id) => {
+        const w = byId[id];
+        try {
+          await api('PUT', `/webhooks/${id}`, {
+            name: w.name,
+            events: splitEvents(body.querySelector(`[data-events="${id}"]`).value),
+            min_severity: body.querySelector(`[data-severity="${id}"]`).value,
+            enabled: body.querySelector(`[data-enabled="${id}"]`).checked
+          });
+        } catch (e) {
+          alert(e.message);
+        }
+        loadWebhooks(container);
+      };
+      body.querySelectorAll('[data-events], [data-severity], [data-enabled]').forEach(el => {
+        el.addEventListener('change', () => update(el.dataset.events || el.dataset.severity || el.dataset.enabled));
+      });
+      body.querySelectorAll('button[data-test]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          btn.disabled = true;
+          try {
+            await api('POST', `/webhooks/${btn.dataset.test}/test`);
+          } catch (e) {
+            alert(e.message);
+          }
+          btn.disabled = false;
+          loadDeliveries(container);
+        });
+      });
+      body.querySelectorAll('button[data-delete]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          if (!confirm('Remove this webhook? Messages still queued for it are dropped.')) return;
+          try {
+            await api('DELETE', `/webhooks/${btn.dataset.delete}`);
+            loadWebhooks(container);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="dn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadDeliveries(container) {
+    const body = container.querySelector('#dn-deliveries');
+    try {
+      const data = await api('GET', '/deliveries?limit=100');
+      if (data.deliveries.length === 0) {
+        body.innerHTML = '<p>Nothing sent yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="dn-table">
+          <thead><tr><th>Time</th><th>Webhook</th><th>Events</th><th>Result</th></tr></thead>
+          <tbody>
+            ${data.deliveries.map(d => `
+              <tr>
+                <td>${escapeHtml(new Date(d.time).toLocaleString())}</td>
+                <td>${escapeHtml(d.webhook)}</td>
+                <td>${escapeHtml(d.events.join(', '))}</td>
+                <td>${d.ok ? '<span class="dn-ok">Sent</span>' : `<span class="dn-error">${escapeHtml(d.error)}</span>`}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="dn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="dn-app" data-plugin="${PLUGIN_ID}">
+        <div class="dn-status" id="dn-status"></div>
+        <h3>Webhooks</h3>
+        <form class="dn-form" id="dn-add">
+          <input name="name" placeholder="Name, e.g. #staff-bans" required>
+          <input name="url" placeholder="https://discord.com/api/webhooks/..." required>
+          <input name="events" placeholder="Events, e.g. ban_added, netsplit-tracker" value="*" required>
+          <select name="min_severity">${severityOptions('info')}</select>
+          <button type="submit">Add webhook</button>
+        </form>
+        <div class="dn-hint">
+          Events are event types (<code>ban_added</code>, <code>stats_record</code>, or any type another plugin sends),
+          names of the plugins that send them, or <code>*</code> for everything.
+        </div>
+        <div id="dn-webhooks">Loading...</div>
+        <h3>Recent deliveries</h3>
+        <div id="dn-deliveries">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#dn-add').addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = e.target.elements;
+      try {
+        await api('POST', '/webhooks', {
+          name: f.name.value,
+          url: f.url.value,
+          events: splitEvents(f.events.value),
+          min_severity: f.min_severity.value
+        });
+        e.target.reset();
+        loadWebhooks(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    loadStatus(container);
+    loadWebhooks(container);
+    loadDeliveries(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('discord-notifier-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
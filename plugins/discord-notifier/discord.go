@@ -0,0 +1,182 @@
+package discordnotifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Discord limits
+const (
+	maxEmbedsPerMessage = 10
+	maxTitleLen         = 256
+	maxDescriptionLen   = 4096
+	maxFields           = 25
+	maxFieldNameLen     = 256
+	maxFieldValueLen    = 1024
+)
+
+// severityColors are the embed colors of each severity
+var severityColors = map[string]int{
+	notify.SeverityInfo:     0x89b4fa,
+	notify.SeverityWarning:  0xf9e2af,
+	notify.SeverityCritical: 0xf38ba8,
+}
+
+// embed is a Discord message embed
+type embed struct {
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	Color       int          `json:"color"`
+	Fields      []embedField `json:"fields,omitempty"`
+	Footer      *embedFooter `json:"footer,omitempty"`
+	Timestamp   string       `json:"timestamp"`
+}
+
+// embedField is a name and value shown in an embed
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// embedFooter is the small text under an embed
+type embedFooter struct {
+	Text string `json:"text"`
+}
+
+// webhookMessage is the body of a webhook execution
+type webhookMessage struct {
+	Username  string  `json:"username,omitempty"`
+	AvatarURL string  `json:"avatar_url,omitempty"`
+	Embeds    []embed `json:"embeds"`
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// newEmbed formats an event as an embed, with its data as fields
+func newEmbed(ev notify.Event) embed {
+	e := embed{
+		Title:       truncate(ev.Title, maxTitleLen),
+		Description: truncate(ev.Message, maxDescriptionLen),
+		Color:       severityColors[ev.Severity],
+		Timestamp:   ev.Timestamp.UTC().Format(time.RFC3339),
+	}
+	if ev.Source != "" {
+		e.Footer = &embedFooter{Text: ev.Source + " · " + ev.Type}
+	}
+
+	keys := make([]string, 0, len(ev.Data))
+	for k := range ev.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if len(e.Fields) == maxFields {
+			break
+		}
+		var value string
+		switch v := ev.Data[k].(type) {
+		case string:
+			value = v
+		case []interface{}:
+			parts := make([]string, 0, len(v))
+			for _, item := range v {
+				parts = append(parts, fmt.Sprint(item))
+			}
+			value = strings.Join(parts, ", ")
+		case []string:
+			value = strings.Join(v, ", ")
+		default:
+			value = fmt.Sprint(v)
+		}
+		if value == "" {
+			continue
+		}
+		e.Fields = append(e.Fields, embedField{
+			Name:   truncate(strings.ReplaceAll(k, "_", " "), maxFieldNameLen),
+			Value:  truncate(value, maxFieldValueLen),
+			Inline: len(value) <= 40,
+		})
+	}
+	return e
+}
+
+// webhookClient is shared by all webhook deliveries
+var webhookClient = &http.Client{Timeout: 15 * time.Second}
+
+// postWebhook executes a Discord webhook. When Discord rate limits the
+// request, the time to wait before retrying is returned with the error.
+func postWebhook(ctx context.Context, url string, msg webhookMessage) (time.Duration, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var rl struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&rl)
+		wait := time.Duration(rl.RetryAfter * float64(time.Second))
+		if wait <= 0 {
+			wait = time.Second
+		}
+		return wait, fmt.Errorf("rate limited by Discord for %s", wait)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var de struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&de)
+		if de.Message != "" {
+			return 0, fmt.Errorf("discord: %s (%s)", de.Message, resp.Status)
+		}
+		return 0, fmt.Errorf("discord: unexpected status %s", resp.Status)
+	}
+	return 0, nil
+}
+
+// validWebhookURL reports whether url looks like a Discord webhook
+func validWebhookURL(url string) bool {
+	for _, prefix := range []string{
+		"https://discord.com/api/webhooks/",
+		"https://discordapp.com/api/webhooks/",
+		"https://canary.discord.com/api/webhooks/",
+		"https://ptb.discord.com/api/webhooks/",
+	} {
+		if strings.HasPrefix(url, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package discordnotifier
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Built-in event types
+const (
+	EventStatsRecord = "stats_record"
+	EventTest        = "test"
+)
+
+// statsResult is the part of stats.get we need
+type statsResult struct {
+	User struct {
+		Total  int `json:"total"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+}
+
+// recordEvent announces a new user count record
+func recordEvent(users, previous, channels int) notify.Event {
+	return notify.Event{
+		Source:    "discord-notifier",
+		Type:      EventStatsRecord,
+		Severity:  notify.SeverityInfo,
+		Title:     "New user record",
+		Message:   fmt.Sprintf("%d users online, up from the previous record of %d", users, previous),
+		Timestamp: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"users":           users,
+			"previous_record": previous,
+			"channels":        channels,
+		},
+	}
+}
@@ -0,0 +1,881 @@
+// Discord Notifier Plugin for UnrealIRCd Web Panel
+// Posts bans, user records and alerts from other plugins to Discord
+// webhooks as embeds, routed by event type and rate limited per webhook
+
+package discordnotifier
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on queued and remembered messages
+const (
+	queueSize     = 200
+	maxDeliveries = 500
+	maxAttempts   = 3
+)
+
+// DiscordNotifierPlugin implements the Plugin interface
+type DiscordNotifierPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	webhooks     []*Webhook
+	senders      map[string]*sender
+	deliveries   []*Delivery
+	record       int
+	recordDue    bool
+	lastRecord   time.Time
+	pollErr      string
+	received     int
+	dirty        bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	StreamURL      string `json:"stream_url"`
+	LogSources     string `json:"log_sources"`
+	DataDir        string `json:"data_dir"`
+	EventsToken    string `json:"events_token"`
+	BotName        string `json:"bot_name"`
+	AvatarURL      string `json:"avatar_url"`
+	RatePerMinute  int    `json:"rate_per_minute"`
+	NotifyBans     bool   `json:"notify_bans"`
+	BanTypes       string `json:"ban_types"`
+	NotifyRecords  bool   `json:"notify_records"`
+	RecordCooldown int    `json:"record_cooldown"`
+}
+
+// Webhook is a Discord webhook and the events routed to it
+type Webhook struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	MinSeverity string    `json:"min_severity"`
+	Enabled     bool      `json:"enabled"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookRequest is the body of a create or update request
+type WebhookRequest struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	MinSeverity string   `json:"min_severity"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// Delivery is the outcome of one message to a webhook
+type Delivery struct {
+	Time      time.Time `json:"time"`
+	WebhookID string    `json:"webhook_id"`
+	Webhook   string    `json:"webhook"`
+	Events    []string  `json:"events"`
+	OK        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// sender delivers the queued events of one webhook at its rate
+type sender struct {
+	queue   chan notify.Event
+	quit    chan struct{}
+	dropped int
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Webhooks []*Webhook `json:"webhooks"`
+	Record   int        `json:"record"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &DiscordNotifierPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			StreamURL:      "wss://127.0.0.1:8600/",
+			LogSources:     "tkl",
+			DataDir:        "data/plugins/discord-notifier",
+			BotName:        "UnrealIRCd Web Panel",
+			RatePerMinute:  20,
+			NotifyBans:     true,
+			NotifyRecords:  true,
+			RecordCooldown: 60,
+		},
+		webhooks:   make([]*Webhook, 0),
+		senders:    make(map[string]*sender),
+		deliveries: make([]*Delivery, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *DiscordNotifierPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Discord Notifier",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Posts network and plugin events to Discord webhooks",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *DiscordNotifierPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[discord-notifier] failed to load data: %v", err)
+	}
+	if data.Webhooks != nil {
+		p.webhooks = data.Webhooks
+	}
+	p.record = data.Record
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "discord-notifier-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		sent, failed, dropped := 0, 0, 0
+		for i := len(p.deliveries) - 1; i >= 0 && p.deliveries[i].Time.After(since); i-- {
+			if p.deliveries[i].OK {
+				sent++
+			} else {
+				failed++
+			}
+		}
+		for _, s := range p.senders {
+			dropped += s.dropped
+		}
+		return plugins.DashboardCard{
+			Title: "Discord Notifier",
+			Icon:  "MessageSquare",
+			Content: map[string]interface{}{
+				"webhooks":   len(p.webhooks),
+				"sent_24h":   sent,
+				"failed_24h": failed,
+				"dropped":    dropped,
+			},
+			Order: 61,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *DiscordNotifierPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *DiscordNotifierPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/discord-notifier")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/webhooks", p.handleListWebhooks)
+		plugin.POST("/webhooks", p.handleCreateWebhook)
+		plugin.PUT("/webhooks/:id", p.handleUpdateWebhook)
+		plugin.DELETE("/webhooks/:id", p.handleDeleteWebhook)
+		plugin.POST("/webhooks/:id/test", p.handleTestWebhook)
+		plugin.GET("/deliveries", p.handleDeliveries)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *DiscordNotifierPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "discord-notifier.json")
+}
+
+// save persists the state if it changed
+func (p *DiscordNotifierPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Webhooks: p.webhooks, Record: p.record}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *DiscordNotifierPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// webhookByID returns the webhook with the given ID. Caller must hold p.mu.
+func (p *DiscordNotifierPlugin) webhookByID(id string) *Webhook {
+	for _, wh := range p.webhooks {
+		if wh.ID == id {
+			return wh
+		}
+	}
+	return nil
+}
+
+// matches reports whether an event is routed to the webhook. Events lists
+// event types or source plugins, or * for everything.
+func (wh *Webhook) matches(ev notify.Event) bool {
+	if !wh.Enabled || notify.SeverityRank[ev.Severity] < notify.SeverityRank[wh.MinSeverity] {
+		return false
+	}
+	for _, e := range wh.Events {
+		if e == "*" || strings.EqualFold(e, ev.Type) || strings.EqualFold(e, ev.Source) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamLoop follows ban log events until shutdown
+func (p *DiscordNotifierPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *DiscordNotifierPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[discord-notifier] log stream: %v", err)
+	}
+}
+
+// handleEvent announces new server bans
+func (p *DiscordNotifierPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	p.mu.RLock()
+	enabled := p.config.NotifyBans
+	types := lists.Split(p.config.BanTypes)
+	p.mu.RUnlock()
+	if !enabled {
+		return
+	}
+	if a, ok := notify.BanEvent("discord-notifier", ev, types); ok {
+		p.dispatch(a)
+	}
+}
+
+// pollLoop watches the user count for new records every minute until
+// shutdown
+func (p *DiscordNotifierPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.RLock()
+		enabled := p.config.NotifyRecords
+		p.mu.RUnlock()
+		if enabled {
+			p.pollStats()
+		}
+		if err := p.save(); err != nil {
+			log.Printf("[discord-notifier] failed to save data: %v", err)
+		}
+	}
+}
+
+// pollStats compares the user count with the record. A record is
+// announced at most once per record_cooldown minutes, with the highest
+// count reached by then.
+func (p *DiscordNotifierPlugin) pollStats() {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var stats statsResult
+	err := p.client().Call(ctx, "stats.get", nil, &stats)
+
+	p.mu.Lock()
+	if err != nil {
+		p.pollErr = err.Error()
+		p.mu.Unlock()
+		return
+	}
+	p.pollErr = ""
+
+	// The first poll only learns the current record
+	if p.record == 0 {
+		p.record = stats.User.Record
+		if stats.User.Total > p.record {
+			p.record = stats.User.Total
+		}
+		p.dirty = true
+		p.mu.Unlock()
+		return
+	}
+
+	previous := p.record
+	if stats.User.Total > p.record {
+		p.record = stats.User.Total
+		p.recordDue = true
+		p.dirty = true
+	}
+	cooldown := time.Duration(p.config.RecordCooldown) * time.Minute
+	due := p.recordDue && time.Since(p.lastRecord) >= cooldown
+	if due {
+		p.recordDue = false
+		p.lastRecord = time.Now()
+	}
+	users := p.record
+	p.mu.Unlock()
+
+	if due {
+		p.dispatch(recordEvent(users, previous, stats.Channel.Total))
+	}
+}
+
+// dispatch queues an event for every webhook it is routed to. Events
+// arriving while a webhook's queue is full are dropped.
+func (p *DiscordNotifierPlugin) dispatch(ev notify.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.stop:
+		return
+	default:
+	}
+
+	p.received++
+	for _, wh := range p.webhooks {
+		if !wh.matches(ev) {
+			continue
+		}
+		s := p.senderFor(wh.ID)
+		select {
+		case s.queue <- ev:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// senderFor returns the sender of a webhook, starting it on first use.
+// Caller must hold p.mu.
+func (p *DiscordNotifierPlugin) senderFor(id string) *sender {
+	s, ok := p.senders[id]
+	if !ok {
+		s = &sender{queue: make(chan notify.Event, queueSize), quit: make(chan struct{})}
+		p.senders[id] = s
+		p.wg.Add(1)
+		go p.sendLoop(id, s)
+	}
+	return s
+}
+
+// stopSender stops the sender of a webhook, dropping what it had queued.
+// Caller must hold p.mu.
+func (p *DiscordNotifierPlugin) stopSender(id string) {
+	if s, ok := p.senders[id]; ok {
+		close(s.quit)
+		delete(p.senders, id)
+	}
+}
+
+// sendLoop delivers a webhook's events one message at a time, waiting
+// between messages to stay within rate_per_minute. A backlog is sent as up
+// to ten embeds per message.
+func (p *DiscordNotifierPlugin) sendLoop(id string, s *sender) {
+	defer p.wg.Done()
+
+	for {
+		var first notify.Event
+		select {
+		case <-p.stop:
+			return
+		case <-s.quit:
+			return
+		case first = <-s.queue:
+		}
+		batch := []notify.Event{first}
+	drain:
+		for len(batch) < maxEmbedsPerMessage {
+			select {
+			case ev := <-s.queue:
+				batch = append(batch, ev)
+			default:
+				break drain
+			}
+		}
+
+		p.mu.RLock()
+		wh := p.webhookByID(id)
+		var url, name string
+		if wh != nil {
+			url, name = wh.URL, wh.Name
+		}
+		msg := webhookMessage{Username: p.config.BotName, AvatarURL: p.config.AvatarURL, Embeds: make([]embed, 0, len(batch))}
+		interval := time.Minute
+		if p.config.RatePerMinute > 0 {
+			interval = time.Minute / time.Duration(p.config.RatePerMinute)
+		}
+		p.mu.RUnlock()
+		if wh == nil {
+			return
+		}
+
+		types := make([]string, 0, len(batch))
+		for _, ev := range batch {
+			msg.Embeds = append(msg.Embeds, newEmbed(ev))
+			types = append(types, ev.Type)
+		}
+
+		var err error
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			var wait time.Duration
+			wait, err = postWebhook(ctx, url, msg)
+			cancel()
+			if wait == 0 {
+				break
+			}
+			select {
+			case <-p.stop:
+				return
+			case <-s.quit:
+				return
+			case <-time.After(wait):
+			}
+		}
+		if err != nil {
+			log.Printf("[discord-notifier] delivery to %s failed: %v", name, err)
+		}
+		p.addDelivery(&Delivery{Time: time.Now().UTC(), WebhookID: id, Webhook: name, Events: types, OK: err == nil, Error: errString(err)})
+
+		select {
+		case <-p.stop:
+			return
+		case <-s.quit:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// errString returns the message of err, or "" for nil
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// addDelivery records the outcome of a message
+func (p *DiscordNotifierPlugin) addDelivery(d *Delivery) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deliveries = append(p.deliveries, d)
+	if len(p.deliveries) > maxDeliveries {
+		p.deliveries = p.deliveries[len(p.deliveries)-maxDeliveries:]
+	}
+}
+
+// apply validates a request and copies it onto the webhook
+func (req *WebhookRequest) apply(wh *Webhook) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	url := strings.TrimSpace(req.URL)
+	if url == "" && wh.URL != "" {
+		url = wh.URL
+	}
+	if !validWebhookURL(url) {
+		return fmt.Errorf("url must be a Discord webhook URL")
+	}
+	events := make([]string, 0, len(req.Events))
+	for _, e := range req.Events {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	severity := req.MinSeverity
+	if severity == "" {
+		severity = notify.SeverityInfo
+	}
+	if _, ok := notify.SeverityRank[severity]; !ok {
+		return fmt.Errorf("min_severity must be info, warning or critical")
+	}
+
+	wh.Name = name
+	wh.URL = url
+	wh.Events = events
+	wh.MinSeverity = severity
+	if req.Enabled != nil {
+		wh.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// redacted returns a copy of the webhook with the token part of its URL
+// hidden
+func (wh *Webhook) redacted() Webhook {
+	c := *wh
+	if i := strings.LastIndex(c.URL, "/"); i > 0 {
+		c.URL = c.URL[:i+1] + "…"
+	}
+	return c
+}
+
+// handleStatus reports the event sources and queues
+func (p *DiscordNotifierPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	queues := make(map[string]gin.H, len(p.senders))
+	for id, s := range p.senders {
+		queues[id] = gin.H{"queued": len(s.queue), "dropped": s.dropped}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"log_stream":     p.streamOK,
+		"poll_error":     p.pollErr,
+		"notify_bans":    p.config.NotifyBans,
+		"notify_records": p.config.NotifyRecords,
+		"record":         p.record,
+		"events_enabled": p.config.EventsToken != "",
+		"received":       p.received,
+		"queues":         queues,
+	})
+}
+
+// handleListWebhooks returns all webhooks, with their tokens hidden
+func (p *DiscordNotifierPlugin) handleListWebhooks(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Webhook, 0, len(p.webhooks))
+	for _, wh := range p.webhooks {
+		list = append(list, wh.redacted())
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": list})
+}
+
+// handleCreateWebhook adds a webhook
+func (p *DiscordNotifierPlugin) handleCreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	wh := &Webhook{
+		ID:        newID(),
+		Enabled:   true,
+		CreatedBy: access.Actor(c),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := req.apply(wh); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	p.webhooks = append(p.webhooks, wh)
+	p.dirty = true
+	redacted := wh.redacted()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, redacted)
+}
+
+// handleUpdateWebhook changes a webhook. An empty url keeps the current one.
+func (p *DiscordNotifierPlugin) handleUpdateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	wh := p.webhookByID(c.Param("id"))
+	if wh == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	updated := *wh
+	if err := req.apply(&updated); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	*wh = updated
+	if !wh.Enabled {
+		p.stopSender(wh.ID)
+	}
+	p.dirty = true
+	redacted := wh.redacted()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, redacted)
+}
+
+// handleDeleteWebhook removes a webhook and drops its queue
+func (p *DiscordNotifierPlugin) handleDeleteWebhook(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	found := false
+	for i, wh := range p.webhooks {
+		if wh.ID == id {
+			p.webhooks = append(p.webhooks[:i], p.webhooks[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if found {
+		p.stopSender(id)
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// handleTestWebhook posts a test message to a webhook straight away and
+// reports the result
+func (p *DiscordNotifierPlugin) handleTestWebhook(c *gin.Context) {
+	p.mu.RLock()
+	wh := p.webhookByID(c.Param("id"))
+	var url, name string
+	if wh != nil {
+		url, name = wh.URL, wh.Name
+	}
+	msg := webhookMessage{Username: p.config.BotName, AvatarURL: p.config.AvatarURL}
+	p.mu.RUnlock()
+	if wh == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	msg.Embeds = []embed{newEmbed(notify.Event{
+		Source:    "discord-notifier",
+		Type:      EventTest,
+		Severity:  notify.SeverityInfo,
+		Title:     "Test message",
+		Message:   fmt.Sprintf("Sent by %s from the web panel. Events routed to %s will show up here.", access.Actor(c), name),
+		Timestamp: time.Now().UTC(),
+	})}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+	_, err := postWebhook(ctx, url, msg)
+	p.addDelivery(&Delivery{Time: time.Now().UTC(), WebhookID: wh.ID, Webhook: name, Events: []string{EventTest}, OK: err == nil, Error: errString(err)})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test message sent"})
+}
+
+// handleDeliveries returns recent deliveries, newest first
+func (p *DiscordNotifierPlugin) handleDeliveries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > maxDeliveries {
+		limit = 100
+	}
+
+	p.mu.RLock()
+	list := make([]*Delivery, 0, limit)
+	for i := len(p.deliveries) - 1; i >= 0 && len(list) < limit; i-- {
+		list = append(list, p.deliveries[i])
+	}
+	p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": list})
+}
+
+// handleEvents accepts an alert from another plugin or script, sent with
+// the events token in an X-Events-Token header
+func (p *DiscordNotifierPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+
+	p.dispatch(ev)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *DiscordNotifierPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *DiscordNotifierPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.RatePerMinute < 1 || newConfig.RatePerMinute > 30 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate_per_minute must be between 1 and 30"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *DiscordNotifierPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *DiscordNotifierPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,503 @@
+// DNS Health Plugin for UnrealIRCd Web Panel
+// Checks the network's round-robin hostnames against the linked servers and
+// flags records that point at unknown or unreachable addresses
+
+package dnshealth
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Record states
+const (
+	StateOK    = "ok"
+	StateStale = "stale"
+	StateDead  = "dead"
+)
+
+// DNSHealthPlugin implements the Plugin interface
+type DNSHealthPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	report    *Report
+	flagged   map[string]string
+	lastError string
+	checkNow  chan struct{}
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	Hostnames     string `json:"hostnames"`
+	Ports         string `json:"ports"`
+	Exclude       string `json:"exclude_servers"`
+	CheckInterval int    `json:"check_interval"`
+	Timeout       int    `json:"timeout"`
+	AlertWebhook  string `json:"alert_webhook"`
+}
+
+// PortCheck is the result of a connection attempt to one port
+type PortCheck struct {
+	Port      int    `json:"port"`
+	Open      bool   `json:"open"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Record is an address returned for a round-robin hostname
+type Record struct {
+	Address string      `json:"address"`
+	Type    string      `json:"type"`
+	Server  string      `json:"server"`
+	State   string      `json:"state"`
+	Reasons []string    `json:"reasons"`
+	Ports   []PortCheck `json:"ports"`
+}
+
+// Pool is the result of checking one round-robin hostname
+type Pool struct {
+	Hostname string   `json:"hostname"`
+	Error    string   `json:"error,omitempty"`
+	Records  []Record `json:"records"`
+	Missing  []string `json:"missing_servers"`
+}
+
+// Report is the result of a full check
+type Report struct {
+	Pools     []Pool    `json:"pools"`
+	Stale     int       `json:"stale"`
+	Dead      int       `json:"dead"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name     string `json:"name"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	Server   struct {
+		ULined bool `json:"ulined"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &DNSHealthPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			Ports:         "6667, 6697",
+			CheckInterval: 300,
+			Timeout:       5,
+		},
+		flagged:  make(map[string]string),
+		checkNow: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *DNSHealthPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "DNS Health",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Round-robin DNS checks against the linked servers",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *DNSHealthPlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "dns-health-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{"pools": 0, "stale": 0, "dead": 0}
+		if p.report != nil {
+			content["pools"] = len(p.report.Pools)
+			content["stale"] = p.report.Stale
+			content["dead"] = p.report.Dead
+		}
+		return plugins.DashboardCard{
+			Title:   "Round-Robin DNS",
+			Icon:    "Globe",
+			Content: content,
+			Order:   36,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.checkLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *DNSHealthPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *DNSHealthPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/dns-health")
+	{
+		plugin.GET("/report", p.handleReport)
+		plugin.POST("/check", p.handleCheck)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *DNSHealthPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// checkLoop checks the round-robin pools until shutdown
+func (p *DNSHealthPlugin) checkLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.check()
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.CheckInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < time.Minute {
+			interval = time.Minute
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.checkNow:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// check resolves every pool, matches the records against the linked
+// servers and tests each address
+func (p *DNSHealthPlugin) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ports := make([]int, 0)
+	for _, s := range lists.Fields(cfg.Ports) {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n < 65536 {
+			ports = append(ports, n)
+		}
+	}
+
+	var list struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := p.client().Call(ctx, "server.list", nil, &list); err != nil {
+		p.mu.Lock()
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		log.Printf("[dns-health] failed to list servers: %v", err)
+		return
+	}
+
+	// Map every address a linked server is known by back to its name
+	owners := make(map[string]string)
+	servers := make([]string, 0, len(list.List))
+	exclude := make(map[string]bool)
+	for _, name := range lists.Fields(cfg.Exclude) {
+		exclude[strings.ToLower(name)] = true
+	}
+	var resolver net.Resolver
+	for _, s := range list.List {
+		if s.Server.ULined {
+			continue
+		}
+		if !exclude[strings.ToLower(s.Name)] {
+			servers = append(servers, s.Name)
+		}
+		if ip := net.ParseIP(s.IP); ip != nil {
+			owners[ip.String()] = s.Name
+		}
+		for _, host := range []string{s.Name, s.Hostname} {
+			if host == "" || net.ParseIP(host) != nil {
+				continue
+			}
+			if addrs, err := resolver.LookupIPAddr(ctx, host); err == nil {
+				for _, a := range addrs {
+					owners[a.IP.String()] = s.Name
+				}
+			}
+		}
+	}
+
+	report := &Report{Pools: make([]Pool, 0), CheckedAt: time.Now().UTC()}
+	for _, hostname := range lists.Fields(cfg.Hostnames) {
+		pool := Pool{Hostname: hostname, Records: make([]Record, 0), Missing: make([]string, 0)}
+		addrs, err := resolver.LookupIPAddr(ctx, hostname)
+		if err != nil {
+			pool.Error = err.Error()
+			report.Pools = append(report.Pools, pool)
+			continue
+		}
+
+		inPool := make(map[string]bool)
+		var wg sync.WaitGroup
+		pool.Records = make([]Record, len(addrs))
+		for i, a := range addrs {
+			rec := Record{Address: a.IP.String(), Type: "A", Server: owners[a.IP.String()], State: StateOK, Reasons: make([]string, 0)}
+			if a.IP.To4() == nil {
+				rec.Type = "AAAA"
+			}
+			if rec.Server != "" {
+				inPool[rec.Server] = true
+			}
+			pool.Records[i] = rec
+
+			wg.Add(1)
+			go func(rec *Record) {
+				defer wg.Done()
+				rec.Ports = checkPorts(ctx, rec.Address, ports, timeout)
+			}(&pool.Records[i])
+		}
+		wg.Wait()
+
+		for i := range pool.Records {
+			rec := &pool.Records[i]
+			if rec.Server == "" {
+				rec.State = StateStale
+				rec.Reasons = append(rec.Reasons, "address does not belong to any linked server")
+			}
+			open := 0
+			for _, pc := range rec.Ports {
+				if pc.Open {
+					open++
+				} else {
+					rec.Reasons = append(rec.Reasons, fmt.Sprintf("port %d: %s", pc.Port, pc.Error))
+				}
+			}
+			if len(rec.Ports) > 0 && open == 0 {
+				rec.State = StateDead
+			}
+			switch rec.State {
+			case StateStale:
+				report.Stale++
+			case StateDead:
+				report.Dead++
+			}
+		}
+		sort.Slice(pool.Records, func(i, j int) bool { return pool.Records[i].Address < pool.Records[j].Address })
+
+		for _, name := range servers {
+			if !inPool[name] {
+				pool.Missing = append(pool.Missing, name)
+			}
+		}
+		sort.Strings(pool.Missing)
+		report.Pools = append(report.Pools, pool)
+	}
+
+	p.mu.Lock()
+	p.report = report
+	p.lastError = ""
+
+	// Alert once for each record that becomes stale or dead
+	alerts := make([]notify.Event, 0)
+	flagged := make(map[string]string)
+	for _, pool := range report.Pools {
+		for _, rec := range pool.Records {
+			if rec.State == StateOK {
+				continue
+			}
+			key := pool.Hostname + " " + rec.Address
+			flagged[key] = rec.State
+			if p.flagged[key] == rec.State {
+				continue
+			}
+			alerts = append(alerts, notify.Event{
+				Type:     "dns_record_" + rec.State,
+				Severity: "warning",
+				Title:    "Round-robin record " + rec.State,
+				Message:  fmt.Sprintf("%s %s %s: %s", pool.Hostname, rec.Type, rec.Address, strings.Join(rec.Reasons, "; ")),
+				Data: map[string]interface{}{
+					"hostname": pool.Hostname,
+					"address":  rec.Address,
+					"server":   rec.Server,
+					"reasons":  rec.Reasons,
+				},
+			})
+		}
+	}
+	p.flagged = flagged
+	webhook := cfg.AlertWebhook
+	p.mu.Unlock()
+
+	for _, a := range alerts {
+		log.Printf("[dns-health] %s: %s", a.Title, a.Message)
+		if webhook != "" {
+			a.Source = "dns-health"
+			a.Timestamp = report.CheckedAt
+			go p.alert(webhook, a)
+		}
+	}
+}
+
+// checkPorts tries to connect to each port of an address
+func checkPorts(ctx context.Context, addr string, ports []int, timeout time.Duration) []PortCheck {
+	checks := make([]PortCheck, 0, len(ports))
+	for _, port := range ports {
+		pc := PortCheck{Port: port}
+		if err := grants.Allow(grants.Outbound); err != nil {
+			pc.Error = err.Error()
+			checks = append(checks, pc)
+			continue
+		}
+		d := net.Dialer{Timeout: timeout}
+		start := time.Now()
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
+		if err != nil {
+			pc.Error = err.Error()
+		} else {
+			pc.Open = true
+			pc.LatencyMS = time.Since(start).Milliseconds()
+			conn.Close()
+		}
+		checks = append(checks, pc)
+	}
+	return checks
+}
+
+// alert delivers an alert to the webhook
+func (p *DNSHealthPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[dns-health] failed to send alert: %v", err)
+	}
+}
+
+// handleReport returns the latest check result
+func (p *DNSHealthPlugin) handleReport(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.report == nil {
+		msg := "No check has completed yet"
+		if p.lastError != "" {
+			msg = p.lastError
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": msg})
+		return
+	}
+	c.JSON(http.StatusOK, p.report)
+}
+
+// handleCheck schedules an immediate check
+func (p *DNSHealthPlugin) handleCheck(c *gin.Context) {
+	select {
+	case p.checkNow <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check scheduled"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *DNSHealthPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *DNSHealthPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *DNSHealthPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *DNSHealthPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,203 @@
+/**
+ * Email Digest Frontend Script
+ *
+ * Shows what the next digest holds, previews it and sends test mails.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'email-digest';
+  const PLUGIN_NAME = 'Email Digest';
+  const PAGE_PATH = '/plugins/email-digest';
+  const API_BASE = '/api/plugin/email-digest';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(value) {
+    if (!value || value.startsWith('0001-')) return 'never';
+    return new Date(value).toLocaleString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('email-digest-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'email-digest-styles';
+    style.textContent = `
+      .ed-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .ed-actions { display: flex; flex-wrap: wrap; gap: 0.5rem; }
+      .ed-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .ed-stats { display: grid; grid-template-columns: repeat(auto-fit, minmax(140px, 1fr)); gap: 0.75rem; }
+      .ed-stat {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        padding: 0.75rem;
+      }
+      .ed-stat strong { display: block; font-size: 1.4rem; color: var(--text-primary, #cdd6f4); }
+      .ed-preview { width: 100%; min-height: 600px; border: 1px solid var(--border-primary, #313244); border-radius: 8px; background: #fff; }
+      .ed-warning { color: var(--warning, #f9e2af); }
+      .ed-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#ed-status');
+    try {
+      const s = await api('GET', '/status');
+      body.innerHTML = `
+        <p>
+          ${s.configured ? '' : '<span class="ed-warning">Set smtp_host and mail_from before anything can be mailed.</span>'}
+          ${s.log_stream ? '' : '<span class="ed-warning">Not connected to the IRCd log stream; connections and bans are not being counted.</span>'}
+          ${s.poll_error ? `<span class="ed-warning">Could not read stats from the IRCd: ${escapeHtml(s.poll_error)}</span>` : ''}
+          ${s.alert_error ? `<span class="ed-error">Last alert failed: ${escapeHtml(s.alert_error)}</span>` : ''}
+          ${s.digest_error ? `<span class="ed-error">Last digest failed: ${escapeHtml(s.digest_error)}</span>` : ''}
+        </p>
+        <p>
+          Digest: <strong>${escapeHtml(s.schedule)}</strong>, last sent ${escapeHtml(formatTime(s.last_digest))}.
+          Last alert ${escapeHtml(formatTime(s.last_alert))}${s.alerts_muted ? `, ${s.alerts_muted} held back by the hourly limit` : ''}.
+        </p>
+        <div class="ed-stats">
+          <div class="ed-stat"><strong>${s.period.connects}</strong>Connections</div>
+          <div class="ed-stat"><strong>${s.period.bans}</strong>New bans</div>
+          <div class="ed-stat"><strong>${s.period.incidents}</strong>Incidents</div>
+          <div class="ed-stat"><strong>${escapeHtml(formatTime(s.period.since))}</strong>Counting since</div>
+        </div>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="ed-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadPreview(container) {
+    const frame = container.querySelector('#ed-preview');
+    try {
+      const res = await fetch(API_BASE + '/digest/preview', { headers: getAuthHeaders() });
+      if (!res.ok) {
+        const data = await res.json().catch(() => ({}));
+        throw new Error(data.error || `Request failed with status ${res.status}`);
+      }
+      frame.srcdoc = await res.text();
+    } catch (e) {
+      frame.srcdoc = `<p style="font-family:sans-serif;color:#d20f39;">${escapeHtml(e.message)}</p>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="ed-app" data-plugin="${PLUGIN_ID}">
+        <div id="ed-status">Loading...</div>
+        <div class="ed-actions">
+          <button id="ed-test">Send test mail</button>
+          <button id="ed-send">Send digest now</button>
+          <button id="ed-refresh">Refresh preview</button>
+        </div>
+        <h3>Next digest</h3>
+        <iframe class="ed-preview" id="ed-preview" sandbox="" title="Digest preview"></iframe>
+      </div>
+    `;
+
+    const run = async (btn, path, question) => {
+      if (question && !confirm(question)) return;
+      btn.disabled = true;
+      try {
+        const res = await api('POST', path);
+        alert(res.message);
+      } catch (err) {
+        alert(err.message);
+      }
+      btn.disabled = false;
+      loadStatus(container);
+      loadPreview(container);
+    };
+    container.querySelector('#ed-test').addEventListener('click', (e) => run(e.target, '/test'));
+    container.querySelector('#ed-send').addEventListener('click', (e) => run(e.target, '/digest', 'Send the digest now? Counting starts over afterwards.'));
+    container.querySelector('#ed-refresh').addEventListener('click', () => loadPreview(container));
+
+    loadStatus(container);
+    loadPreview(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('email-digest-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,369 @@
+package emaildigest
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Limits on what a period remembers
+const (
+	maxBans      = 200
+	maxIncidents = 100
+)
+
+// Period collects what happens on the network between two digests
+type Period struct {
+	Since      time.Time      `json:"since"`
+	Connects   int            `json:"connects"`
+	Samples    int            `json:"samples"`
+	UserSum    int            `json:"user_sum"`
+	PeakUsers  int            `json:"peak_users"`
+	PeakAt     time.Time      `json:"peak_at"`
+	LowUsers   int            `json:"low_users"`
+	BanCount   int            `json:"ban_count"`
+	BanTypes   map[string]int `json:"ban_types"`
+	Bans       []BanEntry     `json:"bans"`
+	Incidents  []Incident     `json:"incidents"`
+	LastDigest time.Time      `json:"last_digest"`
+}
+
+// BanEntry is a server ban added during the period
+type BanEntry struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Mask     string    `json:"mask"`
+	SetBy    string    `json:"set_by"`
+	Duration string    `json:"duration"`
+	Reason   string    `json:"reason"`
+}
+
+// Incident is an event at or above incident_severity
+type Incident struct {
+	Time     time.Time `json:"time"`
+	Severity string    `json:"severity"`
+	Source   string    `json:"source"`
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+}
+
+// statsResult is the part of stats.get we sample
+type statsResult struct {
+	User struct {
+		Total  int `json:"total"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+	Server struct {
+		Total int `json:"total"`
+	} `json:"server"`
+	ServerBan struct {
+		Total int `json:"total"`
+	} `json:"server_ban"`
+}
+
+// newPeriod starts a period now
+func newPeriod(lastDigest time.Time) *Period {
+	return &Period{
+		Since:      time.Now().UTC(),
+		BanTypes:   make(map[string]int),
+		Bans:       make([]BanEntry, 0),
+		Incidents:  make([]Incident, 0),
+		LastDigest: lastDigest,
+	}
+}
+
+// addSample records a user count
+func (p *Period) addSample(users int) {
+	p.Samples++
+	p.UserSum += users
+	if users > p.PeakUsers {
+		p.PeakUsers = users
+		p.PeakAt = time.Now().UTC()
+	}
+	if p.Samples == 1 || users < p.LowUsers {
+		p.LowUsers = users
+	}
+}
+
+// addBan records a ban event. The first maxBans are listed, all are
+// counted.
+func (p *Period) addBan(ev notify.Event) {
+	str := func(k string) string {
+		s, _ := ev.Data[k].(string)
+		return s
+	}
+	p.BanCount++
+	p.BanTypes[str("type")]++
+	if len(p.Bans) < maxBans {
+		p.Bans = append(p.Bans, BanEntry{
+			Time:     ev.Timestamp,
+			Type:     str("type"),
+			Mask:     str("mask"),
+			SetBy:    str("set_by"),
+			Duration: str("duration"),
+			Reason:   str("reason"),
+		})
+	}
+}
+
+// addIncident records an event, keeping the newest maxIncidents
+func (p *Period) addIncident(ev notify.Event) {
+	p.Incidents = append(p.Incidents, Incident{
+		Time:     ev.Timestamp,
+		Severity: ev.Severity,
+		Source:   ev.Source,
+		Type:     ev.Type,
+		Title:    ev.Title,
+		Message:  ev.Message,
+	})
+	if len(p.Incidents) > maxIncidents {
+		p.Incidents = p.Incidents[len(p.Incidents)-maxIncidents:]
+	}
+}
+
+// clone copies the period so it can be rendered without holding the lock
+func (p *Period) clone() *Period {
+	c := *p
+	c.BanTypes = make(map[string]int, len(p.BanTypes))
+	for t, n := range p.BanTypes {
+		c.BanTypes[t] = n
+	}
+	c.Bans = append([]BanEntry(nil), p.Bans...)
+	c.Incidents = append([]Incident(nil), p.Incidents...)
+	return &c
+}
+
+// digestView is what the digest templates render
+type digestView struct {
+	Network   string
+	Kind      string
+	Since     time.Time
+	Until     time.Time
+	Period    *Period
+	AvgUsers  int
+	Stats     *statsResult
+	StatsErr  string
+	BanTypes  []typeCount
+	MoreBans  int
+	Incidents []Incident
+}
+
+// typeCount is a ban type and how often it was set
+type typeCount struct {
+	Type  string
+	Count int
+}
+
+// newDigestView prepares a period for the templates
+func newDigestView(network, kind string, p *Period, stats *statsResult, statsErr error) digestView {
+	v := digestView{
+		Network:   network,
+		Kind:      kind,
+		Since:     p.Since,
+		Until:     time.Now().UTC(),
+		Period:    p,
+		Stats:     stats,
+		MoreBans:  p.BanCount - len(p.Bans),
+		Incidents: make([]Incident, len(p.Incidents)),
+	}
+	if p.Samples > 0 {
+		v.AvgUsers = p.UserSum / p.Samples
+	}
+	if statsErr != nil {
+		v.Stats = nil
+		v.StatsErr = statsErr.Error()
+	}
+	for t, n := range p.BanTypes {
+		v.BanTypes = append(v.BanTypes, typeCount{Type: t, Count: n})
+	}
+	sort.Slice(v.BanTypes, func(i, j int) bool {
+		if v.BanTypes[i].Count != v.BanTypes[j].Count {
+			return v.BanTypes[i].Count > v.BanTypes[j].Count
+		}
+		return v.BanTypes[i].Type < v.BanTypes[j].Type
+	})
+	// Newest incidents first
+	for i, inc := range p.Incidents {
+		v.Incidents[len(p.Incidents)-1-i] = inc
+	}
+	return v
+}
+
+// templateFuncs are shared by the text and HTML templates
+var templateFuncs = map[string]interface{}{
+	"time":  func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+	"upper": strings.ToUpper,
+}
+
+// digestText is the plain text part of a digest
+var digestText = template.Must(template.New("digest").Funcs(templateFuncs).Parse(`{{.Network}} {{.Kind}} digest
+{{time .Since}} to {{time .Until}}
+
+CONNECTIONS
+{{- if .Stats}}
+Users online now: {{.Stats.User.Total}} (record {{.Stats.User.Record}})
+Channels: {{.Stats.Channel.Total}}, servers: {{.Stats.Server.Total}}, server bans: {{.Stats.ServerBan.Total}}
+{{- else}}
+Current numbers unavailable: {{.StatsErr}}
+{{- end}}
+Connections: {{.Period.Connects}}
+{{- if .Period.Samples}}
+Users: peak {{.Period.PeakUsers}} at {{time .Period.PeakAt}}, average {{.AvgUsers}}, low {{.Period.LowUsers}}
+{{- end}}
+
+NEW BANS ({{.Period.BanCount}})
+{{- range .BanTypes}}
+{{.Count}} {{.Type}}
+{{- end}}
+{{range .Period.Bans}}
+{{time .Time}} {{.Type}} {{.Mask}} by {{.SetBy}}, {{.Duration}}: {{.Reason}}
+{{- end}}
+{{- if .MoreBans}}
+... and {{.MoreBans}} more
+{{- end}}
+
+INCIDENTS ({{len .Incidents}})
+{{- range .Incidents}}
+{{time .Time}} [{{upper .Severity}}] {{.Title}}{{if .Source}} ({{.Source}}){{end}}
+{{- if .Message}}
+    {{.Message}}
+{{- end}}
+{{- else}}
+None
+{{- end}}
+`))
+
+// digestHTML is the HTML part of a digest. Mail clients ignore style
+// sheets, so styles are inline.
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
+<html><body style="margin:0;padding:16px;background:#f4f4f7;font-family:Arial,Helvetica,sans-serif;color:#1e1e2e;">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" style="max-width:720px;margin:0 auto;background:#ffffff;border-radius:8px;">
+<tr><td style="padding:20px 24px;border-bottom:1px solid #e4e4ea;">
+  <h1 style="margin:0;font-size:20px;">{{.Network}} {{.Kind}} digest</h1>
+  <div style="color:#6c7086;font-size:13px;">{{time .Since}} to {{time .Until}}</div>
+</td></tr>
+<tr><td style="padding:16px 24px;">
+  <h2 style="font-size:16px;margin:0 0 8px;">Connections</h2>
+  <table role="presentation" cellpadding="4" cellspacing="0" style="font-size:14px;">
+  {{- if .Stats}}
+    <tr><td>Users online now</td><td><b>{{.Stats.User.Total}}</b> (record {{.Stats.User.Record}})</td></tr>
+    <tr><td>Channels</td><td>{{.Stats.Channel.Total}}</td></tr>
+    <tr><td>Servers</td><td>{{.Stats.Server.Total}}</td></tr>
+    <tr><td>Server bans</td><td>{{.Stats.ServerBan.Total}}</td></tr>
+  {{- else}}
+    <tr><td colspan="2" style="color:#d20f39;">Current numbers unavailable: {{.StatsErr}}</td></tr>
+  {{- end}}
+    <tr><td>Connections</td><td>{{.Period.Connects}}</td></tr>
+  {{- if .Period.Samples}}
+    <tr><td>Peak users</td><td>{{.Period.PeakUsers}} at {{time .Period.PeakAt}}</td></tr>
+    <tr><td>Average users</td><td>{{.AvgUsers}}</td></tr>
+    <tr><td>Lowest users</td><td>{{.Period.LowUsers}}</td></tr>
+  {{- end}}
+  </table>
+</td></tr>
+<tr><td style="padding:16px 24px;">
+  <h2 style="font-size:16px;margin:0 0 8px;">New bans ({{.Period.BanCount}})</h2>
+  {{- if .BanTypes}}
+  <p style="font-size:14px;margin:0 0 8px;">{{range $i, $t := .BanTypes}}{{if $i}}, {{end}}{{$t.Count}} {{$t.Type}}{{end}}</p>
+  <table width="100%" cellpadding="4" cellspacing="0" style="font-size:13px;border-collapse:collapse;">
+    <tr style="background:#eff1f5;text-align:left;"><th>Time</th><th>Type</th><th>Mask</th><th>Set by</th><th>Duration</th><th>Reason</th></tr>
+    {{- range .Period.Bans}}
+    <tr style="border-bottom:1px solid #e4e4ea;"><td>{{time .Time}}</td><td>{{.Type}}</td><td><code>{{.Mask}}</code></td><td>{{.SetBy}}</td><td>{{.Duration}}</td><td>{{.Reason}}</td></tr>
+    {{- end}}
+  </table>
+  {{- if .MoreBans}}<p style="font-size:13px;color:#6c7086;">… and {{.MoreBans}} more</p>{{end}}
+  {{- else}}
+  <p style="font-size:14px;margin:0;">None</p>
+  {{- end}}
+</td></tr>
+<tr><td style="padding:16px 24px;">
+  <h2 style="font-size:16px;margin:0 0 8px;">Incidents ({{len .Incidents}})</h2>
+  {{- range .Incidents}}
+  <div style="margin:0 0 10px;padding:8px 12px;border-left:4px solid {{if eq .Severity "critical"}}#d20f39{{else if eq .Severity "warning"}}#df8e1d{{else}}#1e66f5{{end}};background:#f8f8fb;font-size:14px;">
+    <div><b>{{.Title}}</b> <span style="color:#6c7086;font-size:12px;">{{time .Time}}{{if .Source}} · {{.Source}}{{end}}</span></div>
+    {{- if .Message}}<div>{{.Message}}</div>{{end}}
+  </div>
+  {{- else}}
+  <p style="font-size:14px;margin:0;">None</p>
+  {{- end}}
+</td></tr>
+</table>
+</body></html>
+`))
+
+// renderDigest renders both parts of a digest
+func renderDigest(v digestView) (string, string, error) {
+	var text, html bytes.Buffer
+	if err := digestText.Execute(&text, v); err != nil {
+		return "", "", err
+	}
+	if err := digestHTML.Execute(&html, v); err != nil {
+		return "", "", err
+	}
+	return text.String(), html.String(), nil
+}
+
+// alertView is what the alert templates render
+type alertView struct {
+	Network string
+	Events  []notify.Event
+	Muted   int
+}
+
+// alertText is the plain text part of an alert
+var alertText = template.Must(template.New("alert").Funcs(templateFuncs).Parse(`{{range .Events}}[{{upper .Severity}}] {{.Title}}
+{{time .Timestamp}}{{if .Source}} · {{.Source}}{{end}}
+{{- if .Message}}
+{{.Message}}
+{{- end}}
+{{- range $k, $v := .Data}}
+{{$k}}: {{$v}}
+{{- end}}
+
+{{end}}
+{{- if .Muted}}{{.Muted}} more alerts were not mailed because the hourly limit was reached. They are listed in the next digest.
+{{end}}`))
+
+// alertHTML is the HTML part of an alert
+var alertHTML = htmltemplate.Must(htmltemplate.New("alert").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
+<html><body style="margin:0;padding:16px;background:#f4f4f7;font-family:Arial,Helvetica,sans-serif;color:#1e1e2e;">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" style="max-width:720px;margin:0 auto;background:#ffffff;border-radius:8px;">
+{{- range .Events}}
+<tr><td style="padding:16px 24px;border-left:4px solid {{if eq .Severity "critical"}}#d20f39{{else if eq .Severity "warning"}}#df8e1d{{else}}#1e66f5{{end}};border-bottom:1px solid #e4e4ea;">
+  <h2 style="margin:0;font-size:17px;">{{.Title}}</h2>
+  <div style="color:#6c7086;font-size:12px;">{{upper .Severity}} · {{time .Timestamp}}{{if .Source}} · {{.Source}}{{end}}</div>
+  {{- if .Message}}<p style="font-size:14px;">{{.Message}}</p>{{end}}
+  {{- if .Data}}
+  <table cellpadding="3" cellspacing="0" style="font-size:13px;">
+    {{- range $k, $v := .Data}}<tr><td style="color:#6c7086;">{{$k}}</td><td>{{$v}}</td></tr>{{end}}
+  </table>
+  {{- end}}
+</td></tr>
+{{- end}}
+{{- if .Muted}}
+<tr><td style="padding:12px 24px;font-size:13px;color:#6c7086;">{{.Muted}} more alerts were not mailed because the hourly limit was reached. They are listed in the next digest.</td></tr>
+{{- end}}
+</table>
+</body></html>
+`))
+
+// renderAlert renders both parts of an alert mail
+func renderAlert(v alertView) (string, string, error) {
+	var text, html bytes.Buffer
+	if err := alertText.Execute(&text, v); err != nil {
+		return "", "", err
+	}
+	if err := alertHTML.Execute(&html, v); err != nil {
+		return "", "", err
+	}
+	return text.String(), html.String(), nil
+}
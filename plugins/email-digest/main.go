@@ -0,0 +1,758 @@
+// Email Digest Plugin for UnrealIRCd Web Panel
+// Mails immediate alerts for serious events and a daily or weekly HTML
+// digest of connections, new bans and incidents over SMTP
+
+package emaildigest
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Digest schedules
+const (
+	ScheduleDaily  = "daily"
+	ScheduleWeekly = "weekly"
+	ScheduleOff    = "off"
+)
+
+// Limits and intervals
+const (
+	alertQueueSize  = 200
+	maxAlertsInMail = 50
+	sampleEvery     = 5 // minutes between user count samples
+	digestRetry     = 10 * time.Minute
+)
+
+// EmailDigestPlugin implements the Plugin interface
+type EmailDigestPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	period       *Period
+	alerts       chan notify.Event
+	alertTimes   []time.Time
+	muted        int
+	lastAlert    time.Time
+	alertErr     string
+	digestTried  time.Time
+	digestErr    string
+	pollErr      string
+	dirty        bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL           string `json:"rpc_url"`
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	StreamURL        string `json:"stream_url"`
+	LogSources       string `json:"log_sources"`
+	DataDir          string `json:"data_dir"`
+	NetworkName      string `json:"network_name"`
+	SMTPHost         string `json:"smtp_host"`
+	SMTPPort         int    `json:"smtp_port"`
+	SMTPSecurity     string `json:"smtp_security"`
+	SMTPUser         string `json:"smtp_user"`
+	SMTPPassword     string `json:"smtp_password"`
+	MailFrom         string `json:"mail_from"`
+	AlertTo          string `json:"alert_to"`
+	AlertSeverity    string `json:"alert_severity"`
+	AlertDelay       int    `json:"alert_delay"`
+	MaxAlertsPerHour int    `json:"max_alerts_per_hour"`
+	IncidentSeverity string `json:"incident_severity"`
+	DigestTo         string `json:"digest_to"`
+	DigestSchedule   string `json:"digest_schedule"`
+	DigestHour       int    `json:"digest_hour"`
+	DigestWeekday    string `json:"digest_weekday"`
+	EventsToken      string `json:"events_token"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Period *Period `json:"period"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &EmailDigestPlugin{
+		config: Config{
+			RPCURL:           "https://127.0.0.1:8600/api",
+			StreamURL:        "wss://127.0.0.1:8600/",
+			LogSources:       "connect,tkl",
+			DataDir:          "data/plugins/email-digest",
+			NetworkName:      "IRC network",
+			SMTPPort:         587,
+			SMTPSecurity:     SecurityStartTLS,
+			AlertSeverity:    notify.SeverityCritical,
+			AlertDelay:       60,
+			MaxAlertsPerHour: 10,
+			IncidentSeverity: notify.SeverityWarning,
+			DigestSchedule:   ScheduleDaily,
+			DigestHour:       8,
+			DigestWeekday:    "monday",
+		},
+		period: newPeriod(time.Time{}),
+		alerts: make(chan notify.Event, alertQueueSize),
+	}
+}
+
+// Info returns plugin metadata
+func (p *EmailDigestPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Email Digest",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Mails alerts and a daily or weekly network digest",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *EmailDigestPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[email-digest] failed to load data: %v", err)
+	}
+	if data.Period != nil && data.Period.BanTypes != nil {
+		p.period = data.Period
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "email-digest-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		return plugins.DashboardCard{
+			Title: "Email Digest",
+			Icon:  "Mail",
+			Content: map[string]interface{}{
+				"schedule":  p.config.DigestSchedule,
+				"bans":      p.period.BanCount,
+				"incidents": len(p.period.Incidents),
+				"connects":  p.period.Connects,
+			},
+			Order: 64,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(3)
+	go p.streamLoop()
+	go p.alertLoop()
+	go p.digestLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *EmailDigestPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *EmailDigestPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/email-digest")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/test", p.handleTest)
+		plugin.GET("/digest/preview", p.handlePreview)
+		plugin.POST("/digest", p.handleSendDigest)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *EmailDigestPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "email-digest.json")
+}
+
+// save persists the state if it changed
+func (p *EmailDigestPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Period: p.period}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *EmailDigestPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// mailer returns a mailer for the current SMTP settings. Caller must hold
+// p.mu.
+func (p *EmailDigestPlugin) mailer() *mailer {
+	return &mailer{
+		host:     p.config.SMTPHost,
+		port:     p.config.SMTPPort,
+		security: p.config.SMTPSecurity,
+		user:     p.config.SMTPUser,
+		password: p.config.SMTPPassword,
+		from:     p.config.MailFrom,
+	}
+}
+
+// digestRecipients returns who receives the digest, falling back to the
+// alert recipients. Caller must hold p.mu.
+func (p *EmailDigestPlugin) digestRecipients() []string {
+	list := p.config.DigestTo
+	if strings.TrimSpace(list) == "" {
+		list = p.config.AlertTo
+	}
+	// Both lists were validated when the config was saved
+	to, _ := parseAddresses(list)
+	return to
+}
+
+// streamLoop follows connect and ban log events until shutdown
+func (p *EmailDigestPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *EmailDigestPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[email-digest] log stream: %v", err)
+	}
+}
+
+// handleEvent counts connections and new bans for the digest
+func (p *EmailDigestPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	if strings.HasSuffix(ev.EventID, "_CLIENT_CONNECT") {
+		p.mu.Lock()
+		p.period.Connects++
+		p.dirty = true
+		p.mu.Unlock()
+		return
+	}
+	if a, ok := notify.BanEvent("email-digest", ev, nil); ok {
+		p.mu.Lock()
+		p.period.addBan(a)
+		p.dirty = true
+		p.mu.Unlock()
+		p.dispatch(a)
+	}
+}
+
+// dispatch records an event as an incident and queues it for an
+// immediate alert, each if it is serious enough. Alerts arriving while the
+// queue is full are only counted.
+func (p *EmailDigestPlugin) dispatch(ev notify.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if notify.SeverityRank[ev.Severity] >= notify.SeverityRank[p.config.IncidentSeverity] {
+		p.period.addIncident(ev)
+		p.dirty = true
+	}
+	if p.config.AlertTo == "" || notify.SeverityRank[ev.Severity] < notify.SeverityRank[p.config.AlertSeverity] {
+		return
+	}
+	select {
+	case p.alerts <- ev:
+	default:
+		p.muted++
+	}
+}
+
+// alertLoop mails alerts until shutdown. Alerts arriving within
+// alert_delay seconds of the first are mailed together, and no more than
+// max_alerts_per_hour mails are sent in an hour.
+func (p *EmailDigestPlugin) alertLoop() {
+	defer p.wg.Done()
+
+	for {
+		var first notify.Event
+		select {
+		case <-p.stop:
+			return
+		case first = <-p.alerts:
+		}
+
+		p.mu.RLock()
+		delay := time.Duration(p.config.AlertDelay) * time.Second
+		p.mu.RUnlock()
+
+		batch := []notify.Event{first}
+		timer := time.NewTimer(delay)
+	collect:
+		for {
+			select {
+			case <-p.stop:
+				timer.Stop()
+				return
+			case ev := <-p.alerts:
+				if len(batch) < maxAlertsInMail {
+					batch = append(batch, ev)
+				} else {
+					p.mu.Lock()
+					p.muted++
+					p.mu.Unlock()
+				}
+			case <-timer.C:
+				break collect
+			}
+		}
+
+		p.sendAlerts(batch)
+	}
+}
+
+// sendAlerts mails a batch of alerts, unless the hourly limit is reached
+func (p *EmailDigestPlugin) sendAlerts(batch []notify.Event) {
+	p.mu.Lock()
+	now := time.Now()
+	recent := p.alertTimes[:0]
+	for _, t := range p.alertTimes {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	p.alertTimes = recent
+	if len(p.alertTimes) >= p.config.MaxAlertsPerHour {
+		p.muted += len(batch)
+		p.mu.Unlock()
+		return
+	}
+	p.alertTimes = append(p.alertTimes, now)
+	muted := p.muted
+	p.muted = 0
+	m := p.mailer()
+	to, _ := parseAddresses(p.config.AlertTo)
+	network := p.config.NetworkName
+	p.mu.Unlock()
+
+	subject := fmt.Sprintf("[%s] %s: %s", network, strings.ToUpper(batch[0].Severity), batch[0].Title)
+	if len(batch) > 1 {
+		subject = fmt.Sprintf("[%s] %d alerts", network, len(batch))
+	}
+	text, html, err := renderAlert(alertView{Network: network, Events: batch, Muted: muted})
+	if err == nil {
+		err = m.send(email{To: to, Subject: subject, Text: text, HTML: html})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		log.Printf("[email-digest] failed to mail alerts: %v", err)
+		p.alertErr = err.Error()
+		return
+	}
+	p.alertErr = ""
+	p.lastAlert = now.UTC()
+}
+
+// digestLoop samples the user count and sends the digest when it is due,
+// until shutdown
+func (p *EmailDigestPlugin) digestLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for tick := 0; ; tick++ {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if tick%sampleEvery == 0 {
+			p.sample()
+		}
+		if p.digestDue(time.Now()) {
+			if err := p.sendDigest(); err != nil {
+				log.Printf("[email-digest] failed to send digest: %v", err)
+			}
+		}
+		if err := p.save(); err != nil {
+			log.Printf("[email-digest] failed to save data: %v", err)
+		}
+	}
+}
+
+// sample records the current user count
+func (p *EmailDigestPlugin) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var stats statsResult
+	err := p.client().Call(ctx, "stats.get", nil, &stats)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.pollErr = err.Error()
+		return
+	}
+	p.pollErr = ""
+	p.period.addSample(stats.User.Total)
+	p.dirty = true
+}
+
+// digestDue reports whether the scheduled digest should go out now. A
+// failed digest is tried again every ten minutes within the hour.
+func (p *EmailDigestPlugin) digestDue(now time.Time) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if now.Hour() != p.config.DigestHour || now.Sub(p.digestTried) < digestRetry {
+		return false
+	}
+	last := p.period.LastDigest
+	switch p.config.DigestSchedule {
+	case ScheduleDaily:
+		return now.Sub(last) > 23*time.Hour
+	case ScheduleWeekly:
+		return strings.EqualFold(now.Weekday().String(), p.config.DigestWeekday) && now.Sub(last) > 6*24*time.Hour
+	}
+	return false
+}
+
+// digestKind names the digest after the schedule
+func digestKind(schedule string) string {
+	if schedule == ScheduleWeekly {
+		return "weekly"
+	}
+	return "daily"
+}
+
+// buildDigest renders the digest of the current period
+func (p *EmailDigestPlugin) buildDigest() (email, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var stats statsResult
+	statsErr := p.client().Call(ctx, "stats.get", nil, &stats)
+
+	p.mu.RLock()
+	period := p.period.clone()
+	network := p.config.NetworkName
+	kind := digestKind(p.config.DigestSchedule)
+	to := p.digestRecipients()
+	p.mu.RUnlock()
+
+	text, html, err := renderDigest(newDigestView(network, kind, period, &stats, statsErr))
+	if err != nil {
+		return email{}, err
+	}
+	return email{
+		To:      to,
+		Subject: fmt.Sprintf("[%s] %s%s digest", network, strings.ToUpper(kind[:1]), kind[1:]),
+		Text:    text,
+		HTML:    html,
+	}, nil
+}
+
+// sendDigest mails the digest and starts a new period once it is sent
+func (p *EmailDigestPlugin) sendDigest() error {
+	p.mu.Lock()
+	p.digestTried = time.Now()
+	m := p.mailer()
+	p.mu.Unlock()
+
+	e, err := p.buildDigest()
+	if err == nil {
+		err = m.send(e)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.digestErr = err.Error()
+		return err
+	}
+	p.digestErr = ""
+	p.period = newPeriod(time.Now().UTC())
+	p.dirty = true
+	return nil
+}
+
+// handleStatus reports the event sources, alert and digest state
+func (p *EmailDigestPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"configured":     p.config.SMTPHost != "" && p.config.MailFrom != "",
+		"log_stream":     p.streamOK,
+		"poll_error":     p.pollErr,
+		"events_enabled": p.config.EventsToken != "",
+		"alerts_queued":  len(p.alerts),
+		"alerts_muted":   p.muted,
+		"last_alert":     p.lastAlert,
+		"alert_error":    p.alertErr,
+		"schedule":       p.config.DigestSchedule,
+		"last_digest":    p.period.LastDigest,
+		"digest_error":   p.digestErr,
+		"period": gin.H{
+			"since":     p.period.Since,
+			"connects":  p.period.Connects,
+			"bans":      p.period.BanCount,
+			"incidents": len(p.period.Incidents),
+		},
+	})
+}
+
+// handleTest mails a test message to the alert recipients straight away
+func (p *EmailDigestPlugin) handleTest(c *gin.Context) {
+	p.mu.RLock()
+	m := p.mailer()
+	to, _ := parseAddresses(p.config.AlertTo)
+	if len(to) == 0 {
+		to = p.digestRecipients()
+	}
+	network := p.config.NetworkName
+	p.mu.RUnlock()
+
+	ev := notify.Event{
+		Source:    "email-digest",
+		Type:      EventTest,
+		Severity:  notify.SeverityInfo,
+		Title:     "Test message",
+		Message:   fmt.Sprintf("Sent by %s from the web panel.", access.Actor(c)),
+		Timestamp: time.Now().UTC(),
+	}
+	text, html, err := renderAlert(alertView{Network: network, Events: []notify.Event{ev}})
+	if err == nil {
+		err = m.send(email{To: to, Subject: fmt.Sprintf("[%s] Test message", network), Text: text, HTML: html})
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test message sent to " + strings.Join(to, ", ")})
+}
+
+// handlePreview returns the HTML of the digest so far, without sending it
+func (p *EmailDigestPlugin) handlePreview(c *gin.Context) {
+	e, err := p.buildDigest()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(e.HTML))
+}
+
+// handleSendDigest sends the digest now and starts a new period
+func (p *EmailDigestPlugin) handleSendDigest(c *gin.Context) {
+	if err := p.sendDigest(); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if err := p.save(); err != nil {
+		log.Printf("[email-digest] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Digest sent"})
+}
+
+// handleEvents accepts an alert from another plugin or script, sent with
+// the events token in an X-Events-Token header
+func (p *EmailDigestPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+
+	p.dispatch(ev)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event accepted"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *EmailDigestPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.SMTPPassword = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// validate checks the settings that cannot be fixed up silently
+func (cfg *Config) validate() error {
+	if cfg.SMTPPort < 1 || cfg.SMTPPort > 65535 {
+		return fmt.Errorf("smtp_port must be between 1 and 65535")
+	}
+	switch cfg.SMTPSecurity {
+	case SecurityStartTLS, SecurityTLS, SecurityNone:
+	default:
+		return fmt.Errorf("smtp_security must be starttls, tls or none")
+	}
+	if cfg.MailFrom != "" {
+		if _, err := mail.ParseAddress(cfg.MailFrom); err != nil {
+			return fmt.Errorf("mail_from: %v", err)
+		}
+	}
+	if _, err := parseAddresses(cfg.AlertTo); err != nil {
+		return fmt.Errorf("alert_to: %v", err)
+	}
+	if _, err := parseAddresses(cfg.DigestTo); err != nil {
+		return fmt.Errorf("digest_to: %v", err)
+	}
+	for _, s := range []string{cfg.AlertSeverity, cfg.IncidentSeverity} {
+		if _, ok := notify.SeverityRank[s]; !ok {
+			return fmt.Errorf("severities must be info, warning or critical")
+		}
+	}
+	if cfg.AlertDelay < 0 || cfg.AlertDelay > 3600 {
+		return fmt.Errorf("alert_delay must be between 0 and 3600 seconds")
+	}
+	if cfg.MaxAlertsPerHour < 1 {
+		return fmt.Errorf("max_alerts_per_hour must be at least 1")
+	}
+	switch cfg.DigestSchedule {
+	case ScheduleDaily, ScheduleWeekly, ScheduleOff:
+	default:
+		return fmt.Errorf("digest_schedule must be daily, weekly or off")
+	}
+	if cfg.DigestHour < 0 || cfg.DigestHour > 23 {
+		return fmt.Errorf("digest_hour must be between 0 and 23")
+	}
+	valid := false
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), cfg.DigestWeekday) {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("digest_weekday must be a day of the week")
+	}
+	return nil
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *EmailDigestPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if err := newConfig.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.SMTPPassword == "" {
+		newConfig.SMTPPassword = p.config.SMTPPassword
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *EmailDigestPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *EmailDigestPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,253 @@
+/**
+ * Flood Dashboard Frontend Script
+ *
+ * Draws flood event counters, a timeline per flood type and top offender
+ * lists on the plugin page.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'flood-dashboard';
+  const PLUGIN_NAME = 'Flood Dashboard';
+  const PAGE_PATH = '/plugins/flood-dashboard';
+  const API_BASE = '/api/plugin/flood-dashboard';
+
+  const COLORS = ['#f38ba8', '#fab387', '#f9e2af', '#a6e3a1', '#89b4fa', '#cba6f7', '#94e2d5', '#f5c2e7'];
+
+  let range = '24h';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function typeColor(types, type) {
+    return COLORS[types.indexOf(type) % COLORS.length];
+  }
+
+  // Stacked bar chart with one colour per flood type
+  function timelineChart(timeline, types, width, height) {
+    const n = timeline.buckets.length;
+    const totals = new Array(n).fill(0);
+    types.forEach(t => (timeline.series[t] || []).forEach((v, i) => { totals[i] += v; }));
+    const max = Math.max(...totals, 1);
+    const barWidth = width / n;
+
+    const bars = [];
+    for (let i = 0; i < n; i++) {
+      let y = height;
+      const label = new Date(timeline.buckets[i]).toLocaleString();
+      types.forEach(t => {
+        const v = (timeline.series[t] || [])[i] || 0;
+        if (!v) return;
+        const h = (v / max) * (height - 10);
+        y -= h;
+        bars.push(`
+          <rect x="${(i * barWidth).toFixed(1)}" y="${y.toFixed(1)}" width="${Math.max(barWidth - 1, 1).toFixed(1)}" height="${h.toFixed(1)}" fill="${typeColor(types, t)}">
+            <title>${escapeHtml(label)}: ${v} ${escapeHtml(t)}</title>
+          </rect>
+        `);
+      });
+    }
+
+    return `
+      <svg viewBox="0 0 ${width} ${height}" class="flood-chart" preserveAspectRatio="none">
+        ${bars.join('')}
+      </svg>
+    `;
+  }
+
+  function countTable(title, rows, showNick) {
+    if (!rows.length) {
+      return `<div><h3>${escapeHtml(title)}</h3><div class="flood-empty">Nothing recorded.</div></div>`;
+    }
+    return `
+      <div>
+        <h3>${escapeHtml(title)}</h3>
+        <table class="flood-table">
+          <tbody>
+            ${rows.map(r => `
+              <tr>
+                <td><code>${escapeHtml(r.name)}</code>${showNick && r.nick && r.nick !== r.name ? `<br><small>${escapeHtml(r.nick)}</small>` : ''}</td>
+                <td>${Object.entries(r.types || {}).map(([t, c]) => `${escapeHtml(t)}: ${c}`).join(', ')}</td>
+                <td class="flood-num">${r.count}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      </div>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('flood-dashboard-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'flood-dashboard-styles';
+    style.textContent = `
+      .flood-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .flood-tiles { display: grid; grid-template-columns: repeat(auto-fit, minmax(140px, 1fr)); gap: 0.75rem; }
+      .flood-tile {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-left-width: 4px;
+        border-radius: 8px;
+        padding: 0.75rem;
+      }
+      .flood-tile strong { display: block; font-size: 1.5rem; color: var(--text-primary, #cdd6f4); }
+      .flood-chart { width: 100%; height: 180px; background: var(--bg-secondary, #181825); border-radius: 8px; }
+      .flood-columns { display: grid; grid-template-columns: repeat(auto-fit, minmax(280px, 1fr)); gap: 1rem; }
+      .flood-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .flood-table td { padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .flood-num { text-align: right; color: var(--text-primary, #cdd6f4); font-weight: 600; }
+      .flood-range button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        margin-right: 0.25rem;
+        cursor: pointer;
+      }
+      .flood-range button.active { background: var(--accent, #89b4fa); color: #fff; }
+      .flood-warning { color: var(--warning, #f9e2af); }
+      .flood-error { color: var(--error, #f38ba8); }
+      .flood-empty { color: var(--text-muted, #6c7086); padding: 0.5rem 0; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#flood-body');
+    try {
+      const [summary, timeline, recent] = await Promise.all([
+        api(`/summary?range=${range}`),
+        api(`/timeline?range=${range}`),
+        api('/events?limit=25')
+      ]);
+
+      const types = Object.keys(summary.by_type).sort((a, b) => summary.by_type[b] - summary.by_type[a]);
+      Object.keys(timeline.series).forEach(t => { if (!types.includes(t)) types.push(t); });
+
+      body.innerHTML = `
+        ${summary.streaming ? '' : '<div class="flood-warning">Not connected to the IRCd log stream; new events are not being recorded.</div>'}
+        <div class="flood-tiles">
+          <div class="flood-tile"><strong>${summary.total}</strong>flood events</div>
+          ${types.map(t => `
+            <div class="flood-tile" style="border-left-color: ${typeColor(types, t)}"><strong>${summary.by_type[t] || 0}</strong>${escapeHtml(t)}</div>
+          `).join('')}
+        </div>
+        <h3>Timeline</h3>
+        ${timelineChart(timeline, types, 600, 180)}
+        <div class="flood-columns">
+          ${countTable('Top offenders', summary.offenders, true)}
+          ${countTable('Most flooded targets', summary.targets, false)}
+          ${countTable('By server', summary.servers, false)}
+        </div>
+        <h3>Recent events</h3>
+        <table class="flood-table">
+          <tbody>
+            ${recent.events.map(e => `
+              <tr>
+                <td>${escapeHtml(new Date(e.time).toLocaleString())}</td>
+                <td>${escapeHtml(e.type)}${e.detail ? ` <small>(${escapeHtml(e.detail)})</small>` : ''}</td>
+                <td>${escapeHtml(e.message)}</td>
+              </tr>
+            `).join('') || '<tr><td class="flood-empty">Nothing recorded.</td></tr>'}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="flood-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="flood-app" data-plugin="${PLUGIN_ID}">
+        <div class="flood-range">
+          ${['24h', '7d'].map(r => `<button data-range="${r}" class="${r === range ? 'active' : ''}">${r === '24h' ? 'Last 24 hours' : 'Last 7 days'}</button>`).join('')}
+        </div>
+        <div id="flood-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelectorAll('.flood-range button').forEach(btn => {
+      btn.addEventListener('click', () => {
+        range = btn.dataset.range;
+        container.querySelectorAll('.flood-range button').forEach(b => b.classList.toggle('active', b === btn));
+        load(container);
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('flood-dashboard-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,641 @@
+// Flood Dashboard Plugin for UnrealIRCd Web Panel
+// Collects flood related events from the IRCd log stream into per-type
+// counters, timelines and top offender lists
+
+package flooddashboard
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// FloodDashboardPlugin implements the Plugin interface
+type FloodDashboardPlugin struct {
+	config       Config
+	events       []*FloodEvent
+	dirty        bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	StreamURL     string `json:"stream_url"`
+	LogSources    string `json:"log_sources"`
+	FloodEvents   string `json:"flood_events"`
+	MatchUnknown  bool   `json:"match_unknown"`
+	DataDir       string `json:"data_dir"`
+	RetentionDays int    `json:"retention_days"`
+	MaxEvents     int    `json:"max_events"`
+}
+
+// FloodEvent is a single flood related log event
+type FloodEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	EventID string    `json:"event_id"`
+	Detail  string    `json:"detail,omitempty"`
+	Nick    string    `json:"nick,omitempty"`
+	IP      string    `json:"ip,omitempty"`
+	Target  string    `json:"target,omitempty"`
+	Server  string    `json:"server,omitempty"`
+	Message string    `json:"message"`
+}
+
+// Count is a name with a number of events
+type Count struct {
+	Name  string         `json:"name"`
+	Count int            `json:"count"`
+	Types map[string]int `json:"types,omitempty"`
+	Nick  string         `json:"nick,omitempty"`
+}
+
+// floodEntry is the subset of a flood log entry we need
+type floodEntry struct {
+	LogSource string          `json:"log_source"`
+	FloodType string          `json:"flood_type"`
+	Reason    string          `json:"reason"`
+	Target    json.RawMessage `json:"target"`
+	Channel   *struct {
+		Name string `json:"name"`
+	} `json:"channel"`
+	Client *struct {
+		Name string `json:"name"`
+		IP   string `json:"ip"`
+	} `json:"client"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &FloodDashboardPlugin{
+		config: Config{
+			StreamURL:     "wss://127.0.0.1:8600/",
+			LogSources:    "all,!debug",
+			FloodEvents:   "FLOOD_BLOCKED=target_flood, CONNTHROTTLE_ACTIVATED=connect_flood, CONNTHROTTLE_REJECT=connect_flood, FLOOD_KILL=message_flood",
+			MatchUnknown:  true,
+			DataDir:       "data/plugins/flood-dashboard",
+			RetentionDays: 7,
+			MaxEvents:     100000,
+		},
+		events: make([]*FloodEvent, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *FloodDashboardPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Flood Dashboard",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Flood event counters, timelines and top offenders",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *FloodDashboardPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.events); err != nil {
+		log.Printf("[flood-dashboard] failed to load events: %v", err)
+	}
+	if p.events == nil {
+		p.events = make([]*FloodEvent, 0)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "flood-dashboard-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		byType, total := p.countTypes(time.Now().Add(-24 * time.Hour))
+		top := ""
+		if offenders := p.offenders(time.Now().Add(-24*time.Hour), "", 1); len(offenders) > 0 {
+			top = offenders[0].Name
+		}
+		return plugins.DashboardCard{
+			Title: "Floods (24h)",
+			Icon:  "Waves",
+			Content: map[string]interface{}{
+				"total":        total,
+				"by_type":      byType,
+				"top_offender": top,
+				"streaming":    p.streamOK,
+			},
+			Order: 48,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.maintainLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *FloodDashboardPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *FloodDashboardPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/flood-dashboard")
+	{
+		plugin.GET("/summary", p.handleSummary)
+		plugin.GET("/timeline", p.handleTimeline)
+		plugin.GET("/offenders", p.handleOffenders)
+		plugin.GET("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *FloodDashboardPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "events.json")
+}
+
+// save persists the events if they changed
+func (p *FloodDashboardPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.events); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// streamLoop follows the IRCd log until shutdown
+func (p *FloodDashboardPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *FloodDashboardPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[flood-dashboard] log stream: %v", err)
+	}
+}
+
+// classify returns the flood type of a log event, or "" if it is not flood
+// related. Caller must hold p.mu.
+func (p *FloodDashboardPlugin) classify(ev jsonrpc.LogEvent, entry floodEntry) string {
+	for _, rule := range lists.Split(p.config.FloodEvents) {
+		id, kind, ok := strings.Cut(rule, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(id), ev.EventID) {
+			return strings.TrimSpace(kind)
+		}
+	}
+
+	// Clients disconnected for flooding are logged as ordinary quits
+	if strings.HasSuffix(ev.EventID, "CLIENT_DISCONNECT") {
+		reason := entry.Reason
+		if reason == "" {
+			reason = ev.Msg
+		}
+		if strings.Contains(strings.ToLower(reason), "excess flood") {
+			return "excess_flood"
+		}
+		return ""
+	}
+
+	if p.config.MatchUnknown && (strings.Contains(ev.EventID, "FLOOD") || strings.Contains(ev.EventID, "THROTTLE")) {
+		return strings.ToLower(ev.EventID)
+	}
+	return ""
+}
+
+// handleEvent records flood related log events
+func (p *FloodDashboardPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	var entry floodEntry
+	_ = json.Unmarshal(ev.Raw, &entry)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	kind := p.classify(ev, entry)
+	if kind == "" {
+		return
+	}
+
+	fe := &FloodEvent{
+		Time:    time.Now().UTC(),
+		Type:    kind,
+		EventID: ev.EventID,
+		Detail:  entry.FloodType,
+		Server:  entry.LogSource,
+		Message: ev.Msg,
+	}
+	if t, err := time.Parse(time.RFC3339, ev.Timestamp); err == nil {
+		fe.Time = t.UTC()
+	}
+	if entry.Client != nil {
+		fe.Nick = entry.Client.Name
+		fe.IP = entry.Client.IP
+	}
+	fe.Target = targetName(entry)
+
+	p.events = append(p.events, fe)
+	if max := p.config.MaxEvents; max > 0 && len(p.events) > max {
+		p.events = append([]*FloodEvent(nil), p.events[len(p.events)-max:]...)
+	}
+	p.dirty = true
+}
+
+// targetName extracts the flooded user or channel, which depending on the
+// event is a string or an object
+func targetName(entry floodEntry) string {
+	if len(entry.Target) > 0 {
+		var s string
+		if json.Unmarshal(entry.Target, &s) == nil {
+			return s
+		}
+		var obj struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(entry.Target, &obj) == nil && obj.Name != "" {
+			return obj.Name
+		}
+	}
+	if entry.Channel != nil {
+		return entry.Channel.Name
+	}
+	return ""
+}
+
+// maintainLoop drops expired events and saves until shutdown
+func (p *FloodDashboardPlugin) maintainLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		days := p.config.RetentionDays
+		if days < 7 {
+			days = 7
+		}
+		cutoff := time.Now().AddDate(0, 0, -days)
+		drop := sort.Search(len(p.events), func(i int) bool { return !p.events[i].Time.Before(cutoff) })
+		if drop > 0 {
+			p.events = append([]*FloodEvent(nil), p.events[drop:]...)
+			p.dirty = true
+		}
+		p.mu.Unlock()
+
+		if err := p.save(); err != nil {
+			log.Printf("[flood-dashboard] failed to save events: %v", err)
+		}
+	}
+}
+
+// parseRange reads the range parameter (24h or 7d)
+func parseRange(c *gin.Context) (time.Duration, bool) {
+	switch c.DefaultQuery("range", "24h") {
+	case "24h":
+		return 24 * time.Hour, true
+	case "7d":
+		return 7 * 24 * time.Hour, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "range must be 24h or 7d"})
+	return 0, false
+}
+
+// since iterates over events newer than t, newest first. Caller must hold
+// p.mu.
+func (p *FloodDashboardPlugin) since(t time.Time, fn func(e *FloodEvent)) {
+	for i := len(p.events) - 1; i >= 0 && p.events[i].Time.After(t); i-- {
+		fn(p.events[i])
+	}
+}
+
+// countTypes counts events per type since t. Caller must hold p.mu.
+func (p *FloodDashboardPlugin) countTypes(t time.Time) (map[string]int, int) {
+	byType := make(map[string]int)
+	total := 0
+	p.since(t, func(e *FloodEvent) {
+		byType[e.Type]++
+		total++
+	})
+	return byType, total
+}
+
+// offenders ranks the sources of events since t by IP, falling back to the
+// nick for events without one. Caller must hold p.mu.
+func (p *FloodDashboardPlugin) offenders(t time.Time, kind string, limit int) []Count {
+	byKey := make(map[string]*Count)
+	p.since(t, func(e *FloodEvent) {
+		if kind != "" && e.Type != kind {
+			return
+		}
+		key := e.IP
+		if key == "" {
+			key = e.Nick
+		}
+		if key == "" {
+			return
+		}
+		c, ok := byKey[key]
+		if !ok {
+			// Walking newest first, so this is the most recent nick
+			c = &Count{Name: key, Types: make(map[string]int), Nick: e.Nick}
+			byKey[key] = c
+		}
+		c.Count++
+		c.Types[e.Type]++
+	})
+	return topCounts(byKey, limit)
+}
+
+// targets ranks the flooded users and channels since t. Caller must hold
+// p.mu.
+func (p *FloodDashboardPlugin) targets(t time.Time, limit int) []Count {
+	byKey := make(map[string]*Count)
+	p.since(t, func(e *FloodEvent) {
+		if e.Target == "" {
+			return
+		}
+		c, ok := byKey[strings.ToLower(e.Target)]
+		if !ok {
+			c = &Count{Name: e.Target, Types: make(map[string]int)}
+			byKey[strings.ToLower(e.Target)] = c
+		}
+		c.Count++
+		c.Types[e.Type]++
+	})
+	return topCounts(byKey, limit)
+}
+
+// topCounts sorts counts by size and keeps the first limit
+func topCounts(byKey map[string]*Count, limit int) []Count {
+	list := make([]Count, 0, len(byKey))
+	for _, c := range byKey {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// handleSummary returns counters and top lists for a range
+func (p *FloodDashboardPlugin) handleSummary(c *gin.Context) {
+	span, ok := parseRange(c)
+	if !ok {
+		return
+	}
+	t := time.Now().Add(-span)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	byType, total := p.countTypes(t)
+	servers := make(map[string]*Count)
+	p.since(t, func(e *FloodEvent) {
+		if e.Server == "" {
+			return
+		}
+		if _, ok := servers[e.Server]; !ok {
+			servers[e.Server] = &Count{Name: e.Server}
+		}
+		servers[e.Server].Count++
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"range":     c.DefaultQuery("range", "24h"),
+		"total":     total,
+		"by_type":   byType,
+		"offenders": p.offenders(t, "", 10),
+		"targets":   p.targets(t, 10),
+		"servers":   topCounts(servers, 0),
+		"streaming": p.streamOK,
+	})
+}
+
+// handleTimeline returns event counts per type in time buckets: 15 minutes
+// for 24h and one hour for 7d
+func (p *FloodDashboardPlugin) handleTimeline(c *gin.Context) {
+	span, ok := parseRange(c)
+	if !ok {
+		return
+	}
+	bucket := 15 * time.Minute
+	if span > 24*time.Hour {
+		bucket = time.Hour
+	}
+	kind := c.Query("type")
+
+	now := time.Now().UTC()
+	end := now.Truncate(bucket).Add(bucket)
+	start := end.Add(-span)
+	n := int(span / bucket)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	series := make(map[string][]int)
+	p.since(start, func(e *FloodEvent) {
+		if kind != "" && e.Type != kind {
+			return
+		}
+		i := int(e.Time.Sub(start) / bucket)
+		if i < 0 || i >= n {
+			return
+		}
+		if _, ok := series[e.Type]; !ok {
+			series[e.Type] = make([]int, n)
+		}
+		series[e.Type][i]++
+	})
+
+	buckets := make([]time.Time, n)
+	for i := range buckets {
+		buckets[i] = start.Add(time.Duration(i) * bucket)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"bucket_seconds": int(bucket.Seconds()),
+		"buckets":        buckets,
+		"series":         series,
+	})
+}
+
+// handleOffenders returns the top offenders for a range, optionally for
+// one flood type
+func (p *FloodDashboardPlugin) handleOffenders(c *gin.Context) {
+	span, ok := parseRange(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "25"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	t := time.Now().Add(-span)
+	c.JSON(http.StatusOK, gin.H{
+		"offenders": p.offenders(t, c.Query("type"), limit),
+		"targets":   p.targets(t, limit),
+	})
+}
+
+// handleEvents returns recent events, newest first
+func (p *FloodDashboardPlugin) handleEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	kind := c.Query("type")
+	source := c.Query("source")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*FloodEvent, 0)
+	for i := len(p.events) - 1; i >= 0 && len(list) < limit; i-- {
+		e := p.events[i]
+		if kind != "" && e.Type != kind {
+			continue
+		}
+		if source != "" && e.IP != source && !strings.EqualFold(e.Nick, source) {
+			continue
+		}
+		list = append(list, e)
+	}
+	c.JSON(http.StatusOK, gin.H{"events": list})
+}
+
+// handleGetConfig returns the current configuration
+func (p *FloodDashboardPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *FloodDashboardPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *FloodDashboardPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *FloodDashboardPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
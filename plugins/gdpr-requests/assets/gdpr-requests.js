@@ -0,0 +1,300 @@
+/**
+ * GDPR Requests Frontend Script
+ *
+ * Lists data subject requests on the plugin page, with a form to open one
+ * and buttons to export, erase, verify and close them.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'gdpr-requests';
+  const PLUGIN_NAME = 'GDPR Requests';
+  const PAGE_PATH = '/plugins/gdpr-requests';
+  const API_BASE = '/api/plugin/gdpr-requests';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('gdpr-requests-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'gdpr-requests-styles';
+    style.textContent = `
+      .gdr-app { display: flex; flex-direction: column; gap: 1rem; }
+      .gdr-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .gdr-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .gdr-btn.danger { color: var(--error, #f38ba8); }
+      .gdr-btn.small { padding: 0.2rem 0.5rem; font-size: 0.75rem; }
+      .gdr-app input[type="text"] {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .gdr-row { display: flex; gap: 0.75rem; align-items: center; flex-wrap: wrap; font-size: 0.85rem; color: var(--text-secondary, #a6adc8); }
+      .gdr-request {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.6rem 0.75rem;
+        margin-bottom: 0.5rem;
+        font-size: 0.85rem;
+        color: var(--text-secondary, #a6adc8);
+      }
+      .gdr-request strong { color: var(--text-primary, #cdd6f4); }
+      .gdr-request ul { margin: 0.4rem 0 0; padding-left: 1.2rem; }
+      .gdr-status { font-size: 0.7rem; padding: 0.05rem 0.35rem; border-radius: 4px; background: var(--bg-tertiary, #45475a); }
+      .gdr-status.erased { color: var(--warning, #f9e2af); }
+      .gdr-status.verified { color: var(--success, #a6e3a1); }
+      .gdr-muted { color: var(--text-muted, #6c7086); }
+      .gdr-error { color: var(--error, #f38ba8); }
+      .gdr-empty { color: var(--text-muted, #6c7086); padding: 0.5rem 0; }
+      .gdr-request details { margin-top: 0.4rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function subjectLabel(r) {
+    if (r.masked) return r.masked;
+    return [r.subject.nick, r.subject.account, r.subject.ip].filter(Boolean).join(' / ');
+  }
+
+  function renderRequest(r) {
+    const id = escapeHtml(r.id);
+    const buttons = [];
+    if (r.status === 'open') {
+      buttons.push(`<button class="gdr-btn small" data-action="export" data-id="${id}">Export</button>`);
+      buttons.push(`<button class="gdr-btn small" data-action="scan" data-id="${id}">Scan again</button>`);
+      buttons.push(`<button class="gdr-btn small danger" data-action="erase" data-id="${id}">Erase</button>`);
+      buttons.push(`<button class="gdr-btn small" data-action="close" data-id="${id}">Close</button>`);
+    } else if (r.status === 'erased') {
+      buttons.push(`<button class="gdr-btn small" data-action="verify" data-id="${id}">Verify now</button>`);
+    }
+
+    let note = '';
+    if (r.status === 'erased') {
+      note = '<div class="gdr-muted">Erased. Verification completes after the next panel restart, once plugins have reloaded their data.</div>';
+    }
+
+    return `
+      <div class="gdr-request">
+        <div class="gdr-row">
+          <strong>${escapeHtml(subjectLabel(r))}</strong>
+          <span class="gdr-status ${escapeHtml(r.status)}">${escapeHtml(r.status)}</span>
+          ${r.reference ? `<span>${escapeHtml(r.reference)}</span>` : ''}
+          <span class="gdr-muted">opened by ${escapeHtml(r.created_by)} ${escapeHtml(formatTime(r.created_at))}</span>
+          <span style="flex: 1"></span>
+          ${buttons.join('')}
+        </div>
+        ${note}
+        <div>${r.findings} entries found in ${r.sources.length} files <span class="gdr-muted">(scanned ${escapeHtml(formatTime(r.scanned_at))})</span></div>
+        ${r.sources.length ? `<ul>${r.sources.map(s => `<li>${escapeHtml(s.plugin)}: ${s.findings} in ${escapeHtml(s.file)}</li>`).join('')}</ul>` : ''}
+        <details>
+          <summary>Audit trail</summary>
+          <ul>${r.events.map(e => `<li>${escapeHtml(formatTime(e.time))} ${escapeHtml(e.actor)} ${escapeHtml(e.action)}${e.detail ? `: ${escapeHtml(e.detail)}` : ''}</li>`).join('')}</ul>
+        </details>
+      </div>
+    `;
+  }
+
+  async function load(container) {
+    const list = container.querySelector('#gdr-list');
+    const status = container.querySelector('#gdr-filter').value;
+    try {
+      const data = await api('GET', `/requests?status=${encodeURIComponent(status)}`);
+      list.innerHTML = data.requests.length ? data.requests.map(renderRequest).join('') : '<div class="gdr-empty">No requests.</div>';
+    } catch (e) {
+      list.innerHTML = `<div class="gdr-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function download(id) {
+    const res = await fetch(`${API_BASE}/requests/${encodeURIComponent(id)}/export`, { headers: getAuthHeaders() });
+    if (!res.ok) {
+      const data = await res.json().catch(() => ({}));
+      throw new Error(data.error || `Export failed with status ${res.status}`);
+    }
+    const url = URL.createObjectURL(await res.blob());
+    const a = document.createElement('a');
+    a.href = url;
+    a.download = `subject-access-${id}.json`;
+    a.click();
+    setTimeout(() => URL.revokeObjectURL(url), 1000);
+  }
+
+  async function requestAction(container, btn) {
+    const id = btn.dataset.id;
+    const path = `/requests/${encodeURIComponent(id || '')}`;
+    switch (btn.dataset.action) {
+      case 'export':
+        await download(id);
+        break;
+      case 'scan':
+        await api('POST', `${path}/scan`);
+        break;
+      case 'erase': {
+        const confirmId = prompt(`This removes every entry found from the plugins' data files and cannot be undone.\n\nType the request ID (${id}) to erase.`);
+        if (confirmId === null) return;
+        const result = await api('POST', `${path}/erase`, { confirm: confirmId.trim() });
+        if (result.errors && result.errors.length) alert(`Some files could not be changed:\n${result.errors.join('\n')}`);
+        break;
+      }
+      case 'verify':
+        await api('POST', `${path}/verify`);
+        break;
+      case 'close':
+        if (!confirm('Close this request without erasing anything?')) return;
+        await api('POST', `${path}/close`);
+        break;
+      default:
+        return;
+    }
+    load(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="gdr-app" data-plugin="${PLUGIN_ID}">
+        <form id="gdr-form" class="gdr-row">
+          <input type="text" name="nick" placeholder="Nick">
+          <input type="text" name="account" placeholder="Account">
+          <input type="text" name="ip" placeholder="IP address">
+          <input type="text" name="reference" placeholder="Reference (ticket, email...)">
+          <button class="gdr-btn primary" type="submit">Open request</button>
+        </form>
+        <div class="gdr-row">
+          <select id="gdr-filter">
+            <option value="">All requests</option>
+            <option value="open">Open</option>
+            <option value="erased">Awaiting verification</option>
+            <option value="verified">Verified</option>
+            <option value="closed">Closed</option>
+          </select>
+        </div>
+        <div id="gdr-list"><div class="gdr-empty">Loading...</div></div>
+      </div>
+    `;
+
+    const form = container.querySelector('#gdr-form');
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = form.elements;
+      try {
+        const result = await api('POST', '/requests', {
+          nick: f.nick.value,
+          account: f.account.value,
+          ip: f.ip.value,
+          reference: f.reference.value
+        });
+        if (result.errors && result.errors.length) alert(`Some files could not be read:\n${result.errors.join('\n')}`);
+        form.reset();
+        load(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    container.querySelector('#gdr-filter').addEventListener('change', () => load(container));
+
+    container.querySelector('#gdr-list').addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+      try {
+        await requestAction(container, btn);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('gdpr-requests-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,78 @@
+package gdprrequests
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// IRCdData is what the IRCd itself holds about the subject. It can only
+// be exported: connected users and WHOWAS history expire on their own.
+type IRCdData struct {
+	Users       []json.RawMessage `json:"users"`
+	Whowas      []json.RawMessage `json:"whowas"`
+	UsersError  string            `json:"users_error,omitempty"`
+	WhowasError string            `json:"whowas_error,omitempty"`
+}
+
+// rpcUser is the subset of the UnrealIRCd user object used for matching
+type rpcUser struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	User     struct {
+		Account string `json:"account"`
+	} `json:"user"`
+}
+
+// ircdData collects the connected users and WHOWAS entries matching the
+// subject. Errors are recorded rather than returned, so an unreachable or
+// older IRCd doesn't stop an export.
+func (p *GDPRRequestsPlugin) ircdData(ctx context.Context, s Subject) *IRCdData {
+	out := &IRCdData{Users: make([]json.RawMessage, 0), Whowas: make([]json.RawMessage, 0)}
+	client := p.client()
+	if client == nil {
+		out.UsersError = "RPC is not configured"
+		return out
+	}
+
+	var users struct {
+		List []json.RawMessage `json:"list"`
+	}
+	if err := client.Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &users); err != nil {
+		out.UsersError = err.Error()
+	}
+	for _, raw := range users.List {
+		var u rpcUser
+		if json.Unmarshal(raw, &u) != nil {
+			continue
+		}
+		if s.matches(u.Name, u.User.Account, u.IP) {
+			out.Users = append(out.Users, raw)
+		}
+	}
+
+	// whowas.get needs UnrealIRCd 6.1 or later
+	var whowas struct {
+		List []json.RawMessage `json:"list"`
+	}
+	params := map[string]interface{}{"object_detail_level": 2}
+	if s.Nick != "" {
+		params["nick"] = s.Nick
+	}
+	if s.IP != "" {
+		params["ip"] = s.IP
+	}
+	if err := client.Call(ctx, "whowas.get", params, &whowas); err != nil {
+		out.WhowasError = err.Error()
+	}
+	for _, raw := range whowas.List {
+		var u rpcUser
+		if json.Unmarshal(raw, &u) != nil {
+			continue
+		}
+		if s.matches(u.Name, u.User.Account, u.IP) {
+			out.Whowas = append(out.Whowas, raw)
+		}
+	}
+	return out
+}
@@ -0,0 +1,810 @@
+// GDPR Requests Plugin for UnrealIRCd Web Panel
+// Finds what plugins and the IRCd hold about a nick, account or IP for
+// access requests, and erases it from plugin data with an audit record
+
+package gdprrequests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Request statuses
+const (
+	StatusOpen     = "open"
+	StatusErased   = "erased"
+	StatusVerified = "verified"
+	StatusClosed   = "closed"
+)
+
+// verifyInterval is how often erased requests are checked again
+const verifyInterval = 10 * time.Minute
+
+// GDPRRequestsPlugin implements the Plugin interface
+type GDPRRequestsPlugin struct {
+	config   Config
+	requests []*Request
+	rpc      *jsonrpc.Client
+	started  time.Time
+	mu       sync.RWMutex
+	eraseMu  sync.Mutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL       string `json:"rpc_url"`
+	RPCUser      string `json:"rpc_user"`
+	RPCPassword  string `json:"rpc_password"`
+	RPCInsecure  bool   `json:"rpc_insecure"`
+	DataDir      string `json:"data_dir"`
+	ScanPaths    string `json:"scan_paths"`
+	ExcludePaths string `json:"exclude_paths"`
+	MaxFileMB    int    `json:"max_file_mb"`
+}
+
+// Subject identifies the person a request is about
+type Subject struct {
+	Nick    string `json:"nick"`
+	Account string `json:"account"`
+	IP      string `json:"ip"`
+}
+
+// matcher returns a matcher for the subject's identifiers
+func (s Subject) matcher() *matcher {
+	return newMatcher(s.Nick, s.Account, s.IP)
+}
+
+// matches reports whether an IRC user is the subject
+func (s Subject) matches(nick, account, ip string) bool {
+	return (s.Nick != "" && strings.EqualFold(s.Nick, nick)) ||
+		(s.Account != "" && strings.EqualFold(s.Account, account)) ||
+		(s.IP != "" && s.IP == ip)
+}
+
+// empty reports whether the subject has no identifiers left
+func (s Subject) empty() bool {
+	return s.Nick == "" && s.Account == "" && s.IP == ""
+}
+
+// Request is a data subject request and its audit trail
+type Request struct {
+	ID          string        `json:"id"`
+	Reference   string        `json:"reference"`
+	Subject     Subject       `json:"subject"`
+	Masked      string        `json:"masked,omitempty"`
+	SubjectHash string        `json:"subject_hash,omitempty"`
+	Salt        string        `json:"salt,omitempty"`
+	Status      string        `json:"status"`
+	CreatedBy   string        `json:"created_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+	ScannedAt   time.Time     `json:"scanned_at"`
+	Findings    int           `json:"findings"`
+	Sources     []SourceCount `json:"sources"`
+	ErasedBy    string        `json:"erased_by,omitempty"`
+	ErasedAt    time.Time     `json:"erased_at"`
+	VerifiedAt  time.Time     `json:"verified_at"`
+	Events      []Event       `json:"events"`
+}
+
+// Event is an entry in a request's audit trail
+type Event struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Export is everything held about a subject, as downloaded for an access
+// request
+type Export struct {
+	Request     string               `json:"request"`
+	Reference   string               `json:"reference"`
+	Subject     Subject              `json:"subject"`
+	GeneratedAt time.Time            `json:"generated_at"`
+	GeneratedBy string               `json:"generated_by"`
+	Plugins     map[string][]Finding `json:"plugins"`
+	Errors      []string             `json:"errors"`
+	IRCd        *IRCdData            `json:"ircd"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &GDPRRequestsPlugin{
+		config: Config{
+			RPCURL:    "https://127.0.0.1:8600/api",
+			DataDir:   "data/plugins/gdpr-requests",
+			ScanPaths: "data/plugins",
+			MaxFileMB: 64,
+		},
+		requests: make([]*Request, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *GDPRRequestsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "GDPR Requests",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Data subject access and erasure requests across plugin data",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *GDPRRequestsPlugin) Init() error {
+	p.mu.Lock()
+	p.started = time.Now().UTC()
+	if err := storage.LoadJSON(p.storePath(), &p.requests); err != nil {
+		log.Printf("[gdpr-requests] failed to load requests: %v", err)
+	}
+	if p.requests == nil {
+		p.requests = make([]*Request, 0)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "gdpr-requests-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		open, erased := 0, 0
+		for _, r := range p.requests {
+			switch r.Status {
+			case StatusOpen:
+				open++
+			case StatusErased:
+				erased++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Data Requests",
+			Icon:  "ShieldCheck",
+			Content: map[string]interface{}{
+				"open":                  open,
+				"awaiting_verification": erased,
+			},
+			Order: 84,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.verifyLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *GDPRRequestsPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *GDPRRequestsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/gdpr-requests")
+	{
+		plugin.GET("/requests", p.handleList)
+		plugin.POST("/requests", p.handleCreate)
+		plugin.GET("/requests/:id", p.handleGet)
+		plugin.POST("/requests/:id/scan", p.handleScan)
+		plugin.GET("/requests/:id/export", p.handleExport)
+		plugin.POST("/requests/:id/erase", p.handleErase)
+		plugin.POST("/requests/:id/verify", p.handleVerify)
+		plugin.POST("/requests/:id/close", p.handleClose)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the request database
+func (p *GDPRRequestsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "requests.json")
+}
+
+// save persists all requests. Caller must hold p.mu.
+func (p *GDPRRequestsPlugin) save() {
+	if err := storage.SaveJSON(p.storePath(), p.requests); err != nil {
+		log.Printf("[gdpr-requests] failed to save requests: %v", err)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if
+// needed, or nil when RPC isn't configured
+func (p *GDPRRequestsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.RPCURL == "" {
+		return nil
+	}
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// find returns a request by ID. Caller must hold p.mu.
+func (p *GDPRRequestsPlugin) find(id string) *Request {
+	for _, r := range p.requests {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// files returns the data files to scan. This plugin's own data is never
+// scanned: the audit trail has to survive an erasure.
+func (p *GDPRRequestsPlugin) files() ([]string, error) {
+	p.mu.RLock()
+	scan := lists.Split(p.config.ScanPaths)
+	exclude := append(lists.Split(p.config.ExcludePaths), p.config.DataDir)
+	maxBytes := int64(p.config.MaxFileMB) << 20
+	p.mu.RUnlock()
+
+	return dataFiles(scan, exclude, maxBytes)
+}
+
+// scan finds what the data files hold about a subject. Files that can't
+// be read are reported as errors and skipped.
+func (p *GDPRRequestsPlugin) scan(s Subject) (map[string][]Finding, []string, error) {
+	files, err := p.files()
+	if err != nil {
+		return nil, nil, err
+	}
+	m := s.matcher()
+	found := make(map[string][]Finding)
+	errs := make([]string, 0)
+	for _, file := range files {
+		findings, err := scanFile(file, m)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if len(findings) > 0 {
+			found[file] = findings
+		}
+	}
+	return found, errs, nil
+}
+
+// eraseAll erases a subject from every data file and returns the number
+// of changes and any files that couldn't be changed. Erasures run one at
+// a time, so two never rewrite the same file at once.
+func (p *GDPRRequestsPlugin) eraseAll(s Subject) (int, []string, error) {
+	p.eraseMu.Lock()
+	defer p.eraseMu.Unlock()
+
+	files, err := p.files()
+	if err != nil {
+		return 0, nil, err
+	}
+	m := s.matcher()
+	total := 0
+	errs := make([]string, 0)
+	for _, file := range files {
+		n, err := eraseFile(file, m)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		total += n
+	}
+	return total, errs, nil
+}
+
+// sources summarises findings per file
+func sources(found map[string][]Finding) ([]SourceCount, int) {
+	list := make([]SourceCount, 0, len(found))
+	total := 0
+	for file, findings := range found {
+		list = append(list, SourceCount{File: file, Plugin: pluginOf(file), Findings: len(findings)})
+		total += len(findings)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].File < list[j].File })
+	return list, total
+}
+
+// addEvent appends to a request's audit trail. Caller must hold p.mu.
+func (r *Request) addEvent(actor, action, detail string) {
+	r.Events = append(r.Events, Event{Time: time.Now().UTC(), Actor: actor, Action: action, Detail: detail})
+}
+
+// record stores the result of a scan on a request. Caller must hold p.mu.
+func (r *Request) record(found map[string][]Finding) {
+	r.Sources, r.Findings = sources(found)
+	r.ScannedAt = time.Now().UTC()
+}
+
+// pseudonymise replaces the subject's identifiers with a masked form and
+// a salted hash once their data is verified gone, so the audit trail no
+// longer holds them. Caller must hold p.mu.
+func (r *Request) pseudonymise() {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+	r.Salt = hex.EncodeToString(salt)
+	r.SubjectHash = subjectHash(r.Salt, r.Subject)
+	parts := make([]string, 0, 3)
+	for _, id := range []string{r.Subject.Nick, r.Subject.Account, r.Subject.IP} {
+		if id != "" {
+			parts = append(parts, mask(id))
+		}
+	}
+	r.Masked = strings.Join(parts, " / ")
+	r.Subject = Subject{}
+}
+
+// subjectHash hashes a subject's identifiers with a salt
+func subjectHash(salt string, s Subject) string {
+	sum := sha256.Sum256([]byte(salt + "\n" + strings.ToLower(s.Nick) + "\n" + strings.ToLower(s.Account) + "\n" + s.IP))
+	return hex.EncodeToString(sum[:])
+}
+
+// mask keeps the first character of an identifier
+func mask(id string) string {
+	r := []rune(id)
+	return string(r[0]) + strings.Repeat("*", len(r)-1)
+}
+
+// verifyLoop checks erased requests until their data is verified gone
+func (p *GDPRRequestsPlugin) verifyLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(verifyInterval)
+	defer ticker.Stop()
+
+	p.verifyAll()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.verifyAll()
+		}
+	}
+}
+
+// verifyAll verifies every erased request
+func (p *GDPRRequestsPlugin) verifyAll() {
+	p.mu.RLock()
+	ids := make([]string, 0)
+	for _, r := range p.requests {
+		if r.Status == StatusErased {
+			ids = append(ids, r.ID)
+		}
+	}
+	p.mu.RUnlock()
+
+	for _, id := range ids {
+		if _, err := p.verify(id, "system"); err != nil {
+			log.Printf("[gdpr-requests] failed to verify %s: %v", id, err)
+		}
+	}
+}
+
+// verify scans again for an erased subject. Plugins keep their data in
+// memory and may write it back after an erasure, so anything found is
+// erased again. The erasure is verified by a clean scan after the panel
+// has restarted since the last time anything was erased, when every
+// plugin has loaded its data from the erased files.
+func (p *GDPRRequestsPlugin) verify(id, actor string) (*Request, error) {
+	p.mu.RLock()
+	r := p.find(id)
+	if r == nil || r.Status != StatusErased {
+		p.mu.RUnlock()
+		return nil, fmt.Errorf("request is not awaiting verification")
+	}
+	subject := r.Subject
+	p.mu.RUnlock()
+
+	found, errs, err := p.scan(subject)
+	if err != nil {
+		return nil, err
+	}
+	erased := 0
+	if len(found) > 0 {
+		erased, _, err = p.eraseAll(subject)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if r = p.find(id); r == nil || r.Status != StatusErased {
+		return nil, fmt.Errorf("request is not awaiting verification")
+	}
+	r.record(found)
+	switch {
+	case len(found) > 0:
+		r.ErasedAt = time.Now().UTC()
+		r.addEvent(actor, "erased", fmt.Sprintf("found again in %d files, %d entries erased", len(found), erased))
+	case len(errs) > 0:
+		r.addEvent(actor, "verify-failed", fmt.Sprintf("%d files could not be read", len(errs)))
+	case p.started.After(r.ErasedAt):
+		r.Status = StatusVerified
+		r.VerifiedAt = time.Now().UTC()
+		r.pseudonymise()
+		r.addEvent(actor, "verified", "no data found after a panel restart")
+		log.Printf("[gdpr-requests] erasure for request %s verified", r.ID)
+	}
+	p.save()
+	return r, nil
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleList returns requests, newest first
+func (p *GDPRRequestsPlugin) handleList(c *gin.Context) {
+	status := c.Query("status")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Request, 0)
+	for _, r := range p.requests {
+		if status == "" || r.Status == status {
+			list = append(list, r)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{"requests": list, "count": len(list)})
+}
+
+// handleCreate opens a request and scans for the subject's data
+func (p *GDPRRequestsPlugin) handleCreate(c *gin.Context) {
+	var req struct {
+		Subject
+		Reference string `json:"reference"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	s := Subject{
+		Nick:    strings.TrimSpace(req.Nick),
+		Account: strings.TrimSpace(req.Account),
+		IP:      strings.TrimSpace(req.IP),
+	}
+	if s.empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a nick, account or IP is required"})
+		return
+	}
+	if s.IP != "" {
+		ip := net.ParseIP(s.IP)
+		if ip == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ip is not a valid IP address"})
+			return
+		}
+		s.IP = ip.String()
+	}
+
+	found, errs, err := p.scan(s)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := access.Actor(c)
+	r := &Request{
+		ID:        newID(),
+		Reference: strings.TrimSpace(req.Reference),
+		Subject:   s,
+		Status:    StatusOpen,
+		CreatedBy: actor,
+		CreatedAt: time.Now().UTC(),
+		Events:    make([]Event, 0),
+	}
+	r.record(found)
+	r.addEvent(actor, "created", fmt.Sprintf("%d entries found in %d files", r.Findings, len(r.Sources)))
+
+	p.mu.Lock()
+	p.requests = append(p.requests, r)
+	p.save()
+	p.mu.Unlock()
+
+	log.Printf("[gdpr-requests] %s opened request %s", actor, r.ID)
+	c.JSON(http.StatusCreated, gin.H{"request": r, "errors": errs})
+}
+
+// handleGet returns a request
+func (p *GDPRRequestsPlugin) handleGet(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	r := p.find(c.Param("id"))
+	if r == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// subjectOf returns the subject of an open request, answering the client
+// if there is none
+func (p *GDPRRequestsPlugin) subjectOf(c *gin.Context) (Subject, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	r := p.find(c.Param("id"))
+	if r == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return Subject{}, false
+	}
+	if r.Subject.empty() {
+		c.JSON(http.StatusConflict, gin.H{"error": "The subject of this request has been erased"})
+		return Subject{}, false
+	}
+	return r.Subject, true
+}
+
+// handleScan scans again for an open request's subject
+func (p *GDPRRequestsPlugin) handleScan(c *gin.Context) {
+	s, ok := p.subjectOf(c)
+	if !ok {
+		return
+	}
+	found, errs, err := p.scan(s)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.find(c.Param("id"))
+	if r == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+	r.record(found)
+	p.save()
+	c.JSON(http.StatusOK, gin.H{"request": r, "errors": errs})
+}
+
+// handleExport returns everything held about a request's subject as a
+// JSON download
+func (p *GDPRRequestsPlugin) handleExport(c *gin.Context) {
+	s, ok := p.subjectOf(c)
+	if !ok {
+		return
+	}
+	found, errs, err := p.scan(s)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	export := Export{
+		Request:     c.Param("id"),
+		Subject:     s,
+		GeneratedAt: time.Now().UTC(),
+		GeneratedBy: access.Actor(c),
+		Plugins:     make(map[string][]Finding),
+		Errors:      errs,
+		IRCd:        p.ircdData(ctx, s),
+	}
+	for file, findings := range found {
+		plugin := pluginOf(file)
+		export.Plugins[plugin] = append(export.Plugins[plugin], findings...)
+	}
+
+	p.mu.Lock()
+	if r := p.find(export.Request); r != nil {
+		export.Reference = r.Reference
+		r.record(found)
+		r.addEvent(export.GeneratedBy, "exported", fmt.Sprintf("%d entries from %d plugins", r.Findings, len(export.Plugins)))
+		p.save()
+	}
+	p.mu.Unlock()
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="subject-access-%s.json"`, export.Request))
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// handleErase erases an open request's subject from every data file. The
+// request ID must be repeated in the body to confirm.
+func (p *GDPRRequestsPlugin) handleErase(c *gin.Context) {
+	var req struct {
+		Confirm string `json:"confirm"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	id := c.Param("id")
+	if req.Confirm != id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "confirm must be the request ID"})
+		return
+	}
+	s, ok := p.subjectOf(c)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	r := p.find(id)
+	if r == nil || r.Status != StatusOpen {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Only open requests can be erased"})
+		return
+	}
+	// Mark the request first, so a second click can't erase twice
+	r.Status = StatusErased
+	r.ErasedBy = access.Actor(c)
+	r.ErasedAt = time.Now().UTC()
+	p.save()
+	p.mu.Unlock()
+
+	erased, errs, err := p.eraseAll(s)
+	var found map[string][]Finding
+	if err == nil {
+		found, _, err = p.scan(s)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		r.Status = StatusOpen
+		r.addEvent(r.ErasedBy, "erase-failed", err.Error())
+		p.save()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r.record(found)
+	detail := fmt.Sprintf("%d entries erased", erased)
+	if len(errs) > 0 {
+		detail += fmt.Sprintf(", %d files could not be changed", len(errs))
+	}
+	if r.Findings > 0 {
+		detail += fmt.Sprintf(", %d entries still found", r.Findings)
+	}
+	r.addEvent(r.ErasedBy, "erased", detail)
+	p.save()
+
+	log.Printf("[gdpr-requests] %s erased request %s: %s", r.ErasedBy, r.ID, detail)
+	c.JSON(http.StatusOK, gin.H{"request": r, "errors": errs})
+}
+
+// handleVerify verifies an erased request now instead of waiting for the
+// next check
+func (p *GDPRRequestsPlugin) handleVerify(c *gin.Context) {
+	r, err := p.verify(c.Param("id"), access.Actor(c))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, r)
+}
+
+// handleClose closes an open request that needed no erasure, such as an
+// access request
+func (p *GDPRRequestsPlugin) handleClose(c *gin.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r := p.find(c.Param("id"))
+	if r == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+	if r.Status != StatusOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only open requests can be closed"})
+		return
+	}
+	r.Status = StatusClosed
+	r.addEvent(access.Actor(c), "closed", "")
+	p.save()
+	c.JSON(http.StatusOK, r)
+}
+
+// handleGetConfig returns the current configuration
+func (p *GDPRRequestsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *GDPRRequestsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if len(lists.Split(newConfig.ScanPaths)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scan_paths needs at least one path"})
+		return
+	}
+	if newConfig.MaxFileMB < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_file_mb must be at least 1"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *GDPRRequestsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *GDPRRequestsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,310 @@
+package gdprrequests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// minWordLen is the shortest identifier matched inside longer strings
+const minWordLen = 3
+
+// redacted replaces a matching value that isn't part of a removable record
+const redacted = "[erased]"
+
+// Finding is a piece of data about the subject found in a data file
+type Finding struct {
+	File  string      `json:"file"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// SourceCount is how many findings a data file has, kept on a request
+// so the audit trail shows where data was without repeating it
+type SourceCount struct {
+	File     string `json:"file"`
+	Plugin   string `json:"plugin"`
+	Findings int    `json:"findings"`
+}
+
+// matcher matches strings that mention any of a subject's identifiers.
+// A string matches when it is an identifier or contains one as a whole
+// word, so "bob" matches "bob!~bob@host" and "~account:bob" but not
+// "bobby". Identifiers shorter than minWordLen only match exactly, or a
+// two letter nick would match half the messages on the network.
+type matcher struct {
+	terms []string
+}
+
+// newMatcher returns a matcher for the non-empty identifiers
+func newMatcher(ids ...string) *matcher {
+	m := &matcher{}
+	for _, id := range ids {
+		if id = strings.ToLower(strings.TrimSpace(id)); id != "" {
+			m.terms = append(m.terms, id)
+		}
+	}
+	return m
+}
+
+// isSep reports whether r can't be part of a nick, account or IP address
+func isSep(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		return false
+	case strings.ContainsRune("_-[]\\`^{}|.:", r):
+		return false
+	}
+	return true
+}
+
+// isSepOrColon also splits on ':', for prefixes like "~account:"
+func isSepOrColon(r rune) bool {
+	return r == ':' || isSep(r)
+}
+
+// Match reports whether s mentions the subject
+func (m *matcher) Match(s string) bool {
+	if len(m.terms) == 0 || s == "" {
+		return false
+	}
+	s = strings.ToLower(s)
+	for _, term := range m.terms {
+		if s == term {
+			return true
+		}
+		if len(term) < minWordLen || !strings.Contains(s, term) {
+			continue
+		}
+		for _, split := range []func(rune) bool{isSep, isSepOrColon} {
+			for _, tok := range strings.FieldsFunc(s, split) {
+				if tok == term || strings.TrimRight(tok, ".:") == term {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// contains reports whether any key or string in v mentions the subject
+func (m *matcher) contains(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return m.Match(v)
+	case []interface{}:
+		for _, item := range v {
+			if m.contains(item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			if m.Match(k) || m.contains(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dataFiles returns the JSON files under the scan paths, leaving out
+// anything under an excluded path and files larger than maxBytes
+func dataFiles(scan, exclude []string, maxBytes int64) ([]string, error) {
+	files := make([]string, 0)
+	for _, root := range scan {
+		root = filepath.Clean(root)
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && p == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if underAny(p, exclude) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !d.Type().IsRegular() || !strings.EqualFold(filepath.Ext(p), ".json") {
+				return nil
+			}
+			if info, err := d.Info(); err != nil || (maxBytes > 0 && info.Size() > maxBytes) {
+				return nil
+			}
+			files = append(files, filepath.ToSlash(p))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// underAny reports whether p is one of dirs or inside one of them
+func underAny(p string, dirs []string) bool {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	for _, dir := range dirs {
+		d, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if abs == d || strings.HasPrefix(abs, d+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginOf names the plugin a data file belongs to, from the directory
+// under plugins/ it is in
+func pluginOf(file string) string {
+	parts := strings.Split(file, "/")
+	for i, part := range parts[:len(parts)-1] {
+		if part == "plugins" && i+1 < len(parts)-1 {
+			return parts[i+1]
+		}
+	}
+	return filepath.Base(filepath.Dir(file))
+}
+
+// readData decodes a JSON data file, keeping numbers as they were written
+func readData(file string) (interface{}, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return v, nil
+}
+
+// scanFile returns what a data file holds about the subject. An array
+// element that mentions the subject anywhere is one finding, as is a map
+// entry keyed by one of its identifiers; other matching strings are
+// found on their own.
+func scanFile(file string, m *matcher) ([]Finding, error) {
+	v, err := readData(file)
+	if err != nil {
+		return nil, err
+	}
+	findings := make([]Finding, 0)
+	walk(v, "", m, func(path string, value interface{}) {
+		findings = append(findings, Finding{File: file, Path: path, Value: value})
+	})
+	return findings, nil
+}
+
+// walk calls found for each finding in v. Paths are JSON pointers.
+func walk(v interface{}, path string, m *matcher, found func(string, interface{})) {
+	switch v := v.(type) {
+	case string:
+		if m.Match(v) {
+			found(path, v)
+		}
+	case []interface{}:
+		for i, item := range v {
+			p := path + "/" + strconv.Itoa(i)
+			if m.contains(item) {
+				found(p, item)
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := path + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(k)
+			if m.Match(k) {
+				found(p, v[k])
+				continue
+			}
+			walk(v[k], p, m, found)
+		}
+	}
+}
+
+// erase removes what v holds about the subject, the same way walk finds
+// it: array elements and map entries are dropped, other strings are
+// replaced. It returns the new value and the number of changes.
+func erase(v interface{}, m *matcher) (interface{}, int) {
+	switch v := v.(type) {
+	case string:
+		if m.Match(v) {
+			return redacted, 1
+		}
+	case []interface{}:
+		kept := make([]interface{}, 0, len(v))
+		n := 0
+		for _, item := range v {
+			if m.contains(item) {
+				n++
+				continue
+			}
+			kept = append(kept, item)
+		}
+		return kept, n
+	case map[string]interface{}:
+		n := 0
+		for k, item := range v {
+			if m.Match(k) {
+				delete(v, k)
+				n++
+				continue
+			}
+			var c int
+			v[k], c = erase(item, m)
+			n += c
+		}
+		return v, n
+	}
+	return v, 0
+}
+
+// eraseFile rewrites a data file without what it holds about the subject,
+// keeping its permissions, and returns the number of changes
+func eraseFile(file string, m *matcher) (int, error) {
+	v, err := readData(file)
+	if err != nil {
+		return 0, err
+	}
+	v, n := erase(v, m)
+	if n == 0 {
+		return 0, nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return 0, err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	tmp := file + ".erase-tmp"
+	if err := os.WriteFile(tmp, data, info.Mode().Perm()); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return n, nil
+}
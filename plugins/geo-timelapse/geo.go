@@ -0,0 +1,96 @@
+package geotimelapse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// City lookup limits
+const (
+	// ip-api.com allows 45 requests a minute without a key
+	lookupsPerMinute = 40
+	// Locations are looked up again after this long
+	cityTTL = 7 * 24 * time.Hour
+	// Failed lookups are retried after this long
+	cityRetry = time.Hour
+	// At most this many addresses are remembered
+	maxCities = 50000
+)
+
+// unknown is the key under which users without a location are counted
+const unknown = "unknown"
+
+// cityEntry is the cached location of an address
+type cityEntry struct {
+	Country string
+	City    string
+	Fetched time.Time
+	Failed  bool
+}
+
+// fresh reports whether the entry can still be used
+func (e *cityEntry) fresh(now time.Time) bool {
+	if e.Failed {
+		return now.Sub(e.Fetched) < cityRetry
+	}
+	return now.Sub(e.Fetched) < cityTTL
+}
+
+// key returns the name the city is counted under, "CC/City"
+func (e *cityEntry) key() string {
+	if e.Failed || e.City == "" {
+		return unknown
+	}
+	return e.Country + "/" + e.City
+}
+
+// httpClient is used for city lookups
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// lookupCity queries ip-api.com, which is free without a key but only
+// over plain HTTP
+func lookupCity(ctx context.Context, ip string) (*cityEntry, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	u := "http://ip-api.com/json/" + url.PathEscape(ip) + "?fields=status,message,countryCode,city"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ip-api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ip-api: unexpected status %s", resp.Status)
+	}
+
+	var out struct {
+		Status      string `json:"status"`
+		Message     string `json:"message"`
+		CountryCode string `json:"countryCode"`
+		City        string `json:"city"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("ip-api: %w", err)
+	}
+	if out.Status != "success" {
+		return nil, fmt.Errorf("ip-api: %s", out.Message)
+	}
+	return &cityEntry{Country: out.CountryCode, City: out.City}, nil
+}
+
+// publicIP reports whether ip is worth looking up
+func publicIP(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr != nil && !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast() && !addr.IsUnspecified()
+}
@@ -0,0 +1,467 @@
+// Geo Timelapse Plugin for UnrealIRCd Web Panel
+// Records where connected users are each hour, by country and optionally
+// by city, for a map that plays back how the network's geography shifts
+
+package geotimelapse
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Timelapse views
+const (
+	ViewHours = "hours"
+	ViewDay   = "day"
+)
+
+// GeoTimelapsePlugin implements the Plugin interface
+type GeoTimelapsePlugin struct {
+	config      Config
+	rpc         *jsonrpc.Client
+	hours       map[string]*Hour
+	cities      map[string]*cityEntry
+	dirty       bool
+	lastSample  time.Time
+	lastError   string
+	lookupError string
+	mu          sync.RWMutex
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	SampleInterval int    `json:"sample_interval"`
+	CityLookup     bool   `json:"city_lookup"`
+	RetentionDays  int    `json:"retention_days"`
+}
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	IP    string `json:"ip"`
+	GeoIP struct {
+		CountryCode string `json:"country_code"`
+	} `json:"geoip"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &GeoTimelapsePlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/geo-timelapse",
+			SampleInterval: 300,
+			RetentionDays:  14,
+		},
+		hours:  make(map[string]*Hour),
+		cities: make(map[string]*cityEntry),
+	}
+}
+
+// Info returns plugin metadata
+func (p *GeoTimelapsePlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Geo Timelapse",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Hourly snapshots of where users connect from",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *GeoTimelapsePlugin) Init() error {
+	p.mu.Lock()
+	stored := make(map[string]*Hour)
+	if err := storage.LoadJSON(p.storePath(), &stored); err != nil {
+		log.Printf("[geo-timelapse] failed to load snapshots: %v", err)
+	}
+	for key, h := range stored {
+		if h != nil && h.Countries != nil {
+			p.hours[key] = h
+		}
+	}
+	p.mu.Unlock()
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.sampleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *GeoTimelapsePlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *GeoTimelapsePlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/geo-timelapse")
+	{
+		plugin.GET("/timelapse", p.handleTimelapse)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the snapshot file
+func (p *GeoTimelapsePlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "hours.json")
+}
+
+// save persists the snapshots if they changed
+func (p *GeoTimelapsePlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.hours); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *GeoTimelapsePlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// sampleLoop takes a sample every sample_interval until shutdown
+func (p *GeoTimelapsePlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	p.sample()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.sample()
+			if err := p.save(); err != nil {
+				log.Printf("[geo-timelapse] failed to save snapshots: %v", err)
+			}
+		}
+	}
+}
+
+// sample counts connected users by country, and by city when enabled,
+// into the current hour. Addresses without a known city count as unknown
+// until they have been looked up; a sample looks up at most
+// lookupsPerMinute of them.
+func (p *GeoTimelapsePlugin) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result)
+
+	p.mu.Lock()
+	if err != nil {
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		log.Printf("[geo-timelapse] failed to list users: %v", err)
+		return
+	}
+	p.lastError = ""
+	now := time.Now().UTC()
+	p.lastSample = now
+
+	countries := make(map[string]int)
+	var cities map[string]int
+	pending := make([]string, 0)
+	if p.config.CityLookup {
+		cities = make(map[string]int)
+	}
+	queued := make(map[string]bool)
+	for _, u := range result.List {
+		cc := u.GeoIP.CountryCode
+		if cc == "" {
+			cc = unknown
+		}
+		countries[cc]++
+		if cities == nil {
+			continue
+		}
+		if e, ok := p.cities[u.IP]; ok && e.fresh(now) {
+			cities[e.key()]++
+			continue
+		}
+		cities[unknown]++
+		if publicIP(u.IP) && !queued[u.IP] && len(pending) < lookupsPerMinute {
+			queued[u.IP] = true
+			pending = append(pending, u.IP)
+		}
+	}
+
+	key := hourKey(now)
+	h, ok := p.hours[key]
+	if !ok {
+		h = &Hour{Countries: make(map[string]int)}
+		p.hours[key] = h
+	}
+	h.add(countries, cities)
+	p.prune(now)
+	p.dirty = true
+	p.mu.Unlock()
+
+	if len(pending) > 0 {
+		p.lookup(pending)
+	}
+}
+
+// lookup finds the cities of addresses and caches them
+func (p *GeoTimelapsePlugin) lookup(ips []string) {
+	var lastErr string
+	for _, ip := range ips {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		e, err := lookupCity(ctx, ip)
+		cancel()
+		if err != nil {
+			lastErr = err.Error()
+			e = &cityEntry{Failed: true}
+		}
+		e.Fetched = time.Now().UTC()
+
+		p.mu.Lock()
+		p.storeCity(ip, e)
+		p.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	p.lookupError = lastErr
+	p.mu.Unlock()
+}
+
+// storeCity caches the city of an address, making room by dropping
+// entries that are no longer fresh. Caller must hold p.mu.
+func (p *GeoTimelapsePlugin) storeCity(ip string, e *cityEntry) {
+	if _, ok := p.cities[ip]; !ok && len(p.cities) >= maxCities {
+		for key, old := range p.cities {
+			if !old.fresh(e.Fetched) {
+				delete(p.cities, key)
+			}
+		}
+		if len(p.cities) >= maxCities {
+			return
+		}
+	}
+	p.cities[ip] = e
+}
+
+// prune drops hours older than the retention period. Caller must hold p.mu.
+func (p *GeoTimelapsePlugin) prune(now time.Time) {
+	cutoff := hourKey(now.AddDate(0, 0, -p.config.RetentionDays))
+	for key := range p.hours {
+		if key < cutoff {
+			delete(p.hours, key)
+			p.dirty = true
+		}
+	}
+}
+
+// handleTimelapse returns frames for the last ?days= days, 7 by default.
+// With ?view=hours (the default) there is a frame for every hour, oldest
+// first; with ?view=day the days are laid over each other into 24 frames,
+// one per hour of the day (UTC). Places outside the ?top= busiest, 20 by
+// default, are added together as "other".
+func (p *GeoTimelapsePlugin) handleTimelapse(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	days := 7
+	if s := c.Query("days"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > p.config.RetentionDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and " + strconv.Itoa(p.config.RetentionDays)})
+			return
+		}
+		days = v
+	}
+	top := 20
+	if s := c.Query("top"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > 250 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "top must be between 1 and 250"})
+			return
+		}
+		top = v
+	}
+	view := c.DefaultQuery("view", ViewHours)
+	if view != ViewHours && view != ViewDay {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "view must be hours or day"})
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	start := now.Add(-time.Duration(days*24-1) * time.Hour)
+	keys := make([]string, 0, days*24)
+	hours := make([]*Hour, 0, days*24)
+	for t := start; !t.After(now); t = t.Add(time.Hour) {
+		key := t.Format(time.RFC3339)
+		keys = append(keys, key)
+		if h, ok := p.hours[key]; ok {
+			hours = append(hours, h)
+		} else {
+			hours = append(hours, &Hour{})
+		}
+	}
+	countryKeys, keepCountries := topKeys(hours, top, false)
+	cityKeys, keepCities := topKeys(hours, top, true)
+
+	frames := make([]Frame, 0, len(hours))
+	if view == ViewHours {
+		for i, h := range hours {
+			f := frame(h.Samples, h.Users, h.Countries, h.Cities, keepCountries, keepCities)
+			f.Time = keys[i]
+			frames = append(frames, f)
+		}
+	} else {
+		var byHour [24]Hour
+		for i := range byHour {
+			byHour[i].Countries = make(map[string]int)
+		}
+		for i, h := range hours {
+			t, _ := time.Parse(time.RFC3339, keys[i])
+			byHour[t.Hour()].merge(h)
+		}
+		for i := range byHour {
+			hour := i
+			f := frame(byHour[i].Samples, byHour[i].Users, byHour[i].Countries, byHour[i].Cities, keepCountries, keepCities)
+			f.Hour = &hour
+			frames = append(frames, f)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"view":      view,
+		"days":      days,
+		"countries": countryKeys,
+		"cities":    cityKeys,
+		"frames":    frames,
+	})
+}
+
+// handleStatus returns the sampling and lookup state
+func (p *GeoTimelapsePlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"hours":          len(p.hours),
+		"city_lookup":    p.config.CityLookup,
+		"cities_cached":  len(p.cities),
+		"retention_days": p.config.RetentionDays,
+		"last_error":     p.lastError,
+		"lookup_error":   p.lookupError,
+	}
+	if !p.lastSample.IsZero() {
+		status["last_sample"] = p.lastSample
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *GeoTimelapsePlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *GeoTimelapsePlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.SampleInterval < 60 || newConfig.SampleInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sample_interval must be between 60 and 3600 seconds"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 90"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *GeoTimelapsePlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *GeoTimelapsePlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,130 @@
+package geotimelapse
+
+import (
+	"sort"
+	"time"
+)
+
+// Hour sums the samples taken in one UTC hour
+type Hour struct {
+	Samples   int            `json:"samples"`
+	Users     int            `json:"users"`
+	Countries map[string]int `json:"countries"`
+	Cities    map[string]int `json:"cities,omitempty"`
+}
+
+// add folds one sample into the hour
+func (h *Hour) add(countries, cities map[string]int) {
+	h.Samples++
+	for cc, n := range countries {
+		h.Countries[cc] += n
+		h.Users += n
+	}
+	if len(cities) > 0 && h.Cities == nil {
+		h.Cities = make(map[string]int)
+	}
+	for city, n := range cities {
+		h.Cities[city] += n
+	}
+}
+
+// merge adds the samples of another hour to this one
+func (h *Hour) merge(o *Hour) {
+	h.Samples += o.Samples
+	h.Users += o.Users
+	for cc, n := range o.Countries {
+		h.Countries[cc] += n
+	}
+	if len(o.Cities) > 0 && h.Cities == nil {
+		h.Cities = make(map[string]int)
+	}
+	for city, n := range o.Cities {
+		h.Cities[city] += n
+	}
+}
+
+// Frame is the average number of users in each place over one hour, or
+// over one hour of the day across several days
+type Frame struct {
+	Time      string             `json:"time,omitempty"`
+	Hour      *int               `json:"hour,omitempty"`
+	Samples   int                `json:"samples"`
+	Users     float64            `json:"users"`
+	Countries map[string]float64 `json:"countries"`
+	Cities    map[string]float64 `json:"cities,omitempty"`
+}
+
+// frame averages summed counts over the samples taken
+func frame(samples, users int, countries, cities map[string]int, keep, keepCities map[string]bool) Frame {
+	f := Frame{Samples: samples, Countries: make(map[string]float64)}
+	if samples == 0 {
+		return f
+	}
+	f.Users = round1(float64(users) / float64(samples))
+	f.Countries = average(countries, samples, keep)
+	if len(cities) > 0 {
+		f.Cities = average(cities, samples, keepCities)
+	}
+	return f
+}
+
+// average divides counts by the samples taken, adding those not kept
+// together as "other"
+func average(counts map[string]int, samples int, keep map[string]bool) map[string]float64 {
+	out := make(map[string]float64, len(keep)+1)
+	other := 0
+	for key, n := range counts {
+		if keep[key] {
+			out[key] = round1(float64(n) / float64(samples))
+		} else {
+			other += n
+		}
+	}
+	if other > 0 {
+		out["other"] = round1(float64(other) / float64(samples))
+	}
+	return out
+}
+
+// topKeys returns the n places with the most users summed over the hours,
+// as a list in order and as a set
+func topKeys(hours []*Hour, n int, cities bool) ([]string, map[string]bool) {
+	totals := make(map[string]int)
+	for _, h := range hours {
+		m := h.Countries
+		if cities {
+			m = h.Cities
+		}
+		for key, c := range m {
+			totals[key] += c
+		}
+	}
+	keys := make([]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]] != totals[keys[j]] {
+			return totals[keys[i]] > totals[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return keys, set
+}
+
+// hourKey returns the key of the hour holding t
+func hourKey(t time.Time) string {
+	return t.UTC().Truncate(time.Hour).Format(time.RFC3339)
+}
+
+// round1 rounds to one decimal place
+func round1(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
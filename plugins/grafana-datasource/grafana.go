@@ -0,0 +1,264 @@
+package grafanadatasource
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sample is one reading of the network's numbers
+type Sample struct {
+	Time        time.Time      `json:"t"`
+	Users       int            `json:"users"`
+	Opers       int            `json:"opers"`
+	Channels    int            `json:"channels"`
+	Servers     int            `json:"servers"`
+	Bans        int            `json:"bans"`
+	ServerUsers map[string]int `json:"server_users,omitempty"`
+}
+
+// Metric and table names served to Grafana
+const (
+	serverUsersPrefix = "server_users:"
+	tableNetsplits    = "netsplits"
+	tableIncidents    = "incidents"
+)
+
+// metricNames are the network-wide metrics, in the order search lists them
+var metricNames = []string{"users", "opers", "channels", "servers", "bans"}
+
+// value returns the reading of a metric in a sample
+func (s *Sample) value(metric string) (float64, bool) {
+	switch metric {
+	case "users":
+		return float64(s.Users), true
+	case "opers":
+		return float64(s.Opers), true
+	case "channels":
+		return float64(s.Channels), true
+	case "servers":
+		return float64(s.Servers), true
+	case "bans":
+		return float64(s.Bans), true
+	}
+	if name := strings.TrimPrefix(metric, serverUsersPrefix); name != metric {
+		n, ok := s.ServerUsers[name]
+		return float64(n), ok
+	}
+	return 0, false
+}
+
+// timeRange is the dashboard's time range
+type timeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// searchRequest is the body of /search
+type searchRequest struct {
+	Target string `json:"target"`
+}
+
+// queryTarget is one query of a panel
+type queryTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	Type   string `json:"type"`
+	Hide   bool   `json:"hide"`
+}
+
+// queryRequest is the body of /query
+type queryRequest struct {
+	Range         timeRange     `json:"range"`
+	MaxDataPoints int           `json:"maxDataPoints"`
+	Targets       []queryTarget `json:"targets"`
+}
+
+// annotationRequest is the body of /annotations
+type annotationRequest struct {
+	Range      timeRange `json:"range"`
+	Annotation struct {
+		Name   string `json:"name"`
+		Query  string `json:"query"`
+		Enable bool   `json:"enable"`
+	} `json:"annotation"`
+}
+
+// timeSeries is a query result in the time series format. Each data point
+// is a value and a time in milliseconds.
+type timeSeries struct {
+	Target     string       `json:"target"`
+	RefID      string       `json:"refId,omitempty"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// column describes a table column
+type column struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+// table is a query result in the table format
+type table struct {
+	Type    string          `json:"type"`
+	RefID   string          `json:"refId,omitempty"`
+	Columns []column        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// metricList returns the metrics the samples can answer, matching filter
+// when it is not empty
+func metricList(samples []Sample, filter string) []string {
+	servers := make(map[string]bool)
+	for _, s := range samples {
+		for name := range s.ServerUsers {
+			servers[name] = true
+		}
+	}
+	perServer := make([]string, 0, len(servers))
+	for name := range servers {
+		perServer = append(perServer, serverUsersPrefix+name)
+	}
+	sort.Strings(perServer)
+
+	all := append(append(append([]string{}, metricNames...), perServer...), tableNetsplits, tableIncidents)
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	list := make([]string, 0, len(all))
+	for _, name := range all {
+		if filter == "" || strings.Contains(strings.ToLower(name), filter) {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
+// points returns the readings of a metric in the range, oldest first.
+// Samples must be sorted by time.
+func points(samples []Sample, metric string, from, to time.Time) [][2]float64 {
+	start := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(from) })
+	out := make([][2]float64, 0)
+	for _, s := range samples[start:] {
+		if s.Time.After(to) {
+			break
+		}
+		if v, ok := s.value(metric); ok {
+			out = append(out, [2]float64{v, float64(millis(s.Time))})
+		}
+	}
+	return out
+}
+
+// downsample averages runs of points so no more than max are left. The
+// time of each run is the time of its first point.
+func downsample(pts [][2]float64, max int) [][2]float64 {
+	if max <= 0 || len(pts) <= max {
+		return pts
+	}
+	size := (len(pts) + max - 1) / max
+	out := make([][2]float64, 0, max)
+	for i := 0; i < len(pts); i += size {
+		end := i + size
+		if end > len(pts) {
+			end = len(pts)
+		}
+		sum := 0.0
+		for _, pt := range pts[i:end] {
+			sum += pt[0]
+		}
+		out = append(out, [2]float64{sum / float64(end-i), pts[i][1]})
+	}
+	return out
+}
+
+// metricTable returns a metric's readings as a table of time and value
+func metricTable(metric string, pts [][2]float64) table {
+	t := table{
+		Type:    "table",
+		Columns: []column{{Text: "Time", Type: "time"}, {Text: metric, Type: "number"}},
+		Rows:    make([][]interface{}, 0, len(pts)),
+	}
+	for _, pt := range pts {
+		t.Rows = append(t.Rows, []interface{}{int64(pt[1]), pt[0]})
+	}
+	return t
+}
+
+// netsplitTable lists the netsplits in the range, newest first
+func netsplitTable(list []netsplit, from, to time.Time) table {
+	t := table{
+		Type: "table",
+		Columns: []column{
+			{Text: "Start", Type: "time"},
+			{Text: "End", Type: "time"},
+			{Text: "Duration", Type: "number"},
+			{Text: "Servers", Type: "string"},
+			{Text: "Users lost", Type: "number"},
+		},
+		Rows: make([][]interface{}, 0),
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		ns := list[i]
+		if !overlaps(ns.Start, ns.End, from, to) {
+			continue
+		}
+		names := make([]string, 0, len(ns.Servers))
+		for _, s := range ns.Servers {
+			names = append(names, s.Name)
+		}
+		var end interface{}
+		duration := time.Since(ns.Start)
+		if ns.End != nil {
+			end = millis(*ns.End)
+			duration = ns.End.Sub(ns.Start)
+		}
+		t.Rows = append(t.Rows, []interface{}{millis(ns.Start), end, int64(duration.Seconds()), strings.Join(names, ", "), ns.UsersLost})
+	}
+	return t
+}
+
+// escalationTable lists the escalated incidents in the range, newest first
+func escalationTable(list []escalation, from, to time.Time) table {
+	t := table{
+		Type: "table",
+		Columns: []column{
+			{Text: "Triggered", Type: "time"},
+			{Text: "Resolved", Type: "time"},
+			{Text: "State", Type: "string"},
+			{Text: "Severity", Type: "string"},
+			{Text: "Type", Type: "string"},
+			{Text: "Source", Type: "string"},
+			{Text: "Summary", Type: "string"},
+		},
+		Rows: make([][]interface{}, 0),
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		inc := list[i]
+		if !overlaps(inc.TriggeredAt, inc.ResolvedAt, from, to) {
+			continue
+		}
+		var resolved interface{}
+		if inc.ResolvedAt != nil {
+			resolved = millis(*inc.ResolvedAt)
+		}
+		t.Rows = append(t.Rows, []interface{}{millis(inc.TriggeredAt), resolved, inc.State, inc.Severity, inc.Type, inc.Source, inc.Summary})
+	}
+	return t
+}
+
+// parseTime reads a time given as RFC 3339 or as milliseconds since 1970,
+// as Grafana sends them in query strings
+func parseTime(s string, fallback time.Time) (time.Time, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)), nil
+}
@@ -0,0 +1,588 @@
+// Grafana Datasource Plugin for UnrealIRCd Web Panel
+// Serves network statistics, netsplits and incidents to Grafana through the
+// simple JSON datasource API
+
+package grafanadatasource
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// saveEvery is how often new samples are written to disk
+const saveEvery = 5 * time.Minute
+
+// GrafanaDatasourcePlugin implements the Plugin interface
+type GrafanaDatasourcePlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	samples    []Sample
+	lastSample time.Time
+	sampleErr  string
+	queries    int
+	lastQuery  time.Time
+	dirty      bool
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL          string `json:"rpc_url"`
+	RPCUser         string `json:"rpc_user"`
+	RPCPassword     string `json:"rpc_password"`
+	RPCInsecure     bool   `json:"rpc_insecure"`
+	DataDir         string `json:"data_dir"`
+	DatasourceToken string `json:"datasource_token"`
+	SampleInterval  int    `json:"sample_interval"`
+	RetentionDays   int    `json:"retention_days"`
+	PerServer       bool   `json:"per_server"`
+	NetsplitsFile   string `json:"netsplits_file"`
+	IncidentsFile   string `json:"incidents_file"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Samples []Sample `json:"samples"`
+}
+
+// statsResult is the part of stats.get we sample
+type statsResult struct {
+	Server struct {
+		Total int `json:"total"`
+	} `json:"server"`
+	User struct {
+		Total int `json:"total"`
+		Oper  int `json:"oper"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+	ServerBan struct {
+		Total int `json:"total"`
+	} `json:"server_ban"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		NumUsers int `json:"num_users"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &GrafanaDatasourcePlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/grafana-datasource",
+			SampleInterval: 60,
+			RetentionDays:  14,
+			PerServer:      true,
+			NetsplitsFile:  "data/plugins/netsplit-tracker/netsplits.json",
+			IncidentsFile:  "data/plugins/incident-escalation/incident-escalation.json",
+		},
+		samples: make([]Sample, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *GrafanaDatasourcePlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Grafana Datasource",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Serves network statistics, netsplits and incidents to Grafana",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *GrafanaDatasourcePlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[grafana-datasource] failed to load data: %v", err)
+	}
+	if data.Samples != nil {
+		p.samples = data.Samples
+		sort.Slice(p.samples, func(i, j int) bool { return p.samples[i].Time.Before(p.samples[j].Time) })
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "grafana-datasource-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"samples": len(p.samples),
+			"queries": p.queries,
+		}
+		if len(p.samples) > 0 {
+			content["since"] = p.samples[0].Time.Format("2006-01-02")
+		}
+		if p.config.DatasourceToken == "" {
+			content["status"] = "No datasource token set"
+		}
+		return plugins.DashboardCard{
+			Title:   "Grafana Datasource",
+			Icon:    "LineChart",
+			Content: content,
+			Order:   69,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.sampleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *GrafanaDatasourcePlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *GrafanaDatasourcePlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/grafana-datasource")
+	{
+		// Simple JSON datasource API
+		plugin.GET("/", p.handleTest)
+		plugin.POST("/search", p.handleSearch)
+		plugin.POST("/query", p.handleQuery)
+		plugin.POST("/annotations", p.handleAnnotations)
+		// Plain JSON for the Infinity datasource
+		plugin.GET("/series", p.handleSeries)
+
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file samples are kept in
+func (p *GrafanaDatasourcePlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "samples.json")
+}
+
+// save writes samples to disk if they changed
+func (p *GrafanaDatasourcePlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Samples: p.samples}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *GrafanaDatasourcePlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// sampleLoop samples the network every sample_interval until shutdown
+func (p *GrafanaDatasourcePlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	lastSave := time.Now()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.sample()
+		if time.Since(lastSave) >= saveEvery {
+			if err := p.save(); err != nil {
+				log.Printf("[grafana-datasource] failed to save data: %v", err)
+			}
+			lastSave = time.Now()
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// sample records the network's numbers and drops samples older than the
+// retention
+func (p *GrafanaDatasourcePlugin) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	p.mu.RLock()
+	perServer := p.config.PerServer
+	p.mu.RUnlock()
+
+	rpc := p.client()
+	var stats statsResult
+	err := rpc.Call(ctx, "stats.get", nil, &stats)
+	var servers struct {
+		List []rpcServer `json:"list"`
+	}
+	if err == nil && perServer {
+		err = rpc.Call(ctx, "server.list", nil, &servers)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.sampleErr = err.Error()
+		log.Printf("[grafana-datasource] failed to sample: %v", err)
+		return
+	}
+	p.sampleErr = ""
+
+	now := time.Now().UTC()
+	s := Sample{
+		Time:     now,
+		Users:    stats.User.Total,
+		Opers:    stats.User.Oper,
+		Channels: stats.Channel.Total,
+		Servers:  stats.Server.Total,
+		Bans:     stats.ServerBan.Total,
+	}
+	if perServer {
+		s.ServerUsers = make(map[string]int, len(servers.List))
+		for _, srv := range servers.List {
+			s.ServerUsers[srv.Name] = srv.Server.NumUsers
+		}
+	}
+	p.samples = append(p.samples, s)
+	p.lastSample = now
+
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays)
+	drop := sort.Search(len(p.samples), func(i int) bool { return !p.samples[i].Time.Before(cutoff) })
+	if drop > 0 {
+		p.samples = append([]Sample(nil), p.samples[drop:]...)
+	}
+	p.dirty = true
+}
+
+// authorized checks the datasource token of a request, from a bearer
+// Authorization header or the token query parameter, and answers 401 when
+// it does not match
+func (p *GrafanaDatasourcePlugin) authorized(c *gin.Context) bool {
+	p.mu.RLock()
+	token := p.config.DatasourceToken
+	p.mu.RUnlock()
+
+	given := c.Query("token")
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		given = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid datasource token"})
+		return false
+	}
+	return true
+}
+
+// countQuery records a query for the status page
+func (p *GrafanaDatasourcePlugin) countQuery() {
+	p.mu.Lock()
+	p.queries++
+	p.lastQuery = time.Now()
+	p.mu.Unlock()
+}
+
+// handleTest answers Grafana's connection test
+func (p *GrafanaDatasourcePlugin) handleTest(c *gin.Context) {
+	if !p.authorized(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Data source is working"})
+}
+
+// handleSearch lists the metrics and tables that can be queried
+func (p *GrafanaDatasourcePlugin) handleSearch(c *gin.Context) {
+	if !p.authorized(c) {
+		return
+	}
+	var req searchRequest
+	// Grafana may send an empty body
+	_ = c.ShouldBindJSON(&req)
+
+	p.mu.RLock()
+	list := metricList(p.samples, req.Target)
+	p.mu.RUnlock()
+	c.JSON(http.StatusOK, list)
+}
+
+// handleQuery answers a panel's queries with time series or tables
+func (p *GrafanaDatasourcePlugin) handleQuery(c *gin.Context) {
+	if !p.authorized(c) {
+		return
+	}
+	var req queryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query"})
+		return
+	}
+	from, to := req.Range.From, req.Range.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	p.countQuery()
+
+	p.mu.RLock()
+	netsplitsFile := p.config.NetsplitsFile
+	incidentsFile := p.config.IncidentsFile
+	p.mu.RUnlock()
+
+	results := make([]interface{}, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		if t.Hide || t.Target == "" {
+			continue
+		}
+		switch t.Target {
+		case tableNetsplits:
+			list, err := loadNetsplits(netsplitsFile)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			tbl := netsplitTable(list, from, to)
+			tbl.RefID = t.RefID
+			results = append(results, tbl)
+		case tableIncidents:
+			list, err := loadEscalations(incidentsFile)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			tbl := escalationTable(list, from, to)
+			tbl.RefID = t.RefID
+			results = append(results, tbl)
+		default:
+			p.mu.RLock()
+			pts := downsample(points(p.samples, t.Target, from, to), req.MaxDataPoints)
+			p.mu.RUnlock()
+			if t.Type == "table" {
+				tbl := metricTable(t.Target, pts)
+				tbl.RefID = t.RefID
+				results = append(results, tbl)
+			} else {
+				results = append(results, timeSeries{Target: t.Target, RefID: t.RefID, Datapoints: pts})
+			}
+		}
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// handleAnnotations returns netsplits and incidents in the range. The
+// annotation's query picks "netsplits" or "incidents"; empty gives both.
+func (p *GrafanaDatasourcePlugin) handleAnnotations(c *gin.Context) {
+	if !p.authorized(c) {
+		return
+	}
+	var req annotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid annotation query"})
+		return
+	}
+	from, to := req.Range.From, req.Range.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	query := strings.ToLower(strings.TrimSpace(req.Annotation.Query))
+	p.countQuery()
+
+	p.mu.RLock()
+	netsplitsFile := p.config.NetsplitsFile
+	incidentsFile := p.config.IncidentsFile
+	p.mu.RUnlock()
+
+	out := make([]Annotation, 0)
+	if query == "" || query == tableNetsplits {
+		list, err := loadNetsplits(netsplitsFile)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out = append(out, netsplitAnnotations(list, from, to)...)
+	}
+	if query == "" || query == tableIncidents {
+		list, err := loadEscalations(incidentsFile)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out = append(out, escalationAnnotations(list, from, to)...)
+	}
+	// Older Grafana versions match results to the annotation by this field
+	for i := range out {
+		out[i].Annotation = req.Annotation
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// handleSeries returns a metric as a plain list of times and values, for
+// the Infinity datasource. The range defaults to the last 24 hours.
+func (p *GrafanaDatasourcePlugin) handleSeries(c *gin.Context) {
+	if !p.authorized(c) {
+		return
+	}
+	metric := c.Query("target")
+	if metric == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target is required"})
+		return
+	}
+	now := time.Now()
+	from, err := parseTime(c.Query("from"), now.Add(-24*time.Hour))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := parseTime(c.Query("to"), now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	max, _ := strconv.Atoi(c.Query("max"))
+	p.countQuery()
+
+	p.mu.RLock()
+	pts := downsample(points(p.samples, metric, from, to), max)
+	p.mu.RUnlock()
+
+	rows := make([]gin.H, 0, len(pts))
+	for _, pt := range pts {
+		rows = append(rows, gin.H{"time": time.Unix(0, int64(pt[1])*int64(time.Millisecond)).UTC(), "value": pt[0]})
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// handleStatus returns sampling and query state
+func (p *GrafanaDatasourcePlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"samples":   len(p.samples),
+		"error":     p.sampleErr,
+		"queries":   p.queries,
+		"token_set": p.config.DatasourceToken != "",
+		"metrics":   metricList(p.samples, ""),
+	}
+	if len(p.samples) > 0 {
+		status["oldest"] = p.samples[0].Time
+	}
+	if !p.lastSample.IsZero() {
+		status["last_sample"] = p.lastSample
+	}
+	if !p.lastQuery.IsZero() {
+		status["last_query"] = p.lastQuery
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *GrafanaDatasourcePlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.DatasourceToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *GrafanaDatasourcePlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.SampleInterval < 10 || newConfig.SampleInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sample_interval must be between 10 and 3600 seconds"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 365"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DatasourceToken == "" {
+		newConfig.DatasourceToken = p.config.DatasourceToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *GrafanaDatasourcePlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *GrafanaDatasourcePlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,143 @@
+package grafanadatasource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// netsplit is the part of a netsplit-tracker incident we serve
+type netsplit struct {
+	ID        string     `json:"id"`
+	Start     time.Time  `json:"start"`
+	End       *time.Time `json:"end"`
+	UsersLost int        `json:"users_lost"`
+	Servers   []struct {
+		Name   string `json:"name"`
+		Uplink string `json:"uplink"`
+	} `json:"servers"`
+}
+
+// escalation is the part of an incident-escalation incident we serve
+type escalation struct {
+	ID          string     `json:"id"`
+	Source      string     `json:"source"`
+	Type        string     `json:"type"`
+	Severity    string     `json:"severity"`
+	Summary     string     `json:"summary"`
+	State       string     `json:"state"`
+	TriggeredAt time.Time  `json:"triggered_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+}
+
+// loadNetsplits reads the incidents stored by the netsplit-tracker plugin.
+// A missing file gives an empty list.
+func loadNetsplits(path string) ([]netsplit, error) {
+	var data struct {
+		Incidents []netsplit `json:"incidents"`
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if err := storage.LoadJSON(path, &data); err != nil {
+		return nil, fmt.Errorf("netsplits %s: %w", path, err)
+	}
+	return data.Incidents, nil
+}
+
+// loadEscalations reads the incidents stored by the incident-escalation
+// plugin. A missing file gives an empty list.
+func loadEscalations(path string) ([]escalation, error) {
+	var data struct {
+		Incidents []escalation `json:"incidents"`
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if err := storage.LoadJSON(path, &data); err != nil {
+		return nil, fmt.Errorf("incidents %s: %w", path, err)
+	}
+	return data.Incidents, nil
+}
+
+// overlaps reports whether something that ran from start to end, or is
+// still running when end is nil, overlaps the range from..to
+func overlaps(start time.Time, end *time.Time, from, to time.Time) bool {
+	if start.After(to) {
+		return false
+	}
+	return end == nil || !end.Before(from)
+}
+
+// Annotation is an event in the simple-JSON annotations format
+type Annotation struct {
+	Annotation interface{} `json:"annotation,omitempty"`
+	Time       int64       `json:"time"`
+	TimeEnd    int64       `json:"timeEnd,omitempty"`
+	IsRegion   bool        `json:"isRegion,omitempty"`
+	Title      string      `json:"title"`
+	Text       string      `json:"text"`
+	Tags       []string    `json:"tags"`
+}
+
+// millis returns t in milliseconds since 1970, as Grafana expects
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// netsplitAnnotations turns netsplits in the range into region annotations
+func netsplitAnnotations(list []netsplit, from, to time.Time) []Annotation {
+	out := make([]Annotation, 0)
+	for _, ns := range list {
+		if !overlaps(ns.Start, ns.End, from, to) {
+			continue
+		}
+		names := make([]string, 0, len(ns.Servers))
+		for _, s := range ns.Servers {
+			names = append(names, s.Name)
+		}
+		sort.Strings(names)
+		a := Annotation{
+			Time:  millis(ns.Start),
+			Title: fmt.Sprintf("Netsplit: %d server(s) lost", len(names)),
+			Text:  fmt.Sprintf("%s; %d users lost", strings.Join(names, ", "), ns.UsersLost),
+			Tags:  []string{"netsplit"},
+		}
+		if ns.End != nil {
+			a.TimeEnd = millis(*ns.End)
+			a.IsRegion = true
+		} else {
+			a.Tags = append(a.Tags, "ongoing")
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// escalationAnnotations turns escalated incidents in the range into
+// region annotations
+func escalationAnnotations(list []escalation, from, to time.Time) []Annotation {
+	out := make([]Annotation, 0)
+	for _, inc := range list {
+		if !overlaps(inc.TriggeredAt, inc.ResolvedAt, from, to) {
+			continue
+		}
+		a := Annotation{
+			Time:  millis(inc.TriggeredAt),
+			Title: inc.Summary,
+			Text:  fmt.Sprintf("%s from %s (%s)", inc.Type, inc.Source, inc.Severity),
+			Tags:  []string{"incident", inc.Type, inc.Severity},
+		}
+		if inc.ResolvedAt != nil {
+			a.TimeEnd = millis(*inc.ResolvedAt)
+			a.IsRegion = true
+		} else {
+			a.Tags = append(a.Tags, "ongoing")
+		}
+		out = append(out, a)
+	}
+	return out
+}
@@ -0,0 +1,270 @@
+/**
+ * GraphQL Gateway Frontend Script
+ *
+ * A query editor for the GraphQL endpoint, with example queries, variables,
+ * the response and the schema to look fields up in.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'graphql-gateway';
+  const PLUGIN_NAME = 'GraphQL Gateway';
+  const PAGE_PATH = '/plugins/graphql-gateway';
+  const API_BASE = '/api/plugin/graphql-gateway';
+
+  const EXAMPLES = {
+    'Overview': `{
+  stats { users opers channels servers serverBans }
+  servers { name userCount synced }
+  countries(limit: 10) { countryCode users }
+}`,
+    'Largest channels with members': `{
+  channels(minUsers: 10, limit: 5) {
+    name
+    userCount
+    topic
+    members { nick level user { account geo { countryCode } } }
+  }
+}`,
+    'Users by country': `query ($country: String!) {
+  users(country: $country, limit: 50) {
+    nick
+    account
+    geo { countryCode asn asName }
+    server { name }
+    channelNames
+  }
+}`,
+    'User of a nick': `query ($nick: String!) {
+  user(nick: $nick) {
+    nick username hostname ip account reputation
+    channels { name userCount }
+  }
+}`,
+    'Users over the last day': `{
+  statsHistory(minutes: 1440) { time users opers channels }
+}`,
+    'G-lines': `{
+  serverBans(type: "gline") { name reason setBy expireAt }
+}`,
+  };
+
+  const EXAMPLE_VARIABLES = {
+    'Users by country': '{ "country": "NL" }',
+    'User of a nick': '{ "nick": "Valware" }',
+  };
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const res = await fetch(API_BASE + path, {
+      method,
+      headers: getAuthHeaders(),
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok && !data.errors) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('graphql-gateway-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'graphql-gateway-styles';
+    style.textContent = `
+      .gqlg-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .gqlg-main { display: grid; grid-template-columns: 1fr 1fr; gap: 1rem; }
+      .gqlg-editor { display: flex; flex-direction: column; gap: 0.5rem; min-width: 0; }
+      .gqlg-toolbar { display: flex; gap: 0.5rem; align-items: center; flex-wrap: wrap; }
+      .gqlg-app textarea, .gqlg-app select { background: var(--bg-secondary, #1e1e2e); color: var(--text-primary, #cdd6f4); border: 1px solid var(--border-primary, #313244); border-radius: 6px; padding: 0.4rem; }
+      .gqlg-app textarea { font-family: monospace; font-size: 0.85rem; width: 100%; box-sizing: border-box; tab-size: 2; }
+      .gqlg-query { min-height: 22rem; }
+      .gqlg-vars { min-height: 5rem; }
+      .gqlg-app button.gqlg-btn { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); border: none; border-radius: 6px; padding: 0.4rem 0.8rem; cursor: pointer; }
+      .gqlg-app button:disabled { opacity: 0.5; cursor: default; }
+      .gqlg-result { background: var(--bg-secondary, #1e1e2e); border-radius: 6px; padding: 0.6rem; min-height: 22rem; max-height: 70vh; overflow: auto; white-space: pre; font-family: monospace; font-size: 0.85rem; color: var(--text-primary, #cdd6f4); margin: 0; }
+      .gqlg-error { color: var(--error, #f38ba8); }
+      .gqlg-desc { font-size: 0.8rem; }
+      .gqlg-app details summary { cursor: pointer; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function run(container) {
+    const btn = container.querySelector('#gqlg-run');
+    const out = container.querySelector('#gqlg-result');
+    const info = container.querySelector('#gqlg-info');
+    let variables;
+    const raw = container.querySelector('#gqlg-vars').value.trim();
+    if (raw) {
+      try {
+        variables = JSON.parse(raw);
+      } catch (e) {
+        info.innerHTML = `<span class="gqlg-error">Variables are not valid JSON: ${escapeHtml(e.message)}</span>`;
+        return;
+      }
+    }
+
+    btn.disabled = true;
+    const start = performance.now();
+    try {
+      const data = await api('POST', '/graphql', { query: container.querySelector('#gqlg-query').value, variables });
+      const ms = Math.round(performance.now() - start);
+      const size = JSON.stringify(data).length;
+      info.innerHTML = data.errors
+        ? `<span class="gqlg-error">${data.errors.length} error(s)</span>, ${ms} ms`
+        : `${ms} ms, ${(size / 1024).toFixed(1)} KiB`;
+      out.textContent = JSON.stringify(data, null, 2);
+    } catch (err) {
+      info.innerHTML = `<span class="gqlg-error">${escapeHtml(err.message)}</span>`;
+    }
+    btn.disabled = false;
+  }
+
+  async function loadSchema(container) {
+    const el = container.querySelector('#gqlg-schema');
+    try {
+      const res = await fetch(API_BASE + '/schema', { headers: getAuthHeaders() });
+      if (!res.ok) throw new Error(`Request failed with status ${res.status}`);
+      el.textContent = await res.text();
+    } catch (e) {
+      el.innerHTML = `<span class="gqlg-error">${escapeHtml(e.message)}</span>`;
+    }
+  }
+
+  async function loadStatus(container) {
+    const el = container.querySelector('#gqlg-status');
+    try {
+      const s = await api('GET', '/status');
+      el.innerHTML = `${s.queries} queries served, ${s.samples} stats samples${s.oldest ? ` since ${formatTime(s.oldest)}` : ''}
+        ${s.error ? `<span class="gqlg-error">Sampling failed: ${escapeHtml(s.error)}</span>` : ''}`;
+    } catch (e) {
+      el.innerHTML = `<span class="gqlg-error">${escapeHtml(e.message)}</span>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="gqlg-app" data-plugin="${PLUGIN_ID}">
+        <div id="gqlg-status" class="gqlg-desc">Loading...</div>
+        <div class="gqlg-main">
+          <div class="gqlg-editor">
+            <div class="gqlg-toolbar">
+              <select id="gqlg-example">
+                <option value="">Examples...</option>
+                ${Object.keys(EXAMPLES).map(name => `<option>${escapeHtml(name)}</option>`).join('')}
+              </select>
+              <button class="gqlg-btn" id="gqlg-run">Run (Ctrl+Enter)</button>
+              <span id="gqlg-info" class="gqlg-desc"></span>
+            </div>
+            <textarea id="gqlg-query" class="gqlg-query" spellcheck="false">${escapeHtml(EXAMPLES['Overview'])}</textarea>
+            <label class="gqlg-desc" for="gqlg-vars">Variables (JSON)</label>
+            <textarea id="gqlg-vars" class="gqlg-vars" spellcheck="false"></textarea>
+          </div>
+          <pre id="gqlg-result" class="gqlg-result"></pre>
+        </div>
+        <details>
+          <summary>Schema</summary>
+          <pre id="gqlg-schema" class="gqlg-result"></pre>
+        </details>
+        <div class="gqlg-desc">Endpoint: <code>POST ${API_BASE}/graphql</code> with <code>{"query": ..., "variables": ...}</code> and the panel token.</div>
+      </div>
+    `;
+
+    const query = container.querySelector('#gqlg-query');
+    container.querySelector('#gqlg-example').addEventListener('change', e => {
+      const name = e.target.value;
+      if (!name) return;
+      query.value = EXAMPLES[name];
+      container.querySelector('#gqlg-vars').value = EXAMPLE_VARIABLES[name] || '';
+      e.target.value = '';
+    });
+    container.querySelector('#gqlg-run').addEventListener('click', () => run(container));
+    container.querySelectorAll('textarea').forEach(el => {
+      el.addEventListener('keydown', e => {
+        if (e.key === 'Enter' && (e.ctrlKey || e.metaKey)) {
+          e.preventDefault();
+          run(container);
+        } else if (e.key === 'Tab' && el === query) {
+          e.preventDefault();
+          const pos = el.selectionStart;
+          el.value = el.value.slice(0, pos) + '  ' + el.value.slice(el.selectionEnd);
+          el.selectionStart = el.selectionEnd = pos + 2;
+        }
+      });
+    });
+
+    loadStatus(container);
+    loadSchema(container);
+    run(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('graphql-gateway-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,657 @@
+package graphqlgateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxValues is how many values a single response may hold
+const maxValues = 200000
+
+// record is a value of an object type, keyed by field name
+type record map[string]interface{}
+
+// resolver computes a field of an object. Fields without a resolver read
+// their name from the parent record.
+type resolver func(r *request, parent interface{}, args map[string]interface{}) (interface{}, error)
+
+// field is a field of an object type
+type field struct {
+	name        string
+	typ         string
+	description string
+	args        []argDef
+	resolve     resolver
+}
+
+// argDef is an argument a field takes
+type argDef struct {
+	name        string
+	typ         string
+	description string
+}
+
+// objectType is an object type of the schema
+type objectType struct {
+	name        string
+	description string
+	fields      []*field
+	byName      map[string]*field
+}
+
+// schema is the set of object types, with Query as the root
+type schema struct {
+	types map[string]*objectType
+	order []string
+}
+
+// newSchema returns an empty schema
+func newSchema() *schema {
+	return &schema{types: make(map[string]*objectType)}
+}
+
+// object adds an object type with its fields
+func (s *schema) object(name, description string, fields ...*field) {
+	t := &objectType{name: name, description: description, fields: fields, byName: make(map[string]*field, len(fields))}
+	for _, f := range fields {
+		t.byName[f.name] = f
+	}
+	s.types[name] = t
+	s.order = append(s.order, name)
+}
+
+// typeInfo is a parsed field type: a named type, optionally in a list,
+// either of which may be non-null
+type typeInfo struct {
+	name        string
+	list        bool
+	nonNull     bool
+	elemNonNull bool
+}
+
+// parseType parses a type such as [User!]!
+func parseType(s string) typeInfo {
+	t := typeInfo{}
+	if strings.HasSuffix(s, "!") {
+		t.nonNull = true
+		s = strings.TrimSuffix(s, "!")
+	}
+	if strings.HasPrefix(s, "[") {
+		t.list = true
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+		if strings.HasSuffix(s, "!") {
+			t.elemNonNull = true
+			s = strings.TrimSuffix(s, "!")
+		}
+	}
+	t.name = s
+	return t
+}
+
+// isScalar reports whether name is a built in scalar type
+func isScalar(name string) bool {
+	switch name {
+	case "String", "Int", "Float", "Boolean", "ID":
+		return true
+	}
+	return false
+}
+
+// gqlError is an error in a response
+type gqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// orderedMap is a response object, which keeps the order fields were
+// selected in
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// MarshalJSON writes the fields in order
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// executor runs one operation of a document
+type executor struct {
+	schema   *schema
+	doc      *document
+	op       *operation
+	vars     map[string]interface{}
+	req      *request
+	errors   []gqlError
+	maxDepth int
+	values   int
+}
+
+// execute runs the named operation of a query against the root Query
+// type. Errors in the query itself are returned as an error; errors while
+// resolving fields are collected in the response, next to partial data.
+func (s *schema) execute(req *request, query, operationName string, variables map[string]interface{}, maxDepth int) (interface{}, []gqlError, error) {
+	doc, err := parseQuery(query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("syntax error: %v", err)
+	}
+
+	var op *operation
+	for _, o := range doc.operations {
+		if operationName == "" || o.name == operationName {
+			if op != nil {
+				return nil, nil, fmt.Errorf("operationName is required when the document has several operations")
+			}
+			op = o
+		}
+	}
+	if op == nil {
+		return nil, nil, fmt.Errorf("unknown operation %q", operationName)
+	}
+	if op.kind != "query" {
+		return nil, nil, fmt.Errorf("only queries are supported, this endpoint is read-only")
+	}
+
+	vars, err := coerceVariables(op, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e := &executor{schema: s, doc: doc, op: op, vars: vars, req: req, maxDepth: maxDepth}
+	if err := e.validate(s.types["Query"], op.selection, 1, make(map[string]bool)); err != nil {
+		return nil, nil, err
+	}
+	data, _ := e.selectionSet(s.types["Query"], nil, op.selection, nil)
+	if data == nil {
+		return nil, e.errors, nil
+	}
+	return data, e.errors, nil
+}
+
+// coerceVariables checks the given variables against the operation's
+// declarations and fills in defaults
+func coerceVariables(op *operation, given map[string]interface{}) (map[string]interface{}, error) {
+	vars := make(map[string]interface{}, len(op.variables))
+	for _, def := range op.variables {
+		v, ok := given[def.name]
+		if !ok && def.fallback != nil {
+			v, ok = def.fallback.resolve(nil), true
+		}
+		if !ok || v == nil {
+			if def.nonNull {
+				return nil, fmt.Errorf("variable $%s of type %s is required", def.name, def.typ)
+			}
+			continue
+		}
+		c, err := coerceInput(def.typ, v)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %v", def.name, err)
+		}
+		vars[def.name] = c
+	}
+	return vars, nil
+}
+
+// coerceInput converts an input value to the Go value of typ: int, float64,
+// string, bool or a slice of those
+func coerceInput(typ string, v interface{}) (interface{}, error) {
+	t := parseType(typ)
+	if v == nil {
+		if t.nonNull {
+			return nil, fmt.Errorf("expected %s, got null", typ)
+		}
+		return nil, nil
+	}
+	if t.list {
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+		elem := t.name
+		if t.elemNonNull {
+			elem += "!"
+		}
+		out := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			c, err := coerceInput(elem, item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, c)
+		}
+		return out, nil
+	}
+	if n, ok := v.(int); ok {
+		v = float64(n)
+	}
+	switch t.name {
+	case "Int":
+		if f, ok := v.(float64); ok && f == float64(int(f)) && f >= -2147483648 && f <= 2147483647 {
+			return int(f), nil
+		}
+	case "Float":
+		if f, ok := v.(float64); ok {
+			return f, nil
+		}
+	case "String":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case "ID":
+		switch id := v.(type) {
+		case string:
+			return id, nil
+		case float64:
+			if id == float64(int64(id)) {
+				return fmt.Sprintf("%d", int64(id)), nil
+			}
+		}
+	case "Boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown input type %s", t.name)
+	}
+	return nil, fmt.Errorf("expected %s", typ)
+}
+
+// validate checks a selection set against a type before anything is
+// resolved: fields and arguments must exist, required arguments must be
+// given, objects need a selection and scalars may not have one
+func (e *executor) validate(t *objectType, sels []*selection, depth int, spreading map[string]bool) error {
+	if depth > e.maxDepth {
+		return fmt.Errorf("the query is nested deeper than %d levels", e.maxDepth)
+	}
+	for _, sel := range sels {
+		for _, d := range sel.directive {
+			if d.name != "skip" && d.name != "include" {
+				return fmt.Errorf("unknown directive @%s", d.name)
+			}
+			if _, ok := d.args["if"]; !ok {
+				return fmt.Errorf("directive @%s needs an if argument", d.name)
+			}
+		}
+		switch {
+		case sel.spread != "":
+			f, ok := e.doc.fragments[sel.spread]
+			if !ok {
+				return fmt.Errorf("unknown fragment %s", sel.spread)
+			}
+			if spreading[f.name] {
+				return fmt.Errorf("fragment %s spreads itself", f.name)
+			}
+			if err := e.checkCondition(t, f.on); err != nil {
+				return err
+			}
+			spreading[f.name] = true
+			err := e.validate(t, f.selection, depth, spreading)
+			delete(spreading, f.name)
+			if err != nil {
+				return err
+			}
+		case sel.inline:
+			if sel.on != "" {
+				if err := e.checkCondition(t, sel.on); err != nil {
+					return err
+				}
+			}
+			if err := e.validate(t, sel.selection, depth, spreading); err != nil {
+				return err
+			}
+		default:
+			if err := e.validateField(t, sel, depth, spreading); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkCondition checks the type condition of a fragment spread into t.
+// The schema has no interfaces or unions, so it must name t itself.
+func (e *executor) checkCondition(t *objectType, on string) error {
+	if _, ok := e.schema.types[on]; !ok {
+		return fmt.Errorf("unknown type %s", on)
+	}
+	if on != t.name {
+		return fmt.Errorf("fragment on %s can't be spread into %s", on, t.name)
+	}
+	return nil
+}
+
+// validateField checks a field selection
+func (e *executor) validateField(t *objectType, sel *selection, depth int, spreading map[string]bool) error {
+	if sel.name == "__typename" {
+		if sel.selection != nil {
+			return fmt.Errorf("field __typename of type String! must not have a selection")
+		}
+		return nil
+	}
+	if sel.name == "__schema" || sel.name == "__type" {
+		return fmt.Errorf("introspection is not supported, fetch the schema from /schema instead")
+	}
+	f, ok := t.byName[sel.name]
+	if !ok {
+		return fmt.Errorf("cannot query field %s on type %s", sel.name, t.name)
+	}
+	for name, v := range sel.args {
+		var def *argDef
+		for i := range f.args {
+			if f.args[i].name == name {
+				def = &f.args[i]
+			}
+		}
+		if def == nil {
+			return fmt.Errorf("unknown argument %s on field %s.%s", name, t.name, f.name)
+		}
+		if err := e.validateVariables(v); err != nil {
+			return err
+		}
+	}
+	for _, def := range f.args {
+		if _, ok := sel.args[def.name]; !ok && strings.HasSuffix(def.typ, "!") {
+			return fmt.Errorf("field %s.%s needs argument %s of type %s", t.name, f.name, def.name, def.typ)
+		}
+	}
+
+	ti := parseType(f.typ)
+	if isScalar(ti.name) {
+		if sel.selection != nil {
+			return fmt.Errorf("field %s of type %s must not have a selection", f.name, f.typ)
+		}
+		return nil
+	}
+	if sel.selection == nil {
+		return fmt.Errorf("field %s of type %s must have a selection of subfields", f.name, f.typ)
+	}
+	return e.validate(e.schema.types[ti.name], sel.selection, depth+1, spreading)
+}
+
+// validateVariables checks that the variables a value uses are declared
+func (e *executor) validateVariables(v *value) error {
+	switch v.kind {
+	case valVariable:
+		for _, def := range e.op.variables {
+			if def.name == v.text {
+				return nil
+			}
+		}
+		return fmt.Errorf("variable $%s is not declared", v.text)
+	case valList:
+		for _, item := range v.list {
+			if err := e.validateVariables(item); err != nil {
+				return err
+			}
+		}
+	case valObject:
+		for _, f := range v.fields {
+			if err := e.validateVariables(f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// included reports whether the @skip and @include directives keep a
+// selection
+func (e *executor) included(sel *selection) bool {
+	for _, d := range sel.directive {
+		cond, _ := d.args["if"].resolve(e.vars).(bool)
+		if (d.name == "skip" && cond) || (d.name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// collected is a response key with the field selections that produce it
+type collected struct {
+	key  string
+	sels []*selection
+}
+
+// collect flattens fragments into the fields selected on t, grouped by
+// response key in order of first appearance
+func (e *executor) collect(sels []*selection, out []*collected, index map[string]*collected) []*collected {
+	for _, sel := range sels {
+		if !e.included(sel) {
+			continue
+		}
+		switch {
+		case sel.spread != "":
+			out = e.collect(e.doc.fragments[sel.spread].selection, out, index)
+		case sel.inline:
+			out = e.collect(sel.selection, out, index)
+		default:
+			key := sel.alias
+			if key == "" {
+				key = sel.name
+			}
+			if c, ok := index[key]; ok {
+				c.sels = append(c.sels, sel)
+				continue
+			}
+			c := &collected{key: key, sels: []*selection{sel}}
+			index[key] = c
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// selectionSet resolves the selected fields of an object. It returns false
+// when a non-null field came out null, which makes the object null.
+func (e *executor) selectionSet(t *objectType, parent interface{}, sels []*selection, path []interface{}) (*orderedMap, bool) {
+	fields := e.collect(sels, nil, make(map[string]*collected))
+	out := &orderedMap{values: make(map[string]interface{}, len(fields))}
+	for _, c := range fields {
+		sel := c.sels[0]
+		fieldPath := append(append([]interface{}(nil), path...), c.key)
+		out.keys = append(out.keys, c.key)
+		if sel.name == "__typename" {
+			out.values[c.key] = t.name
+			continue
+		}
+
+		f := t.byName[sel.name]
+		subs := make([]*selection, 0)
+		for _, s := range c.sels {
+			subs = append(subs, s.selection...)
+		}
+		v, ok := e.field(f, parent, sel, subs, fieldPath)
+		if !ok {
+			return nil, false
+		}
+		out.values[c.key] = v
+	}
+	return out, true
+}
+
+// field resolves one field and completes its value
+func (e *executor) field(f *field, parent interface{}, sel *selection, subs []*selection, path []interface{}) (interface{}, bool) {
+	args := make(map[string]interface{}, len(f.args))
+	for _, def := range f.args {
+		v, ok := sel.args[def.name]
+		if !ok {
+			continue
+		}
+		c, err := coerceInput(def.typ, v.resolve(e.vars))
+		if err != nil {
+			return e.fail(parseType(f.typ), fmt.Sprintf("argument %s: %v", def.name, err), path)
+		}
+		if c != nil {
+			args[def.name] = c
+		}
+	}
+
+	var v interface{}
+	if f.resolve != nil {
+		var err error
+		if v, err = f.resolve(e.req, parent, args); err != nil {
+			return e.fail(parseType(f.typ), err.Error(), path)
+		}
+	} else if rec, ok := parent.(record); ok {
+		v = rec[f.name]
+	}
+	return e.complete(parseType(f.typ), v, subs, path)
+}
+
+// fail records an error for a field and returns null for it
+func (e *executor) fail(t typeInfo, message string, path []interface{}) (interface{}, bool) {
+	e.errors = append(e.errors, gqlError{Message: message, Path: path})
+	return nil, !t.nonNull
+}
+
+// complete turns a resolved value into its response value
+func (e *executor) complete(t typeInfo, v interface{}, subs []*selection, path []interface{}) (interface{}, bool) {
+	if isNull(v) {
+		if t.nonNull {
+			return e.fail(t, "cannot return null for a non-null field", path)
+		}
+		return nil, true
+	}
+	e.values++
+	if e.values > maxValues {
+		return e.fail(t, "the result is too large, narrow the query", path)
+	}
+
+	if t.list {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return e.fail(t, "expected a list", path)
+		}
+		elem := typeInfo{name: t.name, nonNull: t.elemNonNull}
+		out := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, ok := e.complete(elem, rv.Index(i).Interface(), subs, append(append([]interface{}(nil), path...), i))
+			if !ok {
+				if t.nonNull {
+					return nil, false
+				}
+				return nil, true
+			}
+			out = append(out, item)
+		}
+		return out, true
+	}
+
+	if isScalar(t.name) {
+		s, err := serialize(t.name, v)
+		if err != nil {
+			return e.fail(t, err.Error(), path)
+		}
+		return s, true
+	}
+
+	obj, ok := e.selectionSet(e.schema.types[t.name], v, subs, path)
+	if !ok {
+		return nil, !t.nonNull
+	}
+	return obj, true
+}
+
+// isNull reports whether v is nil or a nil pointer, map or slice
+func isNull(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// serialize converts a resolved value to a scalar of the named type
+func serialize(name string, v interface{}) (interface{}, error) {
+	switch name {
+	case "Int":
+		switch n := v.(type) {
+		case int:
+			return n, nil
+		case int64:
+			return n, nil
+		case float64:
+			return int64(n), nil
+		}
+	case "Float":
+		switch n := v.(type) {
+		case int:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+	case "Boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case "String", "ID":
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case fmt.Stringer:
+			return s.String(), nil
+		case int, int64, float64:
+			return fmt.Sprint(s), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot serialize %T as %s", v, name)
+}
+
+// sdl writes the schema in the GraphQL schema definition language
+func (s *schema) sdl() string {
+	var b strings.Builder
+	for i, name := range s.order {
+		t := s.types[name]
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if t.description != "" {
+			fmt.Fprintf(&b, "\"\"\"%s\"\"\"\n", t.description)
+		}
+		fmt.Fprintf(&b, "type %s {\n", t.name)
+		for _, f := range t.fields {
+			if f.description != "" {
+				fmt.Fprintf(&b, "  \"\"\"%s\"\"\"\n", f.description)
+			}
+			b.WriteString("  " + f.name)
+			if len(f.args) > 0 {
+				parts := make([]string, 0, len(f.args))
+				for _, a := range f.args {
+					part := a.name + ": " + a.typ
+					if a.description != "" {
+						part = "\"" + a.description + "\" " + part
+					}
+					parts = append(parts, part)
+				}
+				b.WriteString("(" + strings.Join(parts, ", ") + ")")
+			}
+			b.WriteString(": " + f.typ + "\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
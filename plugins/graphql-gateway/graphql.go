@@ -0,0 +1,618 @@
+package graphqlgateway
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file parses the query language of GraphQL: operations with
+// variables, fields with aliases and arguments, fragments and the @skip
+// and @include directives. Type system definitions are not accepted.
+
+// token kinds
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+// token is a lexical token of a query
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// lex splits a query into tokens, dropping whitespace, commas and comments
+func lex(src string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' || c == 0xef || c == 0xbb || c == 0xbf:
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, token{tokPunct, "...", i})
+			i += 3
+		case strings.ContainsRune("!$()=:@[]{}|&", rune(c)):
+			tokens = append(tokens, token{tokPunct, string(c), i})
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokName, src[start:i], start})
+		case c == '-' || isDigit(c):
+			start := i
+			i++
+			float := false
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.' || src[i] == 'e' || src[i] == 'E' ||
+				((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				if !isDigit(src[i]) {
+					float = true
+				}
+				i++
+			}
+			kind := tokInt
+			if float {
+				kind = tokFloat
+			}
+			tokens = append(tokens, token{kind, src[start:i], start})
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{tokString, blockString(src[i+3 : i+3+end]), i})
+			i += end + 6
+		case c == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(src) || src[i] == '\n' {
+					return nil, fmt.Errorf("unterminated string at %d", start)
+				}
+				if src[i] == '"' {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					esc := src[i+1]
+					switch esc {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case 'r':
+						b.WriteByte('\r')
+					case 'b':
+						b.WriteByte('\b')
+					case 'f':
+						b.WriteByte('\f')
+					case 'u':
+						if i+6 > len(src) {
+							return nil, fmt.Errorf("bad escape at %d", i)
+						}
+						r, err := strconv.ParseUint(src[i+2:i+6], 16, 32)
+						if err != nil {
+							return nil, fmt.Errorf("bad escape at %d", i)
+						}
+						b.WriteRune(rune(r))
+						i += 4
+					default:
+						b.WriteByte(esc)
+					}
+					i += 2
+					continue
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			tokens = append(tokens, token{tokString, b.String(), start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// blockString removes the common indentation of a """block string"""
+func blockString(s string) string {
+	lines := strings.Split(s, "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && (indent < 0 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		}
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// document is a parsed query
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query, mutation or subscription
+type operation struct {
+	kind      string
+	name      string
+	variables []varDef
+	selection []*selection
+}
+
+// varDef is a declared variable of an operation
+type varDef struct {
+	name     string
+	typ      string
+	nonNull  bool
+	fallback *value
+}
+
+// fragment is a named fragment
+type fragment struct {
+	name      string
+	on        string
+	selection []*selection
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	// Fields
+	alias     string
+	name      string
+	args      map[string]*value
+	directive []directive
+	selection []*selection
+
+	// Fragment spreads have spread set; inline fragments have inline set
+	// and on the type condition, if any
+	spread string
+	inline bool
+	on     string
+}
+
+// directive is a @name(args) annotation
+type directive struct {
+	name string
+	args map[string]*value
+}
+
+// value kinds
+const (
+	valNull = iota
+	valInt
+	valFloat
+	valString
+	valBool
+	valEnum
+	valList
+	valObject
+	valVariable
+)
+
+// value is a literal or variable in a query
+type value struct {
+	kind   int
+	text   string
+	list   []*value
+	fields map[string]*value
+}
+
+// parser holds the state of parsing a query
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseQuery parses a query document
+func parseQuery(src string) (*document, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.peek().kind != tokEOF {
+		switch {
+		case p.peekPunct("{"):
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selection: sel})
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peekName("fragment"):
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.fragments[f.name]; dup {
+				return nil, fmt.Errorf("fragment %s is defined twice", f.name)
+			}
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("the document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) peekPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+func (p *parser) peekName(s string) bool {
+	t := p.peek()
+	return t.kind == tokName && t.text == s
+}
+
+// unexpected returns an error for the next token
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("unexpected end of query")
+	}
+	return fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// expect consumes the punctuator s
+func (p *parser) expect(s string) error {
+	if !p.peekPunct(s) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+// name consumes a name
+func (p *parser) name() (string, error) {
+	if p.peek().kind != tokName {
+		return "", p.unexpected()
+	}
+	return p.next().text, nil
+}
+
+// operation parses a named operation
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.next().text}
+	if p.peek().kind == tokName {
+		op.name = p.next().text
+	}
+	if p.peekPunct("(") {
+		p.next()
+		for !p.peekPunct(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			v := varDef{}
+			var err error
+			if v.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if v.typ, err = p.typeRef(); err != nil {
+				return nil, err
+			}
+			v.nonNull = strings.HasSuffix(v.typ, "!")
+			if p.peekPunct("=") {
+				p.next()
+				if v.fallback, err = p.value(true); err != nil {
+					return nil, err
+				}
+			}
+			op.variables = append(op.variables, v)
+		}
+		p.next()
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selection = sel
+	return op, nil
+}
+
+// typeRef parses a type such as [String!]! into its text
+func (p *parser) typeRef() (string, error) {
+	var typ string
+	if p.peekPunct("[") {
+		p.next()
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.peekPunct("!") {
+		p.next()
+		typ += "!"
+	}
+	return typ, nil
+}
+
+// fragment parses a fragment definition
+func (p *parser) fragment() (*fragment, error) {
+	p.next()
+	f := &fragment{}
+	var err error
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if !p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	p.next()
+	if f.on, err = p.name(); err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	if f.selection, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// selectionSet parses { selections }
+func (p *parser) selectionSet() ([]*selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	list := make([]*selection, 0)
+	for !p.peekPunct("}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	p.next()
+	if len(list) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return list, nil
+}
+
+// selection parses a field, fragment spread or inline fragment
+func (p *parser) selection() (*selection, error) {
+	s := &selection{}
+	var err error
+	if p.peekPunct("...") {
+		p.next()
+		if p.peek().kind == tokName && p.peek().text != "on" {
+			s.spread = p.next().text
+			s.directive, err = p.directives()
+			return s, err
+		}
+		s.inline = true
+		if p.peekName("on") {
+			p.next()
+			if s.on, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if s.directive, err = p.directives(); err != nil {
+			return nil, err
+		}
+		s.selection, err = p.selectionSet()
+		return s, err
+	}
+
+	if s.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peekPunct(":") {
+		p.next()
+		s.alias = s.name
+		if s.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if s.args, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if s.directive, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peekPunct("{") {
+		if s.selection, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// arguments parses (name: value ...), if present
+func (p *parser) arguments() (map[string]*value, error) {
+	args := make(map[string]*value)
+	if !p.peekPunct("(") {
+		return args, nil
+	}
+	p.next()
+	for !p.peekPunct(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := args[name]; dup {
+			return nil, fmt.Errorf("argument %s is given twice", name)
+		}
+		args[name] = v
+	}
+	p.next()
+	return args, nil
+}
+
+// directives parses @name(args) annotations
+func (p *parser) directives() ([]directive, error) {
+	list := make([]directive, 0)
+	for p.peekPunct("@") {
+		p.next()
+		d := directive{}
+		var err error
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.args, err = p.arguments(); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, nil
+}
+
+// value parses a value. Constant values, such as variable defaults, may
+// not contain variables.
+func (p *parser) value(constant bool) (*value, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokPunct && t.text == "$" && !constant:
+		p.next()
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return &value{kind: valVariable, text: name}, nil
+	case t.kind == tokInt:
+		p.next()
+		return &value{kind: valInt, text: t.text}, nil
+	case t.kind == tokFloat:
+		p.next()
+		return &value{kind: valFloat, text: t.text}, nil
+	case t.kind == tokString:
+		p.next()
+		return &value{kind: valString, text: t.text}, nil
+	case t.kind == tokName:
+		p.next()
+		switch t.text {
+		case "true", "false":
+			return &value{kind: valBool, text: t.text}, nil
+		case "null":
+			return &value{kind: valNull}, nil
+		}
+		return &value{kind: valEnum, text: t.text}, nil
+	case t.kind == tokPunct && t.text == "[":
+		p.next()
+		v := &value{kind: valList}
+		for !p.peekPunct("]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+		}
+		p.next()
+		return v, nil
+	case t.kind == tokPunct && t.text == "{":
+		p.next()
+		v := &value{kind: valObject, fields: make(map[string]*value)}
+		for !p.peekPunct("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if v.fields[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+		return v, nil
+	}
+	return nil, p.unexpected()
+}
+
+// resolve turns a value into a Go value, looking up variables
+func (v *value) resolve(vars map[string]interface{}) interface{} {
+	switch v.kind {
+	case valInt:
+		n, _ := strconv.ParseInt(v.text, 10, 64)
+		return float64(n)
+	case valFloat:
+		f, _ := strconv.ParseFloat(v.text, 64)
+		return f
+	case valString, valEnum:
+		return v.text
+	case valBool:
+		return v.text == "true"
+	case valList:
+		list := make([]interface{}, 0, len(v.list))
+		for _, item := range v.list {
+			list = append(list, item.resolve(vars))
+		}
+		return list
+	case valObject:
+		obj := make(map[string]interface{}, len(v.fields))
+		for name, f := range v.fields {
+			obj[name] = f.resolve(vars)
+		}
+		return obj
+	case valVariable:
+		return vars[v.text]
+	}
+	return nil
+}
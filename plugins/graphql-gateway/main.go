@@ -0,0 +1,442 @@
+// GraphQL Gateway Plugin for UnrealIRCd Web Panel
+// A read-only GraphQL endpoint over users, channels, servers, bans, stats
+// history and GeoIP data, so dashboards fetch what they need in one request
+
+package graphqlgateway
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/apidocs"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// saveEvery is how often new samples are written to disk
+const saveEvery = 5 * time.Minute
+
+// maxQuerySize is the longest query accepted, in bytes
+const maxQuerySize = 64 << 10
+
+// GraphQLGatewayPlugin implements the Plugin interface
+type GraphQLGatewayPlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	schema     *schema
+	samples    []Sample
+	lastSample time.Time
+	sampleErr  string
+	queries    int
+	failed     int
+	lastQuery  time.Time
+	dirty      bool
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	SampleInterval int    `json:"sample_interval"`
+	RetentionDays  int    `json:"retention_days"`
+	MaxDepth       int    `json:"max_depth"`
+	MaxResults     int    `json:"max_results"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// Sample is the network's totals at one time, for statsHistory
+type Sample struct {
+	Time       time.Time `json:"time"`
+	Users      int       `json:"users"`
+	Opers      int       `json:"opers"`
+	Channels   int       `json:"channels"`
+	Servers    int       `json:"servers"`
+	ServerBans int       `json:"server_bans"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Samples []Sample `json:"samples"`
+}
+
+// queryRequest is a GraphQL request, as POSTed or given in the query string
+type queryRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &GraphQLGatewayPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/graphql-gateway",
+			SampleInterval: 60,
+			RetentionDays:  7,
+			MaxDepth:       8,
+			MaxResults:     1000,
+			TimeoutSeconds: 20,
+		},
+		schema:  buildSchema(),
+		samples: make([]Sample, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *GraphQLGatewayPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "GraphQL Gateway",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Read-only GraphQL endpoint over users, channels, servers, bans, stats history and GeoIP data",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *GraphQLGatewayPlugin) Init() error {
+	queryResponse := struct {
+		Data   interface{} `json:"data"`
+		Errors []gqlError  `json:"errors,omitempty"`
+	}{}
+	apidocs.Describe("graphql-gateway", "Read-only GraphQL over users, channels, servers, bans and stats history",
+		apidocs.Operation{
+			Method: "POST", Path: "/graphql", Summary: "Run a GraphQL query",
+			Description: "Errors in single fields are listed in errors next to the data that could be resolved.",
+			Request:     queryRequest{}, Response: queryResponse,
+		},
+		apidocs.Operation{
+			Method: "GET", Path: "/graphql", Summary: "Run a GraphQL query given in the query string",
+			Query: []apidocs.Param{
+				{Name: "query", Description: "The query document", Required: true},
+				{Name: "operationName", Description: "Operation to run when the document has several"},
+				{Name: "variables", Description: "Variables as a JSON object"},
+			},
+			Response: queryResponse,
+		},
+		apidocs.Operation{Method: "GET", Path: "/schema", Summary: "The schema in GraphQL schema definition language", ContentType: "text/plain"},
+		apidocs.Operation{Method: "GET", Path: "/status", Summary: "Sampling and query counters"},
+		apidocs.Operation{Method: "GET", Path: "/config", Summary: "Get current configuration", Response: Config{}},
+		apidocs.Operation{Method: "PUT", Path: "/config", Summary: "Update configuration", Request: Config{}},
+	)
+
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[graphql-gateway] failed to load data: %v", err)
+	}
+	if data.Samples != nil {
+		p.samples = data.Samples
+		sort.Slice(p.samples, func(i, j int) bool { return p.samples[i].Time.Before(p.samples[j].Time) })
+	}
+	p.mu.Unlock()
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.sampleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *GraphQLGatewayPlugin) Shutdown() error {
+	apidocs.Forget("graphql-gateway")
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *GraphQLGatewayPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/graphql-gateway")
+	{
+		plugin.POST("/graphql", p.handleQuery)
+		plugin.GET("/graphql", p.handleQuery)
+		plugin.GET("/schema", p.handleSchema)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file samples are kept in
+func (p *GraphQLGatewayPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "samples.json")
+}
+
+// save writes samples to disk if they changed
+func (p *GraphQLGatewayPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Samples: p.samples}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *GraphQLGatewayPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// sampleLoop samples the network every sample_interval until shutdown
+func (p *GraphQLGatewayPlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	lastSave := time.Now()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.sample()
+		if time.Since(lastSave) >= saveEvery {
+			if err := p.save(); err != nil {
+				log.Printf("[graphql-gateway] failed to save data: %v", err)
+			}
+			lastSave = time.Now()
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// sample records the network's totals and drops samples older than the
+// retention
+func (p *GraphQLGatewayPlugin) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var stats statsResult
+	err := p.client().Call(ctx, "stats.get", nil, &stats)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.sampleErr = err.Error()
+		log.Printf("[graphql-gateway] failed to sample: %v", err)
+		return
+	}
+	p.sampleErr = ""
+
+	now := time.Now().UTC()
+	p.samples = append(p.samples, Sample{
+		Time:       now,
+		Users:      stats.User.Total,
+		Opers:      stats.User.Oper,
+		Channels:   stats.Channel.Total,
+		Servers:    stats.Server.Total,
+		ServerBans: stats.ServerBan.Total,
+	})
+	p.lastSample = now
+
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays)
+	drop := sort.Search(len(p.samples), func(i int) bool { return !p.samples[i].Time.Before(cutoff) })
+	if drop > 0 {
+		p.samples = append([]Sample(nil), p.samples[drop:]...)
+	}
+	p.dirty = true
+}
+
+// queryError answers a request that could not be run, in the shape
+// GraphQL clients expect
+func queryError(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{"errors": []gqlError{{Message: message}}})
+}
+
+// handleQuery runs a GraphQL query, POSTed as JSON or given in the query
+// string as query, operationName and variables
+func (p *GraphQLGatewayPlugin) handleQuery(c *gin.Context) {
+	var req queryRequest
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			queryError(c, http.StatusBadRequest, "Invalid request")
+			return
+		}
+	} else {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if v := c.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				queryError(c, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	}
+	if req.Query == "" {
+		queryError(c, http.StatusBadRequest, "query is required")
+		return
+	}
+	if len(req.Query) > maxQuerySize {
+		queryError(c, http.StatusBadRequest, "query is too long")
+		return
+	}
+
+	p.mu.RLock()
+	cfg := p.config
+	samples := p.samples
+	p.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(cfg.TimeoutSeconds)*time.Second)
+	defer cancel()
+	r := &request{ctx: ctx, rpc: p.client(), samples: samples, maxResults: cfg.MaxResults}
+
+	start := time.Now()
+	data, errs, err := p.schema.execute(r, req.Query, req.OperationName, req.Variables, cfg.MaxDepth)
+
+	p.mu.Lock()
+	p.queries++
+	if err != nil || len(errs) > 0 {
+		p.failed++
+	}
+	p.lastQuery = start
+	p.mu.Unlock()
+
+	if err != nil {
+		queryError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp := gin.H{"data": data}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleSchema returns the schema in the GraphQL schema definition language
+func (p *GraphQLGatewayPlugin) handleSchema(c *gin.Context) {
+	c.String(http.StatusOK, p.schema.sdl())
+}
+
+// handleStatus returns sampling and query state
+func (p *GraphQLGatewayPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"samples": len(p.samples),
+		"error":   p.sampleErr,
+		"queries": p.queries,
+		"failed":  p.failed,
+	}
+	if len(p.samples) > 0 {
+		status["oldest"] = p.samples[0].Time
+	}
+	if !p.lastSample.IsZero() {
+		status["last_sample"] = p.lastSample
+	}
+	if !p.lastQuery.IsZero() {
+		status["last_query"] = p.lastQuery
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *GraphQLGatewayPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *GraphQLGatewayPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.SampleInterval < 10 || newConfig.SampleInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sample_interval must be between 10 and 3600 seconds"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 90"})
+		return
+	}
+	if newConfig.MaxDepth < 2 || newConfig.MaxDepth > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_depth must be between 2 and 20"})
+		return
+	}
+	if newConfig.MaxResults < 10 || newConfig.MaxResults > 100000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_results must be between 10 and 100000"})
+		return
+	}
+	if newConfig.TimeoutSeconds < 1 || newConfig.TimeoutSeconds > 120 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeout_seconds must be between 1 and 120"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *GraphQLGatewayPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *GraphQLGatewayPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
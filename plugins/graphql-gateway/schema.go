@@ -0,0 +1,741 @@
+package graphqlgateway
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// request holds what one query has fetched from UnrealIRCd, so each list
+// is fetched at most once however often the query refers to it
+type request struct {
+	ctx        context.Context
+	rpc        *jsonrpc.Client
+	samples    []Sample
+	maxResults int
+
+	users         []record
+	usersByNick   map[string]record
+	channels      []record
+	channelByName map[string]record
+	members       map[string][]interface{}
+	servers       []record
+	serverByName  map[string]record
+	bans          map[string][]record
+	stats         record
+	failed        map[string]error
+}
+
+// fail remembers that a call failed, so resolvers of later fields don't
+// repeat it
+func (r *request) fail(key string, err error) error {
+	if r.failed == nil {
+		r.failed = make(map[string]error)
+	}
+	r.failed[key] = err
+	return err
+}
+
+// rpcUser is a user as returned by user.list at detail level 2
+type rpcUser struct {
+	Name           string `json:"name"`
+	ID             string `json:"id"`
+	Hostname       string `json:"hostname"`
+	IP             string `json:"ip"`
+	ConnectedSince string `json:"connected_since"`
+	IdleSince      string `json:"idle_since"`
+	GeoIP          *struct {
+		CountryCode string `json:"country_code"`
+		ASN         *int   `json:"asn"`
+		ASName      string `json:"asname"`
+	} `json:"geoip"`
+	TLS *struct {
+		Cipher string `json:"cipher"`
+		CertFP string `json:"certfp"`
+	} `json:"tls"`
+	User struct {
+		Username       string        `json:"username"`
+		Realname       string        `json:"realname"`
+		Vhost          string        `json:"vhost"`
+		CloakedHost    string        `json:"cloakedhost"`
+		ServerName     string        `json:"servername"`
+		Account        string        `json:"account"`
+		Reputation     *int          `json:"reputation"`
+		SecurityGroups []string      `json:"security-groups"`
+		Modes          string        `json:"modes"`
+		OperLogin      string        `json:"operlogin"`
+		OperClass      string        `json:"operclass"`
+		Channels       []interface{} `json:"channels"`
+	} `json:"user"`
+}
+
+// rpcChannel is a channel as returned by channel.list
+type rpcChannel struct {
+	Name         string `json:"name"`
+	CreationTime string `json:"creation_time"`
+	NumUsers     int    `json:"num_users"`
+	Topic        string `json:"topic"`
+	TopicSetBy   string `json:"topic_set_by"`
+	TopicSetAt   string `json:"topic_set_at"`
+	Modes        string `json:"modes"`
+	Members      []struct {
+		Name  string `json:"name"`
+		Level string `json:"level"`
+	} `json:"members"`
+}
+
+// rpcServer is a server as returned by server.list
+type rpcServer struct {
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+	Info   string `json:"info"`
+	Server struct {
+		Uplink   string `json:"uplink"`
+		NumUsers int    `json:"num_users"`
+		BootTime string `json:"boot_time"`
+		Synced   bool   `json:"synced"`
+		Ulined   bool   `json:"ulined"`
+		Features struct {
+			Software string `json:"software"`
+		} `json:"features"`
+	} `json:"server"`
+}
+
+// rpcBan is an entry of server_ban.list, name_ban.list and
+// server_ban_exception.list
+type rpcBan struct {
+	Type           string `json:"type"`
+	Name           string `json:"name"`
+	Reason         string `json:"reason"`
+	SetBy          string `json:"set_by"`
+	SetAt          string `json:"set_at"`
+	ExpireAt       string `json:"expire_at"`
+	Duration       string `json:"duration_string"`
+	ExceptionTypes string `json:"exception_types"`
+}
+
+// statsResult is the part of stats.get the Stats type exposes
+type statsResult struct {
+	Server struct {
+		Total  int `json:"total"`
+		Ulined int `json:"ulined"`
+	} `json:"server"`
+	User struct {
+		Total  int `json:"total"`
+		Oper   int `json:"oper"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+	ServerBan struct {
+		Total int `json:"total"`
+	} `json:"server_ban"`
+}
+
+// opt returns s, or nil when it is empty, so absent strings are null
+func opt(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// optInt returns *n, or nil
+func optInt(n *int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
+
+// loadUsers fetches user.list
+func (r *request) loadUsers() error {
+	if r.users != nil {
+		return nil
+	}
+	if err := r.failed["users"]; err != nil {
+		return err
+	}
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	if err := r.rpc.Call(r.ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result); err != nil {
+		return r.fail("users", fmt.Errorf("user.list: %v", err))
+	}
+	r.users = make([]record, 0, len(result.List))
+	r.usersByNick = make(map[string]record, len(result.List))
+	for _, u := range result.List {
+		channels := make([]string, 0, len(u.User.Channels))
+		for _, c := range u.User.Channels {
+			switch c := c.(type) {
+			case string:
+				channels = append(channels, c)
+			case map[string]interface{}:
+				if name, ok := c["name"].(string); ok {
+					channels = append(channels, name)
+				}
+			}
+		}
+		groups := u.User.SecurityGroups
+		if groups == nil {
+			groups = []string{}
+		}
+		rec := record{
+			"id":             u.ID,
+			"nick":           u.Name,
+			"username":       opt(u.User.Username),
+			"realname":       opt(u.User.Realname),
+			"hostname":       opt(u.Hostname),
+			"ip":             opt(u.IP),
+			"vhost":          opt(u.User.Vhost),
+			"cloakedHost":    opt(u.User.CloakedHost),
+			"account":        opt(u.User.Account),
+			"modes":          opt(u.User.Modes),
+			"operLogin":      opt(u.User.OperLogin),
+			"operClass":      opt(u.User.OperClass),
+			"reputation":     optInt(u.User.Reputation),
+			"securityGroups": groups,
+			"connectedSince": opt(u.ConnectedSince),
+			"idleSince":      opt(u.IdleSince),
+			"serverName":     opt(u.User.ServerName),
+			"channelNames":   channels,
+		}
+		if u.TLS != nil {
+			rec["tlsCipher"] = opt(u.TLS.Cipher)
+			rec["certfp"] = opt(u.TLS.CertFP)
+		}
+		if u.GeoIP != nil && (u.GeoIP.CountryCode != "" || u.GeoIP.ASN != nil) {
+			rec["geo"] = record{
+				"countryCode": opt(u.GeoIP.CountryCode),
+				"asn":         optInt(u.GeoIP.ASN),
+				"asName":      opt(u.GeoIP.ASName),
+			}
+		}
+		r.users = append(r.users, rec)
+		r.usersByNick[strings.ToLower(u.Name)] = rec
+	}
+	return nil
+}
+
+// loadChannels fetches channel.list
+func (r *request) loadChannels() error {
+	if r.channels != nil {
+		return nil
+	}
+	if err := r.failed["channels"]; err != nil {
+		return err
+	}
+	var result struct {
+		List []rpcChannel `json:"list"`
+	}
+	if err := r.rpc.Call(r.ctx, "channel.list", map[string]interface{}{"object_detail_level": 1}, &result); err != nil {
+		return r.fail("channels", fmt.Errorf("channel.list: %v", err))
+	}
+	r.channels = make([]record, 0, len(result.List))
+	r.channelByName = make(map[string]record, len(result.List))
+	for _, c := range result.List {
+		rec := record{
+			"name":       c.Name,
+			"userCount":  c.NumUsers,
+			"topic":      opt(c.Topic),
+			"topicSetBy": opt(c.TopicSetBy),
+			"topicSetAt": opt(c.TopicSetAt),
+			"createdAt":  opt(c.CreationTime),
+			"modes":      opt(c.Modes),
+		}
+		r.channels = append(r.channels, rec)
+		r.channelByName[strings.ToLower(c.Name)] = rec
+	}
+	return nil
+}
+
+// loadMembers fetches channel.list at the detail level that includes
+// members. It is only done when a query asks for members, as the list is
+// much larger.
+func (r *request) loadMembers() error {
+	if r.members != nil {
+		return nil
+	}
+	if err := r.failed["members"]; err != nil {
+		return err
+	}
+	var result struct {
+		List []rpcChannel `json:"list"`
+	}
+	if err := r.rpc.Call(r.ctx, "channel.list", map[string]interface{}{"object_detail_level": 3}, &result); err != nil {
+		return r.fail("members", fmt.Errorf("channel.list: %v", err))
+	}
+	r.members = make(map[string][]interface{}, len(result.List))
+	for _, c := range result.List {
+		list := make([]interface{}, 0, len(c.Members))
+		for _, m := range c.Members {
+			list = append(list, record{"nick": m.Name, "level": opt(m.Level)})
+		}
+		r.members[strings.ToLower(c.Name)] = list
+	}
+	return nil
+}
+
+// loadServers fetches server.list
+func (r *request) loadServers() error {
+	if r.servers != nil {
+		return nil
+	}
+	if err := r.failed["servers"]; err != nil {
+		return err
+	}
+	var result struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := r.rpc.Call(r.ctx, "server.list", nil, &result); err != nil {
+		return r.fail("servers", fmt.Errorf("server.list: %v", err))
+	}
+	r.servers = make([]record, 0, len(result.List))
+	r.serverByName = make(map[string]record, len(result.List))
+	for _, s := range result.List {
+		rec := record{
+			"id":        opt(s.ID),
+			"name":      s.Name,
+			"info":      opt(s.Info),
+			"uplink":    opt(s.Server.Uplink),
+			"software":  opt(s.Server.Features.Software),
+			"userCount": s.Server.NumUsers,
+			"bootTime":  opt(s.Server.BootTime),
+			"synced":    s.Server.Synced,
+			"ulined":    s.Server.Ulined,
+		}
+		r.servers = append(r.servers, rec)
+		r.serverByName[strings.ToLower(s.Name)] = rec
+	}
+	return nil
+}
+
+// loadBans fetches a ban list by its method
+func (r *request) loadBans(method string) ([]record, error) {
+	if list, ok := r.bans[method]; ok {
+		return list, nil
+	}
+	if err := r.failed[method]; err != nil {
+		return nil, err
+	}
+	var result struct {
+		List []rpcBan `json:"list"`
+	}
+	var err error
+	switch method {
+	case "server_ban.list":
+		err = r.rpc.Call(r.ctx, "server_ban.list", nil, &result)
+	case "name_ban.list":
+		err = r.rpc.Call(r.ctx, "name_ban.list", nil, &result)
+	case "server_ban_exception.list":
+		err = r.rpc.Call(r.ctx, "server_ban_exception.list", nil, &result)
+	}
+	if err != nil {
+		return nil, r.fail(method, fmt.Errorf("%s: %v", method, err))
+	}
+	list := make([]record, 0, len(result.List))
+	for _, b := range result.List {
+		list = append(list, record{
+			"type":           b.Type,
+			"name":           b.Name,
+			"reason":         opt(b.Reason),
+			"setBy":          opt(b.SetBy),
+			"setAt":          opt(b.SetAt),
+			"expireAt":       opt(b.ExpireAt),
+			"duration":       opt(b.Duration),
+			"exceptionTypes": opt(b.ExceptionTypes),
+		})
+	}
+	if r.bans == nil {
+		r.bans = make(map[string][]record)
+	}
+	r.bans[method] = list
+	return list, nil
+}
+
+// loadStats fetches stats.get
+func (r *request) loadStats() (record, error) {
+	if r.stats != nil {
+		return r.stats, nil
+	}
+	if err := r.failed["stats"]; err != nil {
+		return nil, err
+	}
+	var s statsResult
+	if err := r.rpc.Call(r.ctx, "stats.get", nil, &s); err != nil {
+		return nil, r.fail("stats", fmt.Errorf("stats.get: %v", err))
+	}
+	r.stats = record{
+		"users":      s.User.Total,
+		"opers":      s.User.Oper,
+		"userRecord": s.User.Record,
+		"channels":   s.Channel.Total,
+		"servers":    s.Server.Total,
+		"serverBans": s.ServerBan.Total,
+	}
+	return r.stats, nil
+}
+
+// limit cuts a list to the limit argument, if given, and to max_results
+func (r *request) limit(list []interface{}, args map[string]interface{}) []interface{} {
+	n := r.maxResults
+	if l, ok := args["limit"].(int); ok && l >= 0 && l < n {
+		n = l
+	}
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// str returns a string argument, or ""
+func str(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+// strOf returns a string field of a record, or ""
+func strOf(rec record, name string) string {
+	s, _ := rec[name].(string)
+	return s
+}
+
+// buildSchema defines the graph of panel data
+func buildSchema() *schema {
+	s := newSchema()
+
+	// Fields that call UnrealIRCd are nullable, so one failed call leaves
+	// the rest of a query's data in place
+	s.object("Query", "The root of every query",
+		&field{name: "users", typ: "[User!]", description: "Connected users",
+			args: []argDef{
+				{name: "server", typ: "String", description: "Only users on this server"},
+				{name: "country", typ: "String", description: "Only users from this country code"},
+				{name: "account", typ: "String", description: "Only users logged in to this account"},
+				{name: "channel", typ: "String", description: "Only users in this channel"},
+				{name: "limit", typ: "Int"},
+			},
+			resolve: func(r *request, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				if err := r.loadUsers(); err != nil {
+					return nil, err
+				}
+				server, country, account, channel := str(args, "server"), str(args, "country"), str(args, "account"), str(args, "channel")
+				list := make([]interface{}, 0, len(r.users))
+				for _, u := range r.users {
+					if server != "" && !strings.EqualFold(strOf(u, "serverName"), server) {
+						continue
+					}
+					if account != "" && !strings.EqualFold(strOf(u, "account"), account) {
+						continue
+					}
+					if country != "" {
+						geo, _ := u["geo"].(record)
+						if geo == nil || !strings.EqualFold(strOf(geo, "countryCode"), country) {
+							continue
+						}
+					}
+					if channel != "" && !lists.ContainsFold(u["channelNames"].([]string), channel) {
+						continue
+					}
+					list = append(list, u)
+				}
+				return r.limit(list, args), nil
+			}},
+		&field{name: "user", typ: "User", description: "A user by nick",
+			args: []argDef{{name: "nick", typ: "String!"}},
+			resolve: func(r *request, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				if err := r.loadUsers(); err != nil {
+					return nil, err
+				}
+				return r.usersByNick[strings.ToLower(str(args, "nick"))], nil
+			}},
+		&field{name: "channels", typ: "[Channel!]", description: "Channels, largest first",
+			args: []argDef{
+				{name: "minUsers", typ: "Int", description: "Only channels with at least this many users"},
+				{name: "limit", typ: "Int"},
+			},
+			resolve: func(r *request, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				if err := r.loadChannels(); err != nil {
+					return nil, err
+				}
+				min, _ := args["minUsers"].(int)
+				list := make([]interface{}, 0, len(r.channels))
+				for _, c := range r.channels {
+					if c["userCount"].(int) >= min {
+						list = append(list, c)
+					}
+				}
+				sort.SliceStable(list, func(i, j int) bool {
+					return list[i].(record)["userCount"].(int) > list[j].(record)["userCount"].(int)
+				})
+				return r.limit(list, args), nil
+			}},
+		&field{name: "channel", typ: "Channel", description: "A channel by name",
+			args: []argDef{{name: "name", typ: "String!"}},
+			resolve: func(r *request, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				if err := r.loadChannels(); err != nil {
+					return nil, err
+				}
+				return r.channelByName[strings.ToLower(str(args, "name"))], nil
+			}},
+		&field{name: "servers", typ: "[Server!]", description: "Linked servers",
+			resolve: func(r *request, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+				if err := r.loadServers(); err != nil {
+					return nil, err
+				}
+				return r.servers, nil
+			}},
+		&field{name: "server", typ: "Server", description: "A server by name",
+			args: []argDef{{name: "name", typ: "String!"}},
+			resolve: func(r *request, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				if err := r.loadServers(); err != nil {
+					return nil, err
+				}
+				return r.serverByName[strings.ToLower(str(args, "name"))], nil
+			}},
+		&field{name: "serverBans", typ: "[Ban!]", description: "K/G/Z-lines, shuns and other server bans",
+			args: []argDef{
+				{name: "type", typ: "String", description: "Only bans of this type, such as gline"},
+				{name: "limit", typ: "Int"},
+			},
+			resolve: banList("server_ban.list")},
+		&field{name: "nameBans", typ: "[Ban!]", description: "Nick and channel name bans (Q-lines)",
+			args:    []argDef{{name: "limit", typ: "Int"}},
+			resolve: banList("name_ban.list")},
+		&field{name: "banExceptions", typ: "[Ban!]", description: "Exceptions to server bans",
+			args:    []argDef{{name: "limit", typ: "Int"}},
+			resolve: banList("server_ban_exception.list")},
+		&field{name: "stats", typ: "Stats", description: "Network totals right now",
+			resolve: func(r *request, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+				return r.loadStats()
+			}},
+		&field{name: "statsHistory", typ: "[StatsSample!]!", description: "Network totals sampled by this plugin, oldest first",
+			args: []argDef{
+				{name: "minutes", typ: "Int", description: "Only samples from the last this many minutes"},
+				{name: "limit", typ: "Int", description: "Only the latest this many samples"},
+			},
+			resolve: func(r *request, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				samples := r.samples
+				if minutes, ok := args["minutes"].(int); ok {
+					cutoff := time.Now().Add(-time.Duration(minutes) * time.Minute)
+					drop := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(cutoff) })
+					samples = samples[drop:]
+				}
+				n := r.maxResults
+				if l, ok := args["limit"].(int); ok && l >= 0 && l < n {
+					n = l
+				}
+				if len(samples) > n {
+					samples = samples[len(samples)-n:]
+				}
+				list := make([]interface{}, 0, len(samples))
+				for _, smp := range samples {
+					list = append(list, record{
+						"time":       smp.Time.Format(time.RFC3339),
+						"users":      smp.Users,
+						"opers":      smp.Opers,
+						"channels":   smp.Channels,
+						"servers":    smp.Servers,
+						"serverBans": smp.ServerBans,
+					})
+				}
+				return list, nil
+			}},
+		&field{name: "countries", typ: "[CountryCount!]", description: "Connected users by GeoIP country, most first",
+			args: []argDef{{name: "limit", typ: "Int"}},
+			resolve: func(r *request, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				if err := r.loadUsers(); err != nil {
+					return nil, err
+				}
+				counts := make(map[string]int)
+				for _, u := range r.users {
+					if geo, ok := u["geo"].(record); ok && strOf(geo, "countryCode") != "" {
+						counts[strings.ToUpper(strOf(geo, "countryCode"))]++
+					}
+				}
+				list := make([]interface{}, 0, len(counts))
+				for code, n := range counts {
+					list = append(list, record{"countryCode": code, "users": n})
+				}
+				sort.Slice(list, func(i, j int) bool {
+					a, b := list[i].(record), list[j].(record)
+					if a["users"].(int) != b["users"].(int) {
+						return a["users"].(int) > b["users"].(int)
+					}
+					return a["countryCode"].(string) < b["countryCode"].(string)
+				})
+				return r.limit(list, args), nil
+			}},
+	)
+
+	s.object("User", "A connected user",
+		&field{name: "id", typ: "ID!", description: "The UID"},
+		&field{name: "nick", typ: "String!"},
+		&field{name: "username", typ: "String"},
+		&field{name: "realname", typ: "String"},
+		&field{name: "hostname", typ: "String"},
+		&field{name: "ip", typ: "String"},
+		&field{name: "vhost", typ: "String"},
+		&field{name: "cloakedHost", typ: "String"},
+		&field{name: "account", typ: "String", description: "The services account, if logged in"},
+		&field{name: "modes", typ: "String"},
+		&field{name: "operLogin", typ: "String"},
+		&field{name: "operClass", typ: "String"},
+		&field{name: "reputation", typ: "Int"},
+		&field{name: "securityGroups", typ: "[String!]!"},
+		&field{name: "connectedSince", typ: "String"},
+		&field{name: "idleSince", typ: "String"},
+		&field{name: "tlsCipher", typ: "String"},
+		&field{name: "certfp", typ: "String"},
+		&field{name: "geo", typ: "Geo"},
+		&field{name: "serverName", typ: "String"},
+		&field{name: "server", typ: "Server", description: "The server the user is on",
+			resolve: func(r *request, parent interface{}, _ map[string]interface{}) (interface{}, error) {
+				if err := r.loadServers(); err != nil {
+					return nil, err
+				}
+				return r.serverByName[strings.ToLower(strOf(parent.(record), "serverName"))], nil
+			}},
+		&field{name: "channelNames", typ: "[String!]!"},
+		&field{name: "channels", typ: "[Channel!]!", description: "The channels the user is in",
+			resolve: func(r *request, parent interface{}, _ map[string]interface{}) (interface{}, error) {
+				if err := r.loadChannels(); err != nil {
+					return nil, err
+				}
+				list := make([]interface{}, 0)
+				for _, name := range parent.(record)["channelNames"].([]string) {
+					if c, ok := r.channelByName[strings.ToLower(name)]; ok {
+						list = append(list, c)
+					}
+				}
+				return list, nil
+			}},
+	)
+
+	s.object("Geo", "GeoIP data of a user",
+		&field{name: "countryCode", typ: "String"},
+		&field{name: "asn", typ: "Int"},
+		&field{name: "asName", typ: "String"},
+	)
+
+	s.object("Channel", "A channel",
+		&field{name: "name", typ: "String!"},
+		&field{name: "userCount", typ: "Int!"},
+		&field{name: "topic", typ: "String"},
+		&field{name: "topicSetBy", typ: "String"},
+		&field{name: "topicSetAt", typ: "String"},
+		&field{name: "createdAt", typ: "String"},
+		&field{name: "modes", typ: "String"},
+		&field{name: "members", typ: "[Member!]!", description: "The users in the channel",
+			resolve: func(r *request, parent interface{}, _ map[string]interface{}) (interface{}, error) {
+				if err := r.loadMembers(); err != nil {
+					return nil, err
+				}
+				list := r.members[strings.ToLower(strOf(parent.(record), "name"))]
+				if list == nil {
+					list = []interface{}{}
+				}
+				return list, nil
+			}},
+	)
+
+	s.object("Member", "A user in a channel",
+		&field{name: "nick", typ: "String!"},
+		&field{name: "level", typ: "String", description: "Channel status modes, such as o or v"},
+		&field{name: "user", typ: "User",
+			resolve: func(r *request, parent interface{}, _ map[string]interface{}) (interface{}, error) {
+				if err := r.loadUsers(); err != nil {
+					return nil, err
+				}
+				return r.usersByNick[strings.ToLower(strOf(parent.(record), "nick"))], nil
+			}},
+	)
+
+	s.object("Server", "A linked server",
+		&field{name: "id", typ: "ID", description: "The SID"},
+		&field{name: "name", typ: "String!"},
+		&field{name: "info", typ: "String"},
+		&field{name: "uplink", typ: "String"},
+		&field{name: "software", typ: "String"},
+		&field{name: "userCount", typ: "Int!"},
+		&field{name: "bootTime", typ: "String"},
+		&field{name: "synced", typ: "Boolean!"},
+		&field{name: "ulined", typ: "Boolean!", description: "Whether this is a services server"},
+		&field{name: "users", typ: "[User!]!", description: "The users on this server",
+			args: []argDef{{name: "limit", typ: "Int"}},
+			resolve: func(r *request, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				if err := r.loadUsers(); err != nil {
+					return nil, err
+				}
+				name := strOf(parent.(record), "name")
+				list := make([]interface{}, 0)
+				for _, u := range r.users {
+					if strings.EqualFold(strOf(u, "serverName"), name) {
+						list = append(list, u)
+					}
+				}
+				return r.limit(list, args), nil
+			}},
+	)
+
+	s.object("Ban", "A server ban, name ban or ban exception",
+		&field{name: "type", typ: "String!"},
+		&field{name: "name", typ: "String!", description: "The mask"},
+		&field{name: "reason", typ: "String"},
+		&field{name: "setBy", typ: "String"},
+		&field{name: "setAt", typ: "String"},
+		&field{name: "expireAt", typ: "String"},
+		&field{name: "duration", typ: "String"},
+		&field{name: "exceptionTypes", typ: "String", description: "The ban types an exception is for"},
+	)
+
+	s.object("Stats", "Network totals",
+		&field{name: "users", typ: "Int!"},
+		&field{name: "opers", typ: "Int!"},
+		&field{name: "userRecord", typ: "Int!"},
+		&field{name: "channels", typ: "Int!"},
+		&field{name: "servers", typ: "Int!"},
+		&field{name: "serverBans", typ: "Int!"},
+	)
+
+	s.object("StatsSample", "Network totals at one time",
+		&field{name: "time", typ: "String!"},
+		&field{name: "users", typ: "Int!"},
+		&field{name: "opers", typ: "Int!"},
+		&field{name: "channels", typ: "Int!"},
+		&field{name: "servers", typ: "Int!"},
+		&field{name: "serverBans", typ: "Int!"},
+	)
+
+	s.object("CountryCount", "The number of users from a country",
+		&field{name: "countryCode", typ: "String!"},
+		&field{name: "users", typ: "Int!"},
+	)
+
+	return s
+}
+
+// banList resolves a list of bans from the given method, filtered by the
+// type argument
+func banList(method string) resolver {
+	return func(r *request, _ interface{}, args map[string]interface{}) (interface{}, error) {
+		bans, err := r.loadBans(method)
+		if err != nil {
+			return nil, err
+		}
+		typ := str(args, "type")
+		list := make([]interface{}, 0, len(bans))
+		for _, b := range bans {
+			if typ == "" || strings.EqualFold(strOf(b, "type"), typ) {
+				list = append(list, b)
+			}
+		}
+		return r.limit(list, args), nil
+	}
+}
@@ -0,0 +1,103 @@
+package grpcservice
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// certPaths returns the certificate and key files the server uses: the
+// configured ones, or a self-signed pair in the data directory. Caller must
+// hold p.mu.
+func (p *GRPCServicePlugin) certPaths() (string, string, bool) {
+	if p.config.TLSCert != "" && p.config.TLSKey != "" {
+		return p.config.TLSCert, p.config.TLSKey, false
+	}
+	return filepath.Join(p.config.DataDir, "grpc-cert.pem"), filepath.Join(p.config.DataDir, "grpc-key.pem"), true
+}
+
+// certificate loads the server's TLS certificate, creating a self-signed
+// one on first use when none is configured, and returns the TLS settings
+// to serve it with. Caller must hold p.mu.
+func (p *GRPCServicePlugin) certificate() (*tls.Config, error) {
+	certFile, keyFile, selfSigned := p.certPaths()
+	if selfSigned {
+		if _, err := os.Stat(certFile); os.IsNotExist(err) {
+			if err := createCertificate(certFile, keyFile); err != nil {
+				return nil, fmt.Errorf("failed to create a certificate: %v", err)
+			}
+			log.Printf("[grpc-service] created a self-signed certificate in %s", certFile)
+		}
+	}
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the certificate: %v", err)
+	}
+	sum := sha256.Sum256(pair.Certificate[0])
+	p.certFingerprint = hex.EncodeToString(sum[:])
+	return &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// createCertificate writes a self-signed ECDSA certificate for this host,
+// valid for ten years
+func createCertificate(certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	names := []string{"localhost"}
+	if host, err := os.Hostname(); err == nil && host != "" && host != "localhost" {
+		names = append(names, host)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: names[len(names)-1], Organization: []string{"UnrealIRCd Web Panel"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              names,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
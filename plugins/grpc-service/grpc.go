@@ -0,0 +1,335 @@
+package grpcservice
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// maxRequestSize is the largest request message accepted
+const maxRequestSize = 64 << 10
+
+// gRPC status codes
+const (
+	codeOK                = 0
+	codeCanceled          = 1
+	codeInvalidArgument   = 3
+	codeDeadlineExceeded  = 4
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+	codeInternal          = 13
+	codeUnavailable       = 14
+	codeUnauthenticated   = 16
+)
+
+// Full method names
+const (
+	methodGetStats     = "/uwp.panel.v1.PanelStream/GetStats"
+	methodStreamStats  = "/uwp.panel.v1.PanelStream/StreamStats"
+	methodStreamEvents = "/uwp.panel.v1.PanelStream/StreamEvents"
+	methodHealthCheck  = "/grpc.health.v1.Health/Check"
+)
+
+// statusError is a call that ends with a gRPC status other than OK
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("grpc status %d: %s", e.code, e.message)
+}
+
+// status returns a status error
+func status(code int, format string, args ...interface{}) error {
+	return &statusError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// server is the gRPC listener. closing is closed when it stops, to end
+// open streams.
+type server struct {
+	http    *http.Server
+	addr    string
+	closing chan struct{}
+}
+
+// startServer listens on the configured address, if the service is
+// enabled. HTTP/2, which gRPC runs over, is negotiated through TLS.
+func (p *GRPCServicePlugin) startServer() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.serverErr = ""
+	if p.config.ListenAddr == "" {
+		return
+	}
+	if p.config.AuthToken == "" {
+		p.serverErr = "auth_token is required"
+		return
+	}
+
+	cert, err := p.certificate()
+	if err != nil {
+		p.serverErr = err.Error()
+		log.Printf("[grpc-service] %v", err)
+		return
+	}
+
+	if err := grants.Allow(grants.Listen); err != nil {
+		p.serverErr = err.Error()
+		return
+	}
+	ln, err := net.Listen("tcp", p.config.ListenAddr)
+	if err != nil {
+		p.serverErr = err.Error()
+		log.Printf("[grpc-service] failed to listen on %s: %v", p.config.ListenAddr, err)
+		return
+	}
+
+	s := &server{addr: ln.Addr().String(), closing: make(chan struct{})}
+	// No read or write timeouts, as streams stay open for as long as the
+	// client wants
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p.serveGRPC(s, w, r)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         cert,
+	}
+	s.http = srv
+	p.server = s
+
+	go func() {
+		err := srv.ServeTLS(ln, "", "")
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[grpc-service] server stopped: %v", err)
+			p.mu.Lock()
+			if p.server == s {
+				p.server = nil
+				p.serverErr = err.Error()
+			}
+			p.mu.Unlock()
+		}
+	}()
+	log.Printf("[grpc-service] listening on %s", s.addr)
+}
+
+// stopServer shuts the listener down. Open streams are ended first, so
+// Shutdown doesn't wait for them.
+func (p *GRPCServicePlugin) stopServer() {
+	p.mu.Lock()
+	s := p.server
+	p.server = nil
+	p.mu.Unlock()
+	if s == nil {
+		return
+	}
+	close(s.closing)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.http.Shutdown(ctx); err != nil {
+		log.Printf("[grpc-service] failed to stop server: %v", err)
+		s.http.Close()
+	}
+}
+
+// serveGRPC handles a gRPC call
+func (p *GRPCServicePlugin) serveGRPC(s *server, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2 POST requests", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "application/grpc" && !strings.HasPrefix(ct, "application/grpc+proto") && !strings.HasPrefix(ct, "application/grpc;") {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Set("Grpc-Accept-Encoding", "gzip")
+	// Announce the trailers, so the status is sent after the messages
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	ctx := r.Context()
+	if timeout, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	call := &grpcCall{w: w, r: r, ctx: ctx, closing: s.closing}
+	err := p.dispatch(call)
+	switch {
+	case err == nil:
+	case ctx.Err() == context.DeadlineExceeded:
+		err = status(codeDeadlineExceeded, "deadline exceeded")
+	case ctx.Err() != nil:
+		err = status(codeCanceled, "canceled")
+	}
+	call.finish(err)
+}
+
+// dispatch runs the method of a call
+func (p *GRPCServicePlugin) dispatch(call *grpcCall) error {
+	if call.r.URL.Path == methodHealthCheck {
+		return p.healthCheck(call)
+	}
+	if !p.authorized(call.r) {
+		p.mu.Lock()
+		p.rejected++
+		p.mu.Unlock()
+		return status(codeUnauthenticated, "invalid token")
+	}
+
+	switch call.r.URL.Path {
+	case methodGetStats:
+		return p.getStats(call)
+	case methodStreamStats:
+		return p.streamStats(call)
+	case methodStreamEvents:
+		return p.streamEvents(call)
+	}
+	return status(codeUnimplemented, "unknown method %s", call.r.URL.Path)
+}
+
+// authorized checks the bearer token in the call's metadata
+func (p *GRPCServicePlugin) authorized(r *http.Request) bool {
+	p.mu.RLock()
+	token := p.config.AuthToken
+	p.mu.RUnlock()
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	given := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	return token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// grpcCall is a call being served
+type grpcCall struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	ctx     context.Context
+	closing <-chan struct{}
+}
+
+// recv reads the single request message of a call
+func (c *grpcCall) recv() ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(c.r.Body, prefix[:]); err != nil {
+		if err == io.EOF {
+			// An empty body is an empty message
+			return nil, nil
+		}
+		return nil, status(codeInvalidArgument, "failed to read the request: %v", err)
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxRequestSize {
+		return nil, status(codeResourceExhausted, "the request is larger than %d bytes", maxRequestSize)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(c.r.Body, msg); err != nil {
+		return nil, status(codeInvalidArgument, "failed to read the request: %v", err)
+	}
+
+	if prefix[0] == 1 {
+		if enc := c.r.Header.Get("Grpc-Encoding"); enc != "gzip" {
+			return nil, status(codeUnimplemented, "unsupported compression %q", enc)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(msg))
+		if err != nil {
+			return nil, status(codeInvalidArgument, "failed to decompress the request: %v", err)
+		}
+		if msg, err = io.ReadAll(io.LimitReader(zr, maxRequestSize+1)); err != nil {
+			return nil, status(codeInvalidArgument, "failed to decompress the request: %v", err)
+		}
+		if len(msg) > maxRequestSize {
+			return nil, status(codeResourceExhausted, "the request is larger than %d bytes", maxRequestSize)
+		}
+	}
+	return msg, nil
+}
+
+// send writes a response message and flushes it to the client
+func (c *grpcCall) send(msg []byte) error {
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(msg)))
+	if _, err := c.w.Write(prefix[:]); err != nil {
+		return err
+	}
+	if _, err := c.w.Write(msg); err != nil {
+		return err
+	}
+	if f, ok := c.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// finish ends the call with the status of err
+func (c *grpcCall) finish(err error) {
+	code, message := codeOK, ""
+	if err != nil {
+		var se *statusError
+		if errors.As(err, &se) {
+			code, message = se.code, se.message
+		} else {
+			code, message = codeInternal, err.Error()
+		}
+	}
+	c.w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		c.w.Header().Set("Grpc-Message", encodeMessage(message))
+	}
+}
+
+// encodeMessage percent-encodes a status message as gRPC requires
+func encodeMessage(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= 0x20 && c <= 0x7e && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// parseTimeout parses a grpc-timeout header such as 250m or 30S
+func parseTimeout(s string) (time.Duration, bool) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
@@ -0,0 +1,534 @@
+// gRPC Service Plugin for UnrealIRCd Web Panel
+// An optional gRPC server streaming stats samples and IRCd events, for
+// internal systems where polling the JSON API is too heavy
+
+package grpcservice
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// protoFile is the service definition, served for clients to generate
+// code from
+//
+//go:embed panel.proto
+var protoFile string
+
+// GRPCServicePlugin implements the Plugin interface
+type GRPCServicePlugin struct {
+	config          Config
+	rpc             *jsonrpc.Client
+	server          *server
+	serverErr       string
+	certFingerprint string
+	samples         []*Sample
+	events          []*Event
+	sequence        uint64
+	statsSubs       map[*statsSub]struct{}
+	eventSubs       map[*eventSub]struct{}
+	sampleErr       string
+	lastSample      time.Time
+	streamOK        bool
+	streamErr       string
+	sentSamples     int
+	sentEvents      int
+	dropped         int
+	rejected        int
+	cancelStream    context.CancelFunc
+	mu              sync.RWMutex
+	stop            chan struct{}
+	wg              sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	StreamURL      string `json:"stream_url"`
+	ListenAddr     string `json:"listen_addr"`
+	AuthToken      string `json:"auth_token"`
+	TLSCert        string `json:"tls_cert"`
+	TLSKey         string `json:"tls_key"`
+	SampleInterval int    `json:"sample_interval"`
+	PerServer      bool   `json:"per_server"`
+	HistorySize    int    `json:"history_size"`
+	EventSources   string `json:"event_sources"`
+	EventHistory   int    `json:"event_history"`
+	EventBuffer    int    `json:"event_buffer"`
+	MaxStreams     int    `json:"max_streams"`
+}
+
+// Sample is the network's totals at one time
+type Sample struct {
+	Time        time.Time      `json:"time"`
+	Users       int            `json:"users"`
+	Opers       int            `json:"opers"`
+	Channels    int            `json:"channels"`
+	Servers     int            `json:"servers"`
+	ServerBans  int            `json:"server_bans"`
+	UserRecord  int            `json:"user_record"`
+	ServerUsers map[string]int `json:"server_users,omitempty"`
+}
+
+// Event is an IRCd log event
+type Event struct {
+	Sequence  uint64          `json:"sequence"`
+	Time      time.Time       `json:"time"`
+	Level     string          `json:"level"`
+	Subsystem string          `json:"subsystem"`
+	EventID   string          `json:"event_id"`
+	Message   string          `json:"message"`
+	JSON      json.RawMessage `json:"json"`
+}
+
+// statsResult is the part of stats.get we sample
+type statsResult struct {
+	Server struct {
+		Total int `json:"total"`
+	} `json:"server"`
+	User struct {
+		Total  int `json:"total"`
+		Oper   int `json:"oper"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+	ServerBan struct {
+		Total int `json:"total"`
+	} `json:"server_ban"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		NumUsers int `json:"num_users"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &GRPCServicePlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/grpc-service",
+			StreamURL:      "wss://127.0.0.1:8600/",
+			SampleInterval: 10,
+			PerServer:      true,
+			HistorySize:    360,
+			EventSources:   "all",
+			EventHistory:   1000,
+			EventBuffer:    1000,
+			MaxStreams:     32,
+		},
+		samples:   make([]*Sample, 0),
+		events:    make([]*Event, 0),
+		statsSubs: make(map[*statsSub]struct{}),
+		eventSubs: make(map[*eventSub]struct{}),
+	}
+}
+
+// Info returns plugin metadata
+func (p *GRPCServicePlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "gRPC Service",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "gRPC server streaming stats samples and IRCd events to internal systems",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *GRPCServicePlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "grpc-service-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"streams": p.openStreams(),
+			"events":  p.sentEvents,
+		}
+		switch {
+		case p.serverErr != "":
+			content["status"] = p.serverErr
+		case p.server == nil:
+			content["status"] = "Disabled"
+		}
+		return plugins.DashboardCard{
+			Title:   "gRPC Service",
+			Icon:    "Cable",
+			Content: content,
+			Order:   97,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.startServer()
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.sampleLoop()
+	go p.streamLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *GRPCServicePlugin) Shutdown() error {
+	p.stopServer()
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *GRPCServicePlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/grpc-service")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/proto", p.handleProto)
+		plugin.GET("/certificate", p.handleCertificate)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *GRPCServicePlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// sampleLoop samples the network every sample_interval while the service
+// is enabled, until shutdown. Samples are taken once for all streams, so
+// the load on the IRCd doesn't grow with the number of clients.
+func (p *GRPCServicePlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.mu.RLock()
+		enabled := p.server != nil
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+		if enabled {
+			p.sample()
+		}
+		timer.Reset(interval)
+	}
+}
+
+// fetchSample asks UnrealIRCd for the network's totals
+func (p *GRPCServicePlugin) fetchSample(ctx context.Context) (*Sample, error) {
+	p.mu.RLock()
+	perServer := p.config.PerServer
+	p.mu.RUnlock()
+
+	rpc := p.client()
+	var stats statsResult
+	if err := rpc.Call(ctx, "stats.get", nil, &stats); err != nil {
+		return nil, err
+	}
+	s := &Sample{
+		Time:       time.Now().UTC(),
+		Users:      stats.User.Total,
+		Opers:      stats.User.Oper,
+		Channels:   stats.Channel.Total,
+		Servers:    stats.Server.Total,
+		ServerBans: stats.ServerBan.Total,
+		UserRecord: stats.User.Record,
+	}
+	if perServer {
+		var servers struct {
+			List []rpcServer `json:"list"`
+		}
+		if err := rpc.Call(ctx, "server.list", nil, &servers); err != nil {
+			return nil, err
+		}
+		s.ServerUsers = make(map[string]int, len(servers.List))
+		for _, srv := range servers.List {
+			s.ServerUsers[srv.Name] = srv.Server.NumUsers
+		}
+	}
+	return s, nil
+}
+
+// sample records a sample and hands it to the stats streams
+func (p *GRPCServicePlugin) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	s, err := p.fetchSample(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.sampleErr = err.Error()
+		return
+	}
+	p.sampleErr = ""
+	p.lastSample = s.Time
+	p.samples = append(p.samples, s)
+	if over := len(p.samples) - p.config.HistorySize; over > 0 {
+		p.samples = append([]*Sample(nil), p.samples[over:]...)
+	}
+	p.broadcastSample(s)
+}
+
+// streamLoop subscribes to the IRCd log while the service is enabled,
+// starting over when the configuration changes, until shutdown
+func (p *GRPCServicePlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		enabled := p.server != nil
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.EventSources))
+		p.mu.Unlock()
+
+		if enabled {
+			stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		} else {
+			<-ctx.Done()
+		}
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log subscription
+func (p *GRPCServicePlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	p.streamErr = ""
+	if err != nil {
+		p.streamErr = err.Error()
+	}
+}
+
+// handleEvent records a log event and hands it to the event streams
+func (p *GRPCServicePlugin) handleEvent(le jsonrpc.LogEvent) {
+	ts, err := time.Parse(time.RFC3339, le.Timestamp)
+	if err != nil {
+		ts = time.Now()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sequence++
+	ev := &Event{
+		Sequence:  p.sequence,
+		Time:      ts.UTC(),
+		Level:     le.Level,
+		Subsystem: le.Subsystem,
+		EventID:   le.EventID,
+		Message:   le.Msg,
+		JSON:      le.Raw,
+	}
+	p.events = append(p.events, ev)
+	if over := len(p.events) - p.config.EventHistory; over > 0 {
+		p.events = append([]*Event(nil), p.events[over:]...)
+	}
+	p.broadcastEvent(ev)
+}
+
+// handleStatus returns the state of the server and its streams
+func (p *GRPCServicePlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"listening":      p.server != nil,
+		"error":          p.serverErr,
+		"stats_streams":  len(p.statsSubs),
+		"event_streams":  len(p.eventSubs),
+		"samples":        len(p.samples),
+		"events":         len(p.events),
+		"sent_samples":   p.sentSamples,
+		"sent_events":    p.sentEvents,
+		"dropped":        p.dropped,
+		"rejected":       p.rejected,
+		"sample_error":   p.sampleErr,
+		"log_stream_ok":  p.streamOK,
+		"log_stream_err": p.streamErr,
+	}
+	if p.server != nil {
+		status["address"] = p.server.addr
+		status["certificate_sha256"] = p.certFingerprint
+	}
+	if !p.lastSample.IsZero() {
+		status["last_sample"] = p.lastSample
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleProto returns the protocol buffer definitions of the service
+func (p *GRPCServicePlugin) handleProto(c *gin.Context) {
+	c.Header("Content-Disposition", `attachment; filename="panel.proto"`)
+	c.String(http.StatusOK, protoFile)
+}
+
+// handleCertificate returns the certificate the server presents, for
+// clients to trust
+func (p *GRPCServicePlugin) handleCertificate(c *gin.Context) {
+	p.mu.RLock()
+	certFile, _, _ := p.certPaths()
+	p.mu.RUnlock()
+
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No certificate yet, enable the server first"})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="grpc-service.pem"`)
+	c.Data(http.StatusOK, "application/x-pem-file", data)
+}
+
+// handleGetConfig returns the current configuration
+func (p *GRPCServicePlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AuthToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration and restarts the server
+func (p *GRPCServicePlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if (newConfig.TLSCert == "") != (newConfig.TLSKey == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tls_cert and tls_key must be set together"})
+		return
+	}
+	if newConfig.SampleInterval < 1 || newConfig.SampleInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sample_interval must be between 1 and 3600 seconds"})
+		return
+	}
+	if newConfig.HistorySize < 1 || newConfig.HistorySize > 100000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "history_size must be between 1 and 100000"})
+		return
+	}
+	if newConfig.EventHistory < 0 || newConfig.EventHistory > 100000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event_history must be between 0 and 100000"})
+		return
+	}
+	if newConfig.EventBuffer < 10 || newConfig.EventBuffer > 100000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event_buffer must be between 10 and 100000"})
+		return
+	}
+	if newConfig.MaxStreams < 1 || newConfig.MaxStreams > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_streams must be between 1 and 1000"})
+		return
+	}
+	newConfig.EventSources = strings.Join(lists.Split(newConfig.EventSources), ",")
+	if newConfig.EventSources == "" {
+		newConfig.EventSources = "all"
+	}
+
+	p.stopServer()
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.AuthToken == "" {
+		newConfig.AuthToken = p.config.AuthToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	p.startServer()
+
+	p.mu.Lock()
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	serverErr := p.serverErr
+	p.mu.Unlock()
+
+	if serverErr != "" {
+		c.JSON(http.StatusOK, gin.H{"message": "Configuration updated", "warning": serverErr})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *GRPCServicePlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *GRPCServicePlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
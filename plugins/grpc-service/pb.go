@@ -0,0 +1,201 @@
+package grpcservice
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// This file encodes and decodes the messages of panel.proto in the
+// protocol buffers wire format. Fields holding their zero value are left
+// out, as proto3 does.
+
+// Wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errTruncated is returned for messages that end in the middle of a field
+var errTruncated = errors.New("truncated message")
+
+// pbWriter builds an encoded message
+type pbWriter struct {
+	buf []byte
+}
+
+func (w *pbWriter) tag(field, wire int) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(field)<<3|uint64(wire))
+}
+
+func (w *pbWriter) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	w.tag(field, wireVarint)
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *pbWriter) int(field int, v int64) {
+	w.uint(field, uint64(v))
+}
+
+func (w *pbWriter) bool(field int, v bool) {
+	if v {
+		w.uint(field, 1)
+	}
+}
+
+func (w *pbWriter) bytes(field int, b []byte) {
+	w.tag(field, wireBytes)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *pbWriter) string(field int, s string) {
+	if s != "" {
+		w.bytes(field, []byte(s))
+	}
+}
+
+// pbFields calls fn for each field of an encoded message. v holds varint
+// and fixed values, b the contents of length-delimited fields.
+func pbFields(data []byte, fn func(field, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		field, wire := int(key>>3), int(key&7)
+		if field == 0 {
+			return errors.New("invalid field number 0")
+		}
+
+		var v uint64
+		var b []byte
+		switch wire {
+		case wireVarint:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			v, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return errTruncated
+			}
+			b, data = data[n:n+int(size)], data[n+int(size):]
+		default:
+			return errors.New("unsupported wire type")
+		}
+		if err := fn(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getStatsRequest is GetStatsRequest
+type getStatsRequest struct {
+	Fresh bool
+}
+
+func (m *getStatsRequest) unmarshal(data []byte) error {
+	return pbFields(data, func(field, wire int, v uint64, b []byte) error {
+		if field == 1 && wire == wireVarint {
+			m.Fresh = v != 0
+		}
+		return nil
+	})
+}
+
+// streamStatsRequest is StreamStatsRequest
+type streamStatsRequest struct {
+	Backlog int
+}
+
+func (m *streamStatsRequest) unmarshal(data []byte) error {
+	return pbFields(data, func(field, wire int, v uint64, b []byte) error {
+		if field == 1 && wire == wireVarint {
+			m.Backlog = int(uint32(v))
+		}
+		return nil
+	})
+}
+
+// streamEventsRequest is StreamEventsRequest
+type streamEventsRequest struct {
+	Subsystems []string
+	EventIDs   []string
+	MinLevel   string
+	Backlog    int
+}
+
+func (m *streamEventsRequest) unmarshal(data []byte) error {
+	return pbFields(data, func(field, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == wireBytes:
+			m.Subsystems = append(m.Subsystems, string(b))
+		case field == 2 && wire == wireBytes:
+			m.EventIDs = append(m.EventIDs, string(b))
+		case field == 3 && wire == wireBytes:
+			m.MinLevel = string(b)
+		case field == 4 && wire == wireVarint:
+			m.Backlog = int(uint32(v))
+		}
+		return nil
+	})
+}
+
+// marshal encodes a sample as StatsSample
+func (s *Sample) marshal() []byte {
+	w := &pbWriter{}
+	w.int(1, s.Time.UnixMilli())
+	w.uint(2, uint64(s.Users))
+	w.uint(3, uint64(s.Opers))
+	w.uint(4, uint64(s.Channels))
+	w.uint(5, uint64(s.Servers))
+	w.uint(6, uint64(s.ServerBans))
+	w.uint(7, uint64(s.UserRecord))
+
+	// Map entries are messages of a key and a value; sorted for a stable
+	// encoding
+	names := make([]string, 0, len(s.ServerUsers))
+	for name := range s.ServerUsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entry := &pbWriter{}
+		entry.string(1, name)
+		entry.uint(2, uint64(s.ServerUsers[name]))
+		w.bytes(8, entry.buf)
+	}
+	return w.buf
+}
+
+// marshal encodes an event as Event
+func (e *Event) marshal() []byte {
+	w := &pbWriter{}
+	w.uint(1, e.Sequence)
+	w.int(2, e.Time.UnixMilli())
+	w.string(3, e.Level)
+	w.string(4, e.Subsystem)
+	w.string(5, e.EventID)
+	w.string(6, e.Message)
+	w.string(7, string(e.JSON))
+	return w.buf
+}
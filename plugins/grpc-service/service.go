@@ -0,0 +1,260 @@
+package grpcservice
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// statsBuffer is how many samples may wait for a slow stats stream before
+// newer ones are skipped for it
+const statsBuffer = 8
+
+// levelRank orders log levels
+var levelRank = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+	"fatal": 4,
+}
+
+// statsSub is an open StreamStats call
+type statsSub struct {
+	ch chan *Sample
+}
+
+// eventSub is an open StreamEvents call. lagged is closed when the client
+// falls more than event_buffer events behind.
+type eventSub struct {
+	filter streamEventsRequest
+	ch     chan *Event
+	lagged chan struct{}
+}
+
+// matches reports whether an event passes the filters of a subscription
+func (f *streamEventsRequest) matches(ev *Event) bool {
+	if len(f.Subsystems) > 0 && !lists.ContainsFold(f.Subsystems, ev.Subsystem) {
+		return false
+	}
+	if len(f.EventIDs) > 0 {
+		ok := false
+		for _, mask := range f.EventIDs {
+			if ircname.MatchMask(mask, ev.EventID) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.MinLevel != "" && levelRank[strings.ToLower(ev.Level)] < levelRank[strings.ToLower(f.MinLevel)] {
+		return false
+	}
+	return true
+}
+
+// openStreams returns the number of open streams. Caller must hold p.mu.
+func (p *GRPCServicePlugin) openStreams() int {
+	return len(p.statsSubs) + len(p.eventSubs)
+}
+
+// healthCheck answers grpc.health.v1.Health/Check, without a token, so
+// load balancers can probe the service
+func (p *GRPCServicePlugin) healthCheck(call *grpcCall) error {
+	if _, err := call.recv(); err != nil {
+		return err
+	}
+	// HealthCheckResponse with status SERVING
+	w := &pbWriter{}
+	w.uint(1, 1)
+	return call.send(w.buf)
+}
+
+// getStats answers GetStats with the latest sample, or a new one
+func (p *GRPCServicePlugin) getStats(call *grpcCall) error {
+	msg, err := call.recv()
+	if err != nil {
+		return err
+	}
+	var req getStatsRequest
+	if err := req.unmarshal(msg); err != nil {
+		return status(codeInvalidArgument, "invalid request: %v", err)
+	}
+
+	var sample *Sample
+	if !req.Fresh {
+		p.mu.RLock()
+		if n := len(p.samples); n > 0 {
+			sample = p.samples[n-1]
+		}
+		p.mu.RUnlock()
+	}
+	if sample == nil {
+		ctx, cancel := context.WithTimeout(call.ctx, 30*time.Second)
+		defer cancel()
+		if sample, err = p.fetchSample(ctx); err != nil {
+			return status(codeUnavailable, "failed to get stats: %v", err)
+		}
+	}
+
+	p.countSent(1, 0)
+	return call.send(sample.marshal())
+}
+
+// streamStats answers StreamStats: the backlog, then every new sample
+// until the client goes away
+func (p *GRPCServicePlugin) streamStats(call *grpcCall) error {
+	msg, err := call.recv()
+	if err != nil {
+		return err
+	}
+	var req streamStatsRequest
+	if err := req.unmarshal(msg); err != nil {
+		return status(codeInvalidArgument, "invalid request: %v", err)
+	}
+
+	sub := &statsSub{ch: make(chan *Sample, statsBuffer)}
+	p.mu.Lock()
+	if p.openStreams() >= p.config.MaxStreams {
+		p.mu.Unlock()
+		return status(codeResourceExhausted, "too many open streams")
+	}
+	backlog := p.samples
+	if len(backlog) > req.Backlog {
+		backlog = backlog[len(backlog)-req.Backlog:]
+	}
+	backlog = append([]*Sample(nil), backlog...)
+	p.statsSubs[sub] = struct{}{}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.statsSubs, sub)
+		p.mu.Unlock()
+	}()
+
+	for _, s := range backlog {
+		if err := call.send(s.marshal()); err != nil {
+			return err
+		}
+	}
+	p.countSent(len(backlog), 0)
+
+	for {
+		select {
+		case <-call.ctx.Done():
+			return call.ctx.Err()
+		case <-call.closing:
+			return status(codeUnavailable, "the server is shutting down")
+		case s := <-sub.ch:
+			if err := call.send(s.marshal()); err != nil {
+				return err
+			}
+			p.countSent(1, 0)
+		}
+	}
+}
+
+// streamEvents answers StreamEvents: the matching backlog, then every new
+// matching event until the client goes away or falls too far behind
+func (p *GRPCServicePlugin) streamEvents(call *grpcCall) error {
+	msg, err := call.recv()
+	if err != nil {
+		return err
+	}
+	var req streamEventsRequest
+	if err := req.unmarshal(msg); err != nil {
+		return status(codeInvalidArgument, "invalid request: %v", err)
+	}
+	if _, ok := levelRank[strings.ToLower(req.MinLevel)]; req.MinLevel != "" && !ok {
+		return status(codeInvalidArgument, "min_level must be debug, info, warn, error or fatal")
+	}
+
+	p.mu.Lock()
+	if p.openStreams() >= p.config.MaxStreams {
+		p.mu.Unlock()
+		return status(codeResourceExhausted, "too many open streams")
+	}
+	sub := &eventSub{filter: req, ch: make(chan *Event, p.config.EventBuffer), lagged: make(chan struct{})}
+	backlog := make([]*Event, 0)
+	for i := len(p.events) - 1; i >= 0 && len(backlog) < req.Backlog; i-- {
+		if req.matches(p.events[i]) {
+			backlog = append(backlog, p.events[i])
+		}
+	}
+	p.eventSubs[sub] = struct{}{}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.eventSubs, sub)
+		p.mu.Unlock()
+	}()
+
+	// The backlog was collected newest first
+	for i := len(backlog) - 1; i >= 0; i-- {
+		if err := call.send(backlog[i].marshal()); err != nil {
+			return err
+		}
+	}
+	p.countSent(0, len(backlog))
+
+	for {
+		select {
+		case <-call.ctx.Done():
+			return call.ctx.Err()
+		case <-call.closing:
+			return status(codeUnavailable, "the server is shutting down")
+		case <-sub.lagged:
+			return status(codeResourceExhausted, "the client fell too far behind")
+		case ev := <-sub.ch:
+			if err := call.send(ev.marshal()); err != nil {
+				return err
+			}
+			p.countSent(0, 1)
+		}
+	}
+}
+
+// countSent adds to the counts of messages sent
+func (p *GRPCServicePlugin) countSent(samples, events int) {
+	p.mu.Lock()
+	p.sentSamples += samples
+	p.sentEvents += events
+	p.mu.Unlock()
+}
+
+// broadcastSample hands a new sample to the stats streams. A stream still
+// busy with earlier samples skips it. Caller must hold p.mu.
+func (p *GRPCServicePlugin) broadcastSample(s *Sample) {
+	for sub := range p.statsSubs {
+		select {
+		case sub.ch <- s:
+		default:
+			p.dropped++
+		}
+	}
+}
+
+// broadcastEvent hands a new event to the event streams it matches. A
+// stream whose buffer is full is ended, so the client knows it missed
+// events. Caller must hold p.mu.
+func (p *GRPCServicePlugin) broadcastEvent(ev *Event) {
+	for sub := range p.eventSubs {
+		if !sub.filter.matches(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			close(sub.lagged)
+			delete(p.eventSubs, sub)
+			p.dropped++
+		}
+	}
+}
@@ -0,0 +1,470 @@
+/**
+ * Help Tickets Frontend Script
+ *
+ * Lists tickets on the plugin page, opens new ones and shows a ticket's
+ * timeline with replies, staff notes, assignment, status and linked events.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'help-tickets';
+  const PLUGIN_NAME = 'Help Tickets';
+  const PAGE_PATH = '/plugins/help-tickets';
+  const API_BASE = '/api/plugin/help-tickets';
+
+  let meta = { categories: [], priorities: [] };
+  let current = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  function options(list, selected) {
+    return list.map(v => `<option value="${escapeHtml(v)}"${v === selected ? ' selected' : ''}>${escapeHtml(v)}</option>`).join('');
+  }
+
+  function injectStyles() {
+    if (document.getElementById('help-tickets-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'help-tickets-styles';
+    style.textContent = `
+      .hlt-app { display: flex; flex-direction: column; gap: 1rem; }
+      .hlt-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .hlt-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .hlt-btn.small { padding: 0.2rem 0.5rem; font-size: 0.75rem; }
+      .hlt-form { display: grid; grid-template-columns: repeat(auto-fit, minmax(160px, 1fr)); gap: 0.5rem; align-items: end; }
+      .hlt-form label { display: flex; flex-direction: column; gap: 0.25rem; font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .hlt-app input, .hlt-app select, .hlt-app textarea {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .hlt-form .wide { grid-column: 1 / -1; }
+      .hlt-filter { display: flex; gap: 0.5rem; flex-wrap: wrap; }
+      .hlt-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .hlt-table th, .hlt-table td {
+        text-align: left;
+        padding: 0.5rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+        vertical-align: top;
+      }
+      .hlt-table th { color: var(--text-primary, #cdd6f4); }
+      .hlt-table tbody tr { cursor: pointer; }
+      .hlt-status { font-size: 0.75rem; padding: 0.1rem 0.4rem; border-radius: 4px; background: var(--bg-tertiary, #45475a); }
+      .hlt-status.open { color: var(--accent, #89b4fa); }
+      .hlt-status.waiting { color: var(--warning, #f9e2af); }
+      .hlt-status.closed { color: var(--text-muted, #6c7086); }
+      .hlt-prio-urgent, .hlt-prio-high { color: var(--error, #f38ba8); }
+      .hlt-detail { display: grid; grid-template-columns: 2fr 1fr; gap: 1rem; }
+      .hlt-panel {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.75rem;
+        display: flex;
+        flex-direction: column;
+        gap: 0.5rem;
+        color: var(--text-secondary, #a6adc8);
+        font-size: 0.85rem;
+      }
+      .hlt-entry { border-left: 3px solid var(--border-primary, #313244); padding: 0.25rem 0.5rem; }
+      .hlt-entry.comment { border-color: var(--accent, #89b4fa); }
+      .hlt-entry.note { border-color: var(--warning, #f9e2af); }
+      .hlt-entry.linked, .hlt-entry.unlinked { border-color: var(--success, #a6e3a1); }
+      .hlt-entry p { margin: 0.25rem 0 0; white-space: pre-wrap; color: var(--text-primary, #cdd6f4); }
+      .hlt-muted { color: var(--text-muted, #6c7086); }
+      .hlt-error { color: var(--error, #f38ba8); }
+      .hlt-empty { color: var(--text-muted, #6c7086); padding: 1rem 0; }
+      .hlt-row { display: flex; gap: 0.5rem; align-items: center; flex-wrap: wrap; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderRow(t) {
+    return `
+      <tr data-id="${escapeHtml(t.id)}">
+        <td>#${t.number}</td>
+        <td><strong>${escapeHtml(t.subject)}</strong><br><small class="hlt-muted">${escapeHtml(t.category)}</small></td>
+        <td>${escapeHtml(t.requester.nick)}${t.requester.account ? `<br><small class="hlt-muted">${escapeHtml(t.requester.account)}</small>` : ''}</td>
+        <td class="hlt-prio-${escapeHtml(t.priority)}">${escapeHtml(t.priority)}</td>
+        <td><span class="hlt-status ${escapeHtml(t.status)}">${escapeHtml(t.status)}</span></td>
+        <td>${t.assigned_to ? escapeHtml(t.assigned_to) : '<span class="hlt-muted">Unassigned</span>'}</td>
+        <td>${escapeHtml(formatTime(t.updated_at))}</td>
+      </tr>
+    `;
+  }
+
+  async function loadList(container) {
+    const list = container.querySelector('#hlt-list');
+    const f = container.querySelector('#hlt-filter').elements;
+    const query = new URLSearchParams({ status: f.status.value, category: f.category.value, assigned: f.assigned.value, q: f.q.value.trim() });
+
+    try {
+      const data = await api('GET', '/tickets?' + query);
+      if (!data.tickets.length) {
+        list.innerHTML = '<div class="hlt-empty">No tickets.</div>';
+        return;
+      }
+      list.innerHTML = `
+        <table class="hlt-table">
+          <thead><tr><th>#</th><th>Subject</th><th>Requester</th><th>Priority</th><th>Status</th><th>Assigned</th><th>Updated</th></tr></thead>
+          <tbody>${data.tickets.map(renderRow).join('')}</tbody>
+        </table>
+      `;
+    } catch (e) {
+      list.innerHTML = `<div class="hlt-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderEntry(e) {
+    const labels = {
+      opened: 'opened the ticket',
+      comment: 'replied',
+      note: 'added a staff note',
+      assigned: 'assigned',
+      status: 'set status',
+      changed: 'changed',
+      linked: 'linked',
+      unlinked: 'unlinked'
+    };
+    const long = e.type === 'comment' || e.type === 'note';
+    return `
+      <div class="hlt-entry ${escapeHtml(e.type)}">
+        <small class="hlt-muted">${escapeHtml(formatTime(e.time))}</small>
+        <strong>${escapeHtml(e.actor)}</strong> ${escapeHtml(labels[e.type] || e.type)}${!long && e.text ? ' ' + escapeHtml(e.text) : ''}
+        ${long ? `<p>${escapeHtml(e.text)}</p>` : ''}
+      </div>
+    `;
+  }
+
+  function renderDetail(container) {
+    const t = current;
+    const panel = container.querySelector('#hlt-detail');
+    const r = t.requester;
+    panel.innerHTML = `
+      <div class="hlt-row">
+        <button class="hlt-btn" data-action="back">Back</button>
+        <h3>#${t.number} ${escapeHtml(t.subject)}</h3>
+        <span class="hlt-status ${escapeHtml(t.status)}">${escapeHtml(t.status)}</span>
+      </div>
+      <div class="hlt-detail">
+        <div class="hlt-panel">
+          ${t.timeline.map(renderEntry).join('')}
+          <textarea id="hlt-comment" rows="3" placeholder="Reply or staff note"></textarea>
+          <div class="hlt-row">
+            <button class="hlt-btn primary" data-action="reply">Reply</button>
+            <button class="hlt-btn" data-action="note">Add staff note</button>
+          </div>
+        </div>
+        <div class="hlt-panel">
+          <div><strong>Requester</strong><br>${escapeHtml(r.nick)}${r.account ? ' (' + escapeHtml(r.account) + ')' : ''}
+            ${r.host || r.ip ? `<br><small class="hlt-muted">${escapeHtml(r.host)} ${escapeHtml(r.ip)}</small>` : ''}
+            ${r.contact ? `<br><small>${escapeHtml(r.contact)}</small>` : ''}
+            <br><small class="hlt-muted">Opened by ${escapeHtml(t.opened_by)}${t.on_behalf ? ' on their behalf' : ''}</small></div>
+          <label>Category<select id="hlt-category">${options(meta.categories, t.category)}</select></label>
+          <label>Priority<select id="hlt-priority">${options(meta.priorities, t.priority)}</select></label>
+          <div class="hlt-row">
+            <input id="hlt-assignee" placeholder="Assignee" value="${escapeHtml(t.assigned_to || '')}">
+            <button class="hlt-btn small" data-action="assign">Assign</button>
+            <button class="hlt-btn small" data-action="take">Take</button>
+          </div>
+          <div class="hlt-row">
+            ${t.status !== 'open' ? '<button class="hlt-btn small" data-action="status" data-status="open">Reopen</button>' : ''}
+            ${t.status === 'open' ? '<button class="hlt-btn small" data-action="status" data-status="waiting">Waiting on user</button>' : ''}
+            ${t.status !== 'closed' ? '<button class="hlt-btn small primary" data-action="status" data-status="closed">Close</button>' : ''}
+          </div>
+          ${t.resolution ? `<div><small class="hlt-muted">Resolution:</small> ${escapeHtml(t.resolution)}</div>` : ''}
+          <strong>Links</strong>
+          ${t.links.length ? t.links.map(l => `
+            <div class="hlt-row"><span>${escapeHtml(l.kind)} <code>${escapeHtml(l.ref)}</code>${l.summary ? ' - ' + escapeHtml(l.summary) : ''}</span>
+              <button class="hlt-btn small" data-action="unlink" data-link="${escapeHtml(l.id)}">Remove</button></div>
+          `).join('') : '<span class="hlt-muted">Nothing linked</span>'}
+          <div class="hlt-row">
+            <select id="hlt-link-kind">${options(['ban', 'note', 'ticket', 'event'], 'note')}</select>
+            <input id="hlt-link-ref" placeholder="Reference">
+            <input id="hlt-link-summary" placeholder="Summary">
+            <button class="hlt-btn small" data-action="link">Link</button>
+          </div>
+          <button class="hlt-btn small" data-action="related">Find related bans and tickets</button>
+          <div id="hlt-related"></div>
+        </div>
+      </div>
+    `;
+  }
+
+  async function openTicket(container, id) {
+    try {
+      current = await api('GET', `/tickets/${encodeURIComponent(id)}`);
+    } catch (e) {
+      alert(e.message);
+      return;
+    }
+    container.querySelector('#hlt-main').style.display = 'none';
+    container.querySelector('#hlt-detail').style.display = '';
+    renderDetail(container);
+  }
+
+  async function loadRelated(container) {
+    const out = container.querySelector('#hlt-related');
+    out.innerHTML = '<span class="hlt-muted">Loading...</span>';
+    try {
+      const data = await api('GET', `/tickets/${encodeURIComponent(current.id)}/related`);
+      const bans = data.bans.map(b => `
+        <div class="hlt-row"><span>${escapeHtml(b.type)} <code>${escapeHtml(b.name)}</code> ${escapeHtml(b.reason)}</span>
+          <button class="hlt-btn small" data-action="link-suggested" data-kind="ban" data-ref="${escapeHtml(b.type + ' ' + b.name)}" data-summary="${escapeHtml(b.reason)}">Link</button></div>
+      `).join('');
+      const tickets = data.tickets.map(o => `
+        <div class="hlt-row"><span>#${o.number} ${escapeHtml(o.subject)} <small class="hlt-muted">${escapeHtml(o.status)}</small></span>
+          <button class="hlt-btn small" data-action="link-suggested" data-kind="ticket" data-ref="${o.number}" data-summary="">Link</button></div>
+      `).join('');
+      out.innerHTML = (bans || tickets)
+        ? bans + tickets
+        : '<span class="hlt-muted">Nothing related found</span>';
+      if (data.bans_error) {
+        out.innerHTML += `<div class="hlt-error">Bans: ${escapeHtml(data.bans_error)}</div>`;
+      }
+    } catch (e) {
+      out.innerHTML = `<div class="hlt-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function detailAction(container, btn) {
+    const path = `/tickets/${encodeURIComponent(current.id)}`;
+    const text = container.querySelector('#hlt-comment')?.value.trim();
+
+    switch (btn.dataset.action) {
+      case 'back':
+        current = null;
+        container.querySelector('#hlt-detail').style.display = 'none';
+        container.querySelector('#hlt-main').style.display = '';
+        loadList(container);
+        return;
+      case 'reply':
+      case 'note':
+        if (!text) return;
+        current = await api('POST', path + '/comments', { text, internal: btn.dataset.action === 'note' });
+        break;
+      case 'assign':
+        current = await api('POST', path + '/assign', { assignee: container.querySelector('#hlt-assignee').value });
+        break;
+      case 'take':
+        current = await api('POST', path + '/assign', { assignee: 'me' });
+        break;
+      case 'status': {
+        let resolution = '';
+        if (btn.dataset.status === 'closed') {
+          resolution = prompt('Resolution (optional)');
+          if (resolution === null) return;
+        }
+        current = await api('POST', path + '/status', { status: btn.dataset.status, resolution });
+        break;
+      }
+      case 'link':
+        current = await api('POST', path + '/links', {
+          kind: container.querySelector('#hlt-link-kind').value,
+          ref: container.querySelector('#hlt-link-ref').value,
+          summary: container.querySelector('#hlt-link-summary').value
+        });
+        break;
+      case 'link-suggested':
+        current = await api('POST', path + '/links', { kind: btn.dataset.kind, ref: btn.dataset.ref, summary: btn.dataset.summary });
+        break;
+      case 'unlink':
+        current = await api('DELETE', `${path}/links/${encodeURIComponent(btn.dataset.link)}`);
+        break;
+      case 'related':
+        loadRelated(container);
+        return;
+      default:
+        return;
+    }
+    renderDetail(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="hlt-app" data-plugin="${PLUGIN_ID}">
+        <div id="hlt-main" class="hlt-app">
+          <form class="hlt-form" id="hlt-form">
+            <label>Subject<input name="subject" required placeholder="Can't join #help"></label>
+            <label>Category<select name="category"></select></label>
+            <label>Priority<select name="priority"></select></label>
+            <label>For nick<input name="nick" placeholder="Leave empty for yourself"></label>
+            <label>Contact<input name="contact" placeholder="Email or other contact"></label>
+            <label class="wide">Description<textarea name="description" rows="3"></textarea></label>
+            <button class="hlt-btn primary" type="submit">Open ticket</button>
+          </form>
+          <form class="hlt-filter" id="hlt-filter">
+            <select name="status">
+              <option value="">Any status</option>
+              <option value="open" selected>Open</option>
+              <option value="waiting">Waiting</option>
+              <option value="closed">Closed</option>
+            </select>
+            <select name="category"><option value="">All categories</option></select>
+            <select name="assigned">
+              <option value="">Anyone</option>
+              <option value="me">Assigned to me</option>
+              <option value="none">Unassigned</option>
+            </select>
+            <input name="q" placeholder="Search">
+          </form>
+          <div id="hlt-list"><div class="hlt-empty">Loading...</div></div>
+        </div>
+        <div id="hlt-detail" style="display: none"></div>
+      </div>
+    `;
+
+    const form = container.querySelector('#hlt-form');
+    const filter = container.querySelector('#hlt-filter');
+
+    api('GET', '/categories').then(data => {
+      meta = data;
+      form.elements.category.innerHTML = options(meta.categories, meta.categories[0]);
+      form.elements.priority.innerHTML = options(meta.priorities, 'normal');
+      filter.elements.category.innerHTML += options(meta.categories, '');
+    }).catch(e => console.error(`[${PLUGIN_NAME}]`, e));
+
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = form.elements;
+      try {
+        const t = await api('POST', '/tickets', {
+          subject: f.subject.value,
+          category: f.category.value,
+          priority: f.priority.value,
+          description: f.description.value,
+          requester: { nick: f.nick.value, contact: f.contact.value }
+        });
+        form.reset();
+        openTicket(container, t.id);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    filter.addEventListener('change', () => loadList(container));
+    filter.addEventListener('input', () => loadList(container));
+    filter.addEventListener('submit', (e) => e.preventDefault());
+
+    container.querySelector('#hlt-list').addEventListener('click', (e) => {
+      const row = e.target.closest('tr[data-id]');
+      if (row) openTicket(container, row.dataset.id);
+    });
+
+    const detail = container.querySelector('#hlt-detail');
+    detail.addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn || !current) return;
+      try {
+        await detailAction(container, btn);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+    detail.addEventListener('change', async (e) => {
+      if (!current || (e.target.id !== 'hlt-category' && e.target.id !== 'hlt-priority')) return;
+      try {
+        current = await api('PUT', `/tickets/${encodeURIComponent(current.id)}`, {
+          category: detail.querySelector('#hlt-category').value,
+          priority: detail.querySelector('#hlt-priority').value
+        });
+        renderDetail(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    loadList(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('help-tickets-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,962 @@
+// Help Tickets Plugin for UnrealIRCd Web Panel
+// A lightweight help desk: tickets opened by users or by staff on their
+// behalf, assigned, discussed and closed, with links to related bans and notes
+
+package helptickets
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Ticket statuses
+const (
+	StatusOpen    = "open"
+	StatusWaiting = "waiting"
+	StatusClosed  = "closed"
+)
+
+// Timeline entry types
+const (
+	EntryOpened   = "opened"
+	EntryComment  = "comment"
+	EntryNote     = "note"
+	EntryAssigned = "assigned"
+	EntryStatus   = "status"
+	EntryChanged  = "changed"
+	EntryLinked   = "linked"
+	EntryUnlinked = "unlinked"
+)
+
+// Link kinds
+const (
+	LinkBan    = "ban"
+	LinkNote   = "note"
+	LinkTicket = "ticket"
+	LinkEvent  = "event"
+)
+
+// priorities in increasing order of urgency
+var priorities = []string{"low", "normal", "high", "urgent"}
+
+// HelpTicketsPlugin implements the Plugin interface
+type HelpTicketsPlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	tickets    []*Ticket
+	nextNumber int
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	DataDir       string `json:"data_dir"`
+	Categories    string `json:"categories"`
+	AutoCloseDays int    `json:"auto_close_days"`
+	LookupOnOpen  bool   `json:"lookup_on_open"`
+}
+
+// Requester identifies the person a ticket is for
+type Requester struct {
+	Nick    string `json:"nick"`
+	Account string `json:"account,omitempty"`
+	Host    string `json:"host,omitempty"`
+	IP      string `json:"ip,omitempty"`
+	Contact string `json:"contact,omitempty"`
+}
+
+// Ticket is a help request and everything that happened to it
+type Ticket struct {
+	ID         string    `json:"id"`
+	Number     int       `json:"number"`
+	Subject    string    `json:"subject"`
+	Category   string    `json:"category"`
+	Priority   string    `json:"priority"`
+	Status     string    `json:"status"`
+	Requester  Requester `json:"requester"`
+	OpenedBy   string    `json:"opened_by"`
+	OnBehalf   bool      `json:"on_behalf"`
+	AssignedTo string    `json:"assigned_to,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	ClosedAt   time.Time `json:"closed_at,omitempty"`
+	ClosedBy   string    `json:"closed_by,omitempty"`
+	Resolution string    `json:"resolution,omitempty"`
+	Links      []*Link   `json:"links"`
+	Timeline   []Entry   `json:"timeline"`
+}
+
+// Link ties a ticket to something that happened on IRC or in the panel
+type Link struct {
+	ID      string    `json:"id"`
+	Kind    string    `json:"kind"`
+	Ref     string    `json:"ref"`
+	Summary string    `json:"summary,omitempty"`
+	At      time.Time `json:"at,omitempty"`
+	AddedBy string    `json:"added_by"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Entry is an item on a ticket's timeline
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Type   string    `json:"type"`
+	Text   string    `json:"text,omitempty"`
+	LinkID string    `json:"link_id,omitempty"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	NextNumber int       `json:"next_number"`
+	Tickets    []*Ticket `json:"tickets"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &HelpTicketsPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/help-tickets",
+			Categories:    "General,Ban appeal,Account help,Abuse report,Channel help",
+			AutoCloseDays: 14,
+			LookupOnOpen:  true,
+		},
+		tickets:    make([]*Ticket, 0),
+		nextNumber: 1,
+	}
+}
+
+// Info returns plugin metadata
+func (p *HelpTicketsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Help Tickets",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Lightweight help desk with tickets, assignment and linked IRC events",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *HelpTicketsPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[help-tickets] failed to load tickets: %v", err)
+	}
+	if data.Tickets != nil {
+		p.tickets = data.Tickets
+	}
+	if data.NextNumber > p.nextNumber {
+		p.nextNumber = data.NextNumber
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "help-tickets-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		open, waiting, unassigned, urgent := 0, 0, 0, 0
+		for _, t := range p.tickets {
+			switch t.Status {
+			case StatusOpen:
+				open++
+				if t.AssignedTo == "" {
+					unassigned++
+				}
+				if t.Priority == "urgent" {
+					urgent++
+				}
+			case StatusWaiting:
+				waiting++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Help Tickets",
+			Icon:  "LifeBuoy",
+			Content: map[string]interface{}{
+				"open":       open,
+				"waiting":    waiting,
+				"unassigned": unassigned,
+				"urgent":     urgent,
+			},
+			Order: 81,
+			Size:  "sm",
+		}
+	}, 50)
+
+	// Add the requester's tickets to user lookups
+	hm.Register(hooks.HookUserLookup, "help-tickets-lookup", func(args interface{}) interface{} {
+		r := lookupRequester(args)
+		if r.Nick == "" && r.Account == "" {
+			return nil
+		}
+
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		list := make([]gin.H, 0)
+		for _, t := range p.tickets {
+			if sameRequester(r, t.Requester) {
+				list = append(list, gin.H{"number": t.Number, "subject": t.Subject, "status": t.Status})
+			}
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		return map[string]interface{}{"help_tickets": list}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.autoCloseLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *HelpTicketsPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *HelpTicketsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/help-tickets")
+	{
+		plugin.GET("/tickets", p.handleList)
+		plugin.POST("/tickets", p.handleOpen)
+		plugin.GET("/tickets/:id", p.handleGet)
+		plugin.PUT("/tickets/:id", p.handleUpdate)
+		plugin.POST("/tickets/:id/assign", p.handleAssign)
+		plugin.POST("/tickets/:id/comments", p.handleComment)
+		plugin.POST("/tickets/:id/status", p.handleStatus)
+		plugin.GET("/tickets/:id/related", p.handleRelated)
+		plugin.POST("/tickets/:id/links", p.handleAddLink)
+		plugin.DELETE("/tickets/:id/links/:link", p.handleRemoveLink)
+		plugin.GET("/categories", p.handleCategories)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the ticket database
+func (p *HelpTicketsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "tickets.json")
+}
+
+// save persists all tickets. Caller must hold p.mu.
+func (p *HelpTicketsPlugin) save() {
+	data := storeData{NextNumber: p.nextNumber, Tickets: p.tickets}
+	if err := storage.SaveJSON(p.storePath(), data); err != nil {
+		log.Printf("[help-tickets] failed to save tickets: %v", err)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *HelpTicketsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// find returns a ticket by ID or number. Caller must hold p.mu.
+func (p *HelpTicketsPlugin) find(id string) *Ticket {
+	n, _ := strconv.Atoi(strings.TrimPrefix(id, "#"))
+	for _, t := range p.tickets {
+		if t.ID == id || (n > 0 && t.Number == n) {
+			return t
+		}
+	}
+	return nil
+}
+
+// record appends an entry to a ticket's timeline
+func (t *Ticket) record(actor, typ, text, linkID string) {
+	now := time.Now().UTC()
+	t.Timeline = append(t.Timeline, Entry{Time: now, Actor: actor, Type: typ, Text: text, LinkID: linkID})
+	t.UpdatedAt = now
+}
+
+// setStatus moves a ticket to a new status and records it
+func (t *Ticket) setStatus(actor, status, resolution string) {
+	t.Status = status
+	if status == StatusClosed {
+		t.ClosedAt = time.Now().UTC()
+		t.ClosedBy = actor
+		t.Resolution = resolution
+	} else {
+		t.ClosedAt = time.Time{}
+		t.ClosedBy = ""
+		t.Resolution = ""
+	}
+	t.record(actor, EntryStatus, status+textSuffix(resolution), "")
+}
+
+// textSuffix formats optional detail to follow a timeline message
+func textSuffix(s string) string {
+	if s == "" {
+		return ""
+	}
+	return ": " + s
+}
+
+// autoCloseLoop closes tickets that have been waiting on the requester for
+// longer than auto_close_days
+func (p *HelpTicketsPlugin) autoCloseLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		p.autoClose(time.Now())
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// autoClose closes waiting tickets with no activity since the cutoff
+func (p *HelpTicketsPlugin) autoClose(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.config.AutoCloseDays <= 0 {
+		return
+	}
+	cutoff := now.Add(-time.Duration(p.config.AutoCloseDays) * 24 * time.Hour)
+	closed := 0
+	for _, t := range p.tickets {
+		if t.Status == StatusWaiting && t.UpdatedAt.Before(cutoff) {
+			t.setStatus("system", StatusClosed, fmt.Sprintf("no reply for %d days", p.config.AutoCloseDays))
+			closed++
+		}
+	}
+	if closed > 0 {
+		log.Printf("[help-tickets] closed %d tickets waiting without a reply", closed)
+		p.save()
+	}
+}
+
+// lookupRequester extracts a nick and account from HookUserLookup arguments
+func lookupRequester(args interface{}) Requester {
+	var r Requester
+	m, ok := args.(map[string]interface{})
+	if !ok {
+		return r
+	}
+	r.Nick, _ = m["nick"].(string)
+	if r.Nick == "" {
+		r.Nick, _ = m["name"].(string)
+	}
+	r.Account, _ = m["account"].(string)
+	if user, ok := m["user"].(map[string]interface{}); ok && r.Account == "" {
+		r.Account, _ = user["account"].(string)
+	}
+	r.IP, _ = m["ip"].(string)
+	return r
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleList returns tickets, most recently updated first. ?status=,
+// ?category=, ?assigned= (a name, "me" or "none"), ?nick= and ?q= filter
+// the list.
+func (p *HelpTicketsPlugin) handleList(c *gin.Context) {
+	status := c.Query("status")
+	category := c.Query("category")
+	assigned := c.Query("assigned")
+	if assigned == "me" {
+		assigned = access.Actor(c)
+	}
+	nick := c.Query("nick")
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]gin.H, 0)
+	for _, t := range p.tickets {
+		if status != "" && t.Status != status {
+			continue
+		}
+		if category != "" && !strings.EqualFold(t.Category, category) {
+			continue
+		}
+		switch assigned {
+		case "":
+		case "none":
+			if t.AssignedTo != "" {
+				continue
+			}
+		default:
+			if !strings.EqualFold(t.AssignedTo, assigned) {
+				continue
+			}
+		}
+		if nick != "" && !strings.EqualFold(t.Requester.Nick, nick) && !strings.EqualFold(t.Requester.Account, nick) {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(t.Subject+" "+t.Requester.Nick), q) {
+			continue
+		}
+		list = append(list, gin.H{
+			"id":          t.ID,
+			"number":      t.Number,
+			"subject":     t.Subject,
+			"category":    t.Category,
+			"priority":    t.Priority,
+			"status":      t.Status,
+			"requester":   t.Requester,
+			"assigned_to": t.AssignedTo,
+			"created_at":  t.CreatedAt,
+			"updated_at":  t.UpdatedAt,
+			"entries":     len(t.Timeline),
+			"links":       len(t.Links),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["updated_at"].(time.Time).After(list[j]["updated_at"].(time.Time))
+	})
+
+	c.JSON(http.StatusOK, gin.H{"tickets": list, "count": len(list)})
+}
+
+// handleOpen opens a ticket. Staff opening one for someone else give the
+// requester's nick; it defaults to the panel user otherwise.
+func (p *HelpTicketsPlugin) handleOpen(c *gin.Context) {
+	var req struct {
+		Subject     string    `json:"subject" binding:"required"`
+		Category    string    `json:"category"`
+		Priority    string    `json:"priority"`
+		Description string    `json:"description"`
+		Requester   Requester `json:"requester"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	actor := access.Actor(c)
+	req.Subject = strings.TrimSpace(req.Subject)
+	if req.Subject == "" || len(req.Subject) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "subject must be between 1 and 200 characters"})
+		return
+	}
+	if req.Priority == "" {
+		req.Priority = "normal"
+	}
+	if !lists.ContainsFold(priorities, req.Priority) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must be one of " + strings.Join(priorities, ", ")})
+		return
+	}
+	r := req.Requester
+	r.Nick = strings.TrimSpace(r.Nick)
+	named := r.Nick != ""
+	onBehalf := named && !strings.EqualFold(r.Nick, actor)
+	if !named {
+		r.Nick = actor
+	}
+
+	p.mu.RLock()
+	categories := lists.Split(p.config.Categories)
+	lookup := p.config.LookupOnOpen
+	p.mu.RUnlock()
+	if req.Category == "" && len(categories) > 0 {
+		req.Category = categories[0]
+	}
+	category := canonical(categories, req.Category)
+	if category == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category must be one of " + strings.Join(categories, ", ")})
+		return
+	}
+
+	// Fill in who the requester is on IRC right now, so bans can be matched
+	// later even after they disconnect
+	if lookup && named && (r.Host == "" || r.IP == "" || r.Account == "") {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+		u, err := p.lookupUser(ctx, r.Nick)
+		cancel()
+		if err == nil {
+			if r.Host == "" {
+				r.Host = u.Hostname
+			}
+			if r.IP == "" {
+				r.IP = u.IP
+			}
+			if r.Account == "" {
+				r.Account = u.User.Account
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	t := &Ticket{
+		ID:        newID(),
+		Subject:   req.Subject,
+		Category:  category,
+		Priority:  strings.ToLower(req.Priority),
+		Status:    StatusOpen,
+		Requester: r,
+		OpenedBy:  actor,
+		OnBehalf:  onBehalf,
+		CreatedAt: now,
+		Links:     make([]*Link, 0),
+		Timeline:  make([]Entry, 0),
+	}
+	opened := ""
+	if onBehalf {
+		opened = "on behalf of " + r.Nick
+	}
+	t.record(actor, EntryOpened, opened, "")
+	if desc := strings.TrimSpace(req.Description); desc != "" {
+		t.record(actor, EntryComment, desc, "")
+	}
+
+	p.mu.Lock()
+	t.Number = p.nextNumber
+	p.nextNumber++
+	p.tickets = append(p.tickets, t)
+	p.save()
+	p.mu.Unlock()
+
+	log.Printf("[help-tickets] %s opened #%d for %s", actor, t.Number, r.Nick)
+	c.JSON(http.StatusCreated, t)
+}
+
+// handleGet returns a ticket with its links and timeline
+func (p *HelpTicketsPlugin) handleGet(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	t := p.find(c.Param("id"))
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// handleUpdate changes the subject, category or priority of a ticket
+func (p *HelpTicketsPlugin) handleUpdate(c *gin.Context) {
+	var req struct {
+		Subject  string `json:"subject"`
+		Category string `json:"category"`
+		Priority string `json:"priority"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.find(c.Param("id"))
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	category := canonical(lists.Split(p.config.Categories), req.Category)
+	if req.Category != "" && category == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category must be one of " + strings.Join(lists.Split(p.config.Categories), ", ")})
+		return
+	}
+	if req.Priority != "" && !lists.ContainsFold(priorities, req.Priority) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must be one of " + strings.Join(priorities, ", ")})
+		return
+	}
+
+	changes := make([]string, 0, 3)
+	if s := strings.TrimSpace(req.Subject); s != "" && s != t.Subject {
+		if len(s) > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "subject must be between 1 and 200 characters"})
+			return
+		}
+		changes = append(changes, fmt.Sprintf("subject to %q", s))
+		t.Subject = s
+	}
+	if category != "" && category != t.Category {
+		changes = append(changes, "category to "+category)
+		t.Category = category
+	}
+	if prio := strings.ToLower(req.Priority); prio != "" && prio != t.Priority {
+		changes = append(changes, "priority to "+prio)
+		t.Priority = prio
+	}
+	if len(changes) > 0 {
+		t.record(actor, EntryChanged, strings.Join(changes, ", "), "")
+		p.save()
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// handleAssign assigns a ticket to a staff member. An empty assignee
+// unassigns it and "me" is the panel user making the request.
+func (p *HelpTicketsPlugin) handleAssign(c *gin.Context) {
+	var req struct {
+		Assignee string `json:"assignee"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	actor := access.Actor(c)
+	assignee := strings.TrimSpace(req.Assignee)
+	if assignee == "me" {
+		assignee = actor
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.find(c.Param("id"))
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	if t.Status == StatusClosed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ticket is closed"})
+		return
+	}
+	if assignee == t.AssignedTo {
+		c.JSON(http.StatusOK, t)
+		return
+	}
+
+	t.AssignedTo = assignee
+	if assignee == "" {
+		t.record(actor, EntryAssigned, "unassigned", "")
+	} else {
+		t.record(actor, EntryAssigned, assignee, "")
+	}
+	p.save()
+
+	c.JSON(http.StatusOK, t)
+}
+
+// handleComment adds a reply or an internal staff note to a ticket. A reply
+// to a closed ticket reopens it.
+func (p *HelpTicketsPlugin) handleComment(c *gin.Context) {
+	var req struct {
+		Text     string `json:"text" binding:"required"`
+		Internal bool   `json:"internal"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Text) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment text is required"})
+		return
+	}
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.find(c.Param("id"))
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+
+	typ := EntryComment
+	if req.Internal {
+		typ = EntryNote
+	} else if t.Status == StatusClosed {
+		t.setStatus(actor, StatusOpen, "")
+	}
+	t.record(actor, typ, strings.TrimSpace(req.Text), "")
+	p.save()
+
+	c.JSON(http.StatusCreated, t)
+}
+
+// handleStatus opens, puts on hold or closes a ticket
+func (p *HelpTicketsPlugin) handleStatus(c *gin.Context) {
+	var req struct {
+		Status     string `json:"status" binding:"required"`
+		Resolution string `json:"resolution"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	switch req.Status {
+	case StatusOpen, StatusWaiting, StatusClosed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, waiting or closed"})
+		return
+	}
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.find(c.Param("id"))
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	if t.Status == req.Status {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ticket is already " + t.Status})
+		return
+	}
+
+	t.setStatus(actor, req.Status, strings.TrimSpace(req.Resolution))
+	p.save()
+	if req.Status == StatusClosed {
+		log.Printf("[help-tickets] %s closed #%d", actor, t.Number)
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// handleRelated suggests things to link: current bans matching the
+// requester and other tickets from the same person
+func (p *HelpTicketsPlugin) handleRelated(c *gin.Context) {
+	p.mu.RLock()
+	t := p.find(c.Param("id"))
+	if t == nil {
+		p.mu.RUnlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	r := t.Requester
+	others := make([]gin.H, 0)
+	for _, o := range p.tickets {
+		if o != t && sameRequester(r, o.Requester) {
+			others = append(others, gin.H{"id": o.ID, "number": o.Number, "subject": o.Subject, "status": o.Status, "created_at": o.CreatedAt})
+		}
+	}
+	p.mu.RUnlock()
+
+	result := gin.H{"tickets": others, "bans": []rpcBan{}}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+	bans, err := p.relatedBans(ctx, r)
+	if err != nil {
+		result["bans_error"] = err.Error()
+	} else {
+		result["bans"] = bans
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handleAddLink links a ban, note, ticket or other event to a ticket
+func (p *HelpTicketsPlugin) handleAddLink(c *gin.Context) {
+	var req struct {
+		Kind    string `json:"kind" binding:"required"`
+		Ref     string `json:"ref" binding:"required"`
+		Summary string `json:"summary"`
+		At      string `json:"at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	switch req.Kind {
+	case LinkBan, LinkNote, LinkTicket, LinkEvent:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be ban, note, ticket or event"})
+		return
+	}
+	var at time.Time
+	if req.At != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, req.At); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.find(c.Param("id"))
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	ref := strings.TrimSpace(req.Ref)
+	if req.Kind == LinkTicket {
+		o := p.find(ref)
+		if o == nil || o == t {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Linked ticket not found"})
+			return
+		}
+		ref = "#" + strconv.Itoa(o.Number)
+		if req.Summary == "" {
+			req.Summary = o.Subject
+		}
+	}
+	for _, l := range t.Links {
+		if l.Kind == req.Kind && strings.EqualFold(l.Ref, ref) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This is already linked"})
+			return
+		}
+	}
+
+	l := &Link{
+		ID:      newID(),
+		Kind:    req.Kind,
+		Ref:     ref,
+		Summary: strings.TrimSpace(req.Summary),
+		At:      at.UTC(),
+		AddedBy: actor,
+		AddedAt: time.Now().UTC(),
+	}
+	t.Links = append(t.Links, l)
+	t.record(actor, EntryLinked, l.Kind+" "+l.Ref+textSuffix(l.Summary), l.ID)
+	p.save()
+
+	c.JSON(http.StatusCreated, t)
+}
+
+// handleRemoveLink removes a link from a ticket. The timeline keeps a
+// record of it.
+func (p *HelpTicketsPlugin) handleRemoveLink(c *gin.Context) {
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.find(c.Param("id"))
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	for i, l := range t.Links {
+		if l.ID != c.Param("link") {
+			continue
+		}
+		t.Links = append(t.Links[:i], t.Links[i+1:]...)
+		t.record(actor, EntryUnlinked, l.Kind+" "+l.Ref, l.ID)
+		p.save()
+		c.JSON(http.StatusOK, t)
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+}
+
+// handleCategories returns the configured categories and priorities
+func (p *HelpTicketsPlugin) handleCategories(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": lists.Split(p.config.Categories),
+		"priorities": priorities,
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *HelpTicketsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *HelpTicketsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if len(lists.Split(newConfig.Categories)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one category is required"})
+		return
+	}
+	if newConfig.AutoCloseDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "auto_close_days cannot be negative"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *HelpTicketsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *HelpTicketsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
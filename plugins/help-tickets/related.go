@@ -0,0 +1,113 @@
+package helptickets
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+)
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	User     struct {
+		Username string `json:"username"`
+		Account  string `json:"account"`
+	} `json:"user"`
+}
+
+// rpcBan is a ban as returned by server_ban.list
+type rpcBan struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	SetBy    string `json:"set_by"`
+	SetAt    string `json:"set_at"`
+	ExpireAt string `json:"expire_at"`
+	Reason   string `json:"reason"`
+}
+
+// lookupUser fetches an online user by nick
+func (p *HelpTicketsPlugin) lookupUser(ctx context.Context, nick string) (*rpcUser, error) {
+	var out struct {
+		Client rpcUser `json:"client"`
+	}
+	params := map[string]interface{}{"nick": nick, "object_detail_level": 2}
+	if err := p.client().Call(ctx, "user.get", params, &out); err != nil {
+		return nil, err
+	}
+	return &out.Client, nil
+}
+
+// relatedBans returns the current server bans that match the requester of
+// a ticket
+func (p *HelpTicketsPlugin) relatedBans(ctx context.Context, r Requester) ([]rpcBan, error) {
+	var out struct {
+		List []rpcBan `json:"list"`
+	}
+	if err := p.client().Call(ctx, "server_ban.list", nil, &out); err != nil {
+		return nil, err
+	}
+	bans := make([]rpcBan, 0)
+	for _, b := range out.List {
+		if banMatches(b.Name, r) {
+			bans = append(bans, b)
+		}
+	}
+	return bans, nil
+}
+
+// banMatches reports whether a ban mask covers the requester. Account
+// extbans (~account: and ~a:) match the account; other masks match the
+// host or IP, with or without a user part.
+func banMatches(mask string, r Requester) bool {
+	lower := strings.ToLower(mask)
+	for _, prefix := range []string{"~account:", "~a:"} {
+		if strings.HasPrefix(lower, prefix) {
+			return r.Account != "" && ircname.MatchMask(mask[len(prefix):], r.Account)
+		}
+	}
+	if strings.HasPrefix(mask, "~") {
+		return false
+	}
+	host := mask
+	if i := strings.LastIndex(mask, "@"); i >= 0 {
+		host = mask[i+1:]
+	}
+	if _, ipnet, err := net.ParseCIDR(host); err == nil {
+		ip := net.ParseIP(r.IP)
+		return ip != nil && ipnet.Contains(ip)
+	}
+	// A mask of only wildcards would tie every ticket to it
+	if strings.Trim(host, "*?.") == "" {
+		return false
+	}
+	return (r.Host != "" && ircname.MatchMask(host, r.Host)) || (r.IP != "" && ircname.MatchMask(host, r.IP))
+}
+
+// sameRequester reports whether two requesters are the same person by
+// account, nick or IP
+func sameRequester(a, b Requester) bool {
+	switch {
+	case a.Account != "" && strings.EqualFold(a.Account, b.Account):
+		return true
+	case a.Nick != "" && strings.EqualFold(a.Nick, b.Nick):
+		return true
+	case a.IP != "" && a.IP == b.IP:
+		return true
+	}
+	return false
+}
+
+// canonical returns the item of list equal to s ignoring case, or "" if
+// there is none
+func canonical(list []string, s string) string {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return item
+		}
+	}
+	return ""
+}
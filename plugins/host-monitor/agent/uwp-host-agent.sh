@@ -0,0 +1,46 @@
+#!/bin/sh
+# Host agent for the Host Monitor plugin.
+# Reads CPU, memory, file descriptor and load figures from /proc and posts
+# them to the panel. Run it from cron every minute on each IRCd host:
+#
+#   * * * * * PANEL_URL=https://panel.example.net SERVER=irc1.example.net \
+#             AGENT_TOKEN=secret /usr/local/bin/uwp-host-agent.sh
+#
+# PANEL_TOKEN may hold a panel API token if your panel requires one.
+
+set -eu
+
+: "${PANEL_URL:?PANEL_URL is required}"
+: "${SERVER:?SERVER is required}"
+: "${AGENT_TOKEN:?AGENT_TOKEN is required}"
+
+cpu_sample() {
+	awk '/^cpu / { idle = $5 + $6; total = 0; for (i = 2; i <= NF; i++) total += $i; print total, idle }' /proc/stat
+}
+
+set -- $(cpu_sample)
+t1=$1 i1=$2
+sleep 1
+set -- $(cpu_sample)
+t2=$1 i2=$2
+cpu=$(awk -v t="$((t2 - t1))" -v i="$((i2 - i1))" 'BEGIN { if (t > 0) printf "%.2f", (1 - i / t) * 100; else print 0 }')
+
+mem_total=$(awk '/^MemTotal:/ { print $2 * 1024 }' /proc/meminfo)
+mem_avail=$(awk '/^MemAvailable:/ { print $2 * 1024 }' /proc/meminfo)
+mem_used=$((mem_total - mem_avail))
+
+set -- $(cat /proc/sys/fs/file-nr)
+fd_used=$(($1 - $2))
+fd_max=$3
+
+load1=$(cut -d ' ' -f 1 /proc/loadavg)
+
+body=$(printf '{"server":"%s","cpu_percent":%s,"memory_used_bytes":%s,"memory_total_bytes":%s,"fd_used":%s,"fd_max":%s,"load1":%s}' \
+	"$SERVER" "$cpu" "$mem_used" "$mem_total" "$fd_used" "$fd_max" "$load1")
+
+curl -fsS -X POST \
+	-H "Content-Type: application/json" \
+	-H "X-Agent-Token: $AGENT_TOKEN" \
+	${PANEL_TOKEN:+-H "Authorization: Bearer $PANEL_TOKEN"} \
+	-d "$body" \
+	"$PANEL_URL/api/plugin/host-monitor/agent" >/dev/null
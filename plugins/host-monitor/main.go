@@ -0,0 +1,618 @@
+// Host Monitor Plugin for UnrealIRCd Web Panel
+// Shows CPU, memory, file descriptor and SendQ usage of each IRCd host next
+// to its IRC-level stats, from a small push agent or node_exporter
+
+package hostmonitor
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Metric names used for thresholds and alerts
+const (
+	metricCPU   = "cpu"
+	metricMem   = "memory"
+	metricFD    = "fd"
+	metricSendQ = "sendq"
+	metricStale = "stale"
+)
+
+// HostMonitorPlugin implements the Plugin interface
+type HostMonitorPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	hosts     map[string]*Host
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string  `json:"rpc_url"`
+	RPCUser        string  `json:"rpc_user"`
+	RPCPassword    string  `json:"rpc_password"`
+	RPCInsecure    bool    `json:"rpc_insecure"`
+	Exporters      string  `json:"exporters"`
+	AgentToken     string  `json:"agent_token"`
+	PollInterval   int     `json:"poll_interval"`
+	RetentionHours int     `json:"retention_hours"`
+	CPUThreshold   float64 `json:"cpu_threshold"`
+	MemThreshold   float64 `json:"memory_threshold"`
+	FDThreshold    float64 `json:"fd_threshold"`
+	SendQThreshold int64   `json:"sendq_threshold"`
+	StaleMinutes   int     `json:"stale_minutes"`
+	AlertWebhook   string  `json:"alert_webhook"`
+}
+
+// Sample is one point of a host's history
+type Sample struct {
+	Time  time.Time `json:"time"`
+	CPU   *float64  `json:"cpu_percent,omitempty"`
+	Mem   *float64  `json:"memory_percent,omitempty"`
+	FD    *float64  `json:"fd_percent,omitempty"`
+	Load1 *float64  `json:"load1,omitempty"`
+	SendQ *int64    `json:"sendq_bytes,omitempty"`
+	Users int       `json:"users"`
+}
+
+// Host is the current state of one IRCd host
+type Host struct {
+	Server      string          `json:"server"`
+	Source      string          `json:"source"`
+	Linked      bool            `json:"linked"`
+	Users       int             `json:"users"`
+	SendQ       *int64          `json:"sendq_bytes"`
+	CPU         *float64        `json:"cpu_percent"`
+	MemUsed     *float64        `json:"memory_used_bytes"`
+	MemTotal    *float64        `json:"memory_total_bytes"`
+	Mem         *float64        `json:"memory_percent"`
+	FDUsed      *float64        `json:"fd_used"`
+	FDMax       *float64        `json:"fd_max"`
+	FD          *float64        `json:"fd_percent"`
+	Load1       *float64        `json:"load1"`
+	MetricsAt   *time.Time      `json:"metrics_at"`
+	Error       string          `json:"error,omitempty"`
+	Alerting    map[string]bool `json:"alerting"`
+	History     []Sample        `json:"-"`
+	exporterURL string
+	prevCPU     *nodeMetrics
+}
+
+// AgentReport is the payload pushed by the host agent
+type AgentReport struct {
+	Server   string   `json:"server" binding:"required"`
+	CPU      *float64 `json:"cpu_percent"`
+	MemUsed  *float64 `json:"memory_used_bytes"`
+	MemTotal *float64 `json:"memory_total_bytes"`
+	FDUsed   *float64 `json:"fd_used"`
+	FDMax    *float64 `json:"fd_max"`
+	Load1    *float64 `json:"load1"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		NumUsers int    `json:"num_users"`
+		ULined   bool   `json:"ulined"`
+		SendQ    *int64 `json:"sendq"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &HostMonitorPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			PollInterval:   60,
+			RetentionHours: 24,
+			CPUThreshold:   90,
+			MemThreshold:   90,
+			FDThreshold:    80,
+			SendQThreshold: 1048576,
+			StaleMinutes:   5,
+		},
+		hosts: make(map[string]*Host),
+	}
+}
+
+// Info returns plugin metadata
+func (p *HostMonitorPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Host Monitor",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "CPU, memory, file descriptor and SendQ usage per IRCd host",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *HostMonitorPlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "host-monitor-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		reporting, alerting := 0, 0
+		var busiest string
+		var maxCPU float64
+		for _, h := range p.hosts {
+			if h.MetricsAt != nil {
+				reporting++
+			}
+			if len(h.Alerting) > 0 {
+				alerting++
+			}
+			if h.CPU != nil && *h.CPU >= maxCPU {
+				maxCPU = *h.CPU
+				busiest = h.Server
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "IRCd Hosts",
+			Icon:  "Cpu",
+			Content: map[string]interface{}{
+				"reporting":   reporting,
+				"alerting":    alerting,
+				"busiest":     busiest,
+				"busiest_cpu": maxCPU,
+			},
+			Order: 39,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *HostMonitorPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *HostMonitorPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/host-monitor")
+	{
+		plugin.GET("/hosts", p.handleHosts)
+		plugin.GET("/hosts/:server/history", p.handleHistory)
+		plugin.POST("/agent", p.handleAgent)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *HostMonitorPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop collects IRC stats and scrapes exporters until shutdown
+func (p *HostMonitorPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.poll()
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 15*time.Second {
+			interval = 15 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// exporters parses the exporters setting: one "server=url" per line or
+// comma separated
+func exporters(s string) map[string]string {
+	out := make(map[string]string)
+	for _, entry := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		server, url, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if ok {
+			out[strings.ToLower(strings.TrimSpace(server))] = strings.TrimSpace(url)
+		}
+	}
+	return out
+}
+
+// host returns the state of a server, creating it if needed. Caller must
+// hold p.mu.
+func (p *HostMonitorPlugin) host(server string) *Host {
+	key := strings.ToLower(server)
+	h, ok := p.hosts[key]
+	if !ok {
+		h = &Host{Server: server, Alerting: make(map[string]bool), History: make([]Sample, 0)}
+		p.hosts[key] = h
+	}
+	return h
+}
+
+// poll updates IRC stats, scrapes node_exporter targets, records a sample
+// per host and evaluates thresholds
+func (p *HostMonitorPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	p.mu.RLock()
+	targets := exporters(p.config.Exporters)
+	p.mu.RUnlock()
+
+	var list struct {
+		List []rpcServer `json:"list"`
+	}
+	rpcErr := p.client().Call(ctx, "server.list", nil, &list)
+
+	scraped := make(map[string]*nodeMetrics, len(targets))
+	scrapeErrs := make(map[string]string)
+	var smu sync.Mutex
+	var wg sync.WaitGroup
+	for server, url := range targets {
+		wg.Add(1)
+		go func(server, url string) {
+			defer wg.Done()
+			m, err := scrapeNode(ctx, url)
+			smu.Lock()
+			defer smu.Unlock()
+			if err != nil {
+				scrapeErrs[server] = err.Error()
+				return
+			}
+			scraped[server] = m
+		}(server, url)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	now := time.Now().UTC()
+	cfg := p.config
+
+	if rpcErr != nil {
+		p.lastError = rpcErr.Error()
+		log.Printf("[host-monitor] failed to list servers: %v", rpcErr)
+	} else {
+		p.lastError = ""
+		for _, h := range p.hosts {
+			h.Linked = false
+		}
+		for _, rs := range list.List {
+			if rs.Server.ULined {
+				continue
+			}
+			h := p.host(rs.Name)
+			h.Linked = true
+			h.Users = rs.Server.NumUsers
+			h.SendQ = rs.Server.SendQ
+		}
+	}
+
+	for server, url := range targets {
+		h := p.host(server)
+		h.exporterURL = url
+		if msg, failed := scrapeErrs[server]; failed {
+			h.Error = msg
+			continue
+		}
+		h.applyNode(scraped[server])
+	}
+
+	alerts := make([]notify.Event, 0)
+	cutoff := now.Add(-time.Duration(cfg.RetentionHours) * time.Hour)
+	stale := time.Duration(cfg.StaleMinutes) * time.Minute
+	for _, h := range p.hosts {
+		fresh := h.MetricsAt != nil && (stale <= 0 || now.Sub(*h.MetricsAt) < stale)
+		s := Sample{Time: now, Users: h.Users, SendQ: h.SendQ}
+		if fresh {
+			s.CPU, s.Mem, s.FD, s.Load1 = h.CPU, h.Mem, h.FD, h.Load1
+		}
+		h.History = append(h.History, s)
+		keep := sort.Search(len(h.History), func(i int) bool { return h.History[i].Time.After(cutoff) })
+		if keep > 0 {
+			h.History = append([]Sample(nil), h.History[keep:]...)
+		}
+
+		if h.MetricsAt != nil || h.exporterURL != "" {
+			alerts = h.check(alerts, metricStale, !fresh, "stopped reporting host metrics", "warning", 0, 0)
+		}
+		if fresh {
+			alerts = h.checkPercent(alerts, metricCPU, h.CPU, cfg.CPUThreshold, "CPU")
+			alerts = h.checkPercent(alerts, metricMem, h.Mem, cfg.MemThreshold, "memory")
+			alerts = h.checkPercent(alerts, metricFD, h.FD, cfg.FDThreshold, "file descriptor")
+		}
+		if h.SendQ != nil && cfg.SendQThreshold > 0 {
+			alerts = h.check(alerts, metricSendQ, *h.SendQ > cfg.SendQThreshold,
+				fmt.Sprintf("SendQ at %d bytes (threshold %d)", *h.SendQ, cfg.SendQThreshold), "warning", float64(*h.SendQ), float64(cfg.SendQThreshold))
+		}
+	}
+	webhook := cfg.AlertWebhook
+	p.mu.Unlock()
+
+	for _, a := range alerts {
+		log.Printf("[host-monitor] %s: %s", a.Title, a.Message)
+		if webhook != "" {
+			a.Source = "host-monitor"
+			a.Timestamp = now
+			go p.alert(webhook, a)
+		}
+	}
+}
+
+// applyNode updates the host from a node_exporter scrape. CPU usage needs
+// two scrapes, since node_exporter only exposes counters.
+func (h *Host) applyNode(m *nodeMetrics) {
+	h.Source = "node_exporter"
+	h.Error = ""
+	at := m.ScrapedAt.UTC()
+	h.MetricsAt = &at
+
+	h.CPU = nil
+	if m.HaveCPU && h.prevCPU != nil {
+		total := m.CPUTotal - h.prevCPU.CPUTotal
+		idle := m.CPUIdle - h.prevCPU.CPUIdle
+		if total > 0 {
+			v := (1 - idle/total) * 100
+			h.CPU = &v
+		}
+	}
+	if m.HaveCPU {
+		h.prevCPU = m
+	}
+
+	h.MemUsed, h.MemTotal, h.Mem = nil, nil, nil
+	if m.HaveMem && m.MemTotal > 0 {
+		used := m.MemTotal - m.MemAvail
+		total := m.MemTotal
+		h.MemUsed, h.MemTotal, h.Mem = &used, &total, percent(used, total)
+	}
+	h.FDUsed, h.FDMax, h.FD = nil, nil, nil
+	if m.HaveFD {
+		used, max := m.FDUsed, m.FDMax
+		h.FDUsed, h.FDMax, h.FD = &used, &max, percent(used, max)
+	}
+	h.Load1 = nil
+	if m.HaveLoad {
+		load := m.Load1
+		h.Load1 = &load
+	}
+}
+
+// percent returns used/total as a percentage, or nil if total is unknown
+func percent(used, total float64) *float64 {
+	if total <= 0 {
+		return nil
+	}
+	v := used / total * 100
+	return &v
+}
+
+// checkPercent evaluates a percentage threshold
+func (h *Host) checkPercent(alerts []notify.Event, metric string, value *float64, threshold float64, label string) []notify.Event {
+	if value == nil || threshold <= 0 {
+		return alerts
+	}
+	return h.check(alerts, metric, *value >= threshold,
+		fmt.Sprintf("%s usage at %.0f%% (threshold %.0f%%)", label, *value, threshold), "warning", *value, threshold)
+}
+
+// check raises an alert when a condition starts and a recovery alert when
+// it ends
+func (h *Host) check(alerts []notify.Event, metric string, active bool, detail, severity string, value, threshold float64) []notify.Event {
+	if active == h.Alerting[metric] {
+		return alerts
+	}
+	data := map[string]interface{}{"server": h.Server, "metric": metric}
+	if threshold > 0 {
+		data["value"] = value
+		data["threshold"] = threshold
+	}
+	if active {
+		h.Alerting[metric] = true
+		return append(alerts, notify.Event{
+			Type:     "host_" + metric,
+			Severity: severity,
+			Title:    "Host alert: " + h.Server,
+			Message:  h.Server + ": " + detail,
+			Data:     data,
+		})
+	}
+	delete(h.Alerting, metric)
+	return append(alerts, notify.Event{
+		Type:     "host_" + metric + "_recovered",
+		Severity: "info",
+		Title:    "Host recovered: " + h.Server,
+		Message:  fmt.Sprintf("%s: %s back to normal", h.Server, metric),
+		Data:     data,
+	})
+}
+
+// alert delivers an alert to the webhook
+func (p *HostMonitorPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[host-monitor] failed to send alert: %v", err)
+	}
+}
+
+// handleHosts returns the current state of every host
+func (p *HostMonitorPlugin) handleHosts(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Host, 0, len(p.hosts))
+	for _, h := range p.hosts {
+		list = append(list, h)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Server < list[j].Server })
+
+	c.JSON(http.StatusOK, gin.H{
+		"hosts": list,
+		"error": p.lastError,
+	})
+}
+
+// handleHistory returns the sample history of one host
+func (p *HostMonitorPlugin) handleHistory(c *gin.Context) {
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hours < 1 {
+		hours = 24
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	h, ok := p.hosts[strings.ToLower(c.Param("server"))]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found"})
+		return
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	history := make([]Sample, 0)
+	for _, s := range h.History {
+		if !s.Time.Before(since) {
+			history = append(history, s)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"server":  h.Server,
+		"history": history,
+	})
+}
+
+// handleAgent accepts a metrics report from the host agent
+func (p *HostMonitorPlugin) handleAgent(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.AgentToken
+	p.mu.RUnlock()
+	if token == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Agent-Token")), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid agent token"})
+		return
+	}
+
+	var report AgentReport
+	if err := c.ShouldBindJSON(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report"})
+		return
+	}
+
+	p.mu.Lock()
+	h := p.host(report.Server)
+	now := time.Now().UTC()
+	h.Source = "agent"
+	h.Error = ""
+	h.MetricsAt = &now
+	h.CPU = report.CPU
+	h.MemUsed, h.MemTotal, h.Mem = report.MemUsed, report.MemTotal, nil
+	if report.MemUsed != nil && report.MemTotal != nil {
+		h.Mem = percent(*report.MemUsed, *report.MemTotal)
+	}
+	h.FDUsed, h.FDMax, h.FD = report.FDUsed, report.FDMax, nil
+	if report.FDUsed != nil && report.FDMax != nil {
+		h.FD = percent(*report.FDUsed, *report.FDMax)
+	}
+	h.Load1 = report.Load1
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report accepted"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *HostMonitorPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AgentToken = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *HostMonitorPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.AgentToken == "" {
+		newConfig.AgentToken = p.config.AgentToken
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *HostMonitorPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *HostMonitorPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
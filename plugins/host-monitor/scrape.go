@@ -0,0 +1,120 @@
+package hostmonitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// nodeMetrics is the subset of node_exporter metrics we use
+type nodeMetrics struct {
+	CPUTotal  float64
+	CPUIdle   float64
+	MemTotal  float64
+	MemAvail  float64
+	FDUsed    float64
+	FDMax     float64
+	Load1     float64
+	HaveCPU   bool
+	HaveMem   bool
+	HaveFD    bool
+	HaveLoad  bool
+	ScrapedAt time.Time
+}
+
+// scrapeClient is used for node_exporter scrapes
+var scrapeClient = &http.Client{Timeout: 10 * time.Second}
+
+// scrapeNode fetches and parses a node_exporter /metrics page
+func scrapeNode(ctx context.Context, url string) (*nodeMetrics, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := scrapeClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("node_exporter: unexpected status %s", resp.Status)
+	}
+	return parseNodeMetrics(io.LimitReader(resp.Body, 16<<20))
+}
+
+// parseNodeMetrics reads the Prometheus text format and sums up the
+// metrics we need
+func parseNodeMetrics(r io.Reader) (*nodeMetrics, error) {
+	m := &nodeMetrics{ScrapedAt: time.Now()}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name, labels, value, ok := parseSample(line)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "node_cpu_seconds_total":
+			m.HaveCPU = true
+			m.CPUTotal += value
+			if strings.Contains(labels, `mode="idle"`) || strings.Contains(labels, `mode="iowait"`) {
+				m.CPUIdle += value
+			}
+		case "node_memory_MemTotal_bytes":
+			m.HaveMem = true
+			m.MemTotal = value
+		case "node_memory_MemAvailable_bytes":
+			m.MemAvail = value
+		case "node_filefd_allocated":
+			m.HaveFD = true
+			m.FDUsed = value
+		case "node_filefd_maximum":
+			m.FDMax = value
+		case "node_load1":
+			m.HaveLoad = true
+			m.Load1 = value
+		}
+	}
+	return m, sc.Err()
+}
+
+// parseSample splits "name{labels} value [timestamp]"
+func parseSample(line string) (name, labels string, value float64, ok bool) {
+	rest := line
+	if i := strings.IndexByte(line, '{'); i >= 0 {
+		j := strings.LastIndexByte(line, '}')
+		if j < i {
+			return "", "", 0, false
+		}
+		name, labels, rest = line[:i], line[i+1:j], line[j+1:]
+	} else {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return "", "", 0, false
+		}
+		name, rest = fields[0], strings.Join(fields[1:], " ")
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", "", 0, false
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", "", 0, false
+	}
+	return name, labels, v, true
+}
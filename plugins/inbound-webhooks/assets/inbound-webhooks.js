@@ -0,0 +1,368 @@
+/**
+ * Inbound Webhooks Frontend Script
+ *
+ * Manages the hooks that turn webhooks from other services into IRC
+ * notices, previews their templates and lists recent requests.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'inbound-webhooks';
+  const PLUGIN_NAME = 'Inbound Webhooks';
+  const PAGE_PATH = '/plugins/inbound-webhooks';
+  const API_BASE = '/api/plugin/inbound-webhooks';
+
+  // Hooks as last loaded, for the form's preview
+  let currentHooks = [];
+
+  const FORMATS = {
+    github: 'GitHub',
+    statuspage: 'Statuspage',
+    alertmanager: 'Alertmanager / Grafana',
+    generic: 'Generic JSON or text',
+  };
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const res = await fetch(API_BASE + path, {
+      method,
+      headers: getAuthHeaders(),
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t && !t.startsWith('0001') ? new Date(t).toLocaleString() : '';
+  }
+
+  function hookURL(hook) {
+    return `${window.location.origin}${API_BASE}/public/hooks/${hook.id}`;
+  }
+
+  function list(s) {
+    return s.split(/[\s,]+/).map(x => x.trim()).filter(Boolean);
+  }
+
+  function injectStyles() {
+    if (document.getElementById('inbound-webhooks-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'inbound-webhooks-styles';
+    style.textContent = `
+      .ihk-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .ihk-form { display: grid; grid-template-columns: repeat(auto-fill, minmax(14rem, 1fr)); gap: 0.5rem; align-items: end; }
+      .ihk-form label { display: flex; flex-direction: column; gap: 0.2rem; font-size: 0.8rem; }
+      .ihk-form .ihk-wide { grid-column: 1 / -1; }
+      .ihk-app input, .ihk-app select, .ihk-app textarea { background: var(--bg-secondary, #181825); color: var(--text-primary, #cdd6f4); border: 1px solid var(--border-primary, #313244); border-radius: 6px; padding: 0.35rem 0.5rem; font-family: inherit; }
+      .ihk-app textarea { font-family: monospace; min-height: 4rem; }
+      .ihk-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .ihk-table th, .ihk-table td { text-align: left; padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .ihk-table td { color: var(--text-primary, #cdd6f4); }
+      .ihk-table code { word-break: break-all; font-size: 0.8rem; }
+      .ihk-app button { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); border: none; border-radius: 6px; padding: 0.4rem 0.8rem; cursor: pointer; }
+      .ihk-app button:disabled { opacity: 0.5; cursor: default; }
+      .ihk-app button.ihk-danger { background: var(--error, #f38ba8); }
+      .ihk-sent { color: var(--success, #a6e3a1); }
+      .ihk-ignored, .ihk-queued { color: var(--text-secondary, #a6adc8); }
+      .ihk-dropped { color: var(--warning, #f9e2af); }
+      .ihk-failed, .ihk-rejected, .ihk-error { color: var(--error, #f38ba8); }
+      .ihk-secret { background: var(--bg-secondary, #181825); border: 1px solid var(--warning, #f9e2af); border-radius: 6px; padding: 0.6rem; }
+      .ihk-preview { white-space: pre-wrap; font-family: monospace; font-size: 0.85rem; }
+      .ihk-desc { font-size: 0.8rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderForm(hook) {
+    const h = hook || { format: 'github', target: 'channel', min_severity: 'info', enabled: true };
+    return `
+      <form class="ihk-form" id="ihk-form" data-id="${escapeHtml(h.id || '')}">
+        <label>Name<input name="name" value="${escapeHtml(h.name || '')}" maxlength="64" required></label>
+        <label>Format
+          <select name="format">
+            ${Object.entries(FORMATS).map(([k, v]) => `<option value="${k}" ${h.format === k ? 'selected' : ''}>${v}</option>`).join('')}
+          </select>
+        </label>
+        <label>Send to
+          <select name="target">
+            <option value="channel" ${h.target === 'channel' ? 'selected' : ''}>Channel members</option>
+            <option value="opers" ${h.target === 'opers' ? 'selected' : ''}>IRC operators</option>
+            <option value="global" ${h.target === 'global' ? 'selected' : ''}>Everyone (global notice)</option>
+          </select>
+        </label>
+        <label>Channels<input name="channels" value="${escapeHtml((h.channels || []).join(', '))}" placeholder="#staff, #dev"></label>
+        <label>Events<input name="events" value="${escapeHtml((h.events || []).join(', '))}" placeholder="All events"></label>
+        <label>Minimum severity
+          <select name="min_severity">
+            ${['info', 'warning', 'critical'].map(s => `<option value="${s}" ${h.min_severity === s ? 'selected' : ''}>${s}</option>`).join('')}
+          </select>
+        </label>
+        <label>Allowed addresses<input name="allow_ips" value="${escapeHtml((h.allow_ips || []).join(', '))}" placeholder="Any address"></label>
+        <label>Secret<input name="secret" type="password" autocomplete="new-password" placeholder="${h.id ? 'Unchanged' : 'Generated'}"></label>
+        <label class="ihk-wide">Template<textarea name="template" placeholder="Default: [{{.Hook}}] {{.Title}} - message and link">${escapeHtml(h.template || '')}</textarea></label>
+        <div class="ihk-wide">
+          <button type="submit">${h.id ? 'Save hook' : 'Add hook'}</button>
+          ${h.id ? '<button type="button" id="ihk-cancel">Cancel</button>' : ''}
+          <button type="button" id="ihk-preview">Preview</button>
+          <span class="ihk-desc">Templates use Go template syntax with .Title, .Message, .URL, .Event, .Action, .Severity and the payload as .Data. Each line is one notice; a template that renders nothing skips the event.</span>
+        </div>
+      </form>
+      <div id="ihk-preview-out" class="ihk-preview"></div>
+    `;
+  }
+
+  function formBody(form) {
+    const f = form.elements;
+    const body = {
+      name: f.name.value,
+      format: f.format.value,
+      target: f.target.value,
+      channels: list(f.channels.value),
+      events: list(f.events.value),
+      min_severity: f.min_severity.value,
+      allow_ips: list(f.allow_ips.value),
+      template: f.template.value,
+    };
+    if (f.secret.value) body.secret = f.secret.value;
+    return body;
+  }
+
+  function showSecret(container, hook, secret) {
+    container.querySelector('#ihk-secret').innerHTML = `
+      <div class="ihk-secret">
+        <strong>Secret for ${escapeHtml(hook)}</strong>, shown only this once:
+        <code>${escapeHtml(secret)}</code>
+        <div class="ihk-desc">GitHub: use it as the webhook secret. Others: send it as a bearer token, in an X-Webhook-Token header or as ?token=.</div>
+      </div>
+    `;
+  }
+
+  function renderHooks(hooks) {
+    return `
+      <table class="ihk-table">
+        <thead><tr><th>Hook</th><th>Format</th><th>Sends to</th><th>URL</th><th>Requests</th><th>Last</th><th></th></tr></thead>
+        <tbody>
+          ${hooks.map(h => `
+            <tr>
+              <td>${escapeHtml(h.name)}${h.enabled ? '' : ' <span class="ihk-error">(disabled)</span>'}</td>
+              <td>${escapeHtml(FORMATS[h.format] || h.format)}</td>
+              <td>${h.target === 'channel' ? escapeHtml(h.channels.join(', ')) : h.target === 'opers' ? 'IRC operators' : 'Everyone'}</td>
+              <td><code>${escapeHtml(hookURL(h))}</code></td>
+              <td>${h.received} received, ${h.sent} sent, ${h.rejected} rejected</td>
+              <td>${formatTime(h.last_received)}${h.last_error ? `<div class="ihk-error">${escapeHtml(h.last_error)}</div>` : ''}</td>
+              <td>
+                <button class="ihk-edit" data-id="${escapeHtml(h.id)}">Edit</button>
+                <button class="ihk-toggle" data-id="${escapeHtml(h.id)}">${h.enabled ? 'Disable' : 'Enable'}</button>
+                <button class="ihk-rotate" data-id="${escapeHtml(h.id)}">New secret</button>
+                <button class="ihk-danger ihk-delete" data-id="${escapeHtml(h.id)}">Delete</button>
+              </td>
+            </tr>
+          `).join('') || '<tr><td colspan="7">No hooks yet</td></tr>'}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function renderDeliveries(deliveries) {
+    return `
+      <table class="ihk-table">
+        <thead><tr><th>Time</th><th>Hook</th><th>From</th><th>Event</th><th>Result</th><th>Notice</th></tr></thead>
+        <tbody>
+          ${deliveries.map(d => `
+            <tr>
+              <td>${formatTime(d.time)}</td>
+              <td>${escapeHtml(d.hook)}</td>
+              <td>${escapeHtml(d.remote_ip)}</td>
+              <td>${escapeHtml(d.event || '')}</td>
+              <td class="ihk-${escapeHtml(d.state)}">${escapeHtml(d.state)}${d.state === 'sent' ? ` to ${d.recipients}` : ''}${d.error ? `: ${escapeHtml(d.error)}` : ''}</td>
+              <td>${(d.lines || []).map(escapeHtml).join('<br>')}</td>
+            </tr>
+          `).join('') || '<tr><td colspan="6">No requests yet</td></tr>'}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function bindForm(container) {
+    const form = container.querySelector('#ihk-form');
+    const id = form.dataset.id;
+    form.addEventListener('submit', e => {
+      e.preventDefault();
+      run(container, form.querySelector('button[type="submit"]'), async () => {
+        const body = formBody(form);
+        if (id) {
+          await api('PUT', `/hooks/${id}`, body);
+        } else {
+          const created = await api('POST', '/hooks', body);
+          if (!body.secret) showSecret(container, created.name, created.secret);
+        }
+        container.querySelector('#ihk-editor').innerHTML = renderForm();
+        bindForm(container);
+      });
+    });
+
+    const cancel = form.querySelector('#ihk-cancel');
+    if (cancel) {
+      cancel.addEventListener('click', () => {
+        container.querySelector('#ihk-editor').innerHTML = renderForm();
+        bindForm(container);
+      });
+    }
+
+    form.querySelector('#ihk-preview').addEventListener('click', async () => {
+      const out = container.querySelector('#ihk-preview-out');
+      const target = id || (currentHooks[0] && currentHooks[0].id);
+      if (!target) {
+        out.textContent = 'Add a hook first to preview templates.';
+        return;
+      }
+      try {
+        const res = await api('POST', `/hooks/${target}/preview`, { template: form.elements.template.value });
+        out.textContent = res.lines.length ? res.lines.join('\n') : '(nothing; the event would be skipped)';
+      } catch (err) {
+        out.innerHTML = `<span class="ihk-error">${escapeHtml(err.message)}</span>`;
+      }
+    });
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#ihk-body');
+    try {
+      const [hooks, deliveries] = await Promise.all([api('GET', '/hooks'), api('GET', '/deliveries?limit=100')]);
+      currentHooks = hooks.hooks;
+      body.innerHTML = `
+        <h3>Hooks</h3>
+        ${renderHooks(hooks.hooks)}
+        <h3>Recent requests</h3>
+        ${renderDeliveries(deliveries.deliveries)}
+      `;
+
+      if (!container.querySelector('#ihk-form')) {
+        container.querySelector('#ihk-editor').innerHTML = renderForm();
+        bindForm(container);
+      }
+
+      const byId = id => hooks.hooks.find(h => h.id === id);
+      body.querySelectorAll('.ihk-edit').forEach(btn => {
+        btn.addEventListener('click', () => {
+          container.querySelector('#ihk-editor').innerHTML = renderForm(byId(btn.dataset.id));
+          bindForm(container);
+          container.querySelector('#ihk-editor').scrollIntoView({ behavior: 'smooth' });
+        });
+      });
+      body.querySelectorAll('.ihk-toggle').forEach(btn => {
+        const h = byId(btn.dataset.id);
+        btn.addEventListener('click', () => run(container, btn, () => api('PUT', `/hooks/${h.id}`, { ...h, enabled: !h.enabled, secret: undefined })));
+      });
+      body.querySelectorAll('.ihk-rotate').forEach(btn => {
+        btn.addEventListener('click', () => {
+          if (!confirm('Replace this hook\'s secret? The service sending to it must be updated with the new one.')) return;
+          run(container, btn, async () => {
+            const res = await api('POST', `/hooks/${btn.dataset.id}/secret`);
+            showSecret(container, byId(btn.dataset.id).name, res.secret);
+          });
+        });
+      });
+      body.querySelectorAll('.ihk-delete').forEach(btn => {
+        btn.addEventListener('click', () => {
+          if (!confirm('Delete this hook? Requests to its URL will fail.')) return;
+          run(container, btn, () => api('DELETE', `/hooks/${btn.dataset.id}`));
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="ihk-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function run(container, button, fn) {
+    button.disabled = true;
+    try {
+      await fn();
+    } catch (err) {
+      alert(err.message);
+    }
+    button.disabled = false;
+    load(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="ihk-app" data-plugin="${PLUGIN_ID}">
+        <div class="ihk-desc">Each hook has its own URL. Point GitHub, your status page or your monitoring at it, and its events are sent as IRC notices.</div>
+        <div id="ihk-secret"></div>
+        <div id="ihk-editor"></div>
+        <div id="ihk-body">Loading...</div>
+      </div>
+    `;
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('inbound-webhooks-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,150 @@
+package inboundwebhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	Name string `json:"name"`
+	User struct {
+		Modes     string `json:"modes"`
+		Operlogin string `json:"operlogin"`
+	} `json:"user"`
+}
+
+// rpcMember is the subset of a channel member we need
+type rpcMember struct {
+	Name string `json:"name"`
+}
+
+// sendLoop sends queued events until shutdown
+func (p *InboundWebhooksPlugin) sendLoop() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case d := <-p.queue:
+			p.deliver(d)
+			if err := p.save(); err != nil {
+				log.Printf("[inbound-webhooks] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// deliver sends a queued delivery's lines as notices to everyone its hook
+// targets and records the result
+func (p *InboundWebhooksPlugin) deliver(d *Delivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	p.mu.RLock()
+	target, channels, lines := d.Target, d.Channels, d.Lines
+	p.mu.RUnlock()
+
+	rpc := p.client()
+	nicks, lastErr := p.recipients(ctx, target, channels)
+	sent, failed := 0, 0
+	for _, nick := range nicks {
+		ok := true
+		for _, line := range lines {
+			var out json.RawMessage
+			if err := rpc.Call(ctx, "message.send_notice", map[string]interface{}{"nick": nick, "message": line}, &out); err != nil {
+				lastErr = err
+				ok = false
+				break
+			}
+		}
+		if ok {
+			sent++
+		} else {
+			failed++
+		}
+	}
+
+	p.mu.Lock()
+	d.Recipients = sent
+	d.Failed = failed
+	d.State = StateSent
+	d.Error = ""
+	if lastErr != nil {
+		d.Error = lastErr.Error()
+		if sent == 0 {
+			d.State = StateFailed
+		}
+	}
+	if h := p.hookByID(d.HookID); h != nil {
+		if d.State == StateSent {
+			h.Sent++
+		}
+		h.LastError = d.Error
+	}
+	p.dirty = true
+	p.mu.Unlock()
+
+	if lastErr != nil {
+		log.Printf("[inbound-webhooks] %s %s sent to %d users, %d failed: %v", d.Hook, d.Event, sent, failed, lastErr)
+	}
+}
+
+// recipients returns the nicks a target reaches. Global notices go to
+// every user and opers notices to every oper, leaving out services (user
+// mode +S). JSON-RPC cannot speak in a channel, so channel targets reach
+// each member of the channels, once.
+func (p *InboundWebhooksPlugin) recipients(ctx context.Context, target string, channels []string) ([]string, error) {
+	rpc := p.client()
+	nicks := make([]string, 0)
+
+	if target != TargetChannel {
+		var result struct {
+			List []rpcUser `json:"list"`
+		}
+		if err := rpc.Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result); err != nil {
+			return nil, err
+		}
+		for _, u := range result.List {
+			if strings.ContainsRune(u.User.Modes, 'S') || (target == TargetOpers && u.User.Operlogin == "") {
+				continue
+			}
+			nicks = append(nicks, u.Name)
+		}
+		return nicks, nil
+	}
+
+	var lastErr error
+	for _, ch := range channels {
+		var out struct {
+			Channel struct {
+				Members []rpcMember `json:"members"`
+			} `json:"channel"`
+		}
+		params := map[string]interface{}{"channel": ch, "object_detail_level": 4}
+		if err := rpc.Call(ctx, "channel.get", params, &out); err != nil {
+			lastErr = fmt.Errorf("%s: %v", ch, err)
+			continue
+		}
+		for _, m := range out.Channel.Members {
+			if !lists.ContainsFold(nicks, m.Name) {
+				nicks = append(nicks, m.Name)
+			}
+		}
+	}
+	return nicks, lastErr
+}
@@ -0,0 +1,308 @@
+package inboundwebhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Payload formats
+const (
+	FormatGitHub       = "github"
+	FormatStatuspage   = "statuspage"
+	FormatAlertmanager = "alertmanager"
+	FormatGeneric      = "generic"
+)
+
+// formats lists the payload formats, for validation and the page
+var formats = []string{FormatGitHub, FormatStatuspage, FormatAlertmanager, FormatGeneric}
+
+// inboundEvent is what a request is turned into before it is rendered with
+// the hook's template. Data is the payload as received, so templates can
+// use fields the format does not pick out.
+type inboundEvent struct {
+	Event    string
+	Action   string
+	Title    string
+	Message  string
+	URL      string
+	Severity string
+	Data     map[string]interface{}
+}
+
+// name returns the event with its action, as matched by a hook's events
+// list, such as deployment_status.success
+func (ev *inboundEvent) name() string {
+	if ev.Action == "" {
+		return ev.Event
+	}
+	return ev.Event + "." + ev.Action
+}
+
+// parseEvent reads a request body in a hook's format
+func parseEvent(format string, header http.Header, body []byte) (inboundEvent, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		if format != FormatGeneric {
+			return inboundEvent{}, fmt.Errorf("body is not a JSON object")
+		}
+		data = nil
+	}
+
+	switch format {
+	case FormatGitHub:
+		return parseGitHub(header.Get("X-GitHub-Event"), data)
+	case FormatStatuspage:
+		return parseStatuspage(data)
+	case FormatAlertmanager:
+		return parseAlertmanager(data)
+	}
+	return parseGeneric(data, body)
+}
+
+// parseGitHub reads a GitHub webhook delivery. The event name comes from
+// the X-GitHub-Event header.
+func parseGitHub(event string, data map[string]interface{}) (inboundEvent, error) {
+	if event == "" {
+		return inboundEvent{}, fmt.Errorf("X-GitHub-Event header is missing")
+	}
+	repo := str(data, "repository", "full_name")
+	ev := inboundEvent{
+		Event:    event,
+		Action:   str(data, "action"),
+		URL:      str(data, "repository", "html_url"),
+		Severity: notify.SeverityInfo,
+		Data:     data,
+	}
+
+	switch event {
+	case "ping":
+		ev.Title = "Webhook connected for " + firstNonEmpty(repo, str(data, "organization", "login"), "GitHub")
+		ev.Message = str(data, "zen")
+	case "deployment":
+		ev.Action = "created"
+		ev.Title = fmt.Sprintf("Deploying %s %s to %s", repo, str(data, "deployment", "ref"), str(data, "deployment", "environment"))
+		ev.Message = firstNonEmpty(str(data, "deployment", "description"), "by "+str(data, "deployment", "creator", "login"))
+		ev.URL = firstNonEmpty(str(data, "deployment", "payload", "web_url"), ev.URL)
+	case "deployment_status":
+		state := str(data, "deployment_status", "state")
+		ev.Action = state
+		ev.Title = fmt.Sprintf("Deploy of %s %s to %s: %s", repo, str(data, "deployment", "ref"), str(data, "deployment", "environment"), state)
+		ev.Message = str(data, "deployment_status", "description")
+		ev.URL = firstNonEmpty(str(data, "deployment_status", "environment_url"), str(data, "deployment_status", "log_url"), str(data, "deployment_status", "target_url"), ev.URL)
+		if state == "failure" || state == "error" {
+			ev.Severity = notify.SeverityWarning
+		}
+	case "push":
+		commits, _ := data["commits"].([]interface{})
+		ref := strings.TrimPrefix(strings.TrimPrefix(str(data, "ref"), "refs/heads/"), "refs/tags/")
+		ev.Title = fmt.Sprintf("%s pushed %d commit(s) to %s %s", str(data, "pusher", "name"), len(commits), repo, ref)
+		if len(commits) > 0 {
+			if last, ok := commits[len(commits)-1].(map[string]interface{}); ok {
+				ev.Message = firstLine(str(last, "message"))
+			}
+		}
+		ev.URL = firstNonEmpty(str(data, "compare"), ev.URL)
+	case "release":
+		ev.Title = fmt.Sprintf("%s release %s %s", repo, str(data, "release", "tag_name"), ev.Action)
+		ev.Message = str(data, "release", "name")
+		ev.URL = firstNonEmpty(str(data, "release", "html_url"), ev.URL)
+	case "workflow_run":
+		conclusion := str(data, "workflow_run", "conclusion")
+		ev.Title = fmt.Sprintf("%s on %s %s: %s", str(data, "workflow_run", "name"), repo, str(data, "workflow_run", "head_branch"), firstNonEmpty(conclusion, ev.Action))
+		ev.URL = firstNonEmpty(str(data, "workflow_run", "html_url"), ev.URL)
+		if conclusion == "failure" || conclusion == "timed_out" {
+			ev.Severity = notify.SeverityWarning
+		}
+	case "issues", "pull_request":
+		item := "issue"
+		if event == "pull_request" {
+			item = "pull_request"
+		}
+		ev.Title = fmt.Sprintf("%s #%s %s: %s", repo, str(data, item, "number"), ev.Action, str(data, item, "title"))
+		ev.Message = "by " + str(data, "sender", "login")
+		ev.URL = firstNonEmpty(str(data, item, "html_url"), ev.URL)
+	default:
+		ev.Title = strings.TrimSpace(fmt.Sprintf("%s %s on %s", event, ev.Action, firstNonEmpty(repo, "GitHub")))
+		ev.Message = "by " + str(data, "sender", "login")
+	}
+	return ev, nil
+}
+
+// parseStatuspage reads an Atlassian Statuspage notification, which is
+// about either an incident or a component
+func parseStatuspage(data map[string]interface{}) (inboundEvent, error) {
+	ev := inboundEvent{Severity: notify.SeverityInfo, Data: data}
+
+	if incident, ok := data["incident"].(map[string]interface{}); ok {
+		status := str(incident, "status")
+		ev.Event = "incident"
+		ev.Action = status
+		ev.Title = fmt.Sprintf("Incident %s: %s", strings.ReplaceAll(status, "_", " "), str(incident, "name"))
+		if updates, ok := incident["incident_updates"].([]interface{}); ok && len(updates) > 0 {
+			if u, ok := updates[0].(map[string]interface{}); ok {
+				ev.Message = str(u, "body")
+			}
+		}
+		ev.URL = str(incident, "shortlink")
+		// Updates keep the severity of the incident, so a hook that passes
+		// it on also passes on its resolution
+		switch str(incident, "impact") {
+		case "critical", "major":
+			ev.Severity = notify.SeverityCritical
+		case "minor":
+			ev.Severity = notify.SeverityWarning
+		}
+		return ev, nil
+	}
+
+	if update, ok := data["component_update"].(map[string]interface{}); ok {
+		status := str(update, "new_status")
+		ev.Event = "component"
+		ev.Action = status
+		ev.Title = fmt.Sprintf("%s is %s", firstNonEmpty(str(data, "component", "name"), "A component"), strings.ReplaceAll(status, "_", " "))
+		ev.Message = "was " + strings.ReplaceAll(str(update, "old_status"), "_", " ")
+		switch status {
+		case "major_outage":
+			ev.Severity = notify.SeverityCritical
+		case "partial_outage", "degraded_performance":
+			ev.Severity = notify.SeverityWarning
+		}
+		return ev, nil
+	}
+
+	return ev, fmt.Errorf("neither an incident nor a component update")
+}
+
+// parseAlertmanager reads a Prometheus Alertmanager notification, which
+// Grafana alerting sends in the same shape
+func parseAlertmanager(data map[string]interface{}) (inboundEvent, error) {
+	alerts, ok := data["alerts"].([]interface{})
+	if !ok {
+		return inboundEvent{}, fmt.Errorf("alerts is missing")
+	}
+	status := str(data, "status")
+	ev := inboundEvent{
+		Event:  "alert",
+		Action: status,
+		URL:    str(data, "externalURL"),
+		Data:   data,
+	}
+
+	name := str(data, "commonLabels", "alertname")
+	summary := firstNonEmpty(str(data, "commonAnnotations", "summary"), str(data, "commonAnnotations", "description"))
+	if len(alerts) > 0 {
+		if first, ok := alerts[0].(map[string]interface{}); ok {
+			name = firstNonEmpty(name, str(first, "labels", "alertname"))
+			summary = firstNonEmpty(summary, str(first, "annotations", "summary"), str(first, "annotations", "description"))
+			ev.URL = firstNonEmpty(str(first, "generatorURL"), ev.URL)
+		}
+	}
+	ev.Title = fmt.Sprintf("[%s:%d] %s", strings.ToUpper(status), len(alerts), firstNonEmpty(name, "alert"))
+	ev.Message = summary
+	// Resolved alerts keep their severity, like statuspage incidents
+	ev.Severity = severityOf(str(data, "commonLabels", "severity"), notify.SeverityWarning)
+	return ev, nil
+}
+
+// parseGeneric reads a JSON object with common field names, or any other
+// body as plain text
+func parseGeneric(data map[string]interface{}, body []byte) (inboundEvent, error) {
+	if data == nil {
+		text := strings.TrimSpace(string(body))
+		if text == "" {
+			return inboundEvent{}, fmt.Errorf("body is empty")
+		}
+		title, rest := splitTitle(text)
+		return inboundEvent{
+			Event:    "message",
+			Title:    title,
+			Message:  rest,
+			Severity: notify.SeverityInfo,
+		}, nil
+	}
+
+	ev := inboundEvent{
+		Event:    firstNonEmpty(str(data, "event"), str(data, "type"), "message"),
+		Action:   firstNonEmpty(str(data, "action"), str(data, "status"), str(data, "state")),
+		Title:    firstNonEmpty(str(data, "title"), str(data, "summary"), str(data, "subject"), str(data, "name")),
+		Message:  firstNonEmpty(str(data, "message"), str(data, "text"), str(data, "body"), str(data, "description")),
+		URL:      firstNonEmpty(str(data, "url"), str(data, "link")),
+		Severity: severityOf(firstNonEmpty(str(data, "severity"), str(data, "level"), str(data, "priority")), notify.SeverityInfo),
+		Data:     data,
+	}
+	if ev.Title == "" && ev.Message == "" {
+		return ev, fmt.Errorf("title or message is required")
+	}
+	if ev.Title == "" {
+		ev.Title, ev.Message = splitTitle(ev.Message)
+	}
+	return ev, nil
+}
+
+// severityOf maps the severity names monitoring tools use onto ours
+func severityOf(s, def string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "critical", "crit", "fatal", "emergency", "alert", "page", "high", "p1", "error":
+		return notify.SeverityCritical
+	case "warning", "warn", "major", "minor", "medium", "p2", "p3":
+		return notify.SeverityWarning
+	case "info", "information", "notice", "low", "ok", "none", "p4", "p5":
+		return notify.SeverityInfo
+	}
+	return def
+}
+
+// str returns the field at path in a decoded JSON object as a string, or
+// "" when it is missing or not a scalar
+func str(data map[string]interface{}, path ...string) string {
+	var v interface{} = data
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = m[key]
+	}
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	case bool:
+		return fmt.Sprint(s)
+	}
+	return ""
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// splitTitle splits text into its first line, used as the title, and the
+// rest
+func splitTitle(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	first, rest, _ := strings.Cut(text, "\n")
+	return strings.TrimSpace(first), strings.TrimSpace(rest)
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,393 @@
+package inboundwebhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Hook targets
+const (
+	TargetGlobal  = "global"
+	TargetOpers   = "opers"
+	TargetChannel = "channel"
+)
+
+// Limits on hooks
+const (
+	minSecret   = 16
+	maxChannels = 20
+	maxTemplate = 4096
+)
+
+// defaultTemplate is used by hooks without a template of their own
+const defaultTemplate = `[{{.Hook}}] {{.Title}}{{with .Message}} - {{firstline .}}{{end}}{{with .URL}} {{.}}{{end}}`
+
+// Hook is an inbound webhook endpoint. Requests to it are checked against
+// its allowlist and secret, read in its format and rendered with its
+// template into the lines sent to its target.
+type Hook struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Format       string    `json:"format"`
+	Secret       string    `json:"secret,omitempty"`
+	HasSecret    bool      `json:"has_secret"`
+	AllowIPs     []string  `json:"allow_ips"`
+	Events       []string  `json:"events"`
+	MinSeverity  string    `json:"min_severity"`
+	Target       string    `json:"target"`
+	Channels     []string  `json:"channels"`
+	Template     string    `json:"template"`
+	Enabled      bool      `json:"enabled"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	Received     int       `json:"received"`
+	Sent         int       `json:"sent"`
+	Rejected     int       `json:"rejected"`
+	LastReceived time.Time `json:"last_received"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// HookRequest is the body of create and update requests. A secret left
+// out of an update keeps the current one.
+type HookRequest struct {
+	Name        string   `json:"name"`
+	Format      string   `json:"format"`
+	Secret      *string  `json:"secret"`
+	AllowIPs    []string `json:"allow_ips"`
+	Events      []string `json:"events"`
+	MinSeverity string   `json:"min_severity"`
+	Target      string   `json:"target"`
+	Channels    []string `json:"channels"`
+	Template    string   `json:"template"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// apply validates a request and copies it onto the hook
+func (req *HookRequest) apply(h *Hook) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(name) > 64 {
+		return fmt.Errorf("name must be at most 64 characters")
+	}
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	if format == "" {
+		format = FormatGeneric
+	}
+	if !lists.ContainsFold(formats, format) {
+		return fmt.Errorf("format must be one of %s", strings.Join(formats, ", "))
+	}
+	if req.Secret != nil && *req.Secret != "" && len(*req.Secret) < minSecret {
+		return fmt.Errorf("secret must be at least %d characters", minSecret)
+	}
+
+	allow := make([]string, 0, len(req.AllowIPs))
+	for _, entry := range req.AllowIPs {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return fmt.Errorf("allow_ips: %q is not an IP address or CIDR range", entry)
+		}
+		allow = append(allow, entry)
+	}
+	events := make([]string, 0, len(req.Events))
+	for _, e := range req.Events {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	severity := req.MinSeverity
+	if severity == "" {
+		severity = notify.SeverityInfo
+	}
+	if _, ok := notify.SeverityRank[severity]; !ok {
+		return fmt.Errorf("min_severity must be info, warning or critical")
+	}
+
+	target := req.Target
+	if target == "" {
+		target = TargetChannel
+	}
+	channels := make([]string, 0, len(req.Channels))
+	switch target {
+	case TargetGlobal, TargetOpers:
+	case TargetChannel:
+		for _, ch := range req.Channels {
+			if ch = strings.TrimSpace(ch); ch == "" {
+				continue
+			}
+			if !strings.HasPrefix(ch, "#") || strings.ContainsAny(ch, " ,\x07") {
+				return fmt.Errorf("%q is not a channel name", ch)
+			}
+			if !lists.ContainsFold(channels, ch) {
+				channels = append(channels, ch)
+			}
+		}
+		if len(channels) == 0 {
+			return fmt.Errorf("at least one channel is required for target %s", TargetChannel)
+		}
+		if len(channels) > maxChannels {
+			return fmt.Errorf("at most %d channels can be set", maxChannels)
+		}
+	default:
+		return fmt.Errorf("target must be %s, %s or %s", TargetGlobal, TargetOpers, TargetChannel)
+	}
+
+	if len(req.Template) > maxTemplate {
+		return fmt.Errorf("template must be at most %d bytes", maxTemplate)
+	}
+	if _, err := render(req.Template, name, FormatGeneric, sampleEvent); err != nil {
+		return fmt.Errorf("template: %v", err)
+	}
+
+	h.Name = name
+	h.Format = format
+	if req.Secret != nil && *req.Secret != "" {
+		h.Secret = *req.Secret
+	}
+	h.AllowIPs = allow
+	h.Events = events
+	h.MinSeverity = severity
+	h.Target = target
+	h.Channels = channels
+	h.Template = req.Template
+	if req.Enabled != nil {
+		h.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// redacted returns a copy of the hook with its secret hidden
+func (h *Hook) redacted() Hook {
+	c := *h
+	c.HasSecret = c.Secret != ""
+	c.Secret = ""
+	return c
+}
+
+// allowed reports whether a request from ip may use the hook. An empty
+// allowlist allows every address.
+func (h *Hook) allowed(ip string) bool {
+	if len(h.AllowIPs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range h.AllowIPs {
+		if _, ipnet, err := net.ParseCIDR(entry); err == nil {
+			if ipnet.Contains(addr) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate checks a request against the hook's secret. GitHub style
+// signatures, the hex HMAC-SHA256 of the body in X-Hub-Signature-256, are
+// checked when present; otherwise the secret itself must be sent as a
+// bearer token, in X-Webhook-Token or as ?token=.
+func (h *Hook) authenticate(header http.Header, token string, body []byte) error {
+	if h.Secret == "" {
+		return fmt.Errorf("hook has no secret")
+	}
+	if sig := header.Get("X-Hub-Signature-256"); sig != "" {
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(sig), []byte(want)) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+	if h.Format == FormatGitHub {
+		return fmt.Errorf("X-Hub-Signature-256 header is missing")
+	}
+
+	if auth := header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	} else if t := header.Get("X-Webhook-Token"); t != "" {
+		token = t
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.Secret)) != 1 {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+// wants reports whether the hook passes an event on. Events lists event
+// names, with or without their action, or * for everything.
+func (h *Hook) wants(ev inboundEvent) bool {
+	if notify.SeverityRank[ev.Severity] < notify.SeverityRank[h.MinSeverity] {
+		return false
+	}
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == "*" || strings.EqualFold(e, ev.Event) || strings.EqualFold(e, ev.name()) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateData is what a hook template is executed with
+type templateData struct {
+	Hook     string
+	Format   string
+	Event    string
+	Action   string
+	Title    string
+	Message  string
+	URL      string
+	Severity string
+	Data     map[string]interface{}
+}
+
+// templateFuncs are available to hook templates
+var templateFuncs = template.FuncMap{
+	"upper":     strings.ToUpper,
+	"lower":     strings.ToLower,
+	"firstline": firstLine,
+	"truncate": func(n int, s string) string {
+		if utf8.RuneCountInString(s) <= n {
+			return s
+		}
+		return string([]rune(s)[:n]) + "…"
+	},
+	"default": func(def string, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	"bold": func(s string) string {
+		return "\x02" + s + "\x02"
+	},
+	"color": func(code int, s string) string {
+		return fmt.Sprintf("\x03%02d%s\x03", code, s)
+	},
+}
+
+// sampleEvent is rendered to check templates before they are saved, and
+// previewed on the page
+var sampleEvent = inboundEvent{
+	Event:    "deployment_status",
+	Action:   "success",
+	Title:    "Deploy of example/ircd main to production: success",
+	Message:  "Deployed 3 commits",
+	URL:      "https://github.com/example/ircd/deployments",
+	Severity: notify.SeverityInfo,
+	Data: map[string]interface{}{
+		"repository": map[string]interface{}{"full_name": "example/ircd"},
+	},
+}
+
+// render executes a hook's template, or the default one, and returns the
+// lines to send. A template that renders nothing skips the event.
+func render(tmpl, hook, format string, ev inboundEvent) ([]string, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = defaultTemplate
+	}
+	t, err := template.New("hook").Funcs(templateFuncs).Option("missingkey=zero").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, templateData{
+		Hook:     hook,
+		Format:   format,
+		Event:    ev.Event,
+		Action:   ev.Action,
+		Title:    ev.Title,
+		Message:  ev.Message,
+		URL:      ev.URL,
+		Severity: ev.Severity,
+		Data:     ev.Data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line = strings.TrimSpace(sanitize(line)); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out, nil
+}
+
+// sanitize removes control characters other than IRC formatting codes,
+// so a payload cannot smuggle raw protocol into a notice
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\x02', '\x03', '\x0f', '\x11', '\x16', '\x1d', '\x1e', '\x1f':
+			return r
+		case '\t':
+			return ' '
+		}
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// wrap splits lines longer than max bytes at word boundaries
+func wrap(lines []string, max int) []string {
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		cur := ""
+		for _, w := range strings.Fields(line) {
+			// Words longer than a whole line are cut
+			for len(w) > max {
+				if cur != "" {
+					out = append(out, cur)
+					cur = ""
+				}
+				cut := max
+				for cut > 0 && !utf8.RuneStart(w[cut]) {
+					cut--
+				}
+				out = append(out, w[:cut])
+				w = w[cut:]
+			}
+			switch {
+			case cur == "":
+				cur = w
+			case len(cur)+1+len(w) <= max:
+				cur += " " + w
+			default:
+				out = append(out, cur)
+				cur = w
+			}
+		}
+		if cur != "" {
+			out = append(out, cur)
+		}
+	}
+	return out
+}
@@ -0,0 +1,737 @@
+// Inbound Webhooks Plugin for UnrealIRCd Web Panel
+// Turns webhooks from GitHub, status pages and monitoring into global
+// notices or channel notices, with a template and allowlist per hook
+
+package inboundwebhooks
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on request bodies, queued events and history
+const (
+	maxBody       = 1 << 20
+	queueSize     = 100
+	maxHooks      = 100
+	maxDeliveries = 1000
+)
+
+// Delivery states
+const (
+	StateQueued   = "queued"
+	StateSent     = "sent"
+	StateFailed   = "failed"
+	StateIgnored  = "ignored"
+	StateRejected = "rejected"
+	StateDropped  = "dropped"
+)
+
+// InboundWebhooksPlugin implements the Plugin interface
+type InboundWebhooksPlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	hooks      []*Hook
+	deliveries []*Delivery
+	queue      chan *Delivery
+	rate       map[string][]time.Time
+	received   int
+	dirty      bool
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	DataDir       string `json:"data_dir"`
+	MaxLineLength int    `json:"max_line_length"`
+	MaxLines      int    `json:"max_lines"`
+	RatePerMinute int    `json:"rate_per_minute"`
+}
+
+// Delivery is one request to a hook and what became of it
+type Delivery struct {
+	ID         string    `json:"id"`
+	HookID     string    `json:"hook_id"`
+	Hook       string    `json:"hook"`
+	Time       time.Time `json:"time"`
+	RemoteIP   string    `json:"remote_ip"`
+	Event      string    `json:"event,omitempty"`
+	Severity   string    `json:"severity,omitempty"`
+	Target     string    `json:"target,omitempty"`
+	Channels   []string  `json:"channels,omitempty"`
+	Lines      []string  `json:"lines,omitempty"`
+	Recipients int       `json:"recipients"`
+	Failed     int       `json:"failed"`
+	State      string    `json:"state"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// PreviewRequest is the body of preview requests. Payload is a request
+// body as the hook's source would send it; Event is the GitHub event
+// name. Without a payload a sample event is rendered.
+type PreviewRequest struct {
+	Payload  json.RawMessage `json:"payload"`
+	Event    string          `json:"event"`
+	Template *string         `json:"template"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Hooks      []*Hook     `json:"hooks"`
+	Deliveries []*Delivery `json:"deliveries"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &InboundWebhooksPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/inbound-webhooks",
+			MaxLineLength: 400,
+			MaxLines:      4,
+			RatePerMinute: 10,
+		},
+		hooks:      make([]*Hook, 0),
+		deliveries: make([]*Delivery, 0),
+		queue:      make(chan *Delivery, queueSize),
+		rate:       make(map[string][]time.Time),
+	}
+}
+
+// Info returns plugin metadata
+func (p *InboundWebhooksPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Inbound Webhooks",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Turns webhooks from GitHub, status pages and monitoring into IRC notices",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *InboundWebhooksPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[inbound-webhooks] failed to load data: %v", err)
+	}
+	if data.Hooks != nil {
+		p.hooks = data.Hooks
+	}
+	if data.Deliveries != nil {
+		p.deliveries = data.Deliveries
+	}
+	// Events that were waiting when the panel stopped are not sent late
+	for _, d := range p.deliveries {
+		if d.State == StateQueued {
+			d.State = StateFailed
+			d.Error = "not sent: the panel stopped first"
+		}
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "inbound-webhooks-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		sent, failed, rejected := 0, 0, 0
+		for _, d := range p.deliveries {
+			switch {
+			case d.Time.Before(since):
+			case d.State == StateSent:
+				sent++
+			case d.State == StateFailed || d.State == StateDropped:
+				failed++
+			case d.State == StateRejected:
+				rejected++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Inbound Webhooks",
+			Icon:  "Webhook",
+			Content: map[string]interface{}{
+				"hooks":        len(p.hooks),
+				"sent_24h":     sent,
+				"failed_24h":   failed,
+				"rejected_24h": rejected,
+			},
+			Order: 99,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.sendLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *InboundWebhooksPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *InboundWebhooksPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/inbound-webhooks")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/hooks", p.handleListHooks)
+		plugin.POST("/hooks", p.handleCreateHook)
+		plugin.PUT("/hooks/:id", p.handleUpdateHook)
+		plugin.DELETE("/hooks/:id", p.handleDeleteHook)
+		plugin.POST("/hooks/:id/secret", p.handleRotateSecret)
+		plugin.POST("/hooks/:id/preview", p.handlePreview)
+		plugin.GET("/deliveries", p.handleListDeliveries)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+
+		// Called by the services sending the webhooks, which authenticate
+		// with each hook's secret rather than a panel login
+		plugin.POST("/public/hooks/:id", p.handleReceive)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *InboundWebhooksPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "inbound-webhooks.json")
+}
+
+// save persists the state if it changed
+func (p *InboundWebhooksPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Hooks: p.hooks, Deliveries: p.deliveries}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *InboundWebhooksPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// newSecret returns a random hook secret
+func newSecret() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// hookByID returns the hook with the given ID. Caller must hold p.mu.
+func (p *InboundWebhooksPlugin) hookByID(id string) *Hook {
+	for _, h := range p.hooks {
+		if h.ID == id {
+			return h
+		}
+	}
+	return nil
+}
+
+// addDelivery records a delivery, dropping the oldest beyond the limit.
+// Caller must hold p.mu.
+func (p *InboundWebhooksPlugin) addDelivery(d *Delivery) {
+	p.deliveries = append(p.deliveries, d)
+	if len(p.deliveries) > maxDeliveries {
+		p.deliveries = p.deliveries[len(p.deliveries)-maxDeliveries:]
+	}
+	p.dirty = true
+}
+
+// limited reports whether a hook has sent rate_per_minute events in the
+// last minute, and counts this one if not. Caller must hold p.mu.
+func (p *InboundWebhooksPlugin) limited(id string, now time.Time) bool {
+	recent := p.rate[id][:0]
+	for _, t := range p.rate[id] {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	if p.config.RatePerMinute > 0 && len(recent) >= p.config.RatePerMinute {
+		p.rate[id] = recent
+		return true
+	}
+	p.rate[id] = append(recent, now)
+	return false
+}
+
+// reject records a request that was turned away and answers it. Caller
+// must not hold p.mu.
+func (p *InboundWebhooksPlugin) reject(c *gin.Context, h *Hook, d *Delivery, status int, reason string) {
+	p.mu.Lock()
+	d.State = StateRejected
+	d.Error = reason
+	h.Rejected++
+	p.addDelivery(d)
+	p.mu.Unlock()
+
+	log.Printf("[inbound-webhooks] rejected a request to %s from %s: %s", h.Name, d.RemoteIP, reason)
+	c.JSON(status, gin.H{"error": reason})
+}
+
+// handleReceive accepts a webhook: it checks the sender's address and
+// secret, reads the event in the hook's format and queues the lines its
+// template renders. Sending them can take a while on a big network, so
+// the request is answered first.
+func (p *InboundWebhooksPlugin) handleReceive(c *gin.Context) {
+	now := time.Now().UTC()
+
+	p.mu.Lock()
+	p.received++
+	h := p.hookByID(c.Param("id"))
+	if h == nil || !h.Enabled {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hook not found"})
+		return
+	}
+	hook := *h
+	maxLine, maxLines := p.config.MaxLineLength, p.config.MaxLines
+	p.mu.Unlock()
+
+	d := &Delivery{
+		ID:       newID(),
+		HookID:   hook.ID,
+		Hook:     hook.Name,
+		Time:     now,
+		RemoteIP: c.ClientIP(),
+		Target:   hook.Target,
+		Channels: hook.Channels,
+	}
+	if !hook.allowed(d.RemoteIP) {
+		p.reject(c, h, d, http.StatusForbidden, "Source address not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBody+1))
+	if err != nil {
+		p.reject(c, h, d, http.StatusBadRequest, "Failed to read body")
+		return
+	}
+	if len(body) > maxBody {
+		p.reject(c, h, d, http.StatusRequestEntityTooLarge, "Body is larger than 1 MiB")
+		return
+	}
+	if err := hook.authenticate(c.Request.Header, c.Query("token"), body); err != nil {
+		p.reject(c, h, d, http.StatusUnauthorized, "Authentication failed: "+err.Error())
+		return
+	}
+
+	ev, err := parseEvent(hook.Format, c.Request.Header, body)
+	if err != nil {
+		p.reject(c, h, d, http.StatusBadRequest, "Invalid "+hook.Format+" payload: "+err.Error())
+		return
+	}
+	d.Event = ev.name()
+	d.Severity = ev.Severity
+
+	lines, err := render(hook.Template, hook.Name, hook.Format, ev)
+	if err != nil {
+		lines = nil
+		d.Error = "template: " + err.Error()
+	}
+	lines = wrap(lines, maxLine)
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+	d.Lines = lines
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h.Received++
+	h.LastReceived = now
+	p.dirty = true
+
+	switch {
+	case d.Error != "":
+		d.State = StateFailed
+		h.LastError = d.Error
+		p.addDelivery(d)
+		c.JSON(http.StatusOK, gin.H{"message": "Event not sent", "error": d.Error})
+		return
+	case ev.Event == "ping" || !hook.wants(ev) || len(lines) == 0:
+		// Ignored events are answered with success, so the sender does
+		// not retry them or mark the hook as failing
+		d.State = StateIgnored
+		p.addDelivery(d)
+		c.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+		return
+	case p.limited(hook.ID, now):
+		d.State = StateDropped
+		d.Error = "more than rate_per_minute events in a minute"
+		p.addDelivery(d)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many events; try again later"})
+		return
+	}
+
+	d.State = StateQueued
+	select {
+	case p.queue <- d:
+	default:
+		d.State = StateDropped
+		d.Error = "queue is full"
+		p.addDelivery(d)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many events queued; try again later"})
+		return
+	}
+	p.addDelivery(d)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued", "delivery": d.ID})
+}
+
+// handleStatus reports the queue and deliveries by state
+func (p *InboundWebhooksPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	states := map[string]int{StateQueued: 0, StateSent: 0, StateFailed: 0, StateIgnored: 0, StateRejected: 0, StateDropped: 0}
+	for _, d := range p.deliveries {
+		states[d.State]++
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"hooks":      len(p.hooks),
+		"received":   p.received,
+		"queued":     len(p.queue),
+		"deliveries": states,
+		"formats":    formats,
+	})
+}
+
+// handleListHooks returns all hooks, with their secrets hidden
+func (p *InboundWebhooksPlugin) handleListHooks(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Hook, 0, len(p.hooks))
+	for _, h := range p.hooks {
+		list = append(list, h.redacted())
+	}
+	c.JSON(http.StatusOK, gin.H{"hooks": list})
+}
+
+// handleCreateHook adds a hook. Without a secret in the request one is
+// generated; the response is the only time it is shown.
+func (p *InboundWebhooksPlugin) handleCreateHook(c *gin.Context) {
+	var req HookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	h := &Hook{
+		ID:        newID(),
+		Enabled:   true,
+		CreatedBy: access.Actor(c),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := req.apply(h); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.Secret == "" {
+		h.Secret = newSecret()
+	}
+
+	p.mu.Lock()
+	if len(p.hooks) >= maxHooks {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many hooks"})
+		return
+	}
+	p.hooks = append(p.hooks, h)
+	p.dirty = true
+	created := h.redacted()
+	created.Secret = h.Secret
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	log.Printf("[inbound-webhooks] %s created hook %s (%s)", access.Actor(c), h.Name, h.Format)
+	c.JSON(http.StatusCreated, created)
+}
+
+// handleUpdateHook changes a hook
+func (p *InboundWebhooksPlugin) handleUpdateHook(c *gin.Context) {
+	var req HookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	h := p.hookByID(c.Param("id"))
+	if h == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hook not found"})
+		return
+	}
+	updated := *h
+	if err := req.apply(&updated); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	*h = updated
+	p.dirty = true
+	redacted := h.redacted()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, redacted)
+}
+
+// handleDeleteHook removes a hook
+func (p *InboundWebhooksPlugin) handleDeleteHook(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	found := false
+	for i, h := range p.hooks {
+		if h.ID == id {
+			p.hooks = append(p.hooks[:i], p.hooks[i+1:]...)
+			delete(p.rate, id)
+			found = true
+			break
+		}
+	}
+	if found {
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hook not found"})
+		return
+	}
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Hook deleted"})
+}
+
+// handleRotateSecret replaces a hook's secret with a new random one and
+// returns it. Senders using the old secret are rejected from then on.
+func (p *InboundWebhooksPlugin) handleRotateSecret(c *gin.Context) {
+	p.mu.Lock()
+	h := p.hookByID(c.Param("id"))
+	if h == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hook not found"})
+		return
+	}
+	h.Secret = newSecret()
+	secret := h.Secret
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	log.Printf("[inbound-webhooks] %s rotated the secret of hook %s", access.Actor(c), h.Name)
+	c.JSON(http.StatusOK, gin.H{"secret": secret})
+}
+
+// handlePreview renders a payload with a hook's template, or one being
+// edited, without sending anything
+func (p *InboundWebhooksPlugin) handlePreview(c *gin.Context) {
+	var req PreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.RLock()
+	h := p.hookByID(c.Param("id"))
+	var hook Hook
+	if h != nil {
+		hook = *h
+	}
+	maxLine, maxLines := p.config.MaxLineLength, p.config.MaxLines
+	p.mu.RUnlock()
+	if h == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hook not found"})
+		return
+	}
+	if req.Template != nil {
+		hook.Template = *req.Template
+	}
+
+	ev := sampleEvent
+	if len(req.Payload) > 0 {
+		header := http.Header{}
+		header.Set("X-GitHub-Event", req.Event)
+		body := []byte(req.Payload)
+		// A plain text payload arrives as a JSON string
+		var text string
+		if json.Unmarshal(req.Payload, &text) == nil {
+			body = []byte(text)
+		}
+		var err error
+		if ev, err = parseEvent(hook.Format, header, body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + hook.Format + " payload: " + err.Error()})
+			return
+		}
+	}
+
+	lines, err := render(hook.Template, hook.Name, hook.Format, ev)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template: " + err.Error()})
+		return
+	}
+	lines = wrap(lines, maxLine)
+	truncated := len(lines) > maxLines
+	if truncated {
+		lines = lines[:maxLines]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"event":     ev.name(),
+		"severity":  ev.Severity,
+		"wanted":    ev.Event != "ping" && hook.wants(ev),
+		"lines":     lines,
+		"truncated": truncated,
+	})
+}
+
+// handleListDeliveries returns recent deliveries, newest first
+func (p *InboundWebhooksPlugin) handleListDeliveries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > maxDeliveries {
+		limit = 100
+	}
+	hook := c.Query("hook_id")
+	state := c.Query("state")
+
+	p.mu.RLock()
+	list := make([]Delivery, 0, limit)
+	for i := len(p.deliveries) - 1; i >= 0 && len(list) < limit; i-- {
+		d := p.deliveries[i]
+		if (hook != "" && d.HookID != hook) || (state != "" && d.State != state) {
+			continue
+		}
+		list = append(list, *d)
+	}
+	p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": list})
+}
+
+// handleGetConfig returns the current configuration
+func (p *InboundWebhooksPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *InboundWebhooksPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.MaxLineLength < 100 || newConfig.MaxLineLength > 450 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_line_length must be between 100 and 450"})
+		return
+	}
+	if newConfig.MaxLines < 1 || newConfig.MaxLines > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_lines must be between 1 and 20"})
+		return
+	}
+	if newConfig.RatePerMinute < 0 || newConfig.RatePerMinute > 600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate_per_minute must be between 0 and 600"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *InboundWebhooksPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *InboundWebhooksPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
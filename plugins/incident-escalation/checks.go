@@ -0,0 +1,114 @@
+package incidentescalation
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// rpcServer is the part of a server.list entry we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined bool `json:"ulined"`
+	} `json:"server"`
+}
+
+// CertStatus is the result of the last check of one TLS port
+type CertStatus struct {
+	NotAfter  time.Time `json:"not_after"`
+	Subject   string    `json:"subject,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// checkCert connects to a TLS port and returns its certificate. The
+// certificate is not verified, so expired and self-signed ones can still
+// be inspected.
+func checkCert(ctx context.Context, target string, timeout time.Duration) CertStatus {
+	status := CertStatus{CheckedAt: time.Now().UTC()}
+	if err := grants.Allow(grants.Outbound); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		status.Error = "no certificate presented"
+		return status
+	}
+	status.NotAfter = certs[0].NotAfter.UTC()
+	status.Subject = certs[0].Subject.CommonName
+	return status
+}
+
+// certEvent reports an expired certificate
+func certEvent(target string, cs CertStatus) notify.Event {
+	return notify.Event{
+		Source:    "incident-escalation",
+		Type:      EventCertExpired,
+		Severity:  notify.SeverityCritical,
+		Title:     "TLS certificate expired",
+		Message:   fmt.Sprintf("The certificate on %s expired on %s", target, cs.NotAfter.Format("2006-01-02 15:04 MST")),
+		Timestamp: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"target":    target,
+			"subject":   cs.Subject,
+			"not_after": cs.NotAfter,
+		},
+	}
+}
+
+// rpcDownEvent reports that the IRCd has stopped answering the panel
+func rpcDownEvent(failures int, err error, since time.Time) notify.Event {
+	return notify.Event{
+		Source:    "incident-escalation",
+		Type:      EventRPCDown,
+		Severity:  notify.SeverityCritical,
+		Title:     "Panel cannot reach the IRCd",
+		Message:   fmt.Sprintf("JSON-RPC has failed %d times in a row: %v", failures, err),
+		Timestamp: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"failures": failures,
+			"error":    err.Error(),
+			"since":    since,
+		},
+	}
+}
+
+// delinkedEvent reports that the panel's server has lost all its links
+func delinkedEvent(server string, lost []string) notify.Event {
+	return notify.Event{
+		Source:    "incident-escalation",
+		Type:      EventServersDelinked,
+		Severity:  notify.SeverityCritical,
+		Title:     "All servers delinked",
+		Message:   fmt.Sprintf("%s has no links left; %d server(s) are gone", server, len(lost)),
+		Timestamp: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"server": server,
+			"lost":   lost,
+		},
+	}
+}
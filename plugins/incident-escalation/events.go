@@ -0,0 +1,48 @@
+package incidentescalation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Built-in event types
+const (
+	EventRPCDown         = "rpc_down"
+	EventServersDelinked = "servers_delinked"
+	EventCertExpired     = "cert_expired"
+	EventTest            = "test"
+)
+
+// recoverySuffixes mark an event that ends the incident of the event type
+// before the suffix, such as netsplit_healed for netsplit
+var recoverySuffixes = []string{"_healed", "_resolved", "_recovered"}
+
+// keyFields are data fields that tell incidents of one type apart, in
+// order of preference
+var keyFields = []string{"incident_id", "target", "server"}
+
+// recovery returns the event type an event recovers from, if it is a
+// recovery event
+func recovery(ev notify.Event) (string, bool) {
+	for _, suffix := range recoverySuffixes {
+		if base := strings.TrimSuffix(ev.Type, suffix); base != ev.Type && base != "" {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// dedupKey identifies the incident of an event of the given type. A
+// recovery event passes the type it recovers from, so it finds the same
+// incident as the event that opened it.
+func dedupKey(ev notify.Event, kind string) string {
+	key := ev.Source + ":" + kind
+	for _, field := range keyFields {
+		if v, ok := ev.Data[field]; ok && v != nil && fmt.Sprint(v) != "" {
+			return key + ":" + fmt.Sprint(v)
+		}
+	}
+	return key
+}
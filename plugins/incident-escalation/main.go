@@ -0,0 +1,885 @@
+// Incident Escalation Plugin for UnrealIRCd Web Panel
+// Pages the on-call admin through PagerDuty or Opsgenie when the network
+// is in serious trouble, and resolves the page when it recovers
+
+package incidentescalation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on queued actions, delivery attempts and history
+const (
+	queueSize    = 100
+	maxAttempts  = 5
+	maxResolved  = 200
+	maxKeyLength = 255
+)
+
+// Incident states
+const (
+	StateOpen     = "open"
+	StateResolved = "resolved"
+)
+
+// IncidentEscalationPlugin implements the Plugin interface
+type IncidentEscalationPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	incidents    []*Incident
+	open         map[string]*Incident
+	queue        chan action
+	queued       map[string]bool
+	rpcFailures  int
+	rpcDownSince time.Time
+	rpcErr       string
+	servers      []string
+	lastCheck    time.Time
+	certs        map[string]CertStatus
+	lastCerts    time.Time
+	sent         int
+	failed       int
+	dirty        bool
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	Provider       string `json:"provider"`
+	RoutingKey     string `json:"pagerduty_routing_key"`
+	OpsgenieAPIKey string `json:"opsgenie_api_key"`
+	OpsgenieRegion string `json:"opsgenie_region"`
+	CheckInterval  int    `json:"check_interval"`
+	RPCFailures    int    `json:"rpc_failures"`
+	WatchLinks     bool   `json:"watch_links"`
+	CertTargets    string `json:"cert_targets"`
+	CertInterval   int    `json:"cert_interval"`
+	EventsToken    string `json:"events_token"`
+	MinSeverity    string `json:"min_severity"`
+	AutoResolve    bool   `json:"auto_resolve"`
+}
+
+// Incident is a problem that has been escalated to the provider. Key is
+// the deduplication key, so repeats of an open incident do not page
+// again.
+type Incident struct {
+	ID              string                 `json:"id"`
+	Key             string                 `json:"key"`
+	Source          string                 `json:"source"`
+	Type            string                 `json:"type"`
+	Severity        string                 `json:"severity"`
+	Summary         string                 `json:"summary"`
+	Message         string                 `json:"message,omitempty"`
+	Details         map[string]interface{} `json:"details,omitempty"`
+	State           string                 `json:"state"`
+	Count           int                    `json:"count"`
+	TriggeredAt     time.Time              `json:"triggered_at"`
+	LastSeen        time.Time              `json:"last_seen"`
+	ResolvedAt      *time.Time             `json:"resolved_at,omitempty"`
+	ResolvedBy      string                 `json:"resolved_by,omitempty"`
+	Recovery        string                 `json:"recovery,omitempty"`
+	Notified        bool                   `json:"notified"`
+	ResolveNotified bool                   `json:"resolve_notified"`
+	Error           string                 `json:"error,omitempty"`
+}
+
+// action is a trigger or resolve waiting to be sent to the provider
+type action struct {
+	ID      string
+	Resolve bool
+}
+
+// storeData is the on-disk format
+type storeData struct {
+	Incidents []*Incident `json:"incidents"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &IncidentEscalationPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/incident-escalation",
+			Provider:       ProviderPagerDuty,
+			OpsgenieRegion: "us",
+			CheckInterval:  60,
+			RPCFailures:    3,
+			WatchLinks:     true,
+			CertTargets:    "127.0.0.1:6697",
+			CertInterval:   60,
+			MinSeverity:    notify.SeverityCritical,
+			AutoResolve:    true,
+		},
+		incidents: make([]*Incident, 0),
+		open:      make(map[string]*Incident),
+		queue:     make(chan action, queueSize),
+		queued:    make(map[string]bool),
+		certs:     make(map[string]CertStatus),
+	}
+}
+
+// Info returns plugin metadata
+func (p *IncidentEscalationPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Incident Escalation",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Escalates critical network problems to PagerDuty or Opsgenie and resolves them on recovery",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *IncidentEscalationPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[incident-escalation] failed to load data: %v", err)
+	}
+	if data.Incidents != nil {
+		p.incidents = data.Incidents
+	}
+	for _, inc := range p.incidents {
+		if inc.State == StateOpen {
+			p.open[inc.Key] = inc
+		}
+	}
+	// Triggers and resolves that had not been sent when the panel stopped
+	p.requeue()
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "incident-escalation-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"open":    len(p.open),
+			"pending": len(p.queue),
+			"failed":  p.failed,
+		}
+		if p.pagerFor() == nil {
+			content["status"] = "Not configured"
+		}
+		return plugins.DashboardCard{
+			Title:   "Incident Escalation",
+			Icon:    "Siren",
+			Content: content,
+			Order:   67,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.checkLoop()
+	go p.sendLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *IncidentEscalationPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *IncidentEscalationPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/incident-escalation")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/incidents", p.handleListIncidents)
+		plugin.POST("/incidents/:id/resolve", p.handleResolve)
+		plugin.POST("/test", p.handleTest)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file incidents are kept in
+func (p *IncidentEscalationPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "incident-escalation.json")
+}
+
+// save writes incidents to disk if they changed
+func (p *IncidentEscalationPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Incidents: p.incidents}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *IncidentEscalationPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pagerFor returns the configured provider, or nil when its key is not
+// set. Caller must hold p.mu.
+func (p *IncidentEscalationPlugin) pagerFor() pager {
+	switch p.config.Provider {
+	case ProviderPagerDuty:
+		if p.config.RoutingKey != "" {
+			return &pagerDuty{routingKey: p.config.RoutingKey}
+		}
+	case ProviderOpsgenie:
+		if p.config.OpsgenieAPIKey != "" {
+			target := opsgenieURL
+			if p.config.OpsgenieRegion == "eu" {
+				target = opsgenieEUURL
+			}
+			return &opsgenie{apiKey: p.config.OpsgenieAPIKey, url: target}
+		}
+	}
+	return nil
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// errString returns the message of err, or "" for nil
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// keyFor turns a dedup key into one the providers accept. PagerDuty takes
+// at most 255 characters, so longer keys are hashed.
+func keyFor(key string) string {
+	if len(key) <= maxKeyLength {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// incidentByID returns the incident with the given ID. Caller must hold
+// p.mu.
+func (p *IncidentEscalationPlugin) incidentByID(id string) *Incident {
+	for _, inc := range p.incidents {
+		if inc.ID == id {
+			return inc
+		}
+	}
+	return nil
+}
+
+// raise opens an incident for an event, or counts a repeat of one that is
+// already open. Caller must hold p.mu.
+func (p *IncidentEscalationPlugin) raise(ev notify.Event, key string) {
+	key = keyFor(key)
+	now := time.Now().UTC()
+	if inc, ok := p.open[key]; ok {
+		inc.Count++
+		inc.LastSeen = now
+		p.dirty = true
+		return
+	}
+
+	summary := ev.Title
+	if ev.Message != "" && ev.Message != ev.Title {
+		summary += ": " + ev.Message
+	}
+	inc := &Incident{
+		ID:          newID(),
+		Key:         key,
+		Source:      ev.Source,
+		Type:        ev.Type,
+		Severity:    ev.Severity,
+		Summary:     summary,
+		Message:     ev.Message,
+		Details:     ev.Data,
+		State:       StateOpen,
+		Count:       1,
+		TriggeredAt: ev.Timestamp,
+		LastSeen:    now,
+	}
+	p.open[key] = inc
+	p.incidents = append(p.incidents, inc)
+	p.prune()
+	p.dirty = true
+	log.Printf("[incident-escalation] incident %s opened: %s", key, summary)
+	p.enqueue(inc, false)
+}
+
+// recoverKey resolves the open incident with the given key, if any. Caller
+// must hold p.mu.
+func (p *IncidentEscalationPlugin) recoverKey(key, recovery string) {
+	if inc, ok := p.open[keyFor(key)]; ok {
+		p.resolve(inc, recovery, "auto", p.config.AutoResolve)
+	}
+}
+
+// resolve closes an incident and, if notify is set, resolves it with the
+// provider too. Caller must hold p.mu.
+func (p *IncidentEscalationPlugin) resolve(inc *Incident, recovery, by string, notify bool) {
+	now := time.Now().UTC()
+	inc.State = StateResolved
+	inc.ResolvedAt = &now
+	inc.ResolvedBy = by
+	inc.Recovery = recovery
+	// A resolve that is not sent counts as done, so it is not retried
+	// after a restart
+	inc.ResolveNotified = !notify
+	delete(p.open, inc.Key)
+	p.dirty = true
+	log.Printf("[incident-escalation] incident %s resolved by %s: %s", inc.Key, by, recovery)
+	if notify {
+		p.enqueue(inc, true)
+	}
+}
+
+// prune drops the oldest resolved incidents beyond maxResolved. Caller
+// must hold p.mu.
+func (p *IncidentEscalationPlugin) prune() {
+	resolved := len(p.incidents) - len(p.open)
+	if resolved <= maxResolved {
+		return
+	}
+	kept := p.incidents[:0]
+	for _, inc := range p.incidents {
+		if inc.State == StateResolved && resolved > maxResolved {
+			resolved--
+			continue
+		}
+		kept = append(kept, inc)
+	}
+	p.incidents = kept
+}
+
+// enqueue queues a trigger or resolve for the send loop. Caller must hold
+// p.mu.
+func (p *IncidentEscalationPlugin) enqueue(inc *Incident, resolve bool) {
+	a := action{ID: inc.ID, Resolve: resolve}
+	tag := fmt.Sprintf("%s:%t", a.ID, a.Resolve)
+	if p.queued[tag] {
+		return
+	}
+	select {
+	case p.queue <- a:
+		p.queued[tag] = true
+	default:
+		inc.Error = "send queue is full"
+		log.Printf("[incident-escalation] send queue full, incident %s not sent", inc.Key)
+	}
+}
+
+// requeue queues every trigger and resolve the provider has not accepted
+// yet. Caller must hold p.mu.
+func (p *IncidentEscalationPlugin) requeue() {
+	for _, inc := range p.incidents {
+		if !inc.Notified && inc.State == StateOpen {
+			p.enqueue(inc, false)
+		}
+		if inc.Notified && inc.State == StateResolved && !inc.ResolveNotified {
+			p.enqueue(inc, true)
+		}
+	}
+}
+
+// sendLoop sends queued triggers and resolves in order until shutdown.
+// One loop keeps a resolve from overtaking the trigger it belongs to.
+func (p *IncidentEscalationPlugin) sendLoop() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case a := <-p.queue:
+			p.deliver(a)
+		}
+	}
+}
+
+// deliver sends one action, retrying while the provider asks for it
+func (p *IncidentEscalationPlugin) deliver(a action) {
+	p.mu.Lock()
+	delete(p.queued, fmt.Sprintf("%s:%t", a.ID, a.Resolve))
+	found := p.incidentByID(a.ID)
+	if found == nil || (a.Resolve && found.ResolveNotified) || (!a.Resolve && found.Notified) {
+		p.mu.Unlock()
+		return
+	}
+	// A resolve is only sent for an incident the provider knows about; an
+	// incident that recovers before its trigger went out is never paged
+	if a.Resolve && !found.Notified {
+		found.ResolveNotified = true
+		p.dirty = true
+		p.mu.Unlock()
+		return
+	}
+	pg := p.pagerFor()
+	inc := *found
+	p.mu.Unlock()
+
+	if pg == nil {
+		p.recordResult(a, errors.New("no provider is configured"))
+		return
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if a.Resolve {
+			err = pg.resolve(ctx, &inc)
+		} else {
+			err = pg.trigger(ctx, &inc)
+		}
+		cancel()
+
+		var pe *providerError
+		if err == nil || !errors.As(err, &pe) || !pe.Retry || attempt == maxAttempts {
+			break
+		}
+		wait := time.Duration(5<<uint(attempt-1)) * time.Second
+		if pe.RetryAfter > wait {
+			wait = pe.RetryAfter
+		}
+		log.Printf("[incident-escalation] sending %s failed, retrying in %s: %v", inc.Key, wait, err)
+		select {
+		case <-p.stop:
+			// Sent again after the next start
+			p.recordResult(a, errors.New("panel stopped before the provider answered"))
+			return
+		case <-time.After(wait):
+		}
+	}
+	p.recordResult(a, err)
+}
+
+// recordResult stores the outcome of sending an action
+func (p *IncidentEscalationPlugin) recordResult(a action, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	inc := p.incidentByID(a.ID)
+	if inc == nil {
+		return
+	}
+	inc.Error = errString(err)
+	p.dirty = true
+	if err != nil {
+		p.failed++
+		log.Printf("[incident-escalation] sending %s failed: %v", inc.Key, err)
+		return
+	}
+	p.sent++
+	if a.Resolve {
+		inc.ResolveNotified = true
+	} else {
+		inc.Notified = true
+	}
+}
+
+// checkLoop runs the built-in checks until shutdown
+func (p *IncidentEscalationPlugin) checkLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.checkRPC()
+		p.checkCerts()
+		if err := p.save(); err != nil {
+			log.Printf("[incident-escalation] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.CheckInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// checkRPC calls server.list. The IRCd counts as down after rpc_failures
+// failed calls in a row, and the panel's server as delinked when no other
+// server is left in the list.
+func (p *IncidentEscalationPlugin) checkRPC() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcServer `json:"list"`
+	}
+	err := p.client().Call(ctx, "server.list", nil, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastCheck = time.Now().UTC()
+
+	if err != nil {
+		if p.rpcFailures == 0 {
+			p.rpcDownSince = p.lastCheck
+		}
+		p.rpcFailures++
+		p.rpcErr = err.Error()
+		if p.rpcFailures >= p.config.RPCFailures {
+			p.raise(rpcDownEvent(p.rpcFailures, err, p.rpcDownSince), "incident-escalation:"+EventRPCDown)
+		}
+		return
+	}
+	p.rpcFailures = 0
+	p.rpcErr = ""
+	p.recoverKey("incident-escalation:"+EventRPCDown, "JSON-RPC is answering again")
+
+	// Services are U-lined and do not count as links
+	servers := make([]string, 0, len(result.List))
+	for _, rs := range result.List {
+		if !rs.Server.ULined {
+			servers = append(servers, rs.Name)
+		}
+	}
+	sort.Strings(servers)
+
+	key := "incident-escalation:" + EventServersDelinked
+	switch {
+	case !p.config.WatchLinks:
+		p.recoverKey(key, "Link monitoring turned off")
+	case len(servers) == 1 && len(p.servers) > 1:
+		lost := make([]string, 0, len(p.servers))
+		for _, name := range p.servers {
+			if name != servers[0] {
+				lost = append(lost, name)
+			}
+		}
+		p.raise(delinkedEvent(servers[0], lost), key)
+	case len(servers) > 1:
+		p.recoverKey(key, fmt.Sprintf("%d servers linked again", len(servers)))
+	}
+	// Only a linked network is remembered, so the lost servers stay known
+	// while the incident is open
+	if len(servers) > 1 {
+		p.servers = servers
+	}
+}
+
+// checkCerts checks the certificates of cert_targets every cert_interval
+// minutes
+func (p *IncidentEscalationPlugin) checkCerts() {
+	p.mu.RLock()
+	due := time.Since(p.lastCerts) >= time.Duration(p.config.CertInterval)*time.Minute
+	targets := lists.Split(p.config.CertTargets)
+	p.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	results := make(map[string]CertStatus, len(targets))
+	for _, target := range targets {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		results[target] = checkCert(ctx, target, 10*time.Second)
+		cancel()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastCerts = time.Now()
+	p.certs = results
+	for target, cs := range results {
+		key := "incident-escalation:" + EventCertExpired + ":" + target
+		switch {
+		case cs.Error != "":
+			// An unreachable port says nothing about the certificate
+		case time.Now().After(cs.NotAfter):
+			p.raise(certEvent(target, cs), key)
+		default:
+			p.recoverKey(key, "Certificate renewed, valid until "+cs.NotAfter.Format("2006-01-02"))
+		}
+	}
+	// Targets removed from the list can no longer recover on their own
+	for key, inc := range p.open {
+		if inc.Source == "incident-escalation" && inc.Type == EventCertExpired {
+			if _, ok := results[strings.TrimPrefix(key, "incident-escalation:"+EventCertExpired+":")]; !ok {
+				p.resolve(inc, "No longer checked", "auto", p.config.AutoResolve)
+			}
+		}
+	}
+}
+
+// handleStatus returns the provider, the checks and the open incidents
+func (p *IncidentEscalationPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	open := make([]*Incident, 0, len(p.open))
+	for _, inc := range p.open {
+		open = append(open, inc)
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].TriggeredAt.After(open[j].TriggeredAt) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"provider":     p.config.Provider,
+		"configured":   p.pagerFor() != nil,
+		"rpc_ok":       p.rpcFailures == 0 && !p.lastCheck.IsZero(),
+		"rpc_error":    p.rpcErr,
+		"rpc_failures": p.rpcFailures,
+		"servers":      p.servers,
+		"last_check":   p.lastCheck,
+		"certs":        p.certs,
+		"open":         open,
+		"pending":      len(p.queue),
+		"sent":         p.sent,
+		"failed":       p.failed,
+	})
+}
+
+// handleListIncidents returns incidents, newest first
+func (p *IncidentEscalationPlugin) handleListIncidents(c *gin.Context) {
+	state := c.Query("state")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Incident, 0, len(p.incidents))
+	for i := len(p.incidents) - 1; i >= 0; i-- {
+		if state == "" || p.incidents[i].State == state {
+			list = append(list, p.incidents[i])
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"incidents": list, "total": len(list)})
+}
+
+// handleResolve resolves an open incident by hand, with the provider too
+func (p *IncidentEscalationPlugin) handleResolve(c *gin.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	inc := p.incidentByID(c.Param("id"))
+	if inc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	if inc.State != StateOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": "Incident is already resolved"})
+		return
+	}
+	user := access.Actor(c)
+	p.resolve(inc, "Resolved by "+user+" in the panel", user, true)
+	c.JSON(http.StatusOK, gin.H{"message": "Incident resolved"})
+}
+
+// handleTest triggers a test incident and resolves it straight away, so
+// both directions are checked without leaving an alert open
+func (p *IncidentEscalationPlugin) handleTest(c *gin.Context) {
+	p.mu.RLock()
+	pg := p.pagerFor()
+	p.mu.RUnlock()
+	if pg == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No provider is configured"})
+		return
+	}
+
+	inc := &Incident{
+		Key:         "incident-escalation:" + EventTest + ":" + newID(),
+		Source:      "incident-escalation",
+		Type:        EventTest,
+		Severity:    notify.SeverityInfo,
+		Summary:     "Test incident from the UnrealIRCd Web Panel",
+		Message:     "Sent by " + access.Actor(c) + " to check the escalation settings. It resolves itself straight away.",
+		TriggeredAt: time.Now().UTC(),
+		Recovery:    "Test finished",
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	if err := pg.trigger(ctx, inc); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Trigger failed: " + err.Error()})
+		return
+	}
+	if err := pg.resolve(ctx, inc); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Triggered, but resolve failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test incident triggered and resolved"})
+}
+
+// handleEvents accepts alerts from other plugins. Events at or above
+// min_severity open an incident, and recovery events resolve one.
+func (p *IncidentEscalationPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+	if ev.Source == "" {
+		ev.Source = "external"
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if base, ok := recovery(ev); ok {
+		recovery := ev.Message
+		if recovery == "" {
+			recovery = ev.Title
+		}
+		p.recoverKey(dedupKey(ev, base), recovery)
+		c.JSON(http.StatusAccepted, gin.H{"message": "Recovery recorded"})
+		return
+	}
+	if notify.SeverityRank[ev.Severity] < notify.SeverityRank[p.config.MinSeverity] {
+		c.JSON(http.StatusAccepted, gin.H{"message": "Below minimum severity"})
+		return
+	}
+	p.raise(ev, dedupKey(ev, ev.Type))
+	c.JSON(http.StatusAccepted, gin.H{"message": "Incident raised"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *IncidentEscalationPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.RoutingKey = ""
+	cfg.OpsgenieAPIKey = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *IncidentEscalationPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.Provider != ProviderPagerDuty && newConfig.Provider != ProviderOpsgenie {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider must be pagerduty or opsgenie"})
+		return
+	}
+	if newConfig.OpsgenieRegion != "us" && newConfig.OpsgenieRegion != "eu" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "opsgenie_region must be us or eu"})
+		return
+	}
+	if newConfig.CheckInterval < 10 || newConfig.CheckInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check_interval must be between 10 and 3600 seconds"})
+		return
+	}
+	if newConfig.RPCFailures < 1 || newConfig.RPCFailures > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rpc_failures must be between 1 and 20"})
+		return
+	}
+	if newConfig.CertInterval < 5 || newConfig.CertInterval > 1440 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cert_interval must be between 5 and 1440 minutes"})
+		return
+	}
+	if _, ok := notify.SeverityRank[newConfig.MinSeverity]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_severity must be info, warning or critical"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.RoutingKey == "" {
+		newConfig.RoutingKey = p.config.RoutingKey
+	}
+	if newConfig.OpsgenieAPIKey == "" {
+		newConfig.OpsgenieAPIKey = p.config.OpsgenieAPIKey
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	// Certificates are checked again with the new targets
+	p.lastCerts = time.Time{}
+	// Incidents that could not be sent before get another go
+	p.requeue()
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *IncidentEscalationPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *IncidentEscalationPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,199 @@
+package incidentescalation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Providers
+const (
+	ProviderPagerDuty = "pagerduty"
+	ProviderOpsgenie  = "opsgenie"
+)
+
+// Provider endpoints
+const (
+	pagerDutyURL   = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieURL    = "https://api.opsgenie.com/v2/alerts"
+	opsgenieEUURL  = "https://api.eu.opsgenie.com/v2/alerts"
+	providerSource = "UnrealIRCd Web Panel"
+)
+
+// pager raises and resolves incidents with an on-call provider
+type pager interface {
+	trigger(ctx context.Context, inc *Incident) error
+	resolve(ctx context.Context, inc *Incident) error
+}
+
+// providerError is a failed request to a provider. Retry is set when
+// trying again later may succeed.
+type providerError struct {
+	Status     int
+	Message    string
+	Retry      bool
+	RetryAfter time.Duration
+}
+
+func (e *providerError) Error() string {
+	if e.Status == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("provider returned %d: %s", e.Status, e.Message)
+}
+
+var httpClient = &http.Client{Timeout: 20 * time.Second}
+
+// post sends a JSON request and turns anything but a 2xx answer into a
+// providerError
+func post(ctx context.Context, target string, headers map[string]string, body interface{}) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return &providerError{Message: err.Error(), Retry: true}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	pe := &providerError{Status: resp.StatusCode, Message: string(bytes.TrimSpace(msg))}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		pe.Retry = true
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			pe.RetryAfter = time.Duration(secs) * time.Second
+		}
+	}
+	return pe
+}
+
+// pagerDuty sends events to a PagerDuty service through Events API v2
+type pagerDuty struct {
+	routingKey string
+}
+
+// pagerDutySeverities maps severities onto PagerDuty's
+var pagerDutySeverities = map[string]string{
+	notify.SeverityInfo:     "info",
+	notify.SeverityWarning:  "warning",
+	notify.SeverityCritical: "critical",
+}
+
+func (pd *pagerDuty) trigger(ctx context.Context, inc *Incident) error {
+	return post(ctx, pagerDutyURL, nil, map[string]interface{}{
+		"routing_key":  pd.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    inc.Key,
+		"payload": map[string]interface{}{
+			"summary":        truncate(inc.Summary, 1024),
+			"source":         inc.Source,
+			"severity":       pagerDutySeverities[inc.Severity],
+			"timestamp":      inc.TriggeredAt.Format(time.RFC3339),
+			"class":          inc.Type,
+			"group":          providerSource,
+			"custom_details": inc.Details,
+		},
+	})
+}
+
+func (pd *pagerDuty) resolve(ctx context.Context, inc *Incident) error {
+	return post(ctx, pagerDutyURL, nil, map[string]interface{}{
+		"routing_key":  pd.routingKey,
+		"event_action": "resolve",
+		"dedup_key":    inc.Key,
+	})
+}
+
+// opsgenie sends alerts to Opsgenie through its Alert API. The dedup key
+// is used as the alert's alias.
+type opsgenie struct {
+	apiKey string
+	url    string
+}
+
+// opsgeniePriorities maps severities onto Opsgenie priorities
+var opsgeniePriorities = map[string]string{
+	notify.SeverityInfo:     "P5",
+	notify.SeverityWarning:  "P3",
+	notify.SeverityCritical: "P1",
+}
+
+func (og *opsgenie) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + og.apiKey}
+}
+
+func (og *opsgenie) trigger(ctx context.Context, inc *Incident) error {
+	// Opsgenie only takes strings as detail values
+	details := make(map[string]string, len(inc.Details))
+	for k, v := range inc.Details {
+		details[k] = fmt.Sprint(v)
+	}
+	return post(ctx, og.url, og.headers(), map[string]interface{}{
+		"message":     truncate(inc.Summary, 130),
+		"alias":       inc.Key,
+		"description": truncate(inc.Description(), 15000),
+		"priority":    opsgeniePriorities[inc.Severity],
+		"source":      providerSource,
+		"entity":      inc.Source,
+		"tags":        []string{"unrealircd", inc.Type},
+		"details":     details,
+	})
+}
+
+func (og *opsgenie) resolve(ctx context.Context, inc *Incident) error {
+	target := og.url + "/" + url.PathEscape(inc.Key) + "/close?identifierType=alias"
+	return post(ctx, target, og.headers(), map[string]interface{}{
+		"source": providerSource,
+		"note":   "Recovered: " + inc.Recovery,
+	})
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// Description returns the incident's message followed by its details
+func (inc *Incident) Description() string {
+	var b bytes.Buffer
+	b.WriteString(inc.Message)
+	keys := make([]string, 0, len(inc.Details))
+	for k := range inc.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n%s: %v", k, inc.Details[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package influxdbwriter
+
+import (
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// alertPoint turns a plugin alert into a point. Numbers and booleans in
+// the event's data become fields too.
+func alertPoint(measurement string, ev notify.Event) point {
+	pt := point{
+		Measurement: measurement,
+		Tags: map[string]string{
+			"source":   ev.Source,
+			"type":     ev.Type,
+			"severity": ev.Severity,
+		},
+		Fields: map[string]interface{}{
+			"count": 1,
+			"title": ev.Title,
+		},
+		Time: ev.Timestamp,
+	}
+	if ev.Message != "" {
+		pt.Fields["message"] = ev.Message
+	}
+	for k, v := range ev.Data {
+		switch v.(type) {
+		case float64, bool:
+			if _, taken := pt.Fields[k]; !taken {
+				pt.Fields[k] = v
+			}
+		}
+	}
+	return pt
+}
+
+// logPoint turns an IRCd log entry into a point
+func logPoint(measurement string, ev jsonrpc.LogEvent) point {
+	ts, err := time.Parse(time.RFC3339Nano, ev.Timestamp)
+	if err != nil {
+		ts = time.Now().UTC()
+	}
+	return point{
+		Measurement: measurement,
+		Tags: map[string]string{
+			"level":     ev.Level,
+			"subsystem": ev.Subsystem,
+			"event_id":  ev.EventID,
+		},
+		Fields: map[string]interface{}{
+			"count": 1,
+			"msg":   ev.Msg,
+		},
+		Time: ts,
+	}
+}
@@ -0,0 +1,213 @@
+package influxdbwriter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Outputs
+const (
+	OutputInfluxDB = "influxdb"
+	OutputTelegraf = "telegraf"
+)
+
+// maxDatagram is the most line protocol sent in one UDP packet
+const maxDatagram = 8192
+
+// point is one line of InfluxDB line protocol
+type point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]interface{}
+	Time        time.Time
+}
+
+// Escapers for the parts of a line, as the line protocol requires
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `, "\n", `\n`)
+	keyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`)
+	stringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// line encodes a point with extra tags added. Tags and fields are written
+// in sorted order, and empty tag values are left out. A point without
+// fields gives an empty line.
+func (pt point) line(extra map[string]string) string {
+	if len(pt.Fields) == 0 || pt.Measurement == "" {
+		return ""
+	}
+	tags := make(map[string]string, len(pt.Tags)+len(extra))
+	for k, v := range extra {
+		tags[k] = v
+	}
+	for k, v := range pt.Tags {
+		tags[k] = v
+	}
+
+	var b strings.Builder
+	b.WriteString(measurementEscaper.Replace(pt.Measurement))
+	for _, k := range sortedKeys(tags) {
+		if tags[k] == "" {
+			continue
+		}
+		fmt.Fprintf(&b, ",%s=%s", keyEscaper.Replace(k), keyEscaper.Replace(tags[k]))
+	}
+
+	fields := make([]string, 0, len(pt.Fields))
+	for k := range pt.Fields {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	for i, k := range fields {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(keyEscaper.Replace(k))
+		b.WriteByte('=')
+		switch v := pt.Fields[k].(type) {
+		case int:
+			b.WriteString(strconv.Itoa(v) + "i")
+		case int64:
+			b.WriteString(strconv.FormatInt(v, 10) + "i")
+		case float64:
+			b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+		case bool:
+			b.WriteString(strconv.FormatBool(v))
+		default:
+			b.WriteString(`"` + stringEscaper.Replace(fmt.Sprint(v)) + `"`)
+		}
+	}
+
+	fmt.Fprintf(&b, " %d", pt.Time.UnixNano())
+	return b.String()
+}
+
+// sortedKeys returns the keys of a tag set in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseTags reads a tag setting such as "network=example,env=prod"
+func parseTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, item := range lists.Split(s) {
+		k, v, ok := strings.Cut(item, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" || v == "" {
+			return nil, fmt.Errorf("tag %q must be key=value", item)
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+// writeError is a failed write. Retry is set when the same lines may be
+// accepted later.
+type writeError struct {
+	Message string
+	Retry   bool
+}
+
+func (e *writeError) Error() string {
+	return e.Message
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// writeInflux posts lines to the InfluxDB v2 write API
+func writeInflux(ctx context.Context, base, org, bucket, token string, lines []string) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	target := strings.TrimRight(base, "/") + "/api/v2/write?" + url.Values{
+		"org":       {org},
+		"bucket":    {bucket},
+		"precision": {"ns"},
+	}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		return &writeError{Message: err.Error()}
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return &writeError{Message: err.Error(), Retry: true}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &writeError{
+		Message: fmt.Sprintf("InfluxDB returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg)),
+		Retry:   resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+	}
+}
+
+// writeTelegraf sends lines to a Telegraf socket_listener, given as
+// tcp://host:port or udp://host:port. Over UDP, lines are packed into
+// datagrams of at most maxDatagram bytes.
+func writeTelegraf(ctx context.Context, address string, lines []string) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "tcp" && u.Scheme != "udp") || u.Host == "" {
+		return &writeError{Message: fmt.Sprintf("invalid Telegraf address %q", address)}
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, u.Scheme, u.Host)
+	if err != nil {
+		return &writeError{Message: err.Error(), Retry: true}
+	}
+	defer conn.Close()
+	_ = conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+
+	if u.Scheme == "tcp" {
+		if _, err := io.WriteString(conn, strings.Join(lines, "\n")+"\n"); err != nil {
+			return &writeError{Message: err.Error(), Retry: true}
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, l := range lines {
+		if buf.Len() > 0 && buf.Len()+len(l)+1 > maxDatagram {
+			if _, err := conn.Write(buf.Bytes()); err != nil {
+				return &writeError{Message: err.Error(), Retry: true}
+			}
+			buf.Reset()
+		}
+		buf.WriteString(l)
+		buf.WriteByte('\n')
+	}
+	if buf.Len() > 0 {
+		if _, err := conn.Write(buf.Bytes()); err != nil {
+			return &writeError{Message: err.Error(), Retry: true}
+		}
+	}
+	return nil
+}
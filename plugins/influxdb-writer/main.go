@@ -0,0 +1,666 @@
+// InfluxDB Writer Plugin for UnrealIRCd Web Panel
+// Writes network samples, IRCd log events and plugin alerts to InfluxDB v2
+// or a Telegraf socket listener
+
+package influxdbwriter
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// InfluxDBWriterPlugin implements the Plugin interface
+type InfluxDBWriterPlugin struct {
+	config       Config
+	tags         map[string]string
+	rpc          *jsonrpc.Client
+	buffer       []string
+	flushNow     chan struct{}
+	written      int
+	dropped      int
+	lastWrite    time.Time
+	writeErr     string
+	sampleErr    string
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL            string `json:"rpc_url"`
+	RPCUser           string `json:"rpc_user"`
+	RPCPassword       string `json:"rpc_password"`
+	RPCInsecure       bool   `json:"rpc_insecure"`
+	StreamURL         string `json:"stream_url"`
+	LogSources        string `json:"log_sources"`
+	Output            string `json:"output"`
+	InfluxURL         string `json:"influx_url"`
+	InfluxOrg         string `json:"influx_org"`
+	InfluxBucket      string `json:"influx_bucket"`
+	InfluxToken       string `json:"influx_token"`
+	TelegrafAddress   string `json:"telegraf_address"`
+	Tags              string `json:"tags"`
+	SampleInterval    int    `json:"sample_interval"`
+	StatsMeasurement  string `json:"stats_measurement"`
+	ServerMeasurement string `json:"server_measurement"`
+	LogMeasurement    string `json:"log_measurement"`
+	AlertMeasurement  string `json:"alert_measurement"`
+	EventsToken       string `json:"events_token"`
+	BatchSize         int    `json:"batch_size"`
+	FlushInterval     int    `json:"flush_interval"`
+	BufferSize        int    `json:"buffer_size"`
+}
+
+// statsResult is the part of stats.get we write
+type statsResult struct {
+	Server struct {
+		Total  int `json:"total"`
+		ULined int `json:"ulined"`
+	} `json:"server"`
+	User struct {
+		Total  int `json:"total"`
+		ULined int `json:"ulined"`
+		Oper   int `json:"oper"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+	ServerBan struct {
+		Total              int `json:"total"`
+		ServerBan          int `json:"server_ban"`
+		Spamfilter         int `json:"spamfilter"`
+		NameBan            int `json:"name_ban"`
+		ServerBanException int `json:"server_ban_exception"`
+	} `json:"server_ban"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		NumUsers int  `json:"num_users"`
+		Synced   bool `json:"synced"`
+		ULined   bool `json:"ulined"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &InfluxDBWriterPlugin{
+		config: Config{
+			RPCURL:            "https://127.0.0.1:8600/api",
+			StreamURL:         "wss://127.0.0.1:8600/",
+			LogSources:        "all,!debug",
+			Output:            OutputInfluxDB,
+			InfluxURL:         "http://127.0.0.1:8086",
+			InfluxBucket:      "unrealircd",
+			TelegrafAddress:   "udp://127.0.0.1:8094",
+			SampleInterval:    30,
+			StatsMeasurement:  "unrealircd_stats",
+			ServerMeasurement: "unrealircd_server",
+			LogMeasurement:    "unrealircd_log",
+			AlertMeasurement:  "unrealircd_alert",
+			BatchSize:         500,
+			FlushInterval:     10,
+			BufferSize:        10000,
+		},
+		tags:     make(map[string]string),
+		buffer:   make([]string, 0),
+		flushNow: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *InfluxDBWriterPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "InfluxDB Writer",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Writes network samples, log events and alerts to InfluxDB or Telegraf",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *InfluxDBWriterPlugin) Init() error {
+	p.mu.Lock()
+	tags, err := parseTags(p.config.Tags)
+	if err != nil {
+		log.Printf("[influxdb-writer] ignoring tags: %v", err)
+	} else {
+		p.tags = tags
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "influxdb-writer-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"written":  p.written,
+			"buffered": len(p.buffer),
+			"dropped":  p.dropped,
+		}
+		if p.writeErr != "" {
+			content["status"] = "Write failing"
+		}
+		return plugins.DashboardCard{
+			Title:   "InfluxDB Writer",
+			Icon:    "Database",
+			Content: content,
+			Order:   70,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(3)
+	go p.streamLoop()
+	go p.sampleLoop()
+	go p.flushLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin. Points still buffered get one last
+// chance to be written.
+func (p *InfluxDBWriterPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+		p.flush()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *InfluxDBWriterPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/influxdb-writer")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/flush", p.handleFlush)
+		plugin.POST("/test", p.handleTest)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *InfluxDBWriterPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// enqueue encodes points into the buffer. When the buffer is full the
+// oldest lines are dropped, so an unreachable database cannot use up the
+// panel's memory. Caller must hold p.mu.
+func (p *InfluxDBWriterPlugin) enqueue(points ...point) {
+	for _, pt := range points {
+		if l := pt.line(p.tags); l != "" {
+			p.buffer = append(p.buffer, l)
+		}
+	}
+	if over := len(p.buffer) - p.config.BufferSize; over > 0 {
+		p.buffer = append([]string(nil), p.buffer[over:]...)
+		p.dropped += over
+	}
+	if len(p.buffer) >= p.config.BatchSize {
+		select {
+		case p.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// write sends lines to the configured output
+func (p *InfluxDBWriterPlugin) write(ctx context.Context, lines []string) error {
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	if cfg.Output == OutputTelegraf {
+		return writeTelegraf(ctx, cfg.TelegrafAddress, lines)
+	}
+	return writeInflux(ctx, cfg.InfluxURL, cfg.InfluxOrg, cfg.InfluxBucket, cfg.InfluxToken, lines)
+}
+
+// flush writes the buffer in batches. Lines that failed with an error
+// worth retrying stay buffered for the next flush; lines the database
+// rejects are dropped.
+func (p *InfluxDBWriterPlugin) flush() {
+	for {
+		p.mu.Lock()
+		n := len(p.buffer)
+		if n > p.config.BatchSize {
+			n = p.config.BatchSize
+		}
+		batch := append([]string(nil), p.buffer[:n]...)
+		p.mu.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := p.write(ctx, batch)
+		cancel()
+
+		p.mu.Lock()
+		var we *writeError
+		retry := err != nil && errors.As(err, &we) && we.Retry
+		// The buffer may have been trimmed while writing, so only lines
+		// still at its front are removed
+		if !retry {
+			removed := 0
+			for removed < len(batch) && removed < len(p.buffer) && p.buffer[removed] == batch[removed] {
+				removed++
+			}
+			p.buffer = append([]string(nil), p.buffer[removed:]...)
+		}
+		if err != nil {
+			p.writeErr = err.Error()
+			if !retry {
+				p.dropped += len(batch)
+			}
+			p.mu.Unlock()
+			log.Printf("[influxdb-writer] write failed: %v", err)
+			return
+		}
+		p.writeErr = ""
+		p.written += len(batch)
+		p.lastWrite = time.Now()
+		p.mu.Unlock()
+	}
+}
+
+// flushLoop flushes every flush_interval, or sooner when a batch is full
+func (p *InfluxDBWriterPlugin) flushLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.FlushInterval) * time.Second
+		p.mu.RUnlock()
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.flushNow:
+		case <-time.After(interval):
+		}
+		p.flush()
+	}
+}
+
+// sampleLoop writes network samples every sample_interval until shutdown
+func (p *InfluxDBWriterPlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.sample()
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// sample reads the network's numbers and buffers them as points
+func (p *InfluxDBWriterPlugin) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	p.mu.RLock()
+	statsMeasurement := p.config.StatsMeasurement
+	serverMeasurement := p.config.ServerMeasurement
+	p.mu.RUnlock()
+	if statsMeasurement == "" && serverMeasurement == "" {
+		return
+	}
+
+	rpc := p.client()
+	now := time.Now()
+	points := make([]point, 0)
+	var firstErr error
+
+	if statsMeasurement != "" {
+		var stats statsResult
+		start := time.Now()
+		if err := rpc.Call(ctx, "stats.get", nil, &stats); err != nil {
+			firstErr = err
+		} else {
+			points = append(points, point{
+				Measurement: statsMeasurement,
+				Fields: map[string]interface{}{
+					"users":                 stats.User.Total,
+					"users_ulined":          stats.User.ULined,
+					"users_record":          stats.User.Record,
+					"opers":                 stats.User.Oper,
+					"channels":              stats.Channel.Total,
+					"servers":               stats.Server.Total,
+					"servers_ulined":        stats.Server.ULined,
+					"bans":                  stats.ServerBan.Total,
+					"server_bans":           stats.ServerBan.ServerBan,
+					"spamfilters":           stats.ServerBan.Spamfilter,
+					"name_bans":             stats.ServerBan.NameBan,
+					"server_ban_exceptions": stats.ServerBan.ServerBanException,
+					"rpc_latency_ms":        float64(time.Since(start).Microseconds()) / 1000,
+				},
+				Time: now,
+			})
+		}
+	}
+
+	if serverMeasurement != "" {
+		var result struct {
+			List []rpcServer `json:"list"`
+		}
+		if err := rpc.Call(ctx, "server.list", nil, &result); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			for _, s := range result.List {
+				points = append(points, point{
+					Measurement: serverMeasurement,
+					Tags: map[string]string{
+						"server": s.Name,
+						"ulined": boolTag(s.Server.ULined),
+					},
+					Fields: map[string]interface{}{
+						"users":  s.Server.NumUsers,
+						"synced": s.Server.Synced,
+					},
+					Time: now,
+				})
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sampleErr = ""
+	if firstErr != nil {
+		p.sampleErr = firstErr.Error()
+		log.Printf("[influxdb-writer] failed to sample: %v", firstErr)
+	}
+	p.enqueue(points...)
+}
+
+// boolTag returns a boolean as a tag value
+func boolTag(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// streamLoop runs the log stream until shutdown, restarting it when the
+// configuration changes
+func (p *InfluxDBWriterPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		enabled := p.config.LogMeasurement != ""
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		if enabled {
+			stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		} else {
+			// Wait for a configuration change that turns logging on
+			<-ctx.Done()
+		}
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *InfluxDBWriterPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[influxdb-writer] log stream: %v", err)
+	}
+}
+
+// handleEvent buffers an IRCd log entry
+func (p *InfluxDBWriterPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.LogMeasurement != "" {
+		p.enqueue(logPoint(p.config.LogMeasurement, ev))
+	}
+}
+
+// handleStatus returns the output, the buffer and the event sources
+func (p *InfluxDBWriterPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"output":       p.config.Output,
+		"buffered":     len(p.buffer),
+		"written":      p.written,
+		"dropped":      p.dropped,
+		"write_error":  p.writeErr,
+		"sample_error": p.sampleErr,
+		"stream_ok":    p.streamOK,
+	}
+	if !p.lastWrite.IsZero() {
+		status["last_write"] = p.lastWrite
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleFlush writes the buffer now
+func (p *InfluxDBWriterPlugin) handleFlush(c *gin.Context) {
+	p.flush()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.writeErr != "" {
+		c.JSON(http.StatusBadGateway, gin.H{"error": p.writeErr, "buffered": len(p.buffer)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Buffer flushed", "buffered": len(p.buffer)})
+}
+
+// handleTest writes a single test point straight away, bypassing the
+// buffer, and reports the outcome
+func (p *InfluxDBWriterPlugin) handleTest(c *gin.Context) {
+	p.mu.RLock()
+	measurement := p.config.AlertMeasurement
+	if measurement == "" {
+		measurement = p.config.StatsMeasurement
+	}
+	l := alertPoint(measurement, notify.Event{
+		Source:    "influxdb-writer",
+		Type:      "test",
+		Severity:  notify.SeverityInfo,
+		Title:     "Test point",
+		Message:   "Written by the test button in the panel",
+		Timestamp: time.Now().UTC(),
+	}).line(p.tags)
+	p.mu.RUnlock()
+	if l == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No measurement is configured"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	if err := p.write(ctx, []string{l}); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test point written", "line": l})
+}
+
+// handleEvents accepts alerts from other plugins
+func (p *InfluxDBWriterPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.AlertMeasurement == "" {
+		c.JSON(http.StatusAccepted, gin.H{"message": "Alerts are not written"})
+		return
+	}
+	p.enqueue(alertPoint(p.config.AlertMeasurement, ev))
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *InfluxDBWriterPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.InfluxToken = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *InfluxDBWriterPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.Output != OutputInfluxDB && newConfig.Output != OutputTelegraf {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "output must be influxdb or telegraf"})
+		return
+	}
+	if newConfig.Output == OutputInfluxDB && (newConfig.InfluxURL == "" || newConfig.InfluxBucket == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "influx_url and influx_bucket are required"})
+		return
+	}
+	if newConfig.Output == OutputTelegraf && !strings.HasPrefix(newConfig.TelegrafAddress, "tcp://") && !strings.HasPrefix(newConfig.TelegrafAddress, "udp://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "telegraf_address must start with tcp:// or udp://"})
+		return
+	}
+	tags, err := parseTags(newConfig.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if newConfig.SampleInterval < 5 || newConfig.SampleInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sample_interval must be between 5 and 3600 seconds"})
+		return
+	}
+	if newConfig.BatchSize < 1 || newConfig.BatchSize > 5000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "batch_size must be between 1 and 5000"})
+		return
+	}
+	if newConfig.FlushInterval < 1 || newConfig.FlushInterval > 300 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "flush_interval must be between 1 and 300 seconds"})
+		return
+	}
+	if newConfig.BufferSize < newConfig.BatchSize || newConfig.BufferSize > 1000000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "buffer_size must be at least batch_size and at most 1000000"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.InfluxToken == "" {
+		newConfig.InfluxToken = p.config.InfluxToken
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	p.config = newConfig
+	p.tags = tags
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *InfluxDBWriterPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *InfluxDBWriterPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,90 @@
+// Package access answers whether a panel user holds a permission, for
+// plugins that guard their own routes. The permissions are the ones the
+// RBAC Roles plugin assigns. While it is not loaded nobody holds any, so
+// every check fails closed.
+
+package access
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// Permissions a role can grant
+const (
+	ViewStats         = "view_stats"
+	ManageUsers       = "manage_users"
+	ManageBans        = "manage_bans"
+	ManageSpamfilters = "manage_spamfilters"
+	ManagePlugins     = "manage_plugins"
+	ManageRoles       = "manage_roles"
+)
+
+// provider is the only plugin that answers permission checks
+const provider = "rbac-roles"
+
+// Checker reports whether a panel user holds a permission
+type Checker func(username, permission string) bool
+
+var (
+	checkerMu sync.RWMutex
+	checker   Checker
+)
+
+// SetChecker installs the function that answers permission checks, or
+// removes it when fn is nil. Only the RBAC Roles plugin may call it.
+func SetChecker(fn Checker) error {
+	if caller := grants.Caller(); caller != provider {
+		return fmt.Errorf("permission checks are answered by %s, not %q", provider, caller)
+	}
+	checkerMu.Lock()
+	defer checkerMu.Unlock()
+	checker = fn
+	return nil
+}
+
+// Can reports whether a panel user holds a permission. It is false for
+// requests without a user and while RBAC Roles is not loaded.
+func Can(username, permission string) bool {
+	checkerMu.RLock()
+	fn := checker
+	checkerMu.RUnlock()
+	if fn == nil || username == "" {
+		return false
+	}
+	return fn(username, permission)
+}
+
+// IsAdmin reports whether a panel user administers plugins: changes their
+// configuration and acts for other users. That takes manage_plugins.
+func IsAdmin(username string) bool {
+	return Can(username, ManagePlugins)
+}
+
+// Actor returns the panel user making the request, as set by the panel's
+// auth middleware, for logs and audit records
+func Actor(c *gin.Context) string {
+	if name := c.GetString("username"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// RequireAdmin writes an error response unless the panel user making the
+// request administers plugins, and returns the user
+func RequireAdmin(c *gin.Context) (string, bool) {
+	name := c.GetString("username")
+	if name == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+		return "", false
+	}
+	if !IsAdmin(name) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + ManagePlugins})
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,87 @@
+// Package apidocs is where plugins describe the routes of their API. The
+// OpenAPI Docs plugin builds its document from what is described here, but
+// describing routes works whether or not it is installed.
+
+package apidocs
+
+import (
+	"sort"
+	"sync"
+)
+
+// Operation describes a route of a plugin's API. Request and Response are
+// values of the Go types the handler binds and returns, such as Config{}
+// or []Entry{}; their JSON schemas are derived from the types and their
+// json tags. A doc:"..." struct tag adds a description to a field.
+type Operation struct {
+	// Method is the HTTP method, such as GET
+	Method string
+	// Path is the route as given to the plugin's router group, below
+	// /plugin/<id>, such as /servers/:server. Path parameters are
+	// documented from it.
+	Path        string
+	Summary     string
+	Description string
+	// Query lists the query parameters
+	Query []Param
+	// Request is the JSON body, or nil for none
+	Request interface{}
+	// Response is the JSON returned on success, or nil for an object of
+	// unspecified shape
+	Response interface{}
+	// Status is the success status, 200 when zero
+	Status int
+	// ContentType is the type of the success response, application/json
+	// when empty. Other types are documented as a string body.
+	ContentType string
+}
+
+// Param is a query or path parameter
+type Param struct {
+	Name        string
+	Description string
+	// Type is a JSON schema type: string, integer, number or boolean.
+	// Empty means string.
+	Type     string
+	Required bool
+}
+
+// Plugin is what a plugin described with Describe
+type Plugin struct {
+	ID          string
+	Description string
+	Operations  []Operation
+}
+
+// docs holds the described plugins, by ID
+var (
+	docsMu sync.RWMutex
+	docs   = make(map[string]Plugin)
+)
+
+// Describe registers the API routes of a plugin, replacing what it
+// registered before. Plugins call it from Init.
+func Describe(pluginID, description string, ops ...Operation) {
+	docsMu.Lock()
+	defer docsMu.Unlock()
+	docs[pluginID] = Plugin{ID: pluginID, Description: description, Operations: append([]Operation(nil), ops...)}
+}
+
+// Forget removes the routes of a plugin, for its Shutdown
+func Forget(pluginID string) {
+	docsMu.Lock()
+	defer docsMu.Unlock()
+	delete(docs, pluginID)
+}
+
+// Described returns the described plugins sorted by ID
+func Described() []Plugin {
+	docsMu.RLock()
+	defer docsMu.RUnlock()
+	list := make([]Plugin, 0, len(docs))
+	for _, d := range docs {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
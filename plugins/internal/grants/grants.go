@@ -0,0 +1,248 @@
+// Package grants enforces the permissions plugins declare in their
+// manifests. The shared JSON-RPC client, storage and notifier check with it
+// before acting, as do plugins that connect out, listen or run commands
+// themselves.
+//
+// A plugin holds nothing until it registers its plugin.json. It then holds
+// the permissions the manifest declares, less any the admin withdrew in
+// GrantsFile. Callers are identified by the package they run in, so a
+// plugin cannot act under another plugin's grants.
+
+package grants
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Permissions checked outside the JSON-RPC client
+const (
+	Storage  = "storage"
+	Outbound = "network.outbound"
+	Listen   = "network.listen"
+	Exec     = "system.exec"
+)
+
+// GrantsFile is where the admin records the permissions each plugin holds,
+// as a JSON object of plugin IDs and permission lists. A plugin without an
+// entry holds what its manifest declares.
+const GrantsFile = "data/plugins/permissions.json"
+
+// pluginsPath is the import path plugins live under. Packages below
+// sharedPath are the shared code and never hold permissions of their own.
+const (
+	pluginsPath = "github.com/unrealircd/unrealircd-webpanel/internal/plugins/"
+	sharedPath  = pluginsPath + "internal/"
+)
+
+// rpcPermissionsJSON maps JSON-RPC methods to the permission they need,
+// first match wins. scripts/validate-plugins.js reads the same file.
+//
+//go:embed rpc-permissions.json
+var rpcPermissionsJSON []byte
+
+// rpcPermissions is rpcPermissionsJSON compiled
+var rpcPermissions = func() []rpcRule {
+	var rules []struct {
+		Pattern    string `json:"pattern"`
+		Permission string `json:"permission"`
+	}
+	if err := json.Unmarshal(rpcPermissionsJSON, &rules); err != nil {
+		panic("grants: invalid rpc-permissions.json: " + err.Error())
+	}
+	compiled := make([]rpcRule, 0, len(rules))
+	for _, r := range rules {
+		compiled = append(compiled, rpcRule{regexp.MustCompile(r.Pattern), r.Permission})
+	}
+	return compiled
+}()
+
+// rpcRule is one entry of the method table
+type rpcRule struct {
+	pattern *regexp.Regexp
+	perm    string
+}
+
+// declared holds the permissions in each registered plugin's manifest
+var declared struct {
+	sync.RWMutex
+	perms map[string][]string
+}
+
+// grants caches GrantsFile, read again whenever it changes
+var grants struct {
+	sync.Mutex
+	modTime time.Time
+	size    int64
+	entries map[string][]string
+}
+
+// Register records the permissions in a plugin's manifest. Plugins call it
+// from NewPlugin with their plugin.json embedded, and the manifest's id
+// must name the calling plugin's directory. A plugin that has not
+// registered holds no permissions.
+func Register(manifest []byte) {
+	caller := callerPlugin()
+	var m struct {
+		ID          string   `json:"id"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		log.Printf("[grants] %s: invalid manifest: %v", caller, err)
+		return
+	}
+	if caller == "" || m.ID != caller {
+		log.Printf("[grants] manifest for %q registered from %q, ignoring it", m.ID, caller)
+		return
+	}
+
+	declared.Lock()
+	defer declared.Unlock()
+	if declared.perms == nil {
+		declared.perms = make(map[string][]string)
+	}
+	declared.perms[m.ID] = m.Permissions
+}
+
+// Allow returns an error unless the calling plugin holds perm
+func Allow(perm string) error {
+	return Check(callerPlugin(), perm)
+}
+
+// AllowRPC returns an error unless the calling plugin holds the permission
+// a JSON-RPC method needs
+func AllowRPC(method string) error {
+	return Check(callerPlugin(), RPCPermission(method))
+}
+
+// Caller returns the ID of the plugin calling into the shared code, or ""
+// if there is none. Shared clients record it when they are created, so
+// calls made later from their own goroutines are checked against the
+// plugin that created them.
+func Caller() string {
+	return callerPlugin()
+}
+
+// Check returns an error unless the plugin holds perm. rpc.* covers every
+// RPC permission.
+func Check(plugin, perm string) error {
+	if plugin == "" {
+		return fmt.Errorf("permission %s is only granted to plugins", perm)
+	}
+	for _, held := range Held(plugin) {
+		if held == perm || (held == "rpc.*" && strings.HasPrefix(perm, "rpc.")) {
+			return nil
+		}
+	}
+	return fmt.Errorf("permission %s has not been granted to %s", perm, plugin)
+}
+
+// RPCPermission returns the permission a JSON-RPC method needs. Methods
+// outside the table need rpc.*.
+func RPCPermission(method string) string {
+	for _, r := range rpcPermissions {
+		if r.pattern.MatchString(method) {
+			return r.perm
+		}
+	}
+	return "rpc.*"
+}
+
+// Held returns the permissions a plugin holds: those its manifest
+// declares, less any left out of its entry in GrantsFile. A grants file
+// that cannot be read grants nothing, so a broken file does not quietly
+// give everything back.
+func Held(plugin string) []string {
+	declared.RLock()
+	perms, ok := declared.perms[plugin]
+	declared.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	entries, err := loadGrants()
+	if err != nil {
+		log.Printf("[grants] failed to read permission grants: %v", err)
+		return nil
+	}
+	entry, ok := entries[plugin]
+	if !ok {
+		return perms
+	}
+	held := make([]string, 0, len(entry))
+	for _, perm := range entry {
+		if contains(perms, perm) {
+			held = append(held, perm)
+		}
+	}
+	return held
+}
+
+// loadGrants returns the entries of GrantsFile, or nil if there is none
+func loadGrants() (map[string][]string, error) {
+	grants.Lock()
+	defer grants.Unlock()
+
+	fi, err := os.Stat(GrantsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		grants.entries = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if grants.entries != nil && fi.ModTime().Equal(grants.modTime) && fi.Size() == grants.size {
+		return grants.entries, nil
+	}
+
+	var entries map[string][]string
+	data, err := os.ReadFile(GrantsFile)
+	if err == nil {
+		err = json.Unmarshal(data, &entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = make(map[string][]string)
+	}
+	grants.modTime, grants.size, grants.entries = fi.ModTime(), fi.Size(), entries
+	return entries, nil
+}
+
+// callerPlugin returns the ID of the innermost plugin on the calling
+// goroutine's stack, skipping the shared packages
+func callerPlugin() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if rest, ok := strings.CutPrefix(frame.Function, pluginsPath); ok && !strings.HasPrefix(frame.Function, sharedPath) {
+			if i := strings.IndexAny(rest, "./"); i > 0 {
+				return rest[:i]
+			}
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// contains reports whether list holds s exactly
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
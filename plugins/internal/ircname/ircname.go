@@ -0,0 +1,82 @@
+// Package ircname checks nicknames, account names and channel names taken
+// from requests before they are put into commands for the IRCd or
+// services. Services split commands on whitespace, so a name that isn't
+// valid could add arguments of its own. It also matches names against
+// wildcard masks.
+
+package ircname
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Longest names accepted. UnrealIRCd allows nicknames of up to 50
+// characters and channel names of up to 64 bytes.
+const (
+	maxNickLen    = 50
+	maxChannelLen = 64
+)
+
+// ValidNick reports whether s can be a nickname or services account name:
+// letters, digits and -[]\^_`{|}, not starting with a digit or -
+func ValidNick(s string) bool {
+	if s == "" || len([]rune(s)) > maxNickLen {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r), strings.ContainsRune("[]\\^_`{|}", r):
+		case unicode.IsDigit(r), r == '-':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidChannel reports whether s is a channel name: # followed by
+// anything but spaces, commas and control characters
+func ValidChannel(s string) bool {
+	if len(s) < 2 || len(s) > maxChannelLen || s[0] != '#' {
+		return false
+	}
+	for _, r := range s {
+		if r == ',' || unicode.IsSpace(r) || unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchMask reports whether s matches a wildcard mask with * and ?,
+// ignoring case, as IRC masks such as nick!user@host are matched
+func MatchMask(mask, s string) bool {
+	m := []rune(strings.ToLower(mask))
+	t := []rune(strings.ToLower(s))
+	mi, ti := 0, 0
+	star, mark := -1, 0
+	for ti < len(t) {
+		switch {
+		case mi < len(m) && (m[mi] == '?' || m[mi] == t[ti]):
+			mi++
+			ti++
+		case mi < len(m) && m[mi] == '*':
+			star, mark = mi, ti
+			mi++
+		case star >= 0:
+			mi = star + 1
+			mark++
+			ti = mark
+		default:
+			return false
+		}
+	}
+	for mi < len(m) && m[mi] == '*' {
+		mi++
+	}
+	return mi == len(m)
+}
@@ -0,0 +1,153 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// LogEvent is an UnrealIRCd JSON log entry delivered by log.subscribe.
+// Raw holds the full entry so callers can decode the objects they need.
+type LogEvent struct {
+	Timestamp string          `json:"timestamp"`
+	Level     string          `json:"level"`
+	Subsystem string          `json:"subsystem"`
+	EventID   string          `json:"event_id"`
+	Msg       string          `json:"msg"`
+	Raw       json.RawMessage `json:"-"`
+}
+
+// LogStream subscribes to the IRCd log over a JSON-RPC websocket
+type LogStream struct {
+	plugin   string
+	url      string
+	user     string
+	password string
+	insecure bool
+	sources  []string
+}
+
+// NewLogStream creates a stream for the given websocket endpoint, acting
+// for the calling plugin
+func NewLogStream(url, user, password string, insecure bool, sources []string) *LogStream {
+	return &LogStream{
+		plugin:   grants.Caller(),
+		url:      url,
+		user:     user,
+		password: password,
+		insecure: insecure,
+		sources:  sources,
+	}
+}
+
+// Run delivers log events to handle until ctx is cancelled, reconnecting
+// with backoff. status is called with nil once subscribed and with the
+// error whenever the connection is lost.
+func (s *LogStream) Run(ctx context.Context, handle func(LogEvent), status func(error)) {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := s.session(ctx, handle, status)
+		if ctx.Err() != nil {
+			return
+		}
+		status(err)
+
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// session runs a single websocket connection until it fails
+func (s *LogStream) session(ctx context.Context, handle func(LogEvent), status func(error)) error {
+	if err := grants.Check(s.plugin, grants.RPCPermission("log.subscribe")); err != nil {
+		return err
+	}
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 15 * time.Second,
+	}
+	if s.insecure {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(s.user+":"+s.password)))
+
+	conn, _, err := dialer.DialContext(ctx, s.url, header)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock ReadMessage when the caller gives up
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = conn.WriteJSON(request{
+		JSONRPC: "2.0",
+		Method:  "log.subscribe",
+		Params:  map[string]interface{}{"sources": s.sources},
+		ID:      1,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var msg struct {
+			ID     int64           `json:"id"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  *Error          `json:"error"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+
+		// Events arrive either as notifications or as further results
+		// for the subscribe request, depending on the IRCd version
+		payload := msg.Params
+		if len(payload) == 0 {
+			payload = msg.Result
+		}
+		var ev LogEvent
+		if err := json.Unmarshal(payload, &ev); err != nil || ev.EventID == "" {
+			if msg.ID == 1 {
+				status(nil)
+			}
+			continue
+		}
+		ev.Raw = payload
+		handle(ev)
+	}
+}
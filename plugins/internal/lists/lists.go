@@ -0,0 +1,37 @@
+// Package lists handles the list settings of plugins, which are kept as
+// comma separated strings in their configuration.
+
+package lists
+
+import "strings"
+
+// Split splits a comma separated setting into trimmed, non-empty items.
+// An empty setting gives an empty list rather than nil, so it is
+// marshalled as [].
+func Split(s string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Fields splits a setting separated by commas or whitespace, for lists
+// that are often pasted one item per line
+func Fields(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	})
+}
+
+// ContainsFold reports whether list contains s, ignoring case
+func ContainsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
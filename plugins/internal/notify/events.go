@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Severities, lowest first
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// SeverityRank orders severities, for minimums such as min_severity. A
+// severity missing from it is not valid.
+var SeverityRank = map[string]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityCritical: 2,
+}
+
+// EventBanAdded is the type of the events BanEvent makes
+const EventBanAdded = "ban_added"
+
+// Normalize fills in the defaults of an event received from elsewhere
+func (ev *Event) Normalize() error {
+	ev.Type = strings.TrimSpace(ev.Type)
+	if ev.Type == "" {
+		return fmt.Errorf("type is required")
+	}
+	if ev.Title == "" && ev.Message == "" {
+		return fmt.Errorf("title or message is required")
+	}
+	if _, ok := SeverityRank[ev.Severity]; !ok {
+		ev.Severity = SeverityInfo
+	}
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+	if ev.Title == "" {
+		ev.Title = ev.Type
+	}
+	return nil
+}
+
+// Receive reads an event posted to a notifier plugin's events endpoint,
+// sent with the plugin's events token in an X-Events-Token header or a
+// token query parameter. It writes the error response and returns false
+// when the token or the event is not accepted. An empty token accepts
+// nothing.
+func Receive(c *gin.Context, token string) (Event, bool) {
+	given := c.GetHeader("X-Events-Token")
+	if given == "" {
+		given = c.Query("token")
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid events token"})
+		return Event{}, false
+	}
+
+	var ev Event
+	if err := c.ShouldBindJSON(&ev); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event"})
+		return Event{}, false
+	}
+	if err := ev.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return Event{}, false
+	}
+	return ev, true
+}
+
+// TKL holds the server ban of a TKL_ADD or TKL_DEL log entry
+type TKL struct {
+	Type           string `json:"type"`
+	TypeString     string `json:"type_string"`
+	Name           string `json:"name"`
+	SetBy          string `json:"set_by"`
+	DurationString string `json:"duration_string"`
+	Reason         string `json:"reason"`
+}
+
+// ParseTKL returns the server ban of a TKL_ADD or TKL_DEL log entry. Bans
+// from the configuration file are skipped.
+func ParseTKL(ev jsonrpc.LogEvent) (*TKL, bool) {
+	if ev.EventID != "TKL_ADD" && ev.EventID != "TKL_DEL" {
+		return nil, false
+	}
+	var te struct {
+		TKL *TKL `json:"tkl"`
+	}
+	if err := json.Unmarshal(ev.Raw, &te); err != nil || te.TKL == nil {
+		return nil, false
+	}
+	if te.TKL.SetBy == "-config-" {
+		return nil, false
+	}
+	return te.TKL, true
+}
+
+// Kind returns the name of the ban's type, such as "G-Line"
+func (t *TKL) Kind() string {
+	if t.TypeString != "" {
+		return t.TypeString
+	}
+	return t.Type
+}
+
+// BanEvent turns a TKL_ADD log entry into an event from source. Bans from
+// the configuration file and types not in types, when it is not empty,
+// are skipped.
+func BanEvent(source string, ev jsonrpc.LogEvent, types []string) (Event, bool) {
+	if ev.EventID != "TKL_ADD" {
+		return Event{}, false
+	}
+	t, ok := ParseTKL(ev)
+	if !ok {
+		return Event{}, false
+	}
+	if len(types) > 0 && !lists.ContainsFold(types, t.Type) {
+		return Event{}, false
+	}
+
+	duration := t.DurationString
+	if duration == "" || duration == "0" {
+		duration = "permanent"
+	}
+	ts, err := time.Parse(time.RFC3339, ev.Timestamp)
+	if err != nil {
+		ts = time.Now()
+	}
+	return Event{
+		Source:    source,
+		Type:      EventBanAdded,
+		Severity:  SeverityInfo,
+		Title:     t.Kind() + " added",
+		Message:   fmt.Sprintf("%s by %s: %s", t.Name, t.SetBy, t.Reason),
+		Timestamp: ts.UTC(),
+		Data: map[string]interface{}{
+			"type":     t.Type,
+			"mask":     t.Name,
+			"set_by":   t.SetBy,
+			"duration": duration,
+			"reason":   t.Reason,
+		},
+	}, true
+}
@@ -0,0 +1,83 @@
+// Package notify posts alerts from plugins to a webhook. Delivering them
+// needs the network.outbound permission.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// Event is the JSON envelope posted to the alert webhook. Notifier
+// plugins accept the same envelope on their events endpoint.
+type Event struct {
+	Source    string                 `json:"source"`
+	Type      string                 `json:"type"`
+	Severity  string                 `json:"severity"`
+	Title     string                 `json:"title"`
+	Message   string                 `json:"message"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// client is shared by all alert deliveries
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Send posts an alert to the given webhook URL
+func Send(ctx context.Context, url string, ev Event) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("alert webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Mask hides the path and query of a webhook URL, which usually carry its
+// secret, leaving the scheme and host. Plugins show it in place of the URL
+// when returning their configuration.
+func Mask(webhook string) string {
+	if webhook == "" {
+		return ""
+	}
+	u, err := neturl.Parse(webhook)
+	if err != nil || u.Host == "" {
+		return "…"
+	}
+	return u.Scheme + "://" + u.Host + "/…"
+}
+
+// Unmask returns the webhook URL a configuration update stores: the
+// current one if the update sent back its mask unchanged, otherwise the
+// one sent, so an empty value still turns alerts off
+func Unmask(given, current string) string {
+	if current != "" && given == Mask(current) {
+		return current
+	}
+	return given
+}
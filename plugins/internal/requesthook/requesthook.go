@@ -0,0 +1,108 @@
+// Package requesthook runs the checks plugins register on authenticated
+// panel API requests. The panel's plugin loader installs Middleware once on
+// the API group, right after authentication and before any route is added,
+// so the checks see the panel's own routes as well as every plugin's. A
+// middleware a plugin installs on the router it is given only sees the
+// routes added after it.
+
+package requesthook
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Func checks a request. It calls next to pass the request on, which runs
+// the later checks and the route's handler, and can look at the response
+// once next returns. A check that does not call next must abort the
+// request with a response.
+type Func func(c *gin.Context, next func())
+
+// hook is a registered check
+type hook struct {
+	name  string
+	order int
+	fn    Func
+}
+
+// ranKey marks a request in the gin context once Middleware has run for it
+const ranKey = "requesthook.ran"
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []hook
+	// installed is set once Middleware has seen a request
+	installed atomic.Bool
+)
+
+// Register adds a check under a name, replacing any registered under the
+// same name. Checks with a lower order run first, so they can turn a
+// request away before later checks see it.
+func Register(name string, order int, fn Func) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = remove(hooks, name)
+	hooks = append(hooks, hook{name: name, order: order, fn: fn})
+	sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].order < hooks[j].order })
+}
+
+// Unregister removes the check registered under a name, for a plugin's
+// Shutdown
+func Unregister(name string) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = remove(hooks, name)
+}
+
+// Installed reports whether the panel has run Middleware for a request, so
+// plugins can warn that their checks are not in effect
+func Installed() bool {
+	return installed.Load()
+}
+
+// Ran reports whether Middleware ran for a request. Plugin routes are on
+// the same API group as the panel's own, so a plugin handler can use it to
+// confirm that its checks cover the panel's routes before it turns on
+// anything that depends on them.
+func Ran(c *gin.Context) bool {
+	return c.GetBool(ranKey)
+}
+
+// Middleware returns the gin middleware that runs the registered checks
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		installed.Store(true)
+		c.Set(ranKey, true)
+
+		hooksMu.RLock()
+		chain := append([]hook(nil), hooks...)
+		hooksMu.RUnlock()
+
+		var run func(i int)
+		run = func(i int) {
+			if c.IsAborted() {
+				return
+			}
+			if i == len(chain) {
+				c.Next()
+				return
+			}
+			chain[i].fn(c, func() { run(i + 1) })
+		}
+		run(0)
+	}
+}
+
+// remove returns list without the hook called name
+func remove(list []hook, name string) []hook {
+	out := list[:0]
+	for _, h := range list {
+		if h.name != name {
+			out = append(out, h)
+		}
+	}
+	return out
+}
@@ -0,0 +1,310 @@
+// Package services runs commands against IRC services, Anope through its
+// XML-RPC module and Atheme through its JSON-RPC transport, for the plugins
+// that read services data. Every request is checked against the
+// permissions of the plugin that created the backend.
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// Backend runs commands against a services package and returns the raw
+// text output, one line per element
+type Backend interface {
+	Name() string
+	Command(ctx context.Context, service, command string) ([]string, error)
+}
+
+// Config selects a services package and how to reach it
+type Config struct {
+	// Backend is "anope" or "atheme"
+	Backend  string
+	Endpoint string
+	Username string
+	Password string
+	// SourceIP is the address Atheme records for the session
+	SourceIP string
+}
+
+// New creates the backend selected in cfg, acting for the calling plugin
+func New(cfg Config) (Backend, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch cfg.Backend {
+	case "anope":
+		return &anopeBackend{plugin: grants.Caller(), url: cfg.Endpoint, source: cfg.Username, client: client}, nil
+	case "atheme":
+		return &athemeBackend{plugin: grants.Caller(), url: cfg.Endpoint, account: cfg.Username, password: cfg.Password, sourceIP: cfg.SourceIP, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown services backend %q", cfg.Backend)
+	}
+}
+
+// anopeBackend talks to Anope's m_xmlrpc/m_xmlrpc_main modules
+type anopeBackend struct {
+	plugin string
+	url    string
+	source string
+	client *http.Client
+}
+
+func (b *anopeBackend) Name() string { return "anope" }
+
+// xmlrpcValue is a (string only) XML-RPC value as produced by Anope
+type xmlrpcValue struct {
+	String string         `xml:"string"`
+	Text   string         `xml:",chardata"`
+	Struct []xmlrpcMember `xml:"struct>member"`
+}
+
+// xmlrpcMember is a member of an XML-RPC struct
+type xmlrpcMember struct {
+	Name  string      `xml:"name"`
+	Value xmlrpcValue `xml:"value"`
+}
+
+// xmlrpcResponse is an XML-RPC methodResponse
+type xmlrpcResponse struct {
+	Params []xmlrpcValue `xml:"params>param>value"`
+	Fault  *xmlrpcValue  `xml:"fault>value"`
+}
+
+func (v xmlrpcValue) str() string {
+	if v.String != "" {
+		return v.String
+	}
+	return strings.TrimSpace(v.Text)
+}
+
+// Command runs a services command through the XML-RPC "command" method
+func (b *anopeBackend) Command(ctx context.Context, service, command string) ([]string, error) {
+	if err := grants.Check(b.plugin, grants.Outbound); err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0"?><methodCall><methodName>command</methodName><params>`)
+	for _, param := range []string{service, b.source, command} {
+		body.WriteString("<param><value><string>")
+		xml.EscapeText(&body, []byte(param))
+		body.WriteString("</string></value></param>")
+	}
+	body.WriteString("</params></methodCall>")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("anope xmlrpc: unexpected status %s", resp.Status)
+	}
+
+	var r xmlrpcResponse
+	if err := xml.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("anope xmlrpc: %w", err)
+	}
+	if r.Fault != nil {
+		return nil, fmt.Errorf("anope xmlrpc fault: %s", memberValue(r.Fault.Struct, "faultString"))
+	}
+	if len(r.Params) == 0 {
+		return nil, errors.New("anope xmlrpc: empty response")
+	}
+	return SplitLines(memberValue(r.Params[0].Struct, "return")), nil
+}
+
+// memberValue finds a struct member by name
+func memberValue(members []xmlrpcMember, name string) string {
+	for _, m := range members {
+		if m.Name == name {
+			return m.Value.str()
+		}
+	}
+	return ""
+}
+
+// athemeBackend talks to Atheme's transport/jsonrpc module
+type athemeBackend struct {
+	plugin   string
+	url      string
+	account  string
+	password string
+	sourceIP string
+	client   *http.Client
+
+	mu     sync.Mutex
+	cookie string
+	nextID int
+}
+
+func (b *athemeBackend) Name() string { return "atheme" }
+
+// athemeFaultBadAuthCookie is returned when the login session has expired
+const athemeFaultBadAuthCookie = 15
+
+// athemeError is a JSON-RPC fault returned by Atheme
+type athemeError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *athemeError) Error() string {
+	return fmt.Sprintf("atheme fault %d: %s", e.Code, e.Message)
+}
+
+// call performs a raw Atheme JSON-RPC call
+func (b *athemeBackend) call(ctx context.Context, method string, params []string) (string, error) {
+	if err := grants.Check(b.plugin, grants.Outbound); err != nil {
+		return "", err
+	}
+	b.mu.Lock()
+	b.nextID++
+	id := strconv.Itoa(b.nextID)
+	b.mu.Unlock()
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      id,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		Result string       `json:"result"`
+		Error  *athemeError `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("atheme jsonrpc: %w", err)
+	}
+	if r.Error != nil {
+		return "", r.Error
+	}
+	return r.Result, nil
+}
+
+// login obtains an authcookie for the configured account
+func (b *athemeBackend) login(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	cookie := b.cookie
+	b.mu.Unlock()
+	if cookie != "" {
+		return cookie, nil
+	}
+
+	cookie, err := b.call(ctx, "atheme.login", []string{b.account, b.password, b.sourceIP})
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	b.cookie = cookie
+	b.mu.Unlock()
+	return cookie, nil
+}
+
+// Command runs a services command through atheme.command, logging in again
+// if the cookie has expired
+func (b *athemeBackend) Command(ctx context.Context, service, command string) ([]string, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		cookie, err := b.login(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		params := append([]string{cookie, b.account, b.sourceIP, service}, strings.Fields(command)...)
+		out, err := b.call(ctx, "atheme.command", params)
+
+		var fault *athemeError
+		if errors.As(err, &fault) && fault.Code == athemeFaultBadAuthCookie {
+			b.mu.Lock()
+			b.cookie = ""
+			b.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return SplitLines(out), nil
+	}
+	return nil, errors.New("atheme jsonrpc: unable to authenticate")
+}
+
+// infoLine matches "Key : Value" lines produced by INFO commands
+var infoLine = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z ]*?)\s*:\s*(.*)$`)
+
+// ParseInfo turns INFO output into a field map with lower_snake_case keys
+func ParseInfo(lines []string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range lines {
+		m := infoLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		key := strings.ReplaceAll(strings.ToLower(m[1]), " ", "_")
+		if _, exists := fields[key]; !exists {
+			fields[key] = strings.TrimSpace(m[2])
+		}
+	}
+	return fields
+}
+
+// ParseGroupList extracts nicknames from Anope's GLIST output
+func ParseGroupList(lines []string) []string {
+	nicks := make([]string, 0)
+	for _, line := range lines {
+		if !strings.HasPrefix(line, " ") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 && !strings.HasSuffix(fields[0], ":") {
+			nicks = append(nicks, fields[0])
+		}
+	}
+	return nicks
+}
+
+// SplitLines splits multi-line command output, dropping empty lines
+func SplitLines(s string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,295 @@
+/**
+ * IP Threat Score Frontend Script
+ *
+ * Looks up the threat score of an address with its breakdown, and lists
+ * the highest scores and the most banned addresses.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'ip-threat-score';
+  const PLUGIN_NAME = 'IP Threat Score';
+  const PAGE_PATH = '/plugins/ip-threat-score';
+  const API_BASE = '/api/plugin/ip-threat-score';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('ip-threat-score-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'ip-threat-score-styles';
+    style.textContent = `
+      .its-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .its-row { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .its-app input {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+        min-width: 16rem;
+      }
+      .its-app button {
+        background: var(--accent, #89b4fa);
+        color: var(--bg-primary, #11111b);
+        border: none;
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+      }
+      .its-result {
+        padding: 1rem;
+        border-radius: 8px;
+        background: var(--bg-secondary, #1e1e2e);
+        border: 1px solid var(--border-primary, #313244);
+      }
+      .its-score { font-size: 2rem; font-weight: 700; }
+      .its-low { color: var(--success, #a6e3a1); }
+      .its-medium { color: var(--warning, #f9e2af); }
+      .its-high { color: var(--error, #f38ba8); }
+      .its-table { width: 100%; border-collapse: collapse; }
+      .its-table th, .its-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+      }
+      .its-table tr[data-ip] { cursor: pointer; }
+      .its-meta { font-size: 0.75rem; color: var(--text-muted, #6c7086); }
+      .its-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderScore(s) {
+    return `
+      <div class="its-result">
+        <div class="its-row">
+          <span class="its-score its-${escapeHtml(s.level)}">${s.score}</span>
+          <span><code>${escapeHtml(s.ip)}</code> - ${escapeHtml(s.level)} threat</span>
+        </div>
+        ${s.components.length ? `
+          <table class="its-table">
+            <tbody>
+              ${s.components.map(c => `<tr><td>+${c.points}</td><td>${escapeHtml(c.detail)}</td></tr>`).join('')}
+            </tbody>
+          </table>
+        ` : '<p>Nothing known against this address.</p>'}
+        <div class="its-meta">
+          ${s.asn ? `AS${escapeHtml(s.asn)} ${escapeHtml(s.asname)} - ` : ''}checked ${formatTime(s.checked_at)}
+        </div>
+        ${(s.errors || []).map(e => `<div class="its-error">${escapeHtml(e)}</div>`).join('')}
+      </div>
+    `;
+  }
+
+  async function lookup(container, ip, refresh) {
+    const out = container.querySelector('#its-result');
+    out.innerHTML = 'Checking...';
+    try {
+      const s = await api('GET', `/score/${encodeURIComponent(ip)}${refresh ? '?refresh=1' : ''}`);
+      out.innerHTML = renderScore(s);
+      loadScores(container);
+    } catch (e) {
+      out.innerHTML = `<div class="its-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#its-status');
+    try {
+      const s = await api('GET', '/status');
+      body.innerHTML = `
+        <div class="its-meta">
+          DNSBLs: ${escapeHtml(s.dnsbls.join(', ') || 'none')} -
+          proxy detection: ${escapeHtml(s.proxy_service || 'none')} -
+          ${s.cached} cached scores, ${s.banned_ips} banned addresses on record
+        </div>
+        ${s.poll_error ? `<div class="its-error">Could not read bans: ${escapeHtml(s.poll_error)}</div>` : ''}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="its-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadScores(container) {
+    const body = container.querySelector('#its-scores');
+    try {
+      const data = await api('GET', '/scores?min=1&limit=50');
+      if (data.scores.length === 0) {
+        body.innerHTML = '<p>No scored addresses with a threat yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="its-table">
+          <thead><tr><th>Score</th><th>IP</th><th>Reasons</th><th>Network</th><th>Checked</th></tr></thead>
+          <tbody>
+            ${data.scores.map(s => `
+              <tr data-ip="${escapeHtml(s.ip)}">
+                <td class="its-${escapeHtml(s.level)}">${s.score}</td>
+                <td><code>${escapeHtml(s.ip)}</code></td>
+                <td>${escapeHtml(s.components.map(c => c.detail).join('; '))}</td>
+                <td>${s.asn ? `AS${escapeHtml(s.asn)} ${escapeHtml(s.asname)}` : ''}</td>
+                <td>${formatTime(s.checked_at)}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+      body.querySelectorAll('[data-ip]').forEach(row => {
+        row.addEventListener('click', () => {
+          container.querySelector('#its-ip').value = row.dataset.ip;
+          lookup(container, row.dataset.ip, false);
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="its-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadHistory(container) {
+    const body = container.querySelector('#its-history');
+    try {
+      const data = await api('GET', '/history?limit=50');
+      if (data.history.length === 0) {
+        body.innerHTML = '<p>No bans on single addresses seen yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="its-table">
+          <thead><tr><th>IP</th><th>Bans</th><th>Last ban</th></tr></thead>
+          <tbody>
+            ${data.history.map(h => {
+              const last = h.bans[h.bans.length - 1];
+              return `
+                <tr data-ip="${escapeHtml(h.ip)}">
+                  <td><code>${escapeHtml(h.ip)}</code></td>
+                  <td>${h.bans.length}</td>
+                  <td>${escapeHtml(last.type)} by ${escapeHtml(last.set_by)}: ${escapeHtml(last.reason)}</td>
+                </tr>
+              `;
+            }).join('')}
+          </tbody>
+        </table>
+      `;
+      body.querySelectorAll('[data-ip]').forEach(row => {
+        row.addEventListener('click', () => {
+          container.querySelector('#its-ip').value = row.dataset.ip;
+          lookup(container, row.dataset.ip, false);
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="its-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="its-app" data-plugin="${PLUGIN_ID}">
+        <div id="its-status"></div>
+        <div class="its-row">
+          <input type="text" id="its-ip" placeholder="IPv4 or IPv6 address">
+          <button id="its-check">Check</button>
+          <button id="its-refresh">Check again</button>
+        </div>
+        <div id="its-result"></div>
+        <h3>Highest scores</h3>
+        <div id="its-scores">Loading...</div>
+        <h3>Most banned addresses</h3>
+        <div id="its-history">Loading...</div>
+      </div>
+    `;
+
+    const input = container.querySelector('#its-ip');
+    const run = refresh => {
+      const ip = input.value.trim();
+      if (ip) lookup(container, ip, refresh);
+    };
+    container.querySelector('#its-check').addEventListener('click', () => run(false));
+    container.querySelector('#its-refresh').addEventListener('click', () => run(true));
+    input.addEventListener('keydown', e => { if (e.key === 'Enter') run(false); });
+
+    loadStatus(container);
+    loadScores(container);
+    loadHistory(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('ip-threat-score-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,283 @@
+package ipthreatscore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// Listing is a DNSBL that lists an address
+type Listing struct {
+	Zone   string   `json:"zone"`
+	Codes  []string `json:"codes"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// droneBLProxyCodes are DroneBL return codes for proxies and VPNs
+var droneBLProxyCodes = map[string]string{
+	"127.0.0.8":  "SOCKS proxy",
+	"127.0.0.9":  "HTTP proxy",
+	"127.0.0.10": "ProxyChain",
+	"127.0.0.11": "web page proxy",
+	"127.0.0.14": "open Wingate",
+	"127.0.0.19": "abused VPN",
+}
+
+// reverseName returns the reversed form of ip used by DNSBLs, or "" for
+// an invalid address
+func reverseName(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", v4[3], v4[2], v4[1], v4[0])
+	}
+	nibbles := make([]string, 0, 32)
+	for i := len(addr) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, fmt.Sprintf("%x.%x", addr[i]&0x0f, addr[i]>>4))
+	}
+	return strings.Join(nibbles, ".")
+}
+
+// checkDNSBLs looks ip up in each zone in parallel and returns the zones
+// listing it
+func checkDNSBLs(ctx context.Context, ip string, zones []string) []Listing {
+	rev := reverseName(ip)
+	listings := make([]Listing, 0)
+	if rev == "" || grants.Allow(grants.Outbound) != nil {
+		return listings
+	}
+
+	results := make(chan *Listing, len(zones))
+	for _, zone := range zones {
+		go func(zone string) {
+			addrs, err := net.DefaultResolver.LookupHost(ctx, rev+"."+zone)
+			if err != nil || len(addrs) == 0 {
+				results <- nil
+				return
+			}
+			l := &Listing{Zone: zone, Codes: addrs}
+			if txt, err := net.DefaultResolver.LookupTXT(ctx, rev+"."+zone); err == nil && len(txt) > 0 {
+				l.Reason = txt[0]
+			}
+			results <- l
+		}(zone)
+	}
+	for range zones {
+		if l := <-results; l != nil {
+			listings = append(listings, *l)
+		}
+	}
+	return listings
+}
+
+// proxyFromListings reports whether a listing marks ip as a proxy or VPN,
+// using the DroneBL return codes
+func proxyFromListings(listings []Listing) (bool, string) {
+	for _, l := range listings {
+		if !strings.Contains(l.Zone, "dronebl") {
+			continue
+		}
+		for _, code := range l.Codes {
+			if kind, ok := droneBLProxyCodes[code]; ok {
+				return true, kind
+			}
+		}
+	}
+	return false, ""
+}
+
+// proxyResult is the answer of a proxy detection service
+type proxyResult struct {
+	Proxy   bool
+	Type    string
+	Hosting bool
+}
+
+// httpClient is used for proxy detection services
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// checkProxy asks the configured proxy detection service about ip
+func checkProxy(ctx context.Context, service, apiKey, ip string) (proxyResult, error) {
+	switch service {
+	case "proxycheck":
+		return checkProxycheck(ctx, apiKey, ip)
+	case "ip-api":
+		return checkIPAPI(ctx, ip)
+	}
+	return proxyResult{}, nil
+}
+
+// getJSON fetches a URL and decodes its JSON body into out
+func getJSON(ctx context.Context, u string, out interface{}) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// checkProxycheck queries proxycheck.io, whose answer is keyed by the
+// address: {"status":"ok","192.0.2.1":{"proxy":"yes","type":"VPN"}}
+func checkProxycheck(ctx context.Context, apiKey, ip string) (proxyResult, error) {
+	q := url.Values{}
+	q.Set("vpn", "1")
+	if apiKey != "" {
+		q.Set("key", apiKey)
+	}
+	var out map[string]json.RawMessage
+	if err := getJSON(ctx, "https://proxycheck.io/v2/"+url.PathEscape(ip)+"?"+q.Encode(), &out); err != nil {
+		return proxyResult{}, fmt.Errorf("proxycheck: %w", err)
+	}
+	var status string
+	_ = json.Unmarshal(out["status"], &status)
+	if status != "ok" && status != "warning" {
+		var message string
+		_ = json.Unmarshal(out["message"], &message)
+		return proxyResult{}, fmt.Errorf("proxycheck: %s %s", status, message)
+	}
+	var entry struct {
+		Proxy string `json:"proxy"`
+		Type  string `json:"type"`
+	}
+	if err := json.Unmarshal(out[ip], &entry); err != nil {
+		return proxyResult{}, fmt.Errorf("proxycheck: no answer for %s", ip)
+	}
+	return proxyResult{
+		Proxy:   entry.Proxy == "yes",
+		Type:    entry.Type,
+		Hosting: strings.EqualFold(entry.Type, "Business") || strings.EqualFold(entry.Type, "Hosting"),
+	}, nil
+}
+
+// checkIPAPI queries ip-api.com, which is free without a key but only over
+// plain HTTP
+func checkIPAPI(ctx context.Context, ip string) (proxyResult, error) {
+	var out struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Proxy   bool   `json:"proxy"`
+		Hosting bool   `json:"hosting"`
+	}
+	if err := getJSON(ctx, "http://ip-api.com/json/"+url.PathEscape(ip)+"?fields=status,message,proxy,hosting", &out); err != nil {
+		return proxyResult{}, fmt.Errorf("ip-api: %w", err)
+	}
+	if out.Status != "success" {
+		return proxyResult{}, fmt.Errorf("ip-api: %s", out.Message)
+	}
+	r := proxyResult{Proxy: out.Proxy, Hosting: out.Hosting}
+	if out.Proxy {
+		r.Type = "proxy"
+	}
+	return r, nil
+}
+
+// hostingAS reports whether an AS looks like a hosting provider, by its
+// number or by a keyword in its name
+func hostingAS(info asInfo, asns, keywords []string) bool {
+	if info.ASN == "" {
+		return false
+	}
+	for _, asn := range asns {
+		if strings.TrimPrefix(strings.ToUpper(asn), "AS") == info.ASN {
+			return true
+		}
+	}
+	name := strings.ToLower(info.ASName)
+	for _, kw := range keywords {
+		if kw != "" && strings.Contains(name, strings.ToLower(kw)) {
+			return true
+		}
+	}
+	return false
+}
+
+// feedFile is a cached copy of a proxy feed file, such as the one written
+// by the Proxy Scanner plugin
+type feedFile struct {
+	modTime time.Time
+	Proxies map[string]feedEntry `json:"proxies"`
+}
+
+// feedEntry is an address listed in a proxy feed
+type feedEntry struct {
+	Type string `json:"type"`
+}
+
+// feedReader reads proxy feed files, parsing each again only when it
+// changes on disk
+type feedReader struct {
+	files map[string]*feedFile
+	mu    sync.Mutex
+}
+
+// newFeedReader creates an empty feed reader
+func newFeedReader() *feedReader {
+	return &feedReader{files: make(map[string]*feedFile)}
+}
+
+// Lookup reports whether one of the feeds lists ip as an open proxy, and
+// of which type
+func (r *feedReader) Lookup(paths []string, ip string) (bool, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var firstErr error
+	for _, path := range paths {
+		st, err := os.Stat(path)
+		if err != nil {
+			// A feed that does not exist yet is not an error
+			if !os.IsNotExist(err) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		f, ok := r.files[path]
+		if !ok || !f.modTime.Equal(st.ModTime()) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			f = &feedFile{modTime: st.ModTime()}
+			if err := json.Unmarshal(data, f); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("proxy feed %s: %w", path, err)
+				}
+				continue
+			}
+			r.files[path] = f
+		}
+		if e, ok := f.Proxies[ip]; ok {
+			kind := "open proxy"
+			if e.Type != "" {
+				kind = "open " + e.Type + " proxy"
+			}
+			return true, kind, nil
+		}
+	}
+	return false, "", firstErr
+}
@@ -0,0 +1,739 @@
+// IP Threat Score Plugin for UnrealIRCd Web Panel
+// Combines DNSBL listings, proxy and VPN detection, the type of network and
+// past bans into one 0-100 threat score per IP address
+
+package ipthreatscore
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on stored data
+const (
+	maxCached     = 20000
+	maxBansPerIP  = 20
+	maxBatch      = 100
+	batchParallel = 8
+)
+
+// IPThreatScorePlugin implements the Plugin interface
+type IPThreatScorePlugin struct {
+	config   Config
+	rpc      *jsonrpc.Client
+	asn      *asnResolver
+	feeds    *feedReader
+	scores   map[string]*Score
+	history  map[string]*BanHistory
+	lastPoll time.Time
+	pollErr  string
+	dirty    bool
+	mu       sync.RWMutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL          string `json:"rpc_url"`
+	RPCUser         string `json:"rpc_user"`
+	RPCPassword     string `json:"rpc_password"`
+	RPCInsecure     bool   `json:"rpc_insecure"`
+	DataDir         string `json:"data_dir"`
+	DNSBLs          string `json:"dnsbls"`
+	ProxyService    string `json:"proxy_service"`
+	ProxyAPIKey     string `json:"proxy_api_key"`
+	ProxyFeeds      string `json:"proxy_feeds"`
+	ASNLookup       bool   `json:"asn_lookup"`
+	HostingASNs     string `json:"hosting_asns"`
+	HostingKeywords string `json:"hosting_keywords"`
+	WeightDNSBL     int    `json:"weight_dnsbl"`
+	WeightProxy     int    `json:"weight_proxy"`
+	WeightHosting   int    `json:"weight_hosting"`
+	WeightBans      int    `json:"weight_bans"`
+	HighThreshold   int    `json:"high_threshold"`
+	CacheHours      int    `json:"cache_hours"`
+	BanPollMinutes  int    `json:"ban_poll_minutes"`
+}
+
+// Score is the threat assessment of an address
+type Score struct {
+	IP         string      `json:"ip"`
+	Score      int         `json:"score"`
+	Level      string      `json:"level"`
+	Components []Component `json:"components"`
+	DNSBL      []Listing   `json:"dnsbl"`
+	Proxy      bool        `json:"proxy"`
+	ProxyType  string      `json:"proxy_type,omitempty"`
+	ASN        string      `json:"asn,omitempty"`
+	ASName     string      `json:"asname,omitempty"`
+	Hosting    bool        `json:"hosting"`
+	PastBans   int         `json:"past_bans"`
+	Errors     []string    `json:"errors,omitempty"`
+	CheckedAt  time.Time   `json:"checked_at"`
+}
+
+// Component is one source's contribution to a score
+type Component struct {
+	Source string `json:"source"`
+	Points int    `json:"points"`
+	Detail string `json:"detail"`
+}
+
+// BanHistory is the bans an address has had, as seen on the network
+type BanHistory struct {
+	IP   string       `json:"ip"`
+	Bans []*BanRecord `json:"bans"`
+}
+
+// BanRecord is a single server ban on an address
+type BanRecord struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	SetAt  string `json:"set_at"`
+	SetBy  string `json:"set_by"`
+	Reason string `json:"reason"`
+}
+
+// rpcBan is a ban as returned by server_ban.list
+type rpcBan struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	SetAt  string `json:"set_at"`
+	SetBy  string `json:"set_by"`
+	Reason string `json:"reason"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Scores  map[string]*Score      `json:"scores"`
+	History map[string]*BanHistory `json:"history"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &IPThreatScorePlugin{
+		config: Config{
+			RPCURL:          "https://127.0.0.1:8600/api",
+			DataDir:         "data/plugins/ip-threat-score",
+			DNSBLs:          "dnsbl.dronebl.org,rbl.efnetrbl.org",
+			ProxyService:    "none",
+			ProxyFeeds:      "data/plugins/proxy-scanner/open-proxies.json",
+			ASNLookup:       true,
+			HostingKeywords: "hosting,cloud,datacenter,data center,server,vps,colo,ovh,hetzner,digitalocean,linode,vultr,choopa,contabo,leaseweb,m247,amazon,google cloud,microsoft",
+			WeightDNSBL:     40,
+			WeightProxy:     30,
+			WeightHosting:   15,
+			WeightBans:      30,
+			HighThreshold:   70,
+			CacheHours:      6,
+			BanPollMinutes:  10,
+		},
+		asn:     newASNResolver(),
+		feeds:   newFeedReader(),
+		scores:  make(map[string]*Score),
+		history: make(map[string]*BanHistory),
+	}
+}
+
+// Info returns plugin metadata
+func (p *IPThreatScorePlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "IP Threat Score",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "One threat score per IP from DNSBLs, proxy detection, network type and past bans",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *IPThreatScorePlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[ip-threat-score] failed to load data: %v", err)
+	}
+	if data.Scores != nil {
+		p.scores = data.Scores
+	}
+	if data.History != nil {
+		p.history = data.History
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "ip-threat-score-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		checked, high := 0, 0
+		for _, s := range p.scores {
+			if s.CheckedAt.After(since) {
+				checked++
+				if s.Level == "high" {
+					high++
+				}
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "IP Threat Scores",
+			Icon:  "shield-alert",
+			Content: map[string]interface{}{
+				"checked_24h": checked,
+				"high_24h":    high,
+				"banned_ips":  len(p.history),
+			},
+			Order: 58,
+			Size:  "sm",
+		}
+	}, 50)
+
+	// Add the score to user lookups
+	hm.Register(hooks.HookUserLookup, "ip-threat-score-lookup", func(args interface{}) interface{} {
+		m, ok := args.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		ip, _ := m["ip"].(string)
+		if net.ParseIP(ip) == nil {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		s := p.score(ctx, ip, false)
+		reasons := make([]string, 0, len(s.Components))
+		for _, c := range s.Components {
+			reasons = append(reasons, c.Detail)
+		}
+		return map[string]interface{}{
+			"threat_score":   s.Score,
+			"threat_level":   s.Level,
+			"threat_reasons": reasons,
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *IPThreatScorePlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *IPThreatScorePlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/ip-threat-score")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/score/:ip", p.handleScore)
+		plugin.POST("/scores", p.handleBatch)
+		plugin.GET("/scores", p.handleListScores)
+		plugin.GET("/history", p.handleHistory)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *IPThreatScorePlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "scores.json")
+}
+
+// save persists the state if it changed
+func (p *IPThreatScorePlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Scores: p.scores, History: p.history}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it on first use
+func (p *IPThreatScorePlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop records new bans and prunes the cache until shutdown
+func (p *IPThreatScorePlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.pollBans()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.BanPollMinutes) * time.Minute
+		due := interval > 0 && time.Since(p.lastPoll) >= interval
+		p.mu.RUnlock()
+		if due {
+			p.pollBans()
+		}
+
+		p.prune()
+		if err := p.save(); err != nil {
+			log.Printf("[ip-threat-score] failed to save data: %v", err)
+		}
+	}
+}
+
+// banIP returns the address a ban name such as "*@192.0.2.1" covers, or ""
+// for masks, ranges and hostnames
+func banIP(name string) string {
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name = name[i+1:]
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// pollBans adds bans placed since the last poll to the ban history
+func (p *IPThreatScorePlugin) pollBans() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var out struct {
+		List []rpcBan `json:"list"`
+	}
+	err := p.client().Call(ctx, "server_ban.list", nil, &out)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastPoll = time.Now()
+	if err != nil {
+		p.pollErr = err.Error()
+		log.Printf("[ip-threat-score] failed to list bans: %v", err)
+		return
+	}
+	p.pollErr = ""
+
+	for _, b := range out.List {
+		ip := banIP(b.Name)
+		if ip == "" {
+			continue
+		}
+		h, ok := p.history[ip]
+		if !ok {
+			h = &BanHistory{IP: ip, Bans: make([]*BanRecord, 0, 1)}
+			p.history[ip] = h
+		}
+		known := false
+		for _, r := range h.Bans {
+			if r.Type == b.Type && r.SetAt == b.SetAt {
+				known = true
+				break
+			}
+		}
+		if known {
+			continue
+		}
+		h.Bans = append(h.Bans, &BanRecord{Type: b.Type, Name: b.Name, SetAt: b.SetAt, SetBy: b.SetBy, Reason: b.Reason})
+		if len(h.Bans) > maxBansPerIP {
+			h.Bans = h.Bans[len(h.Bans)-maxBansPerIP:]
+		}
+		// The cached score no longer counts this ban
+		delete(p.scores, ip)
+		p.dirty = true
+	}
+}
+
+// prune drops expired scores and keeps the cache within its limit
+func (p *IPThreatScorePlugin) prune() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ttl := time.Duration(p.config.CacheHours) * time.Hour
+	for ip, s := range p.scores {
+		if time.Since(s.CheckedAt) > ttl {
+			delete(p.scores, ip)
+			p.dirty = true
+		}
+	}
+	if len(p.scores) <= maxCached {
+		return
+	}
+	list := make([]*Score, 0, len(p.scores))
+	for _, s := range p.scores {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CheckedAt.Before(list[j].CheckedAt) })
+	for _, s := range list[:len(list)-maxCached] {
+		delete(p.scores, s.IP)
+	}
+	p.dirty = true
+}
+
+// score returns the cached score of ip, or computes it when there is none
+// or refresh is set
+func (p *IPThreatScorePlugin) score(ctx context.Context, ip string, refresh bool) *Score {
+	p.mu.RLock()
+	cached, ok := p.scores[ip]
+	ttl := time.Duration(p.config.CacheHours) * time.Hour
+	p.mu.RUnlock()
+	if ok && !refresh && time.Since(cached.CheckedAt) < ttl {
+		return cached
+	}
+
+	s := p.compute(ctx, ip)
+
+	p.mu.Lock()
+	p.scores[ip] = s
+	p.dirty = true
+	p.mu.Unlock()
+	return s
+}
+
+// compute gathers everything known about ip and scores it
+func (p *IPThreatScorePlugin) compute(ctx context.Context, ip string) *Score {
+	p.mu.RLock()
+	cfg := p.config
+	pastBans := 0
+	if h, ok := p.history[ip]; ok {
+		pastBans = len(h.Bans)
+	}
+	p.mu.RUnlock()
+
+	s := &Score{
+		IP:         ip,
+		Components: make([]Component, 0),
+		PastBans:   pastBans,
+		CheckedAt:  time.Now().UTC(),
+	}
+
+	s.DNSBL = checkDNSBLs(ctx, ip, lists.Split(cfg.DNSBLs))
+	s.Proxy, s.ProxyType = proxyFromListings(s.DNSBL)
+
+	if found, kind, err := p.feeds.Lookup(lists.Split(cfg.ProxyFeeds), ip); err != nil {
+		s.Errors = append(s.Errors, err.Error())
+	} else if found && !s.Proxy {
+		s.Proxy, s.ProxyType = true, kind
+	}
+
+	if cfg.ProxyService != "" && cfg.ProxyService != "none" {
+		r, err := checkProxy(ctx, cfg.ProxyService, cfg.ProxyAPIKey, ip)
+		if err != nil {
+			s.Errors = append(s.Errors, err.Error())
+		} else {
+			if r.Proxy && !s.Proxy {
+				s.Proxy, s.ProxyType = true, r.Type
+			}
+			s.Hosting = s.Hosting || r.Hosting
+		}
+	}
+
+	if cfg.ASNLookup {
+		info := p.asn.Lookup(ctx, ip)
+		s.ASN, s.ASName = info.ASN, info.ASName
+		s.Hosting = s.Hosting || hostingAS(info, lists.Split(cfg.HostingASNs), lists.Split(cfg.HostingKeywords))
+	}
+
+	if n := len(s.DNSBL); n > 0 {
+		zones := make([]string, 0, n)
+		for _, l := range s.DNSBL {
+			zones = append(zones, l.Zone)
+		}
+		s.add("dnsbl", minInt(cfg.WeightDNSBL, n*cfg.WeightDNSBL/2), "Listed in "+strings.Join(zones, ", "))
+	}
+	if s.Proxy {
+		kind := s.ProxyType
+		if kind == "" {
+			kind = "proxy or VPN"
+		}
+		s.add("proxy", cfg.WeightProxy, "Detected as "+kind)
+	}
+	if s.Hosting {
+		detail := "Hosting or datacenter network"
+		if s.ASName != "" {
+			detail += " (" + s.ASName + ")"
+		}
+		s.add("network", cfg.WeightHosting, detail)
+	}
+	if pastBans > 0 {
+		s.add("history", minInt(cfg.WeightBans, pastBans*cfg.WeightBans/2), fmt.Sprintf("Banned %d times before", pastBans))
+	}
+
+	switch {
+	case s.Score >= cfg.HighThreshold:
+		s.Level = "high"
+	case s.Score >= cfg.HighThreshold/2:
+		s.Level = "medium"
+	default:
+		s.Level = "low"
+	}
+	return s
+}
+
+// add records a component and adds its points, keeping the score at most
+// 100
+func (s *Score) add(source string, points int, detail string) {
+	if points <= 0 {
+		return
+	}
+	s.Components = append(s.Components, Component{Source: source, Points: points, Detail: detail})
+	s.Score += points
+	if s.Score > 100 {
+		s.Score = 100
+	}
+}
+
+// minInt returns the smaller of a and b
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// handleStatus reports the ban poll and cache state
+func (p *IPThreatScorePlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"cached":        len(p.scores),
+		"banned_ips":    len(p.history),
+		"last_poll":     p.lastPoll,
+		"poll_error":    p.pollErr,
+		"dnsbls":        lists.Split(p.config.DNSBLs),
+		"proxy_service": p.config.ProxyService,
+		"proxy_feeds":   lists.Split(p.config.ProxyFeeds),
+		"asn_lookup":    p.config.ASNLookup,
+	})
+}
+
+// handleScore returns the score of one address
+func (p *IPThreatScorePlugin) handleScore(c *gin.Context) {
+	addr := net.ParseIP(c.Param("ip"))
+	if addr == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	c.JSON(http.StatusOK, p.score(ctx, addr.String(), c.Query("refresh") == "1"))
+}
+
+// handleBatch scores several addresses at once
+func (p *IPThreatScorePlugin) handleBatch(c *gin.Context) {
+	var req struct {
+		IPs []string `json:"ips"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if len(req.IPs) > maxBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d addresses per request", maxBatch)})
+		return
+	}
+
+	ips := make([]string, 0, len(req.IPs))
+	for _, raw := range req.IPs {
+		if addr := net.ParseIP(strings.TrimSpace(raw)); addr != nil {
+			ips = append(ips, addr.String())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	results := make([]*Score, len(ips))
+	sem := make(chan struct{}, batchParallel)
+	var wg sync.WaitGroup
+	for i, ip := range ips {
+		wg.Add(1)
+		go func(i int, ip string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = p.score(ctx, ip, false)
+		}(i, ip)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{"scores": results})
+}
+
+// handleListScores returns cached scores, highest first
+func (p *IPThreatScorePlugin) handleListScores(c *gin.Context) {
+	minScore, _ := strconv.Atoi(c.DefaultQuery("min", "0"))
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	p.mu.RLock()
+	list := make([]*Score, 0)
+	for _, s := range p.scores {
+		if s.Score >= minScore {
+			list = append(list, s)
+		}
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].CheckedAt.After(list[j].CheckedAt)
+	})
+	total := len(list)
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	c.JSON(http.StatusOK, gin.H{"scores": list, "total": total})
+}
+
+// handleHistory returns the addresses with the most past bans
+func (p *IPThreatScorePlugin) handleHistory(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	p.mu.RLock()
+	list := make([]*BanHistory, 0, len(p.history))
+	for _, h := range p.history {
+		list = append(list, h)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].Bans) != len(list[j].Bans) {
+			return len(list[i].Bans) > len(list[j].Bans)
+		}
+		return list[i].IP < list[j].IP
+	})
+	total := len(list)
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	c.JSON(http.StatusOK, gin.H{"history": list, "total": total})
+}
+
+// handleGetConfig returns the current configuration
+func (p *IPThreatScorePlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.ProxyAPIKey = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration. Cached scores are
+// dropped because the weights may have changed.
+func (p *IPThreatScorePlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	switch newConfig.ProxyService {
+	case "", "none", "proxycheck", "ip-api":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "proxy_service must be none, proxycheck or ip-api"})
+		return
+	}
+	if newConfig.HighThreshold < 1 || newConfig.HighThreshold > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "high_threshold must be between 1 and 100"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.ProxyAPIKey == "" {
+		newConfig.ProxyAPIKey = p.config.ProxyAPIKey
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.scores = make(map[string]*Score)
+	p.dirty = true
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *IPThreatScorePlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *IPThreatScorePlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,686 @@
+// Join Flood Plugin for UnrealIRCd Web Panel
+// Watches join rates per channel and network-wide, detects mass-join
+// events and records the hosts involved
+
+package joinflood
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Incident scopes
+const (
+	ScopeChannel = "channel"
+	ScopeNetwork = "network"
+)
+
+// maxClientsPerIncident limits how many joins an incident keeps
+const maxClientsPerIncident = 1000
+
+// JoinFloodPlugin implements the Plugin interface
+type JoinFloodPlugin struct {
+	config       Config
+	incidents    []*Incident
+	open         map[string]*Incident
+	channelJoins map[string][]time.Time
+	networkJoins []time.Time
+	dirty        bool
+	streamOK     bool
+	streamError  string
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	StreamURL        string `json:"stream_url"`
+	DataDir          string `json:"data_dir"`
+	Window           int    `json:"window"`
+	ChannelThreshold int    `json:"channel_threshold"`
+	NetworkThreshold int    `json:"network_threshold"`
+	Cooldown         int    `json:"cooldown"`
+	AlertWebhook     string `json:"alert_webhook"`
+	MaxIncidents     int    `json:"max_incidents"`
+}
+
+// Join is a single join recorded during an incident
+type Join struct {
+	Time    time.Time `json:"time"`
+	Nick    string    `json:"nick"`
+	Channel string    `json:"channel"`
+	Host    string    `json:"host"`
+	IP      string    `json:"ip"`
+	Account string    `json:"account,omitempty"`
+}
+
+// Incident is a detected join flood
+type Incident struct {
+	ID       string    `json:"id"`
+	Scope    string    `json:"scope"`
+	Channel  string    `json:"channel,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end,omitempty"`
+	LastJoin time.Time `json:"last_join"`
+	Open     bool      `json:"open"`
+	Joins    int       `json:"joins"`
+	PeakRate int       `json:"peak_rate"`
+	Clients  []Join    `json:"clients"`
+}
+
+// Summary describes the clients involved in an incident
+type Summary struct {
+	DistinctIPs   int            `json:"distinct_ips"`
+	DistinctHosts int            `json:"distinct_hosts"`
+	WithAccount   int            `json:"with_account"`
+	TopSubnets    map[string]int `json:"top_subnets"`
+}
+
+// joinEvent holds the objects we need from a join log entry
+type joinEvent struct {
+	Client *struct {
+		Name     string `json:"name"`
+		Hostname string `json:"hostname"`
+		IP       string `json:"ip"`
+		User     struct {
+			Account string `json:"account"`
+		} `json:"user"`
+	} `json:"client"`
+	Channel *struct {
+		Name string `json:"name"`
+	} `json:"channel"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &JoinFloodPlugin{
+		config: Config{
+			StreamURL:        "wss://127.0.0.1:8600/",
+			DataDir:          "data/plugins/join-flood",
+			Window:           10,
+			ChannelThreshold: 15,
+			NetworkThreshold: 100,
+			Cooldown:         60,
+			MaxIncidents:     500,
+		},
+		incidents:    make([]*Incident, 0),
+		open:         make(map[string]*Incident),
+		channelJoins: make(map[string][]time.Time),
+	}
+}
+
+// Info returns plugin metadata
+func (p *JoinFloodPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Join Flood",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Detects join floods and mass-join events",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *JoinFloodPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.incidents); err != nil {
+		log.Printf("[join-flood] failed to load incidents: %v", err)
+	}
+	if p.incidents == nil {
+		p.incidents = make([]*Incident, 0)
+	}
+	// Anything left open by a previous run is over by now
+	for _, inc := range p.incidents {
+		if inc.Open {
+			inc.Open = false
+			inc.End = inc.LastJoin
+			p.dirty = true
+		}
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "join-flood-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		recent := 0
+		for _, inc := range p.incidents {
+			if inc.Start.After(since) {
+				recent++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Join Floods",
+			Icon:  "Zap",
+			Content: map[string]interface{}{
+				"active":   len(p.open),
+				"last_24h": recent,
+			},
+			Order: 36,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.tickLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *JoinFloodPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *JoinFloodPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/join-flood")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/rates", p.handleRates)
+		plugin.GET("/incidents", p.handleListIncidents)
+		plugin.GET("/incidents/:id", p.handleGetIncident)
+		plugin.GET("/incidents/:id/hosts", p.handleIncidentHosts)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the incidents file
+func (p *JoinFloodPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "incidents.json")
+}
+
+// save persists the incidents if they changed
+func (p *JoinFloodPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.incidents); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// streamLoop follows join events until shutdown, restarting the stream
+// whenever the configuration changes
+func (p *JoinFloodPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, []string{"join"})
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *JoinFloodPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		p.streamError = err.Error()
+		log.Printf("[join-flood] log stream: %v", err)
+	} else {
+		p.streamError = ""
+	}
+}
+
+// tickLoop closes quiet incidents and saves until shutdown
+func (p *JoinFloodPlugin) tickLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.closeQuiet(time.Now())
+			if err := p.save(); err != nil {
+				log.Printf("[join-flood] failed to save incidents: %v", err)
+			}
+		}
+	}
+}
+
+// handleEvent counts a join and opens or extends incidents
+func (p *JoinFloodPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	if !strings.HasSuffix(ev.EventID, "_JOIN") {
+		return
+	}
+	var data joinEvent
+	if err := json.Unmarshal(ev.Raw, &data); err != nil || data.Client == nil || data.Channel == nil {
+		return
+	}
+
+	now := time.Now()
+	join := Join{
+		Time:    now.UTC(),
+		Nick:    data.Client.Name,
+		Channel: data.Channel.Name,
+		Host:    data.Client.Hostname,
+		IP:      data.Client.IP,
+		Account: data.Client.User.Account,
+	}
+	key := strings.ToLower(join.Channel)
+
+	p.mu.Lock()
+	window := time.Duration(p.config.Window) * time.Second
+	if window < time.Second {
+		window = time.Second
+	}
+	cutoff := now.Add(-window)
+
+	p.channelJoins[key] = append(prune(p.channelJoins[key], cutoff), now)
+	p.networkJoins = append(prune(p.networkJoins, cutoff), now)
+
+	opened := make([]*Incident, 0, 2)
+	if inc := p.track(ScopeChannel, key, join, len(p.channelJoins[key]), p.config.ChannelThreshold); inc != nil {
+		opened = append(opened, inc)
+	}
+	if inc := p.track(ScopeNetwork, "", join, len(p.networkJoins), p.config.NetworkThreshold); inc != nil {
+		opened = append(opened, inc)
+	}
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	for _, inc := range opened {
+		log.Printf("[join-flood] %s join flood started %s", inc.Scope, inc.Channel)
+		if webhook != "" {
+			go p.alert(webhook, inc.ID, inc.Scope, inc.Channel, inc.PeakRate)
+		}
+	}
+}
+
+// track adds a join to the open incident for key, opening one if the rate
+// crossed the threshold. It returns the incident if it was just opened.
+// Caller must hold p.mu.
+func (p *JoinFloodPlugin) track(scope, key string, join Join, rate, threshold int) *Incident {
+	openKey := scope + ":" + key
+	inc, ok := p.open[openKey]
+	if !ok {
+		if threshold <= 0 || rate < threshold {
+			return nil
+		}
+		inc = &Incident{
+			ID:      newID(),
+			Scope:   scope,
+			Start:   join.Time,
+			Open:    true,
+			Clients: make([]Join, 0),
+		}
+		if scope == ScopeChannel {
+			inc.Channel = join.Channel
+		}
+		p.open[openKey] = inc
+		p.incidents = append(p.incidents, inc)
+		if max := p.config.MaxIncidents; max > 0 && len(p.incidents) > max {
+			p.incidents = append([]*Incident(nil), p.incidents[len(p.incidents)-max:]...)
+		}
+		p.dirty = true
+		p.addJoin(inc, join, rate)
+		return inc
+	}
+	p.addJoin(inc, join, rate)
+	return nil
+}
+
+// addJoin records a join on an incident. Caller must hold p.mu.
+func (p *JoinFloodPlugin) addJoin(inc *Incident, join Join, rate int) {
+	inc.Joins++
+	inc.LastJoin = join.Time
+	if rate > inc.PeakRate {
+		inc.PeakRate = rate
+	}
+	if len(inc.Clients) < maxClientsPerIncident {
+		inc.Clients = append(inc.Clients, join)
+	}
+	p.dirty = true
+}
+
+// closeQuiet ends incidents that have had no joins for the cooldown period
+func (p *JoinFloodPlugin) closeQuiet(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cooldown := time.Duration(p.config.Cooldown) * time.Second
+	for key, inc := range p.open {
+		if now.Sub(inc.LastJoin) >= cooldown {
+			inc.Open = false
+			inc.End = inc.LastJoin
+			delete(p.open, key)
+			p.dirty = true
+		}
+	}
+
+	cutoff := now.Add(-time.Duration(p.config.Window) * time.Second)
+	for key, times := range p.channelJoins {
+		if times = prune(times, cutoff); len(times) == 0 {
+			delete(p.channelJoins, key)
+		} else {
+			p.channelJoins[key] = times
+		}
+	}
+	p.networkJoins = prune(p.networkJoins, cutoff)
+}
+
+// prune drops timestamps before cutoff from a sorted slice
+func prune(times []time.Time, cutoff time.Time) []time.Time {
+	i := sort.Search(len(times), func(i int) bool {
+		return times[i].After(cutoff)
+	})
+	return times[i:]
+}
+
+// alert delivers a new incident to the alert webhook
+func (p *JoinFloodPlugin) alert(url, id, scope, channel string, rate int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	p.mu.RLock()
+	window := p.config.Window
+	p.mu.RUnlock()
+
+	title := "Network-wide mass join"
+	if scope == ScopeChannel {
+		title = "Join flood in " + channel
+	}
+	err := notify.Send(ctx, url, notify.Event{
+		Source:    "join-flood",
+		Type:      "join_flood",
+		Severity:  "warning",
+		Title:     title,
+		Message:   fmt.Sprintf("%d joins in %d seconds", rate, window),
+		Timestamp: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"incident_id": id,
+			"scope":       scope,
+			"channel":     channel,
+			"rate":        rate,
+		},
+	})
+	if err != nil {
+		log.Printf("[join-flood] failed to send alert: %v", err)
+	}
+}
+
+// summarize describes the clients involved in an incident
+func summarize(inc *Incident) Summary {
+	ips := make(map[string]bool)
+	hosts := make(map[string]bool)
+	subnets := make(map[string]int)
+	s := Summary{TopSubnets: make(map[string]int)}
+
+	for _, j := range inc.Clients {
+		ips[j.IP] = true
+		hosts[strings.ToLower(j.Host)] = true
+		if j.Account != "" {
+			s.WithAccount++
+		}
+		if subnet := subnetOf(j.IP); subnet != "" {
+			subnets[subnet]++
+		}
+	}
+	s.DistinctIPs = len(ips)
+	s.DistinctHosts = len(hosts)
+
+	type kv struct {
+		k string
+		v int
+	}
+	sorted := make([]kv, 0, len(subnets))
+	for k, v := range subnets {
+		sorted = append(sorted, kv{k, v})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].v > sorted[j].v })
+	for i := 0; i < len(sorted) && i < 5; i++ {
+		s.TopSubnets[sorted[i].k] = sorted[i].v
+	}
+	return s
+}
+
+// subnetOf returns the /24 (IPv4) or /48 (IPv6) containing ip
+func subnetOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// findIncident returns an incident by ID. Caller must hold p.mu.
+func (p *JoinFloodPlugin) findIncident(id string) *Incident {
+	for _, inc := range p.incidents {
+		if inc.ID == id {
+			return inc
+		}
+	}
+	return nil
+}
+
+// handleStatus reports the state of the log stream
+func (p *JoinFloodPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"stream_connected": p.streamOK,
+		"stream_error":     p.streamError,
+		"open_incidents":   len(p.open),
+		"network_rate":     len(p.networkJoins),
+	})
+}
+
+// handleRates returns the current join rate of the busiest channels
+func (p *JoinFloodPlugin) handleRates(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	type rate struct {
+		Channel string `json:"channel"`
+		Joins   int    `json:"joins"`
+	}
+	rates := make([]rate, 0, len(p.channelJoins))
+	for key, times := range p.channelJoins {
+		rates = append(rates, rate{Channel: key, Joins: len(times)})
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Joins > rates[j].Joins })
+	if limit > 0 && len(rates) > limit {
+		rates = rates[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window":   p.config.Window,
+		"network":  len(p.networkJoins),
+		"channels": rates,
+	})
+}
+
+// handleListIncidents returns incidents without their client lists, newest first
+func (p *JoinFloodPlugin) handleListIncidents(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Incident, 0, len(p.incidents))
+	for i := len(p.incidents) - 1; i >= 0; i-- {
+		inc := *p.incidents[i]
+		inc.Clients = nil
+		list = append(list, inc)
+	}
+	c.JSON(http.StatusOK, gin.H{"incidents": list})
+}
+
+// handleGetIncident returns one incident with its clients and a summary
+func (p *JoinFloodPlugin) handleGetIncident(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	inc := p.findIncident(c.Param("id"))
+	if inc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"incident": inc,
+		"summary":  summarize(inc),
+	})
+}
+
+// handleIncidentHosts returns the distinct hosts of an incident as ban
+// masks, ready to be handed to a ban tool
+func (p *JoinFloodPlugin) handleIncidentHosts(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	inc := p.findIncident(c.Param("id"))
+	if inc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+
+	seen := make(map[string]bool)
+	masks := make([]string, 0)
+	for _, j := range inc.Clients {
+		if j.IP == "" || seen[j.IP] {
+			continue
+		}
+		seen[j.IP] = true
+		masks = append(masks, "*@"+j.IP)
+	}
+
+	if c.Query("format") == "text" {
+		c.String(http.StatusOK, strings.Join(masks, "\n")+"\n")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"incident_id": inc.ID, "masks": masks})
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleGetConfig returns the current configuration
+func (p *JoinFloodPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *JoinFloodPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *JoinFloodPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *JoinFloodPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,239 @@
+/**
+ * Keyword Monitor Frontend Script
+ *
+ * Manage blocklist rules and review matches.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'keyword-monitor';
+  const PLUGIN_NAME = 'Keyword Monitor';
+  const PAGE_PATH = '/plugins/keyword-monitor';
+  const API_BASE = '/api/plugin/keyword-monitor';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('keyword-monitor-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'keyword-monitor-styles';
+    style.textContent = `
+      .kwmon-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .kwmon-form { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .kwmon-form input, .kwmon-form select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.35rem 0.6rem;
+      }
+      .kwmon-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .kwmon-table { width: 100%; border-collapse: collapse; }
+      .kwmon-table th, .kwmon-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        overflow-wrap: anywhere;
+      }
+      .kwmon-sev-critical { color: var(--error, #f38ba8); }
+      .kwmon-sev-warning { color: var(--warning, #f9e2af); }
+      .kwmon-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadRules(container) {
+    const body = container.querySelector('#kwmon-rules');
+    try {
+      const data = await api('GET', '/rules');
+      if (data.rules.length === 0) {
+        body.innerHTML = '<p>No rules yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="kwmon-table">
+          <thead><tr><th>Pattern</th><th>Type</th><th>Field</th><th>Severity</th><th>Added by</th><th></th></tr></thead>
+          <tbody>
+            ${data.rules.map(r => `
+              <tr>
+                <td><code>${escapeHtml(r.pattern)}</code></td>
+                <td>${r.regex ? 'regex' : 'keyword'}</td>
+                <td>${escapeHtml(r.field)}</td>
+                <td class="kwmon-sev-${escapeHtml(r.severity)}">${escapeHtml(r.severity)}</td>
+                <td>${escapeHtml(r.added_by)}</td>
+                <td><button data-delete="${escapeHtml(r.id)}">Delete</button></td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+      body.querySelectorAll('button[data-delete]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          try {
+            await api('DELETE', `/rules/${btn.dataset.delete}`);
+            loadRules(container);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="kwmon-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadMatches(container) {
+    const body = container.querySelector('#kwmon-matches');
+    try {
+      const data = await api('GET', '/matches?limit=200');
+      body.innerHTML = `
+        ${data.last_error ? `<div class="kwmon-error">Last scan failed: ${escapeHtml(data.last_error)}</div>` : ''}
+        <table class="kwmon-table">
+          <thead><tr><th>Time</th><th>Channel</th><th>Field</th><th>Text</th><th>Rule</th></tr></thead>
+          <tbody>
+            ${data.matches.map(m => `
+              <tr class="kwmon-sev-${escapeHtml(m.severity)}">
+                <td>${escapeHtml(new Date(m.time).toLocaleString())}</td>
+                <td>${escapeHtml(m.channel)}</td>
+                <td>${escapeHtml(m.field)}</td>
+                <td>${escapeHtml(m.text)}</td>
+                <td><code>${escapeHtml(m.pattern)}</code></td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="kwmon-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="kwmon-app" data-plugin="${PLUGIN_ID}">
+        <h3>Rules</h3>
+        <form class="kwmon-form" id="kwmon-add">
+          <input name="pattern" placeholder="Keyword or regex" required>
+          <label><input type="checkbox" name="regex"> Regex</label>
+          <select name="field">
+            <option value="both">Name and topic</option>
+            <option value="name">Name only</option>
+            <option value="topic">Topic only</option>
+          </select>
+          <select name="severity">
+            <option value="warning">Warning</option>
+            <option value="critical">Critical</option>
+            <option value="info">Info</option>
+          </select>
+          <button type="submit">Add rule</button>
+        </form>
+        <div id="kwmon-rules">Loading...</div>
+        <h3>Matches</h3>
+        <div id="kwmon-matches">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#kwmon-add').addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const form = e.target;
+      try {
+        await api('POST', '/rules', {
+          pattern: form.pattern.value,
+          regex: form.regex.checked,
+          field: form.field.value,
+          severity: form.severity.value
+        });
+        form.reset();
+        loadRules(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    loadRules(container);
+    loadMatches(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('keyword-monitor-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
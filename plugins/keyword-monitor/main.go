@@ -0,0 +1,560 @@
+// Keyword Monitor Plugin for UnrealIRCd Web Panel
+// Scans channel names and topics against a keyword and regex blocklist
+// and alerts staff when matches appear
+
+package keywordmonitor
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Fields a rule can be applied to
+const (
+	FieldName  = "name"
+	FieldTopic = "topic"
+	FieldBoth  = "both"
+)
+
+// KeywordMonitorPlugin implements the Plugin interface
+type KeywordMonitorPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	rules     []*Rule
+	matches   []Match
+	active    map[string]string
+	dirty     bool
+	lastScan  time.Time
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL       string `json:"rpc_url"`
+	RPCUser      string `json:"rpc_user"`
+	RPCPassword  string `json:"rpc_password"`
+	RPCInsecure  bool   `json:"rpc_insecure"`
+	DataDir      string `json:"data_dir"`
+	ScanInterval int    `json:"scan_interval"`
+	AlertWebhook string `json:"alert_webhook"`
+	MaxMatches   int    `json:"max_matches"`
+}
+
+// Rule is a keyword or regular expression to look for
+type Rule struct {
+	ID       string    `json:"id"`
+	Pattern  string    `json:"pattern"`
+	Regex    bool      `json:"regex"`
+	Field    string    `json:"field"`
+	Severity string    `json:"severity"`
+	Note     string    `json:"note"`
+	AddedBy  string    `json:"added_by"`
+	AddedAt  time.Time `json:"added_at"`
+	re       *regexp.Regexp
+}
+
+// Match is a channel that matched a rule
+type Match struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Channel  string    `json:"channel"`
+	Field    string    `json:"field"`
+	Text     string    `json:"text"`
+	RuleID   string    `json:"rule_id"`
+	Pattern  string    `json:"pattern"`
+	Severity string    `json:"severity"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Rules   []*Rule `json:"rules"`
+	Matches []Match `json:"matches"`
+}
+
+// rpcChannel is the subset of the UnrealIRCd channel object we need
+type rpcChannel struct {
+	Name  string `json:"name"`
+	Topic string `json:"topic"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &KeywordMonitorPlugin{
+		config: Config{
+			RPCURL:       "https://127.0.0.1:8600/api",
+			DataDir:      "data/plugins/keyword-monitor",
+			ScanInterval: 60,
+			MaxMatches:   5000,
+		},
+		rules:   make([]*Rule, 0),
+		matches: make([]Match, 0),
+		active:  make(map[string]string),
+	}
+}
+
+// Info returns plugin metadata
+func (p *KeywordMonitorPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Keyword Monitor",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Alerts on channel names and topics matching a blocklist",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *KeywordMonitorPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[keyword-monitor] failed to load data: %v", err)
+	}
+	for _, r := range data.Rules {
+		if err := r.compile(); err != nil {
+			log.Printf("[keyword-monitor] skipping rule %s: %v", r.ID, err)
+			continue
+		}
+		p.rules = append(p.rules, r)
+	}
+	if data.Matches != nil {
+		p.matches = data.Matches
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "keyword-monitor-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		recent := 0
+		for _, m := range p.matches {
+			if m.Time.After(since) {
+				recent++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Keyword Matches",
+			Icon:  "AlertTriangle",
+			Content: map[string]interface{}{
+				"active":   len(p.active),
+				"last_24h": recent,
+				"rules":    len(p.rules),
+			},
+			Order: 35,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.scanLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *KeywordMonitorPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *KeywordMonitorPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/keyword-monitor")
+	{
+		plugin.GET("/rules", p.handleListRules)
+		plugin.POST("/rules", p.handleAddRule)
+		plugin.DELETE("/rules/:id", p.handleDeleteRule)
+		plugin.GET("/matches", p.handleListMatches)
+		plugin.POST("/scan", p.handleScan)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// compile prepares the rule for matching
+func (r *Rule) compile() error {
+	pattern := r.Pattern
+	if !r.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return err
+	}
+	r.re = re
+	return nil
+}
+
+// appliesTo reports whether the rule checks the given field
+func (r *Rule) appliesTo(field string) bool {
+	return r.Field == FieldBoth || r.Field == field
+}
+
+// storePath returns the location of the data file
+func (p *KeywordMonitorPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "keywords.json")
+}
+
+// save persists rules and matches if they changed
+func (p *KeywordMonitorPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Rules: p.rules, Matches: p.matches}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *KeywordMonitorPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// scanLoop scans channels until shutdown
+func (p *KeywordMonitorPlugin) scanLoop() {
+	defer p.wg.Done()
+
+	p.scan()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.ScanInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 15*time.Second {
+			interval = 15 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.scan()
+			if err := p.save(); err != nil {
+				log.Printf("[keyword-monitor] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// scan checks every channel name and topic against the rules. A match is
+// only reported once for as long as the matching text stays the same.
+func (p *KeywordMonitorPlugin) scan() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcChannel `json:"list"`
+	}
+	err := p.client().Call(ctx, "channel.list", map[string]interface{}{"object_detail_level": 1}, &result)
+
+	p.mu.Lock()
+	if err != nil {
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		return err
+	}
+	p.lastError = ""
+	p.lastScan = time.Now().UTC()
+
+	found := make([]Match, 0)
+	active := make(map[string]string)
+	for _, ch := range result.List {
+		for _, r := range p.rules {
+			for field, text := range map[string]string{FieldName: ch.Name, FieldTopic: ch.Topic} {
+				if text == "" || !r.appliesTo(field) || !r.re.MatchString(text) {
+					continue
+				}
+				key := strings.ToLower(ch.Name) + "\x00" + r.ID + "\x00" + field
+				active[key] = text
+				if p.active[key] == text {
+					continue
+				}
+				found = append(found, Match{
+					ID:       newID(),
+					Time:     p.lastScan,
+					Channel:  ch.Name,
+					Field:    field,
+					Text:     text,
+					RuleID:   r.ID,
+					Pattern:  r.Pattern,
+					Severity: r.Severity,
+				})
+			}
+		}
+	}
+	p.active = active
+
+	p.matches = append(p.matches, found...)
+	if max := p.config.MaxMatches; max > 0 && len(p.matches) > max {
+		p.matches = append([]Match(nil), p.matches[len(p.matches)-max:]...)
+	}
+	if len(found) > 0 {
+		p.dirty = true
+	}
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	for _, m := range found {
+		log.Printf("[keyword-monitor] %s %s matches %q", m.Channel, m.Field, m.Pattern)
+		if webhook != "" {
+			go p.alert(webhook, m)
+		}
+	}
+	return nil
+}
+
+// alert delivers a match to the alert webhook
+func (p *KeywordMonitorPlugin) alert(url string, m Match) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	err := notify.Send(ctx, url, notify.Event{
+		Source:    "keyword-monitor",
+		Type:      "keyword_match",
+		Severity:  m.Severity,
+		Title:     "Keyword match in " + m.Channel,
+		Message:   fmt.Sprintf("Channel %s of %s matches %q: %s", m.Field, m.Channel, m.Pattern, m.Text),
+		Timestamp: m.Time,
+		Data: map[string]interface{}{
+			"channel": m.Channel,
+			"field":   m.Field,
+			"text":    m.Text,
+			"rule_id": m.RuleID,
+			"pattern": m.Pattern,
+		},
+	})
+	if err != nil {
+		log.Printf("[keyword-monitor] failed to send alert: %v", err)
+	}
+}
+
+// handleListRules returns all rules
+func (p *KeywordMonitorPlugin) handleListRules(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"rules": p.rules})
+}
+
+// handleAddRule adds a keyword or regex rule
+func (p *KeywordMonitorPlugin) handleAddRule(c *gin.Context) {
+	var req struct {
+		Pattern  string `json:"pattern" binding:"required"`
+		Regex    bool   `json:"regex"`
+		Field    string `json:"field"`
+		Severity string `json:"severity"`
+		Note     string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pattern is required"})
+		return
+	}
+
+	rule := &Rule{
+		ID:       newID(),
+		Pattern:  req.Pattern,
+		Regex:    req.Regex,
+		Field:    req.Field,
+		Severity: req.Severity,
+		Note:     req.Note,
+		AddedBy:  access.Actor(c),
+		AddedAt:  time.Now().UTC(),
+	}
+	if rule.Field == "" {
+		rule.Field = FieldBoth
+	}
+	if rule.Severity == "" {
+		rule.Severity = "warning"
+	}
+	switch rule.Field {
+	case FieldName, FieldTopic, FieldBoth:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "field must be name, topic or both"})
+		return
+	}
+	switch rule.Severity {
+	case "info", "warning", "critical":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "severity must be info, warning or critical"})
+		return
+	}
+	if err := rule.compile(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid regular expression: " + err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	p.rules = append(p.rules, rule)
+	p.dirty = true
+	p.mu.Unlock()
+	p.save()
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// handleDeleteRule removes a rule
+func (p *KeywordMonitorPlugin) handleDeleteRule(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	found := false
+	for i, r := range p.rules {
+		if r.ID == id {
+			p.rules = append(p.rules[:i], p.rules[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if found {
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+	p.save()
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted"})
+}
+
+// handleListMatches returns the match history, newest first
+func (p *KeywordMonitorPlugin) handleListMatches(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	channel := c.Query("channel")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	matches := make([]Match, 0)
+	for i := len(p.matches) - 1; i >= 0 && len(matches) < limit; i-- {
+		if channel == "" || strings.EqualFold(p.matches[i].Channel, channel) {
+			matches = append(matches, p.matches[i])
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"matches":    matches,
+		"active":     len(p.active),
+		"last_scan":  p.lastScan,
+		"last_error": p.lastError,
+	})
+}
+
+// handleScan runs a scan immediately
+func (p *KeywordMonitorPlugin) handleScan(c *gin.Context) {
+	if err := p.scan(); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	p.save()
+	c.JSON(http.StatusOK, gin.H{"message": "Scan complete"})
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleGetConfig returns the current configuration
+func (p *KeywordMonitorPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *KeywordMonitorPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *KeywordMonitorPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *KeywordMonitorPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
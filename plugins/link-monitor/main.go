@@ -0,0 +1,570 @@
+// Link Monitor Plugin for UnrealIRCd Web Panel
+// Tracks server links: latency, uplinks, SendQ, user counts and link flaps,
+// with alerting when a server lags
+
+package linkmonitor
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Link event types
+const (
+	EventLinked   = "linked"
+	EventSplit    = "split"
+	EventRelinked = "uplink_changed"
+)
+
+// lagSamples is how many latency samples are kept per server
+const lagSamples = 120
+
+// LinkMonitorPlugin implements the Plugin interface
+type LinkMonitorPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	servers   map[string]*Server
+	events    []Event
+	seeded    bool
+	dirty     bool
+	lastPoll  time.Time
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL       string `json:"rpc_url"`
+	RPCUser      string `json:"rpc_user"`
+	RPCPassword  string `json:"rpc_password"`
+	RPCInsecure  bool   `json:"rpc_insecure"`
+	DataDir      string `json:"data_dir"`
+	PollInterval int    `json:"poll_interval"`
+	ProbeLatency bool   `json:"probe_latency"`
+	ProbeMethod  string `json:"probe_method"`
+	LagThreshold int    `json:"lag_threshold"`
+	AlertOnSplit bool   `json:"alert_on_split"`
+	AlertWebhook string `json:"alert_webhook"`
+	MaxEvents    int    `json:"max_events"`
+}
+
+// Server is the current state of one server
+type Server struct {
+	Name           string    `json:"name"`
+	Info           string    `json:"info"`
+	Uplink         string    `json:"uplink"`
+	Users          int       `json:"users"`
+	Synced         bool      `json:"synced"`
+	ULined         bool      `json:"ulined"`
+	Software       string    `json:"software"`
+	SendQ          *int64    `json:"sendq"`
+	ConnectedSince string    `json:"connected_since,omitempty"`
+	LagMS          int64     `json:"lag_ms"`
+	Lagging        bool      `json:"lagging"`
+	Linked         bool      `json:"linked"`
+	LastSeen       time.Time `json:"last_seen"`
+	Lag            []int64   `json:"-"`
+}
+
+// Event is a link state change
+type Event struct {
+	Time   time.Time `json:"time"`
+	Server string    `json:"server"`
+	Type   string    `json:"type"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Servers map[string]*Server `json:"servers"`
+	Events  []Event            `json:"events"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name           string `json:"name"`
+	ConnectedSince string `json:"connected_since"`
+	Server         struct {
+		Info     string `json:"info"`
+		Uplink   string `json:"uplink"`
+		NumUsers int    `json:"num_users"`
+		Synced   bool   `json:"synced"`
+		ULined   bool   `json:"ulined"`
+		SendQ    *int64 `json:"sendq"`
+		Features struct {
+			Software string `json:"software"`
+		} `json:"features"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &LinkMonitorPlugin{
+		config: Config{
+			RPCURL:       "https://127.0.0.1:8600/api",
+			DataDir:      "data/plugins/link-monitor",
+			PollInterval: 30,
+			ProbeLatency: true,
+			ProbeMethod:  "server.module_list",
+			LagThreshold: 5000,
+			AlertOnSplit: true,
+			MaxEvents:    2000,
+		},
+		servers: make(map[string]*Server),
+		events:  make([]Event, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *LinkMonitorPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Link Monitor",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Server link latency, uplinks, user counts and flaps",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *LinkMonitorPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[link-monitor] failed to load data: %v", err)
+	}
+	if data.Servers != nil {
+		p.servers = data.Servers
+		p.seeded = len(p.servers) > 0
+	}
+	if data.Events != nil {
+		p.events = data.Events
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "link-monitor-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		linked, lagging := 0, 0
+		for _, s := range p.servers {
+			if s.Linked {
+				linked++
+			}
+			if s.Lagging {
+				lagging++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Server Links",
+			Icon:  "Network",
+			Content: map[string]interface{}{
+				"linked":    linked,
+				"lagging":   lagging,
+				"flaps_24h": p.countSplits("", time.Now().Add(-24*time.Hour)),
+			},
+			Order: 30,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *LinkMonitorPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *LinkMonitorPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/link-monitor")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/servers/:name", p.handleServer)
+		plugin.GET("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *LinkMonitorPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "links.json")
+}
+
+// save persists servers and events if they changed
+func (p *LinkMonitorPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Servers: p.servers, Events: p.events}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *LinkMonitorPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop polls the server list until shutdown
+func (p *LinkMonitorPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.poll()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 10*time.Second {
+			interval = 10 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.poll()
+			if err := p.save(); err != nil {
+				log.Printf("[link-monitor] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// poll reads the server list, probes latency and records link changes
+func (p *LinkMonitorPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	rpc := p.client()
+	var result struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := rpc.Call(ctx, "server.list", nil, &result); err != nil {
+		p.mu.Lock()
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		return
+	}
+
+	p.mu.RLock()
+	probe := p.config.ProbeLatency
+	method := p.config.ProbeMethod
+	p.mu.RUnlock()
+
+	// Round trip of a call forwarded to each server over the links
+	lags := make(map[string]int64, len(result.List))
+	if probe {
+		for _, rs := range result.List {
+			start := time.Now()
+			err := rpc.Call(ctx, method, map[string]interface{}{"server": rs.Name}, nil)
+			if err != nil {
+				lags[rs.Name] = -1
+				continue
+			}
+			lags[rs.Name] = time.Since(start).Milliseconds()
+		}
+	}
+
+	p.mu.Lock()
+	now := time.Now().UTC()
+	p.lastPoll = now
+	p.lastError = ""
+
+	alerts := make([]notify.Event, 0)
+	present := make(map[string]bool, len(result.List))
+	for _, rs := range result.List {
+		key := strings.ToLower(rs.Name)
+		present[key] = true
+
+		s, ok := p.servers[key]
+		if !ok {
+			s = &Server{Name: rs.Name}
+			p.servers[key] = s
+		}
+		if p.seeded && !s.Linked {
+			p.addEvent(Event{Time: now, Server: rs.Name, Type: EventLinked, Detail: "via " + rs.Server.Uplink})
+		} else if p.seeded && s.Uplink != "" && !strings.EqualFold(s.Uplink, rs.Server.Uplink) {
+			p.addEvent(Event{Time: now, Server: rs.Name, Type: EventRelinked, Detail: s.Uplink + " -> " + rs.Server.Uplink})
+		}
+
+		s.Info = rs.Server.Info
+		s.Uplink = rs.Server.Uplink
+		s.Users = rs.Server.NumUsers
+		s.Synced = rs.Server.Synced
+		s.ULined = rs.Server.ULined
+		s.Software = rs.Server.Features.Software
+		s.SendQ = rs.Server.SendQ
+		s.ConnectedSince = rs.ConnectedSince
+		s.Linked = true
+		s.LastSeen = now
+
+		if lag, ok := lags[rs.Name]; ok {
+			s.LagMS = lag
+			s.Lag = append(s.Lag, lag)
+			if len(s.Lag) > lagSamples {
+				s.Lag = s.Lag[len(s.Lag)-lagSamples:]
+			}
+			lagging := p.config.LagThreshold > 0 && lag > int64(p.config.LagThreshold)
+			if lagging && !s.Lagging {
+				alerts = append(alerts, notify.Event{
+					Type:     "link_lag",
+					Severity: "warning",
+					Title:    "Server lagging: " + s.Name,
+					Message:  fmt.Sprintf("%s responded in %d ms (threshold %d ms)", s.Name, lag, p.config.LagThreshold),
+					Data:     map[string]interface{}{"server": s.Name, "lag_ms": lag, "uplink": s.Uplink},
+				})
+			}
+			s.Lagging = lagging
+		}
+	}
+
+	for key, s := range p.servers {
+		if present[key] || !s.Linked {
+			continue
+		}
+		s.Linked = false
+		s.Lagging = false
+		p.addEvent(Event{Time: now, Server: s.Name, Type: EventSplit, Detail: "was linked to " + s.Uplink})
+		if p.config.AlertOnSplit {
+			alerts = append(alerts, notify.Event{
+				Type:     "link_split",
+				Severity: "critical",
+				Title:    "Server split: " + s.Name,
+				Message:  fmt.Sprintf("%s is no longer linked (uplink was %s, %d users)", s.Name, s.Uplink, s.Users),
+				Data:     map[string]interface{}{"server": s.Name, "uplink": s.Uplink, "users": s.Users},
+			})
+		}
+	}
+	p.seeded = true
+	p.dirty = true
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	for _, a := range alerts {
+		log.Printf("[link-monitor] %s", a.Message)
+		if webhook != "" {
+			a.Source = "link-monitor"
+			a.Timestamp = now
+			go p.alert(webhook, a)
+		}
+	}
+}
+
+// addEvent records a link event, trimming the oldest. Caller must hold p.mu.
+func (p *LinkMonitorPlugin) addEvent(ev Event) {
+	p.events = append(p.events, ev)
+	if max := p.config.MaxEvents; max > 0 && len(p.events) > max {
+		p.events = append([]Event(nil), p.events[len(p.events)-max:]...)
+	}
+}
+
+// countSplits counts splits since a time, for one server or all of them if
+// name is empty. Caller must hold p.mu.
+func (p *LinkMonitorPlugin) countSplits(name string, since time.Time) int {
+	n := 0
+	for _, ev := range p.events {
+		if ev.Type == EventSplit && ev.Time.After(since) && (name == "" || strings.EqualFold(ev.Server, name)) {
+			n++
+		}
+	}
+	return n
+}
+
+// alert delivers an alert to the webhook
+func (p *LinkMonitorPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[link-monitor] failed to send alert: %v", err)
+	}
+}
+
+// serverStatus is a server with its flap counts
+type serverStatus struct {
+	*Server
+	Flaps24h int `json:"flaps_24h"`
+	Flaps7d  int `json:"flaps_7d"`
+}
+
+// status returns a server with its flap counts. Caller must hold p.mu.
+func (p *LinkMonitorPlugin) status(s *Server) serverStatus {
+	now := time.Now()
+	return serverStatus{
+		Server:   s,
+		Flaps24h: p.countSplits(s.Name, now.Add(-24*time.Hour)),
+		Flaps7d:  p.countSplits(s.Name, now.AddDate(0, 0, -7)),
+	}
+}
+
+// handleStatus returns all known servers, linked first
+func (p *LinkMonitorPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]serverStatus, 0, len(p.servers))
+	for _, s := range p.servers {
+		list = append(list, p.status(s))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Linked != list[j].Linked {
+			return list[i].Linked
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"servers":    list,
+		"last_poll":  p.lastPoll,
+		"last_error": p.lastError,
+	})
+}
+
+// handleServer returns one server with its recent latency samples and events
+func (p *LinkMonitorPlugin) handleServer(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s, ok := p.servers[strings.ToLower(c.Param("name"))]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+		return
+	}
+
+	events := make([]Event, 0)
+	for i := len(p.events) - 1; i >= 0 && len(events) < 50; i-- {
+		if strings.EqualFold(p.events[i].Server, s.Name) {
+			events = append(events, p.events[i])
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"server": p.status(s),
+		"lag":    s.Lag,
+		"events": events,
+	})
+}
+
+// handleEvents returns link events, newest first
+func (p *LinkMonitorPlugin) handleEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	server := c.Query("server")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	events := make([]Event, 0)
+	for i := len(p.events) - 1; i >= 0 && len(events) < limit; i-- {
+		if server == "" || strings.EqualFold(p.events[i].Server, server) {
+			events = append(events, p.events[i])
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// handleGetConfig returns the current configuration
+func (p *LinkMonitorPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *LinkMonitorPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	if newConfig.ProbeMethod == "" {
+		newConfig.ProbeMethod = p.config.ProbeMethod
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *LinkMonitorPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *LinkMonitorPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
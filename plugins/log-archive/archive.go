@@ -0,0 +1,426 @@
+package logarchive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// segmentTime names segments after the time they were started, so names
+// sort oldest first
+const segmentTime = "20060102-150405.000"
+
+// Entry is one archived IRCd log entry
+type Entry struct {
+	Time      time.Time       `json:"time"`
+	Level     string          `json:"level"`
+	Subsystem string          `json:"subsystem"`
+	EventID   string          `json:"event_id"`
+	Server    string          `json:"server,omitempty"`
+	Msg       string          `json:"msg"`
+	Details   json.RawMessage `json:"details,omitempty"`
+}
+
+// segment is one archive file, one entry per line, with a summary of what
+// is in it so searches can skip files that cannot match
+type segment struct {
+	Name       string         `json:"name"`
+	First      time.Time      `json:"first"`
+	Last       time.Time      `json:"last"`
+	Count      int            `json:"count"`
+	Size       int64          `json:"size"`
+	Compressed bool           `json:"compressed"`
+	Levels     map[string]int `json:"levels"`
+	Subsystems map[string]int `json:"subsystems"`
+	EventIDs   map[string]int `json:"event_ids"`
+	Servers    map[string]int `json:"servers"`
+}
+
+// segmentIndex is a segment summary plus its word index, which maps each
+// word to the positions of the entries containing it in ascending order
+type segmentIndex struct {
+	segment
+	Terms map[string][]int `json:"terms"`
+}
+
+// newSegmentIndex creates an empty index for a segment
+func newSegmentIndex(name string) *segmentIndex {
+	return &segmentIndex{
+		segment: segment{
+			Name:       name,
+			Levels:     make(map[string]int),
+			Subsystems: make(map[string]int),
+			EventIDs:   make(map[string]int),
+			Servers:    make(map[string]int),
+		},
+		Terms: make(map[string][]int),
+	}
+}
+
+// add indexes the entry at the next position of the segment
+func (ix *segmentIndex) add(e Entry) {
+	pos := ix.Count
+	ix.Count++
+	if ix.First.IsZero() || e.Time.Before(ix.First) {
+		ix.First = e.Time
+	}
+	if e.Time.After(ix.Last) {
+		ix.Last = e.Time
+	}
+	ix.Levels[e.Level]++
+	ix.Subsystems[e.Subsystem]++
+	ix.EventIDs[e.EventID]++
+	if e.Server != "" {
+		ix.Servers[e.Server]++
+	}
+	for term := range entryTerms(e) {
+		ix.Terms[term] = append(ix.Terms[term], pos)
+	}
+}
+
+// entryTerms returns the words of an entry's message and of the string
+// values in its details, such as nicks, hosts and IPs
+func entryTerms(e Entry) map[string]bool {
+	terms := make(map[string]bool)
+	for _, w := range words(e.Msg) {
+		terms[w] = true
+	}
+	if len(e.Details) > 0 {
+		var details interface{}
+		if json.Unmarshal(e.Details, &details) == nil {
+			detailWords(details, terms)
+		}
+	}
+	return terms
+}
+
+// detailWords adds the words of every string in a decoded JSON value
+func detailWords(v interface{}, terms map[string]bool) {
+	switch v := v.(type) {
+	case string:
+		for _, w := range words(v) {
+			terms[w] = true
+		}
+	case []interface{}:
+		for _, item := range v {
+			detailWords(item, terms)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			detailWords(item, terms)
+		}
+	}
+}
+
+// words splits text into lower case words of letters and digits. Single
+// characters and very long runs are not indexed.
+func words(s string) []string {
+	list := make([]string, 0)
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) >= 2 && len(w) <= 64 {
+			list = append(list, w)
+		}
+	}
+	return list
+}
+
+// archive is the set of segment files in a directory. New entries go to
+// the active segment, which is sealed when it rotates; sealed segments are
+// compressed afterwards by compress.
+type archive struct {
+	dir      string
+	segments []*segment
+	active   *segmentIndex
+	file     *os.File
+	opened   time.Time
+}
+
+// Segment file names
+func (a *archive) plainPath(name string) string {
+	return filepath.Join(a.dir, name+".jsonl")
+}
+
+func (a *archive) gzipPath(name string) string {
+	return filepath.Join(a.dir, name+".jsonl.gz")
+}
+
+func (a *archive) indexPath(name string) string {
+	return filepath.Join(a.dir, name+".idx.json")
+}
+
+// openArchive loads the segments in dir. Plain segments left behind by a
+// crash are indexed again and sealed; they are compressed later. Segments
+// that can't be read are left out of the archive but not deleted.
+func openArchive(dir string) (*archive, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	a := &archive{dir: dir, segments: make([]*segment, 0)}
+	for _, f := range files {
+		name := f.Name()
+		switch {
+		case strings.HasSuffix(name, ".tmp"):
+			os.Remove(filepath.Join(dir, name))
+		case strings.HasSuffix(name, ".jsonl.gz"):
+			s, err := a.load(strings.TrimSuffix(name, ".jsonl.gz"))
+			if err != nil {
+				log.Printf("[log-archive] skipping %v", err)
+				continue
+			}
+			a.segments = append(a.segments, s)
+		case strings.HasSuffix(name, ".jsonl"):
+			base := strings.TrimSuffix(name, ".jsonl")
+			if _, err := os.Stat(a.gzipPath(base)); err == nil {
+				// Compressed before the crash, not yet removed
+				os.Remove(filepath.Join(dir, name))
+				continue
+			}
+			s, err := a.reindex(base)
+			if err != nil {
+				log.Printf("[log-archive] skipping %v", err)
+				continue
+			}
+			a.segments = append(a.segments, s)
+		}
+	}
+	sort.Slice(a.segments, func(i, j int) bool { return a.segments[i].Name < a.segments[j].Name })
+	return a, nil
+}
+
+// load reads the summary of a compressed segment, rebuilding its index if
+// the index file is missing
+func (a *archive) load(name string) (*segment, error) {
+	ix, err := a.readIndex(name)
+	if errors.Is(err, os.ErrNotExist) {
+		if ix, err = a.buildIndex(name); err == nil {
+			err = storage.SaveJSON(a.indexPath(name), ix)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("segment %s: %v", name, err)
+	}
+	s := ix.segment
+	s.Compressed = true
+	if info, err := os.Stat(a.gzipPath(name)); err == nil {
+		s.Size = info.Size()
+	}
+	return &s, nil
+}
+
+// reindex indexes a plain segment and writes its index file
+func (a *archive) reindex(name string) (*segment, error) {
+	ix, err := a.buildIndex(name)
+	if err == nil {
+		err = storage.SaveJSON(a.indexPath(name), ix)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("segment %s: %v", name, err)
+	}
+	s := ix.segment
+	if info, err := os.Stat(a.plainPath(name)); err == nil {
+		s.Size = info.Size()
+	}
+	return &s, nil
+}
+
+// readIndex reads the index file of a segment
+func (a *archive) readIndex(name string) (*segmentIndex, error) {
+	data, err := os.ReadFile(a.indexPath(name))
+	if err != nil {
+		return nil, err
+	}
+	ix := newSegmentIndex(name)
+	if err := json.Unmarshal(data, ix); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// buildIndex indexes a segment by reading every entry in it
+func (a *archive) buildIndex(name string) (*segmentIndex, error) {
+	ix := newSegmentIndex(name)
+	err := a.scan(name, -1, func(pos int, e Entry, ok bool) bool {
+		if ok {
+			ix.add(e)
+		} else {
+			// Keep positions in step with lines
+			ix.Count++
+		}
+		return true
+	})
+	return ix, err
+}
+
+// scan reads the entries of a segment in order, calling fn with each
+// entry's position until fn returns false or limit lines were read (-1
+// for all). ok is false for lines that could not be decoded.
+func (a *archive) scan(name string, limit int, fn func(pos int, e Entry, ok bool) bool) error {
+	f, err := os.Open(a.gzipPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		f, err = os.Open(a.plainPath(name))
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(f.Name(), ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	br := bufio.NewReaderSize(r, 64*1024)
+	for pos := 0; limit < 0 || pos < limit; pos++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		var e Entry
+		ok := json.Unmarshal(line, &e) == nil
+		if !fn(pos, e, ok) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+	return nil
+}
+
+// append writes an entry to the active segment, starting one if needed
+func (a *archive) append(e Entry, now time.Time) error {
+	if a.active == nil {
+		if err := a.start(now); err != nil {
+			return err
+		}
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	n, err := a.file.Write(append(line, '\n'))
+	a.active.Size += int64(n)
+	if err != nil {
+		// A partial line would shift every position after it
+		a.seal()
+		return err
+	}
+	a.active.add(e)
+	return nil
+}
+
+// start opens a new active segment
+func (a *archive) start(now time.Time) error {
+	name := now.UTC().Format(segmentTime)
+	for a.exists(name) {
+		now = now.Add(time.Millisecond)
+		name = now.UTC().Format(segmentTime)
+	}
+	f, err := os.OpenFile(a.plainPath(name), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	a.active = newSegmentIndex(name)
+	a.file = f
+	a.opened = now
+	return nil
+}
+
+// exists reports whether a segment name is taken
+func (a *archive) exists(name string) bool {
+	if len(a.segments) > 0 && a.segments[len(a.segments)-1].Name >= name {
+		return true
+	}
+	_, err := os.Stat(a.plainPath(name))
+	return err == nil
+}
+
+// seal closes the active segment and writes its index file
+func (a *archive) seal() error {
+	if a.active == nil {
+		return nil
+	}
+	err := a.file.Close()
+	if ixErr := storage.SaveJSON(a.indexPath(a.active.Name), a.active); err == nil {
+		err = ixErr
+	}
+	s := a.active.segment
+	a.segments = append(a.segments, &s)
+	a.active = nil
+	a.file = nil
+	return err
+}
+
+// compress gzips a sealed segment. It only touches the segment's files, so
+// it can run without the plugin lock; searches read whichever file exists.
+func (a *archive) compress(name string) (int64, error) {
+	src, err := os.Open(a.plainPath(name))
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	tmp := a.gzipPath(name) + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return 0, err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, a.gzipPath(name))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	os.Remove(a.plainPath(name))
+
+	info, err := os.Stat(a.gzipPath(name))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// remove deletes a sealed segment's files
+func (a *archive) remove(name string) {
+	os.Remove(a.gzipPath(name))
+	os.Remove(a.plainPath(name))
+	os.Remove(a.indexPath(name))
+}
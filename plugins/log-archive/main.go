@@ -0,0 +1,555 @@
+// Log Archive Plugin for UnrealIRCd Web Panel
+// Keeps the IRCd's JSON log on disk in rotated, compressed segments and
+// indexes them so months of logs can be searched from the panel
+
+package logarchive
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// levelRank orders UnrealIRCd log levels, lowest first
+var levelRank = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+	"fatal": 4,
+}
+
+// LogArchivePlugin implements the Plugin interface
+type LogArchivePlugin struct {
+	config       Config
+	archive      *archive
+	written      int
+	writeErr     string
+	streamOK     bool
+	streamErr    string
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	StreamURL     string `json:"stream_url"`
+	LogSources    string `json:"log_sources"`
+	DataDir       string `json:"data_dir"`
+	RotateMinutes int    `json:"rotate_minutes"`
+	RotateSizeMB  int    `json:"rotate_size_mb"`
+	RetentionDays int    `json:"retention_days"`
+	MaxSizeMB     int    `json:"max_size_mb"`
+}
+
+// logSource holds the server that wrote a log entry
+type logSource struct {
+	LogSource string `json:"log_source"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &LogArchivePlugin{
+		config: Config{
+			StreamURL:     "wss://127.0.0.1:8600/",
+			LogSources:    "all,!debug",
+			DataDir:       "data/plugins/log-archive",
+			RotateMinutes: 60,
+			RotateSizeMB:  64,
+			RetentionDays: 90,
+			MaxSizeMB:     4096,
+		},
+	}
+}
+
+// Info returns plugin metadata
+func (p *LogArchivePlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Log Archive",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Searchable on-disk archive of the IRCd log",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *LogArchivePlugin) Init() error {
+	p.mu.Lock()
+	a, err := openArchive(p.config.DataDir)
+	if err != nil {
+		log.Printf("[log-archive] failed to open archive: %v", err)
+		a = &archive{dir: p.config.DataDir, segments: make([]*segment, 0)}
+	}
+	p.archive = a
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "log-archive-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"entries":  p.archivedCount(),
+			"size_mb":  p.archivedSize() >> 20,
+			"segments": len(p.archive.segments),
+		}
+		switch {
+		case p.writeErr != "":
+			content["status"] = "Archive not writable"
+		case !p.streamOK:
+			content["status"] = "Log stream disconnected"
+		}
+		return plugins.DashboardCard{
+			Title:   "Log Archive",
+			Icon:    "Archive",
+			Content: content,
+			Order:   75,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.maintainLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *LogArchivePlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.archive == nil {
+		return nil
+	}
+	return p.archive.seal()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *LogArchivePlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/log-archive")
+	{
+		plugin.GET("/search", p.handleSearch)
+		plugin.GET("/segments", p.handleSegments)
+		plugin.GET("/segments/:name/download", p.handleDownload)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// archivedCount returns the number of entries in the archive. Caller must
+// hold p.mu.
+func (p *LogArchivePlugin) archivedCount() int {
+	n := 0
+	for _, s := range p.archive.segments {
+		n += s.Count
+	}
+	if p.archive.active != nil {
+		n += p.archive.active.Count
+	}
+	return n
+}
+
+// archivedSize returns the size of the archive on disk in bytes. Caller
+// must hold p.mu.
+func (p *LogArchivePlugin) archivedSize() int64 {
+	var n int64
+	for _, s := range p.archive.segments {
+		n += s.Size
+	}
+	if p.archive.active != nil {
+		n += p.archive.active.Size
+	}
+	return n
+}
+
+// streamLoop runs the log stream until shutdown, restarting it when the
+// configuration changes
+func (p *LogArchivePlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *LogArchivePlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	p.streamErr = ""
+	if err != nil {
+		p.streamErr = err.Error()
+		log.Printf("[log-archive] log stream: %v", err)
+	}
+}
+
+// handleEvent appends a log entry to the archive
+func (p *LogArchivePlugin) handleEvent(ev jsonrpc.LogEvent) {
+	now := time.Now().UTC()
+	ts, err := time.Parse(time.RFC3339Nano, ev.Timestamp)
+	if err != nil {
+		ts = now
+	}
+	var src logSource
+	_ = json.Unmarshal(ev.Raw, &src)
+	e := Entry{
+		Time:      ts,
+		Level:     ev.Level,
+		Subsystem: ev.Subsystem,
+		EventID:   ev.EventID,
+		Server:    src.LogSource,
+		Msg:       ev.Msg,
+		Details:   ev.Raw,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.archive.append(e, now); err != nil {
+		// Log each new problem once rather than once per entry
+		if err.Error() != p.writeErr {
+			log.Printf("[log-archive] failed to write entry: %v", err)
+		}
+		p.writeErr = err.Error()
+		return
+	}
+	p.writeErr = ""
+	p.written++
+}
+
+// maintainLoop rotates, compresses and expires segments once a minute
+func (p *LogArchivePlugin) maintainLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		p.maintain(time.Now().UTC())
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// maintain seals the active segment when it is due to rotate, deletes
+// segments past the retention limits and compresses sealed segments. It is
+// only called from maintainLoop, so nothing else deletes or compresses
+// segments meanwhile.
+func (p *LogArchivePlugin) maintain(now time.Time) {
+	p.mu.Lock()
+	a := p.archive
+	if a.active != nil && (now.Sub(a.opened) >= time.Duration(p.config.RotateMinutes)*time.Minute ||
+		a.active.Size >= int64(p.config.RotateSizeMB)<<20) {
+		if err := a.seal(); err != nil {
+			log.Printf("[log-archive] failed to seal segment: %v", err)
+		}
+	}
+
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays)
+	size := p.archivedSize()
+	maxSize := int64(p.config.MaxSizeMB) << 20
+	expired := 0
+	for _, s := range a.segments {
+		if !s.Last.Before(cutoff) && (maxSize == 0 || size <= maxSize) {
+			break
+		}
+		a.remove(s.Name)
+		size -= s.Size
+		expired++
+	}
+	if expired > 0 {
+		a.segments = append(a.segments[:0:0], a.segments[expired:]...)
+		log.Printf("[log-archive] removed %d expired segments", expired)
+	}
+
+	pending := make([]string, 0)
+	for _, s := range a.segments {
+		if !s.Compressed {
+			pending = append(pending, s.Name)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, name := range pending {
+		size, err := a.compress(name)
+		if err != nil {
+			log.Printf("[log-archive] failed to compress segment %s: %v", name, err)
+			continue
+		}
+		p.mu.Lock()
+		for _, s := range a.segments {
+			if s.Name == name {
+				s.Compressed = true
+				s.Size = size
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// handleSearch searches the archive, newest entries first. Pass the id of
+// the last result as cursor to get the next page.
+func (p *LogArchivePlugin) handleSearch(c *gin.Context) {
+	q, err := parseQuery(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Take what the search needs from the active segment now; sealed
+	// segments don't change apart from being compressed or deleted
+	p.mu.RLock()
+	a := p.archive
+	segments := append([]*segment(nil), a.segments...)
+	var active *segment
+	var activeTerms map[string][]int
+	if a.active != nil && !q.skip(&a.active.segment) {
+		s := segment{Name: a.active.Name, Count: a.active.Count}
+		active = &s
+		activeTerms = make(map[string][]int)
+		for _, term := range q.terms {
+			activeTerms[term] = append([]int(nil), a.active.Terms[term]...)
+		}
+	}
+	p.mu.RUnlock()
+
+	results := make([]result, 0)
+	searched := 0
+	if active != nil {
+		found, err := a.searchSegment(q, active, active.Count, activeTerms, q.limit)
+		if err != nil {
+			log.Printf("[log-archive] failed to search segment %s: %v", active.Name, err)
+		}
+		results = append(results, found...)
+		searched++
+	}
+	for i := len(segments) - 1; i >= 0 && len(results) < q.limit; i-- {
+		s := segments[i]
+		if q.skip(s) {
+			continue
+		}
+		var terms map[string][]int
+		if len(q.terms) > 0 {
+			ix, err := a.readIndex(s.Name)
+			if errors.Is(err, os.ErrNotExist) {
+				// Expired since the search started
+				continue
+			}
+			if err != nil {
+				log.Printf("[log-archive] failed to read index of %s: %v", s.Name, err)
+				continue
+			}
+			terms = ix.Terms
+		}
+		found, err := a.searchSegment(q, s, s.Count, terms, q.limit-len(results))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[log-archive] failed to search segment %s: %v", s.Name, err)
+		}
+		results = append(results, found...)
+		searched++
+	}
+
+	// A full page may be followed by more; the next page says if not
+	resp := gin.H{"results": results, "segments_searched": searched}
+	if len(results) == q.limit {
+		resp["next_cursor"] = results[len(results)-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleSegments lists the segments, newest first
+func (p *LogArchivePlugin) handleSegments(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]gin.H, 0, len(p.archive.segments)+1)
+	if a := p.archive.active; a != nil {
+		list = append(list, gin.H{"name": a.Name, "first": a.First, "last": a.Last, "count": a.Count, "size": a.Size, "active": true})
+	}
+	for i := len(p.archive.segments) - 1; i >= 0; i-- {
+		s := p.archive.segments[i]
+		list = append(list, gin.H{
+			"name":       s.Name,
+			"first":      s.First,
+			"last":       s.Last,
+			"count":      s.Count,
+			"size":       s.Size,
+			"compressed": s.Compressed,
+			"levels":     s.Levels,
+			"servers":    s.Servers,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"segments": list})
+}
+
+// handleDownload sends a compressed segment as it is stored
+func (p *LogArchivePlugin) handleDownload(c *gin.Context) {
+	name := c.Param("name")
+
+	p.mu.RLock()
+	var found *segment
+	for _, s := range p.archive.segments {
+		if s.Name == name {
+			found = s
+		}
+	}
+	compressed := found != nil && found.Compressed
+	p.mu.RUnlock()
+
+	if found == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Segment not found"})
+		return
+	}
+	if !compressed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Segment is not compressed yet"})
+		return
+	}
+	c.FileAttachment(p.archive.gzipPath(name), "unrealircd-"+name+".jsonl.gz")
+}
+
+// handleStatus returns the state of the log stream and the archive
+func (p *LogArchivePlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"stream_ok":   p.streamOK,
+		"error":       p.streamErr,
+		"write_error": p.writeErr,
+		"written":     p.written,
+		"entries":     p.archivedCount(),
+		"size":        p.archivedSize(),
+		"segments":    len(p.archive.segments),
+	}
+	if len(p.archive.segments) > 0 {
+		status["oldest"] = p.archive.segments[0].First
+	}
+	if p.archive.active != nil {
+		status["active_segment"] = p.archive.active.Name
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *LogArchivePlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *LogArchivePlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.RotateMinutes < 5 || newConfig.RotateMinutes > 1440 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rotate_minutes must be between 5 and 1440"})
+		return
+	}
+	if newConfig.RotateSizeMB < 1 || newConfig.RotateSizeMB > 1024 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rotate_size_mb must be between 1 and 1024"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 3650 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 3650"})
+		return
+	}
+	if newConfig.MaxSizeMB < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_size_mb must not be negative"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *LogArchivePlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *LogArchivePlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
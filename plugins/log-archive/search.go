@@ -0,0 +1,205 @@
+package logarchive
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Search limits
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// query is a parsed search request. Empty fields match everything; terms
+// must all appear as words in an entry's message or details.
+type query struct {
+	from       time.Time
+	to         time.Time
+	levels     []string
+	subsystems []string
+	eventIDs   []string
+	servers    []string
+	terms      []string
+	limit      int
+	cursor     string
+	cursorPos  int
+}
+
+// result is a search hit. Its ID names the segment and position, and is
+// also the cursor for the next page.
+type result struct {
+	ID string `json:"id"`
+	Entry
+}
+
+// parseQuery reads a search from query parameters: from and to (RFC 3339),
+// level, subsystem, event_id and server (comma separated lists), q
+// (words), limit and cursor
+func parseQuery(get func(string) string) (query, error) {
+	q := query{limit: defaultLimit}
+	for _, t := range []struct {
+		param string
+		dst   *time.Time
+	}{{"from", &q.from}, {"to", &q.to}} {
+		if s := get(t.param); s != "" {
+			ts, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return q, fmt.Errorf("%s must be an RFC 3339 time", t.param)
+			}
+			*t.dst = ts
+		}
+	}
+	if !q.from.IsZero() && !q.to.IsZero() && q.to.Before(q.from) {
+		return q, fmt.Errorf("to must not be before from")
+	}
+	q.levels = lists.Split(get("level"))
+	for _, level := range q.levels {
+		if _, ok := levelRank[strings.ToLower(level)]; !ok {
+			return q, fmt.Errorf("level must be debug, info, warn, error or fatal")
+		}
+	}
+	q.subsystems = lists.Split(get("subsystem"))
+	q.eventIDs = lists.Split(get("event_id"))
+	q.servers = lists.Split(get("server"))
+	q.terms = words(get("q"))
+	if s := get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		q.limit = n
+	}
+	if s := get("cursor"); s != "" {
+		i := strings.LastIndex(s, ":")
+		pos, err := strconv.Atoi(s[i+1:])
+		if i < 1 || err != nil || pos < 0 {
+			return q, fmt.Errorf("cursor is not valid")
+		}
+		q.cursor, q.cursorPos = s[:i], pos
+	}
+	return q, nil
+}
+
+// skip reports whether a segment cannot hold any match, from its summary
+func (q query) skip(s *segment) bool {
+	if q.cursor != "" && s.Name > q.cursor {
+		return true
+	}
+	if s.Count == 0 || (!q.from.IsZero() && s.Last.Before(q.from)) || (!q.to.IsZero() && s.First.After(q.to)) {
+		return true
+	}
+	return !anyKey(s.Levels, q.levels) || !anyKey(s.Subsystems, q.subsystems) ||
+		!anyKey(s.EventIDs, q.eventIDs) || !anyKey(s.Servers, q.servers)
+}
+
+// match reports whether an entry passes the filters other than the words,
+// which the index has already checked
+func (q query) match(e Entry) bool {
+	if (!q.from.IsZero() && e.Time.Before(q.from)) || (!q.to.IsZero() && e.Time.After(q.to)) {
+		return false
+	}
+	return (len(q.levels) == 0 || lists.ContainsFold(q.levels, e.Level)) &&
+		(len(q.subsystems) == 0 || lists.ContainsFold(q.subsystems, e.Subsystem)) &&
+		(len(q.eventIDs) == 0 || lists.ContainsFold(q.eventIDs, e.EventID)) &&
+		(len(q.servers) == 0 || lists.ContainsFold(q.servers, e.Server))
+}
+
+// anyKey reports whether m has any of keys, ignoring case. No keys
+// matches anything.
+func anyKey(m map[string]int, keys []string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	for k := range m {
+		if lists.ContainsFold(keys, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the positions holding every search word, ascending,
+// or nil when the search has no words and every position is a candidate
+func (q query) candidates(terms map[string][]int) []int {
+	if len(q.terms) == 0 {
+		return nil
+	}
+	out := terms[q.terms[0]]
+	for _, term := range q.terms[1:] {
+		out = intersect(out, terms[term])
+	}
+	if out == nil {
+		out = make([]int, 0)
+	}
+	return out
+}
+
+// intersect returns the positions in both ascending lists
+func intersect(a, b []int) []int {
+	out := make([]int, 0)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// searchSegment returns the newest matches in a segment, newest first, up
+// to want of them. Only the first count lines are read, and in the
+// cursor's segment only those before it.
+func (a *archive) searchSegment(q query, s *segment, count int, terms map[string][]int, want int) ([]result, error) {
+	end := count
+	if s.Name == q.cursor && q.cursorPos < end {
+		end = q.cursorPos
+	}
+	cands := q.candidates(terms)
+	if cands != nil {
+		for len(cands) > 0 && cands[len(cands)-1] >= end {
+			cands = cands[:len(cands)-1]
+		}
+		if len(cands) == 0 {
+			return nil, nil
+		}
+		end = cands[len(cands)-1] + 1
+	}
+
+	// Keep a window of the newest matches so a broad search doesn't
+	// hold a whole segment in memory
+	found := make([]result, 0)
+	next := 0
+	err := a.scan(s.Name, end, func(pos int, e Entry, ok bool) bool {
+		if cands != nil {
+			if pos < cands[next] {
+				return true
+			}
+			next++
+		}
+		if ok && q.match(e) {
+			found = append(found, result{ID: fmt.Sprintf("%s:%d", s.Name, pos), Entry: e})
+			if len(found) >= 2*want {
+				found = append(found[:0], found[len(found)-want:]...)
+			}
+		}
+		return cands == nil || next < len(cands)
+	})
+	if len(found) > want {
+		found = found[len(found)-want:]
+	}
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found, err
+}
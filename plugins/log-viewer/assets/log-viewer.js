@@ -0,0 +1,306 @@
+/**
+ * Log Viewer Frontend Script
+ *
+ * Shows a live tail of the IRCd log on the plugin page, with filters by
+ * level, subsystem, server and text, and pausing.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'log-viewer';
+  const PLUGIN_NAME = 'Log Viewer';
+  const PAGE_PATH = '/plugins/log-viewer';
+  const API_BASE = '/api/plugin/log-viewer';
+  const MAX_LINES = 2000;
+
+  let controller = null;
+  let lastSeq = 0;
+  let paused = false;
+  let held = [];
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    return new Date(ts).toLocaleTimeString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('log-viewer-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'log-viewer-styles';
+    style.textContent = `
+      .lgv-app { display: flex; flex-direction: column; gap: 1rem; }
+      .lgv-toolbar { display: flex; gap: 0.5rem; flex-wrap: wrap; align-items: end; }
+      .lgv-toolbar label { display: flex; flex-direction: column; gap: 0.25rem; font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .lgv-toolbar input, .lgv-toolbar select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .lgv-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .lgv-btn.active { background: var(--warning, #f9e2af); color: #000; }
+      .lgv-state { font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .lgv-log {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        height: 60vh;
+        overflow-y: auto;
+        font-family: monospace;
+        font-size: 0.8rem;
+        padding: 0.5rem;
+      }
+      .lgv-line { white-space: pre-wrap; word-break: break-word; color: var(--text-secondary, #a6adc8); }
+      .lgv-line .time, .lgv-line .server { color: var(--text-muted, #6c7086); }
+      .lgv-line .source { color: var(--accent, #89b4fa); }
+      .lgv-line.warn .level { color: var(--warning, #f9e2af); }
+      .lgv-line.error .level, .lgv-line.fatal .level { color: var(--error, #f38ba8); }
+      .lgv-line.notice { color: var(--text-muted, #6c7086); font-style: italic; }
+      .lgv-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function filterQuery(form) {
+    const params = new URLSearchParams();
+    ['level', 'subsystem', 'server', 'q'].forEach(name => {
+      const value = form.elements[name].value.trim();
+      if (value) params.set(name, value);
+    });
+    return params;
+  }
+
+  function appendLines(log, html) {
+    const atBottom = log.scrollTop + log.clientHeight >= log.scrollHeight - 20;
+    log.insertAdjacentHTML('beforeend', html);
+    while (log.childElementCount > MAX_LINES) {
+      log.firstElementChild.remove();
+    }
+    if (atBottom) log.scrollTop = log.scrollHeight;
+  }
+
+  function renderEntry(e) {
+    return `<div class="lgv-line ${escapeHtml(e.level)}" title="${escapeHtml(e.details ? JSON.stringify(e.details) : '')}">` +
+      `<span class="time">${escapeHtml(formatTime(e.time))}</span> ` +
+      (e.server ? `<span class="server">${escapeHtml(e.server)}</span> ` : '') +
+      `<span class="level">${escapeHtml(e.level)}</span> ` +
+      `<span class="source">${escapeHtml(e.subsystem)}.${escapeHtml(e.event_id)}</span> ` +
+      `${escapeHtml(e.msg)}</div>`;
+  }
+
+  function notice(log, text) {
+    appendLines(log, `<div class="lgv-line notice">${escapeHtml(text)}</div>`);
+  }
+
+  function handleEvent(container, event, data, id) {
+    const log = container.querySelector('#lgv-log');
+    if (event === 'log') {
+      const entry = JSON.parse(data);
+      lastSeq = Number(id) || lastSeq;
+      if (paused) {
+        held.push(entry);
+        if (held.length > MAX_LINES) held.shift();
+        container.querySelector('#lgv-pause').textContent = `Resume (${held.length})`;
+      } else {
+        appendLines(log, renderEntry(entry));
+      }
+    } else if (event === 'dropped') {
+      notice(log, `${JSON.parse(data).count} entries skipped because the page fell behind`);
+    }
+  }
+
+  // The panel token lives in localStorage, which EventSource can't send,
+  // so the stream is read with fetch and the events are parsed here.
+  async function connect(container) {
+    if (controller) controller.abort();
+    controller = new AbortController();
+    const signal = controller.signal;
+    const form = container.querySelector('#lgv-filters');
+    const state = container.querySelector('#lgv-state');
+
+    while (!signal.aborted) {
+      const params = filterQuery(form);
+      const headers = getAuthHeaders();
+      if (lastSeq) {
+        headers['Last-Event-ID'] = String(lastSeq);
+      } else {
+        params.set('backlog', '200');
+      }
+
+      try {
+        const res = await fetch(`${API_BASE}/stream?${params}`, { headers, signal });
+        if (!res.ok) {
+          const data = await res.json().catch(() => ({}));
+          throw new Error(data.error || `Request failed with status ${res.status}`);
+        }
+        state.textContent = 'Live';
+        state.classList.remove('lgv-error');
+
+        const reader = res.body.getReader();
+        const decoder = new TextDecoder();
+        let buffer = '';
+        for (;;) {
+          const { value, done } = await reader.read();
+          if (done) break;
+          buffer += decoder.decode(value, { stream: true });
+          let end;
+          while ((end = buffer.indexOf('\n\n')) >= 0) {
+            const block = buffer.slice(0, end);
+            buffer = buffer.slice(end + 2);
+            let event = 'message', id = '', data = '';
+            block.split('\n').forEach(line => {
+              if (line.startsWith('event: ')) event = line.slice(7);
+              else if (line.startsWith('id: ')) id = line.slice(4);
+              else if (line.startsWith('data: ')) data += line.slice(6);
+            });
+            if (data) handleEvent(container, event, data, id);
+          }
+        }
+      } catch (e) {
+        if (signal.aborted) return;
+        state.textContent = e.message;
+        state.classList.add('lgv-error');
+      }
+      if (signal.aborted) return;
+      state.textContent = 'Reconnecting...';
+      await new Promise(resolve => setTimeout(resolve, 5000));
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="lgv-app" data-plugin="${PLUGIN_ID}">
+        <form class="lgv-toolbar" id="lgv-filters">
+          <label>Level
+            <select name="level">
+              ${['debug', 'info', 'warn', 'error', 'fatal'].map(l =>
+                `<option value="${l}"${l === 'info' ? ' selected' : ''}>${l} and up</option>`
+              ).join('')}
+            </select>
+          </label>
+          <label>Subsystems<input name="subsystem" placeholder="e.g. link,tkl"></label>
+          <label>Servers<input name="server" placeholder="All servers"></label>
+          <label>Search<input name="q" placeholder="Text in the message"></label>
+          <button class="lgv-btn" type="submit">Apply</button>
+          <button class="lgv-btn" type="button" id="lgv-pause">Pause</button>
+          <button class="lgv-btn" type="button" id="lgv-clear">Clear</button>
+          <span class="lgv-state" id="lgv-state">Connecting...</span>
+        </form>
+        <div class="lgv-log" id="lgv-log"></div>
+      </div>
+    `;
+
+    const log = container.querySelector('#lgv-log');
+
+    container.querySelector('#lgv-filters').addEventListener('submit', (e) => {
+      e.preventDefault();
+      lastSeq = 0;
+      held = [];
+      log.innerHTML = '';
+      connect(container);
+    });
+
+    container.querySelector('#lgv-pause').addEventListener('click', (e) => {
+      paused = !paused;
+      e.target.classList.toggle('active', paused);
+      e.target.textContent = paused ? 'Resume' : 'Pause';
+      if (!paused && held.length) {
+        appendLines(log, held.map(renderEntry).join(''));
+        held = [];
+      }
+    });
+
+    container.querySelector('#lgv-clear').addEventListener('click', () => {
+      log.innerHTML = '';
+      held = [];
+    });
+
+    lastSeq = 0;
+    paused = false;
+    held = [];
+    connect(container);
+    return true;
+  }
+
+  function disconnect() {
+    if (controller) {
+      controller.abort();
+      controller = null;
+    }
+  }
+
+  function cleanup() {
+    disconnect();
+    const style = document.getElementById('log-viewer-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection; the stream is closed when leaving the page
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      } else {
+        disconnect();
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,152 @@
+package logviewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// levelRank orders UnrealIRCd log levels, lowest first
+var levelRank = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+	"fatal": 4,
+}
+
+// Entry is one IRCd log entry as served to clients
+type Entry struct {
+	Seq       int64           `json:"seq"`
+	Time      time.Time       `json:"time"`
+	Level     string          `json:"level"`
+	Subsystem string          `json:"subsystem"`
+	EventID   string          `json:"event_id"`
+	Server    string          `json:"server,omitempty"`
+	Msg       string          `json:"msg"`
+	Details   json.RawMessage `json:"details,omitempty"`
+}
+
+// ring is a fixed-size buffer of the newest entries
+type ring struct {
+	entries []Entry
+	start   int
+	n       int
+}
+
+// newRing creates a buffer holding up to size entries
+func newRing(size int) *ring {
+	return &ring{entries: make([]Entry, size)}
+}
+
+// push adds an entry, overwriting the oldest when the buffer is full
+func (r *ring) push(e Entry) {
+	if len(r.entries) == 0 {
+		return
+	}
+	if r.n < len(r.entries) {
+		r.entries[(r.start+r.n)%len(r.entries)] = e
+		r.n++
+		return
+	}
+	r.entries[r.start] = e
+	r.start = (r.start + 1) % len(r.entries)
+}
+
+// at returns the i-th oldest entry
+func (r *ring) at(i int) Entry {
+	return r.entries[(r.start+i)%len(r.entries)]
+}
+
+// resize changes the capacity, keeping the newest entries
+func (r *ring) resize(size int) {
+	keep := r.n
+	if keep > size {
+		keep = size
+	}
+	entries := make([]Entry, size)
+	for i := 0; i < keep; i++ {
+		entries[i] = r.at(r.n - keep + i)
+	}
+	r.entries = entries
+	r.start = 0
+	r.n = keep
+}
+
+// last returns up to limit of the newest entries matching f with a
+// sequence number below before (0 for no bound) and above after, oldest
+// first, and whether older matches were left out
+func (r *ring) last(f filter, before, after int64, limit int) ([]Entry, bool) {
+	out := make([]Entry, 0)
+	for i := r.n - 1; i >= 0; i-- {
+		e := r.at(i)
+		if e.Seq <= after {
+			break
+		}
+		if (before > 0 && e.Seq >= before) || !f.match(e) {
+			continue
+		}
+		if len(out) == limit {
+			return reverse(out), true
+		}
+		out = append(out, e)
+	}
+	return reverse(out), false
+}
+
+// reverse reverses a list of entries in place
+func reverse(list []Entry) []Entry {
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+// filter selects entries by level, subsystem, event ID, server and text.
+// Empty fields match everything.
+type filter struct {
+	minLevel   int
+	subsystems []string
+	eventIDs   []string
+	servers    []string
+	text       string
+}
+
+// parseFilter reads a filter from query parameters: level (the lowest
+// level shown), subsystem, event_id and server (comma separated lists)
+// and q (text the message must contain)
+func parseFilter(get func(string) string) (filter, error) {
+	var f filter
+	if level := strings.ToLower(get("level")); level != "" {
+		rank, ok := levelRank[level]
+		if !ok {
+			return f, fmt.Errorf("level must be debug, info, warn, error or fatal")
+		}
+		f.minLevel = rank
+	}
+	f.subsystems = lists.Split(get("subsystem"))
+	f.eventIDs = lists.Split(get("event_id"))
+	f.servers = lists.Split(get("server"))
+	f.text = strings.ToLower(strings.TrimSpace(get("q")))
+	return f, nil
+}
+
+// match reports whether an entry passes the filter
+func (f filter) match(e Entry) bool {
+	if levelRank[e.Level] < f.minLevel {
+		return false
+	}
+	if len(f.subsystems) > 0 && !lists.ContainsFold(f.subsystems, e.Subsystem) {
+		return false
+	}
+	if len(f.eventIDs) > 0 && !lists.ContainsFold(f.eventIDs, e.EventID) {
+		return false
+	}
+	if len(f.servers) > 0 && !lists.ContainsFold(f.servers, e.Server) {
+		return false
+	}
+	return f.text == "" || strings.Contains(strings.ToLower(e.Msg), f.text)
+}
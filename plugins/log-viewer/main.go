@@ -0,0 +1,464 @@
+// Log Viewer Plugin for UnrealIRCd Web Panel
+// Follows the IRCd's JSON log over RPC and serves a filtered live tail and
+// scrollback, so operators no longer need a shell on the servers
+
+package logviewer
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Stream limits
+const (
+	clientBuffer   = 256
+	heartbeat      = 15 * time.Second
+	maxBacklog     = 1000
+	defaultBacklog = 100
+)
+
+// LogViewerPlugin implements the Plugin interface
+type LogViewerPlugin struct {
+	config       Config
+	buffer       *ring
+	seq          int64
+	received     int
+	clients      map[int]*client
+	nextClient   int
+	streamOK     bool
+	streamErr    string
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	StreamURL      string `json:"stream_url"`
+	LogSources     string `json:"log_sources"`
+	Scrollback     int    `json:"scrollback"`
+	MaxClients     int    `json:"max_clients"`
+	IncludeDetails bool   `json:"include_details"`
+}
+
+// client is a live tail connection. Entries that don't fit in its channel
+// are counted as dropped rather than holding up the log stream.
+type client struct {
+	ch      chan Entry
+	f       filter
+	dropped int
+}
+
+// logSource holds the server that wrote a log entry
+type logSource struct {
+	LogSource string `json:"log_source"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &LogViewerPlugin{
+		config: Config{
+			StreamURL:      "wss://127.0.0.1:8600/",
+			LogSources:     "all,!debug",
+			Scrollback:     5000,
+			MaxClients:     20,
+			IncludeDetails: true,
+		},
+		buffer:  newRing(5000),
+		clients: make(map[int]*client),
+	}
+}
+
+// Info returns plugin metadata
+func (p *LogViewerPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Log Viewer",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Live, filtered tail of the IRCd log with scrollback",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *LogViewerPlugin) Init() error {
+	p.mu.Lock()
+	p.buffer = newRing(p.config.Scrollback)
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "log-viewer-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"buffered": p.buffer.n,
+			"watching": len(p.clients),
+		}
+		if !p.streamOK {
+			content["status"] = "Log stream disconnected"
+		}
+		return plugins.DashboardCard{
+			Title:   "Log Viewer",
+			Icon:    "Terminal",
+			Content: content,
+			Order:   74,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.streamLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *LogViewerPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *LogViewerPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/log-viewer")
+	{
+		plugin.GET("/logs", p.handleLogs)
+		plugin.GET("/stream", p.handleStream)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// streamLoop runs the log stream until shutdown, restarting it when the
+// configuration changes
+func (p *LogViewerPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *LogViewerPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	p.streamErr = ""
+	if err != nil {
+		p.streamErr = err.Error()
+		log.Printf("[log-viewer] log stream: %v", err)
+	}
+}
+
+// handleEvent buffers a log entry and hands it to every live client whose
+// filter it passes
+func (p *LogViewerPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	ts, err := time.Parse(time.RFC3339Nano, ev.Timestamp)
+	if err != nil {
+		ts = time.Now().UTC()
+	}
+	var src logSource
+	_ = json.Unmarshal(ev.Raw, &src)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	p.received++
+	e := Entry{
+		Seq:       p.seq,
+		Time:      ts,
+		Level:     ev.Level,
+		Subsystem: ev.Subsystem,
+		EventID:   ev.EventID,
+		Server:    src.LogSource,
+		Msg:       ev.Msg,
+	}
+	if p.config.IncludeDetails {
+		e.Details = ev.Raw
+	}
+	p.buffer.push(e)
+
+	for _, cl := range p.clients {
+		if !cl.f.match(e) {
+			continue
+		}
+		select {
+		case cl.ch <- e:
+		default:
+			cl.dropped++
+		}
+	}
+}
+
+// queryInt reads a non-negative integer query parameter
+func queryInt(c *gin.Context, name string, def int64) (int64, error) {
+	s := c.Query(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return n, nil
+}
+
+// handleLogs returns buffered entries, oldest first. before pages back
+// through the scrollback: pass the seq of the oldest entry received.
+func (p *LogViewerPlugin) handleLogs(c *gin.Context) {
+	f, err := parseFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := queryInt(c, "limit", 200)
+	if err == nil && (limit < 1 || limit > maxBacklog) {
+		err = fmt.Errorf("limit must be between 1 and %d", maxBacklog)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	before, err := queryInt(c, "before", 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	entries, more := p.buffer.last(f, before, 0, int(limit))
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "has_more": more, "latest_seq": p.seq})
+}
+
+// handleStream serves the live tail as server-sent events. Each entry is
+// a "log" event whose id is its seq. A reconnecting client that sends
+// Last-Event-ID, or ?after=, first gets what it missed from the
+// scrollback; a new client gets the last backlog matching entries.
+func (p *LogViewerPlugin) handleStream(c *gin.Context) {
+	f, err := parseFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	backlog, err := queryInt(c, "backlog", defaultBacklog)
+	if err == nil && backlog > maxBacklog {
+		err = fmt.Errorf("backlog must be at most %d", maxBacklog)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	after := int64(-1)
+	resume := c.GetHeader("Last-Event-ID")
+	if resume == "" {
+		resume = c.Query("after")
+	}
+	if resume != "" {
+		if after, err = strconv.ParseInt(resume, 10, 64); err != nil || after < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+	}
+
+	p.mu.Lock()
+	if len(p.clients) >= p.config.MaxClients {
+		p.mu.Unlock()
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many live log viewers"})
+		return
+	}
+	var initial []Entry
+	if after >= 0 {
+		initial, _ = p.buffer.last(f, 0, after, p.buffer.n)
+	} else {
+		initial, _ = p.buffer.last(f, 0, 0, int(backlog))
+	}
+	cl := &client{ch: make(chan Entry, clientBuffer), f: f}
+	id := p.nextClient
+	p.nextClient++
+	p.clients[id] = cl
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.clients, id)
+		p.mu.Unlock()
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, e := range initial {
+		if writeEntry(c.Writer, e) != nil {
+			return
+		}
+	}
+	fmt.Fprint(c.Writer, "event: ready\ndata: {}\n\n")
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-p.stop:
+			return false
+		case e := <-cl.ch:
+			p.mu.Lock()
+			dropped := cl.dropped
+			cl.dropped = 0
+			p.mu.Unlock()
+			if dropped > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: {\"count\":%d}\n\n", dropped)
+			}
+			return writeEntry(w, e) == nil
+		case <-ticker.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// writeEntry writes an entry as a server-sent event
+func writeEntry(w io.Writer, e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", e.Seq, data)
+	return err
+}
+
+// handleStatus returns the state of the log stream and the buffer
+func (p *LogViewerPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"stream_ok":  p.streamOK,
+		"error":      p.streamErr,
+		"received":   p.received,
+		"buffered":   p.buffer.n,
+		"scrollback": p.config.Scrollback,
+		"latest_seq": p.seq,
+		"clients":    len(p.clients),
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *LogViewerPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *LogViewerPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.Scrollback < 100 || newConfig.Scrollback > 100000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scrollback must be between 100 and 100000"})
+		return
+	}
+	if newConfig.MaxClients < 1 || newConfig.MaxClients > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_clients must be between 1 and 200"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.Scrollback != p.config.Scrollback {
+		p.buffer.resize(newConfig.Scrollback)
+	}
+	p.config = newConfig
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *LogViewerPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *LogViewerPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,260 @@
+/**
+ * Maintenance Announcer Frontend Script
+ *
+ * Lists maintenance windows on the plugin page and lets staff schedule,
+ * cancel and end them.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'maintenance-announcer';
+  const PLUGIN_NAME = 'Maintenance Announcer';
+  const PAGE_PATH = '/plugins/maintenance-announcer';
+  const API_BASE = '/api/plugin/maintenance-announcer';
+
+  let currentStatus = '';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    return new Date(ts).toLocaleString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('maintenance-announcer-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'maintenance-announcer-styles';
+    style.textContent = `
+      .mwa-app { display: flex; flex-direction: column; gap: 1rem; }
+      .mwa-toolbar { display: flex; gap: 0.5rem; flex-wrap: wrap; align-items: center; }
+      .mwa-tab, .mwa-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .mwa-tab.active, .mwa-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .mwa-btn.danger { background: var(--error, #f38ba8); color: #fff; }
+      .mwa-form { display: grid; grid-template-columns: repeat(auto-fit, minmax(180px, 1fr)); gap: 0.5rem; align-items: end; }
+      .mwa-form label { display: flex; flex-direction: column; gap: 0.25rem; font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .mwa-form input, .mwa-form textarea {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .mwa-form .wide { grid-column: 1 / -1; }
+      .mwa-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .mwa-table th, .mwa-table td {
+        text-align: left;
+        padding: 0.5rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+        vertical-align: top;
+      }
+      .mwa-table th { color: var(--text-primary, #cdd6f4); }
+      .mwa-status { font-weight: 600; text-transform: capitalize; }
+      .mwa-status.active { color: var(--warning, #f9e2af); }
+      .mwa-status.upcoming { color: var(--accent, #89b4fa); }
+      .mwa-status.cancelled { color: var(--text-muted, #6c7086); }
+      .mwa-notices { font-size: 0.8rem; color: var(--text-muted, #6c7086); margin: 0.25rem 0 0 0; padding-left: 1rem; }
+      .mwa-error { color: var(--error, #f38ba8); }
+      .mwa-empty { color: var(--text-muted, #6c7086); padding: 1rem 0; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderRow(w) {
+    let actions = '';
+    if (w.status === 'active') {
+      actions = `<button class="mwa-btn" data-action="end" data-id="${escapeHtml(w.id)}">End now</button>`;
+    }
+    if (w.status === 'active' || w.status === 'upcoming') {
+      actions += ` <button class="mwa-btn danger" data-action="cancel" data-id="${escapeHtml(w.id)}">Cancel</button>`;
+    }
+    const notices = (w.notices || []).map(n =>
+      `<li>${escapeHtml(formatTime(n.time))} - ${escapeHtml(n.kind)} to ${n.recipients} users${n.error ? ` <span class="mwa-error">(${escapeHtml(n.error)})</span>` : ''}</li>`
+    ).join('');
+
+    return `
+      <tr>
+        <td><strong>${escapeHtml(w.title)}</strong><br><small>${escapeHtml(w.description)}</small></td>
+        <td>${escapeHtml(formatTime(w.start))}<br>${escapeHtml(formatTime(w.end))}</td>
+        <td>${w.servers && w.servers.length ? w.servers.map(escapeHtml).join('<br>') : 'All servers'}</td>
+        <td><span class="mwa-status ${escapeHtml(w.status)}">${escapeHtml(w.status)}</span>
+          ${w.suppressed_alerts ? `<br><small>${w.suppressed_alerts} alerts held back</small>` : ''}
+          <ul class="mwa-notices">${notices}</ul></td>
+        <td>${actions}</td>
+      </tr>
+    `;
+  }
+
+  async function loadWindows(container) {
+    const list = container.querySelector('#mwa-list');
+    list.innerHTML = '<div class="mwa-empty">Loading...</div>';
+
+    try {
+      const query = currentStatus ? `?status=${encodeURIComponent(currentStatus)}` : '';
+      const data = await api('GET', '/windows' + query);
+      if (!data.windows.length) {
+        list.innerHTML = '<div class="mwa-empty">No maintenance windows.</div>';
+        return;
+      }
+      list.innerHTML = `
+        <table class="mwa-table">
+          <thead><tr><th>Window</th><th>Start / End</th><th>Servers</th><th>Status</th><th></th></tr></thead>
+          <tbody>${data.windows.map(renderRow).join('')}</tbody>
+        </table>
+      `;
+    } catch (e) {
+      list.innerHTML = `<div class="mwa-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="mwa-app" data-plugin="${PLUGIN_ID}">
+        <form class="mwa-form" id="mwa-schedule">
+          <label>Title<input name="title" required></label>
+          <label>Start<input name="start" type="datetime-local" required></label>
+          <label>End<input name="end" type="datetime-local" required></label>
+          <label>Servers<input name="servers" placeholder="All servers"></label>
+          <label class="wide">Description<input name="description" placeholder="Shown to users in the notices"></label>
+          <label><span><input name="announce" type="checkbox" checked> Notice users</span></label>
+          <button class="mwa-btn primary" type="submit">Schedule</button>
+        </form>
+        <div class="mwa-toolbar">
+          ${['', 'upcoming', 'active', 'completed', 'cancelled'].map(s =>
+            `<button class="mwa-tab${s === currentStatus ? ' active' : ''}" data-status="${s}">${s || 'all'}</button>`
+          ).join('')}
+        </div>
+        <div id="mwa-list"></div>
+      </div>
+    `;
+
+    container.querySelector('#mwa-schedule').addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const form = e.target;
+      const f = form.elements;
+      try {
+        await api('POST', '/windows', {
+          title: f.title.value,
+          description: f.description.value,
+          servers: f.servers.value.split(/[,\s]+/).filter(Boolean),
+          start: new Date(f.start.value).toISOString(),
+          end: new Date(f.end.value).toISOString(),
+          announce: f.announce.checked
+        });
+        form.reset();
+        loadWindows(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    container.querySelectorAll('.mwa-tab').forEach(tab => {
+      tab.addEventListener('click', () => {
+        currentStatus = tab.dataset.status;
+        container.querySelectorAll('.mwa-tab').forEach(t => t.classList.toggle('active', t === tab));
+        loadWindows(container);
+      });
+    });
+
+    container.querySelector('#mwa-list').addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+
+      const question = btn.dataset.action === 'cancel' ? 'Cancel this maintenance window?' : 'End this maintenance window now?';
+      if (!confirm(question)) return;
+
+      try {
+        await api('POST', `/windows/${encodeURIComponent(btn.dataset.id)}/${btn.dataset.action}`);
+      } catch (err) {
+        alert(err.message);
+      }
+      loadWindows(container);
+    });
+
+    loadWindows(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('maintenance-announcer-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,154 @@
+package maintenanceannouncer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// calendarHistory is how long finished windows stay in the calendar
+const calendarHistory = 90 * 24 * time.Hour
+
+// runLength is how long a scheduled command run is shown for, matching
+// the scheduled-commands feed
+const runLength = 15 * time.Minute
+
+// icalTime is the UTC date-time format of iCalendar
+const icalTime = "20060102T150405Z"
+
+// icalEscaper escapes TEXT values as RFC 5545 requires
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// ScheduledRun is an upcoming run of a scheduled-commands job
+type ScheduledRun struct {
+	JobID  string    `json:"job_id"`
+	Name   string    `json:"name"`
+	Action string    `json:"action"`
+	Method string    `json:"method,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// loadScheduledRuns reads the upcoming runs the scheduled-commands plugin
+// publishes in its data directory, leaving out those already past. A
+// missing file gives none, so the calendar works without that plugin.
+func loadScheduledRuns(path string, now time.Time) ([]ScheduledRun, error) {
+	var data struct {
+		Occurrences []ScheduledRun `json:"occurrences"`
+	}
+	if err := storage.LoadJSON(path, &data); err != nil {
+		return nil, err
+	}
+	runs := make([]ScheduledRun, 0, len(data.Occurrences))
+	for _, r := range data.Occurrences {
+		if r.Time.After(now) {
+			runs = append(runs, r)
+		}
+	}
+	return runs, nil
+}
+
+// calendarWriter builds an iCalendar document with CRLF line endings and
+// long lines folded at 75 octets
+type calendarWriter struct {
+	b strings.Builder
+}
+
+// line writes one content line, folding it as needed. Folds never split a
+// UTF-8 sequence.
+func (cw *calendarWriter) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		cw.b.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts
+		limit = 74
+	}
+	cw.b.WriteString(s + "\r\n")
+}
+
+// calendar renders windows and scheduled command runs as an iCalendar
+// feed. Cancelled windows are kept with STATUS:CANCELLED so subscribed
+// calendars remove them, and every edit raises SEQUENCE so calendars pick
+// up new times. Runs are shown as free time, since they don't take the
+// network down.
+func calendar(name string, windows []*Window, runs []ScheduledRun, now time.Time) string {
+	var cw calendarWriter
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//ValwareIRC//Maintenance Announcer//EN")
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	cw.line("X-WR-CALNAME:" + icalEscaper.Replace(name))
+	cw.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	cw.line("X-PUBLISHED-TTL:PT1H")
+
+	for _, w := range windows {
+		if w.End.Before(now.Add(-calendarHistory)) {
+			continue
+		}
+		servers := "All servers"
+		if len(w.Servers) > 0 {
+			servers = strings.Join(w.Servers, ", ")
+		}
+		status := "CONFIRMED"
+		if w.Cancelled {
+			status = "CANCELLED"
+		}
+		modified := w.CreatedAt
+		for _, h := range w.History {
+			if h.Time.After(modified) {
+				modified = h.Time
+			}
+		}
+		sequence := len(w.History) - 1
+		if sequence < 0 {
+			sequence = 0
+		}
+
+		cw.line("BEGIN:VEVENT")
+		cw.line(fmt.Sprintf("UID:%s@maintenance-announcer", w.ID))
+		cw.line("DTSTAMP:" + now.UTC().Format(icalTime))
+		cw.line("DTSTART:" + w.Start.UTC().Format(icalTime))
+		cw.line("DTEND:" + w.End.UTC().Format(icalTime))
+		cw.line("CREATED:" + w.CreatedAt.UTC().Format(icalTime))
+		cw.line("LAST-MODIFIED:" + modified.UTC().Format(icalTime))
+		cw.line(fmt.Sprintf("SEQUENCE:%d", sequence))
+		cw.line("SUMMARY:" + icalEscaper.Replace("Maintenance: "+w.Title))
+		if w.Description != "" {
+			cw.line("DESCRIPTION:" + icalEscaper.Replace(w.Description))
+		}
+		cw.line("LOCATION:" + icalEscaper.Replace(servers))
+		cw.line("CATEGORIES:Maintenance")
+		cw.line("STATUS:" + status)
+		cw.line("TRANSP:OPAQUE")
+		cw.line("END:VEVENT")
+	}
+
+	for _, r := range runs {
+		what := r.Action
+		if r.Method != "" {
+			what += " " + r.Method
+		}
+		cw.line("BEGIN:VEVENT")
+		cw.line(fmt.Sprintf("UID:run-%s-%d@maintenance-announcer", r.JobID, r.Time.Unix()))
+		cw.line("DTSTAMP:" + now.UTC().Format(icalTime))
+		cw.line("DTSTART:" + r.Time.UTC().Format(icalTime))
+		cw.line("DTEND:" + r.Time.Add(runLength).UTC().Format(icalTime))
+		cw.line("SUMMARY:" + icalEscaper.Replace("Scheduled: "+r.Name))
+		cw.line("DESCRIPTION:" + icalEscaper.Replace(what))
+		cw.line("CATEGORIES:Scheduled command")
+		cw.line("STATUS:CONFIRMED")
+		cw.line("TRANSP:TRANSPARENT")
+		cw.line("END:VEVENT")
+	}
+
+	cw.line("END:VCALENDAR")
+	return cw.b.String()
+}
@@ -0,0 +1,1015 @@
+// Maintenance Announcer Plugin for UnrealIRCd Web Panel
+// Schedules maintenance windows, notices users ahead of them over JSON-RPC,
+// holds back monitoring alerts while they run and publishes the schedule
+
+package maintenanceannouncer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Window states
+const (
+	StatusUpcoming  = "upcoming"
+	StatusActive    = "active"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+)
+
+// maxSuppressed is the number of held back alerts that are kept
+const maxSuppressed = 500
+
+// MaintenanceAnnouncerPlugin implements the Plugin interface
+type MaintenanceAnnouncerPlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	windows    []*Window
+	suppressed []Suppressed
+	dirty      bool
+	kick       chan struct{}
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL            string `json:"rpc_url"`
+	RPCUser           string `json:"rpc_user"`
+	RPCPassword       string `json:"rpc_password"`
+	RPCInsecure       bool   `json:"rpc_insecure"`
+	DataDir           string `json:"data_dir"`
+	NoticeIntervals   string `json:"notice_intervals"`
+	NoticeTemplate    string `json:"notice_template"`
+	StartTemplate     string `json:"start_template"`
+	EndTemplate       string `json:"end_template"`
+	CancelTemplate    string `json:"cancel_template"`
+	RelayToken        string `json:"relay_token"`
+	ForwardWebhook    string `json:"forward_webhook"`
+	GraceMinutes      int    `json:"grace_minutes"`
+	CalendarName      string `json:"calendar_name"`
+	CalendarToken     string `json:"calendar_token"`
+	CalendarFeed      bool   `json:"calendar_feed"`
+	ScheduledRuns     bool   `json:"scheduled_runs"`
+	ScheduledRunsFile string `json:"scheduled_runs_file"`
+}
+
+// Window is a scheduled maintenance window
+type Window struct {
+	ID          string       `json:"id"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Servers     []string     `json:"servers"`
+	Start       time.Time    `json:"start"`
+	End         time.Time    `json:"end"`
+	Announce    bool         `json:"announce"`
+	Cancelled   bool         `json:"cancelled"`
+	CreatedBy   string       `json:"created_by"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Sent        []int        `json:"sent_intervals"`
+	Started     bool         `json:"started"`
+	Ended       bool         `json:"ended"`
+	Notices     []NoticeLog  `json:"notices"`
+	Status      string       `json:"status,omitempty"`
+	Suppressed  int          `json:"suppressed_alerts"`
+	History     []WindowEdit `json:"history"`
+}
+
+// NoticeLog records one round of notices sent for a window
+type NoticeLog struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	Text       string    `json:"text"`
+	Recipients int       `json:"recipients"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// WindowEdit records a change made to a window
+type WindowEdit struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+}
+
+// Suppressed is a monitoring alert held back during a maintenance window
+type Suppressed struct {
+	Time     time.Time    `json:"time"`
+	WindowID string       `json:"window_id"`
+	Alert    notify.Event `json:"alert"`
+}
+
+// WindowRequest is the body of create and update requests
+type WindowRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Servers     []string `json:"servers"`
+	Start       string   `json:"start"`
+	End         string   `json:"end"`
+	Announce    *bool    `json:"announce"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Windows    []*Window    `json:"windows"`
+	Suppressed []Suppressed `json:"suppressed"`
+}
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	Name string `json:"name"`
+	User struct {
+		Servername string `json:"servername"`
+	} `json:"user"`
+}
+
+// notice is a round of notices waiting to be sent
+type notice struct {
+	windowID string
+	kind     string
+	text     string
+	servers  []string
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &MaintenanceAnnouncerPlugin{
+		config: Config{
+			RPCURL:            "https://127.0.0.1:8600/api",
+			DataDir:           "data/plugins/maintenance-announcer",
+			NoticeIntervals:   "1440,60,15,5",
+			NoticeTemplate:    "Scheduled maintenance: {title} starts in {in} ({start} UTC) and should take about {duration}. {description}",
+			StartTemplate:     "Maintenance has started: {title}. It should be finished by {end} UTC.",
+			EndTemplate:       "Maintenance complete: {title}. Thank you for your patience.",
+			CancelTemplate:    "The maintenance announced for {start} UTC ({title}) has been cancelled.",
+			GraceMinutes:      10,
+			CalendarName:      "IRC network maintenance",
+			CalendarFeed:      true,
+			ScheduledRuns:     true,
+			ScheduledRunsFile: "data/plugins/scheduled-commands/upcoming.json",
+		},
+		windows:    make([]*Window, 0),
+		suppressed: make([]Suppressed, 0),
+		kick:       make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *MaintenanceAnnouncerPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Maintenance Announcer",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Schedule maintenance windows, notice users and hold back alerts",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *MaintenanceAnnouncerPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[maintenance-announcer] failed to load data: %v", err)
+	}
+	if data.Windows != nil {
+		p.windows = data.Windows
+	}
+	if data.Suppressed != nil {
+		p.suppressed = data.Suppressed
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "maintenance-announcer-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		now := time.Now().UTC()
+		content := map[string]interface{}{"active": 0, "upcoming": 0}
+		var next *Window
+		for _, w := range p.windows {
+			switch w.status(now) {
+			case StatusActive:
+				content["active"] = content["active"].(int) + 1
+			case StatusUpcoming:
+				content["upcoming"] = content["upcoming"].(int) + 1
+				if next == nil || w.Start.Before(next.Start) {
+					next = w
+				}
+			}
+		}
+		if next != nil {
+			content["next"] = next.Title
+			content["next_start"] = next.Start
+		}
+		return plugins.DashboardCard{
+			Title:   "Maintenance",
+			Icon:    "Wrench",
+			Content: content,
+			Order:   45,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.announceLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *MaintenanceAnnouncerPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *MaintenanceAnnouncerPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/maintenance-announcer")
+	{
+		plugin.GET("/windows", p.handleListWindows)
+		plugin.POST("/windows", p.handleCreateWindow)
+		plugin.GET("/windows/:id", p.handleGetWindow)
+		plugin.PUT("/windows/:id", p.handleUpdateWindow)
+		plugin.POST("/windows/:id/cancel", p.handleCancelWindow)
+		plugin.POST("/windows/:id/end", p.handleEndWindow)
+		plugin.GET("/schedule", p.handleSchedule)
+		plugin.GET("/calendar.ics", p.handleCalendar)
+		plugin.GET("/active", p.handleActive)
+		plugin.POST("/relay", p.handleRelay)
+		plugin.GET("/suppressed", p.handleSuppressed)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *MaintenanceAnnouncerPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "windows.json")
+}
+
+// save persists the state if it changed
+func (p *MaintenanceAnnouncerPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Windows: p.windows, Suppressed: p.suppressed}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *MaintenanceAnnouncerPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// status returns the state of the window at the given time
+func (w *Window) status(now time.Time) string {
+	switch {
+	case w.Cancelled:
+		return StatusCancelled
+	case now.Before(w.Start):
+		return StatusUpcoming
+	case now.Before(w.End):
+		return StatusActive
+	default:
+		return StatusCompleted
+	}
+}
+
+// covers reports whether the window applies to the named server
+func (w *Window) covers(server string) bool {
+	return coversServer(w.Servers, server)
+}
+
+// coversServer reports whether a server list includes the named server. An
+// empty list covers the whole network.
+func coversServer(servers []string, server string) bool {
+	if len(servers) == 0 {
+		return true
+	}
+	for _, s := range servers {
+		if strings.EqualFold(s, server) {
+			return true
+		}
+	}
+	return false
+}
+
+// view returns a copy of the window with its current status filled in
+func (w *Window) view(now time.Time) Window {
+	v := *w
+	v.Status = w.status(now)
+	return v
+}
+
+// announceLoop sends due notices until shutdown
+func (p *MaintenanceAnnouncerPlugin) announceLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		p.announce()
+		if err := p.save(); err != nil {
+			log.Printf("[maintenance-announcer] failed to save data: %v", err)
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		case <-p.kick:
+		}
+	}
+}
+
+// announce works out which notices are due and sends them
+func (p *MaintenanceAnnouncerPlugin) announce() {
+	now := time.Now().UTC()
+
+	p.mu.Lock()
+	cfg := p.config
+	intervals := parseIntervals(cfg.NoticeIntervals)
+	due := make([]notice, 0)
+	for _, w := range p.windows {
+		if w.Cancelled || w.Ended {
+			continue
+		}
+
+		switch w.status(now) {
+		case StatusUpcoming:
+			// When several reminders are due at once (e.g. a window was
+			// scheduled at short notice), only the latest one is sent
+			send := false
+			for _, m := range intervals {
+				if now.Before(w.Start.Add(-time.Duration(m)*time.Minute)) || containsInt(w.Sent, m) {
+					continue
+				}
+				w.Sent = append(w.Sent, m)
+				send = true
+				p.dirty = true
+			}
+			if send && w.Announce && cfg.NoticeTemplate != "" {
+				due = append(due, notice{windowID: w.ID, kind: "reminder", text: expand(cfg.NoticeTemplate, w, now), servers: w.Servers})
+			}
+		case StatusActive:
+			if !w.Started {
+				w.Started = true
+				p.dirty = true
+				if w.Announce && cfg.StartTemplate != "" {
+					due = append(due, notice{windowID: w.ID, kind: "start", text: expand(cfg.StartTemplate, w, now), servers: w.Servers})
+				}
+				log.Printf("[maintenance-announcer] window %q started", w.Title)
+			}
+		case StatusCompleted:
+			w.Ended = true
+			p.dirty = true
+			// Windows that ran entirely while the panel was down are not
+			// announced as complete
+			if w.Started && w.Announce && cfg.EndTemplate != "" {
+				due = append(due, notice{windowID: w.ID, kind: "end", text: expand(cfg.EndTemplate, w, now), servers: w.Servers})
+			}
+			log.Printf("[maintenance-announcer] window %q ended", w.Title)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, n := range due {
+		p.send(n)
+	}
+}
+
+// send notices every user on the affected servers and records the result
+func (p *MaintenanceAnnouncerPlugin) send(n notice) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	rpc := p.client()
+	entry := NoticeLog{Time: time.Now().UTC(), Kind: n.kind, Text: n.text}
+
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	if err := rpc.Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 1}, &result); err != nil {
+		entry.Error = err.Error()
+	} else {
+		failed := 0
+		for _, u := range result.List {
+			if len(n.servers) > 0 && !coversServer(n.servers, u.User.Servername) {
+				continue
+			}
+			params := map[string]interface{}{"nick": u.Name, "message": n.text}
+			var out json.RawMessage
+			if err := rpc.Call(ctx, "message.send_notice", params, &out); err != nil {
+				failed++
+				entry.Error = err.Error()
+				continue
+			}
+			entry.Recipients++
+		}
+		if failed > 0 {
+			entry.Error = fmt.Sprintf("%d notices failed, last error: %s", failed, entry.Error)
+		}
+	}
+
+	if entry.Error != "" {
+		log.Printf("[maintenance-announcer] %s notice: %s", n.kind, entry.Error)
+	}
+
+	p.mu.Lock()
+	if w := p.window(n.windowID); w != nil {
+		w.Notices = append(w.Notices, entry)
+		p.dirty = true
+	}
+	p.mu.Unlock()
+}
+
+// window finds a window by ID. Caller must hold p.mu.
+func (p *MaintenanceAnnouncerPlugin) window(id string) *Window {
+	for _, w := range p.windows {
+		if w.ID == id {
+			return w
+		}
+	}
+	return nil
+}
+
+// parseIntervals parses a comma separated list of minutes, largest first
+func parseIntervals(s string) []int {
+	out := make([]int, 0)
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if m, err := strconv.Atoi(f); err == nil && m > 0 && !containsInt(out, m) {
+			out = append(out, m)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(out)))
+	return out
+}
+
+// containsInt reports whether list contains v
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// expand fills in the placeholders of a notice template
+func expand(tmpl string, w *Window, now time.Time) string {
+	r := strings.NewReplacer(
+		"{title}", w.Title,
+		"{description}", w.Description,
+		"{start}", w.Start.Format("2006-01-02 15:04"),
+		"{end}", w.End.Format("2006-01-02 15:04"),
+		"{in}", formatDuration(w.Start.Sub(now)),
+		"{duration}", formatDuration(w.End.Sub(w.Start)),
+		"{servers}", serverList(w.Servers),
+	)
+	return strings.TrimSpace(r.Replace(tmpl))
+}
+
+// serverList describes the servers a window covers
+func serverList(servers []string) string {
+	if len(servers) == 0 {
+		return "all servers"
+	}
+	return strings.Join(servers, ", ")
+}
+
+// formatDuration renders a duration as e.g. "1d 2h" or "15m"
+func formatDuration(d time.Duration) string {
+	mins := int(d.Round(time.Minute).Minutes())
+	if mins < 1 {
+		return "less than a minute"
+	}
+	days, hours, mins := mins/1440, mins%1440/60, mins%60
+	parts := make([]string, 0, 3)
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if mins > 0 && days == 0 {
+		parts = append(parts, fmt.Sprintf("%dm", mins))
+	}
+	return strings.Join(parts, " ")
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// apply validates a request and copies it onto the window
+func (req *WindowRequest) apply(w *Window) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return fmt.Errorf("title is required")
+	}
+	start, err := time.Parse(time.RFC3339, req.Start)
+	if err != nil {
+		return fmt.Errorf("start must be an RFC 3339 timestamp")
+	}
+	end, err := time.Parse(time.RFC3339, req.End)
+	if err != nil {
+		return fmt.Errorf("end must be an RFC 3339 timestamp")
+	}
+	if !end.After(start) {
+		return fmt.Errorf("end must be after start")
+	}
+
+	servers := make([]string, 0, len(req.Servers))
+	for _, s := range req.Servers {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+
+	w.Title = title
+	w.Description = strings.TrimSpace(req.Description)
+	w.Servers = servers
+	w.Start = start.UTC()
+	w.End = end.UTC()
+	if req.Announce != nil {
+		w.Announce = *req.Announce
+	}
+	return nil
+}
+
+// handleListWindows returns windows, optionally filtered by status
+func (p *MaintenanceAnnouncerPlugin) handleListWindows(c *gin.Context) {
+	status := c.Query("status")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now().UTC()
+	list := make([]Window, 0)
+	for _, w := range p.windows {
+		v := w.view(now)
+		if status == "" || v.Status == status {
+			list = append(list, v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Start.After(list[j].Start) })
+	c.JSON(http.StatusOK, gin.H{"windows": list})
+}
+
+// handleCreateWindow schedules a new window
+func (p *MaintenanceAnnouncerPlugin) handleCreateWindow(c *gin.Context) {
+	var req WindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	now := time.Now().UTC()
+	w := &Window{
+		ID:        newID(),
+		Announce:  true,
+		CreatedBy: access.Actor(c),
+		CreatedAt: now,
+		Sent:      make([]int, 0),
+		Notices:   make([]NoticeLog, 0),
+		History:   []WindowEdit{{Time: now, Actor: access.Actor(c), Action: "created"}},
+	}
+	if err := req.apply(w); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !w.End.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Window has already ended"})
+		return
+	}
+
+	p.mu.Lock()
+	p.windows = append(p.windows, w)
+	p.dirty = true
+	v := w.view(now)
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[maintenance-announcer] failed to save data: %v", err)
+	}
+	log.Printf("[maintenance-announcer] %s scheduled %q for %s", w.CreatedBy, w.Title, w.Start.Format(time.RFC3339))
+	c.JSON(http.StatusCreated, v)
+}
+
+// handleGetWindow returns a single window with its notice log
+func (p *MaintenanceAnnouncerPlugin) handleGetWindow(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	w := p.window(c.Param("id"))
+	if w == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Window not found"})
+		return
+	}
+	c.JSON(http.StatusOK, w.view(time.Now().UTC()))
+}
+
+// handleUpdateWindow changes a window that has not finished yet
+func (p *MaintenanceAnnouncerPlugin) handleUpdateWindow(c *gin.Context) {
+	var req WindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	now := time.Now().UTC()
+	w := p.window(c.Param("id"))
+	if w == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Window not found"})
+		return
+	}
+	if s := w.status(now); s == StatusCompleted || s == StatusCancelled {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Window is " + s})
+		return
+	}
+
+	updated := *w
+	if err := req.apply(&updated); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Moving the start means the reminders have to go out again
+	if !updated.Start.Equal(w.Start) {
+		updated.Sent = make([]int, 0)
+		if updated.Start.After(now) {
+			updated.Started = false
+		}
+	}
+	updated.History = append(updated.History, WindowEdit{Time: now, Actor: access.Actor(c), Action: "updated"})
+	*w = updated
+	p.dirty = true
+	v := w.view(now)
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[maintenance-announcer] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// handleCancelWindow cancels a window, telling users if they were already
+// told about it
+func (p *MaintenanceAnnouncerPlugin) handleCancelWindow(c *gin.Context) {
+	p.mu.Lock()
+	now := time.Now().UTC()
+	w := p.window(c.Param("id"))
+	if w == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Window not found"})
+		return
+	}
+	if s := w.status(now); s == StatusCompleted || s == StatusCancelled {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Window is " + s})
+		return
+	}
+
+	w.Cancelled = true
+	w.History = append(w.History, WindowEdit{Time: now, Actor: access.Actor(c), Action: "cancelled"})
+	p.dirty = true
+	var n *notice
+	if w.Announce && len(w.Notices) > 0 && p.config.CancelTemplate != "" {
+		n = &notice{windowID: w.ID, kind: "cancel", text: expand(p.config.CancelTemplate, w, now), servers: w.Servers}
+	}
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[maintenance-announcer] failed to save data: %v", err)
+	}
+	if n != nil {
+		go p.send(*n)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Window cancelled"})
+}
+
+// handleEndWindow finishes an active window early
+func (p *MaintenanceAnnouncerPlugin) handleEndWindow(c *gin.Context) {
+	p.mu.Lock()
+	now := time.Now().UTC()
+	w := p.window(c.Param("id"))
+	if w == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Window not found"})
+		return
+	}
+	if w.status(now) != StatusActive {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Window is not active"})
+		return
+	}
+	w.End = now
+	w.History = append(w.History, WindowEdit{Time: now, Actor: access.Actor(c), Action: "ended early"})
+	p.dirty = true
+	p.mu.Unlock()
+
+	// Let the loop send the completion notice
+	select {
+	case p.kick <- struct{}{}:
+	default:
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Window ended"})
+}
+
+// handleSchedule returns active and upcoming windows for status pages,
+// without notice logs or staff names
+func (p *MaintenanceAnnouncerPlugin) handleSchedule(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now().UTC()
+	list := make([]gin.H, 0)
+	for _, w := range p.windows {
+		status := w.status(now)
+		if status != StatusActive && status != StatusUpcoming {
+			continue
+		}
+		list = append(list, gin.H{
+			"id":          w.ID,
+			"title":       w.Title,
+			"description": w.Description,
+			"servers":     w.Servers,
+			"start":       w.Start,
+			"end":         w.End,
+			"status":      status,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i]["start"].(time.Time).Before(list[j]["start"].(time.Time)) })
+	c.JSON(http.StatusOK, gin.H{"generated": now, "windows": list})
+}
+
+// handleCalendar serves the windows as an iCalendar feed staff can
+// subscribe to, along with the upcoming runs of scheduled commands. Like
+// the schedule it leaves out staff names and notice logs.
+func (p *MaintenanceAnnouncerPlugin) handleCalendar(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	token := p.config.CalendarToken
+	given := c.Query("token")
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		given = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if !p.config.CalendarFeed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed is turned off"})
+		return
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid calendar token"})
+		return
+	}
+
+	now := time.Now().UTC()
+	var runs []ScheduledRun
+	if p.config.ScheduledRuns && p.config.ScheduledRunsFile != "" {
+		var err error
+		if runs, err = loadScheduledRuns(p.config.ScheduledRunsFile, now); err != nil {
+			log.Printf("[maintenance-announcer] failed to read scheduled runs: %v", err)
+		}
+	}
+	ics := calendar(p.config.CalendarName, p.windows, runs, now)
+	c.Header("Content-Disposition", `inline; filename="maintenance.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(ics))
+}
+
+// suppressingWindow returns the window holding back alerts for a server, if
+// any. Alerts that don't name a server are only held back by network-wide
+// windows. The grace period keeps alerts quiet while servers relink after a
+// window. Caller must hold p.mu.
+func (p *MaintenanceAnnouncerPlugin) suppressingWindow(server string, now time.Time) *Window {
+	grace := time.Duration(p.config.GraceMinutes) * time.Minute
+	for _, w := range p.windows {
+		if w.Cancelled || now.Before(w.Start) || !now.Before(w.End.Add(grace)) {
+			continue
+		}
+		if len(w.Servers) == 0 || (server != "" && w.covers(server)) {
+			return w
+		}
+	}
+	return nil
+}
+
+// handleActive returns the windows in progress, optionally only those
+// covering one server
+func (p *MaintenanceAnnouncerPlugin) handleActive(c *gin.Context) {
+	server := c.Query("server")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now().UTC()
+	list := make([]Window, 0)
+	for _, w := range p.windows {
+		if w.status(now) == StatusActive && (server == "" || w.covers(server)) {
+			list = append(list, w.view(now))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"active": len(list) > 0, "windows": list})
+}
+
+// alertServer works out which server an alert is about
+func alertServer(ev notify.Event) string {
+	for _, key := range []string{"server", "uplink"} {
+		if s, ok := ev.Data[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// handleRelay receives alerts from monitoring plugins, holds them back
+// during maintenance and forwards the rest
+func (p *MaintenanceAnnouncerPlugin) handleRelay(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.RelayToken
+	forward := p.config.ForwardWebhook
+	p.mu.RUnlock()
+
+	given := c.GetHeader("X-Relay-Token")
+	if given == "" {
+		given = c.Query("token")
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid relay token"})
+		return
+	}
+
+	var ev notify.Event
+	if err := c.ShouldBindJSON(&ev); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert"})
+		return
+	}
+
+	now := time.Now().UTC()
+	server := alertServer(ev)
+
+	p.mu.Lock()
+	w := p.suppressingWindow(server, now)
+	if w != nil {
+		w.Suppressed++
+		p.suppressed = append(p.suppressed, Suppressed{Time: now, WindowID: w.ID, Alert: ev})
+		if len(p.suppressed) > maxSuppressed {
+			p.suppressed = append([]Suppressed(nil), p.suppressed[len(p.suppressed)-maxSuppressed:]...)
+		}
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if w != nil {
+		c.JSON(http.StatusAccepted, gin.H{"suppressed": true, "window": w.ID})
+		return
+	}
+	if forward != "" {
+		go p.forward(forward, ev)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"suppressed": false})
+}
+
+// forward passes an alert on to the real webhook
+func (p *MaintenanceAnnouncerPlugin) forward(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[maintenance-announcer] failed to forward alert: %v", err)
+	}
+}
+
+// handleSuppressed returns held back alerts, newest first
+func (p *MaintenanceAnnouncerPlugin) handleSuppressed(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	window := c.Query("window")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Suppressed, 0)
+	for i := len(p.suppressed) - 1; i >= 0 && len(list) < limit; i-- {
+		if window == "" || p.suppressed[i].WindowID == window {
+			list = append(list, p.suppressed[i])
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"suppressed": list})
+}
+
+// handleGetConfig returns the current configuration
+func (p *MaintenanceAnnouncerPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.RelayToken = ""
+	cfg.CalendarToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *MaintenanceAnnouncerPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.RelayToken == "" {
+		newConfig.RelayToken = p.config.RelayToken
+	}
+	if newConfig.CalendarToken == "" {
+		newConfig.CalendarToken = p.config.CalendarToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	if strings.TrimSpace(newConfig.CalendarName) == "" {
+		newConfig.CalendarName = "IRC network maintenance"
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *MaintenanceAnnouncerPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *MaintenanceAnnouncerPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
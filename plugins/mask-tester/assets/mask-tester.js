@@ -0,0 +1,298 @@
+/**
+ * Mask Tester Frontend Script
+ *
+ * A sandbox for ban masks and spamfilter patterns: try them against sample
+ * users and text, and see the warnings before setting them.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'mask-tester';
+  const PLUGIN_NAME = 'Mask Tester';
+  const PAGE_PATH = '/plugins/mask-tester';
+  const API_BASE = '/api/plugin/mask-tester';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('mask-tester-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'mask-tester-styles';
+    style.textContent = `
+      .mkt-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .mkt-card { background: var(--bg-secondary, #181825); border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 1rem; }
+      .mkt-card h3 { margin: 0 0 0.75rem; color: var(--text-primary, #cdd6f4); font-size: 1rem; }
+      .mkt-form { display: grid; grid-template-columns: 10rem 1fr; gap: 0.5rem; }
+      .mkt-form label { display: flex; flex-direction: column; gap: 0.2rem; font-size: 0.8rem; }
+      .mkt-form .mkt-wide { grid-column: 1 / -1; }
+      .mkt-form input, .mkt-form textarea, .mkt-form select {
+        background: var(--bg-primary, #11111b);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.5rem;
+        font-family: monospace;
+      }
+      .mkt-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.4rem 0.9rem;
+        cursor: pointer;
+      }
+      .mkt-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .mkt-result { margin-top: 0.75rem; display: flex; flex-direction: column; gap: 0.5rem; }
+      .mkt-findings { margin: 0; padding-left: 1.2rem; }
+      .mkt-findings li { margin-bottom: 0.25rem; }
+      .mkt-danger { color: var(--error, #f38ba8); }
+      .mkt-warning { color: var(--warning, #f9e2af); }
+      .mkt-info { color: var(--text-muted, #6c7086); }
+      .mkt-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .mkt-table th, .mkt-table td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .mkt-mono { font-family: monospace; word-break: break-all; white-space: pre-wrap; }
+      .mkt-mono mark { background: var(--accent, #89b4fa); color: #fff; border-radius: 2px; }
+      .mkt-yes { color: var(--success, #a6e3a1); font-weight: 600; }
+      .mkt-no { color: var(--text-muted, #6c7086); }
+      .mkt-muted { color: var(--text-muted, #6c7086); font-size: 0.8rem; }
+      .mkt-growth { font-size: 0.8rem; margin-top: 0.2rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function lines(text) {
+    return text.split('\n').map(l => l.replace(/\r$/, '')).filter(l => l.trim() !== '');
+  }
+
+  function findingsHtml(findings) {
+    if (findings.length === 0) return '<div class="mkt-yes">No problems found</div>';
+    return `
+      <ul class="mkt-findings">
+        ${findings.map(f => `
+          <li class="mkt-${escapeHtml(f.severity)}">
+            ${escapeHtml(f.message)}
+            ${f.growth ? `
+              <div class="mkt-growth mkt-muted">
+                Backtracking is <strong>${escapeHtml(f.growth.verdict)}</strong> on ${escapeHtml(f.growth.input)}:
+                ${f.growth.points.map(p => `n=${p.length}: ${p.exceeded ? 'over the limit' : `${p.steps.toLocaleString()} steps`}`).join(', ')}
+              </div>
+            ` : ''}
+          </li>
+        `).join('')}
+      </ul>
+    `;
+  }
+
+  // Offsets are in characters, so the sample is split the same way
+  function highlight(sample, start, end) {
+    const chars = Array.from(sample);
+    return `${escapeHtml(chars.slice(0, start).join(''))}<mark>${escapeHtml(chars.slice(start, end).join(''))}</mark>${escapeHtml(chars.slice(end).join(''))}`;
+  }
+
+  function renderMask(el, r) {
+    if (!r.valid) {
+      el.innerHTML = `<div class="mkt-danger">${escapeHtml(r.error)}</div>`;
+      return;
+    }
+    el.innerHTML = `
+      <div>Tested as <code>${escapeHtml(r.normalized)}</code></div>
+      ${findingsHtml(r.findings)}
+      ${r.results.length ? `
+        <table class="mkt-table">
+          <thead><tr><th>Sample</th><th>Result</th><th>Why</th></tr></thead>
+          <tbody>
+            ${r.results.map(s => `
+              <tr>
+                <td class="mkt-mono">${escapeHtml(s.sample)}</td>
+                <td>${s.matches ? '<span class="mkt-yes">Matches</span>' : '<span class="mkt-no">No match</span>'}</td>
+                <td>${escapeHtml(s.why)}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      ` : ''}
+    `;
+  }
+
+  function renderRegex(el, r) {
+    if (!r.valid) {
+      el.innerHTML = `<div class="mkt-danger">${escapeHtml(r.error)}</div>`;
+      return;
+    }
+    el.innerHTML = `
+      ${findingsHtml(r.findings)}
+      ${r.results.length ? `
+        <table class="mkt-table">
+          <thead><tr><th>Sample</th><th>Result</th><th>Groups</th><th>Steps</th></tr></thead>
+          <tbody>
+            ${r.results.map(s => `
+              <tr>
+                <td class="mkt-mono">${s.matched ? highlight(s.sample, s.start, s.end) : escapeHtml(s.sample)}</td>
+                <td>
+                  ${s.matched ? '<span class="mkt-yes">Matches</span>' : '<span class="mkt-no">No match</span>'}
+                  ${s.note ? `<div class="mkt-warning">${escapeHtml(s.note)}</div>` : ''}
+                </td>
+                <td class="mkt-mono">${s.groups.map(g => `${g.name ? escapeHtml(g.name) : g.index}: ${g.set ? escapeHtml(JSON.stringify(g.text)) : '<span class="mkt-no">unset</span>'}`).join('<br>')}</td>
+                <td class="${s.exceeded ? 'mkt-danger' : ''}">${r.match_type === 'regex' ? s.steps.toLocaleString() : ''}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      ` : ''}
+    `;
+  }
+
+  async function runMask(container, form) {
+    const el = container.querySelector('#mkt-mask-result');
+    el.innerHTML = 'Testing...';
+    try {
+      const r = await api('POST', '/mask', {
+        kind: form.kind.value,
+        mask: form.mask.value.trim(),
+        samples: lines(form.samples.value)
+      });
+      renderMask(el, r);
+    } catch (e) {
+      el.innerHTML = `<div class="mkt-danger">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function runRegex(container, form) {
+    const el = container.querySelector('#mkt-regex-result');
+    el.innerHTML = 'Testing...';
+    try {
+      const r = await api('POST', '/regex', {
+        pattern: form.pattern.value,
+        match_type: form.match_type.value,
+        samples: lines(form.samples.value)
+      });
+      renderRegex(el, r);
+    } catch (e) {
+      el.innerHTML = `<div class="mkt-danger">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="mkt-app" data-plugin="${PLUGIN_ID}">
+        <div class="mkt-card">
+          <h3>Ban mask</h3>
+          <form class="mkt-form" id="mkt-mask-form">
+            <label>Kind
+              <select name="kind">
+                <option value="server">G-line / K-line / shun</option>
+                <option value="zline">Z-line</option>
+                <option value="channel">Channel ban</option>
+              </select>
+            </label>
+            <label>Mask<input name="mask" required placeholder="*@*.example.net"></label>
+            <label class="mkt-wide">Samples, one per line: nick!user@host, user@host or a host
+              <textarea name="samples" rows="4" placeholder="bob!~bob@dsl-1.example.net"></textarea>
+            </label>
+            <div class="mkt-wide"><button class="mkt-btn primary" type="submit">Test mask</button></div>
+          </form>
+          <div class="mkt-result" id="mkt-mask-result"></div>
+        </div>
+        <div class="mkt-card">
+          <h3>Spamfilter</h3>
+          <form class="mkt-form" id="mkt-regex-form">
+            <label>Match type
+              <select name="match_type">
+                <option value="regex">regex</option>
+                <option value="simple">simple</option>
+              </select>
+            </label>
+            <label>Pattern<input name="pattern" required placeholder="free (money|cash)"></label>
+            <label class="mkt-wide">Sample text, one per line
+              <textarea name="samples" rows="4" placeholder="get free cash now"></textarea>
+              <span class="mkt-muted">For target u (user), give samples as nick!user@host:realname. Matching ignores case, as spamfilters do.</span>
+            </label>
+            <div class="mkt-wide"><button class="mkt-btn primary" type="submit">Test pattern</button></div>
+          </form>
+          <div class="mkt-result" id="mkt-regex-result"></div>
+        </div>
+      </div>
+    `;
+
+    const app = container.querySelector('.mkt-app');
+    app.addEventListener('submit', (e) => {
+      e.preventDefault();
+      if (e.target.id === 'mkt-mask-form') runMask(container, e.target);
+      if (e.target.id === 'mkt-regex-form') runRegex(container, e.target);
+    });
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('mask-tester-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+})();
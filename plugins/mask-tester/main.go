@@ -0,0 +1,246 @@
+// Mask Tester Plugin for UnrealIRCd Web Panel
+// A sandbox for trying ban masks and spamfilter patterns against sample
+// users and text, with warnings about masks too broad and regexes too slow
+
+package masktester
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+)
+
+// MaskTesterPlugin implements the Plugin interface
+type MaskTesterPlugin struct {
+	config Config
+	mu     sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	StepLimit    int `json:"step_limit"`
+	MaxSamples   int `json:"max_samples"`
+	MaxSampleLen int `json:"max_sample_length"`
+}
+
+// maskRequest is the body of a mask test
+type maskRequest struct {
+	Kind    string   `json:"kind"`
+	Mask    string   `json:"mask"`
+	Samples []string `json:"samples"`
+}
+
+// regexRequest is the body of a spamfilter test
+type regexRequest struct {
+	Pattern   string   `json:"pattern"`
+	MatchType string   `json:"match_type"`
+	Samples   []string `json:"samples"`
+}
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	return &MaskTesterPlugin{
+		config: Config{
+			StepLimit:    1000000,
+			MaxSamples:   50,
+			MaxSampleLen: 1024,
+		},
+	}
+}
+
+// Info returns plugin metadata
+func (p *MaskTesterPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Mask Tester",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Try ban masks and spamfilter regexes against samples before setting them",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *MaskTesterPlugin) Init() error {
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *MaskTesterPlugin) Shutdown() error {
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *MaskTesterPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/mask-tester")
+	{
+		plugin.POST("/mask", p.handleMask)
+		plugin.POST("/regex", p.handleRegex)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// cleanSamples drops blank samples and enforces the configured limits
+func cleanSamples(cfg Config, samples []string) ([]string, error) {
+	out := make([]string, 0, len(samples))
+	for _, s := range samples {
+		s = strings.TrimRight(s, "\r")
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		if len([]rune(s)) > cfg.MaxSampleLen {
+			return nil, fmt.Errorf("samples can be at most %d characters", cfg.MaxSampleLen)
+		}
+		out = append(out, s)
+	}
+	if len(out) > cfg.MaxSamples {
+		return nil, fmt.Errorf("at most %d samples can be tested at once", cfg.MaxSamples)
+	}
+	return out, nil
+}
+
+// handleMask tests a server or channel ban mask against sample users
+func (p *MaskTesterPlugin) handleMask(c *gin.Context) {
+	var req maskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	switch req.Kind {
+	case KindServer, KindZline, KindChannel:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be server, zline or channel"})
+		return
+	}
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	samples := make([]string, 0, len(req.Samples))
+	for _, s := range req.Samples {
+		samples = append(samples, strings.TrimSpace(s))
+	}
+	samples, err := cleanSamples(cfg, samples)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, testMask(req.Kind, strings.TrimSpace(req.Mask), samples))
+}
+
+// handleRegex tests a spamfilter pattern against sample text and looks
+// for patterns that would make the IRCd backtrack badly
+func (p *MaskTesterPlugin) handleRegex(c *gin.Context) {
+	var req regexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if req.MatchType == "" {
+		req.MatchType = MatchRegex
+	}
+	if req.MatchType != MatchRegex && req.MatchType != MatchSimple {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "match_type must be regex or simple"})
+		return
+	}
+	if req.Pattern == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pattern is required"})
+		return
+	}
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	samples, err := cleanSamples(cfg, req.Samples)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := RegexReport{
+		Pattern:   req.Pattern,
+		MatchType: req.MatchType,
+		Findings:  make([]*Finding, 0),
+		Results:   make([]RegexResult, 0),
+	}
+	if req.MatchType == MatchSimple {
+		report.Valid = true
+		report.Findings = simpleFindings(req.Pattern)
+		report.Results = testSimple(req.Pattern, samples)
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	re, err := parsePattern(req.Pattern)
+	if err != nil {
+		report.Error = err.Error()
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	report.Valid = true
+	report.Findings = analyze(re, cfg.StepLimit)
+	report.Results = testRegex(re, samples, cfg.StepLimit)
+	for _, f := range report.Findings {
+		if f.Severity == SeverityDanger && f.Growth != nil {
+			log.Printf("[mask-tester] %s tested a regex with %s backtracking: %s", access.Actor(c), f.Growth.Verdict, req.Pattern)
+			break
+		}
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleGetConfig returns the current configuration
+func (p *MaskTesterPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *MaskTesterPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.StepLimit < 1000 || newConfig.StepLimit > 100000000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "step_limit must be between 1000 and 100000000"})
+		return
+	}
+	if newConfig.MaxSamples < 1 || newConfig.MaxSampleLen < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_samples and max_sample_length must be positive"})
+		return
+	}
+
+	p.mu.Lock()
+	p.config = newConfig
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *MaskTesterPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *MaskTesterPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,257 @@
+package masktester
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+)
+
+// Mask kinds
+const (
+	KindServer  = "server"
+	KindZline   = "zline"
+	KindChannel = "channel"
+)
+
+// extbanPattern matches an extended ban such as ~account:name, as opposed
+// to a mask whose ident starts with ~
+var extbanPattern = regexp.MustCompile(`^~[A-Za-z-]+:`)
+
+// ipMaskPattern matches the host part of a mask that can only match IP
+// addresses
+var ipMaskPattern = regexp.MustCompile(`^[0-9A-Fa-f.:*?/]+$`)
+
+// probes are unrelated users; a mask matching all of them matches anyone
+var probes = []Sample{
+	{Nick: "alice", User: "alice", Host: "host-1.example.com"},
+	{Nick: "Bob", User: "~bob", Host: "192.0.2.17"},
+	{Nick: "zed", User: "zz", Host: "2001:db8::42"},
+	{Nick: "guest123", User: "~u", Host: "dsl.provider.net"},
+}
+
+// Sample is a user a mask is tested against. Empty fields weren't given.
+type Sample struct {
+	Nick string `json:"nick"`
+	User string `json:"user"`
+	Host string `json:"host"`
+}
+
+// MaskResult is the outcome of matching one sample
+type MaskResult struct {
+	Sample  string `json:"sample"`
+	Matches bool   `json:"matches"`
+	Why     string `json:"why"`
+}
+
+// MaskReport is everything found about a ban mask
+type MaskReport struct {
+	Kind       string       `json:"kind"`
+	Mask       string       `json:"mask"`
+	Normalized string       `json:"normalized"`
+	Valid      bool         `json:"valid"`
+	Error      string       `json:"error,omitempty"`
+	Findings   []*Finding   `json:"findings"`
+	Results    []MaskResult `json:"results"`
+}
+
+// parseSample splits nick!user@host, user@host or a bare host
+func parseSample(s string) Sample {
+	var out Sample
+	if nick, rest, ok := strings.Cut(s, "!"); ok {
+		out.Nick = nick
+		s = rest
+	}
+	if user, host, ok := strings.Cut(s, "@"); ok {
+		out.User = user
+		s = host
+	}
+	out.Host = s
+	return out
+}
+
+// matchHost reports whether a ban's host part matches a host, handling
+// CIDR masks for IP addresses
+func matchHost(mask, host string) bool {
+	if _, ipnet, err := net.ParseCIDR(mask); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && ipnet.Contains(ip)
+	}
+	return ircname.MatchMask(mask, host)
+}
+
+// normalizeChannelMask expands a channel ban mask to nick!user@host the
+// way the IRCd does, so "bob" is bob!*@* and "*.example.net" is
+// *!*@*.example.net
+func normalizeChannelMask(mask string) string {
+	hasBang := strings.Contains(mask, "!")
+	hasAt := strings.Contains(mask, "@")
+	switch {
+	case hasBang && hasAt:
+		return mask
+	case hasBang:
+		return mask + "@*"
+	case hasAt:
+		return "*!" + mask
+	case strings.ContainsAny(mask, ".:"):
+		return "*!*@" + mask
+	default:
+		return mask + "!*@*"
+	}
+}
+
+// normalizeServerMask expands a server ban mask to user@host, so a bare
+// host bans every ident on it
+func normalizeServerMask(mask string) string {
+	if strings.Contains(mask, "@") {
+		return mask
+	}
+	return "*@" + mask
+}
+
+// testMask checks a mask for mistakes and matches it against samples
+func testMask(kind, mask string, samples []string) *MaskReport {
+	r := &MaskReport{
+		Kind:     kind,
+		Mask:     mask,
+		Findings: make([]*Finding, 0),
+		Results:  make([]MaskResult, 0, len(samples)),
+	}
+	finding := func(severity, format string, args ...interface{}) {
+		r.Findings = append(r.Findings, &Finding{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case mask == "":
+		r.Error = "mask is required"
+	case strings.ContainsAny(mask, " ,\r\n\x00"):
+		r.Error = "masks can't contain spaces or commas"
+	case extbanPattern.MatchString(mask):
+		if kind != KindChannel {
+			r.Error = "extended bans can only be tested as channel bans here"
+		} else {
+			r.Error = "extended bans depend on more than nick!user@host and can't be tested against samples"
+		}
+	case kind != KindChannel && strings.Contains(mask, "!"):
+		r.Error = "server bans are user@host and can't match nicks; use a Q-line to ban a nick"
+	}
+	if r.Error != "" {
+		return r
+	}
+
+	var user, host string
+	if kind == KindChannel {
+		r.Normalized = normalizeChannelMask(mask)
+		_, uh, _ := strings.Cut(r.Normalized, "!")
+		user, host, _ = strings.Cut(uh, "@")
+	} else {
+		r.Normalized = normalizeServerMask(mask)
+		user, host, _ = strings.Cut(r.Normalized, "@")
+	}
+	if strings.Count(r.Normalized, "@") > 1 || strings.Count(r.Normalized, "!") > 1 {
+		r.Error = "mask has more than one @ or !"
+		return r
+	}
+	r.Valid = true
+	if r.Normalized != mask {
+		finding(SeverityInfo, "The IRCd reads %s as %s", mask, r.Normalized)
+	}
+
+	if _, ipnet, err := net.ParseCIDR(host); err == nil {
+		ones, bits := ipnet.Mask.Size()
+		if (bits == 32 && ones < 16) || (bits == 128 && ones < 32) {
+			finding(SeverityDanger, "%s covers 2^%d addresses", host, bits-ones)
+		} else if bits-ones > 0 {
+			finding(SeverityInfo, "%s covers 2^%d addresses", host, bits-ones)
+		}
+	} else if strings.Contains(host, "/") {
+		finding(SeverityWarning, "%s looks like a CIDR mask but isn't one, so it's matched as text and will match nothing", host)
+	}
+
+	if kind == KindZline {
+		if !ipMaskPattern.MatchString(host) {
+			finding(SeverityDanger, "Z-lines are checked before DNS lookups, so they only match IP addresses and %s will never match", host)
+		}
+		if strings.Trim(user, "*") != "" {
+			finding(SeverityWarning, "Z-lines don't look at the ident; the IRCd requires the user part to be *")
+		}
+	}
+	if kind != KindZline && user != "" && !strings.HasPrefix(user, "~") && !strings.HasPrefix(user, "*") && !strings.HasPrefix(user, "?") {
+		finding(SeverityInfo, "Users without an ident reply have ~ in front of their user name, which %s won't match", user)
+	}
+
+	matchesAll := true
+	for _, s := range probes {
+		if !matchSample(kind, r.Normalized, s) {
+			matchesAll = false
+			break
+		}
+	}
+	if matchesAll {
+		if kind == KindChannel {
+			finding(SeverityDanger, "Matches every user, so it bans everyone who isn't voiced, opped or excepted")
+		} else {
+			finding(SeverityDanger, "Matches every user on the network; the IRCd refuses masks this broad unless set::options::allow-insane-bans is on")
+		}
+	}
+
+	for _, text := range samples {
+		s := parseSample(text)
+		res := MaskResult{Sample: text, Matches: matchSample(kind, r.Normalized, s)}
+		res.Why = explain(kind, user, host, r.Normalized, s)
+		r.Results = append(r.Results, res)
+	}
+	return r
+}
+
+// matchSample reports whether a normalized mask matches a sample
+func matchSample(kind, mask string, s Sample) bool {
+	if kind == KindChannel {
+		nick, uh, _ := strings.Cut(mask, "!")
+		user, host, _ := strings.Cut(uh, "@")
+		return ircname.MatchMask(nick, s.Nick) && ircname.MatchMask(user, s.User) && matchHost(host, s.Host)
+	}
+	user, host, _ := strings.Cut(mask, "@")
+	if kind == KindZline {
+		return net.ParseIP(s.Host) != nil && matchHost(host, s.Host)
+	}
+	return ircname.MatchMask(user, s.User) && matchHost(host, s.Host)
+}
+
+// explain says which parts of a sample made a mask miss
+func explain(kind, user, host, mask string, s Sample) string {
+	if kind == KindZline && net.ParseIP(s.Host) == nil {
+		return fmt.Sprintf("%s isn't an IP address; Z-lines only see the IP address", s.Host)
+	}
+	misses := make([]string, 0)
+	if kind == KindChannel {
+		nick, _, _ := strings.Cut(mask, "!")
+		if !ircname.MatchMask(nick, s.Nick) {
+			misses = append(misses, missing("nick", s.Nick, nick))
+		}
+	}
+	if kind != KindZline && !ircname.MatchMask(user, s.User) {
+		misses = append(misses, missing("ident", s.User, user))
+	}
+	if !matchHost(host, s.Host) {
+		misses = append(misses, missing("host", s.Host, host))
+	}
+	if len(misses) > 0 {
+		msg := strings.Join(misses, "; ")
+		return strings.ToUpper(msg[:1]) + msg[1:]
+	}
+	if kind != KindChannel && s.Nick != "" {
+		return "Matches; server bans ignore the nick"
+	}
+	return "Matches"
+}
+
+// missing describes a part of a sample that a mask doesn't match
+func missing(label, value, mask string) string {
+	if value == "" {
+		return fmt.Sprintf("the sample has no %s", label)
+	}
+	return fmt.Sprintf("the %s %s doesn't match %s", label, value, mask)
+}
@@ -0,0 +1,820 @@
+package masktester
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+)
+
+// Spamfilter match types
+const (
+	MatchRegex  = "regex"
+	MatchSimple = "simple"
+)
+
+// Finding severities
+const (
+	SeverityDanger  = "danger"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Growth verdicts
+const (
+	GrowthLinear      = "linear"
+	GrowthPolynomial  = "polynomial"
+	GrowthExponential = "exponential"
+)
+
+// growthLengths are the pump counts of the backtracking test
+var growthLengths = []int{5, 10, 15, 20, 25}
+
+// attackSuffixes are tried after the pumped string to make the match fail
+// late, which is what makes backtracking expensive
+var attackSuffixes = []string{"!", " ", "1", "_", "a"}
+
+// pcreOnly lists PCRE features RE2 can't parse, to explain why a pattern
+// can't be tested here
+var pcreOnly = []struct {
+	re   *regexp.Regexp
+	name string
+}{
+	{regexp.MustCompile(`\(\?<?[=!]`), "lookaround assertions"},
+	{regexp.MustCompile(`\\[1-9]|\\g\{?-?\d|\\k[<{']`), "backreferences"},
+	{regexp.MustCompile(`\(\?>`), "atomic groups"},
+	{regexp.MustCompile(`[*+?}]\+`), "possessive quantifiers"},
+	{regexp.MustCompile(`\(\?R\)|\(\?[+-]?\d+\)|\(\?&`), "recursion"},
+	{regexp.MustCompile(`\\K`), `\K`},
+	{regexp.MustCompile(`\(\*[A-Z_]+`), "backtracking control verbs"},
+	{regexp.MustCompile(`\(\?\(`), "conditionals"},
+}
+
+// Finding is a problem found by looking at a pattern
+type Finding struct {
+	Severity string  `json:"severity"`
+	Message  string  `json:"message"`
+	Fragment string  `json:"fragment,omitempty"`
+	Growth   *Growth `json:"growth,omitempty"`
+
+	node *syntax.Regexp
+	path []*syntax.Regexp
+}
+
+// Growth is how the work of a failing match grows with the input
+type Growth struct {
+	Input   string        `json:"input"`
+	Points  []GrowthPoint `json:"points"`
+	Verdict string        `json:"verdict"`
+}
+
+// GrowthPoint is the work done for one input length
+type GrowthPoint struct {
+	Length   int  `json:"length"`
+	Steps    int  `json:"steps"`
+	Exceeded bool `json:"exceeded"`
+}
+
+// Group is a capture group of a match
+type Group struct {
+	Index int    `json:"index"`
+	Name  string `json:"name,omitempty"`
+	Set   bool   `json:"set"`
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// RegexResult is the outcome of matching one sample
+type RegexResult struct {
+	Sample   string  `json:"sample"`
+	Matched  bool    `json:"matched"`
+	Match    string  `json:"match,omitempty"`
+	Start    int     `json:"start"`
+	End      int     `json:"end"`
+	Groups   []Group `json:"groups"`
+	Steps    int     `json:"steps"`
+	Exceeded bool    `json:"exceeded"`
+	Duration float64 `json:"duration_ms"`
+	Note     string  `json:"note,omitempty"`
+}
+
+// RegexReport is everything found about a spamfilter pattern
+type RegexReport struct {
+	Pattern   string        `json:"pattern"`
+	MatchType string        `json:"match_type"`
+	Valid     bool          `json:"valid"`
+	Error     string        `json:"error,omitempty"`
+	Findings  []*Finding    `json:"findings"`
+	Results   []RegexResult `json:"results"`
+}
+
+// parsePattern parses a spamfilter regex. Spamfilters are always case
+// insensitive. Patterns using PCRE features RE2 lacks are rejected with
+// the names of those features.
+func parsePattern(pattern string) (*syntax.Regexp, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl|syntax.FoldCase)
+	if err == nil {
+		return re, nil
+	}
+	features := make([]string, 0)
+	for _, f := range pcreOnly {
+		if f.re.MatchString(pattern) {
+			features = append(features, f.name)
+		}
+	}
+	if len(features) > 0 {
+		return nil, fmt.Errorf("uses %s, which UnrealIRCd's PCRE2 supports but this tester can't run: %v", strings.Join(features, ", "), err)
+	}
+	return nil, err
+}
+
+// matcher is a backtracking regex matcher that works like PCRE, trying
+// alternatives and repeat counts in order and backing up on failure. It
+// counts its steps so runaway backtracking shows up as a number rather
+// than as a hung IRCd.
+type matcher struct {
+	input    []rune
+	caps     []int
+	steps    int
+	limit    int
+	exceeded bool
+}
+
+// newMatcher returns a matcher for input with room for the captures of re
+func newMatcher(re *syntax.Regexp, input string, limit int) *matcher {
+	return &matcher{input: []rune(input), caps: make([]int, 2*(re.MaxCap()+1)), limit: limit}
+}
+
+// search finds the leftmost match of re, trying each start position in
+// turn as an unanchored PCRE match does
+func (m *matcher) search(re *syntax.Regexp) bool {
+	for start := 0; start <= len(m.input); start++ {
+		for i := range m.caps {
+			m.caps[i] = -1
+		}
+		m.caps[0] = start
+		if m.match(re, start, func(end int) bool {
+			m.caps[1] = end
+			return true
+		}) {
+			return true
+		}
+		if m.exceeded {
+			return false
+		}
+	}
+	return false
+}
+
+// match matches re at pos and calls k with the end of each way it can
+// match, in PCRE's order of preference, until k accepts one
+func (m *matcher) match(re *syntax.Regexp, pos int, k func(int) bool) bool {
+	if m.exceeded {
+		return false
+	}
+	m.steps++
+	if m.steps > m.limit {
+		m.exceeded = true
+		return false
+	}
+
+	in := m.input
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return false
+	case syntax.OpEmptyMatch:
+		return k(pos)
+	case syntax.OpLiteral:
+		fold := re.Flags&syntax.FoldCase != 0
+		for _, r := range re.Rune {
+			if pos >= len(in) || !runeEqual(r, in[pos], fold) {
+				return false
+			}
+			pos++
+		}
+		return k(pos)
+	case syntax.OpCharClass:
+		return pos < len(in) && inClass(re.Rune, in[pos]) && k(pos+1)
+	case syntax.OpAnyCharNotNL:
+		return pos < len(in) && in[pos] != '\n' && k(pos+1)
+	case syntax.OpAnyChar:
+		return pos < len(in) && k(pos+1)
+	case syntax.OpBeginLine:
+		return (pos == 0 || in[pos-1] == '\n') && k(pos)
+	case syntax.OpEndLine:
+		return (pos == len(in) || in[pos] == '\n') && k(pos)
+	case syntax.OpBeginText:
+		return pos == 0 && k(pos)
+	case syntax.OpEndText:
+		return pos == len(in) && k(pos)
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		before := pos > 0 && isWordRune(in[pos-1])
+		after := pos < len(in) && isWordRune(in[pos])
+		return (before != after) == (re.Op == syntax.OpWordBoundary) && k(pos)
+	case syntax.OpCapture:
+		i := 2 * re.Cap
+		oldStart, oldEnd := m.caps[i], m.caps[i+1]
+		m.caps[i] = pos
+		if m.match(re.Sub[0], pos, func(end int) bool {
+			prev := m.caps[i+1]
+			m.caps[i+1] = end
+			if k(end) {
+				return true
+			}
+			m.caps[i+1] = prev
+			return false
+		}) {
+			return true
+		}
+		m.caps[i], m.caps[i+1] = oldStart, oldEnd
+		return false
+	case syntax.OpConcat:
+		return m.concat(re.Sub, pos, k)
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if m.match(sub, pos, k) {
+				return true
+			}
+		}
+		return false
+	case syntax.OpStar:
+		return m.repeat(re.Sub[0], 0, -1, re.Flags&syntax.NonGreedy != 0, pos, k)
+	case syntax.OpPlus:
+		return m.repeat(re.Sub[0], 1, -1, re.Flags&syntax.NonGreedy != 0, pos, k)
+	case syntax.OpQuest:
+		return m.repeat(re.Sub[0], 0, 1, re.Flags&syntax.NonGreedy != 0, pos, k)
+	case syntax.OpRepeat:
+		return m.repeat(re.Sub[0], re.Min, re.Max, re.Flags&syntax.NonGreedy != 0, pos, k)
+	}
+	return false
+}
+
+// concat matches a sequence of expressions
+func (m *matcher) concat(subs []*syntax.Regexp, pos int, k func(int) bool) bool {
+	if len(subs) == 0 {
+		return k(pos)
+	}
+	return m.match(subs[0], pos, func(p int) bool {
+		return m.concat(subs[1:], p, k)
+	})
+}
+
+// repeat matches re between min and max times (max -1 is unlimited),
+// preferring more repeats unless lazy. An iteration that matches nothing
+// ends the repeat, as in PCRE.
+func (m *matcher) repeat(re *syntax.Regexp, min, max int, lazy bool, pos int, k func(int) bool) bool {
+	var loop func(count, pos int) bool
+	loop = func(count, pos int) bool {
+		more := func() bool {
+			if max != -1 && count >= max {
+				return false
+			}
+			return m.match(re, pos, func(p int) bool {
+				if p == pos && count >= min {
+					return false
+				}
+				return loop(count+1, p)
+			})
+		}
+		if count < min {
+			return more()
+		}
+		if lazy {
+			return k(pos) || more()
+		}
+		return more() || k(pos)
+	}
+	return loop(0, pos)
+}
+
+// runeEqual compares runes, optionally ignoring case
+func runeEqual(a, b rune, fold bool) bool {
+	if a == b {
+		return true
+	}
+	return fold && (unicode.ToLower(a) == unicode.ToLower(b) || unicode.ToUpper(a) == unicode.ToUpper(b))
+}
+
+// inClass reports whether r is in a character class given as range pairs
+func inClass(ranges []rune, r rune) bool {
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if r >= ranges[i] && r <= ranges[i+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordRune reports whether r is a \w character
+func isWordRune(r rune) bool {
+	return r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// testRegex matches every sample against a pattern
+func testRegex(re *syntax.Regexp, samples []string, limit int) []RegexResult {
+	names := re.CapNames()
+	results := make([]RegexResult, 0, len(samples))
+	for _, sample := range samples {
+		start := time.Now()
+		m := newMatcher(re, sample, limit)
+		matched := m.search(re)
+		r := RegexResult{
+			Sample:   sample,
+			Matched:  matched,
+			Groups:   make([]Group, 0),
+			Steps:    m.steps,
+			Exceeded: m.exceeded,
+			Duration: millis(time.Since(start)),
+		}
+		if m.exceeded {
+			r.Note = fmt.Sprintf("Gave up after %d steps; PCRE2 would hit its match limit or take very long on this text", limit)
+		}
+		if matched {
+			r.Start, r.End = m.caps[0], m.caps[1]
+			r.Match = string(m.input[r.Start:r.End])
+			for i := 1; i < len(names); i++ {
+				g := Group{Index: i, Name: names[i], Start: m.caps[2*i], End: m.caps[2*i+1]}
+				if g.Start >= 0 && g.End >= 0 {
+					g.Set = true
+					g.Text = string(m.input[g.Start:g.End])
+				}
+				r.Groups = append(r.Groups, g)
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// testSimple matches every sample against a simple spamfilter, which uses
+// * and ? wildcards and must match the whole text
+func testSimple(pattern string, samples []string) []RegexResult {
+	results := make([]RegexResult, 0, len(samples))
+	for _, sample := range samples {
+		r := RegexResult{Sample: sample, Groups: make([]Group, 0)}
+		if ircname.MatchMask(pattern, sample) {
+			r.Matched = true
+			r.Match = sample
+			r.End = len([]rune(sample))
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// simpleFindings looks for mistakes in a simple spamfilter
+func simpleFindings(pattern string) []*Finding {
+	findings := make([]*Finding, 0)
+	if strings.Trim(pattern, "*?") == "" {
+		findings = append(findings, &Finding{Severity: SeverityDanger, Message: "Matches every text"})
+	}
+	if !strings.HasPrefix(pattern, "*") || !strings.HasSuffix(pattern, "*") {
+		findings = append(findings, &Finding{Severity: SeverityInfo, Message: "Simple spamfilters must match the whole text; add * at the start or end to match it anywhere"})
+	}
+	if strings.ContainsAny(pattern, "()[]|+\\^$") {
+		findings = append(findings, &Finding{Severity: SeverityWarning, Message: "Simple spamfilters only understand * and ?; the other special characters are matched literally. Use match-type regex for a regular expression"})
+	}
+	return findings
+}
+
+// analyze looks for patterns that make PCRE backtrack badly, then
+// measures how a failing match grows with the input for each one found
+func analyze(re *syntax.Regexp, limit int) []*Finding {
+	findings := make([]*Finding, 0)
+	seen := make(map[*syntax.Regexp]bool)
+	add := func(f *Finding) {
+		if !seen[f.node] {
+			seen[f.node] = true
+			findings = append(findings, f)
+		}
+	}
+
+	var walk func(node *syntax.Regexp, path []*syntax.Regexp, outer *syntax.Regexp)
+	walk = func(node *syntax.Regexp, path []*syntax.Regexp, outer *syntax.Regexp) {
+		path = append(path[:len(path):len(path)], node)
+		if unbounded(node) && consumes(node.Sub[0]) {
+			if outer != nil {
+				add(&Finding{
+					Severity: SeverityDanger,
+					Message:  fmt.Sprintf("Nested repeat: %s is repeated inside %s, so a failing match can try every way of splitting the text between them", show(node), show(outer)),
+					Fragment: show(outer),
+					node:     outer,
+					path:     pathTo(path, outer),
+				})
+			} else {
+				outer = node
+			}
+			if alt := alternation(node.Sub[0]); alt != nil && overlapping(alt.Sub) {
+				add(&Finding{
+					Severity: SeverityDanger,
+					Message:  fmt.Sprintf("Repeated alternatives overlap: more than one branch of %s can match the same text, so a failing match tries every combination", show(alt)),
+					Fragment: show(node),
+					node:     node,
+					path:     path,
+				})
+			} else if opt := optionalOverlap(node.Sub[0], firstSet(node.Sub[0])); opt != nil {
+				add(&Finding{
+					Severity: SeverityDanger,
+					Message:  fmt.Sprintf("Ambiguous repeat: the optional %s can match what starts the next repeat of %s, so a failing match tries every way of splitting the text", show(opt), show(node)),
+					Fragment: show(node),
+					node:     node,
+					path:     path,
+				})
+			}
+		}
+		if node.Op == syntax.OpConcat {
+			for i := 0; i+1 < len(node.Sub); i++ {
+				a, b := node.Sub[i], node.Sub[i+1]
+				if unbounded(a) && unbounded(b) && firstSet(a.Sub[0]).overlaps(firstSet(b.Sub[0])) {
+					add(&Finding{
+						Severity: SeverityWarning,
+						Message:  fmt.Sprintf("Adjacent repeats %s and %s can match the same characters, so a failing match tries every split between them", show(a), show(b)),
+						Fragment: show(a) + show(b),
+						node:     a,
+						path:     append(path[:len(path):len(path)], a),
+					})
+				}
+			}
+		}
+		for _, sub := range node.Sub {
+			walk(sub, path, outer)
+		}
+	}
+	walk(re, nil, nil)
+
+	if m := newMatcher(re, "", limit); m.search(re) {
+		findings = append(findings, &Finding{Severity: SeverityDanger, Message: "Matches an empty string, so it matches every text"})
+	}
+	if leadingDotStar(re) {
+		findings = append(findings, &Finding{Severity: SeverityInfo, Message: "A leading .* is redundant in an unanchored pattern and makes every failed match slower"})
+	}
+
+	for _, f := range findings {
+		if f.node == nil {
+			continue
+		}
+		f.Growth = measure(re, f, limit)
+		// The pattern looked risky but backtracking stayed in check
+		switch f.Growth.Verdict {
+		case GrowthLinear:
+			f.Severity = SeverityInfo
+			f.Message += "; a test input stayed linear, so this may be harmless"
+		case GrowthPolynomial:
+			f.Severity = SeverityWarning
+		}
+	}
+	return findings
+}
+
+// measure runs a failing match against increasingly long inputs that pump
+// the risky part of the pattern and classifies how the steps grow
+func measure(re *syntax.Regexp, f *Finding, limit int) *Growth {
+	prefix := prefixFor(f.path)
+	pump := example(f.node.Sub[0])
+	if pump == "" {
+		pump = firstSet(f.node.Sub[0]).sample()
+	}
+
+	var worst *Growth
+	for _, suffix := range attackSuffixes {
+		g := &Growth{Input: fmt.Sprintf("%q + %q × n + %q", prefix, pump, suffix), Points: make([]GrowthPoint, 0, len(growthLengths))}
+		for _, n := range growthLengths {
+			m := newMatcher(re, prefix+strings.Repeat(pump, n)+suffix, limit)
+			m.search(re)
+			g.Points = append(g.Points, GrowthPoint{Length: n, Steps: m.steps, Exceeded: m.exceeded})
+			if m.exceeded {
+				break
+			}
+		}
+		if worst == nil || worse(g, worst) {
+			worst = g
+		}
+	}
+	worst.Verdict = classify(worst.Points)
+	return worst
+}
+
+// worse reports whether growth a did more work than b
+func worse(a, b *Growth) bool {
+	la, lb := a.Points[len(a.Points)-1], b.Points[len(b.Points)-1]
+	if la.Exceeded != lb.Exceeded {
+		return la.Exceeded
+	}
+	if len(a.Points) != len(b.Points) {
+		return len(a.Points) < len(b.Points)
+	}
+	return la.Steps > lb.Steps
+}
+
+// classify names how the steps grow between the pump counts. Doubling
+// the input doubles linear work, multiplies polynomial work by a small
+// factor and exponential work by a large one.
+func classify(points []GrowthPoint) string {
+	last := points[len(points)-1]
+	if last.Exceeded {
+		return GrowthExponential
+	}
+	var at10, at20 int
+	for _, p := range points {
+		switch p.Length {
+		case 10:
+			at10 = p.Steps
+		case 20:
+			at20 = p.Steps
+		}
+	}
+	if at10 == 0 {
+		return GrowthLinear
+	}
+	ratio := float64(at20) / float64(at10)
+	switch {
+	case ratio > 50:
+		return GrowthExponential
+	case ratio > 3:
+		return GrowthPolynomial
+	}
+	return GrowthLinear
+}
+
+// show prints part of a pattern without the case folding flags the
+// parser adds, so it reads like what was typed
+func show(node *syntax.Regexp) string {
+	var clear func(n *syntax.Regexp) *syntax.Regexp
+	clear = func(n *syntax.Regexp) *syntax.Regexp {
+		c := *n
+		c.Flags &^= syntax.FoldCase
+		if c.Op == syntax.OpLiteral {
+			c.Rune = []rune(strings.ToLower(string(c.Rune)))
+		}
+		c.Sub = make([]*syntax.Regexp, len(n.Sub))
+		for i, sub := range n.Sub {
+			c.Sub[i] = clear(sub)
+		}
+		return &c
+	}
+	return clear(node).String()
+}
+
+// unbounded reports whether node repeats without an upper limit
+func unbounded(node *syntax.Regexp) bool {
+	switch node.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		return true
+	case syntax.OpRepeat:
+		return node.Max == -1
+	}
+	return false
+}
+
+// consumes reports whether node can match at least one character
+func consumes(node *syntax.Regexp) bool {
+	switch node.Op {
+	case syntax.OpLiteral, syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return true
+	case syntax.OpRepeat:
+		return node.Max != 0 && consumes(node.Sub[0])
+	case syntax.OpCapture, syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpConcat, syntax.OpAlternate:
+		for _, sub := range node.Sub {
+			if consumes(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// alternation returns the alternation a repeat body consists of, looking
+// through capture groups
+func alternation(node *syntax.Regexp) *syntax.Regexp {
+	for node.Op == syntax.OpCapture {
+		node = node.Sub[0]
+	}
+	if node.Op == syntax.OpAlternate {
+		return node
+	}
+	return nil
+}
+
+// overlapping reports whether two branches can start with the same
+// character
+func overlapping(branches []*syntax.Regexp) bool {
+	for i := range branches {
+		for j := i + 1; j < len(branches); j++ {
+			if firstSet(branches[i]).overlaps(firstSet(branches[j])) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// optionalOverlap returns an optional part of a repeat body that can
+// start with the same characters as the body itself, as in (aa?)+, which
+// the parser makes of (a|aa)+
+func optionalOverlap(node *syntax.Regexp, start runeSet) *syntax.Regexp {
+	switch node.Op {
+	case syntax.OpQuest, syntax.OpStar:
+		if consumes(node.Sub[0]) && firstSet(node.Sub[0]).overlaps(start) {
+			return node
+		}
+	case syntax.OpRepeat:
+		if node.Min < node.Max && consumes(node.Sub[0]) && firstSet(node.Sub[0]).overlaps(start) {
+			return node
+		}
+	case syntax.OpAlternate:
+		if nullable(node) && consumes(node) && firstSet(node).overlaps(start) {
+			return node
+		}
+		fallthrough
+	case syntax.OpCapture, syntax.OpConcat:
+		for _, sub := range node.Sub {
+			if opt := optionalOverlap(sub, start); opt != nil {
+				return opt
+			}
+		}
+	}
+	return nil
+}
+
+// leadingDotStar reports whether an unanchored pattern starts with .*
+func leadingDotStar(re *syntax.Regexp) bool {
+	first := re
+	if re.Op == syntax.OpConcat && len(re.Sub) > 0 {
+		first = re.Sub[0]
+	}
+	return first.Op == syntax.OpStar && (first.Sub[0].Op == syntax.OpAnyCharNotNL || first.Sub[0].Op == syntax.OpAnyChar)
+}
+
+// pathTo cuts a path from the root off at node
+func pathTo(path []*syntax.Regexp, node *syntax.Regexp) []*syntax.Regexp {
+	for i, n := range path {
+		if n == node {
+			return path[:i+1]
+		}
+	}
+	return path
+}
+
+// prefixFor builds text that leads the pattern up to the last node of
+// path, from examples of whatever comes before it in each sequence
+func prefixFor(path []*syntax.Regexp) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(path); i++ {
+		if path[i].Op != syntax.OpConcat {
+			continue
+		}
+		for _, sub := range path[i].Sub {
+			if sub == path[i+1] {
+				break
+			}
+			b.WriteString(example(sub))
+		}
+	}
+	return b.String()
+}
+
+// example returns a short text that node matches
+func example(node *syntax.Regexp) string {
+	switch node.Op {
+	case syntax.OpLiteral:
+		if node.Flags&syntax.FoldCase != 0 {
+			return strings.ToLower(string(node.Rune))
+		}
+		return string(node.Rune)
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return firstSet(node).sample()
+	case syntax.OpCapture, syntax.OpPlus:
+		return example(node.Sub[0])
+	case syntax.OpRepeat:
+		return strings.Repeat(example(node.Sub[0]), node.Min)
+	case syntax.OpAlternate:
+		return example(node.Sub[0])
+	case syntax.OpConcat:
+		var b strings.Builder
+		for _, sub := range node.Sub {
+			b.WriteString(example(sub))
+		}
+		return b.String()
+	}
+	return ""
+}
+
+// runeSet is the set of characters an expression can start with
+type runeSet struct {
+	any    bool
+	ranges []rune
+}
+
+// overlaps reports whether two sets share a character
+func (s runeSet) overlaps(o runeSet) bool {
+	if (s.any && (o.any || len(o.ranges) > 0)) || (o.any && len(s.ranges) > 0) {
+		return true
+	}
+	for i := 0; i+1 < len(s.ranges); i += 2 {
+		for j := 0; j+1 < len(o.ranges); j += 2 {
+			if s.ranges[i] <= o.ranges[j+1] && o.ranges[j] <= s.ranges[i+1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sample returns a printable character from the set, preferring letters
+func (s runeSet) sample() string {
+	if s.any {
+		return "a"
+	}
+	for _, r := range []rune{'a', 'x', '0', ' ', '.'} {
+		if inClass(s.ranges, r) {
+			return string(r)
+		}
+	}
+	if len(s.ranges) > 0 {
+		return string(s.ranges[0])
+	}
+	return ""
+}
+
+// firstSet returns the characters node can start with
+func firstSet(node *syntax.Regexp) runeSet {
+	switch node.Op {
+	case syntax.OpLiteral:
+		if len(node.Rune) == 0 {
+			return runeSet{}
+		}
+		r := node.Rune[0]
+		if node.Flags&syntax.FoldCase != 0 {
+			lo, up := unicode.ToLower(r), unicode.ToUpper(r)
+			return runeSet{ranges: []rune{lo, lo, up, up}}
+		}
+		return runeSet{ranges: []rune{r, r}}
+	case syntax.OpCharClass:
+		return runeSet{ranges: node.Rune}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return runeSet{any: true}
+	case syntax.OpCapture, syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		return firstSet(node.Sub[0])
+	case syntax.OpAlternate:
+		set := runeSet{}
+		for _, sub := range node.Sub {
+			s := firstSet(sub)
+			set.any = set.any || s.any
+			set.ranges = append(set.ranges, s.ranges...)
+		}
+		return set
+	case syntax.OpConcat:
+		set := runeSet{}
+		for _, sub := range node.Sub {
+			s := firstSet(sub)
+			set.any = set.any || s.any
+			set.ranges = append(set.ranges, s.ranges...)
+			if !nullable(sub) {
+				break
+			}
+		}
+		return set
+	}
+	return runeSet{}
+}
+
+// nullable reports whether node can match an empty string
+func nullable(node *syntax.Regexp) bool {
+	switch node.Op {
+	case syntax.OpLiteral:
+		return len(node.Rune) == 0
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL, syntax.OpNoMatch:
+		return false
+	case syntax.OpStar, syntax.OpQuest:
+		return true
+	case syntax.OpRepeat:
+		return node.Min == 0 || nullable(node.Sub[0])
+	case syntax.OpCapture, syntax.OpPlus:
+		return nullable(node.Sub[0])
+	case syntax.OpAlternate:
+		for _, sub := range node.Sub {
+			if nullable(sub) {
+				return true
+			}
+		}
+		return false
+	case syntax.OpConcat:
+		for _, sub := range node.Sub {
+			if !nullable(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+// millis converts a duration to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
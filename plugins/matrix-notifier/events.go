@@ -0,0 +1,4 @@
+package matrixnotifier
+
+// EventTest is the type of the events the test button sends
+const EventTest = "test"
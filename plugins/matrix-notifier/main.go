@@ -0,0 +1,556 @@
+// Matrix Notifier Plugin for UnrealIRCd Web Panel
+// Posts bans and alerts from other plugins into a Matrix room through the
+// client-server API
+
+package matrixnotifier
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Limits on queued events and delivery attempts
+const (
+	queueSize   = 200
+	maxBatch    = 20
+	maxAttempts = 3
+)
+
+// MatrixNotifierPlugin implements the Plugin interface
+type MatrixNotifierPlugin struct {
+	config       Config
+	matrix       *matrixClient
+	roomID       string
+	userID       string
+	queue        chan notify.Event
+	received     int
+	sent         int
+	failed       int
+	dropped      int
+	lastSent     time.Time
+	sendErr      string
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	StreamURL     string `json:"stream_url"`
+	LogSources    string `json:"log_sources"`
+	Homeserver    string `json:"homeserver"`
+	AccessToken   string `json:"access_token"`
+	Room          string `json:"room"`
+	Notice        bool   `json:"notice"`
+	Events        string `json:"events"`
+	MinSeverity   string `json:"min_severity"`
+	EventsToken   string `json:"events_token"`
+	RatePerMinute int    `json:"rate_per_minute"`
+	NotifyBans    bool   `json:"notify_bans"`
+	BanTypes      string `json:"ban_types"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &MatrixNotifierPlugin{
+		config: Config{
+			StreamURL:     "wss://127.0.0.1:8600/",
+			LogSources:    "tkl",
+			Notice:        true,
+			Events:        "*",
+			MinSeverity:   notify.SeverityInfo,
+			RatePerMinute: 20,
+			NotifyBans:    true,
+		},
+		queue: make(chan notify.Event, queueSize),
+	}
+}
+
+// Info returns plugin metadata
+func (p *MatrixNotifierPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Matrix Notifier",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Posts bans and plugin alerts into a Matrix room",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *MatrixNotifierPlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "matrix-notifier-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"sent":   p.sent,
+			"failed": p.failed,
+			"queued": len(p.queue),
+		}
+		if !p.configured() {
+			content["status"] = "Not configured"
+		}
+		return plugins.DashboardCard{
+			Title:   "Matrix Notifier",
+			Icon:    "MessageCircle",
+			Content: content,
+			Order:   65,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.sendLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *MatrixNotifierPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *MatrixNotifierPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/matrix-notifier")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/test", p.handleTest)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// configured reports whether messages can be sent. Caller must hold p.mu.
+func (p *MatrixNotifierPlugin) configured() bool {
+	return p.config.Homeserver != "" && p.config.AccessToken != "" && p.config.Room != ""
+}
+
+// matrixFor returns the Matrix client and the room to post to, or nil when
+// the homeserver, access token or room is not set. Caller must hold p.mu.
+func (p *MatrixNotifierPlugin) matrixFor() (*matrixClient, string) {
+	if !p.configured() {
+		return nil, ""
+	}
+	if p.matrix == nil {
+		p.matrix = newMatrixClient(p.config.Homeserver, p.config.AccessToken)
+	}
+	return p.matrix, p.config.Room
+}
+
+// msgType returns the message type events are posted as. Caller must hold
+// p.mu.
+func (p *MatrixNotifierPlugin) msgType() string {
+	if p.config.Notice {
+		return "m.notice"
+	}
+	return "m.text"
+}
+
+// wanted reports whether an event is posted to the room. The events
+// setting lists event types or source plugins, or * for everything.
+// Caller must hold p.mu.
+func (p *MatrixNotifierPlugin) wanted(ev notify.Event) bool {
+	if notify.SeverityRank[ev.Severity] < notify.SeverityRank[p.config.MinSeverity] {
+		return false
+	}
+	for _, e := range lists.Split(p.config.Events) {
+		if e == "*" || strings.EqualFold(e, ev.Type) || strings.EqualFold(e, ev.Source) {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch queues an event for the room. Events arriving while the queue
+// is full are dropped.
+func (p *MatrixNotifierPlugin) dispatch(ev notify.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.received++
+	if !p.wanted(ev) {
+		return
+	}
+	select {
+	case p.queue <- ev:
+	default:
+		p.dropped++
+	}
+}
+
+// streamLoop follows ban log events until shutdown
+func (p *MatrixNotifierPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *MatrixNotifierPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[matrix-notifier] log stream: %v", err)
+	}
+}
+
+// handleEvent announces new server bans
+func (p *MatrixNotifierPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	p.mu.RLock()
+	enabled := p.config.NotifyBans
+	types := lists.Split(p.config.BanTypes)
+	p.mu.RUnlock()
+	if !enabled {
+		return
+	}
+	if a, ok := notify.BanEvent("matrix-notifier", ev, types); ok {
+		p.dispatch(a)
+	}
+}
+
+// sendLoop delivers queued events one message at a time, waiting between
+// messages to stay within rate_per_minute. A backlog is packed into as few
+// messages as fit.
+func (p *MatrixNotifierPlugin) sendLoop() {
+	defer p.wg.Done()
+
+	for {
+		var first notify.Event
+		select {
+		case <-p.stop:
+			return
+		case first = <-p.queue:
+		}
+		events := []formatted{formatEvent(first)}
+	drain:
+		for len(events) < maxBatch {
+			select {
+			case ev := <-p.queue:
+				events = append(events, formatEvent(ev))
+			default:
+				break drain
+			}
+		}
+
+		p.mu.RLock()
+		messages, counts := joinMessages(events, p.msgType())
+		p.mu.RUnlock()
+		for i, msg := range messages {
+			p.mu.Lock()
+			mc, room := p.matrixFor()
+			interval := time.Minute
+			if p.config.RatePerMinute > 0 {
+				interval = time.Minute / time.Duration(p.config.RatePerMinute)
+			}
+			p.mu.Unlock()
+
+			var err error
+			if mc == nil {
+				err = fmt.Errorf("homeserver, access_token and room are not set")
+			} else {
+				err = p.send(mc, room, msg)
+			}
+
+			p.mu.Lock()
+			if err != nil {
+				p.failed += counts[i]
+				p.sendErr = err.Error()
+			} else {
+				p.sent += counts[i]
+				p.sendErr = ""
+				p.lastSent = time.Now().UTC()
+			}
+			p.mu.Unlock()
+			if err != nil {
+				log.Printf("[matrix-notifier] delivery failed: %v", err)
+			}
+
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(interval):
+			}
+		}
+	}
+}
+
+// joinedRoom returns the ID of the room, joining it first if the plugin
+// has not done so since the client was created. Joining also accepts an
+// invite and resolves an alias.
+func (p *MatrixNotifierPlugin) joinedRoom(ctx context.Context, mc *matrixClient, room string) (string, error) {
+	p.mu.RLock()
+	id := p.roomID
+	p.mu.RUnlock()
+	if id != "" {
+		return id, nil
+	}
+
+	id, err := mc.join(ctx, room)
+	if err != nil {
+		return "", fmt.Errorf("join %s: %w", room, err)
+	}
+	user, err := mc.whoami(ctx)
+	if err != nil {
+		log.Printf("[matrix-notifier] whoami: %v", err)
+	}
+
+	p.mu.Lock()
+	// The config may have changed while joining
+	if p.matrix == mc {
+		p.roomID = id
+		p.userID = user
+	}
+	p.mu.Unlock()
+	return id, nil
+}
+
+// forgetRoom makes the next message join the room again
+func (p *MatrixNotifierPlugin) forgetRoom(mc *matrixClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.matrix == mc {
+		p.roomID = ""
+	}
+}
+
+// send posts a message, waiting and trying again when the homeserver rate
+// limits the user. Every attempt uses the same transaction ID, so a retry
+// never posts the message twice.
+func (p *MatrixNotifierPlugin) send(mc *matrixClient, room string, msg message) error {
+	txnID := newTxnID()
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		var roomID string
+		roomID, err = p.joinedRoom(ctx, mc, room)
+		if err == nil {
+			err = mc.send(ctx, roomID, txnID, msg)
+		}
+		cancel()
+
+		var me *matrixError
+		ok := errors.As(err, &me)
+		if ok && me.Status == http.StatusForbidden {
+			// Kicked or otherwise no longer in the room
+			p.forgetRoom(mc)
+		}
+		if !ok || me.RetryAfter == 0 {
+			return err
+		}
+		select {
+		case <-p.stop:
+			return err
+		case <-time.After(me.RetryAfter):
+		}
+	}
+	return err
+}
+
+// handleStatus reports the Matrix user and room, the event sources and the
+// queue
+func (p *MatrixNotifierPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"configured":     p.configured(),
+		"user_id":        p.userID,
+		"room_id":        p.roomID,
+		"log_stream":     p.streamOK,
+		"notify_bans":    p.config.NotifyBans,
+		"events_enabled": p.config.EventsToken != "",
+		"received":       p.received,
+		"queued":         len(p.queue),
+		"sent":           p.sent,
+		"failed":         p.failed,
+		"dropped":        p.dropped,
+		"last_sent":      p.lastSent,
+		"send_error":     p.sendErr,
+	})
+}
+
+// handleTest posts a test message to the room straight away and reports
+// the result
+func (p *MatrixNotifierPlugin) handleTest(c *gin.Context) {
+	p.mu.Lock()
+	mc, room := p.matrixFor()
+	msgType := p.msgType()
+	p.mu.Unlock()
+	if mc == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set homeserver, access_token and room first"})
+		return
+	}
+
+	messages, _ := joinMessages([]formatted{formatEvent(notify.Event{
+		Source:   "matrix-notifier",
+		Type:     EventTest,
+		Severity: notify.SeverityInfo,
+		Title:    "Test message",
+		Message:  fmt.Sprintf("Sent by %s from the web panel.", access.Actor(c)),
+	})}, msgType)
+	if err := p.send(mc, room, messages[0]); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test message sent"})
+}
+
+// handleEvents accepts an alert from another plugin or script, sent with
+// the events token in an X-Events-Token header
+func (p *MatrixNotifierPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+
+	p.dispatch(ev)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *MatrixNotifierPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AccessToken = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *MatrixNotifierPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.RatePerMinute < 1 || newConfig.RatePerMinute > 60 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate_per_minute must be between 1 and 60"})
+		return
+	}
+	if _, ok := notify.SeverityRank[newConfig.MinSeverity]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_severity must be info, warning or critical"})
+		return
+	}
+	if newConfig.Homeserver != "" {
+		if u, err := url.Parse(newConfig.Homeserver); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "homeserver must be an http(s) URL"})
+			return
+		}
+	}
+	if newConfig.Room != "" && !strings.HasPrefix(newConfig.Room, "!") && !strings.HasPrefix(newConfig.Room, "#") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "room must be a room ID (!id:server) or alias (#name:server)"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.AccessToken == "" {
+		newConfig.AccessToken = p.config.AccessToken
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.Homeserver != p.config.Homeserver || newConfig.AccessToken != p.config.AccessToken || newConfig.Room != p.config.Room {
+		p.matrix = nil
+		p.roomID = ""
+		p.userID = ""
+	}
+	p.config = newConfig
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *MatrixNotifierPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *MatrixNotifierPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.matrix = nil
+	p.roomID = ""
+	p.userID = ""
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,257 @@
+package matrixnotifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Message limits. Matrix events may be up to 64 KiB including their
+// envelope; both bodies of a message are counted towards maxMessageLen.
+const (
+	maxMessageLen = 32000
+	maxEventLen   = 4000
+)
+
+// severityLabels prefix the title of each event
+var severityLabels = map[string]string{
+	notify.SeverityInfo:     "ℹ️",
+	notify.SeverityWarning:  "⚠️",
+	notify.SeverityCritical: "🚨",
+}
+
+// txnCounter makes transaction IDs unique within a run of the panel
+var txnCounter uint64
+
+// matrixClient is a minimal client for the Matrix client-server API
+type matrixClient struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// matrixError is an error returned by the homeserver. RetryAfter is set
+// when the request was rate limited.
+type matrixError struct {
+	Status     int
+	ErrCode    string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *matrixError) Error() string {
+	if e.ErrCode == "" {
+		return fmt.Sprintf("matrix error %d", e.Status)
+	}
+	return fmt.Sprintf("matrix error %d: %s: %s", e.Status, e.ErrCode, e.Message)
+}
+
+// message is an m.room.message event with an HTML body
+type message struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// newMatrixClient creates a client for the homeserver, acting as the user
+// the access token belongs to
+func newMatrixClient(homeserver, token string) *matrixClient {
+	return &matrixClient{
+		url:    strings.TrimRight(homeserver, "/") + "/_matrix/client/v3",
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a request to the homeserver and decodes its response into out
+func (m *matrixClient) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.url+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		me := &matrixError{Status: resp.StatusCode}
+		var detail struct {
+			ErrCode      string `json:"errcode"`
+			Error        string `json:"error"`
+			RetryAfterMS int64  `json:"retry_after_ms"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&detail) == nil {
+			me.ErrCode = detail.ErrCode
+			me.Message = detail.Error
+			me.RetryAfter = time.Duration(detail.RetryAfterMS) * time.Millisecond
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && me.RetryAfter == 0 {
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				me.RetryAfter = time.Duration(secs) * time.Second
+			} else {
+				me.RetryAfter = 5 * time.Second
+			}
+		}
+		return me
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// whoami returns the user ID the access token belongs to
+func (m *matrixClient) whoami(ctx context.Context) (string, error) {
+	var res struct {
+		UserID string `json:"user_id"`
+	}
+	err := m.do(ctx, http.MethodGet, "/account/whoami", nil, &res)
+	return res.UserID, err
+}
+
+// join joins a room by ID or alias, accepting a pending invite, and
+// returns the room ID. Joining a room the user is already in does nothing.
+func (m *matrixClient) join(ctx context.Context, room string) (string, error) {
+	var res struct {
+		RoomID string `json:"room_id"`
+	}
+	err := m.do(ctx, http.MethodPost, "/join/"+url.PathEscape(room), map[string]interface{}{}, &res)
+	return res.RoomID, err
+}
+
+// newTxnID returns a transaction ID for a new message. Sending again with
+// the same ID does not post the message twice.
+func newTxnID() string {
+	return fmt.Sprintf("uwp%d.%d", time.Now().UnixNano(), atomic.AddUint64(&txnCounter, 1))
+}
+
+// send posts a message to a room
+func (m *matrixClient) send(ctx context.Context, roomID, txnID string, msg message) error {
+	path := fmt.Sprintf("/rooms/%s/send/m.room.message/%s", url.PathEscape(roomID), url.PathEscape(txnID))
+	return m.do(ctx, http.MethodPut, path, msg, nil)
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// formatted is an event rendered as plain text and as HTML
+type formatted struct {
+	Text string
+	HTML string
+}
+
+// formatEvent renders an event with its data as a list of fields
+func formatEvent(ev notify.Event) formatted {
+	var text, markup strings.Builder
+	title := truncate(ev.Title, 256)
+	fmt.Fprintf(&text, "%s %s", severityLabels[ev.Severity], title)
+	fmt.Fprintf(&markup, "%s <strong>%s</strong>", severityLabels[ev.Severity], html.EscapeString(title))
+	if ev.Message != "" && ev.Message != ev.Title {
+		msg := truncate(ev.Message, 2048)
+		fmt.Fprintf(&text, "\n%s", msg)
+		fmt.Fprintf(&markup, "<br>%s", html.EscapeString(msg))
+	}
+
+	keys := make([]string, 0, len(ev.Data))
+	for k := range ev.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var value string
+		switch v := ev.Data[k].(type) {
+		case string:
+			value = v
+		case []interface{}:
+			parts := make([]string, 0, len(v))
+			for _, item := range v {
+				parts = append(parts, fmt.Sprint(item))
+			}
+			value = strings.Join(parts, ", ")
+		case []string:
+			value = strings.Join(v, ", ")
+		default:
+			value = fmt.Sprint(v)
+		}
+		if value == "" {
+			continue
+		}
+		name := strings.ReplaceAll(k, "_", " ")
+		value = truncate(value, 256)
+		line := fmt.Sprintf("<br><strong>%s:</strong> %s", html.EscapeString(name), html.EscapeString(value))
+		if markup.Len()+len(line) > maxEventLen {
+			break
+		}
+		markup.WriteString(line)
+		fmt.Fprintf(&text, "\n%s: %s", name, value)
+	}
+
+	if ev.Source != "" {
+		fmt.Fprintf(&text, "\n%s · %s", ev.Source, ev.Type)
+		fmt.Fprintf(&markup, "<br><em>%s · %s</em>", html.EscapeString(ev.Source), html.EscapeString(ev.Type))
+	}
+	return formatted{Text: text.String(), HTML: markup.String()}
+}
+
+// joinMessages packs formatted events into as few messages as fit within
+// maxMessageLen, returning the messages and how many events each holds
+func joinMessages(events []formatted, msgType string) ([]message, []int) {
+	messages := make([]message, 0, 1)
+	counts := make([]int, 0, 1)
+	for _, f := range events {
+		last := len(messages) - 1
+		if last >= 0 {
+			m := &messages[last]
+			if len(m.Body)+len(m.FormattedBody)+len(f.Text)+len(f.HTML)+8 <= maxMessageLen {
+				m.Body += "\n\n" + f.Text
+				m.FormattedBody += "<hr>" + f.HTML
+				counts[last]++
+				continue
+			}
+		}
+		messages = append(messages, message{
+			MsgType:       msgType,
+			Body:          f.Text,
+			Format:        "org.matrix.custom.html",
+			FormattedBody: f.HTML,
+		})
+		counts = append(counts, 1)
+	}
+	return messages, counts
+}
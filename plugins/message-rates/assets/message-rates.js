@@ -0,0 +1,235 @@
+/**
+ * Message Rates Frontend Script
+ *
+ * Charts messages per minute and users online for each hour.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'message-rates';
+  const PLUGIN_NAME = 'Message Rates';
+  const PAGE_PATH = '/plugins/message-rates';
+  const API_BASE = '/api/plugin/message-rates';
+
+  let rangeHours = 24;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function hourLabel(h) {
+    return new Date(h.start).toLocaleString([], { month: 'short', day: 'numeric', hour: '2-digit', minute: '2-digit' });
+  }
+
+  // Stacked bars of private messages and notices per minute
+  function rateChart(hours, width, height) {
+    const max = Math.max(...hours.map(h => (h.privmsg_per_min || 0) + (h.notice_per_min || 0)), 1);
+    const barWidth = width / hours.length;
+
+    const bars = hours.map((h, i) => {
+      if (h.privmsg_per_min == null) return '';
+      const x = i * barWidth + 0.5;
+      const priv = (h.privmsg_per_min / max) * (height - 10);
+      const notice = (h.notice_per_min / max) * (height - 10);
+      return `
+        <rect x="${x}" y="${height - priv}" width="${Math.max(barWidth - 1, 1)}" height="${priv}" fill="var(--accent, #89b4fa)">
+          <title>${escapeHtml(hourLabel(h))}: ${h.privmsg_per_min} messages/min</title>
+        </rect>
+        <rect x="${x}" y="${height - priv - notice}" width="${Math.max(barWidth - 1, 1)}" height="${notice}" fill="var(--warning, #f9e2af)">
+          <title>${escapeHtml(hourLabel(h))}: ${h.notice_per_min} notices/min</title>
+        </rect>
+      `;
+    }).join('');
+
+    return `
+      <svg viewBox="0 0 ${width} ${height}" class="mrt-chart" preserveAspectRatio="none">${bars}</svg>
+    `;
+  }
+
+  // Line of the average users online
+  function userChart(hours, width, height) {
+    const max = Math.max(...hours.map(h => h.avg_users || 0), 1);
+    const step = width / Math.max(hours.length - 1, 1);
+    const runs = [];
+    let run = [];
+    hours.forEach((h, i) => {
+      if (h.avg_users == null) {
+        if (run.length) runs.push(run);
+        run = [];
+        return;
+      }
+      const y = height - (h.avg_users / max) * (height - 10);
+      run.push(`${(i * step).toFixed(1)},${y.toFixed(1)}`);
+    });
+    if (run.length) runs.push(run);
+
+    return `
+      <svg viewBox="0 0 ${width} ${height}" class="mrt-chart" preserveAspectRatio="none">
+        ${runs.map(r => `<polyline points="${r.join(' ')}" fill="none" stroke="var(--success, #a6e3a1)" stroke-width="2"/>`).join('')}
+      </svg>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('message-rates-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'message-rates-styles';
+    style.textContent = `
+      .mrt-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .mrt-tiles { display: grid; grid-template-columns: repeat(auto-fit, minmax(140px, 1fr)); gap: 0.75rem; }
+      .mrt-tile {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        padding: 0.75rem;
+      }
+      .mrt-tile strong { display: block; font-size: 1.5rem; color: var(--text-primary, #cdd6f4); }
+      .mrt-chart { width: 100%; height: 180px; background: var(--bg-secondary, #181825); border-radius: 8px; }
+      .mrt-legend { font-size: 0.8rem; }
+      .mrt-legend span { display: inline-block; width: 10px; height: 10px; border-radius: 2px; margin: 0 0.3rem 0 0.75rem; }
+      .mrt-range button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        margin-right: 0.25rem;
+        cursor: pointer;
+      }
+      .mrt-range button.active { background: var(--accent, #89b4fa); color: #fff; }
+      .mrt-warning { color: var(--warning, #f9e2af); }
+      .mrt-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#mrt-body');
+    try {
+      const status = await api('/status');
+      const history = await api(`/history?hours=${Math.min(rangeHours, status.max_hours)}`);
+      const current = await api('/current').catch(() => null);
+
+      const hours = history.hours;
+      const measured = hours.reduce((sum, h) => sum + h.measured_seconds, 0);
+      const messages = history.privmsg + history.notice;
+      const perMin = measured ? (messages / (measured / 60)).toFixed(1) : '-';
+      const nowRate = current && current.privmsg_per_min != null
+        ? (current.privmsg_per_min + current.notice_per_min).toFixed(1)
+        : '-';
+
+      body.innerHTML = `
+        ${status.counting ? '' : '<div class="mrt-warning">No message source is set up, so only users are recorded. See the plugin settings.</div>'}
+        ${status.error ? `<div class="mrt-error">Last sample: ${escapeHtml(status.error)}</div>` : ''}
+        <div class="mrt-tiles">
+          <div class="mrt-tile"><strong>${nowRate}</strong>messages/min now</div>
+          <div class="mrt-tile"><strong>${current && current.users != null ? current.users : '-'}</strong>users online</div>
+          <div class="mrt-tile"><strong>${messages.toLocaleString()}</strong>messages in range</div>
+          <div class="mrt-tile"><strong>${perMin}</strong>messages/min in range</div>
+        </div>
+        <h3>Messages per minute</h3>
+        <div class="mrt-legend">
+          <span style="background: var(--accent, #89b4fa)"></span>Private messages
+          <span style="background: var(--warning, #f9e2af)"></span>Notices
+        </div>
+        ${rateChart(hours, 600, 180)}
+        <h3>Average users online</h3>
+        ${userChart(hours, 600, 180)}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="mrt-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="mrt-app" data-plugin="${PLUGIN_ID}">
+        <div class="mrt-range">
+          ${[[24, '24 hours'], [168, '7 days'], [720, '30 days']].map(([h, label]) =>
+            `<button data-hours="${h}" class="${h === rangeHours ? 'active' : ''}">${label}</button>`).join('')}
+        </div>
+        <div id="mrt-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelectorAll('.mrt-range button').forEach(btn => {
+      btn.addEventListener('click', () => {
+        rangeHours = parseInt(btn.dataset.hours, 10);
+        container.querySelectorAll('.mrt-range button').forEach(b => b.classList.toggle('active', b === btn));
+        load(container);
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('message-rates-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
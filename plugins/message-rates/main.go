@@ -0,0 +1,622 @@
+// Message Rates Plugin for UnrealIRCd Web Panel
+// Samples how many private messages and notices the network carries,
+// alongside the user count, and keeps an hourly history of the rates
+
+package messagerates
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// MessageRatesPlugin implements the Plugin interface
+type MessageRatesPlugin struct {
+	config     Config
+	rpc        *jsonrpc.Client
+	hours      []*Hour
+	current    *Current
+	lastSample time.Time
+	sampleErr  string
+	dirty      bool
+	// Metrics source: the counters at the previous scrape
+	prevCounts []map[string]float64
+	prevScrape time.Time
+	// Log source: messages seen since pendingSince
+	pendingPrivmsg int64
+	pendingNotice  int64
+	pendingSince   time.Time
+	streamOK       bool
+	cancelStream   context.CancelFunc
+	restart        chan struct{}
+	mu             sync.RWMutex
+	stop           chan struct{}
+	wg             sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	Source         string `json:"source"`
+	MetricsURL     string `json:"metrics_url"`
+	PrivmsgCounter string `json:"privmsg_counter"`
+	NoticeCounter  string `json:"notice_counter"`
+	StreamURL      string `json:"stream_url"`
+	LogSources     string `json:"log_sources"`
+	PrivmsgEvents  string `json:"privmsg_events"`
+	NoticeEvents   string `json:"notice_events"`
+	SampleInterval int    `json:"sample_interval"`
+	RetentionDays  int    `json:"retention_days"`
+}
+
+// Current is the rate over the latest sample interval
+type Current struct {
+	Time          time.Time `json:"time"`
+	Users         *int      `json:"users"`
+	PrivmsgPerMin *float64  `json:"privmsg_per_min"`
+	NoticePerMin  *float64  `json:"notice_per_min"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Hours []*Hour `json:"hours"`
+}
+
+// statsResult is the part of stats.get we sample
+type statsResult struct {
+	User struct {
+		Total int `json:"total"`
+	} `json:"user"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &MessageRatesPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/message-rates",
+			Source:         SourceMetrics,
+			PrivmsgCounter: `unrealircd_messages_total{type="privmsg"}`,
+			NoticeCounter:  `unrealircd_messages_total{type="notice"}`,
+			StreamURL:      "wss://127.0.0.1:8600/",
+			LogSources:     "all,!debug",
+			SampleInterval: 60,
+			RetentionDays:  30,
+		},
+		hours:   make([]*Hour, 0),
+		restart: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *MessageRatesPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Message Rates",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Keeps an hourly history of message throughput alongside user counts",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *MessageRatesPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[message-rates] failed to load data: %v", err)
+	}
+	if data.Hours != nil {
+		p.hours = data.Hours
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "message-rates-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"messages_24h": p.total(24),
+		}
+		if cur := p.current; cur != nil {
+			if cur.PrivmsgPerMin != nil {
+				content["per_minute"] = round1(*cur.PrivmsgPerMin + *cur.NoticePerMin)
+			}
+			if cur.Users != nil {
+				content["users"] = *cur.Users
+			}
+		}
+		if p.sampleErr != "" {
+			content["status"] = p.sampleErr
+		}
+		return plugins.DashboardCard{
+			Title:   "Message Rates",
+			Icon:    "MessageSquare",
+			Content: content,
+			Order:   87,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.sampleLoop()
+	go p.streamLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *MessageRatesPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *MessageRatesPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/message-rates")
+	{
+		plugin.GET("/history", p.handleHistory)
+		plugin.GET("/current", p.handleCurrent)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file the hourly history is kept in
+func (p *MessageRatesPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "hours.json")
+}
+
+// save writes the history to disk if it changed
+func (p *MessageRatesPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Hours: p.hours}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *MessageRatesPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// counterSpecs returns the counters read from the metrics page: the
+// private message counter and, if set, the notice counter
+func counterSpecs(cfg Config) ([]counterSpec, error) {
+	priv, err := parseCounterSpec(cfg.PrivmsgCounter)
+	if err != nil {
+		return nil, err
+	}
+	specs := []counterSpec{priv}
+	if strings.TrimSpace(cfg.NoticeCounter) != "" {
+		notice, err := parseCounterSpec(cfg.NoticeCounter)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, notice)
+	}
+	return specs, nil
+}
+
+// sampleLoop samples the network every sample_interval until shutdown
+func (p *MessageRatesPlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.sample()
+		if err := p.save(); err != nil {
+			log.Printf("[message-rates] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// sample reads the user count and the messages carried since the last
+// sample, and adds them to the current hour
+func (p *MessageRatesPlugin) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	var stats statsResult
+	statsErr := p.client().Call(ctx, "stats.get", nil, &stats)
+
+	var counts []map[string]float64
+	var scrapeErr error
+	if cfg.Source == SourceMetrics && cfg.MetricsURL != "" {
+		specs, err := counterSpecs(cfg)
+		if err == nil {
+			counts, err = scrapeCounters(ctx, cfg.MetricsURL, specs)
+		}
+		scrapeErr = err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now().UTC()
+	var h *Hour
+	p.hours, h = hourAt(p.hours, now)
+	cur := &Current{Time: now}
+
+	var errs []string
+	if statsErr != nil {
+		errs = append(errs, "stats: "+statsErr.Error())
+	} else {
+		users := stats.User.Total
+		cur.Users = &users
+		h.UserSum += int64(users)
+		h.UserSamples++
+		if users > h.PeakUsers {
+			h.PeakUsers = users
+		}
+	}
+
+	// Work out the messages carried and the time they were counted over
+	var privmsg, notice int64
+	var elapsed time.Duration
+	switch cfg.Source {
+	case SourceMetrics:
+		if scrapeErr != nil {
+			errs = append(errs, "metrics: "+scrapeErr.Error())
+			break
+		}
+		if counts == nil {
+			break
+		}
+		// After a long gap, start counting afresh rather than put all
+		// that was missed into this hour
+		gap := now.Sub(p.prevScrape)
+		if p.prevCounts != nil && gap <= 3*time.Duration(cfg.SampleInterval)*time.Second {
+			elapsed = gap
+			privmsg = counterIncrease(p.prevCounts[0], counts[0])
+			if len(counts) > 1 {
+				notice = counterIncrease(p.prevCounts[1], counts[1])
+			}
+		}
+		p.prevCounts = counts
+		p.prevScrape = now
+	case SourceLog:
+		if p.streamOK {
+			elapsed = now.Sub(p.pendingSince)
+			privmsg, notice = p.pendingPrivmsg, p.pendingNotice
+		} else {
+			errs = append(errs, "log stream not connected")
+		}
+		p.pendingPrivmsg, p.pendingNotice = 0, 0
+		p.pendingSince = now
+	}
+	if elapsed > 0 {
+		h.Privmsg += privmsg
+		h.Notice += notice
+		h.Measured += int(math.Round(elapsed.Seconds()))
+		if h.Measured > 3600 {
+			h.Measured = 3600
+		}
+		minutes := elapsed.Minutes()
+		privRate := round1(float64(privmsg) / minutes)
+		noticeRate := round1(float64(notice) / minutes)
+		cur.PrivmsgPerMin = &privRate
+		cur.NoticePerMin = &noticeRate
+	}
+
+	p.current = cur
+	p.lastSample = now
+	p.sampleErr = strings.Join(errs, "; ")
+	if p.sampleErr != "" {
+		log.Printf("[message-rates] sample incomplete: %s", p.sampleErr)
+	}
+	p.hours = pruneHours(p.hours, now.AddDate(0, 0, -cfg.RetentionDays))
+	p.dirty = true
+}
+
+// streamLoop follows the IRCd log while the log source is chosen, until
+// shutdown
+func (p *MessageRatesPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		if p.config.Source != SourceLog {
+			p.cancelStream = nil
+			p.mu.Unlock()
+			select {
+			case <-p.stop:
+				return
+			case <-p.restart:
+				continue
+			}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		privmsg := eventSet(p.config.PrivmsgEvents)
+		notice := eventSet(p.config.NoticeEvents)
+		p.mu.Unlock()
+
+		stream.Run(ctx, func(ev jsonrpc.LogEvent) { p.countEvent(ev, privmsg, notice) }, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream. Counting starts
+// afresh whenever the stream connects.
+func (p *MessageRatesPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[message-rates] log stream: %v", err)
+		return
+	}
+	p.pendingPrivmsg, p.pendingNotice = 0, 0
+	p.pendingSince = time.Now().UTC()
+}
+
+// countEvent counts a log event that stands for a message
+func (p *MessageRatesPlugin) countEvent(ev jsonrpc.LogEvent, privmsg, notice map[string]bool) {
+	id := strings.ToUpper(ev.EventID)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case privmsg[id]:
+		p.pendingPrivmsg++
+	case notice[id]:
+		p.pendingNotice++
+	}
+}
+
+// eventSet reads a comma separated list of event IDs
+func eventSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, id := range lists.Split(s) {
+		set[strings.ToUpper(id)] = true
+	}
+	return set
+}
+
+// total returns the messages counted in the last n hours. Caller must
+// hold p.mu.
+func (p *MessageRatesPlugin) total(n int) int64 {
+	cutoff := time.Now().UTC().Truncate(time.Hour).Add(-time.Duration(n-1) * time.Hour)
+	var sum int64
+	for _, h := range p.hours {
+		if !h.Start.Before(cutoff) {
+			sum += h.Privmsg + h.Notice
+		}
+	}
+	return sum
+}
+
+// handleHistory returns the rates of each hour in the last ?hours=
+// hours, 24 by default, oldest first. Hours with nothing sampled are
+// included with empty rates.
+func (p *MessageRatesPlugin) handleHistory(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	max := p.config.RetentionDays * 24
+	n := 24
+	if s := c.Query("hours"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > max {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be between 1 and " + strconv.Itoa(max)})
+			return
+		}
+		n = v
+	}
+
+	byStart := make(map[time.Time]*Hour, len(p.hours))
+	for _, h := range p.hours {
+		byStart[h.Start] = h
+	}
+	first := time.Now().UTC().Truncate(time.Hour).Add(-time.Duration(n-1) * time.Hour)
+	out := make([]HourRate, 0, n)
+	var privmsg, notice int64
+	for i := 0; i < n; i++ {
+		start := first.Add(time.Duration(i) * time.Hour)
+		h, ok := byStart[start]
+		if !ok {
+			h = &Hour{Start: start}
+		}
+		privmsg += h.Privmsg
+		notice += h.Notice
+		out = append(out, h.rate())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"source":  p.config.Source,
+		"hours":   out,
+		"privmsg": privmsg,
+		"notice":  notice,
+	})
+}
+
+// handleCurrent returns the rates over the latest sample interval
+func (p *MessageRatesPlugin) handleCurrent(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.current == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No sample taken yet"})
+		return
+	}
+	c.JSON(http.StatusOK, p.current)
+}
+
+// handleStatus returns sampling and log stream state
+func (p *MessageRatesPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"source":    p.config.Source,
+		"hours":     len(p.hours),
+		"max_hours": p.config.RetentionDays * 24,
+		"error":     p.sampleErr,
+	}
+	switch p.config.Source {
+	case SourceMetrics:
+		status["counting"] = p.config.MetricsURL != ""
+	case SourceLog:
+		status["counting"] = p.config.PrivmsgEvents != "" || p.config.NoticeEvents != ""
+		status["streaming"] = p.streamOK
+	}
+	if !p.lastSample.IsZero() {
+		status["last_sample"] = p.lastSample
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *MessageRatesPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *MessageRatesPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	switch newConfig.Source {
+	case SourceMetrics, SourceLog:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source must be metrics or log"})
+		return
+	}
+	newConfig.MetricsURL = strings.TrimSpace(newConfig.MetricsURL)
+	if newConfig.MetricsURL != "" && !strings.HasPrefix(newConfig.MetricsURL, "http://") && !strings.HasPrefix(newConfig.MetricsURL, "https://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metrics_url must start with http:// or https://"})
+		return
+	}
+	if newConfig.Source == SourceMetrics {
+		if _, err := counterSpecs(newConfig); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid counter: " + err.Error()})
+			return
+		}
+	}
+	if newConfig.SampleInterval < 10 || newConfig.SampleInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sample_interval must be between 10 and 3600 seconds"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 365"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	// Counting starts afresh with the new source
+	p.prevCounts = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	select {
+	case p.restart <- struct{}{}:
+	default:
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *MessageRatesPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *MessageRatesPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
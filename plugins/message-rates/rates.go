@@ -0,0 +1,93 @@
+package messagerates
+
+import (
+	"sort"
+	"time"
+)
+
+// Message sources
+const (
+	SourceMetrics = "metrics"
+	SourceLog     = "log"
+)
+
+// Hour holds what was counted in one UTC hour
+type Hour struct {
+	Start   time.Time `json:"start"`
+	Privmsg int64     `json:"privmsg"`
+	Notice  int64     `json:"notice"`
+	// Seconds of the hour over which messages were counted
+	Measured    int   `json:"measured"`
+	UserSum     int64 `json:"user_sum"`
+	UserSamples int   `json:"user_samples"`
+	PeakUsers   int   `json:"peak_users"`
+}
+
+// HourRate is an hour as served by the API
+type HourRate struct {
+	Start         time.Time `json:"start"`
+	Privmsg       int64     `json:"privmsg"`
+	Notice        int64     `json:"notice"`
+	Messages      int64     `json:"messages"`
+	Measured      int       `json:"measured_seconds"`
+	PrivmsgPerMin *float64  `json:"privmsg_per_min"`
+	NoticePerMin  *float64  `json:"notice_per_min"`
+	AvgUsers      *float64  `json:"avg_users"`
+	PeakUsers     int       `json:"peak_users"`
+	// Messages each user sent in the hour, on average
+	PerUser *float64 `json:"messages_per_user"`
+}
+
+// rate turns an hour's counts into rates. Rates are only given for the
+// time actually measured, so gaps in sampling don't lower them.
+func (h *Hour) rate() HourRate {
+	r := HourRate{
+		Start:     h.Start,
+		Privmsg:   h.Privmsg,
+		Notice:    h.Notice,
+		Messages:  h.Privmsg + h.Notice,
+		Measured:  h.Measured,
+		PeakUsers: h.PeakUsers,
+	}
+	if h.Measured > 0 {
+		minutes := float64(h.Measured) / 60
+		priv := round1(float64(h.Privmsg) / minutes)
+		notice := round1(float64(h.Notice) / minutes)
+		r.PrivmsgPerMin = &priv
+		r.NoticePerMin = &notice
+	}
+	if h.UserSamples > 0 {
+		avg := round1(float64(h.UserSum) / float64(h.UserSamples))
+		r.AvgUsers = &avg
+		if h.Measured > 0 && avg > 0 {
+			per := round1(float64(r.Messages) * 3600 / float64(h.Measured) / avg)
+			r.PerUser = &per
+		}
+	}
+	return r
+}
+
+// hourAt returns the bucket of the hour t falls in, adding it if needed.
+// Hours must be sorted by start.
+func hourAt(hours []*Hour, t time.Time) ([]*Hour, *Hour) {
+	start := t.UTC().Truncate(time.Hour)
+	if n := len(hours); n > 0 && hours[n-1].Start.Equal(start) {
+		return hours, hours[n-1]
+	}
+	h := &Hour{Start: start}
+	return append(hours, h), h
+}
+
+// pruneHours drops hours that started before cutoff
+func pruneHours(hours []*Hour, cutoff time.Time) []*Hour {
+	drop := sort.Search(len(hours), func(i int) bool { return !hours[i].Start.Before(cutoff) })
+	if drop == 0 {
+		return hours
+	}
+	return append([]*Hour(nil), hours[drop:]...)
+}
+
+// round1 rounds to one decimal place
+func round1(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
@@ -0,0 +1,147 @@
+package messagerates
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// scrapeClient is used for metrics scrapes
+var scrapeClient = &http.Client{Timeout: 10 * time.Second}
+
+// counterSpec selects the series of a counter: a metric name, optionally
+// with labels that must all be present, as in
+// messages_total{type="privmsg"}
+type counterSpec struct {
+	Name   string
+	Labels []string
+}
+
+// parseCounterSpec reads a counter setting
+func parseCounterSpec(s string) (counterSpec, error) {
+	s = strings.TrimSpace(s)
+	spec := counterSpec{Name: s}
+	if i := strings.IndexByte(s, '{'); i >= 0 {
+		if !strings.HasSuffix(s, "}") {
+			return spec, fmt.Errorf("missing } in %q", s)
+		}
+		spec.Name = s[:i]
+		for _, l := range strings.Split(s[i+1:len(s)-1], ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				spec.Labels = append(spec.Labels, l)
+			}
+		}
+	}
+	if spec.Name == "" {
+		return spec, fmt.Errorf("no metric name in %q", s)
+	}
+	return spec, nil
+}
+
+// matches reports whether a series belongs to the counter
+func (c counterSpec) matches(name, labels string) bool {
+	if name != c.Name {
+		return false
+	}
+	for _, l := range c.Labels {
+		if !strings.Contains(labels, l) {
+			return false
+		}
+	}
+	return true
+}
+
+// scrapeCounters fetches a Prometheus text format page and returns the
+// value of every series of each counter, keyed by its labels
+func scrapeCounters(ctx context.Context, url string, specs []counterSpec) ([]map[string]float64, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := scrapeClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics: unexpected status %s", resp.Status)
+	}
+
+	out := make([]map[string]float64, len(specs))
+	for i := range out {
+		out[i] = make(map[string]float64)
+	}
+	sc := bufio.NewScanner(io.LimitReader(resp.Body, 16<<20))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name, labels, value, ok := parseSample(line)
+		if !ok {
+			continue
+		}
+		for i, spec := range specs {
+			if spec.matches(name, labels) {
+				out[i][labels] = value
+			}
+		}
+	}
+	return out, sc.Err()
+}
+
+// counterIncrease sums how much each series grew since prev. A series
+// that went down was reset, so all of its value is new; a series not seen
+// before is left out, as its growth is unknown.
+func counterIncrease(prev, cur map[string]float64) int64 {
+	var sum float64
+	for labels, v := range cur {
+		before, ok := prev[labels]
+		switch {
+		case !ok:
+		case v < before:
+			sum += v
+		default:
+			sum += v - before
+		}
+	}
+	return int64(sum)
+}
+
+// parseSample splits "name{labels} value [timestamp]"
+func parseSample(line string) (name, labels string, value float64, ok bool) {
+	rest := line
+	if i := strings.IndexByte(line, '{'); i >= 0 {
+		j := strings.LastIndexByte(line, '}')
+		if j < i {
+			return "", "", 0, false
+		}
+		name, labels, rest = line[:i], line[i+1:j], line[j+1:]
+	} else {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return "", "", 0, false
+		}
+		name, rest = fields[0], strings.Join(fields[1:], " ")
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", "", 0, false
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", "", 0, false
+	}
+	return name, labels, v, true
+}
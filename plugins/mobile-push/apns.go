@@ -0,0 +1,189 @@
+package mobilepush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// APNs hosts
+const (
+	apnsProduction = "https://api.push.apple.com"
+	apnsSandbox    = "https://api.sandbox.push.apple.com"
+)
+
+// apnsTokenAge is how long a provider token is reused. Apple rejects
+// tokens older than an hour and ones refreshed more than every 20 minutes.
+const apnsTokenAge = 45 * time.Minute
+
+// apnsClient sends through APNs with token-based authentication, signing
+// a provider token with the team's .p8 key
+type apnsClient struct {
+	key    *ecdsa.PrivateKey
+	keyID  string
+	teamID string
+	topic  string
+	host   string
+	http   *http.Client
+
+	mu     sync.Mutex
+	token  string
+	issued time.Time
+}
+
+// newAPNsClient loads a .p8 signing key
+func newAPNsClient(keyFile, keyID, teamID, topic string, sandbox bool) (*apnsClient, error) {
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, fmt.Errorf("apns_key_id, apns_team_id and apns_topic are required")
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("the APNs key is not PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the APNs key: %v", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the APNs key is not an EC key")
+	}
+
+	host := apnsProduction
+	if sandbox {
+		host = apnsSandbox
+	}
+	// APNs only speaks HTTP/2, which the default transport negotiates
+	return &apnsClient{
+		key:    key,
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+		host:   host,
+		http:   &http.Client{Timeout: 20 * time.Second},
+	}, nil
+}
+
+// providerToken returns the current provider token, signing a new one when
+// it is due
+func (a *apnsClient) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Since(a.issued) < apnsTokenAge {
+		return a.token, nil
+	}
+
+	now := time.Now()
+	token, err := signJWT(
+		map[string]interface{}{"alg": "ES256", "kid": a.keyID},
+		map[string]interface{}{"iss": a.teamID, "iat": now.Unix()},
+		func(digest []byte) ([]byte, error) {
+			r, s, err := ecdsa.Sign(rand.Reader, a.key, digest)
+			if err != nil {
+				return nil, err
+			}
+			// JWS wants r and s as two fixed-size big-endian integers
+			sig := make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+			return sig, nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	a.token = token
+	a.issued = now
+	return token, nil
+}
+
+// send pushes a notification to one device token
+func (a *apnsClient) send(ctx context.Context, token string, n notification) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	provider, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+
+	// Critical alerts break through Focus modes as time-sensitive; real
+	// critical alerts need an entitlement from Apple
+	level := "active"
+	priority := 5
+	if n.Severity == notify.SeverityCritical {
+		level = "time-sensitive"
+	}
+	if notify.SeverityRank[n.Severity] >= notify.SeverityRank[notify.SeverityWarning] {
+		priority = 10
+	}
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert":              map[string]string{"title": n.Title, "body": n.Body},
+			"sound":              "default",
+			"interruption-level": level,
+		},
+	}
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.host+"/3/device/"+token, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+provider)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", strconv.Itoa(priority))
+	req.Header.Set("apns-expiration", strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10))
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return &pushError{Reason: err.Error(), Retry: true}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.Unmarshal(body, &result)
+	pe := &pushError{Status: resp.StatusCode, Reason: firstNonEmpty(result.Reason, http.StatusText(resp.StatusCode))}
+	switch {
+	case resp.StatusCode == http.StatusGone, result.Reason == "BadDeviceToken", result.Reason == "DeviceTokenNotForTopic":
+		pe.Unregistered = true
+	case result.Reason == "ExpiredProviderToken":
+		a.mu.Lock()
+		a.token = ""
+		a.mu.Unlock()
+		pe.Retry = true
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		pe.Retry = true
+	}
+	return pe
+}
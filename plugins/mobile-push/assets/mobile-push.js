@@ -0,0 +1,275 @@
+/**
+ * Mobile Push Frontend Script
+ *
+ * Registers staff devices for push notifications, with their severity
+ * filter and quiet hours, sends test pushes and lists recent deliveries.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'mobile-push';
+  const PLUGIN_NAME = 'Mobile Push';
+  const PAGE_PATH = '/plugins/mobile-push';
+  const API_BASE = '/api/plugin/mobile-push';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const res = await fetch(API_BASE + path, {
+      method,
+      headers: getAuthHeaders(),
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t && !t.startsWith('0001') ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('mobile-push-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'mobile-push-styles';
+    style.textContent = `
+      .mpush-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .mpush-form { display: flex; gap: 0.5rem; align-items: flex-end; flex-wrap: wrap; }
+      .mpush-form label { display: flex; flex-direction: column; gap: 0.2rem; font-size: 0.8rem; }
+      .mpush-app input, .mpush-app select { background: var(--bg-secondary, #181825); color: var(--text-primary, #cdd6f4); border: 1px solid var(--border-primary, #313244); border-radius: 6px; padding: 0.35rem 0.5rem; }
+      .mpush-token { min-width: 18rem; }
+      .mpush-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .mpush-table th, .mpush-table td { text-align: left; padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .mpush-table td { color: var(--text-primary, #cdd6f4); }
+      .mpush-app button { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); border: none; border-radius: 6px; padding: 0.4rem 0.8rem; cursor: pointer; }
+      .mpush-app button:disabled { opacity: 0.5; cursor: default; }
+      .mpush-app button.mpush-danger { background: var(--error, #f38ba8); }
+      .mpush-ok, .mpush-sent { color: var(--success, #a6e3a1); }
+      .mpush-held { color: var(--warning, #f9e2af); }
+      .mpush-error, .mpush-failed { color: var(--error, #f38ba8); }
+      .mpush-desc { font-size: 0.8rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderStatus(s) {
+    const services = [
+      s.fcm_configured ? 'FCM' : '',
+      s.apns_configured ? 'APNs' : '',
+    ].filter(Boolean).join(' and ');
+    return `
+      <div>
+        ${services ? `Pushing through ${services}.` : '<span class="mpush-error">Neither FCM nor APNs is configured yet; set them up in the plugin settings.</span>'}
+        ${s.enabled} of ${s.devices} devices enabled, ${s.sent} sent, ${s.failed} failed,
+        ${s.held} held for quiet hours, ${s.repeats} repeats skipped.
+      </div>
+      ${s.send_error ? `<div class="mpush-error">Last error: ${escapeHtml(s.send_error)}</div>` : ''}
+    `;
+  }
+
+  function renderDevices(devices, admin) {
+    return `
+      <table class="mpush-table">
+        <thead><tr>${admin ? '<th>Owner</th>' : ''}<th>Device</th><th>Platform</th><th>Token</th><th>Minimum</th><th>Quiet hours</th><th>Last push</th><th></th></tr></thead>
+        <tbody>
+          ${devices.map(d => `
+            <tr>
+              ${admin ? `<td>${escapeHtml(d.owner)}</td>` : ''}
+              <td>${escapeHtml(d.name)}${d.enabled ? '' : ' <span class="mpush-error">(disabled)</span>'}</td>
+              <td>${d.platform === 'apns' ? 'APNs' : 'FCM'}</td>
+              <td><code>${escapeHtml(d.token)}</code></td>
+              <td>${escapeHtml(d.min_severity || 'default')}</td>
+              <td>${d.quiet_start ? `${escapeHtml(d.quiet_start)}–${escapeHtml(d.quiet_end)} ${escapeHtml(d.timezone)}${d.quiet_now ? ` <span class="mpush-held">(now, ${d.held} held)</span>` : ''}` : '-'}</td>
+              <td>${formatTime(d.last_push)}${d.last_error ? `<div class="mpush-error">${escapeHtml(d.last_error)}</div>` : ''}</td>
+              <td>
+                <button class="mpush-test" data-id="${escapeHtml(d.id)}">Test</button>
+                <button class="mpush-toggle" data-id="${escapeHtml(d.id)}">${d.enabled ? 'Disable' : 'Enable'}</button>
+                <button class="mpush-danger mpush-delete" data-id="${escapeHtml(d.id)}">Remove</button>
+              </td>
+            </tr>
+          `).join('') || `<tr><td colspan="${admin ? 8 : 7}">No devices registered yet</td></tr>`}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function renderDeliveries(deliveries, admin) {
+    return `
+      <table class="mpush-table">
+        <thead><tr><th>Time</th>${admin ? '<th>Owner</th>' : ''}<th>Device</th><th>Severity</th><th>Alert</th><th>Result</th></tr></thead>
+        <tbody>
+          ${deliveries.slice(0, 100).map(d => `
+            <tr>
+              <td>${formatTime(d.time)}</td>
+              ${admin ? `<td>${escapeHtml(d.owner)}</td>` : ''}
+              <td>${escapeHtml(d.device)}</td>
+              <td>${escapeHtml(d.severity)}</td>
+              <td>${escapeHtml(d.title)} <span class="mpush-desc">${escapeHtml(d.source)} · ${escapeHtml(d.type)}</span></td>
+              <td class="mpush-${escapeHtml(d.status)}">${escapeHtml(d.status)}${d.error ? `: ${escapeHtml(d.error)}` : ''}</td>
+            </tr>
+          `).join('') || `<tr><td colspan="${admin ? 6 : 5}">Nothing pushed yet</td></tr>`}
+        </tbody>
+      </table>
+    `;
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#mpush-body');
+    try {
+      const [status, devices, deliveries] = await Promise.all([
+        api('GET', '/status'),
+        api('GET', '/devices'),
+        api('GET', '/deliveries'),
+      ]);
+      body.innerHTML = `
+        ${renderStatus(status)}
+        <h3>Devices</h3>
+        ${renderDevices(devices.devices, status.is_admin)}
+        <h3>Recent deliveries</h3>
+        ${renderDeliveries(deliveries.deliveries, status.is_admin)}
+      `;
+
+      body.querySelectorAll('.mpush-test').forEach(btn => {
+        btn.addEventListener('click', () => run(container, btn, async () => {
+          const res = await api('POST', `/devices/${btn.dataset.id}/test`);
+          alert(res.message);
+        }));
+      });
+      body.querySelectorAll('.mpush-toggle').forEach(btn => {
+        const d = devices.devices.find(x => x.id === btn.dataset.id);
+        btn.addEventListener('click', () => run(container, btn, () => api('PUT', `/devices/${d.id}`, {
+          name: d.name,
+          min_severity: d.min_severity,
+          quiet_start: d.quiet_start,
+          quiet_end: d.quiet_end,
+          timezone: d.timezone,
+          enabled: !d.enabled,
+        })));
+      });
+      body.querySelectorAll('.mpush-delete').forEach(btn => {
+        btn.addEventListener('click', () => {
+          if (!confirm('Remove this device? It will stop getting notifications.')) return;
+          run(container, btn, () => api('DELETE', `/devices/${btn.dataset.id}`));
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="mpush-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function run(container, button, fn) {
+    button.disabled = true;
+    try {
+      await fn();
+    } catch (err) {
+      alert(err.message);
+    }
+    button.disabled = false;
+    load(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    const zone = Intl.DateTimeFormat().resolvedOptions().timeZone || 'UTC';
+    container.innerHTML = `
+      <div class="mpush-app" data-plugin="${PLUGIN_ID}">
+        <form class="mpush-form" id="mpush-register">
+          <label>Name<input name="name" placeholder="My phone" maxlength="64" required></label>
+          <label>Platform
+            <select name="platform"><option value="fcm">FCM (Android, web)</option><option value="apns">APNs (iOS)</option></select>
+          </label>
+          <label>Push token<input name="token" class="mpush-token" required></label>
+          <label>Minimum severity
+            <select name="min_severity">
+              <option value="">Default</option><option value="info">Info</option><option value="warning">Warning</option><option value="critical">Critical</option>
+            </select>
+          </label>
+          <label>Quiet from<input name="quiet_start" type="time"></label>
+          <label>Quiet until<input name="quiet_end" type="time"></label>
+          <label>Timezone<input name="timezone" value="${escapeHtml(zone)}"></label>
+          <button type="submit">Register device</button>
+        </form>
+        <div class="mpush-desc">The push token comes from the notification app on your device. Alerts arriving during quiet hours are held and summarised when they end.</div>
+        <div id="mpush-body">Loading...</div>
+      </div>
+    `;
+
+    const form = container.querySelector('#mpush-register');
+    form.addEventListener('submit', e => {
+      e.preventDefault();
+      const data = Object.fromEntries(new FormData(form).entries());
+      run(container, form.querySelector('button'), async () => {
+        await api('POST', '/devices', data);
+        form.reset();
+        form.elements.timezone.value = zone;
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('mobile-push-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,191 @@
+package mobilepush
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Device platforms
+const (
+	PlatformFCM  = "fcm"
+	PlatformAPNs = "apns"
+)
+
+// Device is a staff member's phone or tablet. Token is the push token the
+// device's app got from FCM or APNs.
+type Device struct {
+	ID          string    `json:"id"`
+	Owner       string    `json:"owner"`
+	Name        string    `json:"name"`
+	Platform    string    `json:"platform"`
+	Token       string    `json:"token"`
+	MinSeverity string    `json:"min_severity"`
+	QuietStart  string    `json:"quiet_start"`
+	QuietEnd    string    `json:"quiet_end"`
+	Timezone    string    `json:"timezone"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastPush    time.Time `json:"last_push"`
+	LastError   string    `json:"last_error,omitempty"`
+	Held        int       `json:"held"`
+	HeldTitle   string    `json:"held_title,omitempty"`
+	HeldLevel   string    `json:"held_severity,omitempty"`
+}
+
+// DeviceRequest is the body of register and update requests
+type DeviceRequest struct {
+	Name        string `json:"name"`
+	Platform    string `json:"platform"`
+	Token       string `json:"token"`
+	MinSeverity string `json:"min_severity"`
+	QuietStart  string `json:"quiet_start"`
+	QuietEnd    string `json:"quiet_end"`
+	Timezone    string `json:"timezone"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// deviceView is a device as the API shows it
+type deviceView struct {
+	Device
+	Quiet bool `json:"quiet_now"`
+}
+
+// view returns the device with only the end of its token, which is all
+// anyone needs to tell devices apart
+func (d *Device) view(now time.Time) deviceView {
+	v := deviceView{Device: *d, Quiet: d.quiet(now)}
+	if len(v.Token) > 8 {
+		v.Token = "…" + v.Token[len(v.Token)-8:]
+	}
+	return v
+}
+
+// apply validates a request and copies it onto the device. The token and
+// platform may be left out of updates.
+func (req *DeviceRequest) apply(d *Device) error {
+	updated := *d
+	updated.Name = strings.TrimSpace(req.Name)
+	if updated.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(updated.Name) > 64 {
+		return fmt.Errorf("name must be at most 64 characters")
+	}
+	if p := strings.ToLower(strings.TrimSpace(req.Platform)); p != "" {
+		updated.Platform = p
+	}
+	if updated.Platform != PlatformFCM && updated.Platform != PlatformAPNs {
+		return fmt.Errorf("platform must be fcm or apns")
+	}
+	if t := strings.TrimSpace(req.Token); t != "" {
+		updated.Token = t
+	}
+	if updated.Token == "" {
+		return fmt.Errorf("token is required")
+	}
+	if len(updated.Token) > 4096 || strings.ContainsAny(updated.Token, " \t\r\n/") {
+		return fmt.Errorf("token is not a valid push token")
+	}
+	if updated.Platform == PlatformAPNs {
+		t, err := hexToken(updated.Token)
+		if err != nil {
+			return err
+		}
+		updated.Token = t
+	}
+
+	updated.MinSeverity = strings.ToLower(strings.TrimSpace(req.MinSeverity))
+	if _, ok := notify.SeverityRank[updated.MinSeverity]; updated.MinSeverity != "" && !ok {
+		return fmt.Errorf("min_severity must be info, warning or critical")
+	}
+	updated.QuietStart = strings.TrimSpace(req.QuietStart)
+	updated.QuietEnd = strings.TrimSpace(req.QuietEnd)
+	if (updated.QuietStart == "") != (updated.QuietEnd == "") {
+		return fmt.Errorf("quiet_start and quiet_end must be set together")
+	}
+	if updated.QuietStart != "" {
+		if _, err := parseClock(updated.QuietStart); err != nil {
+			return fmt.Errorf("quiet_start %v", err)
+		}
+		if _, err := parseClock(updated.QuietEnd); err != nil {
+			return fmt.Errorf("quiet_end %v", err)
+		}
+	}
+	updated.Timezone = strings.TrimSpace(req.Timezone)
+	if updated.Timezone == "" {
+		updated.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(updated.Timezone); err != nil {
+		return fmt.Errorf("timezone must be an IANA zone such as Europe/Amsterdam")
+	}
+	if req.Enabled != nil {
+		updated.Enabled = *req.Enabled
+	}
+
+	*d = updated
+	return nil
+}
+
+// hexToken checks an APNs device token, which is 32 or more bytes in hex
+func hexToken(token string) (string, error) {
+	if len(token) < 64 || len(token)%2 != 0 {
+		return "", fmt.Errorf("APNs tokens are at least 64 hex characters")
+	}
+	for _, c := range token {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return "", fmt.Errorf("APNs tokens are at least 64 hex characters")
+		}
+	}
+	return strings.ToLower(token), nil
+}
+
+// parseClock parses an HH:MM time of day into minutes after midnight
+func parseClock(s string) (int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("must be a time such as 22:30")
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("must be a time such as 22:30")
+	}
+	return h*60 + m, nil
+}
+
+// quiet reports whether now falls within the device's quiet hours, in its
+// own timezone. Quiet hours may span midnight, such as 22:00 to 07:00.
+func (d *Device) quiet(now time.Time) bool {
+	if d.QuietStart == "" || d.QuietEnd == "" {
+		return false
+	}
+	start, err1 := parseClock(d.QuietStart)
+	end, err2 := parseClock(d.QuietEnd)
+	if err1 != nil || err2 != nil || start == end {
+		return false
+	}
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// wants reports whether the device should get an event of a severity,
+// given the default minimum
+func (d *Device) wants(severity, defaultMin string) bool {
+	min := d.MinSeverity
+	if min == "" {
+		min = defaultMin
+	}
+	return d.Enabled && notify.SeverityRank[severity] >= notify.SeverityRank[min]
+}
@@ -0,0 +1,74 @@
+package mobilepush
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Built-in event types
+const (
+	EventTest         = "test"
+	EventQuietSummary = "quiet_summary"
+)
+
+// severityLabels prefix the title of each notification
+var severityLabels = map[string]string{
+	notify.SeverityInfo:     "ℹ️",
+	notify.SeverityWarning:  "⚠️",
+	notify.SeverityCritical: "🚨",
+}
+
+// keyFields are data fields that tell alerts of one type apart, in order
+// of preference
+var keyFields = []string{"incident_id", "target", "server"}
+
+// dedupKey identifies repeats of an alert, so a flapping check pages once
+// per repeat_minutes rather than on every report
+func dedupKey(ev notify.Event) string {
+	key := ev.Source + ":" + ev.Type
+	for _, field := range keyFields {
+		if v, ok := ev.Data[field]; ok && v != nil && fmt.Sprint(v) != "" {
+			return key + ":" + fmt.Sprint(v)
+		}
+	}
+	return key
+}
+
+// notification is what a device shows for an event
+type notification struct {
+	Title    string
+	Body     string
+	Severity string
+	Data     map[string]string
+}
+
+// newNotification renders an event for a lock screen: a short title with
+// the severity, and the message as the body
+func newNotification(ev notify.Event) notification {
+	body := ev.Message
+	if body == "" || body == ev.Title {
+		body = ev.Source + " · " + ev.Type
+	}
+	return notification{
+		Title:    severityLabels[ev.Severity] + " " + truncate(ev.Title, 100),
+		Body:     truncate(body, 500),
+		Severity: ev.Severity,
+		Data: map[string]string{
+			"source":    ev.Source,
+			"type":      ev.Type,
+			"severity":  ev.Severity,
+			"timestamp": ev.Timestamp.UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,272 @@
+package mobilepush
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// fcmScope is the OAuth scope of the FCM HTTP v1 API
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// pushError is a failed push. Unregistered is set when the token is no
+// longer valid, so the device should stop getting pushes, and Retry when
+// trying again later may work.
+type pushError struct {
+	Status       int
+	Reason       string
+	Unregistered bool
+	Retry        bool
+}
+
+func (e *pushError) Error() string {
+	if e.Status == 0 {
+		return e.Reason
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Reason)
+}
+
+// serviceAccount is the part of a Google service account key file we need
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// fcmClient sends through the FCM HTTP v1 API with a service account,
+// trading a signed JWT for an OAuth access token about once an hour
+type fcmClient struct {
+	account serviceAccount
+	key     *rsa.PrivateKey
+	http    *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newFCMClient loads a service account key file
+func newFCMClient(path string) (*fcmClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sa serviceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("not a service account key file: %v", err)
+	}
+	if sa.ProjectID == "" || sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("the service account key file lacks project_id, client_email or private_key")
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("the service account private key is not PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to parse the service account private key: %v", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the service account private key is not an RSA key")
+	}
+
+	return &fcmClient{
+		account: sa,
+		key:     key,
+		http:    &http.Client{Timeout: 20 * time.Second},
+	}, nil
+}
+
+// accessToken returns a valid OAuth access token, fetching a new one when
+// the last is about to expire
+func (f *fcmClient) accessToken(ctx context.Context) (string, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token != "" && time.Now().Before(f.expires.Add(-5*time.Minute)) {
+		return f.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(
+		map[string]interface{}{"alg": "RS256", "typ": "JWT"},
+		map[string]interface{}{
+			"iss":   f.account.ClientEmail,
+			"scope": fcmScope,
+			"aud":   f.account.TokenURI,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		func(digest []byte) ([]byte, error) {
+			return rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest)
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", f.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return "", &pushError{Reason: err.Error(), Retry: true}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	_ = json.Unmarshal(body, &result)
+	if resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		reason := firstNonEmpty(result.Description, result.Error, http.StatusText(resp.StatusCode))
+		return "", &pushError{Status: resp.StatusCode, Reason: "OAuth token request failed: " + reason, Retry: resp.StatusCode >= 500}
+	}
+	f.token = result.AccessToken
+	f.expires = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return f.token, nil
+}
+
+// send pushes a notification to one device token
+func (f *fcmClient) send(ctx context.Context, token string, n notification) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	access, err := f.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	priority := "NORMAL"
+	apnsPriority := "5"
+	if notify.SeverityRank[n.Severity] >= notify.SeverityRank[notify.SeverityWarning] {
+		priority = "HIGH"
+		apnsPriority = "10"
+	}
+	msg := map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        token,
+			"notification": map[string]string{"title": n.Title, "body": n.Body},
+			"data":         n.Data,
+			"android":      map[string]interface{}{"priority": priority},
+			"apns": map[string]interface{}{
+				"headers": map[string]string{"apns-priority": apnsPriority},
+				"payload": map[string]interface{}{"aps": map[string]interface{}{"sound": "default"}},
+			},
+		},
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	endpoint := "https://fcm.googleapis.com/v1/projects/" + url.PathEscape(f.account.ProjectID) + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+access)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return &pushError{Reason: err.Error(), Retry: true}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &result)
+	code := result.Error.Status
+	for _, d := range result.Error.Details {
+		if d.ErrorCode != "" {
+			code = d.ErrorCode
+		}
+	}
+
+	pe := &pushError{Status: resp.StatusCode, Reason: firstNonEmpty(code, http.StatusText(resp.StatusCode))}
+	if result.Error.Message != "" {
+		pe.Reason += ": " + result.Error.Message
+	}
+	switch {
+	case code == "UNREGISTERED" || code == "SENDER_ID_MISMATCH":
+		pe.Unregistered = true
+	case resp.StatusCode == http.StatusUnauthorized:
+		// The access token was revoked; fetch a new one next time
+		f.mu.Lock()
+		f.token = ""
+		f.mu.Unlock()
+		pe.Retry = true
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		pe.Retry = true
+	}
+	return pe
+}
+
+// signJWT builds a compact JWT, signing the SHA-256 digest of its header
+// and claims with sign
+func signJWT(header, claims map[string]interface{}, sign func(digest []byte) ([]byte, error)) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := sign(digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,852 @@
+// Mobile Push Plugin for UnrealIRCd Web Panel
+// Delivers critical alerts to staff phones through Firebase Cloud
+// Messaging and APNs, with severity filtering and quiet hours per device
+
+package mobilepush
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on queued events, devices, delivery attempts and history
+const (
+	queueSize     = 200
+	maxDevices    = 200
+	maxAttempts   = 3
+	maxDeliveries = 500
+)
+
+// Delivery states
+const (
+	StatusSent   = "sent"
+	StatusFailed = "failed"
+	StatusHeld   = "held"
+)
+
+// MobilePushPlugin implements the Plugin interface
+type MobilePushPlugin struct {
+	config     Config
+	devices    []*Device
+	deliveries []*Delivery
+	recent     map[string]time.Time
+	queue      chan notify.Event
+	fcm        *fcmClient
+	apns       *apnsClient
+	received   int
+	sent       int
+	failed     int
+	held       int
+	repeats    int
+	dropped    int
+	lastSent   time.Time
+	sendErr    string
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	DataDir             string `json:"data_dir"`
+	FCMCredentials      string `json:"fcm_credentials_file"`
+	APNsKeyFile         string `json:"apns_key_file"`
+	APNsKeyID           string `json:"apns_key_id"`
+	APNsTeamID          string `json:"apns_team_id"`
+	APNsTopic           string `json:"apns_topic"`
+	APNsSandbox         bool   `json:"apns_sandbox"`
+	Events              string `json:"events"`
+	MinSeverity         string `json:"min_severity"`
+	CriticalBreaksQuiet bool   `json:"critical_breaks_quiet"`
+	RepeatMinutes       int    `json:"repeat_minutes"`
+	EventsToken         string `json:"events_token"`
+}
+
+// Delivery is the outcome of one alert for one device
+type Delivery struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	DeviceID string    `json:"device_id"`
+	Device   string    `json:"device"`
+	Owner    string    `json:"owner"`
+	Platform string    `json:"platform"`
+	Source   string    `json:"source"`
+	Type     string    `json:"type"`
+	Severity string    `json:"severity"`
+	Title    string    `json:"title"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// storeData is the on-disk format
+type storeData struct {
+	Devices    []*Device   `json:"devices"`
+	Deliveries []*Delivery `json:"deliveries"`
+}
+
+// sender is a push service
+type sender interface {
+	send(ctx context.Context, token string, n notification) error
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &MobilePushPlugin{
+		config: Config{
+			DataDir:       "data/plugins/mobile-push",
+			Events:        "*",
+			MinSeverity:   notify.SeverityCritical,
+			RepeatMinutes: 15,
+		},
+		devices:    make([]*Device, 0),
+		deliveries: make([]*Delivery, 0),
+		recent:     make(map[string]time.Time),
+		queue:      make(chan notify.Event, queueSize),
+	}
+}
+
+// Info returns plugin metadata
+func (p *MobilePushPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Mobile Push",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Push notifications for critical alerts to staff phones through FCM and APNs",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *MobilePushPlugin) Init() error {
+	if err := p.load(); err != nil {
+		log.Printf("[mobile-push] failed to load data: %v", err)
+	}
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "mobile-push-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		enabled := 0
+		for _, d := range p.devices {
+			if d.Enabled {
+				enabled++
+			}
+		}
+		content := map[string]interface{}{
+			"devices": enabled,
+			"sent":    p.sent,
+			"failed":  p.failed,
+			"held":    p.held,
+		}
+		if p.config.FCMCredentials == "" && p.config.APNsKeyFile == "" {
+			content["status"] = "Not configured"
+		}
+		return plugins.DashboardCard{
+			Title:   "Mobile Push",
+			Icon:    "Smartphone",
+			Content: content,
+			Order:   98,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.sendLoop()
+	go p.quietLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *MobilePushPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *MobilePushPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/mobile-push")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/devices", p.handleListDevices)
+		plugin.POST("/devices", p.handleRegister)
+		plugin.PUT("/devices/:id", p.handleUpdateDevice)
+		plugin.DELETE("/devices/:id", p.handleDeleteDevice)
+		plugin.POST("/devices/:id/test", p.handleTest)
+		plugin.GET("/deliveries", p.handleDeliveries)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *MobilePushPlugin) storePath() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return filepath.Join(p.config.DataDir, "mobile-push.json")
+}
+
+// load reads devices and deliveries from disk
+func (p *MobilePushPlugin) load() error {
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if data.Devices != nil {
+		p.devices = data.Devices
+	}
+	if data.Deliveries != nil {
+		p.deliveries = data.Deliveries
+	}
+	return nil
+}
+
+// save writes devices and deliveries to disk
+func (p *MobilePushPlugin) save() error {
+	path := p.storePath()
+	p.mu.RLock()
+	data := storeData{Devices: p.devices, Deliveries: p.deliveries}
+	err := storage.SaveJSON(path, data)
+	p.mu.RUnlock()
+	return err
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// device returns a device by ID. Caller must hold p.mu.
+func (p *MobilePushPlugin) device(id string) *Device {
+	for _, d := range p.devices {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// ownDevice returns a device the requesting user may manage, or writes an
+// error response. Caller must hold p.mu.
+func (p *MobilePushPlugin) ownDevice(c *gin.Context) *Device {
+	d := p.device(c.Param("id"))
+	if d == nil || (!strings.EqualFold(d.Owner, access.Actor(c)) && !access.IsAdmin(access.Actor(c))) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return nil
+	}
+	return d
+}
+
+// wanted reports whether an event is pushed at all. The events setting
+// lists event types or source plugins, or * for everything. Caller must
+// hold p.mu.
+func (p *MobilePushPlugin) wanted(ev notify.Event) bool {
+	for _, e := range lists.Split(p.config.Events) {
+		if e == "*" || strings.EqualFold(e, ev.Type) || strings.EqualFold(e, ev.Source) {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch queues an event for delivery. Events arriving while the queue
+// is full are dropped.
+func (p *MobilePushPlugin) dispatch(ev notify.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.received++
+	if !p.wanted(ev) {
+		return
+	}
+	select {
+	case p.queue <- ev:
+	default:
+		p.dropped++
+	}
+}
+
+// pusher returns the push service of a platform, loading its credentials
+// on first use. Caller must hold p.mu.
+func (p *MobilePushPlugin) pusher(platform string) (sender, error) {
+	switch platform {
+	case PlatformFCM:
+		if p.fcm == nil {
+			if p.config.FCMCredentials == "" {
+				return nil, fmt.Errorf("fcm_credentials_file is not set")
+			}
+			f, err := newFCMClient(p.config.FCMCredentials)
+			if err != nil {
+				return nil, err
+			}
+			p.fcm = f
+		}
+		return p.fcm, nil
+	case PlatformAPNs:
+		if p.apns == nil {
+			if p.config.APNsKeyFile == "" {
+				return nil, fmt.Errorf("apns_key_file is not set")
+			}
+			a, err := newAPNsClient(p.config.APNsKeyFile, p.config.APNsKeyID, p.config.APNsTeamID, p.config.APNsTopic, p.config.APNsSandbox)
+			if err != nil {
+				return nil, err
+			}
+			p.apns = a
+		}
+		return p.apns, nil
+	}
+	return nil, fmt.Errorf("unknown platform %q", platform)
+}
+
+// sendLoop delivers queued events until shutdown
+func (p *MobilePushPlugin) sendLoop() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case ev := <-p.queue:
+			p.deliver(ev)
+			if err := p.save(); err != nil {
+				log.Printf("[mobile-push] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// deliver pushes an event to every device that wants it. Repeats of an
+// alert within repeat_minutes are skipped, and alerts for a device in its
+// quiet hours are held for a summary when they end.
+func (p *MobilePushPlugin) deliver(ev notify.Event) {
+	now := time.Now()
+	n := newNotification(ev)
+
+	p.mu.Lock()
+	repeat := time.Duration(p.config.RepeatMinutes) * time.Minute
+	targets := make([]Device, 0)
+	for _, d := range p.devices {
+		if !d.wants(ev.Severity, p.config.MinSeverity) {
+			continue
+		}
+		key := dedupKey(ev) + "|" + d.ID
+		if last, ok := p.recent[key]; ok && now.Sub(last) < repeat {
+			p.repeats++
+			continue
+		}
+		p.recent[key] = now
+
+		if d.quiet(now) && !(p.config.CriticalBreaksQuiet && ev.Severity == notify.SeverityCritical) {
+			d.Held++
+			d.HeldTitle = ev.Title
+			if notify.SeverityRank[ev.Severity] > notify.SeverityRank[d.HeldLevel] || d.HeldLevel == "" {
+				d.HeldLevel = ev.Severity
+			}
+			p.held++
+			p.record(d, ev, StatusHeld, "")
+			continue
+		}
+		targets = append(targets, *d)
+	}
+	p.mu.Unlock()
+
+	for _, d := range targets {
+		p.push(d, ev, n)
+	}
+}
+
+// push sends a notification to one device, trying again after transient
+// errors, and records the outcome. A device whose token the service no
+// longer accepts is disabled.
+func (p *MobilePushPlugin) push(d Device, ev notify.Event, n notification) error {
+	p.mu.Lock()
+	svc, err := p.pusher(d.Platform)
+	p.mu.Unlock()
+
+	if err == nil {
+		for attempt := 0; ; attempt++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			err = svc.send(ctx, d.Token, n)
+			cancel()
+
+			pe, ok := err.(*pushError)
+			if err == nil || !ok || !pe.Retry || attempt == maxAttempts-1 {
+				break
+			}
+			if !p.wait(time.Duration(2<<attempt) * time.Second) {
+				break
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stored := p.device(d.ID)
+	if err != nil {
+		p.failed++
+		p.sendErr = err.Error()
+		if stored != nil {
+			stored.LastError = err.Error()
+			if pe, ok := err.(*pushError); ok && pe.Unregistered {
+				stored.Enabled = false
+				stored.LastError = "The push service no longer accepts this token (" + pe.Reason + "); register the device again"
+				log.Printf("[mobile-push] disabled %s's device %s: %v", d.Owner, d.Name, err)
+			}
+		}
+		p.record(&d, ev, StatusFailed, err.Error())
+		return err
+	}
+
+	p.sent++
+	p.sendErr = ""
+	p.lastSent = time.Now().UTC()
+	if stored != nil {
+		stored.LastPush = p.lastSent
+		stored.LastError = ""
+	}
+	p.record(&d, ev, StatusSent, "")
+	return nil
+}
+
+// wait sleeps for d, returning false if the plugin shuts down first
+func (p *MobilePushPlugin) wait(d time.Duration) bool {
+	select {
+	case <-p.stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// record adds a delivery to the history. Caller must hold p.mu.
+func (p *MobilePushPlugin) record(d *Device, ev notify.Event, status, errMsg string) {
+	p.deliveries = append(p.deliveries, &Delivery{
+		ID:       newID(),
+		Time:     time.Now().UTC(),
+		DeviceID: d.ID,
+		Device:   d.Name,
+		Owner:    d.Owner,
+		Platform: d.Platform,
+		Source:   ev.Source,
+		Type:     ev.Type,
+		Severity: ev.Severity,
+		Title:    ev.Title,
+		Status:   status,
+		Error:    errMsg,
+	})
+	if len(p.deliveries) > maxDeliveries {
+		p.deliveries = p.deliveries[len(p.deliveries)-maxDeliveries:]
+	}
+}
+
+// quietLoop checks every minute for devices whose quiet hours have ended
+// with alerts held, and sends each one summary, until shutdown
+func (p *MobilePushPlugin) quietLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		p.mu.Lock()
+		due := make([]Device, 0)
+		for _, d := range p.devices {
+			if d.Held > 0 && d.Enabled && !d.quiet(now) {
+				due = append(due, *d)
+				d.Held, d.HeldTitle, d.HeldLevel = 0, "", ""
+			}
+		}
+		// Forget repeats that can no longer suppress anything
+		repeat := time.Duration(p.config.RepeatMinutes) * time.Minute
+		for key, last := range p.recent {
+			if now.Sub(last) >= repeat {
+				delete(p.recent, key)
+			}
+		}
+		p.mu.Unlock()
+
+		for _, d := range due {
+			ev := quietSummary(d)
+			p.push(d, ev, newNotification(ev))
+		}
+		if len(due) > 0 {
+			if err := p.save(); err != nil {
+				log.Printf("[mobile-push] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// quietSummary is the event sent when a device's quiet hours end with
+// alerts held
+func quietSummary(d Device) notify.Event {
+	title := "1 alert during quiet hours"
+	if d.Held > 1 {
+		title = fmt.Sprintf("%d alerts during quiet hours", d.Held)
+	}
+	return notify.Event{
+		Source:    "mobile-push",
+		Type:      EventQuietSummary,
+		Severity:  firstNonEmpty(d.HeldLevel, notify.SeverityInfo),
+		Title:     title,
+		Message:   "Latest: " + d.HeldTitle + ". See the Mobile Push page for all of them.",
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// handleStatus reports the push services, devices and counts
+func (p *MobilePushPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	enabled := 0
+	for _, d := range p.devices {
+		if d.Enabled {
+			enabled++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"fcm_configured":  p.config.FCMCredentials != "",
+		"apns_configured": p.config.APNsKeyFile != "",
+		"events_enabled":  p.config.EventsToken != "",
+		"is_admin":        access.IsAdmin(access.Actor(c)),
+		"devices":         len(p.devices),
+		"enabled":         enabled,
+		"received":        p.received,
+		"queued":          len(p.queue),
+		"sent":            p.sent,
+		"failed":          p.failed,
+		"held":            p.held,
+		"repeats":         p.repeats,
+		"dropped":         p.dropped,
+		"last_sent":       p.lastSent,
+		"send_error":      p.sendErr,
+	})
+}
+
+// handleListDevices returns the user's devices, or every device for admins
+func (p *MobilePushPlugin) handleListDevices(c *gin.Context) {
+	name := access.Actor(c)
+	now := time.Now()
+
+	p.mu.RLock()
+	admin := access.IsAdmin(name)
+	list := make([]deviceView, 0)
+	for _, d := range p.devices {
+		if admin || strings.EqualFold(d.Owner, name) {
+			list = append(list, d.view(now))
+		}
+	}
+	p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"devices": list})
+}
+
+// handleRegister adds a device for the requesting user. Registering a
+// token that is already known updates that device instead.
+func (p *MobilePushPlugin) handleRegister(c *gin.Context) {
+	var req DeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	owner := access.Actor(c)
+	d := &Device{ID: newID(), Owner: owner, Enabled: true, CreatedAt: time.Now().UTC()}
+	if err := req.apply(d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	status := http.StatusCreated
+	existing := false
+	for _, other := range p.devices {
+		if other.Token == d.Token {
+			// A reinstalled app or a device changing hands
+			d.ID, d.CreatedAt = other.ID, other.CreatedAt
+			*other = *d
+			d = other
+			existing = true
+			status = http.StatusOK
+			break
+		}
+	}
+	if !existing {
+		if len(p.devices) >= maxDevices {
+			p.mu.Unlock()
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d devices can be registered", maxDevices)})
+			return
+		}
+		p.devices = append(p.devices, d)
+	}
+	v := d.view(time.Now())
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[mobile-push] failed to save data: %v", err)
+	}
+	log.Printf("[mobile-push] %s registered %s device %s", owner, d.Platform, d.Name)
+	c.JSON(status, v)
+}
+
+// handleUpdateDevice changes a device's name, token or filters
+func (p *MobilePushPlugin) handleUpdateDevice(c *gin.Context) {
+	var req DeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	d := p.ownDevice(c)
+	if d == nil {
+		p.mu.Unlock()
+		return
+	}
+	oldToken := d.Token
+	if err := req.apply(d); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if d.Token != oldToken {
+		d.LastError = ""
+	}
+	v := d.view(time.Now())
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[mobile-push] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// handleDeleteDevice removes a device
+func (p *MobilePushPlugin) handleDeleteDevice(c *gin.Context) {
+	p.mu.Lock()
+	d := p.ownDevice(c)
+	if d == nil {
+		p.mu.Unlock()
+		return
+	}
+	for i, other := range p.devices {
+		if other == d {
+			p.devices = append(p.devices[:i], p.devices[i+1:]...)
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[mobile-push] failed to save data: %v", err)
+	}
+	log.Printf("[mobile-push] %s removed device %s", access.Actor(c), d.Name)
+	c.JSON(http.StatusOK, gin.H{"message": "Device removed"})
+}
+
+// handleTest pushes a test notification to a device straight away,
+// ignoring its filters and quiet hours, and reports the result
+func (p *MobilePushPlugin) handleTest(c *gin.Context) {
+	p.mu.RLock()
+	d := p.ownDevice(c)
+	var target Device
+	if d != nil {
+		target = *d
+	}
+	p.mu.RUnlock()
+	if d == nil {
+		return
+	}
+
+	ev := notify.Event{
+		Source:    "mobile-push",
+		Type:      EventTest,
+		Severity:  notify.SeverityCritical,
+		Title:     "Test notification",
+		Message:   fmt.Sprintf("Sent by %s from the web panel to %s.", access.Actor(c), target.Name),
+		Timestamp: time.Now().UTC(),
+	}
+	err := p.push(target, ev, newNotification(ev))
+	if saveErr := p.save(); saveErr != nil {
+		log.Printf("[mobile-push] failed to save data: %v", saveErr)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test notification sent"})
+}
+
+// handleDeliveries returns recent deliveries to the user's devices, or to
+// every device for admins, newest first
+func (p *MobilePushPlugin) handleDeliveries(c *gin.Context) {
+	name := access.Actor(c)
+	status := c.Query("status")
+
+	p.mu.RLock()
+	admin := access.IsAdmin(name)
+	list := make([]*Delivery, 0)
+	for i := len(p.deliveries) - 1; i >= 0 && len(list) < 200; i-- {
+		d := p.deliveries[i]
+		if (admin || strings.EqualFold(d.Owner, name)) && (status == "" || d.Status == status) {
+			list = append(list, d)
+		}
+	}
+	p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"deliveries": list})
+}
+
+// handleEvents accepts an alert from another plugin or script, sent with
+// the events token in an X-Events-Token header
+func (p *MobilePushPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+	if ev.Source == "" {
+		ev.Source = "external"
+	}
+
+	p.dispatch(ev)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *MobilePushPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration. Only admins may change
+// it, since it decides where alerts come from.
+func (p *MobilePushPlugin) handleUpdateConfig(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if _, ok := notify.SeverityRank[newConfig.MinSeverity]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_severity must be info, warning or critical"})
+		return
+	}
+	if newConfig.RepeatMinutes < 0 || newConfig.RepeatMinutes > 1440 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "repeat_minutes must be between 0 and 1440"})
+		return
+	}
+	if newConfig.APNsKeyFile != "" && (newConfig.APNsKeyID == "" || newConfig.APNsTeamID == "" || newConfig.APNsTopic == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "apns_key_id, apns_team_id and apns_topic are required with apns_key_file"})
+		return
+	}
+	if strings.TrimSpace(newConfig.Events) == "" {
+		newConfig.Events = "*"
+	}
+
+	// Check the credentials now rather than on the first alert
+	if newConfig.FCMCredentials != "" {
+		if _, err := newFCMClient(newConfig.FCMCredentials); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "fcm_credentials_file: " + err.Error()})
+			return
+		}
+	}
+	if newConfig.APNsKeyFile != "" {
+		if _, err := newAPNsClient(newConfig.APNsKeyFile, newConfig.APNsKeyID, newConfig.APNsTeamID, newConfig.APNsTopic, newConfig.APNsSandbox); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "apns_key_file: " + err.Error()})
+			return
+		}
+	}
+
+	p.mu.Lock()
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.fcm = nil
+	p.apns = nil
+	p.mu.Unlock()
+
+	log.Printf("[mobile-push] %s updated the configuration", access.Actor(c))
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *MobilePushPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *MobilePushPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,194 @@
+package modulemanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// CatalogModule is a module published in unrealircd-contrib
+type CatalogModule struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	Version       string `json:"version"`
+	Author        string `json:"author"`
+	Documentation string `json:"documentation"`
+	Source        string `json:"source"`
+	MinVersion    string `json:"min_unrealircd_version"`
+}
+
+// ChangelogEntry is a commit touching a module's source file
+type ChangelogEntry struct {
+	SHA     string    `json:"sha"`
+	Date    time.Time `json:"date"`
+	Author  string    `json:"author"`
+	Message string    `json:"message"`
+	URL     string    `json:"url"`
+}
+
+// httpClient is used for catalog and changelog requests
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// fetchCatalog downloads and parses a modules.list file
+func fetchCatalog(ctx context.Context, listURL string) (map[string]*CatalogModule, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("modules.list: unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, err
+	}
+	return parseCatalog(string(data)), nil
+}
+
+// parseCatalog parses the unrealircd.conf style modules.list format:
+//
+//	module "third/example"
+//	{
+//		description "...";
+//		version "1.0";
+//		...
+//	}
+//
+// Nested blocks such as post-install-text are skipped.
+func parseCatalog(text string) map[string]*CatalogModule {
+	modules := make(map[string]*CatalogModule)
+	var cur *CatalogModule
+	depth := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		switch {
+		case depth == 0 && strings.HasPrefix(line, "module "):
+			name := unquote(strings.TrimSuffix(strings.TrimPrefix(line, "module "), "{"))
+			cur = &CatalogModule{Name: name}
+			modules[name] = cur
+			if strings.HasSuffix(line, "{") {
+				depth = 1
+			}
+			continue
+		case strings.HasPrefix(line, "}"):
+			if depth > 0 {
+				depth--
+			}
+			if depth == 0 {
+				cur = nil
+			}
+			continue
+		case strings.HasSuffix(line, "{"):
+			depth++
+			continue
+		}
+		if cur == nil || depth != 1 {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimSuffix(line, ";"), " ")
+		if !ok {
+			continue
+		}
+		value = unquote(value)
+		switch key {
+		case "description":
+			cur.Description = value
+		case "version":
+			cur.Version = value
+		case "author":
+			cur.Author = value
+		case "documentation":
+			cur.Documentation = value
+		case "source":
+			cur.Source = value
+		case "min-unrealircd-version":
+			cur.MinVersion = value
+		}
+	}
+	return modules
+}
+
+// unquote strips whitespace and surrounding quotes
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.Unquote(s); err == nil {
+		return v
+	}
+	return strings.Trim(s, `"`)
+}
+
+// fetchChangelog lists the recent commits of a module's source file in
+// unrealircd-contrib through the GitHub API
+func fetchChangelog(ctx context.Context, repo string, m *CatalogModule, limit int) ([]ChangelogEntry, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	file := "files/" + path.Base(m.Name) + ".c"
+	if u, err := url.Parse(m.Source); err == nil && strings.Contains(u.Path, "/files/") {
+		file = u.Path[strings.Index(u.Path, "/files/")+1:]
+	}
+
+	endpoint := fmt.Sprintf("https://api.github.com/repos/%s/commits?path=%s&per_page=%d", repo, url.QueryEscape(file), limit)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github: unexpected status %s", resp.Status)
+	}
+
+	var commits []struct {
+		SHA     string `json:"sha"`
+		HTMLURL string `json:"html_url"`
+		Commit  struct {
+			Message string `json:"message"`
+			Author  struct {
+				Name string    `json:"name"`
+				Date time.Time `json:"date"`
+			} `json:"author"`
+		} `json:"commit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
+		return nil, err
+	}
+
+	entries := make([]ChangelogEntry, 0, len(commits))
+	for _, c := range commits {
+		entries = append(entries, ChangelogEntry{
+			SHA:     c.SHA,
+			Date:    c.Commit.Author.Date,
+			Author:  c.Commit.Author.Name,
+			Message: c.Commit.Message,
+			URL:     c.HTMLURL,
+		})
+	}
+	return entries, nil
+}
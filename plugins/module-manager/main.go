@@ -0,0 +1,665 @@
+// Module Manager Plugin for UnrealIRCd Web Panel
+// Lists the third-party modules loaded on each server, checks
+// unrealircd-contrib for updates and can install or upgrade modules on the
+// panel host
+
+package modulemanager
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// ModuleManagerPlugin implements the Plugin interface
+type ModuleManagerPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	servers   []*ServerModules
+	catalog   map[string]*CatalogModule
+	catalogAt time.Time
+	actions   []*Action
+	dirty     bool
+	lastError string
+	refresh   chan struct{}
+	installMu sync.Mutex
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL          string `json:"rpc_url"`
+	RPCUser         string `json:"rpc_user"`
+	RPCPassword     string `json:"rpc_password"`
+	RPCInsecure     bool   `json:"rpc_insecure"`
+	DataDir         string `json:"data_dir"`
+	CatalogURL      string `json:"catalog_url"`
+	ContribRepo     string `json:"contrib_repo"`
+	RefreshInterval int    `json:"refresh_interval"`
+	AllowInstall    bool   `json:"allow_install"`
+	InstallCommand  string `json:"install_command"`
+}
+
+// LoadedModule is a third-party module loaded on a server
+type LoadedModule struct {
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	Author          string `json:"author"`
+	Description     string `json:"description"`
+	InContrib       bool   `json:"in_contrib"`
+	LatestVersion   string `json:"latest_version,omitempty"`
+	UpdateAvailable bool   `json:"update_available"`
+}
+
+// ServerModules is the list of third-party modules of one server
+type ServerModules struct {
+	Server  string         `json:"server"`
+	Modules []LoadedModule `json:"modules"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// Action is an install or upgrade run on the panel host
+type Action struct {
+	ID         string     `json:"id"`
+	Module     string     `json:"module"`
+	Action     string     `json:"action"`
+	Actor      string     `json:"actor"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Success    bool       `json:"success"`
+	Output     string     `json:"output"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined bool `json:"ulined"`
+	} `json:"server"`
+}
+
+// rpcModule is an entry of server.module_list
+type rpcModule struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Author      string `json:"author"`
+	Description string `json:"description"`
+	ThirdParty  bool   `json:"third_party"`
+}
+
+// moduleName restricts what can be passed to the install command
+var moduleName = regexp.MustCompile(`^third/[A-Za-z0-9_-]+$`)
+
+// maxActions is the number of install actions kept
+const maxActions = 200
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ModuleManagerPlugin{
+		config: Config{
+			RPCURL:          "https://127.0.0.1:8600/api",
+			DataDir:         "data/plugins/module-manager",
+			CatalogURL:      "https://modules.unrealircd.org/modules.list",
+			ContribRepo:     "unrealircd/unrealircd-contrib",
+			RefreshInterval: 6,
+			InstallCommand:  "/home/ircd/unrealircd/unrealircd module install {module}",
+		},
+		servers: make([]*ServerModules, 0),
+		catalog: make(map[string]*CatalogModule),
+		actions: make([]*Action, 0),
+		refresh: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ModuleManagerPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Module Manager",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Third-party module inventory with contrib update checks",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ModuleManagerPlugin) Init() error {
+	p.mu.Lock()
+	var actions []*Action
+	if err := storage.LoadJSON(p.storePath(), &actions); err != nil {
+		log.Printf("[module-manager] failed to load data: %v", err)
+	}
+	if actions != nil {
+		p.actions = actions
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "module-manager-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		modules, updates := 0, 0
+		for _, s := range p.servers {
+			modules += len(s.Modules)
+			for _, m := range s.Modules {
+				if m.UpdateAvailable {
+					updates++
+				}
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Third-Party Modules",
+			Icon:  "Puzzle",
+			Content: map[string]interface{}{
+				"loaded":  modules,
+				"updates": updates,
+			},
+			Order: 38,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.refreshLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ModuleManagerPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ModuleManagerPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/module-manager")
+	{
+		plugin.GET("/modules", p.handleModules)
+		plugin.GET("/updates", p.handleUpdates)
+		plugin.GET("/available", p.handleAvailable)
+		plugin.GET("/changelog", p.handleChangelog)
+		plugin.POST("/install", p.handleInstall)
+		plugin.GET("/actions", p.handleActions)
+		plugin.POST("/refresh", p.handleRefresh)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *ModuleManagerPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "actions.json")
+}
+
+// save persists the action history if it changed
+func (p *ModuleManagerPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.actions); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ModuleManagerPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// refreshLoop reloads the catalog and module lists until shutdown
+func (p *ModuleManagerPlugin) refreshLoop() {
+	defer p.wg.Done()
+
+	p.load()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.RefreshInterval) * time.Hour
+		p.mu.RUnlock()
+		if interval < time.Hour {
+			interval = time.Hour
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.refresh:
+		case <-time.After(interval):
+		}
+		p.load()
+	}
+}
+
+// load fetches the contrib catalog and the third-party modules of every
+// server, and marks modules with a newer version in the catalog
+func (p *ModuleManagerPlugin) load() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p.mu.RLock()
+	catalogURL := p.config.CatalogURL
+	p.mu.RUnlock()
+
+	catalog, err := fetchCatalog(ctx, catalogURL)
+	if err != nil {
+		log.Printf("[module-manager] failed to fetch module catalog: %v", err)
+		p.mu.RLock()
+		catalog = p.catalog
+		p.mu.RUnlock()
+	} else {
+		p.mu.Lock()
+		p.catalog = catalog
+		p.catalogAt = time.Now().UTC()
+		p.mu.Unlock()
+	}
+
+	rpc := p.client()
+	var list struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := rpc.Call(ctx, "server.list", nil, &list); err != nil {
+		p.mu.Lock()
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		log.Printf("[module-manager] failed to list servers: %v", err)
+		return
+	}
+
+	servers := make([]*ServerModules, 0, len(list.List))
+	for _, s := range list.List {
+		if s.Server.ULined {
+			continue
+		}
+		sm := &ServerModules{Server: s.Name, Modules: make([]LoadedModule, 0)}
+		var mods struct {
+			List []rpcModule `json:"list"`
+		}
+		if err := rpc.Call(ctx, "server.module_list", map[string]interface{}{"server": s.Name}, &mods); err != nil {
+			sm.Error = err.Error()
+			servers = append(servers, sm)
+			continue
+		}
+		for _, m := range mods.List {
+			if !m.ThirdParty && !strings.HasPrefix(m.Name, "third/") {
+				continue
+			}
+			lm := LoadedModule{Name: m.Name, Version: m.Version, Author: m.Author, Description: m.Description}
+			if c, ok := catalog[m.Name]; ok {
+				lm.InContrib = true
+				lm.LatestVersion = c.Version
+				lm.UpdateAvailable = compareVersions(c.Version, m.Version) > 0
+			}
+			sm.Modules = append(sm.Modules, lm)
+		}
+		sort.Slice(sm.Modules, func(i, j int) bool { return sm.Modules[i].Name < sm.Modules[j].Name })
+		servers = append(servers, sm)
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Server < servers[j].Server })
+
+	p.mu.Lock()
+	p.servers = servers
+	p.lastError = ""
+	p.mu.Unlock()
+}
+
+// compareVersions compares two dotted version strings numerically,
+// ignoring any non-numeric suffix of each part
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = leadingInt(pa[i])
+		}
+		if i < len(pb) {
+			y = leadingInt(pb[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// leadingInt parses the digits at the start of s
+func leadingInt(s string) int {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
+// handleModules returns the third-party modules of every server
+func (p *ModuleManagerPlugin) handleModules(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"servers":    p.servers,
+		"catalog_at": p.catalogAt,
+		"error":      p.lastError,
+	})
+}
+
+// handleUpdates returns every loaded module with a newer contrib version,
+// grouped by module
+func (p *ModuleManagerPlugin) handleUpdates(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	type update struct {
+		Module  string            `json:"module"`
+		Latest  string            `json:"latest_version"`
+		Servers map[string]string `json:"servers"`
+	}
+	byModule := make(map[string]*update)
+	for _, s := range p.servers {
+		for _, m := range s.Modules {
+			if !m.UpdateAvailable {
+				continue
+			}
+			u, ok := byModule[m.Name]
+			if !ok {
+				u = &update{Module: m.Name, Latest: m.LatestVersion, Servers: make(map[string]string)}
+				byModule[m.Name] = u
+			}
+			u.Servers[s.Server] = m.Version
+		}
+	}
+	updates := make([]*update, 0, len(byModule))
+	for _, u := range byModule {
+		updates = append(updates, u)
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Module < updates[j].Module })
+
+	c.JSON(http.StatusOK, gin.H{"updates": updates})
+}
+
+// handleAvailable returns the contrib catalog, optionally filtered by q
+func (p *ModuleManagerPlugin) handleAvailable(c *gin.Context) {
+	q := strings.ToLower(c.Query("q"))
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	loaded := make(map[string]bool)
+	for _, s := range p.servers {
+		for _, m := range s.Modules {
+			loaded[m.Name] = true
+		}
+	}
+
+	list := make([]gin.H, 0, len(p.catalog))
+	for _, m := range p.catalog {
+		if q != "" && !strings.Contains(strings.ToLower(m.Name), q) && !strings.Contains(strings.ToLower(m.Description), q) {
+			continue
+		}
+		list = append(list, gin.H{
+			"name":                   m.Name,
+			"description":            m.Description,
+			"version":                m.Version,
+			"author":                 m.Author,
+			"documentation":          m.Documentation,
+			"min_unrealircd_version": m.MinVersion,
+			"loaded":                 loaded[m.Name],
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i]["name"].(string) < list[j]["name"].(string) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"modules":    list,
+		"catalog_at": p.catalogAt,
+	})
+}
+
+// handleChangelog returns the recent commits of a contrib module
+func (p *ModuleManagerPlugin) handleChangelog(c *gin.Context) {
+	name := c.Query("module")
+
+	p.mu.RLock()
+	m, ok := p.catalog[name]
+	repo := p.config.ContribRepo
+	p.mu.RUnlock()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found in unrealircd-contrib"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	entries, err := fetchChangelog(ctx, repo, m, limit)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"module":    m.Name,
+		"version":   m.Version,
+		"changelog": entries,
+	})
+}
+
+// handleInstall installs or upgrades a contrib module by running the
+// configured command on the panel host
+func (p *ModuleManagerPlugin) handleInstall(c *gin.Context) {
+	var req struct {
+		Module string `json:"module" binding:"required"`
+		Action string `json:"action"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if req.Action == "" {
+		req.Action = "install"
+	}
+	if req.Action != "install" && req.Action != "upgrade" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be install or upgrade"})
+		return
+	}
+	if !moduleName.MatchString(req.Module) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid module name"})
+		return
+	}
+
+	p.mu.RLock()
+	allowed := p.config.AllowInstall
+	command := p.config.InstallCommand
+	_, known := p.catalog[req.Module]
+	p.mu.RUnlock()
+	if !allowed || strings.TrimSpace(command) == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Module installation is disabled in the plugin settings"})
+		return
+	}
+	if err := grants.Allow(grants.Exec); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if !known {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found in unrealircd-contrib"})
+		return
+	}
+
+	// One install at a time, the IRCd build directory is shared
+	if !p.installMu.TryLock() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another install is in progress"})
+		return
+	}
+	defer p.installMu.Unlock()
+
+	action := &Action{
+		ID:        newID(),
+		Module:    req.Module,
+		Action:    req.Action,
+		Actor:     access.Actor(c),
+		StartedAt: time.Now().UTC(),
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+	defer cancel()
+
+	// "unrealircd module install" also upgrades an installed module
+	args := strings.Fields(strings.ReplaceAll(command, "{module}", req.Module))
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	now := time.Now().UTC()
+	action.FinishedAt = &now
+	action.Output = string(out)
+	action.Success = err == nil
+	if err != nil {
+		action.Output += fmt.Sprintf("\n%v", err)
+	}
+
+	p.mu.Lock()
+	p.actions = append(p.actions, action)
+	if len(p.actions) > maxActions {
+		p.actions = append([]*Action(nil), p.actions[len(p.actions)-maxActions:]...)
+	}
+	p.dirty = true
+	p.mu.Unlock()
+	if err := p.save(); err != nil {
+		log.Printf("[module-manager] failed to save data: %v", err)
+	}
+	log.Printf("[module-manager] %s ran %s of %s: success=%v", action.Actor, action.Action, action.Module, action.Success)
+
+	status := http.StatusOK
+	if !action.Success {
+		status = http.StatusBadGateway
+	}
+	c.JSON(status, action)
+}
+
+// handleActions returns the install history, newest first
+func (p *ModuleManagerPlugin) handleActions(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Action, 0, len(p.actions))
+	for i := len(p.actions) - 1; i >= 0; i-- {
+		list = append(list, p.actions[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"actions": list})
+}
+
+// handleRefresh schedules an immediate reload
+func (p *ModuleManagerPlugin) handleRefresh(c *gin.Context) {
+	select {
+	case p.refresh <- struct{}{}:
+	default:
+		// A refresh is already pending
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Refresh scheduled"})
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleGetConfig returns the current configuration
+func (p *ModuleManagerPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ModuleManagerPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ModuleManagerPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ModuleManagerPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
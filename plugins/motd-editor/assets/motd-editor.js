@@ -0,0 +1,336 @@
+/**
+ * MOTD Editor Frontend Script
+ *
+ * Edit the MOTD draft, preview it against the live file, push it to the
+ * servers and browse the version history with diffs and rollback.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'motd-editor';
+  const PLUGIN_NAME = 'MOTD Editor';
+  const PAGE_PATH = '/plugins/motd-editor';
+  const API_BASE = '/api/plugin/motd-editor';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('motd-editor-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'motd-editor-styles';
+    style.textContent = `
+      .motd-app { display: flex; flex-direction: column; gap: 1rem; }
+      .motd-toolbar { display: flex; gap: 0.5rem; flex-wrap: wrap; align-items: center; }
+      .motd-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .motd-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .motd-btn.danger { background: var(--error, #f38ba8); color: #fff; }
+      .motd-input, .motd-editor {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .motd-editor { width: 100%; min-height: 280px; font-family: monospace; font-size: 0.85rem; box-sizing: border-box; }
+      .motd-panes { display: grid; grid-template-columns: repeat(auto-fit, minmax(320px, 1fr)); gap: 1rem; }
+      .motd-pre {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.5rem;
+        margin: 0;
+        font-size: 0.8rem;
+        color: var(--text-secondary, #a6adc8);
+        white-space: pre-wrap;
+        max-height: 360px;
+        overflow: auto;
+      }
+      .motd-add { color: var(--success, #a6e3a1); }
+      .motd-del { color: var(--error, #f38ba8); }
+      .motd-warn { color: var(--warning, #f9e2af); font-size: 0.85rem; }
+      .motd-status { color: var(--text-muted, #6c7086); font-size: 0.85rem; }
+      .motd-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .motd-table th, .motd-table td {
+        text-align: left;
+        padding: 0.5rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+      }
+      .motd-table th { color: var(--text-primary, #cdd6f4); }
+      .motd-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderDiff(diff) {
+    return diff.map(l => {
+      const cls = l.op === '+' ? 'motd-add' : l.op === '-' ? 'motd-del' : '';
+      return `<span class="${cls}">${escapeHtml(l.op + ' ' + l.text)}</span>`;
+    }).join('\n');
+  }
+
+  function servers(container) {
+    return container.querySelector('#motd-servers').value.split(',').map(s => s.trim()).filter(Boolean);
+  }
+
+  function setStatus(container, msg, isError) {
+    const el = container.querySelector('#motd-status');
+    el.className = isError ? 'motd-error' : 'motd-status';
+    el.textContent = msg;
+  }
+
+  async function loadDraft(container) {
+    const editor = container.querySelector('#motd-text');
+    try {
+      const data = await api('GET', '/draft');
+      if (data.draft) {
+        editor.value = data.draft.content;
+        setStatus(container, `Draft saved by ${data.draft.updated_by} at ${new Date(data.draft.updated_at).toLocaleString()}`);
+        return;
+      }
+      const live = await api('GET', '/motd');
+      editor.value = live.content;
+      setStatus(container, `Loaded live MOTD from ${live.path}`);
+    } catch (e) {
+      setStatus(container, e.message, true);
+    }
+  }
+
+  async function preview(container) {
+    const out = container.querySelector('#motd-preview');
+    try {
+      const data = await api('POST', '/preview', { content: container.querySelector('#motd-text').value });
+      const warnings = data.warnings.map(w =>
+        `<div class="motd-warn">${w.line ? 'Line ' + w.line + ': ' : ''}${escapeHtml(w.message)}</div>`
+      ).join('');
+      const diff = data.diff
+        ? `<h4>Changes against live (+${data.added} / -${data.removed})</h4><pre class="motd-pre">${renderDiff(data.diff)}</pre>`
+        : `<div class="motd-warn">${escapeHtml(data.diff_error)}</div>`;
+      out.innerHTML = `
+        ${warnings}
+        <h4>As clients will see it</h4>
+        <pre class="motd-pre">${escapeHtml(data.rendered.join('\n'))}</pre>
+        ${diff}
+      `;
+    } catch (e) {
+      out.innerHTML = `<div class="motd-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadVersions(container) {
+    const list = container.querySelector('#motd-versions');
+    try {
+      const data = await api('GET', '/versions');
+      if (!data.versions.length) {
+        list.innerHTML = '<div class="motd-status">No versions pushed yet.</div>';
+        return;
+      }
+      list.innerHTML = `
+        <table class="motd-table">
+          <thead><tr><th>When</th><th>Author</th><th>Comment</th><th>Servers</th><th></th></tr></thead>
+          <tbody>${data.versions.map(v => `
+            <tr>
+              <td>${escapeHtml(new Date(v.created_at).toLocaleString())}</td>
+              <td>${escapeHtml(v.author)}</td>
+              <td>${escapeHtml(v.comment)}</td>
+              <td>${v.servers}${v.failed ? ` <span class="motd-error">(${v.failed} failed)</span>` : ''}</td>
+              <td>
+                <button class="motd-btn" data-action="diff" data-id="${escapeHtml(v.id)}">Diff</button>
+                <button class="motd-btn danger" data-action="rollback" data-id="${escapeHtml(v.id)}">Rollback</button>
+              </td>
+            </tr>
+          `).join('')}</tbody>
+        </table>
+      `;
+    } catch (e) {
+      list.innerHTML = `<div class="motd-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="motd-app" data-plugin="${PLUGIN_ID}">
+        <div class="motd-toolbar">
+          <button class="motd-btn" id="motd-live">Load live</button>
+          <button class="motd-btn" id="motd-save">Save draft</button>
+          <button class="motd-btn" id="motd-discard">Discard draft</button>
+          <button class="motd-btn" id="motd-do-preview">Preview</button>
+          <input class="motd-input" id="motd-servers" placeholder="Servers (empty = all)">
+          <input class="motd-input" id="motd-comment" placeholder="Comment">
+          <button class="motd-btn primary" id="motd-push">Push</button>
+        </div>
+        <div id="motd-status" class="motd-status"></div>
+        <div class="motd-panes">
+          <textarea class="motd-editor" id="motd-text" spellcheck="false"></textarea>
+          <div id="motd-preview"></div>
+        </div>
+        <h3>History</h3>
+        <div id="motd-versions"></div>
+        <div id="motd-diff"></div>
+      </div>
+    `;
+
+    const editor = container.querySelector('#motd-text');
+
+    container.querySelector('#motd-live').addEventListener('click', async () => {
+      try {
+        const live = await api('GET', '/motd');
+        editor.value = live.content;
+        setStatus(container, `Loaded live MOTD from ${live.path}`);
+      } catch (e) {
+        setStatus(container, e.message, true);
+      }
+    });
+
+    container.querySelector('#motd-save').addEventListener('click', async () => {
+      try {
+        await api('PUT', '/draft', { content: editor.value });
+        setStatus(container, 'Draft saved');
+      } catch (e) {
+        setStatus(container, e.message, true);
+      }
+    });
+
+    container.querySelector('#motd-discard').addEventListener('click', async () => {
+      if (!confirm('Discard the saved draft?')) return;
+      try {
+        await api('DELETE', '/draft');
+        loadDraft(container);
+      } catch (e) {
+        setStatus(container, e.message, true);
+      }
+    });
+
+    container.querySelector('#motd-do-preview').addEventListener('click', () => preview(container));
+
+    container.querySelector('#motd-push').addEventListener('click', async () => {
+      const target = servers(container);
+      if (!confirm(`Push this MOTD to ${target.length ? target.join(', ') : 'all servers'}?`)) return;
+      try {
+        await api('PUT', '/draft', { content: editor.value });
+        const v = await api('POST', '/push', { servers: target, comment: container.querySelector('#motd-comment').value });
+        const failed = v.results.filter(r => r.error);
+        setStatus(container, failed.length
+          ? `Pushed with errors: ${failed.map(r => `${r.server}: ${r.error}`).join('; ')}`
+          : `Pushed to ${v.results.length} server(s)`, failed.length > 0);
+        loadVersions(container);
+      } catch (e) {
+        setStatus(container, e.message, true);
+      }
+    });
+
+    container.querySelector('#motd-versions').addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+      const id = encodeURIComponent(btn.dataset.id);
+
+      if (btn.dataset.action === 'diff') {
+        try {
+          const d = await api('GET', `/versions/${id}/diff`);
+          container.querySelector('#motd-diff').innerHTML =
+            `<h4>Version ${escapeHtml(d.id)} against ${escapeHtml(d.against)} (+${d.added} / -${d.removed})</h4><pre class="motd-pre">${renderDiff(d.diff)}</pre>`;
+        } catch (err) {
+          alert(err.message);
+        }
+        return;
+      }
+
+      const target = servers(container);
+      if (!confirm(`Roll back ${target.length ? target.join(', ') : 'all servers'} to this version?`)) return;
+      try {
+        await api('POST', `/versions/${id}/rollback`, { servers: target });
+        setStatus(container, 'Rolled back');
+        loadVersions(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    loadDraft(container);
+    loadVersions(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('motd-editor-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,78 @@
+package motdeditor
+
+import "strings"
+
+// DiffLine is a line of a line-based diff. Op is " " for unchanged lines,
+// "-" for removed lines and "+" for added lines.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// splitMOTD splits MOTD text into lines, ignoring a trailing newline
+func splitMOTD(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines computes a line diff from a to b using the longest common
+// subsequence. MOTDs are small, so the quadratic table is fine.
+func diffLines(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	out := make([]DiffLine, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, DiffLine{Op: " ", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{Op: "-", Text: a[i]})
+			i++
+		default:
+			out = append(out, DiffLine{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		out = append(out, DiffLine{Op: "-", Text: a[i]})
+	}
+	for ; j < m; j++ {
+		out = append(out, DiffLine{Op: "+", Text: b[j]})
+	}
+	return out
+}
+
+// diffStats counts added and removed lines
+func diffStats(d []DiffLine) (added, removed int) {
+	for _, l := range d {
+		switch l.Op {
+		case "+":
+			added++
+		case "-":
+			removed++
+		}
+	}
+	return added, removed
+}
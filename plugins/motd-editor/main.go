@@ -0,0 +1,728 @@
+// MOTD Editor Plugin for UnrealIRCd Web Panel
+// Edit the MOTD as a draft, preview it and push it to one or all servers,
+// with a version history, diffs and rollback
+
+package motdeditor
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// maxMOTDLine is the longest MOTD line that fits in a 372 numeric without
+// risking truncation for long server and nick names
+const maxMOTDLine = 400
+
+// MOTDEditorPlugin implements the Plugin interface
+type MOTDEditorPlugin struct {
+	config   Config
+	rpc      *jsonrpc.Client
+	draft    *Draft
+	versions []*Version
+	dirty    bool
+	pushMu   sync.Mutex
+	mu       sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL      string `json:"rpc_url"`
+	RPCUser     string `json:"rpc_user"`
+	RPCPassword string `json:"rpc_password"`
+	RPCInsecure bool   `json:"rpc_insecure"`
+	DataDir     string `json:"data_dir"`
+	MOTDFiles   string `json:"motd_files"`
+	Rehash      bool   `json:"rehash"`
+	MaxVersions int    `json:"max_versions"`
+}
+
+// Draft is the MOTD being edited
+type Draft struct {
+	Content   string    `json:"content"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PushResult is the outcome of pushing a MOTD to one server
+type PushResult struct {
+	Server   string          `json:"server"`
+	Path     string          `json:"path"`
+	Written  bool            `json:"written"`
+	Rehashed bool            `json:"rehashed"`
+	Output   json.RawMessage `json:"output,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Version is a MOTD that was pushed
+type Version struct {
+	ID        string       `json:"id"`
+	Content   string       `json:"content"`
+	Comment   string       `json:"comment"`
+	Author    string       `json:"author"`
+	CreatedAt time.Time    `json:"created_at"`
+	Rollback  string       `json:"rollback_of,omitempty"`
+	Results   []PushResult `json:"results"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Draft    *Draft     `json:"draft"`
+	Versions []*Version `json:"versions"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined bool `json:"ulined"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &MOTDEditorPlugin{
+		config: Config{
+			RPCURL:      "https://127.0.0.1:8600/api",
+			DataDir:     "data/plugins/motd-editor",
+			MOTDFiles:   "*=/home/ircd/unrealircd/conf/ircd.motd",
+			Rehash:      true,
+			MaxVersions: 100,
+		},
+		versions: make([]*Version, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *MOTDEditorPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "MOTD Editor",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Edit, preview and push the MOTD with version history",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *MOTDEditorPlugin) Init() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[motd-editor] failed to load data: %v", err)
+	}
+	p.draft = data.Draft
+	if data.Versions != nil {
+		p.versions = data.Versions
+	}
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *MOTDEditorPlugin) Shutdown() error {
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *MOTDEditorPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/motd-editor")
+	{
+		plugin.GET("/motd", p.handleGetMOTD)
+		plugin.GET("/draft", p.handleGetDraft)
+		plugin.PUT("/draft", p.handleSaveDraft)
+		plugin.DELETE("/draft", p.handleDiscardDraft)
+		plugin.POST("/preview", p.handlePreview)
+		plugin.POST("/push", p.handlePush)
+		plugin.GET("/versions", p.handleListVersions)
+		plugin.GET("/versions/:id", p.handleGetVersion)
+		plugin.GET("/versions/:id/diff", p.handleDiff)
+		plugin.POST("/versions/:id/rollback", p.handleRollback)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *MOTDEditorPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "motd.json")
+}
+
+// save persists the draft and history if they changed
+func (p *MOTDEditorPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Draft: p.draft, Versions: p.versions}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// saveOrLog persists the state and logs failures
+func (p *MOTDEditorPlugin) saveOrLog() {
+	if err := p.save(); err != nil {
+		log.Printf("[motd-editor] failed to save data: %v", err)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *MOTDEditorPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// motdFiles parses the motd_files setting. Each line or comma separated
+// entry is "server=path"; the server "*" is used for servers without an
+// entry of their own.
+func motdFiles(s string) map[string]string {
+	files := make(map[string]string)
+	for _, entry := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		server, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		files[strings.ToLower(strings.TrimSpace(server))] = strings.TrimSpace(path)
+	}
+	return files
+}
+
+// pathFor returns the MOTD file of a server
+func pathFor(files map[string]string, server string) string {
+	if path, ok := files[strings.ToLower(server)]; ok {
+		return path
+	}
+	return files["*"]
+}
+
+// normalize makes sure MOTD content uses plain newlines and ends with one
+func normalize(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content
+}
+
+// readLive reads the MOTD file currently used by a server
+func (p *MOTDEditorPlugin) readLive(server string) (string, string, error) {
+	p.mu.RLock()
+	path := pathFor(motdFiles(p.config.MOTDFiles), server)
+	p.mu.RUnlock()
+	if path == "" {
+		return "", "", fmt.Errorf("no MOTD file configured for %s", server)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", path, err
+	}
+	return string(data), path, nil
+}
+
+// lineWarning is a problem found in a MOTD line
+type lineWarning struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// lint checks MOTD content for lines that would be truncated or that
+// contain control characters other than IRC formatting codes
+func lint(lines []string) []lineWarning {
+	warnings := make([]lineWarning, 0)
+	if len(lines) == 0 {
+		warnings = append(warnings, lineWarning{Line: 0, Message: "MOTD is empty"})
+	}
+	for i, line := range lines {
+		if len(line) > maxMOTDLine {
+			warnings = append(warnings, lineWarning{Line: i + 1, Message: fmt.Sprintf("line is %d bytes and may be truncated (limit %d)", len(line), maxMOTDLine)})
+		}
+		for _, r := range line {
+			if r >= 0x20 || strings.ContainsRune("\x02\x03\x04\x0f\x11\x16\x1d\x1e\x1f", r) {
+				continue
+			}
+			warnings = append(warnings, lineWarning{Line: i + 1, Message: fmt.Sprintf("contains control character %U", r)})
+			break
+		}
+	}
+	return warnings
+}
+
+// handleGetMOTD returns the live MOTD of a server (default: the "*" file)
+func (p *MOTDEditorPlugin) handleGetMOTD(c *gin.Context) {
+	server := c.DefaultQuery("server", "*")
+	content, path, err := p.readLive(server)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "path": path})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"server":  server,
+		"path":    path,
+		"content": content,
+	})
+}
+
+// handleGetDraft returns the current draft
+func (p *MOTDEditorPlugin) handleGetDraft(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"draft": p.draft})
+}
+
+// handleSaveDraft replaces the draft
+func (p *MOTDEditorPlugin) handleSaveDraft(c *gin.Context) {
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	draft := &Draft{
+		Content:   normalize(req.Content),
+		UpdatedBy: access.Actor(c),
+		UpdatedAt: time.Now().UTC(),
+	}
+	p.mu.Lock()
+	p.draft = draft
+	p.dirty = true
+	p.mu.Unlock()
+	p.saveOrLog()
+
+	c.JSON(http.StatusOK, gin.H{"draft": draft})
+}
+
+// handleDiscardDraft removes the draft
+func (p *MOTDEditorPlugin) handleDiscardDraft(c *gin.Context) {
+	p.mu.Lock()
+	p.draft = nil
+	p.dirty = true
+	p.mu.Unlock()
+	p.saveOrLog()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Draft discarded"})
+}
+
+// handlePreview shows content (or the draft) the way clients will see it,
+// with warnings and a diff against the live MOTD
+func (p *MOTDEditorPlugin) handlePreview(c *gin.Context) {
+	var req struct {
+		Content *string `json:"content"`
+		Server  string  `json:"server"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	content := ""
+	if req.Content != nil {
+		content = normalize(*req.Content)
+	} else {
+		p.mu.RLock()
+		if p.draft == nil {
+			p.mu.RUnlock()
+			c.JSON(http.StatusNotFound, gin.H{"error": "No draft to preview"})
+			return
+		}
+		content = p.draft.Content
+		p.mu.RUnlock()
+	}
+	if req.Server == "" {
+		req.Server = "*"
+	}
+
+	lines := splitMOTD(content)
+	rendered := make([]string, 0, len(lines)+2)
+	rendered = append(rendered, "- "+req.Server+" Message of the Day -")
+	for _, line := range lines {
+		rendered = append(rendered, "- "+line)
+	}
+	rendered = append(rendered, "End of /MOTD command.")
+
+	resp := gin.H{
+		"lines":    lines,
+		"rendered": rendered,
+		"warnings": lint(lines),
+	}
+	if live, _, err := p.readLive(req.Server); err == nil {
+		diff := diffLines(splitMOTD(live), lines)
+		added, removed := diffStats(diff)
+		resp["diff"] = diff
+		resp["added"] = added
+		resp["removed"] = removed
+	} else {
+		resp["diff_error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// targetServers resolves the servers to push to. An empty list means all
+// linked servers except services.
+func (p *MOTDEditorPlugin) targetServers(ctx context.Context, requested []string) ([]string, error) {
+	if len(requested) > 0 {
+		return requested, nil
+	}
+	var list struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := p.client().Call(ctx, "server.list", nil, &list); err != nil {
+		return nil, err
+	}
+	servers := make([]string, 0, len(list.List))
+	for _, s := range list.List {
+		if !s.Server.ULined {
+			servers = append(servers, s.Name)
+		}
+	}
+	sort.Strings(servers)
+	return servers, nil
+}
+
+// push writes content to the MOTD file of each server, rehashes the
+// servers and records a new version
+func (p *MOTDEditorPlugin) push(ctx context.Context, content string, servers []string, author, comment, rollback string) (*Version, error) {
+	// One push at a time so files and history stay consistent
+	p.pushMu.Lock()
+	defer p.pushMu.Unlock()
+
+	servers, err := p.targetServers(ctx, servers)
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no servers to push to")
+	}
+
+	p.mu.RLock()
+	files := motdFiles(p.config.MOTDFiles)
+	rehash := p.config.Rehash
+	p.mu.RUnlock()
+
+	v := &Version{
+		ID:        newID(),
+		Content:   content,
+		Comment:   comment,
+		Author:    author,
+		CreatedAt: time.Now().UTC(),
+		Rollback:  rollback,
+		Results:   make([]PushResult, 0, len(servers)),
+	}
+
+	// Servers sharing a file only need it written once
+	written := make(map[string]error)
+	rpc := p.client()
+	for _, server := range servers {
+		res := PushResult{Server: server, Path: pathFor(files, server)}
+		if res.Path == "" {
+			res.Error = "no MOTD file configured"
+			v.Results = append(v.Results, res)
+			continue
+		}
+
+		werr, done := written[res.Path]
+		if !done {
+			werr = writeFile(res.Path, content)
+			written[res.Path] = werr
+		}
+		if werr != nil {
+			res.Error = werr.Error()
+			v.Results = append(v.Results, res)
+			continue
+		}
+		res.Written = true
+
+		if rehash {
+			var out json.RawMessage
+			if err := rpc.Call(ctx, "server.rehash", map[string]interface{}{"server": server}, &out); err != nil {
+				res.Error = "rehash: " + err.Error()
+			} else {
+				res.Rehashed = true
+				res.Output = out
+			}
+		}
+		v.Results = append(v.Results, res)
+	}
+
+	p.mu.Lock()
+	p.versions = append(p.versions, v)
+	if max := p.config.MaxVersions; max > 0 && len(p.versions) > max {
+		p.versions = append([]*Version(nil), p.versions[len(p.versions)-max:]...)
+	}
+	p.dirty = true
+	p.mu.Unlock()
+	p.saveOrLog()
+
+	log.Printf("[motd-editor] %s pushed MOTD version %s to %d server(s)", author, v.ID, len(servers))
+	return v, nil
+}
+
+// writeFile atomically replaces a MOTD file, keeping its permissions
+func writeFile(path, content string) error {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// pushRequest is the body of push and rollback requests
+type pushRequest struct {
+	Servers []string `json:"servers"`
+	Comment string   `json:"comment"`
+}
+
+// handlePush pushes the draft and clears it
+func (p *MOTDEditorPlugin) handlePush(c *gin.Context) {
+	var req pushRequest
+	_ = c.ShouldBindJSON(&req)
+
+	p.mu.RLock()
+	draft := p.draft
+	p.mu.RUnlock()
+	if draft == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No draft to push"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+
+	v, err := p.push(ctx, draft.Content, req.Servers, access.Actor(c), req.Comment, "")
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	if p.draft == draft {
+		p.draft = nil
+		p.dirty = true
+	}
+	p.mu.Unlock()
+	p.saveOrLog()
+
+	c.JSON(http.StatusOK, v)
+}
+
+// findVersion returns a version by ID. Caller must hold p.mu.
+func (p *MOTDEditorPlugin) findVersion(id string) (*Version, int) {
+	for i, v := range p.versions {
+		if v.ID == id {
+			return v, i
+		}
+	}
+	return nil, -1
+}
+
+// handleListVersions returns the version history, newest first, without content
+func (p *MOTDEditorPlugin) handleListVersions(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]gin.H, 0, len(p.versions))
+	for i := len(p.versions) - 1; i >= 0; i-- {
+		v := p.versions[i]
+		failed := 0
+		for _, r := range v.Results {
+			if r.Error != "" {
+				failed++
+			}
+		}
+		list = append(list, gin.H{
+			"id":          v.ID,
+			"comment":     v.Comment,
+			"author":      v.Author,
+			"created_at":  v.CreatedAt,
+			"rollback_of": v.Rollback,
+			"servers":     len(v.Results),
+			"failed":      failed,
+			"lines":       len(splitMOTD(v.Content)),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": list})
+}
+
+// handleGetVersion returns a single version
+func (p *MOTDEditorPlugin) handleGetVersion(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	v, _ := p.findVersion(c.Param("id"))
+	if v == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// handleDiff diffs a version against the previous version (default), the
+// live MOTD (against=live) or another version (against=<id>)
+func (p *MOTDEditorPlugin) handleDiff(c *gin.Context) {
+	against := c.DefaultQuery("against", "previous")
+
+	p.mu.RLock()
+	v, idx := p.findVersion(c.Param("id"))
+	if v == nil {
+		p.mu.RUnlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	content := v.Content
+	base := ""
+	baseLabel := against
+	switch against {
+	case "previous":
+		if idx > 0 {
+			base = p.versions[idx-1].Content
+			baseLabel = p.versions[idx-1].ID
+		}
+	case "live":
+	default:
+		other, _ := p.findVersion(against)
+		if other == nil {
+			p.mu.RUnlock()
+			c.JSON(http.StatusNotFound, gin.H{"error": "Version to compare against not found"})
+			return
+		}
+		base = other.Content
+	}
+	p.mu.RUnlock()
+
+	if against == "live" {
+		live, _, err := p.readLive(c.DefaultQuery("server", "*"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		base = live
+	}
+
+	diff := diffLines(splitMOTD(base), splitMOTD(content))
+	added, removed := diffStats(diff)
+	c.JSON(http.StatusOK, gin.H{
+		"id":      v.ID,
+		"against": baseLabel,
+		"diff":    diff,
+		"added":   added,
+		"removed": removed,
+	})
+}
+
+// handleRollback pushes an older version again as a new version
+func (p *MOTDEditorPlugin) handleRollback(c *gin.Context) {
+	var req pushRequest
+	_ = c.ShouldBindJSON(&req)
+
+	p.mu.RLock()
+	v, _ := p.findVersion(c.Param("id"))
+	p.mu.RUnlock()
+	if v == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	if req.Comment == "" {
+		req.Comment = "Rollback to " + v.ID
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+
+	nv, err := p.push(ctx, v.Content, req.Servers, access.Actor(c), req.Comment, v.ID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, nv)
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleGetConfig returns the current configuration
+func (p *MOTDEditorPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *MOTDEditorPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *MOTDEditorPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *MOTDEditorPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,57 @@
+package mqttpublisher
+
+import (
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// banEvent is the payload published on events/ban
+type banEvent struct {
+	Action    string    `json:"action"`
+	Type      string    `json:"type"`
+	Kind      string    `json:"kind"`
+	Mask      string    `json:"mask"`
+	SetBy     string    `json:"set_by"`
+	Duration  string    `json:"duration,omitempty"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// banFromLog turns a TKL_ADD or TKL_DEL log entry into a ban event. Bans
+// from the configuration file are skipped.
+func banFromLog(ev jsonrpc.LogEvent) (banEvent, bool) {
+	var action string
+	switch ev.EventID {
+	case "TKL_ADD":
+		action = "added"
+	case "TKL_DEL":
+		action = "removed"
+	default:
+		return banEvent{}, false
+	}
+	t, ok := notify.ParseTKL(ev)
+	if !ok {
+		return banEvent{}, false
+	}
+
+	duration := t.DurationString
+	if action == "added" && (duration == "" || duration == "0") {
+		duration = "permanent"
+	}
+	ts, err := time.Parse(time.RFC3339, ev.Timestamp)
+	if err != nil {
+		ts = time.Now()
+	}
+	return banEvent{
+		Action:    action,
+		Type:      t.Type,
+		Kind:      t.Kind(),
+		Mask:      t.Name,
+		SetBy:     t.SetBy,
+		Duration:  duration,
+		Reason:    t.Reason,
+		Timestamp: ts.UTC(),
+	}, true
+}
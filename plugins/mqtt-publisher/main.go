@@ -0,0 +1,759 @@
+// MQTT Publisher Plugin for UnrealIRCd Web Panel
+// Publishes live network stats, bans, netsplits and plugin alerts to MQTT
+// topics for home automation and custom dashboards
+
+package mqttpublisher
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// maxBackoff is the longest wait between attempts to reach the broker
+const maxBackoff = 5 * time.Minute
+
+// MQTTPublisherPlugin implements the Plugin interface
+type MQTTPublisherPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	outbox       []*message
+	notify       chan struct{}
+	poke         chan struct{}
+	reconnect    bool
+	connected    bool
+	published    int
+	dropped      int
+	lastPublish  time.Time
+	publishErr   string
+	pollErr      string
+	known        map[string]serverState
+	seeded       bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL           string `json:"rpc_url"`
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	StreamURL        string `json:"stream_url"`
+	Broker           string `json:"broker"`
+	ClientID         string `json:"client_id"`
+	Username         string `json:"username"`
+	Password         string `json:"password"`
+	TLSInsecure      bool   `json:"tls_insecure"`
+	TopicPrefix      string `json:"topic_prefix"`
+	QoS              int    `json:"qos"`
+	RetainStats      bool   `json:"retain_stats"`
+	KeepAlive        int    `json:"keep_alive"`
+	Interval         int    `json:"interval"`
+	PublishBans      bool   `json:"publish_bans"`
+	PublishNetsplits bool   `json:"publish_netsplits"`
+	EventsToken      string `json:"events_token"`
+	OutboxSize       int    `json:"outbox_size"`
+}
+
+// statsResult is the part of stats.get we publish
+type statsResult struct {
+	Server struct {
+		Total  int `json:"total"`
+		ULined int `json:"ulined"`
+	} `json:"server"`
+	User struct {
+		Total  int `json:"total"`
+		ULined int `json:"ulined"`
+		Oper   int `json:"oper"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+	ServerBan struct {
+		Total int `json:"total"`
+	} `json:"server_ban"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		Uplink   string `json:"uplink"`
+		NumUsers int    `json:"num_users"`
+		ULined   bool   `json:"ulined"`
+	} `json:"server"`
+}
+
+// serverState is the last known state of a linked server
+type serverState struct {
+	Name   string
+	Uplink string
+	Users  int
+	ULined bool
+}
+
+// netsplitEvent is the payload published on events/netsplit
+type netsplitEvent struct {
+	Action    string    `json:"action"`
+	Server    string    `json:"server"`
+	Uplink    string    `json:"uplink"`
+	Users     int       `json:"users"`
+	ULined    bool      `json:"ulined"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &MQTTPublisherPlugin{
+		config: Config{
+			RPCURL:           "https://127.0.0.1:8600/api",
+			StreamURL:        "wss://127.0.0.1:8600/",
+			Broker:           "tcp://127.0.0.1:1883",
+			ClientID:         "unrealircd-webpanel",
+			TopicPrefix:      "unrealircd",
+			RetainStats:      true,
+			KeepAlive:        60,
+			Interval:         30,
+			PublishBans:      true,
+			PublishNetsplits: true,
+			OutboxSize:       1000,
+		},
+		outbox: make([]*message, 0),
+		notify: make(chan struct{}, 1),
+		poke:   make(chan struct{}, 1),
+		known:  make(map[string]serverState),
+	}
+}
+
+// Info returns plugin metadata
+func (p *MQTTPublisherPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "MQTT Publisher",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Publishes live stats, bans, netsplits and alerts to MQTT topics",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *MQTTPublisherPlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "mqtt-publisher-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"published": p.published,
+			"queued":    len(p.outbox),
+		}
+		if !p.connected {
+			content["status"] = "Broker unreachable"
+		}
+		return plugins.DashboardCard{
+			Title:   "MQTT Publisher",
+			Icon:    "Radio",
+			Content: content,
+			Order:   72,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(3)
+	go p.publishLoop()
+	go p.pollLoop()
+	go p.streamLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *MQTTPublisherPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *MQTTPublisherPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/mqtt-publisher")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/test", p.handleTest)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the RPC client, creating it on first use
+func (p *MQTTPublisherPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// topic returns a topic under the configured prefix. Caller must hold
+// p.mu.
+func (p *MQTTPublisherPlugin) topic(levels ...string) string {
+	return p.config.TopicPrefix + "/" + strings.Join(levels, "/")
+}
+
+// topicLevel makes a value safe to use as one topic level
+func topicLevel(s string) string {
+	s = strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(strings.TrimSpace(s))
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// options returns the broker connection settings. The will marks us
+// offline on the status topic if the connection drops without a goodbye.
+// Caller must hold p.mu.
+func (p *MQTTPublisherPlugin) options() mqttOptions {
+	return mqttOptions{
+		Broker:      p.config.Broker,
+		ClientID:    p.config.ClientID,
+		Username:    p.config.Username,
+		Password:    p.config.Password,
+		TLSInsecure: p.config.TLSInsecure,
+		KeepAlive:   time.Duration(p.config.KeepAlive) * time.Second,
+		Will:        &message{Topic: p.topic("status"), Payload: []byte("offline"), Retain: true},
+	}
+}
+
+// enqueue adds messages to the outbox. A retained message replaces one
+// still waiting for the same topic, as only the latest value matters;
+// beyond outbox_size the oldest messages are dropped. Caller must hold
+// p.mu.
+func (p *MQTTPublisherPlugin) enqueue(msgs ...*message) {
+	for _, m := range msgs {
+		replaced := false
+		if m.Retain {
+			for i, queued := range p.outbox {
+				if queued.Retain && queued.Topic == m.Topic {
+					p.outbox[i] = m
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			p.outbox = append(p.outbox, m)
+		}
+	}
+	if over := len(p.outbox) - p.config.OutboxSize; over > 0 {
+		p.outbox = append([]*message(nil), p.outbox[over:]...)
+		p.dropped += over
+	}
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueJSON adds a JSON message to the outbox. Caller must hold p.mu.
+func (p *MQTTPublisherPlugin) enqueueJSON(topic string, v interface{}, retain bool) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("[mqtt-publisher] failed to encode %s: %v", topic, err)
+		return
+	}
+	p.enqueue(&message{Topic: topic, Payload: payload, Retain: retain})
+}
+
+// publishLoop keeps a connection to the broker and publishes the outbox
+// in order until shutdown, reconnecting with backoff when the broker is
+// unreachable. Idle connections are kept alive with pings.
+func (p *MQTTPublisherPlugin) publishLoop() {
+	defer p.wg.Done()
+
+	var conn *mqttConn
+	defer func() {
+		if conn != nil {
+			p.mu.RLock()
+			status := message{Topic: p.topic("status"), Payload: []byte("offline"), Retain: true}
+			p.mu.RUnlock()
+			_ = conn.publish(status, 0)
+			conn.close()
+		}
+	}()
+
+	backoff := time.Duration(0)
+	fail := func(err error) bool {
+		if conn != nil {
+			conn.close()
+			conn = nil
+		}
+		p.mu.Lock()
+		p.connected = false
+		p.publishErr = err.Error()
+		p.mu.Unlock()
+
+		if backoff == 0 {
+			backoff = 5 * time.Second
+			log.Printf("[mqtt-publisher] broker unreachable: %v", err)
+		} else if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		select {
+		case <-p.stop:
+			return false
+		case <-time.After(backoff):
+			return true
+		}
+	}
+
+	for {
+		p.mu.Lock()
+		if p.reconnect && conn != nil {
+			conn.close()
+			conn = nil
+		}
+		p.reconnect = false
+		broker := p.config.Broker
+		opts := p.options()
+		qos := p.config.QoS
+		online := message{Topic: p.topic("status"), Payload: []byte("online"), Retain: true}
+		var next *message
+		if len(p.outbox) > 0 {
+			next = p.outbox[0]
+		}
+		p.mu.Unlock()
+
+		if broker == "" {
+			select {
+			case <-p.stop:
+				return
+			case <-p.notify:
+			}
+			continue
+		}
+
+		if conn == nil {
+			c, err := dialMQTT(opts)
+			if err == nil {
+				err = c.publish(online, qos)
+				if err != nil {
+					c.conn.Close()
+				}
+			}
+			if err != nil {
+				if !fail(err) {
+					return
+				}
+				continue
+			}
+			conn = c
+			p.mu.Lock()
+			p.connected = true
+			p.publishErr = ""
+			p.mu.Unlock()
+			if backoff > 0 {
+				log.Printf("[mqtt-publisher] connected to broker again")
+				backoff = 0
+			}
+		}
+
+		if next == nil {
+			select {
+			case <-p.stop:
+				return
+			case <-p.notify:
+			case <-time.After(opts.KeepAlive / 2):
+				if err := conn.ping(); err != nil && !fail(err) {
+					return
+				}
+			}
+			continue
+		}
+
+		if err := conn.publish(*next, qos); err != nil {
+			if !fail(err) {
+				return
+			}
+			continue
+		}
+		p.mu.Lock()
+		// The message may have been replaced or dropped while it was sent
+		if len(p.outbox) > 0 && p.outbox[0] == next {
+			p.outbox = p.outbox[1:]
+		}
+		p.published++
+		p.lastPublish = time.Now()
+		p.mu.Unlock()
+	}
+}
+
+// pollLoop publishes stats every interval until shutdown. Link log events
+// cut the wait short so netsplits are published within a second or two.
+func (p *MQTTPublisherPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.poke:
+			// Give the IRCd a moment to finish processing the squit
+			time.Sleep(time.Second)
+			p.poll()
+		case <-timer.C:
+			p.poll()
+			p.mu.RLock()
+			interval := time.Duration(p.config.Interval) * time.Second
+			p.mu.RUnlock()
+			timer.Reset(interval)
+		}
+	}
+}
+
+// poll publishes the network stats and any change in the server list
+func (p *MQTTPublisherPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rpc := p.client()
+	var stats statsResult
+	statsErr := rpc.Call(ctx, "stats.get", nil, &stats)
+	var servers struct {
+		List []rpcServer `json:"list"`
+	}
+	serversErr := rpc.Call(ctx, "server.list", nil, &servers)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case statsErr != nil:
+		p.pollErr = statsErr.Error()
+	case serversErr != nil:
+		p.pollErr = serversErr.Error()
+	default:
+		p.pollErr = ""
+	}
+
+	if statsErr == nil {
+		retain := p.config.RetainStats
+		values := []struct {
+			topic string
+			value int
+		}{
+			{p.topic("users", "current"), stats.User.Total},
+			{p.topic("users", "record"), stats.User.Record},
+			{p.topic("opers", "current"), stats.User.Oper},
+			{p.topic("channels", "current"), stats.Channel.Total},
+			{p.topic("servers", "current"), stats.Server.Total},
+			{p.topic("bans", "current"), stats.ServerBan.Total},
+		}
+		for _, v := range values {
+			p.enqueue(&message{Topic: v.topic, Payload: []byte(strconv.Itoa(v.value)), Retain: retain})
+		}
+		p.enqueueJSON(p.topic("stats"), map[string]interface{}{
+			"users":          stats.User.Total,
+			"users_ulined":   stats.User.ULined,
+			"users_record":   stats.User.Record,
+			"opers":          stats.User.Oper,
+			"channels":       stats.Channel.Total,
+			"servers":        stats.Server.Total,
+			"servers_ulined": stats.Server.ULined,
+			"bans":           stats.ServerBan.Total,
+			"timestamp":      time.Now().UTC(),
+		}, retain)
+	}
+
+	if serversErr == nil {
+		p.diffServers(servers.List)
+	}
+}
+
+// diffServers publishes a netsplit event for every server that vanished
+// or rejoined since the last poll. The first poll only learns the list.
+// Caller must hold p.mu.
+func (p *MQTTPublisherPlugin) diffServers(list []rpcServer) {
+	current := make(map[string]serverState, len(list))
+	for _, rs := range list {
+		current[strings.ToLower(rs.Name)] = serverState{
+			Name:   rs.Name,
+			Uplink: rs.Server.Uplink,
+			Users:  rs.Server.NumUsers,
+			ULined: rs.Server.ULined,
+		}
+	}
+
+	if p.seeded && p.config.PublishNetsplits {
+		now := time.Now().UTC()
+		for key, s := range p.known {
+			if _, ok := current[key]; !ok {
+				p.enqueueJSON(p.topic("events", "netsplit"), netsplitEvent{
+					Action: "split", Server: s.Name, Uplink: s.Uplink, Users: s.Users, ULined: s.ULined, Timestamp: now,
+				}, false)
+			}
+		}
+		for key, s := range current {
+			if _, ok := p.known[key]; !ok {
+				p.enqueueJSON(p.topic("events", "netsplit"), netsplitEvent{
+					Action: "rejoin", Server: s.Name, Uplink: s.Uplink, Users: s.Users, ULined: s.ULined, Timestamp: now,
+				}, false)
+			}
+		}
+	}
+	p.known = current
+	p.seeded = true
+}
+
+// streamLoop follows ban and link log events until shutdown
+func (p *MQTTPublisherPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, []string{"tkl", "link"})
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *MQTTPublisherPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[mqtt-publisher] log stream: %v", err)
+	}
+}
+
+// handleEvent publishes bans and triggers a poll on link events
+func (p *MQTTPublisherPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	if ev.Subsystem == "link" {
+		select {
+		case p.poke <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	ban, ok := banFromLog(ev)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.PublishBans {
+		p.enqueueJSON(p.topic("events", "ban"), ban, false)
+	}
+}
+
+// handleStatus returns the broker connection and the outbox
+func (p *MQTTPublisherPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"broker":       p.config.Broker,
+		"topic_prefix": p.config.TopicPrefix,
+		"connected":    p.connected,
+		"error":        p.publishErr,
+		"published":    p.published,
+		"queued":       len(p.outbox),
+		"dropped":      p.dropped,
+		"servers":      len(p.known),
+		"rpc_error":    p.pollErr,
+		"stream_ok":    p.streamOK,
+	}
+	if !p.lastPublish.IsZero() {
+		status["last_published"] = p.lastPublish
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleTest publishes a test message over a connection of its own and
+// reports the outcome. It connects with its own client ID so the broker
+// does not drop the main connection.
+func (p *MQTTPublisherPlugin) handleTest(c *gin.Context) {
+	p.mu.RLock()
+	opts := p.options()
+	opts.ClientID += "-test"
+	opts.Will = nil
+	qos := p.config.QoS
+	m := message{Topic: p.topic("test"), Payload: []byte("Test message from the UnrealIRCd Web Panel")}
+	p.mu.RUnlock()
+
+	conn, err := dialMQTT(opts)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer conn.close()
+	if err := conn.publish(m, qos); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test message published", "topic": m.Topic})
+}
+
+// handleEvents accepts alerts from other plugins and publishes them under
+// alerts/<source>
+func (p *MQTTPublisherPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	p.enqueueJSON(p.topic("alerts", topicLevel(ev.Source)), ev, false)
+	p.mu.Unlock()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *MQTTPublisherPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.Password = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *MQTTPublisherPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.Broker != "" {
+		if _, _, err := brokerAddress(newConfig.Broker); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if newConfig.ClientID == "" || len(newConfig.ClientID) > 65535 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "client_id is required"})
+		return
+	}
+	newConfig.TopicPrefix = strings.Trim(strings.TrimSpace(newConfig.TopicPrefix), "/")
+	if newConfig.TopicPrefix == "" || strings.ContainsAny(newConfig.TopicPrefix, "+#") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "topic_prefix must not be empty or contain + or #"})
+		return
+	}
+	if newConfig.QoS != 0 && newConfig.QoS != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "qos must be 0 or 1"})
+		return
+	}
+	if newConfig.KeepAlive < 10 || newConfig.KeepAlive > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep_alive must be between 10 and 3600 seconds"})
+		return
+	}
+	if newConfig.Interval < 5 || newConfig.Interval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be between 5 and 3600 seconds"})
+		return
+	}
+	if newConfig.OutboxSize < 10 || newConfig.OutboxSize > 100000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "outbox_size must be between 10 and 100000"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.Password == "" {
+		newConfig.Password = p.config.Password
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.RPCURL != p.config.RPCURL {
+		// Another network's servers are not a netsplit
+		p.seeded = false
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.reconnect = true
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *MQTTPublisherPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *MQTTPublisherPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,288 @@
+package mqttpublisher
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// MQTT 3.1.1 control packet types, in the upper nibble of the first byte
+const (
+	packetConnect    = 0x10
+	packetConnack    = 0x20
+	packetPublish    = 0x30
+	packetPuback     = 0x40
+	packetPingreq    = 0xC0
+	packetPingresp   = 0xD0
+	packetDisconnect = 0xE0
+)
+
+// maxPacket is the largest packet we accept from the broker
+const maxPacket = 1 << 16
+
+// connackErrors describes the CONNACK return codes that refuse a connection
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client ID rejected",
+	3: "server unavailable",
+	4: "bad username or password",
+	5: "not authorized",
+}
+
+// message is one MQTT publish
+type message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// mqttOptions are the connection settings for a broker
+type mqttOptions struct {
+	Broker      string
+	ClientID    string
+	Username    string
+	Password    string
+	TLSInsecure bool
+	KeepAlive   time.Duration
+	Will        *message
+}
+
+// mqttConn is a connection to an MQTT broker. It only publishes, so
+// everything the broker sends is an answer to something we sent and is
+// read right after sending it; no reader goroutine is needed. It is not
+// safe for concurrent use.
+type mqttConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	nextID uint16
+}
+
+// brokerAddress returns the host:port of a broker URL and whether it uses
+// TLS. tcp:// and mqtt:// are plain, ssl://, tls:// and mqtts:// use TLS.
+func brokerAddress(broker string) (string, bool, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Hostname() == "" {
+		return "", false, fmt.Errorf("invalid broker URL %q", broker)
+	}
+	var secure bool
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		secure = true
+		port = "8883"
+	default:
+		return "", false, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), secure, nil
+}
+
+// dialMQTT connects and logs in to a broker
+func dialMQTT(opts mqttOptions) (*mqttConn, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	addr, secure, err := brokerAddress(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if secure {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: opts.TLSInsecure,
+		})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &mqttConn{conn: conn, r: bufio.NewReader(conn)}
+	if err := c.connect(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// connect sends CONNECT with a clean session and waits for CONNACK
+func (c *mqttConn) connect(opts mqttOptions) error {
+	var flags byte = 0x02
+	body := appendString(nil, "MQTT")
+	body = append(body, 4)
+
+	payload := appendString(nil, opts.ClientID)
+	if opts.Will != nil {
+		flags |= 0x04
+		if opts.Will.Retain {
+			flags |= 0x20
+		}
+		payload = appendString(payload, opts.Will.Topic)
+		payload = appendBytes(payload, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= 0x40
+			payload = appendString(payload, opts.Password)
+		}
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = append(body, payload...)
+
+	if err := c.write(packetConnect, body); err != nil {
+		return err
+	}
+	kind, resp, err := c.read()
+	if err != nil {
+		return err
+	}
+	if kind != packetConnack || len(resp) != 2 {
+		return fmt.Errorf("broker did not answer CONNECT")
+	}
+	if resp[1] != 0 {
+		if msg, ok := connackErrors[resp[1]]; ok {
+			return fmt.Errorf("broker refused connection: %s", msg)
+		}
+		return fmt.Errorf("broker refused connection with code %d", resp[1])
+	}
+	return nil
+}
+
+// publish sends a message. With QoS 1 it waits for the broker's PUBACK.
+func (c *mqttConn) publish(m message, qos int) error {
+	var flags byte = packetPublish
+	if m.Retain {
+		flags |= 0x01
+	}
+	body := appendString(nil, m.Topic)
+	var id uint16
+	if qos > 0 {
+		flags |= 0x02
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+		id = c.nextID
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	body = append(body, m.Payload...)
+
+	if err := c.write(flags, body); err != nil {
+		return err
+	}
+	if qos == 0 {
+		return nil
+	}
+	for {
+		kind, resp, err := c.read()
+		if err != nil {
+			return err
+		}
+		if kind == packetPuback && len(resp) == 2 && binary.BigEndian.Uint16(resp) == id {
+			return nil
+		}
+	}
+}
+
+// ping sends PINGREQ and waits for PINGRESP, keeping the connection alive
+func (c *mqttConn) ping() error {
+	if err := c.write(packetPingreq, nil); err != nil {
+		return err
+	}
+	for {
+		kind, _, err := c.read()
+		if err != nil {
+			return err
+		}
+		if kind == packetPingresp {
+			return nil
+		}
+	}
+}
+
+// close disconnects cleanly, so the broker does not publish the will
+func (c *mqttConn) close() {
+	_ = c.write(packetDisconnect, nil)
+	c.conn.Close()
+}
+
+// write sends one packet
+func (c *mqttConn) write(header byte, body []byte) error {
+	buf := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			break
+		}
+	}
+	buf = append(buf, body...)
+	_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// read receives one packet and returns its type and body
+func (c *mqttConn) read() (byte, []byte, error) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, shift := 0, 0
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, fmt.Errorf("malformed packet length")
+		}
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(b&0x7F) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if n > maxPacket {
+		return 0, nil, fmt.Errorf("packet of %d bytes is too large", n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return header & 0xF0, body, nil
+}
+
+// appendString appends a length-prefixed UTF-8 string
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+// appendBytes appends length-prefixed binary data
+func appendBytes(b, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
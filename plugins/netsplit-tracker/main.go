@@ -0,0 +1,597 @@
+// Netsplit Tracker Plugin for UnrealIRCd Web Panel
+// Records every netsplit with its duration and the users lost, and reports
+// split frequency and mean time to recovery
+
+package netsplittracker
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// NetsplitTrackerPlugin implements the Plugin interface
+type NetsplitTrackerPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	known        map[string]serverState
+	incidents    []*Incident
+	seeded       bool
+	dirty        bool
+	lastPoll     time.Time
+	lastError    string
+	streamOK     bool
+	poke         chan struct{}
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL       string `json:"rpc_url"`
+	RPCUser      string `json:"rpc_user"`
+	RPCPassword  string `json:"rpc_password"`
+	RPCInsecure  bool   `json:"rpc_insecure"`
+	StreamURL    string `json:"stream_url"`
+	UseStream    bool   `json:"use_stream"`
+	DataDir      string `json:"data_dir"`
+	PollInterval int    `json:"poll_interval"`
+	AlertWebhook string `json:"alert_webhook"`
+	MaxIncidents int    `json:"max_incidents"`
+}
+
+// SplitServer is a server that was lost in a split
+type SplitServer struct {
+	Name     string     `json:"name"`
+	Uplink   string     `json:"uplink"`
+	Users    int        `json:"users"`
+	Rejoined *time.Time `json:"rejoined"`
+}
+
+// Incident is a single netsplit
+type Incident struct {
+	ID        string        `json:"id"`
+	Start     time.Time     `json:"start"`
+	End       *time.Time    `json:"end"`
+	Duration  int64         `json:"duration_seconds"`
+	UsersLost int           `json:"users_lost"`
+	Servers   []SplitServer `json:"servers"`
+}
+
+// serverState is the last known state of a linked server
+type serverState struct {
+	Name   string `json:"name"`
+	Uplink string `json:"uplink"`
+	Users  int    `json:"users"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Known     map[string]serverState `json:"known"`
+	Incidents []*Incident            `json:"incidents"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		Uplink   string `json:"uplink"`
+		NumUsers int    `json:"num_users"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &NetsplitTrackerPlugin{
+		config: Config{
+			RPCURL:       "https://127.0.0.1:8600/api",
+			StreamURL:    "wss://127.0.0.1:8600/",
+			UseStream:    true,
+			DataDir:      "data/plugins/netsplit-tracker",
+			PollInterval: 15,
+			MaxIncidents: 1000,
+		},
+		known:     make(map[string]serverState),
+		incidents: make([]*Incident, 0),
+		poke:      make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *NetsplitTrackerPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Netsplit Tracker",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Netsplit history with duration, users lost and MTTR",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *NetsplitTrackerPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[netsplit-tracker] failed to load data: %v", err)
+	}
+	if data.Known != nil {
+		p.known = data.Known
+		p.seeded = len(p.known) > 0
+	}
+	if data.Incidents != nil {
+		p.incidents = data.Incidents
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "netsplit-tracker-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		st := p.stats(time.Now().AddDate(0, 0, -30))
+		return plugins.DashboardCard{
+			Title: "Netsplits",
+			Icon:  "Unlink",
+			Content: map[string]interface{}{
+				"ongoing":      st.Ongoing,
+				"splits_30d":   st.Splits,
+				"mttr_seconds": st.MTTRSeconds,
+			},
+			Order: 31,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.pollLoop()
+	go p.streamLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *NetsplitTrackerPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *NetsplitTrackerPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/netsplit-tracker")
+	{
+		plugin.GET("/incidents", p.handleListIncidents)
+		plugin.GET("/incidents/:id", p.handleGetIncident)
+		plugin.GET("/stats", p.handleStats)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *NetsplitTrackerPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "netsplits.json")
+}
+
+// save persists the state if it changed
+func (p *NetsplitTrackerPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Known: p.known, Incidents: p.incidents}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *NetsplitTrackerPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop polls the server list until shutdown. Link log events cut the
+// wait short so splits are noticed within a second or two.
+func (p *NetsplitTrackerPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.poll()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 5*time.Second {
+			interval = 5 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.poke:
+			// Give the IRCd a moment to finish processing the squit
+			time.Sleep(time.Second)
+		case <-time.After(interval):
+		}
+		p.poll()
+		if err := p.save(); err != nil {
+			log.Printf("[netsplit-tracker] failed to save data: %v", err)
+		}
+	}
+}
+
+// streamLoop follows link log events until shutdown
+func (p *NetsplitTrackerPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		enabled := p.config.UseStream
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, []string{"link"})
+		p.mu.Unlock()
+
+		if enabled {
+			stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		} else {
+			<-ctx.Done()
+		}
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *NetsplitTrackerPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[netsplit-tracker] log stream: %v", err)
+	}
+}
+
+// handleEvent triggers an immediate poll on any link event
+func (p *NetsplitTrackerPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	select {
+	case p.poke <- struct{}{}:
+	default:
+	}
+}
+
+// poll compares the server list with the last known state
+func (p *NetsplitTrackerPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcServer `json:"list"`
+	}
+	err := p.client().Call(ctx, "server.list", nil, &result)
+
+	p.mu.Lock()
+	if err != nil {
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		return
+	}
+	p.lastError = ""
+	now := time.Now().UTC()
+	p.lastPoll = now
+
+	current := make(map[string]serverState, len(result.List))
+	for _, rs := range result.List {
+		current[strings.ToLower(rs.Name)] = serverState{Name: rs.Name, Uplink: rs.Server.Uplink, Users: rs.Server.NumUsers}
+	}
+
+	alerts := make([]notify.Event, 0)
+	if p.seeded {
+		// Servers that rejoined close their part of any open incident
+		for _, inc := range p.incidents {
+			if inc.End != nil {
+				continue
+			}
+			open := 0
+			for i := range inc.Servers {
+				s := &inc.Servers[i]
+				if s.Rejoined == nil {
+					if _, back := current[strings.ToLower(s.Name)]; back {
+						t := now
+						s.Rejoined = &t
+					} else {
+						open++
+					}
+				}
+			}
+			if open == 0 {
+				t := now
+				inc.End = &t
+				inc.Duration = int64(now.Sub(inc.Start).Seconds())
+				alerts = append(alerts, notify.Event{
+					Type:     "netsplit_healed",
+					Severity: "info",
+					Title:    "Netsplit healed",
+					Message:  fmt.Sprintf("All %d server(s) rejoined after %s", len(inc.Servers), time.Duration(inc.Duration)*time.Second),
+					Data:     map[string]interface{}{"incident_id": inc.ID, "duration_seconds": inc.Duration},
+				})
+			}
+			p.dirty = true
+		}
+
+		// Servers that vanished since the last poll form one new incident
+		lost := make([]SplitServer, 0)
+		users := 0
+		for key, s := range p.known {
+			if _, ok := current[key]; !ok {
+				lost = append(lost, SplitServer{Name: s.Name, Uplink: s.Uplink, Users: s.Users})
+				users += s.Users
+			}
+		}
+		if len(lost) > 0 {
+			sort.Slice(lost, func(i, j int) bool { return lost[i].Name < lost[j].Name })
+			inc := &Incident{ID: newID(), Start: now, UsersLost: users, Servers: lost}
+			p.incidents = append(p.incidents, inc)
+			if max := p.config.MaxIncidents; max > 0 && len(p.incidents) > max {
+				p.incidents = append([]*Incident(nil), p.incidents[len(p.incidents)-max:]...)
+			}
+			names := make([]string, 0, len(lost))
+			for _, s := range lost {
+				names = append(names, s.Name)
+			}
+			alerts = append(alerts, notify.Event{
+				Type:     "netsplit",
+				Severity: "critical",
+				Title:    "Netsplit",
+				Message:  fmt.Sprintf("Lost %s (%d users)", strings.Join(names, ", "), users),
+				Data:     map[string]interface{}{"incident_id": inc.ID, "servers": names, "users_lost": users},
+			})
+			p.dirty = true
+		}
+	}
+
+	p.known = current
+	p.dirty = true
+	p.seeded = true
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	for _, a := range alerts {
+		log.Printf("[netsplit-tracker] %s: %s", a.Title, a.Message)
+		if webhook != "" {
+			a.Source = "netsplit-tracker"
+			a.Timestamp = now
+			go p.alert(webhook, a)
+		}
+	}
+}
+
+// alert delivers an alert to the webhook
+func (p *NetsplitTrackerPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[netsplit-tracker] failed to send alert: %v", err)
+	}
+}
+
+// Stats summarises incidents over a period
+type Stats struct {
+	Splits         int            `json:"splits"`
+	Ongoing        int            `json:"ongoing"`
+	MTTRSeconds    int64          `json:"mttr_seconds"`
+	LongestSeconds int64          `json:"longest_seconds"`
+	UsersLost      int            `json:"users_lost"`
+	PerServer      map[string]int `json:"per_server"`
+}
+
+// stats computes split statistics since a time. MTTR only covers resolved
+// incidents. Caller must hold p.mu.
+func (p *NetsplitTrackerPlugin) stats(since time.Time) Stats {
+	st := Stats{PerServer: make(map[string]int)}
+	var total int64
+	resolved := 0
+	for _, inc := range p.incidents {
+		if inc.Start.Before(since) {
+			continue
+		}
+		st.Splits++
+		st.UsersLost += inc.UsersLost
+		for _, s := range inc.Servers {
+			st.PerServer[s.Name]++
+		}
+		if inc.End == nil {
+			st.Ongoing++
+			continue
+		}
+		resolved++
+		total += inc.Duration
+		if inc.Duration > st.LongestSeconds {
+			st.LongestSeconds = inc.Duration
+		}
+	}
+	if resolved > 0 {
+		st.MTTRSeconds = total / int64(resolved)
+	}
+	return st
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleListIncidents returns incidents, newest first
+func (p *NetsplitTrackerPlugin) handleListIncidents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	server := c.Query("server")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Incident, 0)
+	for i := len(p.incidents) - 1; i >= 0 && len(list) < limit; i-- {
+		inc := p.incidents[i]
+		if server != "" {
+			found := false
+			for _, s := range inc.Servers {
+				if strings.EqualFold(s.Name, server) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		list = append(list, inc)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"incidents":  list,
+		"last_poll":  p.lastPoll,
+		"last_error": p.lastError,
+	})
+}
+
+// handleGetIncident returns one incident
+func (p *NetsplitTrackerPlugin) handleGetIncident(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, inc := range p.incidents {
+		if inc.ID == c.Param("id") {
+			c.JSON(http.StatusOK, inc)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+}
+
+// handleStats returns split statistics over a number of days
+func (p *NetsplitTrackerPlugin) handleStats(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive number"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":             days,
+		"stats":            p.stats(time.Now().AddDate(0, 0, -days)),
+		"stream_connected": p.streamOK,
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *NetsplitTrackerPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *NetsplitTrackerPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *NetsplitTrackerPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *NetsplitTrackerPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
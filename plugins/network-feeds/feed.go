@@ -0,0 +1,200 @@
+package networkfeeds
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// Item categories a feed can include
+const (
+	CategoryAnnouncements = "announcements"
+	CategoryMaintenance   = "maintenance"
+	CategoryIncidents     = "incidents"
+	CategoryRecords       = "records"
+)
+
+// categories lists every category, in the order they are shown
+var categories = []string{CategoryAnnouncements, CategoryMaintenance, CategoryIncidents, CategoryRecords}
+
+// Item is one entry of a feed
+type Item struct {
+	ID          string    `json:"id"`
+	Category    string    `json:"category"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Author      string    `json:"author,omitempty"`
+	Published   time.Time `json:"published"`
+	Updated     time.Time `json:"updated"`
+}
+
+// entryID returns a stable urn:uuid for an item, a name-based (version 5
+// style) UUID of its ID, as Atom wants an IRI that never changes
+func entryID(id string) string {
+	sum := sha1.Sum([]byte("uwp-network-feeds:" + id))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// rss is an RSS 2.0 document
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	TTL           int       `xml:"ttl"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Description string  `xml:"description"`
+	Category    string  `xml:"category"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atom is an Atom 1.0 document
+type atom struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published"`
+	Author    *atomAuthor  `xml:"author,omitempty"`
+	Category  atomCategory `xml:"category"`
+	Content   atomContent  `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// feedMeta describes the feed being rendered
+type feedMeta struct {
+	ID      string
+	Title   string
+	Link    string
+	SelfURL string
+	TTL     int
+}
+
+// renderRSS encodes items, newest first, as an RSS 2.0 document
+func renderRSS(meta feedMeta, items []Item) ([]byte, error) {
+	ch := rssChannel{
+		Title:         meta.Title,
+		Link:          meta.Link,
+		Description:   meta.Title,
+		LastBuildDate: lastUpdated(items).Format(time.RFC1123Z),
+		TTL:           meta.TTL,
+		Items:         make([]rssItem, 0, len(items)),
+	}
+	if meta.SelfURL != "" {
+		ch.Self = &atomLink{Href: meta.SelfURL, Rel: "self", Type: "application/rss+xml"}
+	}
+	for _, it := range items {
+		ch.Items = append(ch.Items, rssItem{
+			Title:       it.Title,
+			Description: it.Description,
+			Category:    it.Category,
+			GUID:        rssGUID{IsPermaLink: "false", Value: entryID(it.ID)},
+			PubDate:     it.Published.Format(time.RFC1123Z),
+		})
+	}
+	return encodeXML(rss{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: ch})
+}
+
+// renderAtom encodes items, newest first, as an Atom 1.0 document
+func renderAtom(meta feedMeta, items []Item) ([]byte, error) {
+	feed := atom{
+		ID:      entryID("feed:" + meta.ID),
+		Title:   meta.Title,
+		Updated: lastUpdated(items).Format(time.RFC3339),
+		Author:  atomAuthor{Name: meta.Title},
+		Entries: make([]atomEntry, 0, len(items)),
+	}
+	if meta.Link != "" {
+		feed.Links = append(feed.Links, atomLink{Href: meta.Link})
+	}
+	if meta.SelfURL != "" {
+		feed.Links = append(feed.Links, atomLink{Href: meta.SelfURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	for _, it := range items {
+		e := atomEntry{
+			ID:        entryID(it.ID),
+			Title:     it.Title,
+			Updated:   it.Updated.Format(time.RFC3339),
+			Published: it.Published.Format(time.RFC3339),
+			Category:  atomCategory{Term: it.Category},
+			Content:   atomContent{Type: "text", Value: it.Description},
+		}
+		if it.Author != "" {
+			e.Author = &atomAuthor{Name: it.Author}
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+	return encodeXML(feed)
+}
+
+// lastUpdated returns the latest update among items, or now for an
+// empty feed
+func lastUpdated(items []Item) time.Time {
+	var t time.Time
+	for _, it := range items {
+		if it.Updated.After(t) {
+			t = it.Updated
+		}
+	}
+	if t.IsZero() {
+		t = time.Now().UTC()
+	}
+	return t.UTC()
+}
+
+// encodeXML encodes a document with the XML declaration
+func encodeXML(v interface{}) ([]byte, error) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
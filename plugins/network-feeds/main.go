@@ -0,0 +1,917 @@
+// Network Feeds Plugin for UnrealIRCd Web Panel
+// Serves RSS and Atom feeds of announcements, maintenance, incidents and
+// user record peaks, each feed with its own access token
+
+package networkfeeds
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Feed limits
+const (
+	maxFeeds         = 100
+	maxAnnouncements = 500
+	maxRecords       = 100
+	feedTTL          = 15
+)
+
+// NetworkFeedsPlugin implements the Plugin interface
+type NetworkFeedsPlugin struct {
+	config        Config
+	rpc           *jsonrpc.Client
+	feeds         []*Feed
+	announcements []*Announcement
+	records       []Record
+	best          int
+	pending       *Record
+	pollErr       string
+	lastPoll      time.Time
+	dirty         bool
+	mu            sync.RWMutex
+	stop          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL           string `json:"rpc_url"`
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	DataDir          string `json:"data_dir"`
+	Title            string `json:"title"`
+	BaseURL          string `json:"base_url"`
+	MaintenanceFile  string `json:"maintenance_file"`
+	NetsplitsFile    string `json:"netsplits_file"`
+	IncidentsFile    string `json:"incidents_file"`
+	IncidentSeverity string `json:"incident_severity"`
+	RecordSettle     int    `json:"record_settle"`
+	PollInterval     int    `json:"poll_interval"`
+	MaxItems         int    `json:"max_items"`
+}
+
+// Feed is a set of categories served under its own token
+type Feed struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Token       string     `json:"token"`
+	Categories  []string   `json:"categories"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Fetches     int        `json:"fetches"`
+	LastFetched *time.Time `json:"last_fetched,omitempty"`
+}
+
+// FeedRequest is the body of a feed create or update
+type FeedRequest struct {
+	Name       string   `json:"name"`
+	Categories []string `json:"categories"`
+}
+
+// Announcement is a news item written by staff
+type Announcement struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Author    string    `json:"author"`
+	Published time.Time `json:"published"`
+	Updated   time.Time `json:"updated"`
+}
+
+// AnnouncementRequest is the body of an announcement create or update
+type AnnouncementRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Record is a new peak of users online
+type Record struct {
+	Users    int       `json:"users"`
+	Previous int       `json:"previous"`
+	Time     time.Time `json:"time"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Feeds         []*Feed         `json:"feeds"`
+	Announcements []*Announcement `json:"announcements"`
+	Records       []Record        `json:"records"`
+	Best          int             `json:"best"`
+	Pending       *Record         `json:"pending,omitempty"`
+}
+
+// statsResult is the part of stats.get we need
+type statsResult struct {
+	User struct {
+		Total  int `json:"total"`
+		Record int `json:"record"`
+	} `json:"user"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &NetworkFeedsPlugin{
+		config: Config{
+			RPCURL:           "https://127.0.0.1:8600/api",
+			DataDir:          "data/plugins/network-feeds",
+			Title:            "IRC Network",
+			MaintenanceFile:  "data/plugins/maintenance-announcer/windows.json",
+			NetsplitsFile:    "data/plugins/netsplit-tracker/netsplits.json",
+			IncidentsFile:    "data/plugins/incident-escalation/incident-escalation.json",
+			IncidentSeverity: "warning",
+			RecordSettle:     10,
+			PollInterval:     60,
+			MaxItems:         50,
+		},
+		feeds:         make([]*Feed, 0),
+		announcements: make([]*Announcement, 0),
+		records:       make([]Record, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *NetworkFeedsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Network Feeds",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "RSS and Atom feeds of announcements, incidents and record peaks",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *NetworkFeedsPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[network-feeds] failed to load data: %v", err)
+	}
+	if data.Feeds != nil {
+		p.feeds = data.Feeds
+	}
+	if data.Announcements != nil {
+		p.announcements = data.Announcements
+	}
+	if data.Records != nil {
+		p.records = data.Records
+	}
+	p.best = data.Best
+	p.pending = data.Pending
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "network-feeds-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		fetches := 0
+		for _, f := range p.feeds {
+			fetches += f.Fetches
+		}
+		return plugins.DashboardCard{
+			Title: "Network Feeds",
+			Icon:  "Rss",
+			Content: map[string]interface{}{
+				"feeds":         len(p.feeds),
+				"announcements": len(p.announcements),
+				"fetches":       fetches,
+				"user_record":   p.best,
+			},
+			Order: 73,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *NetworkFeedsPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *NetworkFeedsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/network-feeds")
+	{
+		plugin.GET("/feeds/:id/rss", p.handleRSS)
+		plugin.GET("/feeds/:id/atom", p.handleAtom)
+		plugin.GET("/feeds", p.handleListFeeds)
+		plugin.POST("/feeds", p.handleCreateFeed)
+		plugin.PUT("/feeds/:id", p.handleUpdateFeed)
+		plugin.DELETE("/feeds/:id", p.handleDeleteFeed)
+		plugin.POST("/feeds/:id/token", p.handleRotateToken)
+		plugin.GET("/announcements", p.handleListAnnouncements)
+		plugin.POST("/announcements", p.handleCreateAnnouncement)
+		plugin.PUT("/announcements/:id", p.handleUpdateAnnouncement)
+		plugin.DELETE("/announcements/:id", p.handleDeleteAnnouncement)
+		plugin.GET("/records", p.handleRecords)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *NetworkFeedsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "feeds.json")
+}
+
+// save persists the state if it changed
+func (p *NetworkFeedsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	data := storeData{
+		Feeds:         p.feeds,
+		Announcements: p.announcements,
+		Records:       p.records,
+		Best:          p.best,
+		Pending:       p.pending,
+	}
+	if err := storage.SaveJSON(p.storePath(), data); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *NetworkFeedsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// newToken returns a random feed token
+func newToken() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// pollLoop watches the user record until shutdown and saves the state
+func (p *NetworkFeedsPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+		p.poll()
+		if err := p.save(); err != nil {
+			log.Printf("[network-feeds] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// poll checks the user record. A new record becomes a feed item once it
+// has held for record_settle minutes, so a growing crowd gives one item
+// for its peak instead of one for every user who joins. The first poll
+// only learns the current record.
+func (p *NetworkFeedsPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var stats statsResult
+	err := p.client().Call(ctx, "stats.get", nil, &stats)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.pollErr = err.Error()
+		return
+	}
+	p.pollErr = ""
+	now := time.Now().UTC()
+	p.lastPoll = now
+
+	peak := stats.User.Record
+	if stats.User.Total > peak {
+		peak = stats.User.Total
+	}
+	if p.best == 0 {
+		p.best = peak
+		p.dirty = true
+		return
+	}
+	if peak > p.best && (p.pending == nil || peak > p.pending.Users) {
+		p.pending = &Record{Users: peak, Previous: p.best, Time: now}
+		p.dirty = true
+	}
+	if p.pending != nil && now.Sub(p.pending.Time) >= time.Duration(p.config.RecordSettle)*time.Minute {
+		log.Printf("[network-feeds] new user record of %d", p.pending.Users)
+		p.records = append(p.records, *p.pending)
+		if len(p.records) > maxRecords {
+			p.records = p.records[len(p.records)-maxRecords:]
+		}
+		p.best = p.pending.Users
+		p.pending = nil
+		p.dirty = true
+	}
+}
+
+// recordItems turns record peaks into items. Caller must hold p.mu.
+func (p *NetworkFeedsPlugin) recordItems() []Item {
+	out := make([]Item, 0, len(p.records))
+	for _, r := range p.records {
+		out = append(out, Item{
+			ID:          fmt.Sprintf("record:%d", r.Time.Unix()),
+			Category:    CategoryRecords,
+			Title:       fmt.Sprintf("New user record: %d users online", r.Users),
+			Description: fmt.Sprintf("%d users were online at once, beating the previous record of %d.", r.Users, r.Previous),
+			Published:   r.Time,
+			Updated:     r.Time,
+		})
+	}
+	return out
+}
+
+// announcementItems turns announcements into items. Caller must hold
+// p.mu.
+func (p *NetworkFeedsPlugin) announcementItems() []Item {
+	out := make([]Item, 0, len(p.announcements))
+	for _, a := range p.announcements {
+		out = append(out, Item{
+			ID:          "announcement:" + a.ID,
+			Category:    CategoryAnnouncements,
+			Title:       a.Title,
+			Description: a.Body,
+			Author:      a.Author,
+			Published:   a.Published,
+			Updated:     a.Updated,
+		})
+	}
+	return out
+}
+
+// items gathers the items of the given categories, newest first. Data
+// files of other plugins that cannot be read are skipped and logged.
+func (p *NetworkFeedsPlugin) items(cats []string) []Item {
+	p.mu.RLock()
+	cfg := p.config
+	items := make([]Item, 0)
+	if lists.ContainsFold(cats, CategoryAnnouncements) {
+		items = append(items, p.announcementItems()...)
+	}
+	if lists.ContainsFold(cats, CategoryRecords) {
+		items = append(items, p.recordItems()...)
+	}
+	p.mu.RUnlock()
+
+	if lists.ContainsFold(cats, CategoryMaintenance) {
+		windows, err := loadWindows(cfg.MaintenanceFile)
+		if err != nil {
+			log.Printf("[network-feeds] %v", err)
+		}
+		items = append(items, windowItems(windows)...)
+	}
+	if lists.ContainsFold(cats, CategoryIncidents) {
+		netsplits, err := loadNetsplits(cfg.NetsplitsFile)
+		if err != nil {
+			log.Printf("[network-feeds] %v", err)
+		}
+		items = append(items, netsplitItems(netsplits)...)
+		escalations, err := loadEscalations(cfg.IncidentsFile)
+		if err != nil {
+			log.Printf("[network-feeds] %v", err)
+		}
+		items = append(items, escalationItems(escalations, cfg.IncidentSeverity)...)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Published.After(items[j].Published) })
+	if len(items) > cfg.MaxItems {
+		items = items[:cfg.MaxItems]
+	}
+	return items
+}
+
+// findFeed returns the feed with the given ID. Caller must hold p.mu.
+func (p *NetworkFeedsPlugin) findFeed(id string) (*Feed, int) {
+	for i, f := range p.feeds {
+		if f.ID == id {
+			return f, i
+		}
+	}
+	return nil, -1
+}
+
+// feedURL returns the address of a feed in the given format, absolute
+// when base_url is set. Caller must hold p.mu.
+func (p *NetworkFeedsPlugin) feedURL(f *Feed, format string) string {
+	return fmt.Sprintf("%s/api/plugin/network-feeds/feeds/%s/%s?token=%s", strings.TrimRight(p.config.BaseURL, "/"), f.ID, format, f.Token)
+}
+
+// feedToken returns the token sent with a feed request
+func feedToken(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return c.Query("token")
+}
+
+// serveFeed checks the feed's token and serves its items, or 304 Not
+// Modified when nothing changed since the reader's last fetch
+func (p *NetworkFeedsPlugin) serveFeed(c *gin.Context, format string) {
+	p.mu.Lock()
+	f, _ := p.findFeed(c.Param("id"))
+	if f == nil || subtle.ConstantTimeCompare([]byte(feedToken(c)), []byte(f.Token)) != 1 {
+		p.mu.Unlock()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid feed token"})
+		return
+	}
+	now := time.Now().UTC()
+	f.Fetches++
+	f.LastFetched = &now
+	p.dirty = true
+	meta := feedMeta{
+		ID:    f.ID,
+		Title: p.config.Title + ": " + f.Name,
+		Link:  p.config.BaseURL,
+		TTL:   feedTTL,
+	}
+	if p.config.BaseURL != "" {
+		meta.SelfURL = p.feedURL(f, format)
+	}
+	cats := append([]string(nil), f.Categories...)
+	p.mu.Unlock()
+
+	items := p.items(cats)
+	modified := lastUpdated(items).Truncate(time.Second)
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !modified.After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Header("Last-Modified", modified.Format(http.TimeFormat))
+
+	var body []byte
+	var err error
+	contentType := "application/atom+xml; charset=utf-8"
+	if format == "rss" {
+		body, err = renderRSS(meta, items)
+		contentType = "application/rss+xml; charset=utf-8"
+	} else {
+		body, err = renderAtom(meta, items)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// handleRSS serves a feed as RSS 2.0
+func (p *NetworkFeedsPlugin) handleRSS(c *gin.Context) {
+	p.serveFeed(c, "rss")
+}
+
+// handleAtom serves a feed as Atom 1.0
+func (p *NetworkFeedsPlugin) handleAtom(c *gin.Context) {
+	p.serveFeed(c, "atom")
+}
+
+// feedView is a feed with its subscription URLs
+type feedView struct {
+	*Feed
+	RSSURL  string `json:"rss_url"`
+	AtomURL string `json:"atom_url"`
+}
+
+// view returns a feed with its URLs. Caller must hold p.mu.
+func (p *NetworkFeedsPlugin) view(f *Feed) feedView {
+	return feedView{Feed: f, RSSURL: p.feedURL(f, "rss"), AtomURL: p.feedURL(f, "atom")}
+}
+
+// handleListFeeds returns all feeds with their URLs
+func (p *NetworkFeedsPlugin) handleListFeeds(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]feedView, 0, len(p.feeds))
+	for _, f := range p.feeds {
+		list = append(list, p.view(f))
+	}
+	c.JSON(http.StatusOK, gin.H{"feeds": list, "categories": categories})
+}
+
+// apply validates a request and copies it onto the feed
+func (req *FeedRequest) apply(f *Feed) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return fmt.Errorf("name must be 1 to 100 characters")
+	}
+	cats := make([]string, 0, len(req.Categories))
+	for _, cat := range req.Categories {
+		cat = strings.ToLower(strings.TrimSpace(cat))
+		if !lists.ContainsFold(categories, cat) {
+			return fmt.Errorf("unknown category %q", cat)
+		}
+		if !lists.ContainsFold(cats, cat) {
+			cats = append(cats, cat)
+		}
+	}
+	if len(cats) == 0 {
+		return fmt.Errorf("at least one category is required")
+	}
+	f.Name = name
+	f.Categories = cats
+	return nil
+}
+
+// handleCreateFeed adds a feed with a new token
+func (p *NetworkFeedsPlugin) handleCreateFeed(c *gin.Context) {
+	var req FeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	f := &Feed{
+		ID:        newID(),
+		Token:     newToken(),
+		CreatedBy: access.Actor(c),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := req.apply(f); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	if len(p.feeds) >= maxFeeds {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d feeds are allowed", maxFeeds)})
+		return
+	}
+	p.feeds = append(p.feeds, f)
+	p.dirty = true
+	v := p.view(f)
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[network-feeds] failed to save data: %v", err)
+	}
+	log.Printf("[network-feeds] %s created feed %q", f.CreatedBy, f.Name)
+	c.JSON(http.StatusCreated, v)
+}
+
+// handleUpdateFeed renames a feed or changes its categories
+func (p *NetworkFeedsPlugin) handleUpdateFeed(c *gin.Context) {
+	var req FeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	f, _ := p.findFeed(c.Param("id"))
+	if f == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		return
+	}
+	updated := *f
+	if err := req.apply(&updated); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	*f = updated
+	p.dirty = true
+	v := p.view(f)
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[network-feeds] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// handleDeleteFeed removes a feed; its URLs stop working at once
+func (p *NetworkFeedsPlugin) handleDeleteFeed(c *gin.Context) {
+	p.mu.Lock()
+	f, i := p.findFeed(c.Param("id"))
+	if f == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		return
+	}
+	p.feeds = append(p.feeds[:i], p.feeds[i+1:]...)
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[network-feeds] failed to save data: %v", err)
+	}
+	log.Printf("[network-feeds] %s deleted feed %q", access.Actor(c), f.Name)
+	c.JSON(http.StatusOK, gin.H{"message": "Feed deleted"})
+}
+
+// handleRotateToken gives a feed a new token, for when its URL was shared
+// too widely
+func (p *NetworkFeedsPlugin) handleRotateToken(c *gin.Context) {
+	p.mu.Lock()
+	f, _ := p.findFeed(c.Param("id"))
+	if f == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		return
+	}
+	f.Token = newToken()
+	p.dirty = true
+	v := p.view(f)
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[network-feeds] failed to save data: %v", err)
+	}
+	log.Printf("[network-feeds] %s rotated the token of feed %q", access.Actor(c), f.Name)
+	c.JSON(http.StatusOK, v)
+}
+
+// handleListAnnouncements returns announcements, newest first
+func (p *NetworkFeedsPlugin) handleListAnnouncements(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Announcement, 0, len(p.announcements))
+	for _, a := range p.announcements {
+		list = append(list, *a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Published.After(list[j].Published) })
+	c.JSON(http.StatusOK, gin.H{"announcements": list})
+}
+
+// apply validates a request and copies it onto the announcement
+func (req *AnnouncementRequest) apply(a *Announcement) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" || len(title) > 200 {
+		return fmt.Errorf("title must be 1 to 200 characters")
+	}
+	body := strings.TrimSpace(req.Body)
+	if len(body) > 20000 {
+		return fmt.Errorf("body must be at most 20000 characters")
+	}
+	a.Title = title
+	a.Body = body
+	return nil
+}
+
+// handleCreateAnnouncement publishes an announcement. Beyond the limit the
+// oldest announcements are removed.
+func (p *NetworkFeedsPlugin) handleCreateAnnouncement(c *gin.Context) {
+	var req AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	now := time.Now().UTC()
+	a := &Announcement{ID: newID(), Author: access.Actor(c), Published: now, Updated: now}
+	if err := req.apply(a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	p.announcements = append(p.announcements, a)
+	if len(p.announcements) > maxAnnouncements {
+		p.announcements = p.announcements[len(p.announcements)-maxAnnouncements:]
+	}
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[network-feeds] failed to save data: %v", err)
+	}
+	log.Printf("[network-feeds] %s published %q", a.Author, a.Title)
+	c.JSON(http.StatusCreated, a)
+}
+
+// handleUpdateAnnouncement edits an announcement, which feed readers see
+// as an update
+func (p *NetworkFeedsPlugin) handleUpdateAnnouncement(c *gin.Context) {
+	var req AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	var a *Announcement
+	for _, cur := range p.announcements {
+		if cur.ID == c.Param("id") {
+			a = cur
+			break
+		}
+	}
+	if a == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	updated := *a
+	if err := req.apply(&updated); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updated.Updated = time.Now().UTC()
+	*a = updated
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[network-feeds] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// handleDeleteAnnouncement removes an announcement from every feed
+func (p *NetworkFeedsPlugin) handleDeleteAnnouncement(c *gin.Context) {
+	p.mu.Lock()
+	found := false
+	for i, a := range p.announcements {
+		if a.ID == c.Param("id") {
+			p.announcements = append(p.announcements[:i], p.announcements[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if !found {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	}
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[network-feeds] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Announcement deleted"})
+}
+
+// handleRecords returns the record peaks and any record still settling
+func (p *NetworkFeedsPlugin) handleRecords(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Record, len(p.records))
+	copy(list, p.records)
+	sort.Slice(list, func(i, j int) bool { return list[i].Time.After(list[j].Time) })
+	c.JSON(http.StatusOK, gin.H{"records": list, "best": p.best, "pending": p.pending})
+}
+
+// handleStatus returns the state of the record poll
+func (p *NetworkFeedsPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"feeds":         len(p.feeds),
+		"announcements": len(p.announcements),
+		"records":       len(p.records),
+		"best":          p.best,
+		"error":         p.pollErr,
+	}
+	if !p.lastPoll.IsZero() {
+		status["last_poll"] = p.lastPoll
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *NetworkFeedsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *NetworkFeedsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	newConfig.Title = strings.TrimSpace(newConfig.Title)
+	if newConfig.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return
+	}
+	newConfig.BaseURL = strings.TrimRight(strings.TrimSpace(newConfig.BaseURL), "/")
+	if newConfig.BaseURL != "" && !strings.HasPrefix(newConfig.BaseURL, "https://") && !strings.HasPrefix(newConfig.BaseURL, "http://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "base_url must start with http:// or https://"})
+		return
+	}
+	if _, ok := notify.SeverityRank[newConfig.IncidentSeverity]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "incident_severity must be info, warning or critical"})
+		return
+	}
+	if newConfig.RecordSettle < 0 || newConfig.RecordSettle > 1440 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "record_settle must be between 0 and 1440 minutes"})
+		return
+	}
+	if newConfig.PollInterval < 10 || newConfig.PollInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poll_interval must be between 10 and 3600 seconds"})
+		return
+	}
+	if newConfig.MaxItems < 1 || newConfig.MaxItems > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_items must be between 1 and 500"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *NetworkFeedsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *NetworkFeedsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,186 @@
+package networkfeeds
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// window is the part of a maintenance-announcer window we serve
+type window struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Servers     []string  `json:"servers"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Cancelled   bool      `json:"cancelled"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	History     []struct {
+		Time time.Time `json:"time"`
+	} `json:"history"`
+}
+
+// netsplit is the part of a netsplit-tracker incident we serve
+type netsplit struct {
+	ID        string     `json:"id"`
+	Start     time.Time  `json:"start"`
+	End       *time.Time `json:"end"`
+	UsersLost int        `json:"users_lost"`
+	Servers   []struct {
+		Name string `json:"name"`
+	} `json:"servers"`
+}
+
+// escalation is the part of an incident-escalation incident we serve
+type escalation struct {
+	ID          string     `json:"id"`
+	Source      string     `json:"source"`
+	Severity    string     `json:"severity"`
+	Summary     string     `json:"summary"`
+	Message     string     `json:"message"`
+	TriggeredAt time.Time  `json:"triggered_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+}
+
+// loadWindows reads the windows stored by the maintenance-announcer
+// plugin. A missing file gives an empty list.
+func loadWindows(path string) ([]window, error) {
+	var data struct {
+		Windows []window `json:"windows"`
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if err := storage.LoadJSON(path, &data); err != nil {
+		return nil, fmt.Errorf("maintenance windows %s: %w", path, err)
+	}
+	return data.Windows, nil
+}
+
+// loadNetsplits reads the incidents stored by the netsplit-tracker plugin.
+// A missing file gives an empty list.
+func loadNetsplits(path string) ([]netsplit, error) {
+	var data struct {
+		Incidents []netsplit `json:"incidents"`
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if err := storage.LoadJSON(path, &data); err != nil {
+		return nil, fmt.Errorf("netsplits %s: %w", path, err)
+	}
+	return data.Incidents, nil
+}
+
+// loadEscalations reads the incidents stored by the incident-escalation
+// plugin. A missing file gives an empty list.
+func loadEscalations(path string) ([]escalation, error) {
+	var data struct {
+		Incidents []escalation `json:"incidents"`
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if err := storage.LoadJSON(path, &data); err != nil {
+		return nil, fmt.Errorf("incidents %s: %w", path, err)
+	}
+	return data.Incidents, nil
+}
+
+// windowItems turns maintenance windows into items. A window's item is
+// updated whenever the window is edited or cancelled.
+func windowItems(list []window) []Item {
+	out := make([]Item, 0, len(list))
+	for _, w := range list {
+		servers := "all servers"
+		if len(w.Servers) > 0 {
+			servers = strings.Join(w.Servers, ", ")
+		}
+		title := "Maintenance: " + w.Title
+		if w.Cancelled {
+			title = "Cancelled maintenance: " + w.Title
+		}
+		desc := fmt.Sprintf("%s to %s on %s.", w.Start.UTC().Format("Mon 2 Jan 2006 15:04"), w.End.UTC().Format("Mon 2 Jan 2006 15:04 MST"), servers)
+		if w.Description != "" {
+			desc += "\n\n" + w.Description
+		}
+		updated := w.CreatedAt
+		for _, h := range w.History {
+			if h.Time.After(updated) {
+				updated = h.Time
+			}
+		}
+		out = append(out, Item{
+			ID:          "maintenance:" + w.ID,
+			Category:    CategoryMaintenance,
+			Title:       title,
+			Description: desc,
+			Author:      w.CreatedBy,
+			Published:   w.CreatedAt,
+			Updated:     updated,
+		})
+	}
+	return out
+}
+
+// netsplitItems turns netsplits into items, updated when they end
+func netsplitItems(list []netsplit) []Item {
+	out := make([]Item, 0, len(list))
+	for _, ns := range list {
+		names := make([]string, 0, len(ns.Servers))
+		for _, s := range ns.Servers {
+			names = append(names, s.Name)
+		}
+		sort.Strings(names)
+		it := Item{
+			ID:          "netsplit:" + ns.ID,
+			Category:    CategoryIncidents,
+			Title:       fmt.Sprintf("Netsplit: %d server(s) lost", len(names)),
+			Description: fmt.Sprintf("%s split from the network; %d users lost.", strings.Join(names, ", "), ns.UsersLost),
+			Published:   ns.Start,
+			Updated:     ns.Start,
+		}
+		if ns.End != nil {
+			it.Title = fmt.Sprintf("Netsplit resolved: %d server(s) rejoined", len(names))
+			it.Description += fmt.Sprintf(" All rejoined after %s.", ns.End.Sub(ns.Start).Round(time.Second))
+			it.Updated = *ns.End
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// escalationItems turns escalated incidents of at least minSeverity into
+// items, updated when they are resolved
+func escalationItems(list []escalation, minSeverity string) []Item {
+	out := make([]Item, 0, len(list))
+	for _, inc := range list {
+		if notify.SeverityRank[inc.Severity] < notify.SeverityRank[minSeverity] {
+			continue
+		}
+		it := Item{
+			ID:          "incident:" + inc.ID,
+			Category:    CategoryIncidents,
+			Title:       inc.Summary,
+			Description: fmt.Sprintf("A %s incident raised by %s.", inc.Severity, inc.Source),
+			Published:   inc.TriggeredAt,
+			Updated:     inc.TriggeredAt,
+		}
+		if inc.Message != "" && inc.Message != inc.Summary {
+			it.Description += "\n\n" + inc.Message
+		}
+		if inc.ResolvedAt != nil {
+			it.Title = "Resolved: " + inc.Summary
+			it.Description += fmt.Sprintf("\n\nResolved after %s.", inc.ResolvedAt.Sub(inc.TriggeredAt).Round(time.Second))
+			it.Updated = *inc.ResolvedAt
+		}
+		out = append(out, it)
+	}
+	return out
+}
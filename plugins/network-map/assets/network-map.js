@@ -0,0 +1,358 @@
+/**
+ * Network Map Frontend Script
+ *
+ * Draws the server link graph on the plugin page. Drag to pan, scroll to
+ * zoom and click a server to see its details.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'network-map';
+  const PLUGIN_NAME = 'Network Map';
+  const PAGE_PATH = '/plugins/network-map';
+  const API_BASE = '/api/plugin/network-map';
+
+  const COL_WIDTH = 180;
+  const ROW_HEIGHT = 110;
+  const MARGIN = 60;
+  const ROLE_COLORS = {
+    root: 'var(--accent, #89b4fa)',
+    hub: '#cba6f7',
+    leaf: 'var(--success, #a6e3a1)',
+    services: 'var(--warning, #f9e2af)'
+  };
+
+  let view = { x: 0, y: 0, scale: 1 };
+  let currentGraph = null;
+  let refreshTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('network-map-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'network-map-styles';
+    style.textContent = `
+      .nmap-app { display: flex; flex-direction: column; gap: 1rem; }
+      .nmap-toolbar { display: flex; gap: 0.5rem; flex-wrap: wrap; align-items: center; color: var(--text-secondary, #a6adc8); font-size: 0.85rem; }
+      .nmap-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .nmap-body { display: flex; gap: 1rem; align-items: stretch; }
+      .nmap-canvas {
+        flex: 1;
+        height: 560px;
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        cursor: grab;
+        user-select: none;
+      }
+      .nmap-canvas.dragging { cursor: grabbing; }
+      .nmap-node { cursor: pointer; }
+      .nmap-node text { fill: var(--text-primary, #cdd6f4); font-size: 12px; }
+      .nmap-node .nmap-users { fill: var(--text-secondary, #a6adc8); font-size: 10px; }
+      .nmap-node.selected circle { stroke: var(--text-primary, #cdd6f4); stroke-width: 3; }
+      .nmap-edge { stroke: var(--border-primary, #585b70); stroke-width: 2; }
+      .nmap-details {
+        width: 240px;
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        padding: 0.75rem;
+        color: var(--text-secondary, #a6adc8);
+        font-size: 0.85rem;
+      }
+      .nmap-details h4 { margin: 0 0 0.5rem 0; color: var(--text-primary, #cdd6f4); word-break: break-all; }
+      .nmap-details dt { color: var(--text-muted, #6c7086); margin-top: 0.4rem; }
+      .nmap-details dd { margin: 0; }
+      .nmap-legend span { display: inline-flex; align-items: center; gap: 0.25rem; margin-right: 0.75rem; }
+      .nmap-legend i { width: 10px; height: 10px; border-radius: 50%; display: inline-block; }
+      .nmap-error { color: var(--error, #f38ba8); }
+      .nmap-empty { color: var(--text-muted, #6c7086); padding: 1rem 0; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function nodePos(n) {
+    return { x: n.x * COL_WIDTH + COL_WIDTH / 2, y: n.y * ROW_HEIGHT + MARGIN / 2 + 10 };
+  }
+
+  function applyView(svg) {
+    const layer = svg.querySelector('#nmap-layer');
+    if (layer) layer.setAttribute('transform', `translate(${view.x} ${view.y}) scale(${view.scale})`);
+  }
+
+  function renderDetails(container, node, graph) {
+    const details = container.querySelector('#nmap-details');
+    if (!node) {
+      details.innerHTML = '<div class="nmap-empty">Click a server to see its details.</div>';
+      return;
+    }
+    const uplink = graph.edges.find(e => e.to === node.name);
+    const links = graph.edges.filter(e => e.from === node.name).map(e => e.to);
+    details.innerHTML = `
+      <h4>${escapeHtml(node.name)}</h4>
+      <dl>
+        <dt>Role</dt><dd>${escapeHtml(node.role)}</dd>
+        <dt>Users</dt><dd>${node.users}</dd>
+        <dt>Description</dt><dd>${escapeHtml(node.info) || '-'}</dd>
+        <dt>Software</dt><dd>${escapeHtml(node.software) || '-'}</dd>
+        <dt>Synced</dt><dd>${node.synced ? 'yes' : 'no'}</dd>
+        <dt>Uplink</dt><dd>${uplink ? escapeHtml(uplink.from) : '-'}</dd>
+        <dt>Linked servers</dt><dd>${links.length ? links.map(escapeHtml).join('<br>') : '-'}</dd>
+      </dl>
+    `;
+  }
+
+  function renderGraph(container, graph) {
+    const svg = container.querySelector('#nmap-canvas');
+    if (!graph.nodes.length) {
+      svg.innerHTML = '';
+      container.querySelector('#nmap-summary').textContent = 'No servers.';
+      return;
+    }
+
+    const byName = {};
+    graph.nodes.forEach(n => { byName[n.name] = n; });
+
+    const edges = graph.edges.map(e => {
+      const a = nodePos(byName[e.from]);
+      const b = nodePos(byName[e.to]);
+      return `<line class="nmap-edge" x1="${a.x}" y1="${a.y}" x2="${b.x}" y2="${b.y}"></line>`;
+    }).join('');
+    const nodes = graph.nodes.map(n => {
+      const p = nodePos(n);
+      const r = 10 + Math.min(12, Math.sqrt(n.users));
+      return `
+        <g class="nmap-node" data-name="${escapeHtml(n.name)}">
+          <title>${escapeHtml(n.name)} (${n.users} users)</title>
+          <circle cx="${p.x}" cy="${p.y}" r="${r}" fill="${ROLE_COLORS[n.role] || ROLE_COLORS.leaf}"></circle>
+          <text x="${p.x}" y="${p.y + r + 16}" text-anchor="middle">${escapeHtml(n.name)}</text>
+          <text class="nmap-users" x="${p.x}" y="${p.y + r + 30}" text-anchor="middle">${n.users} users</text>
+        </g>
+      `;
+    }).join('');
+    svg.innerHTML = `<g id="nmap-layer">${edges}${nodes}</g>`;
+    applyView(svg);
+
+    container.querySelector('#nmap-summary').textContent =
+      `${graph.nodes.length} servers, ${graph.total_users} users - updated ${new Date(graph.updated_at).toLocaleTimeString()}`;
+
+    svg.querySelectorAll('.nmap-node').forEach(el => {
+      el.addEventListener('click', (e) => {
+        e.stopPropagation();
+        svg.querySelectorAll('.nmap-node').forEach(o => o.classList.toggle('selected', o === el));
+        renderDetails(container, byName[el.dataset.name], graph);
+      });
+    });
+  }
+
+  async function loadGraph(container) {
+    const summary = container.querySelector('#nmap-summary');
+    try {
+      currentGraph = await api('GET', '/graph');
+      renderGraph(container, currentGraph);
+    } catch (e) {
+      summary.innerHTML = `<span class="nmap-error">${escapeHtml(e.message)}</span>`;
+    }
+  }
+
+  function fitView(container) {
+    if (!currentGraph || !currentGraph.nodes.length) return;
+    const svg = container.querySelector('#nmap-canvas');
+    const width = currentGraph.width * COL_WIDTH + MARGIN;
+    const height = currentGraph.height * ROW_HEIGHT + MARGIN;
+    const scale = Math.min(1.5, svg.clientWidth / width, svg.clientHeight / height) || 1;
+    view = {
+      scale,
+      x: (svg.clientWidth - width * scale) / 2,
+      y: (svg.clientHeight - height * scale) / 2
+    };
+    applyView(svg);
+  }
+
+  async function downloadSVG() {
+    const res = await fetch(API_BASE + '/map.svg', { headers: getAuthHeaders() });
+    if (!res.ok) throw new Error(`Request failed with status ${res.status}`);
+    const url = URL.createObjectURL(await res.blob());
+    const a = document.createElement('a');
+    a.href = url;
+    a.download = 'network-map.svg';
+    a.click();
+    URL.revokeObjectURL(url);
+  }
+
+  function attachPanZoom(svg) {
+    let drag = null;
+
+    svg.addEventListener('mousedown', (e) => {
+      drag = { x: e.clientX - view.x, y: e.clientY - view.y };
+      svg.classList.add('dragging');
+    });
+    window.addEventListener('mousemove', (e) => {
+      if (!drag) return;
+      view.x = e.clientX - drag.x;
+      view.y = e.clientY - drag.y;
+      applyView(svg);
+    });
+    window.addEventListener('mouseup', () => {
+      drag = null;
+      svg.classList.remove('dragging');
+    });
+    svg.addEventListener('wheel', (e) => {
+      e.preventDefault();
+      const rect = svg.getBoundingClientRect();
+      const mx = e.clientX - rect.left;
+      const my = e.clientY - rect.top;
+      const factor = e.deltaY < 0 ? 1.1 : 1 / 1.1;
+      const scale = Math.min(4, Math.max(0.2, view.scale * factor));
+      view.x = mx - (mx - view.x) * (scale / view.scale);
+      view.y = my - (my - view.y) * (scale / view.scale);
+      view.scale = scale;
+      applyView(svg);
+    }, { passive: false });
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="nmap-app" data-plugin="${PLUGIN_ID}">
+        <div class="nmap-toolbar">
+          <button class="nmap-btn" id="nmap-refresh">Refresh</button>
+          <button class="nmap-btn" id="nmap-fit">Fit</button>
+          <button class="nmap-btn" id="nmap-svg">Download SVG</button>
+          <span id="nmap-summary">Loading...</span>
+        </div>
+        <div class="nmap-toolbar nmap-legend">
+          ${Object.keys(ROLE_COLORS).map(role =>
+            `<span><i style="background:${ROLE_COLORS[role]}"></i>${role}</span>`
+          ).join('')}
+        </div>
+        <div class="nmap-body">
+          <svg class="nmap-canvas" id="nmap-canvas" xmlns="http://www.w3.org/2000/svg"></svg>
+          <div class="nmap-details" id="nmap-details"></div>
+        </div>
+      </div>
+    `;
+
+    const svg = container.querySelector('#nmap-canvas');
+    attachPanZoom(svg);
+    svg.addEventListener('click', () => {
+      svg.querySelectorAll('.nmap-node').forEach(o => o.classList.remove('selected'));
+      renderDetails(container, null);
+    });
+
+    container.querySelector('#nmap-refresh').addEventListener('click', async () => {
+      try {
+        await api('POST', '/refresh');
+      } catch (err) {
+        alert(err.message);
+      }
+      setTimeout(() => loadGraph(container), 1000);
+    });
+    container.querySelector('#nmap-fit').addEventListener('click', () => fitView(container));
+    container.querySelector('#nmap-svg').addEventListener('click', () => {
+      downloadSVG().catch(err => alert(err.message));
+    });
+
+    renderDetails(container, null);
+    loadGraph(container).then(() => fitView(container));
+
+    if (refreshTimer) clearInterval(refreshTimer);
+    refreshTimer = setInterval(() => {
+      if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) {
+        clearInterval(refreshTimer);
+        refreshTimer = null;
+        return;
+      }
+      loadGraph(container);
+    }, 30000);
+
+    return true;
+  }
+
+  function cleanup() {
+    if (refreshTimer) {
+      clearInterval(refreshTimer);
+      refreshTimer = null;
+    }
+    const style = document.getElementById('network-map-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,428 @@
+// Network Map Plugin for UnrealIRCd Web Panel
+// Exposes the server link graph as JSON and as a rendered SVG map
+
+package networkmap
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+)
+
+// Node roles
+const (
+	RoleRoot     = "root"
+	RoleHub      = "hub"
+	RoleLeaf     = "leaf"
+	RoleServices = "services"
+)
+
+// NetworkMapPlugin implements the Plugin interface
+type NetworkMapPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	graph     *Graph
+	lastError string
+	refresh   chan struct{}
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL          string `json:"rpc_url"`
+	RPCUser         string `json:"rpc_user"`
+	RPCPassword     string `json:"rpc_password"`
+	RPCInsecure     bool   `json:"rpc_insecure"`
+	RefreshInterval int    `json:"refresh_interval"`
+	ShowServices    bool   `json:"show_services"`
+}
+
+// Node is a server in the link graph. X and Y are layout positions in
+// columns and rows.
+type Node struct {
+	Name     string  `json:"name"`
+	Info     string  `json:"info"`
+	Users    int     `json:"users"`
+	Role     string  `json:"role"`
+	Software string  `json:"software"`
+	Synced   bool    `json:"synced"`
+	Depth    int     `json:"depth"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+}
+
+// Edge is a link between a hub and the server linked to it
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Graph is the network link graph
+type Graph struct {
+	Nodes      []Node    `json:"nodes"`
+	Edges      []Edge    `json:"edges"`
+	Root       string    `json:"root"`
+	TotalUsers int       `json:"total_users"`
+	Width      float64   `json:"width"`
+	Height     float64   `json:"height"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		Info     string `json:"info"`
+		Uplink   string `json:"uplink"`
+		NumUsers int    `json:"num_users"`
+		Synced   bool   `json:"synced"`
+		ULined   bool   `json:"ulined"`
+		Features struct {
+			Software string `json:"software"`
+		} `json:"features"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &NetworkMapPlugin{
+		config: Config{
+			RPCURL:          "https://127.0.0.1:8600/api",
+			RefreshInterval: 60,
+			ShowServices:    true,
+		},
+		refresh: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *NetworkMapPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Network Map",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Interactive map of the server link topology",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *NetworkMapPlugin) Init() error {
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.refreshLoop()
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *NetworkMapPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *NetworkMapPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/network-map")
+	{
+		plugin.GET("/graph", p.handleGraph)
+		plugin.GET("/map.svg", p.handleSVG)
+		plugin.POST("/refresh", p.handleRefresh)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *NetworkMapPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// refreshLoop rebuilds the graph until shutdown
+func (p *NetworkMapPlugin) refreshLoop() {
+	defer p.wg.Done()
+
+	p.load()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.RefreshInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 10*time.Second {
+			interval = 10 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.refresh:
+			p.load()
+		case <-time.After(interval):
+			p.load()
+		}
+	}
+}
+
+// load fetches the server list and rebuilds the graph
+func (p *NetworkMapPlugin) load() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcServer `json:"list"`
+	}
+	err := p.client().Call(ctx, "server.list", nil, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		log.Printf("[network-map] failed to load server list: %v", err)
+		return
+	}
+	p.lastError = ""
+	p.graph = buildGraph(result.List, p.config.ShowServices)
+}
+
+// buildGraph turns the server list into a laid out tree. The root is the
+// server without an uplink, i.e. the one the panel is connected to.
+func buildGraph(servers []rpcServer, showServices bool) *Graph {
+	byName := make(map[string]rpcServer, len(servers))
+	children := make(map[string][]string)
+	root := ""
+	for _, s := range servers {
+		if s.Server.ULined && !showServices {
+			continue
+		}
+		key := strings.ToLower(s.Name)
+		byName[key] = s
+		uplink := strings.ToLower(s.Server.Uplink)
+		if uplink == "" || uplink == key {
+			root = key
+			continue
+		}
+		children[uplink] = append(children[uplink], key)
+	}
+	for _, list := range children {
+		sort.Strings(list)
+	}
+
+	g := &Graph{
+		Nodes:     make([]Node, 0, len(byName)),
+		Edges:     make([]Edge, 0, len(byName)),
+		UpdatedAt: time.Now().UTC(),
+	}
+	if root == "" {
+		return g
+	}
+	g.Root = byName[root].Name
+
+	// Leaves take consecutive columns; parents sit above their children
+	nextColumn := 0.0
+	var place func(key string, depth int) float64
+	place = func(key string, depth int) float64 {
+		s := byName[key]
+		node := Node{
+			Name:     s.Name,
+			Info:     s.Server.Info,
+			Users:    s.Server.NumUsers,
+			Software: s.Server.Features.Software,
+			Synced:   s.Server.Synced,
+			Depth:    depth,
+			Y:        float64(depth),
+		}
+		kids := children[key]
+		switch {
+		case depth == 0:
+			node.Role = RoleRoot
+		case s.Server.ULined:
+			node.Role = RoleServices
+		case len(kids) > 0:
+			node.Role = RoleHub
+		default:
+			node.Role = RoleLeaf
+		}
+
+		index := len(g.Nodes)
+		g.Nodes = append(g.Nodes, node)
+		g.TotalUsers += node.Users
+		if depth+1 > int(g.Height) {
+			g.Height = float64(depth + 1)
+		}
+
+		if len(kids) == 0 {
+			g.Nodes[index].X = nextColumn
+			nextColumn++
+			return g.Nodes[index].X
+		}
+		sum := 0.0
+		for _, kid := range kids {
+			g.Edges = append(g.Edges, Edge{From: s.Name, To: byName[kid].Name})
+			sum += place(kid, depth+1)
+		}
+		g.Nodes[index].X = sum / float64(len(kids))
+		return g.Nodes[index].X
+	}
+	place(root, 0)
+	g.Width = nextColumn
+
+	return g
+}
+
+// renderSVG draws the graph as a standalone SVG document
+func renderSVG(g *Graph) string {
+	const (
+		colWidth  = 180.0
+		rowHeight = 110.0
+		margin    = 60.0
+	)
+	width := g.Width*colWidth + margin
+	height := g.Height*rowHeight + margin
+	pos := func(n Node) (float64, float64) {
+		return n.X*colWidth + colWidth/2, n.Y*rowHeight + margin/2 + 10
+	}
+
+	nodes := make(map[string]Node, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes[n.Name] = n
+	}
+	colors := map[string]string{
+		RoleRoot:     "#89b4fa",
+		RoleHub:      "#cba6f7",
+		RoleLeaf:     "#a6e3a1",
+		RoleServices: "#f9e2af",
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="sans-serif" font-size="12">`, width, height, width, height)
+	b.WriteString(`<rect width="100%" height="100%" fill="#1e1e2e"/>`)
+	for _, e := range g.Edges {
+		x1, y1 := pos(nodes[e.From])
+		x2, y2 := pos(nodes[e.To])
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#585b70" stroke-width="2"/>`, x1, y1, x2, y2)
+	}
+	for _, n := range g.Nodes {
+		x, y := pos(n)
+		fmt.Fprintf(&b, `<g><title>%s (%d users)</title>`, html.EscapeString(n.Name), n.Users)
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="14" fill="%s"/>`, x, y, colors[n.Role])
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="#cdd6f4" text-anchor="middle">%s</text>`, x, y+30, html.EscapeString(n.Name))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="#a6adc8" text-anchor="middle" font-size="10">%d users</text>`, x, y+44, n.Users)
+		b.WriteString(`</g>`)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// currentGraph returns the graph or writes an error response
+func (p *NetworkMapPlugin) currentGraph(c *gin.Context) *Graph {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.graph == nil {
+		msg := "Network map not loaded yet"
+		if p.lastError != "" {
+			msg = p.lastError
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": msg})
+		return nil
+	}
+	return p.graph
+}
+
+// handleGraph returns the link graph with layout positions
+func (p *NetworkMapPlugin) handleGraph(c *gin.Context) {
+	if g := p.currentGraph(c); g != nil {
+		c.JSON(http.StatusOK, g)
+	}
+}
+
+// handleSVG returns the link graph rendered as SVG
+func (p *NetworkMapPlugin) handleSVG(c *gin.Context) {
+	if g := p.currentGraph(c); g != nil {
+		c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(renderSVG(g)))
+	}
+}
+
+// handleRefresh schedules an immediate rebuild of the graph
+func (p *NetworkMapPlugin) handleRefresh(c *gin.Context) {
+	select {
+	case p.refresh <- struct{}{}:
+	default:
+		// A refresh is already pending
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Refresh scheduled"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *NetworkMapPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *NetworkMapPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *NetworkMapPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *NetworkMapPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,193 @@
+/**
+ * OpenAPI Docs Frontend Script
+ *
+ * Shows Swagger UI for the plugin API document inside the panel, with the
+ * described plugins and a link to download the document.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'openapi-docs';
+  const PLUGIN_NAME = 'OpenAPI Docs';
+  const PAGE_PATH = '/plugins/openapi-docs';
+  const API_BASE = '/api/plugin/openapi-docs';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const res = await fetch(API_BASE + path, {
+      method,
+      headers: getAuthHeaders(),
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  // Loads Swagger UI from the configured location. The script is loaded
+  // once; the stylesheet again after cleanup removed it.
+  let swaggerLoading = null;
+  function loadSwagger(base) {
+    base = base.replace(/\/+$/, '');
+    if (!document.getElementById('openapi-docs-swagger-css')) {
+      const css = document.createElement('link');
+      css.rel = 'stylesheet';
+      css.href = `${base}/swagger-ui.css`;
+      css.id = 'openapi-docs-swagger-css';
+      document.head.appendChild(css);
+    }
+    if (window.SwaggerUIBundle) return Promise.resolve();
+    if (swaggerLoading) return swaggerLoading;
+    swaggerLoading = new Promise((resolve, reject) => {
+      const script = document.createElement('script');
+      script.src = `${base}/swagger-ui-bundle.js`;
+      script.onload = resolve;
+      script.onerror = () => {
+        swaggerLoading = null;
+        script.remove();
+        reject(new Error(`Could not load Swagger UI from ${base}`));
+      };
+      document.head.appendChild(script);
+    });
+    return swaggerLoading;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('openapi-docs-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'openapi-docs-styles';
+    style.textContent = `
+      .oad-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .oad-toolbar { display: flex; gap: 0.75rem; align-items: center; flex-wrap: wrap; }
+      .oad-toolbar a { color: var(--accent, #89b4fa); }
+      .oad-plugins { font-size: 0.85rem; }
+      .oad-error { color: var(--error, #f38ba8); }
+      .oad-swagger { background: #fff; border-radius: 8px; padding: 0.5rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function load(container) {
+    const status = container.querySelector('#oad-plugins');
+    try {
+      const [config, described] = await Promise.all([api('GET', '/config'), api('GET', '/plugins')]);
+      status.innerHTML = `Documented: ${described.plugins.map(p =>
+        `<span title="${escapeHtml(p.routes.join('\n'))}">${escapeHtml(p.id)} (${p.routes.length})</span>`).join(', ')}`;
+
+      await loadSwagger(config.swagger_ui_url);
+      window.SwaggerUIBundle({
+        url: `${API_BASE}/openapi.json`,
+        domNode: container.querySelector('#oad-swagger'),
+        requestInterceptor: req => {
+          const token = localStorage.getItem('token');
+          if (token && !req.headers['Authorization']) {
+            req.headers['Authorization'] = `Bearer ${token}`;
+          }
+          return req;
+        },
+      });
+    } catch (e) {
+      container.querySelector('#oad-swagger').innerHTML = `<div class="oad-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function download() {
+    try {
+      const doc = await api('GET', '/openapi.json');
+      const blob = new Blob([JSON.stringify(doc, null, 2)], { type: 'application/json' });
+      const a = document.createElement('a');
+      a.href = URL.createObjectURL(blob);
+      a.download = 'openapi.json';
+      a.click();
+      URL.revokeObjectURL(a.href);
+    } catch (err) {
+      alert(err.message);
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="oad-app" data-plugin="${PLUGIN_ID}">
+        <div class="oad-toolbar">
+          <a href="#" id="oad-download">Download openapi.json</a>
+          <span id="oad-plugins" class="oad-plugins">Loading...</span>
+        </div>
+        <div id="oad-swagger" class="oad-swagger"></div>
+      </div>
+    `;
+    container.querySelector('#oad-download').addEventListener('click', e => {
+      e.preventDefault();
+      download();
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('openapi-docs-styles');
+    if (style) style.remove();
+    const css = document.getElementById('openapi-docs-swagger-css');
+    if (css) css.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
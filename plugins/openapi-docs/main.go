@@ -0,0 +1,214 @@
+// OpenAPI Docs Plugin for UnrealIRCd Web Panel
+// Builds an OpenAPI 3 document of the routes plugins describe with
+// apidocs.Describe, and serves it with Swagger UI for external integrators
+
+package openapidocs
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/apidocs"
+)
+
+// pluginID is the ID this plugin documents its own routes under
+const pluginID = "openapi-docs"
+
+// OpenAPIDocsPlugin implements the Plugin interface
+type OpenAPIDocsPlugin struct {
+	config Config
+	mu     sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	Title        string `json:"title"`
+	APIVersion   string `json:"api_version"`
+	ServerURL    string `json:"server_url"`
+	SwaggerUIURL string `json:"swagger_ui_url"`
+}
+
+// PluginSummary is a plugin in the document, as listed by /plugins
+type PluginSummary struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Routes      []string `json:"routes" doc:"Method and path of each route"`
+}
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	return &OpenAPIDocsPlugin{
+		config: Config{
+			Title:        "UnrealIRCd Web Panel Plugin API",
+			APIVersion:   "1.0.0",
+			ServerURL:    "/api",
+			SwaggerUIURL: "https://unpkg.com/swagger-ui-dist@5",
+		},
+	}
+}
+
+// Info returns plugin metadata
+func (p *OpenAPIDocsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "OpenAPI Docs",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "OpenAPI 3 document and Swagger UI for the APIs of plugins",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *OpenAPIDocsPlugin) Init() error {
+	apidocs.Describe(pluginID, "OpenAPI document of the plugin APIs",
+		apidocs.Operation{Method: "GET", Path: "/openapi.json", Summary: "The OpenAPI 3 document of every described plugin", Response: map[string]interface{}{}},
+		apidocs.Operation{Method: "GET", Path: "/ui", Summary: "Swagger UI for the document", ContentType: "text/html"},
+		apidocs.Operation{Method: "GET", Path: "/plugins", Summary: "The described plugins and their routes", Response: struct {
+			Plugins []PluginSummary `json:"plugins"`
+		}{}},
+		apidocs.Operation{Method: "GET", Path: "/config", Summary: "Get current configuration", Response: Config{}},
+		apidocs.Operation{Method: "PUT", Path: "/config", Summary: "Update configuration", Request: Config{}},
+	)
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *OpenAPIDocsPlugin) Shutdown() error {
+	apidocs.Forget(pluginID)
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *OpenAPIDocsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/openapi-docs")
+	{
+		plugin.GET("/openapi.json", p.handleDocument)
+		plugin.GET("/ui", p.handleUI)
+		plugin.GET("/plugins", p.handlePlugins)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// handleDocument returns the OpenAPI document
+func (p *OpenAPIDocsPlugin) handleDocument(c *gin.Context) {
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, buildDocument(cfg.Title, cfg.APIVersion, cfg.ServerURL))
+}
+
+// uiPage is the Swagger UI page. Requests from it carry the panel token
+// the panel keeps in local storage, so the page works for logged in users.
+var uiPage = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="{{.Base}}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.Base}}/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      url: 'openapi.json',
+      dom_id: '#swagger-ui',
+      requestInterceptor: function(req) {
+        var token = localStorage.getItem('token');
+        if (token && !req.headers['Authorization']) {
+          req.headers['Authorization'] = 'Bearer ' + token;
+        }
+        return req;
+      }
+    });
+  </script>
+</body>
+</html>
+`))
+
+// handleUI serves Swagger UI for the document
+func (p *OpenAPIDocsPlugin) handleUI(c *gin.Context) {
+	p.mu.RLock()
+	data := struct{ Title, Base string }{p.config.Title, strings.TrimRight(p.config.SwaggerUIURL, "/")}
+	p.mu.RUnlock()
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := uiPage.Execute(c.Writer, data); err != nil {
+		log.Printf("[openapi-docs] failed to render the UI: %v", err)
+	}
+}
+
+// handlePlugins lists the described plugins with their routes
+func (p *OpenAPIDocsPlugin) handlePlugins(c *gin.Context) {
+	list := make([]PluginSummary, 0)
+	for _, d := range apidocs.Described() {
+		s := PluginSummary{ID: d.ID, Description: d.Description, Routes: make([]string, 0, len(d.Operations))}
+		for _, op := range d.Operations {
+			path, _ := openAPIPath(d.ID, op.Path)
+			s.Routes = append(s.Routes, strings.ToUpper(op.Method)+" "+path)
+		}
+		list = append(list, s)
+	}
+	c.JSON(http.StatusOK, gin.H{"plugins": list})
+}
+
+// handleGetConfig returns the current configuration
+func (p *OpenAPIDocsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *OpenAPIDocsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if strings.TrimSpace(newConfig.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return
+	}
+	if !strings.HasPrefix(newConfig.SwaggerUIURL, "https://") && !strings.HasPrefix(newConfig.SwaggerUIURL, "http://") && !strings.HasPrefix(newConfig.SwaggerUIURL, "/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "swagger_ui_url must be an http or https URL or a path"})
+		return
+	}
+	if newConfig.ServerURL == "" {
+		newConfig.ServerURL = "/api"
+	}
+	if newConfig.APIVersion == "" {
+		newConfig.APIVersion = "1.0.0"
+	}
+
+	p.mu.Lock()
+	p.config = newConfig
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *OpenAPIDocsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *OpenAPIDocsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
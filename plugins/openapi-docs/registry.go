@@ -0,0 +1,175 @@
+package openapidocs
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/apidocs"
+)
+
+// openAPIPath turns a plugin's gin route into an OpenAPI path below the
+// API base, returning the names of its path parameters
+func openAPIPath(pluginID, route string) (string, []string) {
+	parts := strings.Split(strings.Trim(route, "/"), "/")
+	params := make([]string, 0)
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	path := "/plugin/" + pluginID
+	if joined := strings.Join(parts, "/"); joined != "" {
+		path += "/" + joined
+	}
+	return path, params
+}
+
+// buildDocument builds the OpenAPI 3 document of the described plugins
+func buildDocument(title, version, serverURL string) map[string]interface{} {
+	b := newSchemaBuilder()
+	errorRef := b.schema(typeOf(ErrorResponse{}))
+
+	paths := make(map[string]interface{})
+	tags := make([]interface{}, 0)
+	for _, d := range apidocs.Described() {
+		tag := map[string]interface{}{"name": d.ID}
+		if d.Description != "" {
+			tag["description"] = d.Description
+		}
+		tags = append(tags, tag)
+
+		for _, op := range d.Operations {
+			path, pathParams := openAPIPath(d.ID, op.Path)
+			item, ok := paths[path].(map[string]interface{})
+			if !ok {
+				item = make(map[string]interface{})
+				paths[path] = item
+			}
+			item[strings.ToLower(op.Method)] = b.operation(d.ID, op, pathParams, errorRef)
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"servers": []interface{}{map[string]interface{}{"url": serverURL}},
+		"tags":    tags,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []string{}}},
+	}
+}
+
+// operation builds the OpenAPI operation object of op
+func (b *schemaBuilder) operation(pluginID string, op apidocs.Operation, pathParams []string, errorRef map[string]interface{}) map[string]interface{} {
+	method := strings.ToUpper(op.Method)
+	out := map[string]interface{}{
+		"tags":        []string{pluginID},
+		"operationId": operationID(pluginID, method, op.Path),
+	}
+	if op.Summary != "" {
+		out["summary"] = op.Summary
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+
+	params := make([]interface{}, 0, len(pathParams)+len(op.Query))
+	for _, name := range pathParams {
+		params = append(params, parameter("path", apidocs.Param{Name: name, Required: true}))
+	}
+	for _, q := range op.Query {
+		params = append(params, parameter("query", q))
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	if op.Request != nil {
+		out["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(b.schema(typeOf(op.Request))),
+		}
+	}
+
+	success := jsonContent(map[string]interface{}{"type": "object"})
+	if op.ContentType != "" && op.ContentType != "application/json" {
+		success = map[string]interface{}{
+			op.ContentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		}
+	} else if op.Response != nil {
+		success = jsonContent(b.schema(typeOf(op.Response)))
+	}
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	out["responses"] = map[string]interface{}{
+		strconv.Itoa(status): map[string]interface{}{
+			"description": http.StatusText(status),
+			"content":     success,
+		},
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content":     jsonContent(errorRef),
+		},
+	}
+	return out
+}
+
+// parameter builds an OpenAPI parameter object
+func parameter(in string, p apidocs.Param) map[string]interface{} {
+	typ := p.Type
+	if typ == "" {
+		typ = "string"
+	}
+	out := map[string]interface{}{
+		"name":   p.Name,
+		"in":     in,
+		"schema": map[string]interface{}{"type": typ},
+	}
+	if p.Required {
+		out["required"] = true
+	}
+	if p.Description != "" {
+		out["description"] = p.Description
+	}
+	return out
+}
+
+// jsonContent wraps a schema in an application/json content map
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// operationID derives a unique operation ID, such as
+// config-drift.getServersSnapshots
+func operationID(pluginID, method, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(route, func(r rune) bool { return r == '/' || r == '-' || r == '_' || r == '.' }) {
+		if (strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*")) && len(part) > 1 {
+			part = "by" + strings.ToUpper(part[1:2]) + part[2:]
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return pluginID + "." + b.String()
+}
+
+// ErrorResponse is the body of error responses, documented as the default
+// response of every operation
+type ErrorResponse struct {
+	Error string `json:"error" doc:"What went wrong"`
+}
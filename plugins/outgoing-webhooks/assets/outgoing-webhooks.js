@@ -0,0 +1,439 @@
+/**
+ * Outgoing Webhooks Frontend Script
+ *
+ * Manage webhooks and their payload templates, and review and redeliver
+ * past deliveries.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'outgoing-webhooks';
+  const PLUGIN_NAME = 'Outgoing Webhooks';
+  const PAGE_PATH = '/plugins/outgoing-webhooks';
+  const API_BASE = '/api/plugin/outgoing-webhooks';
+
+  const SEVERITIES = ['info', 'warning', 'critical'];
+  const METHODS = ['POST', 'PUT', 'PATCH'];
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function splitEvents(value) {
+    return value.split(',').map(s => s.trim()).filter(Boolean);
+  }
+
+  function options(list, selected) {
+    return list.map(s => `<option value="${s}" ${s === selected ? 'selected' : ''}>${s}</option>`).join('');
+  }
+
+  // Headers are edited as one "Name: value" per line
+  function parseHeaders(text) {
+    const headers = {};
+    text.split('\n').map(l => l.trim()).filter(Boolean).forEach(line => {
+      const i = line.indexOf(':');
+      if (i > 0) headers[line.slice(0, i).trim()] = line.slice(i + 1).trim();
+    });
+    return headers;
+  }
+
+  function formatHeaders(headers) {
+    return Object.entries(headers || {}).map(([k, v]) => `${k}: ${v}`).join('\n');
+  }
+
+  function formatTime(value) {
+    if (!value || value.startsWith('0001-')) return '';
+    return new Date(value).toLocaleString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('outgoing-webhooks-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'outgoing-webhooks-styles';
+    style.textContent = `
+      .ow-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .ow-form { display: grid; grid-template-columns: 9rem 1fr; gap: 0.5rem; align-items: start; max-width: 60rem; }
+      .ow-app input, .ow-app select, .ow-app textarea {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.35rem 0.6rem;
+      }
+      .ow-app textarea { font-family: monospace; min-height: 8rem; }
+      .ow-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .ow-actions { display: flex; gap: 0.5rem; grid-column: 2; }
+      .ow-table { width: 100%; border-collapse: collapse; }
+      .ow-table th, .ow-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        overflow-wrap: anywhere;
+      }
+      .ow-table tr[data-delivery] { cursor: pointer; }
+      .ow-pre {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.6rem;
+        white-space: pre-wrap;
+        overflow-wrap: anywhere;
+        max-height: 20rem;
+        overflow: auto;
+      }
+      .ow-hint { font-size: 0.85rem; color: var(--text-muted, #6c7086); }
+      .ow-disabled { color: var(--text-muted, #6c7086); }
+      .ow-succeeded { color: var(--success, #a6e3a1); }
+      .ow-retrying, .ow-queued, .ow-warning { color: var(--warning, #f9e2af); }
+      .ow-failed, .ow-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#ow-status');
+    try {
+      const s = await api('GET', '/status');
+      body.innerHTML = `
+        ${s.notify_bans && !s.log_stream ? '<span class="ow-warning">Not connected to the IRCd log stream; new bans are not being seen.</span>' : ''}
+        ${s.events_enabled ? '' : '<span class="ow-warning">No events token set; other plugins cannot send events.</span>'}
+        Deliveries kept: ${s.deliveries.succeeded} succeeded, ${s.deliveries.failed} failed,
+        ${s.deliveries.retrying} waiting to retry, ${s.deliveries.queued} queued.
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="ow-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function fillEditor(container, w) {
+    const f = container.querySelector('#ow-editor').elements;
+    f.id.value = w ? w.id : '';
+    f.name.value = w ? w.name : '';
+    f.url.value = w ? w.url : '';
+    f.method.value = w ? w.method : 'POST';
+    f.events.value = w ? w.events.join(', ') : '*';
+    f.min_severity.value = w ? w.min_severity : 'info';
+    f.content_type.value = w ? w.content_type : 'application/json';
+    f.headers.value = w ? formatHeaders(w.headers) : '';
+    f.template.value = w ? w.template : '';
+    f.secret.value = '';
+    f.secret.placeholder = w && w.has_secret ? 'Unchanged (a secret is set)' : 'None';
+    f.enabled.checked = w ? w.enabled : true;
+    container.querySelector('#ow-editor-title').textContent = w ? `Edit ${w.name}` : 'Add webhook';
+    container.querySelector('#ow-preview').textContent = '';
+  }
+
+  async function loadWebhooks(container) {
+    const body = container.querySelector('#ow-webhooks');
+    try {
+      const data = await api('GET', '/webhooks');
+      if (data.webhooks.length === 0) {
+        body.innerHTML = '<p>No webhooks yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="ow-table">
+          <thead><tr><th>Name</th><th>Endpoint</th><th>Events</th><th>Minimum</th><th>Signed</th><th></th></tr></thead>
+          <tbody>
+            ${data.webhooks.map(w => `
+              <tr class="${w.enabled ? '' : 'ow-disabled'}">
+                <td>${escapeHtml(w.name)}${w.enabled ? '' : ' (disabled)'}</td>
+                <td><code>${escapeHtml(w.method)} ${escapeHtml(w.url)}</code></td>
+                <td>${escapeHtml(w.events.join(', '))}</td>
+                <td>${escapeHtml(w.min_severity)}</td>
+                <td>${w.has_secret ? 'Yes' : 'No'}</td>
+                <td>
+                  <button data-edit="${escapeHtml(w.id)}">Edit</button>
+                  <button data-test="${escapeHtml(w.id)}">Test</button>
+                  <button data-delete="${escapeHtml(w.id)}">Delete</button>
+                </td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+      const byId = Object.fromEntries(data.webhooks.map(w => [w.id, w]));
+      body.querySelectorAll('button[data-edit]').forEach(btn => {
+        btn.addEventListener('click', () => fillEditor(container, byId[btn.dataset.edit]));
+      });
+      body.querySelectorAll('button[data-test]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          btn.disabled = true;
+          try {
+            const res = await api('POST', `/webhooks/${btn.dataset.test}/test`);
+            alert(`${res.message} (HTTP ${res.status})`);
+          } catch (e) {
+            alert(e.message);
+          }
+          btn.disabled = false;
+          loadDeliveries(container);
+        });
+      });
+      body.querySelectorAll('button[data-delete]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          if (!confirm('Remove this webhook? Deliveries still waiting for it fail.')) return;
+          try {
+            await api('DELETE', `/webhooks/${btn.dataset.delete}`);
+            loadWebhooks(container);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="ow-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function showDelivery(container, id) {
+    const body = container.querySelector('#ow-delivery');
+    try {
+      const d = await api('GET', `/deliveries/${id}`);
+      body.innerHTML = `
+        <h4>Delivery ${escapeHtml(d.id)} to ${escapeHtml(d.webhook)}</h4>
+        ${d.redelivery_of ? `<p>Redelivery of ${escapeHtml(d.redelivery_of)}</p>` : ''}
+        ${d.error ? `<p class="ow-error">${escapeHtml(d.error)}</p>` : ''}
+        <table class="ow-table">
+          <thead><tr><th>Attempt</th><th>Status</th><th>Time taken</th><th>Response</th></tr></thead>
+          <tbody>
+            ${d.attempts.map(a => `
+              <tr>
+                <td>${escapeHtml(formatTime(a.time))}</td>
+                <td class="${a.error ? 'ow-failed' : 'ow-succeeded'}">${escapeHtml(a.status || '')} ${escapeHtml(a.error || '')}</td>
+                <td>${a.duration_ms} ms</td>
+                <td><code>${escapeHtml(a.response || '')}</code></td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+        ${d.next_attempt && !d.next_attempt.startsWith('0001-') ? `<p>Next attempt ${escapeHtml(formatTime(d.next_attempt))}</p>` : ''}
+        <div class="ow-hint">${escapeHtml(d.content_type)}</div>
+        <div class="ow-pre">${escapeHtml(d.body)}</div>
+        ${d.body ? '<button id="ow-redeliver">Redeliver</button>' : ''}
+      `;
+      const btn = body.querySelector('#ow-redeliver');
+      if (btn) {
+        btn.addEventListener('click', async () => {
+          try {
+            const res = await api('POST', `/deliveries/${d.id}/redeliver`);
+            loadDeliveries(container);
+            showDelivery(container, res.id);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+      }
+    } catch (e) {
+      body.innerHTML = `<div class="ow-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadDeliveries(container) {
+    const body = container.querySelector('#ow-deliveries');
+    const state = container.querySelector('#ow-state').value;
+    try {
+      const data = await api('GET', `/deliveries?limit=100${state ? `&state=${state}` : ''}`);
+      if (data.deliveries.length === 0) {
+        body.innerHTML = '<p>No deliveries.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="ow-table">
+          <thead><tr><th>Time</th><th>Webhook</th><th>Event</th><th>State</th><th>Attempts</th><th>Last result</th></tr></thead>
+          <tbody>
+            ${data.deliveries.map(d => `
+              <tr data-delivery="${escapeHtml(d.id)}">
+                <td>${escapeHtml(formatTime(d.created_at))}</td>
+                <td>${escapeHtml(d.webhook)}</td>
+                <td>${escapeHtml(d.event)}</td>
+                <td class="ow-${escapeHtml(d.state)}">${escapeHtml(d.state)}${d.redelivery_of ? ' (redelivery)' : ''}</td>
+                <td>${d.attempts}</td>
+                <td>${escapeHtml(d.error || (d.status ? `HTTP ${d.status}` : ''))}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+      body.querySelectorAll('tr[data-delivery]').forEach(row => {
+        row.addEventListener('click', () => showDelivery(container, row.dataset.delivery));
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="ow-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="ow-app" data-plugin="${PLUGIN_ID}">
+        <div id="ow-status"></div>
+        <h3>Webhooks</h3>
+        <div id="ow-webhooks">Loading...</div>
+        <h3 id="ow-editor-title">Add webhook</h3>
+        <form class="ow-form" id="ow-editor">
+          <input type="hidden" name="id">
+          <label>Name</label><input name="name" required>
+          <label>URL</label><input name="url" placeholder="https://example.org/hooks/irc" required>
+          <label>Method</label><select name="method">${options(METHODS, 'POST')}</select>
+          <label>Events</label><input name="events" value="*" required>
+          <label>Minimum severity</label><select name="min_severity">${options(SEVERITIES, 'info')}</select>
+          <label>Content type</label><input name="content_type" value="application/json">
+          <label>Headers</label><textarea name="headers" placeholder="Authorization: Bearer ..."></textarea>
+          <label>Secret</label><input name="secret" type="password" autocomplete="new-password">
+          <label>Payload template</label><textarea name="template" placeholder="Empty sends the event as JSON"></textarea>
+          <label>Enabled</label><input type="checkbox" name="enabled" checked>
+          <div class="ow-actions">
+            <button type="submit">Save</button>
+            <button type="button" id="ow-preview-btn">Preview</button>
+            <button type="button" id="ow-clear">New</button>
+          </div>
+        </form>
+        <div class="ow-hint">
+          Events are event types (<code>ban_added</code>, or any type another plugin sends), names of the plugins that
+          send them, or <code>*</code> for everything. Templates use Go template syntax with <code>.Type</code>,
+          <code>.Title</code>, <code>.Message</code>, <code>.Severity</code>, <code>.Source</code>, <code>.Timestamp</code>,
+          <code>.Data</code>, <code>.Webhook</code> and <code>.Delivery</code>, and the functions <code>json</code>,
+          <code>upper</code>, <code>lower</code>, <code>join</code>, <code>default</code> and <code>unix</code>.
+        </div>
+        <div class="ow-pre" id="ow-preview"></div>
+        <h3>Deliveries</h3>
+        <select id="ow-state">
+          <option value="">All</option>
+          <option value="failed">Failed</option>
+          <option value="retrying">Retrying</option>
+          <option value="succeeded">Succeeded</option>
+        </select>
+        <div id="ow-deliveries">Loading...</div>
+        <div id="ow-delivery"></div>
+      </div>
+    `;
+
+    const editor = container.querySelector('#ow-editor');
+    editor.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = editor.elements;
+      const req = {
+        name: f.name.value,
+        url: f.url.value,
+        method: f.method.value,
+        events: splitEvents(f.events.value),
+        min_severity: f.min_severity.value,
+        content_type: f.content_type.value,
+        headers: parseHeaders(f.headers.value),
+        template: f.template.value,
+        enabled: f.enabled.checked
+      };
+      // An empty secret field keeps the current secret
+      if (f.secret.value) req.secret = f.secret.value;
+      try {
+        if (f.id.value) {
+          await api('PUT', `/webhooks/${f.id.value}`, req);
+        } else {
+          await api('POST', '/webhooks', req);
+        }
+        fillEditor(container, null);
+        loadWebhooks(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+    container.querySelector('#ow-preview-btn').addEventListener('click', async () => {
+      const f = editor.elements;
+      const out = container.querySelector('#ow-preview');
+      try {
+        const res = await api('POST', '/preview', { template: f.template.value, content_type: f.content_type.value });
+        out.textContent = res.body;
+        out.className = 'ow-pre';
+      } catch (err) {
+        out.textContent = err.message;
+        out.className = 'ow-pre ow-error';
+      }
+    });
+    container.querySelector('#ow-clear').addEventListener('click', () => fillEditor(container, null));
+    container.querySelector('#ow-state').addEventListener('change', () => loadDeliveries(container));
+
+    loadStatus(container);
+    loadWebhooks(container);
+    loadDeliveries(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('outgoing-webhooks-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
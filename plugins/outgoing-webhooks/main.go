@@ -0,0 +1,1179 @@
+// Outgoing Webhooks Plugin for UnrealIRCd Web Panel
+// Sends network and plugin events to any HTTP endpoint, with payloads
+// built from templates, HMAC signatures, retries and a delivery log
+
+package outgoingwebhooks
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on queued and remembered deliveries
+const (
+	queueSize     = 200
+	maxDeliveries = 500
+	maxBackoff    = time.Hour
+)
+
+// Delivery states
+const (
+	StateQueued    = "queued"
+	StateRetrying  = "retrying"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+)
+
+// OutgoingWebhooksPlugin implements the Plugin interface
+type OutgoingWebhooksPlugin struct {
+	config       Config
+	webhooks     []*Webhook
+	senders      map[string]*sender
+	deliveries   []*Delivery
+	received     int
+	dirty        bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCUser     string `json:"rpc_user"`
+	RPCPassword string `json:"rpc_password"`
+	RPCInsecure bool   `json:"rpc_insecure"`
+	StreamURL   string `json:"stream_url"`
+	LogSources  string `json:"log_sources"`
+	DataDir     string `json:"data_dir"`
+	EventsToken string `json:"events_token"`
+	NotifyBans  bool   `json:"notify_bans"`
+	BanTypes    string `json:"ban_types"`
+	MaxAttempts int    `json:"max_attempts"`
+	RetryDelay  int    `json:"retry_delay"`
+	Timeout     int    `json:"timeout"`
+}
+
+// Webhook is an HTTP endpoint, the events sent to it and how their
+// payloads are built
+type Webhook struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Events      []string          `json:"events"`
+	MinSeverity string            `json:"min_severity"`
+	ContentType string            `json:"content_type"`
+	Headers     map[string]string `json:"headers"`
+	Template    string            `json:"template"`
+	Secret      string            `json:"secret,omitempty"`
+	HasSecret   bool              `json:"has_secret"`
+	Enabled     bool              `json:"enabled"`
+	CreatedBy   string            `json:"created_by"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// WebhookRequest is the body of a create or update request. A missing
+// secret keeps the current one and an empty one removes it.
+type WebhookRequest struct {
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Events      []string          `json:"events"`
+	MinSeverity string            `json:"min_severity"`
+	ContentType string            `json:"content_type"`
+	Headers     map[string]string `json:"headers"`
+	Template    string            `json:"template"`
+	Secret      *string           `json:"secret"`
+	Enabled     *bool             `json:"enabled"`
+}
+
+// Delivery is one event sent to one webhook, with every attempt made
+type Delivery struct {
+	ID           string    `json:"id"`
+	WebhookID    string    `json:"webhook_id"`
+	Webhook      string    `json:"webhook"`
+	Event        string    `json:"event"`
+	Source       string    `json:"source"`
+	ContentType  string    `json:"content_type"`
+	Body         string    `json:"body"`
+	State        string    `json:"state"`
+	Error        string    `json:"error,omitempty"`
+	Attempts     []Attempt `json:"attempts"`
+	NextAttempt  time.Time `json:"next_attempt"`
+	RedeliveryOf string    `json:"redelivery_of,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Attempt is one try at sending a delivery
+type Attempt struct {
+	Time     time.Time `json:"time"`
+	Status   int       `json:"status,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Response string    `json:"response,omitempty"`
+	Duration int64     `json:"duration_ms"`
+}
+
+// DeliverySummary is a delivery without its body and attempts, as listed
+type DeliverySummary struct {
+	ID           string    `json:"id"`
+	WebhookID    string    `json:"webhook_id"`
+	Webhook      string    `json:"webhook"`
+	Event        string    `json:"event"`
+	Source       string    `json:"source"`
+	State        string    `json:"state"`
+	Attempts     int       `json:"attempts"`
+	Status       int       `json:"status,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	NextAttempt  time.Time `json:"next_attempt"`
+	RedeliveryOf string    `json:"redelivery_of,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// sender delivers the queued deliveries of one webhook in order
+type sender struct {
+	queue chan *Delivery
+	quit  chan struct{}
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Webhooks   []*Webhook  `json:"webhooks"`
+	Deliveries []*Delivery `json:"deliveries"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &OutgoingWebhooksPlugin{
+		config: Config{
+			StreamURL:   "wss://127.0.0.1:8600/",
+			LogSources:  "tkl",
+			DataDir:     "data/plugins/outgoing-webhooks",
+			NotifyBans:  true,
+			MaxAttempts: 5,
+			RetryDelay:  30,
+			Timeout:     10,
+		},
+		webhooks:   make([]*Webhook, 0),
+		senders:    make(map[string]*sender),
+		deliveries: make([]*Delivery, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *OutgoingWebhooksPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Outgoing Webhooks",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Sends events to HTTP endpoints with templated, signed payloads",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *OutgoingWebhooksPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[outgoing-webhooks] failed to load data: %v", err)
+	}
+	if data.Webhooks != nil {
+		p.webhooks = data.Webhooks
+	}
+	if data.Deliveries != nil {
+		p.deliveries = data.Deliveries
+	}
+	// Deliveries that were waiting when the panel stopped are sent again
+	// by the retry loop
+	now := time.Now().UTC()
+	for _, d := range p.deliveries {
+		if d.State == StateQueued {
+			d.State = StateRetrying
+			d.NextAttempt = now
+		}
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "outgoing-webhooks-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		sent, failed, retrying := 0, 0, 0
+		for _, d := range p.deliveries {
+			switch {
+			case d.State == StateRetrying:
+				retrying++
+			case d.CreatedAt.Before(since):
+			case d.State == StateSucceeded:
+				sent++
+			case d.State == StateFailed:
+				failed++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Outgoing Webhooks",
+			Icon:  "Webhook",
+			Content: map[string]interface{}{
+				"webhooks":   len(p.webhooks),
+				"sent_24h":   sent,
+				"failed_24h": failed,
+				"retrying":   retrying,
+			},
+			Order: 66,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.retryLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *OutgoingWebhooksPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *OutgoingWebhooksPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/outgoing-webhooks")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/webhooks", p.handleListWebhooks)
+		plugin.POST("/webhooks", p.handleCreateWebhook)
+		plugin.PUT("/webhooks/:id", p.handleUpdateWebhook)
+		plugin.DELETE("/webhooks/:id", p.handleDeleteWebhook)
+		plugin.POST("/webhooks/:id/test", p.handleTestWebhook)
+		plugin.POST("/preview", p.handlePreview)
+		plugin.GET("/deliveries", p.handleListDeliveries)
+		plugin.GET("/deliveries/:id", p.handleGetDelivery)
+		plugin.POST("/deliveries/:id/redeliver", p.handleRedeliver)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *OutgoingWebhooksPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "outgoing-webhooks.json")
+}
+
+// save persists the state if it changed
+func (p *OutgoingWebhooksPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Webhooks: p.webhooks, Deliveries: p.deliveries}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// errString returns the message of err, or "" for nil
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// webhookByID returns the webhook with the given ID. Caller must hold p.mu.
+func (p *OutgoingWebhooksPlugin) webhookByID(id string) *Webhook {
+	for _, wh := range p.webhooks {
+		if wh.ID == id {
+			return wh
+		}
+	}
+	return nil
+}
+
+// deliveryByID returns the delivery with the given ID. Caller must hold
+// p.mu.
+func (p *OutgoingWebhooksPlugin) deliveryByID(id string) *Delivery {
+	for _, d := range p.deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// matches reports whether an event is sent to the webhook. Events lists
+// event types or source plugins, or * for everything.
+func (wh *Webhook) matches(ev notify.Event) bool {
+	if !wh.Enabled || notify.SeverityRank[ev.Severity] < notify.SeverityRank[wh.MinSeverity] {
+		return false
+	}
+	for _, e := range wh.Events {
+		if e == "*" || strings.EqualFold(e, ev.Type) || strings.EqualFold(e, ev.Source) {
+			return true
+		}
+	}
+	return false
+}
+
+// redacted returns a copy of the webhook with its secret hidden
+func (wh *Webhook) redacted() Webhook {
+	c := *wh
+	c.HasSecret = c.Secret != ""
+	c.Secret = ""
+	return c
+}
+
+// summary returns the delivery as listed
+func (d *Delivery) summary() DeliverySummary {
+	s := DeliverySummary{
+		ID:           d.ID,
+		WebhookID:    d.WebhookID,
+		Webhook:      d.Webhook,
+		Event:        d.Event,
+		Source:       d.Source,
+		State:        d.State,
+		Attempts:     len(d.Attempts),
+		Error:        d.Error,
+		NextAttempt:  d.NextAttempt,
+		RedeliveryOf: d.RedeliveryOf,
+		CreatedAt:    d.CreatedAt,
+	}
+	if n := len(d.Attempts); n > 0 {
+		s.Status = d.Attempts[n-1].Status
+		if s.Error == "" {
+			s.Error = d.Attempts[n-1].Error
+		}
+	}
+	return s
+}
+
+// clone returns a copy of the delivery that is safe to use without p.mu
+func (d *Delivery) clone() Delivery {
+	c := *d
+	c.Attempts = append([]Attempt(nil), d.Attempts...)
+	return c
+}
+
+// streamLoop follows ban log events until shutdown
+func (p *OutgoingWebhooksPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *OutgoingWebhooksPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[outgoing-webhooks] log stream: %v", err)
+	}
+}
+
+// handleEvent sends new server bans
+func (p *OutgoingWebhooksPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	p.mu.RLock()
+	enabled := p.config.NotifyBans
+	types := lists.Split(p.config.BanTypes)
+	p.mu.RUnlock()
+	if !enabled {
+		return
+	}
+	if a, ok := notify.BanEvent("outgoing-webhooks", ev, types); ok {
+		p.dispatch(a)
+	}
+}
+
+// dispatch creates a delivery of an event for every webhook it is sent to
+func (p *OutgoingWebhooksPlugin) dispatch(ev notify.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.stop:
+		return
+	default:
+	}
+
+	p.received++
+	for _, wh := range p.webhooks {
+		if !wh.matches(ev) {
+			continue
+		}
+		d := &Delivery{
+			ID:          newID(),
+			WebhookID:   wh.ID,
+			Webhook:     wh.Name,
+			Event:       ev.Type,
+			Source:      ev.Source,
+			ContentType: wh.ContentType,
+			Attempts:    make([]Attempt, 0),
+			CreatedAt:   time.Now().UTC(),
+		}
+		body, err := renderPayload(wh.Template, wh.ContentType, ev, wh.Name, d.ID)
+		if err != nil {
+			d.State = StateFailed
+			d.Error = "template: " + err.Error()
+			log.Printf("[outgoing-webhooks] payload for %s failed: %v", wh.Name, err)
+		} else {
+			d.Body = body
+			p.enqueue(d)
+		}
+		p.addDelivery(d)
+	}
+}
+
+// enqueue hands a delivery to its webhook's sender. When the sender is
+// behind, the delivery waits for the retry loop instead. Caller must hold
+// p.mu.
+func (p *OutgoingWebhooksPlugin) enqueue(d *Delivery) {
+	s := p.senderFor(d.WebhookID)
+	select {
+	case s.queue <- d:
+		d.State = StateQueued
+		d.NextAttempt = time.Time{}
+	default:
+		d.State = StateRetrying
+		d.NextAttempt = time.Now().UTC().Add(time.Duration(p.config.RetryDelay) * time.Second)
+	}
+}
+
+// addDelivery records a delivery, forgetting the oldest beyond
+// maxDeliveries. Caller must hold p.mu.
+func (p *OutgoingWebhooksPlugin) addDelivery(d *Delivery) {
+	p.deliveries = append(p.deliveries, d)
+	if len(p.deliveries) > maxDeliveries {
+		p.deliveries = p.deliveries[len(p.deliveries)-maxDeliveries:]
+	}
+	p.dirty = true
+}
+
+// senderFor returns the sender of a webhook, starting it on first use.
+// Caller must hold p.mu.
+func (p *OutgoingWebhooksPlugin) senderFor(id string) *sender {
+	s, ok := p.senders[id]
+	if !ok {
+		s = &sender{queue: make(chan *Delivery, queueSize), quit: make(chan struct{})}
+		p.senders[id] = s
+		p.wg.Add(1)
+		go p.sendLoop(id, s)
+	}
+	return s
+}
+
+// stopSender stops the sender of a webhook and fails every delivery still
+// waiting for it. Caller must hold p.mu.
+func (p *OutgoingWebhooksPlugin) stopSender(id, reason string) {
+	if s, ok := p.senders[id]; ok {
+		close(s.quit)
+		delete(p.senders, id)
+	}
+	for _, d := range p.deliveries {
+		if d.WebhookID == id && (d.State == StateQueued || d.State == StateRetrying) {
+			d.State = StateFailed
+			d.Error = reason
+			d.NextAttempt = time.Time{}
+			p.dirty = true
+		}
+	}
+}
+
+// backoff returns how long to wait before attempt n+1, doubling from
+// retry_delay. Caller must hold p.mu.
+func (p *OutgoingWebhooksPlugin) backoff(n int) time.Duration {
+	d := time.Duration(p.config.RetryDelay) * time.Second
+	for i := 1; i < n && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// sendLoop makes one attempt at each delivery handed to it, in order.
+// Deliveries worth retrying are scheduled for the retry loop.
+func (p *OutgoingWebhooksPlugin) sendLoop(id string, s *sender) {
+	defer p.wg.Done()
+
+	for {
+		var d *Delivery
+		select {
+		case <-p.stop:
+			return
+		case <-s.quit:
+			return
+		case d = <-s.queue:
+		}
+
+		p.mu.RLock()
+		wh := p.webhookByID(id)
+		var req request
+		if wh != nil {
+			req = request{
+				Method:      wh.Method,
+				URL:         wh.URL,
+				ContentType: d.ContentType,
+				Headers:     wh.Headers,
+				Secret:      wh.Secret,
+				Event:       d.Event,
+				Delivery:    d.ID,
+				Body:        d.Body,
+			}
+		}
+		timeout := time.Duration(p.config.Timeout) * time.Second
+		p.mu.RUnlock()
+		if wh == nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		res := send(ctx, req)
+		cancel()
+		p.recordAttempt(d, start, res)
+	}
+}
+
+// recordAttempt adds the outcome of an attempt to a delivery and decides
+// what happens to it next
+func (p *OutgoingWebhooksPlugin) recordAttempt(d *Delivery, start time.Time, res result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	d.Attempts = append(d.Attempts, Attempt{
+		Time:     start.UTC(),
+		Status:   res.Status,
+		Error:    errString(res.Err),
+		Response: res.Response,
+		Duration: time.Since(start).Milliseconds(),
+	})
+	d.NextAttempt = time.Time{}
+	p.dirty = true
+
+	switch {
+	case res.Err == nil:
+		d.State = StateSucceeded
+	case res.Retry && len(d.Attempts) < p.config.MaxAttempts:
+		wait := p.backoff(len(d.Attempts))
+		if res.RetryAfter > wait {
+			wait = res.RetryAfter
+		}
+		d.State = StateRetrying
+		d.NextAttempt = time.Now().UTC().Add(wait)
+	default:
+		d.State = StateFailed
+		log.Printf("[outgoing-webhooks] delivery %s to %s failed: %v", d.ID, d.Webhook, res.Err)
+	}
+}
+
+// retryLoop hands deliveries back to their senders once their retry is
+// due, and saves the state every minute, until shutdown
+func (p *OutgoingWebhooksPlugin) retryLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	lastSave := time.Now()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.retryDue()
+		if time.Since(lastSave) >= time.Minute {
+			if err := p.save(); err != nil {
+				log.Printf("[outgoing-webhooks] failed to save data: %v", err)
+			}
+			lastSave = time.Now()
+		}
+	}
+}
+
+// retryDue queues every delivery whose retry is due
+func (p *OutgoingWebhooksPlugin) retryDue() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for _, d := range p.deliveries {
+		if d.State != StateRetrying || d.NextAttempt.After(now) {
+			continue
+		}
+		wh := p.webhookByID(d.WebhookID)
+		switch {
+		case wh == nil:
+			d.State = StateFailed
+			d.Error = "Webhook was removed"
+		case !wh.Enabled:
+			d.State = StateFailed
+			d.Error = "Webhook is disabled"
+		default:
+			s := p.senderFor(d.WebhookID)
+			select {
+			case s.queue <- d:
+				d.State = StateQueued
+			default:
+				// Still behind, try again on the next tick
+				continue
+			}
+		}
+		d.NextAttempt = time.Time{}
+		p.dirty = true
+	}
+}
+
+// validHeaderName reports whether s may be used as a header name
+func validHeaderName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > 127 || !(r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// apply validates a request and copies it onto the webhook
+func (req *WebhookRequest) apply(wh *Webhook) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	target := strings.TrimSpace(req.URL)
+	if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http(s) URL")
+	}
+	method := strings.ToUpper(strings.TrimSpace(req.Method))
+	if method == "" {
+		method = http.MethodPost
+	}
+	if method != http.MethodPost && method != http.MethodPut && method != http.MethodPatch {
+		return fmt.Errorf("method must be POST, PUT or PATCH")
+	}
+	events := make([]string, 0, len(req.Events))
+	for _, e := range req.Events {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	severity := req.MinSeverity
+	if severity == "" {
+		severity = notify.SeverityInfo
+	}
+	if _, ok := notify.SeverityRank[severity]; !ok {
+		return fmt.Errorf("min_severity must be info, warning or critical")
+	}
+	contentType := strings.TrimSpace(req.ContentType)
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	headers := make(map[string]string, len(req.Headers))
+	for k, v := range req.Headers {
+		k = strings.TrimSpace(k)
+		if !validHeaderName(k) {
+			return fmt.Errorf("invalid header name %q", k)
+		}
+		if strings.EqualFold(k, "Content-Type") || strings.HasPrefix(strings.ToUpper(k), "X-UWP-") {
+			return fmt.Errorf("header %s is set by the plugin", k)
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("header %s must be on one line", k)
+		}
+		headers[k] = v
+	}
+	if _, err := renderPayload(req.Template, contentType, sampleEvent, name, "sample"); err != nil {
+		return fmt.Errorf("template: %v", err)
+	}
+
+	wh.Name = name
+	wh.URL = target
+	wh.Method = method
+	wh.Events = events
+	wh.MinSeverity = severity
+	wh.ContentType = contentType
+	wh.Headers = headers
+	wh.Template = req.Template
+	if req.Secret != nil {
+		wh.Secret = *req.Secret
+	}
+	if req.Enabled != nil {
+		wh.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// handleStatus reports the event sources, queues and deliveries by state
+func (p *OutgoingWebhooksPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	queues := make(map[string]int, len(p.senders))
+	for id, s := range p.senders {
+		queues[id] = len(s.queue)
+	}
+	states := map[string]int{StateQueued: 0, StateRetrying: 0, StateSucceeded: 0, StateFailed: 0}
+	for _, d := range p.deliveries {
+		states[d.State]++
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"log_stream":     p.streamOK,
+		"notify_bans":    p.config.NotifyBans,
+		"events_enabled": p.config.EventsToken != "",
+		"received":       p.received,
+		"queues":         queues,
+		"deliveries":     states,
+	})
+}
+
+// handleListWebhooks returns all webhooks, with their secrets hidden
+func (p *OutgoingWebhooksPlugin) handleListWebhooks(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Webhook, 0, len(p.webhooks))
+	for _, wh := range p.webhooks {
+		list = append(list, wh.redacted())
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": list})
+}
+
+// handleCreateWebhook adds a webhook
+func (p *OutgoingWebhooksPlugin) handleCreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	wh := &Webhook{
+		ID:        newID(),
+		Enabled:   true,
+		CreatedBy: access.Actor(c),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := req.apply(wh); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	p.webhooks = append(p.webhooks, wh)
+	p.dirty = true
+	redacted := wh.redacted()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, redacted)
+}
+
+// handleUpdateWebhook changes a webhook
+func (p *OutgoingWebhooksPlugin) handleUpdateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	wh := p.webhookByID(c.Param("id"))
+	if wh == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	updated := *wh
+	if err := req.apply(&updated); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	*wh = updated
+	if !wh.Enabled {
+		p.stopSender(wh.ID, "Webhook is disabled")
+	}
+	p.dirty = true
+	redacted := wh.redacted()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, redacted)
+}
+
+// handleDeleteWebhook removes a webhook and fails its waiting deliveries
+func (p *OutgoingWebhooksPlugin) handleDeleteWebhook(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	found := false
+	for i, wh := range p.webhooks {
+		if wh.ID == id {
+			p.webhooks = append(p.webhooks[:i], p.webhooks[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if found {
+		p.stopSender(id, "Webhook was removed")
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// handleTestWebhook sends a test event to a webhook straight away, once,
+// and reports the result. The attempt is kept in the delivery log.
+func (p *OutgoingWebhooksPlugin) handleTestWebhook(c *gin.Context) {
+	ev := notify.Event{
+		Source:    "outgoing-webhooks",
+		Type:      EventTest,
+		Severity:  notify.SeverityInfo,
+		Title:     "Test message",
+		Message:   fmt.Sprintf("Sent by %s from the web panel", access.Actor(c)),
+		Timestamp: time.Now().UTC(),
+	}
+
+	p.mu.Lock()
+	wh := p.webhookByID(c.Param("id"))
+	if wh == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	d := &Delivery{
+		ID:          newID(),
+		WebhookID:   wh.ID,
+		Webhook:     wh.Name,
+		Event:       ev.Type,
+		Source:      ev.Source,
+		ContentType: wh.ContentType,
+		State:       StateQueued,
+		Attempts:    make([]Attempt, 0),
+		CreatedAt:   ev.Timestamp,
+	}
+	body, err := renderPayload(wh.Template, wh.ContentType, ev, wh.Name, d.ID)
+	if err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template: " + err.Error()})
+		return
+	}
+	d.Body = body
+	req := request{
+		Method:      wh.Method,
+		URL:         wh.URL,
+		ContentType: wh.ContentType,
+		Headers:     wh.Headers,
+		Secret:      wh.Secret,
+		Event:       d.Event,
+		Delivery:    d.ID,
+		Body:        body,
+	}
+	timeout := time.Duration(p.config.Timeout) * time.Second
+	p.addDelivery(d)
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	start := time.Now()
+	res := send(ctx, req)
+	// A test is not retried
+	res.Retry = false
+	p.recordAttempt(d, start, res)
+
+	if res.Err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": res.Err.Error(), "delivery": d.ID, "status": res.Status, "response": res.Response})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test event delivered", "delivery": d.ID, "status": res.Status, "response": res.Response})
+}
+
+// handlePreview renders a template against an event, the sample ban when
+// none is given, without sending anything
+func (p *OutgoingWebhooksPlugin) handlePreview(c *gin.Context) {
+	var req struct {
+		Template    string        `json:"template"`
+		ContentType string        `json:"content_type"`
+		Event       *notify.Event `json:"event"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	ev := sampleEvent
+	if req.Event != nil {
+		ev = *req.Event
+		if err := ev.Normalize(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	contentType := req.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	body, err := renderPayload(req.Template, contentType, ev, "preview", "preview")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"body": body})
+}
+
+// handleListDeliveries returns recent deliveries, newest first, optionally
+// of one webhook or in one state
+func (p *OutgoingWebhooksPlugin) handleListDeliveries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > maxDeliveries {
+		limit = 100
+	}
+	webhook := c.Query("webhook_id")
+	state := c.Query("state")
+
+	p.mu.RLock()
+	list := make([]DeliverySummary, 0, limit)
+	for i := len(p.deliveries) - 1; i >= 0 && len(list) < limit; i-- {
+		d := p.deliveries[i]
+		if (webhook != "" && d.WebhookID != webhook) || (state != "" && d.State != state) {
+			continue
+		}
+		list = append(list, d.summary())
+	}
+	p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": list})
+}
+
+// handleGetDelivery returns a delivery with its body and every attempt
+func (p *OutgoingWebhooksPlugin) handleGetDelivery(c *gin.Context) {
+	p.mu.RLock()
+	d := p.deliveryByID(c.Param("id"))
+	var copied Delivery
+	if d != nil {
+		copied = d.clone()
+	}
+	p.mu.RUnlock()
+
+	if d == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	c.JSON(http.StatusOK, copied)
+}
+
+// handleRedeliver sends the body of a past delivery again as a new
+// delivery, signed afresh with the webhook's current settings
+func (p *OutgoingWebhooksPlugin) handleRedeliver(c *gin.Context) {
+	p.mu.Lock()
+	orig := p.deliveryByID(c.Param("id"))
+	if orig == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	if orig.Body == "" {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Delivery has no payload to send"})
+		return
+	}
+	wh := p.webhookByID(orig.WebhookID)
+	if wh == nil || !wh.Enabled {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Webhook was removed or is disabled"})
+		return
+	}
+	d := &Delivery{
+		ID:           newID(),
+		WebhookID:    wh.ID,
+		Webhook:      wh.Name,
+		Event:        orig.Event,
+		Source:       orig.Source,
+		ContentType:  orig.ContentType,
+		Body:         orig.Body,
+		Attempts:     make([]Attempt, 0),
+		RedeliveryOf: orig.ID,
+		CreatedAt:    time.Now().UTC(),
+	}
+	p.enqueue(d)
+	p.addDelivery(d)
+	summary := d.summary()
+	p.mu.Unlock()
+
+	log.Printf("[outgoing-webhooks] %s redelivered %s to %s", access.Actor(c), orig.ID, wh.Name)
+	c.JSON(http.StatusAccepted, summary)
+}
+
+// handleEvents accepts an alert from another plugin or script, sent with
+// the events token in an X-Events-Token header
+func (p *OutgoingWebhooksPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+
+	p.dispatch(ev)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *OutgoingWebhooksPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *OutgoingWebhooksPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.MaxAttempts < 1 || newConfig.MaxAttempts > 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_attempts must be between 1 and 10"})
+		return
+	}
+	if newConfig.RetryDelay < 5 || newConfig.RetryDelay > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retry_delay must be between 5 and 3600 seconds"})
+		return
+	}
+	if newConfig.Timeout < 1 || newConfig.Timeout > 60 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be between 1 and 60 seconds"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *OutgoingWebhooksPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *OutgoingWebhooksPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,233 @@
+package outgoingwebhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Limits on payloads and what is kept of responses
+const (
+	maxPayload  = 64 << 10
+	maxResponse = 1 << 10
+)
+
+// Headers sent with every delivery
+const (
+	headerEvent     = "X-UWP-Event"
+	headerDelivery  = "X-UWP-Delivery"
+	headerTimestamp = "X-UWP-Timestamp"
+	headerSignature = "X-UWP-Signature"
+)
+
+// httpClient does not follow redirects, so a moved endpoint shows up as a
+// failed delivery rather than a silently dropped body
+var httpClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// templateData is what a payload template is executed with: the fields of
+// the event, plus the webhook and delivery
+type templateData struct {
+	Source    string
+	Type      string
+	Severity  string
+	Title     string
+	Message   string
+	Timestamp time.Time
+	Data      map[string]interface{}
+	Webhook   string
+	Delivery  string
+}
+
+// templateFuncs are available to payload templates
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join": func(sep string, v interface{}) string {
+		switch list := v.(type) {
+		case []string:
+			return strings.Join(list, sep)
+		case []interface{}:
+			parts := make([]string, 0, len(list))
+			for _, item := range list {
+				parts = append(parts, fmt.Sprint(item))
+			}
+			return strings.Join(parts, sep)
+		case nil:
+			return ""
+		default:
+			return fmt.Sprint(list)
+		}
+	},
+	"default": func(def string, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	"unix": func(t time.Time) int64 {
+		return t.Unix()
+	},
+}
+
+// sampleEvent is rendered to check templates before they are saved
+var sampleEvent = notify.Event{
+	Source:    "outgoing-webhooks",
+	Type:      notify.EventBanAdded,
+	Severity:  notify.SeverityInfo,
+	Title:     "G-Line added",
+	Message:   "*@192.0.2.1 by Oper: Spamming",
+	Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	Data: map[string]interface{}{
+		"type":     "gline",
+		"mask":     "*@192.0.2.1",
+		"set_by":   "Oper",
+		"duration": "1d",
+		"reason":   "Spamming",
+	},
+}
+
+// renderPayload builds the body of a delivery. Without a template the
+// event itself is sent as JSON. Payloads declared as JSON must be valid
+// JSON.
+func renderPayload(tmpl, contentType string, ev notify.Event, webhook, delivery string) (string, error) {
+	var body []byte
+	if strings.TrimSpace(tmpl) == "" {
+		var err error
+		if body, err = json.Marshal(ev); err != nil {
+			return "", err
+		}
+	} else {
+		t, err := template.New("payload").Funcs(templateFuncs).Option("missingkey=zero").Parse(tmpl)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		err = t.Execute(&buf, templateData{
+			Source:    ev.Source,
+			Type:      ev.Type,
+			Severity:  ev.Severity,
+			Title:     ev.Title,
+			Message:   ev.Message,
+			Timestamp: ev.Timestamp,
+			Data:      ev.Data,
+			Webhook:   webhook,
+			Delivery:  delivery,
+		})
+		if err != nil {
+			return "", err
+		}
+		body = buf.Bytes()
+	}
+
+	if len(body) > maxPayload {
+		return "", fmt.Errorf("payload is %d bytes, more than the limit of %d", len(body), maxPayload)
+	}
+	if isJSON(contentType) && !json.Valid(body) {
+		return "", fmt.Errorf("payload is not valid JSON")
+	}
+	return string(body), nil
+}
+
+// isJSON reports whether a content type is JSON
+func isJSON(contentType string) bool {
+	ct := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	return ct == "application/json" || strings.HasSuffix(ct, "+json")
+}
+
+// sign returns the signature of a payload sent at ts: the hex HMAC-SHA256
+// of the timestamp, a dot and the body, keyed with the webhook's secret
+func sign(secret string, ts int64, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// request is one attempt at a delivery
+type request struct {
+	Method      string
+	URL         string
+	ContentType string
+	Headers     map[string]string
+	Secret      string
+	Event       string
+	Delivery    string
+	Body        string
+}
+
+// result is the outcome of one attempt. Retry is set when trying again
+// later may succeed, and RetryAfter when the endpoint said when.
+type result struct {
+	Status     int
+	Response   string
+	Err        error
+	Retry      bool
+	RetryAfter time.Duration
+}
+
+// send makes one attempt at a delivery. Any 2xx status is a success;
+// network errors, timeouts, 408, 429 and 5xx are worth retrying.
+func send(ctx context.Context, r request) result {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return result{Err: err}
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, strings.NewReader(r.Body))
+	if err != nil {
+		return result{Err: err}
+	}
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", r.ContentType)
+	req.Header.Set("User-Agent", "UnrealIRCd-Webpanel-Webhooks/1.0")
+	req.Header.Set(headerEvent, r.Event)
+	req.Header.Set(headerDelivery, r.Delivery)
+	if r.Secret != "" {
+		ts := time.Now().Unix()
+		req.Header.Set(headerTimestamp, strconv.FormatInt(ts, 10))
+		req.Header.Set(headerSignature, sign(r.Secret, ts, r.Body))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return result{Err: err, Retry: true}
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	res := result{Status: resp.StatusCode, Response: string(data)}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return res
+	}
+	res.Err = fmt.Errorf("endpoint returned %s", resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode >= 500:
+		res.Retry = true
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			res.RetryAfter = time.Duration(secs) * time.Second
+		}
+	}
+	return res
+}
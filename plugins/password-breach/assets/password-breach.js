@@ -0,0 +1,421 @@
+/**
+ * Password Breach Check Frontend Script
+ *
+ * Hashes panel passwords in the browser when they are used to log in or
+ * set, hands the hash to the plugin for a range API check, and keeps users
+ * with a breached password on the page where they can change it.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'password-breach';
+  const PLUGIN_NAME = 'Password Breach Check';
+  const PAGE_PATH = '/plugins/password-breach';
+  const API_BASE = '/api/plugin/password-breach';
+  const GATE_ID = 'pwbreach-gate';
+  const BANNER_ID = 'pwbreach-banner';
+
+  const originalFetch = window.fetch;
+  // Hashes of passwords seen in requests, waiting for a login to send them
+  // with. They are only kept in memory.
+  const pending = [];
+  let gateToken = null;
+  let status = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await originalFetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t && !t.startsWith('0001') ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('password-breach-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'password-breach-styles';
+    style.textContent = `
+      .pwbreach-gate {
+        position: fixed;
+        inset: 0;
+        z-index: 10000;
+        display: flex;
+        align-items: center;
+        justify-content: center;
+        background: var(--bg-primary, #11111b);
+      }
+      .pwbreach-box {
+        width: min(460px, 92vw);
+        display: flex;
+        flex-direction: column;
+        gap: 0.8rem;
+        padding: 1.5rem;
+        border-radius: 10px;
+        background: var(--bg-secondary, #1e1e2e);
+        border: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+      }
+      .pwbreach-box h2 { margin: 0; color: var(--text-primary, #cdd6f4); }
+      .pwbreach-banner {
+        position: fixed;
+        top: 0;
+        left: 0;
+        right: 0;
+        z-index: 9999;
+        padding: 0.5rem 1rem;
+        text-align: center;
+        background: var(--error, #f38ba8);
+        color: var(--bg-primary, #11111b);
+      }
+      .pwbreach-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .pwbreach-row { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .pwbreach-box button, .pwbreach-app button {
+        background: var(--accent, #89b4fa);
+        color: var(--bg-primary, #11111b);
+        border: none;
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+      }
+      .pwbreach-box button.pwbreach-plain, .pwbreach-app button.pwbreach-plain {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+      }
+      .pwbreach-table { width: 100%; border-collapse: collapse; }
+      .pwbreach-table th, .pwbreach-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+      }
+      .pwbreach-bad { color: var(--error, #f38ba8); }
+      .pwbreach-good { color: var(--success, #a6e3a1); }
+      .pwbreach-meta { font-size: 0.75rem; color: var(--text-muted, #6c7086); }
+      .pwbreach-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  // SHA-1 of a password as upper case hex, the form the range API uses
+  async function sha1Hex(password) {
+    const buf = await crypto.subtle.digest('SHA-1', new TextEncoder().encode(password));
+    return Array.from(new Uint8Array(buf)).map(b => b.toString(16).padStart(2, '0')).join('').toUpperCase();
+  }
+
+  // capture looks for a password in a successful request to the panel's
+  // own API and queues its hash
+  async function capture(input, init, res) {
+    if (!res.ok || !window.crypto || !crypto.subtle) return;
+    const url = new URL(typeof input === 'string' ? input : input.url, window.location.href);
+    if (url.origin !== window.location.origin || !url.pathname.startsWith('/api/') || url.pathname.startsWith('/api/plugin/')) return;
+    const method = ((init && init.method) || (input && input.method) || 'GET').toUpperCase();
+    if (!['POST', 'PUT', 'PATCH'].includes(method) || !init || typeof init.body !== 'string') return;
+
+    let body;
+    try {
+      body = JSON.parse(init.body);
+    } catch (e) {
+      return;
+    }
+    if (!body || typeof body !== 'object') return;
+    const password = body.new_password || body.newPassword || body.password;
+    if (typeof password !== 'string' || password === '') return;
+
+    const hash = await sha1Hex(password);
+    pending.push({
+      path: url.pathname,
+      username: typeof body.username === 'string' ? body.username : '',
+      source: url.pathname.includes('login') ? 'login' : 'set',
+      prefix: hash.slice(0, 5),
+      suffix: hash.slice(5),
+    });
+  }
+
+  window.fetch = async function(input, init) {
+    const res = await originalFetch.apply(this, arguments);
+    capture(input, init, res).catch(() => {});
+    return res;
+  };
+
+  // flushPending sends queued hashes once there is a login to send them
+  // with, keeping only those posted to one of the configured paths
+  async function flushPending() {
+    if (pending.length === 0 || !localStorage.getItem('token')) return;
+    const entries = pending.splice(0, pending.length);
+    try {
+      const s = await api('GET', '/status');
+      for (const entry of entries) {
+        if (!s.password_paths.some(p => entry.path.startsWith(p))) continue;
+        // A login checks the password of whoever just logged in
+        const username = entry.source === 'login' ? '' : entry.username;
+        await api('POST', '/check', { username, prefix: entry.prefix, suffix: entry.suffix, source: entry.source }).catch(() => {});
+      }
+    } catch (e) {
+      return;
+    }
+    gateToken = null;
+  }
+
+  function logout() {
+    localStorage.removeItem('token');
+    window.location.href = '/login';
+  }
+
+  function removeGate() {
+    const gate = document.getElementById(GATE_ID);
+    if (gate) gate.remove();
+    const banner = document.getElementById(BANNER_ID);
+    if (banner) banner.remove();
+  }
+
+  function breachText(s) {
+    return s.count > 0
+      ? `Your panel password appears in ${s.count.toLocaleString()} known data breaches.`
+      : 'An administrator asked you to change your panel password.';
+  }
+
+  function showGate(s) {
+    injectStyles();
+    const onPasswordPage = window.location.pathname.startsWith(s.password_page);
+
+    if (onPasswordPage) {
+      const gate = document.getElementById(GATE_ID);
+      if (gate) gate.remove();
+      if (document.getElementById(BANNER_ID)) return;
+      const banner = document.createElement('div');
+      banner.id = BANNER_ID;
+      banner.className = 'pwbreach-banner';
+      banner.textContent = `${breachText(s)} Choose a new one that you do not use anywhere else.`;
+      document.body.appendChild(banner);
+      return;
+    }
+
+    const banner = document.getElementById(BANNER_ID);
+    if (banner) banner.remove();
+    if (document.getElementById(GATE_ID)) return;
+    const gate = document.createElement('div');
+    gate.id = GATE_ID;
+    gate.className = 'pwbreach-gate';
+    gate.innerHTML = `
+      <div class="pwbreach-box">
+        <h2>Change your password</h2>
+        <p>${escapeHtml(breachText(s))}</p>
+        <p>Anyone can try passwords from breaches against your account. Choose a new password that you do not use anywhere else before you continue.</p>
+        <button data-action="change">Change password</button>
+        <button class="pwbreach-plain" data-action="logout">Log out</button>
+      </div>
+    `;
+    document.body.appendChild(gate);
+    gate.querySelector('[data-action="change"]').addEventListener('click', () => {
+      gate.remove();
+      window.history.pushState({}, '', s.password_page);
+      window.dispatchEvent(new PopStateEvent('popstate'));
+    });
+    gate.querySelector('[data-action="logout"]').addEventListener('click', logout);
+  }
+
+  // checkGate asks the server whether this login has to change its
+  // password, once per token and again after every check
+  async function checkGate() {
+    const token = localStorage.getItem('token');
+    if (!token || window.location.pathname.startsWith('/login')) {
+      gateToken = null;
+      status = null;
+      removeGate();
+      return;
+    }
+    if (token !== gateToken) {
+      gateToken = token;
+      try {
+        status = await api('GET', '/status');
+      } catch (e) {
+        gateToken = null;
+        return;
+      }
+    }
+    if (status && status.must_rotate) {
+      showGate(status);
+    } else {
+      removeGate();
+    }
+  }
+
+  async function loadAccount(container) {
+    const body = container.querySelector('#pwbreach-account');
+    try {
+      const s = await api('GET', '/status');
+      body.innerHTML = !s.checked
+        ? '<p>Your password has not been checked yet. It is checked the next time you log in or change it.</p>'
+        : `
+          <p class="${s.count > 0 ? 'pwbreach-bad' : 'pwbreach-good'}">
+            ${s.count > 0 ? `Your password appears in ${s.count.toLocaleString()} known data breaches.` : 'Your password was not found in any known data breach.'}
+          </p>
+          <div class="pwbreach-meta">Last checked ${formatTime(s.checked_at)}</div>
+        `;
+      if (!s.confirming) {
+        body.innerHTML += '<div class="pwbreach-error">The panel does not run the plugin request hook, so password changes cannot be confirmed. Only an admin can lift a required change.</div>';
+      }
+      return s;
+    } catch (e) {
+      body.innerHTML = `<div class="pwbreach-error">${escapeHtml(e.message)}</div>`;
+      return null;
+    }
+  }
+
+  async function loadUsers(container) {
+    const body = container.querySelector('#pwbreach-users');
+    try {
+      const data = await api('GET', '/users');
+      body.innerHTML = `
+        <div class="pwbreach-row">
+          <button id="pwbreach-recheck">Check all now</button>
+          <span class="pwbreach-meta">Last scheduled check: ${formatTime(data.last_run) || 'not yet'}</span>
+          ${data.last_error ? `<span class="pwbreach-error">${escapeHtml(data.last_error)}</span>` : ''}
+        </div>
+        ${data.users.length === 0 ? '<p>No passwords checked yet.</p>' : `
+          <table class="pwbreach-table">
+            <thead><tr><th>User</th><th>Breaches</th><th>Must change</th><th>Password recorded</th><th>Last checked</th><th></th></tr></thead>
+            <tbody>
+              ${data.users.map(u => `
+                <tr>
+                  <td>${escapeHtml(u.username)}${u.exempt ? ' <span class="pwbreach-meta">(exempt)</span>' : ''}</td>
+                  <td class="${u.count > 0 ? 'pwbreach-bad' : 'pwbreach-good'}">${u.count.toLocaleString()}</td>
+                  <td>${u.must_rotate ? `yes <span class="pwbreach-meta">since ${formatTime(u.flagged_at)} (${escapeHtml(u.flagged_by)})</span>` : 'no'}</td>
+                  <td>${formatTime(u.recorded_at)} <span class="pwbreach-meta">${escapeHtml(u.source)}</span></td>
+                  <td>${formatTime(u.checked_at)}${u.check_error ? `<div class="pwbreach-error">${escapeHtml(u.check_error)}</div>` : ''}</td>
+                  <td class="pwbreach-row">
+                    ${u.must_rotate
+                      ? `<button class="pwbreach-plain" data-unflag="${escapeHtml(u.username)}">Lift</button>`
+                      : `<button class="pwbreach-plain" data-flag="${escapeHtml(u.username)}">Require change</button>`}
+                    <button class="pwbreach-plain" data-forget="${escapeHtml(u.username)}">Forget</button>
+                  </td>
+                </tr>
+              `).join('')}
+            </tbody>
+          </table>
+        `}
+      `;
+
+      const act = async (method, path, confirmText) => {
+        if (confirmText && !confirm(confirmText)) return;
+        try {
+          await api(method, path);
+          loadUsers(container);
+        } catch (e) {
+          alert(e.message);
+        }
+      };
+      body.querySelector('#pwbreach-recheck').addEventListener('click', () => act('POST', '/recheck'));
+      body.querySelectorAll('[data-flag]').forEach(btn => {
+        btn.addEventListener('click', () => act('POST', `/users/${encodeURIComponent(btn.dataset.flag)}/flag`));
+      });
+      body.querySelectorAll('[data-unflag]').forEach(btn => {
+        btn.addEventListener('click', () => act('DELETE', `/users/${encodeURIComponent(btn.dataset.unflag)}/flag`,
+          `Let ${btn.dataset.unflag} keep using their current password?`));
+      });
+      body.querySelectorAll('[data-forget]').forEach(btn => {
+        btn.addEventListener('click', () => act('DELETE', `/users/${encodeURIComponent(btn.dataset.forget)}`,
+          `Remove the record of ${btn.dataset.forget}? Their password is checked again at their next login.`));
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="pwbreach-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="pwbreach-app" data-plugin="${PLUGIN_ID}">
+        <h3>Your password</h3>
+        <div id="pwbreach-account">Loading...</div>
+        <div id="pwbreach-admin"></div>
+      </div>
+    `;
+
+    loadAccount(container).then(s => {
+      if (!s || !s.admin) return;
+      container.querySelector('#pwbreach-admin').innerHTML = `
+        <h3>Users</h3>
+        <div id="pwbreach-users">Loading...</div>
+      `;
+      loadUsers(container);
+    });
+    return true;
+  }
+
+  function cleanup() {
+    window.fetch = originalFetch;
+    removeGate();
+    const style = document.getElementById('password-breach-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    checkGate();
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection, which also catches a fresh login and sends
+  // the hashes it queued
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    flushPending().then(checkGate);
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,108 @@
+package passwordbreach
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// maxBody is the most of a request body read when looking for a password
+const maxBody = 64 << 10
+
+// observe watches the panel's own login and password requests on the
+// panel's request hook. When one to a password_paths route succeeds, the
+// password in it is hashed on the server and checked like one sent by the
+// browser, except that it counts as confirmed: a new password set this way
+// clears a rotation flag. The password is the authenticated user's own
+// unless an admin names another user in the request.
+func (p *PasswordBreachPlugin) observe(c *gin.Context, next func()) {
+	switch c.Request.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		next()
+		return
+	}
+
+	path := c.Request.URL.Path
+	if !p.passwordPath(path) || c.Request.Body == nil {
+		next()
+		return
+	}
+	body, _ := io.ReadAll(io.LimitReader(c.Request.Body, maxBody+1))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+	next()
+
+	if status := c.Writer.Status(); status < 200 || status > 299 || len(body) > maxBody {
+		return
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return
+	}
+	password := ""
+	for _, k := range []string{"new_password", "newPassword", "password"} {
+		if v, ok := fields[k].(string); ok && v != "" {
+			password = v
+			break
+		}
+	}
+	if password == "" {
+		return
+	}
+
+	source := SourceSet
+	if strings.Contains(path, "login") {
+		source = SourceLogin
+	}
+	named, _ := fields["username"].(string)
+	named = strings.TrimSpace(named)
+	actor := c.GetString("username")
+	target := actor
+	switch {
+	case actor == "" && source == SourceLogin:
+		// A login is not authenticated yet, but it succeeded, so the
+		// password is the named user's
+		target, actor = named, named
+	case named != "" && !strings.EqualFold(named, actor):
+		// Only admins record passwords of other users
+		if !access.IsAdmin(actor) {
+			return
+		}
+		target = named
+	}
+	if target == "" {
+		return
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	// The range API can take a while; the response has been written
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		p.check(ctx, target, actor, hash[:prefixLen], hash[prefixLen:], source, true)
+	}()
+}
+
+// passwordPath reports whether path is one of the configured password_paths
+func (p *PasswordBreachPlugin) passwordPath(path string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, prefix := range lists.Split(p.config.PasswordPaths) {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package passwordbreach
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// Lengths of the two parts of a hex SHA-1 hash
+const (
+	prefixLen = 5
+	suffixLen = 35
+)
+
+// rangeClient queries a Pwned Passwords compatible range API
+type rangeClient struct {
+	url  string
+	http *http.Client
+}
+
+// newRangeClient creates a client for the range API at url, to which the
+// five character hash prefix is appended
+func newRangeClient(url string) *rangeClient {
+	return &rangeClient{url: url, http: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// Range returns the hash suffixes known for prefix with the number of
+// breaches each appeared in. Padding entries, which have a count of 0, are
+// left out.
+func (r *rangeClient) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url+prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Padding hides how many suffixes the prefix really has from anyone
+	// watching the response sizes
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "uwp-plugins-password-breach")
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("range API returned %s", resp.Status)
+	}
+
+	suffixes := make(map[string]int)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		suffix, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n == 0 {
+			continue
+		}
+		suffixes[strings.ToUpper(suffix)] = n
+	}
+	return suffixes, scanner.Err()
+}
+
+// validHash reports whether s is an upper or lower case hex string of n
+// characters
+func validHash(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := hex.DecodeString(s + strings.Repeat("0", n%2))
+	return err == nil
+}
+
+// digest returns the keyed hash of a suffix that is stored instead of the
+// suffix itself, so the data file alone does not give away password hashes
+func digest(key []byte, suffix string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.ToUpper(suffix)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// lookupDigest returns the breach count of the suffix with the given
+// digest, or 0 when it is not among the suffixes
+func lookupDigest(key []byte, suffixes map[string]int, d string) int {
+	for suffix, count := range suffixes {
+		if hmac.Equal([]byte(digest(key, suffix)), []byte(d)) {
+			return count
+		}
+	}
+	return 0
+}
+
+// loadKey reads the digest key from path, creating a new random key when
+// there is none yet
+func loadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid key in %s", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
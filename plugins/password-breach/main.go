@@ -0,0 +1,673 @@
+// Password Breach Check Plugin for UnrealIRCd Web Panel
+// Checks panel passwords against Have I Been Pwned when they are used or
+// set, and again on a schedule, and makes users with breached passwords
+// change them
+
+package passwordbreach
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/requesthook"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Where a password check came from
+const (
+	SourceLogin = "login"
+	SourceSet   = "set"
+)
+
+// PasswordBreachPlugin implements the Plugin interface
+type PasswordBreachPlugin struct {
+	config    Config
+	key       []byte
+	users     map[string]*UserRecord
+	lastRun   time.Time
+	lastError string
+	dirty     bool
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	DataDir       string `json:"data_dir"`
+	APIURL        string `json:"api_url"`
+	MinCount      int    `json:"min_count"`
+	RecheckHours  int    `json:"recheck_hours"`
+	Enforce       bool   `json:"enforce"`
+	PasswordPage  string `json:"password_page"`
+	PasswordPaths string `json:"password_paths"`
+	ExemptUsers   string `json:"exempt_users"`
+}
+
+// UserRecord is the breach state of a panel user's current password. The
+// suffix of its hash is only kept as a keyed digest.
+type UserRecord struct {
+	Username   string     `json:"username"`
+	Prefix     string     `json:"prefix"`
+	Digest     string     `json:"digest"`
+	Source     string     `json:"source"`
+	RecordedAt time.Time  `json:"recorded_at"`
+	RecordedBy string     `json:"recorded_by"`
+	CheckedAt  time.Time  `json:"checked_at"`
+	CheckError string     `json:"check_error,omitempty"`
+	Count      int        `json:"count"`
+	MustRotate bool       `json:"must_rotate"`
+	FlaggedAt  *time.Time `json:"flagged_at,omitempty"`
+	FlaggedBy  string     `json:"flagged_by,omitempty"`
+}
+
+// UserSummary is what admins see of a user, without the hash
+type UserSummary struct {
+	Username   string     `json:"username"`
+	Source     string     `json:"source"`
+	RecordedAt time.Time  `json:"recorded_at"`
+	CheckedAt  time.Time  `json:"checked_at"`
+	CheckError string     `json:"check_error,omitempty"`
+	Count      int        `json:"count"`
+	MustRotate bool       `json:"must_rotate"`
+	FlaggedAt  *time.Time `json:"flagged_at,omitempty"`
+	FlaggedBy  string     `json:"flagged_by,omitempty"`
+	Exempt     bool       `json:"exempt"`
+}
+
+// CheckRequest is a password hash sent by the frontend, split the same way
+// as for the range API
+type CheckRequest struct {
+	Username string `json:"username"`
+	Prefix   string `json:"prefix"`
+	Suffix   string `json:"suffix"`
+	Source   string `json:"source"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Users map[string]*UserRecord `json:"users"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &PasswordBreachPlugin{
+		config: Config{
+			DataDir:       "data/plugins/password-breach",
+			APIURL:        "https://api.pwnedpasswords.com/range/",
+			MinCount:      1,
+			RecheckHours:  168,
+			Enforce:       true,
+			PasswordPage:  "/settings",
+			PasswordPaths: "/api/auth,/api/users,/api/profile,/api/me",
+		},
+		users: make(map[string]*UserRecord),
+	}
+}
+
+// Info returns plugin metadata
+func (p *PasswordBreachPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Password Breach Check",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Checks panel passwords against Have I Been Pwned and forces breached ones to be changed",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *PasswordBreachPlugin) Init() error {
+	p.mu.Lock()
+	key, err := loadKey(filepath.Join(p.config.DataDir, "digest.key"))
+	if err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	p.key = key
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[password-breach] failed to load data: %v", err)
+	}
+	if data.Users != nil {
+		p.users = data.Users
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "password-breach-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		breached, rotate := 0, 0
+		for _, u := range p.users {
+			if u.Count > 0 {
+				breached++
+			}
+			if u.MustRotate {
+				rotate++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Breached Passwords",
+			Icon:  "KeyRound",
+			Content: map[string]interface{}{
+				"checked_users":  len(p.users),
+				"breached":       breached,
+				"must_rotate":    rotate,
+				"last_recheck":   p.lastRun,
+				"recheck_failed": p.lastError != "",
+			},
+			Order: 60,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.recheckLoop()
+
+	requesthook.Register("password-breach", 60, p.observe)
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *PasswordBreachPlugin) Shutdown() error {
+	requesthook.Unregister("password-breach")
+
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *PasswordBreachPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/password-breach")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/check", p.handleCheck)
+		plugin.GET("/users", p.handleListUsers)
+		plugin.POST("/users/:username/flag", p.handleFlag)
+		plugin.DELETE("/users/:username/flag", p.handleUnflag)
+		plugin.DELETE("/users/:username", p.handleForget)
+		plugin.POST("/recheck", p.handleRecheck)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *PasswordBreachPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "password-breach.json")
+}
+
+// save persists the state if it changed
+func (p *PasswordBreachPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Users: p.users}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// currentUser returns the logged in panel user or writes an error response
+func currentUser(c *gin.Context) (string, bool) {
+	name := c.GetString("username")
+	if name == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+		return "", false
+	}
+	return name, true
+}
+
+// mustRotate reports whether a user has to change their password before
+// using the panel. Caller must hold p.mu.
+func (p *PasswordBreachPlugin) mustRotate(u *UserRecord) bool {
+	return u.MustRotate && p.config.Enforce && !lists.ContainsFold(lists.Split(p.config.ExemptUsers), u.Username)
+}
+
+// flag marks a user's password for rotation. Caller must hold p.mu.
+func flag(u *UserRecord, by string) {
+	if u.MustRotate {
+		return
+	}
+	now := time.Now().UTC()
+	u.MustRotate = true
+	u.FlaggedAt = &now
+	u.FlaggedBy = by
+}
+
+// recheckLoop checks stored passwords against the range API again every
+// recheck_hours until shutdown
+func (p *PasswordBreachPlugin) recheckLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.RecheckHours) * time.Hour
+		p.mu.RUnlock()
+		if interval > 0 {
+			p.recheck(time.Now().Add(-interval))
+		}
+		if err := p.save(); err != nil {
+			log.Printf("[password-breach] failed to save data: %v", err)
+		}
+	}
+}
+
+// recheck checks every password last checked before cutoff, and those whose
+// last check failed. Each prefix is only fetched once.
+func (p *PasswordBreachPlugin) recheck(cutoff time.Time) {
+	p.mu.RLock()
+	client := newRangeClient(p.config.APIURL)
+	key := p.key
+	due := make(map[string][]string)
+	for name, u := range p.users {
+		if u.CheckedAt.Before(cutoff) || u.CheckError != "" {
+			due[u.Prefix] = append(due[u.Prefix], name)
+		}
+	}
+	p.mu.RUnlock()
+
+	var lastErr error
+	for prefix, names := range due {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		suffixes, err := client.Range(ctx, prefix)
+		cancel()
+		if err != nil {
+			lastErr = err
+		}
+
+		p.mu.Lock()
+		for _, name := range names {
+			u, ok := p.users[name]
+			if !ok || u.Prefix != prefix {
+				continue
+			}
+			u.CheckedAt = time.Now().UTC()
+			if err != nil {
+				u.CheckError = err.Error()
+				continue
+			}
+			u.CheckError = ""
+			u.Count = lookupDigest(key, suffixes, u.Digest)
+			if u.Count >= p.config.MinCount && u.Count > 0 && !u.MustRotate {
+				flag(u, "recheck")
+				log.Printf("[password-breach] password of %s now appears in %d breaches", u.Username, u.Count)
+			}
+		}
+		p.dirty = true
+		p.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	p.lastRun = time.Now().UTC()
+	p.lastError = ""
+	if lastErr != nil {
+		p.lastError = lastErr.Error()
+		log.Printf("[password-breach] recheck failed: %v", lastErr)
+	}
+	p.mu.Unlock()
+}
+
+// handleStatus tells the frontend whether the current user has to change
+// their password
+func (p *PasswordBreachPlugin) handleStatus(c *gin.Context) {
+	name, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	u, ok := p.users[strings.ToLower(name)]
+	if !ok {
+		u = &UserRecord{Username: name}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"username":       name,
+		"checked":        ok,
+		"checked_at":     u.CheckedAt,
+		"count":          u.Count,
+		"must_rotate":    p.mustRotate(u),
+		"password_page":  p.config.PasswordPage,
+		"password_paths": lists.Split(p.config.PasswordPaths),
+		"admin":          access.IsAdmin(name),
+		"confirming":     requesthook.Ran(c),
+	})
+}
+
+// handleCheck records a password that was just used or set and checks it
+// against the range API. Only admins can record passwords of other users.
+// The hash comes from the browser, so it can flag a password but never
+// clear a flag; that takes a change the server observed itself.
+func (p *PasswordBreachPlugin) handleCheck(c *gin.Context) {
+	actor, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req CheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.Prefix = strings.ToUpper(req.Prefix)
+	req.Suffix = strings.ToUpper(req.Suffix)
+	if !validHash(req.Prefix, prefixLen) || !validHash(req.Suffix, suffixLen) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prefix and suffix must be the two parts of a hex SHA-1 hash"})
+		return
+	}
+	if req.Source != SourceLogin {
+		req.Source = SourceSet
+	}
+	target := strings.TrimSpace(req.Username)
+	if target == "" {
+		target = actor
+	}
+	if !strings.EqualFold(target, actor) && !access.IsAdmin(actor) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only password check admins can check other users' passwords"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+	count, rotate, err := p.check(ctx, target, actor, req.Prefix, req.Suffix, req.Source, false)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Could not reach the range API: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"username": target, "count": count, "must_rotate": rotate})
+}
+
+// check looks a password hash up in the range API and records it for
+// target. Only a confirmed hash, one the server saw in a successful request
+// to the panel, replaces the password of a flagged user, and only a
+// confirmed change clears the flag. Hashes sent by the browser are never
+// confirmed.
+func (p *PasswordBreachPlugin) check(ctx context.Context, target, actor, prefix, suffix, source string, confirmed bool) (int, bool, error) {
+	p.mu.RLock()
+	client := newRangeClient(p.config.APIURL)
+	key := p.key
+	minCount := p.config.MinCount
+	p.mu.RUnlock()
+
+	suffixes, rangeErr := client.Range(ctx, prefix)
+
+	d := digest(key, suffix)
+
+	p.mu.Lock()
+	u, known := p.users[strings.ToLower(target)]
+	if !known {
+		u = &UserRecord{Username: target}
+		p.users[strings.ToLower(target)] = u
+	}
+	changed := u.Prefix != prefix || u.Digest != d
+	if changed && u.MustRotate && !confirmed {
+		// Keep the flagged password on record until the server sees it
+		// replaced, so a made up hash cannot pass for a new password
+		count := 0
+		if rangeErr == nil {
+			count = suffixes[suffix]
+		}
+		rotate := p.mustRotate(u)
+		p.mu.Unlock()
+		return count, rotate, rangeErr
+	}
+	u.Prefix, u.Digest, u.Source = prefix, d, source
+	if changed {
+		u.RecordedAt = time.Now().UTC()
+		u.RecordedBy = actor
+		// A new password is a rotation; the same one, or one only used to
+		// log in, is not
+		if confirmed && source == SourceSet {
+			u.MustRotate = false
+			u.FlaggedAt = nil
+			u.FlaggedBy = ""
+		}
+	}
+	u.CheckedAt = time.Now().UTC()
+	if rangeErr != nil {
+		u.CheckError = rangeErr.Error()
+	} else {
+		u.CheckError = ""
+		u.Count = suffixes[suffix]
+		if u.Count >= minCount && u.Count > 0 {
+			flag(u, source)
+		}
+	}
+	p.dirty = true
+	count, rotate := u.Count, p.mustRotate(u)
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[password-breach] failed to save data: %v", err)
+	}
+	if rangeErr != nil {
+		log.Printf("[password-breach] range API failed for %s: %v", target, rangeErr)
+		return 0, rotate, rangeErr
+	}
+	if count > 0 {
+		log.Printf("[password-breach] password of %s appears in %d breaches (%s)", target, count, source)
+	}
+	return count, rotate, nil
+}
+
+// handleListUsers returns the breach state of every checked user
+func (p *PasswordBreachPlugin) handleListUsers(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	exempt := lists.Split(p.config.ExemptUsers)
+	list := make([]UserSummary, 0, len(p.users))
+	for _, u := range p.users {
+		list = append(list, UserSummary{
+			Username:   u.Username,
+			Source:     u.Source,
+			RecordedAt: u.RecordedAt,
+			CheckedAt:  u.CheckedAt,
+			CheckError: u.CheckError,
+			Count:      u.Count,
+			MustRotate: u.MustRotate,
+			FlaggedAt:  u.FlaggedAt,
+			FlaggedBy:  u.FlaggedBy,
+			Exempt:     lists.ContainsFold(exempt, u.Username),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Username) < strings.ToLower(list[j].Username) })
+	c.JSON(http.StatusOK, gin.H{
+		"users":      list,
+		"last_run":   p.lastRun,
+		"last_error": p.lastError,
+	})
+}
+
+// handleFlag makes a user change their password, whether or not it was
+// found in a breach
+func (p *PasswordBreachPlugin) handleFlag(c *gin.Context) {
+	admin, ok := access.RequireAdmin(c)
+	if !ok {
+		return
+	}
+	target := c.Param("username")
+
+	p.mu.Lock()
+	u, found := p.users[strings.ToLower(target)]
+	if found {
+		flag(u, admin)
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	log.Printf("[password-breach] %s flagged the password of %s for rotation", admin, target)
+	c.JSON(http.StatusOK, gin.H{"message": "Password flagged for rotation"})
+}
+
+// handleUnflag lifts the rotation requirement of a user
+func (p *PasswordBreachPlugin) handleUnflag(c *gin.Context) {
+	admin, ok := access.RequireAdmin(c)
+	if !ok {
+		return
+	}
+	target := c.Param("username")
+
+	p.mu.Lock()
+	u, found := p.users[strings.ToLower(target)]
+	if found {
+		u.MustRotate = false
+		u.FlaggedAt = nil
+		u.FlaggedBy = ""
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	log.Printf("[password-breach] %s lifted the rotation requirement of %s", admin, target)
+	c.JSON(http.StatusOK, gin.H{"message": "Rotation requirement lifted"})
+}
+
+// handleForget removes what is stored about a user, for example after
+// their panel account was deleted
+func (p *PasswordBreachPlugin) handleForget(c *gin.Context) {
+	admin, ok := access.RequireAdmin(c)
+	if !ok {
+		return
+	}
+	target := c.Param("username")
+
+	p.mu.Lock()
+	_, found := p.users[strings.ToLower(target)]
+	delete(p.users, strings.ToLower(target))
+	p.dirty = true
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	log.Printf("[password-breach] %s removed the record of %s", admin, target)
+	c.JSON(http.StatusOK, gin.H{"message": "User removed"})
+}
+
+// handleRecheck checks every stored password again now
+func (p *PasswordBreachPlugin) handleRecheck(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+
+	p.recheck(time.Now())
+	if err := p.save(); err != nil {
+		log.Printf("[password-breach] failed to save data: %v", err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.lastError != "" {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Recheck failed: " + p.lastError})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Passwords checked"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *PasswordBreachPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *PasswordBreachPlugin) handleUpdateConfig(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if !strings.HasPrefix(newConfig.APIURL, "https://") && !strings.HasPrefix(newConfig.APIURL, "http://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "api_url must be an http or https URL"})
+		return
+	}
+	if newConfig.MinCount < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_count must be at least 1"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *PasswordBreachPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *PasswordBreachPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
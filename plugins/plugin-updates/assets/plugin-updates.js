@@ -0,0 +1,205 @@
+/**
+ * Plugin Updates Frontend Script
+ *
+ * Lists plugin updates from the catalog and stages them for the admin.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'plugin-updates';
+  const PLUGIN_NAME = 'Plugin Updates';
+  const PAGE_PATH = '/plugins/plugin-updates';
+  const API_BASE = '/api/plugin/plugin-updates';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path, options = {}) {
+    const res = await fetch(API_BASE + path, { ...options, headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function permissionList(u) {
+    const perms = u.permissions || [];
+    if (!perms.length) return '';
+    const added = new Set(u.new_permissions || []);
+    return `<div class="pup-desc">${perms.map(p => added.has(p)
+      ? `<span class="pup-warning" title="Not held by the installed version">${escapeHtml(p)} (new)</span>`
+      : escapeHtml(p)).join(', ')}</div>`;
+  }
+
+  function updateRow(u) {
+    const action = u.staged
+      ? `<button data-discard="${escapeHtml(u.id)}">Discard</button>`
+      : `<button data-stage="${escapeHtml(u.id)}">Stage</button>`;
+    return `
+      <tr>
+        <td><strong>${escapeHtml(u.name)}</strong><div class="pup-desc">${escapeHtml(u.description)}</div></td>
+        <td>${escapeHtml(u.installed)}</td>
+        <td>${escapeHtml(u.available)}${permissionList(u)}</td>
+        <td>${escapeHtml(u.min_panel_version || '-')}</td>
+        <td>${u.staged ? `Staged in <code>${escapeHtml(u.staged_path)}</code>${u.staged_signature ? `<br><small>Signed: ${escapeHtml(u.staged_signature)}</small>` : ''}` : 'Not staged'}</td>
+        <td>${action}</td>
+      </tr>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('plugin-updates-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'plugin-updates-styles';
+    style.textContent = `
+      .pup-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .pup-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .pup-table th, .pup-table td { text-align: left; padding: 0.5rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .pup-table td { color: var(--text-primary, #cdd6f4); }
+      .pup-desc { color: var(--text-secondary, #a6adc8); font-size: 0.8rem; margin-top: 0.2rem; }
+      .pup-app button {
+        background: var(--accent, #89b4fa);
+        color: #fff;
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.8rem;
+        cursor: pointer;
+      }
+      .pup-app button:disabled { opacity: 0.6; cursor: default; }
+      .pup-warning { color: var(--warning, #f9e2af); }
+      .pup-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#pup-body');
+    try {
+      const data = await api('/updates');
+      const checked = data.last_check ? new Date(data.last_check).toLocaleString() : 'never';
+
+      body.innerHTML = `
+        ${data.error ? `<div class="pup-error">Last check failed: ${escapeHtml(data.error)}</div>` : ''}
+        ${data.last_check && !data.error && !data.verified ? '<div class="pup-warning">Catalog and release signatures are not being checked. Set a public key in the plugin settings.</div>' : ''}
+        <div>
+          ${data.installed} plugins installed, ${data.catalog} in the catalog. Last checked: ${escapeHtml(checked)}${data.checking ? ' (checking now...)' : ''}
+        </div>
+        ${data.updates.length ? `
+          <table class="pup-table">
+            <thead>
+              <tr><th>Plugin</th><th>Installed</th><th>Available</th><th>Needs panel</th><th>Staged</th><th></th></tr>
+            </thead>
+            <tbody>${data.updates.map(updateRow).join('')}</tbody>
+          </table>
+          <div>Apply a staged update by replacing the plugin's directory with the staged one and restarting the panel.</div>
+        ` : '<div>All plugins are up to date.</div>'}
+      `;
+
+      body.querySelectorAll('[data-stage]').forEach(btn => {
+        btn.addEventListener('click', () => act(container, btn, `/updates/${encodeURIComponent(btn.dataset.stage)}/stage`, 'POST'));
+      });
+      body.querySelectorAll('[data-discard]').forEach(btn => {
+        btn.addEventListener('click', () => act(container, btn, `/staged/${encodeURIComponent(btn.dataset.discard)}`, 'DELETE'));
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="pup-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function act(container, btn, path, method) {
+    btn.disabled = true;
+    try {
+      await api(path, { method });
+    } catch (e) {
+      alert(e.message);
+    }
+    load(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="pup-app" data-plugin="${PLUGIN_ID}">
+        <div><button id="pup-check">Check now</button></div>
+        <div id="pup-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#pup-check').addEventListener('click', async (e) => {
+      e.target.disabled = true;
+      try {
+        await api('/check', { method: 'POST' });
+      } catch (err) {
+        alert(err.message);
+      }
+      setTimeout(() => {
+        e.target.disabled = false;
+        load(container);
+      }, 3000);
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('plugin-updates-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
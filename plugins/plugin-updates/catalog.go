@@ -0,0 +1,191 @@
+package pluginupdates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// maxCatalogSize limits how much of a catalog is read
+const maxCatalogSize = 8 << 20
+
+// Catalog is a plugin catalog as written by scripts/build-catalog.js
+type Catalog struct {
+	Version     int            `json:"version"`
+	GeneratedAt string         `json:"generated_at"`
+	KeyID       string         `json:"key_id"`
+	Plugins     []CatalogEntry `json:"plugins"`
+}
+
+// CatalogEntry is one plugin release in the catalog
+type CatalogEntry struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Version         string   `json:"version"`
+	Description     string   `json:"description"`
+	MinPanelVersion string   `json:"min_panel_version"`
+	Permissions     []string `json:"permissions"`
+	DownloadURL     string   `json:"download_url"`
+	SignatureURL    string   `json:"signature_url"`
+	Size            int64    `json:"size"`
+	SHA256          string   `json:"sha256"`
+}
+
+// Installed is a plugin found in the plugins directory
+type Installed struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Permissions []string `json:"permissions"`
+}
+
+// httpClient is used for catalog and archive downloads
+var httpClient = &http.Client{Timeout: 60 * time.Second}
+
+// fetch downloads a URL, reading at most limit bytes
+func fetch(ctx context.Context, url string, limit int64) ([]byte, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%s: larger than %d bytes", url, limit)
+	}
+	return body, nil
+}
+
+// fetchCatalog downloads the catalog and, when keys are given, checks its
+// detached signature at catalogURL + ".sig"
+func fetchCatalog(ctx context.Context, catalogURL string, keys []trustedKey) (*Catalog, bool, error) {
+	body, err := fetch(ctx, catalogURL, maxCatalogSize)
+	if err != nil {
+		return nil, false, err
+	}
+	verified := false
+	if len(keys) > 0 {
+		sig, err := fetch(ctx, catalogURL+".sig", 1024)
+		if err != nil {
+			return nil, false, fmt.Errorf("signature: %w", err)
+		}
+		if err := verifyDetached(body, sig, keys); err != nil {
+			return nil, false, err
+		}
+		verified = true
+	}
+
+	var cat Catalog
+	if err := json.Unmarshal(body, &cat); err != nil {
+		return nil, false, fmt.Errorf("catalog: %w", err)
+	}
+	return &cat, verified, nil
+}
+
+// scanInstalled reads the manifest of every plugin in a directory
+func scanInstalled(dir string) ([]Installed, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Installed, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name(), "plugin.json"))
+		if err != nil {
+			continue
+		}
+		var m Installed
+		if json.Unmarshal(data, &m) != nil || m.ID == "" || m.Version == "" {
+			continue
+		}
+		list = append(list, m)
+	}
+	return list, nil
+}
+
+// compareVersions compares two semantic versions, returning -1, 0 or 1.
+// A pre-release sorts before the release it leads up to.
+func compareVersions(a, b string) int {
+	coreA, preA, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	coreB, preB, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	partsA := strings.Split(coreA, ".")
+	partsB := strings.Split(coreB, ".")
+	for i := 0; i < 3; i++ {
+		if c := compareNumber(part(partsA, i), part(partsB, i)); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	idsA := strings.Split(preA, ".")
+	idsB := strings.Split(preB, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		if c := compareNumber(idsA[i], idsB[i]); c != 0 {
+			return c
+		}
+	}
+	return compareNumber(strconv.Itoa(len(idsA)), strconv.Itoa(len(idsB)))
+}
+
+// part returns the ith element of a version, or "0"
+func part(parts []string, i int) string {
+	if i < len(parts) {
+		return parts[i]
+	}
+	return "0"
+}
+
+// compareNumber compares numerically when both are numbers, and as text
+// otherwise, with numbers first
+func compareNumber(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
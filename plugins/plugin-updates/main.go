@@ -0,0 +1,494 @@
+// Plugin Updates Plugin for UnrealIRCd Web Panel
+// Compares installed plugin versions against the published catalog, and
+// can download and stage updates for the admin to apply
+
+package pluginupdates
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// PluginUpdatesPlugin implements the Plugin interface
+type PluginUpdatesPlugin struct {
+	config    Config
+	installed []Installed
+	catalog   map[string]CatalogEntry
+	staged    map[string]*Staged
+	verified  bool
+	lastCheck time.Time
+	lastError string
+	checking  bool
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	DataDir           string `json:"data_dir"`
+	PluginsDir        string `json:"plugins_dir"`
+	CatalogURL        string `json:"catalog_url"`
+	PublicKey         string `json:"public_key"`
+	RequireSignatures bool   `json:"require_signatures"`
+	CheckInterval     int    `json:"check_interval"`
+	AutoStage         bool   `json:"auto_stage"`
+	IgnorePlugins     string `json:"ignore_plugins"`
+}
+
+// Update is an installed plugin with a newer release in the catalog
+type Update struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Installed       string   `json:"installed"`
+	Available       string   `json:"available"`
+	Description     string   `json:"description"`
+	MinPanelVersion string   `json:"min_panel_version"`
+	Permissions     []string `json:"permissions"`
+	NewPermissions  []string `json:"new_permissions"`
+	DownloadURL     string   `json:"download_url"`
+	Staged          bool     `json:"staged"`
+	StagedPath      string   `json:"staged_path,omitempty"`
+	StagedSignature string   `json:"staged_signature,omitempty"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &PluginUpdatesPlugin{
+		config: Config{
+			DataDir:       "data/plugins/plugin-updates",
+			PluginsDir:    "plugins",
+			CatalogURL:    "https://github.com/ValwareIRC/uwp-plugins/releases/latest/download/catalog.json",
+			CheckInterval: 6,
+		},
+		catalog: make(map[string]CatalogEntry),
+		staged:  make(map[string]*Staged),
+	}
+}
+
+// Info returns plugin metadata
+func (p *PluginUpdatesPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Plugin Updates",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Checks installed plugins against the catalog and stages updates",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *PluginUpdatesPlugin) Init() error {
+	p.mu.Lock()
+	p.staged = scanStaged(p.stagingDir())
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "plugin-updates-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		updates := p.updates()
+		names := make([]string, 0, len(updates))
+		staged := 0
+		for _, u := range updates {
+			names = append(names, u.Name+" "+u.Available)
+			if u.Staged {
+				staged++
+			}
+		}
+		content := map[string]interface{}{
+			"updates": len(updates),
+			"staged":  staged,
+		}
+		if len(names) > 0 {
+			content["available"] = names
+		}
+		if p.lastError != "" {
+			content["status"] = "Check failed: " + p.lastError
+		} else if !p.lastCheck.IsZero() && !p.verified {
+			content["status"] = "Catalog signature not checked"
+		}
+		return plugins.DashboardCard{
+			Title:   "Plugin Updates",
+			Icon:    "PackageCheck",
+			Content: content,
+			Order:   90,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.checkLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *PluginUpdatesPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *PluginUpdatesPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/plugin-updates")
+	{
+		plugin.GET("/updates", p.handleUpdates)
+		plugin.POST("/check", p.handleCheck)
+		plugin.POST("/updates/:id/stage", p.handleStage)
+		plugin.DELETE("/staged/:id", p.handleDiscard)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// stagingDir returns where updates are unpacked
+func (p *PluginUpdatesPlugin) stagingDir() string {
+	return filepath.Join(p.config.DataDir, "staged")
+}
+
+// checkLoop checks for updates every check_interval hours until shutdown
+func (p *PluginUpdatesPlugin) checkLoop() {
+	defer p.wg.Done()
+
+	p.check()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.CheckInterval) * time.Hour
+		p.mu.RUnlock()
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.check()
+		}
+	}
+}
+
+// check reads the installed plugins and the catalog, and stages the
+// updates found when auto_stage is on. Updates asking for permissions the
+// installed version doesn't have are left for the admin to stage.
+func (p *PluginUpdatesPlugin) check() {
+	p.mu.Lock()
+	if p.checking {
+		p.mu.Unlock()
+		return
+	}
+	p.checking = true
+	cfg := p.config
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.checking = false
+		p.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	installed, err := scanInstalled(cfg.PluginsDir)
+	var cat *Catalog
+	verified := false
+	if err == nil {
+		var keys []trustedKey
+		if keys, err = configKeys(cfg); err == nil {
+			cat, verified, err = fetchCatalog(ctx, cfg.CatalogURL, keys)
+		}
+	}
+
+	p.mu.Lock()
+	p.lastCheck = time.Now()
+	if err != nil {
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		log.Printf("[plugin-updates] check failed: %v", err)
+		return
+	}
+	p.lastError = ""
+	p.installed = installed
+	p.verified = verified
+	p.catalog = make(map[string]CatalogEntry, len(cat.Plugins))
+	for _, e := range cat.Plugins {
+		p.catalog[e.ID] = e
+	}
+	pending := make([]CatalogEntry, 0)
+	if cfg.AutoStage {
+		for _, u := range p.updates() {
+			if u.Staged {
+				continue
+			}
+			if len(u.NewPermissions) > 0 {
+				log.Printf("[plugin-updates] not staging %s %s, it asks for new permissions: %s", u.ID, u.Available, strings.Join(u.NewPermissions, ", "))
+				continue
+			}
+			pending = append(pending, p.catalog[u.ID])
+		}
+	}
+	p.mu.Unlock()
+
+	for _, entry := range pending {
+		if _, err := p.stageEntry(ctx, entry); err != nil {
+			log.Printf("[plugin-updates] failed to stage %s %s: %v", entry.ID, entry.Version, err)
+		}
+	}
+}
+
+// configKeys returns the keys releases must be signed with, refusing an
+// empty list when signatures are required
+func configKeys(cfg Config) ([]trustedKey, error) {
+	keys, err := parseKeys(cfg.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 && cfg.RequireSignatures {
+		return nil, errors.New("require_signatures is on but public_key holds no keys")
+	}
+	return keys, nil
+}
+
+// stageEntry downloads and unpacks a release into the staging directory
+func (p *PluginUpdatesPlugin) stageEntry(ctx context.Context, entry CatalogEntry) (*Staged, error) {
+	p.mu.RLock()
+	dir := p.stagingDir()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	keys, err := configKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s, err := stage(ctx, entry, dir, keys)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.staged[s.ID] = s
+	p.mu.Unlock()
+	log.Printf("[plugin-updates] staged %s %s in %s", s.ID, s.Version, s.Path)
+	return s, nil
+}
+
+// updates lists the installed plugins with a newer release in the
+// catalog, by name. Caller must hold p.mu.
+func (p *PluginUpdatesPlugin) updates() []Update {
+	ignore := lists.Split(p.config.IgnorePlugins)
+	list := make([]Update, 0)
+	for _, m := range p.installed {
+		e, ok := p.catalog[m.ID]
+		if !ok || lists.ContainsFold(ignore, m.ID) || compareVersions(e.Version, m.Version) <= 0 {
+			continue
+		}
+		name := e.Name
+		if name == "" {
+			name = m.Name
+		}
+		u := Update{
+			ID:              m.ID,
+			Name:            name,
+			Installed:       m.Version,
+			Available:       e.Version,
+			Description:     e.Description,
+			MinPanelVersion: e.MinPanelVersion,
+			Permissions:     e.Permissions,
+			NewPermissions:  newPermissions(m.Permissions, e.Permissions),
+			DownloadURL:     e.DownloadURL,
+		}
+		if s, ok := p.staged[m.ID]; ok && s.Version == e.Version {
+			u.Staged = true
+			u.StagedPath = s.Path
+			u.StagedSignature = s.Signature
+		}
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// handleUpdates returns the updates available and the state of the last
+// check
+func (p *PluginUpdatesPlugin) handleUpdates(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	resp := gin.H{
+		"updates":   p.updates(),
+		"installed": len(p.installed),
+		"catalog":   len(p.catalog),
+		"verified":  p.verified,
+		"checking":  p.checking,
+		"error":     p.lastError,
+	}
+	if !p.lastCheck.IsZero() {
+		resp["last_check"] = p.lastCheck
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleCheck starts a check now
+func (p *PluginUpdatesPlugin) handleCheck(c *gin.Context) {
+	p.mu.RLock()
+	checking := p.checking
+	p.mu.RUnlock()
+	if checking {
+		c.JSON(http.StatusConflict, gin.H{"error": "A check is already running"})
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.check()
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check started"})
+}
+
+// handleStage downloads and unpacks the available update of a plugin
+func (p *PluginUpdatesPlugin) handleStage(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.RLock()
+	var entry CatalogEntry
+	found := false
+	for _, u := range p.updates() {
+		if u.ID == id {
+			entry, found = p.catalog[id], true
+		}
+	}
+	p.mu.RUnlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No update available for this plugin"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+	s, err := p.stageEntry(ctx, entry)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Update staged", "staged": s})
+}
+
+// handleDiscard removes a staged update
+func (p *PluginUpdatesPlugin) handleDiscard(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.staged[id]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Nothing staged for this plugin"})
+		return
+	}
+	if err := os.RemoveAll(s.Path); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove staged update"})
+		return
+	}
+	delete(p.staged, id)
+	c.JSON(http.StatusOK, gin.H{"message": "Staged update removed"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *PluginUpdatesPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *PluginUpdatesPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if !strings.HasPrefix(newConfig.CatalogURL, "https://") && !strings.HasPrefix(newConfig.CatalogURL, "http://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "catalog_url must be an http:// or https:// URL"})
+		return
+	}
+	if _, err := configKeys(newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if newConfig.PluginsDir == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "plugins_dir is required"})
+		return
+	}
+	if newConfig.CheckInterval < 1 || newConfig.CheckInterval > 168 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check_interval must be between 1 and 168 hours"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *PluginUpdatesPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *PluginUpdatesPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
+
+// newPermissions returns the permissions in next that current doesn't have
+func newPermissions(current, next []string) []string {
+	added := make([]string, 0)
+	for _, perm := range next {
+		if !lists.ContainsFold(current, perm) {
+			added = append(added, perm)
+		}
+	}
+	return added
+}
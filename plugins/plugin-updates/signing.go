@@ -0,0 +1,115 @@
+package pluginupdates
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+)
+
+// minisignAlg is minisign's algorithm tag for signatures of the whole file
+var minisignAlg = []byte("Ed")
+
+// trustedKey is a public key releases may be signed with
+type trustedKey struct {
+	num [8]byte
+	pub ed25519.PublicKey
+}
+
+// parseKeys reads the trusted public keys from a setting holding any
+// number of PEM blocks and minisign public keys
+func parseKeys(s string) ([]trustedKey, error) {
+	keys := make([]trustedKey, 0)
+	rest := []byte(s)
+	for {
+		block, remaining := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("public_key holds a key that is not Ed25519")
+		}
+		k := trustedKey{pub: pub}
+		// Matches the key number scripts/release-signing.js derives
+		sum := sha256.Sum256(block.Bytes)
+		copy(k.num[:], sum[:8])
+		keys = append(keys, k)
+		rest = remaining
+	}
+
+	for _, line := range strings.Split(string(rest), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(line)
+		if err != nil || len(raw) != 42 || !bytes.Equal(raw[:2], minisignAlg) {
+			return nil, errors.New("public_key holds something that is neither a PEM nor a minisign Ed25519 public key")
+		}
+		var k trustedKey
+		copy(k.num[:], raw[2:10])
+		k.pub = ed25519.PublicKey(raw[10:])
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// verifyDetached checks a raw base64 Ed25519 signature, as written to
+// catalog.json.sig, against any of the keys
+func verifyDetached(data, sig []byte, keys []trustedKey) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if ed25519.Verify(k.pub, data, raw) {
+			return nil
+		}
+	}
+	return errors.New("catalog signature does not match any trusted key")
+}
+
+// verifyMinisig checks a minisign signature file against data and returns
+// its trusted comment. The signature must be of the whole file ("Ed") by
+// one of the keys, and the trusted comment must be signed too.
+func verifyMinisig(data []byte, minisig string, keys []trustedKey) (string, error) {
+	lines := strings.Split(strings.ReplaceAll(minisig, "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return "", errors.New("malformed signature file")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 74 {
+		return "", errors.New("malformed signature")
+	}
+	if !bytes.Equal(sig[:2], minisignAlg) {
+		return "", errors.New("unsupported signature algorithm; sign without prehashing")
+	}
+	trusted := strings.TrimPrefix(lines[2], "trusted comment: ")
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return "", errors.New("malformed trusted comment signature")
+	}
+
+	for _, k := range keys {
+		if !bytes.Equal(sig[2:10], k.num[:]) {
+			continue
+		}
+		if !ed25519.Verify(k.pub, data, sig[10:]) {
+			return "", errors.New("signature does not match the file")
+		}
+		if !ed25519.Verify(k.pub, append(append([]byte{}, sig[10:]...), trusted...), global) {
+			return "", errors.New("trusted comment signature does not match")
+		}
+		return trusted, nil
+	}
+	return "", errors.New("signed with a key that is not trusted")
+}
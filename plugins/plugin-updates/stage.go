@@ -0,0 +1,167 @@
+package pluginupdates
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive limits
+const (
+	maxArchiveSize  = 32 << 20
+	maxUnpackedSize = 128 << 20
+)
+
+// Staged is an update downloaded and unpacked, ready to be applied
+type Staged struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	// Trusted comment of the release signature, when it was checked
+	Signature string `json:"signature,omitempty"`
+}
+
+// stage downloads a release, checks it against the catalog's checksum and,
+// when keys are given, its minisign signature, then unpacks it into
+// dir/<id>, replacing anything staged there before
+func stage(ctx context.Context, entry CatalogEntry, dir string, keys []trustedKey) (*Staged, error) {
+	if entry.SHA256 == "" {
+		return nil, errors.New("catalog has no checksum for this release")
+	}
+	data, err := fetch(ctx, entry.DownloadURL, maxArchiveSize)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), entry.SHA256) {
+		return nil, errors.New("download does not match the catalog's checksum")
+	}
+	signature := ""
+	if len(keys) > 0 {
+		sigURL := entry.SignatureURL
+		if sigURL == "" {
+			sigURL = entry.DownloadURL + ".minisig"
+		}
+		sig, err := fetch(ctx, sigURL, 4096)
+		if err != nil {
+			return nil, fmt.Errorf("release signature: %w", err)
+		}
+		if signature, err = verifyMinisig(data, string(sig), keys); err != nil {
+			return nil, fmt.Errorf("release signature: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	// Unpack next to the target so the final rename stays on one device
+	tmp, err := os.MkdirTemp(dir, "."+entry.ID+"-")
+	if err != nil {
+		return nil, err
+	}
+	if err := unpack(data, entry.ID, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+
+	var m Installed
+	manifest, err := os.ReadFile(filepath.Join(tmp, "plugin.json"))
+	if err == nil {
+		err = json.Unmarshal(manifest, &m)
+	}
+	if err != nil || m.ID != entry.ID || m.Version != entry.Version {
+		os.RemoveAll(tmp)
+		return nil, errors.New("archive manifest does not match the catalog")
+	}
+
+	target := filepath.Join(dir, entry.ID)
+	if err := os.RemoveAll(target); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	return &Staged{ID: entry.ID, Version: entry.Version, Path: target, Signature: signature}, nil
+}
+
+// unpack extracts the files under the top-level <id>/ directory of a
+// gzipped tarball into dest. Anything else, and any path that would land
+// outside dest, is refused.
+func unpack(data []byte, id, dest string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(hdr.Name)
+		rel := strings.TrimPrefix(name, id+"/")
+		if rel == name || rel == "" || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+			return fmt.Errorf("unexpected path in archive: %s", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return fmt.Errorf("unsupported entry in archive: %s", hdr.Name)
+		}
+
+		total += hdr.Size
+		if total > maxUnpackedSize {
+			return errors.New("archive unpacks to too much data")
+		}
+		target := filepath.Join(dest, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, io.LimitReader(tr, hdr.Size))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// scanStaged lists the updates staged in a directory
+func scanStaged(dir string) map[string]*Staged {
+	staged := make(map[string]*Staged)
+	list, err := scanInstalled(dir)
+	if err != nil {
+		return staged
+	}
+	for _, m := range list {
+		staged[m.ID] = &Staged{ID: m.ID, Version: m.Version, Path: filepath.Join(dir, m.ID)}
+	}
+	return staged
+}
@@ -0,0 +1,232 @@
+/**
+ * Plugin Usage Frontend Script
+ *
+ * Shows the goroutines, memory and time each plugin uses, with details for
+ * one plugin at a time.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'plugin-usage';
+  const PLUGIN_NAME = 'Plugin Usage';
+  const PAGE_PATH = '/plugins/plugin-usage';
+  const API_BASE = '/api/plugin/plugin-usage';
+  const REFRESH_MS = 10000;
+
+  let refreshTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatBytes(n) {
+    const units = ['B', 'KiB', 'MiB', 'GiB'];
+    let i = 0;
+    while (n >= 1024 && i < units.length - 1) {
+      n /= 1024;
+      i++;
+    }
+    return `${i ? n.toFixed(1) : n} ${units[i]}`;
+  }
+
+  function formatMs(ms) {
+    return ms >= 1000 ? `${(ms / 1000).toFixed(1)} s` : `${Math.round(ms)} ms`;
+  }
+
+  function usageRow(u) {
+    const kinds = u.goroutines_by_kind || {};
+    const split = ['hook', 'api', 'background'].filter(k => kinds[k]).map(k => `${kinds[k]} ${k}`).join(', ');
+    return `
+      <tr data-id="${escapeHtml(u.id)}" class="${u.warnings ? 'pus-flagged' : ''}">
+        <td><strong>${escapeHtml(u.id)}</strong>${u.warnings ? `<div class="pus-warning">${u.warnings.map(escapeHtml).join(', ')}</div>` : ''}</td>
+        <td>${u.goroutines}<div class="pus-desc">${escapeHtml(split)}</div></td>
+        <td>${u.goroutines_peak}</td>
+        <td>${formatBytes(u.heap_inuse_bytes)}</td>
+        <td>${formatBytes(u.alloc_bytes_per_sec)}/s</td>
+        <td>${formatMs(u.hook_time_ms)}</td>
+        <td>${formatMs(u.api_time_ms)}</td>
+        <td>${u.requests_seen ? `${formatMs(u.avg_request_ms)} / ${formatMs(u.slowest_request_ms)}` : '-'}</td>
+      </tr>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('plugin-usage-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'plugin-usage-styles';
+    style.textContent = `
+      .pus-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .pus-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .pus-table th, .pus-table td { text-align: left; padding: 0.5rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .pus-table td { color: var(--text-primary, #cdd6f4); }
+      .pus-table tbody tr[data-id] { cursor: pointer; }
+      .pus-table tbody tr[data-id]:hover { background: var(--bg-secondary, #181825); }
+      .pus-desc { color: var(--text-secondary, #a6adc8); font-size: 0.8rem; margin-top: 0.2rem; }
+      .pus-warning { color: var(--warning, #f9e2af); font-size: 0.8rem; }
+      .pus-error { color: var(--error, #f38ba8); }
+      .pus-app select { background: var(--bg-secondary, #181825); color: var(--text-primary, #cdd6f4); border: 1px solid var(--border-primary, #313244); border-radius: 6px; padding: 0.3rem; }
+      .pus-detail { border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 1rem; }
+      .pus-bars { display: flex; align-items: flex-end; gap: 2px; height: 60px; }
+      .pus-bars div { flex: 1; background: var(--accent, #89b4fa); min-height: 1px; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadList(container) {
+    const body = container.querySelector('#pus-body');
+    const sort = container.querySelector('#pus-sort').value;
+    try {
+      const data = await api(`/usage?sort=${encodeURIComponent(sort)}`);
+      const panel = data.panel;
+      body.innerHTML = `
+        <div>
+          ${panel.plugin_goroutines} of the panel's ${panel.goroutines} goroutines belong to plugins.
+          Heap in use: ${formatBytes(panel.heap_inuse_bytes)}. Figures cover the last ${panel.window_minutes} minutes,
+          sampled every ${panel.sample_interval_ms} ms.
+        </div>
+        <table class="pus-table">
+          <thead>
+            <tr><th>Plugin</th><th>Goroutines</th><th>Peak</th><th>Heap in use</th><th>Allocating</th><th>Hook time</th><th>API time</th><th>Requests avg / max</th></tr>
+          </thead>
+          <tbody>${data.plugins.map(usageRow).join('')}</tbody>
+        </table>
+      `;
+      body.querySelectorAll('tr[data-id]').forEach(row => {
+        row.addEventListener('click', () => loadDetail(container, row.dataset.id));
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="pus-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadDetail(container, id) {
+    const detail = container.querySelector('#pus-detail');
+    try {
+      const data = await api(`/usage/${encodeURIComponent(id)}`);
+      const minutes = data.minutes || [];
+      const peak = Math.max(1, ...minutes.map(m => m.goroutines_peak));
+      detail.innerHTML = `
+        <div class="pus-detail">
+          <h3>${escapeHtml(id)}</h3>
+          <div class="pus-desc">Goroutines per minute (peak)</div>
+          <div class="pus-bars">
+            ${minutes.map(m => `<div style="height:${(m.goroutines_peak / peak) * 100}%" title="${escapeHtml(new Date(m.start).toLocaleTimeString())}: ${m.goroutines_peak}"></div>`).join('')}
+          </div>
+          <div>Heap in use: ${formatBytes(data.heap.inuse_bytes)} in ${data.heap.inuse_objects} objects</div>
+          <table class="pus-table">
+            <thead><tr><th>Where</th><th>State</th><th>Doing</th><th>Goroutines</th></tr></thead>
+            <tbody>
+              ${(data.stacks || []).map(s => `
+                <tr><td><code>${escapeHtml(s.function)}</code></td><td>${escapeHtml(s.state)}</td><td>${escapeHtml(s.kind)}</td><td>${s.count}</td></tr>
+              `).join('') || '<tr><td colspan="4">No goroutines right now</td></tr>'}
+            </tbody>
+          </table>
+        </div>
+      `;
+    } catch (e) {
+      detail.innerHTML = `<div class="pus-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="pus-app" data-plugin="${PLUGIN_ID}">
+        <div>
+          Sort by
+          <select id="pus-sort">
+            <option value="goroutines">Goroutines</option>
+            <option value="heap">Heap in use</option>
+            <option value="alloc">Allocations</option>
+            <option value="hooks">Hook time</option>
+            <option value="api">API time</option>
+            <option value="latency">Slowest request</option>
+          </select>
+        </div>
+        <div id="pus-body">Loading...</div>
+        <div id="pus-detail"></div>
+      </div>
+    `;
+
+    container.querySelector('#pus-sort').addEventListener('change', () => loadList(container));
+    loadList(container);
+    clearInterval(refreshTimer);
+    refreshTimer = setInterval(() => {
+      if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) {
+        clearInterval(refreshTimer);
+        return;
+      }
+      loadList(container);
+    }, REFRESH_MS);
+    return true;
+  }
+
+  function cleanup() {
+    clearInterval(refreshTimer);
+    const style = document.getElementById('plugin-usage-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
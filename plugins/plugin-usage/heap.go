@@ -0,0 +1,75 @@
+package pluginusage
+
+import (
+	"math"
+	"runtime"
+)
+
+// HeapTotals is a plugin's share of the heap profile, scaled up from the
+// sampled allocations to an estimate of the real figures
+type HeapTotals struct {
+	InuseBytes   int64 `json:"inuse_bytes"`
+	InuseObjects int64 `json:"inuse_objects"`
+	AllocBytes   int64 `json:"alloc_bytes"`
+	AllocObjects int64 `json:"alloc_objects"`
+}
+
+// heapRecords reads the heap profile as of the last garbage collection
+func heapRecords() []runtime.MemProfileRecord {
+	n, _ := runtime.MemProfile(nil, true)
+	for {
+		records := make([]runtime.MemProfileRecord, n+50)
+		m, ok := runtime.MemProfile(records, true)
+		if ok {
+			return records[:m]
+		}
+		n = m
+	}
+}
+
+// heapByPlugin charges every sampled allocation to the plugin of the
+// innermost plugin frame that made it. owner maps a function name to a
+// plugin ID, or "".
+func heapByPlugin(records []runtime.MemProfileRecord, owner func(string) string) map[string]HeapTotals {
+	rate := int64(runtime.MemProfileRate)
+	totals := make(map[string]HeapTotals)
+	for i := range records {
+		r := &records[i]
+		id := ""
+		frames := runtime.CallersFrames(r.Stack())
+		for {
+			frame, more := frames.Next()
+			if id = owner(frame.Function); id != "" || !more {
+				break
+			}
+		}
+		if id == "" {
+			continue
+		}
+
+		t := totals[id]
+		objects, bytes := scaleHeapSample(r.AllocObjects, r.AllocBytes, rate)
+		t.AllocObjects += objects
+		t.AllocBytes += bytes
+		objects, bytes = scaleHeapSample(r.InUseObjects(), r.InUseBytes(), rate)
+		t.InuseObjects += objects
+		t.InuseBytes += bytes
+		totals[id] = t
+	}
+	return totals
+}
+
+// scaleHeapSample estimates the real count and size behind a heap profile
+// sample, in the same way as runtime/pprof: an allocation of s bytes is
+// sampled with probability 1-exp(-s/rate)
+func scaleHeapSample(count, size, rate int64) (int64, int64) {
+	if count == 0 || size == 0 {
+		return 0, 0
+	}
+	if rate <= 1 {
+		return count, size
+	}
+	avg := float64(size) / float64(count)
+	scale := 1 / (1 - math.Exp(-avg/float64(rate)))
+	return int64(float64(count) * scale), int64(float64(size) * scale)
+}
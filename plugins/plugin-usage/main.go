@@ -0,0 +1,391 @@
+// Plugin Usage Plugin for UnrealIRCd Web Panel
+// Tracks each plugin's goroutines, memory, hook time and API request time,
+// so a misbehaving plugin can be found without profiling the whole panel
+
+package pluginusage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+)
+
+// PluginUsagePlugin implements the Plugin interface
+type PluginUsagePlugin struct {
+	config  Config
+	tracker *tracker
+	started time.Time
+	mu      sync.RWMutex
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	PluginsDir       string `json:"plugins_dir"`
+	SampleInterval   int    `json:"sample_interval"`
+	HeapInterval     int    `json:"heap_interval"`
+	Window           int    `json:"window"`
+	GoroutineWarning int    `json:"goroutine_warning"`
+	HeapWarning      int    `json:"heap_warning"`
+}
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	return &PluginUsagePlugin{
+		config: Config{
+			PluginsDir:       "plugins",
+			SampleInterval:   250,
+			HeapInterval:     30,
+			Window:           15,
+			GoroutineWarning: 100,
+			HeapWarning:      64,
+		},
+		tracker: newTracker(),
+	}
+}
+
+// Info returns plugin metadata
+func (p *PluginUsagePlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Plugin Usage",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Per-plugin goroutines, memory, hook time and API latency",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *PluginUsagePlugin) Init() error {
+	p.mu.Lock()
+	p.started = time.Now()
+	p.loadPlugins()
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "plugin-usage-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		list := p.usages(time.Now())
+		content := map[string]interface{}{
+			"plugins": len(list),
+		}
+		if len(list) > 0 {
+			sortUsages(list, "goroutines")
+			content["most_goroutines"] = fmt.Sprintf("%s (%d)", list[0].ID, list[0].Goroutines)
+			sortUsages(list, "heap")
+			content["most_memory"] = fmt.Sprintf("%s (%s)", list[0].ID, formatBytes(list[0].HeapInuseBytes))
+		}
+		warnings := make([]string, 0)
+		for _, u := range list {
+			for _, w := range u.Warnings {
+				warnings = append(warnings, u.ID+": "+w)
+			}
+		}
+		if len(warnings) > 0 {
+			sort.Strings(warnings)
+			content["warnings"] = warnings
+		}
+		return plugins.DashboardCard{
+			Title:   "Plugin Usage",
+			Icon:    "Gauge",
+			Content: content,
+			Order:   91,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.sampleLoop()
+	go p.heapLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *PluginUsagePlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *PluginUsagePlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/plugin-usage")
+	{
+		plugin.GET("/usage", p.handleUsage)
+		plugin.GET("/usage/:id", p.handlePluginUsage)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// loadPlugins reads the IDs of the installed plugins. Caller must hold p.mu.
+func (p *PluginUsagePlugin) loadPlugins() {
+	entries, err := os.ReadDir(p.config.PluginsDir)
+	if err != nil {
+		log.Printf("[plugin-usage] failed to read %s: %v", p.config.PluginsDir, err)
+		return
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(p.config.PluginsDir, e.Name(), "plugin.json")); err == nil {
+			ids = append(ids, e.Name())
+		}
+	}
+	p.tracker.setPlugins(ids)
+}
+
+// sampleLoop takes a goroutine sample every sample_interval milliseconds
+// until shutdown
+func (p *PluginUsagePlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	var buf []byte
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.SampleInterval) * time.Millisecond
+		window := time.Duration(p.config.Window) * time.Minute
+		p.mu.RUnlock()
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+		}
+
+		var dump []byte
+		buf, dump = dumpStacks(buf)
+		stacks := parseStacks(dump)
+
+		p.mu.Lock()
+		p.tracker.addSample(time.Now(), stacks, interval, window)
+		p.mu.Unlock()
+	}
+}
+
+// heapLoop charges the heap profile to plugins every heap_interval seconds
+// until shutdown
+func (p *PluginUsagePlugin) heapLoop() {
+	defer p.wg.Done()
+
+	for {
+		records := heapRecords()
+		p.mu.Lock()
+		window := time.Duration(p.config.Window) * time.Minute
+		p.tracker.addHeap(time.Now(), heapByPlugin(records, p.tracker.owner), window)
+		interval := time.Duration(p.config.HeapInterval) * time.Second
+		p.mu.Unlock()
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// usage sums up a plugin and flags it when it is over the warning
+// thresholds. Caller must hold p.mu.
+func (p *PluginUsagePlugin) usage(s *pluginState, now time.Time) Usage {
+	u := s.usage(now)
+	if p.config.GoroutineWarning > 0 && u.Goroutines >= p.config.GoroutineWarning {
+		u.Warnings = append(u.Warnings, fmt.Sprintf("%d goroutines", u.Goroutines))
+	}
+	if p.config.HeapWarning > 0 && u.HeapInuseBytes >= int64(p.config.HeapWarning)<<20 {
+		u.Warnings = append(u.Warnings, formatBytes(u.HeapInuseBytes)+" in use")
+	}
+	return u
+}
+
+// usages sums up every plugin. Caller must hold p.mu.
+func (p *PluginUsagePlugin) usages(now time.Time) []Usage {
+	list := make([]Usage, 0, len(p.tracker.plugins))
+	for _, s := range p.tracker.plugins {
+		list = append(list, p.usage(s, now))
+	}
+	return list
+}
+
+// sortUsages orders usages by the chosen measurement, highest first
+func sortUsages(list []Usage, by string) {
+	key := func(u Usage) float64 {
+		switch by {
+		case "heap":
+			return float64(u.HeapInuseBytes)
+		case "alloc":
+			return float64(u.AllocBytes)
+		case "hooks":
+			return u.HookTimeMs
+		case "api":
+			return u.APITimeMs
+		case "latency":
+			return u.SlowestRequestMs
+		}
+		return float64(u.Goroutines)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := key(list[i]), key(list[j])
+		if a != b {
+			return a > b
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+// handleUsage returns the usage of every plugin
+func (p *PluginUsagePlugin) handleUsage(c *gin.Context) {
+	by := c.DefaultQuery("sort", "goroutines")
+	switch by {
+	case "goroutines", "heap", "alloc", "hooks", "api", "latency":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be goroutines, heap, alloc, hooks, api or latency"})
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	now := time.Now()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := p.usages(now)
+	sortUsages(list, by)
+	attributed := 0
+	for _, u := range list {
+		attributed += u.Goroutines
+	}
+	panel := gin.H{
+		"goroutines":          runtime.NumGoroutine(),
+		"plugin_goroutines":   attributed,
+		"heap_inuse_bytes":    mem.HeapInuse,
+		"total_alloc_bytes":   mem.TotalAlloc,
+		"gc_cycles":           mem.NumGC,
+		"memory_profile_rate": runtime.MemProfileRate,
+		"tracking_since":      p.started,
+		"sample_interval_ms":  p.config.SampleInterval,
+		"window_minutes":      p.config.Window,
+	}
+	if !p.tracker.lastHeap.IsZero() {
+		panel["last_heap_profile"] = p.tracker.lastHeap
+	}
+	c.JSON(http.StatusOK, gin.H{"plugins": list, "panel": panel})
+}
+
+// handlePluginUsage returns one plugin's usage with its minute by minute
+// history and where its goroutines are
+func (p *PluginUsagePlugin) handlePluginUsage(c *gin.Context) {
+	id := c.Param("id")
+	now := time.Now()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s, ok := p.tracker.plugins[id]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plugin not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"usage":   p.usage(s, now),
+		"heap":    s.Heap,
+		"minutes": s.series(),
+		"stacks":  s.Stacks,
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *PluginUsagePlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *PluginUsagePlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.PluginsDir == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "plugins_dir is required"})
+		return
+	}
+	if newConfig.SampleInterval < 100 || newConfig.SampleInterval > 10000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sample_interval must be between 100 and 10000 milliseconds"})
+		return
+	}
+	if newConfig.HeapInterval < 10 || newConfig.HeapInterval > 600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "heap_interval must be between 10 and 600 seconds"})
+		return
+	}
+	if newConfig.Window < 1 || newConfig.Window > 120 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be between 1 and 120 minutes"})
+		return
+	}
+	if newConfig.GoroutineWarning < 0 || newConfig.HeapWarning < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "warning thresholds can't be negative"})
+		return
+	}
+
+	p.mu.Lock()
+	p.config = newConfig
+	p.loadPlugins()
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *PluginUsagePlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *PluginUsagePlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
+
+// formatBytes shows a byte count in the largest whole unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,157 @@
+package pluginusage
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// What a plugin's goroutine is doing
+const (
+	KindHook       = "hook"
+	KindAPI        = "api"
+	KindBackground = "background"
+)
+
+// maxStackDump caps the buffer used for a goroutine dump
+const maxStackDump = 64 << 20
+
+// goroutine is one goroutine from a stack dump
+type goroutine struct {
+	ID        int64
+	State     string
+	Functions []string // innermost first
+	CreatedBy string
+}
+
+// dumpStacks returns the stacks of all goroutines, reusing buf when it is
+// large enough
+func dumpStacks(buf []byte) ([]byte, []byte) {
+	if len(buf) == 0 {
+		buf = make([]byte, 1<<20)
+	}
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackDump {
+			return buf, buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parseStacks reads a dump as written by runtime.Stack
+func parseStacks(dump []byte) []goroutine {
+	list := make([]goroutine, 0, 64)
+	for _, block := range strings.Split(string(dump), "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		g, ok := parseHeader(lines[0])
+		if !ok {
+			continue
+		}
+		for _, line := range lines[1:] {
+			switch {
+			case strings.HasPrefix(line, "\t"), strings.HasPrefix(line, "..."):
+			case strings.HasPrefix(line, "created by "):
+				name := strings.TrimPrefix(line, "created by ")
+				if i := strings.Index(name, " in goroutine "); i >= 0 {
+					name = name[:i]
+				}
+				g.CreatedBy = name
+			default:
+				g.Functions = append(g.Functions, funcName(line))
+			}
+		}
+		list = append(list, g)
+	}
+	return list
+}
+
+// parseHeader reads "goroutine 12 [chan receive, 3 minutes]:"
+func parseHeader(line string) (goroutine, bool) {
+	var g goroutine
+	rest, ok := strings.CutPrefix(line, "goroutine ")
+	if !ok {
+		return g, false
+	}
+	id, state, ok := strings.Cut(rest, " [")
+	if !ok {
+		return g, false
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return g, false
+	}
+	state = strings.TrimSuffix(state, "]:")
+	if i := strings.Index(state, ","); i >= 0 {
+		state = state[:i]
+	}
+	g.ID = n
+	g.State = state
+	return g, true
+}
+
+// funcName strips the argument list from a frame line
+func funcName(line string) string {
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// packagePath returns the import path of a function name such as
+// "github.com/x/y/plugin-usage.(*PluginUsagePlugin).sample.func1"
+func packagePath(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	if dot := strings.Index(fn[slash+1:], "."); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
+
+// shortName drops the directories from a function name
+func shortName(fn string) string {
+	return fn[strings.LastIndex(fn, "/")+1:]
+}
+
+// attribution is which plugin a goroutine belongs to and what it is doing
+type attribution struct {
+	Plugin   string
+	Kind     string
+	Function string
+}
+
+// attribute finds the plugin a goroutine belongs to: the innermost frame in
+// a plugin's package, or else the plugin that started it. Frames called by
+// the hook manager make it a hook, frames called by gin an API request.
+// owner maps a function name to a plugin ID, or "".
+func attribute(g goroutine, owner func(string) string) attribution {
+	for i, fn := range g.Functions {
+		id := owner(fn)
+		if id == "" {
+			continue
+		}
+		a := attribution{Plugin: id, Kind: KindBackground, Function: shortName(fn)}
+		for _, caller := range g.Functions[i+1:] {
+			pkg := packagePath(caller)
+			if strings.HasSuffix(pkg, "/hooks") {
+				a.Kind = KindHook
+				break
+			}
+			if pkg == "github.com/gin-gonic/gin" {
+				a.Kind = KindAPI
+				break
+			}
+		}
+		return a
+	}
+	if id := owner(g.CreatedBy); id != "" {
+		fn := g.CreatedBy
+		if len(g.Functions) > 0 {
+			fn = g.Functions[0]
+		}
+		return attribution{Plugin: id, Kind: KindBackground, Function: shortName(fn)}
+	}
+	return attribution{}
+}
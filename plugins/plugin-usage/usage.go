@@ -0,0 +1,337 @@
+package pluginusage
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxStackGroups limits the stack groups kept per plugin
+const maxStackGroups = 20
+
+// bucket holds one minute of measurements for a plugin
+type bucket struct {
+	Start          time.Time
+	Samples        int
+	Goroutines     int // summed over the samples
+	PeakGoroutines int
+	HookTime       time.Duration
+	APITime        time.Duration
+	Requests       int
+	RequestTime    time.Duration
+	SlowestRequest time.Duration
+	AllocBytes     int64
+	AllocObjects   int64
+}
+
+// StackGroup counts a plugin's goroutines parked in the same function
+type StackGroup struct {
+	Function string `json:"function"`
+	State    string `json:"state"`
+	Kind     string `json:"kind"`
+	Count    int    `json:"count"`
+}
+
+// pluginState is what is known about one plugin
+type pluginState struct {
+	ID         string
+	Goroutines map[string]int
+	Stacks     []StackGroup
+	Heap       HeapTotals
+	heapSeen   bool
+	buckets    []*bucket
+}
+
+// request is an API request followed across samples
+type request struct {
+	plugin      string
+	first, last time.Time
+}
+
+// tracker turns stack samples and heap profiles into per-plugin usage
+type tracker struct {
+	plugins  map[string]*pluginState
+	owners   map[string]string // package directory -> plugin ID
+	funcs    map[string]string // function name -> plugin ID, cached
+	active   map[int64]*request
+	lastHeap time.Time
+}
+
+func newTracker() *tracker {
+	return &tracker{
+		plugins: make(map[string]*pluginState),
+		owners:  make(map[string]string),
+		funcs:   make(map[string]string),
+		active:  make(map[int64]*request),
+	}
+}
+
+// setPlugins sets the plugin IDs to look for, forgetting plugins no
+// longer installed. A plugin's package is found by its directory, either
+// the ID itself or the ID without hyphens.
+func (t *tracker) setPlugins(ids []string) {
+	plugins := make(map[string]*pluginState, len(ids))
+	t.owners = make(map[string]string, 2*len(ids))
+	t.funcs = make(map[string]string)
+	for _, id := range ids {
+		t.owners[id] = id
+		t.owners[strings.ReplaceAll(id, "-", "")] = id
+		plugins[id] = t.state(id)
+	}
+	t.plugins = plugins
+}
+
+// owner returns the plugin a function belongs to, or ""
+func (t *tracker) owner(fn string) string {
+	if fn == "" {
+		return ""
+	}
+	if id, ok := t.funcs[fn]; ok {
+		return id
+	}
+	pkg := packagePath(fn)
+	id := t.owners[shortName(pkg)]
+	t.funcs[fn] = id
+	return id
+}
+
+func (t *tracker) state(id string) *pluginState {
+	s, ok := t.plugins[id]
+	if !ok {
+		s = &pluginState{ID: id, Goroutines: make(map[string]int)}
+		t.plugins[id] = s
+	}
+	return s
+}
+
+// bucket returns a plugin's bucket for the minute holding now, dropping
+// buckets older than the window
+func (t *tracker) bucket(s *pluginState, now time.Time, window time.Duration) *bucket {
+	start := now.Truncate(time.Minute)
+	if n := len(s.buckets); n > 0 && s.buckets[n-1].Start.Equal(start) {
+		return s.buckets[n-1]
+	}
+	b := &bucket{Start: start}
+	s.buckets = append(s.buckets, b)
+	cutoff := start.Add(-window)
+	for len(s.buckets) > 0 && !s.buckets[0].Start.After(cutoff) {
+		s.buckets = s.buckets[1:]
+	}
+	return b
+}
+
+// addSample records one stack sample. Each goroutine in a hook or an API
+// handler stands for interval of time spent there. An API goroutine seen
+// in consecutive samples is taken to be one request.
+func (t *tracker) addSample(now time.Time, stacks []goroutine, interval, window time.Duration) {
+	counts := make(map[string]map[string]int)
+	groups := make(map[string]map[StackGroup]int)
+	seen := make(map[int64]bool)
+
+	for _, g := range stacks {
+		a := attribute(g, t.owner)
+		if a.Plugin == "" {
+			continue
+		}
+		s := t.state(a.Plugin)
+		if counts[a.Plugin] == nil {
+			counts[a.Plugin] = make(map[string]int)
+			groups[a.Plugin] = make(map[StackGroup]int)
+		}
+		counts[a.Plugin][a.Kind]++
+		groups[a.Plugin][StackGroup{Function: a.Function, State: g.State, Kind: a.Kind}]++
+
+		m := t.bucket(s, now, window)
+		switch a.Kind {
+		case KindHook:
+			m.HookTime += interval
+		case KindAPI:
+			m.APITime += interval
+			seen[g.ID] = true
+			if r, ok := t.active[g.ID]; ok && r.plugin == a.Plugin {
+				r.last = now
+			} else {
+				if ok {
+					t.finish(r, interval, window)
+				}
+				t.active[g.ID] = &request{plugin: a.Plugin, first: now, last: now}
+			}
+		}
+	}
+
+	for id, r := range t.active {
+		if !seen[id] {
+			t.finish(r, interval, window)
+			delete(t.active, id)
+		}
+	}
+
+	for id, s := range t.plugins {
+		s.Goroutines = counts[id]
+		if s.Goroutines == nil {
+			s.Goroutines = make(map[string]int)
+		}
+		total := 0
+		for _, n := range s.Goroutines {
+			total += n
+		}
+		m := t.bucket(s, now, window)
+		m.Samples++
+		m.Goroutines += total
+		if total > m.PeakGoroutines {
+			m.PeakGoroutines = total
+		}
+
+		s.Stacks = s.Stacks[:0]
+		for group, n := range groups[id] {
+			group.Count = n
+			s.Stacks = append(s.Stacks, group)
+		}
+		sort.Slice(s.Stacks, func(i, j int) bool {
+			if s.Stacks[i].Count != s.Stacks[j].Count {
+				return s.Stacks[i].Count > s.Stacks[j].Count
+			}
+			return s.Stacks[i].Function < s.Stacks[j].Function
+		})
+		if len(s.Stacks) > maxStackGroups {
+			s.Stacks = s.Stacks[:maxStackGroups]
+		}
+	}
+}
+
+// finish records a request that is no longer running. It ran for at least
+// the time between the samples that saw it, plus about one interval.
+func (t *tracker) finish(r *request, interval, window time.Duration) {
+	d := r.last.Sub(r.first) + interval
+	m := t.bucket(t.state(r.plugin), r.last, window)
+	m.Requests++
+	m.RequestTime += d
+	if d > m.SlowestRequest {
+		m.SlowestRequest = d
+	}
+}
+
+// addHeap records a heap profile walk. Allocation figures are cumulative,
+// so each walk adds what was allocated since the one before.
+func (t *tracker) addHeap(now time.Time, totals map[string]HeapTotals, window time.Duration) {
+	for id, s := range t.plugins {
+		cur := totals[id]
+		if s.heapSeen {
+			if d := cur.AllocBytes - s.Heap.AllocBytes; d > 0 {
+				m := t.bucket(s, now, window)
+				m.AllocBytes += d
+				m.AllocObjects += cur.AllocObjects - s.Heap.AllocObjects
+			}
+		}
+		s.Heap = cur
+		s.heapSeen = true
+	}
+	t.lastHeap = now
+}
+
+// Usage sums up a plugin's measurements over the window
+type Usage struct {
+	ID               string         `json:"id"`
+	Goroutines       int            `json:"goroutines"`
+	GoroutinesByKind map[string]int `json:"goroutines_by_kind"`
+	GoroutinesAvg    float64        `json:"goroutines_avg"`
+	GoroutinesPeak   int            `json:"goroutines_peak"`
+	HookTimeMs       float64        `json:"hook_time_ms"`
+	APITimeMs        float64        `json:"api_time_ms"`
+	RequestsSeen     int            `json:"requests_seen"`
+	AvgRequestMs     float64        `json:"avg_request_ms"`
+	SlowestRequestMs float64        `json:"slowest_request_ms"`
+	HeapInuseBytes   int64          `json:"heap_inuse_bytes"`
+	HeapInuseObjects int64          `json:"heap_inuse_objects"`
+	AllocBytes       int64          `json:"alloc_bytes"`
+	AllocBytesPerSec float64        `json:"alloc_bytes_per_sec"`
+	Warnings         []string       `json:"warnings,omitempty"`
+}
+
+// MinuteUsage is one minute of a plugin's measurements
+type MinuteUsage struct {
+	Start            time.Time `json:"start"`
+	GoroutinesAvg    float64   `json:"goroutines_avg"`
+	GoroutinesPeak   int       `json:"goroutines_peak"`
+	HookTimeMs       float64   `json:"hook_time_ms"`
+	APITimeMs        float64   `json:"api_time_ms"`
+	RequestsSeen     int       `json:"requests_seen"`
+	SlowestRequestMs float64   `json:"slowest_request_ms"`
+	AllocBytes       int64     `json:"alloc_bytes"`
+}
+
+// usage sums up a plugin's buckets
+func (s *pluginState) usage(now time.Time) Usage {
+	u := Usage{
+		ID:               s.ID,
+		GoroutinesByKind: s.Goroutines,
+		HeapInuseBytes:   s.Heap.InuseBytes,
+		HeapInuseObjects: s.Heap.InuseObjects,
+	}
+	for _, n := range s.Goroutines {
+		u.Goroutines += n
+	}
+
+	samples, goroutines := 0, 0
+	var requestTime time.Duration
+	for _, m := range s.buckets {
+		samples += m.Samples
+		goroutines += m.Goroutines
+		if m.PeakGoroutines > u.GoroutinesPeak {
+			u.GoroutinesPeak = m.PeakGoroutines
+		}
+		u.HookTimeMs += ms(m.HookTime)
+		u.APITimeMs += ms(m.APITime)
+		u.RequestsSeen += m.Requests
+		requestTime += m.RequestTime
+		if v := ms(m.SlowestRequest); v > u.SlowestRequestMs {
+			u.SlowestRequestMs = v
+		}
+		u.AllocBytes += m.AllocBytes
+	}
+	if samples > 0 {
+		u.GoroutinesAvg = round(float64(goroutines) / float64(samples))
+	}
+	if u.RequestsSeen > 0 {
+		u.AvgRequestMs = round(ms(requestTime) / float64(u.RequestsSeen))
+	}
+	if len(s.buckets) > 0 {
+		if secs := now.Sub(s.buckets[0].Start).Seconds(); secs > 0 {
+			u.AllocBytesPerSec = round(float64(u.AllocBytes) / secs)
+		}
+	}
+	u.HookTimeMs = round(u.HookTimeMs)
+	u.APITimeMs = round(u.APITimeMs)
+	return u
+}
+
+// series returns a plugin's buckets, oldest first
+func (s *pluginState) series() []MinuteUsage {
+	list := make([]MinuteUsage, 0, len(s.buckets))
+	for _, m := range s.buckets {
+		mu := MinuteUsage{
+			Start:            m.Start,
+			GoroutinesPeak:   m.PeakGoroutines,
+			HookTimeMs:       round(ms(m.HookTime)),
+			APITimeMs:        round(ms(m.APITime)),
+			RequestsSeen:     m.Requests,
+			SlowestRequestMs: round(ms(m.SlowestRequest)),
+			AllocBytes:       m.AllocBytes,
+		}
+		if m.Samples > 0 {
+			mu.GoroutinesAvg = round(float64(m.Goroutines) / float64(m.Samples))
+		}
+		list = append(list, mu)
+	}
+	return list
+}
+
+// ms converts a duration to milliseconds
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// round rounds to two decimals
+func round(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
@@ -0,0 +1,557 @@
+// Port Status Plugin for UnrealIRCd Web Panel
+// Probes the network's client listeners (plaintext, TLS and websocket) on a
+// schedule and keeps reachability and latency history
+
+package portstatus
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// PortStatusPlugin implements the Plugin interface
+type PortStatusPlugin struct {
+	config   Config
+	ports    map[string]*Port
+	dirty    bool
+	checkNow chan struct{}
+	mu       sync.RWMutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	Listeners     string `json:"listeners"`
+	DataDir       string `json:"data_dir"`
+	CheckInterval int    `json:"check_interval"`
+	Timeout       int    `json:"timeout"`
+	Insecure      bool   `json:"insecure"`
+	RetentionDays int    `json:"retention_days"`
+	FailThreshold int    `json:"fail_threshold"`
+	AlertWebhook  string `json:"alert_webhook"`
+}
+
+// Sample is the result of one probe
+type Sample struct {
+	Time        time.Time `json:"time"`
+	Up          bool      `json:"up"`
+	ConnectMS   int64     `json:"connect_ms"`
+	HandshakeMS int64     `json:"handshake_ms"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Port is a monitored listener with its probe history
+type Port struct {
+	URL       string     `json:"url"`
+	Kind      string     `json:"kind"`
+	Addr      string     `json:"addr"`
+	Up        bool       `json:"up"`
+	Failures  int        `json:"consecutive_failures"`
+	Down      bool       `json:"down"`
+	DownSince *time.Time `json:"down_since"`
+	Last      *Sample    `json:"last"`
+	History   []Sample   `json:"history"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &PortStatusPlugin{
+		config: Config{
+			Listeners:     "irc://127.0.0.1:6667, ircs://127.0.0.1:6697",
+			DataDir:       "data/plugins/port-status",
+			CheckInterval: 60,
+			Timeout:       10,
+			RetentionDays: 7,
+			FailThreshold: 3,
+		},
+		ports:    make(map[string]*Port),
+		checkNow: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *PortStatusPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Port Status",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Reachability and handshake latency of client listeners",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *PortStatusPlugin) Init() error {
+	p.mu.Lock()
+	var data map[string]*Port
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[port-status] failed to load data: %v", err)
+	}
+	if data != nil {
+		p.ports = data
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "port-status-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		down := 0
+		for _, port := range p.ports {
+			if port.Down {
+				down++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Listeners",
+			Icon:  "Plug",
+			Content: map[string]interface{}{
+				"listeners": len(p.ports),
+				"down":      down,
+			},
+			Order: 35,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.checkLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *PortStatusPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *PortStatusPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/port-status")
+	{
+		plugin.GET("/ports", p.handleListPorts)
+		plugin.GET("/history", p.handleHistory)
+		plugin.GET("/summary", p.handleSummary)
+		plugin.POST("/check", p.handleCheck)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *PortStatusPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "ports.json")
+}
+
+// save persists the probe history if it changed
+func (p *PortStatusPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.ports); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// checkLoop probes all listeners until shutdown
+func (p *PortStatusPlugin) checkLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.checkAll()
+		if err := p.save(); err != nil {
+			log.Printf("[port-status] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.CheckInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 15*time.Second {
+			interval = 15 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.checkNow:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkAll probes every configured listener in parallel and records the
+// results
+func (p *PortStatusPlugin) checkAll() {
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	targets := make([]target, 0)
+	for _, raw := range strings.FieldsFunc(cfg.Listeners, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	}) {
+		t, err := parseTarget(raw)
+		if err != nil {
+			log.Printf("[port-status] ignoring listener: %v", err)
+			continue
+		}
+		targets = append(targets, t)
+	}
+
+	results := make([]probeResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			results[i] = probe(context.Background(), t, timeout, cfg.Insecure)
+		}(i, t)
+	}
+	wg.Wait()
+
+	now := time.Now().UTC()
+	cutoff := now.AddDate(0, 0, -cfg.RetentionDays)
+	threshold := cfg.FailThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	p.mu.Lock()
+	alerts := make([]notify.Event, 0)
+	current := make(map[string]bool, len(targets))
+	for i, t := range targets {
+		current[t.URL] = true
+		res := results[i]
+		port := p.ports[t.URL]
+		if port == nil {
+			port = &Port{URL: t.URL, History: make([]Sample, 0)}
+			p.ports[t.URL] = port
+		}
+		port.Kind = t.Kind
+		port.Addr = t.Addr
+
+		s := Sample{Time: now, Up: res.Up, ConnectMS: res.ConnectMS, HandshakeMS: res.HandshakeMS, Error: res.Error}
+		port.Last = &s
+		port.Up = res.Up
+		port.History = append(port.History, s)
+		if cfg.RetentionDays > 0 {
+			keep := sort.Search(len(port.History), func(i int) bool { return port.History[i].Time.After(cutoff) })
+			port.History = append([]Sample(nil), port.History[keep:]...)
+		}
+
+		// A port is only reported down after several failed probes in a row
+		if res.Up {
+			port.Failures = 0
+			if port.Down {
+				alerts = append(alerts, notify.Event{
+					Type:     "port_up",
+					Severity: "info",
+					Title:    "Listener reachable again",
+					Message:  fmt.Sprintf("%s is reachable again after %s", t.URL, now.Sub(*port.DownSince).Round(time.Second)),
+					Data:     map[string]interface{}{"url": t.URL, "kind": t.Kind},
+				})
+				port.Down = false
+				port.DownSince = nil
+			}
+		} else {
+			port.Failures++
+			if !port.Down && port.Failures >= threshold {
+				since := now
+				port.Down = true
+				port.DownSince = &since
+				alerts = append(alerts, notify.Event{
+					Type:     "port_down",
+					Severity: "critical",
+					Title:    "Listener unreachable",
+					Message:  fmt.Sprintf("%s failed %d probes in a row: %s", t.URL, port.Failures, res.Error),
+					Data:     map[string]interface{}{"url": t.URL, "kind": t.Kind, "error": res.Error},
+				})
+			}
+		}
+	}
+
+	// Forget listeners that were removed from the configuration
+	for url := range p.ports {
+		if !current[url] {
+			delete(p.ports, url)
+		}
+	}
+	p.dirty = true
+	webhook := cfg.AlertWebhook
+	p.mu.Unlock()
+
+	for _, a := range alerts {
+		log.Printf("[port-status] %s: %s", a.Title, a.Message)
+		if webhook != "" {
+			a.Source = "port-status"
+			a.Timestamp = now
+			go p.alert(webhook, a)
+		}
+	}
+}
+
+// alert delivers an alert to the webhook
+func (p *PortStatusPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[port-status] failed to send alert: %v", err)
+	}
+}
+
+// portStats summarises the history of a port since the given time
+type portStats struct {
+	Samples      int     `json:"samples"`
+	UptimePct    float64 `json:"uptime_pct"`
+	AvgHandshake float64 `json:"avg_handshake_ms"`
+	MaxHandshake int64   `json:"max_handshake_ms"`
+}
+
+// stats computes uptime and latency over the history since the given time.
+// Caller must hold p.mu.
+func (port *Port) stats(since time.Time) portStats {
+	var st portStats
+	up := 0
+	var total int64
+	for _, s := range port.History {
+		if s.Time.Before(since) {
+			continue
+		}
+		st.Samples++
+		if !s.Up {
+			continue
+		}
+		up++
+		total += s.HandshakeMS
+		if s.HandshakeMS > st.MaxHandshake {
+			st.MaxHandshake = s.HandshakeMS
+		}
+	}
+	if st.Samples > 0 {
+		st.UptimePct = float64(up) * 100 / float64(st.Samples)
+	}
+	if up > 0 {
+		st.AvgHandshake = float64(total) / float64(up)
+	}
+	return st
+}
+
+// sortedPorts returns the ports ordered by URL. Caller must hold p.mu.
+func (p *PortStatusPlugin) sortedPorts() []*Port {
+	list := make([]*Port, 0, len(p.ports))
+	for _, port := range p.ports {
+		list = append(list, port)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].URL < list[j].URL })
+	return list
+}
+
+// handleListPorts returns every listener with its latest result and 24h stats
+func (p *PortStatusPlugin) handleListPorts(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	since := time.Now().Add(-24 * time.Hour)
+	out := make([]gin.H, 0, len(p.ports))
+	for _, port := range p.sortedPorts() {
+		out = append(out, gin.H{
+			"url":                  port.URL,
+			"kind":                 port.Kind,
+			"addr":                 port.Addr,
+			"up":                   port.Up,
+			"down":                 port.Down,
+			"down_since":           port.DownSince,
+			"consecutive_failures": port.Failures,
+			"last":                 port.Last,
+			"stats_24h":            port.stats(since),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"ports": out})
+}
+
+// handleHistory returns the probe history of one listener
+func (p *PortStatusPlugin) handleHistory(c *gin.Context) {
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hours < 1 {
+		hours = 24
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	port, ok := p.ports[c.Query("url")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listener not found"})
+		return
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	history := make([]Sample, 0)
+	for _, s := range port.History {
+		if !s.Time.Before(since) {
+			history = append(history, s)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"url":     port.URL,
+		"history": history,
+		"stats":   port.stats(since),
+	})
+}
+
+// handleSummary returns a status overview without hostnames or error
+// details, suitable for publishing on a status page
+func (p *PortStatusPlugin) handleSummary(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	since := time.Now().Add(-24 * time.Hour)
+	kinds := make(map[string]gin.H)
+	overall := "operational"
+	for _, port := range p.sortedPorts() {
+		k, ok := kinds[port.Kind]
+		if !ok {
+			k = gin.H{"status": "operational", "ports": make([]gin.H, 0)}
+			kinds[port.Kind] = k
+		}
+
+		status := "operational"
+		switch {
+		case port.Down:
+			status = "down"
+		case !port.Up:
+			status = "degraded"
+		}
+		if status != "operational" {
+			k["status"] = "degraded"
+			overall = "degraded"
+		}
+
+		st := port.stats(since)
+		_, portNum, _ := net.SplitHostPort(port.Addr)
+		k["ports"] = append(k["ports"].([]gin.H), gin.H{
+			"port":           portNum,
+			"status":         status,
+			"uptime_24h":     st.UptimePct,
+			"avg_latency_ms": st.AvgHandshake,
+		})
+	}
+
+	// A kind is down only when all of its ports are down
+	for _, k := range kinds {
+		ports := k["ports"].([]gin.H)
+		down := 0
+		for _, port := range ports {
+			if port["status"] == "down" {
+				down++
+			}
+		}
+		if down > 0 && down == len(ports) {
+			k["status"] = "down"
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     overall,
+		"listeners":  kinds,
+		"updated_at": time.Now().UTC(),
+	})
+}
+
+// handleCheck schedules an immediate probe of all listeners
+func (p *PortStatusPlugin) handleCheck(c *gin.Context) {
+	select {
+	case p.checkNow <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check scheduled"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *PortStatusPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *PortStatusPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *PortStatusPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *PortStatusPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
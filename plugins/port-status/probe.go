@@ -0,0 +1,152 @@
+package portstatus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// Probe kinds
+const (
+	kindPlain     = "plaintext"
+	kindTLS       = "tls"
+	kindWebSocket = "websocket"
+)
+
+// target is a parsed listener URL
+type target struct {
+	URL  string
+	Kind string
+	Addr string
+	Host string
+}
+
+// parseTarget parses irc://, ircs://, ws:// or wss:// listener URLs
+func parseTarget(raw string) (target, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return target{}, err
+	}
+	t := target{URL: raw, Host: u.Hostname()}
+	if t.Host == "" {
+		return target{}, fmt.Errorf("%s: missing host", raw)
+	}
+
+	port := u.Port()
+	switch u.Scheme {
+	case "irc":
+		t.Kind = kindPlain
+		if port == "" {
+			port = "6667"
+		}
+	case "ircs":
+		t.Kind = kindTLS
+		if port == "" {
+			port = "6697"
+		}
+	case "ws":
+		t.Kind = kindWebSocket
+		if port == "" {
+			port = "80"
+		}
+	case "wss":
+		t.Kind = kindWebSocket
+		if port == "" {
+			port = "443"
+		}
+	default:
+		return target{}, fmt.Errorf("%s: unsupported scheme %q", raw, u.Scheme)
+	}
+	t.Addr = net.JoinHostPort(t.Host, port)
+	return t, nil
+}
+
+// probeResult is the outcome of a single probe. ConnectMS is the TCP
+// connect time, HandshakeMS the time until the listener answered at the
+// protocol level (first IRC line, TLS handshake plus first line, or
+// websocket upgrade).
+type probeResult struct {
+	Up          bool
+	ConnectMS   int64
+	HandshakeMS int64
+	Error       string
+}
+
+// probe checks a single listener
+func probe(ctx context.Context, t target, timeout time.Duration, insecure bool) probeResult {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return probeResult{Error: err.Error()}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if t.Kind == kindWebSocket {
+		return probeWebSocket(ctx, t, insecure)
+	}
+
+	start := time.Now()
+	var d net.Dialer
+	raw, err := d.DialContext(ctx, "tcp", t.Addr)
+	if err != nil {
+		return probeResult{Error: err.Error()}
+	}
+	res := probeResult{ConnectMS: time.Since(start).Milliseconds()}
+
+	conn := raw
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if t.Kind == kindTLS {
+		tc := tls.Client(raw, &tls.Config{ServerName: t.Host, InsecureSkipVerify: insecure})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			res.Error = "tls: " + err.Error()
+			return res
+		}
+		conn = tc
+	}
+	defer conn.Close()
+
+	// UnrealIRCd greets new connections with a hostname lookup notice
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+		res.Error = "no greeting: " + err.Error()
+		return res
+	}
+	res.HandshakeMS = time.Since(start).Milliseconds()
+	res.Up = true
+	fmt.Fprint(conn, "QUIT\r\n")
+	return res
+}
+
+// probeWebSocket performs a websocket upgrade against the listener
+func probeWebSocket(ctx context.Context, t target, insecure bool) probeResult {
+	start := time.Now()
+	var connected time.Duration
+	dialer := websocket.Dialer{
+		Subprotocols: []string{"text.ircv3.net", "binary.ircv3.net"},
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, network, addr)
+			connected = time.Since(start)
+			return conn, err
+		},
+	}
+	if insecure {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	conn, _, err := dialer.DialContext(ctx, t.URL, nil)
+	if err != nil {
+		return probeResult{ConnectMS: connected.Milliseconds(), Error: err.Error()}
+	}
+	defer conn.Close()
+
+	return probeResult{Up: true, ConnectMS: connected.Milliseconds(), HandshakeMS: time.Since(start).Milliseconds()}
+}
@@ -0,0 +1,494 @@
+// Prometheus Exporter Plugin for UnrealIRCd Web Panel
+// Exports users, channels, servers, opers, bans and JSON-RPC latency as
+// Prometheus metrics
+
+package prometheusexporter
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+)
+
+// contentType is the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PrometheusExporterPlugin implements the Plugin interface
+type PrometheusExporterPlugin struct {
+	config      Config
+	rpc         *jsonrpc.Client
+	text        []byte
+	collectedAt time.Time
+	duration    time.Duration
+	up          bool
+	collectErr  string
+	latency     map[string]time.Duration
+	rpcErrors   map[string]int
+	collections int
+	scrapes     int
+	lastScrape  time.Time
+	mu          sync.RWMutex
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL       string `json:"rpc_url"`
+	RPCUser      string `json:"rpc_user"`
+	RPCPassword  string `json:"rpc_password"`
+	RPCInsecure  bool   `json:"rpc_insecure"`
+	MetricsToken string `json:"metrics_token"`
+	Interval     int    `json:"interval"`
+	Namespace    string `json:"namespace"`
+	PerServer    bool   `json:"per_server"`
+	BanTypes     bool   `json:"ban_types"`
+}
+
+// statsResult is the part of stats.get we export
+type statsResult struct {
+	Server struct {
+		Total  int `json:"total"`
+		ULined int `json:"ulined"`
+	} `json:"server"`
+	User struct {
+		Total  int `json:"total"`
+		ULined int `json:"ulined"`
+		Oper   int `json:"oper"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+	ServerBan struct {
+		Total              int `json:"total"`
+		ServerBan          int `json:"server_ban"`
+		Spamfilter         int `json:"spamfilter"`
+		NameBan            int `json:"name_ban"`
+		ServerBanException int `json:"server_ban_exception"`
+	} `json:"server_ban"`
+}
+
+// rpcServer is the part of a server.list entry we export
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		NumUsers int  `json:"num_users"`
+		Synced   bool `json:"synced"`
+	} `json:"server"`
+}
+
+// rpcBan is the part of a server_ban.list entry we export
+type rpcBan struct {
+	Type string `json:"type"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &PrometheusExporterPlugin{
+		config: Config{
+			RPCURL:    "https://127.0.0.1:8600/api",
+			Interval:  30,
+			Namespace: "unrealircd",
+			PerServer: true,
+			BanTypes:  true,
+		},
+		latency:   make(map[string]time.Duration),
+		rpcErrors: make(map[string]int),
+	}
+}
+
+// Info returns plugin metadata
+func (p *PrometheusExporterPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Prometheus Exporter",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Exports network and JSON-RPC metrics for Prometheus",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *PrometheusExporterPlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "prometheus-exporter-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"up":      p.up,
+			"scrapes": p.scrapes,
+		}
+		if !p.lastScrape.IsZero() {
+			content["last_scrape"] = p.lastScrape.Format(time.RFC3339)
+		}
+		if p.config.MetricsToken == "" {
+			content["status"] = "No metrics token set"
+		}
+		return plugins.DashboardCard{
+			Title:   "Prometheus Exporter",
+			Icon:    "Activity",
+			Content: content,
+			Order:   68,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.collectLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *PrometheusExporterPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *PrometheusExporterPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/prometheus-exporter")
+	{
+		plugin.GET("/metrics", p.handleMetrics)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *PrometheusExporterPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// collectLoop collects metrics every interval until shutdown. Scrapes are
+// served from the last collection, so a busy Prometheus does not add load
+// on the IRCd.
+func (p *PrometheusExporterPlugin) collectLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.collect()
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.Interval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// collect queries the IRCd and renders a fresh set of metrics. Values the
+// IRCd did not return this time are left out rather than repeated.
+func (p *PrometheusExporterPlugin) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	p.mu.RLock()
+	perServer := p.config.PerServer
+	banTypes := p.config.BanTypes
+	p.mu.RUnlock()
+
+	rpc := p.client()
+	start := time.Now()
+	latency := make(map[string]time.Duration)
+	failed := make([]string, 0)
+	call := func(method string, out interface{}) error {
+		t := time.Now()
+		err := rpc.Call(ctx, method, nil, out)
+		if err != nil {
+			failed = append(failed, method)
+			return err
+		}
+		latency[method] = time.Since(t)
+		return nil
+	}
+
+	var stats statsResult
+	statsErr := call("stats.get", &stats)
+
+	var servers struct {
+		List []rpcServer `json:"list"`
+	}
+	serversErr := statsErr
+	if statsErr == nil && perServer {
+		serversErr = call("server.list", &servers)
+	}
+
+	var bans struct {
+		List []rpcBan `json:"list"`
+	}
+	bansErr := statsErr
+	if statsErr == nil && banTypes {
+		bansErr = call("server_ban.list", &bans)
+	}
+	duration := time.Since(start)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.collections++
+	p.collectedAt = time.Now()
+	p.duration = duration
+	p.up = statsErr == nil
+	p.latency = latency
+	for _, method := range failed {
+		p.rpcErrors[method]++
+	}
+	p.collectErr = ""
+	if statsErr != nil {
+		p.collectErr = statsErr.Error()
+		log.Printf("[prometheus-exporter] failed to collect metrics: %v", statsErr)
+	}
+
+	ns := p.config.Namespace
+	metric := func(name, typ, help string) *family {
+		return &family{Name: ns + "_" + name, Type: typ, Help: help}
+	}
+	families := make([]*family, 0, 20)
+
+	up := metric("up", Gauge, "Whether the last collection from the IRCd succeeded")
+	if p.up {
+		up.add(1)
+	} else {
+		up.add(0)
+	}
+	families = append(families, up)
+
+	rpcLatency := metric("rpc_latency_seconds", Gauge, "Round trip of the last successful JSON-RPC call, by method")
+	methods := make([]string, 0, len(latency))
+	for method := range latency {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		rpcLatency.add(latency[method].Seconds(), "method", method)
+	}
+	rpcErrors := metric("rpc_errors_total", Counter, "Failed JSON-RPC calls since the panel started, by method")
+	for _, method := range sortedKeys(p.rpcErrors) {
+		rpcErrors.add(float64(p.rpcErrors[method]), "method", method)
+	}
+	collections := metric("collections_total", Counter, "Collections since the panel started")
+	collections.add(float64(p.collections))
+	took := metric("collection_duration_seconds", Gauge, "How long the last collection took")
+	took.add(duration.Seconds())
+	last := metric("last_collection_timestamp_seconds", Gauge, "When the last collection finished, in seconds since 1970")
+	last.add(float64(p.collectedAt.UnixNano()) / 1e9)
+	families = append(families, rpcLatency, rpcErrors, collections, took, last)
+
+	if statsErr == nil {
+		users := metric("users", Gauge, "Users on the network")
+		users.add(float64(stats.User.Total))
+		ulinedUsers := metric("users_ulined", Gauge, "Users on U-lined servers, such as services bots")
+		ulinedUsers.add(float64(stats.User.ULined))
+		record := metric("users_record", Gauge, "Highest number of users seen by the IRCd")
+		record.add(float64(stats.User.Record))
+		opers := metric("opers", Gauge, "IRC operators on the network")
+		opers.add(float64(stats.User.Oper))
+		channels := metric("channels", Gauge, "Channels on the network")
+		channels.add(float64(stats.Channel.Total))
+		serverCount := metric("servers", Gauge, "Servers on the network")
+		serverCount.add(float64(stats.Server.Total))
+		ulinedServers := metric("servers_ulined", Gauge, "U-lined servers on the network")
+		ulinedServers.add(float64(stats.Server.ULined))
+		banCount := metric("bans", Gauge, "Server bans, spamfilters, name bans and ban exceptions")
+		banCount.add(float64(stats.ServerBan.ServerBan), "kind", "server_ban")
+		banCount.add(float64(stats.ServerBan.Spamfilter), "kind", "spamfilter")
+		banCount.add(float64(stats.ServerBan.NameBan), "kind", "name_ban")
+		banCount.add(float64(stats.ServerBan.ServerBanException), "kind", "server_ban_exception")
+		families = append(families, users, ulinedUsers, record, opers, channels, serverCount, ulinedServers, banCount)
+	}
+
+	if serversErr == nil && perServer {
+		serverUsers := metric("server_users", Gauge, "Users on each server")
+		synced := metric("server_synced", Gauge, "Whether each server has finished syncing with the network")
+		sort.Slice(servers.List, func(i, j int) bool { return servers.List[i].Name < servers.List[j].Name })
+		for _, s := range servers.List {
+			serverUsers.add(float64(s.Server.NumUsers), "server", s.Name)
+			if s.Server.Synced {
+				synced.add(1, "server", s.Name)
+			} else {
+				synced.add(0, "server", s.Name)
+			}
+		}
+		families = append(families, serverUsers, synced)
+	}
+
+	if bansErr == nil && banTypes {
+		counts := make(map[string]int)
+		for _, b := range bans.List {
+			counts[strings.ToLower(b.Type)]++
+		}
+		byType := metric("server_bans", Gauge, "Server bans by type, such as gline or zline")
+		for _, t := range sortedKeys(counts) {
+			byType.add(float64(counts[t]), "type", t)
+		}
+		families = append(families, byType)
+	}
+
+	p.text = writeText(families)
+}
+
+// sortedKeys returns the keys of a count map in order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// scrapeToken returns the token of a scrape, from an Authorization bearer
+// header or the token query parameter
+func scrapeToken(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return c.Query("token")
+}
+
+// handleMetrics serves the last collection to Prometheus
+func (p *PrometheusExporterPlugin) handleMetrics(c *gin.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	token := p.config.MetricsToken
+	if token == "" || subtle.ConstantTimeCompare([]byte(scrapeToken(c)), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+		return
+	}
+	if p.text == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No metrics collected yet"})
+		return
+	}
+	p.scrapes++
+	p.lastScrape = time.Now()
+	c.Data(http.StatusOK, contentType, p.text)
+}
+
+// handleStatus returns the state of the last collection
+func (p *PrometheusExporterPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	latency := make(map[string]float64, len(p.latency))
+	for method, d := range p.latency {
+		latency[method] = d.Seconds()
+	}
+	status := gin.H{
+		"up":             p.up,
+		"error":          p.collectErr,
+		"collections":    p.collections,
+		"duration":       p.duration.Seconds(),
+		"rpc_latency":    latency,
+		"rpc_errors":     p.rpcErrors,
+		"scrapes":        p.scrapes,
+		"token_set":      p.config.MetricsToken != "",
+		"metrics_length": len(p.text),
+	}
+	if !p.collectedAt.IsZero() {
+		status["collected_at"] = p.collectedAt
+	}
+	if !p.lastScrape.IsZero() {
+		status["last_scrape"] = p.lastScrape
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *PrometheusExporterPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.MetricsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *PrometheusExporterPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.Interval < 5 || newConfig.Interval > 600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be between 5 and 600 seconds"})
+		return
+	}
+	if !validNamespace(newConfig.Namespace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace may only contain letters, digits, _ and :, and must not start with a digit"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.MetricsToken == "" {
+		newConfig.MetricsToken = p.config.MetricsToken
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *PrometheusExporterPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *PrometheusExporterPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
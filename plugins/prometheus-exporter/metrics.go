@@ -0,0 +1,112 @@
+package prometheusexporter
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metric types
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+)
+
+// family is a metric with its samples
+type family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []sample
+}
+
+// sample is one value of a family. Labels are written in sorted order.
+type sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// add appends a sample with labels given as name, value pairs
+func (f *family) add(value float64, labels ...string) {
+	s := sample{Value: value}
+	if len(labels) > 0 {
+		s.Labels = make(map[string]string, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			s.Labels[labels[i]] = labels[i+1]
+		}
+	}
+	f.Samples = append(f.Samples, s)
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// helpEscaper escapes HELP text as the text format requires
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// formatValue writes a sample value the way Prometheus expects
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// writeText renders families in the Prometheus text exposition format,
+// version 0.0.4. Families without samples are left out.
+func writeText(families []*family) []byte {
+	var b bytes.Buffer
+	for _, f := range families {
+		if len(f.Samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.Name, helpEscaper.Replace(f.Help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.Name, f.Type)
+		for _, s := range f.Samples {
+			b.WriteString(f.Name)
+			if len(s.Labels) > 0 {
+				names := make([]string, 0, len(s.Labels))
+				for name := range s.Labels {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				b.WriteByte('{')
+				for i, name := range names {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, `%s="%s"`, name, labelEscaper.Replace(s.Labels[name]))
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(formatValue(s.Value))
+			b.WriteByte('\n')
+		}
+	}
+	return b.Bytes()
+}
+
+// validNamespace reports whether s can prefix metric names
+func validNamespace(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
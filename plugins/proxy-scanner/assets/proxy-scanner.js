@@ -0,0 +1,294 @@
+/**
+ * Proxy Scanner Frontend Script
+ *
+ * Shows the open proxies found among connecting clients and the recent
+ * scans, and scans an address on request.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'proxy-scanner';
+  const PLUGIN_NAME = 'Proxy Scanner';
+  const PAGE_PATH = '/plugins/proxy-scanner';
+  const API_BASE = '/api/plugin/proxy-scanner';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('proxy-scanner-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'proxy-scanner-styles';
+    style.textContent = `
+      .pxs-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .pxs-row { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .pxs-app input {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+        min-width: 16rem;
+      }
+      .pxs-app button {
+        background: var(--accent, #89b4fa);
+        color: var(--bg-primary, #11111b);
+        border: none;
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+      }
+      .pxs-app button.pxs-secondary {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+      }
+      .pxs-result {
+        padding: 1rem;
+        border-radius: 8px;
+        background: var(--bg-secondary, #1e1e2e);
+        border: 1px solid var(--border-primary, #313244);
+      }
+      .pxs-table { width: 100%; border-collapse: collapse; }
+      .pxs-table th, .pxs-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        vertical-align: top;
+      }
+      .pxs-open { color: var(--error, #f38ba8); }
+      .pxs-clean { color: var(--success, #a6e3a1); }
+      .pxs-meta { font-size: 0.75rem; color: var(--text-muted, #6c7086); }
+      .pxs-warning { color: var(--warning, #f9e2af); }
+      .pxs-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderProxies(proxies) {
+    return proxies.map(op => `<div>${op.port}: ${escapeHtml(op.detail)}</div>`).join('');
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#pxs-status');
+    try {
+      const s = await api('GET', '/status');
+      body.innerHTML = `
+        <div class="pxs-meta">
+          ${s.streaming ? 'Following connects' : '<span class="pxs-error">Log stream not connected</span>'} -
+          scanning clients on: ${escapeHtml(s.scan_servers.join(', ') || 'no servers')} -
+          ports ${escapeHtml(s.ports.join(', '))} -
+          action: ${escapeHtml(s.action)} -
+          ${s.queued} queued, ${s.running} running${s.dropped ? `, ${s.dropped} dropped` : ''}
+        </div>
+        ${s.scan_servers.length === 0 ? '<div class="pxs-warning">No servers are opted in to scanning. Set Scan Servers in the plugin settings.</div>' : ''}
+        ${s.check_target ? '' : '<div class="pxs-warning">No check target is set, so only SOCKS5 proxies can be detected.</div>'}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="pxs-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadFindings(container) {
+    const body = container.querySelector('#pxs-findings');
+    try {
+      const data = await api('GET', '/findings');
+      if (data.findings.length === 0) {
+        body.innerHTML = '<p>No open proxies found.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="pxs-table">
+          <thead><tr><th>IP</th><th>Proxies</th><th>Client</th><th>Found</th><th>Last seen</th><th>Action</th><th></th></tr></thead>
+          <tbody>
+            ${data.findings.map(f => `
+              <tr>
+                <td><code>${escapeHtml(f.ip)}</code></td>
+                <td>${renderProxies(f.proxies)}</td>
+                <td>${escapeHtml(f.nick || '')}${f.server ? `<div class="pxs-meta">${escapeHtml(f.server)}</div>` : ''}</td>
+                <td>${formatTime(f.found_at)}</td>
+                <td>${formatTime(f.last_seen)}</td>
+                <td>${f.action ? `${escapeHtml(f.action)}: ${escapeHtml(f.result)}` : ''}</td>
+                <td><button class="pxs-secondary" data-forget="${escapeHtml(f.ip)}">Forget</button></td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+      body.querySelectorAll('[data-forget]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          try {
+            await api('DELETE', `/findings/${encodeURIComponent(btn.dataset.forget)}`);
+            loadFindings(container);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="pxs-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadScans(container) {
+    const body = container.querySelector('#pxs-scans');
+    const openOnly = container.querySelector('#pxs-open-only').checked;
+    try {
+      const data = await api('GET', `/scans?limit=100${openOnly ? '&open=1' : ''}`);
+      if (data.scans.length === 0) {
+        body.innerHTML = '<p>No scans yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="pxs-table">
+          <thead><tr><th>Time</th><th>IP</th><th>Client</th><th>Trigger</th><th>Result</th><th>Took</th></tr></thead>
+          <tbody>
+            ${data.scans.map(s => `
+              <tr>
+                <td>${formatTime(s.time)}</td>
+                <td><code>${escapeHtml(s.ip)}</code></td>
+                <td>${escapeHtml(s.nick || '')}${s.server ? `<div class="pxs-meta">${escapeHtml(s.server)}</div>` : ''}</td>
+                <td>${escapeHtml(s.trigger)}</td>
+                <td>${s.open ? `<span class="pxs-open">${s.open} open</span>` : '<span class="pxs-clean">clean</span>'}</td>
+                <td>${(s.duration_ms / 1000).toFixed(1)}s</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="pxs-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function scan(container, ip) {
+    const out = container.querySelector('#pxs-result');
+    out.innerHTML = 'Scanning...';
+    try {
+      const data = await api('POST', '/scan', { ip });
+      out.innerHTML = `
+        <div class="pxs-result">
+          ${data.finding
+            ? `<div class="pxs-open">Open proxy on <code>${escapeHtml(data.scan.ip)}</code></div>${renderProxies(data.finding.proxies)}`
+            : `<div class="pxs-clean">No open proxy found on <code>${escapeHtml(data.scan.ip)}</code></div>`}
+          <div class="pxs-meta">Took ${(data.scan.duration_ms / 1000).toFixed(1)}s</div>
+        </div>
+      `;
+      loadFindings(container);
+      loadScans(container);
+    } catch (e) {
+      out.innerHTML = `<div class="pxs-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="pxs-app" data-plugin="${PLUGIN_ID}">
+        <div id="pxs-status"></div>
+        <div class="pxs-row">
+          <input type="text" id="pxs-ip" placeholder="IPv4 or IPv6 address">
+          <button id="pxs-scan">Scan now</button>
+        </div>
+        <div id="pxs-result"></div>
+        <h3>Open proxies</h3>
+        <div id="pxs-findings">Loading...</div>
+        <div class="pxs-row">
+          <h3>Recent scans</h3>
+          <label><input type="checkbox" id="pxs-open-only" style="min-width: 0"> Only with open proxies</label>
+        </div>
+        <div id="pxs-scans">Loading...</div>
+      </div>
+    `;
+
+    const input = container.querySelector('#pxs-ip');
+    const run = () => {
+      const ip = input.value.trim();
+      if (ip) scan(container, ip);
+    };
+    container.querySelector('#pxs-scan').addEventListener('click', run);
+    input.addEventListener('keydown', e => { if (e.key === 'Enter') run(); });
+    container.querySelector('#pxs-open-only').addEventListener('change', () => loadScans(container));
+
+    loadStatus(container);
+    loadFindings(container);
+    loadScans(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('proxy-scanner-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
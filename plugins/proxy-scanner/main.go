@@ -0,0 +1,907 @@
+// Proxy Scanner Plugin for UnrealIRCd Web Panel
+// Probes connecting clients for open SOCKS and HTTP proxies, records what
+// it finds, optionally bans them and publishes them for the threat score
+
+package proxyscanner
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Actions on a client found to be an open proxy
+const (
+	ActionNone   = "none"
+	ActionGline  = "gline"
+	ActionGzline = "gzline"
+)
+
+// Scan triggers
+const (
+	TriggerConnect = "connect"
+	TriggerManual  = "manual"
+)
+
+// Limits on queued and concurrent work
+const (
+	queueSize     = 1000
+	maxConcurrent = 200
+	maxPorts      = 32
+)
+
+// ProxyScannerPlugin implements the Plugin interface
+type ProxyScannerPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	prober       *prober
+	findings     map[string]*Finding
+	scans        []*Scan
+	scanned      map[string]time.Time
+	pending      map[string]bool
+	queue        chan scanJob
+	running      int
+	dropped      int
+	dirty        bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	StreamURL      string `json:"stream_url"`
+	LogSources     string `json:"log_sources"`
+	DataDir        string `json:"data_dir"`
+	ScanServers    string `json:"scan_servers"`
+	ScanRanges     string `json:"scan_ranges"`
+	ExemptRanges   string `json:"exempt_ranges"`
+	ExemptLoggedIn bool   `json:"exempt_logged_in"`
+	Ports          string `json:"ports"`
+	CheckTarget    string `json:"check_target"`
+	TimeoutMS      int    `json:"timeout_ms"`
+	MaxConcurrent  int    `json:"max_concurrent"`
+	RescanHours    int    `json:"rescan_hours"`
+	Action         string `json:"action"`
+	BanDuration    string `json:"ban_duration"`
+	BanReason      string `json:"ban_reason"`
+	FindingDays    int    `json:"finding_days"`
+	MaxScans       int    `json:"max_scans"`
+}
+
+// Finding is an address with at least one open proxy
+type Finding struct {
+	IP       string      `json:"ip"`
+	Proxies  []OpenProxy `json:"proxies"`
+	Nick     string      `json:"nick,omitempty"`
+	Server   string      `json:"server,omitempty"`
+	FoundAt  time.Time   `json:"found_at"`
+	LastSeen time.Time   `json:"last_seen"`
+	Action   string      `json:"action,omitempty"`
+	Result   string      `json:"result,omitempty"`
+}
+
+// OpenProxy is a port that acts as an open proxy
+type OpenProxy struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Detail   string `json:"detail"`
+}
+
+// Scan is a record of one scanned address
+type Scan struct {
+	IP         string    `json:"ip"`
+	Nick       string    `json:"nick,omitempty"`
+	Server     string    `json:"server,omitempty"`
+	Trigger    string    `json:"trigger"`
+	Time       time.Time `json:"time"`
+	DurationMS int64     `json:"duration_ms"`
+	Open       int       `json:"open"`
+}
+
+// scanJob is an address waiting to be scanned
+type scanJob struct {
+	IP      string
+	Nick    string
+	Server  string
+	Trigger string
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Findings map[string]*Finding `json:"findings"`
+	Scans    []*Scan             `json:"scans"`
+}
+
+// feedEntry is an open proxy as published in the feed file
+type feedEntry struct {
+	Type    string    `json:"type"`
+	Ports   []int     `json:"ports"`
+	FoundAt time.Time `json:"found_at"`
+}
+
+// connectClient is the subset of the UnrealIRCd client object we need
+type connectClient struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+	User struct {
+		Account    string `json:"account"`
+		Servername string `json:"servername"`
+	} `json:"user"`
+}
+
+// connectEvent holds the objects we need from a connect log entry
+type connectEvent struct {
+	LogSource string         `json:"log_source"`
+	Client    *connectClient `json:"client"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ProxyScannerPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			StreamURL:      "wss://127.0.0.1:8600/",
+			LogSources:     "connect",
+			DataDir:        "data/plugins/proxy-scanner",
+			ExemptLoggedIn: true,
+			Ports:          "1080,1081,3128,4145,8080,8000,8888,9050,80",
+			TimeoutMS:      3000,
+			MaxConcurrent:  20,
+			RescanHours:    24,
+			Action:         ActionNone,
+			BanDuration:    "7d",
+			BanReason:      "Open proxy detected on your address",
+			FindingDays:    30,
+			MaxScans:       2000,
+		},
+		findings: make(map[string]*Finding),
+		scans:    make([]*Scan, 0),
+		scanned:  make(map[string]time.Time),
+		pending:  make(map[string]bool),
+		queue:    make(chan scanJob, queueSize),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ProxyScannerPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Proxy Scanner",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Scans connecting clients for open SOCKS and HTTP proxies",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ProxyScannerPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[proxy-scanner] failed to load data: %v", err)
+	}
+	if data.Findings != nil {
+		p.findings = data.Findings
+	}
+	if data.Scans != nil {
+		p.scans = data.Scans
+	}
+	for _, s := range p.scans {
+		if s.Time.After(p.scanned[s.IP]) {
+			p.scanned[s.IP] = s.Time
+		}
+	}
+	p.mu.Unlock()
+
+	if err := p.writeFeed(); err != nil {
+		log.Printf("[proxy-scanner] failed to write feed: %v", err)
+	}
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "proxy-scanner-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		scans, open := 0, 0
+		for i := len(p.scans) - 1; i >= 0 && p.scans[i].Time.After(since); i-- {
+			scans++
+			if p.scans[i].Open > 0 {
+				open++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Proxy Scanner",
+			Icon:  "Radar",
+			Content: map[string]interface{}{
+				"scans_24h":     scans,
+				"proxies_24h":   open,
+				"open_proxies":  len(p.findings),
+				"scanning_from": p.config.ScanServers,
+			},
+			Order: 59,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(3)
+	go p.streamLoop()
+	go p.scanLoop()
+	go p.saveLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ProxyScannerPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ProxyScannerPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/proxy-scanner")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/findings", p.handleFindings)
+		plugin.DELETE("/findings/:ip", p.handleDeleteFinding)
+		plugin.GET("/scans", p.handleScans)
+		plugin.POST("/scan", p.handleScan)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *ProxyScannerPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "proxy-scanner.json")
+}
+
+// feedPath returns the location of the feed file read by other plugins
+func (p *ProxyScannerPlugin) feedPath() string {
+	return filepath.Join(p.config.DataDir, "open-proxies.json")
+}
+
+// save persists the state if it changed, and publishes the feed with it
+func (p *ProxyScannerPlugin) save() error {
+	p.mu.Lock()
+	if !p.dirty {
+		p.mu.Unlock()
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Findings: p.findings, Scans: p.scans}); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	p.dirty = false
+	p.mu.Unlock()
+	return p.writeFeed()
+}
+
+// writeFeed writes the current open proxies to the feed file
+func (p *ProxyScannerPlugin) writeFeed() error {
+	p.mu.RLock()
+	path := p.feedPath()
+	proxies := make(map[string]feedEntry, len(p.findings))
+	for ip, f := range p.findings {
+		e := feedEntry{Ports: make([]int, 0, len(f.Proxies)), FoundAt: f.FoundAt}
+		for _, op := range f.Proxies {
+			if e.Type == "" {
+				e.Type = op.Protocol
+			}
+			e.Ports = append(e.Ports, op.Port)
+		}
+		proxies[ip] = e
+	}
+	p.mu.RUnlock()
+
+	return storage.SaveJSON(path, map[string]interface{}{
+		"updated": time.Now().UTC(),
+		"proxies": proxies,
+	})
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ProxyScannerPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// getProber returns the prober, creating it from the current config if
+// needed, along with the ports to scan. The check target is resolved
+// outside the lock.
+func (p *ProxyScannerPlugin) getProber() (*prober, []int) {
+	p.mu.RLock()
+	pr := p.prober
+	timeout := time.Duration(p.config.TimeoutMS) * time.Millisecond
+	target := p.config.CheckTarget
+	ports, _ := parsePorts(p.config.Ports)
+	p.mu.RUnlock()
+
+	if pr == nil {
+		pr = newProber(timeout, target)
+		p.mu.Lock()
+		p.prober = pr
+		p.mu.Unlock()
+	}
+	return pr, ports
+}
+
+// parsePorts parses a comma separated list of ports
+func parsePorts(s string) ([]int, error) {
+	ports := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, item := range lists.Split(s) {
+		port, err := strconv.Atoi(item)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", item)
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
+
+// parseRanges parses a comma separated list of CIDR ranges and addresses
+func parseRanges(s string) ([]*net.IPNet, error) {
+	ranges := make([]*net.IPNet, 0)
+	for _, item := range lists.Split(s) {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			ranges = append(ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+		ranges = append(ranges, n)
+	}
+	return ranges, nil
+}
+
+// inRanges reports whether ip is in one of the ranges
+func inRanges(ip net.IP, ranges []*net.IPNet) bool {
+	for _, n := range ranges {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamLoop follows connect log events until shutdown
+func (p *ProxyScannerPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *ProxyScannerPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[proxy-scanner] log stream: %v", err)
+	}
+}
+
+// saveLoop expires old findings and saves the state every minute until
+// shutdown
+func (p *ProxyScannerPlugin) saveLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.prune()
+			if err := p.save(); err != nil {
+				log.Printf("[proxy-scanner] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// prune drops findings not seen for finding_days and forgets scan times
+// older than rescan_hours
+func (p *ProxyScannerPlugin) prune() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.config.FindingDays > 0 {
+		cutoff := time.Now().Add(-time.Duration(p.config.FindingDays) * 24 * time.Hour)
+		for ip, f := range p.findings {
+			if f.LastSeen.Before(cutoff) {
+				delete(p.findings, ip)
+				p.dirty = true
+			}
+		}
+	}
+	cutoff := time.Now().Add(-time.Duration(p.config.RescanHours) * time.Hour)
+	for ip, t := range p.scanned {
+		if t.Before(cutoff) {
+			delete(p.scanned, ip)
+		}
+	}
+}
+
+// handleEvent queues a scan of each connecting client the settings opt in
+func (p *ProxyScannerPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	if !strings.HasSuffix(ev.EventID, "_CLIENT_CONNECT") {
+		return
+	}
+	var ce connectEvent
+	if err := json.Unmarshal(ev.Raw, &ce); err != nil || ce.Client == nil || ce.Client.IP == "" {
+		return
+	}
+	server := ce.Client.User.Servername
+	if server == "" {
+		server = ce.LogSource
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.shouldScan(ce.Client, server) {
+		return
+	}
+	p.enqueue(scanJob{IP: ce.Client.IP, Nick: ce.Client.Name, Server: server, Trigger: TriggerConnect})
+}
+
+// shouldScan reports whether a connecting client is opted in to scanning
+// and was not scanned recently. Caller must hold p.mu.
+func (p *ProxyScannerPlugin) shouldScan(cl *connectClient, server string) bool {
+	servers := lists.Split(p.config.ScanServers)
+	if !lists.ContainsFold(servers, "*") && !lists.ContainsFold(servers, server) {
+		return false
+	}
+	ip := net.ParseIP(cl.IP)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() {
+		return false
+	}
+	if p.config.ExemptLoggedIn && cl.User.Account != "" && cl.User.Account != "0" {
+		return false
+	}
+	if ranges, _ := parseRanges(p.config.ScanRanges); len(ranges) > 0 && !inRanges(ip, ranges) {
+		return false
+	}
+	if exempt, _ := parseRanges(p.config.ExemptRanges); inRanges(ip, exempt) {
+		return false
+	}
+	if t, ok := p.scanned[cl.IP]; ok && time.Since(t) < time.Duration(p.config.RescanHours)*time.Hour {
+		return false
+	}
+	return true
+}
+
+// enqueue adds a job to the scan queue unless the address is already
+// waiting. Caller must hold p.mu.
+func (p *ProxyScannerPlugin) enqueue(job scanJob) {
+	if p.pending[job.IP] {
+		return
+	}
+	select {
+	case p.queue <- job:
+		p.pending[job.IP] = true
+	default:
+		p.dropped++
+	}
+}
+
+// scanLoop runs queued scans, at most max_concurrent at a time, until
+// shutdown
+func (p *ProxyScannerPlugin) scanLoop() {
+	defer p.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	var jobs sync.WaitGroup
+	defer jobs.Wait()
+
+	for {
+		p.mu.RLock()
+		queue := p.queue
+		if p.running >= p.config.MaxConcurrent {
+			// Wait for a running scan to finish first
+			queue = nil
+		}
+		p.mu.RUnlock()
+
+		select {
+		case <-p.stop:
+			cancel()
+			return
+		case <-done:
+		case job := <-queue:
+			p.mu.Lock()
+			p.running++
+			p.mu.Unlock()
+
+			jobs.Add(1)
+			go func(job scanJob) {
+				defer jobs.Done()
+				p.scan(ctx, job)
+
+				p.mu.Lock()
+				p.running--
+				delete(p.pending, job.IP)
+				p.mu.Unlock()
+
+				select {
+				case done <- struct{}{}:
+				case <-p.stop:
+				}
+			}(job)
+		}
+	}
+}
+
+// scan probes one address, records the scan and acts on what it finds
+func (p *ProxyScannerPlugin) scan(ctx context.Context, job scanJob) *Scan {
+	pr, ports := p.getProber()
+	start := time.Now()
+	found := pr.Probe(ctx, job.IP, ports)
+
+	s := &Scan{
+		IP:         job.IP,
+		Nick:       job.Nick,
+		Server:     job.Server,
+		Trigger:    job.Trigger,
+		Time:       start.UTC(),
+		DurationMS: time.Since(start).Milliseconds(),
+		Open:       len(found),
+	}
+
+	p.mu.Lock()
+	p.scanned[job.IP] = start
+	p.scans = append(p.scans, s)
+	if limit := p.config.MaxScans; limit > 0 && len(p.scans) > limit {
+		p.scans = p.scans[len(p.scans)-limit:]
+	}
+	p.dirty = true
+
+	if len(found) == 0 {
+		// A proxy that was found before has been closed
+		delete(p.findings, job.IP)
+		p.mu.Unlock()
+		return s
+	}
+
+	f, known := p.findings[job.IP]
+	if !known {
+		f = &Finding{IP: job.IP, FoundAt: start.UTC()}
+		p.findings[job.IP] = f
+	}
+	f.Proxies = make([]OpenProxy, 0, len(found))
+	for _, r := range found {
+		f.Proxies = append(f.Proxies, OpenProxy{Port: r.Port, Protocol: r.Protocol, Detail: r.Detail})
+	}
+	f.LastSeen = start.UTC()
+	if job.Nick != "" {
+		f.Nick, f.Server = job.Nick, job.Server
+	}
+	action := p.config.Action
+	duration, reason := p.config.BanDuration, p.config.BanReason
+	p.mu.Unlock()
+
+	log.Printf("[proxy-scanner] open proxy on %s (%s): %s", job.IP, job.Nick, found[0].Detail)
+
+	if job.Trigger == TriggerConnect && (action == ActionGline || action == ActionGzline) {
+		p.act(job.IP, action, duration, reason)
+	}
+	if err := p.save(); err != nil {
+		log.Printf("[proxy-scanner] failed to save data: %v", err)
+	}
+	return s
+}
+
+// act bans an open proxy over JSON-RPC and records the result on its
+// finding, unless a later scan or a user removed it in the meantime
+func (p *ProxyScannerPlugin) act(ip, action, duration, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var out json.RawMessage
+	err := p.client().Call(ctx, "server_ban.add", map[string]interface{}{
+		"name":            "*@" + ip,
+		"type":            action,
+		"reason":          reason,
+		"duration_string": duration,
+	}, &out)
+
+	result := "ok"
+	if err != nil {
+		result = "failed: " + err.Error()
+		log.Printf("[proxy-scanner] %s on %s failed: %v", action, ip, err)
+	}
+	p.mu.Lock()
+	if f, ok := p.findings[ip]; ok {
+		f.Action, f.Result = action, result
+		p.dirty = true
+	}
+	p.mu.Unlock()
+}
+
+// handleStatus reports the stream and queue state
+func (p *ProxyScannerPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ports, _ := parsePorts(p.config.Ports)
+	c.JSON(http.StatusOK, gin.H{
+		"streaming":    p.streamOK,
+		"scan_servers": lists.Split(p.config.ScanServers),
+		"ports":        ports,
+		"check_target": p.config.CheckTarget,
+		"action":       p.config.Action,
+		"queued":       len(p.queue),
+		"running":      p.running,
+		"dropped":      p.dropped,
+		"open_proxies": len(p.findings),
+		"scans":        len(p.scans),
+	})
+}
+
+// handleFindings returns the open proxies found, most recent first
+func (p *ProxyScannerPlugin) handleFindings(c *gin.Context) {
+	p.mu.RLock()
+	list := make([]Finding, 0, len(p.findings))
+	for _, f := range p.findings {
+		list = append(list, *f)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].LastSeen.After(list[j].LastSeen) })
+	c.JSON(http.StatusOK, gin.H{"findings": list})
+}
+
+// handleDeleteFinding forgets an open proxy, for example after it was
+// closed, and lets the address be scanned again
+func (p *ProxyScannerPlugin) handleDeleteFinding(c *gin.Context) {
+	ip := c.Param("ip")
+
+	p.mu.Lock()
+	if _, ok := p.findings[ip]; !ok {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Finding not found"})
+		return
+	}
+	delete(p.findings, ip)
+	delete(p.scanned, ip)
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Finding removed"})
+}
+
+// handleScans returns recent scans, newest first, optionally only those
+// that found a proxy
+func (p *ProxyScannerPlugin) handleScans(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	openOnly := c.Query("open") == "1"
+
+	p.mu.RLock()
+	list := make([]*Scan, 0, limit)
+	for i := len(p.scans) - 1; i >= 0 && len(list) < limit; i-- {
+		if !openOnly || p.scans[i].Open > 0 {
+			list = append(list, p.scans[i])
+		}
+	}
+	total := len(p.scans)
+	p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"scans": list, "total": total})
+}
+
+// handleScan scans one address now, ignoring the opt-in settings, and
+// returns the result. Manual scans never lead to a ban.
+func (p *ProxyScannerPlugin) handleScan(c *gin.Context) {
+	var req struct {
+		IP string `json:"ip"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	addr := net.ParseIP(strings.TrimSpace(req.IP))
+	if addr == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	s := p.scan(ctx, scanJob{IP: addr.String(), Nick: access.Actor(c), Trigger: TriggerManual})
+	if err := p.save(); err != nil {
+		log.Printf("[proxy-scanner] failed to save data: %v", err)
+	}
+
+	p.mu.RLock()
+	var f *Finding
+	if cur, ok := p.findings[s.IP]; ok {
+		cp := *cur
+		f = &cp
+	}
+	p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"scan": s, "finding": f})
+}
+
+// validate checks a new configuration
+func (cfg *Config) validate() error {
+	switch cfg.Action {
+	case ActionNone, ActionGline, ActionGzline:
+	default:
+		return fmt.Errorf("action must be none, gline or gzline")
+	}
+	ports, err := parsePorts(cfg.Ports)
+	if err != nil {
+		return err
+	}
+	if len(ports) == 0 || len(ports) > maxPorts {
+		return fmt.Errorf("between 1 and %d ports must be set", maxPorts)
+	}
+	if cfg.TimeoutMS < 100 || cfg.TimeoutMS > 30000 {
+		return fmt.Errorf("timeout_ms must be between 100 and 30000")
+	}
+	if cfg.MaxConcurrent < 1 || cfg.MaxConcurrent > maxConcurrent {
+		return fmt.Errorf("max_concurrent must be between 1 and %d", maxConcurrent)
+	}
+	if _, err := parseRanges(cfg.ScanRanges); err != nil {
+		return fmt.Errorf("scan_ranges: %v", err)
+	}
+	if _, err := parseRanges(cfg.ExemptRanges); err != nil {
+		return fmt.Errorf("exempt_ranges: %v", err)
+	}
+	if cfg.CheckTarget != "" {
+		if _, _, err := net.SplitHostPort(cfg.CheckTarget); err != nil {
+			return fmt.Errorf("check_target must be host:port")
+		}
+	}
+	return nil
+}
+
+// handleGetConfig returns the current configuration
+func (p *ProxyScannerPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ProxyScannerPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if err := newConfig.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.prober = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ProxyScannerPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ProxyScannerPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	p.prober = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,207 @@
+package proxyscanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// Protocols probed for
+const (
+	ProtoSOCKS5 = "socks5"
+	ProtoSOCKS4 = "socks4"
+	ProtoHTTP   = "http"
+)
+
+// probeResult is an open proxy found on a port
+type probeResult struct {
+	Port     int
+	Protocol string
+	Detail   string
+}
+
+// prober tries ports of an address for open proxies
+type prober struct {
+	timeout time.Duration
+	target  string
+	// targetV4 is the IPv4 address of target, needed for SOCKS4
+	targetV4 net.IP
+}
+
+// newProber creates a prober. target is the host:port that proxies are
+// asked to connect to; without one only the SOCKS5 handshake is checked.
+func newProber(timeout time.Duration, target string) *prober {
+	pr := &prober{timeout: timeout, target: target}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			pr.targetV4 = ip.To4()
+		} else if addrs, err := net.LookupIP(host); err == nil {
+			for _, a := range addrs {
+				if v4 := a.To4(); v4 != nil {
+					pr.targetV4 = v4
+					break
+				}
+			}
+		}
+	}
+	return pr
+}
+
+// Probe checks the ports of ip in parallel and returns the open proxies
+// found, in port order. A port stops at the first protocol that answers
+// as an open proxy, and at the first connection that fails.
+func (pr *prober) Probe(ctx context.Context, ip string, ports []int) []probeResult {
+	results := make([]*probeResult, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i, port int) {
+			defer wg.Done()
+			addr := net.JoinHostPort(ip, strconv.Itoa(port))
+			for _, check := range []func(context.Context, string) (string, error){pr.socks5, pr.socks4, pr.httpConnect} {
+				detail, err := check(ctx, addr)
+				if err != nil {
+					return
+				}
+				if detail != "" {
+					results[i] = &probeResult{Port: port, Protocol: strings.SplitN(detail, " ", 2)[0], Detail: detail}
+					return
+				}
+			}
+		}(i, port)
+	}
+	wg.Wait()
+
+	found := make([]probeResult, 0)
+	for _, r := range results {
+		if r != nil {
+			found = append(found, *r)
+		}
+	}
+	return found
+}
+
+// dial connects to addr with the probe timeout as an overall deadline
+func (pr *prober) dial(ctx context.Context, addr string) (net.Conn, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	d := net.Dialer{Timeout: pr.timeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(pr.timeout))
+	return conn, nil
+}
+
+// targetHostPort splits the target into host and port
+func (pr *prober) targetHostPort() (string, uint16, bool) {
+	host, portStr, err := net.SplitHostPort(pr.target)
+	if err != nil {
+		return "", 0, false
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, false
+	}
+	return host, uint16(port), true
+}
+
+// The checks below return a description of the open proxy found, or ""
+// when the port does not act as one. An error means no connection could be
+// made at all, so there is no point trying other protocols on the port.
+
+// socks5 checks for a SOCKS5 proxy that needs no authentication and, with
+// a target, connects through it
+func (pr *prober) socks5(ctx context.Context, addr string) (string, error) {
+	conn, err := pr.dial(ctx, addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte{5, 1, 0}); err != nil {
+		return "", nil
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil || reply[0] != 5 || reply[1] != 0 {
+		return "", nil
+	}
+
+	host, port, ok := pr.targetHostPort()
+	if !ok || len(host) > 255 {
+		return ProtoSOCKS5 + " (accepts clients without authentication)", nil
+	}
+	req := []byte{5, 1, 0, 3, byte(len(host))}
+	req = append(req, host...)
+	req = binary.BigEndian.AppendUint16(req, port)
+	if _, err := conn.Write(req); err != nil {
+		return "", nil
+	}
+	reply = make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil || reply[0] != 5 || reply[1] != 0 {
+		return "", nil
+	}
+	return ProtoSOCKS5 + " (connected to " + pr.target + ")", nil
+}
+
+// socks4 checks for a SOCKS4 proxy that connects to the target
+func (pr *prober) socks4(ctx context.Context, addr string) (string, error) {
+	_, port, ok := pr.targetHostPort()
+	if !ok || pr.targetV4 == nil {
+		return "", nil
+	}
+	conn, err := pr.dial(ctx, addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	req := []byte{4, 1}
+	req = binary.BigEndian.AppendUint16(req, port)
+	req = append(req, pr.targetV4...)
+	req = append(req, 0)
+	if _, err := conn.Write(req); err != nil {
+		return "", nil
+	}
+	reply := make([]byte, 8)
+	if _, err := io.ReadFull(conn, reply); err != nil || reply[1] != 0x5a {
+		return "", nil
+	}
+	return ProtoSOCKS4 + " (connected to " + pr.target + ")", nil
+}
+
+// httpConnect checks for an HTTP proxy that accepts CONNECT to the target
+func (pr *prober) httpConnect(ctx context.Context, addr string) (string, error) {
+	if _, _, ok := pr.targetHostPort(); !ok {
+		return "", nil
+	}
+	conn, err := pr.dial(ctx, addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "CONNECT %s HTTP/1.0\r\nHost: %s\r\n\r\n", pr.target, pr.target); err != nil {
+		return "", nil
+	}
+	line, err := bufio.NewReader(io.LimitReader(conn, 1024)).ReadString('\n')
+	if err != nil {
+		return "", nil
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") || fields[1] != "200" {
+		return "", nil
+	}
+	return ProtoHTTP + " CONNECT (connected to " + pr.target + ")", nil
+}
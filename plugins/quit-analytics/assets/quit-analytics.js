@@ -0,0 +1,247 @@
+/**
+ * Quit Analytics Frontend Script
+ *
+ * Draws daily quit reason breakdowns and lists kills per oper.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'quit-analytics';
+  const PLUGIN_NAME = 'Quit Analytics';
+  const PAGE_PATH = '/plugins/quit-analytics';
+  const API_BASE = '/api/plugin/quit-analytics';
+  const COLORS = {
+    quit: '#89b4fa',
+    ping_timeout: '#f9e2af',
+    read_error: '#fab387',
+    tls_error: '#cba6f7',
+    banned: '#f38ba8',
+    killed: '#eba0ac',
+    excess_flood: '#94e2d5',
+    sendq: '#74c7ec',
+    registration_timeout: '#a6e3a1',
+    other: '#6c7086'
+  };
+
+  let rangeDays = 30;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  // Stacked bars of quits per day, one colour per category
+  function breakdownChart(data, width, height) {
+    if (data.total === 0) {
+      return '<div class="quitstats-empty">No quits recorded in this range yet</div>';
+    }
+    const max = Math.max(...data.days.map(d => d.total), 1);
+    const step = width / data.days.length;
+    const bars = data.days.map((d, i) => {
+      let y = height;
+      return data.categories.map(cat => {
+        const count = d.categories[cat.name] || 0;
+        if (!count) return '';
+        const h = (count / max) * (height - 10);
+        y -= h;
+        return `<rect x="${(i * step + 1).toFixed(1)}" y="${y.toFixed(1)}" width="${Math.max(step - 2, 1).toFixed(1)}" height="${h.toFixed(1)}" fill="${COLORS[cat.name]}"><title>${escapeHtml(d.date)}: ${count} ${escapeHtml(cat.label)}</title></rect>`;
+      }).join('');
+    }).join('');
+
+    return `<svg viewBox="0 0 ${width} ${height}" class="quitstats-chart" preserveAspectRatio="none">${bars}</svg>`;
+  }
+
+  function legend(data) {
+    return `
+      <div class="quitstats-legend">
+        ${data.categories.map(cat => `
+          <span><i style="background:${COLORS[cat.name]}"></i>${escapeHtml(cat.label)}
+            <strong>${data.totals[cat.name] || 0}</strong>
+            ${data.total ? `(${(100 * (data.totals[cat.name] || 0) / data.total).toFixed(1)}%)` : ''}</span>
+        `).join('')}
+      </div>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('quit-analytics-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'quit-analytics-styles';
+    style.textContent = `
+      .quitstats-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .quitstats-chart { width: 100%; height: 200px; background: var(--bg-secondary, #181825); border-radius: 8px; }
+      .quitstats-legend { display: flex; flex-wrap: wrap; gap: 0.75rem; font-size: 0.85rem; }
+      .quitstats-legend i { display: inline-block; width: 0.8rem; height: 0.8rem; border-radius: 2px; margin-right: 0.3rem; vertical-align: middle; }
+      .quitstats-table { width: 100%; border-collapse: collapse; }
+      .quitstats-table th, .quitstats-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+      }
+      .quitstats-range button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        margin-right: 0.25rem;
+        cursor: pointer;
+      }
+      .quitstats-range button.active { background: var(--accent, #89b4fa); color: #fff; }
+      .quitstats-muted { color: var(--text-muted, #6c7086); }
+      .quitstats-empty { padding: 1rem; }
+      .quitstats-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function killsTable(data) {
+    if (data.killers.length === 0) {
+      return '<div class="quitstats-empty">No kills in this range</div>';
+    }
+    return `
+      <table class="quitstats-table">
+        <thead><tr><th>Killed by</th><th>Kills</th><th>Last kill</th></tr></thead>
+        <tbody>
+          ${data.killers.map(k => `
+            <tr>
+              <td>${escapeHtml(k.name)}${k.is_server ? ' <span class="quitstats-muted">(server)</span>' : ''}</td>
+              <td>${k.kills}</td>
+              <td>${escapeHtml(k.last_day)}</td>
+            </tr>
+          `).join('')}
+        </tbody>
+      </table>
+    `;
+  }
+
+  function recentKills(data) {
+    if (data.recent.length === 0) return '';
+    return `
+      <h3>Recent kills</h3>
+      <table class="quitstats-table">
+        <thead><tr><th>Time</th><th>User</th><th>Killed by</th><th>Reason</th></tr></thead>
+        <tbody>
+          ${data.recent.map(k => `
+            <tr>
+              <td>${escapeHtml(new Date(k.time).toLocaleString())}</td>
+              <td>${escapeHtml(k.nick)}</td>
+              <td>${escapeHtml(k.killer)}</td>
+              <td>${escapeHtml(k.reason)}</td>
+            </tr>
+          `).join('')}
+        </tbody>
+      </table>
+    `;
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#quitstats-body');
+    try {
+      const [breakdown, kills] = await Promise.all([
+        api(`/breakdown?days=${rangeDays}`),
+        api(`/kills?days=${rangeDays}`)
+      ]);
+      body.innerHTML = `
+        <h3>Quits per day <span class="quitstats-muted">(${breakdown.total} in total)</span></h3>
+        ${breakdownChart(breakdown, 600, 200)}
+        ${legend(breakdown)}
+        <h3>Kills per oper <span class="quitstats-muted">(${kills.total} in total)</span></h3>
+        ${killsTable(kills)}
+        ${recentKills(kills)}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="quitstats-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="quitstats-app" data-plugin="${PLUGIN_ID}">
+        <div class="quitstats-range">
+          ${[7, 30, 90, 365].map(d => `<button data-days="${d}" class="${d === rangeDays ? 'active' : ''}">${d} days</button>`).join('')}
+        </div>
+        <div id="quitstats-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelectorAll('.quitstats-range button').forEach(btn => {
+      btn.addEventListener('click', () => {
+        rangeDays = parseInt(btn.dataset.days, 10);
+        container.querySelectorAll('.quitstats-range button').forEach(b => b.classList.toggle('active', b === btn));
+        load(container);
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('quit-analytics-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,551 @@
+// Quit Analytics Plugin for UnrealIRCd Web Panel
+// Sorts why users leave the network into ping timeouts, read errors, bans,
+// kills and more, with daily breakdowns and kill counts per oper
+
+package quitanalytics
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Layout of day keys
+const dayFormat = "2006-01-02"
+
+// Examples kept per category
+const maxSamples = 25
+
+// QuitAnalyticsPlugin implements the Plugin interface
+type QuitAnalyticsPlugin struct {
+	config       Config
+	days         map[string]*dayStats
+	samples      map[string][]*sample
+	dirty        bool
+	streamOK     bool
+	streamErr    string
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	StreamURL     string `json:"stream_url"`
+	LogSources    string `json:"log_sources"`
+	DataDir       string `json:"data_dir"`
+	RetentionDays int    `json:"retention_days"`
+}
+
+// dayStats counts the quits of one UTC day by category, and the kills by
+// who made them
+type dayStats struct {
+	Total      int            `json:"total"`
+	Categories map[string]int `json:"categories"`
+	Kills      map[string]int `json:"kills"`
+}
+
+// sample is a recent quit, kept as an example of its category
+type sample struct {
+	Time   time.Time `json:"time"`
+	Nick   string    `json:"nick"`
+	Server string    `json:"server,omitempty"`
+	Reason string    `json:"reason"`
+	Killer string    `json:"killer,omitempty"`
+}
+
+// storeData is what the plugin keeps on disk
+type storeData struct {
+	Days    map[string]*dayStats `json:"days"`
+	Samples map[string][]*sample `json:"samples"`
+}
+
+// disconnectEntry holds the parts of a disconnect log entry the plugin uses
+type disconnectEntry struct {
+	LogSource string `json:"log_source"`
+	Reason    string `json:"reason"`
+	Client    *struct {
+		Name string `json:"name"`
+	} `json:"client"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &QuitAnalyticsPlugin{
+		config: Config{
+			StreamURL:     "wss://127.0.0.1:8600/",
+			LogSources:    "connect",
+			DataDir:       "data/plugins/quit-analytics",
+			RetentionDays: 365,
+		},
+		days:    make(map[string]*dayStats),
+		samples: make(map[string][]*sample),
+	}
+}
+
+// Info returns plugin metadata
+func (p *QuitAnalyticsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Quit Analytics",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Breakdowns of quit and kill reasons",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *QuitAnalyticsPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[quit-analytics] failed to load statistics: %v", err)
+	}
+	if data.Days != nil {
+		p.days = data.Days
+	}
+	if data.Samples != nil {
+		p.samples = data.Samples
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "quit-analytics-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"quits_today":         0,
+			"ping_timeouts_today": 0,
+			"kills_today":         0,
+		}
+		if d := p.days[time.Now().UTC().Format(dayFormat)]; d != nil {
+			content["quits_today"] = d.Total
+			content["ping_timeouts_today"] = d.Categories["ping_timeout"]
+			content["kills_today"] = d.Categories["killed"]
+		}
+		if !p.streamOK {
+			content["status"] = "Log stream disconnected"
+		}
+		return plugins.DashboardCard{
+			Title:   "Quit Reasons",
+			Icon:    "LogOut",
+			Content: content,
+			Order:   77,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.saveLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *QuitAnalyticsPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *QuitAnalyticsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/quit-analytics")
+	{
+		plugin.GET("/breakdown", p.handleBreakdown)
+		plugin.GET("/kills", p.handleKills)
+		plugin.GET("/samples", p.handleSamples)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file statistics are kept in
+func (p *QuitAnalyticsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "quit-analytics.json")
+}
+
+// save writes the statistics to disk if they changed
+func (p *QuitAnalyticsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Days: p.days, Samples: p.samples}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// saveLoop drops days past the retention period and writes the statistics
+// to disk every minute
+func (p *QuitAnalyticsPlugin) saveLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.expire(time.Now().UTC())
+			p.mu.Unlock()
+			if err := p.save(); err != nil {
+				log.Printf("[quit-analytics] failed to save statistics: %v", err)
+			}
+		}
+	}
+}
+
+// expire drops days past the retention period. Caller must hold p.mu.
+func (p *QuitAnalyticsPlugin) expire(now time.Time) {
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays).Format(dayFormat)
+	for day := range p.days {
+		if day < cutoff {
+			delete(p.days, day)
+			p.dirty = true
+		}
+	}
+}
+
+// streamLoop runs the log stream until shutdown, restarting it when the
+// configuration changes
+func (p *QuitAnalyticsPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *QuitAnalyticsPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	p.streamErr = ""
+	if err != nil {
+		p.streamErr = err.Error()
+		log.Printf("[quit-analytics] log stream: %v", err)
+	}
+}
+
+// handleEvent counts a disconnect by the category of its quit reason
+func (p *QuitAnalyticsPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	if !strings.HasSuffix(ev.EventID, "CLIENT_DISCONNECT") {
+		return
+	}
+	var entry disconnectEntry
+	_ = json.Unmarshal(ev.Raw, &entry)
+	reason := entry.Reason
+	if reason == "" {
+		reason = reasonFromMsg(ev.Msg)
+	}
+
+	s := &sample{Time: time.Now().UTC(), Server: entry.LogSource, Reason: reason}
+	if t, err := time.Parse(time.RFC3339, ev.Timestamp); err == nil {
+		s.Time = t.UTC()
+	}
+	if entry.Client != nil {
+		s.Nick = entry.Client.Name
+	}
+	cat := classify(reason)
+	if cat == "killed" {
+		if killer, _, ok := killedBy(reason); ok {
+			s.Killer = killer
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	day := s.Time.Format(dayFormat)
+	d := p.days[day]
+	if d == nil {
+		d = &dayStats{Categories: make(map[string]int), Kills: make(map[string]int)}
+		p.days[day] = d
+	}
+	d.Total++
+	d.Categories[cat]++
+	if s.Killer != "" {
+		d.Kills[s.Killer]++
+	}
+
+	list := append(p.samples[cat], s)
+	if len(list) > maxSamples {
+		list = list[len(list)-maxSamples:]
+	}
+	p.samples[cat] = list
+	p.dirty = true
+}
+
+// queryDays reads the days parameter, the number of days up to and
+// including today to report on, answering the request if it is invalid
+func queryDays(c *gin.Context) (int, bool) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 3650 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 3650"})
+		return 0, false
+	}
+	return days, true
+}
+
+// dayKeys returns the keys of the last n days, oldest first
+func dayKeys(now time.Time, n int) []string {
+	keys := make([]string, 0, n)
+	for i := n - 1; i >= 0; i-- {
+		keys = append(keys, now.AddDate(0, 0, -i).Format(dayFormat))
+	}
+	return keys
+}
+
+// handleBreakdown returns quits per category for each day in the range,
+// with days without quits included as zeros, and the totals
+func (p *QuitAnalyticsPlugin) handleBreakdown(c *gin.Context) {
+	n, ok := queryDays(c)
+	if !ok {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	days := make([]gin.H, 0, n)
+	totals := make(map[string]int)
+	total := 0
+	for _, key := range dayKeys(time.Now().UTC(), n) {
+		counts := make(map[string]int)
+		dayTotal := 0
+		if d := p.days[key]; d != nil {
+			for cat, count := range d.Categories {
+				counts[cat] = count
+				totals[cat] += count
+			}
+			dayTotal = d.Total
+		}
+		total += dayTotal
+		days = append(days, gin.H{"date": key, "total": dayTotal, "categories": counts})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": categories,
+		"days":       days,
+		"totals":     totals,
+		"total":      total,
+	})
+}
+
+// killerStats is the kill count of one oper, server or service
+type killerStats struct {
+	Name     string         `json:"name"`
+	Kills    int            `json:"kills"`
+	LastDay  string         `json:"last_day"`
+	IsServer bool           `json:"is_server"`
+	ByDay    map[string]int `json:"by_day"`
+}
+
+// handleKills returns kills per killer over the range, most first, and
+// the most recent kills with their reasons
+func (p *QuitAnalyticsPlugin) handleKills(c *gin.Context) {
+	n, ok := queryDays(c)
+	if !ok {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	byName := make(map[string]*killerStats)
+	total := 0
+	for _, key := range dayKeys(time.Now().UTC(), n) {
+		d := p.days[key]
+		if d == nil {
+			continue
+		}
+		for name, count := range d.Kills {
+			k := byName[strings.ToLower(name)]
+			if k == nil {
+				k = &killerStats{Name: name, IsServer: strings.Contains(name, "."), ByDay: make(map[string]int)}
+				byName[strings.ToLower(name)] = k
+			}
+			k.Kills += count
+			k.ByDay[key] += count
+			k.LastDay = key
+			total += count
+		}
+	}
+	killers := make([]*killerStats, 0, len(byName))
+	for _, k := range byName {
+		killers = append(killers, k)
+	}
+	sort.Slice(killers, func(i, j int) bool {
+		if killers[i].Kills != killers[j].Kills {
+			return killers[i].Kills > killers[j].Kills
+		}
+		return killers[i].Name < killers[j].Name
+	})
+
+	recent := make([]gin.H, 0)
+	list := p.samples["killed"]
+	for i := len(list) - 1; i >= 0; i-- {
+		s := list[i]
+		_, why, _ := killedBy(s.Reason)
+		recent = append(recent, gin.H{"time": s.Time, "nick": s.Nick, "killer": s.Killer, "reason": why, "server": s.Server})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"killers": killers, "total": total, "recent": recent})
+}
+
+// handleSamples returns recent quits of a category, newest first
+func (p *QuitAnalyticsPlugin) handleSamples(c *gin.Context) {
+	cat := c.Query("category")
+	known := false
+	for _, k := range categories {
+		known = known || k.Name == cat
+	}
+	if !known {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := p.samples[cat]
+	out := make([]*sample, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		out = append(out, list[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"category": cat, "samples": out})
+}
+
+// handleStatus returns the state of the log stream
+func (p *QuitAnalyticsPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	oldest := ""
+	for day := range p.days {
+		if oldest == "" || day < oldest {
+			oldest = day
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"stream_ok":  p.streamOK,
+		"error":      p.streamErr,
+		"days":       len(p.days),
+		"oldest_day": oldest,
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *QuitAnalyticsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *QuitAnalyticsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 3650 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 3650"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.expire(time.Now().UTC())
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *QuitAnalyticsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *QuitAnalyticsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
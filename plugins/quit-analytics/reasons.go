@@ -0,0 +1,96 @@
+package quitanalytics
+
+import (
+	"regexp"
+	"strings"
+)
+
+// category is a kind of quit reason
+type category struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+}
+
+// categories lists the reason categories in the order the panel shows them
+var categories = []category{
+	{"quit", "Quit"},
+	{"ping_timeout", "Ping timeout"},
+	{"read_error", "Read error"},
+	{"tls_error", "TLS error"},
+	{"banned", "Banned"},
+	{"killed", "Killed"},
+	{"excess_flood", "Excess flood"},
+	{"sendq", "Max SendQ exceeded"},
+	{"registration_timeout", "Registration timeout"},
+	{"other", "Other"},
+}
+
+// reasonRules maps quit reasons to categories, tried in order. Each rule
+// matches a reason starting with or containing one of its texts, compared
+// in lower case.
+var reasonRules = []struct {
+	category string
+	prefixes []string
+	contains []string
+}{
+	{"quit", []string{"quit:", "client exited"}, nil},
+	{"ping_timeout", []string{"ping timeout"}, nil},
+	{"read_error", []string{"read error", "write error", "eof from client", "connection reset", "connection timed out", "broken pipe", "remote host closed"}, nil},
+	{"tls_error", []string{"ssl", "tls"}, nil},
+	{"banned", nil, []string{"-lined", "has been banned", "has been permanently banned", "banned from"}},
+	{"killed", []string{"killed"}, nil},
+	{"excess_flood", []string{"excess flood", "flood"}, nil},
+	{"sendq", []string{"max sendq", "sendq exceeded"}, nil},
+	{"registration_timeout", []string{"registration timeout"}, nil},
+}
+
+// killPatterns find the killer and kill reason in a quit reason, in the
+// current "Killed by oper (reason)" form and the older
+// "Killed (oper (reason))" form
+var killPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^killed by (\S+) \((.*)\)$`),
+	regexp.MustCompile(`(?i)^killed \((\S+) \((.*)\)\)$`),
+}
+
+// classify returns the category of a quit reason
+func classify(reason string) string {
+	lower := strings.ToLower(strings.TrimSpace(reason))
+	if lower == "quit" {
+		return "quit"
+	}
+	for _, rule := range reasonRules {
+		for _, p := range rule.prefixes {
+			if strings.HasPrefix(lower, p) {
+				return rule.category
+			}
+		}
+		for _, c := range rule.contains {
+			if strings.Contains(lower, c) {
+				return rule.category
+			}
+		}
+	}
+	return "other"
+}
+
+// killedBy returns who killed a user and why, from their quit reason.
+// ok is false if the reason doesn't name a killer.
+func killedBy(reason string) (killer, why string, ok bool) {
+	for _, re := range killPatterns {
+		if m := re.FindStringSubmatch(strings.TrimSpace(reason)); m != nil {
+			return m[1], m[2], true
+		}
+	}
+	return "", "", false
+}
+
+// reasonFromMsg takes the quit reason from a disconnect log message,
+// "Client exiting: nick (user@host) [ip] (reason)", for log entries that
+// don't carry it separately
+func reasonFromMsg(msg string) string {
+	i := strings.Index(msg, "] (")
+	if i < 0 || !strings.HasSuffix(msg, ")") {
+		return ""
+	}
+	return msg[i+3 : len(msg)-1]
+}
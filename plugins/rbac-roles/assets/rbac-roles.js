@@ -0,0 +1,404 @@
+/**
+ * RBAC Roles Frontend Script
+ *
+ * Edits roles and their permissions, assigns roles to panel users and
+ * manages the rules that map API requests to permissions.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'rbac-roles';
+  const PLUGIN_NAME = 'RBAC Roles';
+  const PAGE_PATH = '/plugins/rbac-roles';
+  const API_BASE = '/api/plugin/rbac-roles';
+
+  let permissions = [];
+  let roles = [];
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('rbac-roles-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'rbac-roles-styles';
+    style.textContent = `
+      .rbac-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .rbac-row { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .rbac-table { width: 100%; border-collapse: collapse; }
+      .rbac-table th, .rbac-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        vertical-align: top;
+      }
+      .rbac-table td.rbac-check { text-align: center; }
+      .rbac-app input[type="text"], .rbac-app select {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.3rem 0.5rem;
+      }
+      .rbac-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .rbac-app button.rbac-primary { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); }
+      .rbac-meta { font-size: 0.75rem; color: var(--text-muted, #6c7086); }
+      .rbac-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadMe(container) {
+    const body = container.querySelector('#rbac-me');
+    try {
+      const me = await api('GET', '/me');
+      body.innerHTML = `
+        <p>You are <strong>${escapeHtml(me.username)}</strong>
+        ${me.superuser ? '(superuser)' : `with ${me.default ? 'the default role' : 'the roles'} ${escapeHtml(me.roles.join(', ') || 'none')}`}.</p>
+        <div class="rbac-meta">Permissions: ${escapeHtml(me.permissions.join(', ') || 'none')}</div>
+        ${me.enforcing ? '' : '<div class="rbac-error">The path rules are not enforced: set a superuser, and make sure the panel runs the plugin request hook.</div>'}
+      `;
+      return me.permissions.includes('manage_roles');
+    } catch (e) {
+      body.innerHTML = `<div class="rbac-error">${escapeHtml(e.message)}</div>`;
+      return false;
+    }
+  }
+
+  async function loadRoles(container) {
+    const body = container.querySelector('#rbac-roles');
+    try {
+      const data = await api('GET', '/roles');
+      roles = data.roles;
+      body.innerHTML = `
+        <table class="rbac-table">
+          <thead><tr>
+            <th>Role</th>
+            ${permissions.map(p => `<th title="${escapeHtml(p.description)}">${escapeHtml(p.label)}</th>`).join('')}
+            <th>Users</th><th></th>
+          </tr></thead>
+          <tbody>
+            ${roles.map(r => `
+              <tr data-role="${escapeHtml(r.id)}">
+                <td>
+                  <strong>${escapeHtml(r.name)}</strong> <code>${escapeHtml(r.id)}</code>
+                  ${r.id === data.default_role ? '<span class="rbac-meta">default</span>' : ''}
+                  <div class="rbac-meta">${escapeHtml(r.description)}</div>
+                </td>
+                ${permissions.map(p => `
+                  <td class="rbac-check"><input type="checkbox" value="${p.id}" ${r.permissions.includes(p.id) ? 'checked' : ''} ${r.id === 'admin' ? 'disabled' : ''}></td>
+                `).join('')}
+                <td>${r.users}</td>
+                <td>${r.id === 'admin' ? '' : `
+                  <button data-save>Save</button>
+                  ${r.builtin ? '' : '<button data-delete>Delete</button>'}
+                `}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+        <div class="rbac-row">
+          <input type="text" id="rbac-new-id" placeholder="Role id, e.g. helper">
+          <input type="text" id="rbac-new-name" placeholder="Name">
+          <input type="text" id="rbac-new-desc" placeholder="Description">
+          <button class="rbac-primary" id="rbac-new-add">Add role</button>
+        </div>
+      `;
+
+      body.querySelectorAll('tr[data-role]').forEach(row => {
+        const id = row.dataset.role;
+        const role = roles.find(r => r.id === id);
+        const save = row.querySelector('[data-save]');
+        if (save) {
+          save.addEventListener('click', async () => {
+            const perms = [...row.querySelectorAll('input[type="checkbox"]:checked')].map(cb => cb.value);
+            try {
+              await api('PUT', `/roles/${encodeURIComponent(id)}`, { name: role.name, description: role.description, permissions: perms });
+              loadRoles(container);
+            } catch (e) {
+              alert(e.message);
+            }
+          });
+        }
+        const del = row.querySelector('[data-delete]');
+        if (del) {
+          del.addEventListener('click', async () => {
+            if (!confirm(`Delete the role ${id}?`)) return;
+            try {
+              await api('DELETE', `/roles/${encodeURIComponent(id)}`);
+              loadRoles(container);
+            } catch (e) {
+              alert(e.message);
+            }
+          });
+        }
+      });
+      body.querySelector('#rbac-new-add').addEventListener('click', async () => {
+        const id = body.querySelector('#rbac-new-id').value.trim();
+        if (!id) return;
+        try {
+          await api('POST', '/roles', {
+            id,
+            name: body.querySelector('#rbac-new-name').value.trim(),
+            description: body.querySelector('#rbac-new-desc').value.trim(),
+            permissions: []
+          });
+          await loadRoles(container);
+          loadAssignments(container);
+        } catch (e) {
+          alert(e.message);
+        }
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="rbac-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function roleCheckboxes(selected) {
+    return roles.map(r => `
+      <label><input type="checkbox" value="${escapeHtml(r.id)}" ${selected.includes(r.id) ? 'checked' : ''}> ${escapeHtml(r.name)}</label>
+    `).join(' ');
+  }
+
+  async function loadAssignments(container) {
+    const body = container.querySelector('#rbac-assignments');
+    try {
+      const data = await api('GET', '/assignments');
+      body.innerHTML = `
+        <p class="rbac-meta">Users not listed here have the default role${data.default_role ? `, ${escapeHtml(data.default_role)}` : ', which is unset, so they have no permissions'}.</p>
+        <table class="rbac-table">
+          <thead><tr><th>User</th><th>Roles</th><th>Changed</th><th></th></tr></thead>
+          <tbody>
+            ${data.assignments.map(a => `
+              <tr data-user="${escapeHtml(a.username)}">
+                <td>${escapeHtml(a.username)}</td>
+                <td>${roleCheckboxes(a.roles)}</td>
+                <td>${formatTime(a.updated_at)}<div class="rbac-meta">by ${escapeHtml(a.updated_by)}</div></td>
+                <td><button data-save>Save</button> <button data-reset>Use default</button></td>
+              </tr>
+            `).join('')}
+            <tr data-new>
+              <td><input type="text" placeholder="Username"></td>
+              <td>${roleCheckboxes([])}</td>
+              <td></td>
+              <td><button class="rbac-primary" data-save>Assign</button></td>
+            </tr>
+          </tbody>
+        </table>
+      `;
+
+      body.querySelectorAll('tbody tr').forEach(row => {
+        const userOf = () => row.dataset.user || row.querySelector('input[type="text"]').value.trim();
+        row.querySelector('[data-save]').addEventListener('click', async () => {
+          const user = userOf();
+          if (!user) return;
+          const selected = [...row.querySelectorAll('input[type="checkbox"]:checked')].map(cb => cb.value);
+          try {
+            await api('PUT', `/assignments/${encodeURIComponent(user)}`, { roles: selected });
+            loadAssignments(container);
+            loadRoles(container);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+        const reset = row.querySelector('[data-reset]');
+        if (reset) {
+          reset.addEventListener('click', async () => {
+            try {
+              await api('DELETE', `/assignments/${encodeURIComponent(userOf())}`);
+              loadAssignments(container);
+              loadRoles(container);
+            } catch (e) {
+              alert(e.message);
+            }
+          });
+        }
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="rbac-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function ruleRow(r) {
+    return `
+      <tr>
+        <td><select data-field="method">
+          ${['*', 'WRITE', 'GET', 'POST', 'PUT', 'PATCH', 'DELETE'].map(m => `<option ${r.method === m ? 'selected' : ''}>${m}</option>`).join('')}
+        </select></td>
+        <td><input type="text" data-field="prefix" value="${escapeHtml(r.prefix)}"></td>
+        <td><select data-field="permission">
+          ${permissions.map(p => `<option value="${p.id}" ${r.permission === p.id ? 'selected' : ''}>${escapeHtml(p.label)}</option>`).join('')}
+        </select></td>
+        <td><button data-remove>Remove</button></td>
+      </tr>
+    `;
+  }
+
+  async function loadRules(container) {
+    const body = container.querySelector('#rbac-rules');
+    try {
+      const data = await api('GET', '/rules');
+      body.innerHTML = `
+        <p class="rbac-meta">The longest matching prefix decides. Requests no rule matches need no permission. WRITE matches every method but GET and HEAD.</p>
+        <table class="rbac-table">
+          <thead><tr><th>Method</th><th>Path prefix</th><th>Permission</th><th></th></tr></thead>
+          <tbody>${data.rules.map(ruleRow).join('')}</tbody>
+        </table>
+        <div class="rbac-row">
+          <button data-add>Add rule</button>
+          <button data-defaults>Restore defaults</button>
+          <button class="rbac-primary" data-save>Save rules</button>
+        </div>
+      `;
+
+      const tbody = body.querySelector('tbody');
+      const bindRemove = () => tbody.querySelectorAll('[data-remove]').forEach(btn => {
+        btn.onclick = () => btn.closest('tr').remove();
+      });
+      bindRemove();
+      body.querySelector('[data-add]').addEventListener('click', () => {
+        tbody.insertAdjacentHTML('beforeend', ruleRow({ method: '*', prefix: '/api/', permission: 'view_stats' }));
+        bindRemove();
+      });
+      body.querySelector('[data-defaults]').addEventListener('click', () => {
+        tbody.innerHTML = data.defaults.map(ruleRow).join('');
+        bindRemove();
+      });
+      body.querySelector('[data-save]').addEventListener('click', async () => {
+        const rules = [...tbody.querySelectorAll('tr')].map(tr => ({
+          method: tr.querySelector('[data-field="method"]').value,
+          prefix: tr.querySelector('[data-field="prefix"]').value.trim(),
+          permission: tr.querySelector('[data-field="permission"]').value
+        }));
+        try {
+          await api('PUT', '/rules', { rules });
+          loadRules(container);
+        } catch (e) {
+          alert(e.message);
+        }
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="rbac-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadAll(container) {
+    const canManage = await loadMe(container);
+    container.querySelector('#rbac-manage').style.display = canManage ? '' : 'none';
+    if (!canManage) return;
+
+    try {
+      permissions = (await api('GET', '/permissions')).permissions;
+    } catch (e) {
+      container.querySelector('#rbac-roles').innerHTML = `<div class="rbac-error">${escapeHtml(e.message)}</div>`;
+      return;
+    }
+    await loadRoles(container);
+    loadAssignments(container);
+    loadRules(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="rbac-app" data-plugin="${PLUGIN_ID}">
+        <div id="rbac-me">Loading...</div>
+        <div id="rbac-manage" style="display: none">
+          <h3>Roles</h3>
+          <div id="rbac-roles">Loading...</div>
+          <h3>Users</h3>
+          <div id="rbac-assignments">Loading...</div>
+          <h3>Permission rules</h3>
+          <div id="rbac-rules">Loading...</div>
+        </div>
+      </div>
+    `;
+
+    loadAll(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('rbac-roles-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
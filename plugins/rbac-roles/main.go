@@ -0,0 +1,697 @@
+// RBAC Roles Plugin for UnrealIRCd Web Panel
+// Defines roles with sets of permissions, assigns them to panel users and
+// lets the panel and other plugins check what a user may do
+
+package rbacroles
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/requesthook"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// adminRole always has every permission and cannot be changed
+const adminRole = "admin"
+
+// viewerRole is the read-only role unassigned users get by default
+const viewerRole = "viewer"
+
+// roleID is the allowed form of role ids
+var roleID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// RBACRolesPlugin implements the Plugin interface
+type RBACRolesPlugin struct {
+	config      Config
+	roles       map[string]*Role
+	assignments map[string]*Assignment
+	rules       []Rule
+	dirty       bool
+	mu          sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	DataDir     string `json:"data_dir"`
+	DefaultRole string `json:"default_role"`
+	Superusers  string `json:"superusers"`
+}
+
+// Role is a named set of permissions
+type Role struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	Builtin     bool      `json:"builtin"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Assignment is the roles given to a panel user
+type Assignment struct {
+	Username  string    `json:"username"`
+	Roles     []string  `json:"roles"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Roles       map[string]*Role       `json:"roles"`
+	Assignments map[string]*Assignment `json:"assignments"`
+	Rules       []Rule                 `json:"rules"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &RBACRolesPlugin{
+		config: Config{
+			DataDir:     "data/plugins/rbac-roles",
+			DefaultRole: viewerRole,
+		},
+		roles:       defaultRoles(),
+		assignments: make(map[string]*Assignment),
+		rules:       append([]Rule(nil), defaultRules...),
+	}
+}
+
+// defaultRoles returns the roles a fresh install starts with
+func defaultRoles() map[string]*Role {
+	all := make([]string, 0, len(permissionList))
+	for _, perm := range permissionList {
+		all = append(all, perm.ID)
+	}
+	return map[string]*Role{
+		adminRole: {
+			ID: adminRole, Name: "Administrator", Builtin: true,
+			Description: "Every permission",
+			Permissions: all,
+		},
+		"operator": {
+			ID: "operator", Name: "Operator",
+			Description: "Handles abuse: users, bans and spamfilters",
+			Permissions: []string{PermViewStats, PermManageUsers, PermManageBans, PermManageSpamfilters},
+		},
+		viewerRole: {
+			ID: viewerRole, Name: "Viewer",
+			Description: "Read-only access",
+			Permissions: []string{PermViewStats},
+		},
+	}
+}
+
+// Info returns plugin metadata
+func (p *RBACRolesPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "RBAC Roles",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Custom roles and permissions for panel users",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *RBACRolesPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[rbac-roles] failed to load data: %v", err)
+	}
+	if data.Roles != nil {
+		p.roles = data.Roles
+	}
+	if data.Assignments != nil {
+		p.assignments = data.Assignments
+	}
+	if data.Rules != nil {
+		p.rules = data.Rules
+	}
+	// The admin role always exists with every permission
+	p.roles[adminRole] = defaultRoles()[adminRole]
+	sortRules(p.rules)
+	p.mu.Unlock()
+
+	activeMu.Lock()
+	active = p
+	activeMu.Unlock()
+
+	if err := access.SetChecker(Can); err != nil {
+		return err
+	}
+	requesthook.Register("rbac-roles", 20, p.enforce)
+
+	p.mu.RLock()
+	if !p.enforcing() {
+		log.Printf("[rbac-roles] no superusers are set, so the path rules are not enforced yet")
+	}
+	p.mu.RUnlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "rbac-roles-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		return plugins.DashboardCard{
+			Title: "Roles",
+			Icon:  "users",
+			Content: map[string]interface{}{
+				"roles":        len(p.roles),
+				"assigned":     len(p.assignments),
+				"default_role": p.config.DefaultRole,
+				"enforcing":    p.enforcing() && requesthook.Installed(),
+			},
+			Order: 57,
+			Size:  "sm",
+		}
+	}, 50)
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *RBACRolesPlugin) Shutdown() error {
+	requesthook.Unregister("rbac-roles")
+	if err := access.SetChecker(nil); err != nil {
+		log.Printf("[rbac-roles] failed to remove the permission check: %v", err)
+	}
+
+	activeMu.Lock()
+	if active == p {
+		active = nil
+	}
+	activeMu.Unlock()
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *RBACRolesPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/rbac-roles")
+	{
+		plugin.GET("/me", p.handleMe)
+		plugin.GET("/check", p.handleCheck)
+		plugin.POST("/check", p.handleCheckRequest)
+		plugin.GET("/permissions", p.handlePermissions)
+
+		manage := plugin.Group("", RequirePermission(PermManageRoles))
+		manage.GET("/roles", p.handleListRoles)
+		manage.POST("/roles", p.handleSaveRole)
+		manage.PUT("/roles/:id", p.handleSaveRole)
+		manage.DELETE("/roles/:id", p.handleDeleteRole)
+		manage.GET("/assignments", p.handleListAssignments)
+		manage.PUT("/assignments/:username", p.handleAssign)
+		manage.DELETE("/assignments/:username", p.handleUnassign)
+		manage.GET("/rules", p.handleGetRules)
+		manage.PUT("/rules", p.handleUpdateRules)
+		manage.GET("/config", p.handleGetConfig)
+		manage.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *RBACRolesPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "roles.json")
+}
+
+// save persists the state if it changed
+func (p *RBACRolesPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	data := storeData{Roles: p.roles, Assignments: p.assignments, Rules: p.rules}
+	if err := storage.SaveJSON(p.storePath(), data); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// persist saves the state, logging failures
+func (p *RBACRolesPlugin) persist() {
+	if err := p.save(); err != nil {
+		log.Printf("[rbac-roles] failed to save data: %v", err)
+	}
+}
+
+// userRoles returns the roles of a panel user. Caller must hold p.mu.
+func (p *RBACRolesPlugin) userRoles(username string) []string {
+	if a, ok := p.assignments[strings.ToLower(username)]; ok {
+		return a.Roles
+	}
+	if p.config.DefaultRole != "" {
+		return []string{p.config.DefaultRole}
+	}
+	return nil
+}
+
+// permissions returns the set of permissions of a panel user. Caller must
+// hold p.mu.
+func (p *RBACRolesPlugin) permissions(username string) map[string]bool {
+	set := make(map[string]bool)
+	if username == "" {
+		return set
+	}
+	if lists.ContainsFold(lists.Split(p.config.Superusers), username) {
+		for _, perm := range permissionList {
+			set[perm.ID] = true
+		}
+		return set
+	}
+	for _, id := range p.userRoles(username) {
+		if role, ok := p.roles[id]; ok {
+			for _, perm := range role.Permissions {
+				set[perm] = true
+			}
+		}
+	}
+	return set
+}
+
+// enforcing reports whether the path rules are enforced. They wait for a
+// superuser, so that installing the plugin cannot lock everyone out before
+// anybody may manage roles. Caller must hold p.mu.
+func (p *RBACRolesPlugin) enforcing() bool {
+	return len(lists.Split(p.config.Superusers)) > 0
+}
+
+// required returns the permission the first matching rule asks for.
+// Caller must hold p.mu.
+func (p *RBACRolesPlugin) required(method, path string) string {
+	for _, r := range p.rules {
+		if r.matches(method, path) {
+			return r.Permission
+		}
+	}
+	return ""
+}
+
+// cleanPermissions drops unknown and duplicate permissions
+func cleanPermissions(perms []string) []string {
+	seen := make(map[string]bool)
+	list := make([]string, 0, len(perms))
+	for _, perm := range perms {
+		if validPermission(perm) && !seen[perm] {
+			seen[perm] = true
+			list = append(list, perm)
+		}
+	}
+	return list
+}
+
+// handleMe returns the roles and permissions of the current user
+func (p *RBACRolesPlugin) handleMe(c *gin.Context) {
+	name := c.GetString("username")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	perms := make([]string, 0)
+	for perm := range p.permissions(name) {
+		perms = append(perms, perm)
+	}
+	sort.Strings(perms)
+	_, assigned := p.assignments[strings.ToLower(name)]
+	c.JSON(http.StatusOK, gin.H{
+		"username":    name,
+		"roles":       p.userRoles(name),
+		"default":     !assigned,
+		"superuser":   lists.ContainsFold(lists.Split(p.config.Superusers), name),
+		"permissions": perms,
+		"enforcing":   p.enforcing() && requesthook.Ran(c),
+	})
+}
+
+// handleCheck reports whether a user has a permission. Checking anyone
+// but yourself needs manage_roles.
+func (p *RBACRolesPlugin) handleCheck(c *gin.Context) {
+	perm := c.Query("permission")
+	if !validPermission(perm) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission"})
+		return
+	}
+	user := c.DefaultQuery("user", c.GetString("username"))
+	if !strings.EqualFold(user, c.GetString("username")) && !Can(c.GetString("username"), PermManageRoles) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + PermManageRoles})
+		return
+	}
+
+	p.mu.RLock()
+	allowed := p.permissions(user)[perm]
+	p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"user": user, "permission": perm, "allowed": allowed})
+}
+
+// handleCheckRequest reports whether the current user may make a panel API
+// request, for callers that cannot call Can
+func (p *RBACRolesPlugin) handleCheckRequest(c *gin.Context) {
+	var req struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Method == "" || req.Path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "method and path are required"})
+		return
+	}
+	path := req.Path
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+
+	p.mu.RLock()
+	perm := ""
+	if p.enforcing() {
+		perm = p.required(strings.ToUpper(req.Method), path)
+	}
+	allowed := perm == "" || p.permissions(c.GetString("username"))[perm]
+	p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"permission": perm, "allowed": allowed})
+}
+
+// handlePermissions lists the known permissions
+func (p *RBACRolesPlugin) handlePermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"permissions": permissionList})
+}
+
+// handleListRoles returns every role with the number of users holding it
+func (p *RBACRolesPlugin) handleListRoles(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	type roleView struct {
+		*Role
+		Users int `json:"users"`
+	}
+	list := make([]roleView, 0, len(p.roles))
+	for _, role := range p.roles {
+		users := 0
+		for _, a := range p.assignments {
+			for _, id := range a.Roles {
+				if id == role.ID {
+					users++
+				}
+			}
+		}
+		list = append(list, roleView{Role: role, Users: users})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Builtin != list[j].Builtin {
+			return list[i].Builtin
+		}
+		return list[i].ID < list[j].ID
+	})
+	c.JSON(http.StatusOK, gin.H{"roles": list, "default_role": p.config.DefaultRole})
+}
+
+// handleSaveRole creates a role, or updates the one named in the path
+func (p *RBACRolesPlugin) handleSaveRole(c *gin.Context) {
+	var req Role
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	id := c.Param("id")
+	if id == "" {
+		id = strings.ToLower(strings.TrimSpace(req.ID))
+	}
+	if !roleID.MatchString(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role id must be lowercase letters, digits, - or _"})
+		return
+	}
+	if id == adminRole {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The admin role cannot be changed"})
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		req.Name = id
+	}
+
+	p.mu.Lock()
+	_, exists := p.roles[id]
+	if c.Param("id") == "" && exists {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "A role with this id already exists"})
+		return
+	}
+	if c.Param("id") != "" && !exists {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	role := &Role{
+		ID:          id,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Permissions: cleanPermissions(req.Permissions),
+		UpdatedBy:   access.Actor(c),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	p.roles[id] = role
+	p.dirty = true
+	p.mu.Unlock()
+
+	p.persist()
+	log.Printf("[rbac-roles] %s saved role %s: %s", access.Actor(c), id, strings.Join(role.Permissions, ","))
+	c.JSON(http.StatusOK, gin.H{"message": "Role saved", "role": role})
+}
+
+// handleDeleteRole removes a role that nobody holds
+func (p *RBACRolesPlugin) handleDeleteRole(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	role, ok := p.roles[id]
+	if !ok {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if role.Builtin {
+		p.mu.Unlock()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Built-in roles cannot be deleted"})
+		return
+	}
+	if p.config.DefaultRole == id {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "The role is the default role"})
+		return
+	}
+	for _, a := range p.assignments {
+		for _, r := range a.Roles {
+			if r == id {
+				p.mu.Unlock()
+				c.JSON(http.StatusConflict, gin.H{"error": "The role is still assigned to " + a.Username})
+				return
+			}
+		}
+	}
+	delete(p.roles, id)
+	p.dirty = true
+	p.mu.Unlock()
+
+	p.persist()
+	log.Printf("[rbac-roles] %s deleted role %s", access.Actor(c), id)
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
+
+// handleListAssignments returns the users with roles of their own
+func (p *RBACRolesPlugin) handleListAssignments(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Assignment, 0, len(p.assignments))
+	for _, a := range p.assignments {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Username) < strings.ToLower(list[j].Username) })
+	c.JSON(http.StatusOK, gin.H{"assignments": list, "default_role": p.config.DefaultRole})
+}
+
+// handleAssign sets the roles of a panel user
+func (p *RBACRolesPlugin) handleAssign(c *gin.Context) {
+	var req struct {
+		Roles []string `json:"roles"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	username := strings.TrimSpace(c.Param("username"))
+	if username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username is required"})
+		return
+	}
+
+	p.mu.Lock()
+	roles := make([]string, 0, len(req.Roles))
+	for _, id := range req.Roles {
+		if _, ok := p.roles[id]; !ok {
+			p.mu.Unlock()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role " + id})
+			return
+		}
+		roles = append(roles, id)
+	}
+	// Keep the acting user from locking themselves out
+	if strings.EqualFold(username, c.GetString("username")) && !lists.ContainsFold(lists.Split(p.config.Superusers), username) {
+		keeps := false
+		for _, id := range roles {
+			for _, perm := range p.roles[id].Permissions {
+				keeps = keeps || perm == PermManageRoles
+			}
+		}
+		if !keeps {
+			p.mu.Unlock()
+			c.JSON(http.StatusConflict, gin.H{"error": "You would lose the manage_roles permission"})
+			return
+		}
+	}
+	p.assignments[strings.ToLower(username)] = &Assignment{
+		Username:  username,
+		Roles:     roles,
+		UpdatedBy: access.Actor(c),
+		UpdatedAt: time.Now().UTC(),
+	}
+	p.dirty = true
+	p.mu.Unlock()
+
+	p.persist()
+	log.Printf("[rbac-roles] %s gave %s the roles %s", access.Actor(c), username, strings.Join(roles, ","))
+	c.JSON(http.StatusOK, gin.H{"message": "Roles updated"})
+}
+
+// handleUnassign returns a panel user to the default role
+func (p *RBACRolesPlugin) handleUnassign(c *gin.Context) {
+	key := strings.ToLower(c.Param("username"))
+
+	p.mu.Lock()
+	if _, ok := p.assignments[key]; !ok {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "User has no roles of their own"})
+		return
+	}
+	delete(p.assignments, key)
+	p.dirty = true
+	p.mu.Unlock()
+
+	p.persist()
+	log.Printf("[rbac-roles] %s returned %s to the default role", access.Actor(c), c.Param("username"))
+	c.JSON(http.StatusOK, gin.H{"message": "User now has the default role"})
+}
+
+// handleGetRules returns the permission rules in the order they are tried
+func (p *RBACRolesPlugin) handleGetRules(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"rules": p.rules, "defaults": defaultRules})
+}
+
+// handleUpdateRules replaces the permission rules
+func (p *RBACRolesPlugin) handleUpdateRules(c *gin.Context) {
+	var req struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rules"})
+		return
+	}
+	for i := range req.Rules {
+		r := &req.Rules[i]
+		r.Method = strings.ToUpper(strings.TrimSpace(r.Method))
+		if r.Method == "" {
+			r.Method = "*"
+		}
+		if !strings.HasPrefix(r.Prefix, "/api") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Rule prefixes must start with /api"})
+			return
+		}
+		if !validPermission(r.Permission) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission " + r.Permission})
+			return
+		}
+	}
+	sortRules(req.Rules)
+
+	p.mu.Lock()
+	p.rules = req.Rules
+	p.dirty = true
+	p.mu.Unlock()
+
+	p.persist()
+	log.Printf("[rbac-roles] %s updated the permission rules", access.Actor(c))
+	c.JSON(http.StatusOK, gin.H{"message": "Rules updated", "rules": req.Rules})
+}
+
+// handleGetConfig returns the current configuration
+func (p *RBACRolesPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *RBACRolesPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.DefaultRole != "" {
+		if _, ok := p.roles[newConfig.DefaultRole]; !ok {
+			p.mu.Unlock()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown default role"})
+			return
+		}
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *RBACRolesPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *RBACRolesPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,177 @@
+package rbacroles
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+)
+
+// Permissions a role can grant
+const (
+	PermViewStats         = access.ViewStats
+	PermManageUsers       = access.ManageUsers
+	PermManageBans        = access.ManageBans
+	PermManageSpamfilters = access.ManageSpamfilters
+	PermManagePlugins     = access.ManagePlugins
+	PermManageRoles       = access.ManageRoles
+)
+
+// PermissionInfo describes a permission in the panel
+type PermissionInfo struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// permissionList is every known permission, in display order
+var permissionList = []PermissionInfo{
+	{PermViewStats, "View stats", "See users, channels, servers, bans and statistics"},
+	{PermManageUsers, "Manage users", "Kill users and change users and channels"},
+	{PermManageBans, "Manage bans", "Add and remove server bans and exceptions"},
+	{PermManageSpamfilters, "Manage spamfilters", "Add and remove spamfilters"},
+	{PermManagePlugins, "Manage plugins", "Install, configure and remove plugins"},
+	{PermManageRoles, "Manage roles", "Edit roles, permission rules and who has which role"},
+}
+
+// validPermission reports whether id is a known permission
+func validPermission(id string) bool {
+	for _, perm := range permissionList {
+		if perm.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule maps panel API requests to the permission they need. Method is an
+// HTTP method, "*" for any or "WRITE" for anything but GET and HEAD.
+type Rule struct {
+	Method     string `json:"method"`
+	Prefix     string `json:"prefix"`
+	Permission string `json:"permission"`
+}
+
+// defaultRules cover the panel's own API
+var defaultRules = []Rule{
+	{"WRITE", "/api/bans/spamfilter", PermManageSpamfilters},
+	{"WRITE", "/api/bans", PermManageBans},
+	{"WRITE", "/api/users", PermManageUsers},
+	{"WRITE", "/api/channels", PermManageUsers},
+	{"*", "/api/plugins", PermManagePlugins},
+	{"*", "/api/plugin/rbac-roles/roles", PermManageRoles},
+	{"*", "/api/plugin/rbac-roles/assignments", PermManageRoles},
+	{"*", "/api/plugin/rbac-roles/rules", PermManageRoles},
+	{"GET", "/api", PermViewStats},
+}
+
+// matches reports whether the rule applies to a request
+func (r Rule) matches(method, path string) bool {
+	switch r.Method {
+	case "*":
+	case "WRITE":
+		if method == http.MethodGet || method == http.MethodHead {
+			return false
+		}
+	default:
+		if !strings.EqualFold(r.Method, method) {
+			return false
+		}
+	}
+	return path == r.Prefix || strings.HasPrefix(path, strings.TrimSuffix(r.Prefix, "/")+"/")
+}
+
+// sortRules orders rules so the longest prefix is tried first
+func sortRules(rules []Rule) {
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].Prefix) > len(rules[j].Prefix) })
+}
+
+// active is the loaded plugin instance that the exported checks use
+var (
+	activeMu sync.RWMutex
+	active   *RBACRolesPlugin
+)
+
+// loaded returns the active plugin instance, or nil
+func loaded() *RBACRolesPlugin {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	return active
+}
+
+// Can reports whether a panel user has a permission. When the plugin is
+// not loaded nobody has any.
+func Can(username, permission string) bool {
+	p := loaded()
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.permissions(username)[permission]
+}
+
+// Permissions returns the permissions of a panel user, sorted. When the
+// plugin is not loaded the list is empty.
+func Permissions(username string) []string {
+	p := loaded()
+	list := make([]string, 0, len(permissionList))
+	if p == nil {
+		return list
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for perm := range p.permissions(username) {
+		list = append(list, perm)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// RequiredPermission returns the permission a panel API request needs, or
+// "" when no rule covers it
+func RequiredPermission(method, path string) string {
+	p := loaded()
+	if p == nil {
+		return ""
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.required(method, path)
+}
+
+// RequirePermission returns gin middleware that rejects requests from
+// panel users without the given permission. Other plugins put it in front
+// of their handlers.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Can(c.GetString("username"), permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+			return
+		}
+		c.Next()
+	}
+}
+
+// enforce checks a request against the path rules. It runs on the panel's
+// request hook, so the rules cover the panel's own routes and every
+// plugin's.
+func (p *RBACRolesPlugin) enforce(c *gin.Context, next func()) {
+	p.mu.RLock()
+	perm := ""
+	if p.enforcing() {
+		perm = p.required(c.Request.Method, c.Request.URL.Path)
+	}
+	allowed := perm == "" || p.permissions(c.GetString("username"))[perm]
+	p.mu.RUnlock()
+
+	if !allowed {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Missing permission " + perm})
+		return
+	}
+	next()
+}
@@ -0,0 +1,214 @@
+/**
+ * Registration Stats Frontend Script
+ *
+ * Draws account growth charts on the plugin page.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'registration-stats';
+  const PLUGIN_NAME = 'Registration Stats';
+  const PAGE_PATH = '/plugins/registration-stats';
+  const API_BASE = '/api/plugin/registration-stats';
+
+  let rangeDays = 30;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  // Line chart of the running total
+  function totalChart(days, width, height) {
+    const values = days.map(d => d.total);
+    const max = Math.max(...values, 1);
+    const min = Math.min(...values.filter(v => v > 0), max);
+    const span = Math.max(max - min, 1);
+    const step = width / Math.max(days.length - 1, 1);
+
+    const points = values.map((v, i) => {
+      const y = height - ((Math.max(v, min) - min) / span) * (height - 20) - 10;
+      return `${(i * step).toFixed(1)},${y.toFixed(1)}`;
+    }).join(' ');
+
+    return `
+      <svg viewBox="0 0 ${width} ${height}" class="regstats-chart" preserveAspectRatio="none">
+        <polyline points="${points}" fill="none" stroke="var(--accent, #89b4fa)" stroke-width="2"/>
+      </svg>
+    `;
+  }
+
+  // Bar chart of registrations (up) and drops (down)
+  function flowChart(days, width, height) {
+    const max = Math.max(...days.map(d => Math.max(d.registrations, d.drops)), 1);
+    const mid = height / 2;
+    const barWidth = width / days.length;
+
+    const bars = days.map((d, i) => {
+      const x = i * barWidth + 1;
+      const up = (d.registrations / max) * (mid - 4);
+      const down = (d.drops / max) * (mid - 4);
+      return `
+        <rect x="${x}" y="${mid - up}" width="${barWidth - 2}" height="${up}" fill="var(--success, #a6e3a1)">
+          <title>${escapeHtml(d.date)}: +${d.registrations}</title>
+        </rect>
+        <rect x="${x}" y="${mid}" width="${barWidth - 2}" height="${down}" fill="var(--error, #f38ba8)">
+          <title>${escapeHtml(d.date)}: -${d.drops}</title>
+        </rect>
+      `;
+    }).join('');
+
+    return `
+      <svg viewBox="0 0 ${width} ${height}" class="regstats-chart" preserveAspectRatio="none">
+        ${bars}
+        <line x1="0" y1="${mid}" x2="${width}" y2="${mid}" stroke="var(--border-primary, #313244)"/>
+      </svg>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('registration-stats-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'registration-stats-styles';
+    style.textContent = `
+      .regstats-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .regstats-tiles { display: grid; grid-template-columns: repeat(auto-fit, minmax(140px, 1fr)); gap: 0.75rem; }
+      .regstats-tile {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        padding: 0.75rem;
+      }
+      .regstats-tile strong { display: block; font-size: 1.5rem; color: var(--text-primary, #cdd6f4); }
+      .regstats-chart { width: 100%; height: 180px; background: var(--bg-secondary, #181825); border-radius: 8px; }
+      .regstats-range button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        margin-right: 0.25rem;
+        cursor: pointer;
+      }
+      .regstats-range button.active { background: var(--accent, #89b4fa); color: #fff; }
+      .regstats-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#regstats-body');
+    try {
+      const [summary, daily] = await Promise.all([
+        api('/summary'),
+        api(`/daily?days=${rangeDays}`)
+      ]);
+
+      body.innerHTML = `
+        <div class="regstats-tiles">
+          <div class="regstats-tile"><strong>${summary.total}</strong>registered accounts</div>
+          <div class="regstats-tile"><strong>+${summary.registrations_7d}</strong>registrations (7d)</div>
+          <div class="regstats-tile"><strong>-${summary.drops_7d}</strong>drops (7d)</div>
+          <div class="regstats-tile"><strong>${summary.net_30d >= 0 ? '+' : ''}${summary.net_30d}</strong>net growth (30d)</div>
+        </div>
+        <h3>Total accounts</h3>
+        ${totalChart(daily.days, 600, 180)}
+        <h3>Registrations and drops per day</h3>
+        ${flowChart(daily.days, 600, 180)}
+        ${summary.last_error ? `<div class="regstats-error">Last sample failed: ${escapeHtml(summary.last_error)}</div>` : ''}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="regstats-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="regstats-app" data-plugin="${PLUGIN_ID}">
+        <div class="regstats-range">
+          ${[30, 90, 365].map(d => `<button data-days="${d}" class="${d === rangeDays ? 'active' : ''}">${d} days</button>`).join('')}
+        </div>
+        <div id="regstats-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelectorAll('.regstats-range button').forEach(btn => {
+      btn.addEventListener('click', () => {
+        rangeDays = parseInt(btn.dataset.days, 10);
+        container.querySelectorAll('.regstats-range button').forEach(b => b.classList.toggle('active', b === btn));
+        load(container);
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('registration-stats-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
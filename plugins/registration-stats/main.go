@@ -0,0 +1,475 @@
+// Registration Stats Plugin for UnrealIRCd Web Panel
+// Tracks daily account registrations, drops and the total number of
+// registered accounts by sampling the services account list
+
+package registrationstats
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/services"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// RegistrationStatsPlugin implements the Plugin interface
+type RegistrationStatsPlugin struct {
+	config     Config
+	services   services.Backend
+	data       storeData
+	lastSample time.Time
+	lastError  string
+	mu         sync.RWMutex
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	DataDir          string `json:"data_dir"`
+	ServicesBackend  string `json:"services_backend"`
+	ServicesEndpoint string `json:"services_endpoint"`
+	ServicesUser     string `json:"services_user"`
+	ServicesPassword string `json:"services_password"`
+	ServicesSourceIP string `json:"services_source_ip"`
+	SampleInterval   int    `json:"sample_interval"`
+	RetentionDays    int    `json:"retention_days"`
+}
+
+// servicesConfig returns the settings of the services client
+func (cfg Config) servicesConfig() services.Config {
+	return services.Config{
+		Backend:  cfg.ServicesBackend,
+		Endpoint: cfg.ServicesEndpoint,
+		Username: cfg.ServicesUser,
+		Password: cfg.ServicesPassword,
+		SourceIP: cfg.ServicesSourceIP,
+	}
+}
+
+// DayStats holds the registration figures for one day
+type DayStats struct {
+	Date          string `json:"date"`
+	Registrations int    `json:"registrations"`
+	Drops         int    `json:"drops"`
+	Total         int    `json:"total"`
+	Exact         bool   `json:"exact"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Days     map[string]*DayStats `json:"days"`
+	Accounts []string             `json:"accounts"`
+	Total    int                  `json:"total"`
+}
+
+// Matches "End of list - 50/1234 matches shown." (Anope) and
+// "*** 1234 matches for criteria ***" (Atheme)
+var (
+	anopeTotal  = regexp.MustCompile(`(\d+)/(\d+) match`)
+	athemeTotal = regexp.MustCompile(`(\d+) match`)
+	formatting  = regexp.MustCompile("\x03\\d{1,2}(,\\d{1,2})?|[\x02\x03\x0f\x16\x1d\x1f]")
+)
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &RegistrationStatsPlugin{
+		config: Config{
+			DataDir:          "data/plugins/registration-stats",
+			ServicesBackend:  "anope",
+			ServicesEndpoint: "http://127.0.0.1:8080/xmlrpc",
+			ServicesSourceIP: "127.0.0.1",
+			SampleInterval:   3600,
+			RetentionDays:    730,
+		},
+		data: storeData{
+			Days: make(map[string]*DayStats),
+		},
+	}
+}
+
+// Info returns plugin metadata
+func (p *RegistrationStatsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Registration Stats",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Daily account registrations, drops and growth",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *RegistrationStatsPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.data); err != nil {
+		log.Printf("[registration-stats] failed to load data: %v", err)
+	}
+	if p.data.Days == nil {
+		p.data.Days = make(map[string]*DayStats)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "registration-stats-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		regs, drops := 0, 0
+		for _, d := range p.lastDays(7) {
+			regs += d.Registrations
+			drops += d.Drops
+		}
+		return plugins.DashboardCard{
+			Title: "Registered Accounts",
+			Icon:  "Users",
+			Content: map[string]interface{}{
+				"total":            p.data.Total,
+				"registrations_7d": regs,
+				"drops_7d":         drops,
+				"net_7d":           regs - drops,
+			},
+			Order: 40,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.sampleLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *RegistrationStatsPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *RegistrationStatsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/registration-stats")
+	{
+		plugin.GET("/summary", p.handleSummary)
+		plugin.GET("/daily", p.handleDaily)
+		plugin.POST("/sample", p.handleSample)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *RegistrationStatsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "registrations.json")
+}
+
+// sampleLoop samples the account list until shutdown
+func (p *RegistrationStatsPlugin) sampleLoop() {
+	defer p.wg.Done()
+
+	p.sample()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.SampleInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < time.Minute {
+			interval = time.Minute
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.sample()
+		}
+	}
+}
+
+// sample lists all registered accounts and updates today's figures
+func (p *RegistrationStatsPlugin) sample() error {
+	p.mu.Lock()
+	if p.services == nil {
+		b, err := services.New(p.config.servicesConfig())
+		if err != nil {
+			p.lastError = err.Error()
+			p.mu.Unlock()
+			return err
+		}
+		p.services = b
+	}
+	backend := p.services
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	cmd := "LIST *"
+	if backend.Name() == "atheme" {
+		cmd = "LIST pattern *"
+	}
+	lines, err := backend.Command(ctx, "NickServ", cmd)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		return err
+	}
+	p.lastError = ""
+	p.lastSample = time.Now()
+
+	names, total := parseList(lines)
+	exact := total == len(names)
+
+	today := p.lastSample.Format("2006-01-02")
+	day, ok := p.data.Days[today]
+	if !ok {
+		day = &DayStats{Date: today, Exact: true}
+		p.data.Days[today] = day
+	}
+
+	switch {
+	case exact && p.data.Accounts != nil:
+		// Full list available, so registrations and drops can be told apart
+		added, removed := diff(p.data.Accounts, names)
+		day.Registrations += added
+		day.Drops += removed
+	case p.data.Total > 0:
+		// Truncated list, only the net change is known
+		if delta := total - p.data.Total; delta > 0 {
+			day.Registrations += delta
+		} else {
+			day.Drops -= delta
+		}
+		day.Exact = false
+	}
+	day.Total = total
+
+	if exact {
+		p.data.Accounts = names
+	} else {
+		p.data.Accounts = nil
+	}
+	p.data.Total = total
+	p.prune()
+
+	if err := storage.SaveJSON(p.storePath(), p.data); err != nil {
+		log.Printf("[registration-stats] failed to save data: %v", err)
+	}
+	return nil
+}
+
+// prune removes days older than the retention period. Caller must hold p.mu.
+func (p *RegistrationStatsPlugin) prune() {
+	if p.config.RetentionDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -p.config.RetentionDays).Format("2006-01-02")
+	for date := range p.data.Days {
+		if date < cutoff {
+			delete(p.data.Days, date)
+		}
+	}
+}
+
+// lastDays returns the stats of the last n days, oldest first. Caller must hold p.mu.
+func (p *RegistrationStatsPlugin) lastDays(n int) []DayStats {
+	days := make([]DayStats, 0, n)
+	now := time.Now()
+	total := 0
+	for i := n - 1; i >= 0; i-- {
+		date := now.AddDate(0, 0, -i).Format("2006-01-02")
+		if d, ok := p.data.Days[date]; ok {
+			total = d.Total
+			days = append(days, *d)
+			continue
+		}
+		// Carry the last known total over days without a sample
+		days = append(days, DayStats{Date: date, Total: total})
+	}
+	return days
+}
+
+// parseList extracts account names and the reported match count from
+// NickServ LIST output
+func parseList(lines []string) ([]string, int) {
+	names := make([]string, 0, len(lines))
+	total := -1
+
+	for _, raw := range lines {
+		line := formatting.ReplaceAllString(raw, "")
+		if m := anopeTotal.FindStringSubmatch(line); m != nil {
+			total, _ = strconv.Atoi(m[2])
+			continue
+		}
+		if m := athemeTotal.FindStringSubmatch(line); m != nil && strings.Contains(line, "***") {
+			total, _ = strconv.Atoi(m[1])
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "- ") && len(fields) > 1:
+			names = append(names, strings.ToLower(fields[1]))
+		case strings.HasPrefix(line, " ") && len(fields) > 0:
+			names = append(names, strings.ToLower(fields[0]))
+		}
+	}
+
+	if total < 0 {
+		total = len(names)
+	}
+	sort.Strings(names)
+	return names, total
+}
+
+// diff counts names added to and removed from a sorted list
+func diff(before, after []string) (added, removed int) {
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] == after[j]:
+			i++
+			j++
+		case before[i] < after[j]:
+			removed++
+			i++
+		default:
+			added++
+			j++
+		}
+	}
+	return added + len(after) - j, removed + len(before) - i
+}
+
+// handleSummary returns the current total and recent growth
+func (p *RegistrationStatsPlugin) handleSummary(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	summary := gin.H{
+		"total":       p.data.Total,
+		"last_sample": p.lastSample,
+		"last_error":  p.lastError,
+	}
+	for _, n := range []int{1, 7, 30} {
+		regs, drops := 0, 0
+		for _, d := range p.lastDays(n) {
+			regs += d.Registrations
+			drops += d.Drops
+		}
+		key := strconv.Itoa(n) + "d"
+		summary["registrations_"+key] = regs
+		summary["drops_"+key] = drops
+		summary["net_"+key] = regs - drops
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// handleDaily returns per-day figures for charting
+func (p *RegistrationStatsPlugin) handleDaily(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 3650 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 3650"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"days": p.lastDays(days),
+	})
+}
+
+// handleSample takes a sample immediately
+func (p *RegistrationStatsPlugin) handleSample(c *gin.Context) {
+	if err := p.sample(); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sample taken",
+		"total":   p.data.Total,
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *RegistrationStatsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.ServicesPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *RegistrationStatsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.ServicesPassword == "" {
+		newConfig.ServicesPassword = p.config.ServicesPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.services = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *RegistrationStatsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *RegistrationStatsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.services = nil
+	return json.Unmarshal(data, &p.config)
+}
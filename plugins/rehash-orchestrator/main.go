@@ -0,0 +1,640 @@
+// Rehash Orchestrator Plugin for UnrealIRCd Web Panel
+// Rehashes selected or all servers over JSON-RPC, gathers the output and
+// config errors of each server and keeps a history of every rehash
+
+package rehashorchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Run states
+const (
+	StatusRunning = "running"
+	StatusOK      = "ok"
+	StatusErrors  = "errors"
+	StatusFailed  = "failed"
+)
+
+// RehashOrchestratorPlugin implements the Plugin interface
+type RehashOrchestratorPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	runs         []*Run
+	active       *Run
+	dirty        bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	StreamURL      string `json:"stream_url"`
+	UseStream      bool   `json:"use_stream"`
+	LogSources     string `json:"log_sources"`
+	DataDir        string `json:"data_dir"`
+	CollectSeconds int    `json:"collect_seconds"`
+	MaxRuns        int    `json:"max_runs"`
+}
+
+// LogLine is a log message attributed to a rehash
+type LogLine struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	EventID string `json:"event_id"`
+	Server  string `json:"server,omitempty"`
+	Message string `json:"message"`
+}
+
+// ServerResult is the outcome of rehashing one server
+type ServerResult struct {
+	Server   string          `json:"server"`
+	Accepted bool            `json:"accepted"`
+	Success  *bool           `json:"success"`
+	Error    string          `json:"error,omitempty"`
+	Errors   int             `json:"errors"`
+	Warnings int             `json:"warnings"`
+	Log      []LogLine       `json:"log"`
+	Output   json.RawMessage `json:"output,omitempty"`
+}
+
+// Run is a single rehash of one or more servers
+type Run struct {
+	ID         string          `json:"id"`
+	Actor      string          `json:"actor"`
+	Reason     string          `json:"reason"`
+	All        bool            `json:"all_servers"`
+	Status     string          `json:"status"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+	Errors     int             `json:"errors"`
+	Warnings   int             `json:"warnings"`
+	Servers    []*ServerResult `json:"servers"`
+	Other      []LogLine       `json:"other_log"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined bool `json:"ulined"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &RehashOrchestratorPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			StreamURL:      "wss://127.0.0.1:8600/",
+			UseStream:      true,
+			LogSources:     "config, rehash",
+			DataDir:        "data/plugins/rehash-orchestrator",
+			CollectSeconds: 15,
+			MaxRuns:        500,
+		},
+		runs: make([]*Run, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *RehashOrchestratorPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Rehash Orchestrator",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Network-wide rehash with aggregated output and history",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *RehashOrchestratorPlugin) Init() error {
+	p.mu.Lock()
+	var runs []*Run
+	if err := storage.LoadJSON(p.storePath(), &runs); err != nil {
+		log.Printf("[rehash-orchestrator] failed to load data: %v", err)
+	}
+	if runs != nil {
+		p.runs = runs
+	}
+	for _, r := range p.runs {
+		// The panel stopped before this rehash finished collecting output
+		if r.Status == StatusRunning {
+			r.Status = StatusErrors
+		}
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "rehash-orchestrator-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{"rehashes": len(p.runs)}
+		if len(p.runs) > 0 {
+			last := p.runs[len(p.runs)-1]
+			content["last_status"] = last.Status
+			content["last_by"] = last.Actor
+			content["last_at"] = last.StartedAt
+		}
+		return plugins.DashboardCard{
+			Title:   "Last Rehash",
+			Icon:    "RefreshCw",
+			Content: content,
+			Order:   37,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.streamLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *RehashOrchestratorPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *RehashOrchestratorPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/rehash-orchestrator")
+	{
+		plugin.GET("/servers", p.handleServers)
+		plugin.POST("/rehash", p.handleRehash)
+		plugin.GET("/runs", p.handleListRuns)
+		plugin.GET("/runs/:id", p.handleGetRun)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *RehashOrchestratorPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "runs.json")
+}
+
+// save persists the history if it changed
+func (p *RehashOrchestratorPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.runs); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *RehashOrchestratorPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// streamLoop follows config log events until shutdown
+func (p *RehashOrchestratorPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		enabled := p.config.UseStream
+		sources := strings.FieldsFunc(p.config.LogSources, func(r rune) bool { return r == ',' || r == ' ' })
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, sources)
+		p.mu.Unlock()
+
+		if enabled {
+			stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		} else {
+			<-ctx.Done()
+		}
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *RehashOrchestratorPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[rehash-orchestrator] log stream: %v", err)
+	}
+}
+
+// handleEvent attaches log events to the rehash in progress, attributed to
+// the server that logged them
+func (p *RehashOrchestratorPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	var extra struct {
+		LogSource string `json:"log_source"`
+	}
+	_ = json.Unmarshal(ev.Raw, &extra)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active == nil {
+		return
+	}
+	p.active.addLog(LogLine{
+		Time:    ev.Timestamp,
+		Level:   ev.Level,
+		EventID: ev.EventID,
+		Server:  extra.LogSource,
+		Message: ev.Msg,
+	})
+}
+
+// addLog attributes a log line to the matching server, or to the run if
+// no server matches. Caller must hold p.mu.
+func (r *Run) addLog(line LogLine) {
+	for _, s := range r.Servers {
+		if strings.EqualFold(s.Server, line.Server) {
+			s.addLog(line)
+			return
+		}
+	}
+	r.Other = append(r.Other, line)
+}
+
+// addLog records a log line and counts errors and warnings
+func (s *ServerResult) addLog(line LogLine) {
+	s.Log = append(s.Log, line)
+	switch strings.ToLower(line.Level) {
+	case "error", "fatal":
+		s.Errors++
+	case "warn", "warning":
+		s.Warnings++
+	}
+}
+
+// targetServers resolves the servers to rehash. An empty list means all
+// linked servers except services.
+func (p *RehashOrchestratorPlugin) targetServers(ctx context.Context, requested []string) ([]string, error) {
+	if len(requested) > 0 {
+		return requested, nil
+	}
+	var list struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := p.client().Call(ctx, "server.list", nil, &list); err != nil {
+		return nil, err
+	}
+	servers := make([]string, 0, len(list.List))
+	for _, s := range list.List {
+		if !s.Server.ULined {
+			servers = append(servers, s.Name)
+		}
+	}
+	sort.Strings(servers)
+	return servers, nil
+}
+
+// execute rehashes every server of the run in parallel, then keeps
+// collecting log events for the configured window before finishing it
+func (p *RehashOrchestratorPlugin) execute(run *Run) {
+	defer p.wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	rpc := p.client()
+	var wg sync.WaitGroup
+	for _, s := range run.Servers {
+		wg.Add(1)
+		go func(s *ServerResult) {
+			defer wg.Done()
+			var out json.RawMessage
+			err := rpc.Call(ctx, "server.rehash", map[string]interface{}{"server": s.Server}, &out)
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if err != nil {
+				s.Error = err.Error()
+				return
+			}
+			s.Accepted = true
+			s.Output = out
+			s.parseOutput(out)
+		}(s)
+	}
+	wg.Wait()
+
+	p.mu.RLock()
+	collect := time.Duration(p.config.CollectSeconds) * time.Second
+	streaming := p.config.UseStream && p.streamOK
+	p.mu.RUnlock()
+	if streaming && collect > 0 {
+		select {
+		case <-p.stop:
+		case <-time.After(collect):
+		}
+	}
+
+	p.mu.Lock()
+	now := time.Now().UTC()
+	run.FinishedAt = &now
+	run.Status = StatusOK
+	failed := 0
+	for _, s := range run.Servers {
+		run.Errors += s.Errors
+		run.Warnings += s.Warnings
+		if s.Error != "" || (s.Success != nil && !*s.Success) {
+			failed++
+		}
+	}
+	switch {
+	case failed == len(run.Servers):
+		run.Status = StatusFailed
+	case failed > 0 || run.Errors > 0:
+		run.Status = StatusErrors
+	}
+	p.active = nil
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[rehash-orchestrator] failed to save data: %v", err)
+	}
+	log.Printf("[rehash-orchestrator] rehash %s by %s finished: %s (%d errors, %d warnings)", run.ID, run.Actor, run.Status, run.Errors, run.Warnings)
+}
+
+// parseOutput picks the success flag and log lines out of a server.rehash
+// result. Remote servers only acknowledge the request; the local server
+// reports the outcome and log of the rehash.
+func (s *ServerResult) parseOutput(out json.RawMessage) {
+	var result struct {
+		Success *bool `json:"success"`
+		Log     []struct {
+			Level     string `json:"level"`
+			EventID   string `json:"event_id"`
+			Msg       string `json:"msg"`
+			Timestamp string `json:"timestamp"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return
+	}
+	s.Success = result.Success
+	for _, l := range result.Log {
+		s.addLog(LogLine{Time: l.Timestamp, Level: l.Level, EventID: l.EventID, Server: s.Server, Message: l.Msg})
+	}
+}
+
+// handleServers lists the servers that can be rehashed
+func (p *RehashOrchestratorPlugin) handleServers(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	servers, err := p.targetServers(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"servers": servers})
+}
+
+// handleRehash starts a rehash of the requested servers (all if none are
+// given). The run is returned straight away and completes in the
+// background.
+func (p *RehashOrchestratorPlugin) handleRehash(c *gin.Context) {
+	var req struct {
+		Servers []string `json:"servers"`
+		Reason  string   `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	servers, err := p.targetServers(ctx, req.Servers)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if len(servers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No servers to rehash"})
+		return
+	}
+
+	run := &Run{
+		ID:        newID(),
+		Actor:     access.Actor(c),
+		Reason:    req.Reason,
+		All:       len(req.Servers) == 0,
+		Status:    StatusRunning,
+		StartedAt: time.Now().UTC(),
+		Servers:   make([]*ServerResult, 0, len(servers)),
+		Other:     make([]LogLine, 0),
+	}
+	for _, name := range servers {
+		run.Servers = append(run.Servers, &ServerResult{Server: name, Log: make([]LogLine, 0)})
+	}
+
+	p.mu.Lock()
+	select {
+	case <-p.stop:
+		p.mu.Unlock()
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plugin is shutting down"})
+		return
+	default:
+	}
+	if p.active != nil {
+		active := p.active.ID
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "A rehash is already in progress", "id": active})
+		return
+	}
+	p.active = run
+	p.runs = append(p.runs, run)
+	if max := p.config.MaxRuns; max > 0 && len(p.runs) > max {
+		p.runs = append([]*Run(nil), p.runs[len(p.runs)-max:]...)
+	}
+	p.dirty = true
+	p.wg.Add(1)
+	p.mu.Unlock()
+
+	log.Printf("[rehash-orchestrator] %s started rehash %s of %s", run.Actor, run.ID, strings.Join(servers, ", "))
+	go p.execute(run)
+
+	c.JSON(http.StatusAccepted, gin.H{"id": run.ID, "servers": servers})
+}
+
+// handleListRuns returns the rehash history, newest first, without logs
+func (p *RehashOrchestratorPlugin) handleListRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 {
+		limit = 50
+	}
+	actor := c.Query("actor")
+	server := c.Query("server")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]gin.H, 0)
+	for i := len(p.runs) - 1; i >= 0 && len(list) < limit; i-- {
+		r := p.runs[i]
+		if actor != "" && !strings.EqualFold(r.Actor, actor) {
+			continue
+		}
+		names := make([]string, 0, len(r.Servers))
+		match := server == ""
+		for _, s := range r.Servers {
+			names = append(names, s.Server)
+			if strings.EqualFold(s.Server, server) {
+				match = true
+			}
+		}
+		if !match {
+			continue
+		}
+		list = append(list, gin.H{
+			"id":          r.ID,
+			"actor":       r.Actor,
+			"reason":      r.Reason,
+			"all_servers": r.All,
+			"servers":     names,
+			"status":      r.Status,
+			"errors":      r.Errors,
+			"warnings":    r.Warnings,
+			"started_at":  r.StartedAt,
+			"finished_at": r.FinishedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": list})
+}
+
+// handleGetRun returns a rehash with the output of every server
+func (p *RehashOrchestratorPlugin) handleGetRun(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, r := range p.runs {
+		if r.ID == c.Param("id") {
+			c.JSON(http.StatusOK, r)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Rehash not found"})
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleGetConfig returns the current configuration
+func (p *RehashOrchestratorPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *RehashOrchestratorPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *RehashOrchestratorPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *RehashOrchestratorPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,292 @@
+/**
+ * Reputation Viewer Frontend Script
+ *
+ * Lists connected users by reputation score, shows the score distribution
+ * and lets authorized staff adjust scores.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'reputation-viewer';
+  const PLUGIN_NAME = 'Reputation Viewer';
+  const PAGE_PATH = '/plugins/reputation-viewer';
+  const API_BASE = '/api/plugin/reputation-viewer';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('reputation-viewer-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'reputation-viewer-styles';
+    style.textContent = `
+      .repv-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .repv-form { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .repv-app input {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.35rem 0.6rem;
+      }
+      .repv-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .repv-dist { display: flex; gap: 0.5rem; align-items: flex-end; height: 120px; }
+      .repv-bar { flex: 1; display: flex; flex-direction: column; align-items: center; justify-content: flex-end; height: 100%; font-size: 0.8rem; }
+      .repv-bar div { width: 100%; background: var(--accent, #89b4fa); border-radius: 4px 4px 0 0; min-height: 1px; }
+      .repv-table { width: 100%; border-collapse: collapse; }
+      .repv-table th, .repv-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        overflow-wrap: anywhere;
+      }
+      .repv-low { color: var(--warning, #f9e2af); }
+      .repv-zero { color: var(--error, #f38ba8); }
+      .repv-adjust {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        padding: 0.75rem;
+      }
+      .repv-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function scoreClass(score, low) {
+    if (score === 0) return 'repv-zero';
+    return score < low ? 'repv-low' : '';
+  }
+
+  async function loadDistribution(container) {
+    const body = container.querySelector('#repv-dist');
+    try {
+      const data = await api('GET', '/distribution');
+      const max = Math.max(...data.buckets.map(b => b.count), 1);
+      body.innerHTML = `
+        <div class="repv-dist">
+          ${data.buckets.map(b => `
+            <div class="repv-bar" title="${b.count} users with score ${escapeHtml(b.label)}">
+              <span>${b.count}</span>
+              <div style="height: ${(b.count / max * 80).toFixed(1)}%"></div>
+              <span>${escapeHtml(b.label)}</span>
+            </div>
+          `).join('')}
+        </div>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="repv-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function showAdjust(container, ip, current) {
+    const box = container.querySelector('#repv-adjust');
+    box.innerHTML = `
+      <form class="repv-form repv-adjust">
+        <strong>Adjust ${escapeHtml(ip)}</strong> (currently ${current})
+        <input name="score" type="number" min="0" value="${current}" required>
+        <input name="reason" placeholder="Reason" required size="40">
+        <button type="submit">Save</button>
+        <button type="button" data-cancel>Cancel</button>
+      </form>
+    `;
+    const form = box.querySelector('form');
+    form.querySelector('[data-cancel]').addEventListener('click', () => { box.innerHTML = ''; });
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = form.elements;
+      try {
+        await api('POST', '/adjust', { ip, score: parseInt(f.score.value, 10), reason: f.reason.value });
+        box.innerHTML = '';
+        loadUsers(container);
+        loadHistory(container);
+        loadDistribution(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+  }
+
+  async function loadUsers(container) {
+    const body = container.querySelector('#repv-users');
+    const q = container.querySelector('#repv-filter').elements.q.value;
+    try {
+      const data = await api('GET', `/users?limit=500&q=${encodeURIComponent(q)}`);
+      body.innerHTML = `
+        ${data.last_error ? `<div class="repv-error">Last update failed: ${escapeHtml(data.last_error)}</div>` : ''}
+        <p>${data.total} users${data.total > data.users.length ? `, showing the lowest ${data.users.length}` : ''}. Scores below ${data.low_score} are highlighted.</p>
+        <table class="repv-table">
+          <thead><tr><th>Score</th><th>Nick</th><th>IP</th><th>Host</th><th>Account</th><th>Security groups</th>${data.can_adjust ? '<th></th>' : ''}</tr></thead>
+          <tbody>
+            ${data.users.map(u => `
+              <tr>
+                <td class="${scoreClass(u.reputation, data.low_score)}"><strong>${u.reputation}</strong></td>
+                <td>${escapeHtml(u.nick)}</td>
+                <td><code>${escapeHtml(u.ip)}</code></td>
+                <td>${escapeHtml(u.host)}</td>
+                <td>${escapeHtml(u.account || '')}</td>
+                <td>${escapeHtml((u.security_groups || []).join(', '))}</td>
+                ${data.can_adjust ? `<td><button data-ip="${escapeHtml(u.ip)}" data-score="${u.reputation}">Adjust</button></td>` : ''}
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+      body.querySelectorAll('button[data-ip]').forEach(btn => {
+        btn.addEventListener('click', () => showAdjust(container, btn.dataset.ip, parseInt(btn.dataset.score, 10)));
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="repv-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadHistory(container) {
+    const body = container.querySelector('#repv-history');
+    try {
+      const data = await api('GET', '/history?limit=100');
+      if (data.adjustments.length === 0) {
+        body.innerHTML = '<p>No adjustments yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="repv-table">
+          <thead><tr><th>Time</th><th>IP</th><th>Nicks</th><th>Change</th><th>By</th><th>Reason</th></tr></thead>
+          <tbody>
+            ${data.adjustments.map(a => `
+              <tr>
+                <td>${escapeHtml(new Date(a.time).toLocaleString())}</td>
+                <td><code>${escapeHtml(a.ip)}</code></td>
+                <td>${escapeHtml((a.nicks || []).join(', '))}</td>
+                <td>${a.old_score != null ? `${a.old_score} &rarr; ` : ''}${a.new_score}</td>
+                <td>${escapeHtml(a.actor)}</td>
+                <td>${escapeHtml(a.reason)}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="repv-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="repv-app" data-plugin="${PLUGIN_ID}">
+        <h3>Score distribution</h3>
+        <div id="repv-dist">Loading...</div>
+        <h3>Connected users</h3>
+        <form class="repv-form" id="repv-filter">
+          <input name="q" placeholder="Filter by nick, IP, host or account">
+          <button type="submit">Filter</button>
+          <button type="button" data-refresh>Refresh now</button>
+        </form>
+        <div id="repv-adjust"></div>
+        <div id="repv-users">Loading...</div>
+        <h3>Adjustment history</h3>
+        <div id="repv-history">Loading...</div>
+      </div>
+    `;
+
+    const filter = container.querySelector('#repv-filter');
+    filter.addEventListener('submit', (e) => {
+      e.preventDefault();
+      loadUsers(container);
+    });
+    filter.querySelector('[data-refresh]').addEventListener('click', async () => {
+      try {
+        await api('POST', '/refresh');
+        loadUsers(container);
+        loadDistribution(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    loadDistribution(container);
+    loadUsers(container);
+    loadHistory(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('reputation-viewer-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,645 @@
+// Reputation Viewer Plugin for UnrealIRCd Web Panel
+// Shows the IRCd's reputation scores for connected users and lets
+// authorized staff adjust them, keeping a history of every adjustment
+
+package reputationviewer
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// scoreBuckets are the lower bounds of the distribution buckets
+var scoreBuckets = []int{0, 1, 10, 25, 50, 100, 250}
+
+// ReputationViewerPlugin implements the Plugin interface
+type ReputationViewerPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	users     []*User
+	history   []*Adjustment
+	dirty     bool
+	lastPoll  time.Time
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL       string `json:"rpc_url"`
+	RPCUser      string `json:"rpc_user"`
+	RPCPassword  string `json:"rpc_password"`
+	RPCInsecure  bool   `json:"rpc_insecure"`
+	DataDir      string `json:"data_dir"`
+	PollInterval int    `json:"poll_interval"`
+	LowScore     int    `json:"low_score"`
+	AllowAdjust  bool   `json:"allow_adjust"`
+	AdjustUsers  string `json:"adjust_users"`
+	AdjustMethod string `json:"adjust_method"`
+	MaxHistory   int    `json:"max_history"`
+}
+
+// User is a connected client with its reputation score
+type User struct {
+	Nick           string   `json:"nick"`
+	IP             string   `json:"ip"`
+	Host           string   `json:"host"`
+	Account        string   `json:"account,omitempty"`
+	Server         string   `json:"server,omitempty"`
+	Reputation     int      `json:"reputation"`
+	SecurityGroups []string `json:"security_groups,omitempty"`
+	ConnectedSince string   `json:"connected_since,omitempty"`
+}
+
+// Adjustment is a reputation change made by a staff member
+type Adjustment struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	IP       string    `json:"ip"`
+	Nicks    []string  `json:"nicks,omitempty"`
+	OldScore *int      `json:"old_score,omitempty"`
+	NewScore int       `json:"new_score"`
+	Reason   string    `json:"reason"`
+}
+
+// AdjustRequest is the body of an adjustment request
+type AdjustRequest struct {
+	IP     string `json:"ip"`
+	Score  *int   `json:"score"`
+	Reason string `json:"reason"`
+}
+
+// rpcUser is the subset of the UnrealIRCd client object we need
+type rpcUser struct {
+	Name           string `json:"name"`
+	Hostname       string `json:"hostname"`
+	IP             string `json:"ip"`
+	ConnectedSince string `json:"connected_since"`
+	User           struct {
+		Account        string   `json:"account"`
+		Servername     string   `json:"servername"`
+		Reputation     int      `json:"reputation"`
+		SecurityGroups []string `json:"security-groups"`
+	} `json:"user"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ReputationViewerPlugin{
+		config: Config{
+			RPCURL:       "https://127.0.0.1:8600/api",
+			DataDir:      "data/plugins/reputation-viewer",
+			PollInterval: 60,
+			LowScore:     25,
+			MaxHistory:   2000,
+		},
+		users:   make([]*User, 0),
+		history: make([]*Adjustment, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ReputationViewerPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Reputation Viewer",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "View and adjust reputation scores of connected users",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ReputationViewerPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.history); err != nil {
+		log.Printf("[reputation-viewer] failed to load history: %v", err)
+	}
+	if p.history == nil {
+		p.history = make([]*Adjustment, 0)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "reputation-viewer-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		low := 0
+		for _, u := range p.users {
+			if u.Reputation < p.config.LowScore {
+				low++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Reputation",
+			Icon:  "Gauge",
+			Content: map[string]interface{}{
+				"users":          len(p.users),
+				"low_reputation": low,
+				"low_score":      p.config.LowScore,
+			},
+			Order: 52,
+			Size:  "sm",
+		}
+	}, 50)
+
+	// Add the reputation score and recent adjustments to user lookups
+	hm.Register(hooks.HookUserLookup, "reputation-viewer-lookup", func(args interface{}) interface{} {
+		m, ok := args.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		ip, _ := m["ip"].(string)
+		if ip == "" {
+			return nil
+		}
+
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		result := make(map[string]interface{})
+		if u := p.userByIP(ip); u != nil {
+			result["reputation"] = u.Reputation
+		}
+		if adj := p.adjustments(ip, 5); len(adj) > 0 {
+			result["reputation_adjustments"] = adj
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		return result
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ReputationViewerPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ReputationViewerPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/reputation-viewer")
+	{
+		plugin.GET("/users", p.handleUsers)
+		plugin.GET("/distribution", p.handleDistribution)
+		plugin.GET("/ip/:ip", p.handleIP)
+		plugin.POST("/refresh", p.handleRefresh)
+		plugin.POST("/adjust", p.handleAdjust)
+		plugin.GET("/history", p.handleHistory)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *ReputationViewerPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "adjustments.json")
+}
+
+// save persists the adjustment history if it changed
+func (p *ReputationViewerPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.history); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ReputationViewerPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop reads the user list until shutdown
+func (p *ReputationViewerPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.poll()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 10*time.Second {
+			interval = 10 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.poll()
+		}
+	}
+}
+
+// poll fetches all connected users with their reputation
+func (p *ReputationViewerPlugin) poll() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		return err
+	}
+	p.lastError = ""
+	p.lastPoll = time.Now()
+
+	users := make([]*User, 0, len(result.List))
+	for _, u := range result.List {
+		// Servers and services pseudo-clients have no IP and no score
+		if u.IP == "" {
+			continue
+		}
+		users = append(users, &User{
+			Nick:           u.Name,
+			IP:             u.IP,
+			Host:           u.Hostname,
+			Account:        u.User.Account,
+			Server:         u.User.Servername,
+			Reputation:     u.User.Reputation,
+			SecurityGroups: u.User.SecurityGroups,
+			ConnectedSince: u.ConnectedSince,
+		})
+	}
+	p.users = users
+	return nil
+}
+
+// userByIP returns a connected user on ip. Caller must hold p.mu.
+func (p *ReputationViewerPlugin) userByIP(ip string) *User {
+	for _, u := range p.users {
+		if u.IP == ip {
+			return u
+		}
+	}
+	return nil
+}
+
+// adjustments returns up to limit adjustments for ip, newest first. Caller
+// must hold p.mu.
+func (p *ReputationViewerPlugin) adjustments(ip string, limit int) []*Adjustment {
+	list := make([]*Adjustment, 0)
+	for i := len(p.history) - 1; i >= 0 && len(list) < limit; i-- {
+		if ip == "" || p.history[i].IP == ip {
+			list = append(list, p.history[i])
+		}
+	}
+	return list
+}
+
+// bucketLabel returns the distribution bucket a score falls in
+func bucketLabel(score int) string {
+	for i := len(scoreBuckets) - 1; i >= 0; i-- {
+		if score >= scoreBuckets[i] {
+			if i == len(scoreBuckets)-1 {
+				return fmt.Sprintf("%d+", scoreBuckets[i])
+			}
+			if scoreBuckets[i+1]-1 == scoreBuckets[i] {
+				return strconv.Itoa(scoreBuckets[i])
+			}
+			return fmt.Sprintf("%d-%d", scoreBuckets[i], scoreBuckets[i+1]-1)
+		}
+	}
+	return strconv.Itoa(scoreBuckets[0])
+}
+
+// canAdjust reports whether a panel user may change scores. Caller must
+// hold p.mu.
+func (p *ReputationViewerPlugin) canAdjust(actor string) error {
+	if !p.config.AllowAdjust {
+		return fmt.Errorf("adjusting reputation is disabled in the plugin settings")
+	}
+	if p.config.AdjustMethod == "" {
+		return fmt.Errorf("no adjust_method is configured")
+	}
+	if allowed := lists.Split(p.config.AdjustUsers); len(allowed) > 0 && !lists.ContainsFold(allowed, actor) {
+		return fmt.Errorf("%s is not allowed to adjust reputation", actor)
+	}
+	return nil
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleUsers returns connected users, lowest score first by default
+func (p *ReputationViewerPlugin) handleUsers(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	min, minErr := strconv.Atoi(c.DefaultQuery("min", "-1"))
+	max, maxErr := strconv.Atoi(c.DefaultQuery("max", "-1"))
+	if minErr != nil || maxErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min and max must be numbers"})
+		return
+	}
+	q := strings.ToLower(c.Query("q"))
+	sortBy := c.DefaultQuery("sort", "score")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*User, 0)
+	for _, u := range p.users {
+		if min >= 0 && u.Reputation < min {
+			continue
+		}
+		if max >= 0 && u.Reputation > max {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(u.Nick), q) && !strings.Contains(u.IP, q) &&
+			!strings.Contains(strings.ToLower(u.Host), q) && !strings.Contains(strings.ToLower(u.Account), q) {
+			continue
+		}
+		list = append(list, u)
+	}
+	switch sortBy {
+	case "nick":
+		sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Nick) < strings.ToLower(list[j].Nick) })
+	case "-score":
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Reputation > list[j].Reputation })
+	default:
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Reputation < list[j].Reputation })
+	}
+	total := len(list)
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	actorErr := p.canAdjust(access.Actor(c))
+	c.JSON(http.StatusOK, gin.H{
+		"users":      list,
+		"total":      total,
+		"low_score":  p.config.LowScore,
+		"last_poll":  p.lastPoll,
+		"last_error": p.lastError,
+		"can_adjust": actorErr == nil,
+	})
+}
+
+// handleDistribution returns how many connected users fall in each score
+// bucket
+func (p *ReputationViewerPlugin) handleDistribution(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, u := range p.users {
+		counts[bucketLabel(u.Reputation)]++
+	}
+	buckets := make([]gin.H, 0, len(scoreBuckets))
+	for _, b := range scoreBuckets {
+		label := bucketLabel(b)
+		buckets = append(buckets, gin.H{"label": label, "min": b, "count": counts[label]})
+	}
+	c.JSON(http.StatusOK, gin.H{"buckets": buckets, "users": len(p.users)})
+}
+
+// handleIP returns the users on an IP, its score and its adjustments
+func (p *ReputationViewerPlugin) handleIP(c *gin.Context) {
+	ip := c.Param("ip")
+	if net.ParseIP(ip) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	users := make([]*User, 0)
+	var score *int
+	for _, u := range p.users {
+		if u.IP == ip {
+			users = append(users, u)
+			s := u.Reputation
+			score = &s
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"ip":          ip,
+		"reputation":  score,
+		"users":       users,
+		"adjustments": p.adjustments(ip, p.config.MaxHistory),
+	})
+}
+
+// handleRefresh polls the user list straight away
+func (p *ReputationViewerPlugin) handleRefresh(c *gin.Context) {
+	if err := p.poll(); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User list refreshed"})
+}
+
+// handleAdjust sets the reputation score of an IP through the configured
+// RPC method and records the change
+func (p *ReputationViewerPlugin) handleAdjust(c *gin.Context) {
+	var req AdjustRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if net.ParseIP(req.IP) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+	if req.Score == nil || *req.Score < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "score must be zero or more"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	actor := access.Actor(c)
+	p.mu.RLock()
+	err := p.canAdjust(actor)
+	method := p.config.AdjustMethod
+	p.mu.RUnlock()
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+	params := map[string]interface{}{
+		"ip":     req.IP,
+		"score":  *req.Score,
+		"reason": reason,
+	}
+	var out json.RawMessage
+	if err := p.client().Call(ctx, method, params, &out); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	adj := &Adjustment{
+		ID:       newID(),
+		Time:     time.Now().UTC(),
+		Actor:    actor,
+		IP:       req.IP,
+		NewScore: *req.Score,
+		Reason:   reason,
+	}
+
+	p.mu.Lock()
+	for _, u := range p.users {
+		if u.IP != req.IP {
+			continue
+		}
+		if adj.OldScore == nil {
+			old := u.Reputation
+			adj.OldScore = &old
+		}
+		adj.Nicks = append(adj.Nicks, u.Nick)
+		u.Reputation = *req.Score
+	}
+	p.history = append(p.history, adj)
+	if max := p.config.MaxHistory; max > 0 && len(p.history) > max {
+		p.history = append([]*Adjustment(nil), p.history[len(p.history)-max:]...)
+	}
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[reputation-viewer] failed to save history: %v", err)
+	}
+
+	log.Printf("[reputation-viewer] %s set reputation of %s to %d: %s", actor, req.IP, *req.Score, reason)
+	c.JSON(http.StatusOK, adj)
+}
+
+// handleHistory returns adjustments, newest first
+func (p *ReputationViewerPlugin) handleHistory(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"adjustments": p.adjustments(c.Query("ip"), limit)})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ReputationViewerPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ReputationViewerPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ReputationViewerPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ReputationViewerPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,358 @@
+/**
+ * RPC Console Frontend Script
+ *
+ * Lists the JSON-RPC methods, builds and sends requests, shows the
+ * responses and the history of executed calls.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'rpc-console';
+  const PLUGIN_NAME = 'RPC Console';
+  const PAGE_PATH = '/plugins/rpc-console';
+  const API_BASE = '/api/plugin/rpc-console';
+
+  let methods = [];
+  let selected = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const res = await fetch(API_BASE + path, {
+      method,
+      headers: getAuthHeaders(),
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function formatSize(n) {
+    if (n < 1024) return `${n} B`;
+    return `${(n / 1024).toFixed(1)} KiB`;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('rpc-console-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'rpc-console-styles';
+    style.textContent = `
+      .rpcc-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .rpcc-main { display: grid; grid-template-columns: 16rem 1fr; gap: 1rem; }
+      .rpcc-methods { max-height: 70vh; overflow-y: auto; border-right: 1px solid var(--border-primary, #313244); padding-right: 0.5rem; }
+      .rpcc-group { margin: 0.6rem 0 0.2rem; font-size: 0.75rem; text-transform: uppercase; }
+      .rpcc-method { display: block; width: 100%; text-align: left; background: none; border: none; color: var(--text-primary, #cdd6f4); padding: 0.2rem 0.4rem; border-radius: 4px; cursor: pointer; font-family: monospace; }
+      .rpcc-method.active { background: var(--bg-tertiary, #313244); }
+      .rpcc-method.unavailable, .rpcc-method.blocked { opacity: 0.5; }
+      .rpcc-builder { display: flex; flex-direction: column; gap: 0.6rem; min-width: 0; }
+      .rpcc-field { display: grid; grid-template-columns: 12rem 1fr; gap: 0.5rem; align-items: center; }
+      .rpcc-field label { font-family: monospace; }
+      .rpcc-app input, .rpcc-app select, .rpcc-app textarea { background: var(--bg-secondary, #1e1e2e); color: var(--text-primary, #cdd6f4); border: 1px solid var(--border-primary, #313244); border-radius: 6px; padding: 0.35rem; }
+      .rpcc-app textarea { font-family: monospace; min-height: 6rem; width: 100%; box-sizing: border-box; }
+      .rpcc-app button.rpcc-btn { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); border: none; border-radius: 6px; padding: 0.4rem 0.8rem; cursor: pointer; }
+      .rpcc-app button:disabled { opacity: 0.5; cursor: default; }
+      .rpcc-result { background: var(--bg-secondary, #1e1e2e); border-radius: 6px; padding: 0.6rem; max-height: 50vh; overflow: auto; white-space: pre; font-family: monospace; font-size: 0.85rem; color: var(--text-primary, #cdd6f4); }
+      .rpcc-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .rpcc-table th, .rpcc-table td { text-align: left; padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .rpcc-table td { color: var(--text-primary, #cdd6f4); word-break: break-all; }
+      .rpcc-ok { color: var(--success, #a6e3a1); }
+      .rpcc-error { color: var(--error, #f38ba8); }
+      .rpcc-desc { font-size: 0.8rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderMethods(container) {
+    const list = container.querySelector('#rpcc-methods');
+    const groups = {};
+    methods.forEach(m => { (groups[m.group] = groups[m.group] || []).push(m); });
+    list.innerHTML = Object.keys(groups).map(g => `
+      <div class="rpcc-group">${escapeHtml(g)}</div>
+      ${groups[g].map(m => `
+        <button class="rpcc-method ${m.available ? '' : 'unavailable'} ${m.blocked ? 'blocked' : ''} ${selected && selected.name === m.name ? 'active' : ''}"
+          data-method="${escapeHtml(m.name)}" title="${escapeHtml(m.blocked || (m.available ? m.description : 'Not supported by the server'))}">${escapeHtml(m.name)}</button>
+      `).join('')}
+    `).join('');
+    list.querySelectorAll('.rpcc-method').forEach(btn => {
+      btn.addEventListener('click', () => selectMethod(container, btn.dataset.method, null));
+    });
+  }
+
+  function fieldInput(p) {
+    const id = `rpcc-param-${p.name}`;
+    if (p.enum) {
+      return `<select id="${id}" data-param="${escapeHtml(p.name)}" data-type="${p.type}">
+        <option value=""></option>${p.enum.map(v => `<option>${escapeHtml(v)}</option>`).join('')}</select>`;
+    }
+    if (p.type === 'boolean') {
+      return `<select id="${id}" data-param="${escapeHtml(p.name)}" data-type="boolean">
+        <option value=""></option><option value="true">true</option><option value="false">false</option></select>`;
+    }
+    const placeholder = p.type === 'string' ? '' : p.type === 'integer' ? 'number' : `JSON ${p.type}`;
+    return `<input id="${id}" data-param="${escapeHtml(p.name)}" data-type="${p.type}" placeholder="${placeholder}">`;
+  }
+
+  // Reads the form into a params object, leaving out empty fields
+  function formParams(container) {
+    const params = {};
+    container.querySelectorAll('[data-param]').forEach(el => {
+      const v = el.value.trim();
+      if (v === '') return;
+      switch (el.dataset.type) {
+        case 'integer': params[el.dataset.param] = Number(v); break;
+        case 'boolean': params[el.dataset.param] = v === 'true'; break;
+        case 'array':
+        case 'object':
+          try {
+            params[el.dataset.param] = JSON.parse(v);
+          } catch (e) {
+            params[el.dataset.param] = v;
+          }
+          break;
+        default: params[el.dataset.param] = v;
+      }
+    });
+    return params;
+  }
+
+  function syncRaw(container) {
+    const raw = container.querySelector('#rpcc-raw');
+    raw.value = JSON.stringify(formParams(container), null, 2);
+  }
+
+  function selectMethod(container, name, params) {
+    selected = methods.find(m => m.name === name) || { name, group: '', description: '', params: null };
+    renderMethods(container);
+    const builder = container.querySelector('#rpcc-builder');
+    builder.innerHTML = `
+      <div>
+        <strong style="font-family: monospace">${escapeHtml(selected.name)}</strong>
+        ${selected.read_only ? '<span class="rpcc-ok">read-only</span>' : '<span class="rpcc-error">makes changes</span>'}
+        <div class="rpcc-desc">${escapeHtml(selected.description)}${selected.module ? ` (${escapeHtml(selected.module)} ${escapeHtml(selected.version)})` : ''}</div>
+        ${selected.blocked ? `<div class="rpcc-error">${escapeHtml(selected.blocked)}</div>` : ''}
+      </div>
+      ${selected.params ? selected.params.map(p => `
+        <div class="rpcc-field">
+          <label for="rpcc-param-${escapeHtml(p.name)}">${escapeHtml(p.name)}${p.required ? ' *' : ''}</label>
+          <div>${fieldInput(p)} <span class="rpcc-desc">${escapeHtml(p.type)} - ${escapeHtml(p.description)}</span></div>
+        </div>
+      `).join('') || '<div class="rpcc-desc">No parameters</div>' : '<div class="rpcc-desc">Parameters of this method are unknown; write them as JSON below.</div>'}
+      <label class="rpcc-desc" for="rpcc-raw">Params (JSON, sent as is)</label>
+      <textarea id="rpcc-raw">{}</textarea>
+      <div><button class="rpcc-btn" id="rpcc-send" ${selected.blocked ? 'disabled' : ''}>Send</button></div>
+    `;
+
+    const raw = builder.querySelector('#rpcc-raw');
+    if (params) {
+      raw.value = JSON.stringify(params, null, 2);
+      builder.querySelectorAll('[data-param]').forEach(el => {
+        const v = params[el.dataset.param];
+        if (v == null) return;
+        el.value = typeof v === 'object' ? JSON.stringify(v) : String(v);
+      });
+    } else {
+      syncRaw(container);
+    }
+    builder.querySelectorAll('[data-param]').forEach(el => {
+      el.addEventListener('input', () => syncRaw(container));
+      el.addEventListener('change', () => syncRaw(container));
+    });
+
+    const send = builder.querySelector('#rpcc-send');
+    send.addEventListener('click', async () => {
+      let body;
+      try {
+        body = raw.value.trim() ? JSON.parse(raw.value) : {};
+      } catch (e) {
+        alert('Params are not valid JSON: ' + e.message);
+        return;
+      }
+      if (!selected.read_only && !confirm(`${selected.name} can make changes on the network. Send it?`)) return;
+      send.disabled = true;
+      try {
+        showCall(container, await api('POST', '/call', { method: selected.name, params: body }));
+      } catch (err) {
+        container.querySelector('#rpcc-response').innerHTML = `<div class="rpcc-error">${escapeHtml(err.message)}</div>`;
+      }
+      send.disabled = false;
+      loadHistory(container);
+    });
+  }
+
+  function showCall(container, call) {
+    const out = container.querySelector('#rpcc-response');
+    let body;
+    if (!call.success) {
+      body = `<div class="rpcc-error">${call.error_code ? `Error ${call.error_code}: ` : ''}${escapeHtml(call.error)}</div>`;
+    } else if (call.truncated) {
+      body = '<div class="rpcc-desc">The result was too large to keep in the history.</div>';
+    } else {
+      body = `<div class="rpcc-result">${escapeHtml(JSON.stringify(call.result, null, 2))}</div>`;
+    }
+    out.innerHTML = `
+      <h3>Response</h3>
+      <div class="rpcc-desc">${escapeHtml(call.method)} by ${escapeHtml(call.actor)} at ${formatTime(call.time)},
+        ${call.duration_ms} ms${call.success ? `, ${formatSize(call.result_size)}` : ''}</div>
+      ${body}
+    `;
+  }
+
+  async function loadHistory(container) {
+    const el = container.querySelector('#rpcc-history');
+    try {
+      const data = await api('GET', '/history');
+      el.innerHTML = `
+        <table class="rpcc-table">
+          <thead><tr><th>Time</th><th>By</th><th>Method</th><th>Params</th><th>Result</th><th></th></tr></thead>
+          <tbody>
+            ${data.calls.slice(0, 100).map(c => `
+              <tr>
+                <td>${formatTime(c.time)}</td><td>${escapeHtml(c.actor)}</td>
+                <td style="font-family: monospace">${escapeHtml(c.method)}</td>
+                <td style="font-family: monospace">${escapeHtml(c.params ? JSON.stringify(c.params) : '')}</td>
+                <td class="${c.success ? 'rpcc-ok' : 'rpcc-error'}">${c.success ? `${formatSize(c.result_size)}, ${c.duration_ms} ms` : escapeHtml(c.error)}</td>
+                <td>
+                  <button class="rpcc-btn rpcc-view" data-id="${c.id}">View</button>
+                  <button class="rpcc-btn rpcc-load" data-id="${c.id}">Load</button>
+                </td>
+              </tr>
+            `).join('') || '<tr><td colspan="6">No calls yet</td></tr>'}
+          </tbody>
+        </table>
+      `;
+      el.querySelectorAll('.rpcc-view').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          try {
+            showCall(container, await api('GET', `/history/${btn.dataset.id}`));
+          } catch (err) {
+            alert(err.message);
+          }
+        });
+      });
+      el.querySelectorAll('.rpcc-load').forEach(btn => {
+        btn.addEventListener('click', () => {
+          const call = data.calls.find(c => c.id === btn.dataset.id);
+          if (call) selectMethod(container, call.method, call.params || {});
+        });
+      });
+    } catch (e) {
+      el.innerHTML = `<div class="rpcc-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function load(container) {
+    try {
+      const data = await api('GET', '/methods');
+      methods = data.methods;
+      container.querySelector('#rpcc-status').innerHTML = data.info_error
+        ? `<span class="rpcc-error">Could not ask the server for its methods (${escapeHtml(data.info_error)}); showing every known method.</span>`
+        : `${methods.filter(m => m.available).length} methods available${data.read_only ? ', read-only mode' : ''}`;
+      renderMethods(container);
+      loadHistory(container);
+    } catch (e) {
+      container.querySelector('#rpcc-status').innerHTML = `<span class="rpcc-error">${escapeHtml(e.message)}</span>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    selected = null;
+    container.innerHTML = `
+      <div class="rpcc-app" data-plugin="${PLUGIN_ID}">
+        <div id="rpcc-status" class="rpcc-desc">Loading...</div>
+        <div class="rpcc-main">
+          <div id="rpcc-methods" class="rpcc-methods"></div>
+          <div>
+            <div id="rpcc-builder" class="rpcc-builder"><div class="rpcc-desc">Pick a method on the left.</div></div>
+            <div id="rpcc-response"></div>
+          </div>
+        </div>
+        <div>
+          <h3>History <button class="rpcc-btn" id="rpcc-clear">Clear</button></h3>
+          <div id="rpcc-history"></div>
+        </div>
+      </div>
+    `;
+
+    container.querySelector('#rpcc-clear').addEventListener('click', async () => {
+      if (!confirm('Clear the call history for everyone?')) return;
+      try {
+        await api('DELETE', '/history');
+      } catch (err) {
+        alert(err.message);
+      }
+      loadHistory(container);
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('rpc-console-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,475 @@
+// RPC Console Plugin for UnrealIRCd Web Panel
+// An explorer for the UnrealIRCd JSON-RPC API: lists the methods with their
+// parameters, runs calls for panel admins and keeps a history of them
+
+package rpcconsole
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/apidocs"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// maxStoredResult is the largest result kept in the history. Larger
+// results are shown once and only their size is kept.
+const maxStoredResult = 64 << 10
+
+// RPCConsolePlugin implements the Plugin interface
+type RPCConsolePlugin struct {
+	config  Config
+	rpc     *jsonrpc.Client
+	history []*Call
+	mu      sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	AllowedMethods string `json:"allowed_methods"`
+	ReadOnly       bool   `json:"read_only"`
+	HistorySize    int    `json:"history_size"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// Call is an executed JSON-RPC call
+type Call struct {
+	ID         string          `json:"id"`
+	Time       time.Time       `json:"time"`
+	Actor      string          `json:"actor"`
+	Method     string          `json:"method"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Success    bool            `json:"success"`
+	Error      string          `json:"error,omitempty"`
+	ErrorCode  int             `json:"error_code,omitempty"`
+	DurationMS int64           `json:"duration_ms"`
+	ResultSize int             `json:"result_size"`
+	Truncated  bool            `json:"truncated,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &RPCConsolePlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/rpc-console",
+			AllowedMethods: "*",
+			HistorySize:    200,
+			TimeoutSeconds: 15,
+		},
+		history: make([]*Call, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *RPCConsolePlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "RPC Console",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Interactive JSON-RPC explorer with a request builder and call history",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *RPCConsolePlugin) Init() error {
+	apidocs.Describe("rpc-console", "JSON-RPC method list and calls with a history",
+		apidocs.Operation{Method: "GET", Path: "/methods", Summary: "The JSON-RPC methods with their parameters and availability (admins)"},
+		apidocs.Operation{
+			Method: "POST", Path: "/call", Summary: "Call a JSON-RPC method (admins)",
+			Description: "The call is recorded in the history. Failed calls are returned with success false rather than an error status.",
+			Request: struct {
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params,omitempty" doc:"Parameters as a JSON object"`
+			}{},
+			Response: Call{},
+		},
+		apidocs.Operation{
+			Method: "GET", Path: "/history", Summary: "Executed calls without their results, newest first (admins)",
+			Query: []apidocs.Param{
+				{Name: "method", Description: "Only calls of this method"},
+				{Name: "user", Description: "Only calls by this panel user"},
+			},
+			Response: struct {
+				Calls []Call `json:"calls"`
+			}{},
+		},
+		apidocs.Operation{Method: "GET", Path: "/history/:id", Summary: "One call with its result (admins)", Response: Call{}},
+		apidocs.Operation{Method: "DELETE", Path: "/history", Summary: "Clear the history (admins)"},
+		apidocs.Operation{Method: "GET", Path: "/config", Summary: "Get current configuration", Response: Config{}},
+		apidocs.Operation{Method: "PUT", Path: "/config", Summary: "Update configuration", Request: Config{}},
+	)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var history []*Call
+	if err := storage.LoadJSON(p.historyPath(), &history); err != nil {
+		log.Printf("[rpc-console] failed to load history: %v", err)
+	}
+	if history != nil {
+		p.history = history
+	}
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *RPCConsolePlugin) Shutdown() error {
+	apidocs.Forget("rpc-console")
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *RPCConsolePlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/rpc-console")
+	{
+		plugin.GET("/methods", p.handleMethods)
+		plugin.POST("/call", p.handleCall)
+		plugin.GET("/history", p.handleHistory)
+		plugin.GET("/history/:id", p.handleGetCall)
+		plugin.DELETE("/history", p.handleClearHistory)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// historyPath returns the file the call history is kept in
+func (p *RPCConsolePlugin) historyPath() string {
+	return filepath.Join(p.config.DataDir, "history.json")
+}
+
+// save writes the call history to disk
+func (p *RPCConsolePlugin) save() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return storage.SaveJSON(p.historyPath(), p.history)
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *RPCConsolePlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// permitted reports why a method may not be called, or "" if it may.
+// Caller must hold p.mu.
+func (p *RPCConsolePlugin) permitted(method string) string {
+	allowed := false
+	for _, mask := range lists.Split(p.config.AllowedMethods) {
+		if ircname.MatchMask(mask, method) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "Method " + method + " is not in allowed_methods"
+	}
+	if p.config.ReadOnly {
+		// Methods missing from the catalog may change anything
+		if m := findMethod(method); m == nil || !m.ReadOnly {
+			return "The console is read-only and " + method + " can make changes"
+		}
+	}
+	return ""
+}
+
+// handleMethods lists the methods with their parameters, marking the ones
+// the IRCd supports and the ones the console may call
+func (p *RPCConsolePlugin) handleMethods(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	var info struct {
+		Methods map[string]rpcMethodInfo `json:"methods"`
+	}
+	infoErr := ""
+	if err := p.client().Call(ctx, "rpc.info", nil, &info); err != nil {
+		infoErr = err.Error()
+		info.Methods = nil
+	} else if info.Methods == nil {
+		info.Methods = make(map[string]rpcMethodInfo)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	methods := mergeMethods(info.Methods)
+	list := make([]gin.H, 0, len(methods))
+	for _, m := range methods {
+		list = append(list, gin.H{
+			"name":        m.Name,
+			"group":       m.Group,
+			"description": m.Description,
+			"read_only":   m.ReadOnly,
+			"params":      m.Params,
+			"available":   m.Available,
+			"module":      m.Module,
+			"version":     m.Version,
+			"blocked":     p.permitted(m.Name),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"methods": list, "info_error": infoErr, "read_only": p.config.ReadOnly})
+}
+
+// handleCall runs a JSON-RPC call and records it in the history. The
+// response holds the call, with the full result even if it is too large
+// to keep.
+func (p *RPCConsolePlugin) handleCall(c *gin.Context) {
+	actor, ok := access.RequireAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.Method = strings.TrimSpace(req.Method)
+	if req.Method == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "method is required"})
+		return
+	}
+
+	var params map[string]json.RawMessage
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "params must be a JSON object"})
+			return
+		}
+	}
+	if m := findMethod(req.Method); m != nil {
+		if err := checkParams(m, params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	p.mu.RLock()
+	reason := p.permitted(req.Method)
+	timeout := time.Duration(p.config.TimeoutSeconds) * time.Second
+	p.mu.RUnlock()
+	if reason != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	var callParams interface{}
+	if params != nil {
+		callParams = params
+	}
+	call := &Call{ID: newID(), Time: time.Now().UTC(), Actor: actor, Method: req.Method}
+	if params != nil {
+		call.Params, _ = json.Marshal(params)
+	}
+	var result json.RawMessage
+	start := time.Now()
+	err := p.client().Call(ctx, req.Method, callParams, &result)
+	call.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		call.Error = err.Error()
+		var rpcErr *jsonrpc.Error
+		if errors.As(err, &rpcErr) {
+			call.Error = rpcErr.Message
+			call.ErrorCode = rpcErr.Code
+		}
+	} else {
+		call.Success = true
+		call.ResultSize = len(result)
+	}
+	log.Printf("[rpc-console] %s called %s", actor, req.Method)
+
+	stored := *call
+	if len(result) <= maxStoredResult {
+		stored.Result = result
+	} else {
+		stored.Truncated = true
+	}
+	p.mu.Lock()
+	p.history = append(p.history, &stored)
+	if len(p.history) > p.config.HistorySize {
+		p.history = p.history[len(p.history)-p.config.HistorySize:]
+	}
+	p.mu.Unlock()
+	if err := p.save(); err != nil {
+		log.Printf("[rpc-console] failed to save history: %v", err)
+	}
+
+	call.Result = result
+	c.JSON(http.StatusOK, call)
+}
+
+// handleHistory lists the executed calls, newest first, without their
+// results. ?method= and ?user= filter the list.
+func (p *RPCConsolePlugin) handleHistory(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+	method, user := c.Query("method"), c.Query("user")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Call, 0, len(p.history))
+	for i := len(p.history) - 1; i >= 0; i-- {
+		call := *p.history[i]
+		if (method != "" && call.Method != method) || (user != "" && !strings.EqualFold(call.Actor, user)) {
+			continue
+		}
+		call.Result = nil
+		list = append(list, call)
+	}
+	c.JSON(http.StatusOK, gin.H{"calls": list})
+}
+
+// handleGetCall returns one executed call with its result
+func (p *RPCConsolePlugin) handleGetCall(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, call := range p.history {
+		if call.ID == c.Param("id") {
+			c.JSON(http.StatusOK, call)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Call not found"})
+}
+
+// handleClearHistory removes every call from the history
+func (p *RPCConsolePlugin) handleClearHistory(c *gin.Context) {
+	actor, ok := access.RequireAdmin(c)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	p.history = make([]*Call, 0)
+	p.mu.Unlock()
+	if err := p.save(); err != nil {
+		log.Printf("[rpc-console] failed to save history: %v", err)
+	}
+	log.Printf("[rpc-console] %s cleared the history", actor)
+	c.JSON(http.StatusOK, gin.H{"message": "History cleared"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *RPCConsolePlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration. Only plugin admins may
+// change it, since allowed_methods decides which calls can be run.
+func (p *RPCConsolePlugin) handleUpdateConfig(c *gin.Context) {
+	admin, ok := access.RequireAdmin(c)
+	if !ok {
+		return
+	}
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.HistorySize < 10 || newConfig.HistorySize > 5000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "history_size must be between 10 and 5000"})
+		return
+	}
+	if newConfig.TimeoutSeconds < 1 || newConfig.TimeoutSeconds > 120 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeout_seconds must be between 1 and 120"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if len(p.history) > newConfig.HistorySize {
+		p.history = p.history[len(p.history)-newConfig.HistorySize:]
+	}
+	p.mu.Unlock()
+
+	log.Printf("[rpc-console] %s updated the configuration", admin)
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *RPCConsolePlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *RPCConsolePlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
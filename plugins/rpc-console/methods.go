@@ -0,0 +1,338 @@
+package rpcconsole
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Param describes a parameter of a JSON-RPC method
+type Param struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required,omitempty"`
+	Description string   `json:"description"`
+	Enum        []string `json:"enum,omitempty"`
+}
+
+// Method describes a JSON-RPC method. Params is nil for methods the IRCd
+// reports that are not in the catalog, whose parameters are unknown.
+type Method struct {
+	Name        string  `json:"name"`
+	Group       string  `json:"group"`
+	Description string  `json:"description"`
+	ReadOnly    bool    `json:"read_only"`
+	Params      []Param `json:"params"`
+	Available   bool    `json:"available"`
+	Module      string  `json:"module,omitempty"`
+	Version     string  `json:"version,omitempty"`
+}
+
+// Parameters shared by many methods
+var (
+	detailParam = Param{Name: "object_detail_level", Type: "integer", Description: "How much detail to return, 0-4 (higher is more)"}
+	setByParam  = Param{Name: "set_by", Type: "string", Description: "Name shown as the setter, defaults to the RPC user"}
+	durParam    = Param{Name: "duration_string", Type: "string", Description: "Duration such as 1d or 2h30m; 0 is permanent"}
+	expireParam = Param{Name: "expire_at", Type: "string", Description: "Expiry as an ISO 8601 time, instead of duration_string"}
+	reasonParam = Param{Name: "reason", Type: "string", Required: true, Description: "Reason"}
+	nickParam   = Param{Name: "nick", Type: "string", Required: true, Description: "Nick or UID of the user"}
+	chanParam   = Param{Name: "channel", Type: "string", Required: true, Description: "Channel name"}
+	serverParam = Param{Name: "server", Type: "string", Description: "Server to ask, defaults to the server the panel talks to"}
+	banTypes    = []string{"gline", "kline", "gzline", "zline", "shun", "spamfilter", "qline", "local-qline", "except"}
+	sfTargets   = Param{Name: "spamfilter_targets", Type: "string", Required: true, Description: "Targets such as cpnN"}
+	sfMatch     = Param{Name: "match_type", Type: "string", Required: true, Description: "How name is matched", Enum: []string{"simple", "regex"}}
+	sfAction    = Param{Name: "ban_action", Type: "string", Required: true, Description: "Action on a match, such as block or gline"}
+)
+
+// catalog lists the methods of the UnrealIRCd JSON-RPC API, with their
+// parameters, in the order they are shown
+var catalog = []Method{
+	{Name: "rpc.info", Group: "rpc", ReadOnly: true, Description: "List the RPC methods the server supports"},
+	{Name: "rpc.set_issuer", Group: "rpc", Description: "Set the name actions of this connection are logged under", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Issuer name"},
+	}},
+	{Name: "rpc.add_timer", Group: "rpc", Description: "Run a request on an interval", Params: []Param{
+		{Name: "timer_id", Type: "string", Required: true, Description: "Name of the timer"},
+		{Name: "every_msec", Type: "integer", Required: true, Description: "Interval in milliseconds"},
+		{Name: "request", Type: "object", Required: true, Description: "JSON-RPC request to run"},
+	}},
+	{Name: "rpc.del_timer", Group: "rpc", Description: "Remove a timer", Params: []Param{
+		{Name: "timer_id", Type: "string", Required: true, Description: "Name of the timer"},
+	}},
+
+	{Name: "stats.get", Group: "stats", ReadOnly: true, Description: "Network statistics", Params: []Param{detailParam}},
+
+	{Name: "log.list", Group: "log", ReadOnly: true, Description: "Recent log lines kept in memory", Params: []Param{
+		{Name: "sources", Type: "array", Description: "Log sources to return, such as all or !debug"},
+	}},
+
+	{Name: "user.list", Group: "user", ReadOnly: true, Description: "All users on the network", Params: []Param{detailParam}},
+	{Name: "user.get", Group: "user", ReadOnly: true, Description: "One user", Params: []Param{nickParam, detailParam}},
+	{Name: "user.set_nick", Group: "user", Description: "Change a user's nick", Params: []Param{
+		nickParam,
+		{Name: "newnick", Type: "string", Required: true, Description: "New nick"},
+		{Name: "force", Type: "boolean", Description: "Skip Q-line and flood checks"},
+	}},
+	{Name: "user.set_username", Group: "user", Description: "Change a user's username", Params: []Param{
+		nickParam, {Name: "username", Type: "string", Required: true, Description: "New username"},
+	}},
+	{Name: "user.set_realname", Group: "user", Description: "Change a user's real name", Params: []Param{
+		nickParam, {Name: "realname", Type: "string", Required: true, Description: "New real name"},
+	}},
+	{Name: "user.set_vhost", Group: "user", Description: "Set a user's virtual host", Params: []Param{
+		nickParam, {Name: "vhost", Type: "string", Required: true, Description: "New host"},
+	}},
+	{Name: "user.set_mode", Group: "user", Description: "Change a user's modes", Params: []Param{
+		nickParam,
+		{Name: "modes", Type: "string", Required: true, Description: "Mode change such as +x-w"},
+		{Name: "hidden", Type: "boolean", Description: "Don't tell the user"},
+	}},
+	{Name: "user.set_snomask", Group: "user", Description: "Change a user's server notice mask", Params: []Param{
+		nickParam,
+		{Name: "snomask", Type: "string", Required: true, Description: "Snomask change such as +bc"},
+		{Name: "hidden", Type: "boolean", Description: "Don't tell the user"},
+	}},
+	{Name: "user.set_oper", Group: "user", Description: "Make a user an IRC operator", Params: []Param{
+		nickParam,
+		{Name: "oper_account", Type: "string", Required: true, Description: "Oper account name"},
+		{Name: "oper_class", Type: "string", Required: true, Description: "Operclass"},
+		{Name: "class", Type: "string", Description: "Connection class"},
+		{Name: "modes", Type: "string", Description: "User modes to set"},
+		{Name: "snomask", Type: "string", Description: "Snomask to set"},
+		{Name: "vhost", Type: "string", Description: "Virtual host to set"},
+	}},
+	{Name: "user.join", Group: "user", Description: "Make a user join a channel", Params: []Param{
+		nickParam, chanParam,
+		{Name: "key", Type: "string", Description: "Channel key"},
+		{Name: "force", Type: "boolean", Description: "Bypass bans, limits and keys"},
+	}},
+	{Name: "user.part", Group: "user", Description: "Make a user leave a channel", Params: []Param{
+		nickParam, chanParam,
+		{Name: "force", Type: "boolean", Description: "Part even if the user can't normally"},
+	}},
+	{Name: "user.quit", Group: "user", Description: "Disconnect a user as if they quit", Params: []Param{nickParam, reasonParam}},
+	{Name: "user.kill", Group: "user", Description: "Kill a user", Params: []Param{nickParam, reasonParam}},
+
+	{Name: "whowas.get", Group: "whowas", ReadOnly: true, Description: "WHOWAS history", Params: []Param{
+		{Name: "nick", Type: "string", Description: "Nick to look up"},
+		{Name: "ip", Type: "string", Description: "IP to look up"},
+		detailParam,
+	}},
+
+	{Name: "channel.list", Group: "channel", ReadOnly: true, Description: "All channels", Params: []Param{detailParam}},
+	{Name: "channel.get", Group: "channel", ReadOnly: true, Description: "One channel", Params: []Param{chanParam, detailParam}},
+	{Name: "channel.set_mode", Group: "channel", Description: "Change channel modes", Params: []Param{
+		chanParam,
+		{Name: "modes", Type: "string", Required: true, Description: "Mode change such as +nt-s"},
+		{Name: "parameters", Type: "string", Description: "Mode parameters, space separated"},
+	}},
+	{Name: "channel.set_topic", Group: "channel", Description: "Set the topic", Params: []Param{
+		chanParam,
+		{Name: "topic", Type: "string", Required: true, Description: "New topic"},
+		setByParam,
+		{Name: "set_at", Type: "string", Description: "Time the topic was set, ISO 8601"},
+	}},
+	{Name: "channel.kick", Group: "channel", Description: "Kick a user from a channel", Params: []Param{
+		chanParam, nickParam, reasonParam,
+	}},
+
+	{Name: "server.list", Group: "server", ReadOnly: true, Description: "All linked servers"},
+	{Name: "server.get", Group: "server", ReadOnly: true, Description: "One server", Params: []Param{serverParam}},
+	{Name: "server.module_list", Group: "server", ReadOnly: true, Description: "Modules loaded on a server", Params: []Param{serverParam}},
+	{Name: "server.rehash", Group: "server", Description: "Rehash a server", Params: []Param{serverParam}},
+	{Name: "server.connect", Group: "server", Description: "Link to a server from a link block", Params: []Param{
+		{Name: "link", Type: "string", Required: true, Description: "Name of the link block"},
+	}},
+	{Name: "server.disconnect", Group: "server", Description: "Delink a server", Params: []Param{
+		{Name: "link", Type: "string", Required: true, Description: "Server to delink"},
+		reasonParam,
+	}},
+
+	{Name: "server_ban.list", Group: "server_ban", ReadOnly: true, Description: "Server bans (K/G/Z/GZ-lines, shuns, Q-lines)", Params: []Param{serverParam}},
+	{Name: "server_ban.get", Group: "server_ban", ReadOnly: true, Description: "One server ban", Params: []Param{
+		{Name: "type", Type: "string", Required: true, Description: "Ban type", Enum: banTypes},
+		{Name: "name", Type: "string", Required: true, Description: "Mask such as *@203.0.113.7"},
+	}},
+	{Name: "server_ban.add", Group: "server_ban", Description: "Add a server ban", Params: []Param{
+		{Name: "type", Type: "string", Required: true, Description: "Ban type", Enum: banTypes},
+		{Name: "name", Type: "string", Required: true, Description: "Mask such as *@203.0.113.7"},
+		reasonParam, setByParam, durParam, expireParam,
+	}},
+	{Name: "server_ban.del", Group: "server_ban", Description: "Remove a server ban", Params: []Param{
+		{Name: "type", Type: "string", Required: true, Description: "Ban type", Enum: banTypes},
+		{Name: "name", Type: "string", Required: true, Description: "Mask of the ban"},
+		setByParam,
+	}},
+
+	{Name: "server_ban_exception.list", Group: "server_ban_exception", ReadOnly: true, Description: "Ban exceptions", Params: []Param{serverParam}},
+	{Name: "server_ban_exception.get", Group: "server_ban_exception", ReadOnly: true, Description: "One ban exception", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Mask of the exception"},
+	}},
+	{Name: "server_ban_exception.add", Group: "server_ban_exception", Description: "Add a ban exception", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Mask such as *@198.51.100.0/24"},
+		{Name: "exception_types", Type: "string", Required: true, Description: "Ban types exempted, as letters such as kGzZ"},
+		reasonParam, setByParam, durParam, expireParam,
+	}},
+	{Name: "server_ban_exception.del", Group: "server_ban_exception", Description: "Remove a ban exception", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Mask of the exception"},
+		setByParam,
+	}},
+
+	{Name: "name_ban.list", Group: "name_ban", ReadOnly: true, Description: "Nick and channel name bans (Q-lines)", Params: []Param{serverParam}},
+	{Name: "name_ban.get", Group: "name_ban", ReadOnly: true, Description: "One name ban", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Banned nick or channel mask"},
+	}},
+	{Name: "name_ban.add", Group: "name_ban", Description: "Ban a nick or channel name", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Nick or channel mask"},
+		reasonParam, setByParam, durParam, expireParam,
+	}},
+	{Name: "name_ban.del", Group: "name_ban", Description: "Remove a name ban", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Nick or channel mask"},
+		setByParam,
+	}},
+
+	{Name: "spamfilter.list", Group: "spamfilter", ReadOnly: true, Description: "All spamfilters", Params: []Param{serverParam}},
+	{Name: "spamfilter.get", Group: "spamfilter", ReadOnly: true, Description: "One spamfilter", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Match string"},
+		sfMatch, sfTargets, sfAction,
+	}},
+	{Name: "spamfilter.add", Group: "spamfilter", Description: "Add a spamfilter", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Match string"},
+		sfMatch, sfTargets, sfAction,
+		{Name: "ban_duration", Type: "string", Description: "Duration of bans the filter sets"},
+		reasonParam, setByParam,
+	}},
+	{Name: "spamfilter.del", Group: "spamfilter", Description: "Remove a spamfilter", Params: []Param{
+		{Name: "name", Type: "string", Required: true, Description: "Match string"},
+		sfMatch, sfTargets, sfAction, setByParam,
+	}},
+
+	{Name: "message.send_privmsg", Group: "message", Description: "Send a private message to a user", Params: []Param{
+		nickParam, {Name: "message", Type: "string", Required: true, Description: "Message text"},
+	}},
+	{Name: "message.send_notice", Group: "message", Description: "Send a notice to a user", Params: []Param{
+		nickParam, {Name: "message", Type: "string", Required: true, Description: "Message text"},
+	}},
+	{Name: "message.send_numeric", Group: "message", Description: "Send a numeric reply to a user", Params: []Param{
+		nickParam,
+		{Name: "numeric", Type: "integer", Required: true, Description: "Numeric, 1-999"},
+		{Name: "message", Type: "string", Required: true, Description: "Text after the numeric"},
+	}},
+	{Name: "message.send_standard_reply", Group: "message", Description: "Send a FAIL, WARN or NOTE standard reply", Params: []Param{
+		nickParam,
+		{Name: "type", Type: "string", Required: true, Description: "Reply type", Enum: []string{"FAIL", "WARN", "NOTE"}},
+		{Name: "code", Type: "string", Required: true, Description: "Machine readable code"},
+		{Name: "context", Type: "string", Description: "Context of the reply"},
+		{Name: "description", Type: "string", Required: true, Description: "Human readable text"},
+	}},
+}
+
+// findMethod returns the catalog entry of a method, or nil
+func findMethod(name string) *Method {
+	for i := range catalog {
+		if catalog[i].Name == name {
+			return &catalog[i]
+		}
+	}
+	return nil
+}
+
+// rpcMethodInfo is an entry of the rpc.info result
+type rpcMethodInfo struct {
+	Name    string `json:"name"`
+	Module  string `json:"module"`
+	Version string `json:"version"`
+}
+
+// mergeMethods combines the catalog with the methods the IRCd reports.
+// Without a report every catalog method is assumed available.
+func mergeMethods(info map[string]rpcMethodInfo) []Method {
+	list := make([]Method, 0, len(catalog)+len(info))
+	for _, m := range catalog {
+		m.Available = info == nil
+		if mi, ok := info[m.Name]; ok {
+			m.Available = true
+			m.Module, m.Version = mi.Module, mi.Version
+		}
+		list = append(list, m)
+	}
+	extra := make([]Method, 0)
+	for name, mi := range info {
+		if findMethod(name) != nil {
+			continue
+		}
+		group := name
+		if i := strings.Index(name, "."); i > 0 {
+			group = name[:i]
+		}
+		extra = append(extra, Method{
+			Name:      name,
+			Group:     group,
+			Available: true,
+			Module:    mi.Module,
+			Version:   mi.Version,
+		})
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Name < extra[j].Name })
+	return append(list, extra...)
+}
+
+// checkParams checks params against a method's parameters: params must be
+// a JSON object with every required parameter, each of the right type.
+// Parameters the catalog doesn't know are passed through, as newer IRCd
+// versions may add them.
+func checkParams(m *Method, params map[string]json.RawMessage) error {
+	for _, p := range m.Params {
+		raw, ok := params[p.Name]
+		if !ok || string(raw) == "null" {
+			if p.Required {
+				return fmt.Errorf("%s is required", p.Name)
+			}
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return fmt.Errorf("%s is not valid JSON", p.Name)
+		}
+		if !hasType(v, p.Type) {
+			return fmt.Errorf("%s must be of type %s", p.Name, p.Type)
+		}
+		if s, ok := v.(string); ok && len(p.Enum) > 0 && !containsString(p.Enum, s) {
+			return fmt.Errorf("%s must be one of %s", p.Name, strings.Join(p.Enum, ", "))
+		}
+	}
+	return nil
+}
+
+// hasType reports whether a decoded JSON value is of the schema type
+func hasType(v interface{}, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package saslwatch
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// asInfo is the autonomous system an address is announced from
+type asInfo struct {
+	ASN    string `json:"asn"`
+	ASName string `json:"asname"`
+}
+
+// asnResolver looks up addresses through the Team Cymru IP to ASN DNS
+// service and caches the answers
+type asnResolver struct {
+	mu    sync.Mutex
+	cache map[string]asnEntry
+	names map[string]string
+}
+
+// asnEntry is a cached lookup, including failed ones so that they are not
+// retried on every attempt
+type asnEntry struct {
+	info    asInfo
+	expires time.Time
+}
+
+// asnCacheTTL is how long a lookup is cached
+const asnCacheTTL = 24 * time.Hour
+
+// newASNResolver creates a resolver with an empty cache
+func newASNResolver() *asnResolver {
+	return &asnResolver{
+		cache: make(map[string]asnEntry),
+		names: make(map[string]string),
+	}
+}
+
+// Lookup returns the AS of ip, or an empty asInfo when it is unknown
+func (r *asnResolver) Lookup(ctx context.Context, ip string) asInfo {
+	r.mu.Lock()
+	if e, ok := r.cache[ip]; ok && time.Now().Before(e.expires) {
+		r.mu.Unlock()
+		return e.info
+	}
+	r.mu.Unlock()
+
+	info, err := r.lookup(ctx, ip)
+	if err != nil {
+		info = asInfo{}
+	}
+
+	r.mu.Lock()
+	r.cache[ip] = asnEntry{info: info, expires: time.Now().Add(asnCacheTTL)}
+	if len(r.cache) > 50000 {
+		for k, e := range r.cache {
+			if time.Now().After(e.expires) {
+				delete(r.cache, k)
+			}
+		}
+	}
+	r.mu.Unlock()
+	return info
+}
+
+// lookup queries the origin of ip and then the name of its AS. Answers
+// look like "15169 | 8.8.8.0/24 | US | arin | 2023-12-28" and
+// "15169 | US | arin | 2000-03-30 | GOOGLE - Google LLC, US".
+func (r *asnResolver) lookup(ctx context.Context, ip string) (asInfo, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return asInfo{}, err
+	}
+	query, err := originQuery(ip)
+	if err != nil {
+		return asInfo{}, err
+	}
+	txt, err := net.DefaultResolver.LookupTXT(ctx, query)
+	if err != nil || len(txt) == 0 {
+		return asInfo{}, fmt.Errorf("no origin for %s", ip)
+	}
+	// Multi-origin prefixes list several ASNs separated by spaces
+	asn := strings.Fields(strings.SplitN(txt[0], "|", 2)[0])
+	if len(asn) == 0 {
+		return asInfo{}, fmt.Errorf("no origin for %s", ip)
+	}
+	info := asInfo{ASN: asn[0]}
+
+	r.mu.Lock()
+	name, ok := r.names[info.ASN]
+	r.mu.Unlock()
+	if !ok {
+		if txt, err := net.DefaultResolver.LookupTXT(ctx, "AS"+info.ASN+".asn.cymru.com"); err == nil && len(txt) > 0 {
+			if fields := strings.Split(txt[0], "|"); len(fields) >= 5 {
+				name = strings.TrimSpace(fields[4])
+			}
+		}
+		r.mu.Lock()
+		r.names[info.ASN] = name
+		r.mu.Unlock()
+	}
+	info.ASName = name
+	return info, nil
+}
+
+// originQuery returns the DNS name to query for the origin of ip
+func originQuery(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", v4[3], v4[2], v4[1], v4[0]), nil
+	}
+	nibbles := make([]string, 0, 32)
+	for i := len(addr) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, fmt.Sprintf("%x.%x", addr[i]&0x0f, addr[i]>>4))
+	}
+	return strings.Join(nibbles, ".") + ".origin6.asn.cymru.com", nil
+}
@@ -0,0 +1,333 @@
+/**
+ * SASL Watch Frontend Script
+ *
+ * Ranks IPs and networks by failed SASL and NickServ logins, lists the
+ * accounts being guessed and bans attackers in one click.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'sasl-watch';
+  const PLUGIN_NAME = 'SASL Watch';
+  const PAGE_PATH = '/plugins/sasl-watch';
+  const API_BASE = '/api/plugin/sasl-watch';
+
+  let hours = 24;
+  let group = 'ip';
+  let allowBan = false;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('sasl-watch-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'sasl-watch-styles';
+    style.textContent = `
+      .saslw-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .saslw-toolbar { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .saslw-toolbar .saslw-spacer { flex: 1; }
+      .saslw-table { width: 100%; border-collapse: collapse; }
+      .saslw-table th, .saslw-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        overflow-wrap: anywhere;
+      }
+      .saslw-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .saslw-app button.active { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); }
+      .saslw-app button.saslw-ban { background: var(--error, #f38ba8); color: var(--bg-primary, #11111b); }
+      .saslw-meta { font-size: 0.75rem; color: var(--text-muted, #6c7086); }
+      .saslw-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#saslw-status');
+    try {
+      const s = await api('GET', '/status');
+      allowBan = s.allow_ban;
+      body.innerHTML = `
+        ${!s.services_log_file ? '<div class="saslw-error">No services log file is configured.</div>' : ''}
+        ${s.services_log_error ? `<div class="saslw-error">Could not read ${escapeHtml(s.services_log_file)}: ${escapeHtml(s.services_log_error)}</div>` : ''}
+        <div class="saslw-meta">
+          ${allowBan ? `Bans are placed as ${escapeHtml(s.ban_type)} for ${escapeHtml(s.ban_duration)}.` : 'Banning is disabled in the plugin settings.'}
+        </div>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="saslw-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadAttackers(container) {
+    const body = container.querySelector('#saslw-attackers');
+    try {
+      const data = await api('GET', `/attackers?hours=${hours}&group=${group}`);
+      if (data.attackers.length === 0) {
+        body.innerHTML = '<p>No failed logins in this period.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="saslw-table">
+          <thead><tr>
+            <th>${group === 'asn' ? 'Network' : 'IP'}</th>
+            ${group === 'ip' ? '<th>Network</th>' : '<th>IPs</th>'}
+            <th>Failures</th><th>Accounts</th><th>Last</th><th></th>
+          </tr></thead>
+          <tbody>
+            ${data.attackers.slice(0, 200).map(a => {
+              const network = a.asn ? `AS${escapeHtml(a.asn)} ${escapeHtml(a.asname)}` : '';
+              const done = a.banned >= a.ips.length;
+              return `
+                <tr>
+                  <td>${group === 'asn' ? network : `<code>${escapeHtml(a.key)}</code>`}</td>
+                  <td>${group === 'asn' ? `${a.ips.length}${a.banned ? ` (${a.banned} banned)` : ''}` : network}</td>
+                  <td>${a.failures}</td>
+                  <td>${escapeHtml(a.accounts.join(', '))}</td>
+                  <td>${formatTime(a.last)}</td>
+                  <td>${allowBan ? (done ? 'banned' : `<button class="saslw-ban" data-key="${escapeHtml(a.key)}">${group === 'asn' ? 'Ban all' : 'Ban'}</button>`) : ''}</td>
+                </tr>
+              `;
+            }).join('')}
+          </tbody>
+        </table>
+      `;
+      body.querySelectorAll('[data-key]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          const key = btn.dataset.key;
+          const label = group === 'asn' ? `every attacking IP of AS${key}` : key;
+          if (!confirm(`Ban ${label}?`)) return;
+          try {
+            const res = await api('POST', '/bans', group === 'asn' ? { asn: key, hours } : { ip: key });
+            alert(res.message);
+            loadAttackers(container);
+            loadBans(container);
+          } catch (err) {
+            alert(err.message);
+          }
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="saslw-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadAccounts(container) {
+    const body = container.querySelector('#saslw-accounts');
+    try {
+      const data = await api('GET', `/accounts?hours=${hours}`);
+      if (data.accounts.length === 0) {
+        body.innerHTML = '<p>No accounts targeted in this period.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="saslw-table">
+          <thead><tr><th>Account</th><th>Failures</th><th>From IPs</th><th>Last</th></tr></thead>
+          <tbody>
+            ${data.accounts.slice(0, 50).map(a => `
+              <tr><td>${escapeHtml(a.account)}</td><td>${a.failures}</td><td>${a.ips}</td><td>${formatTime(a.last)}</td></tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="saslw-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadMechanisms(container) {
+    const body = container.querySelector('#saslw-mechanisms');
+    try {
+      const data = await api('GET', '/mechanisms?days=30');
+      const total = data.total;
+      const mechs = Array.from(new Set([...Object.keys(total.success), ...Object.keys(total.failure)]))
+        .sort((a, b) => (total.success[b] || 0) - (total.success[a] || 0));
+      if (mechs.length === 0) {
+        body.innerHTML = '<p>No SASL mechanisms seen in the last 30 days.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="saslw-table">
+          <thead><tr><th>Mechanism</th><th>Logins</th><th>Share</th><th>Failures</th></tr></thead>
+          <tbody>
+            ${mechs.map(m => `
+              <tr><td>${escapeHtml(m)}</td><td>${total.success[m] || 0}</td><td>${total.share_percent[m] || 0}%</td><td>${total.failure[m] || 0}</td></tr>
+            `).join('')}
+          </tbody>
+        </table>
+        <div class="saslw-meta">
+          EXTERNAL share by day:
+          ${data.days.map(d => `${escapeHtml(d.date)} ${d.share_percent.EXTERNAL || 0}%`).join(' &middot; ')}
+        </div>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="saslw-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadBans(container) {
+    const body = container.querySelector('#saslw-bans');
+    try {
+      const data = await api('GET', '/bans');
+      if (data.bans.length === 0) {
+        body.innerHTML = '<p>No bans placed from this page yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="saslw-table">
+          <thead><tr><th>Time</th><th>IP</th><th>Ban</th><th>By</th><th>Result</th></tr></thead>
+          <tbody>
+            ${data.bans.slice(0, 100).map(b => `
+              <tr>
+                <td>${formatTime(b.time)}</td>
+                <td><code>${escapeHtml(b.ip)}</code>${b.asn ? ` <span class="saslw-meta">AS${escapeHtml(b.asn)}</span>` : ''}</td>
+                <td>${escapeHtml(b.type)} ${escapeHtml(b.duration)}</td>
+                <td>${escapeHtml(b.actor)}</td>
+                <td>${b.error ? `<span class="saslw-error">${escapeHtml(b.error)}</span>` : 'placed'}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="saslw-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadAll(container) {
+    await loadStatus(container);
+    loadAttackers(container);
+    loadAccounts(container);
+    loadMechanisms(container);
+    loadBans(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="saslw-app" data-plugin="${PLUGIN_ID}">
+        <div id="saslw-status"></div>
+        <div class="saslw-toolbar">
+          <button data-group="ip" class="active">By IP</button>
+          <button data-group="asn">By network</button>
+          <span class="saslw-spacer"></span>
+          <button data-hours="1">1h</button>
+          <button data-hours="24" class="active">24h</button>
+          <button data-hours="168">7d</button>
+        </div>
+        <h3>Attackers</h3>
+        <div id="saslw-attackers">Loading...</div>
+        <h3>Targeted accounts</h3>
+        <div id="saslw-accounts">Loading...</div>
+        <h3>SASL mechanisms (30 days)</h3>
+        <div id="saslw-mechanisms">Loading...</div>
+        <h3>Bans</h3>
+        <div id="saslw-bans">Loading...</div>
+      </div>
+    `;
+
+    container.querySelectorAll('[data-group]').forEach(btn => {
+      btn.addEventListener('click', () => {
+        group = btn.dataset.group;
+        container.querySelectorAll('[data-group]').forEach(b => b.classList.toggle('active', b === btn));
+        loadAttackers(container);
+      });
+    });
+    container.querySelectorAll('[data-hours]').forEach(btn => {
+      btn.addEventListener('click', () => {
+        hours = parseInt(btn.dataset.hours, 10);
+        container.querySelectorAll('[data-hours]').forEach(b => b.classList.toggle('active', b === btn));
+        loadAttackers(container);
+        loadAccounts(container);
+      });
+    });
+
+    loadAll(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('sasl-watch-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,858 @@
+// SASL Watch Plugin for UnrealIRCd Web Panel
+// Watches failed SASL and NickServ authentication attempts network-wide,
+// ranks attacking IPs and networks and bans them in one click, and breaks
+// SASL logins down by mechanism
+
+package saslwatch
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// maxBulkBans caps the number of IPs banned at once for one AS
+const maxBulkBans = 256
+
+// SASLWatchPlugin implements the Plugin interface
+type SASLWatchPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	asn       *asnResolver
+	attempts  []*Attempt
+	bans      []*Ban
+	mechDays  map[string]*MechanismDay
+	ipAlerts  map[string]time.Time
+	asnAlerts map[string]time.Time
+	dirty     bool
+	tailError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL          string `json:"rpc_url"`
+	RPCUser         string `json:"rpc_user"`
+	RPCPassword     string `json:"rpc_password"`
+	RPCInsecure     bool   `json:"rpc_insecure"`
+	ServicesLogFile string `json:"services_log_file"`
+	FailurePattern  string `json:"failure_pattern"`
+	MechPattern     string `json:"mechanism_pattern"`
+	MechDays        int    `json:"mechanism_days"`
+	DataDir         string `json:"data_dir"`
+	ASNLookup       bool   `json:"asn_lookup"`
+	BurstThreshold  int    `json:"burst_threshold"`
+	BurstWindow     int    `json:"burst_window"`
+	MaxAttempts     int    `json:"max_attempts"`
+	AllowBan        bool   `json:"allow_ban"`
+	BanType         string `json:"ban_type"`
+	BanDuration     string `json:"ban_duration"`
+	BanReason       string `json:"ban_reason"`
+	AlertWebhook    string `json:"alert_webhook"`
+}
+
+// Attempt is a single failed authentication
+type Attempt struct {
+	Time    time.Time `json:"time"`
+	IP      string    `json:"ip"`
+	Nick    string    `json:"nick,omitempty"`
+	Account string    `json:"account,omitempty"`
+	Mech    string    `json:"mechanism,omitempty"`
+	ASN     string    `json:"asn,omitempty"`
+	ASName  string    `json:"asname,omitempty"`
+}
+
+// Ban is a ban placed from the attacker list
+type Ban struct {
+	IP       string    `json:"ip"`
+	ASN      string    `json:"asn,omitempty"`
+	Type     string    `json:"type"`
+	Duration string    `json:"duration"`
+	Reason   string    `json:"reason"`
+	Actor    string    `json:"actor"`
+	Time     time.Time `json:"time"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Attacker is an IP or AS ranked by failed attempts
+type Attacker struct {
+	Key      string    `json:"key"`
+	ASN      string    `json:"asn,omitempty"`
+	ASName   string    `json:"asname,omitempty"`
+	Failures int       `json:"failures"`
+	IPs      []string  `json:"ips"`
+	Accounts []string  `json:"accounts"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+	Banned   int       `json:"banned"`
+}
+
+// BanRequest selects what to ban: one IP, or every IP of an AS seen
+// attacking within the given hours
+type BanRequest struct {
+	IP    string `json:"ip"`
+	ASN   string `json:"asn"`
+	Hours int    `json:"hours"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Attempts   []*Attempt               `json:"attempts"`
+	Bans       []*Ban                   `json:"bans"`
+	Mechanisms map[string]*MechanismDay `json:"mechanisms"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &SASLWatchPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			FailurePattern: `(?i)(?:(?P<nick>[^\s!:]+)!\S*@(?P<ip>[0-9a-fA-F:.]+)\S*\s+)?failed to (?:identify|authenticate|log ?in)(?: for| as| to)?(?: account)? "?(?P<account>[^\s".,()]+)`,
+			MechPattern:    `\b(?i:using|via|with|mechanism)\s+(?:(?i:SASL)\s+)?(?:(?i:mechanism)\s+)?"?(?P<mechanism>PLAIN|EXTERNAL|SCRAM-SHA-(?:1|256|512)(?:-PLUS)?|ECDSA-NIST256P-CHALLENGE|OAUTHBEARER|ANONYMOUS)\b`,
+			MechDays:       365,
+			DataDir:        "data/plugins/sasl-watch",
+			ASNLookup:      true,
+			BurstThreshold: 10,
+			BurstWindow:    10,
+			MaxAttempts:    20000,
+			BanType:        "gzline",
+			BanDuration:    "1d",
+			BanReason:      "Authentication brute force",
+		},
+		asn:       newASNResolver(),
+		attempts:  make([]*Attempt, 0),
+		bans:      make([]*Ban, 0),
+		mechDays:  make(map[string]*MechanismDay),
+		ipAlerts:  make(map[string]time.Time),
+		asnAlerts: make(map[string]time.Time),
+	}
+}
+
+// Info returns plugin metadata
+func (p *SASLWatchPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "SASL Watch",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Failed SASL and NickServ logins ranked by IP and network",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *SASLWatchPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[sasl-watch] failed to load data: %v", err)
+	}
+	if data.Attempts != nil {
+		p.attempts = data.Attempts
+	}
+	if data.Bans != nil {
+		p.bans = data.Bans
+	}
+	if data.Mechanisms != nil {
+		p.mechDays = data.Mechanisms
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "sasl-watch-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		failures := 0
+		ips := make(map[string]bool)
+		for i := len(p.attempts) - 1; i >= 0 && p.attempts[i].Time.After(since); i-- {
+			failures++
+			ips[p.attempts[i].IP] = true
+		}
+		content := map[string]interface{}{
+			"failures_24h":  failures,
+			"attackers_24h": len(ips),
+			"log_error":     p.tailError,
+		}
+		week := mechanismReport("", sumMechanismDays(p.mechDays, time.Now().UTC().AddDate(0, 0, -6).Format(dayKey)))
+		if week.Logins > 0 {
+			content["external_share_7d"] = week.Share["EXTERNAL"]
+		}
+		return plugins.DashboardCard{
+			Title:   "SASL Attacks",
+			Icon:    "KeyRound",
+			Content: content,
+			Order:   55,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.tailLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *SASLWatchPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *SASLWatchPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/sasl-watch")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/attempts", p.handleAttempts)
+		plugin.GET("/attackers", p.handleAttackers)
+		plugin.GET("/accounts", p.handleAccounts)
+		plugin.GET("/mechanisms", p.handleMechanisms)
+		plugin.GET("/bans", p.handleListBans)
+		plugin.POST("/bans", p.handleBan)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *SASLWatchPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "sasl.json")
+}
+
+// save persists the state if it changed
+func (p *SASLWatchPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Attempts: p.attempts, Bans: p.bans, Mechanisms: p.mechDays}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *SASLWatchPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// tailLoop reads failed authentications and SASL mechanisms from the
+// services log and saves the state until shutdown
+func (p *SASLWatchPlugin) tailLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	var tail *fileTail
+	var pattern, mechPattern string
+	var re, mechRe *regexp.Regexp
+	saveAt := time.Now().Add(time.Minute)
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.RLock()
+		path := p.config.ServicesLogFile
+		cfgPattern := p.config.FailurePattern
+		cfgMechPattern := p.config.MechPattern
+		lookup := p.config.ASNLookup
+		p.mu.RUnlock()
+
+		if path == "" {
+			tail = nil
+		} else if tail == nil || tail.path != path {
+			tail = newFileTail(path)
+		}
+		if cfgPattern != pattern {
+			pattern = cfgPattern
+			var err error
+			if re, err = regexp.Compile(pattern); err != nil {
+				log.Printf("[sasl-watch] invalid failure pattern: %v", err)
+				re = nil
+			}
+		}
+		if cfgMechPattern != mechPattern {
+			mechPattern = cfgMechPattern
+			mechRe = nil
+			if mechPattern != "" {
+				var err error
+				if mechRe, err = regexp.Compile(mechPattern); err != nil {
+					log.Printf("[sasl-watch] invalid mechanism pattern: %v", err)
+					mechRe = nil
+				}
+			}
+		}
+
+		if tail != nil && (re != nil || mechRe != nil) {
+			lines, err := tail.ReadLines()
+			p.mu.Lock()
+			p.tailError = ""
+			if err != nil {
+				p.tailError = err.Error()
+			}
+			p.mu.Unlock()
+			for _, line := range lines {
+				mech := ""
+				if mechRe != nil {
+					mech = matchMechanism(mechRe, line)
+				}
+				f, ok := authFailure{}, false
+				if re != nil {
+					f, ok = matchFailure(re, line)
+				}
+				if mech != "" {
+					p.countMechanism(mech, ok)
+				}
+				if !ok {
+					continue
+				}
+				a := &Attempt{Time: time.Now().UTC(), IP: f.ip, Nick: f.nick, Account: f.account, Mech: mech}
+				if lookup && a.IP != "" {
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					info := p.asn.Lookup(ctx, a.IP)
+					cancel()
+					a.ASN = info.ASN
+					a.ASName = info.ASName
+				}
+				p.record(a)
+			}
+		}
+
+		if time.Now().After(saveAt) {
+			saveAt = time.Now().Add(time.Minute)
+			p.pruneMechanisms()
+			if err := p.save(); err != nil {
+				log.Printf("[sasl-watch] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// countMechanism counts a SASL login, or a failed one, by mechanism
+func (p *SASLWatchPlugin) countMechanism(mech string, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := time.Now().UTC().Format(dayKey)
+	d, ok := p.mechDays[key]
+	if !ok {
+		d = &MechanismDay{}
+		p.mechDays[key] = d
+	}
+	d.add(mech, failed)
+	p.dirty = true
+}
+
+// pruneMechanisms drops mechanism counts older than mechanism_days
+func (p *SASLWatchPlugin) pruneMechanisms() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	days := p.config.MechDays
+	if days < 1 {
+		days = 1
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -days).Format(dayKey)
+	for key := range p.mechDays {
+		if key < cutoff {
+			delete(p.mechDays, key)
+			p.dirty = true
+		}
+	}
+}
+
+// window returns the burst detection window. Caller must hold p.mu.
+func (p *SASLWatchPlugin) window() time.Duration {
+	minutes := p.config.BurstWindow
+	if minutes < 1 {
+		minutes = 1
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// record stores a failed attempt, runs burst detection and sends any alerts
+func (p *SASLWatchPlugin) record(a *Attempt) {
+	alerts := make([]notify.Event, 0)
+
+	p.mu.Lock()
+	p.attempts = append(p.attempts, a)
+	if max := p.config.MaxAttempts; max > 0 && len(p.attempts) > max {
+		p.attempts = append([]*Attempt(nil), p.attempts[len(p.attempts)-max:]...)
+	}
+	p.dirty = true
+
+	window := p.window()
+	since := a.Time.Add(-window)
+	threshold := p.config.BurstThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	ipFailures := 0
+	asnFailures := 0
+	asnIPs := make(map[string]bool)
+	accounts := make([]string, 0)
+	for i := len(p.attempts) - 1; i >= 0 && !p.attempts[i].Time.Before(since); i-- {
+		o := p.attempts[i]
+		if a.IP != "" && o.IP == a.IP {
+			ipFailures++
+			if o.Account != "" && !lists.ContainsFold(accounts, o.Account) && len(accounts) < 20 {
+				accounts = append(accounts, o.Account)
+			}
+		}
+		if a.ASN != "" && o.ASN == a.ASN {
+			asnFailures++
+			asnIPs[o.IP] = true
+		}
+	}
+
+	if a.IP != "" && ipFailures >= threshold && a.Time.Sub(p.ipAlerts[a.IP]) > window {
+		p.ipAlerts[a.IP] = a.Time
+		alerts = append(alerts, notify.Event{
+			Type:     "sasl_brute_force",
+			Severity: "critical",
+			Title:    "Authentication brute force detected",
+			Message:  fmt.Sprintf("%d failed logins from %s in %s against %d account(s)", ipFailures, a.IP, window, len(accounts)),
+			Data:     map[string]interface{}{"ip": a.IP, "asn": a.ASN, "asname": a.ASName, "failures": ipFailures, "accounts": accounts},
+		})
+	}
+	// Many addresses of the same network taking turns
+	if a.ASN != "" && asnFailures >= threshold && len(asnIPs) > 1 && a.Time.Sub(p.asnAlerts[a.ASN]) > window {
+		p.asnAlerts[a.ASN] = a.Time
+		alerts = append(alerts, notify.Event{
+			Type:     "sasl_asn_brute_force",
+			Severity: "critical",
+			Title:    "Distributed authentication brute force detected",
+			Message:  fmt.Sprintf("%d failed logins from %d addresses in AS%s (%s) in %s", asnFailures, len(asnIPs), a.ASN, a.ASName, window),
+			Data:     map[string]interface{}{"asn": a.ASN, "asname": a.ASName, "failures": asnFailures, "ips": len(asnIPs)},
+		})
+	}
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	for _, al := range alerts {
+		log.Printf("[sasl-watch] %s: %s", al.Title, al.Message)
+		if webhook != "" {
+			al.Source = "sasl-watch"
+			al.Timestamp = a.Time
+			go p.alert(webhook, al)
+		}
+	}
+}
+
+// alert posts an alert to the webhook
+func (p *SASLWatchPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[sasl-watch] failed to send alert: %v", err)
+	}
+}
+
+// bannedIPs returns the IPs banned successfully from the attacker list.
+// Caller must hold p.mu.
+func (p *SASLWatchPlugin) bannedIPs() map[string]bool {
+	banned := make(map[string]bool)
+	for _, b := range p.bans {
+		if b.Error == "" {
+			banned[b.IP] = true
+		}
+	}
+	return banned
+}
+
+// hoursParam parses the hours query parameter
+func hoursParam(c *gin.Context) (int, bool) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be a positive number"})
+		return 0, false
+	}
+	return hours, true
+}
+
+// handleStatus reports whether the services log is being read
+func (p *SASLWatchPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"services_log_file":  p.config.ServicesLogFile,
+		"services_log_error": p.tailError,
+		"attempts":           len(p.attempts),
+		"allow_ban":          p.config.AllowBan,
+		"ban_type":           p.config.BanType,
+		"ban_duration":       p.config.BanDuration,
+	})
+}
+
+// handleAttempts returns recent failed attempts, newest first
+func (p *SASLWatchPlugin) handleAttempts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	ip := c.Query("ip")
+	asn := strings.TrimPrefix(strings.ToUpper(c.Query("asn")), "AS")
+	account := c.Query("account")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Attempt, 0)
+	for i := len(p.attempts) - 1; i >= 0 && len(list) < limit; i-- {
+		a := p.attempts[i]
+		if ip != "" && a.IP != ip {
+			continue
+		}
+		if asn != "" && a.ASN != asn {
+			continue
+		}
+		if account != "" && !strings.EqualFold(a.Account, account) {
+			continue
+		}
+		list = append(list, a)
+	}
+	c.JSON(http.StatusOK, gin.H{"attempts": list})
+}
+
+// handleAttackers ranks IPs, or ASes with group=asn, by failed attempts
+// over a period
+func (p *SASLWatchPlugin) handleAttackers(c *gin.Context) {
+	hours, ok := hoursParam(c)
+	if !ok {
+		return
+	}
+	group := c.DefaultQuery("group", "ip")
+	if group != "ip" && group != "asn" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group must be ip or asn"})
+		return
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	banned := p.bannedIPs()
+	byKey := make(map[string]*Attacker)
+	for i := len(p.attempts) - 1; i >= 0 && p.attempts[i].Time.After(since); i-- {
+		a := p.attempts[i]
+		key := a.IP
+		if group == "asn" {
+			key = a.ASN
+		}
+		if key == "" {
+			continue
+		}
+		at, ok := byKey[key]
+		if !ok {
+			at = &Attacker{Key: key, ASN: a.ASN, ASName: a.ASName, IPs: make([]string, 0), Accounts: make([]string, 0), Last: a.Time}
+			byKey[key] = at
+		}
+		at.Failures++
+		at.First = a.Time
+		if a.IP != "" && !lists.ContainsFold(at.IPs, a.IP) {
+			at.IPs = append(at.IPs, a.IP)
+			if banned[a.IP] {
+				at.Banned++
+			}
+		}
+		if a.Account != "" && !lists.ContainsFold(at.Accounts, a.Account) && len(at.Accounts) < 20 {
+			at.Accounts = append(at.Accounts, a.Account)
+		}
+	}
+
+	list := make([]*Attacker, 0, len(byKey))
+	for _, at := range byKey {
+		list = append(list, at)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Failures > list[j].Failures })
+	c.JSON(http.StatusOK, gin.H{"hours": hours, "group": group, "attackers": list})
+}
+
+// handleAccounts ranks the accounts being guessed over a period
+func (p *SASLWatchPlugin) handleAccounts(c *gin.Context) {
+	hours, ok := hoursParam(c)
+	if !ok {
+		return
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	type target struct {
+		Account  string    `json:"account"`
+		Failures int       `json:"failures"`
+		IPs      int       `json:"ips"`
+		Last     time.Time `json:"last"`
+		seen     map[string]bool
+	}
+	byAccount := make(map[string]*target)
+	for i := len(p.attempts) - 1; i >= 0 && p.attempts[i].Time.After(since); i-- {
+		a := p.attempts[i]
+		if a.Account == "" {
+			continue
+		}
+		key := strings.ToLower(a.Account)
+		t, ok := byAccount[key]
+		if !ok {
+			t = &target{Account: a.Account, Last: a.Time, seen: make(map[string]bool)}
+			byAccount[key] = t
+		}
+		t.Failures++
+		if !t.seen[a.IP] {
+			t.seen[a.IP] = true
+			t.IPs++
+		}
+	}
+
+	list := make([]*target, 0, len(byAccount))
+	for _, t := range byAccount {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Failures > list[j].Failures })
+	c.JSON(http.StatusOK, gin.H{"hours": hours, "accounts": list})
+}
+
+// handleMechanisms returns SASL logins by mechanism for each of the last
+// ?days= days, 30 by default, and for the whole range
+func (p *SASLWatchPlugin) handleMechanisms(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	n := 30
+	if s := c.Query("days"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > p.config.MechDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and " + strconv.Itoa(p.config.MechDays)})
+			return
+		}
+		n = v
+	}
+	from := time.Now().UTC().AddDate(0, 0, -(n - 1)).Format(dayKey)
+
+	days := make([]MechanismReport, 0, n)
+	for _, key := range sortedMechanismDays(p.mechDays, from) {
+		days = append(days, mechanismReport(key, p.mechDays[key]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"days":  days,
+		"total": mechanismReport(from, sumMechanismDays(p.mechDays, from)),
+	})
+}
+
+// handleListBans returns bans placed from the attacker list, newest first
+func (p *SASLWatchPlugin) handleListBans(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Ban, 0, len(p.bans))
+	for i := len(p.bans) - 1; i >= 0; i-- {
+		list = append(list, p.bans[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"bans": list})
+}
+
+// handleBan bans one attacking IP, or every not yet banned IP of an AS,
+// over JSON-RPC
+func (p *SASLWatchPlugin) handleBan(c *gin.Context) {
+	var req BanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.ASN = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(req.ASN)), "AS")
+	if (req.IP == "") == (req.ASN == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give either an ip or an asn"})
+		return
+	}
+	if req.IP != "" && net.ParseIP(req.IP) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IP address"})
+		return
+	}
+	if req.Hours < 1 {
+		req.Hours = 24
+	}
+
+	p.mu.RLock()
+	cfg := p.config
+	targets := make([]string, 0)
+	if req.IP != "" {
+		targets = append(targets, req.IP)
+	} else {
+		banned := p.bannedIPs()
+		since := time.Now().Add(-time.Duration(req.Hours) * time.Hour)
+		seen := make(map[string]bool)
+		for i := len(p.attempts) - 1; i >= 0 && p.attempts[i].Time.After(since) && len(targets) < maxBulkBans; i-- {
+			a := p.attempts[i]
+			if a.ASN == req.ASN && a.IP != "" && !banned[a.IP] && !seen[a.IP] {
+				seen[a.IP] = true
+				targets = append(targets, a.IP)
+			}
+		}
+	}
+	p.mu.RUnlock()
+
+	if !cfg.AllowBan {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Banning is disabled in the plugin settings"})
+		return
+	}
+	if len(targets) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No unbanned attacking IPs found for AS" + req.ASN})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+
+	rpc := p.client()
+	actor := access.Actor(c)
+	results := make([]*Ban, 0, len(targets))
+	failed := 0
+	for _, ip := range targets {
+		b := &Ban{IP: ip, ASN: req.ASN, Type: cfg.BanType, Duration: cfg.BanDuration, Reason: cfg.BanReason, Actor: actor, Time: time.Now().UTC()}
+		params := map[string]interface{}{
+			"name":            "*@" + ip,
+			"type":            cfg.BanType,
+			"reason":          cfg.BanReason,
+			"duration_string": cfg.BanDuration,
+		}
+		var out json.RawMessage
+		if err := rpc.Call(ctx, "server_ban.add", params, &out); err != nil {
+			b.Error = err.Error()
+			failed++
+		}
+		results = append(results, b)
+	}
+
+	p.mu.Lock()
+	p.bans = append(p.bans, results...)
+	p.dirty = true
+	p.mu.Unlock()
+
+	log.Printf("[sasl-watch] %s placed %s on %d of %d IP(s)", actor, cfg.BanType, len(results)-failed, len(results))
+	if failed == len(results) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": results[0].Error, "bans": results})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Banned %d of %d IP(s)", len(results)-failed, len(results)), "bans": results})
+}
+
+// handleGetConfig returns the current configuration
+func (p *SASLWatchPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *SASLWatchPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if _, err := regexp.Compile(newConfig.FailurePattern); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid failure pattern: " + err.Error()})
+		return
+	}
+	if newConfig.MechPattern != "" {
+		re, err := regexp.Compile(newConfig.MechPattern)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mechanism pattern: " + err.Error()})
+			return
+		}
+		if !lists.ContainsFold(re.SubexpNames(), "mechanism") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mechanism_pattern must have a mechanism group"})
+			return
+		}
+	}
+	if newConfig.MechDays < 1 || newConfig.MechDays > 730 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mechanism_days must be between 1 and 730"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *SASLWatchPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *SASLWatchPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,117 @@
+package saslwatch
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// dayKey is the layout of the keys of daily mechanism counts
+const dayKey = "2006-01-02"
+
+// MechanismDay holds one day's SASL logins by mechanism
+type MechanismDay struct {
+	Success map[string]int `json:"success"`
+	Failure map[string]int `json:"failure"`
+}
+
+// add counts one login with a mechanism
+func (d *MechanismDay) add(mechanism string, failed bool) {
+	m := &d.Success
+	if failed {
+		m = &d.Failure
+	}
+	if *m == nil {
+		*m = make(map[string]int)
+	}
+	(*m)[mechanism]++
+}
+
+// merge adds another day's counts to d
+func (d *MechanismDay) merge(o *MechanismDay) {
+	for mech, n := range o.Success {
+		if d.Success == nil {
+			d.Success = make(map[string]int)
+		}
+		d.Success[mech] += n
+	}
+	for mech, n := range o.Failure {
+		if d.Failure == nil {
+			d.Failure = make(map[string]int)
+		}
+		d.Failure[mech] += n
+	}
+}
+
+// MechanismReport is a day, or a range of days, of SASL logins by
+// mechanism, with each mechanism's share of the successful logins
+type MechanismReport struct {
+	Date     string             `json:"date,omitempty"`
+	Logins   int                `json:"logins"`
+	Failures int                `json:"failures"`
+	Success  map[string]int     `json:"success"`
+	Failure  map[string]int     `json:"failure"`
+	Share    map[string]float64 `json:"share_percent"`
+}
+
+// mechanismReport sums up a day's counts
+func mechanismReport(date string, d *MechanismDay) MechanismReport {
+	r := MechanismReport{
+		Date:    date,
+		Success: make(map[string]int),
+		Failure: make(map[string]int),
+		Share:   make(map[string]float64),
+	}
+	for mech, n := range d.Success {
+		r.Success[mech] = n
+		r.Logins += n
+	}
+	for mech, n := range d.Failure {
+		r.Failure[mech] = n
+		r.Failures += n
+	}
+	for mech, n := range r.Success {
+		r.Share[mech] = float64(int64(float64(n)*1000/float64(r.Logins)+0.5)) / 10
+	}
+	return r
+}
+
+// sumMechanismDays adds up the days from the given key onwards
+func sumMechanismDays(days map[string]*MechanismDay, from string) *MechanismDay {
+	total := &MechanismDay{}
+	for key, d := range days {
+		if key >= from {
+			total.merge(d)
+		}
+	}
+	return total
+}
+
+// sortedMechanismDays returns the keys of the days from the given key
+// onwards, oldest first
+func sortedMechanismDays(days map[string]*MechanismDay, from string) []string {
+	keys := make([]string, 0, len(days))
+	for key := range days {
+		if key >= from {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// matchMechanism applies the configured pattern to a log line and returns
+// the SASL mechanism it names in upper case, or "". The pattern must name
+// a "mechanism" group.
+func matchMechanism(re *regexp.Regexp, line string) string {
+	m := re.FindStringSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	for i, name := range re.SubexpNames() {
+		if name == "mechanism" {
+			return strings.ToUpper(strings.Trim(m[i], `"'`))
+		}
+	}
+	return ""
+}
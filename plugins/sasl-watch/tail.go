@@ -0,0 +1,109 @@
+package saslwatch
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// fileTail reads lines appended to a log file since the last read
+type fileTail struct {
+	path    string
+	offset  int64
+	started bool
+	partial string
+}
+
+// newFileTail creates a tail for path. The first read skips what is
+// already in the file.
+func newFileTail(path string) *fileTail {
+	return &fileTail{path: path}
+}
+
+// ReadLines returns the complete lines added since the previous call. A file
+// that shrank is assumed to have been rotated and is read from the start.
+func (t *fileTail) ReadLines() ([]string, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !t.started {
+		t.started = true
+		t.offset = info.Size()
+		return nil, nil
+	}
+	if info.Size() < t.offset {
+		t.offset = 0
+		t.partial = ""
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0)
+	reader := bufio.NewReader(f)
+	for {
+		chunk, err := reader.ReadString('\n')
+		t.offset += int64(len(chunk))
+		if err == io.EOF {
+			// Keep an unfinished line for the next read
+			t.partial += chunk
+			break
+		}
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, strings.TrimRight(t.partial+chunk, "\r\n"))
+		t.partial = ""
+	}
+	return lines, nil
+}
+
+// ipPattern finds an IPv4 or IPv6 address anywhere in a line
+var ipPattern = regexp.MustCompile(`(?:\d{1,3}\.){3}\d{1,3}|[0-9a-fA-F]{0,4}(?::[0-9a-fA-F]{0,4}){2,7}`)
+
+// authFailure is a failed authentication parsed from a log line
+type authFailure struct {
+	nick    string
+	account string
+	ip      string
+}
+
+// matchFailure applies the configured pattern to a log line. The pattern
+// may name "nick", "account" and "ip" groups. Without an "ip" match, the
+// first address in the line is used.
+func matchFailure(re *regexp.Regexp, line string) (authFailure, bool) {
+	m := re.FindStringSubmatch(line)
+	if m == nil {
+		return authFailure{}, false
+	}
+	var f authFailure
+	for i, name := range re.SubexpNames() {
+		switch name {
+		case "nick":
+			f.nick = strings.Trim(m[i], `"'`)
+		case "account":
+			f.account = strings.Trim(m[i], `"'`)
+		case "ip":
+			f.ip = strings.Trim(m[i], `"'[]`)
+		}
+	}
+	if f.ip == "" {
+		for _, candidate := range ipPattern.FindAllString(line, -1) {
+			if net.ParseIP(candidate) != nil {
+				f.ip = candidate
+				break
+			}
+		}
+	}
+	return f, true
+}
@@ -0,0 +1,246 @@
+package scheduledcommands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Job actions
+const (
+	ActionRPC          = "rpc"
+	ActionGlobalNotice = "global_notice"
+	ActionBanCleanup   = "ban_cleanup"
+)
+
+// NoticeParams are the parameters of a global_notice job
+type NoticeParams struct {
+	Message string   `json:"message"`
+	Servers []string `json:"servers"`
+}
+
+// CleanupParams are the parameters of a ban_cleanup job. Empty fields
+// match every ban.
+type CleanupParams struct {
+	Types            []string `json:"types"`
+	ReasonContains   string   `json:"reason_contains"`
+	SetBy            string   `json:"set_by"`
+	MinAgeHours      int      `json:"min_age_hours"`
+	IncludePermanent bool     `json:"include_permanent"`
+	DryRun           bool     `json:"dry_run"`
+}
+
+// rpcBan is a ban as returned by server_ban.list
+type rpcBan struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	SetAt       string          `json:"set_at"`
+	SetBy       string          `json:"set_by"`
+	ExpireAt    json.RawMessage `json:"expire_at"`
+	Reason      string          `json:"reason"`
+	SetInConfig bool            `json:"set_in_config"`
+}
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	Name string `json:"name"`
+	User struct {
+		Servername string `json:"servername"`
+	} `json:"user"`
+}
+
+// validate checks that a job's action and parameters make sense.
+// allowed is the allowed_methods setting.
+func (j *Job) validate(allowed []string) error {
+	switch j.Action {
+	case ActionRPC:
+		if j.Method == "" {
+			return fmt.Errorf("method is required")
+		}
+		if !methodAllowed(allowed, j.Method) {
+			return fmt.Errorf("method %s is not in allowed_methods", j.Method)
+		}
+		if len(j.Params) > 0 && string(j.Params) != "null" {
+			var obj map[string]interface{}
+			if err := json.Unmarshal(j.Params, &obj); err != nil {
+				return fmt.Errorf("params must be a JSON object")
+			}
+		}
+	case ActionGlobalNotice:
+		var np NoticeParams
+		if err := json.Unmarshal(j.Params, &np); err != nil || strings.TrimSpace(np.Message) == "" {
+			return fmt.Errorf("params.message is required")
+		}
+	case ActionBanCleanup:
+		var cp CleanupParams
+		if len(j.Params) > 0 {
+			if err := json.Unmarshal(j.Params, &cp); err != nil {
+				return fmt.Errorf("params are not valid: %v", err)
+			}
+		}
+		if cp.MinAgeHours < 0 {
+			return fmt.Errorf("params.min_age_hours can't be negative")
+		}
+	default:
+		return fmt.Errorf("action must be %s, %s or %s", ActionRPC, ActionGlobalNotice, ActionBanCleanup)
+	}
+	return nil
+}
+
+// methodAllowed reports whether method matches one of the allowed masks
+func methodAllowed(allowed []string, method string) bool {
+	for _, mask := range allowed {
+		if ircname.MatchMask(mask, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// execute runs a job's action and returns its output
+func execute(ctx context.Context, rpc *jsonrpc.Client, j Job, now time.Time) (string, error) {
+	switch j.Action {
+	case ActionRPC:
+		var params interface{}
+		if len(j.Params) > 0 && string(j.Params) != "null" {
+			params = j.Params
+		}
+		var out json.RawMessage
+		if err := rpc.Call(ctx, j.Method, params, &out); err != nil {
+			return "", err
+		}
+		return string(out), nil
+	case ActionGlobalNotice:
+		var np NoticeParams
+		if err := json.Unmarshal(j.Params, &np); err != nil {
+			return "", err
+		}
+		return globalNotice(ctx, rpc, np)
+	case ActionBanCleanup:
+		var cp CleanupParams
+		if len(j.Params) > 0 {
+			if err := json.Unmarshal(j.Params, &cp); err != nil {
+				return "", err
+			}
+		}
+		return banCleanup(ctx, rpc, cp, now)
+	}
+	return "", fmt.Errorf("unknown action %q", j.Action)
+}
+
+// globalNotice sends a notice to every user, or every user on the given
+// servers
+func globalNotice(ctx context.Context, rpc *jsonrpc.Client, np NoticeParams) (string, error) {
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	if err := rpc.Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 1}, &result); err != nil {
+		return "", err
+	}
+
+	sent, failed := 0, 0
+	var lastErr error
+	for _, u := range result.List {
+		if len(np.Servers) > 0 && !lists.ContainsFold(np.Servers, u.User.Servername) {
+			continue
+		}
+		var out json.RawMessage
+		if err := rpc.Call(ctx, "message.send_notice", map[string]interface{}{"nick": u.Name, "message": np.Message}, &out); err != nil {
+			failed++
+			lastErr = err
+			continue
+		}
+		sent++
+	}
+	if failed > 0 {
+		return fmt.Sprintf("Sent to %d users", sent), fmt.Errorf("%d notices failed, last error: %v", failed, lastErr)
+	}
+	return fmt.Sprintf("Sent to %d users", sent), nil
+}
+
+// banCleanup removes the server bans matching cp, or only lists them for a
+// dry run. Bans from the configuration file are never touched.
+func banCleanup(ctx context.Context, rpc *jsonrpc.Client, cp CleanupParams, now time.Time) (string, error) {
+	var result struct {
+		List []rpcBan `json:"list"`
+	}
+	if err := rpc.Call(ctx, "server_ban.list", nil, &result); err != nil {
+		return "", err
+	}
+
+	matched := make([]rpcBan, 0)
+	for _, b := range result.List {
+		if cp.matches(b, now) {
+			matched = append(matched, b)
+		}
+	}
+
+	var b strings.Builder
+	removed, failed := 0, 0
+	var lastErr error
+	for _, ban := range matched {
+		if !cp.DryRun {
+			var out json.RawMessage
+			if err := rpc.Call(ctx, "server_ban.del", map[string]string{"name": ban.Name, "type": ban.Type}, &out); err != nil {
+				failed++
+				lastErr = err
+				continue
+			}
+			removed++
+		}
+		fmt.Fprintf(&b, "%s %s (set by %s: %s)\n", ban.Type, ban.Name, ban.SetBy, ban.Reason)
+	}
+
+	var summary string
+	if cp.DryRun {
+		summary = fmt.Sprintf("Dry run: %d of %d bans match\n", len(matched), len(result.List))
+	} else {
+		summary = fmt.Sprintf("Removed %d of %d bans\n", removed, len(result.List))
+	}
+	if failed > 0 {
+		return summary + b.String(), fmt.Errorf("%d removals failed, last error: %v", failed, lastErr)
+	}
+	return summary + b.String(), nil
+}
+
+// matches reports whether a ban is selected by the cleanup parameters
+func (cp CleanupParams) matches(b rpcBan, now time.Time) bool {
+	if b.SetInConfig {
+		return false
+	}
+	if !cp.IncludePermanent && !expires(b.ExpireAt) {
+		return false
+	}
+	if len(cp.Types) > 0 && !lists.ContainsFold(cp.Types, b.Type) {
+		return false
+	}
+	if cp.SetBy != "" && !ircname.MatchMask(cp.SetBy, b.SetBy) {
+		return false
+	}
+	if cp.ReasonContains != "" && !strings.Contains(strings.ToLower(b.Reason), strings.ToLower(cp.ReasonContains)) {
+		return false
+	}
+	if cp.MinAgeHours > 0 {
+		setAt, err := time.Parse(time.RFC3339, b.SetAt)
+		if err != nil || now.Sub(setAt) < time.Duration(cp.MinAgeHours)*time.Hour {
+			return false
+		}
+	}
+	return true
+}
+
+// expires reports whether a ban has an expiry time. UnrealIRCd sends null
+// (or, in older versions, "never") for permanent bans.
+func expires(raw json.RawMessage) bool {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return false
+	}
+	return s != "" && s != "never"
+}
@@ -0,0 +1,355 @@
+/**
+ * Scheduled Commands Frontend Script
+ *
+ * Lists scheduled jobs on the plugin page and lets staff add, run, enable,
+ * disable and delete them, and read the output of past runs.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'scheduled-commands';
+  const PLUGIN_NAME = 'Scheduled Commands';
+  const PAGE_PATH = '/plugins/scheduled-commands';
+  const API_BASE = '/api/plugin/scheduled-commands';
+
+  const PARAM_HINTS = {
+    rpc: '{"object_detail_level": 1}',
+    global_notice: '{"message": "Weekly reminder: read the network rules at ...", "servers": []}',
+    ban_cleanup: '{"types": ["gline"], "reason_contains": "", "set_by": "", "min_age_hours": 24, "include_permanent": false, "dry_run": true}'
+  };
+
+  let previewTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('scheduled-commands-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'scheduled-commands-styles';
+    style.textContent = `
+      .scc-app { display: flex; flex-direction: column; gap: 1rem; }
+      .scc-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .scc-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .scc-btn.danger { background: var(--error, #f38ba8); color: #fff; }
+      .scc-form { display: grid; grid-template-columns: repeat(auto-fit, minmax(180px, 1fr)); gap: 0.5rem; align-items: end; }
+      .scc-form label { display: flex; flex-direction: column; gap: 0.25rem; font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .scc-form input, .scc-form select, .scc-form textarea {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .scc-form textarea { font-family: monospace; min-height: 4rem; }
+      .scc-form .wide { grid-column: 1 / -1; }
+      .scc-preview { font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .scc-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .scc-table th, .scc-table td {
+        text-align: left;
+        padding: 0.5rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+        vertical-align: top;
+      }
+      .scc-table th { color: var(--text-primary, #cdd6f4); }
+      .scc-table code { font-size: 0.8rem; }
+      .scc-ok { color: var(--success, #a6e3a1); }
+      .scc-error { color: var(--error, #f38ba8); }
+      .scc-muted { color: var(--text-muted, #6c7086); }
+      .scc-empty { color: var(--text-muted, #6c7086); padding: 1rem 0; }
+      .scc-history pre {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.5rem;
+        max-height: 12rem;
+        overflow: auto;
+        font-size: 0.8rem;
+        white-space: pre-wrap;
+        word-break: break-all;
+      }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderLastRun(r) {
+    if (!r) return '<span class="scc-muted">Never run</span>';
+    const state = r.ok ? '<span class="scc-ok">OK</span>' : '<span class="scc-error">Failed</span>';
+    return `${state} ${escapeHtml(formatTime(r.time))}<br><small class="scc-muted">${escapeHtml(r.trigger)}, ${r.duration_ms} ms</small>`;
+  }
+
+  function renderRow(j) {
+    const target = j.action === 'rpc' ? `rpc <code>${escapeHtml(j.method)}</code>` : escapeHtml(j.action);
+    const id = escapeHtml(j.id);
+    return `
+      <tr>
+        <td><strong>${escapeHtml(j.name)}</strong><br><small class="scc-muted">by ${escapeHtml(j.created_by)}</small></td>
+        <td><code>${escapeHtml(j.schedule)}</code></td>
+        <td>${target}</td>
+        <td>${j.enabled ? escapeHtml(formatTime(j.next_run)) : '<span class="scc-muted">Disabled</span>'}</td>
+        <td>${j.running ? '<span class="scc-muted">Running...</span>' : renderLastRun(j.last_run)}</td>
+        <td>
+          <button class="scc-btn" data-action="run" data-id="${id}">Run now</button>
+          <button class="scc-btn" data-action="${j.enabled ? 'disable' : 'enable'}" data-id="${id}">${j.enabled ? 'Disable' : 'Enable'}</button>
+          <button class="scc-btn" data-action="history" data-id="${id}">History</button>
+          <button class="scc-btn danger" data-action="delete" data-id="${id}">Delete</button>
+        </td>
+      </tr>
+    `;
+  }
+
+  async function loadJobs(container) {
+    const list = container.querySelector('#scc-list');
+
+    try {
+      const data = await api('GET', '/jobs');
+      if (!data.jobs.length) {
+        list.innerHTML = '<div class="scc-empty">No scheduled jobs.</div>';
+        return;
+      }
+      list.innerHTML = `
+        <table class="scc-table">
+          <thead><tr><th>Job</th><th>Schedule</th><th>Action</th><th>Next run</th><th>Last run</th><th></th></tr></thead>
+          <tbody>${data.jobs.map(renderRow).join('')}</tbody>
+        </table>
+      `;
+    } catch (e) {
+      list.innerHTML = `<div class="scc-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadHistory(container, id) {
+    const panel = container.querySelector('#scc-history');
+    panel.innerHTML = '<div class="scc-empty">Loading...</div>';
+
+    try {
+      const data = await api('GET', `/jobs/${encodeURIComponent(id)}/history`);
+      if (!data.runs.length) {
+        panel.innerHTML = '<div class="scc-empty">This job has not run yet.</div>';
+        return;
+      }
+      panel.innerHTML = `
+        <h3>History</h3>
+        <table class="scc-table">
+          <thead><tr><th>Time</th><th>Result</th><th>Output</th></tr></thead>
+          <tbody>${data.runs.map(r => `
+            <tr>
+              <td>${escapeHtml(formatTime(r.time))}<br><small class="scc-muted">${escapeHtml(r.trigger)}${r.actor ? ' by ' + escapeHtml(r.actor) : ''}, ${r.duration_ms} ms</small></td>
+              <td>${r.ok ? '<span class="scc-ok">OK</span>' : `<span class="scc-error">${escapeHtml(r.error)}</span>`}</td>
+              <td>${r.output ? `<pre>${escapeHtml(r.output)}${r.truncated ? '\n...' : ''}</pre>` : ''}</td>
+            </tr>
+          `).join('')}</tbody>
+        </table>
+      `;
+    } catch (e) {
+      panel.innerHTML = `<div class="scc-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function updatePreview(container) {
+    const out = container.querySelector('#scc-preview');
+    const schedule = container.querySelector('#scc-form').elements.schedule.value.trim();
+    if (!schedule) {
+      out.textContent = '';
+      return;
+    }
+    try {
+      const data = await api('GET', `/preview?count=3&schedule=${encodeURIComponent(schedule)}`);
+      out.textContent = data.next.length
+        ? `Next runs (${data.timezone}): ${data.next.map(formatTime).join(', ')}`
+        : 'This schedule never fires';
+    } catch (e) {
+      out.textContent = e.message;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="scc-app" data-plugin="${PLUGIN_ID}">
+        <form class="scc-form" id="scc-form">
+          <label>Name<input name="name" required placeholder="Nightly stats snapshot"></label>
+          <label>Schedule<input name="schedule" required placeholder="0 3 * * *"></label>
+          <label>Action
+            <select name="action">
+              <option value="rpc">RPC call</option>
+              <option value="global_notice">Global notice</option>
+              <option value="ban_cleanup">Ban cleanup</option>
+            </select>
+          </label>
+          <label>Method<input name="method" placeholder="stats.get"></label>
+          <label class="wide">Parameters (JSON)<textarea name="params" placeholder='${escapeHtml(PARAM_HINTS.rpc)}'></textarea></label>
+          <div class="wide scc-preview" id="scc-preview"></div>
+          <button class="scc-btn primary" type="submit">Add job</button>
+        </form>
+        <div id="scc-list"><div class="scc-empty">Loading...</div></div>
+        <div class="scc-history" id="scc-history"></div>
+      </div>
+    `;
+
+    const form = container.querySelector('#scc-form');
+
+    form.elements.action.addEventListener('change', () => {
+      const action = form.elements.action.value;
+      form.elements.method.disabled = action !== 'rpc';
+      form.elements.params.placeholder = PARAM_HINTS[action];
+    });
+
+    form.elements.schedule.addEventListener('input', () => {
+      clearTimeout(previewTimer);
+      previewTimer = setTimeout(() => updatePreview(container), 400);
+    });
+
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = form.elements;
+      let params;
+      if (f.params.value.trim()) {
+        try {
+          params = JSON.parse(f.params.value);
+        } catch (err) {
+          alert('Parameters are not valid JSON');
+          return;
+        }
+      }
+      try {
+        await api('POST', '/jobs', {
+          name: f.name.value,
+          schedule: f.schedule.value,
+          action: f.action.value,
+          method: f.method.value,
+          params
+        });
+        form.reset();
+        f.method.disabled = false;
+        container.querySelector('#scc-preview').textContent = '';
+        loadJobs(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    container.querySelector('#scc-list').addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+      const id = btn.dataset.id;
+      const path = `/jobs/${encodeURIComponent(id)}`;
+
+      try {
+        switch (btn.dataset.action) {
+          case 'run':
+            btn.disabled = true;
+            await api('POST', path + '/run');
+            loadHistory(container, id);
+            break;
+          case 'enable':
+          case 'disable':
+            await api('POST', `${path}/${btn.dataset.action}`);
+            break;
+          case 'history':
+            loadHistory(container, id);
+            return;
+          case 'delete':
+            if (!confirm('Delete this job and its history?')) return;
+            await api('DELETE', path);
+            container.querySelector('#scc-history').innerHTML = '';
+            break;
+        }
+      } catch (err) {
+        alert(err.message);
+      }
+      loadJobs(container);
+    });
+
+    loadJobs(container);
+    return true;
+  }
+
+  function cleanup() {
+    clearTimeout(previewTimer);
+    const style = document.getElementById('scheduled-commands-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
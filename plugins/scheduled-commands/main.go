@@ -0,0 +1,848 @@
+// Scheduled Commands Plugin for UnrealIRCd Web Panel
+// Runs recurring JSON-RPC actions on cron schedules, such as stats snapshots,
+// global notices and ban cleanups, keeping the output of every run
+
+package scheduledcommands
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Run triggers
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+const (
+	// catchUpWindow is how late a scheduled run may start, e.g. after the
+	// panel was restarted. Runs missed by more than this are skipped.
+	catchUpWindow = 5 * time.Minute
+	// maxOutput is the number of bytes of output kept per run
+	maxOutput = 8192
+)
+
+// ScheduledCommandsPlugin implements the Plugin interface
+type ScheduledCommandsPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	jobs      []*Job
+	running   map[string]bool
+	dirty     bool
+	published time.Time
+	mu        sync.RWMutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	Timezone       string `json:"timezone"`
+	AllowedMethods string `json:"allowed_methods"`
+	HistorySize    int    `json:"history_size"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// Job is a scheduled action
+type Job struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Schedule  string          `json:"schedule"`
+	Action    string          `json:"action"`
+	Method    string          `json:"method,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Enabled   bool            `json:"enabled"`
+	CreatedBy string          `json:"created_by"`
+	CreatedAt time.Time       `json:"created_at"`
+	NextRun   time.Time       `json:"next_run"`
+	History   []Run           `json:"history,omitempty"`
+	LastRun   *Run            `json:"last_run,omitempty"`
+	Running   bool            `json:"running"`
+}
+
+// Run records one run of a job
+type Run struct {
+	Time       time.Time `json:"time"`
+	Trigger    string    `json:"trigger"`
+	Actor      string    `json:"actor,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	OK         bool      `json:"ok"`
+	Output     string    `json:"output,omitempty"`
+	Truncated  bool      `json:"truncated,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// JobRequest is the body of create and update requests
+type JobRequest struct {
+	Name     string          `json:"name"`
+	Schedule string          `json:"schedule"`
+	Action   string          `json:"action"`
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params"`
+	Enabled  *bool           `json:"enabled"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Jobs []*Job `json:"jobs"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ScheduledCommandsPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/scheduled-commands",
+			Timezone:       "UTC",
+			AllowedMethods: "stats.*,server.list,server.get,server.rehash,server_ban.*,name_ban.*,spamfilter.*,message.*,channel.list,user.list",
+			HistorySize:    50,
+			TimeoutSeconds: 120,
+		},
+		jobs:    make([]*Job, 0),
+		running: make(map[string]bool),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ScheduledCommandsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Scheduled Commands",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Run recurring RPC actions on cron schedules",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ScheduledCommandsPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[scheduled-commands] failed to load data: %v", err)
+	}
+	if data.Jobs != nil {
+		p.jobs = data.Jobs
+	}
+	// Enabled jobs without a next run, e.g. from a hand edited data file,
+	// are scheduled from now
+	for _, j := range p.jobs {
+		if j.Enabled && j.NextRun.IsZero() {
+			p.reschedule(j, time.Now())
+		}
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "scheduled-commands-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		enabled, failing := 0, 0
+		var next *Job
+		for _, j := range p.jobs {
+			if !j.Enabled {
+				continue
+			}
+			enabled++
+			if n := len(j.History); n > 0 && !j.History[n-1].OK {
+				failing++
+			}
+			if !j.NextRun.IsZero() && (next == nil || j.NextRun.Before(next.NextRun)) {
+				next = j
+			}
+		}
+		content := map[string]interface{}{
+			"jobs":    len(p.jobs),
+			"enabled": enabled,
+			"failing": failing,
+		}
+		if next != nil {
+			content["next_job"] = next.Name
+			content["next_run"] = next.NextRun
+		}
+		return plugins.DashboardCard{
+			Title:   "Scheduled Commands",
+			Icon:    "Clock",
+			Content: content,
+			Order:   78,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.schedulerLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ScheduledCommandsPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.cancel()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ScheduledCommandsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/scheduled-commands")
+	{
+		plugin.GET("/jobs", p.handleListJobs)
+		plugin.POST("/jobs", p.handleCreateJob)
+		plugin.GET("/jobs/:id", p.handleGetJob)
+		plugin.PUT("/jobs/:id", p.handleUpdateJob)
+		plugin.DELETE("/jobs/:id", p.handleDeleteJob)
+		plugin.POST("/jobs/:id/run", p.handleRunJob)
+		plugin.POST("/jobs/:id/enable", p.handleSetEnabled(true))
+		plugin.POST("/jobs/:id/disable", p.handleSetEnabled(false))
+		plugin.GET("/jobs/:id/history", p.handleJobHistory)
+		plugin.GET("/preview", p.handlePreview)
+		plugin.GET("/upcoming", p.handleUpcoming)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *ScheduledCommandsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "jobs.json")
+}
+
+// upcomingPath returns where the upcoming runs are published for other
+// plugins, which are pointed at it in their own configuration
+func (p *ScheduledCommandsPlugin) upcomingPath() string {
+	return filepath.Join(p.config.DataDir, "upcoming.json")
+}
+
+// save persists the state if it changed, and publishes the upcoming runs
+// when jobs changed or the last ones are an hour old
+func (p *ScheduledCommandsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if !p.dirty {
+		if now.Sub(p.published) < publishInterval {
+			return nil
+		}
+		return p.publish(now)
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Jobs: p.jobs}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return p.publish(now)
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ScheduledCommandsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// location returns the timezone schedules are read in. Caller must hold p.mu.
+func (p *ScheduledCommandsPlugin) location() *time.Location {
+	if loc, err := time.LoadLocation(p.config.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// reschedule sets a job's next run after now, or clears it for disabled
+// jobs. Caller must hold p.mu.
+func (p *ScheduledCommandsPlugin) reschedule(j *Job, now time.Time) {
+	j.NextRun = time.Time{}
+	if j.Enabled {
+		if s, err := parseSchedule(j.Schedule); err == nil {
+			if next := s.next(now.In(p.location())); !next.IsZero() {
+				j.NextRun = next.UTC()
+			}
+		}
+	}
+	p.dirty = true
+}
+
+// job finds a job by ID. Caller must hold p.mu.
+func (p *ScheduledCommandsPlugin) job(id string) *Job {
+	for _, j := range p.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// view returns a copy of the job for listing, with its last run in place
+// of the full history. Caller must hold p.mu.
+func (p *ScheduledCommandsPlugin) view(j *Job) Job {
+	v := *j
+	v.History = nil
+	if n := len(j.History); n > 0 {
+		last := j.History[n-1]
+		v.LastRun = &last
+	}
+	v.Running = p.running[j.ID]
+	return v
+}
+
+// schedulerLoop starts due jobs until shutdown
+func (p *ScheduledCommandsPlugin) schedulerLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		p.startDue(time.Now())
+		if err := p.save(); err != nil {
+			log.Printf("[scheduled-commands] failed to save data: %v", err)
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startDue starts every enabled job whose next run has come. A job still
+// running from its last run is not started again.
+func (p *ScheduledCommandsPlugin) startDue(now time.Time) {
+	p.mu.Lock()
+	due := make([]Job, 0)
+	for _, j := range p.jobs {
+		if !j.Enabled || j.NextRun.IsZero() || now.Before(j.NextRun) {
+			continue
+		}
+		missed := now.Sub(j.NextRun) > catchUpWindow
+		if missed {
+			log.Printf("[scheduled-commands] job %q missed its run at %s", j.Name, j.NextRun.Format(time.RFC3339))
+		} else if p.running[j.ID] {
+			log.Printf("[scheduled-commands] job %q is still running, skipping this run", j.Name)
+		} else {
+			p.running[j.ID] = true
+			due = append(due, *j)
+		}
+		p.reschedule(j, now)
+	}
+	p.mu.Unlock()
+
+	for _, j := range due {
+		p.wg.Add(1)
+		go func(j Job) {
+			defer p.wg.Done()
+			p.run(j, TriggerSchedule, "")
+		}(j)
+	}
+}
+
+// run executes a job and records the result in its history. The caller
+// marks the job as running.
+func (p *ScheduledCommandsPlugin) run(j Job, trigger, actor string) Run {
+	p.mu.RLock()
+	timeout := time.Duration(p.config.TimeoutSeconds) * time.Second
+	allowed := lists.Split(p.config.AllowedMethods)
+	p.mu.RUnlock()
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(p.ctx, timeout)
+	defer cancel()
+
+	// allowed_methods may have been narrowed since the job was saved
+	start := time.Now()
+	var output string
+	err := j.validate(allowed)
+	if err == nil {
+		output, err = execute(ctx, p.client(), j, start.UTC())
+	}
+	r := Run{
+		Time:       start.UTC(),
+		Trigger:    trigger,
+		Actor:      actor,
+		DurationMs: time.Since(start).Milliseconds(),
+		OK:         err == nil,
+		Output:     output,
+	}
+	if len(r.Output) > maxOutput {
+		r.Output = r.Output[:maxOutput]
+		r.Truncated = true
+	}
+	if err != nil {
+		r.Error = err.Error()
+		log.Printf("[scheduled-commands] job %q failed: %v", j.Name, err)
+	}
+
+	p.mu.Lock()
+	delete(p.running, j.ID)
+	if job := p.job(j.ID); job != nil {
+		job.History = append(job.History, r)
+		size := p.config.HistorySize
+		if size < 1 {
+			size = 50
+		}
+		if len(job.History) > size {
+			job.History = append([]Run(nil), job.History[len(job.History)-size:]...)
+		}
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	return r
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// apply validates a request and copies it onto the job. allowed is the
+// allowed_methods setting.
+func (req *JobRequest) apply(j *Job, allowed []string) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	sched := strings.TrimSpace(req.Schedule)
+	s, err := parseSchedule(sched)
+	if err != nil {
+		return err
+	}
+	if s.next(time.Now().UTC()).IsZero() {
+		return fmt.Errorf("schedule never fires")
+	}
+
+	updated := *j
+	updated.Name = name
+	updated.Schedule = sched
+	updated.Action = strings.TrimSpace(req.Action)
+	updated.Method = ""
+	if updated.Action == ActionRPC {
+		updated.Method = strings.TrimSpace(req.Method)
+	}
+	updated.Params = req.Params
+	if req.Enabled != nil {
+		updated.Enabled = *req.Enabled
+	}
+	if err := updated.validate(allowed); err != nil {
+		return err
+	}
+	*j = updated
+	return nil
+}
+
+// handleListJobs returns all jobs with their last run
+func (p *ScheduledCommandsPlugin) handleListJobs(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Job, 0, len(p.jobs))
+	for _, j := range p.jobs {
+		list = append(list, p.view(j))
+	}
+	sort.Slice(list, func(i, k int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[k].Name) })
+	c.JSON(http.StatusOK, gin.H{"jobs": list})
+}
+
+// handleCreateJob adds a new job
+func (p *ScheduledCommandsPlugin) handleCreateJob(c *gin.Context) {
+	actor, ok := access.RequireAdmin(c)
+	if !ok {
+		return
+	}
+	var req JobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	now := time.Now().UTC()
+	j := &Job{
+		ID:        newID(),
+		Enabled:   true,
+		CreatedBy: actor,
+		CreatedAt: now,
+	}
+
+	p.mu.Lock()
+	if err := req.apply(j, lists.Split(p.config.AllowedMethods)); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.reschedule(j, now)
+	p.jobs = append(p.jobs, j)
+	v := p.view(j)
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[scheduled-commands] failed to save data: %v", err)
+	}
+	log.Printf("[scheduled-commands] %s created job %q (%s, %s)", j.CreatedBy, j.Name, j.Action, j.Schedule)
+	c.JSON(http.StatusCreated, v)
+}
+
+// handleGetJob returns a single job with its full history
+func (p *ScheduledCommandsPlugin) handleGetJob(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	j := p.job(c.Param("id"))
+	if j == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	v := *j
+	v.Running = p.running[j.ID]
+	c.JSON(http.StatusOK, v)
+}
+
+// handleUpdateJob changes a job's name, schedule or action
+func (p *ScheduledCommandsPlugin) handleUpdateJob(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+	var req JobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	j := p.job(c.Param("id"))
+	if j == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if err := req.apply(j, lists.Split(p.config.AllowedMethods)); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.reschedule(j, time.Now())
+	v := p.view(j)
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[scheduled-commands] failed to save data: %v", err)
+	}
+	log.Printf("[scheduled-commands] %s updated job %q", access.Actor(c), v.Name)
+	c.JSON(http.StatusOK, v)
+}
+
+// handleDeleteJob removes a job and its history
+func (p *ScheduledCommandsPlugin) handleDeleteJob(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+	id := c.Param("id")
+
+	p.mu.Lock()
+	var name string
+	for i, j := range p.jobs {
+		if j.ID == id {
+			name = j.Name
+			p.jobs = append(p.jobs[:i], p.jobs[i+1:]...)
+			p.dirty = true
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	if name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if err := p.save(); err != nil {
+		log.Printf("[scheduled-commands] failed to save data: %v", err)
+	}
+	log.Printf("[scheduled-commands] %s deleted job %q", access.Actor(c), name)
+	c.JSON(http.StatusOK, gin.H{"message": "Job deleted"})
+}
+
+// handleRunJob runs a job now, whether or not it is enabled, and returns
+// the result
+func (p *ScheduledCommandsPlugin) handleRunJob(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+	p.mu.Lock()
+	j := p.job(c.Param("id"))
+	if j == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if p.running[j.ID] {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Job is already running"})
+		return
+	}
+	p.running[j.ID] = true
+	job := *j
+	p.mu.Unlock()
+
+	actor := access.Actor(c)
+	log.Printf("[scheduled-commands] %s ran job %q", actor, job.Name)
+	r := p.run(job, TriggerManual, actor)
+	if err := p.save(); err != nil {
+		log.Printf("[scheduled-commands] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// handleSetEnabled returns a handler that enables or disables a job
+func (p *ScheduledCommandsPlugin) handleSetEnabled(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := access.RequireAdmin(c); !ok {
+			return
+		}
+		p.mu.Lock()
+		j := p.job(c.Param("id"))
+		if j == nil {
+			p.mu.Unlock()
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		j.Enabled = enabled
+		p.reschedule(j, time.Now())
+		v := p.view(j)
+		p.mu.Unlock()
+
+		if err := p.save(); err != nil {
+			log.Printf("[scheduled-commands] failed to save data: %v", err)
+		}
+		state := "disabled"
+		if enabled {
+			state = "enabled"
+		}
+		log.Printf("[scheduled-commands] %s %s job %q", access.Actor(c), state, v.Name)
+		c.JSON(http.StatusOK, v)
+	}
+}
+
+// handleJobHistory returns a job's runs, newest first. ?failed=true keeps
+// only failed runs.
+func (p *ScheduledCommandsPlugin) handleJobHistory(c *gin.Context) {
+	failedOnly := c.Query("failed") == "true"
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	j := p.job(c.Param("id"))
+	if j == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	runs := make([]Run, 0, len(j.History))
+	for i := len(j.History) - 1; i >= 0; i-- {
+		if failedOnly && j.History[i].OK {
+			continue
+		}
+		runs = append(runs, j.History[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"job": j.ID, "runs": runs})
+}
+
+// handlePreview returns the next times a schedule fires, to check an
+// expression before saving it
+func (p *ScheduledCommandsPlugin) handlePreview(c *gin.Context) {
+	s, err := parseSchedule(c.Query("schedule"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if err != nil || count < 1 || count > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 50"})
+		return
+	}
+
+	p.mu.RLock()
+	loc := p.location()
+	p.mu.RUnlock()
+
+	times := make([]time.Time, 0, count)
+	t := time.Now().In(loc)
+	for len(times) < count {
+		if t = s.next(t); t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	c.JSON(http.StatusOK, gin.H{"timezone": loc.String(), "next": times})
+}
+
+// handleUpcoming returns the runs of enabled jobs in the next days
+func (p *ScheduledCommandsPlugin) handleUpcoming(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 1 || days > 30 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 30"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	c.JSON(http.StatusOK, gin.H{
+		"timezone": p.location().String(),
+		"runs":     p.upcoming(now, now.AddDate(0, 0, days)),
+	})
+}
+
+// handleStatus returns an overview of the jobs
+func (p *ScheduledCommandsPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	enabled, failing := 0, 0
+	for _, j := range p.jobs {
+		if j.Enabled {
+			enabled++
+		}
+		if n := len(j.History); n > 0 && !j.History[n-1].OK {
+			failing++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":            len(p.jobs),
+		"enabled":         enabled,
+		"running":         len(p.running),
+		"failing":         failing,
+		"timezone":        p.location().String(),
+		"allowed_methods": lists.Split(p.config.AllowedMethods),
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ScheduledCommandsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration. Only admins may change
+// it, since allowed_methods decides which calls jobs can make.
+func (p *ScheduledCommandsPlugin) handleUpdateConfig(c *gin.Context) {
+	if _, ok := access.RequireAdmin(c); !ok {
+		return
+	}
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.Timezone == "" {
+		newConfig.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(newConfig.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone " + newConfig.Timezone})
+		return
+	}
+	if newConfig.HistorySize < 1 || newConfig.HistorySize > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "history_size must be between 1 and 1000"})
+		return
+	}
+	if newConfig.TimeoutSeconds < 5 || newConfig.TimeoutSeconds > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeout_seconds must be between 5 and 3600"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	tzChanged := newConfig.Timezone != p.config.Timezone
+	// Jobs and upcoming runs are written to a new data_dir on the next save
+	if newConfig.DataDir != p.config.DataDir {
+		p.dirty = true
+	}
+	p.config = newConfig
+	p.rpc = nil
+	// Schedules are read in the configured timezone
+	if tzChanged {
+		now := time.Now()
+		for _, j := range p.jobs {
+			p.reschedule(j, now)
+		}
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ScheduledCommandsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ScheduledCommandsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,78 @@
+package scheduledcommands
+
+import (
+	"sort"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+const (
+	// upcomingHorizon is how far ahead runs are published
+	upcomingHorizon = 30 * 24 * time.Hour
+	// maxOccurrences is the most runs listed per job, so a job that runs
+	// every minute does not crowd out the rest
+	maxOccurrences = 100
+	// publishInterval is how often the upcoming runs are published again
+	// when no job changed, to keep the horizon moving
+	publishInterval = time.Hour
+)
+
+// Occurrence is an upcoming run of a job
+type Occurrence struct {
+	JobID  string    `json:"job_id"`
+	Name   string    `json:"name"`
+	Action string    `json:"action"`
+	Method string    `json:"method,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// upcomingData is the file other plugins read the upcoming runs from
+type upcomingData struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Until       time.Time    `json:"until"`
+	Timezone    string       `json:"timezone"`
+	Occurrences []Occurrence `json:"occurrences"`
+}
+
+// upcoming returns the runs of enabled jobs between from and until, in
+// time order. Caller must hold p.mu.
+func (p *ScheduledCommandsPlugin) upcoming(from, until time.Time) []Occurrence {
+	loc := p.location()
+	list := make([]Occurrence, 0)
+	for _, j := range p.jobs {
+		if !j.Enabled {
+			continue
+		}
+		s, err := parseSchedule(j.Schedule)
+		if err != nil {
+			continue
+		}
+		t := from.In(loc)
+		for n := 0; n < maxOccurrences; n++ {
+			if t = s.next(t); t.IsZero() || t.After(until) {
+				break
+			}
+			list = append(list, Occurrence{JobID: j.ID, Name: j.Name, Action: j.Action, Method: j.Method, Time: t.UTC()})
+		}
+	}
+	sort.Slice(list, func(i, k int) bool { return list[i].Time.Before(list[k].Time) })
+	return list
+}
+
+// publish writes the upcoming runs for other plugins, such as the
+// maintenance announcer's calendar. Caller must hold p.mu.
+func (p *ScheduledCommandsPlugin) publish(now time.Time) error {
+	until := now.Add(upcomingHorizon)
+	data := upcomingData{
+		GeneratedAt: now.UTC(),
+		Until:       until.UTC(),
+		Timezone:    p.location().String(),
+		Occurrences: p.upcoming(now, until),
+	}
+	if err := storage.SaveJSON(p.upcomingPath(), data); err != nil {
+		return err
+	}
+	p.published = now
+	return nil
+}
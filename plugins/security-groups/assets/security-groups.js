@@ -0,0 +1,313 @@
+/**
+ * Security Groups Frontend Script
+ *
+ * Shows security groups with their rules and members, WEBIRC gateways and
+ * group sizes over time.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'security-groups';
+  const PLUGIN_NAME = 'Security Groups';
+  const PAGE_PATH = '/plugins/security-groups';
+  const API_BASE = '/api/plugin/security-groups';
+
+  const COLORS = ['#89b4fa', '#a6e3a1', '#f9e2af', '#f38ba8', '#cba6f7', '#fab387', '#94e2d5', '#f5c2e7'];
+
+  let selectedGroup = '';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  // One line per group over the sampled period
+  function historyChart(samples, groups, width, height) {
+    if (samples.length < 2) {
+      return '<p>Not enough samples yet.</p>';
+    }
+    const max = Math.max(...samples.map(s => Math.max(0, ...groups.map(g => s.counts[g] || 0))), 1);
+    const step = width / (samples.length - 1);
+    const lines = groups.map((g, gi) => {
+      const points = samples.map((s, i) => `${(i * step).toFixed(1)},${(height - ((s.counts[g] || 0) / max) * (height - 10)).toFixed(1)}`).join(' ');
+      return `<polyline points="${points}" fill="none" stroke="${COLORS[gi % COLORS.length]}" stroke-width="2"><title>${escapeHtml(g)}</title></polyline>`;
+    });
+    return `
+      <svg viewBox="0 0 ${width} ${height}" class="secg-chart" preserveAspectRatio="none">${lines.join('')}</svg>
+      <div class="secg-legend">
+        ${groups.map((g, gi) => `<span><i style="background: ${COLORS[gi % COLORS.length]}"></i>${escapeHtml(g)}</span>`).join('')}
+      </div>
+    `;
+  }
+
+  function ruleText(r) {
+    let text = r.key;
+    if (r.value) text += ' ' + r.value;
+    if (r.values && r.values.length) text += ` { ${r.values.join('; ')}; }`;
+    return text;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('security-groups-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'security-groups-styles';
+    style.textContent = `
+      .secg-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .secg-groups { display: grid; grid-template-columns: repeat(auto-fill, minmax(240px, 1fr)); gap: 0.75rem; }
+      .secg-group {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        padding: 0.75rem;
+        cursor: pointer;
+      }
+      .secg-group.active { border-color: var(--accent, #89b4fa); }
+      .secg-group h4 { margin: 0 0 0.25rem; color: var(--text-primary, #cdd6f4); }
+      .secg-group strong { font-size: 1.4rem; color: var(--text-primary, #cdd6f4); }
+      .secg-group ul { margin: 0.5rem 0 0; padding-left: 1rem; font-size: 0.8rem; }
+      .secg-meta { font-size: 0.75rem; color: var(--text-muted, #6c7086); }
+      .secg-chart { width: 100%; height: 160px; background: var(--bg-secondary, #181825); border-radius: 8px; }
+      .secg-legend { display: flex; flex-wrap: wrap; gap: 0.75rem; font-size: 0.8rem; }
+      .secg-legend i { display: inline-block; width: 10px; height: 10px; border-radius: 2px; margin-right: 0.3rem; }
+      .secg-table { width: 100%; border-collapse: collapse; }
+      .secg-table th, .secg-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        overflow-wrap: anywhere;
+      }
+      .secg-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .secg-warning { color: var(--warning, #f9e2af); }
+      .secg-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#secg-status');
+    try {
+      const s = await api('GET', '/status');
+      body.innerHTML = `
+        ${s.config_error ? `<div class="secg-error">Could not read ${escapeHtml(s.config_file)}: ${escapeHtml(s.config_error)}</div>` : ''}
+        ${s.last_error ? `<div class="secg-error">Could not read the user list: ${escapeHtml(s.last_error)}</div>` : ''}
+        ${(s.warnings || []).map(w => `<div class="secg-warning">${escapeHtml(w)}</div>`).join('')}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="secg-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadGroups(container) {
+    const body = container.querySelector('#secg-groups');
+    try {
+      const data = await api('GET', '/groups');
+      body.innerHTML = `
+        <div class="secg-groups">
+          ${data.groups.map(g => `
+            <div class="secg-group ${g.name === selectedGroup ? 'active' : ''}" data-group="${escapeHtml(g.name)}">
+              <h4>${escapeHtml(g.name)}</h4>
+              <strong>${g.users}</strong> of ${data.users} users
+              <div class="secg-meta">
+                ${g.built_in ? 'built-in' : ''}${g.built_in && g.defined ? ', ' : ''}${g.defined ? `${escapeHtml(g.file)}:${g.line}` : ''}
+                ${!g.built_in && !g.defined ? 'not in the readable configuration' : ''}
+              </div>
+              ${g.rules.length ? `<ul>${g.rules.map(r => `<li><code>${escapeHtml(ruleText(r))}</code></li>`).join('')}</ul>` : ''}
+            </div>
+          `).join('')}
+        </div>
+      `;
+      body.querySelectorAll('[data-group]').forEach(el => {
+        el.addEventListener('click', () => {
+          selectedGroup = selectedGroup === el.dataset.group ? '' : el.dataset.group;
+          body.querySelectorAll('[data-group]').forEach(o => o.classList.toggle('active', o.dataset.group === selectedGroup));
+          loadUsers(container);
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="secg-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadHistory(container) {
+    const body = container.querySelector('#secg-history');
+    try {
+      const [history, groups] = await Promise.all([api('GET', '/history?hours=168'), api('GET', '/groups')]);
+      const names = groups.groups.filter(g => g.users > 0 || g.built_in).map(g => g.name);
+      body.innerHTML = historyChart(history.samples, names, 600, 160);
+    } catch (e) {
+      body.innerHTML = `<div class="secg-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadGateways(container) {
+    const body = container.querySelector('#secg-gateways');
+    try {
+      const data = await api('GET', '/gateways');
+      if (data.gateways.length === 0) {
+        body.innerHTML = '<p>No WEBIRC gateways configured.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="secg-table">
+          <thead><tr><th>Masks</th><th>Type</th><th>Authentication</th><th>Defined in</th></tr></thead>
+          <tbody>
+            ${data.gateways.map(g => `
+              <tr>
+                <td>${g.masks.map(m => `<code>${escapeHtml(m)}</code>`).join(' ')}</td>
+                <td>${escapeHtml(g.type)}</td>
+                <td>${escapeHtml(g.auth)}</td>
+                <td>${escapeHtml(g.file)}:${g.line}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="secg-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadUsers(container) {
+    const body = container.querySelector('#secg-users');
+    const title = container.querySelector('#secg-users-title');
+    title.textContent = selectedGroup ? `Users in ${selectedGroup}` : 'Connected users';
+    try {
+      const data = await api('GET', `/users?limit=500&group=${encodeURIComponent(selectedGroup)}`);
+      body.innerHTML = `
+        <p>${data.total} users${data.total > data.users.length ? `, showing ${data.users.length}` : ''}.</p>
+        <table class="secg-table">
+          <thead><tr><th>Nick</th><th>IP</th><th>Host</th><th>Account</th><th>Groups</th></tr></thead>
+          <tbody>
+            ${data.users.map(u => `
+              <tr>
+                <td>${escapeHtml(u.nick)}</td>
+                <td><code>${escapeHtml(u.ip)}</code></td>
+                <td>${escapeHtml(u.host)}</td>
+                <td>${escapeHtml(u.account || '')}</td>
+                <td>${escapeHtml(u.groups.join(', '))}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="secg-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function loadAll(container) {
+    loadStatus(container);
+    loadGroups(container);
+    loadHistory(container);
+    loadGateways(container);
+    loadUsers(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="secg-app" data-plugin="${PLUGIN_ID}">
+        <div><button id="secg-reload">Reload configuration and users</button></div>
+        <div id="secg-status"></div>
+        <h3>Security groups</h3>
+        <div id="secg-groups">Loading...</div>
+        <h3>Group sizes (7 days)</h3>
+        <div id="secg-history">Loading...</div>
+        <h3>WEBIRC gateways</h3>
+        <div id="secg-gateways">Loading...</div>
+        <h3 id="secg-users-title">Connected users</h3>
+        <div id="secg-users">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#secg-reload').addEventListener('click', async () => {
+      try {
+        await api('POST', '/reload');
+        loadAll(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    loadAll(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('security-groups-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
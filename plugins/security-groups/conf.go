@@ -0,0 +1,210 @@
+package securitygroups
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// confEntry is one item of an unrealircd.conf style file:
+//
+//	name value { child; child value; };
+type confEntry struct {
+	Name     string
+	Value    string
+	Children []*confEntry
+	File     string
+	Line     int
+}
+
+// confToken is a word, quoted string or punctuation with its line number
+type confToken struct {
+	text   string
+	quoted bool
+	line   int
+}
+
+// tokenizeConf splits a config file into tokens, dropping # // and /* */
+// comments
+func tokenizeConf(text string) ([]confToken, error) {
+	tokens := make([]confToken, 0)
+	line := 1
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(text[i:], "//"):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(text[i:i+2+end], "\n")
+			i += end + 4
+		case c == '{' || c == '}' || c == ';':
+			tokens = append(tokens, confToken{text: string(c), line: line})
+			i++
+		case c == '"':
+			start := line
+			var b strings.Builder
+			i++
+			for i < len(text) && text[i] != '"' {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				if text[i] == '\n' {
+					line++
+				}
+				b.WriteByte(text[i])
+				i++
+			}
+			if i >= len(text) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			i++
+			tokens = append(tokens, confToken{text: b.String(), quoted: true, line: start})
+		default:
+			start := i
+			for i < len(text) && !strings.ContainsRune(" \t\r\n{};\"", rune(text[i])) {
+				i++
+			}
+			tokens = append(tokens, confToken{text: text[start:i], line: line})
+		}
+	}
+	return tokens, nil
+}
+
+// parseConf parses the entries of one config file
+func parseConf(file, text string) ([]*confEntry, error) {
+	tokens, err := tokenizeConf(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	pos := 0
+	entries, err := parseConfEntries(file, tokens, &pos, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return entries, nil
+}
+
+// parseConfEntries parses entries up to the end of the tokens or, inside a
+// block, up to the closing brace
+func parseConfEntries(file string, tokens []confToken, pos *int, inBlock bool) ([]*confEntry, error) {
+	entries := make([]*confEntry, 0)
+	for *pos < len(tokens) {
+		t := tokens[*pos]
+		if !t.quoted && t.text == "}" {
+			if !inBlock {
+				return nil, fmt.Errorf("line %d: unexpected }", t.line)
+			}
+			*pos++
+			// The closing brace is usually followed by a semicolon
+			if *pos < len(tokens) && !tokens[*pos].quoted && tokens[*pos].text == ";" {
+				*pos++
+			}
+			return entries, nil
+		}
+		if !t.quoted && t.text == ";" {
+			*pos++
+			continue
+		}
+
+		e := &confEntry{Name: t.text, File: file, Line: t.line}
+		*pos++
+		for *pos < len(tokens) {
+			t = tokens[*pos]
+			if !t.quoted && t.text == ";" {
+				*pos++
+				break
+			}
+			if !t.quoted && t.text == "{" {
+				*pos++
+				children, err := parseConfEntries(file, tokens, pos, true)
+				if err != nil {
+					return nil, err
+				}
+				e.Children = children
+				break
+			}
+			if !t.quoted && t.text == "}" {
+				break
+			}
+			if e.Value == "" {
+				e.Value = t.text
+			} else {
+				e.Value += " " + t.text
+			}
+			*pos++
+		}
+		entries = append(entries, e)
+	}
+	if inBlock {
+		return nil, fmt.Errorf("unexpected end of file, missing }")
+	}
+	return entries, nil
+}
+
+// loadConf reads a config file and the files it includes. Remote includes
+// cannot be read and are reported as warnings.
+func loadConf(path string) ([]*confEntry, []string, error) {
+	warnings := make([]string, 0)
+	seen := make(map[string]bool)
+	var load func(file string) ([]*confEntry, error)
+	load = func(file string) ([]*confEntry, error) {
+		if seen[file] {
+			return nil, nil
+		}
+		seen[file] = true
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := parseConf(file, string(data))
+		if err != nil {
+			return nil, err
+		}
+
+		all := make([]*confEntry, 0, len(entries))
+		for _, e := range entries {
+			if e.Name != "include" {
+				all = append(all, e)
+				continue
+			}
+			if strings.Contains(e.Value, "://") {
+				warnings = append(warnings, fmt.Sprintf("%s:%d: remote include %s skipped", e.File, e.Line, e.Value))
+				continue
+			}
+			target := e.Value
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			matches, _ := filepath.Glob(target)
+			if len(matches) == 0 {
+				warnings = append(warnings, fmt.Sprintf("%s:%d: include %s not found", e.File, e.Line, e.Value))
+				continue
+			}
+			for _, m := range matches {
+				included, err := load(m)
+				if err != nil {
+					warnings = append(warnings, fmt.Sprintf("%s:%d: %v", e.File, e.Line, err))
+					continue
+				}
+				all = append(all, included...)
+			}
+		}
+		return all, nil
+	}
+
+	entries, err := load(path)
+	return entries, warnings, err
+}
@@ -0,0 +1,590 @@
+// Security Groups Plugin for UnrealIRCd Web Panel
+// Shows the security groups and WEBIRC gateways configured on the IRCd,
+// which group each connected user is in and how group sizes change
+
+package securitygroups
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// builtInGroups exist on every UnrealIRCd server, whether or not the
+// configuration mentions them
+var builtInGroups = []string{"known-users", "unknown-users", "websocket-users", "tls-users", "tls-and-known-users"}
+
+// SecurityGroupsPlugin implements the Plugin interface
+type SecurityGroupsPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	groups    []*SecurityGroup
+	gateways  []*Gateway
+	warnings  []string
+	confError string
+	users     []*GroupUser
+	samples   []*Sample
+	dirty     bool
+	lastPoll  time.Time
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL       string `json:"rpc_url"`
+	RPCUser      string `json:"rpc_user"`
+	RPCPassword  string `json:"rpc_password"`
+	RPCInsecure  bool   `json:"rpc_insecure"`
+	DataDir      string `json:"data_dir"`
+	ConfigFile   string `json:"config_file"`
+	PollInterval int    `json:"poll_interval"`
+	HistoryDays  int    `json:"history_days"`
+}
+
+// Rule is one criterion of a security group block
+type Rule struct {
+	Key    string   `json:"key"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// SecurityGroup is a security group with its rules and current size
+type SecurityGroup struct {
+	Name    string `json:"name"`
+	BuiltIn bool   `json:"built_in"`
+	Defined bool   `json:"defined"`
+	Rules   []Rule `json:"rules"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Users   int    `json:"users"`
+}
+
+// Gateway is a configured WEBIRC block. The password is never exposed.
+type Gateway struct {
+	Masks []string `json:"masks"`
+	Type  string   `json:"type"`
+	Auth  string   `json:"auth"`
+	File  string   `json:"file"`
+	Line  int      `json:"line"`
+}
+
+// GroupUser is a connected user and the groups they are in
+type GroupUser struct {
+	Nick    string   `json:"nick"`
+	IP      string   `json:"ip"`
+	Host    string   `json:"host"`
+	Account string   `json:"account,omitempty"`
+	Groups  []string `json:"groups"`
+}
+
+// Sample is the number of users per group at one point in time
+type Sample struct {
+	Time   time.Time      `json:"time"`
+	Total  int            `json:"total"`
+	Counts map[string]int `json:"counts"`
+}
+
+// rpcUser is the subset of the UnrealIRCd client object we need
+type rpcUser struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	User     struct {
+		Account        string   `json:"account"`
+		SecurityGroups []string `json:"security-groups"`
+	} `json:"user"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &SecurityGroupsPlugin{
+		config: Config{
+			RPCURL:       "https://127.0.0.1:8600/api",
+			DataDir:      "data/plugins/security-groups",
+			ConfigFile:   "/home/ircd/unrealircd/conf/unrealircd.conf",
+			PollInterval: 300,
+			HistoryDays:  7,
+		},
+		groups:   make([]*SecurityGroup, 0),
+		gateways: make([]*Gateway, 0),
+		warnings: make([]string, 0),
+		users:    make([]*GroupUser, 0),
+		samples:  make([]*Sample, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *SecurityGroupsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Security Groups",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Security group and WEBIRC gateway viewer",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *SecurityGroupsPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.samples); err != nil {
+		log.Printf("[security-groups] failed to load history: %v", err)
+	}
+	if p.samples == nil {
+		p.samples = make([]*Sample, 0)
+	}
+	p.mu.Unlock()
+
+	p.readConfigFile()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "security-groups-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		counts := make(map[string]int)
+		for _, g := range p.groups {
+			counts[g.Name] = g.Users
+		}
+		return plugins.DashboardCard{
+			Title: "Security Groups",
+			Icon:  "ShieldCheck",
+			Content: map[string]interface{}{
+				"known_users":   counts["known-users"],
+				"unknown_users": counts["unknown-users"],
+				"groups":        len(p.groups),
+				"gateways":      len(p.gateways),
+			},
+			Order: 53,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *SecurityGroupsPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *SecurityGroupsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/security-groups")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/groups", p.handleGroups)
+		plugin.GET("/gateways", p.handleGateways)
+		plugin.GET("/users", p.handleUsers)
+		plugin.GET("/history", p.handleHistory)
+		plugin.POST("/reload", p.handleReload)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *SecurityGroupsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "history.json")
+}
+
+// save persists the group size history if it changed
+func (p *SecurityGroupsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.samples); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *SecurityGroupsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop reads the configuration and the user list until shutdown
+func (p *SecurityGroupsPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.poll()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 30*time.Second {
+			interval = 30 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.readConfigFile()
+			p.poll()
+			if err := p.save(); err != nil {
+				log.Printf("[security-groups] failed to save history: %v", err)
+			}
+		}
+	}
+}
+
+// readConfigFile parses the security-group and webirc blocks of the IRCd
+// configuration
+func (p *SecurityGroupsPlugin) readConfigFile() {
+	p.mu.RLock()
+	path := p.config.ConfigFile
+	p.mu.RUnlock()
+
+	groups := make([]*SecurityGroup, 0)
+	gateways := make([]*Gateway, 0)
+	entries, warnings, err := loadConf(path)
+	for _, e := range entries {
+		switch e.Name {
+		case "security-group":
+			groups = append(groups, securityGroup(e))
+		case "webirc":
+			gateways = append(gateways, gateway(e))
+		}
+	}
+
+	// Built-in groups are listed even when the configuration leaves them
+	// at their defaults
+	for _, name := range builtInGroups {
+		found := false
+		for _, g := range groups {
+			if g.Name == name {
+				g.BuiltIn = true
+				found = true
+			}
+		}
+		if !found {
+			groups = append(groups, &SecurityGroup{Name: name, BuiltIn: true, Rules: make([]Rule, 0)})
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.confError = ""
+	if err != nil {
+		p.confError = err.Error()
+		log.Printf("[security-groups] failed to read %s: %v", path, err)
+	}
+	p.warnings = warnings
+	p.groups = groups
+	p.gateways = gateways
+	p.countUsers()
+}
+
+// securityGroup converts a security-group block
+func securityGroup(e *confEntry) *SecurityGroup {
+	g := &SecurityGroup{Name: e.Value, Defined: true, Rules: make([]Rule, 0, len(e.Children)), File: e.File, Line: e.Line}
+	for _, c := range e.Children {
+		r := Rule{Key: c.Name, Value: c.Value}
+		for _, v := range c.Children {
+			item := v.Name
+			if v.Value != "" {
+				item += " " + v.Value
+			}
+			r.Values = append(r.Values, item)
+		}
+		g.Rules = append(g.Rules, r)
+	}
+	return g
+}
+
+// gateway converts a webirc block, keeping only how it authenticates
+func gateway(e *confEntry) *Gateway {
+	gw := &Gateway{Masks: make([]string, 0), Type: "webirc", Auth: "none", File: e.File, Line: e.Line}
+	for _, c := range e.Children {
+		switch c.Name {
+		case "mask":
+			if c.Value != "" {
+				gw.Masks = append(gw.Masks, c.Value)
+			}
+			for _, m := range c.Children {
+				gw.Masks = append(gw.Masks, m.Name)
+			}
+		case "type":
+			gw.Type = c.Value
+		case "password":
+			gw.Auth = "password"
+			// password "..." { certfp; }; names the authentication type
+			if len(c.Children) > 0 {
+				gw.Auth = c.Children[0].Name
+			}
+		}
+	}
+	return gw
+}
+
+// poll fetches the groups of every connected user and records a sample
+func (p *SecurityGroupsPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		return
+	}
+	p.lastError = ""
+	p.lastPoll = time.Now()
+
+	users := make([]*GroupUser, 0, len(result.List))
+	for _, u := range result.List {
+		if u.IP == "" {
+			continue
+		}
+		groups := u.User.SecurityGroups
+		if groups == nil {
+			groups = make([]string, 0)
+		}
+		users = append(users, &GroupUser{Nick: u.Name, IP: u.IP, Host: u.Hostname, Account: u.User.Account, Groups: groups})
+	}
+	p.users = users
+	counts := p.countUsers()
+
+	p.samples = append(p.samples, &Sample{Time: p.lastPoll.UTC(), Total: len(users), Counts: counts})
+	days := p.config.HistoryDays
+	if days < 1 {
+		days = 1
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	drop := sort.Search(len(p.samples), func(i int) bool { return !p.samples[i].Time.Before(cutoff) })
+	if drop > 0 {
+		p.samples = append([]*Sample(nil), p.samples[drop:]...)
+	}
+	p.dirty = true
+}
+
+// countUsers updates the user count of each group and returns the counts.
+// Groups users are in but that are not in the configuration are added.
+// Caller must hold p.mu.
+func (p *SecurityGroupsPlugin) countUsers() map[string]int {
+	counts := make(map[string]int)
+	for _, u := range p.users {
+		for _, g := range u.Groups {
+			counts[g]++
+		}
+	}
+	known := make(map[string]bool)
+	for _, g := range p.groups {
+		g.Users = counts[g.Name]
+		known[g.Name] = true
+	}
+	for name, n := range counts {
+		if !known[name] {
+			// Defined by a module or an included file we could not read
+			p.groups = append(p.groups, &SecurityGroup{Name: name, Rules: make([]Rule, 0), Users: n})
+		}
+	}
+	return counts
+}
+
+// handleStatus reports the state of the config file and user list reads
+func (p *SecurityGroupsPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"config_file":  p.config.ConfigFile,
+		"config_error": p.confError,
+		"warnings":     p.warnings,
+		"last_poll":    p.lastPoll,
+		"last_error":   p.lastError,
+		"users":        len(p.users),
+	})
+}
+
+// handleGroups returns all security groups, largest first
+func (p *SecurityGroupsPlugin) handleGroups(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*SecurityGroup, len(p.groups))
+	copy(list, p.groups)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Users > list[j].Users })
+	c.JSON(http.StatusOK, gin.H{"groups": list, "users": len(p.users)})
+}
+
+// handleGateways returns the configured WEBIRC gateways
+func (p *SecurityGroupsPlugin) handleGateways(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"gateways": p.gateways})
+}
+
+// handleUsers returns connected users and their groups, optionally only
+// those in one group
+func (p *SecurityGroupsPlugin) handleUsers(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	group := c.Query("group")
+	q := strings.ToLower(c.Query("q"))
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*GroupUser, 0)
+	total := 0
+	for _, u := range p.users {
+		if group != "" && !containsString(u.Groups, group) {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(u.Nick), q) && !strings.Contains(u.IP, q) &&
+			!strings.Contains(strings.ToLower(u.Host), q) && !strings.Contains(strings.ToLower(u.Account), q) {
+			continue
+		}
+		total++
+		if len(list) < limit {
+			list = append(list, u)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"users": list, "total": total})
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// handleHistory returns group sizes over time
+func (p *SecurityGroupsPlugin) handleHistory(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be a positive number"})
+		return
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	start := sort.Search(len(p.samples), func(i int) bool { return p.samples[i].Time.After(since) })
+	c.JSON(http.StatusOK, gin.H{"hours": hours, "samples": p.samples[start:]})
+}
+
+// handleReload reads the config file and the user list straight away
+func (p *SecurityGroupsPlugin) handleReload(c *gin.Context) {
+	p.readConfigFile()
+	p.poll()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Reloaded",
+		"config_error": p.confError,
+		"last_error":   p.lastError,
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *SecurityGroupsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *SecurityGroupsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	p.readConfigFile()
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *SecurityGroupsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *SecurityGroupsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
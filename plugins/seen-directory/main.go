@@ -0,0 +1,551 @@
+// Seen Directory Plugin for UnrealIRCd Web Panel
+// Maintains a persistent first-seen/last-seen index keyed by account and
+// by host, for veteran-user verification and stale-access cleanup
+
+package seendirectory
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Index kinds
+const (
+	KindAccount = "account"
+	KindHost    = "host"
+	KindIP      = "ip"
+)
+
+// maxNicks is how many distinct nicks are remembered per entry
+const maxNicks = 10
+
+// SeenDirectoryPlugin implements the Plugin interface
+type SeenDirectoryPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	index     map[string]map[string]*Entry
+	dirty     bool
+	lastPoll  time.Time
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	DataDir       string `json:"data_dir"`
+	PollInterval  int    `json:"poll_interval"`
+	IndexHosts    bool   `json:"index_hosts"`
+	RetentionDays int    `json:"retention_days"`
+}
+
+// Entry records when an account, host or IP was first and last seen
+type Entry struct {
+	Key       string    `json:"key"`
+	Kind      string    `json:"kind"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	LastNick  string    `json:"last_nick"`
+	Nicks     []string  `json:"nicks"`
+}
+
+// sighting is a single observation of a client
+type sighting struct {
+	nick    string
+	account string
+	host    string
+	ip      string
+	first   time.Time
+	last    time.Time
+}
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	Name           string `json:"name"`
+	Hostname       string `json:"hostname"`
+	IP             string `json:"ip"`
+	ConnectedSince string `json:"connected_since"`
+	User           struct {
+		Account string `json:"account"`
+	} `json:"user"`
+}
+
+// logEntry is the subset of an UnrealIRCd JSON log line we need
+type logEntry struct {
+	Timestamp string `json:"timestamp"`
+	EventID   string `json:"event_id"`
+	Client    *struct {
+		Name     string `json:"name"`
+		Hostname string `json:"hostname"`
+		IP       string `json:"ip"`
+		User     struct {
+			Account string `json:"account"`
+		} `json:"user"`
+	} `json:"client"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &SeenDirectoryPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/seen-directory",
+			PollInterval:  120,
+			IndexHosts:    true,
+			RetentionDays: 0,
+		},
+		index: newIndex(),
+	}
+}
+
+// newIndex creates an empty index for every kind
+func newIndex() map[string]map[string]*Entry {
+	return map[string]map[string]*Entry{
+		KindAccount: make(map[string]*Entry),
+		KindHost:    make(map[string]*Entry),
+		KindIP:      make(map[string]*Entry),
+	}
+}
+
+// Info returns plugin metadata
+func (p *SeenDirectoryPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Seen Directory",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "First-seen and last-seen index by account and host",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *SeenDirectoryPlugin) Init() error {
+	p.mu.Lock()
+	stored := newIndex()
+	if err := storage.LoadJSON(p.storePath(), &stored); err != nil {
+		log.Printf("[seen-directory] failed to load index: %v", err)
+	}
+	for kind, entries := range stored {
+		if entries != nil {
+			p.index[kind] = entries
+		}
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add first/last seen data to user lookups
+	hm.Register(hooks.HookUserLookup, "seen-directory-lookup", func(args interface{}) interface{} {
+		m, ok := args.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		result := make(map[string]interface{})
+		if account, ok := m["account"].(string); ok && account != "" {
+			if e := p.index[KindAccount][strings.ToLower(account)]; e != nil {
+				result["account_first_seen"] = e.FirstSeen
+			}
+		}
+		if ip, ok := m["ip"].(string); ok && ip != "" {
+			if e := p.index[KindIP][ip]; e != nil {
+				result["ip_first_seen"] = e.FirstSeen
+			}
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		return result
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *SeenDirectoryPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *SeenDirectoryPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/seen-directory")
+	{
+		plugin.GET("/lookup", p.handleLookup)
+		plugin.GET("/stale", p.handleStale)
+		plugin.GET("/stats", p.handleStats)
+		plugin.POST("/import", p.handleImport)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the index file
+func (p *SeenDirectoryPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "seen.json")
+}
+
+// save persists the index if it changed
+func (p *SeenDirectoryPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.index); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *SeenDirectoryPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop records connected users until shutdown
+func (p *SeenDirectoryPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.poll()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 30*time.Second {
+			interval = 30 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.poll()
+			if err := p.save(); err != nil {
+				log.Printf("[seen-directory] failed to save index: %v", err)
+			}
+		}
+	}
+}
+
+// poll records every connected user as seen now
+func (p *SeenDirectoryPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		return
+	}
+	p.lastError = ""
+	now := time.Now()
+	p.lastPoll = now
+
+	for _, u := range result.List {
+		first, err := time.Parse(time.RFC3339, u.ConnectedSince)
+		if err != nil {
+			first = now
+		}
+		p.record(sighting{
+			nick:    u.Name,
+			account: u.User.Account,
+			host:    u.Hostname,
+			ip:      u.IP,
+			first:   first,
+			last:    now,
+		})
+	}
+	p.prune(now)
+}
+
+// record folds a sighting into the index. Caller must hold p.mu.
+func (p *SeenDirectoryPlugin) record(s sighting) {
+	if s.account != "" && s.account != "*" {
+		p.touch(KindAccount, strings.ToLower(s.account), s)
+	}
+	if p.config.IndexHosts {
+		if s.host != "" {
+			p.touch(KindHost, strings.ToLower(s.host), s)
+		}
+		if s.ip != "" && s.ip != s.host {
+			p.touch(KindIP, s.ip, s)
+		}
+	}
+}
+
+// touch updates a single index entry. Caller must hold p.mu.
+func (p *SeenDirectoryPlugin) touch(kind, key string, s sighting) {
+	e, ok := p.index[kind][key]
+	if !ok {
+		e = &Entry{
+			Key:       key,
+			Kind:      kind,
+			FirstSeen: s.first,
+			LastSeen:  s.last,
+			Nicks:     make([]string, 0, 1),
+		}
+		p.index[kind][key] = e
+	}
+	if s.first.Before(e.FirstSeen) {
+		e.FirstSeen = s.first
+	}
+	if !s.last.Before(e.LastSeen) {
+		e.LastSeen = s.last
+		e.LastNick = s.nick
+	}
+	if s.nick != "" && !lists.ContainsFold(e.Nicks, s.nick) {
+		e.Nicks = append(e.Nicks, s.nick)
+		if len(e.Nicks) > maxNicks {
+			e.Nicks = e.Nicks[len(e.Nicks)-maxNicks:]
+		}
+	}
+	p.dirty = true
+}
+
+// prune drops entries not seen within the retention period. Caller must hold p.mu.
+func (p *SeenDirectoryPlugin) prune(now time.Time) {
+	if p.config.RetentionDays <= 0 {
+		return
+	}
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays)
+	for _, entries := range p.index {
+		for key, e := range entries {
+			if e.LastSeen.Before(cutoff) {
+				delete(entries, key)
+				p.dirty = true
+			}
+		}
+	}
+}
+
+// handleLookup returns the entries matching an account, host or IP
+func (p *SeenDirectoryPlugin) handleLookup(c *gin.Context) {
+	queries := map[string]string{
+		KindAccount: strings.ToLower(c.Query("account")),
+		KindHost:    strings.ToLower(c.Query("host")),
+		KindIP:      c.Query("ip"),
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	results := make(map[string]*Entry)
+	asked := false
+	for kind, key := range queries {
+		if key == "" {
+			continue
+		}
+		asked = true
+		if e, ok := p.index[kind][key]; ok {
+			results[kind] = e
+		}
+	}
+
+	if !asked {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Specify account, host or ip"})
+		return
+	}
+	if len(results) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Never seen"})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// handleStale lists accounts not seen for a number of days, oldest first
+func (p *SeenDirectoryPlugin) handleStale(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "180"))
+	if err != nil || days < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive number"})
+		return
+	}
+	kind := c.DefaultQuery("kind", KindAccount)
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	entries, ok := p.index[kind]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be account, host or ip"})
+		return
+	}
+
+	list := make([]*Entry, 0)
+	for _, e := range entries {
+		if e.LastSeen.Before(cutoff) {
+			list = append(list, e)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeen.Before(list[j].LastSeen)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": list,
+		"count":   len(list),
+		"days":    days,
+	})
+}
+
+// handleStats returns the size of the index
+func (p *SeenDirectoryPlugin) handleStats(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts":   len(p.index[KindAccount]),
+		"hosts":      len(p.index[KindHost]),
+		"ips":        len(p.index[KindIP]),
+		"last_poll":  p.lastPoll,
+		"last_error": p.lastError,
+	})
+}
+
+// handleImport bulk-imports sightings from an UnrealIRCd JSON log. The log
+// is sent as the request body, one JSON object per line.
+func (p *SeenDirectoryPlugin) handleImport(c *gin.Context) {
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	imported, skipped := 0, 0
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for scanner.Scan() {
+		var entry logEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Client == nil {
+			skipped++
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		if err != nil {
+			skipped++
+			continue
+		}
+		p.record(sighting{
+			nick:    entry.Client.Name,
+			account: entry.Client.User.Account,
+			host:    entry.Client.Hostname,
+			ip:      entry.Client.IP,
+			first:   ts,
+			last:    ts,
+		})
+		imported++
+	}
+
+	if err := scanner.Err(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "imported": imported})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Import complete",
+		"imported": imported,
+		"skipped":  skipped,
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *SeenDirectoryPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *SeenDirectoryPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *SeenDirectoryPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *SeenDirectoryPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,539 @@
+// Server Inventory Plugin for UnrealIRCd Web Panel
+// Collects the UnrealIRCd version and loaded modules of every linked server
+// and highlights outdated versions, missing modules and module drift
+
+package serverinventory
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// ServerInventoryPlugin implements the Plugin interface
+type ServerInventoryPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	inventory *Inventory
+	lastError string
+	refresh   chan struct{}
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL          string `json:"rpc_url"`
+	RPCUser         string `json:"rpc_user"`
+	RPCPassword     string `json:"rpc_password"`
+	RPCInsecure     bool   `json:"rpc_insecure"`
+	RefreshInterval int    `json:"refresh_interval"`
+	MinVersion      string `json:"min_version"`
+	RequiredModules string `json:"required_modules"`
+	SkipServices    bool   `json:"skip_services"`
+}
+
+// Module is a module loaded on a server
+type Module struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Author     string `json:"author"`
+	ThirdParty bool   `json:"third_party"`
+}
+
+// Server is the inventory of a single server
+type Server struct {
+	Name           string   `json:"name"`
+	Software       string   `json:"software"`
+	Version        string   `json:"version"`
+	Outdated       bool     `json:"outdated"`
+	MissingModules []string `json:"missing_modules"`
+	ExtraModules   []string `json:"extra_modules"`
+	ModuleCount    int      `json:"module_count"`
+	Modules        []Module `json:"modules"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// ModuleDrift is a module that is not loaded everywhere, or not at the
+// same version everywhere
+type ModuleDrift struct {
+	Name      string            `json:"name"`
+	LoadedOn  []string          `json:"loaded_on"`
+	MissingOn []string          `json:"missing_on"`
+	Versions  map[string]string `json:"versions"`
+}
+
+// Inventory is the collected state of the whole network
+type Inventory struct {
+	Servers       []*Server     `json:"servers"`
+	TargetVersion string        `json:"target_version"`
+	Outdated      int           `json:"outdated"`
+	MissingTotal  int           `json:"missing_total"`
+	Drift         []ModuleDrift `json:"drift"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined   bool `json:"ulined"`
+		Features struct {
+			Software string `json:"software"`
+		} `json:"features"`
+	} `json:"server"`
+}
+
+// rpcModule is an entry of server.module_list
+type rpcModule struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Author     string `json:"author"`
+	ThirdParty bool   `json:"third_party"`
+}
+
+// versionPattern extracts the dotted version from a software string such
+// as "UnrealIRCd-6.1.4"
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)*`)
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ServerInventoryPlugin{
+		config: Config{
+			RPCURL:          "https://127.0.0.1:8600/api",
+			RefreshInterval: 600,
+			SkipServices:    true,
+		},
+		refresh: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ServerInventoryPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Server Inventory",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "UnrealIRCd version and module inventory across all servers",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ServerInventoryPlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "server-inventory-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"servers":         0,
+			"outdated":        0,
+			"missing_modules": 0,
+		}
+		if p.inventory != nil {
+			content["servers"] = len(p.inventory.Servers)
+			content["outdated"] = p.inventory.Outdated
+			content["missing_modules"] = p.inventory.MissingTotal
+		}
+		return plugins.DashboardCard{
+			Title:   "Server Versions",
+			Icon:    "Package",
+			Content: content,
+			Order:   33,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.refreshLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ServerInventoryPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ServerInventoryPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/server-inventory")
+	{
+		plugin.GET("/inventory", p.handleInventory)
+		plugin.GET("/servers/:name", p.handleGetServer)
+		plugin.GET("/drift", p.handleDrift)
+		plugin.POST("/refresh", p.handleRefresh)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *ServerInventoryPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// refreshLoop rebuilds the inventory until shutdown
+func (p *ServerInventoryPlugin) refreshLoop() {
+	defer p.wg.Done()
+
+	p.load()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.RefreshInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < time.Minute {
+			interval = time.Minute
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.refresh:
+			p.load()
+		case <-time.After(interval):
+			p.load()
+		}
+	}
+}
+
+// load queries every server for its version and modules
+func (p *ServerInventoryPlugin) load() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	rpc := p.client()
+	var list struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := rpc.Call(ctx, "server.list", nil, &list); err != nil {
+		p.mu.Lock()
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		log.Printf("[server-inventory] failed to list servers: %v", err)
+		return
+	}
+
+	servers := make([]*Server, 0, len(list.List))
+	for _, s := range list.List {
+		if s.Server.ULined && cfg.SkipServices {
+			continue
+		}
+		srv := &Server{
+			Name:     s.Name,
+			Software: s.Server.Features.Software,
+			Version:  versionPattern.FindString(s.Server.Features.Software),
+			Modules:  make([]Module, 0),
+		}
+
+		var mods struct {
+			List []rpcModule `json:"list"`
+		}
+		if err := rpc.Call(ctx, "server.module_list", map[string]interface{}{"server": s.Name}, &mods); err != nil {
+			srv.Error = err.Error()
+		} else {
+			for _, m := range mods.List {
+				srv.Modules = append(srv.Modules, Module(m))
+			}
+			sort.Slice(srv.Modules, func(i, j int) bool { return srv.Modules[i].Name < srv.Modules[j].Name })
+		}
+		srv.ModuleCount = len(srv.Modules)
+		servers = append(servers, srv)
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+
+	inv := analyze(servers, cfg.MinVersion, lists.Fields(cfg.RequiredModules))
+
+	p.mu.Lock()
+	p.inventory = inv
+	p.lastError = ""
+	p.mu.Unlock()
+}
+
+// analyze compares the servers against the target version, the required
+// modules and each other
+func analyze(servers []*Server, minVersion string, required []string) *Inventory {
+	inv := &Inventory{
+		Servers:   servers,
+		Drift:     make([]ModuleDrift, 0),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	// Without a configured minimum, the newest version on the network is the target
+	inv.TargetVersion = versionPattern.FindString(minVersion)
+	if inv.TargetVersion == "" {
+		for _, s := range servers {
+			if compareVersions(s.Version, inv.TargetVersion) > 0 {
+				inv.TargetVersion = s.Version
+			}
+		}
+	}
+
+	loaded := make(map[string]map[string]string)
+	polled := make([]string, 0, len(servers))
+	for _, s := range servers {
+		if s.Error != "" {
+			continue
+		}
+		polled = append(polled, s.Name)
+		for _, m := range s.Modules {
+			if loaded[m.Name] == nil {
+				loaded[m.Name] = make(map[string]string)
+			}
+			loaded[m.Name][s.Name] = m.Version
+		}
+	}
+
+	for _, s := range servers {
+		s.Outdated = s.Version != "" && compareVersions(s.Version, inv.TargetVersion) < 0
+		if s.Outdated {
+			inv.Outdated++
+		}
+
+		s.MissingModules = make([]string, 0)
+		s.ExtraModules = make([]string, 0)
+		if s.Error != "" {
+			continue
+		}
+		for _, name := range required {
+			if _, ok := loaded[name][s.Name]; !ok {
+				s.MissingModules = append(s.MissingModules, name)
+			}
+		}
+		inv.MissingTotal += len(s.MissingModules)
+
+		// Extra modules are ones that most of the other servers don't load
+		for _, m := range s.Modules {
+			if len(loaded[m.Name])*2 <= len(polled) && len(polled) > 1 {
+				s.ExtraModules = append(s.ExtraModules, m.Name)
+			}
+		}
+	}
+
+	for name, on := range loaded {
+		versions := make(map[string]bool)
+		for _, v := range on {
+			versions[v] = true
+		}
+		if len(on) == len(polled) && len(versions) <= 1 {
+			continue
+		}
+
+		d := ModuleDrift{
+			Name:      name,
+			LoadedOn:  make([]string, 0, len(on)),
+			MissingOn: make([]string, 0),
+			Versions:  on,
+		}
+		for _, srv := range polled {
+			if _, ok := on[srv]; ok {
+				d.LoadedOn = append(d.LoadedOn, srv)
+			} else {
+				d.MissingOn = append(d.MissingOn, srv)
+			}
+		}
+		inv.Drift = append(inv.Drift, d)
+	}
+	sort.Slice(inv.Drift, func(i, j int) bool { return inv.Drift[i].Name < inv.Drift[j].Name })
+
+	return inv
+}
+
+// compareVersions compares two dotted version strings numerically. An
+// empty version sorts before everything else.
+func compareVersions(a, b string) int {
+	pa := strings.Split(a, ".")
+	pb := strings.Split(b, ".")
+	if a == "" {
+		pa = nil
+	}
+	if b == "" {
+		pb = nil
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(pa[i])
+		} else {
+			x = -1
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(pb[i])
+		} else {
+			y = -1
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// currentInventory returns the inventory or writes an error response
+func (p *ServerInventoryPlugin) currentInventory(c *gin.Context) *Inventory {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.inventory == nil {
+		msg := "Inventory not collected yet"
+		if p.lastError != "" {
+			msg = p.lastError
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": msg})
+		return nil
+	}
+	return p.inventory
+}
+
+// handleInventory returns the inventory of all servers. Module lists are
+// left out unless modules=true.
+func (p *ServerInventoryPlugin) handleInventory(c *gin.Context) {
+	inv := p.currentInventory(c)
+	if inv == nil {
+		return
+	}
+	if c.Query("modules") == "true" {
+		c.JSON(http.StatusOK, inv)
+		return
+	}
+
+	servers := make([]Server, 0, len(inv.Servers))
+	for _, s := range inv.Servers {
+		brief := *s
+		brief.Modules = nil
+		servers = append(servers, brief)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"servers":        servers,
+		"target_version": inv.TargetVersion,
+		"outdated":       inv.Outdated,
+		"missing_total":  inv.MissingTotal,
+		"drift_count":    len(inv.Drift),
+		"updated_at":     inv.UpdatedAt,
+	})
+}
+
+// handleGetServer returns the full inventory of one server
+func (p *ServerInventoryPlugin) handleGetServer(c *gin.Context) {
+	inv := p.currentInventory(c)
+	if inv == nil {
+		return
+	}
+	for _, s := range inv.Servers {
+		if strings.EqualFold(s.Name, c.Param("name")) {
+			c.JSON(http.StatusOK, s)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+}
+
+// handleDrift returns modules that differ between servers
+func (p *ServerInventoryPlugin) handleDrift(c *gin.Context) {
+	inv := p.currentInventory(c)
+	if inv == nil {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"drift":      inv.Drift,
+		"updated_at": inv.UpdatedAt,
+	})
+}
+
+// handleRefresh schedules an immediate inventory run
+func (p *ServerInventoryPlugin) handleRefresh(c *gin.Context) {
+	select {
+	case p.refresh <- struct{}{}:
+	default:
+		// A refresh is already pending
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Refresh scheduled"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ServerInventoryPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ServerInventoryPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ServerInventoryPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ServerInventoryPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
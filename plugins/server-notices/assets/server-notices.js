@@ -0,0 +1,324 @@
+/**
+ * Server Notices Frontend Script
+ *
+ * Shows server notices live on the plugin page, like a bouncer window,
+ * with toggles per notice type and filters by server, nick and text.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'server-notices';
+  const PLUGIN_NAME = 'Server Notices';
+  const PAGE_PATH = '/plugins/server-notices';
+  const API_BASE = '/api/plugin/server-notices';
+  const MAX_LINES = 2000;
+  const TYPES = ['oper', 'kill', 'ban', 'spamfilter', 'link', 'flood', 'connect', 'nick', 'other'];
+
+  let controller = null;
+  let lastSeq = 0;
+  let paused = false;
+  let held = [];
+  let shownTypes = TYPES.filter(t => t !== 'connect' && t !== 'nick');
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    return new Date(ts).toLocaleTimeString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('server-notices-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'server-notices-styles';
+    style.textContent = `
+      .snt-app { display: flex; flex-direction: column; gap: 1rem; }
+      .snt-toolbar { display: flex; gap: 0.5rem; flex-wrap: wrap; align-items: end; }
+      .snt-toolbar label { display: flex; flex-direction: column; gap: 0.25rem; font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .snt-toolbar input {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .snt-btn, .snt-type {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .snt-type.active { background: var(--accent, #89b4fa); color: #fff; }
+      .snt-btn.active { background: var(--warning, #f9e2af); color: #000; }
+      .snt-state { font-size: 0.8rem; color: var(--text-muted, #6c7086); }
+      .snt-log {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        height: 60vh;
+        overflow-y: auto;
+        font-family: monospace;
+        font-size: 0.8rem;
+        padding: 0.5rem;
+      }
+      .snt-line { white-space: pre-wrap; word-break: break-word; color: var(--text-secondary, #a6adc8); }
+      .snt-line .time, .snt-line .server { color: var(--text-muted, #6c7086); }
+      .snt-line .type { color: var(--accent, #89b4fa); }
+      .snt-line.oper .type { color: var(--success, #a6e3a1); }
+      .snt-line.kill .type, .snt-line.ban .type { color: var(--error, #f38ba8); }
+      .snt-line.link .type, .snt-line.flood .type { color: var(--warning, #f9e2af); }
+      .snt-line.notice { color: var(--text-muted, #6c7086); font-style: italic; }
+      .snt-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function filterQuery(form) {
+    const params = new URLSearchParams();
+    params.set('type', shownTypes.join(','));
+    ['server', 'nick', 'q'].forEach(name => {
+      const value = form.elements[name].value.trim();
+      if (value) params.set(name, value);
+    });
+    return params;
+  }
+
+  function appendLines(log, html) {
+    const atBottom = log.scrollTop + log.clientHeight >= log.scrollHeight - 20;
+    log.insertAdjacentHTML('beforeend', html);
+    while (log.childElementCount > MAX_LINES) {
+      log.firstElementChild.remove();
+    }
+    if (atBottom) log.scrollTop = log.scrollHeight;
+  }
+
+  function renderNotice(n) {
+    return `<div class="snt-line ${escapeHtml(n.type)}" title="${escapeHtml(n.subsystem)}.${escapeHtml(n.event_id)}">` +
+      `<span class="time">${escapeHtml(formatTime(n.time))}</span> ` +
+      (n.server ? `<span class="server">${escapeHtml(n.server)}</span> ` : '') +
+      `<span class="type">[${escapeHtml(n.type)}]</span> ` +
+      `${escapeHtml(n.msg)}</div>`;
+  }
+
+  function notice(log, text) {
+    appendLines(log, `<div class="snt-line notice">${escapeHtml(text)}</div>`);
+  }
+
+  function handleEvent(container, event, data, id) {
+    const log = container.querySelector('#snt-log');
+    if (event === 'notice') {
+      const n = JSON.parse(data);
+      lastSeq = Number(id) || lastSeq;
+      if (paused) {
+        held.push(n);
+        if (held.length > MAX_LINES) held.shift();
+        container.querySelector('#snt-pause').textContent = `Resume (${held.length})`;
+      } else {
+        appendLines(log, renderNotice(n));
+      }
+    } else if (event === 'dropped') {
+      notice(log, `${JSON.parse(data).count} notices skipped because the page fell behind`);
+    }
+  }
+
+  // The panel token lives in localStorage, which EventSource can't send,
+  // so the stream is read with fetch and the events are parsed here.
+  async function connect(container) {
+    if (controller) controller.abort();
+    controller = new AbortController();
+    const signal = controller.signal;
+    const form = container.querySelector('#snt-filters');
+    const state = container.querySelector('#snt-state');
+
+    while (!signal.aborted) {
+      const params = filterQuery(form);
+      const headers = getAuthHeaders();
+      if (lastSeq) {
+        headers['Last-Event-ID'] = String(lastSeq);
+      } else {
+        params.set('backlog', '200');
+      }
+
+      try {
+        const res = await fetch(`${API_BASE}/stream?${params}`, { headers, signal });
+        if (!res.ok) {
+          const data = await res.json().catch(() => ({}));
+          throw new Error(data.error || `Request failed with status ${res.status}`);
+        }
+        state.textContent = 'Live';
+        state.classList.remove('snt-error');
+
+        const reader = res.body.getReader();
+        const decoder = new TextDecoder();
+        let buffer = '';
+        for (;;) {
+          const { value, done } = await reader.read();
+          if (done) break;
+          buffer += decoder.decode(value, { stream: true });
+          let end;
+          while ((end = buffer.indexOf('\n\n')) >= 0) {
+            const block = buffer.slice(0, end);
+            buffer = buffer.slice(end + 2);
+            let event = 'message', id = '', data = '';
+            block.split('\n').forEach(line => {
+              if (line.startsWith('event: ')) event = line.slice(7);
+              else if (line.startsWith('id: ')) id = line.slice(4);
+              else if (line.startsWith('data: ')) data += line.slice(6);
+            });
+            if (data) handleEvent(container, event, data, id);
+          }
+        }
+      } catch (e) {
+        if (signal.aborted) return;
+        state.textContent = e.message;
+        state.classList.add('snt-error');
+      }
+      if (signal.aborted) return;
+      state.textContent = 'Reconnecting...';
+      await new Promise(resolve => setTimeout(resolve, 5000));
+    }
+  }
+
+  function restart(container) {
+    lastSeq = 0;
+    held = [];
+    container.querySelector('#snt-log').innerHTML = '';
+    connect(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="snt-app" data-plugin="${PLUGIN_ID}">
+        <div class="snt-toolbar">
+          ${TYPES.map(t =>
+            `<button class="snt-type${shownTypes.includes(t) ? ' active' : ''}" data-type="${t}">${t}</button>`
+          ).join('')}
+        </div>
+        <form class="snt-toolbar" id="snt-filters">
+          <label>Servers<input name="server" placeholder="All servers"></label>
+          <label>Nicks<input name="nick" placeholder="Any nick"></label>
+          <label>Search<input name="q" placeholder="Text in the notice"></label>
+          <button class="snt-btn" type="submit">Apply</button>
+          <button class="snt-btn" type="button" id="snt-pause">Pause</button>
+          <button class="snt-btn" type="button" id="snt-clear">Clear</button>
+          <span class="snt-state" id="snt-state">Connecting...</span>
+        </form>
+        <div class="snt-log" id="snt-log"></div>
+      </div>
+    `;
+
+    const log = container.querySelector('#snt-log');
+
+    container.querySelectorAll('.snt-type').forEach(btn => {
+      btn.addEventListener('click', () => {
+        const type = btn.dataset.type;
+        shownTypes = shownTypes.includes(type) ? shownTypes.filter(t => t !== type) : shownTypes.concat(type);
+        btn.classList.toggle('active', shownTypes.includes(type));
+        if (shownTypes.length) {
+          restart(container);
+        } else if (controller) {
+          controller.abort();
+          container.querySelector('#snt-state').textContent = 'No notice types selected';
+        }
+      });
+    });
+
+    container.querySelector('#snt-filters').addEventListener('submit', (e) => {
+      e.preventDefault();
+      if (shownTypes.length) restart(container);
+    });
+
+    container.querySelector('#snt-pause').addEventListener('click', (e) => {
+      paused = !paused;
+      e.target.classList.toggle('active', paused);
+      e.target.textContent = paused ? 'Resume' : 'Pause';
+      if (!paused && held.length) {
+        appendLines(log, held.map(renderNotice).join(''));
+        held = [];
+      }
+    });
+
+    container.querySelector('#snt-clear').addEventListener('click', () => {
+      log.innerHTML = '';
+      held = [];
+    });
+
+    paused = false;
+    restart(container);
+    return true;
+  }
+
+  function disconnect() {
+    if (controller) {
+      controller.abort();
+      controller = null;
+    }
+  }
+
+  function cleanup() {
+    disconnect();
+    const style = document.getElementById('server-notices-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection; the stream is closed when leaving the page
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      } else {
+        disconnect();
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,618 @@
+// Server Notices Plugin for UnrealIRCd Web Panel
+// Captures server notices from the IRCd log into a queryable store and a
+// live stream, so staff can follow them in the panel instead of a bouncer
+
+package servernotices
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Query and stream limits
+const (
+	clientBuffer   = 256
+	heartbeat      = 15 * time.Second
+	maxLimit       = 1000
+	defaultBacklog = 50
+)
+
+// ServerNoticesPlugin implements the Plugin interface
+type ServerNoticesPlugin struct {
+	config       Config
+	retention    map[string]int
+	notices      map[string][]*Notice
+	seq          int64
+	clients      map[int]*client
+	nextClient   int
+	dirty        bool
+	streamOK     bool
+	streamErr    string
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCUser     string `json:"rpc_user"`
+	RPCPassword string `json:"rpc_password"`
+	RPCInsecure bool   `json:"rpc_insecure"`
+	StreamURL   string `json:"stream_url"`
+	LogSources  string `json:"log_sources"`
+	DataDir     string `json:"data_dir"`
+	Retention   string `json:"retention"`
+	MaxPerType  int    `json:"max_per_type"`
+	MaxClients  int    `json:"max_clients"`
+}
+
+// storeData is what the plugin keeps on disk
+type storeData struct {
+	Seq     int64                `json:"seq"`
+	Notices map[string][]*Notice `json:"notices"`
+}
+
+// client is a live stream connection. Notices that don't fit in its
+// channel are counted as dropped rather than holding up the log stream.
+type client struct {
+	ch      chan *Notice
+	f       filter
+	dropped int
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	cfg := Config{
+		StreamURL:  "wss://127.0.0.1:8600/",
+		LogSources: "all,!debug",
+		DataDir:    "data/plugins/server-notices",
+		Retention:  "oper:90,kill:90,ban:30,spamfilter:30,link:30,flood:14,connect:3,nick:3,other:7",
+		MaxPerType: 20000,
+		MaxClients: 20,
+	}
+	retention, _ := parseRetention(cfg.Retention)
+	return &ServerNoticesPlugin{
+		config:    cfg,
+		retention: retention,
+		notices:   make(map[string][]*Notice),
+		clients:   make(map[int]*client),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ServerNoticesPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Server Notices",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Queryable store and live stream of server notices",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ServerNoticesPlugin) Init() error {
+	p.mu.Lock()
+	if retention, err := parseRetention(p.config.Retention); err != nil {
+		log.Printf("[server-notices] invalid retention setting, using defaults: %v", err)
+	} else {
+		p.retention = retention
+	}
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[server-notices] failed to load notices: %v", err)
+	}
+	if data.Notices != nil {
+		p.notices = data.Notices
+		p.seq = data.Seq
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "server-notices-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		content := map[string]interface{}{
+			"opers_24h": p.countSince("oper", since),
+			"kills_24h": p.countSince("kill", since),
+			"links_24h": p.countSince("link", since),
+			"watching":  len(p.clients),
+		}
+		if !p.streamOK {
+			content["status"] = "Log stream disconnected"
+		}
+		return plugins.DashboardCard{
+			Title:   "Server Notices",
+			Icon:    "Bell",
+			Content: content,
+			Order:   76,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.saveLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ServerNoticesPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ServerNoticesPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/server-notices")
+	{
+		plugin.GET("/notices", p.handleNotices)
+		plugin.GET("/stream", p.handleStream)
+		plugin.GET("/types", p.handleTypes)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file notices are kept in
+func (p *ServerNoticesPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "notices.json")
+}
+
+// save writes the notices to disk if they changed
+func (p *ServerNoticesPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Seq: p.seq, Notices: p.notices}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// saveLoop expires old notices and writes the store to disk every minute
+func (p *ServerNoticesPlugin) saveLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.expire(time.Now())
+			p.mu.Unlock()
+			if err := p.save(); err != nil {
+				log.Printf("[server-notices] failed to save notices: %v", err)
+			}
+		}
+	}
+}
+
+// expire drops notices older than their type's retention. Caller must
+// hold p.mu.
+func (p *ServerNoticesPlugin) expire(now time.Time) {
+	for typ, list := range p.notices {
+		cutoff := now.AddDate(0, 0, -p.retention[typ])
+		n := 0
+		for n < len(list) && list[n].Time.Before(cutoff) {
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		if n == len(list) {
+			delete(p.notices, typ)
+		} else {
+			p.notices[typ] = append([]*Notice(nil), list[n:]...)
+		}
+		p.dirty = true
+	}
+}
+
+// countSince returns how many notices of a type were stored since t.
+// Caller must hold p.mu.
+func (p *ServerNoticesPlugin) countSince(typ string, t time.Time) int {
+	list := p.notices[typ]
+	i := sort.Search(len(list), func(i int) bool { return !list[i].Time.Before(t) })
+	return len(list) - i
+}
+
+// streamLoop runs the log stream until shutdown, restarting it when the
+// configuration changes
+func (p *ServerNoticesPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *ServerNoticesPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	p.streamErr = ""
+	if err != nil {
+		p.streamErr = err.Error()
+		log.Printf("[server-notices] log stream: %v", err)
+	}
+}
+
+// handleEvent stores a server notice, unless its type is live only, and
+// hands it to every live client whose filter it passes
+func (p *ServerNoticesPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	n := newNotice(ev, time.Now().UTC())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	n.Seq = p.seq
+	if p.retention[n.Type] > 0 {
+		list := append(p.notices[n.Type], n)
+		if len(list) > p.config.MaxPerType {
+			list = append([]*Notice(nil), list[len(list)-p.config.MaxPerType:]...)
+		}
+		p.notices[n.Type] = list
+		p.dirty = true
+	}
+
+	for _, cl := range p.clients {
+		if !cl.f.match(n) {
+			continue
+		}
+		select {
+		case cl.ch <- n:
+		default:
+			cl.dropped++
+		}
+	}
+}
+
+// find returns stored notices matching f with a sequence number below
+// before (0 for no bound) and above after, newest first, up to limit of
+// them, and whether more were left out. Caller must hold p.mu.
+func (p *ServerNoticesPlugin) find(f filter, before, after int64, limit int) ([]*Notice, bool) {
+	out := make([]*Notice, 0)
+	for _, list := range p.notices {
+		found := 0
+		for i := len(list) - 1; i >= 0 && found <= limit; i-- {
+			n := list[i]
+			if n.Seq <= after {
+				break
+			}
+			if (before > 0 && n.Seq >= before) || !f.match(n) {
+				continue
+			}
+			out = append(out, n)
+			found++
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Seq > out[j].Seq })
+	if len(out) > limit {
+		return out[:limit], true
+	}
+	return out, false
+}
+
+// queryInt reads a non-negative integer query parameter
+func queryInt(c *gin.Context, name string, def int64) (int64, error) {
+	s := c.Query(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return n, nil
+}
+
+// handleNotices returns stored notices, newest first. before pages back:
+// pass the seq of the oldest notice received.
+func (p *ServerNoticesPlugin) handleNotices(c *gin.Context) {
+	f, err := parseFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := queryInt(c, "limit", 200)
+	if err == nil && (limit < 1 || limit > maxLimit) {
+		err = fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	before, err := queryInt(c, "before", 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	notices, more := p.find(f, before, 0, int(limit))
+	c.JSON(http.StatusOK, gin.H{"notices": notices, "has_more": more, "latest_seq": p.seq})
+}
+
+// handleStream serves notices live as server-sent events. Each notice is a
+// "notice" event whose id is its seq. A reconnecting client that sends
+// Last-Event-ID, or ?after=, first gets the stored notices it missed; a new
+// client gets the last backlog matching ones.
+func (p *ServerNoticesPlugin) handleStream(c *gin.Context) {
+	f, err := parseFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	backlog, err := queryInt(c, "backlog", defaultBacklog)
+	if err == nil && backlog > maxLimit {
+		err = fmt.Errorf("backlog must be at most %d", maxLimit)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	after := int64(-1)
+	resume := c.GetHeader("Last-Event-ID")
+	if resume == "" {
+		resume = c.Query("after")
+	}
+	if resume != "" {
+		if after, err = strconv.ParseInt(resume, 10, 64); err != nil || after < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+	}
+
+	p.mu.Lock()
+	if len(p.clients) >= p.config.MaxClients {
+		p.mu.Unlock()
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many live notice streams"})
+		return
+	}
+	var initial []*Notice
+	if after >= 0 {
+		initial, _ = p.find(f, 0, after, maxLimit)
+	} else {
+		initial, _ = p.find(f, 0, 0, int(backlog))
+	}
+	cl := &client{ch: make(chan *Notice, clientBuffer), f: f}
+	id := p.nextClient
+	p.nextClient++
+	p.clients[id] = cl
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.clients, id)
+		p.mu.Unlock()
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Oldest first, as they happened
+	for i := len(initial) - 1; i >= 0; i-- {
+		if writeNotice(c.Writer, initial[i]) != nil {
+			return
+		}
+	}
+	fmt.Fprint(c.Writer, "event: ready\ndata: {}\n\n")
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-p.stop:
+			return false
+		case n := <-cl.ch:
+			p.mu.Lock()
+			dropped := cl.dropped
+			cl.dropped = 0
+			p.mu.Unlock()
+			if dropped > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: {\"count\":%d}\n\n", dropped)
+			}
+			return writeNotice(w, n) == nil
+		case <-ticker.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// writeNotice writes a notice as a server-sent event
+func writeNotice(w io.Writer, n *Notice) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: notice\ndata: %s\n\n", n.Seq, data)
+	return err
+}
+
+// handleTypes lists the notice types with their retention and how many
+// are stored
+func (p *ServerNoticesPlugin) handleTypes(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	types := make([]gin.H, 0, len(noticeTypes))
+	for _, t := range noticeTypes {
+		entry := gin.H{
+			"name":           t.Name,
+			"description":    t.Description,
+			"retention_days": p.retention[t.Name],
+			"stored":         len(p.notices[t.Name]),
+		}
+		if list := p.notices[t.Name]; len(list) > 0 {
+			entry["oldest"] = list[0].Time
+			entry["newest"] = list[len(list)-1].Time
+		}
+		types = append(types, entry)
+	}
+	c.JSON(http.StatusOK, gin.H{"types": types})
+}
+
+// handleStatus returns the state of the log stream and the store
+func (p *ServerNoticesPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stored := 0
+	for _, list := range p.notices {
+		stored += len(list)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"stream_ok":  p.streamOK,
+		"error":      p.streamErr,
+		"stored":     stored,
+		"latest_seq": p.seq,
+		"clients":    len(p.clients),
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ServerNoticesPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ServerNoticesPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	retention, err := parseRetention(newConfig.Retention)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if newConfig.MaxPerType < 100 || newConfig.MaxPerType > 1000000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_per_type must be between 100 and 1000000"})
+		return
+	}
+	if newConfig.MaxClients < 1 || newConfig.MaxClients > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_clients must be between 1 and 200"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.retention = retention
+	for typ, list := range p.notices {
+		if retention[typ] == 0 {
+			delete(p.notices, typ)
+			p.dirty = true
+		} else if len(list) > newConfig.MaxPerType {
+			p.notices[typ] = append([]*Notice(nil), list[len(list)-newConfig.MaxPerType:]...)
+			p.dirty = true
+		}
+	}
+	p.expire(time.Now())
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ServerNoticesPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ServerNoticesPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,202 @@
+package servernotices
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// noticeType is a kind of server notice, with how long it is kept unless
+// the retention setting says otherwise
+type noticeType struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Days        int    `json:"default_days"`
+}
+
+// noticeTypes lists the types in the order the panel shows them
+var noticeTypes = []noticeType{
+	{"oper", "Opers logging in, failed oper attempts and oper overrides", 90},
+	{"kill", "Users killed by opers or services", 90},
+	{"ban", "Server bans added, removed and expired", 30},
+	{"spamfilter", "Spamfilter matches", 30},
+	{"link", "Servers linking, splitting and link errors", 30},
+	{"flood", "Flood protection kicking in", 14},
+	{"connect", "Users connecting and disconnecting", 3},
+	{"nick", "Nick changes", 3},
+	{"other", "Every other server notice", 7},
+}
+
+// subsystemTypes maps log subsystems to notice types; eventTypes takes
+// precedence for events that belong elsewhere than their subsystem
+var (
+	subsystemTypes = map[string]string{
+		"oper":         "oper",
+		"operoverride": "oper",
+		"kill":         "kill",
+		"tkl":          "ban",
+		"spamfilter":   "spamfilter",
+		"link":         "link",
+		"flood":        "flood",
+		"connect":      "connect",
+		"nick":         "nick",
+	}
+	eventTypes = map[string]string{
+		"KILL_COMMAND":     "kill",
+		"SPAMFILTER_MATCH": "spamfilter",
+	}
+)
+
+// classify returns the notice type of a log entry
+func classify(subsystem, eventID string) string {
+	if t, ok := eventTypes[eventID]; ok {
+		return t
+	}
+	if t, ok := subsystemTypes[strings.ToLower(subsystem)]; ok {
+		return t
+	}
+	return "other"
+}
+
+// knownType reports whether name is a notice type
+func knownType(name string) bool {
+	for _, t := range noticeTypes {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetention reads the retention setting, a comma separated list of
+// type:days. Types left out keep their default; 0 days streams a type
+// live without storing it.
+func parseRetention(s string) (map[string]int, error) {
+	days := make(map[string]int)
+	for _, t := range noticeTypes {
+		days[t.Name] = t.Days
+	}
+	for _, item := range lists.Split(s) {
+		parts := strings.SplitN(item, ":", 2)
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		if !knownType(name) {
+			return nil, fmt.Errorf("unknown notice type %q", name)
+		}
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("retention for %s must be type:days", name)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n < 0 || n > 3650 {
+			return nil, fmt.Errorf("retention for %s must be between 0 and 3650 days", name)
+		}
+		days[name] = n
+	}
+	return days, nil
+}
+
+// Notice is a server notice as stored and served to clients
+type Notice struct {
+	Seq       int64     `json:"seq"`
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Level     string    `json:"level"`
+	Subsystem string    `json:"subsystem"`
+	EventID   string    `json:"event_id"`
+	Server    string    `json:"server,omitempty"`
+	Nick      string    `json:"nick,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	Msg       string    `json:"msg"`
+}
+
+// noticeDetails holds the parts of a log entry's details kept with a
+// notice
+type noticeDetails struct {
+	LogSource string `json:"log_source"`
+	Client    struct {
+		Name string `json:"name"`
+		IP   string `json:"ip"`
+	} `json:"client"`
+}
+
+// newNotice builds a notice from a log entry
+func newNotice(ev jsonrpc.LogEvent, now time.Time) *Notice {
+	ts, err := time.Parse(time.RFC3339Nano, ev.Timestamp)
+	if err != nil {
+		ts = now
+	}
+	var d noticeDetails
+	_ = json.Unmarshal(ev.Raw, &d)
+	return &Notice{
+		Time:      ts,
+		Type:      classify(ev.Subsystem, ev.EventID),
+		Level:     ev.Level,
+		Subsystem: ev.Subsystem,
+		EventID:   ev.EventID,
+		Server:    d.LogSource,
+		Nick:      d.Client.Name,
+		IP:        d.Client.IP,
+		Msg:       ev.Msg,
+	}
+}
+
+// filter selects notices by type, server, nick, time and text. Empty
+// fields match everything.
+type filter struct {
+	types   []string
+	servers []string
+	nicks   []string
+	since   time.Time
+	until   time.Time
+	text    string
+}
+
+// parseFilter reads a filter from query parameters: type, server and nick
+// (comma separated lists), since and until (RFC 3339) and q (text the
+// message must contain)
+func parseFilter(get func(string) string) (filter, error) {
+	var f filter
+	f.types = lists.Split(strings.ToLower(get("type")))
+	for _, t := range f.types {
+		if !knownType(t) {
+			return f, fmt.Errorf("unknown notice type %q", t)
+		}
+	}
+	f.servers = lists.Split(get("server"))
+	f.nicks = lists.Split(get("nick"))
+	for _, t := range []struct {
+		param string
+		dst   *time.Time
+	}{{"since", &f.since}, {"until", &f.until}} {
+		if s := get(t.param); s != "" {
+			ts, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return f, fmt.Errorf("%s must be an RFC 3339 time", t.param)
+			}
+			*t.dst = ts
+		}
+	}
+	f.text = strings.ToLower(strings.TrimSpace(get("q")))
+	return f, nil
+}
+
+// match reports whether a notice passes the filter
+func (f filter) match(n *Notice) bool {
+	if len(f.types) > 0 && !lists.ContainsFold(f.types, n.Type) {
+		return false
+	}
+	if len(f.servers) > 0 && !lists.ContainsFold(f.servers, n.Server) {
+		return false
+	}
+	if len(f.nicks) > 0 && !lists.ContainsFold(f.nicks, n.Nick) {
+		return false
+	}
+	if (!f.since.IsZero() && n.Time.Before(f.since)) || (!f.until.IsZero() && n.Time.After(f.until)) {
+		return false
+	}
+	return f.text == "" || strings.Contains(strings.ToLower(n.Msg), f.text)
+}
@@ -0,0 +1,196 @@
+/**
+ * Services Health Frontend Script
+ *
+ * Shows whether services are linked and answering, uptime and the
+ * incident history, with a button to check now.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'services-health';
+  const PLUGIN_NAME = 'Services Health';
+  const PAGE_PATH = '/plugins/services-health';
+  const API_BASE = '/api/plugin/services-health';
+  const REFRESH_MS = 30000;
+
+  let refreshTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path) {
+    const res = await fetch(API_BASE + path, { method, headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function formatDuration(start, end) {
+    const mins = Math.round(((end ? new Date(end) : new Date()) - new Date(start)) / 60000);
+    return mins >= 60 ? `${Math.floor(mins / 60)}h ${mins % 60}m` : `${mins}m`;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('services-health-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'services-health-styles';
+    style.textContent = `
+      .svh-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .svh-status { font-size: 1.3rem; font-weight: bold; }
+      .svh-ok { color: var(--success, #a6e3a1); }
+      .svh-degraded, .svh-unknown { color: var(--warning, #f9e2af); }
+      .svh-down { color: var(--error, #f38ba8); }
+      .svh-summary { display: grid; grid-template-columns: repeat(auto-fit, minmax(160px, 1fr)); gap: 0.75rem; }
+      .svh-stat { border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 0.75rem; }
+      .svh-stat strong { display: block; font-size: 1.3rem; color: var(--text-primary, #cdd6f4); }
+      .svh-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .svh-table th, .svh-table td { text-align: left; padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .svh-table td { color: var(--text-primary, #cdd6f4); }
+      .svh-app button { background: var(--accent, #89b4fa); color: var(--bg-primary, #11111b); border: none; border-radius: 6px; padding: 0.4rem 0.8rem; cursor: pointer; }
+      .svh-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderCheck(check) {
+    if (!check) return '<div>No check has run yet.</div>';
+    return `
+      <div class="svh-status svh-${escapeHtml(check.status)}">Services are ${escapeHtml(check.status)}</div>
+      <div>Checked ${formatTime(check.time)}${check.server ? ` &middot; linked as ${escapeHtml(check.server)}` : ''}${check.latency_ms != null ? ` &middot; answered in ${check.latency_ms} ms` : ''}</div>
+      ${check.problems.length ? `<ul>${check.problems.map(p => `<li>${escapeHtml(p)}</li>`).join('')}</ul>` : ''}
+      ${check.error ? `<div class="svh-error">${escapeHtml(check.error)}</div>` : ''}
+      <div>${check.bots.map(b => `<span class="${b.present ? 'svh-ok' : 'svh-down'}">${escapeHtml(b.nick)}</span>`).join(' &middot; ')}</div>
+    `;
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#svh-body');
+    try {
+      const [status, data] = await Promise.all([api('GET', '/status'), api('GET', '/incidents')]);
+      body.innerHTML = `
+        <div>${renderCheck(status.check)}</div>
+        <div class="svh-summary">
+          ${status.uptime.map(u => `
+            <div class="svh-stat"><strong>${u.uptime_percent}%</strong>uptime, last ${u.hours >= 48 ? `${u.hours / 24} days` : `${u.hours} hours`}<br>${u.incidents} incident(s)</div>
+          `).join('')}
+        </div>
+        <div>
+          <h3>Recent incidents</h3>
+          <table class="svh-table">
+            <thead><tr><th>Start</th><th>Lasted</th><th>Status</th><th>Problems</th></tr></thead>
+            <tbody>
+              ${data.incidents.map(i => `
+                <tr>
+                  <td>${formatTime(i.start)}</td>
+                  <td>${formatDuration(i.start, i.end)}${i.end ? '' : ' (ongoing)'}</td>
+                  <td class="svh-${escapeHtml(i.status)}">${escapeHtml(i.status)}</td>
+                  <td>${i.problems.map(escapeHtml).join('<br>')}</td>
+                </tr>
+              `).join('') || '<tr><td colspan="4">No incidents</td></tr>'}
+            </tbody>
+          </table>
+        </div>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="svh-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="svh-app" data-plugin="${PLUGIN_ID}">
+        <div><button id="svh-check">Check now</button></div>
+        <div id="svh-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#svh-check').addEventListener('click', async (e) => {
+      e.target.disabled = true;
+      try {
+        await api('POST', '/check');
+      } catch (err) {
+        alert(err.message);
+      }
+      e.target.disabled = false;
+      load(container);
+    });
+    load(container);
+    clearInterval(refreshTimer);
+    refreshTimer = setInterval(() => {
+      if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) {
+        clearInterval(refreshTimer);
+        return;
+      }
+      load(container);
+    }, REFRESH_MS);
+    return true;
+  }
+
+  function cleanup() {
+    clearInterval(refreshTimer);
+    const style = document.getElementById('services-health-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
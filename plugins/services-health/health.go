@@ -0,0 +1,141 @@
+package serviceshealth
+
+import (
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// Check results, from best to worst
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+	StatusUnknown  = "unknown"
+)
+
+// maxIncidents caps the incidents kept, whatever their age
+const maxIncidents = 1000
+
+// Check is the result of one health check
+type Check struct {
+	Time       time.Time  `json:"time"`
+	Status     string     `json:"status"`
+	Server     string     `json:"server,omitempty"`
+	Linked     bool       `json:"linked"`
+	Bots       []BotState `json:"bots"`
+	Responsive *bool      `json:"responsive,omitempty"`
+	LatencyMs  *int64     `json:"latency_ms,omitempty"`
+	Problems   []string   `json:"problems"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// BotState is whether a service bot is on the network
+type BotState struct {
+	Nick    string `json:"nick"`
+	Present bool   `json:"present"`
+	Server  string `json:"server,omitempty"`
+}
+
+// Incident is a stretch of failed checks
+type Incident struct {
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end,omitempty"`
+	Status   string     `json:"status"`
+	Problems []string   `json:"problems"`
+	Checks   int        `json:"checks"`
+}
+
+// statusRank orders check results from best to worst
+var statusRank = map[string]int{
+	StatusOK:       0,
+	StatusUnknown:  1,
+	StatusDegraded: 2,
+	StatusDown:     3,
+}
+
+// worse returns the worse of two statuses
+func worse(a, b string) string {
+	if statusRank[b] > statusRank[a] {
+		return b
+	}
+	return a
+}
+
+// problem adds a problem to a check and lowers its status to at least
+// status
+func (c *Check) problem(status, msg string) {
+	c.Status = worse(c.Status, status)
+	c.Problems = append(c.Problems, msg)
+}
+
+// failing reports whether a check counts against the services. An
+// unknown result, when the IRCd couldn't be asked, doesn't.
+func (c *Check) failing() bool {
+	return c.Status == StatusDegraded || c.Status == StatusDown
+}
+
+// add records a failed check in an open incident
+func (inc *Incident) add(c *Check) {
+	inc.Checks++
+	inc.Status = worse(inc.Status, c.Status)
+	for _, msg := range c.Problems {
+		if !lists.ContainsFold(inc.Problems, msg) && len(inc.Problems) < 20 {
+			inc.Problems = append(inc.Problems, msg)
+		}
+	}
+}
+
+// duration returns how long an incident lasted within [from, to)
+func (inc *Incident) duration(from, to time.Time) time.Duration {
+	start, end := inc.Start, to
+	if inc.End != nil && inc.End.Before(end) {
+		end = *inc.End
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// Uptime sums up the incidents of a period
+type Uptime struct {
+	Hours           int     `json:"hours"`
+	UptimePercent   float64 `json:"uptime_percent"`
+	DownMinutes     float64 `json:"down_minutes"`
+	DegradedMinutes float64 `json:"degraded_minutes"`
+	Incidents       int     `json:"incidents"`
+}
+
+// uptime sums up the incidents overlapping the hours before now. Time in
+// incidents that reached down counts as downtime; time in the others as
+// degraded.
+func uptime(incidents []*Incident, hours int, now time.Time) Uptime {
+	u := Uptime{Hours: hours}
+	from := now.Add(-time.Duration(hours) * time.Hour)
+	var down, degraded time.Duration
+	for _, inc := range incidents {
+		d := inc.duration(from, now)
+		if d == 0 {
+			continue
+		}
+		u.Incidents++
+		if inc.Status == StatusDown {
+			down += d
+		} else {
+			degraded += d
+		}
+	}
+	u.UptimePercent = float64(int64((1-float64(down)/float64(now.Sub(from)))*10000+0.5)) / 100
+	u.DownMinutes = round1(down.Minutes())
+	u.DegradedMinutes = round1(degraded.Minutes())
+	return u
+}
+
+// round1 rounds to one decimal place
+func round1(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
@@ -0,0 +1,604 @@
+// Services Health Plugin for UnrealIRCd Web Panel
+// Checks that services are linked, that NickServ and ChanServ are online
+// and that services answer, keeps a downtime history and sends alerts
+
+package serviceshealth
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/services"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// ServicesHealthPlugin implements the Plugin interface
+type ServicesHealthPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	backend   services.Backend
+	last      *Check
+	incidents []*Incident
+	failed    []*Check // failed checks not yet part of an incident
+	dirty     bool
+	mu        sync.RWMutex
+	checkMu   sync.Mutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	ServicesServer string `json:"services_server"`
+	ServiceNicks   string `json:"service_nicks"`
+	Backend        string `json:"backend"`
+	Endpoint       string `json:"endpoint"`
+	Username       string `json:"username"`
+	Password       string `json:"password"`
+	SourceIP       string `json:"source_ip"`
+	CheckInterval  int    `json:"check_interval"`
+	AlertAfter     int    `json:"alert_after"`
+	SlowMs         int    `json:"slow_ms"`
+	RetentionDays  int    `json:"retention_days"`
+	AlertWebhook   string `json:"alert_webhook"`
+}
+
+// servicesConfig returns the settings of the services client
+func (cfg Config) servicesConfig() services.Config {
+	return services.Config{
+		Backend:  cfg.Backend,
+		Endpoint: cfg.Endpoint,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		SourceIP: cfg.SourceIP,
+	}
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Incidents []*Incident `json:"incidents"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined bool `json:"ulined"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ServicesHealthPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/services-health",
+			ServiceNicks:  "NickServ,ChanServ",
+			Backend:       "none",
+			Endpoint:      "http://127.0.0.1:8080/xmlrpc",
+			SourceIP:      "127.0.0.1",
+			CheckInterval: 30,
+			AlertAfter:    2,
+			SlowMs:        3000,
+			RetentionDays: 90,
+		},
+		incidents: make([]*Incident, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ServicesHealthPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Services Health",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Checks that services are linked and answering, with downtime history and alerts",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ServicesHealthPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[services-health] failed to load data: %v", err)
+	}
+	if data.Incidents != nil {
+		p.incidents = data.Incidents
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "services-health-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"status":        StatusUnknown,
+			"uptime_7d":     uptime(p.incidents, 7*24, time.Now()).UptimePercent,
+			"open_incident": p.open() != nil,
+		}
+		if p.last != nil {
+			content["status"] = p.last.Status
+			if len(p.last.Problems) > 0 {
+				content["problems"] = p.last.Problems
+			}
+			if p.last.Error != "" {
+				content["error"] = p.last.Error
+			}
+		}
+		return plugins.DashboardCard{
+			Title:   "Services Health",
+			Icon:    "HeartPulse",
+			Content: content,
+			Order:   93,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.checkLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ServicesHealthPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ServicesHealthPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/services-health")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/check", p.handleCheck)
+		plugin.GET("/incidents", p.handleIncidents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file incidents are kept in
+func (p *ServicesHealthPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "health.json")
+}
+
+// save writes incidents to disk if they changed
+func (p *ServicesHealthPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Incidents: p.incidents}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *ServicesHealthPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// getBackend returns the services backend, creating it if needed, or nil
+// when no services backend is set
+func (p *ServicesHealthPlugin) getBackend() (services.Backend, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.Backend == "" || p.config.Backend == "none" {
+		return nil, nil
+	}
+	if p.backend == nil {
+		b, err := services.New(p.config.servicesConfig())
+		if err != nil {
+			return nil, err
+		}
+		p.backend = b
+	}
+	return p.backend, nil
+}
+
+// open returns the incident still going on, or nil. Caller must hold
+// p.mu.
+func (p *ServicesHealthPlugin) open() *Incident {
+	if n := len(p.incidents); n > 0 && p.incidents[n-1].End == nil {
+		return p.incidents[n-1]
+	}
+	return nil
+}
+
+// checkLoop checks services every check_interval until shutdown
+func (p *ServicesHealthPlugin) checkLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.runCheck()
+		if err := p.save(); err != nil {
+			log.Printf("[services-health] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.CheckInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// runCheck checks services and records the result
+func (p *ServicesHealthPlugin) runCheck() *Check {
+	p.checkMu.Lock()
+	defer p.checkMu.Unlock()
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c := p.check(ctx, cfg)
+	p.record(c)
+	return c
+}
+
+// check asks the IRCd whether the services server is linked and the
+// service bots are online on it, and asks services to answer a command
+// when a services backend is set
+func (p *ServicesHealthPlugin) check(ctx context.Context, cfg Config) *Check {
+	c := &Check{
+		Time:     time.Now().UTC(),
+		Status:   StatusOK,
+		Bots:     make([]BotState, 0),
+		Problems: make([]string, 0),
+	}
+	rpc := p.client()
+
+	var servers struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := rpc.Call(ctx, "server.list", nil, &servers); err != nil {
+		c.Status = StatusUnknown
+		c.Error = "Failed to list servers: " + err.Error()
+		return c
+	}
+	for _, s := range servers.List {
+		if (cfg.ServicesServer != "" && strings.EqualFold(s.Name, cfg.ServicesServer)) || (cfg.ServicesServer == "" && s.Server.ULined) {
+			c.Server = s.Name
+			c.Linked = true
+			break
+		}
+	}
+	switch {
+	case c.Linked:
+	case cfg.ServicesServer != "":
+		c.problem(StatusDown, cfg.ServicesServer+" is not linked")
+	default:
+		c.problem(StatusDown, "No U-lined services server is linked")
+	}
+
+	for _, nick := range lists.Split(cfg.ServiceNicks) {
+		bot := BotState{Nick: nick}
+		var out struct {
+			Client struct {
+				User struct {
+					Servername string `json:"servername"`
+				} `json:"user"`
+			} `json:"client"`
+		}
+		err := rpc.Call(ctx, "user.get", map[string]interface{}{"nick": nick}, &out)
+		var rerr *jsonrpc.Error
+		switch {
+		case err == nil:
+			bot.Present = true
+			bot.Server = out.Client.User.Servername
+			if c.Linked && bot.Server != "" && !strings.EqualFold(bot.Server, c.Server) {
+				c.problem(StatusDown, fmt.Sprintf("%s is on %s, not on %s", nick, bot.Server, c.Server))
+			}
+		case errors.As(err, &rerr):
+			c.problem(StatusDown, nick+" is not on the network")
+		default:
+			c.Status = worse(c.Status, StatusUnknown)
+			c.Error = "Failed to look up " + nick + ": " + err.Error()
+		}
+		c.Bots = append(c.Bots, bot)
+	}
+
+	b, err := p.getBackend()
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	if b != nil {
+		start := time.Now()
+		_, err = b.Command(ctx, "NickServ", "HELP")
+		latency := time.Since(start).Milliseconds()
+		responsive := err == nil
+		c.Responsive = &responsive
+		c.LatencyMs = &latency
+		switch {
+		case err != nil:
+			c.problem(StatusDegraded, "Services did not answer: "+err.Error())
+		case cfg.SlowMs > 0 && latency > int64(cfg.SlowMs):
+			c.problem(StatusDegraded, fmt.Sprintf("Services took %d ms to answer", latency))
+		}
+	}
+	return c
+}
+
+// record keeps a check result. An incident opens after alert_after
+// failed checks in a row, starting at the first of them, and closes at
+// the first good check. Both send an alert.
+func (p *ServicesHealthPlugin) record(c *Check) {
+	var alert *notify.Event
+
+	p.mu.Lock()
+	p.last = c
+	inc := p.open()
+	switch {
+	case c.failing() && inc != nil:
+		inc.add(c)
+		p.dirty = true
+	case c.failing():
+		p.failed = append(p.failed, c)
+		if len(p.failed) >= p.config.AlertAfter {
+			inc = &Incident{Start: p.failed[0].Time, Status: StatusOK, Problems: make([]string, 0)}
+			for _, f := range p.failed {
+				inc.add(f)
+			}
+			p.failed = nil
+			p.incidents = append(p.incidents, inc)
+			p.dirty = true
+			alert = &notify.Event{
+				Type:     "services_" + inc.Status,
+				Severity: "critical",
+				Title:    "Services are " + inc.Status,
+				Message:  strings.Join(inc.Problems, "; "),
+				Data:     map[string]interface{}{"status": inc.Status, "problems": inc.Problems, "since": inc.Start},
+			}
+			if inc.Status == StatusDegraded {
+				alert.Severity = "warning"
+			}
+		}
+	case c.Status == StatusOK:
+		p.failed = nil
+		if inc != nil {
+			end := c.Time
+			inc.End = &end
+			p.dirty = true
+			alert = &notify.Event{
+				Type:     "services_recovered",
+				Severity: "info",
+				Title:    "Services recovered",
+				Message:  fmt.Sprintf("Services are back after %s", end.Sub(inc.Start).Round(time.Second)),
+				Data:     map[string]interface{}{"status": inc.Status, "problems": inc.Problems, "since": inc.Start, "until": end},
+			}
+		}
+	}
+	p.prune(c.Time)
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	if alert != nil {
+		log.Printf("[services-health] %s: %s", alert.Title, alert.Message)
+		if webhook != "" {
+			alert.Source = "services-health"
+			alert.Timestamp = c.Time
+			go p.alert(webhook, *alert)
+		}
+	}
+}
+
+// prune drops closed incidents past the retention. Caller must hold p.mu.
+func (p *ServicesHealthPlugin) prune(now time.Time) {
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays)
+	keep := p.incidents[:0]
+	for _, inc := range p.incidents {
+		if inc.End == nil || inc.End.After(cutoff) {
+			keep = append(keep, inc)
+		}
+	}
+	if len(keep) > maxIncidents {
+		keep = keep[len(keep)-maxIncidents:]
+	}
+	if len(keep) != len(p.incidents) {
+		p.dirty = true
+	}
+	p.incidents = keep
+}
+
+// alert posts an alert to the webhook
+func (p *ServicesHealthPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[services-health] failed to send alert: %v", err)
+	}
+}
+
+// handleStatus returns the last check, the open incident and uptime over
+// the last day, week and month
+func (p *ServicesHealthPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	c.JSON(http.StatusOK, gin.H{
+		"check":    p.last,
+		"incident": p.open(),
+		"uptime": []Uptime{
+			uptime(p.incidents, 24, now),
+			uptime(p.incidents, 7*24, now),
+			uptime(p.incidents, 30*24, now),
+		},
+	})
+}
+
+// handleCheck runs a check now
+func (p *ServicesHealthPlugin) handleCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, p.runCheck())
+}
+
+// handleIncidents returns the incidents of the last ?days= days, 30 (or
+// the retention, if shorter) by default, newest first
+func (p *ServicesHealthPlugin) handleIncidents(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	days := 30
+	if days > p.config.RetentionDays {
+		days = p.config.RetentionDays
+	}
+	if s := c.Query("days"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > p.config.RetentionDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and " + strconv.Itoa(p.config.RetentionDays)})
+			return
+		}
+		days = v
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	list := make([]*Incident, 0)
+	for i := len(p.incidents) - 1; i >= 0; i-- {
+		inc := p.incidents[i]
+		if inc.End != nil && inc.End.Before(since) {
+			break
+		}
+		list = append(list, inc)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"incidents": list,
+		"uptime":    uptime(p.incidents, days*24, time.Now()),
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ServicesHealthPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.Password = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ServicesHealthPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.Backend != "none" {
+		if _, err := services.New(newConfig.servicesConfig()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if newConfig.CheckInterval < 10 || newConfig.CheckInterval > 600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check_interval must be between 10 and 600 seconds"})
+		return
+	}
+	if newConfig.AlertAfter < 1 || newConfig.AlertAfter > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "alert_after must be between 1 and 20"})
+		return
+	}
+	if newConfig.SlowMs < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slow_ms must not be negative"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 730 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 730"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.Password == "" {
+		newConfig.Password = p.config.Password
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.backend = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ServicesHealthPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ServicesHealthPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	p.backend = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,171 @@
+package servicesintegration
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+)
+
+// rpcMember is a channel member as returned by channel.get at detail
+// level 4: the client object plus the member's channel level
+type rpcMember struct {
+	Name     string `json:"name"`
+	Level    string `json:"level"`
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	User     struct {
+		Username string `json:"username"`
+		Account  string `json:"account"`
+		Modes    string `json:"modes"`
+	} `json:"user"`
+}
+
+// opModes are the member levels (channel prefix modes) that count as opped
+const opModes = "qao"
+
+// anopeLevels are the Anope access level names with the numeric level
+// they stand for
+var anopeLevels = map[string]int{
+	"VOP":     3,
+	"HOP":     4,
+	"AOP":     5,
+	"SOP":     10,
+	"QOP":     9999,
+	"FOUNDER": 10000,
+}
+
+// AccessReport merges a channel's services access list with the members
+// in the channel right now
+type AccessReport struct {
+	Channel            string              `json:"channel"`
+	Backend            string              `json:"backend"`
+	Founder            string              `json:"founder,omitempty"`
+	Successor          string              `json:"successor,omitempty"`
+	Access             []AccessReportEntry `json:"access"`
+	Members            []AccessMember      `json:"members"`
+	NotOpped           []string            `json:"not_opped"`
+	OppedWithoutAccess []string            `json:"opped_without_access"`
+}
+
+// AccessReportEntry is an access list entry with the members it matches
+type AccessReportEntry struct {
+	AccessEntry
+	OpAccess bool     `json:"op_access"`
+	Online   []string `json:"online"`
+}
+
+// AccessMember is a channel member with the access they hold
+type AccessMember struct {
+	Nick     string   `json:"nick"`
+	Account  string   `json:"account,omitempty"`
+	Level    string   `json:"level"`
+	Opped    bool     `json:"opped"`
+	Access   []string `json:"access"`
+	Founder  bool     `json:"founder,omitempty"`
+	OpAccess bool     `json:"op_access"`
+	Issue    string   `json:"issue,omitempty"`
+}
+
+// hasOpAccess reports whether an access level grants ops: Atheme flags
+// with o, O, a, q or F, an Anope level name of AOP or above, or a numeric
+// Anope level of at least opLevel
+func hasOpAccess(level string, opLevel int) bool {
+	if strings.HasPrefix(level, "+") {
+		return strings.ContainsAny(level, "oOaqF")
+	}
+	if n, err := strconv.Atoi(level); err == nil {
+		return n >= opLevel
+	}
+	if n, ok := anopeLevels[strings.ToUpper(level)]; ok {
+		return n >= opLevel
+	}
+	return false
+}
+
+// matchesMember reports whether an access entry covers a member. Entries
+// holding ! or @ are masks matched against nick!user@host and
+// nick!user@ip; any other entry is an account, which the member must be
+// logged in to.
+func matchesMember(mask string, m *rpcMember) bool {
+	if strings.ContainsAny(mask, "!@") {
+		if !strings.Contains(mask, "!") {
+			mask = "*!" + mask
+		}
+		prefix := m.Name + "!" + m.User.Username + "@"
+		return ircname.MatchMask(mask, prefix+m.Hostname) || (m.IP != "" && ircname.MatchMask(mask, prefix+m.IP))
+	}
+	return loggedIn(m.User.Account) && strings.EqualFold(mask, m.User.Account)
+}
+
+// buildAccessReport merges services channel info with the live members.
+// Services themselves are left out, as they usually hold ops without
+// being on the access list.
+func buildAccessReport(info *ChannelInfo, members []rpcMember, opLevel int) *AccessReport {
+	r := &AccessReport{
+		Channel:            info.Channel,
+		Backend:            info.Backend,
+		Founder:            info.Fields["founder"],
+		Successor:          info.Fields["successor"],
+		Access:             make([]AccessReportEntry, 0, len(info.Access)),
+		Members:            make([]AccessMember, 0, len(members)),
+		NotOpped:           make([]string, 0),
+		OppedWithoutAccess: make([]string, 0),
+	}
+	for _, e := range info.Access {
+		r.Access = append(r.Access, AccessReportEntry{AccessEntry: e, OpAccess: hasOpAccess(e.Level, opLevel), Online: make([]string, 0)})
+	}
+
+	for i := range members {
+		m := &members[i]
+		if strings.Contains(m.User.Modes, "S") {
+			continue
+		}
+		am := AccessMember{
+			Nick:   m.Name,
+			Level:  m.Level,
+			Opped:  strings.ContainsAny(m.Level, opModes),
+			Access: make([]string, 0),
+		}
+		if loggedIn(m.User.Account) {
+			am.Account = m.User.Account
+			am.Founder = strings.EqualFold(m.User.Account, r.Founder)
+		}
+		am.OpAccess = am.Founder
+		for j := range r.Access {
+			e := &r.Access[j]
+			if !matchesMember(e.Mask, m) {
+				continue
+			}
+			e.Online = append(e.Online, m.Name)
+			am.Access = append(am.Access, e.Mask+" "+e.Level)
+			if e.OpAccess {
+				am.OpAccess = true
+			}
+		}
+		switch {
+		case am.OpAccess && !am.Opped:
+			am.Issue = "not_opped"
+			r.NotOpped = append(r.NotOpped, m.Name)
+		case am.Opped && !am.OpAccess:
+			am.Issue = "opped_without_access"
+			r.OppedWithoutAccess = append(r.OppedWithoutAccess, m.Name)
+		}
+		r.Members = append(r.Members, am)
+	}
+
+	sort.Slice(r.Members, func(i, j int) bool {
+		if (r.Members[i].Issue != "") != (r.Members[j].Issue != "") {
+			return r.Members[i].Issue != ""
+		}
+		return strings.ToLower(r.Members[i].Nick) < strings.ToLower(r.Members[j].Nick)
+	})
+	return r
+}
+
+// loggedIn reports whether an account field means the client is
+// identified; UnrealIRCd may report "0" for none
+func loggedIn(account string) bool {
+	return account != "" && account != "0"
+}
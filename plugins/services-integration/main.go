@@ -0,0 +1,527 @@
+// Services Integration Plugin for UnrealIRCd Web Panel
+// Shows account registration info, linked nicks, channel access lists and
+// memo counts from Anope (XML-RPC) or Atheme (JSON-RPC), and compares
+// channel access with who is opped
+
+package servicesintegration
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/services"
+)
+
+// Names refused before they reach services, where a space or control
+// character would add arguments to the command
+var (
+	errBadAccount = errors.New("invalid account name")
+	errBadChannel = errors.New("invalid channel name")
+)
+
+// ServicesIntegrationPlugin implements the Plugin interface
+type ServicesIntegrationPlugin struct {
+	config    Config
+	backend   services.Backend
+	rpc       *jsonrpc.Client
+	cache     map[string]cacheEntry
+	lastError string
+	mu        sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	Backend     string `json:"backend"`
+	Endpoint    string `json:"endpoint"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	SourceIP    string `json:"source_ip"`
+	CacheTTL    int    `json:"cache_ttl"`
+	OpLevel     int    `json:"op_level"`
+	RPCURL      string `json:"rpc_url"`
+	RPCUser     string `json:"rpc_user"`
+	RPCPassword string `json:"rpc_password"`
+	RPCInsecure bool   `json:"rpc_insecure"`
+}
+
+// servicesConfig returns the settings of the services client
+func (cfg Config) servicesConfig() services.Config {
+	return services.Config{
+		Backend:  cfg.Backend,
+		Endpoint: cfg.Endpoint,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		SourceIP: cfg.SourceIP,
+	}
+}
+
+// AccountInfo is what services know about an account
+type AccountInfo struct {
+	Account     string            `json:"account"`
+	Backend     string            `json:"backend"`
+	Fields      map[string]string `json:"fields"`
+	LinkedNicks []string          `json:"linked_nicks"`
+	Memos       *int              `json:"memos"`
+	Raw         []string          `json:"raw"`
+	FetchedAt   time.Time         `json:"fetched_at"`
+}
+
+// ChannelInfo is what services know about a channel
+type ChannelInfo struct {
+	Channel   string            `json:"channel"`
+	Backend   string            `json:"backend"`
+	Fields    map[string]string `json:"fields"`
+	Access    []AccessEntry     `json:"access"`
+	Raw       []string          `json:"raw"`
+	FetchedAt time.Time         `json:"fetched_at"`
+}
+
+// cacheEntry is a cached lookup result
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ServicesIntegrationPlugin{
+		config: Config{
+			Backend:  "anope",
+			Endpoint: "http://127.0.0.1:8080/xmlrpc",
+			SourceIP: "127.0.0.1",
+			CacheTTL: 120,
+			OpLevel:  5,
+			RPCURL:   "https://127.0.0.1:8600/api",
+		},
+		cache: make(map[string]cacheEntry),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ServicesIntegrationPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Services Integration",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Anope and Atheme account and channel information in the panel",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ServicesIntegrationPlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add services data to user lookups
+	hm.Register(hooks.HookUserLookup, "services-integration-lookup", func(args interface{}) interface{} {
+		account := lookupAccount(args)
+		if account == "" {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		info, err := p.accountInfo(ctx, account)
+		if err != nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"services": info,
+		}
+	}, 50)
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ServicesIntegrationPlugin) Shutdown() error {
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ServicesIntegrationPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/services-integration")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/accounts/:account", p.handleGetAccount)
+		plugin.GET("/channels/:channel", p.handleGetChannel)
+		plugin.GET("/channels/:channel/access", p.handleChannelAccess)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// getBackend returns the services backend, creating it if needed
+func (p *ServicesIntegrationPlugin) getBackend() (services.Backend, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.backend == nil {
+		b, err := services.New(p.config.servicesConfig())
+		if err != nil {
+			return nil, err
+		}
+		p.backend = b
+	}
+	return p.backend, nil
+}
+
+// client returns the UnrealIRCd JSON-RPC client, creating it on first use
+func (p *ServicesIntegrationPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// cached returns a cached value if it has not expired
+func (p *ServicesIntegrationPlugin) cached(key string) (interface{}, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	e, ok := p.cache[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// store caches a value for the configured TTL
+func (p *ServicesIntegrationPlugin) store(key string, value interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range p.cache {
+		if now.After(e.expires) {
+			delete(p.cache, k)
+		}
+	}
+	p.cache[key] = cacheEntry{
+		value:   value,
+		expires: now.Add(time.Duration(p.config.CacheTTL) * time.Second),
+	}
+}
+
+// setError records the result of the last services call
+func (p *ServicesIntegrationPlugin) setError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.lastError = err.Error()
+	} else {
+		p.lastError = ""
+	}
+}
+
+// accountInfo looks up an account in services
+func (p *ServicesIntegrationPlugin) accountInfo(ctx context.Context, account string) (*AccountInfo, error) {
+	if !ircname.ValidNick(account) {
+		return nil, errBadAccount
+	}
+	key := "account:" + strings.ToLower(account)
+	if v, ok := p.cached(key); ok {
+		return v.(*AccountInfo), nil
+	}
+
+	b, err := p.getBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := b.Command(ctx, "NickServ", "INFO "+account)
+	p.setError(err)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &AccountInfo{
+		Account:     account,
+		Backend:     b.Name(),
+		Fields:      services.ParseInfo(raw),
+		LinkedNicks: make([]string, 0),
+		Raw:         raw,
+		FetchedAt:   time.Now(),
+	}
+
+	switch b.Name() {
+	case "anope":
+		if lines, err := b.Command(ctx, "NickServ", "GLIST "+account); err == nil {
+			info.LinkedNicks = services.ParseGroupList(lines)
+		}
+		if lines, err := b.Command(ctx, "MemoServ", "INFO "+account); err == nil {
+			info.Memos = parseMemoCount(lines)
+		}
+	case "atheme":
+		if nicks, ok := info.Fields["nicks"]; ok {
+			info.LinkedNicks = strings.Fields(nicks)
+		}
+	}
+
+	p.store(key, info)
+	return info, nil
+}
+
+// channelInfo looks up a registered channel in services
+func (p *ServicesIntegrationPlugin) channelInfo(ctx context.Context, channel string) (*ChannelInfo, error) {
+	if !ircname.ValidChannel(channel) {
+		return nil, errBadChannel
+	}
+	key := "channel:" + strings.ToLower(channel)
+	if v, ok := p.cached(key); ok {
+		return v.(*ChannelInfo), nil
+	}
+
+	b, err := p.getBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := b.Command(ctx, "ChanServ", "INFO "+channel)
+	p.setError(err)
+	if err != nil {
+		return nil, err
+	}
+
+	accessCmd := "ACCESS " + channel + " LIST"
+	if b.Name() == "atheme" {
+		accessCmd = "FLAGS " + channel
+	}
+	access, err := b.Command(ctx, "ChanServ", accessCmd)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ChannelInfo{
+		Channel:   channel,
+		Backend:   b.Name(),
+		Fields:    services.ParseInfo(raw),
+		Access:    parseAccessList(access),
+		Raw:       append(raw, access...),
+		FetchedAt: time.Now(),
+	}
+
+	p.store(key, info)
+	return info, nil
+}
+
+// parseMemoCount extracts the memo count from MemoServ INFO output
+func parseMemoCount(lines []string) *int {
+	for _, line := range lines {
+		if m := memoCount.FindStringSubmatch(line); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				return &n
+			}
+		}
+	}
+	return nil
+}
+
+// lookupAccount extracts an account name from HookUserLookup arguments
+func lookupAccount(args interface{}) string {
+	m, ok := args.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if account, ok := m["account"].(string); ok {
+		return account
+	}
+	if user, ok := m["user"].(map[string]interface{}); ok {
+		if account, ok := user["account"].(string); ok {
+			return account
+		}
+	}
+	return ""
+}
+
+// handleStatus reports whether services are reachable
+func (p *ServicesIntegrationPlugin) handleStatus(c *gin.Context) {
+	b, err := p.getBackend()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"configured": false, "error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err = b.Command(ctx, "NickServ", "HELP")
+	p.setError(err)
+
+	status := gin.H{
+		"configured": true,
+		"backend":    b.Name(),
+		"reachable":  err == nil,
+		"latency_ms": time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		status["error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetAccount returns services information for an account
+func (p *ServicesIntegrationPlugin) handleGetAccount(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	account := c.Param("account")
+	if !ircname.ValidNick(account) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account name"})
+		return
+	}
+
+	info, err := p.accountInfo(ctx, account)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// handleGetChannel returns services information for a channel
+func (p *ServicesIntegrationPlugin) handleGetChannel(c *gin.Context) {
+	channel := c.Param("channel")
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+	if !ircname.ValidChannel(channel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel name"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	info, err := p.channelInfo(ctx, channel)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// handleChannelAccess compares a channel's access list and founder with
+// the members in the channel, listing those with op access who aren't
+// opped and those opped without access
+func (p *ServicesIntegrationPlugin) handleChannelAccess(c *gin.Context) {
+	channel := c.Param("channel")
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+	if !ircname.ValidChannel(channel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel name"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	info, err := p.channelInfo(ctx, channel)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	var out struct {
+		Channel struct {
+			Members []rpcMember `json:"members"`
+		} `json:"channel"`
+	}
+	params := map[string]interface{}{"channel": channel, "object_detail_level": 4}
+	if err := p.client().Call(ctx, "channel.get", params, &out); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to get channel members: " + err.Error()})
+		return
+	}
+
+	p.mu.RLock()
+	opLevel := p.config.OpLevel
+	p.mu.RUnlock()
+	c.JSON(http.StatusOK, buildAccessReport(info, out.Channel.Members, opLevel))
+}
+
+// handleGetConfig returns the current configuration
+func (p *ServicesIntegrationPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.Password = ""
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, gin.H{
+		"config":     cfg,
+		"last_error": p.lastError,
+	})
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ServicesIntegrationPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if _, err := services.New(newConfig.servicesConfig()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if newConfig.OpLevel < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "op_level must be at least 1"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.Password == "" {
+		newConfig.Password = p.config.Password
+	}
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	p.config = newConfig
+	p.backend = nil
+	p.rpc = nil
+	p.cache = make(map[string]cacheEntry)
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ServicesIntegrationPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ServicesIntegrationPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.backend = nil
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
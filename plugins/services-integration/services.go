@@ -0,0 +1,39 @@
+package servicesintegration
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// memoCount matches MemoServ INFO output
+var memoCount = regexp.MustCompile(`(?i)has (\d+) memos?`)
+
+// AccessEntry is a single entry of a channel access list
+type AccessEntry struct {
+	Mask  string `json:"mask"`
+	Level string `json:"level"`
+}
+
+// parseAccessList extracts numbered entries from ACCESS LIST / FLAGS output
+func parseAccessList(lines []string) []AccessEntry {
+	entries := make([]AccessEntry, 0)
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[0]); err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(fields[2], "+"):
+			// Atheme FLAGS: <num> <mask> <flags>
+			entries = append(entries, AccessEntry{Mask: fields[1], Level: fields[2]})
+		default:
+			// Anope ACCESS LIST: <num> <level> <mask>
+			entries = append(entries, AccessEntry{Mask: fields[2], Level: fields[1]})
+		}
+	}
+	return entries
+}
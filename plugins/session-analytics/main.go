@@ -0,0 +1,603 @@
+// Session Analytics Plugin for UnrealIRCd Web Panel
+// Builds per-account session profiles (typical session length, active hours
+// and country) and flags sessions that deviate from them
+
+package sessionanalytics
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// SessionAnalyticsPlugin implements the Plugin interface
+type SessionAnalyticsPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	data      storeData
+	active    map[string]*activeSession
+	dirty     bool
+	lastPoll  time.Time
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string  `json:"rpc_url"`
+	RPCUser       string  `json:"rpc_user"`
+	RPCPassword   string  `json:"rpc_password"`
+	RPCInsecure   bool    `json:"rpc_insecure"`
+	DataDir       string  `json:"data_dir"`
+	PollInterval  int     `json:"poll_interval"`
+	MinSessions   int     `json:"min_sessions"`
+	HourThreshold float64 `json:"hour_threshold"`
+	LengthFactor  float64 `json:"length_factor"`
+	MaxAnomalies  int     `json:"max_anomalies"`
+}
+
+// AccountProfile holds the accumulated session statistics of one account
+type AccountProfile struct {
+	Account        string         `json:"account"`
+	Sessions       int            `json:"sessions"`
+	TotalSeconds   int64          `json:"total_seconds"`
+	LongestSeconds int64          `json:"longest_seconds"`
+	Hours          [24]int        `json:"hours"`
+	Countries      map[string]int `json:"countries"`
+	FirstSeen      time.Time      `json:"first_seen"`
+	LastSeen       time.Time      `json:"last_seen"`
+}
+
+// Anomaly is a session that did not match the account's usual behaviour
+type Anomaly struct {
+	Account   string    `json:"account"`
+	Nick      string    `json:"nick"`
+	IP        string    `json:"ip"`
+	Kind      string    `json:"kind"`
+	Detail    string    `json:"detail"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Profiles  map[string]*AccountProfile `json:"profiles"`
+	Anomalies []Anomaly                  `json:"anomalies"`
+}
+
+// activeSession is a currently connected, logged-in client
+type activeSession struct {
+	account string
+	nick    string
+	ip      string
+	country string
+	start   time.Time
+	seen    time.Time
+}
+
+// rpcUser is the subset of the UnrealIRCd user object we need
+type rpcUser struct {
+	Name           string `json:"name"`
+	ID             string `json:"id"`
+	IP             string `json:"ip"`
+	ConnectedSince string `json:"connected_since"`
+	GeoIP          struct {
+		CountryCode string `json:"country_code"`
+	} `json:"geoip"`
+	User struct {
+		Account string `json:"account"`
+	} `json:"user"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &SessionAnalyticsPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/session-analytics",
+			PollInterval:  60,
+			MinSessions:   10,
+			HourThreshold: 0.02,
+			LengthFactor:  4,
+			MaxAnomalies:  500,
+		},
+		data: storeData{
+			Profiles:  make(map[string]*AccountProfile),
+			Anomalies: make([]Anomaly, 0),
+		},
+		active: make(map[string]*activeSession),
+	}
+}
+
+// Info returns plugin metadata
+func (p *SessionAnalyticsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Session Analytics",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Per-account session statistics with deviation flagging",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *SessionAnalyticsPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.data); err != nil {
+		log.Printf("[session-analytics] failed to load data: %v", err)
+	}
+	if p.data.Profiles == nil {
+		p.data.Profiles = make(map[string]*AccountProfile)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "session-analytics-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		recent := 0
+		cutoff := time.Now().Add(-24 * time.Hour)
+		for _, a := range p.data.Anomalies {
+			if a.Timestamp.After(cutoff) {
+				recent++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Session Anomalies",
+			Icon:  "Activity",
+			Content: map[string]interface{}{
+				"anomalies_24h":   recent,
+				"active_sessions": len(p.active),
+				"profiles":        len(p.data.Profiles),
+			},
+			Order: 70,
+			Size:  "sm",
+		}
+	}, 50)
+
+	// Enrich user lookups with the account's usual behaviour
+	hm.Register(hooks.HookUserLookup, "session-analytics-lookup", func(args interface{}) interface{} {
+		account := lookupAccount(args)
+		if account == "" {
+			return nil
+		}
+
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		prof, ok := p.data.Profiles[strings.ToLower(account)]
+		if !ok {
+			return nil
+		}
+		return map[string]interface{}{
+			"session_profile": summarize(prof),
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *SessionAnalyticsPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *SessionAnalyticsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/session-analytics")
+	{
+		plugin.GET("/accounts", p.handleListAccounts)
+		plugin.GET("/accounts/:account", p.handleGetAccount)
+		plugin.GET("/anomalies", p.handleGetAnomalies)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *SessionAnalyticsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "sessions.json")
+}
+
+// save persists profiles and anomalies if they changed
+func (p *SessionAnalyticsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.data); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *SessionAnalyticsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop samples the user list until shutdown
+func (p *SessionAnalyticsPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.poll()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 10*time.Second {
+			interval = 10 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.poll()
+			if err := p.save(); err != nil {
+				log.Printf("[session-analytics] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// poll fetches logged-in users, opening and closing sessions as needed
+func (p *SessionAnalyticsPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcUser `json:"list"`
+	}
+	err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		return
+	}
+	p.lastError = ""
+	now := time.Now()
+	p.lastPoll = now
+
+	for _, u := range result.List {
+		if u.User.Account == "" {
+			continue
+		}
+		if s, ok := p.active[u.ID]; ok {
+			s.seen = now
+			continue
+		}
+
+		start, err := time.Parse(time.RFC3339, u.ConnectedSince)
+		if err != nil {
+			start = now
+		}
+		s := &activeSession{
+			account: u.User.Account,
+			nick:    u.Name,
+			ip:      u.IP,
+			country: u.GeoIP.CountryCode,
+			start:   start,
+			seen:    now,
+		}
+		p.active[u.ID] = s
+		p.checkStart(s)
+	}
+
+	for id, s := range p.active {
+		if s.seen.Equal(now) {
+			continue
+		}
+		p.finish(s)
+		delete(p.active, id)
+	}
+}
+
+// checkStart compares a new session with the account profile. Caller must hold p.mu.
+func (p *SessionAnalyticsPlugin) checkStart(s *activeSession) {
+	prof, ok := p.data.Profiles[strings.ToLower(s.account)]
+	if !ok || prof.Sessions < p.config.MinSessions {
+		return
+	}
+
+	hour := s.start.UTC().Hour()
+	if share := float64(prof.Hours[hour]) / float64(prof.Sessions); share < p.config.HourThreshold {
+		p.flag(s, "unusual_hour", fmt.Sprintf("connected at %02d:00 UTC, seen in %.1f%% of previous sessions", hour, share*100))
+	}
+	if s.country != "" && prof.Countries[s.country] == 0 {
+		p.flag(s, "unusual_country", fmt.Sprintf("connected from %s, usually %s", s.country, usualCountry(prof)))
+	}
+}
+
+// finish closes a session and folds it into the account profile. Caller must hold p.mu.
+func (p *SessionAnalyticsPlugin) finish(s *activeSession) {
+	key := strings.ToLower(s.account)
+	prof, ok := p.data.Profiles[key]
+	if !ok {
+		prof = &AccountProfile{
+			Account:   s.account,
+			Countries: make(map[string]int),
+			FirstSeen: s.start,
+		}
+		p.data.Profiles[key] = prof
+	}
+
+	length := int64(s.seen.Sub(s.start).Seconds())
+	if length < 0 {
+		length = 0
+	}
+	if prof.Sessions >= p.config.MinSessions && p.config.LengthFactor > 0 {
+		avg := float64(prof.TotalSeconds) / float64(prof.Sessions)
+		if avg > 0 && float64(length) > avg*p.config.LengthFactor {
+			p.flag(s, "unusual_length", fmt.Sprintf("session lasted %s, average is %s",
+				time.Duration(length)*time.Second, (time.Duration(avg)*time.Second).Round(time.Second)))
+		}
+	}
+
+	prof.Sessions++
+	prof.TotalSeconds += length
+	if length > prof.LongestSeconds {
+		prof.LongestSeconds = length
+	}
+	prof.Hours[s.start.UTC().Hour()]++
+	if s.country != "" {
+		prof.Countries[s.country]++
+	}
+	prof.LastSeen = s.seen
+	p.dirty = true
+}
+
+// flag records an anomaly. Caller must hold p.mu.
+func (p *SessionAnalyticsPlugin) flag(s *activeSession, kind, detail string) {
+	p.data.Anomalies = append(p.data.Anomalies, Anomaly{
+		Account:   s.account,
+		Nick:      s.nick,
+		IP:        s.ip,
+		Kind:      kind,
+		Detail:    detail,
+		Timestamp: time.Now(),
+	})
+	if max := p.config.MaxAnomalies; max > 0 && len(p.data.Anomalies) > max {
+		p.data.Anomalies = p.data.Anomalies[len(p.data.Anomalies)-max:]
+	}
+	p.dirty = true
+}
+
+// summarize turns a profile into a readable summary
+func summarize(prof *AccountProfile) gin.H {
+	avg := time.Duration(0)
+	if prof.Sessions > 0 {
+		avg = time.Duration(prof.TotalSeconds/int64(prof.Sessions)) * time.Second
+	}
+	return gin.H{
+		"account":         prof.Account,
+		"sessions":        prof.Sessions,
+		"average_session": avg.String(),
+		"average_seconds": int64(avg.Seconds()),
+		"longest_seconds": prof.LongestSeconds,
+		"usual_hours":     usualHours(prof, 3),
+		"usual_country":   usualCountry(prof),
+		"countries":       prof.Countries,
+		"hour_histogram":  prof.Hours,
+		"first_seen":      prof.FirstSeen,
+		"last_seen":       prof.LastSeen,
+	}
+}
+
+// usualHours returns the n most common connect hours (UTC)
+func usualHours(prof *AccountProfile, n int) []int {
+	hours := make([]int, 24)
+	for i := range hours {
+		hours[i] = i
+	}
+	sort.SliceStable(hours, func(i, j int) bool {
+		return prof.Hours[hours[i]] > prof.Hours[hours[j]]
+	})
+	out := make([]int, 0, n)
+	for _, h := range hours[:n] {
+		if prof.Hours[h] > 0 {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// usualCountry returns the most common country of an account
+func usualCountry(prof *AccountProfile) string {
+	best, bestCount := "", 0
+	for cc, n := range prof.Countries {
+		if n > bestCount || (n == bestCount && cc < best) {
+			best, bestCount = cc, n
+		}
+	}
+	return best
+}
+
+// lookupAccount extracts an account name from HookUserLookup arguments
+func lookupAccount(args interface{}) string {
+	m, ok := args.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if account, ok := m["account"].(string); ok {
+		return account
+	}
+	if user, ok := m["user"].(map[string]interface{}); ok {
+		if account, ok := user["account"].(string); ok {
+			return account
+		}
+	}
+	return ""
+}
+
+// handleListAccounts returns a summary of every known account
+func (p *SessionAnalyticsPlugin) handleListAccounts(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]gin.H, 0, len(p.data.Profiles))
+	for _, prof := range p.data.Profiles {
+		list = append(list, summarize(prof))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["sessions"].(int) > list[j]["sessions"].(int)
+	})
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts":   list,
+		"total":      len(p.data.Profiles),
+		"last_poll":  p.lastPoll,
+		"last_error": p.lastError,
+	})
+}
+
+// handleGetAccount returns the profile of a single account
+func (p *SessionAnalyticsPlugin) handleGetAccount(c *gin.Context) {
+	account := c.Param("account")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	prof, ok := p.data.Profiles[strings.ToLower(account)]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No sessions recorded for this account"})
+		return
+	}
+
+	anomalies := make([]Anomaly, 0)
+	for _, a := range p.data.Anomalies {
+		if strings.EqualFold(a.Account, account) {
+			anomalies = append(anomalies, a)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile":   summarize(prof),
+		"anomalies": anomalies,
+	})
+}
+
+// handleGetAnomalies returns recently flagged sessions, newest first
+func (p *SessionAnalyticsPlugin) handleGetAnomalies(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	kind := c.Query("kind")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	list := make([]Anomaly, 0)
+	for i := len(p.data.Anomalies) - 1; i >= 0; i-- {
+		a := p.data.Anomalies[i]
+		if kind != "" && a.Kind != kind {
+			continue
+		}
+		list = append(list, a)
+		if limit > 0 && len(list) >= limit {
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"anomalies": list,
+		"count":     len(list),
+	})
+}
+
+// handleGetConfig returns the current configuration
+func (p *SessionAnalyticsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *SessionAnalyticsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *SessionAnalyticsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *SessionAnalyticsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,304 @@
+/**
+ * Shift Notes Frontend Script
+ *
+ * Shows the staff journal on the plugin page: the latest handover, pinned
+ * ongoing issues, your mentions and every note, with a box to write one.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'shift-notes';
+  const PLUGIN_NAME = 'Shift Notes';
+  const PAGE_PATH = '/plugins/shift-notes';
+  const API_BASE = '/api/plugin/shift-notes';
+
+  let oldest = '';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  // Escapes a note and highlights its @mentions
+  function renderText(text) {
+    return escapeHtml(text).replace(/(^|[^\w@])@([A-Za-z0-9_](?:[A-Za-z0-9_.\-]*[A-Za-z0-9_])?)/g, '$1<span class="shn-mention">@$2</span>');
+  }
+
+  function injectStyles() {
+    if (document.getElementById('shift-notes-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'shift-notes-styles';
+    style.textContent = `
+      .shn-app { display: flex; flex-direction: column; gap: 1rem; }
+      .shn-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .shn-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .shn-btn.small { padding: 0.2rem 0.5rem; font-size: 0.75rem; }
+      .shn-app textarea, .shn-app input[type="text"] {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .shn-app textarea { width: 100%; min-height: 5rem; box-sizing: border-box; }
+      .shn-row { display: flex; gap: 0.75rem; align-items: center; flex-wrap: wrap; font-size: 0.85rem; color: var(--text-secondary, #a6adc8); }
+      .shn-section h3 { margin: 0 0 0.5rem; font-size: 1rem; }
+      .shn-note {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-left: 3px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.6rem 0.75rem;
+        margin-bottom: 0.5rem;
+        color: var(--text-secondary, #a6adc8);
+        font-size: 0.85rem;
+      }
+      .shn-note.handover { border-left-color: var(--accent, #89b4fa); }
+      .shn-note.pinned { border-left-color: var(--warning, #f9e2af); }
+      .shn-note.unread { border-left-color: var(--error, #f38ba8); }
+      .shn-note p { margin: 0.4rem 0 0; white-space: pre-wrap; color: var(--text-primary, #cdd6f4); }
+      .shn-mention { color: var(--accent, #89b4fa); font-weight: 600; }
+      .shn-tag { font-size: 0.7rem; padding: 0.05rem 0.35rem; border-radius: 4px; background: var(--bg-tertiary, #45475a); }
+      .shn-muted { color: var(--text-muted, #6c7086); }
+      .shn-error { color: var(--error, #f38ba8); }
+      .shn-empty { color: var(--text-muted, #6c7086); padding: 0.5rem 0; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderNote(n, extraClass) {
+    const classes = ['shn-note'];
+    if (n.handover) classes.push('handover');
+    if (n.pinned) classes.push('pinned');
+    if (extraClass) classes.push(extraClass);
+    const id = escapeHtml(n.id);
+    return `
+      <div class="${classes.join(' ')}">
+        <div class="shn-row">
+          <strong>${escapeHtml(n.author)}</strong>
+          <span class="shn-muted">${escapeHtml(formatTime(n.created_at))}${n.edited_at && !n.edited_at.startsWith('0001-') ? ' (edited)' : ''}</span>
+          ${n.handover ? '<span class="shn-tag">Handover</span>' : ''}
+          ${n.pinned ? `<span class="shn-tag">Pinned by ${escapeHtml(n.pinned_by)}</span>` : ''}
+          <span style="flex: 1"></span>
+          <button class="shn-btn small" data-action="${n.pinned ? 'unpin' : 'pin'}" data-id="${id}">${n.pinned ? 'Unpin' : 'Pin'}</button>
+          <button class="shn-btn small" data-action="edit" data-id="${id}">Edit</button>
+          <button class="shn-btn small" data-action="delete" data-id="${id}">Delete</button>
+        </div>
+        <p>${renderText(n.text)}</p>
+      </div>
+    `;
+  }
+
+  async function loadOverview(container) {
+    const out = container.querySelector('#shn-overview');
+    try {
+      const [latest, mentions] = await Promise.all([api('GET', '/latest'), api('GET', '/mentions')]);
+      const unread = mentions.mentions.filter(m => !m.read);
+      out.innerHTML = `
+        <div class="shn-section">
+          <h3>Latest handover</h3>
+          ${latest.handover ? renderNote(latest.handover) : '<div class="shn-empty">No handover notes yet.</div>'}
+        </div>
+        <div class="shn-section">
+          <h3>Ongoing issues</h3>
+          ${latest.pinned.length ? latest.pinned.map(n => renderNote(n)).join('') : '<div class="shn-empty">Nothing pinned.</div>'}
+        </div>
+        <div class="shn-section">
+          <h3>Mentions ${unread.length ? `(${unread.length} unread) <button class="shn-btn small" data-action="read-all">Mark all read</button>` : ''}</h3>
+          ${unread.length ? unread.map(m => renderNote(m.note, 'unread')).join('') : '<div class="shn-empty">No unread mentions.</div>'}
+        </div>
+      `;
+    } catch (e) {
+      out.innerHTML = `<div class="shn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadJournal(container, more) {
+    const list = container.querySelector('#shn-journal');
+    const q = container.querySelector('#shn-search').value.trim();
+    if (!more) oldest = '';
+
+    try {
+      const data = await api('GET', `/notes?limit=50&q=${encodeURIComponent(q)}&before=${encodeURIComponent(oldest)}`);
+      const html = data.notes.map(n => renderNote(n)).join('');
+      if (data.notes.length) oldest = data.notes[data.notes.length - 1].created_at;
+      if (more) {
+        list.insertAdjacentHTML('beforeend', html);
+      } else {
+        list.innerHTML = html || '<div class="shn-empty">No notes.</div>';
+      }
+      container.querySelector('#shn-more').style.display = data.more ? '' : 'none';
+    } catch (e) {
+      list.innerHTML = `<div class="shn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function refresh(container) {
+    loadOverview(container);
+    loadJournal(container, false);
+  }
+
+  async function noteAction(container, btn) {
+    const id = btn.dataset.id;
+    const path = `/notes/${encodeURIComponent(id || '')}`;
+    switch (btn.dataset.action) {
+      case 'pin':
+      case 'unpin':
+        await api('POST', `${path}/${btn.dataset.action}`);
+        break;
+      case 'edit': {
+        const note = btn.closest('.shn-note');
+        const current = note.querySelector('p').textContent;
+        const text = prompt('Edit note', current);
+        if (text === null || text === current) return;
+        await api('PUT', path, { text, handover: note.classList.contains('handover') });
+        break;
+      }
+      case 'delete':
+        if (!confirm('Delete this note?')) return;
+        await api('DELETE', path);
+        break;
+      case 'read-all':
+        await api('POST', '/mentions/read', {});
+        break;
+      default:
+        return;
+    }
+    refresh(container);
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="shn-app" data-plugin="${PLUGIN_ID}">
+        <form id="shn-form">
+          <textarea name="text" required placeholder="What happened this shift? Use @name to notify someone."></textarea>
+          <div class="shn-row">
+            <label><input type="checkbox" name="handover"> Handover note</label>
+            <label><input type="checkbox" name="pinned"> Pin as ongoing issue</label>
+            <button class="shn-btn primary" type="submit">Post</button>
+          </div>
+        </form>
+        <div id="shn-overview"><div class="shn-empty">Loading...</div></div>
+        <div class="shn-section">
+          <div class="shn-row"><h3>Journal</h3><input type="text" id="shn-search" placeholder="Search"></div>
+          <div id="shn-journal"><div class="shn-empty">Loading...</div></div>
+          <button class="shn-btn" id="shn-more" style="display: none">Older notes</button>
+        </div>
+      </div>
+    `;
+
+    const form = container.querySelector('#shn-form');
+    form.addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = form.elements;
+      try {
+        await api('POST', '/notes', { text: f.text.value, handover: f.handover.checked, pinned: f.pinned.checked });
+        form.reset();
+        refresh(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    container.querySelector('.shn-app').addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+      try {
+        await noteAction(container, btn);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    container.querySelector('#shn-search').addEventListener('input', () => loadJournal(container, false));
+    container.querySelector('#shn-more').addEventListener('click', () => loadJournal(container, true));
+
+    refresh(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('shift-notes-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
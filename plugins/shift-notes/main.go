@@ -0,0 +1,591 @@
+// Shift Notes Plugin for UnrealIRCd Web Panel
+// A shared staff journal for handing over between shifts: timestamped
+// notes, pinned ongoing issues and @mentions that notify other staff
+
+package shiftnotes
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// maxNoteLength is the longest note accepted, in bytes
+const maxNoteLength = 10000
+
+// ShiftNotesPlugin implements the Plugin interface
+type ShiftNotesPlugin struct {
+	config Config
+	notes  []*Note
+	mu     sync.RWMutex
+	wg     sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	DataDir    string `json:"data_dir"`
+	StaffNames string `json:"staff_names"`
+	WebhookURL string `json:"webhook_url"`
+	PanelURL   string `json:"panel_url"`
+	KeepDays   int    `json:"keep_days"`
+}
+
+// Note is an entry in the staff journal
+type Note struct {
+	ID         string    `json:"id"`
+	Author     string    `json:"author"`
+	Text       string    `json:"text"`
+	Handover   bool      `json:"handover"`
+	Pinned     bool      `json:"pinned"`
+	PinnedBy   string    `json:"pinned_by,omitempty"`
+	PinnedAt   time.Time `json:"pinned_at,omitempty"`
+	UnpinnedBy string    `json:"unpinned_by,omitempty"`
+	UnpinnedAt time.Time `json:"unpinned_at,omitempty"`
+	Mentions   []string  `json:"mentions"`
+	ReadBy     []string  `json:"read_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	EditedAt   time.Time `json:"edited_at,omitempty"`
+}
+
+// NoteRequest is the body of create and edit requests
+type NoteRequest struct {
+	Text     string `json:"text"`
+	Handover bool   `json:"handover"`
+	Pinned   bool   `json:"pinned"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &ShiftNotesPlugin{
+		config: Config{
+			DataDir:  "data/plugins/shift-notes",
+			KeepDays: 365,
+		},
+		notes: make([]*Note, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *ShiftNotesPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Shift Notes",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Shared staff journal with handover notes, pinned issues and @mentions",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *ShiftNotesPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.notes); err != nil {
+		log.Printf("[shift-notes] failed to load notes: %v", err)
+	}
+	if p.notes == nil {
+		p.notes = make([]*Note, 0)
+	}
+	p.prune(time.Now())
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card with the latest handover note
+	hm.Register(hooks.HookOverviewCard, "shift-notes-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{"pinned": 0}
+		var latest *Note
+		for _, n := range p.notes {
+			if n.Pinned {
+				content["pinned"] = content["pinned"].(int) + 1
+			}
+			if n.Handover && (latest == nil || n.CreatedAt.After(latest.CreatedAt)) {
+				latest = n
+			}
+		}
+		if latest != nil {
+			content["handover_by"] = latest.Author
+			content["handover_at"] = latest.CreatedAt
+			content["handover"] = excerpt(latest.Text, 200)
+		}
+		return plugins.DashboardCard{
+			Title:   "Shift Handover",
+			Icon:    "NotebookPen",
+			Content: content,
+			Order:   82,
+			Size:    "md",
+		}
+	}, 50)
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *ShiftNotesPlugin) Shutdown() error {
+	p.wg.Wait()
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *ShiftNotesPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/shift-notes")
+	{
+		plugin.GET("/notes", p.handleList)
+		plugin.POST("/notes", p.handleCreate)
+		plugin.PUT("/notes/:id", p.handleEdit)
+		plugin.DELETE("/notes/:id", p.handleDelete)
+		plugin.POST("/notes/:id/pin", p.handlePin)
+		plugin.POST("/notes/:id/unpin", p.handleUnpin)
+		plugin.GET("/latest", p.handleLatest)
+		plugin.GET("/mentions", p.handleMentions)
+		plugin.POST("/mentions/read", p.handleMarkRead)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the journal
+func (p *ShiftNotesPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "notes.json")
+}
+
+// save persists all notes. Caller must hold p.mu.
+func (p *ShiftNotesPlugin) save() {
+	if err := storage.SaveJSON(p.storePath(), p.notes); err != nil {
+		log.Printf("[shift-notes] failed to save notes: %v", err)
+	}
+}
+
+// prune drops unpinned notes older than keep_days. Caller must hold p.mu.
+func (p *ShiftNotesPlugin) prune(now time.Time) {
+	if p.config.KeepDays <= 0 {
+		return
+	}
+	cutoff := now.Add(-time.Duration(p.config.KeepDays) * 24 * time.Hour)
+	kept := p.notes[:0]
+	for _, n := range p.notes {
+		if n.Pinned || !n.CreatedAt.Before(cutoff) {
+			kept = append(kept, n)
+		}
+	}
+	p.notes = kept
+}
+
+// find returns a note by ID. Caller must hold p.mu.
+func (p *ShiftNotesPlugin) find(id string) *Note {
+	for _, n := range p.notes {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// notify sends a webhook notification for new mentions of a note, if a
+// webhook is configured. Caller must hold p.mu.
+func (p *ShiftNotesPlugin) notify(n *Note, names []string) {
+	url := p.config.WebhookURL
+	if url == "" || len(names) == 0 {
+		return
+	}
+	tags := make([]string, len(names))
+	for i, name := range names {
+		tags[i] = "@" + name
+	}
+	text := fmt.Sprintf("%s mentioned %s in a shift note: %s", n.Author, strings.Join(tags, ", "), excerpt(n.Text, 300))
+	if p.config.PanelURL != "" {
+		text += "\n" + strings.TrimRight(p.config.PanelURL, "/") + "/plugins/shift-notes"
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		if err := postWebhook(ctx, url, text); err != nil {
+			log.Printf("[shift-notes] failed to send mention notification: %v", err)
+		}
+	}()
+}
+
+// mentions reports whether a note mentions a user
+func (n *Note) mentions(user string) bool {
+	return lists.ContainsFold(n.Mentions, user)
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validText trims a note and checks its length
+func validText(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	return text, text != "" && len(text) <= maxNoteLength
+}
+
+// handleList returns notes, newest first. ?before= (RFC 3339) and ?limit=
+// page through the journal; ?author=, ?q=, ?handover=true and ?pinned=true
+// filter it.
+func (p *ShiftNotesPlugin) handleList(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	var before time.Time
+	if v := c.Query("before"); v != "" {
+		var err error
+		if before, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	author := c.Query("author")
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	handover := c.Query("handover") == "true"
+	pinned := c.Query("pinned") == "true"
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Note, 0)
+	for _, n := range p.notes {
+		if !before.IsZero() && !n.CreatedAt.Before(before) {
+			continue
+		}
+		if author != "" && !strings.EqualFold(n.Author, author) {
+			continue
+		}
+		if handover && !n.Handover || pinned && !n.Pinned {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(n.Text), q) {
+			continue
+		}
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	more := len(list) > limit
+	if more {
+		list = list[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notes": list, "more": more})
+}
+
+// handleCreate adds a note to the journal
+func (p *ShiftNotesPlugin) handleCreate(c *gin.Context) {
+	var req NoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	text, ok := validText(req.Text)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Note must be between 1 and %d characters", maxNoteLength)})
+		return
+	}
+	actor := access.Actor(c)
+	now := time.Now().UTC()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := &Note{
+		ID:        newID(),
+		Author:    actor,
+		Text:      text,
+		Handover:  req.Handover,
+		Mentions:  parseMentions(text, lists.Split(p.config.StaffNames)),
+		ReadBy:    make([]string, 0),
+		CreatedAt: now,
+	}
+	if req.Pinned {
+		n.Pinned = true
+		n.PinnedBy = actor
+		n.PinnedAt = now
+	}
+	p.notes = append(p.notes, n)
+	p.prune(now)
+	p.save()
+	p.notify(n, n.Mentions)
+
+	c.JSON(http.StatusCreated, n)
+}
+
+// handleEdit changes the text of a note. Only its author can edit it, and
+// only people newly mentioned are notified.
+func (p *ShiftNotesPlugin) handleEdit(c *gin.Context) {
+	var req NoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	text, ok := validText(req.Text)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Note must be between 1 and %d characters", maxNoteLength)})
+		return
+	}
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := p.find(c.Param("id"))
+	if n == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if n.Author != actor {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit a note"})
+		return
+	}
+
+	mentions := parseMentions(text, lists.Split(p.config.StaffNames))
+	added := make([]string, 0)
+	for _, name := range mentions {
+		if !n.mentions(name) {
+			added = append(added, name)
+		}
+	}
+	n.Text = text
+	n.Handover = req.Handover
+	n.Mentions = mentions
+	n.EditedAt = time.Now().UTC()
+	p.save()
+	p.notify(n, added)
+
+	c.JSON(http.StatusOK, n)
+}
+
+// handleDelete removes a note. Only its author can delete it.
+func (p *ShiftNotesPlugin) handleDelete(c *gin.Context) {
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, n := range p.notes {
+		if n.ID != c.Param("id") {
+			continue
+		}
+		if n.Author != actor {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can delete a note"})
+			return
+		}
+		p.notes = append(p.notes[:i], p.notes[i+1:]...)
+		p.save()
+		c.JSON(http.StatusOK, gin.H{"message": "Note deleted"})
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+}
+
+// handlePin pins a note as an ongoing issue
+func (p *ShiftNotesPlugin) handlePin(c *gin.Context) {
+	p.setPinned(c, true)
+}
+
+// handleUnpin unpins a note once the issue is over
+func (p *ShiftNotesPlugin) handleUnpin(c *gin.Context) {
+	p.setPinned(c, false)
+}
+
+// setPinned pins or unpins a note. Anyone can do either, as ongoing issues
+// belong to the whole team.
+func (p *ShiftNotesPlugin) setPinned(c *gin.Context, pinned bool) {
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := p.find(c.Param("id"))
+	if n == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if n.Pinned == pinned {
+		c.JSON(http.StatusOK, n)
+		return
+	}
+
+	n.Pinned = pinned
+	if pinned {
+		n.PinnedBy = actor
+		n.PinnedAt = time.Now().UTC()
+	} else {
+		n.UnpinnedBy = actor
+		n.UnpinnedAt = time.Now().UTC()
+	}
+	p.save()
+
+	c.JSON(http.StatusOK, n)
+}
+
+// handleLatest returns what someone starting a shift needs: the latest
+// handover note, the pinned issues and their own unread mentions
+func (p *ShiftNotesPlugin) handleLatest(c *gin.Context) {
+	actor := access.Actor(c)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var latest *Note
+	pinned := make([]*Note, 0)
+	unread := 0
+	for _, n := range p.notes {
+		if n.Handover && (latest == nil || n.CreatedAt.After(latest.CreatedAt)) {
+			latest = n
+		}
+		if n.Pinned {
+			pinned = append(pinned, n)
+		}
+		if n.mentions(actor) && !lists.ContainsFold(n.ReadBy, actor) {
+			unread++
+		}
+	}
+	sort.Slice(pinned, func(i, j int) bool { return pinned[i].PinnedAt.After(pinned[j].PinnedAt) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"handover":        latest,
+		"pinned":          pinned,
+		"unread_mentions": unread,
+	})
+}
+
+// handleMentions returns the notes that mention the panel user, newest
+// first, with whether each has been read
+func (p *ShiftNotesPlugin) handleMentions(c *gin.Context) {
+	actor := access.Actor(c)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]gin.H, 0)
+	unread := 0
+	for _, n := range p.notes {
+		if !n.mentions(actor) {
+			continue
+		}
+		read := lists.ContainsFold(n.ReadBy, actor)
+		if !read {
+			unread++
+		}
+		list = append(list, gin.H{"note": n, "read": read})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["note"].(*Note).CreatedAt.After(list[j]["note"].(*Note).CreatedAt)
+	})
+
+	c.JSON(http.StatusOK, gin.H{"mentions": list, "unread": unread})
+}
+
+// handleMarkRead marks mentions of the panel user as read: the notes given
+// in ids, or all of them when ids is empty
+func (p *ShiftNotesPlugin) handleMarkRead(c *gin.Context) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	marked := 0
+	for _, n := range p.notes {
+		if !n.mentions(actor) || lists.ContainsFold(n.ReadBy, actor) {
+			continue
+		}
+		if len(req.IDs) > 0 && !lists.ContainsFold(req.IDs, n.ID) {
+			continue
+		}
+		n.ReadBy = append(n.ReadBy, actor)
+		marked++
+	}
+	if marked > 0 {
+		p.save()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// handleGetConfig returns the current configuration
+func (p *ShiftNotesPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *ShiftNotesPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.WebhookURL != "" && !strings.HasPrefix(newConfig.WebhookURL, "https://") && !strings.HasPrefix(newConfig.WebhookURL, "http://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "webhook_url must be an http or https URL"})
+		return
+	}
+	if newConfig.KeepDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep_days cannot be negative"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *ShiftNotesPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *ShiftNotesPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,97 @@
+package shiftnotes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// mentionPattern matches @name, where name is a panel username. The @ must
+// not follow a word character, so email addresses aren't mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_](?:[A-Za-z0-9_.\-]*[A-Za-z0-9_])?)`)
+
+// mentionEveryone mentions every name in staff_names
+const mentionEveryone = "staff"
+
+// webhookClient is shared by all mention notifications
+var webhookClient = &http.Client{Timeout: 15 * time.Second}
+
+// webhookMessage is understood by both Slack and Discord incoming webhooks
+type webhookMessage struct {
+	Text    string `json:"text"`
+	Content string `json:"content"`
+}
+
+// parseMentions returns the names mentioned in text, lower-cased and
+// without duplicates. When staff is not empty, only those names count and
+// @staff mentions all of them.
+func parseMentions(text string, staff []string) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	add := func(name string) {
+		name = strings.ToLower(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := m[1]
+		switch {
+		case len(staff) == 0:
+			add(name)
+		case strings.EqualFold(name, mentionEveryone):
+			for _, s := range staff {
+				add(s)
+			}
+		case lists.ContainsFold(staff, name):
+			add(name)
+		}
+	}
+	return names
+}
+
+// postWebhook sends a mention notification to a Slack or Discord
+// compatible webhook
+func postWebhook(ctx context.Context, url, text string) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	body, err := json.Marshal(webhookMessage{Text: text, Content: text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// excerpt shortens text to at most n runes on one line
+func excerpt(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > n {
+		return string(r[:n-3]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,353 @@
+/**
+ * Slack Notifier Frontend Script
+ *
+ * Manage Slack routes, choose which events and digests go where and review
+ * recent deliveries.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'slack-notifier';
+  const PLUGIN_NAME = 'Slack Notifier';
+  const PAGE_PATH = '/plugins/slack-notifier';
+  const API_BASE = '/api/plugin/slack-notifier';
+
+  const SEVERITIES = ['info', 'warning', 'critical'];
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(value) {
+    if (!value || value.startsWith('0001-')) return 'never';
+    return new Date(value).toLocaleString();
+  }
+
+  function splitEvents(value) {
+    return value.split(',').map(s => s.trim()).filter(Boolean);
+  }
+
+  function severityOptions(selected) {
+    return SEVERITIES.map(s => `<option value="${s}" ${s === selected ? 'selected' : ''}>${s}</option>`).join('');
+  }
+
+  function injectStyles() {
+    if (document.getElementById('slack-notifier-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'slack-notifier-styles';
+    style.textContent = `
+      .sn-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .sn-form { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .sn-app input, .sn-app select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.35rem 0.6rem;
+      }
+      .sn-app input[name="target"] { min-width: 22rem; }
+      .sn-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .sn-table { width: 100%; border-collapse: collapse; }
+      .sn-table th, .sn-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        overflow-wrap: anywhere;
+      }
+      .sn-status { font-size: 0.9rem; }
+      .sn-hint { font-size: 0.85rem; color: var(--text-muted, #6c7086); }
+      .sn-disabled { color: var(--text-muted, #6c7086); }
+      .sn-ok { color: var(--success, #a6e3a1); }
+      .sn-warning { color: var(--warning, #f9e2af); }
+      .sn-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#sn-status');
+    try {
+      const s = await api('GET', '/status');
+      const dropped = Object.values(s.queues || {}).reduce((n, q) => n + q.dropped, 0);
+      body.innerHTML = `
+        ${s.notify_bans && !s.log_stream ? '<span class="sn-warning">Not connected to the IRCd log stream; new bans are not being seen.</span>' : ''}
+        ${s.events_enabled ? '' : '<span class="sn-warning">No events token set; other plugins cannot send events.</span>'}
+        ${s.digest_enabled ? `Daily digest at ${s.digest_hour}:00, last sent ${escapeHtml(formatTime(s.digest_last_sent))}.` : 'Daily digest is off.'}
+        ${dropped ? `<span class="sn-error">${dropped} events dropped because a queue was full.</span>` : ''}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="sn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadDigest(container) {
+    const body = container.querySelector('#sn-digest');
+    try {
+      const d = await api('GET', '/digest');
+      const total = Object.values(d.types || {}).reduce((n, c) => n + c, 0);
+      body.innerHTML = `
+        ${total} events since ${escapeHtml(formatTime(d.since))}:
+        ${d.severities.critical || 0} critical, ${d.severities.warning || 0} warnings, ${d.severities.info || 0} info.
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="sn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadRoutes(container) {
+    const body = container.querySelector('#sn-routes');
+    try {
+      const data = await api('GET', '/routes');
+      if (data.routes.length === 0) {
+        body.innerHTML = '<p>No routes yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="sn-table">
+          <thead><tr><th>Name</th><th>Sends to</th><th>Events</th><th>Minimum</th><th>Digest</th><th>Enabled</th><th></th></tr></thead>
+          <tbody>
+            ${data.routes.map(r => `
+              <tr class="${r.enabled ? '' : 'sn-disabled'}">
+                <td>${escapeHtml(r.name)}</td>
+                <td><code>${escapeHtml(r.channel || r.webhook_url)}</code></td>
+                <td><input data-events="${escapeHtml(r.id)}" value="${escapeHtml(r.events.join(', '))}"></td>
+                <td><select data-severity="${escapeHtml(r.id)}">${severityOptions(r.min_severity)}</select></td>
+                <td><input type="checkbox" data-digest="${escapeHtml(r.id)}" ${r.digest ? 'checked' : ''}></td>
+                <td><input type="checkbox" data-enabled="${escapeHtml(r.id)}" ${r.enabled ? 'checked' : ''}></td>
+                <td>
+                  <button data-test="${escapeHtml(r.id)}">Test</button>
+                  <button data-delete="${escapeHtml(r.id)}">Delete</button>
+                </td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+      const byId = Object.fromEntries(data.routes.map(r => [r.id, r]));
+      const update = async (id) => {
+        const r = byId[id];
+        try {
+          await api('PUT', `/routes/${id}`, {
+            name: r.name,
+            channel: r.channel || '',
+            events: splitEvents(body.querySelector(`[data-events="${id}"]`).value),
+            min_severity: body.querySelector(`[data-severity="${id}"]`).value,
+            digest: body.querySelector(`[data-digest="${id}"]`).checked,
+            enabled: body.querySelector(`[data-enabled="${id}"]`).checked
+          });
+        } catch (e) {
+          alert(e.message);
+        }
+        loadRoutes(container);
+      };
+      body.querySelectorAll('[data-events], [data-severity], [data-digest], [data-enabled]').forEach(el => {
+        el.addEventListener('change', () => update(el.dataset.events || el.dataset.severity || el.dataset.digest || el.dataset.enabled));
+      });
+      body.querySelectorAll('button[data-test]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          btn.disabled = true;
+          try {
+            await api('POST', `/routes/${btn.dataset.test}/test`);
+          } catch (e) {
+            alert(e.message);
+          }
+          btn.disabled = false;
+          loadDeliveries(container);
+        });
+      });
+      body.querySelectorAll('button[data-delete]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          if (!confirm('Remove this route? Messages still queued for it are dropped.')) return;
+          try {
+            await api('DELETE', `/routes/${btn.dataset.delete}`);
+            loadRoutes(container);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="sn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadDeliveries(container) {
+    const body = container.querySelector('#sn-deliveries');
+    try {
+      const data = await api('GET', '/deliveries?limit=100');
+      if (data.deliveries.length === 0) {
+        body.innerHTML = '<p>Nothing sent yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="sn-table">
+          <thead><tr><th>Time</th><th>Route</th><th>Events</th><th>Result</th></tr></thead>
+          <tbody>
+            ${data.deliveries.map(d => `
+              <tr>
+                <td>${escapeHtml(formatTime(d.time))}</td>
+                <td>${escapeHtml(d.route)}</td>
+                <td>${escapeHtml(d.events.join(', '))}</td>
+                <td>${d.ok ? '<span class="sn-ok">Sent</span>' : `<span class="sn-error">${escapeHtml(d.error)}</span>`}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="sn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="sn-app" data-plugin="${PLUGIN_ID}">
+        <div class="sn-status" id="sn-status"></div>
+        <h3>Routes</h3>
+        <form class="sn-form" id="sn-add">
+          <input name="name" placeholder="Name, e.g. #irc-alerts" required>
+          <input name="target" placeholder="https://hooks.slack.com/services/... or a channel ID" required>
+          <input name="events" placeholder="Events, e.g. ban_added, netsplit-tracker" value="*">
+          <select name="min_severity">${severityOptions('info')}</select>
+          <label><input type="checkbox" name="digest"> Digest</label>
+          <button type="submit">Add route</button>
+        </form>
+        <div class="sn-hint">
+          Use an incoming webhook URL, or a channel ID to post with the bot token.
+          Events are event types (<code>ban_added</code>, or any type another plugin sends),
+          names of the plugins that send them, or <code>*</code> for everything.
+        </div>
+        <div id="sn-routes">Loading...</div>
+        <h3>Digest</h3>
+        <div class="sn-form">
+          <span id="sn-digest">Loading...</span>
+          <button id="sn-send-digest">Send now</button>
+        </div>
+        <h3>Recent deliveries</h3>
+        <div id="sn-deliveries">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#sn-add').addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = e.target.elements;
+      const target = f.target.value.trim();
+      const isWebhook = target.startsWith('https://');
+      try {
+        await api('POST', '/routes', {
+          name: f.name.value,
+          webhook_url: isWebhook ? target : '',
+          channel: isWebhook ? '' : target,
+          events: splitEvents(f.events.value),
+          min_severity: f.min_severity.value,
+          digest: f.digest.checked
+        });
+        e.target.reset();
+        loadRoutes(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    container.querySelector('#sn-send-digest').addEventListener('click', async (e) => {
+      if (!confirm('Send the digest now? Counting starts over afterwards.')) return;
+      e.target.disabled = true;
+      try {
+        const res = await api('POST', '/digest');
+        alert(res.message);
+      } catch (err) {
+        alert(err.message);
+      }
+      e.target.disabled = false;
+      loadStatus(container);
+      loadDigest(container);
+      loadDeliveries(container);
+    });
+
+    loadStatus(container);
+    loadRoutes(container);
+    loadDigest(container);
+    loadDeliveries(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('slack-notifier-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
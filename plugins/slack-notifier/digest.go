@@ -0,0 +1,150 @@
+package slacknotifier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// maxHighlights limits the warnings and critical events listed in a digest
+const maxHighlights = 10
+
+// Digest counts the events seen since the last digest
+type Digest struct {
+	Since      time.Time      `json:"since"`
+	Types      map[string]int `json:"types"`
+	Severities map[string]int `json:"severities"`
+	Highlights []Highlight    `json:"highlights"`
+	LastSent   time.Time      `json:"last_sent"`
+}
+
+// Highlight is a warning or critical event listed in a digest
+type Highlight struct {
+	Time     time.Time `json:"time"`
+	Severity string    `json:"severity"`
+	Source   string    `json:"source"`
+	Title    string    `json:"title"`
+}
+
+// statsResult is the part of stats.get shown in a digest
+type statsResult struct {
+	Server struct {
+		Total int `json:"total"`
+	} `json:"server"`
+	User struct {
+		Total  int `json:"total"`
+		Oper   int `json:"oper"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+	ServerBan struct {
+		Total int `json:"total"`
+	} `json:"server_ban"`
+}
+
+// newDigest starts counting from now
+func newDigest(lastSent time.Time) *Digest {
+	return &Digest{
+		Since:      time.Now().UTC(),
+		Types:      make(map[string]int),
+		Severities: make(map[string]int),
+		Highlights: make([]Highlight, 0),
+		LastSent:   lastSent,
+	}
+}
+
+// add counts an event. The newest warnings and critical events are kept,
+// critical ones first.
+func (d *Digest) add(ev notify.Event) {
+	d.Types[ev.Type]++
+	d.Severities[ev.Severity]++
+	if notify.SeverityRank[ev.Severity] < notify.SeverityRank[notify.SeverityWarning] {
+		return
+	}
+	d.Highlights = append(d.Highlights, Highlight{Time: ev.Timestamp, Severity: ev.Severity, Source: ev.Source, Title: ev.Title})
+	sort.SliceStable(d.Highlights, func(i, j int) bool {
+		a, b := d.Highlights[i], d.Highlights[j]
+		if a.Severity != b.Severity {
+			return notify.SeverityRank[a.Severity] > notify.SeverityRank[b.Severity]
+		}
+		return a.Time.After(b.Time)
+	})
+	if len(d.Highlights) > maxHighlights {
+		d.Highlights = d.Highlights[:maxHighlights]
+	}
+}
+
+// digestMessage formats a digest with the network's current numbers, or
+// the error that kept them from being read
+func digestMessage(d *Digest, stats *statsResult, statsErr error) slackMessage {
+	now := time.Now()
+	blocks := []block{{
+		Type: "header",
+		Text: plain(":bar_chart: Network digest"),
+	}, {
+		Type:     "context",
+		Elements: []textObject{mrkdwn("Since " + slackDate(d.Since))},
+	}}
+
+	if statsErr != nil {
+		text := mrkdwn("Network numbers unavailable: " + escape(statsErr.Error()))
+		blocks = append(blocks, block{Type: "section", Text: &text})
+	} else {
+		blocks = append(blocks, block{Type: "section", Fields: []textObject{
+			mrkdwn(fmt.Sprintf("*Users*\n%d (record %d)", stats.User.Total, stats.User.Record)),
+			mrkdwn(fmt.Sprintf("*Opers*\n%d", stats.User.Oper)),
+			mrkdwn(fmt.Sprintf("*Channels*\n%d", stats.Channel.Total)),
+			mrkdwn(fmt.Sprintf("*Servers*\n%d", stats.Server.Total)),
+			mrkdwn(fmt.Sprintf("*Server bans*\n%d", stats.ServerBan.Total)),
+		}})
+	}
+
+	total := 0
+	for _, n := range d.Types {
+		total += n
+	}
+	summary := fmt.Sprintf("*%d events*: %d critical, %d warnings, %d info",
+		total, d.Severities[notify.SeverityCritical], d.Severities[notify.SeverityWarning], d.Severities[notify.SeverityInfo])
+	if total > 0 {
+		types := make([]string, 0, len(d.Types))
+		for t := range d.Types {
+			types = append(types, t)
+		}
+		sort.Slice(types, func(i, j int) bool {
+			if d.Types[types[i]] != d.Types[types[j]] {
+				return d.Types[types[i]] > d.Types[types[j]]
+			}
+			return types[i] < types[j]
+		})
+		lines := make([]string, 0, len(types))
+		for i, t := range types {
+			if i == maxFields {
+				lines = append(lines, fmt.Sprintf("… and %d more types", len(types)-maxFields))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("• `%s` %d", escape(t), d.Types[t]))
+		}
+		summary += "\n" + strings.Join(lines, "\n")
+	}
+	text := mrkdwn(truncate(summary, maxSectionLen))
+	blocks = append(blocks, block{Type: "divider"}, block{Type: "section", Text: &text})
+
+	if len(d.Highlights) > 0 {
+		lines := make([]string, 0, len(d.Highlights))
+		for _, h := range d.Highlights {
+			lines = append(lines, fmt.Sprintf("%s %s %s", severityEmoji[h.Severity], slackDate(h.Time), escape(h.Title)))
+		}
+		text := mrkdwn(truncate("*Highlights*\n"+strings.Join(lines, "\n"), maxSectionLen))
+		blocks = append(blocks, block{Type: "section", Text: &text})
+	}
+
+	return slackMessage{
+		Text:   fmt.Sprintf("Network digest: %d events since %s", total, d.Since.Format(time.RFC1123)),
+		Blocks: append(blocks, block{Type: "context", Elements: []textObject{mrkdwn("Sent " + slackDate(now))}}),
+	}
+}
@@ -0,0 +1,7 @@
+package slacknotifier
+
+// Built-in event types
+const (
+	EventDigest = "digest"
+	EventTest   = "test"
+)
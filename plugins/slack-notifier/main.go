@@ -0,0 +1,929 @@
+// Slack Notifier Plugin for UnrealIRCd Web Panel
+// Posts bans, alerts from other plugins and a daily digest to Slack
+// channels, routed by event type and severity
+
+package slacknotifier
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on queued and remembered messages
+const (
+	queueSize     = 200
+	maxDeliveries = 500
+	maxAttempts   = 3
+)
+
+// SlackNotifierPlugin implements the Plugin interface
+type SlackNotifierPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	routes       []*Route
+	senders      map[string]*sender
+	deliveries   []*Delivery
+	digest       *Digest
+	received     int
+	dirty        bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	StreamURL     string `json:"stream_url"`
+	LogSources    string `json:"log_sources"`
+	DataDir       string `json:"data_dir"`
+	BotToken      string `json:"bot_token"`
+	EventsToken   string `json:"events_token"`
+	RatePerMinute int    `json:"rate_per_minute"`
+	NotifyBans    bool   `json:"notify_bans"`
+	BanTypes      string `json:"ban_types"`
+	DigestEnabled bool   `json:"digest_enabled"`
+	DigestHour    int    `json:"digest_hour"`
+}
+
+// Route sends events to a Slack channel, through an incoming webhook or
+// with chat.postMessage when Channel is set
+type Route struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	WebhookURL  string    `json:"webhook_url,omitempty"`
+	Channel     string    `json:"channel,omitempty"`
+	Events      []string  `json:"events"`
+	MinSeverity string    `json:"min_severity"`
+	Digest      bool      `json:"digest"`
+	Enabled     bool      `json:"enabled"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RouteRequest is the body of a create or update request
+type RouteRequest struct {
+	Name        string   `json:"name"`
+	WebhookURL  string   `json:"webhook_url"`
+	Channel     string   `json:"channel"`
+	Events      []string `json:"events"`
+	MinSeverity string   `json:"min_severity"`
+	Digest      bool     `json:"digest"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// Delivery is the outcome of one message to a route
+type Delivery struct {
+	Time    time.Time `json:"time"`
+	RouteID string    `json:"route_id"`
+	Route   string    `json:"route"`
+	Events  []string  `json:"events"`
+	OK      bool      `json:"ok"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// sender delivers the queued events of one route at its rate
+type sender struct {
+	queue   chan notify.Event
+	quit    chan struct{}
+	dropped int
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Routes []*Route `json:"routes"`
+	Digest *Digest  `json:"digest"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &SlackNotifierPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			StreamURL:     "wss://127.0.0.1:8600/",
+			LogSources:    "tkl",
+			DataDir:       "data/plugins/slack-notifier",
+			RatePerMinute: 20,
+			NotifyBans:    true,
+			DigestEnabled: true,
+			DigestHour:    9,
+		},
+		routes:     make([]*Route, 0),
+		senders:    make(map[string]*sender),
+		deliveries: make([]*Delivery, 0),
+		digest:     newDigest(time.Time{}),
+	}
+}
+
+// Info returns plugin metadata
+func (p *SlackNotifierPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Slack Notifier",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Posts alerts and daily digests to Slack channels",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *SlackNotifierPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[slack-notifier] failed to load data: %v", err)
+	}
+	if data.Routes != nil {
+		p.routes = data.Routes
+	}
+	if data.Digest != nil && data.Digest.Types != nil && data.Digest.Severities != nil {
+		p.digest = data.Digest
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "slack-notifier-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		sent, failed := 0, 0
+		for i := len(p.deliveries) - 1; i >= 0 && p.deliveries[i].Time.After(since); i-- {
+			if p.deliveries[i].OK {
+				sent++
+			} else {
+				failed++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Slack Notifier",
+			Icon:  "Hash",
+			Content: map[string]interface{}{
+				"routes":     len(p.routes),
+				"sent_24h":   sent,
+				"failed_24h": failed,
+			},
+			Order: 63,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.digestLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *SlackNotifierPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *SlackNotifierPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/slack-notifier")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/routes", p.handleListRoutes)
+		plugin.POST("/routes", p.handleCreateRoute)
+		plugin.PUT("/routes/:id", p.handleUpdateRoute)
+		plugin.DELETE("/routes/:id", p.handleDeleteRoute)
+		plugin.POST("/routes/:id/test", p.handleTestRoute)
+		plugin.GET("/deliveries", p.handleDeliveries)
+		plugin.GET("/digest", p.handleGetDigest)
+		plugin.POST("/digest", p.handleSendDigest)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *SlackNotifierPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "slack-notifier.json")
+}
+
+// save persists the state if it changed
+func (p *SlackNotifierPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Routes: p.routes, Digest: p.digest}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *SlackNotifierPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// routeByID returns the route with the given ID. Caller must hold p.mu.
+func (p *SlackNotifierPlugin) routeByID(id string) *Route {
+	for _, r := range p.routes {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// matches reports whether an event is sent along the route. Events lists
+// event types or source plugins, or * for everything.
+func (r *Route) matches(ev notify.Event) bool {
+	if !r.Enabled || notify.SeverityRank[ev.Severity] < notify.SeverityRank[r.MinSeverity] {
+		return false
+	}
+	for _, e := range r.Events {
+		if e == "*" || strings.EqualFold(e, ev.Type) || strings.EqualFold(e, ev.Source) {
+			return true
+		}
+	}
+	return false
+}
+
+// post sends a message along a route
+func post(ctx context.Context, r Route, token string, msg slackMessage) (time.Duration, error) {
+	if r.Channel == "" {
+		return postWebhook(ctx, r.WebhookURL, msg)
+	}
+	if token == "" {
+		return 0, fmt.Errorf("bot_token is required for channel routes")
+	}
+	msg.Channel = r.Channel
+	return postMessage(ctx, token, msg)
+}
+
+// streamLoop follows ban log events until shutdown
+func (p *SlackNotifierPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *SlackNotifierPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[slack-notifier] log stream: %v", err)
+	}
+}
+
+// handleEvent announces new server bans
+func (p *SlackNotifierPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	p.mu.RLock()
+	enabled := p.config.NotifyBans
+	types := lists.Split(p.config.BanTypes)
+	p.mu.RUnlock()
+	if !enabled {
+		return
+	}
+	if a, ok := notify.BanEvent("slack-notifier", ev, types); ok {
+		p.dispatch(a)
+	}
+}
+
+// dispatch counts an event for the digest and queues it for every route
+// it matches. Events arriving while a route's queue is full are dropped.
+func (p *SlackNotifierPlugin) dispatch(ev notify.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.stop:
+		return
+	default:
+	}
+
+	p.received++
+	p.digest.add(ev)
+	p.dirty = true
+	for _, r := range p.routes {
+		if !r.matches(ev) {
+			continue
+		}
+		s := p.senderFor(r.ID)
+		select {
+		case s.queue <- ev:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// senderFor returns the sender of a route, starting it on first use.
+// Caller must hold p.mu.
+func (p *SlackNotifierPlugin) senderFor(id string) *sender {
+	s, ok := p.senders[id]
+	if !ok {
+		s = &sender{queue: make(chan notify.Event, queueSize), quit: make(chan struct{})}
+		p.senders[id] = s
+		p.wg.Add(1)
+		go p.sendLoop(id, s)
+	}
+	return s
+}
+
+// stopSender stops the sender of a route, dropping what it had queued.
+// Caller must hold p.mu.
+func (p *SlackNotifierPlugin) stopSender(id string) {
+	if s, ok := p.senders[id]; ok {
+		close(s.quit)
+		delete(p.senders, id)
+	}
+}
+
+// sendLoop delivers a route's events one message at a time, waiting
+// between messages to stay within rate_per_minute. A backlog is sent as up
+// to ten events per message.
+func (p *SlackNotifierPlugin) sendLoop(id string, s *sender) {
+	defer p.wg.Done()
+
+	for {
+		var first notify.Event
+		select {
+		case <-p.stop:
+			return
+		case <-s.quit:
+			return
+		case first = <-s.queue:
+		}
+		batch := []notify.Event{first}
+	drain:
+		for len(batch) < maxEventsPerMessage {
+			select {
+			case ev := <-s.queue:
+				batch = append(batch, ev)
+			default:
+				break drain
+			}
+		}
+
+		p.mu.RLock()
+		r := p.routeByID(id)
+		var route Route
+		if r != nil {
+			route = *r
+		}
+		token := p.config.BotToken
+		interval := time.Minute
+		if p.config.RatePerMinute > 0 {
+			interval = time.Minute / time.Duration(p.config.RatePerMinute)
+		}
+		p.mu.RUnlock()
+		if r == nil {
+			return
+		}
+
+		types := make([]string, 0, len(batch))
+		for _, ev := range batch {
+			types = append(types, ev.Type)
+		}
+		err := p.deliver(route, token, newMessage(batch), s.quit)
+		if err != nil {
+			log.Printf("[slack-notifier] delivery to %s failed: %v", route.Name, err)
+		}
+		p.addDelivery(&Delivery{Time: time.Now().UTC(), RouteID: id, Route: route.Name, Events: types, OK: err == nil, Error: errString(err)})
+
+		select {
+		case <-p.stop:
+			return
+		case <-s.quit:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// deliver posts a message along a route, waiting and trying again when
+// Slack rate limits it
+func (p *SlackNotifierPlugin) deliver(r Route, token string, msg slackMessage, quit chan struct{}) error {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		var wait time.Duration
+		wait, err = post(ctx, r, token, msg)
+		cancel()
+		if wait == 0 {
+			return err
+		}
+		select {
+		case <-p.stop:
+			return err
+		case <-quit:
+			return err
+		case <-time.After(wait):
+		}
+	}
+	return err
+}
+
+// errString returns the message of err, or "" for nil
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// addDelivery records the outcome of a message
+func (p *SlackNotifierPlugin) addDelivery(d *Delivery) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deliveries = append(p.deliveries, d)
+	if len(p.deliveries) > maxDeliveries {
+		p.deliveries = p.deliveries[len(p.deliveries)-maxDeliveries:]
+	}
+}
+
+// digestLoop sends the digest once a day at digest_hour, panel time, until
+// shutdown
+func (p *SlackNotifierPlugin) digestLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		p.mu.RLock()
+		due := p.config.DigestEnabled && now.Hour() == p.config.DigestHour &&
+			now.Sub(p.digest.LastSent) > 23*time.Hour
+		p.mu.RUnlock()
+		if due {
+			p.sendDigest()
+		}
+		if err := p.save(); err != nil {
+			log.Printf("[slack-notifier] failed to save data: %v", err)
+		}
+	}
+}
+
+// sendDigest posts the digest to every route that receives it and starts
+// a new one. It returns the number of routes it reached.
+func (p *SlackNotifierPlugin) sendDigest() int {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var stats statsResult
+	statsErr := p.client().Call(ctx, "stats.get", nil, &stats)
+
+	p.mu.Lock()
+	now := time.Now().UTC()
+	d := p.digest
+	d.LastSent = now
+	p.digest = newDigest(now)
+	p.dirty = true
+	token := p.config.BotToken
+	routes := make([]Route, 0)
+	for _, r := range p.routes {
+		if r.Enabled && r.Digest {
+			routes = append(routes, *r)
+		}
+	}
+	p.mu.Unlock()
+
+	msg := digestMessage(d, &stats, statsErr)
+	reached := 0
+	for _, r := range routes {
+		err := p.deliver(r, token, msg, nil)
+		if err != nil {
+			log.Printf("[slack-notifier] digest to %s failed: %v", r.Name, err)
+		} else {
+			reached++
+		}
+		p.addDelivery(&Delivery{Time: time.Now().UTC(), RouteID: r.ID, Route: r.Name, Events: []string{EventDigest}, OK: err == nil, Error: errString(err)})
+	}
+	return reached
+}
+
+// apply validates a request and copies it onto the route. A route has
+// either a webhook URL or a channel.
+func (req *RouteRequest) apply(r *Route) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	url := strings.TrimSpace(req.WebhookURL)
+	channel := strings.TrimSpace(req.Channel)
+	if url == "" && channel == "" && r.WebhookURL != "" {
+		url = r.WebhookURL
+	}
+	switch {
+	case url != "" && channel != "":
+		return fmt.Errorf("set either webhook_url or channel, not both")
+	case url != "" && !validWebhookURL(url):
+		return fmt.Errorf("webhook_url must be a Slack incoming webhook URL")
+	case url == "" && channel == "":
+		return fmt.Errorf("webhook_url or channel is required")
+	case strings.ContainsAny(channel, " ,"):
+		return fmt.Errorf("channel must be a single channel ID or name")
+	}
+	events := make([]string, 0, len(req.Events))
+	for _, e := range req.Events {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 && !req.Digest {
+		return fmt.Errorf("choose at least one event type or the digest")
+	}
+	severity := req.MinSeverity
+	if severity == "" {
+		severity = notify.SeverityInfo
+	}
+	if _, ok := notify.SeverityRank[severity]; !ok {
+		return fmt.Errorf("min_severity must be info, warning or critical")
+	}
+
+	r.Name = name
+	r.WebhookURL = url
+	r.Channel = channel
+	r.Events = events
+	r.MinSeverity = severity
+	r.Digest = req.Digest
+	if req.Enabled != nil {
+		r.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// redacted returns a copy of the route with the secret part of its
+// webhook URL hidden
+func (r *Route) redacted() Route {
+	c := *r
+	if i := strings.LastIndex(c.WebhookURL, "/"); i > 0 {
+		c.WebhookURL = c.WebhookURL[:i+1] + "…"
+	}
+	return c
+}
+
+// handleStatus reports the event sources, queues and digest
+func (p *SlackNotifierPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	queues := make(map[string]gin.H, len(p.senders))
+	for id, s := range p.senders {
+		queues[id] = gin.H{"queued": len(s.queue), "dropped": s.dropped}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"log_stream":       p.streamOK,
+		"notify_bans":      p.config.NotifyBans,
+		"events_enabled":   p.config.EventsToken != "",
+		"bot_token_set":    p.config.BotToken != "",
+		"received":         p.received,
+		"queues":           queues,
+		"digest_enabled":   p.config.DigestEnabled,
+		"digest_hour":      p.config.DigestHour,
+		"digest_last_sent": p.digest.LastSent,
+	})
+}
+
+// handleListRoutes returns all routes, with their webhook URLs hidden
+func (p *SlackNotifierPlugin) handleListRoutes(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Route, 0, len(p.routes))
+	for _, r := range p.routes {
+		list = append(list, r.redacted())
+	}
+	c.JSON(http.StatusOK, gin.H{"routes": list})
+}
+
+// handleCreateRoute adds a route
+func (p *SlackNotifierPlugin) handleCreateRoute(c *gin.Context) {
+	var req RouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	r := &Route{
+		ID:        newID(),
+		Enabled:   true,
+		CreatedBy: access.Actor(c),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := req.apply(r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	p.routes = append(p.routes, r)
+	p.dirty = true
+	redacted := r.redacted()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, redacted)
+}
+
+// handleUpdateRoute changes a route. An empty webhook_url and channel keep
+// the current webhook.
+func (p *SlackNotifierPlugin) handleUpdateRoute(c *gin.Context) {
+	var req RouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	r := p.routeByID(c.Param("id"))
+	if r == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+	updated := *r
+	if err := req.apply(&updated); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	*r = updated
+	if !r.Enabled {
+		p.stopSender(r.ID)
+	}
+	p.dirty = true
+	redacted := r.redacted()
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, redacted)
+}
+
+// handleDeleteRoute removes a route and drops its queue
+func (p *SlackNotifierPlugin) handleDeleteRoute(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	found := false
+	for i, r := range p.routes {
+		if r.ID == id {
+			p.routes = append(p.routes[:i], p.routes[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if found {
+		p.stopSender(id)
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+	if err := p.save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Route deleted"})
+}
+
+// handleTestRoute posts a test message along a route straight away and
+// reports the result
+func (p *SlackNotifierPlugin) handleTestRoute(c *gin.Context) {
+	p.mu.RLock()
+	r := p.routeByID(c.Param("id"))
+	var route Route
+	if r != nil {
+		route = *r
+	}
+	token := p.config.BotToken
+	p.mu.RUnlock()
+	if r == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+
+	msg := newMessage([]notify.Event{{
+		Source:    "slack-notifier",
+		Type:      EventTest,
+		Severity:  notify.SeverityInfo,
+		Title:     "Test message",
+		Message:   fmt.Sprintf("Sent by %s from the web panel. Events routed to %s will show up here.", access.Actor(c), route.Name),
+		Timestamp: time.Now().UTC(),
+	}})
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+	_, err := post(ctx, route, token, msg)
+	p.addDelivery(&Delivery{Time: time.Now().UTC(), RouteID: route.ID, Route: route.Name, Events: []string{EventTest}, OK: err == nil, Error: errString(err)})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test message sent"})
+}
+
+// handleDeliveries returns recent deliveries, newest first
+func (p *SlackNotifierPlugin) handleDeliveries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > maxDeliveries {
+		limit = 100
+	}
+
+	p.mu.RLock()
+	list := make([]*Delivery, 0, limit)
+	for i := len(p.deliveries) - 1; i >= 0 && len(list) < limit; i-- {
+		list = append(list, p.deliveries[i])
+	}
+	p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": list})
+}
+
+// handleGetDigest returns what the next digest has counted so far
+func (p *SlackNotifierPlugin) handleGetDigest(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.digest)
+}
+
+// handleSendDigest sends the digest now instead of at digest_hour
+func (p *SlackNotifierPlugin) handleSendDigest(c *gin.Context) {
+	reached := p.sendDigest()
+	if err := p.save(); err != nil {
+		log.Printf("[slack-notifier] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Digest sent to %d routes", reached), "routes": reached})
+}
+
+// handleEvents accepts an alert from another plugin or script, sent with
+// the events token in an X-Events-Token header
+func (p *SlackNotifierPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+
+	p.dispatch(ev)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *SlackNotifierPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.BotToken = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *SlackNotifierPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.RatePerMinute < 1 || newConfig.RatePerMinute > 60 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate_per_minute must be between 1 and 60"})
+		return
+	}
+	if newConfig.DigestHour < 0 || newConfig.DigestHour > 23 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "digest_hour must be between 0 and 23"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.BotToken == "" {
+		newConfig.BotToken = p.config.BotToken
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *SlackNotifierPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *SlackNotifierPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,256 @@
+package slacknotifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Slack limits
+const (
+	maxEventsPerMessage = 10
+	maxHeaderLen        = 150
+	maxSectionLen       = 3000
+	maxFields           = 10
+	maxFieldLen         = 2000
+)
+
+// postMessageURL is the Web API method used for channel routes
+const postMessageURL = "https://slack.com/api/chat.postMessage"
+
+// severityEmoji prefix the header of each event
+var severityEmoji = map[string]string{
+	notify.SeverityInfo:     ":information_source:",
+	notify.SeverityWarning:  ":warning:",
+	notify.SeverityCritical: ":rotating_light:",
+}
+
+// textObject is a Block Kit text object
+type textObject struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// block is a Block Kit layout block. Only the fields used by header,
+// section, context and divider blocks are included.
+type block struct {
+	Type     string       `json:"type"`
+	Text     *textObject  `json:"text,omitempty"`
+	Fields   []textObject `json:"fields,omitempty"`
+	Elements []textObject `json:"elements,omitempty"`
+}
+
+// slackMessage is the body of a webhook call or chat.postMessage. Text is
+// the fallback shown in notifications.
+type slackMessage struct {
+	Channel string  `json:"channel,omitempty"`
+	Text    string  `json:"text"`
+	Blocks  []block `json:"blocks"`
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// mrkdwnEscaper escapes the characters Slack treats as control sequences
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escape makes text safe to use in mrkdwn
+func escape(s string) string {
+	return mrkdwnEscaper.Replace(s)
+}
+
+// plain returns a plain_text object
+func plain(s string) *textObject {
+	return &textObject{Type: "plain_text", Text: s, Emoji: true}
+}
+
+// mrkdwn returns a mrkdwn text object
+func mrkdwn(s string) textObject {
+	return textObject{Type: "mrkdwn", Text: s}
+}
+
+// slackDate formats a time that Slack shows in the reader's time zone
+func slackDate(t time.Time) string {
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} {time}|%s>", t.Unix(), t.UTC().Format(time.RFC3339))
+}
+
+// dataValue formats a value from an event's data
+func dataValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, ", ")
+	case []string:
+		return strings.Join(v, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// eventBlocks formats an event as a header, its message, its data as
+// fields, and a context line with where it came from
+func eventBlocks(ev notify.Event) []block {
+	blocks := []block{{
+		Type: "header",
+		Text: plain(truncate(severityEmoji[ev.Severity]+" "+ev.Title, maxHeaderLen)),
+	}}
+	if ev.Message != "" && ev.Message != ev.Title {
+		text := mrkdwn(truncate(escape(ev.Message), maxSectionLen))
+		blocks = append(blocks, block{Type: "section", Text: &text})
+	}
+
+	keys := make([]string, 0, len(ev.Data))
+	for k := range ev.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]textObject, 0, len(keys))
+	for _, k := range keys {
+		if len(fields) == maxFields {
+			break
+		}
+		value := dataValue(ev.Data[k])
+		if value == "" {
+			continue
+		}
+		name := strings.ReplaceAll(k, "_", " ")
+		fields = append(fields, mrkdwn(truncate("*"+escape(name)+"*\n"+escape(value), maxFieldLen)))
+	}
+	if len(fields) > 0 {
+		blocks = append(blocks, block{Type: "section", Fields: fields})
+	}
+
+	footer := slackDate(ev.Timestamp)
+	if ev.Source != "" {
+		footer = escape(ev.Source) + " · " + escape(ev.Type) + " · " + footer
+	}
+	return append(blocks, block{Type: "context", Elements: []textObject{mrkdwn(footer)}})
+}
+
+// newMessage puts events into one message, separated by dividers
+func newMessage(events []notify.Event) slackMessage {
+	msg := slackMessage{Blocks: make([]block, 0, len(events)*5)}
+	titles := make([]string, 0, len(events))
+	for i, ev := range events {
+		if i > 0 {
+			msg.Blocks = append(msg.Blocks, block{Type: "divider"})
+		}
+		msg.Blocks = append(msg.Blocks, eventBlocks(ev)...)
+		titles = append(titles, ev.Title)
+	}
+	msg.Text = truncate(strings.Join(titles, "; "), maxSectionLen)
+	return msg
+}
+
+// slackClient is shared by all deliveries
+var slackClient = &http.Client{Timeout: 15 * time.Second}
+
+// retryAfter reads the wait Slack asks for on a 429 response
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return time.Second
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// postWebhook sends a message to an incoming webhook. When Slack rate
+// limits the request, the time to wait before retrying is returned with
+// the error.
+func postWebhook(ctx context.Context, url string, msg slackMessage) (time.Duration, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := slackClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		wait := retryAfter(resp)
+		return wait, fmt.Errorf("rate limited by Slack for %s", wait)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Webhooks answer errors such as invalid_blocks as plain text
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return 0, fmt.Errorf("slack: %s (%s)", strings.TrimSpace(string(text)), resp.Status)
+	}
+	return 0, nil
+}
+
+// postMessage sends a message to a channel with chat.postMessage
+func postMessage(ctx context.Context, token string, msg slackMessage) (time.Duration, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, postMessageURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := slackClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		wait := retryAfter(resp)
+		return wait, fmt.Errorf("rate limited by Slack for %s", wait)
+	}
+	var out struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("slack: unexpected response (%s)", resp.Status)
+	}
+	if !out.OK {
+		return 0, fmt.Errorf("slack: %s", out.Error)
+	}
+	return 0, nil
+}
+
+// validWebhookURL reports whether url looks like a Slack incoming webhook
+func validWebhookURL(url string) bool {
+	return strings.HasPrefix(url, "https://hooks.slack.com/")
+}
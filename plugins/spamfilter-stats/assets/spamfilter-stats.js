@@ -0,0 +1,237 @@
+/**
+ * Spamfilter Stats Frontend Script
+ *
+ * Shows spamfilter hits per filter as a heat map, the noisiest filters,
+ * filters flagged as broad or stale and the latest hits.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'spamfilter-stats';
+  const PLUGIN_NAME = 'Spamfilter Stats';
+  const PAGE_PATH = '/plugins/spamfilter-stats';
+  const API_BASE = '/api/plugin/spamfilter-stats';
+  const REFRESH_MS = 60000;
+
+  let refreshTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function pct(v) {
+    return v == null ? '-' : `${v}%`;
+  }
+
+  function counts(m) {
+    return Object.entries(m || {}).sort((a, b) => b[1] - a[1]).map(([k, n]) => `${escapeHtml(k)} (${n})`).join(', ') || '-';
+  }
+
+  function filterName(f) {
+    return `<code>${escapeHtml(f.name)}</code> <span class="sfs-desc">${escapeHtml(f.match_type)}, ${escapeHtml(f.targets)}, ${escapeHtml(f.action)}</span>`;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('spamfilter-stats-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'spamfilter-stats-styles';
+    style.textContent = `
+      .sfs-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .sfs-summary { display: grid; grid-template-columns: repeat(auto-fit, minmax(150px, 1fr)); gap: 0.75rem; }
+      .sfs-stat { border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 0.75rem; }
+      .sfs-stat strong { display: block; font-size: 1.4rem; color: var(--text-primary, #cdd6f4); }
+      .sfs-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .sfs-table th, .sfs-table td { text-align: left; padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .sfs-table td { color: var(--text-primary, #cdd6f4); word-break: break-all; }
+      .sfs-desc { color: var(--text-secondary, #a6adc8); font-size: 0.8rem; }
+      .sfs-error { color: var(--error, #f38ba8); }
+      .sfs-flag { color: var(--warning, #f9e2af); font-size: 0.8rem; margin-left: 0.3rem; }
+      .sfs-app select { background: var(--bg-secondary, #181825); color: var(--text-primary, #cdd6f4); border: 1px solid var(--border-primary, #313244); border-radius: 6px; padding: 0.3rem; }
+      .sfs-heatmap { overflow-x: auto; }
+      .sfs-heatmap table { border-collapse: collapse; font-size: 0.8rem; }
+      .sfs-heatmap th { text-align: right; padding-right: 0.5rem; white-space: nowrap; max-width: 220px; overflow: hidden; text-overflow: ellipsis; font-weight: normal; }
+      .sfs-heatmap td { width: 12px; height: 16px; padding: 0; border: 1px solid var(--bg-primary, #11111b); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderHeatmap(hm) {
+    if (!hm.rows.length) return '<div class="sfs-desc">No hits in this range</div>';
+    const label = t => hm.bucket === 'day' ? new Date(t).toLocaleDateString() : new Date(t).toLocaleString();
+    return `
+      <div class="sfs-heatmap">
+        <table>
+          <tbody>
+            ${hm.rows.map(row => `
+              <tr>
+                <th title="${escapeHtml(row.name)}">${escapeHtml(row.name)}</th>
+                ${row.counts.map((n, i) => `<td style="background:${n ? `rgba(243, 139, 168, ${0.15 + 0.85 * n / hm.max})` : 'var(--bg-secondary, #181825)'}" title="${escapeHtml(label(hm.columns[i]))}: ${n} hits"></td>`).join('')}
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      </div>
+      <div class="sfs-desc">Hits per ${hm.bucket}, from ${escapeHtml(label(hm.columns[0]))}; darker is more</div>
+    `;
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#sfs-body');
+    const days = container.querySelector('#sfs-range').value;
+    const bucket = days > 7 ? 'day' : 'hour';
+    try {
+      const [noisy, all, hm, hits] = await Promise.all([
+        api(`/noisiest?days=${days}`),
+        api(`/filters?days=${days}`),
+        api(`/heatmap?days=${days}&bucket=${bucket}`),
+        api('/hits'),
+      ]);
+      body.innerHTML = `
+        <div class="sfs-summary">
+          <div class="sfs-stat"><strong>${all.total_hits}</strong>hits</div>
+          <div class="sfs-stat"><strong>${all.filters.filter(f => f.hits > 0).length}</strong>filters hit</div>
+          <div class="sfs-stat"><strong>${noisy.broad.length}</strong>possibly too broad</div>
+          <div class="sfs-stat"><strong>${noisy.stale.length}</strong>no hits in ${noisy.stale_days} days</div>
+        </div>
+        <div><h3>Hits over time</h3>${renderHeatmap(hm)}</div>
+        <div>
+          <h3>Noisiest filters</h3>
+          <table class="sfs-table">
+            <thead><tr><th>Filter</th><th>Hits</th><th>Share</th><th>Per day</th><th>Sources</th><th>Targets</th></tr></thead>
+            <tbody>
+              ${noisy.noisiest.map(f => `
+                <tr>
+                  <td>${filterName(f)}${f.broad ? '<span class="sfs-flag">broad</span>' : ''}</td>
+                  <td>${f.hits}</td><td>${pct(f.share_percent)}</td><td>${f.hits_per_day}</td><td>${f.unique_sources}</td><td>${counts(f.by_target)}</td>
+                </tr>
+              `).join('') || '<tr><td colspan="6">No hits in this range</td></tr>'}
+            </tbody>
+          </table>
+        </div>
+        <div>
+          <h3>Stale filters</h3>
+          <div class="sfs-desc">Filters set on the IRCd that nothing has matched in ${noisy.stale_days} days</div>
+          <table class="sfs-table">
+            <thead><tr><th>Filter</th><th>Reason</th><th>Set by</th><th>Last hit</th></tr></thead>
+            <tbody>
+              ${noisy.stale.map(f => `
+                <tr><td>${filterName(f)}</td><td>${escapeHtml(f.reason)}</td><td>${escapeHtml(f.set_by)}</td><td>${f.last_hit ? escapeHtml(new Date(f.last_hit).toLocaleString()) : 'never'}</td></tr>
+              `).join('') || '<tr><td colspan="4">No stale filters</td></tr>'}
+            </tbody>
+          </table>
+        </div>
+        <div>
+          <h3>Latest hits</h3>
+          <table class="sfs-table">
+            <thead><tr><th>Time</th><th>Filter</th><th>Target</th><th>Client</th><th>Action</th></tr></thead>
+            <tbody>
+              ${hits.hits.slice(0, 50).map(h => `
+                <tr><td>${escapeHtml(new Date(h.time).toLocaleString())}</td><td><code>${escapeHtml(h.filter)}</code></td><td>${escapeHtml(h.target)}${h.destination ? ` ${escapeHtml(h.destination)}` : ''}</td><td>${escapeHtml(h.client || '-')}</td><td>${escapeHtml(h.action)}</td></tr>
+              `).join('') || '<tr><td colspan="5">No hits seen</td></tr>'}
+            </tbody>
+          </table>
+        </div>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="sfs-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="sfs-app" data-plugin="${PLUGIN_ID}">
+        <div>
+          Show the last
+          <select id="sfs-range">
+            <option value="1">24 hours</option>
+            <option value="7" selected>7 days</option>
+            <option value="30">30 days</option>
+          </select>
+        </div>
+        <div id="sfs-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#sfs-range').addEventListener('change', () => load(container));
+    load(container);
+    clearInterval(refreshTimer);
+    refreshTimer = setInterval(() => {
+      if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) {
+        clearInterval(refreshTimer);
+        return;
+      }
+      load(container);
+    }, REFRESH_MS);
+    return true;
+  }
+
+  function cleanup() {
+    clearInterval(refreshTimer);
+    const style = document.getElementById('spamfilter-stats-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,334 @@
+package spamfilterstats
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxSources limits the sources kept per filter and hour
+const maxSources = 200
+
+// Filter is a spamfilter seen in spamfilter.list or in a match
+type Filter struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	MatchType string     `json:"match_type"`
+	Targets   string     `json:"targets"`
+	Action    string     `json:"action"`
+	Reason    string     `json:"reason,omitempty"`
+	SetBy     string     `json:"set_by,omitempty"`
+	Active    bool       `json:"active"`
+	FirstSeen time.Time  `json:"first_seen"`
+	LastHit   *time.Time `json:"last_hit,omitempty"`
+}
+
+// rpcSpamfilter is a spamfilter as the IRCd reports it, in
+// spamfilter.list and in the tkl object of SPAMFILTER_MATCH
+type rpcSpamfilter struct {
+	Name      string `json:"name"`
+	MatchType string `json:"match_type"`
+	Targets   string `json:"spamfilter_targets"`
+	Action    string `json:"ban_action"`
+	Reason    string `json:"reason"`
+	SetBy     string `json:"set_by"`
+}
+
+// id returns a short ID for the spamfilter. The IRCd tells spamfilters
+// apart by pattern, match type, targets and action, so two filters with
+// the same pattern get different IDs.
+func (s *rpcSpamfilter) id() string {
+	sum := sha1.Sum([]byte(strings.Join([]string{s.MatchType, s.Targets, s.Action, s.Name}, "\x00")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// update copies what the IRCd reports about a spamfilter to f
+func (f *Filter) update(s *rpcSpamfilter) {
+	f.Name = s.Name
+	f.MatchType = s.MatchType
+	f.Targets = s.Targets
+	f.Action = s.Action
+	f.Reason = s.Reason
+	f.SetBy = s.SetBy
+}
+
+// FilterHour holds one filter's hits in one hour
+type FilterHour struct {
+	Hits     int            `json:"hits"`
+	ByTarget map[string]int `json:"by_target,omitempty"`
+	Sources  map[string]int `json:"sources,omitempty"`
+}
+
+// add records a hit on target by source
+func (h *FilterHour) add(target, source string) {
+	h.Hits++
+	h.ByTarget = count(h.ByTarget, target)
+	if source != "" {
+		h.Sources = count(h.Sources, source)
+	}
+}
+
+// merge adds another hour's hits to h
+func (h *FilterHour) merge(o *FilterHour) {
+	h.Hits += o.Hits
+	h.ByTarget = mergeCounts(h.ByTarget, o.ByTarget)
+	h.Sources = mergeCounts(h.Sources, o.Sources)
+}
+
+// Hit is one spamfilter match
+type Hit struct {
+	Time        time.Time `json:"time"`
+	FilterID    string    `json:"filter_id"`
+	Filter      string    `json:"filter"`
+	Target      string    `json:"target"`
+	Destination string    `json:"destination,omitempty"`
+	Client      string    `json:"client,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Action      string    `json:"action"`
+}
+
+// targetType names the spamfilter target a match was on, from the
+// command the IRCd logs with it. Messages and notices are split by
+// whether they went to a channel, as spamfilter targets are.
+func targetType(command, destination string) string {
+	channel := strings.HasPrefix(destination, "#")
+	switch strings.ToUpper(command) {
+	case "PRIVMSG":
+		if channel {
+			return "channel"
+		}
+		return "private"
+	case "NOTICE":
+		if channel {
+			return "channel-notice"
+		}
+		return "private-notice"
+	case "":
+		return "unknown"
+	}
+	return strings.ToLower(command)
+}
+
+// FilterReport is a filter with its hits over a range of hours
+type FilterReport struct {
+	Filter
+	Hits          int            `json:"hits"`
+	SharePercent  *float64       `json:"share_percent"`
+	HitsPerDay    float64        `json:"hits_per_day"`
+	UniqueSources int            `json:"unique_sources"`
+	ByTarget      map[string]int `json:"by_target"`
+	TopSources    map[string]int `json:"top_sources,omitempty"`
+	Broad         bool           `json:"broad"`
+	Stale         bool           `json:"stale"`
+}
+
+// reportOptions are the thresholds used to flag filters in a report
+type reportOptions struct {
+	From         string    // first hour key of the range
+	Days         float64   // length of the range
+	BroadSources int       // sources at which a filter counts as broad; 0 turns it off
+	StaleBefore  time.Time // active filters not hit since then are stale
+	Since        time.Time // when the plugin started counting
+}
+
+// buildReports sums each filter's hits over the range. Active filters
+// are included even without hits, so filters nothing matches show up.
+// The reports are sorted by hits, most first.
+func buildReports(filters map[string]*Filter, hours map[string]map[string]*FilterHour, opt reportOptions) ([]FilterReport, int) {
+	sums := make(map[string]*FilterHour)
+	total := 0
+	for key, hour := range hours {
+		if key < opt.From {
+			continue
+		}
+		for id, h := range hour {
+			sum, ok := sums[id]
+			if !ok {
+				sum = &FilterHour{}
+				sums[id] = sum
+			}
+			sum.merge(h)
+			total += h.Hits
+		}
+	}
+
+	reports := make([]FilterReport, 0, len(filters))
+	for id, f := range filters {
+		sum := sums[id]
+		if sum == nil {
+			if !f.Active {
+				continue
+			}
+			sum = &FilterHour{}
+		}
+		r := FilterReport{
+			Filter:        *f,
+			Hits:          sum.Hits,
+			SharePercent:  percent(sum.Hits, total),
+			UniqueSources: len(sum.Sources),
+			ByTarget:      sum.ByTarget,
+			TopSources:    topCounts(sum.Sources, 10),
+		}
+		if r.ByTarget == nil {
+			r.ByTarget = make(map[string]int)
+		}
+		if opt.Days > 0 {
+			r.HitsPerDay = round1(float64(sum.Hits) / opt.Days)
+		}
+		r.Broad = opt.BroadSources > 0 && r.UniqueSources >= opt.BroadSources
+		r.Stale = f.stale(opt.StaleBefore, opt.Since)
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Hits != reports[j].Hits {
+			return reports[i].Hits > reports[j].Hits
+		}
+		return reports[i].Name < reports[j].Name
+	})
+	return reports, total
+}
+
+// stale reports whether an active filter has gone without hits since
+// before. Filters are only judged once they have been watched that long.
+func (f *Filter) stale(before, since time.Time) bool {
+	if !f.Active {
+		return false
+	}
+	watched := since
+	if f.FirstSeen.After(watched) {
+		watched = f.FirstSeen
+	}
+	if watched.After(before) {
+		return false
+	}
+	return f.LastHit == nil || f.LastHit.Before(before)
+}
+
+// Heatmap holds hit counts of filters per time bucket
+type Heatmap struct {
+	Bucket  string       `json:"bucket"`
+	Columns []string     `json:"columns"`
+	Rows    []HeatmapRow `json:"rows"`
+	Max     int          `json:"max"`
+}
+
+// HeatmapRow is one filter's hits per bucket
+type HeatmapRow struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Counts []int  `json:"counts"`
+	Total  int    `json:"total"`
+}
+
+// buildHeatmap lays out the hits of the given filters in n buckets of
+// size step, the last of which holds now
+func buildHeatmap(filters []*Filter, hours map[string]map[string]*FilterHour, step time.Duration, n int, now time.Time) *Heatmap {
+	end := now.UTC().Truncate(step).Add(step)
+	start := end.Add(-time.Duration(n) * step)
+
+	hm := &Heatmap{Bucket: "hour", Columns: make([]string, n), Rows: make([]HeatmapRow, 0, len(filters))}
+	if step == 24*time.Hour {
+		hm.Bucket = "day"
+	}
+	for i := range hm.Columns {
+		hm.Columns[i] = start.Add(time.Duration(i) * step).Format(time.RFC3339)
+	}
+	index := make(map[string]int, len(filters))
+	for _, f := range filters {
+		index[f.ID] = len(hm.Rows)
+		hm.Rows = append(hm.Rows, HeatmapRow{ID: f.ID, Name: f.Name, Counts: make([]int, n)})
+	}
+
+	for key, hour := range hours {
+		t, err := time.Parse(time.RFC3339, key)
+		if err != nil || t.Before(start) || !t.Before(end) {
+			continue
+		}
+		col := int(t.Sub(start) / step)
+		for id, h := range hour {
+			if row, ok := index[id]; ok {
+				hm.Rows[row].Counts[col] += h.Hits
+				hm.Rows[row].Total += h.Hits
+			}
+		}
+	}
+	for _, row := range hm.Rows {
+		for _, c := range row.Counts {
+			if c > hm.Max {
+				hm.Max = c
+			}
+		}
+	}
+	return hm
+}
+
+// hourKey returns the key of the hour holding t
+func hourKey(t time.Time) string {
+	return t.UTC().Truncate(time.Hour).Format(time.RFC3339)
+}
+
+// count adds one to key in a map, creating the map if needed. New keys
+// are dropped once the map holds maxSources.
+func count(m map[string]int, key string) map[string]int {
+	if m == nil {
+		m = make(map[string]int)
+	}
+	if _, ok := m[key]; ok || len(m) < maxSources {
+		m[key]++
+	}
+	return m
+}
+
+// mergeCounts adds the counts in o to m
+func mergeCounts(m, o map[string]int) map[string]int {
+	for key, n := range o {
+		if m == nil {
+			m = make(map[string]int)
+		}
+		m[key] += n
+	}
+	return m
+}
+
+// topCounts keeps the n largest counts, adding the rest together as
+// "other"
+func topCounts(m map[string]int, n int) map[string]int {
+	if len(m) <= n {
+		return m
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	out := make(map[string]int, n+1)
+	for i, key := range keys {
+		if i < n {
+			out[key] = m[key]
+		} else {
+			out["other"] += m[key]
+		}
+	}
+	return out
+}
+
+// percent returns n as a percentage of of, or nil if of is zero
+func percent(n, of int) *float64 {
+	if of == 0 {
+		return nil
+	}
+	v := round1(float64(n) * 100 / float64(of))
+	return &v
+}
+
+// round1 rounds to one decimal place
+func round1(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
@@ -0,0 +1,678 @@
+// Spamfilter Stats Plugin for UnrealIRCd Web Panel
+// Records every spamfilter match from the IRCd log and reports hits per
+// filter over time, the noisiest filters and the ones nothing matches
+
+package spamfilterstats
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on what is kept and returned
+const (
+	maxRecentHits   = 200
+	maxHeatmapRows  = 20
+	maxHeatmapHours = 14 * 24
+)
+
+// SpamfilterStatsPlugin implements the Plugin interface
+type SpamfilterStatsPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	since        time.Time
+	filters      map[string]*Filter
+	hours        map[string]map[string]*FilterHour
+	recent       []Hit
+	lastPoll     time.Time
+	pollErr      string
+	streamOK     bool
+	cancelStream context.CancelFunc
+	dirty        bool
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	DataDir       string `json:"data_dir"`
+	StreamURL     string `json:"stream_url"`
+	LogSources    string `json:"log_sources"`
+	PollInterval  int    `json:"poll_interval"`
+	RetentionDays int    `json:"retention_days"`
+	StaleDays     int    `json:"stale_days"`
+	BroadSources  int    `json:"broad_sources"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Since   time.Time                         `json:"since"`
+	Filters map[string]*Filter                `json:"filters"`
+	Hours   map[string]map[string]*FilterHour `json:"hours"`
+	Recent  []Hit                             `json:"recent"`
+}
+
+// matchEvent holds what we need from a SPAMFILTER_MATCH log entry
+type matchEvent struct {
+	Client *struct {
+		Name     string `json:"name"`
+		Hostname string `json:"hostname"`
+		IP       string `json:"ip"`
+		User     struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	} `json:"client"`
+	TKL         *rpcSpamfilter `json:"tkl"`
+	Command     string         `json:"command"`
+	Destination string         `json:"destination"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &SpamfilterStatsPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/spamfilter-stats",
+			StreamURL:     "wss://127.0.0.1:8600/",
+			LogSources:    "all,!debug",
+			PollInterval:  300,
+			RetentionDays: 30,
+			StaleDays:     30,
+			BroadSources:  25,
+		},
+		filters: make(map[string]*Filter),
+		hours:   make(map[string]map[string]*FilterHour),
+	}
+}
+
+// Info returns plugin metadata
+func (p *SpamfilterStatsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Spamfilter Stats",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Counts spamfilter hits per filter to find noisy and stale filters",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *SpamfilterStatsPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[spamfilter-stats] failed to load data: %v", err)
+	}
+	if data.Filters != nil {
+		p.filters = data.Filters
+	}
+	if data.Hours != nil {
+		p.hours = data.Hours
+	}
+	p.recent = data.Recent
+	p.since = data.Since
+	if p.since.IsZero() {
+		p.since = time.Now().UTC()
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "spamfilter-stats-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		reports, total := p.reports(1)
+		hit, stale := 0, 0
+		for _, r := range reports {
+			if r.Hits > 0 {
+				hit++
+			}
+			if r.Stale {
+				stale++
+			}
+		}
+		content := map[string]interface{}{
+			"hits_24h":        total,
+			"filters_hit_24h": hit,
+			"stale":           stale,
+		}
+		if len(reports) > 0 && reports[0].Hits > 0 {
+			content["noisiest"] = reports[0].Name
+		}
+		if !p.streamOK {
+			content["status"] = "Log stream not connected"
+		} else if p.pollErr != "" {
+			content["status"] = p.pollErr
+		}
+		return plugins.DashboardCard{
+			Title:   "Spamfilter Hits",
+			Icon:    "Filter",
+			Content: content,
+			Order:   94,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.pollLoop()
+	go p.streamLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *SpamfilterStatsPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *SpamfilterStatsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/spamfilter-stats")
+	{
+		plugin.GET("/filters", p.handleFilters)
+		plugin.GET("/noisiest", p.handleNoisiest)
+		plugin.GET("/heatmap", p.handleHeatmap)
+		plugin.GET("/hits", p.handleHits)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file hits are kept in
+func (p *SpamfilterStatsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "spamfilters.json")
+}
+
+// save writes filters and hits to disk if they changed
+func (p *SpamfilterStatsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	data := storeData{Since: p.since, Filters: p.filters, Hours: p.hours, Recent: p.recent}
+	if err := storage.SaveJSON(p.storePath(), data); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *SpamfilterStatsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// filter returns the filter the IRCd reports, adding it if it is new.
+// Caller must hold p.mu.
+func (p *SpamfilterStatsPlugin) filter(s *rpcSpamfilter, now time.Time) *Filter {
+	id := s.id()
+	f, ok := p.filters[id]
+	if !ok {
+		f = &Filter{ID: id, FirstSeen: now}
+		p.filters[id] = f
+	}
+	f.update(s)
+	p.dirty = true
+	return f
+}
+
+// reports sums each filter's hits over the last days. Caller must hold
+// p.mu.
+func (p *SpamfilterStatsPlugin) reports(days int) ([]FilterReport, int) {
+	now := time.Now()
+	return buildReports(p.filters, p.hours, reportOptions{
+		From:         hourKey(now.Add(-time.Duration(days*24-1) * time.Hour)),
+		Days:         float64(days),
+		BroadSources: p.config.BroadSources,
+		StaleBefore:  now.AddDate(0, 0, -p.config.StaleDays),
+		Since:        p.since,
+	})
+}
+
+// streamLoop follows the IRCd log until shutdown
+func (p *SpamfilterStatsPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *SpamfilterStatsPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[spamfilter-stats] log stream: %v", err)
+	}
+}
+
+// handleEvent records a spamfilter match
+func (p *SpamfilterStatsPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	if ev.EventID != "SPAMFILTER_MATCH" {
+		return
+	}
+	var me matchEvent
+	if err := json.Unmarshal(ev.Raw, &me); err != nil || me.TKL == nil {
+		return
+	}
+	now := time.Now().UTC()
+
+	hit := Hit{
+		Time:        now,
+		Filter:      me.TKL.Name,
+		Target:      targetType(me.Command, me.Destination),
+		Destination: me.Destination,
+		Action:      me.TKL.Action,
+	}
+	if cl := me.Client; cl != nil {
+		hit.Client = cl.Name + "!" + cl.User.Username + "@" + cl.Hostname
+		hit.Source = cl.IP
+		if hit.Source == "" {
+			hit.Source = cl.Hostname
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f := p.filter(me.TKL, now)
+	f.LastHit = &now
+	hit.FilterID = f.ID
+
+	key := hourKey(now)
+	hour, ok := p.hours[key]
+	if !ok {
+		hour = make(map[string]*FilterHour)
+		p.hours[key] = hour
+	}
+	h, ok := hour[f.ID]
+	if !ok {
+		h = &FilterHour{}
+		hour[f.ID] = h
+	}
+	h.add(hit.Target, hit.Source)
+
+	p.recent = append(p.recent, hit)
+	if len(p.recent) > maxRecentHits {
+		p.recent = p.recent[len(p.recent)-maxRecentHits:]
+	}
+}
+
+// pollLoop lists the spamfilters every poll_interval until shutdown
+func (p *SpamfilterStatsPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.poll()
+		if err := p.save(); err != nil {
+			log.Printf("[spamfilter-stats] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// poll marks which filters are set on the IRCd, so filters without hits
+// are known too, and drops hits past the retention
+func (p *SpamfilterStatsPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcSpamfilter `json:"list"`
+	}
+	err := p.client().Call(ctx, "spamfilter.list", nil, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now().UTC()
+	p.lastPoll = now
+	p.prune(now)
+	if err != nil {
+		p.pollErr = err.Error()
+		log.Printf("[spamfilter-stats] failed to list spamfilters: %v", err)
+		return
+	}
+	p.pollErr = ""
+
+	active := make(map[string]bool, len(result.List))
+	for i := range result.List {
+		f := p.filter(&result.List[i], now)
+		f.Active = true
+		active[f.ID] = true
+	}
+	for id, f := range p.filters {
+		if f.Active && !active[id] {
+			f.Active = false
+			p.dirty = true
+		}
+	}
+}
+
+// prune drops hours past the retention, and removed filters with no hits
+// left. Caller must hold p.mu.
+func (p *SpamfilterStatsPlugin) prune(now time.Time) {
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays)
+	cutoffKey := hourKey(cutoff)
+	for key := range p.hours {
+		if key < cutoffKey {
+			delete(p.hours, key)
+			p.dirty = true
+		}
+	}
+	for id, f := range p.filters {
+		if !f.Active && (f.LastHit == nil || f.LastHit.Before(cutoff)) {
+			delete(p.filters, id)
+			p.dirty = true
+		}
+	}
+}
+
+// days parses the ?days= parameter, 7 by default or the retention if
+// shorter. It writes the error response and returns false if it is out
+// of range. Caller must hold p.mu.
+func (p *SpamfilterStatsPlugin) days(c *gin.Context) (int, bool) {
+	days := 7
+	if days > p.config.RetentionDays {
+		days = p.config.RetentionDays
+	}
+	if s := c.Query("days"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > p.config.RetentionDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and " + strconv.Itoa(p.config.RetentionDays)})
+			return 0, false
+		}
+		days = v
+	}
+	return days, true
+}
+
+// handleFilters returns every filter with its hits over the last ?days=
+// days, most hits first
+func (p *SpamfilterStatsPlugin) handleFilters(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	days, ok := p.days(c)
+	if !ok {
+		return
+	}
+	reports, total := p.reports(days)
+	c.JSON(http.StatusOK, gin.H{
+		"days":       days,
+		"total_hits": total,
+		"filters":    reports,
+	})
+}
+
+// handleNoisiest returns the ?limit= filters with the most hits over the
+// last ?days= days, the filters flagged as broad and the stale ones
+func (p *SpamfilterStatsPlugin) handleNoisiest(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	days, ok := p.days(c)
+	if !ok {
+		return
+	}
+	limit := 10
+	if s := c.Query("limit"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = v
+	}
+
+	reports, total := p.reports(days)
+	noisiest := make([]FilterReport, 0, limit)
+	broad := make([]FilterReport, 0)
+	stale := make([]FilterReport, 0)
+	for _, r := range reports {
+		if r.Hits > 0 && len(noisiest) < limit {
+			noisiest = append(noisiest, r)
+		}
+		if r.Broad {
+			broad = append(broad, r)
+		}
+		if r.Stale {
+			stale = append(stale, r)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"days":       days,
+		"total_hits": total,
+		"noisiest":   noisiest,
+		"broad":      broad,
+		"stale":      stale,
+		"stale_days": p.config.StaleDays,
+	})
+}
+
+// handleHeatmap returns hits per hour or day (?bucket=) over the last
+// ?days= days, for the filters in ?filter= or else the noisiest ones
+func (p *SpamfilterStatsPlugin) handleHeatmap(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	days, ok := p.days(c)
+	if !ok {
+		return
+	}
+	step, n := time.Hour, days*24
+	switch c.DefaultQuery("bucket", "hour") {
+	case "hour":
+		if n > maxHeatmapHours {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hourly heat maps cover at most " + strconv.Itoa(maxHeatmapHours/24) + " days"})
+			return
+		}
+	case "day":
+		step, n = 24*time.Hour, days
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be hour or day"})
+		return
+	}
+
+	filters := make([]*Filter, 0, maxHeatmapRows)
+	if ids := lists.Split(c.Query("filter")); len(ids) > 0 {
+		for _, id := range ids {
+			f, ok := p.filters[id]
+			if !ok {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Filter not found: " + id})
+				return
+			}
+			filters = append(filters, f)
+		}
+	} else {
+		reports, _ := p.reports(days)
+		for _, r := range reports {
+			if r.Hits == 0 || len(filters) == maxHeatmapRows {
+				break
+			}
+			filters = append(filters, p.filters[r.ID])
+		}
+	}
+	c.JSON(http.StatusOK, buildHeatmap(filters, p.hours, step, n, time.Now()))
+}
+
+// handleHits returns the latest hits, newest first, of the filter in
+// ?filter= or of all filters
+func (p *SpamfilterStatsPlugin) handleHits(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	id := c.Query("filter")
+	list := make([]Hit, 0)
+	for i := len(p.recent) - 1; i >= 0; i-- {
+		if id == "" || p.recent[i].FilterID == id {
+			list = append(list, p.recent[i])
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"hits": list})
+}
+
+// handleStatus returns stream and poll state
+func (p *SpamfilterStatsPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	active := 0
+	for _, f := range p.filters {
+		if f.Active {
+			active++
+		}
+	}
+	status := gin.H{
+		"streaming":      p.streamOK,
+		"since":          p.since,
+		"filters":        len(p.filters),
+		"active_filters": active,
+		"error":          p.pollErr,
+		"retention_days": p.config.RetentionDays,
+	}
+	if !p.lastPoll.IsZero() {
+		status["last_poll"] = p.lastPoll
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *SpamfilterStatsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *SpamfilterStatsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.PollInterval < 60 || newConfig.PollInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poll_interval must be between 60 and 3600 seconds"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 365"})
+		return
+	}
+	if newConfig.StaleDays < 1 || newConfig.StaleDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stale_days must be between 1 and 365"})
+		return
+	}
+	if newConfig.BroadSources < 0 || newConfig.BroadSources > 10000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "broad_sources must be between 0 and 10000"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *SpamfilterStatsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *SpamfilterStatsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
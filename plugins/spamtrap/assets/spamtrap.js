@@ -0,0 +1,277 @@
+/**
+ * Spamtrap Frontend Script
+ *
+ * Manage trap nicks and channels and review who walked into them.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'spamtrap';
+  const PLUGIN_NAME = 'Spamtrap';
+  const PAGE_PATH = '/plugins/spamtrap';
+  const API_BASE = '/api/plugin/spamtrap';
+
+  const ACTIONS = ['default', 'none', 'kill', 'gline', 'gzline', 'shun'];
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function actionOptions(selected) {
+    return ACTIONS.map(a => `<option value="${a}" ${a === selected ? 'selected' : ''}>${a === 'default' ? 'Policy default' : a}</option>`).join('');
+  }
+
+  function injectStyles() {
+    if (document.getElementById('spamtrap-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'spamtrap-styles';
+    style.textContent = `
+      .strap-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .strap-form { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+      .strap-app input, .strap-app select {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.35rem 0.6rem;
+      }
+      .strap-app button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        cursor: pointer;
+      }
+      .strap-table { width: 100%; border-collapse: collapse; }
+      .strap-table th, .strap-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        overflow-wrap: anywhere;
+      }
+      .strap-status { font-size: 0.9rem; }
+      .strap-actioned { color: var(--error, #f38ba8); }
+      .strap-exempt { color: var(--text-muted, #6c7086); }
+      .strap-warning { color: var(--warning, #f9e2af); }
+      .strap-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function loadStatus(container) {
+    const body = container.querySelector('#strap-status');
+    try {
+      const s = await api('GET', '/status');
+      body.innerHTML = `
+        Auto-action policy: <strong>${escapeHtml(s.auto_action)}</strong>.
+        ${s.log_stream ? '' : '<span class="strap-warning">Not connected to the IRCd log stream; joins and nick use are not being seen.</span>'}
+        ${s.reporting ? '' : '<span class="strap-warning">No report token set; messages to trap nicks cannot be reported.</span>'}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="strap-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadTraps(container) {
+    const body = container.querySelector('#strap-traps');
+    try {
+      const data = await api('GET', '/traps');
+      if (data.traps.length === 0) {
+        body.innerHTML = '<p>No traps yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="strap-table">
+          <thead><tr><th>Trap</th><th>Kind</th><th>Action</th><th>Hits</th><th>Last hit</th><th>Note</th><th></th></tr></thead>
+          <tbody>
+            ${data.traps.map(t => `
+              <tr>
+                <td><code>${escapeHtml(t.name)}</code></td>
+                <td>${escapeHtml(t.kind)}</td>
+                <td><select data-action="${escapeHtml(t.id)}">${actionOptions(t.action)}</select></td>
+                <td>${t.hits}</td>
+                <td>${t.last_hit ? escapeHtml(new Date(t.last_hit).toLocaleString()) : ''}</td>
+                <td>${escapeHtml(t.note || '')}</td>
+                <td><button data-delete="${escapeHtml(t.id)}">Delete</button></td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+      const byId = Object.fromEntries(data.traps.map(t => [t.id, t]));
+      body.querySelectorAll('select[data-action]').forEach(sel => {
+        sel.addEventListener('change', async () => {
+          const t = byId[sel.dataset.action];
+          try {
+            await api('PUT', `/traps/${t.id}`, { kind: t.kind, name: t.name, note: t.note || '', action: sel.value });
+            t.action = sel.value;
+          } catch (e) {
+            alert(e.message);
+            sel.value = t.action;
+          }
+        });
+      });
+      body.querySelectorAll('button[data-delete]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          if (!confirm('Remove this trap? Its hits are kept.')) return;
+          try {
+            await api('DELETE', `/traps/${btn.dataset.delete}`);
+            loadTraps(container);
+          } catch (e) {
+            alert(e.message);
+          }
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="strap-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadHits(container) {
+    const body = container.querySelector('#strap-hits');
+    try {
+      const data = await api('GET', '/hits?limit=200');
+      if (data.hits.length === 0) {
+        body.innerHTML = '<p>Nobody has walked into a trap yet.</p>';
+        return;
+      }
+      body.innerHTML = `
+        <table class="strap-table">
+          <thead><tr><th>Time</th><th>Trap</th><th>Kind</th><th>Client</th><th>Message</th><th>Action</th></tr></thead>
+          <tbody>
+            ${data.hits.map(h => `
+              <tr class="${h.action ? 'strap-actioned' : h.exempt ? 'strap-exempt' : ''}">
+                <td>${escapeHtml(new Date(h.time).toLocaleString())}</td>
+                <td><code>${escapeHtml(h.trap)}</code></td>
+                <td>${escapeHtml(h.kind)}</td>
+                <td>${escapeHtml(h.nick)}${h.ident ? '!' + escapeHtml(h.ident) : ''}${h.host ? '@' + escapeHtml(h.host) : ''}${h.ip ? `<br><small>${escapeHtml(h.ip)}</small>` : ''}</td>
+                <td>${escapeHtml(h.message || '')}</td>
+                <td>${h.action ? `${escapeHtml(h.action)} <small>${escapeHtml(h.result || 'pending')}</small>` : h.exempt ? `exempt (${escapeHtml(h.exempt)})` : ''}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="strap-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="strap-app" data-plugin="${PLUGIN_ID}">
+        <div class="strap-status" id="strap-status"></div>
+        <h3>Traps</h3>
+        <form class="strap-form" id="strap-add">
+          <select name="kind">
+            <option value="channel">Channel</option>
+            <option value="nick">Nick</option>
+          </select>
+          <input name="name" placeholder="#trap or TrapNick (wildcards allowed)" required>
+          <select name="action">${actionOptions('default')}</select>
+          <input name="note" placeholder="Note">
+          <button type="submit">Add trap</button>
+        </form>
+        <div id="strap-traps">Loading...</div>
+        <h3>Event log</h3>
+        <div id="strap-hits">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#strap-add').addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const f = e.target.elements;
+      try {
+        await api('POST', '/traps', {
+          kind: f.kind.value,
+          name: f.name.value,
+          action: f.action.value,
+          note: f.note.value
+        });
+        e.target.reset();
+        loadTraps(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    loadStatus(container);
+    loadTraps(container);
+    loadHits(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('spamtrap-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
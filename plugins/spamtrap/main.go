@@ -0,0 +1,943 @@
+// Spamtrap Plugin for UnrealIRCd Web Panel
+// Manages trap nicks and channels, records everyone who messages, joins or
+// takes them and optionally acts on them automatically
+
+package spamtrap
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Trap kinds
+const (
+	TrapNick    = "nick"
+	TrapChannel = "channel"
+)
+
+// Hit kinds
+const (
+	HitJoin    = "join"
+	HitMessage = "message"
+	HitNickUse = "nick"
+)
+
+// Actions. ActionDefault on a trap means the policy's auto_action is used.
+const (
+	ActionDefault = "default"
+	ActionNone    = "none"
+	ActionKill    = "kill"
+	ActionGline   = "gline"
+	ActionGzline  = "gzline"
+	ActionShun    = "shun"
+)
+
+var validActions = map[string]bool{
+	ActionNone:   true,
+	ActionKill:   true,
+	ActionGline:  true,
+	ActionGzline: true,
+	ActionShun:   true,
+}
+
+// SpamtrapPlugin implements the Plugin interface
+type SpamtrapPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	traps        []*Trap
+	hits         []*Hit
+	acted        map[string]time.Time
+	dirty        bool
+	streamOK     bool
+	cancelStream context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL          string `json:"rpc_url"`
+	RPCUser         string `json:"rpc_user"`
+	RPCPassword     string `json:"rpc_password"`
+	RPCInsecure     bool   `json:"rpc_insecure"`
+	StreamURL       string `json:"stream_url"`
+	LogSources      string `json:"log_sources"`
+	DataDir         string `json:"data_dir"`
+	AutoAction      string `json:"auto_action"`
+	ActionThreshold int    `json:"action_threshold"`
+	ActionWindow    int    `json:"action_window"`
+	ActionDuration  string `json:"action_duration"`
+	ActionReason    string `json:"action_reason"`
+	ExemptOpers     bool   `json:"exempt_opers"`
+	ExemptLoggedIn  bool   `json:"exempt_logged_in"`
+	ExemptMasks     string `json:"exempt_masks"`
+	ReportToken     string `json:"report_token"`
+	AlertWebhook    string `json:"alert_webhook"`
+	MaxHits         int    `json:"max_hits"`
+}
+
+// Trap is a nick or channel nobody legitimate should use
+type Trap struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Action    string    `json:"action"`
+	Note      string    `json:"note,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TrapRequest is the body of a create or update request
+type TrapRequest struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Note   string `json:"note"`
+}
+
+// Hit is a client caught by a trap
+type Hit struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	TrapID  string    `json:"trap_id"`
+	Trap    string    `json:"trap"`
+	Kind    string    `json:"kind"`
+	Nick    string    `json:"nick"`
+	Ident   string    `json:"ident,omitempty"`
+	Host    string    `json:"host,omitempty"`
+	IP      string    `json:"ip,omitempty"`
+	Account string    `json:"account,omitempty"`
+	Server  string    `json:"server,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Exempt  string    `json:"exempt,omitempty"`
+	Action  string    `json:"action,omitempty"`
+	Result  string    `json:"result,omitempty"`
+}
+
+// Report is a message to a trap nick, sent in by the bot holding it
+type Report struct {
+	Trap    string `json:"trap"`
+	Nick    string `json:"nick"`
+	Ident   string `json:"ident"`
+	Host    string `json:"host"`
+	IP      string `json:"ip"`
+	Message string `json:"message"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Traps []*Trap `json:"traps"`
+	Hits  []*Hit  `json:"hits"`
+}
+
+// trapClient is the subset of the UnrealIRCd client object we need
+type trapClient struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	User     struct {
+		Username  string `json:"username"`
+		Account   string `json:"account"`
+		Operlogin string `json:"operlogin"`
+	} `json:"user"`
+}
+
+// trapEvent holds the objects we need from a join, nick change or connect
+// log entry
+type trapEvent struct {
+	LogSource string      `json:"log_source"`
+	NewNick   string      `json:"new_nick"`
+	Client    *trapClient `json:"client"`
+	Channel   *struct {
+		Name string `json:"name"`
+	} `json:"channel"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &SpamtrapPlugin{
+		config: Config{
+			RPCURL:          "https://127.0.0.1:8600/api",
+			StreamURL:       "wss://127.0.0.1:8600/",
+			LogSources:      "join,nick,connect",
+			DataDir:         "data/plugins/spamtrap",
+			AutoAction:      ActionNone,
+			ActionThreshold: 1,
+			ActionWindow:    60,
+			ActionDuration:  "1d",
+			ActionReason:    "Spamtrap triggered",
+			ExemptOpers:     true,
+			ExemptLoggedIn:  true,
+			MaxHits:         5000,
+		},
+		traps: make([]*Trap, 0),
+		hits:  make([]*Hit, 0),
+		acted: make(map[string]time.Time),
+	}
+}
+
+// Info returns plugin metadata
+func (p *SpamtrapPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Spamtrap",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Trap nicks and channels that record and act on spambots",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *SpamtrapPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[spamtrap] failed to load data: %v", err)
+	}
+	if data.Traps != nil {
+		p.traps = data.Traps
+	}
+	if data.Hits != nil {
+		p.hits = data.Hits
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "spamtrap-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		since := time.Now().Add(-24 * time.Hour)
+		hits, actions := 0, 0
+		for i := len(p.hits) - 1; i >= 0 && p.hits[i].Time.After(since); i-- {
+			hits++
+			if p.hits[i].Action != "" {
+				actions++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "Spamtraps",
+			Icon:  "Bug",
+			Content: map[string]interface{}{
+				"traps":       len(p.traps),
+				"hits_24h":    hits,
+				"actions_24h": actions,
+				"streaming":   p.streamOK,
+			},
+			Order: 49,
+			Size:  "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.streamLoop()
+	go p.saveLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *SpamtrapPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *SpamtrapPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/spamtrap")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/traps", p.handleListTraps)
+		plugin.POST("/traps", p.handleCreateTrap)
+		plugin.PUT("/traps/:id", p.handleUpdateTrap)
+		plugin.DELETE("/traps/:id", p.handleDeleteTrap)
+		plugin.GET("/hits", p.handleHits)
+		plugin.POST("/report", p.handleReport)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *SpamtrapPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "spamtrap.json")
+}
+
+// save persists the state if it changed
+func (p *SpamtrapPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Traps: p.traps, Hits: p.hits}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *SpamtrapPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// findTrap returns the trap of kind matching name. Caller must hold p.mu.
+func (p *SpamtrapPlugin) findTrap(kind, name string) *Trap {
+	for _, t := range p.traps {
+		if t.Kind == kind && ircname.MatchMask(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// trapByID returns the trap with the given ID. Caller must hold p.mu.
+func (p *SpamtrapPlugin) trapByID(id string) *Trap {
+	for _, t := range p.traps {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// streamLoop follows join, nick and connect log events until shutdown
+func (p *SpamtrapPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *SpamtrapPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[spamtrap] log stream: %v", err)
+	}
+}
+
+// saveLoop forgets expired auto-actions and saves the state every minute
+// until shutdown
+func (p *SpamtrapPlugin) saveLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			cutoff := time.Now().Add(-p.actionWindow())
+			for key, t := range p.acted {
+				if t.Before(cutoff) {
+					delete(p.acted, key)
+				}
+			}
+			p.mu.Unlock()
+
+			if err := p.save(); err != nil {
+				log.Printf("[spamtrap] failed to save data: %v", err)
+			}
+		}
+	}
+}
+
+// handleEvent checks joins against trap channels, and nick changes and
+// connects against trap nicks
+func (p *SpamtrapPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	var kind, trapKind, name string
+	switch {
+	case strings.HasSuffix(ev.EventID, "_JOIN"):
+		kind, trapKind = HitJoin, TrapChannel
+	case strings.HasSuffix(ev.EventID, "_NICK_CHANGE"), strings.HasSuffix(ev.EventID, "_CLIENT_CONNECT"):
+		kind, trapKind = HitNickUse, TrapNick
+	default:
+		return
+	}
+
+	var te trapEvent
+	if err := json.Unmarshal(ev.Raw, &te); err != nil || te.Client == nil {
+		return
+	}
+	switch {
+	case kind == HitJoin && te.Channel != nil:
+		name = te.Channel.Name
+	case kind == HitNickUse && te.NewNick != "":
+		name = te.NewNick
+	case kind == HitNickUse:
+		name = te.Client.Name
+	default:
+		return
+	}
+
+	p.mu.RLock()
+	trap := p.findTrap(trapKind, name)
+	p.mu.RUnlock()
+	if trap == nil {
+		return
+	}
+
+	h := newHit(trap, kind, te.Client)
+	h.Server = te.LogSource
+	if kind == HitNickUse {
+		h.Nick = name
+	}
+	if t, err := time.Parse(time.RFC3339, ev.Timestamp); err == nil {
+		h.Time = t.UTC()
+	}
+	p.record(h, te.Client)
+}
+
+// newHit creates a hit on trap for the given client
+func newHit(trap *Trap, kind string, cl *trapClient) *Hit {
+	return &Hit{
+		ID:      newID(),
+		Time:    time.Now().UTC(),
+		TrapID:  trap.ID,
+		Trap:    trap.Name,
+		Kind:    kind,
+		Nick:    cl.Name,
+		Ident:   cl.User.Username,
+		Host:    cl.Hostname,
+		IP:      cl.IP,
+		Account: cl.User.Account,
+	}
+}
+
+// exemptReason returns why a client is exempt from auto-actions, or "".
+// Caller must hold p.mu.
+func (p *SpamtrapPlugin) exemptReason(cl *trapClient) string {
+	if p.config.ExemptOpers && cl.User.Operlogin != "" {
+		return "oper"
+	}
+	if p.config.ExemptLoggedIn && cl.User.Account != "" && cl.User.Account != "0" {
+		return "logged in"
+	}
+	for _, mask := range lists.Split(p.config.ExemptMasks) {
+		if (cl.Hostname != "" && ircname.MatchMask(mask, cl.Hostname)) || (cl.IP != "" && ircname.MatchMask(mask, cl.IP)) {
+			return "mask " + mask
+		}
+	}
+	return ""
+}
+
+// trapAction returns the action a hit on trap should lead to. Caller must
+// hold p.mu.
+func (p *SpamtrapPlugin) trapAction(trap *Trap) string {
+	if trap.Action == "" || trap.Action == ActionDefault {
+		if validActions[p.config.AutoAction] {
+			return p.config.AutoAction
+		}
+		return ActionNone
+	}
+	return trap.Action
+}
+
+// actionWindow returns the period over which hits are counted for the
+// auto-action policy. Caller must hold p.mu.
+func (p *SpamtrapPlugin) actionWindow() time.Duration {
+	minutes := p.config.ActionWindow
+	if minutes < 1 {
+		minutes = 1
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// record stores a hit, applies the auto-action policy and sends an alert
+func (p *SpamtrapPlugin) record(h *Hit, cl *trapClient) {
+	p.mu.Lock()
+	h.Exempt = p.exemptReason(cl)
+	p.hits = append(p.hits, h)
+	if max := p.config.MaxHits; max > 0 && len(p.hits) > max {
+		p.hits = append([]*Hit(nil), p.hits[len(p.hits)-max:]...)
+	}
+	p.dirty = true
+
+	action := ActionNone
+	if trap := p.trapByID(h.TrapID); trap != nil && h.Exempt == "" {
+		action = p.trapAction(trap)
+	}
+	key := h.IP
+	if key == "" {
+		key = strings.ToLower(h.Nick)
+	}
+	if action != ActionNone {
+		window := p.actionWindow()
+		since := h.Time.Add(-window)
+		count := 0
+		for i := len(p.hits) - 1; i >= 0 && !p.hits[i].Time.Before(since); i-- {
+			o := p.hits[i]
+			if o.Exempt == "" && ((h.IP != "" && o.IP == h.IP) || strings.EqualFold(o.Nick, h.Nick)) {
+				count++
+			}
+		}
+		// Act once per client and window, however many traps it walks into
+		if count < p.config.ActionThreshold || h.Time.Sub(p.acted[key]) < window {
+			action = ActionNone
+		} else {
+			p.acted[key] = h.Time
+			h.Action = action
+		}
+	}
+	duration := p.config.ActionDuration
+	reason := p.config.ActionReason
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	where := h.Trap
+	switch h.Kind {
+	case HitJoin:
+		where = "joined " + h.Trap
+	case HitMessage:
+		where = "messaged " + h.Trap
+	case HitNickUse:
+		where = "took the nick " + h.Trap
+	}
+	msg := fmt.Sprintf("%s (%s) %s", h.Nick, h.IP, where)
+	if h.Exempt != "" {
+		msg += " (exempt: " + h.Exempt + ")"
+	}
+	if h.Action != "" {
+		msg += "; action: " + h.Action
+	}
+	log.Printf("[spamtrap] %s", msg)
+
+	if h.Action != "" {
+		go p.act(h, h.Action, duration, reason)
+	}
+	if webhook != "" {
+		go p.alert(webhook, notify.Event{
+			Source:    "spamtrap",
+			Type:      "spamtrap_hit",
+			Severity:  "warning",
+			Title:     "Spamtrap hit",
+			Message:   msg,
+			Timestamp: h.Time,
+			Data: map[string]interface{}{
+				"trap":    h.Trap,
+				"kind":    h.Kind,
+				"nick":    h.Nick,
+				"ip":      h.IP,
+				"host":    h.Host,
+				"message": h.Message,
+				"action":  h.Action,
+			},
+		})
+	}
+}
+
+// act carries out an auto-action over JSON-RPC and records the result
+func (p *SpamtrapPlugin) act(h *Hit, action, duration, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var err error
+	var out json.RawMessage
+	switch action {
+	case ActionKill:
+		err = p.client().Call(ctx, "user.kill", map[string]interface{}{"nick": h.Nick, "reason": reason}, &out)
+	default:
+		if h.IP == "" {
+			err = fmt.Errorf("no IP address known for %s", h.Nick)
+			break
+		}
+		err = p.client().Call(ctx, "server_ban.add", map[string]interface{}{
+			"name":            "*@" + h.IP,
+			"type":            action,
+			"reason":          reason,
+			"duration_string": duration,
+		}, &out)
+	}
+
+	result := "ok"
+	if err != nil {
+		result = "failed: " + err.Error()
+		log.Printf("[spamtrap] %s on %s failed: %v", action, h.Nick, err)
+	}
+	p.mu.Lock()
+	h.Result = result
+	p.dirty = true
+	p.mu.Unlock()
+}
+
+// alert posts an alert to the webhook
+func (p *SpamtrapPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[spamtrap] failed to send alert: %v", err)
+	}
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// apply validates a request and copies it onto the trap
+func (req *TrapRequest) apply(t *Trap) error {
+	name := strings.TrimSpace(req.Name)
+	switch req.Kind {
+	case TrapChannel:
+		if !strings.HasPrefix(name, "#") || strings.ContainsAny(name, " ,") {
+			return fmt.Errorf("channel traps must be a channel name starting with #")
+		}
+	case TrapNick:
+		if name == "" || strings.ContainsAny(name, " ,!@#") {
+			return fmt.Errorf("nick traps must be a nick without spaces")
+		}
+	default:
+		return fmt.Errorf("kind must be nick or channel")
+	}
+	action := req.Action
+	if action == "" {
+		action = ActionDefault
+	}
+	if action != ActionDefault && !validActions[action] {
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	t.Kind = req.Kind
+	t.Name = name
+	t.Action = action
+	t.Note = strings.TrimSpace(req.Note)
+	return nil
+}
+
+// handleStatus reports whether the log stream is connected
+func (p *SpamtrapPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"log_stream":  p.streamOK,
+		"traps":       len(p.traps),
+		"hits":        len(p.hits),
+		"auto_action": p.config.AutoAction,
+		"reporting":   p.config.ReportToken != "",
+	})
+}
+
+// handleListTraps returns all traps with their hit counts
+func (p *SpamtrapPlugin) handleListTraps(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	type trapInfo struct {
+		*Trap
+		Hits    int        `json:"hits"`
+		LastHit *time.Time `json:"last_hit,omitempty"`
+	}
+	byTrap := make(map[string]*trapInfo, len(p.traps))
+	list := make([]*trapInfo, 0, len(p.traps))
+	for _, t := range p.traps {
+		info := &trapInfo{Trap: t}
+		byTrap[t.ID] = info
+		list = append(list, info)
+	}
+	for _, h := range p.hits {
+		if info, ok := byTrap[h.TrapID]; ok {
+			info.Hits++
+			last := h.Time
+			info.LastHit = &last
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"traps": list})
+}
+
+// handleCreateTrap adds a trap
+func (p *SpamtrapPlugin) handleCreateTrap(c *gin.Context) {
+	var req TrapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	t := &Trap{
+		ID:        newID(),
+		CreatedBy: access.Actor(c),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := req.apply(t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, o := range p.traps {
+		if o.Kind == t.Kind && strings.EqualFold(o.Name, t.Name) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A trap with that name already exists"})
+			return
+		}
+	}
+	p.traps = append(p.traps, t)
+	p.dirty = true
+
+	log.Printf("[spamtrap] %s added %s trap %s", t.CreatedBy, t.Kind, t.Name)
+	c.JSON(http.StatusCreated, t)
+}
+
+// handleUpdateTrap changes a trap
+func (p *SpamtrapPlugin) handleUpdateTrap(c *gin.Context) {
+	var req TrapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.trapByID(c.Param("id"))
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trap not found"})
+		return
+	}
+	updated := *t
+	if err := req.apply(&updated); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	*t = updated
+	p.dirty = true
+	c.JSON(http.StatusOK, t)
+}
+
+// handleDeleteTrap removes a trap. Its hits are kept.
+func (p *SpamtrapPlugin) handleDeleteTrap(c *gin.Context) {
+	id := c.Param("id")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, t := range p.traps {
+		if t.ID == id {
+			p.traps = append(p.traps[:i], p.traps[i+1:]...)
+			p.dirty = true
+			log.Printf("[spamtrap] %s removed %s trap %s", access.Actor(c), t.Kind, t.Name)
+			c.JSON(http.StatusOK, gin.H{"message": "Trap removed"})
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Trap not found"})
+}
+
+// handleHits returns the event log, newest first
+func (p *SpamtrapPlugin) handleHits(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	trap := c.Query("trap")
+	ip := c.Query("ip")
+	actioned := c.Query("actioned") == "true"
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Hit, 0)
+	for i := len(p.hits) - 1; i >= 0 && len(list) < limit; i-- {
+		h := p.hits[i]
+		if trap != "" && h.TrapID != trap {
+			continue
+		}
+		if ip != "" && h.IP != ip {
+			continue
+		}
+		if actioned && h.Action == "" {
+			continue
+		}
+		list = append(list, h)
+	}
+	c.JSON(http.StatusOK, gin.H{"hits": list})
+}
+
+// handleReport records a message to a trap nick, reported by the bot that
+// holds the nick. Unknown client details are looked up over JSON-RPC.
+func (p *SpamtrapPlugin) handleReport(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.ReportToken
+	p.mu.RUnlock()
+
+	given := c.GetHeader("X-Report-Token")
+	if given == "" {
+		given = c.Query("token")
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid report token"})
+		return
+	}
+
+	var r Report
+	if err := c.ShouldBindJSON(&r); err != nil || r.Trap == "" || r.Nick == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "trap and nick are required"})
+		return
+	}
+
+	p.mu.RLock()
+	trap := p.findTrap(TrapNick, r.Trap)
+	var trapCopy Trap
+	if trap != nil {
+		trapCopy = *trap
+	}
+	p.mu.RUnlock()
+	if trap == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No nick trap matches " + r.Trap})
+		return
+	}
+
+	cl := &trapClient{Name: r.Nick, Hostname: r.Host, IP: r.IP}
+	cl.User.Username = r.Ident
+
+	// The sender may have quit already; the reported details are kept then
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+	var result struct {
+		Client trapClient `json:"client"`
+	}
+	if err := p.client().Call(ctx, "user.get", map[string]interface{}{"nick": r.Nick}, &result); err == nil {
+		cl = &result.Client
+	}
+
+	h := newHit(&trapCopy, HitMessage, cl)
+	if len(r.Message) > 500 {
+		r.Message = r.Message[:500]
+	}
+	h.Message = r.Message
+	p.record(h, cl)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Hit recorded", "id": h.ID})
+}
+
+// handleGetConfig returns the current configuration
+func (p *SpamtrapPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.ReportToken = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *SpamtrapPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if !validActions[newConfig.AutoAction] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "auto_action must be none, kill, gline, gzline or shun"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.ReportToken == "" {
+		newConfig.ReportToken = p.config.ReportToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *SpamtrapPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *SpamtrapPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
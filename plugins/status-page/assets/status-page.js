@@ -0,0 +1,308 @@
+/**
+ * Status Page Frontend Script
+ *
+ * Post and update incidents for the public status page, and preview what
+ * the public sees.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'status-page';
+  const PLUGIN_NAME = 'Status Page';
+  const PAGE_PATH = '/plugins/status-page';
+  const API_BASE = '/api/plugin/status-page';
+
+  const STATES = ['investigating', 'identified', 'monitoring', 'resolved'];
+  const IMPACTS = ['minor', 'partial', 'major'];
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('status-page-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'status-page-styles';
+    style.textContent = `
+      .stp-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .stp-card { background: var(--bg-secondary, #181825); border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 1rem; }
+      .stp-card h3 { margin: 0 0 0.75rem; color: var(--text-primary, #cdd6f4); font-size: 1rem; }
+      .stp-banner { padding: 0.75rem 1rem; border-radius: 6px; color: #fff; font-weight: 600; margin-bottom: 0.75rem; }
+      .stp-form { display: grid; grid-template-columns: 1fr 10rem 10rem; gap: 0.5rem; }
+      .stp-form label { display: flex; flex-direction: column; gap: 0.2rem; font-size: 0.8rem; }
+      .stp-form .stp-wide { grid-column: 1 / -1; }
+      .stp-app input, .stp-app textarea, .stp-app select {
+        background: var(--bg-primary, #11111b);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.5rem;
+      }
+      .stp-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.4rem 0.9rem;
+        cursor: pointer;
+      }
+      .stp-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .stp-btn.danger { background: var(--error, #f38ba8); color: #fff; }
+      .stp-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+      .stp-table th, .stp-table td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .stp-incident { border-top: 1px solid var(--border-primary, #313244); padding: 0.75rem 0; }
+      .stp-incident:first-child { border-top: none; padding-top: 0; }
+      .stp-incident h4 { margin: 0 0 0.25rem; color: var(--text-primary, #cdd6f4); }
+      .stp-updates { margin: 0.5rem 0; padding-left: 1.2rem; font-size: 0.85rem; }
+      .stp-update-form { display: flex; gap: 0.5rem; flex-wrap: wrap; }
+      .stp-update-form input { flex: 1; min-width: 12rem; }
+      .stp-links code { user-select: all; }
+      .stp-muted { color: var(--text-muted, #6c7086); font-size: 0.8rem; }
+      .stp-danger { color: var(--error, #f38ba8); }
+      .stp-operational { background: #2e9d5b; }
+      .stp-maintenance { background: #3b82f6; }
+      .stp-degraded { background: #d4a017; }
+      .stp-partial_outage { background: #e67e22; }
+      .stp-major_outage, .stp-down { background: #d63c3c; }
+      .stp-unknown { background: #8a8f98; }
+      .stp-dot { display: inline-block; width: 0.6rem; height: 0.6rem; border-radius: 50%; margin-right: 0.35rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function label(s) {
+    s = String(s || '').replace(/_/g, ' ');
+    return s.charAt(0).toUpperCase() + s.slice(1);
+  }
+
+  function when(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function options(list, selected) {
+    return list.map(v => `<option value="${v}"${v === selected ? ' selected' : ''}>${label(v)}</option>`).join('');
+  }
+
+  function renderPreview(el, data) {
+    const s = data.public;
+    const origin = window.location.origin;
+    el.innerHTML = `
+      <div class="stp-banner stp-${escapeHtml(s.status)}">${escapeHtml(s.summary)}</div>
+      ${data.enabled ? '' : '<div class="stp-danger">The public status page is disabled; enable it in the plugin settings.</div>'}
+      ${data.error ? `<div class="stp-danger">Last poll failed: ${escapeHtml(data.error)}</div>` : ''}
+      ${(data.snapshot && data.snapshot.errors || []).map(e => `<div class="stp-danger">${escapeHtml(e)}</div>`).join('')}
+      <div class="stp-links">
+        Public page: <code>${escapeHtml(origin)}${API_BASE}/public/status</code><br>
+        Public JSON: <code>${escapeHtml(origin)}${API_BASE}/public/status.json</code>
+      </div>
+      ${s.servers && s.servers.length ? `
+        <table class="stp-table" style="margin-top: 0.75rem;">
+          <thead><tr><th>Server</th><th>Public status</th><th>Users</th></tr></thead>
+          <tbody>
+            ${s.servers.map(sv => `
+              <tr>
+                <td>${escapeHtml(sv.name)}</td>
+                <td><span class="stp-dot stp-${escapeHtml(sv.status)}"></span>${escapeHtml(label(sv.status))}${sv.down_since ? ` <span class="stp-muted">since ${escapeHtml(when(sv.down_since))}</span>` : ''}</td>
+                <td>${sv.users != null ? sv.users : '<span class="stp-muted">hidden</span>'}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      ` : '<div class="stp-muted" style="margin-top: 0.75rem;">No servers are shown publicly.</div>'}
+      ${s.incidents.filter(i => i.automatic).map(i => `
+        <div style="margin-top: 0.5rem;"><span class="stp-dot stp-${i.impact === 'minor' ? 'degraded' : 'partial_outage'}"></span>Automatic: ${escapeHtml(i.title)} <span class="stp-muted">since ${escapeHtml(when(i.started))}</span></div>
+      `).join('')}
+    `;
+  }
+
+  function renderIncidents(el, incidents) {
+    if (incidents.length === 0) {
+      el.innerHTML = '<div class="stp-muted">No incidents posted.</div>';
+      return;
+    }
+    el.innerHTML = incidents.map(inc => `
+      <div class="stp-incident" data-id="${escapeHtml(inc.id)}">
+        <h4><span class="stp-dot stp-${inc.impact === 'minor' ? 'degraded' : inc.impact === 'major' ? 'major_outage' : 'partial_outage'}"></span>${escapeHtml(inc.title)}</h4>
+        <div class="stp-muted">
+          ${escapeHtml(label(inc.impact))} impact &middot; ${escapeHtml(label(inc.state))} &middot; opened by ${escapeHtml(inc.created_by)} ${escapeHtml(when(inc.created_at))}
+          ${inc.servers && inc.servers.length ? ` &middot; ${escapeHtml(inc.servers.join(', '))}` : ''}
+        </div>
+        <ul class="stp-updates">
+          ${inc.updates.slice().reverse().map(u => `
+            <li><strong>${escapeHtml(label(u.state))}</strong> ${escapeHtml(u.message)} <span class="stp-muted">${escapeHtml(u.author)}, ${escapeHtml(when(u.time))}</span></li>
+          `).join('')}
+        </ul>
+        <form class="stp-update-form">
+          <select name="state">${options(STATES, inc.state)}</select>
+          <input name="message" required placeholder="Post an update">
+          <button class="stp-btn primary" type="submit">Post</button>
+          <button class="stp-btn danger" type="button" data-action="delete">Delete</button>
+        </form>
+      </div>
+    `).join('');
+  }
+
+  async function load(container) {
+    const preview = container.querySelector('#stp-preview');
+    const list = container.querySelector('#stp-incidents');
+    try {
+      const [status, incidents] = await Promise.all([api('GET', '/status'), api('GET', '/incidents')]);
+      renderPreview(preview, status);
+      renderIncidents(list, incidents.incidents);
+    } catch (e) {
+      preview.innerHTML = `<div class="stp-danger">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function createIncident(container, form) {
+    const msg = container.querySelector('#stp-create-msg');
+    try {
+      await api('POST', '/incidents', {
+        title: form.title.value.trim(),
+        impact: form.impact.value,
+        state: form.state.value,
+        servers: form.servers.value.split(',').map(s => s.trim()).filter(Boolean),
+        message: form.message.value.trim()
+      });
+      form.reset();
+      msg.textContent = '';
+      load(container);
+    } catch (e) {
+      msg.innerHTML = `<span class="stp-danger">${escapeHtml(e.message)}</span>`;
+    }
+  }
+
+  async function postUpdate(container, form) {
+    const id = form.closest('.stp-incident').dataset.id;
+    try {
+      await api('POST', `/incidents/${encodeURIComponent(id)}/updates`, {
+        state: form.state.value,
+        message: form.message.value.trim()
+      });
+      load(container);
+    } catch (e) {
+      alert(e.message);
+    }
+  }
+
+  async function deleteIncident(container, id) {
+    if (!confirm('Delete this incident from the status page?')) return;
+    try {
+      await api('DELETE', `/incidents/${encodeURIComponent(id)}`);
+      load(container);
+    } catch (e) {
+      alert(e.message);
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="stp-app" data-plugin="${PLUGIN_ID}">
+        <div class="stp-card">
+          <h3>What the public sees</h3>
+          <div id="stp-preview">Loading...</div>
+        </div>
+        <div class="stp-card">
+          <h3>Open an incident</h3>
+          <form class="stp-form" id="stp-create-form">
+            <label>Title<input name="title" required maxlength="200" placeholder="Some users can't connect"></label>
+            <label>Impact<select name="impact">${options(IMPACTS, 'minor')}</select></label>
+            <label>State<select name="state">${options(STATES, 'investigating')}</select></label>
+            <label class="stp-wide">Affected servers, comma-separated (optional)<input name="servers" placeholder="irc1.example.net"></label>
+            <label class="stp-wide">First update<textarea name="message" rows="2" required placeholder="We are looking into reports of..."></textarea></label>
+            <div class="stp-wide"><button class="stp-btn primary" type="submit">Publish</button> <span id="stp-create-msg"></span></div>
+          </form>
+        </div>
+        <div class="stp-card">
+          <h3>Incidents</h3>
+          <div id="stp-incidents"></div>
+        </div>
+      </div>
+    `;
+
+    const app = container.querySelector('.stp-app');
+    app.addEventListener('submit', (e) => {
+      e.preventDefault();
+      if (e.target.id === 'stp-create-form') createIncident(container, e.target);
+      else if (e.target.classList.contains('stp-update-form')) postUpdate(container, e.target);
+    });
+    app.addEventListener('click', (e) => {
+      const btn = e.target.closest('[data-action="delete"]');
+      if (btn) deleteIncident(container, btn.closest('.stp-incident').dataset.id);
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('status-page-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+})();
@@ -0,0 +1,757 @@
+// Status Page Plugin for UnrealIRCd Web Panel
+// Publishes the network's status as public JSON and a simple HTML page,
+// with servers, incidents and maintenance shown as far as admins allow
+
+package statuspage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Incident limits
+const (
+	maxIncidents = 500
+	maxUpdates   = 100
+)
+
+// StatusPagePlugin implements the Plugin interface
+type StatusPagePlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	incidents []*Incident
+	servers   map[string]*knownServer
+	snapshot  *Snapshot
+	pollErr   string
+	dirty     bool
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL             string `json:"rpc_url"`
+	RPCUser            string `json:"rpc_user"`
+	RPCPassword        string `json:"rpc_password"`
+	RPCInsecure        bool   `json:"rpc_insecure"`
+	DataDir            string `json:"data_dir"`
+	Enabled            bool   `json:"enabled"`
+	Title              string `json:"title"`
+	ShowCounts         bool   `json:"show_counts"`
+	ShowServers        string `json:"show_servers"`
+	HideServices       bool   `json:"hide_services"`
+	ShowMaintenance    bool   `json:"show_maintenance"`
+	ShowDetails        bool   `json:"show_details"`
+	MaintenanceFile    string `json:"maintenance_file"`
+	NetsplitsFile      string `json:"netsplits_file"`
+	IncidentsFile      string `json:"incidents_file"`
+	EscalationSeverity string `json:"escalation_severity"`
+	MaintenanceDays    int    `json:"maintenance_days"`
+	HistoryDays        int    `json:"history_days"`
+	ForgetDays         int    `json:"forget_days"`
+	PollInterval       int    `json:"poll_interval"`
+	CORSOrigins        string `json:"cors_origins"`
+	ChartURL           string `json:"chart_url"`
+}
+
+// IncidentRequest is the body of an incident create or edit. Message and
+// State are only used when creating, as the first update.
+type IncidentRequest struct {
+	Title   string   `json:"title"`
+	Impact  string   `json:"impact"`
+	Servers []string `json:"servers"`
+	State   string   `json:"state"`
+	Message string   `json:"message"`
+}
+
+// UpdateRequest is the body of a new incident update
+type UpdateRequest struct {
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Incidents []*Incident             `json:"incidents"`
+	Servers   map[string]*knownServer `json:"servers"`
+}
+
+// statsResult is the part of stats.get we need
+type statsResult struct {
+	User struct {
+		Total int `json:"total"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+}
+
+// serverListResult is the part of server.list we need
+type serverListResult struct {
+	List []struct {
+		Name   string `json:"name"`
+		Server struct {
+			NumUsers int  `json:"num_users"`
+			Synced   bool `json:"synced"`
+			ULined   bool `json:"ulined"`
+		} `json:"server"`
+	} `json:"list"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &StatusPagePlugin{
+		config: Config{
+			RPCURL:             "https://127.0.0.1:8600/api",
+			DataDir:            "data/plugins/status-page",
+			Enabled:            true,
+			Title:              "IRC Network",
+			ShowCounts:         true,
+			ShowServers:        ExposeStatus,
+			HideServices:       true,
+			ShowMaintenance:    true,
+			MaintenanceFile:    "data/plugins/maintenance-announcer/windows.json",
+			NetsplitsFile:      "data/plugins/netsplit-tracker/netsplits.json",
+			IncidentsFile:      "data/plugins/incident-escalation/incident-escalation.json",
+			EscalationSeverity: "critical",
+			MaintenanceDays:    14,
+			HistoryDays:        7,
+			ForgetDays:         7,
+			PollInterval:       60,
+			CORSOrigins:        "*",
+		},
+		incidents: make([]*Incident, 0),
+		servers:   make(map[string]*knownServer),
+	}
+}
+
+// Info returns plugin metadata
+func (p *StatusPagePlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Status Page",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Public status JSON and page for the network",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *StatusPagePlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[status-page] failed to load data: %v", err)
+	}
+	if data.Incidents != nil {
+		p.incidents = data.Incidents
+	}
+	if data.Servers != nil {
+		p.servers = data.Servers
+	}
+	p.mu.Unlock()
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *StatusPagePlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *StatusPagePlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/status-page")
+	{
+		plugin.GET("/public/status.json", p.handlePublicJSON)
+		plugin.GET("/public/status", p.handlePublicPage)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/incidents", p.handleListIncidents)
+		plugin.POST("/incidents", p.handleCreateIncident)
+		plugin.PUT("/incidents/:id", p.handleUpdateIncident)
+		plugin.DELETE("/incidents/:id", p.handleDeleteIncident)
+		plugin.POST("/incidents/:id/updates", p.handleAddUpdate)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *StatusPagePlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "status-page.json")
+}
+
+// save persists the state if it changed
+func (p *StatusPagePlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Incidents: p.incidents, Servers: p.servers}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *StatusPagePlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// pollLoop refreshes the snapshot until shutdown and saves the state
+func (p *StatusPagePlugin) pollLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+		p.poll()
+		if err := p.save(); err != nil {
+			log.Printf("[status-page] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// poll takes a new snapshot of the network. A server that was linked
+// before and is missing now is down; one missing for forget_days is
+// taken to be removed for good. When the RPC fails the old snapshot is
+// kept, and the public status shows it as stale once it is old.
+func (p *StatusPagePlugin) poll() {
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var stats statsResult
+	var list serverListResult
+	err := p.client().Call(ctx, "stats.get", nil, &stats)
+	if err == nil {
+		err = p.client().Call(ctx, "server.list", nil, &list)
+	}
+
+	errs := make([]string, 0)
+	windows, werr := loadWindows(cfg.MaintenanceFile)
+	if werr != nil {
+		errs = append(errs, werr.Error())
+	}
+	netsplits, nerr := loadNetsplits(cfg.NetsplitsFile)
+	if nerr != nil {
+		errs = append(errs, nerr.Error())
+	}
+	escalations, eerr := loadEscalations(cfg.IncidentsFile)
+	if eerr != nil {
+		errs = append(errs, eerr.Error())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		if p.pollErr == "" {
+			log.Printf("[status-page] failed to poll the network: %v", err)
+		}
+		p.pollErr = err.Error()
+		return
+	}
+	p.pollErr = ""
+	now := time.Now().UTC()
+
+	seen := make(map[string]bool, len(list.List))
+	for _, s := range list.List {
+		key := strings.ToLower(s.Name)
+		seen[key] = true
+		ks, ok := p.servers[key]
+		if !ok {
+			ks = &knownServer{}
+			p.servers[key] = ks
+		}
+		if ks.DownSince != nil {
+			log.Printf("[status-page] %s is back", s.Name)
+		}
+		*ks = knownServer{Name: s.Name, Users: s.Server.NumUsers, Synced: s.Server.Synced, ULined: s.Server.ULined, LastSeen: now}
+	}
+	forget := time.Duration(cfg.ForgetDays) * 24 * time.Hour
+	for key, ks := range p.servers {
+		if seen[key] {
+			continue
+		}
+		if now.Sub(ks.LastSeen) > forget {
+			log.Printf("[status-page] forgetting %s, gone since %s", ks.Name, ks.LastSeen.Format(time.RFC3339))
+			delete(p.servers, key)
+			continue
+		}
+		if ks.DownSince == nil {
+			log.Printf("[status-page] %s is down", ks.Name)
+			down := now
+			ks.DownSince = &down
+		}
+	}
+	p.dirty = true
+
+	servers := make([]knownServer, 0, len(p.servers))
+	for _, ks := range p.servers {
+		servers = append(servers, *ks)
+	}
+	sort.Slice(servers, func(i, j int) bool { return strings.ToLower(servers[i].Name) < strings.ToLower(servers[j].Name) })
+	p.snapshot = &Snapshot{
+		Time:        now,
+		Users:       stats.User.Total,
+		Channels:    stats.Channel.Total,
+		Servers:     servers,
+		Windows:     windows,
+		Netsplits:   netsplits,
+		Escalations: escalations,
+		Errors:      errs,
+	}
+}
+
+// publicStatus returns the status as the public sees it
+func (p *StatusPagePlugin) publicStatus() (PublicStatus, Config) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return buildStatus(p.config, p.snapshot, p.incidents, time.Now().UTC()), p.config
+}
+
+// allowOrigin sets the CORS headers of a public response, so status
+// widgets on other sites can fetch it
+func allowOrigin(c *gin.Context, origins string) {
+	origin := c.GetHeader("Origin")
+	if origin == "" || strings.TrimSpace(origins) == "" {
+		return
+	}
+	if strings.TrimSpace(origins) == "*" {
+		c.Header("Access-Control-Allow-Origin", "*")
+		return
+	}
+	c.Header("Vary", "Origin")
+	for _, o := range strings.Split(origins, ",") {
+		if strings.EqualFold(strings.TrimRight(strings.TrimSpace(o), "/"), origin) {
+			c.Header("Access-Control-Allow-Origin", origin)
+			return
+		}
+	}
+}
+
+// cacheFor is how long browsers and proxies may cache a public response
+func cacheFor(cfg Config) string {
+	age := cfg.PollInterval / 2
+	if age < 5 {
+		age = 5
+	}
+	return fmt.Sprintf("public, max-age=%d", age)
+}
+
+// handlePublicJSON serves the public status as JSON. It needs no login.
+func (p *StatusPagePlugin) handlePublicJSON(c *gin.Context) {
+	status, cfg := p.publicStatus()
+	if !cfg.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Status page is disabled"})
+		return
+	}
+	allowOrigin(c, cfg.CORSOrigins)
+	c.Header("Cache-Control", cacheFor(cfg))
+	c.JSON(http.StatusOK, status)
+}
+
+// handlePublicPage serves the public status as an HTML page. It needs no
+// login.
+func (p *StatusPagePlugin) handlePublicPage(c *gin.Context) {
+	status, cfg := p.publicStatus()
+	if !cfg.Enabled {
+		c.String(http.StatusNotFound, "Status page is disabled")
+		return
+	}
+	var buf bytes.Buffer
+	if err := renderPage(&buf, status, cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", cacheFor(cfg))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// handleStatus returns the public status next to everything the last
+// poll found, for staff
+func (p *StatusPagePlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"public":   buildStatus(p.config, p.snapshot, p.incidents, time.Now().UTC()),
+		"snapshot": p.snapshot,
+		"error":    p.pollErr,
+		"enabled":  p.config.Enabled,
+	})
+}
+
+// findIncident returns the incident with the given ID. Caller must hold
+// p.mu.
+func (p *StatusPagePlugin) findIncident(id string) (*Incident, int) {
+	for i, inc := range p.incidents {
+		if inc.ID == id {
+			return inc, i
+		}
+	}
+	return nil, -1
+}
+
+// validState reports whether s is an incident state
+func validState(s string) bool {
+	for _, state := range incidentStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// apply validates a request and copies it onto the incident
+func (req *IncidentRequest) apply(inc *Incident) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" || len(title) > 200 {
+		return fmt.Errorf("title must be 1 to 200 characters")
+	}
+	if _, ok := impactStatus[req.Impact]; !ok {
+		return fmt.Errorf("impact must be minor, partial or major")
+	}
+	servers := make([]string, 0, len(req.Servers))
+	for _, s := range req.Servers {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	if len(servers) > 50 {
+		return fmt.Errorf("at most 50 servers can be affected")
+	}
+	inc.Title = title
+	inc.Impact = req.Impact
+	inc.Servers = servers
+	return nil
+}
+
+// checkUpdate validates an incident update
+func checkUpdate(state, message string) error {
+	if !validState(state) {
+		return fmt.Errorf("state must be investigating, identified, monitoring or resolved")
+	}
+	if message == "" || len(message) > 5000 {
+		return fmt.Errorf("message must be 1 to 5000 characters")
+	}
+	return nil
+}
+
+// handleListIncidents returns all staff incidents, newest first
+func (p *StatusPagePlugin) handleListIncidents(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Incident, 0, len(p.incidents))
+	for _, inc := range p.incidents {
+		list = append(list, *inc)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{"incidents": list})
+}
+
+// handleCreateIncident opens an incident with its first update. Beyond
+// the limit the oldest resolved incidents are removed.
+func (p *StatusPagePlugin) handleCreateIncident(c *gin.Context) {
+	var req IncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if req.State == "" {
+		req.State = StateInvestigating
+	}
+	message := strings.TrimSpace(req.Message)
+	now := time.Now().UTC()
+	inc := &Incident{ID: newID(), State: req.State, CreatedBy: access.Actor(c), CreatedAt: now}
+	if err := req.apply(inc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkUpdate(req.State, message); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inc.Updates = []Update{{Time: now, State: req.State, Message: message, Author: inc.CreatedBy}}
+	if req.State == StateResolved {
+		inc.ResolvedAt = &now
+	}
+
+	p.mu.Lock()
+	p.incidents = append(p.incidents, inc)
+	for len(p.incidents) > maxIncidents {
+		if !p.dropOldestResolved() {
+			break
+		}
+	}
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[status-page] failed to save data: %v", err)
+	}
+	log.Printf("[status-page] %s opened incident %q", inc.CreatedBy, inc.Title)
+	c.JSON(http.StatusCreated, inc)
+}
+
+// dropOldestResolved removes the resolved incident that was resolved
+// first, reporting whether there was one. Caller must hold p.mu.
+func (p *StatusPagePlugin) dropOldestResolved() bool {
+	oldest := -1
+	for i, inc := range p.incidents {
+		if inc.ResolvedAt != nil && (oldest < 0 || inc.ResolvedAt.Before(*p.incidents[oldest].ResolvedAt)) {
+			oldest = i
+		}
+	}
+	if oldest < 0 {
+		return false
+	}
+	p.incidents = append(p.incidents[:oldest], p.incidents[oldest+1:]...)
+	return true
+}
+
+// handleUpdateIncident edits the title, impact or servers of an incident
+func (p *StatusPagePlugin) handleUpdateIncident(c *gin.Context) {
+	var req IncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	inc, _ := p.findIncident(c.Param("id"))
+	if inc == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	updated := *inc
+	if err := req.apply(&updated); err != nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	*inc = updated
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[status-page] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// handleAddUpdate posts an update on an incident and moves it to the
+// update's state. Posting a state other than resolved reopens it.
+func (p *StatusPagePlugin) handleAddUpdate(c *gin.Context) {
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if err := checkUpdate(req.State, message); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now().UTC()
+	author := access.Actor(c)
+
+	p.mu.Lock()
+	inc, _ := p.findIncident(c.Param("id"))
+	if inc == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	if len(inc.Updates) >= maxUpdates {
+		p.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d updates are allowed per incident", maxUpdates)})
+		return
+	}
+	inc.Updates = append(inc.Updates, Update{Time: now, State: req.State, Message: message, Author: author})
+	inc.State = req.State
+	if req.State == StateResolved {
+		inc.ResolvedAt = &now
+	} else {
+		inc.ResolvedAt = nil
+	}
+	p.dirty = true
+	updated := *inc
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[status-page] failed to save data: %v", err)
+	}
+	log.Printf("[status-page] %s marked incident %q %s", author, updated.Title, updated.State)
+	c.JSON(http.StatusOK, updated)
+}
+
+// handleDeleteIncident removes an incident from the status page
+func (p *StatusPagePlugin) handleDeleteIncident(c *gin.Context) {
+	p.mu.Lock()
+	inc, i := p.findIncident(c.Param("id"))
+	if inc == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	p.incidents = append(p.incidents[:i], p.incidents[i+1:]...)
+	p.dirty = true
+	p.mu.Unlock()
+
+	if err := p.save(); err != nil {
+		log.Printf("[status-page] failed to save data: %v", err)
+	}
+	log.Printf("[status-page] %s deleted incident %q", access.Actor(c), inc.Title)
+	c.JSON(http.StatusOK, gin.H{"message": "Incident deleted"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *StatusPagePlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *StatusPagePlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	newConfig.Title = strings.TrimSpace(newConfig.Title)
+	if newConfig.Title == "" || len(newConfig.Title) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title must be 1 to 100 characters"})
+		return
+	}
+	switch newConfig.ShowServers {
+	case ExposeNone, ExposeStatus, ExposeUsers:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "show_servers must be none, status or users"})
+		return
+	}
+	if _, ok := notify.SeverityRank[newConfig.EscalationSeverity]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "escalation_severity must be info, warning or critical"})
+		return
+	}
+	if newConfig.MaintenanceDays < 0 || newConfig.MaintenanceDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "maintenance_days must be between 0 and 365"})
+		return
+	}
+	if newConfig.HistoryDays < 0 || newConfig.HistoryDays > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "history_days must be between 0 and 90"})
+		return
+	}
+	if newConfig.ForgetDays < 1 || newConfig.ForgetDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "forget_days must be between 1 and 365"})
+		return
+	}
+	if newConfig.PollInterval < 10 || newConfig.PollInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poll_interval must be between 10 and 3600 seconds"})
+		return
+	}
+	newConfig.CORSOrigins = strings.TrimSpace(newConfig.CORSOrigins)
+	newConfig.ChartURL = strings.TrimSpace(newConfig.ChartURL)
+	if newConfig.ChartURL != "" && !strings.HasPrefix(newConfig.ChartURL, "https://") && !strings.HasPrefix(newConfig.ChartURL, "http://") && !strings.HasPrefix(newConfig.ChartURL, "/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chart_url must start with http://, https:// or /"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *StatusPagePlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *StatusPagePlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,167 @@
+package statuspage
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// statusColors are the colours of each status on the public page
+var statusColors = map[string]string{
+	StatusOperational:   "#2e9d5b",
+	StatusMaintenance:   "#3b82f6",
+	StatusDegraded:      "#d4a017",
+	StatusPartialOutage: "#e67e22",
+	StatusMajorOutage:   "#d63c3c",
+	StatusUnknown:       "#8a8f98",
+	ServerDown:          "#d63c3c",
+}
+
+// pageFuncs are the helpers available to the page template
+var pageFuncs = htmltemplate.FuncMap{
+	"time": func(t time.Time) string {
+		return t.UTC().Format("2 Jan 2006 15:04 MST")
+	},
+	"label": func(s string) string {
+		s = strings.ReplaceAll(s, "_", " ")
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+	"color": func(s string) string {
+		if c, ok := statusColors[s]; ok {
+			return c
+		}
+		return statusColors[StatusUnknown]
+	},
+	"impactColor": func(impact string) string {
+		return statusColors[impactStatus[impact]]
+	},
+	"join": func(list []string) string {
+		return strings.Join(list, ", ")
+	},
+}
+
+// pageData is what the page template is rendered with
+type pageData struct {
+	Status   PublicStatus
+	Refresh  int
+	ChartURL string
+}
+
+var statusPage = htmltemplate.Must(htmltemplate.New("status").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>{{.Status.Network}} Status</title>
+<style>
+body{margin:0;padding:24px 16px;background:#f4f5f7;font-family:-apple-system,"Segoe UI",Roboto,Arial,sans-serif;color:#1f2328;}
+main{max-width:760px;margin:0 auto;}
+h1{font-size:24px;margin:0 0 16px;}
+h2{font-size:16px;margin:28px 0 10px;}
+.banner{padding:16px 20px;border-radius:8px;color:#fff;font-size:18px;font-weight:600;}
+.muted{color:#6b7280;font-size:13px;}
+.box{background:#fff;border:1px solid #e3e5e8;border-radius:8px;}
+.row{display:flex;justify-content:space-between;align-items:center;padding:10px 16px;border-top:1px solid #eef0f2;}
+.row:first-child{border-top:0;}
+.dot{display:inline-block;width:10px;height:10px;border-radius:50%;margin-right:6px;}
+.counts{display:flex;gap:12px;margin-top:12px;}
+.count{flex:1;padding:12px 16px;}
+.count b{display:block;font-size:22px;}
+.chart{margin-top:12px;padding:8px;}
+.chart img{display:block;width:100%;height:auto;}
+.item{padding:12px 16px;border-top:1px solid #eef0f2;}
+.item:first-child{border-top:0;}
+.item h3{font-size:15px;margin:0 0 4px;}
+.update{margin:8px 0 0;font-size:14px;}
+.update .muted{display:block;}
+footer{margin-top:28px;text-align:center;}
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Status.Network}}</h1>
+<div class="banner" style="background:{{color .Status.Status}};">{{.Status.Summary}}</div>
+{{- if .Status.Updated}}
+<p class="muted">Last checked {{time .Status.Updated.UTC}}{{if .Status.Stale}}; the network could not be reached since{{end}}</p>
+{{- end}}
+
+{{- if .Status.Users}}
+<div class="counts">
+  <div class="box count"><span class="muted">Users online</span><b>{{.Status.Users}}</b></div>
+  <div class="box count"><span class="muted">Channels</span><b>{{.Status.Channels}}</b></div>
+</div>
+{{- end}}
+
+{{- if .ChartURL}}
+<div class="box chart"><img src="{{.ChartURL}}" alt="Users online over time"></div>
+{{- end}}
+
+{{- if .Status.Incidents}}
+<h2>Current incidents</h2>
+<div class="box">
+{{- range .Status.Incidents}}
+  <div class="item">
+    <h3><span class="dot" style="background:{{impactColor .Impact}};"></span>{{.Title}}</h3>
+    <div class="muted">{{label .State}} &middot; since {{time .Started}}{{if .Servers}} &middot; {{join .Servers}}{{end}}</div>
+    {{- range .Updates}}
+    <p class="update"><span class="muted">{{label .State}}, {{time .Time}}</span>{{.Message}}</p>
+    {{- end}}
+  </div>
+{{- end}}
+</div>
+{{- end}}
+
+{{- if .Status.Maintenance}}
+<h2>Scheduled maintenance</h2>
+<div class="box">
+{{- range .Status.Maintenance}}
+  <div class="item">
+    <h3><span class="dot" style="background:{{color "maintenance"}};"></span>{{.Title}}{{if .Active}} (in progress){{end}}</h3>
+    <div class="muted">{{time .Start}} to {{time .End}}{{if .Servers}} &middot; {{join .Servers}}{{end}}</div>
+    {{- if .Description}}
+    <p class="update">{{.Description}}</p>
+    {{- end}}
+  </div>
+{{- end}}
+</div>
+{{- end}}
+
+{{- if .Status.Servers}}
+<h2>Servers</h2>
+<div class="box">
+{{- range .Status.Servers}}
+  <div class="row">
+    <span>{{.Name}}</span>
+    <span><span class="dot" style="background:{{color .Status}};"></span>{{label .Status}}{{if .Users}} &middot; {{.Users}} users{{end}}</span>
+  </div>
+{{- end}}
+</div>
+{{- end}}
+
+{{- if .Status.Resolved}}
+<h2>Recently resolved</h2>
+<div class="box">
+{{- range .Status.Resolved}}
+  <div class="item">
+    <h3>{{.Title}}</h3>
+    <div class="muted">{{time .Started}}{{if .Resolved}} to {{time .Resolved.UTC}}{{end}}</div>
+  </div>
+{{- end}}
+</div>
+{{- end}}
+
+<footer class="muted"><a href="status.json">status.json</a></footer>
+</main>
+</body>
+</html>
+`))
+
+// renderPage writes the public status page
+func renderPage(w io.Writer, status PublicStatus, cfg Config) error {
+	return statusPage.Execute(w, pageData{Status: status, Refresh: cfg.PollInterval, ChartURL: cfg.ChartURL})
+}
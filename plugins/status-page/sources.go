@@ -0,0 +1,84 @@
+package statuspage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// window is the part of a maintenance-announcer window we show
+type window struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Servers     []string  `json:"servers"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Cancelled   bool      `json:"cancelled"`
+}
+
+// netsplit is the part of a netsplit-tracker incident we show
+type netsplit struct {
+	ID        string     `json:"id"`
+	Start     time.Time  `json:"start"`
+	End       *time.Time `json:"end"`
+	UsersLost int        `json:"users_lost"`
+	Servers   []struct {
+		Name string `json:"name"`
+	} `json:"servers"`
+}
+
+// escalation is the part of an incident-escalation incident we show
+type escalation struct {
+	ID          string     `json:"id"`
+	Severity    string     `json:"severity"`
+	Summary     string     `json:"summary"`
+	TriggeredAt time.Time  `json:"triggered_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+}
+
+// loadWindows reads the windows stored by the maintenance-announcer
+// plugin. A missing file gives an empty list.
+func loadWindows(path string) ([]window, error) {
+	var data struct {
+		Windows []window `json:"windows"`
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if err := storage.LoadJSON(path, &data); err != nil {
+		return nil, fmt.Errorf("maintenance windows %s: %w", path, err)
+	}
+	return data.Windows, nil
+}
+
+// loadNetsplits reads the incidents stored by the netsplit-tracker plugin.
+// A missing file gives an empty list.
+func loadNetsplits(path string) ([]netsplit, error) {
+	var data struct {
+		Incidents []netsplit `json:"incidents"`
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if err := storage.LoadJSON(path, &data); err != nil {
+		return nil, fmt.Errorf("netsplits %s: %w", path, err)
+	}
+	return data.Incidents, nil
+}
+
+// loadEscalations reads the incidents stored by the incident-escalation
+// plugin. A missing file gives an empty list.
+func loadEscalations(path string) ([]escalation, error) {
+	var data struct {
+		Incidents []escalation `json:"incidents"`
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if err := storage.LoadJSON(path, &data); err != nil {
+		return nil, fmt.Errorf("incidents %s: %w", path, err)
+	}
+	return data.Incidents, nil
+}
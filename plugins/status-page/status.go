@@ -0,0 +1,413 @@
+package statuspage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Statuses of the network and of each server
+const (
+	StatusOperational   = "operational"
+	StatusMaintenance   = "maintenance"
+	StatusDegraded      = "degraded"
+	StatusPartialOutage = "partial_outage"
+	StatusMajorOutage   = "major_outage"
+	StatusUnknown       = "unknown"
+)
+
+// ServerDown is the status of a server that is no longer linked
+const ServerDown = "down"
+
+// statusRank orders statuses from best to worst
+var statusRank = map[string]int{
+	StatusOperational:   0,
+	StatusMaintenance:   1,
+	StatusDegraded:      2,
+	StatusPartialOutage: 3,
+	StatusMajorOutage:   4,
+	StatusUnknown:       5,
+}
+
+// statusSummary is the headline of each overall status
+var statusSummary = map[string]string{
+	StatusOperational:   "All systems operational",
+	StatusMaintenance:   "Maintenance in progress",
+	StatusDegraded:      "Degraded service",
+	StatusPartialOutage: "Partial outage",
+	StatusMajorOutage:   "Major outage",
+	StatusUnknown:       "Status unavailable",
+}
+
+// Incident impacts
+const (
+	ImpactMinor   = "minor"
+	ImpactPartial = "partial"
+	ImpactMajor   = "major"
+)
+
+// impactStatus is the network status an incident's impact causes
+var impactStatus = map[string]string{
+	ImpactMinor:   StatusDegraded,
+	ImpactPartial: StatusPartialOutage,
+	ImpactMajor:   StatusMajorOutage,
+}
+
+// Incident states
+const (
+	StateInvestigating = "investigating"
+	StateIdentified    = "identified"
+	StateMonitoring    = "monitoring"
+	StateResolved      = "resolved"
+)
+
+// incidentStates are the valid incident states, in order
+var incidentStates = []string{StateInvestigating, StateIdentified, StateMonitoring, StateResolved}
+
+// Server exposure levels
+const (
+	ExposeNone   = "none"
+	ExposeStatus = "status"
+	ExposeUsers  = "users"
+)
+
+// Incident is a problem posted by staff
+type Incident struct {
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	Impact     string     `json:"impact"`
+	State      string     `json:"state"`
+	Servers    []string   `json:"servers"`
+	Updates    []Update   `json:"updates"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Update is a message posted on an incident
+type Update struct {
+	Time    time.Time `json:"time"`
+	State   string    `json:"state"`
+	Message string    `json:"message"`
+	Author  string    `json:"author"`
+}
+
+// knownServer is a server that has been seen linked, kept so it can be
+// shown as down when it disappears
+type knownServer struct {
+	Name      string     `json:"name"`
+	Users     int        `json:"users"`
+	Synced    bool       `json:"synced"`
+	ULined    bool       `json:"ulined"`
+	LastSeen  time.Time  `json:"last_seen"`
+	DownSince *time.Time `json:"down_since,omitempty"`
+}
+
+// Snapshot is what the last poll found
+type Snapshot struct {
+	Time        time.Time     `json:"time"`
+	Users       int           `json:"users"`
+	Channels    int           `json:"channels"`
+	Servers     []knownServer `json:"servers"`
+	Windows     []window      `json:"windows"`
+	Netsplits   []netsplit    `json:"netsplits"`
+	Escalations []escalation  `json:"escalations"`
+	Errors      []string      `json:"errors"`
+}
+
+// PublicStatus is the status served to the public
+type PublicStatus struct {
+	Network     string              `json:"network"`
+	Status      string              `json:"status"`
+	Summary     string              `json:"summary"`
+	Updated     *time.Time          `json:"updated"`
+	Stale       bool                `json:"stale,omitempty"`
+	Users       *int                `json:"users,omitempty"`
+	Channels    *int                `json:"channels,omitempty"`
+	Servers     []PublicServer      `json:"servers,omitempty"`
+	Incidents   []PublicIncident    `json:"incidents"`
+	Maintenance []PublicMaintenance `json:"maintenance"`
+	Resolved    []PublicIncident    `json:"recently_resolved"`
+}
+
+// PublicServer is a server as shown to the public
+type PublicServer struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	Users     *int       `json:"users,omitempty"`
+	DownSince *time.Time `json:"down_since,omitempty"`
+}
+
+// PublicIncident is an incident as shown to the public, without who
+// posted it
+type PublicIncident struct {
+	ID        string         `json:"id"`
+	Title     string         `json:"title"`
+	Impact    string         `json:"impact"`
+	State     string         `json:"state"`
+	Servers   []string       `json:"servers,omitempty"`
+	Automatic bool           `json:"automatic,omitempty"`
+	Started   time.Time      `json:"started"`
+	Resolved  *time.Time     `json:"resolved,omitempty"`
+	Updates   []PublicUpdate `json:"updates"`
+}
+
+// PublicUpdate is an incident update as shown to the public
+type PublicUpdate struct {
+	Time    time.Time `json:"time"`
+	State   string    `json:"state"`
+	Message string    `json:"message"`
+}
+
+// PublicMaintenance is a maintenance window as shown to the public
+type PublicMaintenance struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Servers     []string  `json:"servers,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Active      bool      `json:"active"`
+}
+
+// worse returns the worse of two statuses
+func worse(a, b string) string {
+	if statusRank[b] > statusRank[a] {
+		return b
+	}
+	return a
+}
+
+// covers reports whether a maintenance window applies to a server; a
+// window without servers covers the whole network
+func (w window) covers(server string) bool {
+	if len(w.Servers) == 0 {
+		return true
+	}
+	for _, s := range w.Servers {
+		if strings.EqualFold(s, server) {
+			return true
+		}
+	}
+	return false
+}
+
+// active reports whether a window is in progress
+func (w window) active(now time.Time) bool {
+	return !w.Cancelled && !now.Before(w.Start) && now.Before(w.End)
+}
+
+// buildStatus works out the public status from the last snapshot and the
+// staff incidents, showing only what the settings allow
+func buildStatus(cfg Config, snap *Snapshot, incidents []*Incident, now time.Time) PublicStatus {
+	ps := PublicStatus{
+		Network:     cfg.Title,
+		Status:      StatusOperational,
+		Incidents:   make([]PublicIncident, 0),
+		Maintenance: make([]PublicMaintenance, 0),
+		Resolved:    make([]PublicIncident, 0),
+	}
+	since := now.Add(-time.Duration(cfg.HistoryDays) * 24 * time.Hour)
+
+	for _, inc := range incidents {
+		pi := publicIncident(inc)
+		if inc.State != StateResolved {
+			ps.Incidents = append(ps.Incidents, pi)
+			ps.Status = worse(ps.Status, impactStatus[inc.Impact])
+		} else if inc.ResolvedAt != nil && inc.ResolvedAt.After(since) {
+			ps.Resolved = append(ps.Resolved, pi)
+		}
+	}
+
+	if snap == nil {
+		ps.Status = StatusUnknown
+		ps.Summary = statusSummary[ps.Status]
+		return ps
+	}
+	updated := snap.Time
+	ps.Updated = &updated
+	ps.Stale = now.Sub(snap.Time) > 3*time.Duration(cfg.PollInterval)*time.Second
+
+	if cfg.ShowCounts {
+		users, channels := snap.Users, snap.Channels
+		ps.Users, ps.Channels = &users, &channels
+	}
+
+	active := make([]window, 0)
+	for _, w := range snap.Windows {
+		if w.Cancelled || !w.End.After(now) || w.Start.After(now.Add(time.Duration(cfg.MaintenanceDays)*24*time.Hour)) {
+			continue
+		}
+		if w.active(now) {
+			active = append(active, w)
+		}
+		if cfg.ShowMaintenance {
+			pm := PublicMaintenance{Title: w.Title, Servers: w.Servers, Start: w.Start, End: w.End, Active: w.active(now)}
+			if cfg.ShowDetails {
+				pm.Description = w.Description
+			}
+			ps.Maintenance = append(ps.Maintenance, pm)
+		}
+	}
+	sort.Slice(ps.Maintenance, func(i, j int) bool { return ps.Maintenance[i].Start.Before(ps.Maintenance[j].Start) })
+
+	// Servers under maintenance are expected to be down, so they don't
+	// count as an outage
+	shown, down := 0, 0
+	for _, s := range snap.Servers {
+		if s.ULined && cfg.HideServices {
+			continue
+		}
+		status := StatusOperational
+		switch {
+		case underMaintenance(active, s.Name):
+			status = StatusMaintenance
+		case s.DownSince != nil:
+			status = ServerDown
+			down++
+		case !s.Synced:
+			status = StatusDegraded
+		}
+		shown++
+		if status == ServerDown {
+			ps.Status = worse(ps.Status, StatusPartialOutage)
+		} else {
+			ps.Status = worse(ps.Status, status)
+		}
+		if cfg.ShowServers == ExposeNone {
+			continue
+		}
+		pub := PublicServer{Name: s.Name, Status: status, DownSince: s.DownSince}
+		if cfg.ShowServers == ExposeUsers && s.DownSince == nil {
+			users := s.Users
+			pub.Users = &users
+		}
+		ps.Servers = append(ps.Servers, pub)
+	}
+	if shown > 0 && down == shown {
+		ps.Status = worse(ps.Status, StatusMajorOutage)
+	}
+	if len(active) > 0 {
+		ps.Status = worse(ps.Status, StatusMaintenance)
+	}
+
+	for _, ns := range snap.Netsplits {
+		pi := netsplitIncident(ns, cfg.ShowDetails)
+		if ns.End == nil {
+			ps.Incidents = append(ps.Incidents, pi)
+			ps.Status = worse(ps.Status, StatusPartialOutage)
+		} else if ns.End.After(since) {
+			ps.Resolved = append(ps.Resolved, pi)
+		}
+	}
+	for _, e := range snap.Escalations {
+		if notify.SeverityRank[e.Severity] < notify.SeverityRank[cfg.EscalationSeverity] {
+			continue
+		}
+		pi := escalationIncident(e, cfg.ShowDetails)
+		if e.ResolvedAt == nil {
+			ps.Incidents = append(ps.Incidents, pi)
+			ps.Status = worse(ps.Status, impactStatus[pi.Impact])
+		} else if e.ResolvedAt.After(since) {
+			ps.Resolved = append(ps.Resolved, pi)
+		}
+	}
+
+	sort.Slice(ps.Incidents, func(i, j int) bool { return ps.Incidents[i].Started.After(ps.Incidents[j].Started) })
+	sort.Slice(ps.Resolved, func(i, j int) bool { return ps.Resolved[i].Resolved.After(*ps.Resolved[j].Resolved) })
+	ps.Summary = statusSummary[ps.Status]
+	if ps.Stale {
+		ps.Summary += fmt.Sprintf(" (as of %s)", snap.Time.UTC().Format("15:04 MST"))
+	}
+	return ps
+}
+
+// underMaintenance reports whether an active window covers a server
+func underMaintenance(active []window, server string) bool {
+	for _, w := range active {
+		if w.covers(server) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicIncident strips the staff details from an incident
+func publicIncident(inc *Incident) PublicIncident {
+	pi := PublicIncident{
+		ID:       inc.ID,
+		Title:    inc.Title,
+		Impact:   inc.Impact,
+		State:    inc.State,
+		Servers:  inc.Servers,
+		Started:  inc.CreatedAt,
+		Resolved: inc.ResolvedAt,
+		Updates:  make([]PublicUpdate, 0, len(inc.Updates)),
+	}
+	// Newest update first, as status pages show them
+	for i := len(inc.Updates) - 1; i >= 0; i-- {
+		u := inc.Updates[i]
+		pi.Updates = append(pi.Updates, PublicUpdate{Time: u.Time, State: u.State, Message: u.Message})
+	}
+	return pi
+}
+
+// netsplitIncident describes a netsplit found by the netsplit-tracker
+// plugin. Without details the servers and users lost are left out.
+func netsplitIncident(ns netsplit, details bool) PublicIncident {
+	pi := PublicIncident{
+		ID:        "netsplit-" + ns.ID,
+		Title:     "Some servers are split from the network",
+		Impact:    ImpactPartial,
+		State:     StateInvestigating,
+		Automatic: true,
+		Started:   ns.Start,
+		Resolved:  ns.End,
+		Updates:   make([]PublicUpdate, 0),
+	}
+	msg := "Users on the affected servers may be disconnected or unable to see the rest of the network."
+	if details {
+		for _, s := range ns.Servers {
+			pi.Servers = append(pi.Servers, s.Name)
+		}
+		sort.Strings(pi.Servers)
+		msg = fmt.Sprintf("%s split from the network, disconnecting %d users from the rest of it.", strings.Join(pi.Servers, ", "), ns.UsersLost)
+	}
+	pi.Updates = append(pi.Updates, PublicUpdate{Time: ns.Start, State: StateInvestigating, Message: msg})
+	if ns.End != nil {
+		pi.State = StateResolved
+		pi.Updates = append([]PublicUpdate{{Time: *ns.End, State: StateResolved, Message: "All servers have rejoined the network."}}, pi.Updates...)
+	}
+	return pi
+}
+
+// escalationIncident describes an incident raised by the
+// incident-escalation plugin. Without details its summary, which is
+// written for staff, is replaced by a generic one.
+func escalationIncident(e escalation, details bool) PublicIncident {
+	impact := ImpactMinor
+	if e.Severity == "critical" {
+		impact = ImpactPartial
+	}
+	title := "We are investigating a problem"
+	if details && e.Summary != "" {
+		title = e.Summary
+	}
+	pi := PublicIncident{
+		ID:        "escalation-" + e.ID,
+		Title:     title,
+		Impact:    impact,
+		State:     StateInvestigating,
+		Automatic: true,
+		Started:   e.TriggeredAt,
+		Resolved:  e.ResolvedAt,
+		Updates:   make([]PublicUpdate, 0),
+	}
+	if e.ResolvedAt != nil {
+		pi.State = StateResolved
+		pi.Updates = append(pi.Updates, PublicUpdate{Time: *e.ResolvedAt, State: StateResolved, Message: "This problem has been resolved."})
+	}
+	return pi
+}
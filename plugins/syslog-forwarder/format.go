@@ -0,0 +1,208 @@
+package syslogforwarder
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Message formats
+const (
+	FormatRFC5424 = "rfc5424"
+	FormatCEF     = "cef"
+)
+
+// sdID is the structured data ID of the parameters we add. 32473 is the
+// enterprise number set aside for documentation and examples.
+const sdID = "uwp@32473"
+
+// facilities maps facility names onto their syslog codes
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Levels of forwarded events, lowest first. Alert severities are mapped
+// onto them.
+var levelRank = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+	"fatal": 4,
+}
+
+// alertLevels maps alert severities onto levels
+var alertLevels = map[string]string{
+	notify.SeverityInfo:     "info",
+	notify.SeverityWarning:  "warn",
+	notify.SeverityCritical: "error",
+}
+
+// syslogSeverities maps levels onto syslog severities
+var syslogSeverities = map[string]int{
+	"debug": 7,
+	"info":  6,
+	"warn":  4,
+	"error": 3,
+	"fatal": 2,
+}
+
+// cefSeverities maps levels onto CEF's 0 to 10 scale
+var cefSeverities = map[string]int{
+	"debug": 1,
+	"info":  3,
+	"warn":  6,
+	"error": 8,
+	"fatal": 10,
+}
+
+// record is an event ready to be formatted, from the IRCd log or from a
+// plugin alert
+type record struct {
+	Time     time.Time
+	Level    string
+	Product  string
+	Category string
+	Type     string
+	Name     string
+	Message  string
+	Params   map[string]string
+}
+
+// formatter turns records into syslog messages
+type formatter struct {
+	format   string
+	facility int
+	hostname string
+	appName  string
+}
+
+// render formats a record in the configured format
+func (f *formatter) render(r record) string {
+	if f.format == FormatCEF {
+		return f.header(r, "-") + " " + f.cef(r)
+	}
+	return f.header(r, f.structuredData(r)) + " " + r.Message
+}
+
+// header returns the RFC 5424 header followed by the structured data
+func (f *formatter) header(r record, sd string) string {
+	pri := f.facility*8 + syslogSeverities[r.Level]
+	return fmt.Sprintf("<%d>1 %s %s %s - %s %s",
+		pri,
+		r.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(f.hostname, 255),
+		headerField(f.appName, 48),
+		headerField(r.Type, 32),
+		sd)
+}
+
+// headerField makes a value fit an RFC 5424 header field: printable ASCII
+// without spaces, at most max characters, and "-" when empty
+func headerField(s string, max int) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c > 32 && c < 127 {
+			b.WriteRune(c)
+		}
+		if b.Len() == max {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+// sdEscaper escapes structured data parameter values
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// structuredData returns the record's parameters as an RFC 5424
+// structured data element
+func (f *formatter) structuredData(r record) string {
+	var b strings.Builder
+	b.WriteString("[" + sdID)
+	fmt.Fprintf(&b, ` category="%s" level="%s"`, sdEscaper.Replace(r.Category), r.Level)
+	for _, k := range sortedKeys(r.Params) {
+		if v := r.Params[k]; v != "" {
+			fmt.Fprintf(&b, ` %s="%s"`, headerField(k, 32), sdEscaper.Replace(v))
+		}
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// CEF escapers for header fields and extension values
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// cefKeys maps parameters onto CEF extension keys. Parameters without a
+// key are sent as custom strings.
+var cefKeys = map[string]string{
+	"client_ip":   "src",
+	"client_nick": "suser",
+	"client_host": "shost",
+	"server":      "dvchost",
+}
+
+// cef renders a record as a CEF message
+func (f *formatter) cef(r record) string {
+	ext := []string{
+		"rt=" + strconv.FormatInt(r.Time.UnixNano()/int64(time.Millisecond), 10),
+		"cat=" + cefExtensionEscaper.Replace(r.Category),
+		"msg=" + cefExtensionEscaper.Replace(r.Message),
+	}
+	custom := 0
+	for _, k := range sortedKeys(r.Params) {
+		v := r.Params[k]
+		if v == "" {
+			continue
+		}
+		if key, ok := cefKeys[k]; ok {
+			ext = append(ext, key+"="+cefExtensionEscaper.Replace(v))
+			continue
+		}
+		// CEF has six custom string slots
+		if custom < 6 {
+			custom++
+			ext = append(ext,
+				fmt.Sprintf("cs%dLabel=%s", custom, cefExtensionEscaper.Replace(k)),
+				fmt.Sprintf("cs%d=%s", custom, cefExtensionEscaper.Replace(v)))
+		}
+	}
+	return fmt.Sprintf("CEF:0|UnrealIRCd|%s|1.0|%s|%s|%d|%s",
+		cefHeaderEscaper.Replace(r.Product),
+		cefHeaderEscaper.Replace(r.Type),
+		cefHeaderEscaper.Replace(r.Name),
+		cefSeverities[r.Level],
+		strings.Join(ext, " "))
+}
+
+// frame prepares a message for the wire. Octet counting (RFC 6587) puts
+// the length in front; otherwise messages end with a newline.
+func frame(msg string, octetCounting bool) string {
+	if octetCounting {
+		return strconv.Itoa(len(msg)) + " " + msg
+	}
+	return strings.ReplaceAll(msg, "\n", " ") + "\n"
+}
+
+// sortedKeys returns the keys of a parameter map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
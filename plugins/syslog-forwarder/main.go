@@ -0,0 +1,690 @@
+// Syslog Forwarder Plugin for UnrealIRCd Web Panel
+// Forwards IRCd log events and plugin alerts to a SIEM as RFC 5424 syslog
+// or CEF over TCP or TLS
+
+package syslogforwarder
+
+import (
+	"context"
+	"crypto/tls"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Transports
+const (
+	TransportTCP = "tcp"
+	TransportTLS = "tls"
+)
+
+// Sending limits
+const (
+	batchSize  = 200
+	maxBackoff = 5 * time.Minute
+)
+
+// SyslogForwarderPlugin implements the Plugin interface
+type SyslogForwarderPlugin struct {
+	config       Config
+	spool        []string
+	trimmed      int
+	notify       chan struct{}
+	reconnect    bool
+	connected    bool
+	sent         int
+	filtered     int
+	dropped      int
+	lastSent     time.Time
+	sendErr      string
+	streamOK     bool
+	cancelStream context.CancelFunc
+	dirty        bool
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	StreamURL     string `json:"stream_url"`
+	LogSources    string `json:"log_sources"`
+	DataDir       string `json:"data_dir"`
+	Collector     string `json:"collector"`
+	Transport     string `json:"transport"`
+	TLSInsecure   bool   `json:"tls_insecure"`
+	OctetCounting bool   `json:"octet_counting"`
+	Format        string `json:"format"`
+	Facility      string `json:"facility"`
+	Hostname      string `json:"hostname"`
+	AppName       string `json:"app_name"`
+	Events        string `json:"events"`
+	ExcludeEvents string `json:"exclude_events"`
+	MinLevel      string `json:"min_level"`
+	EventsToken   string `json:"events_token"`
+	SpoolMax      int    `json:"spool_max"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Spool []string `json:"spool"`
+}
+
+// logClient is the part of the client object in a log entry we forward
+type logClient struct {
+	Client *struct {
+		Name     string `json:"name"`
+		Hostname string `json:"hostname"`
+		IP       string `json:"ip"`
+		User     *struct {
+			Account   string `json:"account"`
+			Operlogin string `json:"operlogin"`
+		} `json:"user"`
+	} `json:"client"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &SyslogForwarderPlugin{
+		config: Config{
+			StreamURL:     "wss://127.0.0.1:8600/",
+			LogSources:    "all,!debug",
+			DataDir:       "data/plugins/syslog-forwarder",
+			Transport:     TransportTLS,
+			OctetCounting: true,
+			Format:        FormatRFC5424,
+			Facility:      "local0",
+			AppName:       "unrealircd",
+			Events:        "*",
+			MinLevel:      "info",
+			SpoolMax:      50000,
+		},
+		spool:  make([]string, 0),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *SyslogForwarderPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Syslog Forwarder",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Forwards IRCd log events and plugin alerts to a SIEM as syslog or CEF",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *SyslogForwarderPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[syslog-forwarder] failed to load spool: %v", err)
+	}
+	if data.Spool != nil {
+		p.spool = data.Spool
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "syslog-forwarder-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"sent":    p.sent,
+			"spooled": len(p.spool),
+			"dropped": p.dropped,
+		}
+		switch {
+		case p.config.Collector == "":
+			content["status"] = "Not configured"
+		case !p.connected:
+			content["status"] = "Collector unreachable"
+		}
+		return plugins.DashboardCard{
+			Title:   "Syslog Forwarder",
+			Icon:    "ScrollText",
+			Content: content,
+			Order:   71,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(3)
+	go p.streamLoop()
+	go p.sendLoop()
+	go p.saveLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *SyslogForwarderPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *SyslogForwarderPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/syslog-forwarder")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/test", p.handleTest)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file the spool is kept in
+func (p *SyslogForwarderPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "spool.json")
+}
+
+// save writes the spool to disk if it changed
+func (p *SyslogForwarderPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Spool: p.spool}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// saveLoop writes the spool to disk every minute, so messages waiting for
+// the collector survive a crash
+func (p *SyslogForwarderPlugin) saveLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if err := p.save(); err != nil {
+				log.Printf("[syslog-forwarder] failed to save spool: %v", err)
+			}
+		}
+	}
+}
+
+// formatter returns the formatter for the current configuration. Caller
+// must hold p.mu.
+func (p *SyslogForwarderPlugin) formatter() *formatter {
+	hostname := p.config.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	return &formatter{
+		format:   p.config.Format,
+		facility: facilities[p.config.Facility],
+		hostname: hostname,
+		appName:  p.config.AppName,
+	}
+}
+
+// wanted reports whether a record passes the event filters. Events and
+// exclusions match the record's category, such as a subsystem or plugin
+// name, or its type, such as an event ID or alert type. Caller must hold
+// p.mu.
+func (p *SyslogForwarderPlugin) wanted(r record) bool {
+	if levelRank[r.Level] < levelRank[p.config.MinLevel] {
+		return false
+	}
+	exclude := lists.Split(p.config.ExcludeEvents)
+	if lists.ContainsFold(exclude, r.Category) || lists.ContainsFold(exclude, r.Type) {
+		return false
+	}
+	events := lists.Split(p.config.Events)
+	return lists.ContainsFold(events, "*") || lists.ContainsFold(events, r.Category) || lists.ContainsFold(events, r.Type)
+}
+
+// enqueue formats a record into the spool if it passes the filters. When
+// the spool is full the oldest messages are dropped. Caller must hold
+// p.mu.
+func (p *SyslogForwarderPlugin) enqueue(r record) {
+	if !p.wanted(r) {
+		p.filtered++
+		return
+	}
+	p.spool = append(p.spool, p.formatter().render(r))
+	if over := len(p.spool) - p.config.SpoolMax; over > 0 {
+		p.spool = append([]string(nil), p.spool[over:]...)
+		p.trimmed += over
+		p.dropped += over
+	}
+	p.dirty = true
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// dial connects to the collector
+func dial(cfg Config) (net.Conn, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	if cfg.Collector == "" {
+		return nil, fmt.Errorf("no collector is configured")
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if cfg.Transport == TransportTLS {
+		host, _, err := net.SplitHostPort(cfg.Collector)
+		if err != nil {
+			return nil, err
+		}
+		return tls.DialWithDialer(dialer, "tcp", cfg.Collector, &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: cfg.TLSInsecure,
+		})
+	}
+	return dialer.Dial("tcp", cfg.Collector)
+}
+
+// writeAll sends framed messages over a connection
+func writeAll(conn net.Conn, msgs []string, octetCounting bool) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	var b strings.Builder
+	for _, m := range msgs {
+		b.WriteString(frame(m, octetCounting))
+	}
+	_, err := io.WriteString(conn, b.String())
+	return err
+}
+
+// sendLoop sends the spool to the collector in order until shutdown,
+// reconnecting with backoff while it is unreachable
+func (p *SyslogForwarderPlugin) sendLoop() {
+	defer p.wg.Done()
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	backoff := time.Duration(0)
+	for {
+		p.mu.Lock()
+		if p.reconnect && conn != nil {
+			conn.Close()
+			conn = nil
+		}
+		p.reconnect = false
+		cfg := p.config
+		n := len(p.spool)
+		if n > batchSize {
+			n = batchSize
+		}
+		batch := append([]string(nil), p.spool[:n]...)
+		trimmed := p.trimmed
+		p.mu.Unlock()
+
+		if len(batch) == 0 {
+			select {
+			case <-p.stop:
+				return
+			case <-p.notify:
+			}
+			continue
+		}
+
+		var err error
+		if conn == nil {
+			conn, err = dial(cfg)
+		}
+		if err == nil {
+			err = writeAll(conn, batch, cfg.OctetCounting)
+		}
+
+		p.mu.Lock()
+		if err != nil {
+			if conn != nil {
+				conn.Close()
+				conn = nil
+			}
+			p.connected = false
+			p.sendErr = err.Error()
+			p.mu.Unlock()
+
+			if backoff == 0 {
+				backoff = 5 * time.Second
+				log.Printf("[syslog-forwarder] collector unreachable, spooling: %v", err)
+			} else if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(backoff):
+			}
+			continue
+		}
+		// Messages dropped from a full spool while sending were at its
+		// front, so fewer of the sent ones are left to remove
+		if removed := len(batch) - (p.trimmed - trimmed); removed > 0 {
+			p.spool = append([]string(nil), p.spool[removed:]...)
+		}
+		p.connected = true
+		p.sendErr = ""
+		p.sent += len(batch)
+		p.lastSent = time.Now()
+		p.dirty = true
+		p.mu.Unlock()
+		if backoff > 0 {
+			log.Printf("[syslog-forwarder] collector reachable again")
+			backoff = 0
+		}
+	}
+}
+
+// streamLoop runs the log stream until shutdown, restarting it when the
+// configuration changes
+func (p *SyslogForwarderPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *SyslogForwarderPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[syslog-forwarder] log stream: %v", err)
+	}
+}
+
+// handleEvent forwards an IRCd log entry
+func (p *SyslogForwarderPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	r := logRecord(ev)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enqueue(r)
+}
+
+// logRecord turns an IRCd log entry into a record, with the client it is
+// about when there is one
+func logRecord(ev jsonrpc.LogEvent) record {
+	ts, err := time.Parse(time.RFC3339Nano, ev.Timestamp)
+	if err != nil {
+		ts = time.Now().UTC()
+	}
+	level := strings.ToLower(ev.Level)
+	if _, ok := levelRank[level]; !ok {
+		level = "info"
+	}
+	name := ev.Msg
+	if r := []rune(name); len(r) > 100 {
+		name = string(r[:99]) + "…"
+	}
+	r := record{
+		Time:     ts,
+		Level:    level,
+		Product:  "UnrealIRCd",
+		Category: ev.Subsystem,
+		Type:     ev.EventID,
+		Name:     name,
+		Message:  ev.Msg,
+		Params:   map[string]string{},
+	}
+
+	var lc logClient
+	if json.Unmarshal(ev.Raw, &lc) == nil && lc.Client != nil {
+		r.Params["client_nick"] = lc.Client.Name
+		r.Params["client_host"] = lc.Client.Hostname
+		r.Params["client_ip"] = lc.Client.IP
+		if lc.Client.User != nil {
+			r.Params["account"] = lc.Client.User.Account
+			r.Params["oper"] = lc.Client.User.Operlogin
+		}
+	}
+	return r
+}
+
+// alertRecord turns a plugin alert into a record. Plain values in the
+// alert's data are forwarded as parameters.
+func alertRecord(ev notify.Event) record {
+	msg := ev.Message
+	if msg == "" {
+		msg = ev.Title
+	}
+	r := record{
+		Time:     ev.Timestamp,
+		Level:    alertLevels[ev.Severity],
+		Product:  "Web Panel",
+		Category: ev.Source,
+		Type:     ev.Type,
+		Name:     ev.Title,
+		Message:  msg,
+		Params:   map[string]string{},
+	}
+	for k, v := range ev.Data {
+		switch v.(type) {
+		case string, float64, bool:
+			r.Params[k] = fmt.Sprint(v)
+		}
+	}
+	return r
+}
+
+// handleStatus returns the collector, the spool and the event sources
+func (p *SyslogForwarderPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"collector": p.config.Collector,
+		"transport": p.config.Transport,
+		"format":    p.config.Format,
+		"connected": p.connected,
+		"error":     p.sendErr,
+		"spooled":   len(p.spool),
+		"sent":      p.sent,
+		"filtered":  p.filtered,
+		"dropped":   p.dropped,
+		"stream_ok": p.streamOK,
+	}
+	if !p.lastSent.IsZero() {
+		status["last_sent"] = p.lastSent
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleTest sends a test message over a connection of its own and
+// reports the outcome, without going through the spool
+func (p *SyslogForwarderPlugin) handleTest(c *gin.Context) {
+	p.mu.RLock()
+	cfg := p.config
+	msg := p.formatter().render(record{
+		Time:     time.Now().UTC(),
+		Level:    "info",
+		Product:  "Web Panel",
+		Category: "syslog-forwarder",
+		Type:     "TEST",
+		Name:     "Test message",
+		Message:  "Test message from the UnrealIRCd Web Panel",
+		Params:   map[string]string{},
+	})
+	p.mu.RUnlock()
+
+	conn, err := dial(cfg)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer conn.Close()
+	if err := writeAll(conn, []string{msg}, cfg.OctetCounting); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test message sent", "sent": msg})
+}
+
+// handleEvents accepts alerts from other plugins
+func (p *SyslogForwarderPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	p.enqueue(alertRecord(ev))
+	p.mu.Unlock()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *SyslogForwarderPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *SyslogForwarderPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.Collector != "" {
+		if _, _, err := net.SplitHostPort(newConfig.Collector); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "collector must be host:port"})
+			return
+		}
+	}
+	if newConfig.Transport != TransportTCP && newConfig.Transport != TransportTLS {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "transport must be tcp or tls"})
+		return
+	}
+	if newConfig.Format != FormatRFC5424 && newConfig.Format != FormatCEF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be rfc5424 or cef"})
+		return
+	}
+	if _, ok := facilities[newConfig.Facility]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown facility"})
+		return
+	}
+	if _, ok := levelRank[newConfig.MinLevel]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_level must be debug, info, warn, error or fatal"})
+		return
+	}
+	if newConfig.SpoolMax < 100 || newConfig.SpoolMax > 1000000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "spool_max must be between 100 and 1000000"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.reconnect = true
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	// Wake the send loop, which may be waiting out a backoff for the old
+	// collector
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *SyslogForwarderPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *SyslogForwarderPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
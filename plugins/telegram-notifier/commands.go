@@ -0,0 +1,196 @@
+package telegramnotifier
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// maxListed limits the servers and bans listed in one reply
+const maxListed = 15
+
+// helpText lists the commands the bot answers
+const helpText = `<b>Commands</b>
+/users - Users, opers and channels online
+/servers - Linked servers and their users
+/bans [type] - Server bans, newest first, optionally of one type only`
+
+// statsResult is the part of stats.get we need
+type statsResult struct {
+	Server struct {
+		Total int `json:"total"`
+	} `json:"server"`
+	User struct {
+		Total  int `json:"total"`
+		Oper   int `json:"oper"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+}
+
+// rpcServer is a server as returned by server.list
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		Uplink   string `json:"uplink"`
+		NumUsers int    `json:"num_users"`
+		Synced   bool   `json:"synced"`
+		ULined   bool   `json:"ulined"`
+	} `json:"server"`
+}
+
+// rpcBan is a ban as returned by server_ban.list
+type rpcBan struct {
+	Type           string `json:"type"`
+	Name           string `json:"name"`
+	SetAt          string `json:"set_at"`
+	SetBy          string `json:"set_by"`
+	DurationString string `json:"duration_string"`
+	Reason         string `json:"reason"`
+}
+
+// parseCommand splits a message into a command and its arguments. Commands
+// addressed to another bot, as /users@OtherBot, are ignored.
+func parseCommand(text, botName string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil
+	}
+	name := strings.ToLower(fields[0][1:])
+	if i := strings.IndexByte(name, '@'); i >= 0 {
+		if botName != "" && !strings.EqualFold(name[i+1:], botName) {
+			return "", nil
+		}
+		name = name[:i]
+	}
+	return name, fields[1:]
+}
+
+// runCommand answers a command with an HTML reply. Unknown commands get
+// no reply, as group chats often have more than one bot.
+func (p *TelegramNotifierPlugin) runCommand(ctx context.Context, name string, args []string) (string, bool) {
+	var reply string
+	var err error
+	switch name {
+	case "start", "help":
+		return helpText, true
+	case "users":
+		reply, err = p.usersReply(ctx)
+	case "servers":
+		reply, err = p.serversReply(ctx)
+	case "bans":
+		kind := ""
+		if len(args) > 0 {
+			kind = args[0]
+		}
+		reply, err = p.bansReply(ctx, kind)
+	default:
+		return "", false
+	}
+	if err != nil {
+		return "Could not reach the IRC server: " + html.EscapeString(err.Error()), true
+	}
+	return reply, true
+}
+
+// usersReply summarises stats.get
+func (p *TelegramNotifierPlugin) usersReply(ctx context.Context) (string, error) {
+	var stats statsResult
+	if err := p.client().Call(ctx, "stats.get", nil, &stats); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("👥 <b>%d</b> users online (record %d)\n%d opers, %d channels, %d servers",
+		stats.User.Total, stats.User.Record, stats.User.Oper, stats.Channel.Total, stats.Server.Total), nil
+}
+
+// serversReply lists the linked servers, busiest first
+func (p *TelegramNotifierPlugin) serversReply(ctx context.Context) (string, error) {
+	var out struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := p.client().Call(ctx, "server.list", nil, &out); err != nil {
+		return "", err
+	}
+	sort.Slice(out.List, func(i, j int) bool {
+		return out.List[i].Server.NumUsers > out.List[j].Server.NumUsers
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🖧 <b>%d</b> servers linked", len(out.List))
+	for i, s := range out.List {
+		if i == maxListed {
+			fmt.Fprintf(&sb, "\n… and %d more", len(out.List)-maxListed)
+			break
+		}
+		fmt.Fprintf(&sb, "\n• <code>%s</code> %d users", html.EscapeString(s.Name), s.Server.NumUsers)
+		if s.Server.Uplink != "" {
+			fmt.Fprintf(&sb, ", via %s", html.EscapeString(s.Server.Uplink))
+		}
+		if s.Server.ULined {
+			sb.WriteString(", services")
+		}
+		if !s.Server.Synced {
+			sb.WriteString(", <b>not synced</b>")
+		}
+	}
+	return sb.String(), nil
+}
+
+// bansReply counts the server bans by type and lists the newest, of one
+// type only when kind is given
+func (p *TelegramNotifierPlugin) bansReply(ctx context.Context, kind string) (string, error) {
+	var out struct {
+		List []rpcBan `json:"list"`
+	}
+	if err := p.client().Call(ctx, "server_ban.list", nil, &out); err != nil {
+		return "", err
+	}
+
+	counts := make(map[string]int)
+	bans := make([]rpcBan, 0, len(out.List))
+	for _, b := range out.List {
+		counts[b.Type]++
+		if kind == "" || strings.EqualFold(b.Type, kind) {
+			bans = append(bans, b)
+		}
+	}
+	// set_at is an ISO 8601 timestamp, so it sorts as text
+	sort.Slice(bans, func(i, j int) bool { return bans[i].SetAt > bans[j].SetAt })
+
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[t], html.EscapeString(t)))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔨 <b>%d</b> server bans", len(out.List))
+	if len(parts) > 0 {
+		fmt.Fprintf(&sb, " (%s)", strings.Join(parts, ", "))
+	}
+	if kind != "" {
+		fmt.Fprintf(&sb, "\n%d of type %s", len(bans), html.EscapeString(kind))
+	}
+	for i, b := range bans {
+		if i == maxListed {
+			fmt.Fprintf(&sb, "\n… and %d more", len(bans)-maxListed)
+			break
+		}
+		duration := b.DurationString
+		if duration == "" || duration == "0" {
+			duration = "permanent"
+		}
+		fmt.Fprintf(&sb, "\n• %s <code>%s</code> by %s, %s: %s",
+			html.EscapeString(b.Type), html.EscapeString(b.Name), html.EscapeString(b.SetBy),
+			html.EscapeString(duration), html.EscapeString(truncate(b.Reason, 120)))
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,626 @@
+// Telegram Notifier Plugin for UnrealIRCd Web Panel
+// Sends bans and alerts from other plugins to a staff group chat through a
+// Telegram bot, which also answers a few read-only commands
+
+package telegramnotifier
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Limits on queued events and delivery attempts
+const (
+	queueSize     = 200
+	maxBatch      = 20
+	maxAttempts   = 3
+	pollTimeout   = 50 * time.Second
+	maxCommandAge = 2 * time.Minute
+)
+
+// TelegramNotifierPlugin implements the Plugin interface
+type TelegramNotifierPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	bot          *botClient
+	botName      string
+	queue        chan notify.Event
+	received     int
+	sent         int
+	failed       int
+	dropped      int
+	commands     int
+	lastSent     time.Time
+	sendErr      string
+	updatesErr   string
+	streamOK     bool
+	cancelStream context.CancelFunc
+	cancelPoll   context.CancelFunc
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	StreamURL     string `json:"stream_url"`
+	LogSources    string `json:"log_sources"`
+	APIURL        string `json:"api_url"`
+	BotToken      string `json:"bot_token"`
+	ChatID        string `json:"chat_id"`
+	Events        string `json:"events"`
+	MinSeverity   string `json:"min_severity"`
+	EventsToken   string `json:"events_token"`
+	RatePerMinute int    `json:"rate_per_minute"`
+	NotifyBans    bool   `json:"notify_bans"`
+	BanTypes      string `json:"ban_types"`
+	Commands      bool   `json:"commands"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &TelegramNotifierPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			StreamURL:     "wss://127.0.0.1:8600/",
+			LogSources:    "tkl",
+			APIURL:        "https://api.telegram.org",
+			Events:        "*",
+			MinSeverity:   notify.SeverityInfo,
+			RatePerMinute: 20,
+			NotifyBans:    true,
+			Commands:      true,
+		},
+		queue: make(chan notify.Event, queueSize),
+	}
+}
+
+// Info returns plugin metadata
+func (p *TelegramNotifierPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Telegram Notifier",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Sends alerts to a Telegram staff chat and answers read-only commands",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *TelegramNotifierPlugin) Init() error {
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "telegram-notifier-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"sent":     p.sent,
+			"failed":   p.failed,
+			"queued":   len(p.queue),
+			"commands": p.commands,
+		}
+		if p.config.BotToken == "" || p.config.ChatID == "" {
+			content["status"] = "Not configured"
+		}
+		return plugins.DashboardCard{
+			Title:   "Telegram Notifier",
+			Icon:    "Send",
+			Content: content,
+			Order:   62,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(3)
+	go p.streamLoop()
+	go p.sendLoop()
+	go p.updateLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *TelegramNotifierPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		if p.cancelPoll != nil {
+			p.cancelPoll()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *TelegramNotifierPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/telegram-notifier")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/test", p.handleTest)
+		plugin.POST("/events", p.handleEvents)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *TelegramNotifierPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// botFor returns the bot client and the staff chat, or nil when no bot
+// token is set. Caller must hold p.mu.
+func (p *TelegramNotifierPlugin) botFor() (*botClient, string) {
+	if p.config.BotToken == "" {
+		return nil, ""
+	}
+	if p.bot == nil {
+		p.bot = newBotClient(p.config.APIURL, p.config.BotToken)
+	}
+	return p.bot, p.config.ChatID
+}
+
+// wanted reports whether an event is sent to the chat. The events setting
+// lists event types or source plugins, or * for everything. Caller must
+// hold p.mu.
+func (p *TelegramNotifierPlugin) wanted(ev notify.Event) bool {
+	if notify.SeverityRank[ev.Severity] < notify.SeverityRank[p.config.MinSeverity] {
+		return false
+	}
+	for _, e := range lists.Split(p.config.Events) {
+		if e == "*" || strings.EqualFold(e, ev.Type) || strings.EqualFold(e, ev.Source) {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch queues an event for the chat. Events arriving while the queue
+// is full are dropped.
+func (p *TelegramNotifierPlugin) dispatch(ev notify.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.received++
+	if !p.wanted(ev) {
+		return
+	}
+	select {
+	case p.queue <- ev:
+	default:
+		p.dropped++
+	}
+}
+
+// streamLoop follows ban log events until shutdown
+func (p *TelegramNotifierPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *TelegramNotifierPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[telegram-notifier] log stream: %v", err)
+	}
+}
+
+// handleEvent announces new server bans
+func (p *TelegramNotifierPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	p.mu.RLock()
+	enabled := p.config.NotifyBans
+	types := lists.Split(p.config.BanTypes)
+	p.mu.RUnlock()
+	if !enabled {
+		return
+	}
+	if a, ok := notify.BanEvent("telegram-notifier", ev, types); ok {
+		p.dispatch(a)
+	}
+}
+
+// sendLoop delivers queued events one message at a time, waiting between
+// messages to stay within rate_per_minute. A backlog is packed into as few
+// messages as Telegram allows.
+func (p *TelegramNotifierPlugin) sendLoop() {
+	defer p.wg.Done()
+
+	for {
+		var first notify.Event
+		select {
+		case <-p.stop:
+			return
+		case first = <-p.queue:
+		}
+		texts := []string{formatEvent(first)}
+	drain:
+		for len(texts) < maxBatch {
+			select {
+			case ev := <-p.queue:
+				texts = append(texts, formatEvent(ev))
+			default:
+				break drain
+			}
+		}
+
+		messages, counts := joinMessages(texts)
+		for i, text := range messages {
+			p.mu.Lock()
+			bot, chat := p.botFor()
+			interval := time.Minute
+			if p.config.RatePerMinute > 0 {
+				interval = time.Minute / time.Duration(p.config.RatePerMinute)
+			}
+			p.mu.Unlock()
+
+			var err error
+			if bot == nil || chat == "" {
+				err = fmt.Errorf("bot_token and chat_id are not set")
+			} else {
+				err = p.send(bot, chat, text)
+			}
+
+			p.mu.Lock()
+			if err != nil {
+				p.failed += counts[i]
+				p.sendErr = err.Error()
+			} else {
+				p.sent += counts[i]
+				p.sendErr = ""
+				p.lastSent = time.Now().UTC()
+			}
+			p.mu.Unlock()
+			if err != nil {
+				log.Printf("[telegram-notifier] delivery failed: %v", err)
+			}
+
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(interval):
+			}
+		}
+	}
+}
+
+// send posts a message, waiting and trying again when Telegram rate
+// limits the bot
+func (p *TelegramNotifierPlugin) send(bot *botClient, chat, text string) error {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		err = bot.sendMessage(ctx, chat, text)
+		cancel()
+
+		be, ok := err.(*botError)
+		if !ok || be.RetryAfter == 0 {
+			return err
+		}
+		select {
+		case <-p.stop:
+			return err
+		case <-time.After(be.RetryAfter):
+		}
+	}
+	return err
+}
+
+// updateLoop long-polls the bot for commands from the staff chat until
+// shutdown
+func (p *TelegramNotifierPlugin) updateLoop() {
+	defer p.wg.Done()
+
+	var offset int64
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		bot, chat := p.botFor()
+		enabled := p.config.Commands && bot != nil && chat != ""
+		name := p.botName
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelPoll = cancel
+		p.mu.Unlock()
+
+		if !enabled {
+			cancel()
+			if !p.wait(30 * time.Second) {
+				return
+			}
+			continue
+		}
+
+		// The bot's username tells commands for it apart from commands
+		// for other bots in the chat
+		if name == "" {
+			var me struct {
+				Username string `json:"username"`
+			}
+			if err := bot.call(ctx, "getMe", nil, &me); err == nil {
+				p.mu.Lock()
+				p.botName = me.Username
+				p.mu.Unlock()
+				name = me.Username
+			}
+		}
+
+		updates, err := bot.getUpdates(ctx, offset, pollTimeout)
+		canceled := ctx.Err() != nil
+		cancel()
+		if err != nil {
+			if canceled {
+				// Shutdown or a config change
+				continue
+			}
+			p.mu.Lock()
+			p.updatesErr = err.Error()
+			p.mu.Unlock()
+			log.Printf("[telegram-notifier] failed to get updates: %v", err)
+			if !p.wait(10 * time.Second) {
+				return
+			}
+			continue
+		}
+		p.mu.Lock()
+		p.updatesErr = ""
+		p.mu.Unlock()
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			m := u.Message
+			if m == nil || strconv.FormatInt(m.Chat.ID, 10) != chat {
+				continue
+			}
+			// Skip commands sent while the panel was down
+			if time.Since(time.Unix(m.Date, 0)) > maxCommandAge {
+				continue
+			}
+			p.handleCommand(bot, chat, m.Text, name)
+		}
+	}
+}
+
+// handleCommand answers a message from the staff chat if it is a command
+func (p *TelegramNotifierPlugin) handleCommand(bot *botClient, chat, text, botName string) {
+	command, args := parseCommand(text, botName)
+	if command == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	reply, ok := p.runCommand(ctx, command, args)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	p.commands++
+	p.mu.Unlock()
+
+	if err := bot.sendMessage(ctx, chat, reply); err != nil {
+		log.Printf("[telegram-notifier] failed to answer /%s: %v", command, err)
+	}
+}
+
+// wait sleeps for d, returning false if the plugin shuts down first
+func (p *TelegramNotifierPlugin) wait(d time.Duration) bool {
+	select {
+	case <-p.stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// handleStatus reports the bot, the event sources and the queue
+func (p *TelegramNotifierPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"configured":     p.config.BotToken != "" && p.config.ChatID != "",
+		"bot":            p.botName,
+		"log_stream":     p.streamOK,
+		"notify_bans":    p.config.NotifyBans,
+		"events_enabled": p.config.EventsToken != "",
+		"commands":       p.config.Commands,
+		"received":       p.received,
+		"queued":         len(p.queue),
+		"sent":           p.sent,
+		"failed":         p.failed,
+		"dropped":        p.dropped,
+		"answered":       p.commands,
+		"last_sent":      p.lastSent,
+		"send_error":     p.sendErr,
+		"updates_error":  p.updatesErr,
+	})
+}
+
+// handleTest sends a test message to the chat straight away and reports
+// the result
+func (p *TelegramNotifierPlugin) handleTest(c *gin.Context) {
+	p.mu.Lock()
+	bot, chat := p.botFor()
+	p.mu.Unlock()
+	if bot == nil || chat == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set bot_token and chat_id first"})
+		return
+	}
+
+	text := formatEvent(notify.Event{
+		Source:   "telegram-notifier",
+		Type:     EventTest,
+		Severity: notify.SeverityInfo,
+		Title:    "Test message",
+		Message:  fmt.Sprintf("Sent by %s from the web panel. Send /help for the commands I answer.", access.Actor(c)),
+	})
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+	if err := bot.sendMessage(ctx, chat, text); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test message sent"})
+}
+
+// handleEvents accepts an alert from another plugin or script, sent with
+// the events token in an X-Events-Token header
+func (p *TelegramNotifierPlugin) handleEvents(c *gin.Context) {
+	p.mu.RLock()
+	token := p.config.EventsToken
+	p.mu.RUnlock()
+
+	ev, ok := notify.Receive(c, token)
+	if !ok {
+		return
+	}
+
+	p.dispatch(ev)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event queued"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *TelegramNotifierPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.BotToken = ""
+	cfg.EventsToken = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *TelegramNotifierPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.RatePerMinute < 1 || newConfig.RatePerMinute > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate_per_minute must be between 1 and 20"})
+		return
+	}
+	if _, ok := notify.SeverityRank[newConfig.MinSeverity]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_severity must be info, warning or critical"})
+		return
+	}
+	if newConfig.ChatID != "" {
+		if _, err := strconv.ParseInt(newConfig.ChatID, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chat_id must be a numeric chat ID"})
+			return
+		}
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.BotToken == "" {
+		newConfig.BotToken = p.config.BotToken
+	}
+	if newConfig.EventsToken == "" {
+		newConfig.EventsToken = p.config.EventsToken
+	}
+	if newConfig.BotToken != p.config.BotToken || newConfig.APIURL != p.config.APIURL {
+		p.bot = nil
+		p.botName = ""
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	if p.cancelPoll != nil {
+		p.cancelPoll()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *TelegramNotifierPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *TelegramNotifierPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	p.bot = nil
+	p.botName = ""
+	return json.Unmarshal(data, &p.config)
+}
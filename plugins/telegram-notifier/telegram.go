@@ -0,0 +1,230 @@
+package telegramnotifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+)
+
+// Message limits. Telegram counts characters after parsing the HTML, so
+// measuring the markup in bytes stays on the safe side.
+const (
+	maxMessageLen = 4096
+	maxEventLen   = 3600
+)
+
+// severityLabels prefix the title of each event
+var severityLabels = map[string]string{
+	notify.SeverityInfo:     "ℹ️",
+	notify.SeverityWarning:  "⚠️",
+	notify.SeverityCritical: "🚨",
+}
+
+// botClient is a minimal client for the Telegram Bot API
+type botClient struct {
+	url    string
+	client *http.Client
+}
+
+// botResponse is the envelope of every Bot API response
+type botResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// botError is an error returned by the Bot API. RetryAfter is set when the
+// request was rate limited.
+type botError struct {
+	Code        int
+	Description string
+	RetryAfter  time.Duration
+}
+
+func (e *botError) Error() string {
+	return fmt.Sprintf("telegram error %d: %s", e.Code, e.Description)
+}
+
+// update is an incoming update; only messages are requested
+type update struct {
+	UpdateID int64       `json:"update_id"`
+	Message  *botMessage `json:"message"`
+}
+
+// botMessage is a message sent to the bot
+type botMessage struct {
+	MessageID int64 `json:"message_id"`
+	Date      int64 `json:"date"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	From *struct {
+		Username string `json:"username"`
+	} `json:"from"`
+	Text string `json:"text"`
+}
+
+// newBotClient creates a client for the bot with the given token
+func newBotClient(apiURL, token string) *botClient {
+	return &botClient{
+		url: strings.TrimRight(apiURL, "/") + "/bot" + token + "/",
+		// Long polls for updates take up to a minute
+		client: &http.Client{Timeout: 90 * time.Second},
+	}
+}
+
+// call invokes a Bot API method and decodes its result into out
+func (b *botClient) call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		// The URL holds the bot token, keep it out of logs
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			return fmt.Errorf("telegram %s: %w", method, ue.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	var br botResponse
+	if err := json.NewDecoder(resp.Body).Decode(&br); err != nil {
+		return fmt.Errorf("telegram %s: unexpected response (%s)", method, resp.Status)
+	}
+	if !br.OK {
+		be := &botError{Code: br.ErrorCode, Description: br.Description}
+		if br.Parameters != nil && br.Parameters.RetryAfter > 0 {
+			be.RetryAfter = time.Duration(br.Parameters.RetryAfter) * time.Second
+		}
+		return be
+	}
+	if out != nil {
+		return json.Unmarshal(br.Result, out)
+	}
+	return nil
+}
+
+// sendMessage posts HTML formatted text to a chat
+func (b *botClient) sendMessage(ctx context.Context, chatID, text string) error {
+	return b.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}, nil)
+}
+
+// getUpdates waits up to timeout for messages after offset
+func (b *botClient) getUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]update, error) {
+	var updates []update
+	err := b.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// formatEvent renders an event as an HTML message, with its data as a
+// list of fields
+func formatEvent(ev notify.Event) string {
+	var sb strings.Builder
+	sb.WriteString(severityLabels[ev.Severity])
+	sb.WriteString(" <b>")
+	sb.WriteString(html.EscapeString(truncate(ev.Title, 256)))
+	sb.WriteString("</b>")
+	if ev.Message != "" && ev.Message != ev.Title {
+		sb.WriteString("\n")
+		sb.WriteString(html.EscapeString(truncate(ev.Message, 2048)))
+	}
+
+	keys := make([]string, 0, len(ev.Data))
+	for k := range ev.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var value string
+		switch v := ev.Data[k].(type) {
+		case string:
+			value = v
+		case []interface{}:
+			parts := make([]string, 0, len(v))
+			for _, item := range v {
+				parts = append(parts, fmt.Sprint(item))
+			}
+			value = strings.Join(parts, ", ")
+		case []string:
+			value = strings.Join(v, ", ")
+		default:
+			value = fmt.Sprint(v)
+		}
+		if value == "" {
+			continue
+		}
+		line := fmt.Sprintf("\n<b>%s:</b> %s", html.EscapeString(strings.ReplaceAll(k, "_", " ")), html.EscapeString(truncate(value, 256)))
+		if sb.Len()+len(line) > maxEventLen {
+			break
+		}
+		sb.WriteString(line)
+	}
+
+	if ev.Source != "" {
+		fmt.Fprintf(&sb, "\n<i>%s · %s</i>", html.EscapeString(ev.Source), html.EscapeString(ev.Type))
+	}
+	return sb.String()
+}
+
+// joinMessages packs formatted events into as few messages as fit within
+// maxMessageLen, returning the messages and how many events each holds
+func joinMessages(texts []string) ([]string, []int) {
+	messages := make([]string, 0, 1)
+	counts := make([]int, 0, 1)
+	for _, t := range texts {
+		last := len(messages) - 1
+		if last >= 0 && len(messages[last])+2+len(t) <= maxMessageLen {
+			messages[last] += "\n\n" + t
+			counts[last]++
+			continue
+		}
+		messages = append(messages, t)
+		counts = append(counts, 1)
+	}
+	return messages, counts
+}
@@ -0,0 +1,442 @@
+// Topic History Plugin for UnrealIRCd Web Panel
+// Records every channel topic change and allows restoring a previous topic
+
+package topichistory
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// TopicHistoryPlugin implements the Plugin interface
+type TopicHistoryPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	channels  map[string]*History
+	seeded    bool
+	dirty     bool
+	lastPoll  time.Time
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL        string `json:"rpc_url"`
+	RPCUser       string `json:"rpc_user"`
+	RPCPassword   string `json:"rpc_password"`
+	RPCInsecure   bool   `json:"rpc_insecure"`
+	DataDir       string `json:"data_dir"`
+	PollInterval  int    `json:"poll_interval"`
+	MaxPerChannel int    `json:"max_per_channel"`
+}
+
+// Change is a single topic change
+type Change struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	SetBy    string    `json:"set_by"`
+	OldTopic string    `json:"old_topic"`
+	NewTopic string    `json:"new_topic"`
+}
+
+// History is the topic history of one channel
+type History struct {
+	Channel string   `json:"channel"`
+	Topic   string   `json:"topic"`
+	Changes []Change `json:"changes"`
+}
+
+// rpcChannel is the subset of the UnrealIRCd channel object we need
+type rpcChannel struct {
+	Name       string `json:"name"`
+	Topic      string `json:"topic"`
+	TopicSetBy string `json:"topic_set_by"`
+	TopicSetAt string `json:"topic_set_at"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &TopicHistoryPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/topic-history",
+			PollInterval:  60,
+			MaxPerChannel: 100,
+		},
+		channels: make(map[string]*History),
+	}
+}
+
+// Info returns plugin metadata
+func (p *TopicHistoryPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Topic History",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Channel topic history with one-click restore",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *TopicHistoryPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.channels); err != nil {
+		log.Printf("[topic-history] failed to load history: %v", err)
+	}
+	if p.channels == nil {
+		p.channels = make(map[string]*History)
+	}
+	// Topics stored from a previous run are a valid baseline
+	p.seeded = len(p.channels) > 0
+	p.mu.Unlock()
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *TopicHistoryPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *TopicHistoryPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/topic-history")
+	{
+		plugin.GET("/recent", p.handleRecent)
+		plugin.GET("/channels/:name", p.handleChannel)
+		plugin.POST("/channels/:name/restore", p.handleRestore)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the history file
+func (p *TopicHistoryPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "topics.json")
+}
+
+// save persists the history if it changed
+func (p *TopicHistoryPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), p.channels); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *TopicHistoryPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop checks topics until shutdown
+func (p *TopicHistoryPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	p.poll()
+	for {
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		if interval < 15*time.Second {
+			interval = 15 * time.Second
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+			p.poll()
+			if err := p.save(); err != nil {
+				log.Printf("[topic-history] failed to save history: %v", err)
+			}
+		}
+	}
+}
+
+// poll records topics that changed since the previous poll
+func (p *TopicHistoryPlugin) poll() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcChannel `json:"list"`
+	}
+	err := p.client().Call(ctx, "channel.list", map[string]interface{}{"object_detail_level": 1}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.lastError = err.Error()
+		return err
+	}
+	p.lastError = ""
+	p.lastPoll = time.Now().UTC()
+
+	for _, ch := range result.List {
+		key := strings.ToLower(ch.Name)
+		h, ok := p.channels[key]
+		if !ok {
+			h = &History{Channel: ch.Name, Changes: make([]Change, 0)}
+			p.channels[key] = h
+			if !p.seeded {
+				// First run: take the current topic as the baseline
+				h.Topic = ch.Topic
+				p.dirty = true
+				continue
+			}
+		}
+		if h.Topic == ch.Topic {
+			continue
+		}
+
+		change := Change{
+			ID:       newID(),
+			Time:     p.lastPoll,
+			SetBy:    ch.TopicSetBy,
+			OldTopic: h.Topic,
+			NewTopic: ch.Topic,
+		}
+		if t, err := time.Parse(time.RFC3339, ch.TopicSetAt); err == nil {
+			change.Time = t
+		}
+		h.Topic = ch.Topic
+		h.Changes = append(h.Changes, change)
+		if max := p.config.MaxPerChannel; max > 0 && len(h.Changes) > max {
+			h.Changes = append([]Change(nil), h.Changes[len(h.Changes)-max:]...)
+		}
+		p.dirty = true
+	}
+	p.seeded = true
+	return nil
+}
+
+// channelParam returns the channel name from the URL, adding the # if needed
+func channelParam(c *gin.Context) string {
+	name := c.Param("name")
+	if !strings.HasPrefix(name, "#") {
+		name = "#" + name
+	}
+	return name
+}
+
+// handleRecent returns the most recent topic changes across all channels
+func (p *TopicHistoryPlugin) handleRecent(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	type entry struct {
+		Channel string `json:"channel"`
+		Change
+	}
+	recent := make([]entry, 0)
+	for _, h := range p.channels {
+		for _, ch := range h.Changes {
+			recent = append(recent, entry{Channel: h.Channel, Change: ch})
+		}
+	}
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].Time.After(recent[j].Time)
+	})
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changes":    recent,
+		"last_poll":  p.lastPoll,
+		"last_error": p.lastError,
+	})
+}
+
+// handleChannel returns the topic history of one channel, newest first
+func (p *TopicHistoryPlugin) handleChannel(c *gin.Context) {
+	name := channelParam(c)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	h, ok := p.channels[strings.ToLower(name)]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+
+	changes := make([]Change, 0, len(h.Changes))
+	for i := len(h.Changes) - 1; i >= 0; i-- {
+		changes = append(changes, h.Changes[i])
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"channel": h.Channel,
+		"topic":   h.Topic,
+		"changes": changes,
+	})
+}
+
+// handleRestore sets the channel topic back to the text of an earlier change.
+// With "use_old" the topic from before that change is restored instead.
+func (p *TopicHistoryPlugin) handleRestore(c *gin.Context) {
+	var req struct {
+		ID     string `json:"id" binding:"required"`
+		UseOld bool   `json:"use_old"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		return
+	}
+	name := channelParam(c)
+
+	p.mu.RLock()
+	var topic string
+	found := false
+	if h, ok := p.channels[strings.ToLower(name)]; ok {
+		name = h.Channel
+		for _, ch := range h.Changes {
+			if ch.ID == req.ID {
+				topic, found = ch.NewTopic, true
+				if req.UseOld {
+					topic = ch.OldTopic
+				}
+				break
+			}
+		}
+	}
+	p.mu.RUnlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Change not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	actor := access.Actor(c)
+	err := p.client().Call(ctx, "channel.set_topic", map[string]interface{}{
+		"channel": name,
+		"topic":   topic,
+		"set_by":  actor,
+	}, nil)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("[topic-history] %s restored topic of %s", actor, name)
+
+	// Pick the change up straight away rather than on the next poll
+	if err := p.poll(); err == nil {
+		p.save()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Topic restored", "topic": topic})
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleGetConfig returns the current configuration
+func (p *TopicHistoryPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *TopicHistoryPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *TopicHistoryPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *TopicHistoryPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
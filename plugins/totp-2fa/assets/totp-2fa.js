@@ -0,0 +1,441 @@
+/**
+ * Two-Factor Authentication Frontend Script
+ *
+ * Asks for an authenticator code after login, walks users through
+ * enrollment when it is required and lets admins set per-user policies.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'totp-2fa';
+  const PLUGIN_NAME = 'Two-Factor Authentication';
+  const PAGE_PATH = '/plugins/totp-2fa';
+  const API_BASE = '/api/plugin/totp-2fa';
+  const GATE_ID = 'totp2fa-gate';
+
+  let gateToken = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '';
+  }
+
+  function injectStyles() {
+    if (document.getElementById('totp-2fa-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'totp-2fa-styles';
+    style.textContent = `
+      .totp2fa-gate {
+        position: fixed;
+        inset: 0;
+        z-index: 10000;
+        display: flex;
+        align-items: center;
+        justify-content: center;
+        background: var(--bg-primary, #11111b);
+      }
+      .totp2fa-box {
+        width: min(420px, 92vw);
+        display: flex;
+        flex-direction: column;
+        gap: 0.8rem;
+        padding: 1.5rem;
+        border-radius: 10px;
+        background: var(--bg-secondary, #1e1e2e);
+        border: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+      }
+      .totp2fa-box h2 { margin: 0; color: var(--text-primary, #cdd6f4); }
+      .totp2fa-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .totp2fa-qr { background: #fff; border-radius: 6px; width: 200px; align-self: center; }
+      .totp2fa-qr svg { display: block; width: 100%; height: auto; }
+      .totp2fa-secret { font-family: monospace; overflow-wrap: anywhere; }
+      .totp2fa-codes { display: grid; grid-template-columns: repeat(2, 1fr); gap: 0.3rem; font-family: monospace; }
+      .totp2fa-row { display: flex; gap: 0.5rem; align-items: center; }
+      .totp2fa-box input, .totp2fa-app input, .totp2fa-app select {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .totp2fa-box button, .totp2fa-app button {
+        background: var(--accent, #89b4fa);
+        color: var(--bg-primary, #11111b);
+        border: none;
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+      }
+      .totp2fa-box button.totp2fa-plain, .totp2fa-app button.totp2fa-plain {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+      }
+      .totp2fa-table { width: 100%; border-collapse: collapse; }
+      .totp2fa-table th, .totp2fa-table td {
+        text-align: left;
+        padding: 0.4rem 0.6rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+      }
+      .totp2fa-meta { font-size: 0.75rem; color: var(--text-muted, #6c7086); }
+      .totp2fa-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  // Enrollment steps shared by the login gate and the settings page. Calls
+  // done once the backup codes have been acknowledged.
+  async function renderEnroll(box, done) {
+    box.querySelector('.totp2fa-step').innerHTML = 'Loading...';
+    let data;
+    try {
+      data = await api('POST', '/enroll');
+    } catch (e) {
+      box.querySelector('.totp2fa-step').innerHTML = `<div class="totp2fa-error">${escapeHtml(e.message)}</div>`;
+      return;
+    }
+    const step = box.querySelector('.totp2fa-step');
+    step.innerHTML = `
+      <p>Scan this code with your authenticator app, then enter the code it shows.</p>
+      <div class="totp2fa-qr">${data.qr_svg}</div>
+      <div class="totp2fa-meta">Or enter the key by hand: <span class="totp2fa-secret">${escapeHtml(data.secret)}</span></div>
+      <div class="totp2fa-row">
+        <input type="text" inputmode="numeric" autocomplete="one-time-code" placeholder="123456" maxlength="6">
+        <button>Confirm</button>
+      </div>
+      <div class="totp2fa-error"></div>
+    `;
+    const input = step.querySelector('input');
+    const submit = async () => {
+      try {
+        const res = await api('POST', '/enroll/confirm', { code: input.value });
+        renderBackupCodes(step, res.backup_codes, done);
+      } catch (e) {
+        step.querySelector('.totp2fa-error').textContent = e.message;
+      }
+    };
+    step.querySelector('button').addEventListener('click', submit);
+    input.addEventListener('keydown', e => { if (e.key === 'Enter') submit(); });
+    input.focus();
+  }
+
+  function renderBackupCodes(el, codes, done) {
+    el.innerHTML = `
+      <p>Store these backup codes somewhere safe. Each one works once if you lose your device, and they will not be shown again.</p>
+      <div class="totp2fa-codes">${codes.map(c => `<span>${escapeHtml(c)}</span>`).join('')}</div>
+      <button>I have saved them</button>
+    `;
+    el.querySelector('button').addEventListener('click', done);
+  }
+
+  function logout() {
+    localStorage.removeItem('token');
+    window.location.href = '/login';
+  }
+
+  function removeGate() {
+    const gate = document.getElementById(GATE_ID);
+    if (gate) gate.remove();
+  }
+
+  function showGate(status) {
+    if (document.getElementById(GATE_ID)) return;
+    injectStyles();
+
+    const gate = document.createElement('div');
+    gate.id = GATE_ID;
+    gate.className = 'totp2fa-gate';
+    gate.innerHTML = `
+      <div class="totp2fa-box">
+        <h2>Two-factor authentication</h2>
+        <div class="totp2fa-step"></div>
+        <button class="totp2fa-plain" data-action="logout">Log out</button>
+      </div>
+    `;
+    document.body.appendChild(gate);
+    gate.querySelector('[data-action="logout"]').addEventListener('click', logout);
+
+    const box = gate.querySelector('.totp2fa-box');
+    if (!status.enabled) {
+      renderEnroll(box, removeGate);
+      return;
+    }
+
+    const step = box.querySelector('.totp2fa-step');
+    step.innerHTML = `
+      <p>Enter the code from your authenticator app, or one of your backup codes.</p>
+      <div class="totp2fa-row">
+        <input type="text" autocomplete="one-time-code" placeholder="123456">
+        <button>Verify</button>
+      </div>
+      <div class="totp2fa-error"></div>
+    `;
+    const input = step.querySelector('input');
+    const submit = async () => {
+      try {
+        const res = await api('POST', '/verify', { code: input.value });
+        if (res.backup_codes_left <= 2) {
+          alert(`You have ${res.backup_codes_left} backup codes left. Generate new ones under Two-Factor Auth.`);
+        }
+        removeGate();
+      } catch (e) {
+        step.querySelector('.totp2fa-error').textContent = e.message;
+        input.value = '';
+      }
+    };
+    step.querySelector('button').addEventListener('click', submit);
+    input.addEventListener('keydown', e => { if (e.key === 'Enter') submit(); });
+    input.focus();
+  }
+
+  // checkGate asks the server whether this login still has to verify or
+  // enroll, once per token
+  async function checkGate() {
+    const token = localStorage.getItem('token');
+    if (!token || window.location.pathname.startsWith('/login')) {
+      gateToken = null;
+      removeGate();
+      return;
+    }
+    if (token === gateToken) return;
+    gateToken = token;
+
+    try {
+      const status = await api('GET', '/status');
+      if ((status.enabled && !status.verified) || (status.required && !status.enabled)) {
+        showGate(status);
+      }
+    } catch (e) {
+      gateToken = null;
+    }
+  }
+
+  async function loadAccount(container) {
+    const body = container.querySelector('#totp2fa-account');
+    let s;
+    try {
+      s = await api('GET', '/status');
+    } catch (e) {
+      body.innerHTML = `<div class="totp2fa-error">${escapeHtml(e.message)}</div>`;
+      return;
+    }
+
+    if (!s.enabled) {
+      body.innerHTML = `
+        <p>Two-factor authentication is off for ${escapeHtml(s.username)}${s.required ? ', but your account requires it' : ''}.</p>
+        <div class="totp2fa-box"><div class="totp2fa-step"><button>Set up</button></div></div>
+      `;
+      const box = body.querySelector('.totp2fa-box');
+      box.querySelector('button').addEventListener('click', () => renderEnroll(box, () => loadPage(container)));
+      return;
+    }
+
+    body.innerHTML = `
+      <p>Two-factor authentication is on for ${escapeHtml(s.username)}. ${s.backup_codes_left} backup codes left.</p>
+      <div class="totp2fa-row">
+        <input type="text" placeholder="Current code" id="totp2fa-code">
+        <button data-action="codes">New backup codes</button>
+        ${s.required ? '' : '<button class="totp2fa-plain" data-action="disable">Turn off</button>'}
+      </div>
+      <div id="totp2fa-account-out"></div>
+    `;
+    const out = body.querySelector('#totp2fa-account-out');
+    const code = () => body.querySelector('#totp2fa-code').value;
+    body.querySelector('[data-action="codes"]').addEventListener('click', async () => {
+      try {
+        const res = await api('POST', '/backup-codes', { code: code() });
+        renderBackupCodes(out, res.backup_codes, () => loadPage(container));
+      } catch (e) {
+        out.innerHTML = `<div class="totp2fa-error">${escapeHtml(e.message)}</div>`;
+      }
+    });
+    const disable = body.querySelector('[data-action="disable"]');
+    if (disable) {
+      disable.addEventListener('click', async () => {
+        if (!confirm('Turn off two-factor authentication?')) return;
+        try {
+          await api('POST', '/disable', { code: code() });
+          loadPage(container);
+        } catch (e) {
+          out.innerHTML = `<div class="totp2fa-error">${escapeHtml(e.message)}</div>`;
+        }
+      });
+    }
+  }
+
+  async function loadUsers(container) {
+    const body = container.querySelector('#totp2fa-users');
+    try {
+      const data = await api('GET', '/users');
+      body.innerHTML = `
+        <p class="totp2fa-meta">Users without their own policy follow the default: ${escapeHtml(data.default_policy)}.</p>
+        <div class="totp2fa-row">
+          <input type="text" placeholder="Username" id="totp2fa-new-user">
+          <button data-action="require">Require for user</button>
+        </div>
+        <table class="totp2fa-table">
+          <thead><tr><th>User</th><th>Enrolled</th><th>Backup codes</th><th>Last verified</th><th>Policy</th><th></th></tr></thead>
+          <tbody>
+            ${data.users.map(u => `
+              <tr>
+                <td>${escapeHtml(u.username)}</td>
+                <td>${u.enabled ? formatTime(u.enrolled_at) : (u.required ? '<span class="totp2fa-error">required, not enrolled</span>' : 'no')}</td>
+                <td>${u.enabled ? u.backup_codes_left : ''}</td>
+                <td>${formatTime(u.last_verified)}</td>
+                <td>
+                  <select data-user="${escapeHtml(u.username)}">
+                    ${['default', 'required', 'exempt'].map(p => `<option value="${p}" ${u.policy === p ? 'selected' : ''}>${p}</option>`).join('')}
+                  </select>
+                  ${u.policy_by ? `<span class="totp2fa-meta">by ${escapeHtml(u.policy_by)}</span>` : ''}
+                </td>
+                <td>${u.enabled ? `<button class="totp2fa-plain" data-reset="${escapeHtml(u.username)}">Reset</button>` : ''}</td>
+              </tr>
+            `).join('')}
+          </tbody>
+        </table>
+      `;
+
+      const setPolicy = async (user, policy) => {
+        try {
+          await api('PUT', `/users/${encodeURIComponent(user)}/policy`, { policy });
+        } catch (e) {
+          alert(e.message);
+        }
+        loadUsers(container);
+      };
+      body.querySelector('[data-action="require"]').addEventListener('click', () => {
+        const user = body.querySelector('#totp2fa-new-user').value.trim();
+        if (user) setPolicy(user, 'required');
+      });
+      body.querySelectorAll('select[data-user]').forEach(sel => {
+        sel.addEventListener('change', () => setPolicy(sel.dataset.user, sel.value));
+      });
+      body.querySelectorAll('[data-reset]').forEach(btn => {
+        btn.addEventListener('click', async () => {
+          const user = btn.dataset.reset;
+          if (!confirm(`Reset two-factor authentication for ${user}? They will have to enroll again.`)) return;
+          try {
+            await api('DELETE', `/users/${encodeURIComponent(user)}`);
+          } catch (e) {
+            alert(e.message);
+          }
+          loadUsers(container);
+        });
+      });
+    } catch (e) {
+      body.innerHTML = `<div class="totp2fa-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  async function loadPage(container) {
+    await loadAccount(container);
+    try {
+      const s = await api('GET', '/status');
+      const warning = container.querySelector('#totp2fa-warning');
+      warning.style.display = s.enforced ? 'none' : '';
+      const section = container.querySelector('#totp2fa-admin');
+      section.style.display = s.admin ? '' : 'none';
+      if (s.admin) loadUsers(container);
+    } catch (e) {
+      // loadAccount already shows the error
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="totp2fa-app" data-plugin="${PLUGIN_ID}">
+        <div id="totp2fa-warning" class="totp2fa-error" style="display: none">
+          The panel does not run the plugin request hook, so two-factor authentication cannot be enforced and cannot be turned on.
+        </div>
+        <h3>Your account</h3>
+        <div id="totp2fa-account">Loading...</div>
+        <div id="totp2fa-admin" style="display: none">
+          <h3>Users</h3>
+          <div id="totp2fa-users">Loading...</div>
+        </div>
+      </div>
+    `;
+
+    loadPage(container);
+    return true;
+  }
+
+  function cleanup() {
+    removeGate();
+    const style = document.getElementById('totp-2fa-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    checkGate();
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection, which also catches a fresh login
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    checkGate();
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
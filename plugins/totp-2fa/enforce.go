@@ -0,0 +1,71 @@
+package totp2fa
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openPaths are this plugin's routes a login can use before it passes the
+// verification step, so it can enroll or verify. They are matched exactly.
+var openPaths = map[string]bool{
+	"/api/plugin/totp-2fa/status":         true,
+	"/api/plugin/totp-2fa/enroll":         true,
+	"/api/plugin/totp-2fa/enroll/confirm": true,
+	"/api/plugin/totp-2fa/verify":         true,
+}
+
+// active is the loaded plugin instance that the exported checks use
+var (
+	activeMu sync.RWMutex
+	active   *TOTP2FAPlugin
+)
+
+// loaded returns the active plugin instance, or nil
+func loaded() *TOTP2FAPlugin {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	return active
+}
+
+// Pending reports whether the panel user making the request still has to
+// pass the verification step, or to enroll because their policy requires
+// it. Requests without a panel user, and any request while the plugin is
+// not loaded, are not pending.
+func Pending(c *gin.Context) bool {
+	p := loaded()
+	name := c.GetString("username")
+	if p == nil || name == "" {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pending(c, name)
+}
+
+// pending reports whether a login still has to verify or enroll. Caller
+// must hold p.mu.
+func (p *TOTP2FAPlugin) pending(c *gin.Context, name string) bool {
+	u, ok := p.users[strings.ToLower(name)]
+	if !ok {
+		u = &UserRecord{Username: name, Policy: PolicyDefault}
+	}
+	if u.Enabled {
+		return !p.verified(c, name)
+	}
+	return p.required(u)
+}
+
+// enforce is the request check that rejects requests from logins that
+// have not passed the verification step, except to the routes needed to
+// pass it. It runs on the panel's request hook, after authentication, so
+// it covers the panel's routes and those of every plugin.
+func enforce(c *gin.Context, next func()) {
+	if !openPaths[c.Request.URL.Path] && Pending(c) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Two-factor verification required", "totp_required": true})
+		return
+	}
+	next()
+}
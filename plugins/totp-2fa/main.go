@@ -0,0 +1,767 @@
+// TOTP Two-Factor Plugin for UnrealIRCd Web Panel
+// Adds authenticator app enrollment with backup codes and a verification
+// step after panel login, with per-user enforcement set by admins
+
+package totp2fa
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/apidocs"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/requesthook"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Per-user policies
+const (
+	PolicyDefault  = "default"
+	PolicyRequired = "required"
+	PolicyExempt   = "exempt"
+)
+
+// Failed codes allowed per user within failureWindow before verification
+// is refused
+const (
+	maxFailures   = 5
+	failureWindow = 5 * time.Minute
+)
+
+// TOTP2FAPlugin implements the Plugin interface
+type TOTP2FAPlugin struct {
+	config   Config
+	users    map[string]*UserRecord
+	sessions map[string]*session
+	failures map[string][]time.Time
+	dirty    bool
+	mu       sync.RWMutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	Issuer        string `json:"issuer"`
+	DataDir       string `json:"data_dir"`
+	DefaultPolicy string `json:"default_policy"`
+	SessionHours  int    `json:"session_hours"`
+	BackupCodes   int    `json:"backup_codes"`
+}
+
+// UserRecord is the two-factor state of a panel user
+type UserRecord struct {
+	Username     string     `json:"username"`
+	Secret       string     `json:"secret,omitempty"`
+	Pending      string     `json:"pending_secret,omitempty"`
+	Enabled      bool       `json:"enabled"`
+	EnrolledAt   *time.Time `json:"enrolled_at,omitempty"`
+	LastStep     int64      `json:"last_step"`
+	BackupCodes  []string   `json:"backup_codes"`
+	Policy       string     `json:"policy"`
+	PolicyBy     string     `json:"policy_by,omitempty"`
+	LastVerified *time.Time `json:"last_verified,omitempty"`
+}
+
+// UserSummary is what admins see of a user, without secrets
+type UserSummary struct {
+	Username     string     `json:"username"`
+	Enabled      bool       `json:"enabled"`
+	EnrolledAt   *time.Time `json:"enrolled_at,omitempty"`
+	BackupCodes  int        `json:"backup_codes_left"`
+	Policy       string     `json:"policy"`
+	PolicyBy     string     `json:"policy_by,omitempty"`
+	Required     bool       `json:"required"`
+	LastVerified *time.Time `json:"last_verified,omitempty"`
+}
+
+// session is a panel login that passed the verification step
+type session struct {
+	Username string
+	Expires  time.Time
+}
+
+// CodeRequest carries a TOTP or backup code
+type CodeRequest struct {
+	Code string `json:"code"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Users map[string]*UserRecord `json:"users"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &TOTP2FAPlugin{
+		config: Config{
+			Issuer:        "UnrealIRCd Web Panel",
+			DataDir:       "data/plugins/totp-2fa",
+			DefaultPolicy: "optional",
+			SessionHours:  12,
+			BackupCodes:   10,
+		},
+		users:    make(map[string]*UserRecord),
+		sessions: make(map[string]*session),
+		failures: make(map[string][]time.Time),
+	}
+}
+
+// Info returns plugin metadata
+func (p *TOTP2FAPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Two-Factor Authentication",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "TOTP two-factor authentication for panel logins",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *TOTP2FAPlugin) Init() error {
+	apidocs.Describe("totp-2fa", "Authenticator app enrollment and verification of panel logins",
+		apidocs.Operation{Method: "GET", Path: "/status", Summary: "Enrollment and verification state of the current login"},
+		apidocs.Operation{Method: "POST", Path: "/enroll", Summary: "Start enrollment", Response: struct {
+			Secret string `json:"secret"`
+			URI    string `json:"uri"`
+			QRSVG  string `json:"qr_svg"`
+		}{}},
+		apidocs.Operation{Method: "POST", Path: "/enroll/confirm", Summary: "Finish enrollment with a code", Request: CodeRequest{}, Response: struct {
+			Message     string   `json:"message"`
+			BackupCodes []string `json:"backup_codes"`
+		}{}},
+		apidocs.Operation{Method: "POST", Path: "/verify", Summary: "Verify the current login with a code or backup code", Request: CodeRequest{}, Response: struct {
+			Message         string `json:"message"`
+			BackupCodesLeft int    `json:"backup_codes_left"`
+		}{}},
+		apidocs.Operation{Method: "POST", Path: "/backup-codes", Summary: "Replace the backup codes", Request: CodeRequest{}, Response: struct {
+			BackupCodes []string `json:"backup_codes"`
+		}{}},
+		apidocs.Operation{Method: "POST", Path: "/disable", Summary: "Turn two-factor authentication off", Request: CodeRequest{}},
+		apidocs.Operation{Method: "GET", Path: "/users", Summary: "Users and their policies (admins)", Response: struct {
+			Users         []UserSummary `json:"users"`
+			DefaultPolicy string        `json:"default_policy"`
+		}{}},
+		apidocs.Operation{Method: "PUT", Path: "/users/:username/policy", Summary: "Set a user's policy (admins)", Request: struct {
+			Policy string `json:"policy" doc:"default, required or exempt"`
+		}{}},
+		apidocs.Operation{Method: "DELETE", Path: "/users/:username", Summary: "Reset a user's enrollment (admins)"},
+		apidocs.Operation{Method: "GET", Path: "/config", Summary: "Get current configuration", Response: Config{}},
+		apidocs.Operation{Method: "PUT", Path: "/config", Summary: "Update configuration (admins)", Request: Config{}},
+	)
+
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[totp-2fa] failed to load data: %v", err)
+	}
+	if data.Users != nil {
+		p.users = data.Users
+	}
+	p.mu.Unlock()
+
+	activeMu.Lock()
+	active = p
+	activeMu.Unlock()
+	// Run before other checks, so held back requests reach none of them
+	requesthook.Register("totp-2fa", 10, enforce)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.cleanupLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *TOTP2FAPlugin) Shutdown() error {
+	apidocs.Forget("totp-2fa")
+	requesthook.Unregister("totp-2fa")
+	activeMu.Lock()
+	if active == p {
+		active = nil
+	}
+	activeMu.Unlock()
+
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *TOTP2FAPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/totp-2fa")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/enroll", p.handleEnroll)
+		plugin.POST("/enroll/confirm", p.handleConfirm)
+		plugin.POST("/verify", p.handleVerify)
+		plugin.POST("/backup-codes", p.handleBackupCodes)
+		plugin.POST("/disable", p.handleDisable)
+		plugin.GET("/users", p.handleListUsers)
+		plugin.PUT("/users/:username/policy", p.handleSetPolicy)
+		plugin.DELETE("/users/:username", p.handleReset)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *TOTP2FAPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "users.json")
+}
+
+// save persists the state if it changed
+func (p *TOTP2FAPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Users: p.users}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// cleanupLoop drops expired sessions and old failures and saves the state
+// until shutdown
+func (p *TOTP2FAPlugin) cleanupLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		p.mu.Lock()
+		for k, s := range p.sessions {
+			if now.After(s.Expires) {
+				delete(p.sessions, k)
+			}
+		}
+		for user, times := range p.failures {
+			if len(times) == 0 || now.Sub(times[len(times)-1]) > failureWindow {
+				delete(p.failures, user)
+			}
+		}
+		p.mu.Unlock()
+
+		if err := p.save(); err != nil {
+			log.Printf("[totp-2fa] failed to save data: %v", err)
+		}
+	}
+}
+
+// sessionKey identifies the panel login making a request by a hash of its
+// bearer token
+func sessionKey(c *gin.Context) string {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// currentUser returns the logged in panel user or writes an error response
+func currentUser(c *gin.Context) (string, bool) {
+	name := c.GetString("username")
+	if name == "" || sessionKey(c) == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+		return "", false
+	}
+	return name, true
+}
+
+// record returns the record of a user, creating an empty one. Caller must
+// hold p.mu.
+func (p *TOTP2FAPlugin) record(name string) *UserRecord {
+	key := strings.ToLower(name)
+	u, ok := p.users[key]
+	if !ok {
+		u = &UserRecord{Username: name, BackupCodes: make([]string, 0), Policy: PolicyDefault}
+		p.users[key] = u
+	}
+	return u
+}
+
+// required reports whether a user must use two-factor authentication.
+// Caller must hold p.mu.
+func (p *TOTP2FAPlugin) required(u *UserRecord) bool {
+	switch u.Policy {
+	case PolicyRequired:
+		return true
+	case PolicyExempt:
+		return false
+	}
+	return p.config.DefaultPolicy == "required"
+}
+
+// verified reports whether the request's login passed the verification
+// step. Caller must hold p.mu.
+func (p *TOTP2FAPlugin) verified(c *gin.Context, name string) bool {
+	s, ok := p.sessions[sessionKey(c)]
+	return ok && strings.EqualFold(s.Username, name) && time.Now().Before(s.Expires)
+}
+
+// markVerified records that the request's login passed the verification
+// step. Caller must hold p.mu.
+func (p *TOTP2FAPlugin) markVerified(c *gin.Context, u *UserRecord) {
+	hours := p.config.SessionHours
+	if hours < 1 {
+		hours = 1
+	}
+	now := time.Now().UTC()
+	p.sessions[sessionKey(c)] = &session{Username: u.Username, Expires: now.Add(time.Duration(hours) * time.Hour)}
+	u.LastVerified = &now
+	delete(p.failures, strings.ToLower(u.Username))
+}
+
+// checkCode verifies a TOTP code, or with allowBackup a backup code, which
+// is then used up. It writes an error response when the code is wrong or
+// the user failed too often. Caller must hold p.mu.
+func (p *TOTP2FAPlugin) checkCode(c *gin.Context, u *UserRecord, secret, code string, allowBackup bool) bool {
+	key := strings.ToLower(u.Username)
+	now := time.Now()
+	recent := make([]time.Time, 0, maxFailures)
+	for _, t := range p.failures[key] {
+		if now.Sub(t) < failureWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= maxFailures {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many wrong codes, try again in a few minutes"})
+		return false
+	}
+
+	if step, ok := verifyTOTP(secret, code, now, u.LastStep); ok {
+		u.LastStep = step
+		p.dirty = true
+		return true
+	}
+	if allowBackup {
+		hash := hashCode(code)
+		for i, h := range u.BackupCodes {
+			if h == hash {
+				u.BackupCodes = append(u.BackupCodes[:i:i], u.BackupCodes[i+1:]...)
+				p.dirty = true
+				log.Printf("[totp-2fa] %s used a backup code, %d left", u.Username, len(u.BackupCodes))
+				return true
+			}
+		}
+	}
+
+	p.failures[key] = append(recent, now)
+	log.Printf("[totp-2fa] wrong code for %s from %s", u.Username, c.ClientIP())
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+	return false
+}
+
+// handleStatus tells the frontend whether the current login has to enroll
+// or verify
+func (p *TOTP2FAPlugin) handleStatus(c *gin.Context) {
+	name, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	u, ok := p.users[strings.ToLower(name)]
+	if !ok {
+		u = &UserRecord{Username: name, Policy: PolicyDefault}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"username":          name,
+		"enabled":           u.Enabled,
+		"required":          p.required(u),
+		"policy":            u.Policy,
+		"verified":          !u.Enabled || p.verified(c, name),
+		"backup_codes_left": len(u.BackupCodes),
+		"admin":             access.IsAdmin(name),
+		"enforced":          requesthook.Ran(c),
+	})
+}
+
+// requireHook writes an error response unless the panel runs the request
+// hook, without which two-factor authentication would only be a prompt
+func requireHook(c *gin.Context) bool {
+	if !requesthook.Ran(c) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Two-factor authentication cannot be enforced, because the panel does not run the plugin request hook"})
+		return false
+	}
+	return true
+}
+
+// handleEnroll starts enrollment with a new secret and returns it with its
+// QR code
+func (p *TOTP2FAPlugin) handleEnroll(c *gin.Context) {
+	name, ok := currentUser(c)
+	if !ok || !requireHook(c) {
+		return
+	}
+
+	p.mu.Lock()
+	u := p.record(name)
+	if u.Enabled && !p.verified(c, name) {
+		p.mu.Unlock()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verify your current code first"})
+		return
+	}
+	u.Pending = newSecret()
+	secret := u.Pending
+	issuer := p.config.Issuer
+	p.dirty = true
+	p.mu.Unlock()
+
+	uri := provisioningURI(issuer, name, secret)
+	qr, err := encodeQR(uri)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"secret": secret, "uri": uri, "qr_svg": qr.SVG()})
+}
+
+// handleConfirm finishes enrollment once the app shows a valid code and
+// returns the backup codes, which are shown only this once
+func (p *TOTP2FAPlugin) handleConfirm(c *gin.Context) {
+	name, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u := p.record(name)
+	if u.Pending == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrollment first"})
+		return
+	}
+	u.LastStep = 0
+	if !p.checkCode(c, u, u.Pending, req.Code, false) {
+		return
+	}
+
+	codes, hashes := newBackupCodes(p.config.BackupCodes)
+	now := time.Now().UTC()
+	u.Secret = u.Pending
+	u.Pending = ""
+	u.Enabled = true
+	u.EnrolledAt = &now
+	u.BackupCodes = hashes
+	p.markVerified(c, u)
+	p.dirty = true
+
+	log.Printf("[totp-2fa] %s enrolled", name)
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "backup_codes": codes})
+}
+
+// handleVerify completes the verification step of a login
+func (p *TOTP2FAPlugin) handleVerify(c *gin.Context) {
+	name, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u, ok := p.users[strings.ToLower(name)]
+	if !ok || !u.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !p.checkCode(c, u, u.Secret, req.Code, true) {
+		return
+	}
+	p.markVerified(c, u)
+	p.dirty = true
+	c.JSON(http.StatusOK, gin.H{"message": "Verified", "backup_codes_left": len(u.BackupCodes)})
+}
+
+// handleBackupCodes replaces the backup codes after checking a current code
+func (p *TOTP2FAPlugin) handleBackupCodes(c *gin.Context) {
+	name, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u, ok := p.users[strings.ToLower(name)]
+	if !ok || !u.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !p.checkCode(c, u, u.Secret, req.Code, false) {
+		return
+	}
+	codes, hashes := newBackupCodes(p.config.BackupCodes)
+	u.BackupCodes = hashes
+	p.dirty = true
+	c.JSON(http.StatusOK, gin.H{"backup_codes": codes})
+}
+
+// handleDisable turns two-factor authentication off for the current user,
+// unless their policy requires it
+func (p *TOTP2FAPlugin) handleDisable(c *gin.Context) {
+	name, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u, ok := p.users[strings.ToLower(name)]
+	if !ok || !u.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if p.required(u) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your account"})
+		return
+	}
+	if !p.checkCode(c, u, u.Secret, req.Code, true) {
+		return
+	}
+	u.Enabled = false
+	u.Secret = ""
+	u.BackupCodes = make([]string, 0)
+	p.dirty = true
+
+	log.Printf("[totp-2fa] %s disabled two-factor authentication", name)
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// requireAdmin writes an error response unless the user is a plugin admin
+// who passed verification
+func (p *TOTP2FAPlugin) requireAdmin(c *gin.Context) (string, bool) {
+	name, ok := currentUser(c)
+	if !ok {
+		return "", false
+	}
+	if !access.IsAdmin(name) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only plugin admins can manage two-factor settings"})
+		return "", false
+	}
+	p.mu.RLock()
+	u, enrolled := p.users[strings.ToLower(name)]
+	ok = !enrolled || !u.Enabled || p.verified(c, name)
+	p.mu.RUnlock()
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verify your code first"})
+		return "", false
+	}
+	return name, true
+}
+
+// handleListUsers returns the two-factor state of every known user
+func (p *TOTP2FAPlugin) handleListUsers(c *gin.Context) {
+	if _, ok := p.requireAdmin(c); !ok {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]UserSummary, 0, len(p.users))
+	for _, u := range p.users {
+		list = append(list, UserSummary{
+			Username:     u.Username,
+			Enabled:      u.Enabled,
+			EnrolledAt:   u.EnrolledAt,
+			BackupCodes:  len(u.BackupCodes),
+			Policy:       u.Policy,
+			PolicyBy:     u.PolicyBy,
+			Required:     p.required(u),
+			LastVerified: u.LastVerified,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Username) < strings.ToLower(list[j].Username) })
+	c.JSON(http.StatusOK, gin.H{"users": list, "default_policy": p.config.DefaultPolicy})
+}
+
+// handleSetPolicy sets whether a user must, may or need not use two-factor
+// authentication
+func (p *TOTP2FAPlugin) handleSetPolicy(c *gin.Context) {
+	admin, ok := p.requireAdmin(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Policy string `json:"policy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if req.Policy != PolicyDefault && req.Policy != PolicyRequired && req.Policy != PolicyExempt {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "policy must be default, required or exempt"})
+		return
+	}
+	if req.Policy == PolicyRequired && !requireHook(c) {
+		return
+	}
+	target := strings.TrimSpace(c.Param("username"))
+	if target == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username is required"})
+		return
+	}
+
+	p.mu.Lock()
+	u := p.record(target)
+	u.Policy = req.Policy
+	u.PolicyBy = admin
+	p.dirty = true
+	p.mu.Unlock()
+
+	log.Printf("[totp-2fa] %s set the policy of %s to %s", admin, target, req.Policy)
+	c.JSON(http.StatusOK, gin.H{"message": "Policy updated"})
+}
+
+// handleReset removes a user's enrollment, for example after a lost
+// device. Their policy is kept.
+func (p *TOTP2FAPlugin) handleReset(c *gin.Context) {
+	admin, ok := p.requireAdmin(c)
+	if !ok {
+		return
+	}
+	target := c.Param("username")
+
+	p.mu.Lock()
+	u, found := p.users[strings.ToLower(target)]
+	if found {
+		u.Enabled = false
+		u.Secret = ""
+		u.Pending = ""
+		u.EnrolledAt = nil
+		u.BackupCodes = make([]string, 0)
+		for k, s := range p.sessions {
+			if strings.EqualFold(s.Username, target) {
+				delete(p.sessions, k)
+			}
+		}
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	log.Printf("[totp-2fa] %s reset the enrollment of %s", admin, target)
+	c.JSON(http.StatusOK, gin.H{"message": "Enrollment reset"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *TOTP2FAPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, p.config)
+}
+
+// handleUpdateConfig updates plugin configuration. Only admins may change
+// it, since it decides who has to enroll.
+func (p *TOTP2FAPlugin) handleUpdateConfig(c *gin.Context) {
+	admin, ok := p.requireAdmin(c)
+	if !ok {
+		return
+	}
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.DefaultPolicy != "optional" && newConfig.DefaultPolicy != "required" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "default_policy must be optional or required"})
+		return
+	}
+	if newConfig.DefaultPolicy == "required" && !requireHook(c) {
+		return
+	}
+	if newConfig.BackupCodes < 1 || newConfig.BackupCodes > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "backup_codes must be between 1 and 50"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.mu.Unlock()
+
+	log.Printf("[totp-2fa] %s updated the configuration", admin)
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *TOTP2FAPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *TOTP2FAPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,35 @@
+package uptimesla
+
+import (
+	"fmt"
+	"html"
+)
+
+// badgeColor picks a shields.io style color for an uptime percentage
+func badgeColor(pct, target float64) string {
+	switch {
+	case pct >= target:
+		return "#4c1"
+	case pct >= target-0.5:
+		return "#dfb317"
+	default:
+		return "#e05d44"
+	}
+}
+
+// renderBadge draws a flat two-part badge such as "uptime | 99.95%"
+func renderBadge(label, value, color string) string {
+	// Verdana 11px averages roughly 7px per character
+	lw := 10 + 7*len(label)
+	vw := 10 + 7*len(value)
+	w := lw + vw
+	label = html.EscapeString(label)
+	value = html.EscapeString(value)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+
+		`<title>%s: %s</title>`+
+		`<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%d" y="14">%s</text><text x="%d" y="14">%s</text></g></svg>`,
+		w, label, value, label, value, w, lw, lw, vw, color, lw/2, label, lw+vw/2, value)
+}
@@ -0,0 +1,662 @@
+// Uptime SLA Plugin for UnrealIRCd Web Panel
+// Records per-server availability, computes monthly uptime against an SLA
+// target and serves an uptime badge
+
+package uptimesla
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// dayLayout is the key format of daily buckets
+const dayLayout = "2006-01-02"
+
+// UptimeSLAPlugin implements the Plugin interface
+type UptimeSLAPlugin struct {
+	config    Config
+	rpc       *jsonrpc.Client
+	servers   map[string]*ServerRecord
+	outages   []*Outage
+	lastPoll  time.Time
+	dirty     bool
+	lastError string
+	mu        sync.RWMutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string  `json:"rpc_url"`
+	RPCUser        string  `json:"rpc_user"`
+	RPCPassword    string  `json:"rpc_password"`
+	RPCInsecure    bool    `json:"rpc_insecure"`
+	DataDir        string  `json:"data_dir"`
+	PollInterval   int     `json:"poll_interval"`
+	Probe          bool    `json:"probe"`
+	ProbeMethod    string  `json:"probe_method"`
+	SLATarget      float64 `json:"sla_target"`
+	ExcludeServers string  `json:"exclude_servers"`
+	MaxOutages     int     `json:"max_outages"`
+}
+
+// Day holds the observed up and down seconds of one UTC day
+type Day struct {
+	Up   int64 `json:"up"`
+	Down int64 `json:"down"`
+}
+
+// ServerRecord is the availability record of one server
+type ServerRecord struct {
+	Name       string          `json:"name"`
+	FirstSeen  time.Time       `json:"first_seen"`
+	Up         bool            `json:"up"`
+	LastChange time.Time       `json:"last_change"`
+	Days       map[string]*Day `json:"days"`
+}
+
+// Outage is a period during which a server was unavailable
+type Outage struct {
+	ID       string     `json:"id"`
+	Server   string     `json:"server"`
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end"`
+	Duration int64      `json:"duration_seconds"`
+}
+
+// Availability is the uptime of a server over a period
+type Availability struct {
+	Server          string   `json:"server"`
+	UptimePct       *float64 `json:"uptime_pct"`
+	DowntimeMinutes float64  `json:"downtime_minutes"`
+	ObservedHours   float64  `json:"observed_hours"`
+	Outages         int      `json:"outages"`
+	SLAMet          *bool    `json:"sla_met"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Servers  map[string]*ServerRecord `json:"servers"`
+	Outages  []*Outage                `json:"outages"`
+	LastPoll time.Time                `json:"last_poll"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined bool `json:"ulined"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &UptimeSLAPlugin{
+		config: Config{
+			RPCURL:       "https://127.0.0.1:8600/api",
+			DataDir:      "data/plugins/uptime-sla",
+			PollInterval: 30,
+			ProbeMethod:  "server.module_list",
+			SLATarget:    99.9,
+			MaxOutages:   5000,
+		},
+		servers: make(map[string]*ServerRecord),
+		outages: make([]*Outage, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *UptimeSLAPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Uptime SLA",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Per-server availability, monthly uptime and SLA reports",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *UptimeSLAPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[uptime-sla] failed to load data: %v", err)
+	}
+	if data.Servers != nil {
+		p.servers = data.Servers
+	}
+	if data.Outages != nil {
+		p.outages = data.Outages
+	}
+	p.lastPoll = data.LastPoll
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "uptime-sla-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		month := time.Now().UTC().Format("2006-01")
+		content := map[string]interface{}{"month": month, "sla_target": p.config.SLATarget}
+		net, _ := p.networkAvailability(month)
+		if net.UptimePct != nil {
+			content["uptime_pct"] = round(*net.UptimePct, 3)
+		}
+		breached := 0
+		for _, a := range p.monthReport(month) {
+			if a.SLAMet != nil && !*a.SLAMet {
+				breached++
+			}
+		}
+		content["breached"] = breached
+		return plugins.DashboardCard{
+			Title:   "Uptime This Month",
+			Icon:    "Activity",
+			Content: content,
+			Order:   40,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *UptimeSLAPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *UptimeSLAPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/uptime-sla")
+	{
+		plugin.GET("/report", p.handleReport)
+		plugin.GET("/servers/:name", p.handleServer)
+		plugin.DELETE("/servers/:name", p.handleForget)
+		plugin.GET("/outages", p.handleOutages)
+		plugin.GET("/badge.svg", p.handleBadge)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *UptimeSLAPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "uptime.json")
+}
+
+// save persists the state if it changed
+func (p *UptimeSLAPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Servers: p.servers, Outages: p.outages, LastPoll: p.lastPoll}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *UptimeSLAPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop checks server availability until shutdown
+func (p *UptimeSLAPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.poll()
+		if err := p.save(); err != nil {
+			log.Printf("[uptime-sla] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := p.interval()
+		p.mu.RUnlock()
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// interval returns the poll interval. Caller must hold p.mu.
+func (p *UptimeSLAPlugin) interval() time.Duration {
+	interval := time.Duration(p.config.PollInterval) * time.Second
+	if interval < 10*time.Second {
+		interval = 10 * time.Second
+	}
+	return interval
+}
+
+// poll credits the time since the last poll to each server's state, then
+// updates the states from the server list (and probes, if enabled)
+func (p *UptimeSLAPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	rpc := p.client()
+	var list struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := rpc.Call(ctx, "server.list", nil, &list); err != nil {
+		// Without the server list we can't tell who is up; leave the gap
+		// unaccounted rather than guess
+		p.mu.Lock()
+		p.lastError = err.Error()
+		p.lastPoll = time.Time{}
+		p.mu.Unlock()
+		log.Printf("[uptime-sla] failed to list servers: %v", err)
+		return
+	}
+
+	exclude := make(map[string]bool)
+	for _, name := range strings.FieldsFunc(cfg.ExcludeServers, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' }) {
+		exclude[strings.ToLower(name)] = true
+	}
+
+	up := make(map[string]string)
+	for _, s := range list.List {
+		key := strings.ToLower(s.Name)
+		if s.Server.ULined || exclude[key] {
+			continue
+		}
+		if cfg.Probe && cfg.ProbeMethod != "" {
+			var out json.RawMessage
+			if err := rpc.Call(ctx, cfg.ProbeMethod, map[string]interface{}{"server": s.Name}, &out); err != nil {
+				continue
+			}
+		}
+		up[key] = s.Name
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastError = ""
+	now := time.Now().UTC()
+
+	// Gaps much longer than the poll interval mean the panel was not
+	// watching, so they are not counted either way
+	if !p.lastPoll.IsZero() && now.Sub(p.lastPoll) <= 3*p.interval() {
+		for _, s := range p.servers {
+			s.credit(p.lastPoll, now)
+		}
+	}
+	p.lastPoll = now
+
+	for key, name := range up {
+		if _, ok := p.servers[key]; !ok {
+			p.servers[key] = &ServerRecord{Name: name, FirstSeen: now, Up: true, LastChange: now, Days: make(map[string]*Day)}
+		}
+	}
+	for key, s := range p.servers {
+		if exclude[key] {
+			continue
+		}
+		_, isUp := up[key]
+		if isUp == s.Up {
+			continue
+		}
+		s.Up = isUp
+		s.LastChange = now
+		if !isUp {
+			p.outages = append(p.outages, &Outage{ID: newID(), Server: s.Name, Start: now})
+			if max := cfg.MaxOutages; max > 0 && len(p.outages) > max {
+				p.outages = append([]*Outage(nil), p.outages[len(p.outages)-max:]...)
+			}
+			log.Printf("[uptime-sla] %s is down", s.Name)
+			continue
+		}
+		for _, o := range p.outages {
+			if o.End == nil && o.Server == s.Name {
+				end := now
+				o.End = &end
+				o.Duration = int64(now.Sub(o.Start).Seconds())
+			}
+		}
+		log.Printf("[uptime-sla] %s is up again", s.Name)
+	}
+	p.dirty = true
+}
+
+// credit adds the time between from and to to the server's current state,
+// split at UTC midnight
+func (s *ServerRecord) credit(from, to time.Time) {
+	for from.Before(to) {
+		next := time.Date(from.Year(), from.Month(), from.Day()+1, 0, 0, 0, 0, time.UTC)
+		if next.After(to) {
+			next = to
+		}
+		key := from.Format(dayLayout)
+		d, ok := s.Days[key]
+		if !ok {
+			d = &Day{}
+			s.Days[key] = d
+		}
+		secs := int64(next.Sub(from).Seconds())
+		if s.Up {
+			d.Up += secs
+		} else {
+			d.Down += secs
+		}
+		from = next
+	}
+}
+
+// availability sums the days of a month ("2006-01"). Caller must hold p.mu.
+func (p *UptimeSLAPlugin) availability(s *ServerRecord, month string) Availability {
+	a := Availability{Server: s.Name}
+	var up, down int64
+	for key, d := range s.Days {
+		if strings.HasPrefix(key, month) {
+			up += d.Up
+			down += d.Down
+		}
+	}
+	for _, o := range p.outages {
+		if o.Server == s.Name && o.Start.Format("2006-01") == month {
+			a.Outages++
+		}
+	}
+	a.DowntimeMinutes = round(float64(down)/60, 1)
+	a.ObservedHours = round(float64(up+down)/3600, 1)
+	if up+down > 0 {
+		pct := float64(up) * 100 / float64(up+down)
+		met := pct >= p.config.SLATarget
+		a.UptimePct = &pct
+		a.SLAMet = &met
+	}
+	return a
+}
+
+// monthReport returns the availability of every server for a month.
+// Caller must hold p.mu.
+func (p *UptimeSLAPlugin) monthReport(month string) []Availability {
+	report := make([]Availability, 0, len(p.servers))
+	for _, s := range p.servers {
+		a := p.availability(s, month)
+		if a.UptimePct != nil {
+			report = append(report, a)
+		}
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Server < report[j].Server })
+	return report
+}
+
+// networkAvailability averages the uptime of all servers for a month.
+// Caller must hold p.mu.
+func (p *UptimeSLAPlugin) networkAvailability(month string) (Availability, []Availability) {
+	report := p.monthReport(month)
+	net := Availability{Server: "network"}
+	if len(report) == 0 {
+		return net, report
+	}
+	sum := 0.0
+	for _, a := range report {
+		sum += *a.UptimePct
+		net.DowntimeMinutes += a.DowntimeMinutes
+		net.Outages += a.Outages
+	}
+	pct := sum / float64(len(report))
+	met := pct >= p.config.SLATarget
+	net.UptimePct = &pct
+	net.SLAMet = &met
+	return net, report
+}
+
+// round rounds to the given number of decimals
+func round(v float64, decimals int) float64 {
+	f := math.Pow(10, float64(decimals))
+	return math.Round(v*f) / f
+}
+
+// monthParam returns the requested month, defaulting to the current one
+func monthParam(c *gin.Context) (string, bool) {
+	month := c.DefaultQuery("month", time.Now().UTC().Format("2006-01"))
+	if _, err := time.Parse("2006-01", month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month must be YYYY-MM"})
+		return "", false
+	}
+	return month, true
+}
+
+// handleReport returns the SLA report of a month
+func (p *UptimeSLAPlugin) handleReport(c *gin.Context) {
+	month, ok := monthParam(c)
+	if !ok {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	net, servers := p.networkAvailability(month)
+	c.JSON(http.StatusOK, gin.H{
+		"month":      month,
+		"sla_target": p.config.SLATarget,
+		"network":    net,
+		"servers":    servers,
+	})
+}
+
+// handleServer returns the current state and monthly history of a server
+func (p *UptimeSLAPlugin) handleServer(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s, ok := p.servers[strings.ToLower(c.Param("name"))]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+		return
+	}
+
+	months := make(map[string]bool)
+	for key := range s.Days {
+		months[key[:7]] = true
+	}
+	monthly := make([]gin.H, 0, len(months))
+	for month := range months {
+		monthly = append(monthly, gin.H{"month": month, "availability": p.availability(s, month)})
+	}
+	sort.Slice(monthly, func(i, j int) bool { return monthly[i]["month"].(string) > monthly[j]["month"].(string) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":        s.Name,
+		"up":          s.Up,
+		"first_seen":  s.FirstSeen,
+		"last_change": s.LastChange,
+		"monthly":     monthly,
+	})
+}
+
+// handleForget removes a decommissioned server from the records
+func (p *UptimeSLAPlugin) handleForget(c *gin.Context) {
+	key := strings.ToLower(c.Param("name"))
+
+	p.mu.Lock()
+	s, ok := p.servers[key]
+	if ok {
+		delete(p.servers, key)
+		for _, o := range p.outages {
+			if o.End == nil && o.Server == s.Name {
+				now := time.Now().UTC()
+				o.End = &now
+				o.Duration = int64(now.Sub(o.Start).Seconds())
+			}
+		}
+		p.dirty = true
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
+		return
+	}
+	if err := p.save(); err != nil {
+		log.Printf("[uptime-sla] failed to save data: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Server removed"})
+}
+
+// handleOutages returns the outages of a month, newest first
+func (p *UptimeSLAPlugin) handleOutages(c *gin.Context) {
+	month, ok := monthParam(c)
+	if !ok {
+		return
+	}
+	server := c.Query("server")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Outage, 0)
+	for i := len(p.outages) - 1; i >= 0; i-- {
+		o := p.outages[i]
+		if o.Start.Format("2006-01") != month {
+			continue
+		}
+		if server != "" && !strings.EqualFold(o.Server, server) {
+			continue
+		}
+		list = append(list, o)
+	}
+	c.JSON(http.StatusOK, gin.H{"month": month, "outages": list})
+}
+
+// handleBadge renders an uptime badge for a server or the whole network
+func (p *UptimeSLAPlugin) handleBadge(c *gin.Context) {
+	month, ok := monthParam(c)
+	if !ok {
+		return
+	}
+
+	p.mu.RLock()
+	target := p.config.SLATarget
+	a, _ := p.networkAvailability(month)
+	if name := c.Query("server"); name != "" {
+		a = Availability{}
+		if s, ok := p.servers[strings.ToLower(name)]; ok {
+			a = p.availability(s, month)
+		}
+	}
+	p.mu.RUnlock()
+
+	label := c.DefaultQuery("label", "uptime")
+	value, color := "unknown", "#9f9f9f"
+	if a.UptimePct != nil {
+		value = fmt.Sprintf("%.2f%%", math.Floor(*a.UptimePct*100)/100)
+		color = badgeColor(*a.UptimePct, target)
+	}
+
+	c.Header("Cache-Control", "max-age=300")
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(renderBadge(label, value, color)))
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleGetConfig returns the current configuration
+func (p *UptimeSLAPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *UptimeSLAPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *UptimeSLAPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *UptimeSLAPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,237 @@
+/**
+ * User Counter Frontend Script
+ *
+ * Shows the snippets that put a "users online" badge on a website, with a
+ * preview of each.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'user-counter';
+  const PLUGIN_NAME = 'User Counter';
+  const PAGE_PATH = '/plugins/user-counter';
+  const API_BASE = '/api/plugin/user-counter';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('user-counter-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'user-counter-styles';
+    style.textContent = `
+      .ucn-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .ucn-card { background: var(--bg-secondary, #181825); border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 1rem; }
+      .ucn-card h3 { margin: 0 0 0.75rem; color: var(--text-primary, #cdd6f4); font-size: 1rem; }
+      .ucn-stats { display: flex; gap: 1.5rem; flex-wrap: wrap; }
+      .ucn-stats b { display: block; font-size: 1.4rem; color: var(--text-primary, #cdd6f4); }
+      .ucn-snippet { display: flex; gap: 0.5rem; align-items: flex-start; margin-bottom: 0.75rem; }
+      .ucn-snippet pre {
+        flex: 1;
+        margin: 0;
+        padding: 0.5rem 0.75rem;
+        background: var(--bg-primary, #11111b);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        white-space: pre-wrap;
+        word-break: break-all;
+        font-size: 0.8rem;
+      }
+      .ucn-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.4rem 0.9rem;
+        cursor: pointer;
+      }
+      .ucn-preview { padding: 1rem; border-radius: 6px; background: #f4f5f7; }
+      .ucn-preview iframe { border: 0; width: 320px; height: 36px; }
+      .ucn-muted { color: var(--text-muted, #6c7086); font-size: 0.8rem; }
+      .ucn-danger { color: var(--error, #f38ba8); }
+      .ucn-app select {
+        background: var(--bg-primary, #11111b);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.3rem 0.5rem;
+      }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function snippets(theme) {
+    const base = `${window.location.origin}${API_BASE}/public`;
+    return {
+      script: `<script src="${base}/widget.js" data-theme="${theme}" async></script>`,
+      iframe: `<iframe src="${base}/badge?theme=${theme}" title="Users online" style="border:0;width:320px;height:36px;"></iframe>`,
+      json: `${base}/counts.json`
+    };
+  }
+
+  function snippetHtml(id, title, text) {
+    return `
+      <div class="ucn-muted">${title}</div>
+      <div class="ucn-snippet">
+        <pre id="${id}">${escapeHtml(text)}</pre>
+        <button class="ucn-btn" data-copy="${id}">Copy</button>
+      </div>
+    `;
+  }
+
+  function renderEmbed(container, theme) {
+    const s = snippets(theme);
+    container.querySelector('#ucn-snippets').innerHTML = `
+      ${snippetHtml('ucn-script', 'Script tag: draws the badge where the tag is, or in the element named by <code>data-target</code>', s.script)}
+      ${snippetHtml('ucn-iframe', 'Iframe: for sites that don\'t allow scripts', s.iframe)}
+      ${snippetHtml('ucn-json', 'JSON: for your own widget', s.json)}
+    `;
+    container.querySelector('#ucn-preview').innerHTML =
+      `<iframe src="${API_BASE}/public/badge?theme=${encodeURIComponent(theme)}" title="Badge preview"></iframe>`;
+  }
+
+  async function loadStatus(container) {
+    const el = container.querySelector('#ucn-status');
+    try {
+      const data = await api('GET', '/status');
+      const c = data.counts;
+      el.innerHTML = `
+        ${data.enabled ? '' : '<div class="ucn-danger">The badge is disabled; enable it in the plugin settings.</div>'}
+        ${data.error ? `<div class="ucn-danger">Last poll failed: ${escapeHtml(data.error)}</div>` : ''}
+        <div class="ucn-stats">
+          <div><span class="ucn-muted">Users</span><b>${c.updated ? c.users.toLocaleString() : '-'}</b></div>
+          ${c.channels != null ? `<div><span class="ucn-muted">Channels</span><b>${c.channels.toLocaleString()}</b></div>` : ''}
+          ${c.servers != null ? `<div><span class="ucn-muted">Servers</span><b>${c.servers.toLocaleString()}</b></div>` : ''}
+          ${c.record != null ? `<div><span class="ucn-muted">Record</span><b>${c.record.toLocaleString()}</b></div>` : ''}
+        </div>
+        <div class="ucn-muted">${c.updated ? `Updated ${escapeHtml(new Date(c.updated).toLocaleString())}` : 'Not polled yet'}</div>
+      `;
+      const select = container.querySelector('#ucn-theme');
+      if (!select.dataset.touched) {
+        select.value = c.theme;
+        renderEmbed(container, c.theme);
+      }
+    } catch (e) {
+      el.innerHTML = `<div class="ucn-danger">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="ucn-app" data-plugin="${PLUGIN_ID}">
+        <div class="ucn-card">
+          <h3>What the badge shows</h3>
+          <div id="ucn-status">Loading...</div>
+        </div>
+        <div class="ucn-card">
+          <h3>Embed</h3>
+          <p>
+            Theme
+            <select id="ucn-theme">
+              <option value="light">Light</option>
+              <option value="dark">Dark</option>
+              <option value="auto">Follow the visitor</option>
+            </select>
+          </p>
+          <div id="ucn-snippets"></div>
+          <div class="ucn-muted">Preview</div>
+          <div class="ucn-preview" id="ucn-preview"></div>
+          <p class="ucn-muted">Sites other than the panel can only use the script and JSON if they are listed in Allowed Sites.</p>
+        </div>
+      </div>
+    `;
+
+    const app = container.querySelector('.ucn-app');
+    app.addEventListener('change', (e) => {
+      if (e.target.id === 'ucn-theme') {
+        e.target.dataset.touched = '1';
+        renderEmbed(container, e.target.value);
+      }
+    });
+    app.addEventListener('click', (e) => {
+      const btn = e.target.closest('[data-copy]');
+      if (!btn) return;
+      const text = container.querySelector(`#${btn.dataset.copy}`).textContent;
+      navigator.clipboard.writeText(text).then(() => {
+        btn.textContent = 'Copied';
+        setTimeout(() => { btn.textContent = 'Copy'; }, 1500);
+      });
+    });
+
+    renderEmbed(container, 'light');
+    loadStatus(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('user-counter-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+})();
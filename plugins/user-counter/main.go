@@ -0,0 +1,432 @@
+// User Counter Plugin for UnrealIRCd Web Panel
+// Serves live user and channel counts as public JSON and as a badge that
+// websites can embed with a script tag or an iframe
+
+package usercounter
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+)
+
+// UserCounterPlugin implements the Plugin interface
+type UserCounterPlugin struct {
+	config  Config
+	rpc     *jsonrpc.Client
+	counts  *statsResult
+	updated time.Time
+	pollErr string
+	mu      sync.RWMutex
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL       string `json:"rpc_url"`
+	RPCUser      string `json:"rpc_user"`
+	RPCPassword  string `json:"rpc_password"`
+	RPCInsecure  bool   `json:"rpc_insecure"`
+	Enabled      bool   `json:"enabled"`
+	Network      string `json:"network"`
+	Label        string `json:"label"`
+	LinkURL      string `json:"link_url"`
+	Theme        string `json:"theme"`
+	ShowChannels bool   `json:"show_channels"`
+	ShowServers  bool   `json:"show_servers"`
+	ShowRecord   bool   `json:"show_record"`
+	PollInterval int    `json:"poll_interval"`
+	AllowedSites string `json:"allowed_sites"`
+}
+
+// Counts is what the public endpoint serves and the widget shows
+type Counts struct {
+	Network  string     `json:"network"`
+	Users    int        `json:"users"`
+	Channels *int       `json:"channels,omitempty"`
+	Servers  *int       `json:"servers,omitempty"`
+	Record   *int       `json:"record,omitempty"`
+	Label    string     `json:"label"`
+	Link     string     `json:"link,omitempty"`
+	Theme    string     `json:"theme"`
+	Refresh  int        `json:"refresh"`
+	Updated  *time.Time `json:"updated"`
+	Stale    bool       `json:"stale,omitempty"`
+}
+
+// statsResult is the part of stats.get we need
+type statsResult struct {
+	User struct {
+		Total  int `json:"total"`
+		Record int `json:"record"`
+	} `json:"user"`
+	Channel struct {
+		Total int `json:"total"`
+	} `json:"channel"`
+	Server struct {
+		Total int `json:"total"`
+	} `json:"server"`
+}
+
+// themes are the badge colour schemes
+var themes = []string{"light", "dark", "auto"}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &UserCounterPlugin{
+		config: Config{
+			RPCURL:       "https://127.0.0.1:8600/api",
+			Enabled:      true,
+			Network:      "IRC Network",
+			Label:        "users online",
+			Theme:        "light",
+			ShowChannels: true,
+			PollInterval: 60,
+			AllowedSites: "*",
+		},
+	}
+}
+
+// Info returns plugin metadata
+func (p *UserCounterPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "User Counter",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Embeddable users online badge for your website",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *UserCounterPlugin) Init() error {
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.pollLoop()
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *UserCounterPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *UserCounterPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/user-counter")
+	{
+		plugin.GET("/public/counts.json", p.handleCounts)
+		plugin.GET("/public/widget.js", p.handleWidget)
+		plugin.GET("/public/badge", p.handleBadge)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *UserCounterPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// pollLoop refreshes the counts until shutdown
+func (p *UserCounterPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+		p.poll()
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// poll fetches the counts. When it fails the last counts are kept, so
+// badges don't drop to zero while the network is briefly unreachable.
+func (p *UserCounterPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var stats statsResult
+	err := p.client().Call(ctx, "stats.get", nil, &stats)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		if p.pollErr == "" {
+			log.Printf("[user-counter] failed to fetch stats: %v", err)
+		}
+		p.pollErr = err.Error()
+		return
+	}
+	p.pollErr = ""
+	p.counts = &stats
+	p.updated = time.Now().UTC()
+}
+
+// publicCounts returns the counts as the public sees them. Caller must
+// hold p.mu.
+func (p *UserCounterPlugin) publicCounts() Counts {
+	out := Counts{
+		Network: p.config.Network,
+		Label:   p.config.Label,
+		Link:    p.config.LinkURL,
+		Theme:   p.config.Theme,
+		Refresh: p.config.PollInterval,
+	}
+	if p.counts == nil {
+		return out
+	}
+	updated := p.updated
+	out.Updated = &updated
+	out.Stale = time.Since(p.updated) > 3*time.Duration(p.config.PollInterval)*time.Second
+	out.Users = p.counts.User.Total
+	if p.config.ShowChannels {
+		channels := p.counts.Channel.Total
+		out.Channels = &channels
+	}
+	if p.config.ShowServers {
+		servers := p.counts.Server.Total
+		out.Servers = &servers
+	}
+	if p.config.ShowRecord {
+		record := p.counts.User.Record
+		if out.Users > record {
+			record = out.Users
+		}
+		out.Record = &record
+	}
+	return out
+}
+
+// allowedOrigin reports whether a site may fetch the counts or frame the
+// badge; "*" allows any
+func allowedOrigin(sites, origin string) bool {
+	for _, s := range strings.Split(sites, ",") {
+		s = strings.TrimRight(strings.TrimSpace(s), "/")
+		if s == "*" || (s != "" && strings.EqualFold(s, origin)) {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheFor is how long browsers and proxies may cache a public response.
+// Caller must hold p.mu.
+func (p *UserCounterPlugin) cacheFor() string {
+	age := p.config.PollInterval / 2
+	if age < 5 {
+		age = 5
+	}
+	return fmt.Sprintf("public, max-age=%d", age)
+}
+
+// handleCounts serves the counts as JSON to any site allowed by
+// allowed_sites. It needs no login.
+func (p *UserCounterPlugin) handleCounts(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if !p.config.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User counter is disabled"})
+		return
+	}
+	if origin := c.GetHeader("Origin"); origin != "" {
+		if strings.TrimSpace(p.config.AllowedSites) == "*" {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Vary", "Origin")
+			if allowedOrigin(p.config.AllowedSites, origin) {
+				c.Header("Access-Control-Allow-Origin", origin)
+			}
+		}
+	}
+	c.Header("Cache-Control", p.cacheFor())
+	c.JSON(http.StatusOK, p.publicCounts())
+}
+
+// handleWidget serves the script that draws the badge on other sites
+func (p *UserCounterPlugin) handleWidget(c *gin.Context) {
+	p.mu.RLock()
+	enabled := p.config.Enabled
+	p.mu.RUnlock()
+
+	if !enabled {
+		c.String(http.StatusNotFound, "// User counter is disabled\n")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(widgetJS))
+}
+
+// handleBadge serves the badge as a page for an iframe. Sites that may
+// frame it are limited with frame-ancestors unless allowed_sites is "*".
+func (p *UserCounterPlugin) handleBadge(c *gin.Context) {
+	p.mu.RLock()
+	if !p.config.Enabled {
+		p.mu.RUnlock()
+		c.String(http.StatusNotFound, "User counter is disabled")
+		return
+	}
+	counts := p.publicCounts()
+	sites := strings.TrimSpace(p.config.AllowedSites)
+	cache := p.cacheFor()
+	p.mu.RUnlock()
+
+	if theme := c.Query("theme"); containsString(themes, theme) {
+		counts.Theme = theme
+	}
+	if sites != "*" {
+		ancestors := []string{"'self'"}
+		for _, s := range strings.Split(sites, ",") {
+			if s = strings.TrimRight(strings.TrimSpace(s), "/"); s != "" {
+				ancestors = append(ancestors, s)
+			}
+		}
+		c.Header("Content-Security-Policy", "frame-ancestors "+strings.Join(ancestors, " "))
+	}
+
+	var buf bytes.Buffer
+	if err := badgePage.Execute(&buf, counts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", cache)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// handleStatus returns the counts as served and the state of the poll
+func (p *UserCounterPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"counts":  p.publicCounts(),
+		"enabled": p.config.Enabled,
+		"error":   p.pollErr,
+	})
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGetConfig returns the current configuration
+func (p *UserCounterPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *UserCounterPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	newConfig.Network = strings.TrimSpace(newConfig.Network)
+	if newConfig.Network == "" || len(newConfig.Network) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "network must be 1 to 100 characters"})
+		return
+	}
+	newConfig.Label = strings.TrimSpace(newConfig.Label)
+	if newConfig.Label == "" || len(newConfig.Label) > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "label must be 1 to 50 characters"})
+		return
+	}
+	newConfig.LinkURL = strings.TrimSpace(newConfig.LinkURL)
+	if newConfig.LinkURL != "" && !strings.HasPrefix(newConfig.LinkURL, "https://") && !strings.HasPrefix(newConfig.LinkURL, "http://") && !strings.HasPrefix(newConfig.LinkURL, "irc://") && !strings.HasPrefix(newConfig.LinkURL, "ircs://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "link_url must start with http://, https://, irc:// or ircs://"})
+		return
+	}
+	if !containsString(themes, newConfig.Theme) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "theme must be light, dark or auto"})
+		return
+	}
+	if newConfig.PollInterval < 10 || newConfig.PollInterval > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poll_interval must be between 10 and 3600 seconds"})
+		return
+	}
+	newConfig.AllowedSites = strings.TrimSpace(newConfig.AllowedSites)
+	for _, s := range strings.Split(newConfig.AllowedSites, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" && s != "*" && !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("allowed site %q must be * or start with http:// or https://", s)})
+			return
+		}
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *UserCounterPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *UserCounterPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,141 @@
+package usercounter
+
+import (
+	htmltemplate "html/template"
+	"strconv"
+)
+
+// widgetJS draws the badge on the page that includes it. It finds the
+// panel from its own src, so the same script works wherever the panel is
+// hosted, and refreshes the counts as often as the panel polls them.
+const widgetJS = `(function () {
+  'use strict';
+
+  var script = document.currentScript;
+  if (!script || !script.src) return;
+  var base = script.src.split('?')[0].replace(/\/widget\.js$/, '');
+  var theme = script.getAttribute('data-theme');
+  var target = script.getAttribute('data-target');
+  var el = target ? document.getElementById(target) : null;
+  if (!el) {
+    el = document.createElement('span');
+    script.parentNode.insertBefore(el, script);
+  }
+
+  var colors = {
+    light: { bg: '#ffffff', fg: '#1f2328', border: '#d0d7de' },
+    dark: { bg: '#1e1e2e', fg: '#cdd6f4', border: '#45475a' }
+  };
+
+  function pick(name) {
+    if (name === 'auto') {
+      return window.matchMedia && window.matchMedia('(prefers-color-scheme: dark)').matches ? 'dark' : 'light';
+    }
+    return colors[name] ? name : 'light';
+  }
+
+  function fmt(n) {
+    return Number(n).toLocaleString();
+  }
+
+  function render(data) {
+    var c = colors[pick(theme || data.theme)];
+    var parts = [fmt(data.users) + ' ' + data.label];
+    if (data.channels != null) parts.push(fmt(data.channels) + ' channels');
+    if (data.servers != null) parts.push(fmt(data.servers) + ' servers');
+
+    var badge = document.createElement(data.link ? 'a' : 'span');
+    if (data.link) {
+      badge.href = data.link;
+      badge.target = '_blank';
+      badge.rel = 'noopener';
+    }
+    badge.className = 'uwp-user-counter';
+    badge.title = data.network + (data.record != null ? ' - record ' + fmt(data.record) : '');
+    badge.style.cssText = 'display:inline-flex;align-items:center;gap:6px;padding:4px 10px;border-radius:999px;' +
+      'font:13px/1.4 -apple-system,"Segoe UI",Roboto,Arial,sans-serif;text-decoration:none;' +
+      'background:' + c.bg + ';color:' + c.fg + ';border:1px solid ' + c.border + ';';
+
+    var dot = document.createElement('span');
+    dot.style.cssText = 'width:8px;height:8px;border-radius:50%;background:' + (data.stale || !data.updated ? '#8a8f98' : '#2e9d5b') + ';';
+    badge.appendChild(dot);
+    badge.appendChild(document.createTextNode(parts.join(' · ')));
+
+    el.textContent = '';
+    el.appendChild(badge);
+  }
+
+  function load() {
+    fetch(base + '/counts.json').then(function (res) {
+      if (!res.ok) throw new Error('status ' + res.status);
+      return res.json();
+    }).then(function (data) {
+      render(data);
+      setTimeout(load, Math.max(data.refresh || 60, 10) * 1000);
+    }).catch(function () {
+      setTimeout(load, 60000);
+    });
+  }
+
+  load();
+})();
+`
+
+// badgeFuncs are the helpers available to the badge template
+var badgeFuncs = htmltemplate.FuncMap{
+	"num": formatNumber,
+	// The link is checked to be http, https, irc or ircs when the config
+	// is saved; html/template would otherwise blank irc links
+	"link": func(s string) htmltemplate.URL {
+		return htmltemplate.URL(s)
+	},
+}
+
+// badgePage is the badge as a page for an iframe
+var badgePage = htmltemplate.Must(htmltemplate.New("badge").Funcs(badgeFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+{{- if .Refresh}}
+<meta http-equiv="refresh" content="{{.Refresh}}">
+{{- end}}
+<title>{{.Network}}</title>
+<style>
+html,body{margin:0;padding:0;background:transparent;}
+.badge{display:inline-flex;align-items:center;gap:6px;padding:4px 10px;border-radius:999px;font:13px/1.4 -apple-system,"Segoe UI",Roboto,Arial,sans-serif;text-decoration:none;background:#ffffff;color:#1f2328;border:1px solid #d0d7de;}
+.dark .badge{background:#1e1e2e;color:#cdd6f4;border-color:#45475a;}
+@media (prefers-color-scheme: dark){.auto .badge{background:#1e1e2e;color:#cdd6f4;border-color:#45475a;}}
+.dot{width:8px;height:8px;border-radius:50%;background:#2e9d5b;}
+.stale .dot{background:#8a8f98;}
+</style>
+</head>
+<body class="{{.Theme}}{{if or .Stale (not .Updated)}} stale{{end}}">
+{{- if .Link}}<a class="badge" href="{{link .Link}}" target="_blank" rel="noopener"{{else}}<span class="badge"{{end}} title="{{.Network}}{{if .Record}} - record {{num .Record}}{{end}}">
+<span class="dot"></span>
+{{- if .Updated}}{{num .Users}} {{.Label}}{{if .Channels}} &middot; {{num .Channels}} channels{{end}}{{if .Servers}} &middot; {{num .Servers}} servers{{end}}{{else}}{{.Network}}{{end}}
+{{if .Link}}</a>{{else}}</span>{{end}}
+</body>
+</html>
+`))
+
+// formatNumber writes a count with thousands separators
+func formatNumber(v interface{}) string {
+	var n int
+	switch x := v.(type) {
+	case int:
+		n = x
+	case *int:
+		if x == nil {
+			return ""
+		}
+		n = *x
+	}
+	s := strconv.Itoa(n)
+	if n < 0 {
+		return s
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
@@ -0,0 +1,218 @@
+/**
+ * User Funnel Frontend Script
+ *
+ * Shows how far new connections get, overall and for each day.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'user-funnel';
+  const PLUGIN_NAME = 'User Funnel';
+  const PAGE_PATH = '/plugins/user-funnel';
+  const API_BASE = '/api/plugin/user-funnel';
+
+  const STAGE_LABELS = {
+    connected: 'Connected',
+    registered: 'Completed registration',
+    identified: 'Logged in',
+    joined: 'Joined a channel',
+    stayed: 'Stayed'
+  };
+
+  let rangeDays = 30;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function rate(v) {
+    return v == null ? '-' : `${v}%`;
+  }
+
+  function stageLabel(stage, stayMinutes) {
+    return stage === 'stayed' ? `Stayed ${stayMinutes}+ minutes` : STAGE_LABELS[stage] || stage;
+  }
+
+  // Horizontal bars, one per stage, sized against those who connected
+  function funnelBars(stages, total, stayMinutes) {
+    const top = total.funnel[stages[0]] || 0;
+    return stages.map((stage, i) => {
+      const n = total.funnel[stage];
+      const width = top ? (n / top) * 100 : 0;
+      return `
+        <div class="ufn-stage">
+          <div class="ufn-stage-label">${escapeHtml(stageLabel(stage, stayMinutes))}</div>
+          <div class="ufn-bar"><div style="width: ${width.toFixed(1)}%"></div></div>
+          <div class="ufn-stage-count">
+            <strong>${n.toLocaleString()}</strong>
+            ${i > 0 ? `<span title="Of the previous stage">${rate(total.step_rate[stage])}</span>` : ''}
+          </div>
+        </div>
+      `;
+    }).join('');
+  }
+
+  function dayTable(stages, days) {
+    if (!days.length) {
+      return '<div class="ufn-empty">No new connections recorded in this range yet.</div>';
+    }
+    const rows = days.slice().reverse().map(d => `
+      <tr>
+        <td>${escapeHtml(d.date)}</td>
+        <td>${d.funnel[stages[0]].toLocaleString()}</td>
+        ${stages.slice(1).map(s => `<td>${d.funnel[s].toLocaleString()} <span>${rate(d.overall_rate[s])}</span></td>`).join('')}
+      </tr>
+    `).join('');
+    return `
+      <table class="ufn-table">
+        <thead>
+          <tr><th>Day (UTC)</th>${stages.map(s => `<th>${escapeHtml(STAGE_LABELS[s] || s)}</th>`).join('')}</tr>
+        </thead>
+        <tbody>${rows}</tbody>
+      </table>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('user-funnel-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'user-funnel-styles';
+    style.textContent = `
+      .ufn-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .ufn-stage { display: grid; grid-template-columns: 200px 1fr 120px; gap: 0.75rem; align-items: center; margin-bottom: 0.5rem; }
+      .ufn-stage-label { color: var(--text-primary, #cdd6f4); }
+      .ufn-bar { background: var(--bg-secondary, #181825); border-radius: 6px; height: 22px; overflow: hidden; }
+      .ufn-bar div { background: var(--accent, #89b4fa); height: 100%; }
+      .ufn-stage-count strong { color: var(--text-primary, #cdd6f4); margin-right: 0.4rem; }
+      .ufn-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .ufn-table th, .ufn-table td { text-align: left; padding: 0.4rem 0.5rem; border-bottom: 1px solid var(--border-primary, #313244); }
+      .ufn-table td { color: var(--text-primary, #cdd6f4); }
+      .ufn-table td span { color: var(--text-secondary, #a6adc8); font-size: 0.8rem; }
+      .ufn-range button {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: none;
+        border-radius: 6px;
+        padding: 0.3rem 0.7rem;
+        margin-right: 0.25rem;
+        cursor: pointer;
+      }
+      .ufn-range button.active { background: var(--accent, #89b4fa); color: #fff; }
+      .ufn-warning { color: var(--warning, #f9e2af); }
+      .ufn-error { color: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#ufn-body');
+    try {
+      const status = await api('/status');
+      const data = await api(`/funnel?days=${Math.min(rangeDays, status.retention_days)}`);
+
+      body.innerHTML = `
+        ${status.streaming ? '' : '<div class="ufn-warning">The log stream is not connected, so new connections are not being recorded.</div>'}
+        ${status.error ? `<div class="ufn-error">Last check: ${escapeHtml(status.error)}</div>` : ''}
+        <div>Following ${status.following.toLocaleString()} recent connections.</div>
+        <h3>Funnel</h3>
+        ${funnelBars(data.stages, data.total, data.stay_minutes)}
+        <h3>By day</h3>
+        ${dayTable(data.stages, data.days)}
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="ufn-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="ufn-app" data-plugin="${PLUGIN_ID}">
+        <div class="ufn-range">
+          ${[[7, '7 days'], [30, '30 days'], [90, '90 days']].map(([d, label]) =>
+            `<button data-days="${d}" class="${d === rangeDays ? 'active' : ''}">${label}</button>`).join('')}
+        </div>
+        <div id="ufn-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelectorAll('.ufn-range button').forEach(btn => {
+      btn.addEventListener('click', () => {
+        rangeDays = parseInt(btn.dataset.days, 10);
+        container.querySelectorAll('.ufn-range button').forEach(b => b.classList.toggle('active', b === btn));
+        load(container);
+      });
+    });
+
+    load(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('user-funnel-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,160 @@
+package userfunnel
+
+import (
+	"sort"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+)
+
+// Funnel stages, in order
+const (
+	StageConnected  = "connected"
+	StageRegistered = "registered"
+	StageIdentified = "identified"
+	StageJoined     = "joined"
+	StageStayed     = "stayed"
+)
+
+// stageNames are the stages in funnel order
+var stageNames = []string{StageConnected, StageRegistered, StageIdentified, StageJoined, StageStayed}
+
+// numStages is the number of funnel stages
+const numStages = 5
+
+// dayKey is the layout of the keys of daily counts
+const dayKey = "2006-01-02"
+
+// Day holds the funnel of the clients that connected on one UTC day.
+// Funnel counts clients that reached a stage and every stage before it;
+// Reached counts each stage on its own.
+type Day struct {
+	Funnel  [numStages]int `json:"funnel"`
+	Reached [numStages]int `json:"reached"`
+}
+
+// tracked is a client followed from its connect
+type tracked struct {
+	Day       string
+	Connected time.Time
+	// Stages reached, and how many of them in order
+	Reached [numStages]bool
+	Level   int
+}
+
+// reach marks a stage as reached and counts it in the day. Stages may be
+// reached out of order; the funnel only moves on once every stage before
+// has been reached.
+func (t *tracked) reach(stage int, day *Day) {
+	if t.Reached[stage] {
+		return
+	}
+	t.Reached[stage] = true
+	day.Reached[stage]++
+	for t.Level < numStages && t.Reached[t.Level] {
+		day.Funnel[t.Level]++
+		t.Level++
+	}
+}
+
+// DayReport is a day's funnel as served by the API
+type DayReport struct {
+	Date string `json:"date"`
+	// Counts per stage, strictly in order and each on its own
+	Funnel  map[string]int `json:"funnel"`
+	Reached map[string]int `json:"reached"`
+	// Share of the previous stage that went on to each stage, and share
+	// of those who connected, in percent
+	StepRate    map[string]*float64 `json:"step_rate"`
+	OverallRate map[string]*float64 `json:"overall_rate"`
+}
+
+// report describes the funnel of a day
+func report(date string, d *Day) DayReport {
+	r := DayReport{
+		Date:        date,
+		Funnel:      make(map[string]int, numStages),
+		Reached:     make(map[string]int, numStages),
+		StepRate:    make(map[string]*float64, numStages-1),
+		OverallRate: make(map[string]*float64, numStages-1),
+	}
+	for i, name := range stageNames {
+		r.Funnel[name] = d.Funnel[i]
+		r.Reached[name] = d.Reached[i]
+		if i == 0 {
+			continue
+		}
+		r.StepRate[name] = percent(d.Funnel[i], d.Funnel[i-1])
+		r.OverallRate[name] = percent(d.Funnel[i], d.Funnel[0])
+	}
+	return r
+}
+
+// percent returns n as a share of of, or nil when of is zero
+func percent(n, of int) *float64 {
+	if of == 0 {
+		return nil
+	}
+	v := round1(float64(n) * 100 / float64(of))
+	return &v
+}
+
+// sumDays adds up the days from the given date onwards
+func sumDays(days map[string]*Day, from string) *Day {
+	total := &Day{}
+	for key, d := range days {
+		if key < from {
+			continue
+		}
+		for i := 0; i < numStages; i++ {
+			total.Funnel[i] += d.Funnel[i]
+			total.Reached[i] += d.Reached[i]
+		}
+	}
+	return total
+}
+
+// sortedDays returns the keys of days from the given date onwards, oldest
+// first
+func sortedDays(days map[string]*Day, from string) []string {
+	keys := make([]string, 0, len(days))
+	for key := range days {
+		if key >= from {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// joinedCounted reports whether a client is in a channel that counts as a
+// join, that is, one not matching the ignore masks
+func joinedCounted(channels []string, ignore []string) bool {
+	for _, ch := range channels {
+		if !matchAny(ignore, ch) {
+			return true
+		}
+	}
+	return false
+}
+
+// loggedIn reports whether an account field means the client is
+// identified; UnrealIRCd may report "0" for none
+func loggedIn(account string) bool {
+	return account != "" && account != "0"
+}
+
+// round1 rounds to one decimal place
+func round1(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
+
+// matchAny reports whether s matches any of the masks
+func matchAny(masks []string, s string) bool {
+	for _, m := range masks {
+		if ircname.MatchMask(m, s) {
+			return true
+		}
+	}
+	return false
+}
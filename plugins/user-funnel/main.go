@@ -0,0 +1,539 @@
+// User Funnel Plugin for UnrealIRCd Web Panel
+// Follows new connections from connect to registration, login, first
+// channel and a session of some length, with daily conversion rates
+
+package userfunnel
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on following clients
+const (
+	// A client introduced longer ago than this is not a new connection,
+	// for example one a linking server brings along
+	newConnectWindow = 2 * time.Minute
+	// Clients are followed for a day at most
+	maxTrack = 24 * time.Hour
+	// At most this many clients are followed at once
+	maxTracked = 100000
+)
+
+// UserFunnelPlugin implements the Plugin interface
+type UserFunnelPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	days         map[string]*Day
+	clients      map[string]*tracked
+	lastPoll     time.Time
+	pollErr      string
+	streamOK     bool
+	cancelStream context.CancelFunc
+	dirty        bool
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL             string `json:"rpc_url"`
+	RPCUser            string `json:"rpc_user"`
+	RPCPassword        string `json:"rpc_password"`
+	RPCInsecure        bool   `json:"rpc_insecure"`
+	DataDir            string `json:"data_dir"`
+	StreamURL          string `json:"stream_url"`
+	LogSources         string `json:"log_sources"`
+	UnregisteredEvents string `json:"unregistered_events"`
+	IgnoreChannels     string `json:"ignore_channels"`
+	StayMinutes        int    `json:"stay_minutes"`
+	PollInterval       int    `json:"poll_interval"`
+	RetentionDays      int    `json:"retention_days"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Days map[string]*Day `json:"days"`
+}
+
+// eventClient is the subset of the UnrealIRCd client object we need
+type eventClient struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	ConnectedSince string `json:"connected_since"`
+	User           struct {
+		Account  string `json:"account"`
+		Modes    string `json:"modes"`
+		Channels []struct {
+			Name string `json:"name"`
+		} `json:"channels"`
+	} `json:"user"`
+}
+
+// channelNames lists the channels the client is in
+func (c *eventClient) channelNames() []string {
+	names := make([]string, 0, len(c.User.Channels))
+	for _, ch := range c.User.Channels {
+		names = append(names, ch.Name)
+	}
+	return names
+}
+
+// clientEvent holds the client of a connect or disconnect log entry
+type clientEvent struct {
+	Client *eventClient `json:"client"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &UserFunnelPlugin{
+		config: Config{
+			RPCURL:        "https://127.0.0.1:8600/api",
+			DataDir:       "data/plugins/user-funnel",
+			StreamURL:     "wss://127.0.0.1:8600/",
+			LogSources:    "all,!debug",
+			StayMinutes:   10,
+			PollInterval:  30,
+			RetentionDays: 90,
+		},
+		days:    make(map[string]*Day),
+		clients: make(map[string]*tracked),
+	}
+}
+
+// Info returns plugin metadata
+func (p *UserFunnelPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "User Funnel",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Measures how many new connections register, log in, join a channel and stay",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *UserFunnelPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[user-funnel] failed to load data: %v", err)
+	}
+	if data.Days != nil {
+		p.days = data.Days
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "user-funnel-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		week := report("", sumDays(p.days, time.Now().UTC().AddDate(0, 0, -6).Format(dayKey)))
+		content := map[string]interface{}{
+			"connected_7d": week.Funnel[StageConnected],
+		}
+		for _, stage := range stageNames[1:] {
+			if rate := week.OverallRate[stage]; rate != nil {
+				content[stage+"_rate"] = *rate
+			}
+		}
+		if !p.streamOK {
+			content["status"] = "Log stream not connected"
+		}
+		return plugins.DashboardCard{
+			Title:   "New User Funnel",
+			Icon:    "Filter",
+			Content: content,
+			Order:   88,
+			Size:    "md",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.pollLoop()
+	go p.streamLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *UserFunnelPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *UserFunnelPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/user-funnel")
+	{
+		plugin.GET("/funnel", p.handleFunnel)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file daily counts are kept in
+func (p *UserFunnelPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "funnel.json")
+}
+
+// save writes daily counts to disk if they changed
+func (p *UserFunnelPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Days: p.days}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *UserFunnelPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// day returns the counts of a day, creating them if needed. Caller must
+// hold p.mu.
+func (p *UserFunnelPlugin) day(key string) *Day {
+	d, ok := p.days[key]
+	if !ok {
+		d = &Day{}
+		p.days[key] = d
+	}
+	return d
+}
+
+// streamLoop follows the IRCd log until shutdown
+func (p *UserFunnelPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *UserFunnelPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[user-funnel] log stream: %v", err)
+	}
+}
+
+// handleEvent starts following clients as they connect, counts
+// connections lost before registering, and settles clients as they leave
+func (p *UserFunnelPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	now := time.Now().UTC()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case strings.HasSuffix(ev.EventID, "_CLIENT_CONNECT"):
+		var ce clientEvent
+		if err := json.Unmarshal(ev.Raw, &ce); err != nil || ce.Client == nil || ce.Client.ID == "" {
+			return
+		}
+		p.connected(ce.Client, now)
+	case strings.HasSuffix(ev.EventID, "_CLIENT_DISCONNECT"):
+		var ce clientEvent
+		if err := json.Unmarshal(ev.Raw, &ce); err != nil || ce.Client == nil {
+			return
+		}
+		if t, ok := p.clients[ce.Client.ID]; ok {
+			p.observe(t, ce.Client, now)
+			delete(p.clients, ce.Client.ID)
+		}
+	case lists.ContainsFold(lists.Split(p.config.UnregisteredEvents), ev.EventID):
+		d := p.day(now.Format(dayKey))
+		d.Funnel[0]++
+		d.Reached[0]++
+		p.dirty = true
+	}
+}
+
+// connected starts following a client that has just registered. Services
+// and clients that a linking server brings along are left out. Caller
+// must hold p.mu.
+func (p *UserFunnelPlugin) connected(cl *eventClient, now time.Time) {
+	if _, ok := p.clients[cl.ID]; ok || len(p.clients) >= maxTracked {
+		return
+	}
+	if strings.Contains(cl.User.Modes, "S") {
+		return
+	}
+	if since, err := time.Parse(time.RFC3339, cl.ConnectedSince); err == nil && now.Sub(since) > newConnectWindow {
+		return
+	}
+	t := &tracked{Day: now.Format(dayKey), Connected: now}
+	d := p.day(t.Day)
+	t.reach(0, d)
+	t.reach(1, d)
+	p.observe(t, cl, now)
+	p.clients[cl.ID] = t
+	p.dirty = true
+}
+
+// observe moves a client down the funnel from what is known of it now.
+// Caller must hold p.mu.
+func (p *UserFunnelPlugin) observe(t *tracked, cl *eventClient, now time.Time) {
+	d := p.day(t.Day)
+	if loggedIn(cl.User.Account) {
+		t.reach(2, d)
+	}
+	if joinedCounted(cl.channelNames(), lists.Split(p.config.IgnoreChannels)) {
+		t.reach(3, d)
+	}
+	if now.Sub(t.Connected) >= time.Duration(p.config.StayMinutes)*time.Minute {
+		t.reach(4, d)
+	}
+	p.dirty = true
+}
+
+// pollLoop checks the clients being followed every poll_interval until
+// shutdown
+func (p *UserFunnelPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.poll()
+		if err := p.save(); err != nil {
+			log.Printf("[user-funnel] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// poll looks up the clients being followed to see whether they logged
+// in, joined a channel or stayed, and stops following clients that left
+// or have been followed long enough
+func (p *UserFunnelPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	start := time.Now().UTC()
+	var result struct {
+		List []eventClient `json:"list"`
+	}
+	err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 4}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now().UTC()
+	p.lastPoll = now
+	if err != nil {
+		p.pollErr = err.Error()
+		log.Printf("[user-funnel] failed to list users: %v", err)
+		return
+	}
+	p.pollErr = ""
+
+	online := make(map[string]*eventClient, len(result.List))
+	for i := range result.List {
+		online[result.List[i].ID] = &result.List[i]
+	}
+	for id, t := range p.clients {
+		cl, ok := online[id]
+		switch {
+		case ok:
+			p.observe(t, cl, now)
+			if now.Sub(t.Connected) > maxTrack {
+				delete(p.clients, id)
+			}
+		case t.Connected.Before(start):
+			// Gone without a disconnect being seen
+			delete(p.clients, id)
+		}
+	}
+
+	cutoff := now.AddDate(0, 0, -p.config.RetentionDays).Format(dayKey)
+	for key := range p.days {
+		if key < cutoff {
+			delete(p.days, key)
+			p.dirty = true
+		}
+	}
+}
+
+// handleFunnel returns the funnel of each day in the last ?days= days, 30
+// by default, and of the whole range
+func (p *UserFunnelPlugin) handleFunnel(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	n := 30
+	if s := c.Query("days"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > p.config.RetentionDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and " + strconv.Itoa(p.config.RetentionDays)})
+			return
+		}
+		n = v
+	}
+	from := time.Now().UTC().AddDate(0, 0, -(n - 1)).Format(dayKey)
+
+	days := make([]DayReport, 0, n)
+	for _, key := range sortedDays(p.days, from) {
+		days = append(days, report(key, p.days[key]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"stages":       stageNames,
+		"stay_minutes": p.config.StayMinutes,
+		"days":         days,
+		"total":        report(from, sumDays(p.days, from)),
+	})
+}
+
+// handleStatus returns stream and poll state
+func (p *UserFunnelPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"streaming":      p.streamOK,
+		"following":      len(p.clients),
+		"days":           len(p.days),
+		"error":          p.pollErr,
+		"retention_days": p.config.RetentionDays,
+	}
+	if !p.lastPoll.IsZero() {
+		status["last_poll"] = p.lastPoll
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *UserFunnelPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *UserFunnelPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if newConfig.StayMinutes < 1 || newConfig.StayMinutes > 1440 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stay_minutes must be between 1 and 1440"})
+		return
+	}
+	if newConfig.PollInterval < 10 || newConfig.PollInterval > 600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poll_interval must be between 10 and 600 seconds"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 730 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 730"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *UserFunnelPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *UserFunnelPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,568 @@
+// Version Advisory Plugin for UnrealIRCd Web Panel
+// Compares the running UnrealIRCd and web panel versions against the latest
+// releases and warns when a security release is available
+
+package versionadvisory
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/notify"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// versionPattern extracts the dotted version from a string such as
+// "UnrealIRCd-6.1.4" or "v2.0.1"
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)*`)
+
+// VersionAdvisoryPlugin implements the Plugin interface
+type VersionAdvisoryPlugin struct {
+	config   Config
+	rpc      *jsonrpc.Client
+	state    *State
+	notified map[string]time.Time
+	dirty    bool
+	checkNow chan struct{}
+	mu       sync.RWMutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL           string `json:"rpc_url"`
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	DataDir          string `json:"data_dir"`
+	CheckInterval    int    `json:"check_interval"`
+	ReleaseListURL   string `json:"release_list_url"`
+	PanelRepo        string `json:"panel_repo"`
+	PanelVersion     string `json:"panel_version"`
+	SecurityKeywords string `json:"security_keywords"`
+	SkipServices     bool   `json:"skip_services"`
+	NotifyUpdates    bool   `json:"notify_updates"`
+	AlertWebhook     string `json:"alert_webhook"`
+}
+
+// ServerVersion is the version check of one linked server
+type ServerVersion struct {
+	Name     string `json:"name"`
+	Software string `json:"software"`
+	Version  string `json:"version"`
+	Branch   string `json:"branch"`
+	Latest   string `json:"latest,omitempty"`
+	Security string `json:"security_release,omitempty"`
+	Outdated bool   `json:"outdated"`
+	Urgent   bool   `json:"security_update"`
+}
+
+// PanelVersion is the version check of the web panel
+type PanelVersion struct {
+	Running  string    `json:"running"`
+	Latest   string    `json:"latest,omitempty"`
+	Outdated bool      `json:"outdated"`
+	Urgent   bool      `json:"security_update"`
+	Newer    []Release `json:"newer"`
+}
+
+// State is the outcome of the latest check
+type State struct {
+	CheckedAt time.Time                    `json:"checked_at"`
+	Errors    []string                     `json:"errors"`
+	Releases  map[string]map[string]string `json:"unrealircd_releases"`
+	Servers   []*ServerVersion             `json:"servers"`
+	Panel     *PanelVersion                `json:"panel,omitempty"`
+	Outdated  int                          `json:"outdated"`
+	Urgent    int                          `json:"security_updates"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	State    *State               `json:"state"`
+	Notified map[string]time.Time `json:"notified"`
+}
+
+// rpcServer is the subset of the UnrealIRCd server object we need
+type rpcServer struct {
+	Name   string `json:"name"`
+	Server struct {
+		ULined   bool `json:"ulined"`
+		Features struct {
+			Software string `json:"software"`
+		} `json:"features"`
+	} `json:"server"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &VersionAdvisoryPlugin{
+		config: Config{
+			RPCURL:           "https://127.0.0.1:8600/api",
+			DataDir:          "data/plugins/version-advisory",
+			CheckInterval:    12,
+			ReleaseListURL:   "https://www.unrealircd.org/downloads/list.json",
+			PanelRepo:        "unrealircd/unrealircd-webpanel",
+			SecurityKeywords: "security,vulnerability,CVE-",
+			SkipServices:     true,
+		},
+		notified: make(map[string]time.Time),
+		checkNow: make(chan struct{}, 1),
+	}
+}
+
+// Info returns plugin metadata
+func (p *VersionAdvisoryPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "Version Advisory",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Warns when UnrealIRCd or web panel security releases are available",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *VersionAdvisoryPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[version-advisory] failed to load data: %v", err)
+	}
+	p.state = data.State
+	if data.Notified != nil {
+		p.notified = data.Notified
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "version-advisory-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		content := map[string]interface{}{
+			"servers":          0,
+			"outdated":         0,
+			"security_updates": 0,
+		}
+		if s := p.state; s != nil {
+			content["servers"] = len(s.Servers)
+			content["outdated"] = s.Outdated
+			content["security_updates"] = s.Urgent
+			content["checked_at"] = s.CheckedAt
+			if s.Panel != nil {
+				content["panel_version"] = s.Panel.Running
+				content["panel_latest"] = s.Panel.Latest
+			}
+			if msg := s.warning(); msg != "" {
+				content["warning"] = msg
+			}
+		}
+		return plugins.DashboardCard{
+			Title:   "Version Advisories",
+			Icon:    "ShieldAlert",
+			Content: content,
+			Order:   35,
+			Size:    "sm",
+		}
+	}, 50)
+
+	// Show a notice on every page while a security release is pending
+	hm.Register(hooks.HookFooter, "version-advisory-footer", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		if p.state == nil || p.state.warning() == "" {
+			return nil
+		}
+		return map[string]string{
+			"text":  p.state.warning(),
+			"level": "warning",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.checkLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *VersionAdvisoryPlugin) Shutdown() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *VersionAdvisoryPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/version-advisory")
+	{
+		plugin.GET("/status", p.handleStatus)
+		plugin.POST("/check", p.handleCheck)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the data file
+func (p *VersionAdvisoryPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "advisories.json")
+}
+
+// save persists the state if it changed
+func (p *VersionAdvisoryPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{State: p.state, Notified: p.notified}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *VersionAdvisoryPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// compareVersions compares two dotted version strings numerically. An
+// empty version sorts before everything else.
+func compareVersions(a, b string) int {
+	pa := strings.Split(a, ".")
+	pb := strings.Split(b, ".")
+	if a == "" {
+		pa = nil
+	}
+	if b == "" {
+		pb = nil
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(pa[i])
+		} else {
+			x = -1
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(pb[i])
+		} else {
+			y = -1
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// warning returns the dashboard warning for pending security releases, or
+// an empty string
+func (s *State) warning() string {
+	parts := make([]string, 0, 2)
+	if s.Urgent > 0 {
+		parts = append(parts, fmt.Sprintf("%d server(s) need an UnrealIRCd security update", s.Urgent))
+	}
+	if s.Panel != nil && s.Panel.Urgent {
+		parts = append(parts, fmt.Sprintf("web panel %s has a security update to %s", s.Panel.Running, s.Panel.Latest))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Security release available: " + strings.Join(parts, "; ")
+}
+
+// checkLoop checks versions until shutdown
+func (p *VersionAdvisoryPlugin) checkLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.check()
+		if err := p.save(); err != nil {
+			log.Printf("[version-advisory] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.CheckInterval) * time.Hour
+		p.mu.RUnlock()
+		if interval < time.Hour {
+			interval = time.Hour
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-p.checkNow:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// check compares the running versions against the latest releases and
+// sends alerts for releases not notified before
+func (p *VersionAdvisoryPlugin) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	state := &State{
+		CheckedAt: time.Now().UTC(),
+		Errors:    make([]string, 0),
+		Servers:   make([]*ServerVersion, 0),
+	}
+
+	releases, err := fetchUnrealReleases(ctx, cfg.ReleaseListURL)
+	if err != nil {
+		state.Errors = append(state.Errors, fmt.Sprintf("UnrealIRCd releases: %v", err))
+	}
+	state.Releases = releases
+
+	var list struct {
+		List []rpcServer `json:"list"`
+	}
+	if err := p.client().Call(ctx, "server.list", nil, &list); err != nil {
+		state.Errors = append(state.Errors, fmt.Sprintf("server.list: %v", err))
+	}
+	for _, s := range list.List {
+		if s.Server.ULined && cfg.SkipServices {
+			continue
+		}
+		sv := &ServerVersion{
+			Name:     s.Name,
+			Software: s.Server.Features.Software,
+			Version:  versionPattern.FindString(s.Server.Features.Software),
+		}
+		sv.Branch = strings.SplitN(sv.Version, ".", 2)[0]
+		if channels := releases[sv.Branch]; channels != nil && sv.Version != "" {
+			sv.Latest = channels["stable"]
+			sv.Security = channels["security"]
+			sv.Outdated = compareVersions(sv.Version, sv.Latest) < 0
+			sv.Urgent = sv.Security != "" && compareVersions(sv.Version, sv.Security) < 0
+		}
+		if sv.Outdated {
+			state.Outdated++
+		}
+		if sv.Urgent {
+			state.Urgent++
+		}
+		state.Servers = append(state.Servers, sv)
+	}
+	sort.Slice(state.Servers, func(i, j int) bool { return state.Servers[i].Name < state.Servers[j].Name })
+
+	if running := versionPattern.FindString(cfg.PanelVersion); running != "" && cfg.PanelRepo != "" {
+		panel := &PanelVersion{Running: running, Newer: make([]Release, 0)}
+		rels, err := fetchPanelReleases(ctx, cfg.PanelRepo, lists.Split(cfg.SecurityKeywords))
+		if err != nil {
+			state.Errors = append(state.Errors, fmt.Sprintf("web panel releases: %v", err))
+		}
+		for _, r := range rels {
+			if compareVersions(r.Version, running) <= 0 {
+				continue
+			}
+			panel.Newer = append(panel.Newer, r)
+			if compareVersions(r.Version, panel.Latest) > 0 {
+				panel.Latest = r.Version
+			}
+			panel.Urgent = panel.Urgent || r.Security
+		}
+		panel.Outdated = len(panel.Newer) > 0
+		state.Panel = panel
+	}
+
+	for _, e := range state.Errors {
+		log.Printf("[version-advisory] %s", e)
+	}
+
+	p.mu.Lock()
+	now := time.Now().UTC()
+	alerts := make([]notify.Event, 0)
+	raise := func(key string, a notify.Event) {
+		if _, ok := p.notified[key]; ok {
+			return
+		}
+		p.notified[key] = now
+		alerts = append(alerts, a)
+	}
+	for _, sv := range state.Servers {
+		switch {
+		case sv.Urgent:
+			raise("unrealircd:"+sv.Name+":"+sv.Security, notify.Event{
+				Type:     "security_release",
+				Severity: "critical",
+				Title:    "UnrealIRCd security release available",
+				Message:  fmt.Sprintf("%s runs UnrealIRCd %s; upgrade to %s or later for security fixes", sv.Name, sv.Version, sv.Security),
+				Data:     map[string]interface{}{"server": sv.Name, "version": sv.Version, "security_release": sv.Security, "latest": sv.Latest},
+			})
+		case sv.Outdated && cfg.NotifyUpdates:
+			raise("unrealircd:"+sv.Name+":"+sv.Latest, notify.Event{
+				Type:     "release",
+				Severity: "info",
+				Title:    "UnrealIRCd update available",
+				Message:  fmt.Sprintf("%s runs UnrealIRCd %s; %s is available", sv.Name, sv.Version, sv.Latest),
+				Data:     map[string]interface{}{"server": sv.Name, "version": sv.Version, "latest": sv.Latest},
+			})
+		}
+	}
+	if pv := state.Panel; pv != nil {
+		switch {
+		case pv.Urgent:
+			raise("panel:"+pv.Latest, notify.Event{
+				Type:     "security_release",
+				Severity: "critical",
+				Title:    "Web panel security release available",
+				Message:  fmt.Sprintf("The web panel runs %s; %s includes security fixes", pv.Running, pv.Latest),
+				Data:     map[string]interface{}{"version": pv.Running, "latest": pv.Latest},
+			})
+		case pv.Outdated && cfg.NotifyUpdates:
+			raise("panel:"+pv.Latest, notify.Event{
+				Type:     "release",
+				Severity: "info",
+				Title:    "Web panel update available",
+				Message:  fmt.Sprintf("The web panel runs %s; %s is available", pv.Running, pv.Latest),
+				Data:     map[string]interface{}{"version": pv.Running, "latest": pv.Latest},
+			})
+		}
+	}
+	p.state = state
+	p.dirty = true
+	webhook := p.config.AlertWebhook
+	p.mu.Unlock()
+
+	for _, a := range alerts {
+		log.Printf("[version-advisory] %s: %s", a.Title, a.Message)
+		if webhook != "" {
+			a.Source = "version-advisory"
+			a.Timestamp = now
+			p.alert(webhook, a)
+		}
+	}
+}
+
+// alert posts an alert to the webhook
+func (p *VersionAdvisoryPlugin) alert(url string, ev notify.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := notify.Send(ctx, url, ev); err != nil {
+		log.Printf("[version-advisory] failed to send alert: %v", err)
+	}
+}
+
+// handleStatus returns the outcome of the latest check
+func (p *VersionAdvisoryPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.state == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Versions not checked yet"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"state":   p.state,
+		"warning": p.state.warning(),
+	})
+}
+
+// handleCheck schedules an immediate check
+func (p *VersionAdvisoryPlugin) handleCheck(c *gin.Context) {
+	select {
+	case p.checkNow <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check scheduled"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *VersionAdvisoryPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.AlertWebhook = notify.Mask(cfg.AlertWebhook)
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *VersionAdvisoryPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+
+	p.mu.Lock()
+	newConfig.AlertWebhook = notify.Unmask(newConfig.AlertWebhook, p.config.AlertWebhook)
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *VersionAdvisoryPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *VersionAdvisoryPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	return json.Unmarshal(data, &p.config)
+}
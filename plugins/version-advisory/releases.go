@@ -0,0 +1,124 @@
+package versionadvisory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+)
+
+// Release is a published web panel release
+type Release struct {
+	Tag         string    `json:"tag"`
+	Version     string    `json:"version"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+	Security    bool      `json:"security"`
+}
+
+// httpClient is used for release requests
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// getJSON fetches a URL and decodes the JSON response into out
+func getJSON(ctx context.Context, url string, out interface{}) error {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "uwp-version-advisory")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(out)
+}
+
+// fetchUnrealReleases reads the UnrealIRCd release list, which maps each
+// major version to its release channels:
+//
+//	{"6": {"Stable": {"version": "6.1.9"}, "Security": {"version": "6.1.9"}}}
+//
+// The result maps branch to channel to version.
+func fetchUnrealReleases(ctx context.Context, url string) (map[string]map[string]string, error) {
+	var list map[string]map[string]json.RawMessage
+	if err := getJSON(ctx, url, &list); err != nil {
+		return nil, err
+	}
+
+	branches := make(map[string]map[string]string)
+	for branch, channels := range list {
+		for channel, raw := range channels {
+			var entry struct {
+				Version string `json:"version"`
+			}
+			if json.Unmarshal(raw, &entry) != nil || entry.Version == "" {
+				continue
+			}
+			if branches[branch] == nil {
+				branches[branch] = make(map[string]string)
+			}
+			branches[branch][strings.ToLower(channel)] = entry.Version
+		}
+	}
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("%s: no releases listed", url)
+	}
+	return branches, nil
+}
+
+// fetchPanelReleases lists the recent releases of the web panel through the
+// GitHub API. Drafts and pre-releases are skipped. Releases whose name or
+// notes contain one of the keywords are marked as security releases.
+func fetchPanelReleases(ctx context.Context, repo string, keywords []string) ([]Release, error) {
+	var list []struct {
+		TagName     string    `json:"tag_name"`
+		Name        string    `json:"name"`
+		Body        string    `json:"body"`
+		HTMLURL     string    `json:"html_url"`
+		Draft       bool      `json:"draft"`
+		Prerelease  bool      `json:"prerelease"`
+		PublishedAt time.Time `json:"published_at"`
+	}
+	if err := getJSON(ctx, fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=30", repo), &list); err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(list))
+	for _, r := range list {
+		if r.Draft || r.Prerelease {
+			continue
+		}
+		text := strings.ToLower(r.Name + "\n" + r.Body)
+		security := false
+		for _, k := range keywords {
+			if strings.Contains(text, strings.ToLower(k)) {
+				security = true
+				break
+			}
+		}
+		releases = append(releases, Release{
+			Tag:         r.TagName,
+			Version:     versionPattern.FindString(r.TagName),
+			Name:        r.Name,
+			URL:         r.HTMLURL,
+			PublishedAt: r.PublishedAt,
+			Security:    security,
+		})
+	}
+	return releases, nil
+}
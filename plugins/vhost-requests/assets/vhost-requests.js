@@ -0,0 +1,235 @@
+/**
+ * vHost Requests Frontend Script
+ *
+ * Renders the vhost request queue on the plugin page and lets staff
+ * submit, approve and deny requests.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'vhost-requests';
+  const PLUGIN_NAME = 'vHost Requests';
+  const PAGE_PATH = '/plugins/vhost-requests';
+  const API_BASE = '/api/plugin/vhost-requests';
+
+  let currentStatus = 'pending';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('vhost-requests-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'vhost-requests-styles';
+    style.textContent = `
+      .vhr-app { display: flex; flex-direction: column; gap: 1rem; }
+      .vhr-toolbar { display: flex; gap: 0.5rem; flex-wrap: wrap; align-items: center; }
+      .vhr-tab, .vhr-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .vhr-tab.active, .vhr-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .vhr-btn.danger { background: var(--error, #f38ba8); color: #fff; }
+      .vhr-form { display: grid; grid-template-columns: repeat(auto-fit, minmax(160px, 1fr)); gap: 0.5rem; }
+      .vhr-form input {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .vhr-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .vhr-table th, .vhr-table td {
+        text-align: left;
+        padding: 0.5rem;
+        border-bottom: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+        vertical-align: top;
+      }
+      .vhr-table th { color: var(--text-primary, #cdd6f4); }
+      .vhr-history { font-size: 0.8rem; color: var(--text-muted, #6c7086); margin: 0.25rem 0 0 0; padding-left: 1rem; }
+      .vhr-error { color: var(--error, #f38ba8); }
+      .vhr-empty { color: var(--text-muted, #6c7086); padding: 1rem 0; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function renderRow(r) {
+    const actions = r.status === 'pending' || r.status === 'failed'
+      ? `<button class="vhr-btn primary" data-action="approve" data-id="${escapeHtml(r.id)}">Approve</button>
+         ${r.status === 'pending' ? `<button class="vhr-btn danger" data-action="deny" data-id="${escapeHtml(r.id)}">Deny</button>` : ''}`
+      : '';
+    const history = (r.history || []).map(e =>
+      `<li>${escapeHtml(new Date(e.timestamp).toLocaleString())} - ${escapeHtml(e.actor)} ${escapeHtml(e.action)}${e.detail ? ': ' + escapeHtml(e.detail) : ''}</li>`
+    ).join('');
+
+    return `
+      <tr>
+        <td>${escapeHtml(r.nick)}<br><small>${escapeHtml(r.account)}</small></td>
+        <td><code>${escapeHtml(r.vhost)}</code></td>
+        <td>${escapeHtml(r.reason)}</td>
+        <td>${escapeHtml(r.status)}<ul class="vhr-history">${history}</ul></td>
+        <td>${actions}</td>
+      </tr>
+    `;
+  }
+
+  async function loadRequests(container) {
+    const list = container.querySelector('#vhr-list');
+    list.innerHTML = '<div class="vhr-empty">Loading...</div>';
+
+    try {
+      const query = currentStatus ? `?status=${encodeURIComponent(currentStatus)}` : '';
+      const data = await api('GET', '/requests' + query);
+      if (!data.requests.length) {
+        list.innerHTML = '<div class="vhr-empty">No requests.</div>';
+        return;
+      }
+      list.innerHTML = `
+        <table class="vhr-table">
+          <thead><tr><th>User</th><th>vHost</th><th>Reason</th><th>Status</th><th></th></tr></thead>
+          <tbody>${data.requests.map(renderRow).join('')}</tbody>
+        </table>
+      `;
+    } catch (e) {
+      list.innerHTML = `<div class="vhr-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="vhr-app" data-plugin="${PLUGIN_ID}">
+        <form class="vhr-form" id="vhr-submit">
+          <input name="nick" placeholder="Nick" required>
+          <input name="account" placeholder="Account (defaults to nick)">
+          <input name="vhost" placeholder="vhost.example.net" required>
+          <input name="reason" placeholder="Reason">
+          <button class="vhr-btn primary" type="submit">Submit request</button>
+        </form>
+        <div class="vhr-toolbar">
+          ${['pending', 'approved', 'denied', 'failed', ''].map(s =>
+            `<button class="vhr-tab${s === currentStatus ? ' active' : ''}" data-status="${s}">${s || 'all'}</button>`
+          ).join('')}
+        </div>
+        <div id="vhr-list"></div>
+      </div>
+    `;
+
+    container.querySelector('#vhr-submit').addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const form = new FormData(e.target);
+      try {
+        await api('POST', '/requests', Object.fromEntries(form.entries()));
+        e.target.reset();
+        loadRequests(container);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    container.querySelectorAll('.vhr-tab').forEach(tab => {
+      tab.addEventListener('click', () => {
+        currentStatus = tab.dataset.status;
+        container.querySelectorAll('.vhr-tab').forEach(t => t.classList.toggle('active', t === tab));
+        loadRequests(container);
+      });
+    });
+
+    container.querySelector('#vhr-list').addEventListener('click', async (e) => {
+      const btn = e.target.closest('[data-action]');
+      if (!btn) return;
+
+      const reason = prompt(btn.dataset.action === 'deny' ? 'Reason for denying (required):' : 'Note (optional):');
+      if (reason === null) return;
+
+      try {
+        await api('POST', `/requests/${encodeURIComponent(btn.dataset.id)}/${btn.dataset.action}`, { reason });
+      } catch (err) {
+        alert(err.message);
+      }
+      loadRequests(container);
+    });
+
+    loadRequests(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('vhost-requests-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,553 @@
+// vHost Requests Plugin for UnrealIRCd Web Panel
+// A request queue for vhosts: users or helpers submit requests, staff
+// approve or deny them with a reason, and approved vhosts are applied
+// through services and/or JSON-RPC with a full audit trail
+
+package vhostrequests
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/services"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Request statuses
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusDenied    = "denied"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// VHostRequestsPlugin implements the Plugin interface
+type VHostRequestsPlugin struct {
+	config   Config
+	rpc      *jsonrpc.Client
+	services services.Backend
+	requests []*Request
+	mu       sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL           string `json:"rpc_url"`
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	DataDir          string `json:"data_dir"`
+	ApplyMode        string `json:"apply_mode"`
+	ServicesBackend  string `json:"services_backend"`
+	ServicesEndpoint string `json:"services_endpoint"`
+	ServicesUser     string `json:"services_user"`
+	ServicesPassword string `json:"services_password"`
+	ServicesSourceIP string `json:"services_source_ip"`
+	ForbiddenWords   string `json:"forbidden_words"`
+	RequireDot       bool   `json:"require_dot"`
+}
+
+// servicesConfig returns the settings of the services client
+func (cfg Config) servicesConfig() services.Config {
+	return services.Config{
+		Backend:  cfg.ServicesBackend,
+		Endpoint: cfg.ServicesEndpoint,
+		Username: cfg.ServicesUser,
+		Password: cfg.ServicesPassword,
+		SourceIP: cfg.ServicesSourceIP,
+	}
+}
+
+// Request is a single vhost request and its audit trail
+type Request struct {
+	ID           string    `json:"id"`
+	Nick         string    `json:"nick"`
+	Account      string    `json:"account"`
+	VHost        string    `json:"vhost"`
+	Reason       string    `json:"reason"`
+	Status       string    `json:"status"`
+	SubmittedBy  string    `json:"submitted_by"`
+	SubmittedAt  time.Time `json:"submitted_at"`
+	ReviewedBy   string    `json:"reviewed_by,omitempty"`
+	ReviewedAt   time.Time `json:"reviewed_at,omitempty"`
+	ReviewReason string    `json:"review_reason,omitempty"`
+	History      []Event   `json:"history"`
+}
+
+// Event is an entry in a request's audit trail
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// vhostPattern matches characters UnrealIRCd allows in a vhost
+var vhostPattern = regexp.MustCompile(`^[A-Za-z0-9.:/-]+$`)
+
+// maxVHostLength is HOSTLEN in UnrealIRCd
+const maxVHostLength = 63
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &VHostRequestsPlugin{
+		config: Config{
+			RPCURL:           "https://127.0.0.1:8600/api",
+			DataDir:          "data/plugins/vhost-requests",
+			ApplyMode:        "services",
+			ServicesBackend:  "anope",
+			ServicesEndpoint: "http://127.0.0.1:8080/xmlrpc",
+			ServicesSourceIP: "127.0.0.1",
+			ForbiddenWords:   "admin,oper,staff,netadmin,ircop,services",
+			RequireDot:       true,
+		},
+		requests: make([]*Request, 0),
+	}
+}
+
+// Info returns plugin metadata
+func (p *VHostRequestsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "vHost Requests",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Request, review and apply vhosts with an audit trail",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *VHostRequestsPlugin) Init() error {
+	p.mu.Lock()
+	if err := storage.LoadJSON(p.storePath(), &p.requests); err != nil {
+		log.Printf("[vhost-requests] failed to load requests: %v", err)
+	}
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card with the queue length
+	hm.Register(hooks.HookOverviewCard, "vhost-requests-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		pending := 0
+		for _, r := range p.requests {
+			if r.Status == StatusPending {
+				pending++
+			}
+		}
+		return plugins.DashboardCard{
+			Title: "vHost Requests",
+			Icon:  "ClipboardList",
+			Content: map[string]interface{}{
+				"pending": pending,
+				"total":   len(p.requests),
+			},
+			Order: 80,
+			Size:  "sm",
+		}
+	}, 50)
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *VHostRequestsPlugin) Shutdown() error {
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *VHostRequestsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/vhost-requests")
+	{
+		plugin.GET("/requests", p.handleListRequests)
+		plugin.POST("/requests", p.handleSubmit)
+		plugin.GET("/requests/:id", p.handleGetRequest)
+		plugin.POST("/requests/:id/approve", p.handleApprove)
+		plugin.POST("/requests/:id/deny", p.handleDeny)
+		plugin.POST("/requests/:id/cancel", p.handleCancel)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the location of the request database
+func (p *VHostRequestsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "requests.json")
+}
+
+// save persists all requests. Caller must hold p.mu.
+func (p *VHostRequestsPlugin) save() {
+	if err := storage.SaveJSON(p.storePath(), p.requests); err != nil {
+		log.Printf("[vhost-requests] failed to save requests: %v", err)
+	}
+}
+
+// find returns a request by ID. Caller must hold p.mu.
+func (p *VHostRequestsPlugin) find(id string) *Request {
+	for _, r := range p.requests {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// record appends an event to a request's history
+func (r *Request) record(actor, action, detail string) {
+	r.History = append(r.History, Event{
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Action:    action,
+		Detail:    detail,
+	})
+}
+
+// validate checks a requested vhost against the configured policy. Caller must hold p.mu.
+func (p *VHostRequestsPlugin) validate(vhost string) error {
+	if vhost == "" || len(vhost) > maxVHostLength {
+		return fmt.Errorf("vhost must be between 1 and %d characters", maxVHostLength)
+	}
+	if !vhostPattern.MatchString(vhost) {
+		return fmt.Errorf("vhost may only contain letters, digits and . : / -")
+	}
+	if p.config.RequireDot && !strings.Contains(vhost, ".") {
+		return fmt.Errorf("vhost must contain a dot")
+	}
+	lower := strings.ToLower(vhost)
+	for _, word := range strings.Split(p.config.ForbiddenWords, ",") {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" && strings.Contains(lower, word) {
+			return fmt.Errorf("vhost contains the reserved word %q", word)
+		}
+	}
+	return nil
+}
+
+// apply sets an approved vhost through the configured channels
+func (p *VHostRequestsPlugin) apply(ctx context.Context, r *Request) []string {
+	p.mu.Lock()
+	mode := p.config.ApplyMode
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	rpc := p.rpc
+	var backend services.Backend
+	var servicesErr error
+	if mode == "services" || mode == "both" {
+		if p.services == nil {
+			p.services, servicesErr = services.New(p.config.servicesConfig())
+		}
+		backend = p.services
+	}
+	p.mu.Unlock()
+
+	var errs []string
+
+	if backend != nil || servicesErr != nil {
+		if servicesErr != nil {
+			errs = append(errs, "services: "+servicesErr.Error())
+		} else if !ircname.ValidNick(r.Nick) {
+			// Requests filed before nicks were checked
+			errs = append(errs, fmt.Sprintf("services: %q is not a valid nick", r.Nick))
+		} else {
+			cmd := "SET " + r.Nick + " " + r.VHost
+			if backend.Name() == "atheme" {
+				cmd = "ASSIGN " + r.Nick + " " + r.VHost
+			}
+			if _, err := backend.Command(ctx, "HostServ", cmd); err != nil {
+				errs = append(errs, "services: "+err.Error())
+			}
+		}
+	}
+
+	if mode == "rpc" || mode == "both" {
+		err := rpc.Call(ctx, "user.set_vhost", map[string]string{"nick": r.Nick, "vhost": r.VHost}, nil)
+		// A user that is offline simply gets the vhost from services next time
+		if err != nil && mode == "rpc" {
+			errs = append(errs, "rpc: "+err.Error())
+		}
+	}
+
+	return errs
+}
+
+// handleListRequests returns requests, newest first, optionally filtered
+func (p *VHostRequestsPlugin) handleListRequests(c *gin.Context) {
+	status := c.Query("status")
+	account := c.Query("account")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]*Request, 0)
+	for _, r := range p.requests {
+		if status != "" && r.Status != status {
+			continue
+		}
+		if account != "" && !strings.EqualFold(r.Account, account) {
+			continue
+		}
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SubmittedAt.After(list[j].SubmittedAt)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": list,
+		"count":    len(list),
+	})
+}
+
+// handleSubmit files a new vhost request
+func (p *VHostRequestsPlugin) handleSubmit(c *gin.Context) {
+	var req struct {
+		Nick    string `json:"nick" binding:"required"`
+		Account string `json:"account"`
+		VHost   string `json:"vhost" binding:"required"`
+		Reason  string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if req.Account == "" {
+		req.Account = req.Nick
+	}
+	if !ircname.ValidNick(req.Nick) || !ircname.ValidNick(req.Account) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nick and account must be valid nicknames"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.validate(req.VHost); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, r := range p.requests {
+		if r.Status == StatusPending && strings.EqualFold(r.Account, req.Account) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This account already has a pending request", "id": r.ID})
+			return
+		}
+	}
+
+	actor := access.Actor(c)
+	r := &Request{
+		ID:          newID(),
+		Nick:        req.Nick,
+		Account:     req.Account,
+		VHost:       req.VHost,
+		Reason:      req.Reason,
+		Status:      StatusPending,
+		SubmittedBy: actor,
+		SubmittedAt: time.Now(),
+		History:     make([]Event, 0),
+	}
+	r.record(actor, "submitted", r.VHost)
+	p.requests = append(p.requests, r)
+	p.save()
+
+	c.JSON(http.StatusCreated, r)
+}
+
+// handleGetRequest returns a single request with its history
+func (p *VHostRequestsPlugin) handleGetRequest(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	r := p.find(c.Param("id"))
+	if r == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// handleApprove approves a pending (or previously failed) request and applies it
+func (p *VHostRequestsPlugin) handleApprove(c *gin.Context) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&body)
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	r := p.find(c.Param("id"))
+	if r == nil {
+		p.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+	if r.Status != StatusPending && r.Status != StatusFailed {
+		p.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "Request is already " + r.Status})
+		return
+	}
+	snapshot := *r
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+	errs := p.apply(ctx, &snapshot)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r.ReviewedBy = actor
+	r.ReviewedAt = time.Now()
+	r.ReviewReason = body.Reason
+	if len(errs) > 0 {
+		r.Status = StatusFailed
+		r.record(actor, "apply_failed", strings.Join(errs, "; "))
+		p.save()
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to apply vhost", "details": errs, "request": r})
+		return
+	}
+	r.Status = StatusApproved
+	r.record(actor, "approved", body.Reason)
+	p.save()
+
+	c.JSON(http.StatusOK, r)
+}
+
+// handleDeny denies a pending request; a reason is mandatory
+func (p *VHostRequestsPlugin) handleDeny(c *gin.Context) {
+	var body struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required when denying a request"})
+		return
+	}
+	p.review(c, StatusDenied, "denied", body.Reason)
+}
+
+// handleCancel withdraws a pending request
+func (p *VHostRequestsPlugin) handleCancel(c *gin.Context) {
+	p.review(c, StatusCancelled, "cancelled", "")
+}
+
+// review moves a pending request into a final state without applying it
+func (p *VHostRequestsPlugin) review(c *gin.Context, status, action, reason string) {
+	actor := access.Actor(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r := p.find(c.Param("id"))
+	if r == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+	if r.Status != StatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Request is already " + r.Status})
+		return
+	}
+
+	r.Status = status
+	r.ReviewedBy = actor
+	r.ReviewedAt = time.Now()
+	r.ReviewReason = reason
+	r.record(actor, action, reason)
+	p.save()
+
+	c.JSON(http.StatusOK, r)
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleGetConfig returns the current configuration
+func (p *VHostRequestsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.ServicesPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *VHostRequestsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	switch newConfig.ApplyMode {
+	case "services", "rpc", "both":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "apply_mode must be services, rpc or both"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.ServicesPassword == "" {
+		newConfig.ServicesPassword = p.config.ServicesPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.services = nil
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *VHostRequestsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *VHostRequestsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	p.services = nil
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,201 @@
+/**
+ * WebSocket Stats Frontend Script
+ *
+ * Shows the clients using the websocket listeners, their hourly history,
+ * origins and countries, and the latest failed websocket connections.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'websocket-stats';
+  const PLUGIN_NAME = 'WebSocket Stats';
+  const PAGE_PATH = '/plugins/websocket-stats';
+  const API_BASE = '/api/plugin/websocket-stats';
+  const REFRESH_MS = 60000;
+
+  let refreshTimer = null;
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(path) {
+    const res = await fetch(API_BASE + path, { headers: getAuthHeaders() });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function pct(v) {
+    return v == null ? '-' : `${v}%`;
+  }
+
+  function countList(counts) {
+    const entries = Object.entries(counts || {}).sort((a, b) => b[1] - a[1]);
+    if (!entries.length) return '<div class="wss-desc">None seen</div>';
+    return `
+      <table class="wss-table">
+        <tbody>${entries.map(([k, n]) => `<tr><td>${escapeHtml(k)}</td><td>${n}</td></tr>`).join('')}</tbody>
+      </table>
+    `;
+  }
+
+  function injectStyles() {
+    if (document.getElementById('websocket-stats-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'websocket-stats-styles';
+    style.textContent = `
+      .wss-app { display: flex; flex-direction: column; gap: 1rem; color: var(--text-secondary, #a6adc8); }
+      .wss-summary { display: grid; grid-template-columns: repeat(auto-fit, minmax(150px, 1fr)); gap: 0.75rem; }
+      .wss-stat { border: 1px solid var(--border-primary, #313244); border-radius: 8px; padding: 0.75rem; }
+      .wss-stat strong { display: block; font-size: 1.4rem; color: var(--text-primary, #cdd6f4); }
+      .wss-columns { display: grid; grid-template-columns: repeat(auto-fit, minmax(260px, 1fr)); gap: 1rem; }
+      .wss-table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+      .wss-table th, .wss-table td { text-align: left; padding: 0.4rem; border-bottom: 1px solid var(--border-primary, #313244); vertical-align: top; }
+      .wss-table td { color: var(--text-primary, #cdd6f4); }
+      .wss-desc { color: var(--text-secondary, #a6adc8); font-size: 0.8rem; }
+      .wss-error { color: var(--error, #f38ba8); }
+      .wss-app select { background: var(--bg-secondary, #181825); color: var(--text-primary, #cdd6f4); border: 1px solid var(--border-primary, #313244); border-radius: 6px; padding: 0.3rem; }
+      .wss-bars { display: flex; align-items: flex-end; gap: 2px; height: 80px; }
+      .wss-bars div { flex: 1; background: var(--accent, #89b4fa); min-height: 1px; }
+      .wss-bars div.wss-failed { background: var(--error, #f38ba8); }
+    `;
+    document.head.appendChild(style);
+  }
+
+  async function load(container) {
+    const body = container.querySelector('#wss-body');
+    const hours = container.querySelector('#wss-range').value;
+    try {
+      const [data, failures] = await Promise.all([api(`/stats?hours=${hours}`), api('/failures')]);
+      const cur = data.current;
+      const total = data.total;
+      const list = data.hours || [];
+      const peak = Math.max(1, ...list.map(h => h.peak));
+      body.innerHTML = `
+        <div class="wss-summary">
+          <div class="wss-stat"><strong>${cur.websocket}</strong>online now, ${pct(cur.share_percent)} of local users</div>
+          <div class="wss-stat"><strong>${total.peak}</strong>peak</div>
+          <div class="wss-stat"><strong>${total.connects}</strong>connects, ${pct(total.connect_share_percent)} of local</div>
+          <div class="wss-stat"><strong>${total.avg_session_minutes == null ? '-' : `${total.avg_session_minutes} min`}</strong>average session</div>
+          <div class="wss-stat"><strong>${total.failures}</strong>failed connections</div>
+        </div>
+        <div>
+          <div class="wss-desc">Websocket clients per hour (peak); hours with failures in red. Ports: ${escapeHtml((data.ports || []).join(', '))}</div>
+          <div class="wss-bars">
+            ${list.map(h => `<div class="${h.failures ? 'wss-failed' : ''}" style="height:${(h.peak / peak) * 100}%" title="${escapeHtml(new Date(h.start).toLocaleString())}: peak ${h.peak}, ${h.connects} connects, ${h.failures} failures"></div>`).join('')}
+          </div>
+        </div>
+        <div class="wss-columns">
+          <div><h3>Origins</h3>${countList(total.origins)}</div>
+          <div><h3>Countries</h3>${countList(total.countries)}</div>
+          <div><h3>Failures by event</h3>${countList(total.failures_by_event)}</div>
+        </div>
+        <div>
+          <h3>Latest failures</h3>
+          <table class="wss-table">
+            <thead><tr><th>Time</th><th>Event</th><th>Origin</th><th>Message</th></tr></thead>
+            <tbody>
+              ${failures.failures.map(f => `
+                <tr><td>${escapeHtml(new Date(f.time).toLocaleString())}</td><td>${escapeHtml(f.event_id)}</td><td>${escapeHtml(f.origin || '-')}</td><td>${escapeHtml(f.message)}</td></tr>
+              `).join('') || '<tr><td colspan="4">No failures seen</td></tr>'}
+            </tbody>
+          </table>
+        </div>
+      `;
+    } catch (e) {
+      body.innerHTML = `<div class="wss-error">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="wss-app" data-plugin="${PLUGIN_ID}">
+        <div>
+          Show the last
+          <select id="wss-range">
+            <option value="24">24 hours</option>
+            <option value="72">3 days</option>
+            <option value="168">7 days</option>
+          </select>
+        </div>
+        <div id="wss-body">Loading...</div>
+      </div>
+    `;
+
+    container.querySelector('#wss-range').addEventListener('change', () => load(container));
+    load(container);
+    clearInterval(refreshTimer);
+    refreshTimer = setInterval(() => {
+      if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) {
+        clearInterval(refreshTimer);
+        return;
+      }
+      load(container);
+    }, REFRESH_MS);
+    return true;
+  }
+
+  function cleanup() {
+    clearInterval(refreshTimer);
+    const style = document.getElementById('websocket-stats-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
@@ -0,0 +1,221 @@
+package websocketstats
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+)
+
+// maxKeys limits the origins and countries kept per hour
+const maxKeys = 200
+
+// originPattern finds the origin a websocket log message mentions
+var originPattern = regexp.MustCompile(`(?i)origin\W{0,4}((?:https?|wss?)://[^\s'"\])]+|null)`)
+
+// Hour holds one hour of websocket gateway counts
+type Hour struct {
+	Samples         int            `json:"samples"`
+	Websocket       int            `json:"websocket"` // summed over the samples
+	Local           int            `json:"local"`     // summed over the samples
+	Peak            int            `json:"peak"`
+	Connects        int            `json:"connects"`
+	LocalConnects   int            `json:"local_connects"`
+	Disconnects     int            `json:"disconnects"`
+	SessionSeconds  int64          `json:"session_seconds"`
+	Failures        int            `json:"failures"`
+	FailuresByEvent map[string]int `json:"failures_by_event,omitempty"`
+	Origins         map[string]int `json:"origins,omitempty"`
+	Countries       map[string]int `json:"countries,omitempty"`
+}
+
+// addSample records one count of the clients connected right now
+func (h *Hour) addSample(websocket, local int) {
+	h.Samples++
+	h.Websocket += websocket
+	h.Local += local
+	if websocket > h.Peak {
+		h.Peak = websocket
+	}
+}
+
+// merge adds another hour's counts to h
+func (h *Hour) merge(o *Hour) {
+	h.Samples += o.Samples
+	h.Websocket += o.Websocket
+	h.Local += o.Local
+	if o.Peak > h.Peak {
+		h.Peak = o.Peak
+	}
+	h.Connects += o.Connects
+	h.LocalConnects += o.LocalConnects
+	h.Disconnects += o.Disconnects
+	h.SessionSeconds += o.SessionSeconds
+	h.Failures += o.Failures
+	h.FailuresByEvent = mergeCounts(h.FailuresByEvent, o.FailuresByEvent)
+	h.Origins = mergeCounts(h.Origins, o.Origins)
+	h.Countries = mergeCounts(h.Countries, o.Countries)
+}
+
+// HourReport is an hour, or a range of hours, as returned by the API
+type HourReport struct {
+	Start           string         `json:"start,omitempty"`
+	AvgWebsocket    float64        `json:"avg_websocket"`
+	AvgLocal        float64        `json:"avg_local"`
+	Share           *float64       `json:"share_percent"`
+	Peak            int            `json:"peak"`
+	Connects        int            `json:"connects"`
+	LocalConnects   int            `json:"local_connects"`
+	ConnectShare    *float64       `json:"connect_share_percent"`
+	Disconnects     int            `json:"disconnects"`
+	AvgSessionMins  *float64       `json:"avg_session_minutes"`
+	Failures        int            `json:"failures"`
+	FailuresByEvent map[string]int `json:"failures_by_event,omitempty"`
+	Origins         map[string]int `json:"origins,omitempty"`
+	Countries       map[string]int `json:"countries,omitempty"`
+}
+
+// report turns an hour's counts into averages and shares
+func report(start string, h *Hour) HourReport {
+	r := HourReport{
+		Start:           start,
+		Peak:            h.Peak,
+		Connects:        h.Connects,
+		LocalConnects:   h.LocalConnects,
+		ConnectShare:    percent(h.Connects, h.LocalConnects),
+		Disconnects:     h.Disconnects,
+		Failures:        h.Failures,
+		FailuresByEvent: h.FailuresByEvent,
+		Origins:         h.Origins,
+		Countries:       h.Countries,
+	}
+	if h.Samples > 0 {
+		r.AvgWebsocket = round1(float64(h.Websocket) / float64(h.Samples))
+		r.AvgLocal = round1(float64(h.Local) / float64(h.Samples))
+		r.Share = percent(h.Websocket, h.Local)
+	}
+	if h.Disconnects > 0 {
+		mins := round1(float64(h.SessionSeconds) / 60 / float64(h.Disconnects))
+		r.AvgSessionMins = &mins
+	}
+	return r
+}
+
+// sumHours adds up the hours from the given key onwards
+func sumHours(hours map[string]*Hour, from string) *Hour {
+	total := &Hour{}
+	for key, h := range hours {
+		if key >= from {
+			total.merge(h)
+		}
+	}
+	return total
+}
+
+// sortedHours returns the keys of the hours from the given key onwards,
+// oldest first
+func sortedHours(hours map[string]*Hour, from string) []string {
+	keys := make([]string, 0, len(hours))
+	for key := range hours {
+		if key >= from {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hourKey returns the key of the hour holding t
+func hourKey(t time.Time) string {
+	return t.UTC().Truncate(time.Hour).Format(time.RFC3339)
+}
+
+// count adds one to key in a map, creating the map if needed. New keys
+// are dropped once the map holds maxKeys.
+func count(m map[string]int, key string) map[string]int {
+	if m == nil {
+		m = make(map[string]int)
+	}
+	if _, ok := m[key]; ok || len(m) < maxKeys {
+		m[key]++
+	}
+	return m
+}
+
+// mergeCounts adds the counts in o to m
+func mergeCounts(m, o map[string]int) map[string]int {
+	for key, n := range o {
+		if m == nil {
+			m = make(map[string]int)
+		}
+		m[key] += n
+	}
+	return m
+}
+
+// topCounts keeps the n largest counts, adding the rest together as
+// "other"
+func topCounts(m map[string]int, n int) map[string]int {
+	if len(m) <= n {
+		return m
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	out := make(map[string]int, n+1)
+	for i, key := range keys {
+		if i < n {
+			out[key] = m[key]
+		} else {
+			out["other"] += m[key]
+		}
+	}
+	return out
+}
+
+// originOf returns the origin a log message mentions, or ""
+func originOf(msg string) string {
+	m := originPattern.FindStringSubmatch(msg)
+	if m == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimRight(m[1], ".,;:"))
+}
+
+// parsePorts parses a comma separated list of port numbers
+func parsePorts(s string) (map[int]bool, error) {
+	ports := make(map[int]bool)
+	for _, item := range lists.Split(s) {
+		n, err := strconv.Atoi(item)
+		if err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("websocket_ports: %q is not a port number", item)
+		}
+		ports[n] = true
+	}
+	return ports, nil
+}
+
+// percent returns n as a percentage of of, or nil if of is zero
+func percent(n, of int) *float64 {
+	if of == 0 {
+		return nil
+	}
+	v := round1(float64(n) * 100 / float64(of))
+	return &v
+}
+
+// round1 rounds to one decimal place
+func round1(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
@@ -0,0 +1,574 @@
+// WebSocket Stats Plugin for UnrealIRCd Web Panel
+// Counts the clients using the IRCd's websocket listeners, the origins
+// they come from and failed websocket upgrades, with hourly history
+
+package websocketstats
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/hooks"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on what is kept
+const (
+	maxRecentFailures = 50
+	topN              = 20
+)
+
+// websocketSubsystems are the log subsystems of the IRCd's websocket and
+// HTTP handling
+var websocketSubsystems = []string{"websocket", "webserver"}
+
+// WebsocketStatsPlugin implements the Plugin interface
+type WebsocketStatsPlugin struct {
+	config       Config
+	rpc          *jsonrpc.Client
+	hours        map[string]*Hour
+	recent       []Failure
+	current      Current
+	lastPoll     time.Time
+	pollErr      string
+	streamOK     bool
+	cancelStream context.CancelFunc
+	dirty        bool
+	mu           sync.RWMutex
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL         string `json:"rpc_url"`
+	RPCUser        string `json:"rpc_user"`
+	RPCPassword    string `json:"rpc_password"`
+	RPCInsecure    bool   `json:"rpc_insecure"`
+	DataDir        string `json:"data_dir"`
+	StreamURL      string `json:"stream_url"`
+	LogSources     string `json:"log_sources"`
+	WebsocketPorts string `json:"websocket_ports"`
+	FailureEvents  string `json:"failure_events"`
+	PollInterval   int    `json:"poll_interval"`
+	RetentionDays  int    `json:"retention_days"`
+}
+
+// storeData is the persisted state of the plugin
+type storeData struct {
+	Hours  map[string]*Hour `json:"hours"`
+	Recent []Failure        `json:"recent"`
+}
+
+// Failure is a failed websocket upgrade or other websocket error seen in
+// the log
+type Failure struct {
+	Time    time.Time `json:"time"`
+	EventID string    `json:"event_id"`
+	Level   string    `json:"level"`
+	Origin  string    `json:"origin,omitempty"`
+	Message string    `json:"message"`
+}
+
+// Current is the count of clients connected at the last poll
+type Current struct {
+	Websocket int            `json:"websocket"`
+	Local     int            `json:"local"`
+	Share     *float64       `json:"share_percent"`
+	ByPort    map[string]int `json:"by_port"`
+}
+
+// rpcClientInfo is the subset of the UnrealIRCd client object we need.
+// server_port, the port of the listener the client connected to, is only
+// set for clients on the server the panel talks to.
+type rpcClientInfo struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	ServerPort     int    `json:"server_port"`
+	ConnectedSince string `json:"connected_since"`
+	GeoIP          struct {
+		CountryCode string `json:"country_code"`
+	} `json:"geoip"`
+}
+
+// clientEvent holds the client of a connect or disconnect log entry
+type clientEvent struct {
+	Client *rpcClientInfo `json:"client"`
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &WebsocketStatsPlugin{
+		config: Config{
+			RPCURL:         "https://127.0.0.1:8600/api",
+			DataDir:        "data/plugins/websocket-stats",
+			StreamURL:      "wss://127.0.0.1:8600/",
+			LogSources:     "all,!debug",
+			WebsocketPorts: "8000",
+			PollInterval:   60,
+			RetentionDays:  30,
+		},
+		hours: make(map[string]*Hour),
+	}
+}
+
+// Info returns plugin metadata
+func (p *WebsocketStatsPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "WebSocket Stats",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "Tracks clients connecting through the IRCd's websocket listeners",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *WebsocketStatsPlugin) Init() error {
+	p.mu.Lock()
+	var data storeData
+	if err := storage.LoadJSON(p.storePath(), &data); err != nil {
+		log.Printf("[websocket-stats] failed to load data: %v", err)
+	}
+	if data.Hours != nil {
+		p.hours = data.Hours
+	}
+	p.recent = data.Recent
+	p.mu.Unlock()
+
+	hm := hooks.GetManager()
+
+	// Add dashboard card
+	hm.Register(hooks.HookOverviewCard, "websocket-stats-card", func(args interface{}) interface{} {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		day := sumHours(p.hours, hourKey(time.Now().Add(-23*time.Hour)))
+		content := map[string]interface{}{
+			"websocket_users": p.current.Websocket,
+			"connects_24h":    day.Connects,
+			"failures_24h":    day.Failures,
+		}
+		if p.current.Share != nil {
+			content["share_percent"] = *p.current.Share
+		}
+		if !p.streamOK {
+			content["status"] = "Log stream not connected"
+		} else if p.pollErr != "" {
+			content["status"] = p.pollErr
+		}
+		return plugins.DashboardCard{
+			Title:   "WebSocket Clients",
+			Icon:    "Globe",
+			Content: content,
+			Order:   92,
+			Size:    "sm",
+		}
+	}, 50)
+
+	p.stop = make(chan struct{})
+	p.wg.Add(2)
+	go p.pollLoop()
+	go p.streamLoop()
+
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *WebsocketStatsPlugin) Shutdown() error {
+	if p.stop != nil {
+		p.mu.Lock()
+		close(p.stop)
+		if p.cancelStream != nil {
+			p.cancelStream()
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	}
+	return p.save()
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *WebsocketStatsPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/websocket-stats")
+	{
+		plugin.GET("/stats", p.handleStats)
+		plugin.GET("/failures", p.handleFailures)
+		plugin.GET("/status", p.handleStatus)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// storePath returns the file hourly counts are kept in
+func (p *WebsocketStatsPlugin) storePath() string {
+	return filepath.Join(p.config.DataDir, "websocket.json")
+}
+
+// save writes hourly counts to disk if they changed
+func (p *WebsocketStatsPlugin) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := storage.SaveJSON(p.storePath(), storeData{Hours: p.hours, Recent: p.recent}); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// client returns the JSON-RPC client, creating it on first use
+func (p *WebsocketStatsPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// hour returns the counts of the hour holding t, creating them if
+// needed. Caller must hold p.mu.
+func (p *WebsocketStatsPlugin) hour(t time.Time) *Hour {
+	key := hourKey(t)
+	h, ok := p.hours[key]
+	if !ok {
+		h = &Hour{}
+		p.hours[key] = h
+	}
+	p.dirty = true
+	return h
+}
+
+// ports returns the configured websocket listener ports. The setting is
+// checked when it is saved, so a bad entry only comes from a hand edited
+// config and is logged. Caller must hold p.mu.
+func (p *WebsocketStatsPlugin) ports() map[int]bool {
+	ports, err := parsePorts(p.config.WebsocketPorts)
+	if err != nil {
+		log.Printf("[websocket-stats] %v", err)
+		return map[int]bool{}
+	}
+	return ports
+}
+
+// streamLoop follows the IRCd log until shutdown
+func (p *WebsocketStatsPlugin) streamLoop() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		select {
+		case <-p.stop:
+			p.mu.Unlock()
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelStream = cancel
+		stream := jsonrpc.NewLogStream(p.config.StreamURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure, lists.Split(p.config.LogSources))
+		p.mu.Unlock()
+
+		stream.Run(ctx, p.handleEvent, p.setStreamStatus)
+		cancel()
+	}
+}
+
+// setStreamStatus records the state of the log stream
+func (p *WebsocketStatsPlugin) setStreamStatus(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamOK = err == nil
+	if err != nil {
+		log.Printf("[websocket-stats] log stream: %v", err)
+	}
+}
+
+// handleEvent counts local connects and disconnects, and the failures and
+// origins in websocket log entries
+func (p *WebsocketStatsPlugin) handleEvent(ev jsonrpc.LogEvent) {
+	now := time.Now().UTC()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch ev.EventID {
+	case "LOCAL_CLIENT_CONNECT":
+		var ce clientEvent
+		if err := json.Unmarshal(ev.Raw, &ce); err != nil || ce.Client == nil {
+			return
+		}
+		h := p.hour(now)
+		h.LocalConnects++
+		if !p.ports()[ce.Client.ServerPort] {
+			return
+		}
+		h.Connects++
+		if cc := ce.Client.GeoIP.CountryCode; cc != "" {
+			h.Countries = count(h.Countries, cc)
+		}
+		return
+	case "LOCAL_CLIENT_DISCONNECT":
+		var ce clientEvent
+		if err := json.Unmarshal(ev.Raw, &ce); err != nil || ce.Client == nil || !p.ports()[ce.Client.ServerPort] {
+			return
+		}
+		h := p.hour(now)
+		h.Disconnects++
+		if since, err := time.Parse(time.RFC3339, ce.Client.ConnectedSince); err == nil && now.After(since) {
+			h.SessionSeconds += int64(now.Sub(since) / time.Second)
+		}
+		return
+	}
+
+	websocket := lists.ContainsFold(websocketSubsystems, ev.Subsystem)
+	failure := lists.ContainsFold(lists.Split(p.config.FailureEvents), ev.EventID)
+	if websocket && (ev.Level == "warn" || ev.Level == "error" || ev.Level == "fatal") {
+		failure = true
+	}
+	if !websocket && !failure {
+		return
+	}
+
+	h := p.hour(now)
+	origin := originOf(ev.Msg)
+	if origin != "" {
+		h.Origins = count(h.Origins, origin)
+	}
+	if !failure {
+		return
+	}
+	h.Failures++
+	h.FailuresByEvent = count(h.FailuresByEvent, ev.EventID)
+	p.recent = append(p.recent, Failure{
+		Time:    now,
+		EventID: ev.EventID,
+		Level:   ev.Level,
+		Origin:  origin,
+		Message: ev.Msg,
+	})
+	if len(p.recent) > maxRecentFailures {
+		p.recent = p.recent[len(p.recent)-maxRecentFailures:]
+	}
+}
+
+// pollLoop counts the connected clients every poll_interval until
+// shutdown
+func (p *WebsocketStatsPlugin) pollLoop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+
+		p.poll()
+		if err := p.save(); err != nil {
+			log.Printf("[websocket-stats] failed to save data: %v", err)
+		}
+
+		p.mu.RLock()
+		interval := time.Duration(p.config.PollInterval) * time.Second
+		p.mu.RUnlock()
+		timer.Reset(interval)
+	}
+}
+
+// poll counts the local clients, and those on a websocket listener, and
+// drops hours past the retention
+func (p *WebsocketStatsPlugin) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result struct {
+		List []rpcClientInfo `json:"list"`
+	}
+	err := p.client().Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &result)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now().UTC()
+	p.lastPoll = now
+	if err != nil {
+		p.pollErr = err.Error()
+		log.Printf("[websocket-stats] failed to list users: %v", err)
+		return
+	}
+	p.pollErr = ""
+
+	ports := p.ports()
+	cur := Current{ByPort: make(map[string]int)}
+	for _, cl := range result.List {
+		if cl.ServerPort == 0 {
+			continue
+		}
+		cur.Local++
+		if ports[cl.ServerPort] {
+			cur.Websocket++
+			cur.ByPort[strconv.Itoa(cl.ServerPort)]++
+		}
+	}
+	cur.Share = percent(cur.Websocket, cur.Local)
+	p.current = cur
+	p.hour(now).addSample(cur.Websocket, cur.Local)
+
+	cutoff := hourKey(now.AddDate(0, 0, -p.config.RetentionDays))
+	for key := range p.hours {
+		if key < cutoff {
+			delete(p.hours, key)
+		}
+	}
+}
+
+// handleStats returns each hour of the last ?hours= hours, 24 by default,
+// and the whole range
+func (p *WebsocketStatsPlugin) handleStats(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	n := 24
+	limit := 24 * p.config.RetentionDays
+	if s := c.Query("hours"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > limit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be between 1 and " + strconv.Itoa(limit)})
+			return
+		}
+		n = v
+	}
+	from := hourKey(time.Now().Add(-time.Duration(n-1) * time.Hour))
+
+	hours := make([]HourReport, 0, n)
+	for _, key := range sortedHours(p.hours, from) {
+		r := report(key, p.hours[key])
+		r.Origins, r.Countries = nil, nil
+		hours = append(hours, r)
+	}
+	total := report(from, sumHours(p.hours, from))
+	total.Origins = topCounts(total.Origins, topN)
+	total.Countries = topCounts(total.Countries, topN)
+
+	c.JSON(http.StatusOK, gin.H{
+		"current": p.current,
+		"ports":   lists.Split(p.config.WebsocketPorts),
+		"hours":   hours,
+		"total":   total,
+	})
+}
+
+// handleFailures returns the latest failures, newest first
+func (p *WebsocketStatsPlugin) handleFailures(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	list := make([]Failure, 0, len(p.recent))
+	for i := len(p.recent) - 1; i >= 0; i-- {
+		list = append(list, p.recent[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"failures": list})
+}
+
+// handleStatus returns stream and poll state
+func (p *WebsocketStatsPlugin) handleStatus(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := gin.H{
+		"streaming":      p.streamOK,
+		"hours":          len(p.hours),
+		"error":          p.pollErr,
+		"retention_days": p.config.RetentionDays,
+	}
+	if !p.lastPoll.IsZero() {
+		status["last_poll"] = p.lastPoll
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetConfig returns the current configuration
+func (p *WebsocketStatsPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *WebsocketStatsPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	if ports, err := parsePorts(newConfig.WebsocketPorts); err != nil || len(ports) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "websocket_ports must list the ports of your websocket listeners"})
+		return
+	}
+	if newConfig.PollInterval < 10 || newConfig.PollInterval > 600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poll_interval must be between 10 and 600 seconds"})
+		return
+	}
+	if newConfig.RetentionDays < 1 || newConfig.RetentionDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retention_days must be between 1 and 365"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	if p.cancelStream != nil {
+		p.cancelStream()
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *WebsocketStatsPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *WebsocketStatsPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Unmarshal(data, &p.config)
+}
@@ -0,0 +1,381 @@
+/**
+ * WHOIS Tool Frontend Script
+ *
+ * Looks up a nick, IP or account and shows the IRC WHOIS, location, reverse
+ * DNS, DNSBL listings, services account and oper notes together.
+ */
+
+(function() {
+  'use strict';
+
+  const PLUGIN_ID = 'whois-tool';
+  const PLUGIN_NAME = 'WHOIS Tool';
+  const PAGE_PATH = '/plugins/whois-tool';
+  const API_BASE = '/api/plugin/whois-tool';
+
+  // Helper to get auth headers for API calls
+  function getAuthHeaders() {
+    const headers = { 'Content-Type': 'application/json' };
+    const token = localStorage.getItem('token');
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+    return headers;
+  }
+
+  async function api(method, path, body) {
+    const options = { method, headers: getAuthHeaders() };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    const res = await fetch(API_BASE + path, options);
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || `Request failed with status ${res.status}`);
+    }
+    return data;
+  }
+
+  function escapeHtml(str) {
+    const div = document.createElement('div');
+    div.textContent = str == null ? '' : String(str);
+    return div.innerHTML;
+  }
+
+  function formatTime(ts) {
+    if (!ts || ts.startsWith('0001-')) return '-';
+    return new Date(ts).toLocaleString();
+  }
+
+  function injectStyles() {
+    if (document.getElementById('whois-tool-styles')) return;
+
+    const style = document.createElement('style');
+    style.id = 'whois-tool-styles';
+    style.textContent = `
+      .wht-app { display: flex; flex-direction: column; gap: 1rem; }
+      .wht-btn {
+        background: var(--bg-tertiary, #45475a);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.8rem;
+        cursor: pointer;
+        font-size: 0.85rem;
+      }
+      .wht-btn.primary { background: var(--accent, #89b4fa); color: #fff; }
+      .wht-btn.small { padding: 0.2rem 0.5rem; font-size: 0.75rem; }
+      .wht-btn:disabled { opacity: 0.6; cursor: wait; }
+      .wht-app input, .wht-app select, .wht-app textarea {
+        background: var(--bg-secondary, #181825);
+        color: var(--text-primary, #cdd6f4);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 6px;
+        padding: 0.4rem 0.6rem;
+      }
+      .wht-row { display: flex; gap: 0.5rem; align-items: center; flex-wrap: wrap; }
+      .wht-grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(22rem, 1fr)); gap: 1rem; }
+      .wht-card {
+        background: var(--bg-secondary, #181825);
+        border: 1px solid var(--border-primary, #313244);
+        border-radius: 8px;
+        padding: 0.75rem 1rem;
+      }
+      .wht-card h3 { margin: 0 0 0.5rem; font-size: 0.95rem; color: var(--text-primary, #cdd6f4); }
+      .wht-kv { display: grid; grid-template-columns: 9rem 1fr; gap: 0.2rem 0.75rem; font-size: 0.85rem; }
+      .wht-kv dt { color: var(--text-secondary, #a6adc8); }
+      .wht-kv dd { margin: 0; color: var(--text-primary, #cdd6f4); word-break: break-all; }
+      .wht-sources { display: flex; gap: 0.4rem; flex-wrap: wrap; }
+      .wht-source {
+        font-size: 0.75rem;
+        padding: 0.15rem 0.5rem;
+        border-radius: 999px;
+        border: 1px solid var(--border-primary, #313244);
+        color: var(--text-secondary, #a6adc8);
+      }
+      .wht-source.ok { border-color: var(--success, #a6e3a1); }
+      .wht-source.error { border-color: var(--error, #f38ba8); color: var(--error, #f38ba8); }
+      .wht-ok { color: var(--success, #a6e3a1); }
+      .wht-bad { color: var(--error, #f38ba8); }
+      .wht-muted { color: var(--text-muted, #6c7086); font-size: 0.8rem; }
+      .wht-empty { color: var(--text-muted, #6c7086); padding: 0.25rem 0; font-size: 0.85rem; }
+      .wht-list { list-style: none; margin: 0; padding: 0; font-size: 0.85rem; color: var(--text-primary, #cdd6f4); }
+      .wht-list li { padding: 0.3rem 0; border-bottom: 1px solid var(--border-primary, #313244); }
+      .wht-list li:last-child { border-bottom: none; }
+      .wht-link { color: var(--accent, #89b4fa); cursor: pointer; }
+      .wht-note-form { display: flex; flex-direction: column; gap: 0.4rem; margin-top: 0.5rem; }
+    `;
+    document.head.appendChild(style);
+  }
+
+  function kv(rows) {
+    const items = rows.filter(([, v]) => v !== undefined && v !== null && v !== '');
+    if (!items.length) return '<div class="wht-empty">Nothing known.</div>';
+    return `<dl class="wht-kv">${items.map(([k, v]) => `<dt>${escapeHtml(k)}</dt><dd>${v}</dd>`).join('')}</dl>`;
+  }
+
+  function lookupLink(target, type) {
+    if (!target) return '';
+    return `<span class="wht-link" data-lookup="${escapeHtml(target)}" data-type="${escapeHtml(type)}">${escapeHtml(target)}</span>`;
+  }
+
+  function renderUser(u) {
+    return kv([
+      ['Nick', escapeHtml(u.nick)],
+      ['User@host', `${escapeHtml(u.username)}@${escapeHtml(u.hostname)}`],
+      ['IP', lookupLink(u.ip, 'ip')],
+      ['Realname', escapeHtml(u.realname)],
+      ['Account', lookupLink(u.account, 'account')],
+      ['Server', escapeHtml(u.server)],
+      ['Connected', escapeHtml(formatTime(u.connected_since))],
+      ['Idle since', u.idle_since ? escapeHtml(formatTime(u.idle_since)) : ''],
+      ['Modes', u.modes ? `+${escapeHtml(u.modes)}` : ''],
+      ['Oper', u.oper ? `${escapeHtml(u.oper)} (${escapeHtml(u.oper_class)})` : ''],
+      ['Vhost', escapeHtml(u.vhost)],
+      ['Cloaked host', escapeHtml(u.cloaked_host)],
+      ['Reputation', escapeHtml(u.reputation)],
+      ['Security groups', escapeHtml(u.security_groups.join(', '))],
+      ['TLS', escapeHtml(u.tls_cipher)],
+      ['Cert fingerprint', escapeHtml(u.certfp)],
+      ['Channels', escapeHtml(u.channels.join(' '))]
+    ]);
+  }
+
+  function renderIRC(w) {
+    let body = '';
+    if (w.user) {
+      body += renderUser(w.user);
+    } else if (w.users.length) {
+      body += `<ul class="wht-list">${w.users.map(u =>
+        `<li>${lookupLink(u.nick, 'nick')} ${escapeHtml(u.username)}@${escapeHtml(u.hostname)} ${u.account ? `(${escapeHtml(u.account)})` : ''} <span class="wht-muted">on ${escapeHtml(u.server)}</span></li>`
+      ).join('')}</ul>`;
+    } else {
+      body += '<div class="wht-empty">Not online.</div>';
+    }
+    if (w.whowas.length) {
+      body += `<h3 style="margin-top: 0.75rem">WHOWAS</h3><ul class="wht-list">${w.whowas.map(e =>
+        `<li>${lookupLink(e.nick, 'nick')} ${escapeHtml(e.username)}@${escapeHtml(e.hostname)} ${lookupLink(e.ip, 'ip')} ${e.account ? `(${escapeHtml(e.account)})` : ''} <span class="wht-muted">until ${escapeHtml(formatTime(e.logoff_time))}</span></li>`
+      ).join('')}</ul>`;
+    }
+    return body;
+  }
+
+  function renderAddress(w) {
+    const g = w.geoip || {};
+    const place = [g.city, g.region, g.country || g.country_code].filter(Boolean).join(', ');
+    const rdns = w.rdns
+      ? (w.rdns.names.length
+        ? `${escapeHtml(w.rdns.names.join(', '))} <span class="${w.rdns.confirmed ? 'wht-ok' : 'wht-bad'}">${w.rdns.confirmed ? '✓ confirmed' : '✗ unconfirmed'}</span>`
+        : '<span class="wht-muted">no PTR record</span>')
+      : '';
+    const dnsbl = w.dnsbl.length
+      ? w.dnsbl.map(l => `<div class="wht-bad">${escapeHtml(l.zone)}: ${escapeHtml(l.codes.join(', '))}${l.reason ? ` <span class="wht-muted">${escapeHtml(l.reason)}</span>` : ''}</div>`).join('')
+      : '';
+    return kv([
+      ['IP', w.ip ? lookupLink(w.ip, 'ip') : ''],
+      ['Hostname', escapeHtml(w.hostname)],
+      ['Location', escapeHtml(place)],
+      ['Network', g.asn ? `AS${escapeHtml(g.asn)} ${escapeHtml(g.asname)}` : ''],
+      ['ISP', escapeHtml(g.isp)],
+      ['Reverse DNS', rdns],
+      ['DNSBL', dnsbl || (w.ip ? '<span class="wht-ok">not listed</span>' : '')]
+    ]);
+  }
+
+  function renderServices(w) {
+    const s = w.services;
+    if (!s) return '<div class="wht-empty">No services account.</div>';
+    const rows = Object.keys(s.fields).sort().map(k => [k.replace(/_/g, ' '), escapeHtml(s.fields[k])]);
+    if (s.linked_nicks.length) rows.push(['Linked nicks', escapeHtml(s.linked_nicks.join(', '))]);
+    return kv([['Account', lookupLink(s.account, 'account')], ...rows]);
+  }
+
+  function renderNotes(w) {
+    const list = w.notes.length
+      ? `<ul class="wht-list">${w.notes.map(n => `
+          <li>
+            <div>${escapeHtml(n.text)}</div>
+            <div class="wht-muted">on ${escapeHtml(n.target)} by ${escapeHtml(n.author)}, ${escapeHtml(formatTime(n.created_at))}
+              <button class="wht-btn small" data-action="delete-note" data-id="${escapeHtml(n.id)}">Delete</button></div>
+          </li>`).join('')}</ul>`
+      : '<div class="wht-empty">No notes.</div>';
+    const defaultTarget = w.account || w.target;
+    return `${list}
+      <form class="wht-note-form" id="wht-note-form">
+        <input type="text" name="target" value="${escapeHtml(defaultTarget)}" placeholder="Nick, account, IP or mask">
+        <textarea name="text" rows="2" placeholder="Add a note"></textarea>
+        <div><button class="wht-btn small" type="submit">Add note</button></div>
+      </form>`;
+  }
+
+  function renderResult(w) {
+    const sources = w.sources.map(s =>
+      `<span class="wht-source ${escapeHtml(s.status)}" title="${escapeHtml(s.detail || '')}">${escapeHtml(s.name)} ${escapeHtml(s.status)}${s.cached ? ' (cached)' : ` ${s.duration_ms.toFixed(0)} ms`}</span>`
+    ).join('');
+    return `
+      <div class="wht-row">
+        <strong>${escapeHtml(w.target)}</strong>
+        <span class="wht-muted">${escapeHtml(w.kind)}</span>
+        <span class="${w.online ? 'wht-ok' : 'wht-muted'}">${w.online ? 'online' : 'offline'}</span>
+        <span class="wht-muted">${escapeHtml(w.duration_ms.toFixed(0))} ms</span>
+      </div>
+      <div class="wht-sources">${sources}</div>
+      <div class="wht-grid">
+        <div class="wht-card"><h3>IRC</h3>${renderIRC(w)}</div>
+        <div class="wht-card"><h3>Address</h3>${renderAddress(w)}</div>
+        <div class="wht-card"><h3>Services</h3>${renderServices(w)}</div>
+        <div class="wht-card"><h3>Oper notes</h3>${renderNotes(w)}</div>
+      </div>
+    `;
+  }
+
+  async function loadRecent(container) {
+    const list = container.querySelector('#wht-recent');
+    try {
+      const data = await api('GET', '/recent');
+      list.innerHTML = data.recent.length
+        ? `<ul class="wht-list">${data.recent.map(r =>
+            `<li>${lookupLink(r.target, r.kind)} <span class="wht-muted">${escapeHtml(r.kind)} by ${escapeHtml(r.by)}, ${escapeHtml(formatTime(r.at))}</span></li>`
+          ).join('')}</ul>`
+        : '<div class="wht-empty">No lookups yet.</div>';
+    } catch (e) {
+      list.innerHTML = `<div class="wht-bad">${escapeHtml(e.message)}</div>`;
+    }
+  }
+
+  function renderPage() {
+    const container = document.getElementById('plugin-content');
+    if (!container) return false;
+
+    injectStyles();
+    container.innerHTML = `
+      <div class="wht-app" data-plugin="${PLUGIN_ID}">
+        <form id="wht-form" class="wht-row">
+          <input type="text" name="target" placeholder="Nick, IP address or account" required style="min-width: 18rem">
+          <select name="type">
+            <option value="auto">Auto</option>
+            <option value="nick">Nick</option>
+            <option value="ip">IP</option>
+            <option value="account">Account</option>
+          </select>
+          <label class="wht-muted"><input type="checkbox" name="refresh"> Skip cache</label>
+          <button class="wht-btn primary" type="submit">Look up</button>
+        </form>
+        <div id="wht-result"></div>
+        <div class="wht-card">
+          <h3>Recent lookups</h3>
+          <div id="wht-recent"><div class="wht-empty">Loading...</div></div>
+        </div>
+      </div>
+    `;
+
+    const form = container.querySelector('#wht-form');
+    const out = container.querySelector('#wht-result');
+    let current = null;
+
+    async function lookup(target, type) {
+      const btn = form.querySelector('button[type="submit"]');
+      form.elements.target.value = target;
+      form.elements.type.value = type || 'auto';
+      btn.disabled = true;
+      out.innerHTML = '<div class="wht-empty">Looking up...</div>';
+      try {
+        const params = new URLSearchParams({ type: form.elements.type.value });
+        if (form.elements.refresh.checked) params.set('refresh', '1');
+        current = await api('GET', `/whois/${encodeURIComponent(target)}?${params}`);
+        out.innerHTML = renderResult(current);
+        history.replaceState(null, '', `${PAGE_PATH}?target=${encodeURIComponent(target)}&type=${encodeURIComponent(form.elements.type.value)}`);
+        loadRecent(container);
+      } catch (err) {
+        out.innerHTML = `<div class="wht-bad">${escapeHtml(err.message)}</div>`;
+      } finally {
+        btn.disabled = false;
+      }
+    }
+
+    form.addEventListener('submit', (e) => {
+      e.preventDefault();
+      lookup(form.elements.target.value.trim(), form.elements.type.value);
+    });
+
+    container.querySelector('.wht-app').addEventListener('click', async (e) => {
+      const link = e.target.closest('[data-lookup]');
+      if (link) {
+        lookup(link.dataset.lookup, link.dataset.type);
+        return;
+      }
+      const del = e.target.closest('[data-action="delete-note"]');
+      if (del && confirm('Delete this note?')) {
+        try {
+          await api('DELETE', `/notes/${encodeURIComponent(del.dataset.id)}`);
+          current.notes = current.notes.filter(n => n.id !== del.dataset.id);
+          out.innerHTML = renderResult(current);
+        } catch (err) {
+          alert(err.message);
+        }
+      }
+    });
+
+    out.addEventListener('submit', async (e) => {
+      if (e.target.id !== 'wht-note-form') return;
+      e.preventDefault();
+      const f = e.target.elements;
+      try {
+        const data = await api('POST', '/notes', { target: f.target.value, text: f.text.value });
+        current.notes.push(data.note);
+        out.innerHTML = renderResult(current);
+      } catch (err) {
+        alert(err.message);
+      }
+    });
+
+    const params = new URLSearchParams(window.location.search);
+    if (params.get('target')) {
+      lookup(params.get('target'), params.get('type'));
+    }
+    loadRecent(container);
+    return true;
+  }
+
+  function cleanup() {
+    const style = document.getElementById('whois-tool-styles');
+    if (style) style.remove();
+    console.log(`[${PLUGIN_NAME}] Plugin unloaded`);
+  }
+
+  function init() {
+    console.log(`[${PLUGIN_NAME}] Initializing...`);
+
+    if (window.location.pathname.includes(PAGE_PATH) && !renderPage()) {
+      const observer = new MutationObserver((mutations, obs) => {
+        if (renderPage()) obs.disconnect();
+      });
+      observer.observe(document.body, { childList: true, subtree: true });
+      setTimeout(() => observer.disconnect(), 2000);
+    }
+  }
+
+  // SPA navigation detection
+  let lastPath = window.location.pathname;
+  setInterval(() => {
+    if (window.location.pathname !== lastPath) {
+      lastPath = window.location.pathname;
+      if (lastPath.includes(PAGE_PATH)) {
+        setTimeout(() => {
+          if (!document.querySelector(`[data-plugin="${PLUGIN_ID}"]`)) renderPage();
+        }, 100);
+      }
+    }
+  }, 500);
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', init);
+  } else {
+    init();
+  }
+
+  if (window.UWPPlugins) {
+    window.UWPPlugins.register(PLUGIN_ID, { cleanup });
+  }
+
+})();
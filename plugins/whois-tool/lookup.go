@@ -0,0 +1,526 @@
+package whoistool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/services"
+)
+
+// Target kinds
+const (
+	KindNick    = "nick"
+	KindIP      = "ip"
+	KindAccount = "account"
+)
+
+// Source states
+const (
+	SourceOK      = "ok"
+	SourceError   = "error"
+	SourceSkipped = "skipped"
+)
+
+// IRCUser is what the IRCd reports about a connected user
+type IRCUser struct {
+	Nick           string   `json:"nick"`
+	Username       string   `json:"username"`
+	Realname       string   `json:"realname"`
+	Hostname       string   `json:"hostname"`
+	IP             string   `json:"ip"`
+	Vhost          string   `json:"vhost,omitempty"`
+	CloakedHost    string   `json:"cloaked_host,omitempty"`
+	Server         string   `json:"server"`
+	Account        string   `json:"account,omitempty"`
+	Modes          string   `json:"modes"`
+	Oper           string   `json:"oper,omitempty"`
+	OperClass      string   `json:"oper_class,omitempty"`
+	Reputation     int      `json:"reputation"`
+	SecurityGroups []string `json:"security_groups"`
+	Channels       []string `json:"channels"`
+	TLSCipher      string   `json:"tls_cipher,omitempty"`
+	CertFP         string   `json:"certfp,omitempty"`
+	ConnectedSince string   `json:"connected_since"`
+	IdleSince      string   `json:"idle_since,omitempty"`
+}
+
+// WhowasEntry is a past connection from the IRCd's WHOWAS history
+type WhowasEntry struct {
+	Nick       string `json:"nick"`
+	Username   string `json:"username"`
+	Realname   string `json:"realname"`
+	Hostname   string `json:"hostname"`
+	IP         string `json:"ip"`
+	Server     string `json:"server"`
+	Account    string `json:"account,omitempty"`
+	LogonTime  string `json:"logon_time"`
+	LogoffTime string `json:"logoff_time"`
+}
+
+// GeoInfo is the location and network of an address
+type GeoInfo struct {
+	CountryCode string `json:"country_code,omitempty"`
+	Country     string `json:"country,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+	ASN         string `json:"asn,omitempty"`
+	ASName      string `json:"asname,omitempty"`
+	ISP         string `json:"isp,omitempty"`
+	Source      string `json:"source"`
+}
+
+// RDNS is the reverse DNS of an address. Confirmed is set when one of the
+// names resolves back to the address.
+type RDNS struct {
+	Names     []string `json:"names"`
+	Confirmed bool     `json:"confirmed"`
+}
+
+// Listing is a DNSBL that lists an address
+type Listing struct {
+	Zone   string   `json:"zone"`
+	Codes  []string `json:"codes"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// AccountInfo is what services know about an account
+type AccountInfo struct {
+	Account     string            `json:"account"`
+	Backend     string            `json:"backend"`
+	Fields      map[string]string `json:"fields"`
+	LinkedNicks []string          `json:"linked_nicks"`
+	Raw         []string          `json:"raw"`
+}
+
+// SourceStatus is how one source of a lookup went
+type SourceStatus struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Detail   string  `json:"detail,omitempty"`
+	Duration float64 `json:"duration_ms"`
+	Cached   bool    `json:"cached,omitempty"`
+}
+
+// rpcUser is the subset of the UnrealIRCd user object used here
+type rpcUser struct {
+	Name           string `json:"name"`
+	Hostname       string `json:"hostname"`
+	IP             string `json:"ip"`
+	ConnectedSince string `json:"connected_since"`
+	IdleSince      string `json:"idle_since"`
+	LogonTime      string `json:"logon_time"`
+	LogoffTime     string `json:"logoff_time"`
+	User           struct {
+		Username       string   `json:"username"`
+		Realname       string   `json:"realname"`
+		Vhost          string   `json:"vhost"`
+		CloakedHost    string   `json:"cloakedhost"`
+		Servername     string   `json:"servername"`
+		Account        string   `json:"account"`
+		Reputation     int      `json:"reputation"`
+		SecurityGroups []string `json:"security-groups"`
+		Modes          string   `json:"modes"`
+		OperLogin      string   `json:"operlogin"`
+		OperClass      string   `json:"operclass"`
+		Channels       []struct {
+			Name  string `json:"name"`
+			Level string `json:"level"`
+		} `json:"channels"`
+	} `json:"user"`
+	TLS struct {
+		CertFP string `json:"certfp"`
+		Cipher string `json:"cipher"`
+	} `json:"tls"`
+	GeoIP struct {
+		CountryCode string      `json:"country_code"`
+		ASN         json.Number `json:"asn"`
+		ASName      string      `json:"asname"`
+	} `json:"geoip"`
+}
+
+// channelPrefixes maps channel member modes to their prefix, highest first
+var channelPrefixes = []struct {
+	mode   byte
+	prefix string
+}{{'q', "~"}, {'a', "&"}, {'o', "@"}, {'h', "%"}, {'v', "+"}}
+
+// toUser converts an RPC user object to an IRCUser
+func (u *rpcUser) toUser() *IRCUser {
+	out := &IRCUser{
+		Nick:           u.Name,
+		Username:       u.User.Username,
+		Realname:       u.User.Realname,
+		Hostname:       u.Hostname,
+		IP:             u.IP,
+		Vhost:          u.User.Vhost,
+		CloakedHost:    u.User.CloakedHost,
+		Server:         u.User.Servername,
+		Account:        u.User.Account,
+		Modes:          u.User.Modes,
+		Oper:           u.User.OperLogin,
+		OperClass:      u.User.OperClass,
+		Reputation:     u.User.Reputation,
+		SecurityGroups: u.User.SecurityGroups,
+		Channels:       make([]string, 0, len(u.User.Channels)),
+		TLSCipher:      u.TLS.Cipher,
+		CertFP:         u.TLS.CertFP,
+		ConnectedSince: u.ConnectedSince,
+		IdleSince:      u.IdleSince,
+	}
+	if out.SecurityGroups == nil {
+		out.SecurityGroups = make([]string, 0)
+	}
+	for _, ch := range u.User.Channels {
+		prefix := ""
+		for _, p := range channelPrefixes {
+			if strings.IndexByte(ch.Level, p.mode) >= 0 {
+				prefix = p.prefix
+				break
+			}
+		}
+		out.Channels = append(out.Channels, prefix+ch.Name)
+	}
+	return out
+}
+
+// toWhowas converts an RPC WHOWAS entry to a WhowasEntry
+func (u *rpcUser) toWhowas() WhowasEntry {
+	return WhowasEntry{
+		Nick:       u.Name,
+		Username:   u.User.Username,
+		Realname:   u.User.Realname,
+		Hostname:   u.Hostname,
+		IP:         u.IP,
+		Server:     u.User.Servername,
+		Account:    u.User.Account,
+		LogonTime:  u.LogonTime,
+		LogoffTime: u.LogoffTime,
+	}
+}
+
+// ircdGeo returns the GeoIP data the IRCd attached to a user, if any
+func (u *rpcUser) ircdGeo() *GeoInfo {
+	if u.GeoIP.CountryCode == "" && u.GeoIP.ASN == "" {
+		return nil
+	}
+	return &GeoInfo{
+		CountryCode: u.GeoIP.CountryCode,
+		ASN:         u.GeoIP.ASN.String(),
+		ASName:      u.GeoIP.ASName,
+		Source:      "ircd",
+	}
+}
+
+// ircResult is what the IRCd knows about a target
+type ircResult struct {
+	user   *IRCUser
+	users  []*IRCUser
+	whowas []WhowasEntry
+	geo    *GeoInfo
+	ip     string
+	host   string
+	acct   string
+	detail string
+}
+
+// ircLookup looks a target up in the IRCd. A nick is fetched with
+// user.get and, when it isn't online, from WHOWAS. An IP or account
+// matches every connected user with it, and an IP also its WHOWAS history.
+func ircLookup(ctx context.Context, client *jsonrpc.Client, target, kind string) (*ircResult, error) {
+	res := &ircResult{users: make([]*IRCUser, 0), whowas: make([]WhowasEntry, 0)}
+	if kind == KindAccount {
+		res.acct = target
+	}
+	if kind == KindIP {
+		res.ip = target
+	}
+
+	if kind == KindNick {
+		var out struct {
+			Client rpcUser `json:"client"`
+		}
+		err := client.Call(ctx, "user.get", map[string]interface{}{"nick": target, "object_detail_level": 4}, &out)
+		if err == nil {
+			res.user = out.Client.toUser()
+			res.geo = out.Client.ircdGeo()
+			res.ip, res.host, res.acct = out.Client.IP, out.Client.Hostname, out.Client.User.Account
+			res.detail = "online on " + res.user.Server
+			return res, nil
+		}
+		if !isNotFound(err) {
+			return nil, err
+		}
+	} else {
+		var out struct {
+			List []rpcUser `json:"list"`
+		}
+		if err := client.Call(ctx, "user.list", map[string]interface{}{"object_detail_level": 2}, &out); err != nil {
+			return nil, err
+		}
+		for i := range out.List {
+			u := &out.List[i]
+			if (kind == KindIP && u.IP == target) || (kind == KindAccount && strings.EqualFold(u.User.Account, target)) {
+				res.users = append(res.users, u.toUser())
+				if res.geo == nil {
+					res.geo = u.ircdGeo()
+				}
+				if res.ip == "" {
+					res.ip = u.IP
+				}
+				if res.host == "" {
+					res.host = u.Hostname
+				}
+			}
+		}
+	}
+
+	if kind != KindAccount {
+		// whowas.get needs UnrealIRCd 6.1 or later
+		params := map[string]interface{}{"object_detail_level": 2}
+		if kind == KindIP {
+			params["ip"] = target
+		} else {
+			params["nick"] = target
+		}
+		var out struct {
+			List []rpcUser `json:"list"`
+		}
+		if err := client.Call(ctx, "whowas.get", params, &out); err == nil {
+			for i := range out.List {
+				res.whowas = append(res.whowas, out.List[i].toWhowas())
+			}
+		}
+		sort.Slice(res.whowas, func(i, j int) bool { return res.whowas[i].LogoffTime > res.whowas[j].LogoffTime })
+		if kind == KindNick && len(res.whowas) > 0 {
+			last := res.whowas[0]
+			res.ip, res.host, res.acct = last.IP, last.Hostname, last.Account
+		}
+	}
+
+	switch {
+	case kind == KindNick && len(res.whowas) > 0:
+		res.detail = fmt.Sprintf("offline, %d WHOWAS entries", len(res.whowas))
+	case kind == KindNick:
+		res.detail = "not online and not in WHOWAS"
+	default:
+		res.detail = fmt.Sprintf("%d online, %d WHOWAS entries", len(res.users), len(res.whowas))
+	}
+	return res, nil
+}
+
+// rpcNotFound is the JSON-RPC error code for an object that doesn't exist
+const rpcNotFound = -1000
+
+// isNotFound reports whether an RPC error means the nick isn't online
+func isNotFound(err error) bool {
+	var rerr *jsonrpc.Error
+	return errors.As(err, &rerr) && rerr.Code == rpcNotFound
+}
+
+// publicIP reports whether ip is worth looking up in GeoIP and DNSBLs
+func publicIP(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr != nil && !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast() && !addr.IsUnspecified()
+}
+
+// httpClient is used for GeoIP lookups
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// lookupIPAPI queries ip-api.com, which is free without a key but only
+// over plain HTTP
+func lookupIPAPI(ctx context.Context, ip string) (*GeoInfo, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	u := "http://ip-api.com/json/" + url.PathEscape(ip) + "?fields=status,message,country,countryCode,regionName,city,isp,as"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ip-api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ip-api: unexpected status %s", resp.Status)
+	}
+
+	var out struct {
+		Status      string `json:"status"`
+		Message     string `json:"message"`
+		Country     string `json:"country"`
+		CountryCode string `json:"countryCode"`
+		RegionName  string `json:"regionName"`
+		City        string `json:"city"`
+		ISP         string `json:"isp"`
+		AS          string `json:"as"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("ip-api: %w", err)
+	}
+	if out.Status != "success" {
+		return nil, fmt.Errorf("ip-api: %s", out.Message)
+	}
+
+	geo := &GeoInfo{
+		CountryCode: out.CountryCode,
+		Country:     out.Country,
+		Region:      out.RegionName,
+		City:        out.City,
+		ISP:         out.ISP,
+		Source:      "ip-api",
+	}
+	// "as" is "AS64496 Example Networks"
+	if asn, name, ok := strings.Cut(out.AS, " "); ok && strings.HasPrefix(asn, "AS") {
+		geo.ASN = strings.TrimPrefix(asn, "AS")
+		geo.ASName = name
+	}
+	return geo, nil
+}
+
+// lookupRDNS returns the PTR names of ip and whether one of them resolves
+// back to it
+func lookupRDNS(ctx context.Context, ip string) (*RDNS, error) {
+	if err := grants.Allow(grants.Outbound); err != nil {
+		return nil, err
+	}
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return &RDNS{Names: make([]string, 0)}, nil
+		}
+		return nil, err
+	}
+	out := &RDNS{Names: make([]string, 0, len(names))}
+	want := net.ParseIP(ip)
+	for _, name := range names {
+		name = strings.TrimSuffix(name, ".")
+		out.Names = append(out.Names, name)
+		if out.Confirmed {
+			continue
+		}
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if a.IP.Equal(want) {
+				out.Confirmed = true
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// reverseName returns the reversed form of ip used by DNSBLs, or "" for
+// an invalid address
+func reverseName(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", v4[3], v4[2], v4[1], v4[0])
+	}
+	nibbles := make([]string, 0, 32)
+	for i := len(addr) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, fmt.Sprintf("%x.%x", addr[i]&0x0f, addr[i]>>4))
+	}
+	return strings.Join(nibbles, ".")
+}
+
+// checkDNSBLs looks ip up in each zone in parallel and returns the zones
+// listing it
+func checkDNSBLs(ctx context.Context, ip string, zones []string) []Listing {
+	rev := reverseName(ip)
+	listings := make([]Listing, 0)
+	if rev == "" || grants.Allow(grants.Outbound) != nil {
+		return listings
+	}
+
+	results := make(chan *Listing, len(zones))
+	for _, zone := range zones {
+		go func(zone string) {
+			addrs, err := net.DefaultResolver.LookupHost(ctx, rev+"."+zone)
+			if err != nil || len(addrs) == 0 {
+				results <- nil
+				return
+			}
+			l := &Listing{Zone: zone, Codes: addrs}
+			if txt, err := net.DefaultResolver.LookupTXT(ctx, rev+"."+zone); err == nil && len(txt) > 0 {
+				l.Reason = txt[0]
+			}
+			results <- l
+		}(zone)
+	}
+	for range zones {
+		if l := <-results; l != nil {
+			listings = append(listings, *l)
+		}
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].Zone < listings[j].Zone })
+	return listings
+}
+
+// lookupAccount asks services about an account. Names that could add
+// arguments to the command are refused.
+func lookupAccount(ctx context.Context, b services.Backend, account string) (*AccountInfo, error) {
+	if !ircname.ValidNick(account) {
+		return nil, fmt.Errorf("%q is not a valid account name", account)
+	}
+	raw, err := b.Command(ctx, "NickServ", "INFO "+account)
+	if err != nil {
+		return nil, err
+	}
+	info := &AccountInfo{
+		Account:     account,
+		Backend:     b.Name(),
+		Fields:      services.ParseInfo(raw),
+		LinkedNicks: make([]string, 0),
+		Raw:         raw,
+	}
+	switch b.Name() {
+	case "anope":
+		if lines, err := b.Command(ctx, "NickServ", "GLIST "+account); err == nil {
+			info.LinkedNicks = services.ParseGroupList(lines)
+		}
+	case "atheme":
+		if nicks, ok := info.Fields["nicks"]; ok {
+			info.LinkedNicks = strings.Fields(nicks)
+		}
+	}
+	return info, nil
+}
+
+// notRegistered reports whether services answered that an account or
+// nick isn't registered, rather than failing
+func notRegistered(info *AccountInfo) bool {
+	if len(info.Fields) > 0 {
+		return false
+	}
+	for _, line := range info.Raw {
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "isn't registered") || strings.Contains(lower, "is not registered") {
+			return true
+		}
+	}
+	return false
+}
+
+// millis converts a duration to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
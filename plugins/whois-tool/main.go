@@ -0,0 +1,759 @@
+// WHOIS Tool Plugin for UnrealIRCd Web Panel
+// Combines IRC WHOIS, GeoIP, reverse DNS, DNSBL listings, services account
+// info and oper notes about a nick, IP or account into one lookup
+
+package whoistool
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/access"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/apidocs"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/grants"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/ircname"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/jsonrpc"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/lists"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/services"
+	"github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/storage"
+)
+
+// Limits on stored data
+const (
+	maxNoteLength = 2000
+	maxRecent     = 50
+	maxCached     = 5000
+)
+
+// WhoisToolPlugin implements the Plugin interface
+type WhoisToolPlugin struct {
+	config   Config
+	rpc      *jsonrpc.Client
+	services services.Backend
+	notes    []*Note
+	recent   []Recent
+	cache    map[string]cacheEntry
+	mu       sync.RWMutex
+}
+
+// Config holds plugin configuration
+type Config struct {
+	RPCURL           string `json:"rpc_url"`
+	RPCUser          string `json:"rpc_user"`
+	RPCPassword      string `json:"rpc_password"`
+	RPCInsecure      bool   `json:"rpc_insecure"`
+	DataDir          string `json:"data_dir"`
+	GeoIPService     string `json:"geoip_service"`
+	DNSBLs           string `json:"dnsbls"`
+	ServicesBackend  string `json:"services_backend"`
+	ServicesEndpoint string `json:"services_endpoint"`
+	ServicesUsername string `json:"services_username"`
+	ServicesPassword string `json:"services_password"`
+	ServicesSourceIP string `json:"services_source_ip"`
+	Timeout          int    `json:"timeout"`
+	CacheMinutes     int    `json:"cache_minutes"`
+}
+
+// servicesConfig returns the settings of the services client
+func (cfg Config) servicesConfig() services.Config {
+	return services.Config{
+		Backend:  cfg.ServicesBackend,
+		Endpoint: cfg.ServicesEndpoint,
+		Username: cfg.ServicesUsername,
+		Password: cfg.ServicesPassword,
+		SourceIP: cfg.ServicesSourceIP,
+	}
+}
+
+// Whois is the combined result of a lookup
+type Whois struct {
+	Target     string         `json:"target"`
+	Kind       string         `json:"kind"`
+	Online     bool           `json:"online"`
+	User       *IRCUser       `json:"user,omitempty"`
+	Users      []*IRCUser     `json:"users"`
+	Whowas     []WhowasEntry  `json:"whowas"`
+	IP         string         `json:"ip,omitempty"`
+	Hostname   string         `json:"hostname,omitempty"`
+	Account    string         `json:"account,omitempty"`
+	GeoIP      *GeoInfo       `json:"geoip,omitempty"`
+	RDNS       *RDNS          `json:"rdns,omitempty"`
+	DNSBL      []Listing      `json:"dnsbl"`
+	Services   *AccountInfo   `json:"services,omitempty"`
+	Notes      []*Note        `json:"notes"`
+	Sources    []SourceStatus `json:"sources"`
+	LookedUpBy string         `json:"looked_up_by"`
+	LookedUpAt time.Time      `json:"looked_up_at"`
+	Duration   float64        `json:"duration_ms"`
+}
+
+// Note is an oper note about a nick, account, IP or host. Target may be
+// a wildcard mask, such as 192.0.2.* or *.example.net.
+type Note struct {
+	ID        string    `json:"id"`
+	Target    string    `json:"target"`
+	Text      string    `json:"text"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Recent is a lookup in the recent lookups list
+type Recent struct {
+	Target string    `json:"target"`
+	Kind   string    `json:"kind"`
+	By     string    `json:"by"`
+	At     time.Time `json:"at"`
+}
+
+// cacheEntry is a cached source result
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// manifest is the plugin.json whose permissions this plugin holds
+//
+//go:embed plugin.json
+var manifest []byte
+
+// NewPlugin creates a new instance of the plugin
+func NewPlugin() plugins.Plugin {
+	grants.Register(manifest)
+
+	return &WhoisToolPlugin{
+		config: Config{
+			RPCURL:           "https://127.0.0.1:8600/api",
+			DataDir:          "data/plugins/whois-tool",
+			GeoIPService:     "ircd",
+			DNSBLs:           "dnsbl.dronebl.org,rbl.efnetrbl.org",
+			ServicesBackend:  "none",
+			ServicesEndpoint: "http://127.0.0.1:8080/xmlrpc",
+			ServicesSourceIP: "127.0.0.1",
+			Timeout:          10,
+			CacheMinutes:     10,
+		},
+		notes:  make([]*Note, 0),
+		recent: make([]Recent, 0),
+		cache:  make(map[string]cacheEntry),
+	}
+}
+
+// Info returns plugin metadata
+func (p *WhoisToolPlugin) Info() plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:        "WHOIS Tool",
+		Version:     "1.0.0",
+		Author:      "ValwareIRC",
+		Email:       "plugins@valware.co.uk",
+		Description: "IRC WHOIS, GeoIP, rDNS, DNSBL, services and oper notes in one lookup",
+		Homepage:    "https://github.com/ValwareIRC/uwp-plugins",
+		License:     "MIT",
+	}
+}
+
+// Init initializes the plugin
+func (p *WhoisToolPlugin) Init() error {
+	apidocs.Describe("whois-tool", "Combined WHOIS, GeoIP, DNS, DNSBL, services and note lookups",
+		apidocs.Operation{
+			Method: "GET", Path: "/whois/:target", Summary: "Look up a nick, IP or account",
+			Query: []apidocs.Param{
+				{Name: "type", Description: "auto, nick, ip or account; auto treats IP addresses as IPs and anything else as a nick"},
+				{Name: "refresh", Description: "1 to skip cached results"},
+			},
+			Response: Whois{},
+		},
+		apidocs.Operation{Method: "GET", Path: "/recent", Summary: "Recent lookups", Response: struct {
+			Recent []Recent `json:"recent"`
+		}{}},
+		apidocs.Operation{
+			Method: "GET", Path: "/notes", Summary: "Oper notes, newest first",
+			Query: []apidocs.Param{
+				{Name: "target", Description: "Only notes whose target matches this"},
+				{Name: "limit", Description: "Most notes returned, up to 1000", Type: "integer"},
+			},
+			Response: struct {
+				Notes []*Note `json:"notes"`
+				Total int     `json:"total"`
+			}{},
+		},
+		apidocs.Operation{
+			Method: "POST", Path: "/notes", Summary: "Add an oper note",
+			Request: struct {
+				Target string `json:"target" doc:"Nick, account, IP or host mask the note is about"`
+				Text   string `json:"text"`
+			}{},
+			Response: struct {
+				Message string `json:"message"`
+				Note    *Note  `json:"note"`
+			}{},
+		},
+		apidocs.Operation{Method: "DELETE", Path: "/notes/:id", Summary: "Delete an oper note"},
+		apidocs.Operation{Method: "GET", Path: "/config", Summary: "Get current configuration", Response: Config{}},
+		apidocs.Operation{Method: "PUT", Path: "/config", Summary: "Update configuration", Request: Config{}},
+	)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := storage.LoadJSON(p.notesPath(), &p.notes); err != nil {
+		log.Printf("[whois-tool] failed to load notes: %v", err)
+	}
+	if p.notes == nil {
+		p.notes = make([]*Note, 0)
+	}
+	return nil
+}
+
+// Shutdown cleans up the plugin
+func (p *WhoisToolPlugin) Shutdown() error {
+	apidocs.Forget("whois-tool")
+	return nil
+}
+
+// RegisterRoutes adds API routes for this plugin
+func (p *WhoisToolPlugin) RegisterRoutes(router *gin.RouterGroup) {
+	plugin := router.Group("/plugin/whois-tool")
+	{
+		plugin.GET("/whois/:target", p.handleWhois)
+		plugin.GET("/recent", p.handleRecent)
+		plugin.GET("/notes", p.handleListNotes)
+		plugin.POST("/notes", p.handleAddNote)
+		plugin.DELETE("/notes/:id", p.handleDeleteNote)
+		plugin.GET("/config", p.handleGetConfig)
+		plugin.PUT("/config", p.handleUpdateConfig)
+	}
+}
+
+// notesPath returns the location of the saved notes
+func (p *WhoisToolPlugin) notesPath() string {
+	return filepath.Join(p.config.DataDir, "notes.json")
+}
+
+// save persists the notes. Caller must hold p.mu.
+func (p *WhoisToolPlugin) save() {
+	if err := storage.SaveJSON(p.notesPath(), p.notes); err != nil {
+		log.Printf("[whois-tool] failed to save notes: %v", err)
+	}
+}
+
+// client returns the RPC client, creating it from the current config if needed
+func (p *WhoisToolPlugin) client() *jsonrpc.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rpc == nil {
+		p.rpc = jsonrpc.NewClient(p.config.RPCURL, p.config.RPCUser, p.config.RPCPassword, p.config.RPCInsecure)
+	}
+	return p.rpc
+}
+
+// getBackend returns the services backend, creating it if needed. It
+// returns nil when no services backend is configured.
+func (p *WhoisToolPlugin) getBackend() (services.Backend, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.ServicesBackend == "" || p.config.ServicesBackend == "none" {
+		return nil, nil
+	}
+	if p.services == nil {
+		b, err := services.New(p.config.servicesConfig())
+		if err != nil {
+			return nil, err
+		}
+		p.services = b
+	}
+	return p.services, nil
+}
+
+// cached returns a cached source result that hasn't expired
+func (p *WhoisToolPlugin) cached(key string) (interface{}, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	e, ok := p.cache[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// store caches a source result for cache_minutes
+func (p *WhoisToolPlugin) store(key string, value interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.CacheMinutes <= 0 {
+		return
+	}
+	now := time.Now()
+	if len(p.cache) >= maxCached {
+		for k, e := range p.cache {
+			if now.After(e.expires) {
+				delete(p.cache, k)
+			}
+		}
+		if len(p.cache) >= maxCached {
+			p.cache = make(map[string]cacheEntry)
+		}
+	}
+	p.cache[key] = cacheEntry{value: value, expires: now.Add(time.Duration(p.config.CacheMinutes) * time.Minute)}
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// parseTarget works out what kind of target is being looked up. An
+// explicit kind is checked against the target; otherwise a valid IP
+// address is an IP and anything else a nick.
+func parseTarget(target, kind string) (string, string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", "", fmt.Errorf("target is required")
+	}
+	addr := net.ParseIP(target)
+	if kind == "" || kind == "auto" {
+		kind = KindNick
+		if addr != nil {
+			kind = KindIP
+		}
+	}
+	switch kind {
+	case KindIP:
+		if addr == nil {
+			return "", "", fmt.Errorf("%s is not an IP address", target)
+		}
+		return addr.String(), kind, nil
+	case KindNick, KindAccount:
+		if !ircname.ValidNick(target) {
+			return "", "", fmt.Errorf("%s is not a valid %s", target, kind)
+		}
+		return target, kind, nil
+	}
+	return "", "", fmt.Errorf("type must be auto, nick, ip or account")
+}
+
+// whois looks a target up in every source. The IRCd is asked first, since
+// it supplies the IP address and account the other sources need; the
+// rest run in parallel. A source that fails is reported in Sources and
+// doesn't stop the others.
+func (p *WhoisToolPlugin) whois(ctx context.Context, target, kind string, refresh bool) *Whois {
+	start := time.Now()
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	w := &Whois{
+		Target:     target,
+		Kind:       kind,
+		Users:      make([]*IRCUser, 0),
+		Whowas:     make([]WhowasEntry, 0),
+		DNSBL:      make([]Listing, 0),
+		LookedUpAt: start,
+	}
+	switch kind {
+	case KindIP:
+		w.IP = target
+	case KindAccount:
+		w.Account = target
+	}
+
+	ircStatus := SourceStatus{Name: "irc"}
+	var ircGeo *GeoInfo
+	res, err := ircLookup(ctx, p.client(), target, kind)
+	ircStatus.Duration = millis(time.Since(start))
+	if err != nil {
+		ircStatus.Status, ircStatus.Detail = SourceError, err.Error()
+	} else {
+		ircStatus.Status, ircStatus.Detail = SourceOK, res.detail
+		w.User, w.Users, w.Whowas, ircGeo = res.user, res.users, res.whowas, res.geo
+		w.Online = res.user != nil || len(res.users) > 0
+		w.IP, w.Hostname, w.Account = res.ip, res.host, res.acct
+	}
+
+	// A nick without a known account may still be registered
+	servicesName := w.Account
+	if servicesName == "" && kind == KindNick {
+		servicesName = target
+	}
+
+	sources := []struct {
+		name string
+		run  func(ctx context.Context) (string, string, bool)
+	}{
+		{"geoip", func(ctx context.Context) (string, string, bool) { return p.sourceGeoIP(ctx, cfg, w, ircGeo, refresh) }},
+		{"rdns", func(ctx context.Context) (string, string, bool) { return p.sourceRDNS(ctx, w, refresh) }},
+		{"dnsbl", func(ctx context.Context) (string, string, bool) { return p.sourceDNSBL(ctx, cfg, w, refresh) }},
+		{"services", func(ctx context.Context) (string, string, bool) {
+			return p.sourceServices(ctx, w, servicesName, refresh)
+		}},
+		{"notes", func(ctx context.Context) (string, string, bool) { return p.sourceNotes(w) }},
+	}
+
+	w.Sources = make([]SourceStatus, len(sources)+1)
+	w.Sources[0] = ircStatus
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func(i int, name string, run func(context.Context) (string, string, bool)) {
+			defer wg.Done()
+			began := time.Now()
+			status, detail, fromCache := run(ctx)
+			w.Sources[i+1] = SourceStatus{Name: name, Status: status, Detail: detail, Duration: millis(time.Since(began)), Cached: fromCache}
+		}(i, src.name, src.run)
+	}
+	wg.Wait()
+
+	w.Duration = millis(time.Since(start))
+	return w
+}
+
+// sourceGeoIP fills in the location of the target's address, from the
+// IRCd's GeoIP data or from ip-api.com
+func (p *WhoisToolPlugin) sourceGeoIP(ctx context.Context, cfg Config, w *Whois, ircGeo *GeoInfo, refresh bool) (string, string, bool) {
+	if w.IP == "" {
+		return SourceSkipped, "no IP address known", false
+	}
+	if cfg.GeoIPService != "ip-api" {
+		if ircGeo == nil {
+			return SourceSkipped, "the IRCd has no GeoIP data for this address", false
+		}
+		w.GeoIP = ircGeo
+		return SourceOK, "from the IRCd", false
+	}
+	if !publicIP(w.IP) {
+		return SourceSkipped, "not a public address", false
+	}
+
+	key := "geoip:" + w.IP
+	if v, ok := p.cached(key); ok && !refresh {
+		w.GeoIP = v.(*GeoInfo)
+		return SourceOK, "from ip-api.com", true
+	}
+	geo, err := lookupIPAPI(ctx, w.IP)
+	if err != nil {
+		if ircGeo != nil {
+			w.GeoIP = ircGeo
+			return SourceOK, "from the IRCd; " + err.Error(), false
+		}
+		return SourceError, err.Error(), false
+	}
+	if geo.ASN == "" && ircGeo != nil {
+		geo.ASN, geo.ASName = ircGeo.ASN, ircGeo.ASName
+	}
+	p.store(key, geo)
+	w.GeoIP = geo
+	return SourceOK, "from ip-api.com", false
+}
+
+// sourceRDNS fills in the reverse DNS of the target's address
+func (p *WhoisToolPlugin) sourceRDNS(ctx context.Context, w *Whois, refresh bool) (string, string, bool) {
+	if w.IP == "" {
+		return SourceSkipped, "no IP address known", false
+	}
+	key := "rdns:" + w.IP
+	fromCache := false
+	if v, ok := p.cached(key); ok && !refresh {
+		w.RDNS, fromCache = v.(*RDNS), true
+	} else {
+		r, err := lookupRDNS(ctx, w.IP)
+		if err != nil {
+			return SourceError, err.Error(), false
+		}
+		p.store(key, r)
+		w.RDNS = r
+	}
+	switch {
+	case len(w.RDNS.Names) == 0:
+		return SourceOK, "no PTR record", fromCache
+	case !w.RDNS.Confirmed:
+		return SourceOK, "does not resolve back to the address", fromCache
+	}
+	return SourceOK, "forward confirmed", fromCache
+}
+
+// sourceDNSBL fills in the DNSBLs listing the target's address
+func (p *WhoisToolPlugin) sourceDNSBL(ctx context.Context, cfg Config, w *Whois, refresh bool) (string, string, bool) {
+	zones := lists.Split(cfg.DNSBLs)
+	switch {
+	case len(zones) == 0:
+		return SourceSkipped, "no DNSBLs configured", false
+	case w.IP == "":
+		return SourceSkipped, "no IP address known", false
+	case !publicIP(w.IP):
+		return SourceSkipped, "not a public address", false
+	}
+	key := "dnsbl:" + w.IP
+	fromCache := false
+	if v, ok := p.cached(key); ok && !refresh {
+		w.DNSBL, fromCache = v.([]Listing), true
+	} else {
+		w.DNSBL = checkDNSBLs(ctx, w.IP, zones)
+		if ctx.Err() != nil {
+			return SourceError, "timed out", false
+		}
+		p.store(key, w.DNSBL)
+	}
+	return SourceOK, fmt.Sprintf("listed on %d of %d", len(w.DNSBL), len(zones)), fromCache
+}
+
+// sourceServices fills in what services know about the account, or the
+// nick when no account is known
+func (p *WhoisToolPlugin) sourceServices(ctx context.Context, w *Whois, name string, refresh bool) (string, string, bool) {
+	b, err := p.getBackend()
+	switch {
+	case err != nil:
+		return SourceError, err.Error(), false
+	case b == nil:
+		return SourceSkipped, "no services backend configured", false
+	case name == "":
+		return SourceSkipped, "no account known", false
+	}
+	key := "services:" + strings.ToLower(name)
+	if v, ok := p.cached(key); ok && !refresh {
+		w.Services = v.(*AccountInfo)
+		return SourceOK, "", true
+	}
+	info, err := lookupAccount(ctx, b, name)
+	if err != nil {
+		return SourceError, err.Error(), false
+	}
+	if notRegistered(info) {
+		return SourceOK, name + " is not registered", false
+	}
+	p.store(key, info)
+	w.Services = info
+	return SourceOK, "", false
+}
+
+// sourceNotes fills in the oper notes matching the target, its nicks,
+// account, IP address or host
+func (p *WhoisToolPlugin) sourceNotes(w *Whois) (string, string, bool) {
+	ids := []string{w.Target, w.IP, w.Hostname, w.Account}
+	users := w.Users
+	if w.User != nil {
+		users = append([]*IRCUser{w.User}, users...)
+	}
+	for _, u := range users {
+		ids = append(ids, u.Nick, u.Hostname, u.IP, u.Account, u.Vhost, u.CloakedHost)
+	}
+	for _, e := range w.Whowas {
+		ids = append(ids, e.Nick, e.Hostname, e.IP, e.Account)
+	}
+
+	p.mu.RLock()
+	notes := make([]*Note, 0)
+	for _, n := range p.notes {
+		for _, id := range ids {
+			if id != "" && ircname.MatchMask(n.Target, id) {
+				notes = append(notes, n)
+				break
+			}
+		}
+	}
+	p.mu.RUnlock()
+
+	w.Notes = notes
+	return SourceOK, fmt.Sprintf("%d matching", len(notes)), false
+}
+
+// handleWhois looks a target up. ?type= is auto (the default), nick, ip or
+// account, and ?refresh=1 skips cached results.
+func (p *WhoisToolPlugin) handleWhois(c *gin.Context) {
+	target, kind, err := parseTarget(c.Param("target"), c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.mu.RLock()
+	timeout := time.Duration(p.config.Timeout) * time.Second
+	p.mu.RUnlock()
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	actor := access.Actor(c)
+	w := p.whois(ctx, target, kind, c.Query("refresh") == "1")
+	w.LookedUpBy = actor
+	log.Printf("[whois-tool] %s looked up %s %s", actor, kind, target)
+
+	p.mu.Lock()
+	p.recent = append([]Recent{{Target: target, Kind: kind, By: actor, At: w.LookedUpAt}}, p.recent...)
+	if len(p.recent) > maxRecent {
+		p.recent = p.recent[:maxRecent]
+	}
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, w)
+}
+
+// handleRecent returns the most recent lookups, newest first
+func (p *WhoisToolPlugin) handleRecent(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"recent": p.recent})
+}
+
+// handleListNotes returns oper notes, newest first. ?target= returns the
+// notes matching a nick, account, IP or host.
+func (p *WhoisToolPlugin) handleListNotes(c *gin.Context) {
+	target := strings.TrimSpace(c.Query("target"))
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 200
+	}
+
+	p.mu.RLock()
+	notes := make([]*Note, 0)
+	for i := len(p.notes) - 1; i >= 0 && len(notes) < limit; i-- {
+		if n := p.notes[i]; target == "" || ircname.MatchMask(n.Target, target) {
+			notes = append(notes, n)
+		}
+	}
+	total := len(p.notes)
+	p.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"notes": notes, "total": total})
+}
+
+// handleAddNote adds an oper note
+func (p *WhoisToolPlugin) handleAddNote(c *gin.Context) {
+	var req struct {
+		Target string `json:"target"`
+		Text   string `json:"text"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.Target = strings.TrimSpace(req.Target)
+	req.Text = strings.TrimSpace(req.Text)
+	switch {
+	case req.Target == "" || len(req.Target) > 255 || strings.ContainsAny(req.Target, " \r\n\x00"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target must be a nick, account, IP or host mask"})
+		return
+	case req.Target == "*":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target would match everyone"})
+		return
+	case req.Text == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "text is required"})
+		return
+	case len(req.Text) > maxNoteLength:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("text is longer than %d bytes", maxNoteLength)})
+		return
+	}
+
+	n := &Note{
+		ID:        newID(),
+		Target:    req.Target,
+		Text:      req.Text,
+		Author:    access.Actor(c),
+		CreatedAt: time.Now(),
+	}
+	p.mu.Lock()
+	p.notes = append(p.notes, n)
+	p.save()
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Note added", "note": n})
+}
+
+// handleDeleteNote deletes an oper note
+func (p *WhoisToolPlugin) handleDeleteNote(c *gin.Context) {
+	id := c.Param("id")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, n := range p.notes {
+		if n.ID == id {
+			p.notes = append(p.notes[:i], p.notes[i+1:]...)
+			p.save()
+			log.Printf("[whois-tool] %s deleted note %s on %s by %s", access.Actor(c), n.ID, n.Target, n.Author)
+			c.JSON(http.StatusOK, gin.H{"message": "Note deleted"})
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+}
+
+// handleGetConfig returns the current configuration
+func (p *WhoisToolPlugin) handleGetConfig(c *gin.Context) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.config
+	cfg.RPCPassword = ""
+	cfg.ServicesPassword = ""
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleUpdateConfig updates plugin configuration
+func (p *WhoisToolPlugin) handleUpdateConfig(c *gin.Context) {
+	var newConfig Config
+	if err := c.ShouldBindJSON(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration"})
+		return
+	}
+	switch newConfig.GeoIPService {
+	case "", "ircd", "ip-api":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "geoip_service must be ircd or ip-api"})
+		return
+	}
+	switch newConfig.ServicesBackend {
+	case "", "none", "anope", "atheme":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "services_backend must be none, anope or atheme"})
+		return
+	}
+
+	p.mu.Lock()
+	if newConfig.RPCPassword == "" {
+		newConfig.RPCPassword = p.config.RPCPassword
+	}
+	if newConfig.ServicesPassword == "" {
+		newConfig.ServicesPassword = p.config.ServicesPassword
+	}
+	if newConfig.DataDir == "" {
+		newConfig.DataDir = p.config.DataDir
+	}
+	p.config = newConfig
+	p.rpc = nil
+	p.services = nil
+	p.cache = make(map[string]cacheEntry)
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration updated"})
+}
+
+// MarshalConfig returns the current configuration as JSON
+func (p *WhoisToolPlugin) MarshalConfig() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return json.Marshal(p.config)
+}
+
+// UnmarshalConfig loads configuration from JSON
+func (p *WhoisToolPlugin) UnmarshalConfig(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rpc = nil
+	p.services = nil
+	return json.Unmarshal(data, &p.config)
+}
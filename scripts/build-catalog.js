@@ -0,0 +1,346 @@
+/**
+ * Build Plugin Catalog
+ *
+ * Validates every plugin manifest, packs each valid plugin into a
+ * .tar.gz archive and writes a catalog.json listing them with their
+ * download URLs and checksums. The catalog and every archive are signed
+ * with an Ed25519 key so the webpanel's plugin browser can trust them as a
+ * marketplace source; see release-signing.js for the archive signatures.
+ *
+ * Usage:
+ *   node scripts/build-catalog.js [--out dist] [--base-url URL] [--key FILE] [--unsigned]
+ *   node scripts/build-catalog.js --generate-key DIR
+ *
+ * The signing key is read from --key or the CATALOG_SIGNING_KEY
+ * environment variable (PEM). Without one the build fails unless
+ * --unsigned is given.
+ */
+
+const crypto = require('crypto');
+const fs = require('fs');
+const path = require('path');
+const zlib = require('zlib');
+const signing = require('./release-signing');
+
+const PLUGINS_DIR = path.join(__dirname, '..', 'plugins');
+// Code shared by the plugins rather than a plugin of its own. Archives of
+// plugins that import it carry a copy, which unpacks next to the plugin.
+const SHARED_DIR = 'internal';
+const SHARED_IMPORT = 'github.com/unrealircd/unrealircd-webpanel/internal/plugins/internal/';
+const DEFAULT_OUT = path.join(__dirname, '..', 'dist');
+const DEFAULT_BASE_URL = 'https://github.com/ValwareIRC/uwp-plugins/releases/latest/download';
+const CATALOG_VERSION = 1;
+
+const VALID_CATEGORIES = [
+  'security',
+  'integration',
+  'monitoring',
+  'management',
+  'utilities',
+  'appearance',
+  'fun'
+];
+
+function parseArgs(argv) {
+  const args = {
+    out: DEFAULT_OUT,
+    baseUrl: process.env.CATALOG_BASE_URL || DEFAULT_BASE_URL,
+    key: null,
+    unsigned: false,
+    generateKey: null
+  };
+  for (let i = 0; i < argv.length; i++) {
+    const arg = argv[i];
+    const value = () => {
+      if (i + 1 >= argv.length) {
+        throw new Error(`${arg} needs a value`);
+      }
+      return argv[++i];
+    };
+    switch (arg) {
+      case '--out': args.out = path.resolve(value()); break;
+      case '--base-url': args.baseUrl = value(); break;
+      case '--key': args.key = value(); break;
+      case '--unsigned': args.unsigned = true; break;
+      case '--generate-key': args.generateKey = path.resolve(value()); break;
+      default: throw new Error(`Unknown option ${arg}`);
+    }
+  }
+  args.baseUrl = args.baseUrl.replace(/\/+$/, '');
+  return args;
+}
+
+function validateManifest(manifest, pluginId, pluginDir) {
+  const errors = [];
+
+  const required = ['id', 'name', 'version', 'author', 'description'];
+  for (const field of required) {
+    if (!manifest[field]) {
+      errors.push(`Missing required field: ${field}`);
+    }
+  }
+
+  if (manifest.id && manifest.id !== pluginId) {
+    errors.push(`Plugin ID '${manifest.id}' doesn't match directory name '${pluginId}'`);
+  }
+
+  if (manifest.id && !/^[a-z0-9-]+$/.test(manifest.id)) {
+    errors.push(`Invalid ID format '${manifest.id}'. Use lowercase letters, numbers, and hyphens only`);
+  }
+
+  if (manifest.version && !/^\d+\.\d+\.\d+(-[a-z0-9.]+)?$/.test(manifest.version)) {
+    errors.push(`Invalid version format '${manifest.version}'. Use semantic versioning (e.g., 1.0.0)`);
+  }
+
+  if (manifest.min_panel_version && !/^\d+\.\d+\.\d+/.test(manifest.min_panel_version)) {
+    errors.push(`Invalid min_panel_version '${manifest.min_panel_version}'`);
+  }
+
+  if (!Array.isArray(manifest.permissions)) {
+    errors.push('Missing permissions; run validate-plugins.js for details');
+  }
+
+  if (manifest.category && !VALID_CATEGORIES.includes(manifest.category)) {
+    errors.push(`Invalid category '${manifest.category}'. Must be one of: ${VALID_CATEGORIES.join(', ')}`);
+  }
+
+  if (manifest.description && manifest.description.length > 500) {
+    errors.push('Description too long (maximum 500 characters)');
+  }
+
+  for (const script of manifest.frontend_scripts || []) {
+    if (!fs.existsSync(path.join(pluginDir, 'assets', script))) {
+      errors.push(`Frontend script 'assets/${script}' not found`);
+    }
+  }
+
+  return errors;
+}
+
+// Lists the files of a plugin relative to its directory, sorted so
+// archives come out the same every time
+function listFiles(dir, prefix = '') {
+  const files = [];
+  const entries = fs.readdirSync(path.join(dir, prefix), { withFileTypes: true })
+    .filter(e => !e.name.startsWith('.'))
+    .sort((a, b) => (a.name < b.name ? -1 : a.name > b.name ? 1 : 0));
+  for (const entry of entries) {
+    const rel = prefix ? `${prefix}/${entry.name}` : entry.name;
+    if (entry.isDirectory()) {
+      files.push(...listFiles(dir, rel));
+    } else if (entry.isFile()) {
+      files.push(rel);
+    }
+  }
+  return files;
+}
+
+// Builds a ustar header block. Times and owners are left at zero so the
+// archive only depends on the file contents.
+function tarHeader(name, size) {
+  if (Buffer.byteLength(name) > 100) {
+    throw new Error(`Path too long for archive: ${name}`);
+  }
+  const header = Buffer.alloc(512);
+  const field = (value, offset, length) => header.write(value, offset, length, 'utf8');
+  const octal = (value, offset, length) => field(value.toString(8).padStart(length - 1, '0') + '\0', offset, length);
+
+  field(name, 0, 100);
+  octal(0o644, 100, 8);
+  octal(0, 108, 8);
+  octal(0, 116, 8);
+  octal(size, 124, 12);
+  octal(0, 136, 12);
+  field('        ', 148, 8);
+  field('0', 156, 1);
+  field('ustar\0', 257, 6);
+  field('00', 263, 2);
+
+  let sum = 0;
+  for (const byte of header) sum += byte;
+  field(sum.toString(8).padStart(6, '0') + '\0 ', 148, 8);
+  return header;
+}
+
+// Reports whether a plugin's Go code imports the shared code
+function usesShared(pluginDir) {
+  return fs.readdirSync(pluginDir)
+    .filter(name => name.endsWith('.go'))
+    .some(name => fs.readFileSync(path.join(pluginDir, name), 'utf8').includes(SHARED_IMPORT));
+}
+
+// Packs a plugin directory into a gzipped tarball with the files under
+// a top-level directory named after the plugin, and the shared code under
+// internal/ if the plugin uses it
+function packPlugin(pluginDir, pluginId) {
+  const files = listFiles(pluginDir).map(rel => [`${pluginId}/${rel}`, path.join(pluginDir, rel)]);
+  if (usesShared(pluginDir)) {
+    const sharedDir = path.join(PLUGINS_DIR, SHARED_DIR);
+    files.push(...listFiles(sharedDir).map(rel => [`${SHARED_DIR}/${rel}`, path.join(sharedDir, rel)]));
+  }
+  const blocks = [];
+  for (const [name, file] of files) {
+    const data = fs.readFileSync(file);
+    blocks.push(tarHeader(name, data.length), data);
+    const pad = (512 - (data.length % 512)) % 512;
+    if (pad) blocks.push(Buffer.alloc(pad));
+  }
+  blocks.push(Buffer.alloc(1024));
+  return zlib.gzipSync(Buffer.concat(blocks), { level: 9 });
+}
+
+function loadSigningKey(args) {
+  let pem = process.env.CATALOG_SIGNING_KEY || null;
+  if (args.key) {
+    pem = fs.readFileSync(args.key, 'utf8');
+  }
+  if (!pem) return null;
+
+  const key = crypto.createPrivateKey(pem);
+  if (key.asymmetricKeyType !== 'ed25519') {
+    throw new Error(`Signing key must be Ed25519, not ${key.asymmetricKeyType}`);
+  }
+  return key;
+}
+
+// Identifies a public key by the start of the SHA-256 of its DER form
+function keyId(publicKey) {
+  return signing.keyNumber(publicKey).toString('hex');
+}
+
+function generateKey(dir) {
+  fs.mkdirSync(dir, { recursive: true });
+  const privatePath = path.join(dir, 'catalog-signing.key');
+  const publicPath = path.join(dir, 'catalog-signing.pub');
+  const minisignPath = path.join(dir, 'catalog-signing.minisign.pub');
+  if (fs.existsSync(privatePath)) {
+    throw new Error(`${privatePath} already exists`);
+  }
+
+  const { publicKey, privateKey } = crypto.generateKeyPairSync('ed25519');
+  fs.writeFileSync(privatePath, privateKey.export({ type: 'pkcs8', format: 'pem' }), { mode: 0o600 });
+  fs.writeFileSync(publicPath, publicKey.export({ type: 'spki', format: 'pem' }));
+  fs.writeFileSync(minisignPath, signing.minisignPublicKey(publicKey));
+
+  console.log(`🔑 Wrote ${privatePath} (keep it secret)`);
+  console.log(`🔑 Wrote ${publicPath} (key ID ${keyId(publicKey)})`);
+  console.log(`🔑 Wrote ${minisignPath} (the same key for minisign)`);
+}
+
+function buildCatalog(args) {
+  const signingKey = loadSigningKey(args);
+  if (!signingKey && !args.unsigned) {
+    throw new Error('No signing key. Set CATALOG_SIGNING_KEY, pass --key, or use --unsigned for a test build');
+  }
+
+  console.log('🔍 Scanning plugins directory...\n');
+
+  fs.mkdirSync(args.out, { recursive: true });
+
+  const plugins = [];
+  const errors = [];
+
+  const entries = fs.readdirSync(PLUGINS_DIR, { withFileTypes: true })
+    .filter(e => e.isDirectory() && !e.name.startsWith('.') && e.name !== SHARED_DIR)
+    .sort((a, b) => (a.name < b.name ? -1 : a.name > b.name ? 1 : 0));
+
+  for (const entry of entries) {
+    const pluginDir = path.join(PLUGINS_DIR, entry.name);
+    const manifestPath = path.join(pluginDir, 'plugin.json');
+
+    let manifest;
+    try {
+      manifest = JSON.parse(fs.readFileSync(manifestPath, 'utf8'));
+    } catch (err) {
+      errors.push({ plugin: entry.name, errors: [`Cannot read plugin.json: ${err.message}`] });
+      console.error(`❌ ${entry.name}: cannot read plugin.json`);
+      continue;
+    }
+
+    const validationErrors = validateManifest(manifest, entry.name, pluginDir);
+    if (validationErrors.length > 0) {
+      errors.push({ plugin: entry.name, errors: validationErrors });
+      console.error(`❌ ${entry.name}:`);
+      validationErrors.forEach(err => console.error(`   - ${err}`));
+      continue;
+    }
+
+    const archive = packPlugin(pluginDir, manifest.id);
+    const file = `${manifest.id}-${manifest.version}.tar.gz`;
+    fs.writeFileSync(path.join(args.out, file), archive);
+    const sigPath = path.join(args.out, `${file}.minisig`);
+    if (signingKey) {
+      fs.writeFileSync(sigPath, signing.sign(archive, file, signingKey));
+    } else if (fs.existsSync(sigPath)) {
+      fs.unlinkSync(sigPath);
+    }
+
+    plugins.push({
+      id: manifest.id,
+      name: manifest.name,
+      version: manifest.version,
+      author: manifest.author,
+      description: manifest.description,
+      category: manifest.category || 'utilities',
+      tags: manifest.tags || [],
+      license: manifest.license || 'MIT',
+      homepage: manifest.homepage || null,
+      min_panel_version: manifest.min_panel_version || '2.0.0',
+      permissions: manifest.permissions,
+      download_url: `${args.baseUrl}/${file}`,
+      signature_url: signingKey ? `${args.baseUrl}/${file}.minisig` : null,
+      size: archive.length,
+      sha256: crypto.createHash('sha256').update(archive).digest('hex')
+    });
+    console.log(`✅ ${manifest.name} v${manifest.version}`);
+  }
+
+  if (errors.length > 0) {
+    console.log(`\n⚠️  ${errors.length} plugin(s) had validation errors; no catalog written`);
+    process.exit(1);
+  }
+
+  const catalog = {
+    version: CATALOG_VERSION,
+    generated_at: new Date().toISOString(),
+    key_id: signingKey ? keyId(crypto.createPublicKey(signingKey)) : null,
+    plugin_count: plugins.length,
+    plugins: plugins
+  };
+
+  const body = Buffer.from(JSON.stringify(catalog, null, 2) + '\n');
+  const catalogPath = path.join(args.out, 'catalog.json');
+  fs.writeFileSync(catalogPath, body);
+
+  const sigPath = `${catalogPath}.sig`;
+  if (signingKey) {
+    // Ed25519 signs the exact bytes of catalog.json
+    const signature = crypto.sign(null, body, signingKey);
+    fs.writeFileSync(sigPath, signature.toString('base64') + '\n');
+    console.log(`\n🔏 Signed catalog with key ${catalog.key_id}`);
+  } else if (fs.existsSync(sigPath)) {
+    fs.unlinkSync(sigPath);
+  }
+
+  console.log(`📦 Generated ${path.relative(process.cwd(), catalogPath)} with ${plugins.length} plugin(s)`);
+  if (!signingKey) {
+    console.log('⚠️  Catalog is unsigned');
+  }
+}
+
+function main() {
+  let args;
+  try {
+    args = parseArgs(process.argv.slice(2));
+    if (args.generateKey) {
+      generateKey(args.generateKey);
+    } else {
+      buildCatalog(args);
+    }
+  } catch (err) {
+    console.error(`❌ ${err.message}`);
+    process.exit(1);
+  }
+}
+
+main();
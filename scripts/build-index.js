@@ -9,6 +9,8 @@ const fs = require('fs');
 const path = require('path');
 
 const PLUGINS_DIR = path.join(__dirname, '..', 'plugins');
+// Code shared by the plugins rather than a plugin of its own
+const SHARED_DIR = 'internal';
 const OUTPUT_FILE = path.join(__dirname, '..', 'plugins.json');
 
 const VALID_CATEGORIES = [
@@ -105,7 +107,7 @@ function buildIndex() {
   
   for (const entry of entries) {
     if (!entry.isDirectory()) continue;
-    if (entry.name.startsWith('.')) continue;
+    if (entry.name.startsWith('.') || entry.name === SHARED_DIR) continue;
     
     const pluginDir = path.join(PLUGINS_DIR, entry.name);
     const manifest = readPluginManifest(pluginDir);
@@ -139,6 +141,7 @@ function buildIndex() {
       repository: manifest.repository || manifest.homepage || null,
       homepage: manifest.homepage || null,
       min_panel_version: manifest.min_panel_version || "2.0.0",
+      permissions: manifest.permissions || [],
       hooks: manifest.hooks || [],
       nav_items: manifest.nav_items || [],
       dashboard_cards: manifest.dashboard_cards || [],
@@ -0,0 +1,182 @@
+/**
+ * Release Signing
+ *
+ * Signs and verifies plugin release artifacts with Ed25519, writing
+ * signatures in the minisign format so they can also be checked with the
+ * stock minisign tool (minisign -Vm FILE -P PUBLIC_KEY).
+ *
+ * Usage:
+ *   node scripts/release-signing.js sign --key FILE ARTIFACT...
+ *   node scripts/release-signing.js verify --pubkey FILE ARTIFACT...
+ *   node scripts/release-signing.js pubkey --key FILE
+ *
+ * Keys are the PEM files made by build-catalog.js --generate-key. The
+ * private key can also come from the CATALOG_SIGNING_KEY environment
+ * variable. --pubkey takes a PEM public key or a minisign public key.
+ */
+
+const crypto = require('crypto');
+const fs = require('fs');
+const path = require('path');
+
+// minisign's algorithm tag for signatures of the whole file
+const ALG_ED = Buffer.from('Ed');
+
+// Returns the raw 32-byte Ed25519 public key
+function rawPublicKey(publicKey) {
+  return publicKey.export({ type: 'spki', format: 'der' }).subarray(-32);
+}
+
+// Derives the 8-byte key number from the public key, matching the key_id
+// the catalog is published with
+function keyNumber(publicKey) {
+  const der = publicKey.export({ type: 'spki', format: 'der' });
+  return crypto.createHash('sha256').update(der).digest().subarray(0, 8);
+}
+
+function loadPrivateKey(file) {
+  const pem = file ? fs.readFileSync(file, 'utf8') : process.env.CATALOG_SIGNING_KEY;
+  if (!pem) {
+    throw new Error('No signing key. Pass --key or set CATALOG_SIGNING_KEY');
+  }
+  const key = crypto.createPrivateKey(pem);
+  if (key.asymmetricKeyType !== 'ed25519') {
+    throw new Error(`Signing key must be Ed25519, not ${key.asymmetricKeyType}`);
+  }
+  return key;
+}
+
+// Reads a PEM or minisign public key, returning the key and its number
+function loadPublicKey(text) {
+  if (text.includes('-----BEGIN')) {
+    const key = crypto.createPublicKey(text);
+    return { key, keyNum: keyNumber(key) };
+  }
+  const line = text.split('\n').map(l => l.trim()).find(l => l && !l.startsWith('untrusted comment:'));
+  const raw = Buffer.from(line || '', 'base64');
+  if (raw.length !== 42 || !raw.subarray(0, 2).equals(ALG_ED)) {
+    throw new Error('Not an Ed25519 minisign public key');
+  }
+  const der = Buffer.concat([Buffer.from('302a300506032b6570032100', 'hex'), raw.subarray(10)]);
+  return { key: crypto.createPublicKey({ key: der, format: 'der', type: 'spki' }), keyNum: raw.subarray(2, 10) };
+}
+
+// Shows a key number the way minisign does, as a little-endian integer
+function keyNumberHex(keyNum) {
+  return Buffer.from(keyNum).reverse().toString('hex').toUpperCase();
+}
+
+// Formats a public key the way minisign writes it
+function minisignPublicKey(publicKey) {
+  const keyNum = keyNumber(publicKey);
+  const raw = Buffer.concat([ALG_ED, keyNum, rawPublicKey(publicKey)]);
+  return `untrusted comment: minisign public key ${keyNumberHex(keyNum)}\n${raw.toString('base64')}\n`;
+}
+
+// Signs data, returning the contents of a .minisig file. The trusted
+// comment names the file and when it was signed, and is signed as well.
+function sign(data, fileName, privateKey) {
+  const publicKey = crypto.createPublicKey(privateKey);
+  const keyNum = keyNumber(publicKey);
+  const signature = crypto.sign(null, data, privateKey);
+  const trusted = `timestamp:${Math.floor(Date.now() / 1000)}\tfile:${fileName}`;
+  const global = crypto.sign(null, Buffer.concat([signature, Buffer.from(trusted)]), privateKey);
+
+  return [
+    `untrusted comment: signature from uwp-plugins key ${keyNumberHex(keyNum)}`,
+    Buffer.concat([ALG_ED, keyNum, signature]).toString('base64'),
+    `trusted comment: ${trusted}`,
+    global.toString('base64'),
+    ''
+  ].join('\n');
+}
+
+// Checks a .minisig against data and a public key, returning the trusted
+// comment, or throwing when anything doesn't match
+function verify(data, minisig, publicKey) {
+  const lines = minisig.split('\n').map(l => l.replace(/\r$/, ''));
+  if (lines.length < 4 || !lines[2].startsWith('trusted comment: ')) {
+    throw new Error('Malformed signature file');
+  }
+  const sig = Buffer.from(lines[1], 'base64');
+  if (sig.length !== 74) {
+    throw new Error('Malformed signature');
+  }
+  if (!sig.subarray(0, 2).equals(ALG_ED)) {
+    throw new Error('Unsupported signature algorithm');
+  }
+  if (!sig.subarray(2, 10).equals(publicKey.keyNum)) {
+    throw new Error('Signed with a different key');
+  }
+  const signature = sig.subarray(10);
+  if (!crypto.verify(null, data, publicKey.key, signature)) {
+    throw new Error('Signature does not match the file');
+  }
+  const trusted = lines[2].slice('trusted comment: '.length);
+  const global = Buffer.from(lines[3], 'base64');
+  if (!crypto.verify(null, Buffer.concat([signature, Buffer.from(trusted)]), publicKey.key, global)) {
+    throw new Error('Trusted comment signature does not match');
+  }
+  return trusted;
+}
+
+function signFile(file, privateKey) {
+  const sigFile = `${file}.minisig`;
+  fs.writeFileSync(sigFile, sign(fs.readFileSync(file), path.basename(file), privateKey));
+  return sigFile;
+}
+
+function main() {
+  const [command, ...rest] = process.argv.slice(2);
+  const files = [];
+  let keyFile = null;
+  let pubkeyFile = null;
+  for (let i = 0; i < rest.length; i++) {
+    if (rest[i] === '--key') keyFile = rest[++i];
+    else if (rest[i] === '--pubkey') pubkeyFile = rest[++i];
+    else files.push(rest[i]);
+  }
+
+  try {
+    switch (command) {
+      case 'sign': {
+        const key = loadPrivateKey(keyFile);
+        if (!files.length) throw new Error('Nothing to sign');
+        for (const file of files) {
+          console.log(`🔏 ${signFile(file, key)}`);
+        }
+        break;
+      }
+      case 'verify': {
+        if (!pubkeyFile) throw new Error('verify needs --pubkey');
+        const publicKey = loadPublicKey(fs.readFileSync(pubkeyFile, 'utf8'));
+        let failed = false;
+        for (const file of files) {
+          try {
+            const trusted = verify(fs.readFileSync(file), fs.readFileSync(`${file}.minisig`, 'utf8'), publicKey);
+            console.log(`✅ ${file} (${trusted})`);
+          } catch (err) {
+            console.error(`❌ ${file}: ${err.message}`);
+            failed = true;
+          }
+        }
+        if (failed) process.exit(1);
+        break;
+      }
+      case 'pubkey':
+        process.stdout.write(minisignPublicKey(crypto.createPublicKey(loadPrivateKey(keyFile))));
+        break;
+      default:
+        throw new Error('Usage: release-signing.js sign|verify|pubkey [--key FILE] [--pubkey FILE] [ARTIFACT...]');
+    }
+  } catch (err) {
+    console.error(`❌ ${err.message}`);
+    process.exit(1);
+  }
+}
+
+if (require.main === module) {
+  main();
+}
+
+module.exports = { sign, verify, signFile, loadPublicKey, minisignPublicKey, keyNumber };
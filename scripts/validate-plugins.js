@@ -8,6 +8,8 @@ const fs = require('fs');
 const path = require('path');
 
 const PLUGINS_DIR = path.join(__dirname, '..', 'plugins');
+// Code shared by the plugins rather than a plugin of its own
+const SHARED_DIR = 'internal';
 const isStrict = process.argv.includes('--strict');
 
 const VALID_CATEGORIES = [
@@ -44,6 +46,102 @@ const VALID_HOOKS = [
   'OnChannelListRequest'
 ];
 
+// Permissions a plugin can declare. rpc.* allows any JSON-RPC method, for
+// plugins that call methods chosen at run time.
+const VALID_PERMISSIONS = [
+  'rpc.*',
+  'rpc.users.read',
+  'rpc.users.write',
+  'rpc.channels.read',
+  'rpc.channels.write',
+  'rpc.bans.read',
+  'rpc.bans.write',
+  'rpc.spamfilters.read',
+  'rpc.spamfilters.write',
+  'rpc.server.read',
+  'rpc.server.write',
+  'rpc.logs.read',
+  'rpc.messages.write',
+  'network.outbound',
+  'network.listen',
+  'storage',
+  'system.exec'
+];
+
+// The permission each JSON-RPC method needs. The first match wins. The
+// grants package enforces the same table at run time.
+const RPC_METHOD_PERMISSIONS = require('../plugins/internal/grants/rpc-permissions.json')
+  .map(rule => [new RegExp(rule.pattern), rule.permission]);
+
+// JSON-RPC method names as they appear in Go source
+const RPC_METHOD_PATTERN = /"((?:user|whowas|channel|server_ban_exception|server_ban|name_ban|spamfilter|server|stats|rpc|log|message)\.[a-z_]+)"/g;
+
+// Checks the declared permissions against the plugin's Go code. RPC
+// methods, listeners, commands and the data directory are all found in the
+// code. Outbound connections are only a warning, since the RPC client
+// connects out as well and is told apart by a guess. A plugin with Go code
+// that declares permissions must register its manifest, or it holds none.
+function checkPermissions(pluginDir, manifest, errors, warnings) {
+  if (!Array.isArray(manifest.permissions)) {
+    errors.push('Missing permissions. List what the plugin needs, or [] if nothing');
+    return;
+  }
+  for (const perm of manifest.permissions) {
+    if (!VALID_PERMISSIONS.includes(perm)) {
+      errors.push(`Unknown permission '${perm}'. Must be one of: ${VALID_PERMISSIONS.join(', ')}`);
+    }
+  }
+
+  const declared = new Set(manifest.permissions);
+  const needed = new Map();
+  const need = (perm, reason) => {
+    if (!needed.has(perm)) needed.set(perm, reason);
+  };
+
+  if (manifest.settings_schema && manifest.settings_schema.data_dir) {
+    need('storage', 'has a data_dir setting');
+  }
+  const sources = fs.readdirSync(pluginDir).filter(name => name.endsWith('.go'));
+  if (sources.length > 0 && manifest.permissions.length > 0) {
+    checkRegistration(pluginDir, sources, errors);
+  }
+  for (const file of sources) {
+    const src = fs.readFileSync(path.join(pluginDir, file), 'utf8');
+    for (const [, method] of src.matchAll(RPC_METHOD_PATTERN)) {
+      const rule = RPC_METHOD_PERMISSIONS.find(([pattern]) => pattern.test(method));
+      if (rule) need(rule[1], `calls ${method}`);
+    }
+    if (src.includes('jsonrpc.NewLogStream')) need('rpc.logs.read', `subscribes to the log in ${file}`);
+    if (/net\.Listen|ListenAndServe/.test(src)) need('network.listen', `listens in ${file}`);
+    if (src.includes('"os/exec"')) need('system.exec', `runs commands in ${file}`);
+    const connects = /net\.Dial|tls\.Dial|smtp\.|net\.Lookup|DefaultResolver|notify\.Send/.test(src) ||
+      (/http\.NewRequest/.test(src) && !src.includes('"jsonrpc"'));
+    if (connects && !declared.has('network.outbound')) {
+      warnings.push(`${file} seems to connect out, but network.outbound isn't declared`);
+    }
+  }
+
+  for (const [perm, reason] of needed) {
+    if (!declared.has(perm) && !(perm.startsWith('rpc.') && declared.has('rpc.*'))) {
+      errors.push(`Missing permission '${perm}' (${reason})`);
+    }
+  }
+  for (const perm of declared) {
+    if (perm.startsWith('rpc.') && perm !== 'rpc.*' && !needed.has(perm)) {
+      warnings.push(`Permission '${perm}' is declared but no method needing it is called`);
+    }
+  }
+}
+
+// Checks that the plugin registers its embedded plugin.json with the
+// grants package, which is what its permissions are enforced from
+function checkRegistration(pluginDir, sources, errors) {
+  const src = sources.map(file => fs.readFileSync(path.join(pluginDir, file), 'utf8')).join('\n');
+  if (!/\/\/go:embed plugin\.json\s*\nvar manifest \[\]byte/.test(src) || !src.includes('grants.Register(manifest)')) {
+    errors.push('Permissions are not registered. Embed plugin.json as manifest and call grants.Register(manifest) from NewPlugin');
+  }
+}
+
 function validatePlugin(pluginDir, pluginId) {
   const errors = [];
   const warnings = [];
@@ -141,6 +239,9 @@ function validatePlugin(pluginDir, pluginId) {
     }
   }
   
+  // Permissions must cover what the code does
+  checkPermissions(pluginDir, manifest, errors, warnings);
+  
   return { errors, warnings };
 }
 
@@ -155,7 +256,7 @@ function main() {
   const plugins = fs.readdirSync(PLUGINS_DIR)
     .filter(name => {
       const pluginPath = path.join(PLUGINS_DIR, name);
-      return fs.statSync(pluginPath).isDirectory() && !name.startsWith('.');
+      return fs.statSync(pluginPath).isDirectory() && !name.startsWith('.') && name !== SHARED_DIR;
     });
   
   let validCount = 0;